@@ -0,0 +1,81 @@
+package api
+
+import (
+	"context"
+	"errors"
+
+	"github.com/kernel/hypeman/lib/instances"
+	"github.com/kernel/hypeman/lib/logger"
+	mw "github.com/kernel/hypeman/lib/middleware"
+	"github.com/kernel/hypeman/lib/oapi"
+)
+
+// GetInstanceAgent reports the guest-agent running in an instance
+// Note: Resolution is handled by ResolveResource middleware
+func (s *ApiService) GetInstanceAgent(ctx context.Context, request oapi.GetInstanceAgentRequestObject) (oapi.GetInstanceAgentResponseObject, error) {
+	inst := mw.GetResolvedInstance[instances.Instance](ctx)
+	if inst == nil {
+		return oapi.GetInstanceAgent500ApplicationProblemPlusJSONResponse{
+			Code:    oapi.InternalError,
+			Message: "resource not resolved",
+		}, nil
+	}
+	log := logger.FromContext(ctx)
+
+	info, err := s.InstanceManager.GetGuestAgentInfo(ctx, inst.Id)
+	if err != nil {
+		switch {
+		case errors.Is(err, instances.ErrInvalidState):
+			return oapi.GetInstanceAgent409ApplicationProblemPlusJSONResponse{
+				Code:    oapi.InvalidState,
+				Message: err.Error(),
+			}, nil
+		default:
+			log.ErrorContext(ctx, "failed to get guest agent info", "error", err)
+			return oapi.GetInstanceAgent500ApplicationProblemPlusJSONResponse{
+				Code:    oapi.InternalError,
+				Message: "failed to get guest agent info",
+			}, nil
+		}
+	}
+	return oapi.GetInstanceAgent200JSONResponse(guestAgentToOAPI(info)), nil
+}
+
+// UpdateInstanceAgent pushes the host's bundled guest-agent to a running instance
+// Note: Resolution is handled by ResolveResource middleware
+func (s *ApiService) UpdateInstanceAgent(ctx context.Context, request oapi.UpdateInstanceAgentRequestObject) (oapi.UpdateInstanceAgentResponseObject, error) {
+	inst := mw.GetResolvedInstance[instances.Instance](ctx)
+	if inst == nil {
+		return oapi.UpdateInstanceAgent500ApplicationProblemPlusJSONResponse{
+			Code:    oapi.InternalError,
+			Message: "resource not resolved",
+		}, nil
+	}
+	log := logger.FromContext(ctx)
+
+	info, err := s.InstanceManager.UpdateGuestAgent(ctx, inst.Id)
+	if err != nil {
+		switch {
+		case errors.Is(err, instances.ErrInvalidState):
+			return oapi.UpdateInstanceAgent409ApplicationProblemPlusJSONResponse{
+				Code:    oapi.InvalidState,
+				Message: err.Error(),
+			}, nil
+		default:
+			log.ErrorContext(ctx, "failed to update guest agent", "error", err)
+			return oapi.UpdateInstanceAgent500ApplicationProblemPlusJSONResponse{
+				Code:    oapi.InternalError,
+				Message: "failed to update guest agent",
+			}, nil
+		}
+	}
+	return oapi.UpdateInstanceAgent200JSONResponse(guestAgentToOAPI(info)), nil
+}
+
+func guestAgentToOAPI(info *instances.GuestAgentInfo) oapi.GuestAgent {
+	return oapi.GuestAgent{
+		Sha256:       info.Checksum,
+		LatestSha256: info.LatestChecksum,
+		UpToDate:     info.UpToDate,
+	}
+}
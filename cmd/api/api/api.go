@@ -4,12 +4,21 @@ import (
 	"github.com/kernel/hypeman/cmd/api/config"
 	"github.com/kernel/hypeman/lib/builds"
 	"github.com/kernel/hypeman/lib/devices"
+	"github.com/kernel/hypeman/lib/groups"
+	"github.com/kernel/hypeman/lib/health"
 	"github.com/kernel/hypeman/lib/images"
 	"github.com/kernel/hypeman/lib/ingress"
 	"github.com/kernel/hypeman/lib/instances"
+	"github.com/kernel/hypeman/lib/metering"
 	"github.com/kernel/hypeman/lib/network"
+	"github.com/kernel/hypeman/lib/network/overlay"
+	"github.com/kernel/hypeman/lib/nodeagent"
 	"github.com/kernel/hypeman/lib/oapi"
+	"github.com/kernel/hypeman/lib/quotas"
+	"github.com/kernel/hypeman/lib/recordings"
 	"github.com/kernel/hypeman/lib/resources"
+	"github.com/kernel/hypeman/lib/scheduler"
+	"github.com/kernel/hypeman/lib/secrets"
 	"github.com/kernel/hypeman/lib/volumes"
 )
 
@@ -19,11 +28,20 @@ type ApiService struct {
 	ImageManager    images.Manager
 	InstanceManager instances.Manager
 	VolumeManager   volumes.Manager
+	SecretManager   secrets.Manager
 	NetworkManager  network.Manager
+	OverlayManager  overlay.Manager
 	DeviceManager   devices.Manager
 	IngressManager  ingress.Manager
+	GroupManager    groups.Manager
 	BuildManager    builds.Manager
+	QuotaManager    quotas.Manager
+	Meter           *metering.Meter
 	ResourceManager *resources.Manager
+	NodeAgent       *nodeagent.Agent
+	Scheduler       *scheduler.Scheduler
+	HealthChecker   *health.Checker
+	Recordings      *recordings.Store
 }
 
 var _ oapi.StrictServerInterface = (*ApiService)(nil)
@@ -34,21 +52,39 @@ func New(
 	imageManager images.Manager,
 	instanceManager instances.Manager,
 	volumeManager volumes.Manager,
+	secretManager secrets.Manager,
 	networkManager network.Manager,
+	overlayManager overlay.Manager,
 	deviceManager devices.Manager,
 	ingressManager ingress.Manager,
+	groupManager groups.Manager,
 	buildManager builds.Manager,
+	quotaManager quotas.Manager,
+	meter *metering.Meter,
 	resourceManager *resources.Manager,
+	nodeAgent *nodeagent.Agent,
+	scheduler *scheduler.Scheduler,
+	healthChecker *health.Checker,
+	recordingStore *recordings.Store,
 ) *ApiService {
 	return &ApiService{
 		Config:          config,
 		ImageManager:    imageManager,
 		InstanceManager: instanceManager,
 		VolumeManager:   volumeManager,
+		SecretManager:   secretManager,
 		NetworkManager:  networkManager,
+		OverlayManager:  overlayManager,
 		DeviceManager:   deviceManager,
 		IngressManager:  ingressManager,
+		GroupManager:    groupManager,
 		BuildManager:    buildManager,
+		QuotaManager:    quotaManager,
+		Meter:           meter,
 		ResourceManager: resourceManager,
+		NodeAgent:       nodeAgent,
+		Scheduler:       scheduler,
+		HealthChecker:   healthChecker,
+		Recordings:      recordingStore,
 	}
 }
@@ -1,6 +1,7 @@
 package api
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"os"
@@ -12,11 +13,16 @@ import (
 	"github.com/kernel/hypeman/lib/devices"
 	"github.com/kernel/hypeman/lib/images"
 	"github.com/kernel/hypeman/lib/instances"
+	"github.com/kernel/hypeman/lib/keyring"
 	mw "github.com/kernel/hypeman/lib/middleware"
 	"github.com/kernel/hypeman/lib/network"
+	"github.com/kernel/hypeman/lib/nodeagent"
 	"github.com/kernel/hypeman/lib/oapi"
 	"github.com/kernel/hypeman/lib/paths"
+	"github.com/kernel/hypeman/lib/quotas"
 	"github.com/kernel/hypeman/lib/resources"
+	"github.com/kernel/hypeman/lib/scheduler"
+	"github.com/kernel/hypeman/lib/secrets"
 	"github.com/kernel/hypeman/lib/system"
 	"github.com/kernel/hypeman/lib/volumes"
 	"github.com/stretchr/testify/require"
@@ -29,7 +35,7 @@ func newTestService(t *testing.T) *ApiService {
 	}
 
 	p := paths.New(cfg.DataDir)
-	imageMgr, err := images.NewManager(p, 1, nil)
+	imageMgr, err := images.NewManager(p, 1, nil, nil)
 	if err != nil {
 		t.Fatalf("failed to create image manager: %v", err)
 	}
@@ -37,12 +43,17 @@ func newTestService(t *testing.T) *ApiService {
 	systemMgr := system.NewManager(p)
 	networkMgr := network.NewManager(p, cfg, nil)
 	deviceMgr := devices.NewManager(p)
-	volumeMgr := volumes.NewManager(p, 0, nil) // 0 = unlimited storage
+	volumeMgr := volumes.NewManager(p, 0, nil, nil) // 0 = unlimited storage
+	secretKeys, err := keyring.NewFileKeyring(p.SecretKeyringDir(), bytes.Repeat([]byte{1}, keyring.MasterKeySize))
+	if err != nil {
+		t.Fatalf("failed to create secret keyring: %v", err)
+	}
+	secretMgr := secrets.NewManager(p, secretKeys)
 	resourceMgr := resources.NewManager(cfg, p)
 	limits := instances.ResourceLimits{
 		MaxOverlaySize: 100 * 1024 * 1024 * 1024, // 100GB
 	}
-	instanceMgr := instances.NewManager(p, imageMgr, systemMgr, networkMgr, deviceMgr, volumeMgr, limits, "", nil, nil)
+	instanceMgr := instances.NewManager(p, imageMgr, systemMgr, networkMgr, deviceMgr, volumeMgr, secretMgr, limits, "", nil, nil)
 
 	// Register cleanup for orphaned Cloud Hypervisor processes
 	t.Cleanup(func() {
@@ -54,8 +65,12 @@ func newTestService(t *testing.T) *ApiService {
 		ImageManager:    imageMgr,
 		InstanceManager: instanceMgr,
 		VolumeManager:   volumeMgr,
+		SecretManager:   secretMgr,
 		DeviceManager:   deviceMgr,
 		ResourceManager: resourceMgr,
+		NodeAgent:       nodeagent.NewAgent(nodeagent.Config{NodeID: "test-node", SlotTTL: time.Minute}, resourceMgr),
+		QuotaManager:    quotas.NewManager(nil, instanceMgr, volumeMgr, nil),
+		Scheduler:       scheduler.New(),
 	}
 }
 
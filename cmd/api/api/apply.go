@@ -0,0 +1,940 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/c2h5oh/datasize"
+	"github.com/ghodss/yaml"
+	"github.com/kernel/hypeman/lib/images"
+	"github.com/kernel/hypeman/lib/ingress"
+	"github.com/kernel/hypeman/lib/instances"
+	"github.com/kernel/hypeman/lib/logger"
+	mw "github.com/kernel/hypeman/lib/middleware"
+	"github.com/kernel/hypeman/lib/network"
+	"github.com/kernel/hypeman/lib/oapi"
+	"github.com/kernel/hypeman/lib/problem"
+	"github.com/kernel/hypeman/lib/volumes"
+	"github.com/samber/lo"
+)
+
+// applyImageTimeout bounds how long instance creation waits for its image
+const applyImageTimeout = 10 * time.Minute
+
+// applyStep is a planned change and the function that makes it
+type applyStep struct {
+	oapi.ApplyChange
+	run func(ctx context.Context) (id string, e *oapi.Error)
+	// check runs the change's endpoint with dry_run. Nil if the change can't
+	// be checked ahead of time.
+	check func(ctx context.Context) *oapi.Error
+	// needs lists the resources, as applyKey values, the check relies on
+	needs []string
+}
+
+// applyKey identifies a resource across the steps of a plan
+func applyKey(kind oapi.ApplyResourceKind, name string) string {
+	if kind == oapi.ApplyResourceKindImage {
+		name = normalizedImage(name)
+	}
+	return string(kind) + "/" + name
+}
+
+// ApplyBundle converges the host to a desired-state bundle.
+// Changes are made through the same handlers as the individual endpoints, so
+// validation, tenant scoping and error codes match theirs.
+func (s *ApiService) ApplyBundle(ctx context.Context, request oapi.ApplyBundleRequestObject) (oapi.ApplyBundleResponseObject, error) {
+	log := logger.FromContext(ctx)
+
+	bundle, err := decodeApplyBundle(request)
+	if err != nil {
+		return oapi.ApplyBundle400ApplicationProblemPlusJSONResponse{
+			Code:    oapi.InvalidRequest,
+			Message: err.Error(),
+		}, nil
+	}
+	if err := validateApplyBundle(bundle); err != nil {
+		return oapi.ApplyBundle400ApplicationProblemPlusJSONResponse{
+			Code:    oapi.InvalidRequest,
+			Message: err.Error(),
+		}, nil
+	}
+
+	// Network DNS is shared by every tenant, like the /networks endpoints
+	if bundle.Networks != nil {
+		if claims := mw.GetClaimsFromContext(ctx); claims != nil && !claims.Role.Allows(mw.RoleAdmin) {
+			return oapi.ApplyBundle403ApplicationProblemPlusJSONResponse{
+				Code:    oapi.Forbidden,
+				Message: fmt.Sprintf("role %q is not permitted to apply networks (requires %q)", claims.Role, mw.RoleAdmin),
+			}, nil
+		}
+	}
+
+	dryRun := request.Params.DryRun != nil && *request.Params.DryRun
+	prune := request.Params.Prune != nil && *request.Params.Prune
+
+	steps, err := s.planApply(ctx, bundle, prune)
+	if err != nil {
+		if errors.Is(err, network.ErrNotFound) {
+			return oapi.ApplyBundle400ApplicationProblemPlusJSONResponse{
+				Code:    oapi.InvalidRequest,
+				Message: err.Error(),
+			}, nil
+		}
+		log.ErrorContext(ctx, "failed to plan apply", "error", err)
+		return oapi.ApplyBundle500ApplicationProblemPlusJSONResponse{
+			Code:    oapi.InternalError,
+			Message: "failed to read current state",
+		}, nil
+	}
+
+	resp := oapi.ApplyResponse{
+		DryRun:  dryRun,
+		Plan:    make([]oapi.ApplyChange, len(steps)),
+		Results: []oapi.ApplyResult{},
+	}
+	for i, step := range steps {
+		resp.Plan[i] = step.ApplyChange
+	}
+	if dryRun {
+		checkApplyPlan(ctx, steps, resp.Plan)
+		return oapi.ApplyBundle200JSONResponse(resp), nil
+	}
+
+	// A failed change doesn't stop the others; dependents report their own errors
+	failed := 0
+	for _, step := range steps {
+		result := oapi.ApplyResult{
+			Kind:   step.Kind,
+			Name:   step.Name,
+			Action: step.Action,
+			Status: oapi.ApplySucceeded,
+		}
+		id, e := step.run(ctx)
+		if e != nil {
+			failed++
+			result.Status = oapi.ApplyFailed
+			result.Error = e
+			log.WarnContext(ctx, "apply change failed", "kind", step.Kind, "name", step.Name, "action", step.Action, "error", e.Message)
+		} else if id != "" {
+			result.Id = &id
+		}
+		resp.Results = append(resp.Results, result)
+	}
+	log.InfoContext(ctx, "applied bundle", "changes", len(steps), "failed", failed, "prune", prune)
+
+	return oapi.ApplyBundle200JSONResponse(resp), nil
+}
+
+// checkApplyPlan runs the dry-run checks of each step and records failures on
+// its change in plan. Steps that rely on a resource created or replaced
+// earlier in the plan are skipped, since their checks would see the current
+// state rather than the planned one.
+func checkApplyPlan(ctx context.Context, steps []applyStep, plan []oapi.ApplyChange) {
+	pending := make(map[string]bool)
+	for i, step := range steps {
+		if step.check != nil && !slices.ContainsFunc(step.needs, func(k string) bool { return pending[k] }) {
+			plan[i].Error = step.check(ctx)
+		}
+		if step.Action != oapi.ApplyDelete {
+			pending[applyKey(step.Kind, step.Name)] = true
+		}
+	}
+}
+
+// decodeApplyBundle reads the bundle from a JSON or YAML request body
+func decodeApplyBundle(request oapi.ApplyBundleRequestObject) (*oapi.ApplyBundle, error) {
+	if request.JSONBody != nil {
+		return request.JSONBody, nil
+	}
+	if request.Body == nil {
+		return nil, errors.New("request body is required")
+	}
+
+	data, err := io.ReadAll(request.Body)
+	if err != nil {
+		return nil, fmt.Errorf("read bundle: %w", err)
+	}
+	data, err = yaml.YAMLToJSON(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid YAML bundle: %w", err)
+	}
+	var bundle oapi.ApplyBundle
+	if err := json.Unmarshal(data, &bundle); err != nil {
+		return nil, fmt.Errorf("invalid bundle: %w", err)
+	}
+	return &bundle, nil
+}
+
+// validateApplyBundle rejects bundles that name a resource more than once
+func validateApplyBundle(b *oapi.ApplyBundle) error {
+	check := func(kind string, names []string) error {
+		seen := make(map[string]bool, len(names))
+		for _, name := range names {
+			if seen[name] {
+				return fmt.Errorf("%s %q appears more than once in the bundle", kind, name)
+			}
+			seen[name] = true
+		}
+		return nil
+	}
+
+	if b.Networks != nil {
+		var names []string
+		for _, n := range *b.Networks {
+			names = append(names, n.Name)
+			if n.Records != nil {
+				var records []string
+				for _, r := range *n.Records {
+					records = append(records, r.Name)
+				}
+				if err := check("DNS record in network "+n.Name, records); err != nil {
+					return err
+				}
+			}
+		}
+		if err := check("network", names); err != nil {
+			return err
+		}
+	}
+	if b.Images != nil {
+		var names []string
+		for _, img := range *b.Images {
+			ref, err := images.ParseNormalizedRef(img.Name)
+			if err != nil {
+				return fmt.Errorf("invalid image %q: %w", img.Name, err)
+			}
+			names = append(names, ref.String())
+		}
+		if err := check("image", names); err != nil {
+			return err
+		}
+	}
+	if b.Volumes != nil {
+		var names []string
+		for _, v := range *b.Volumes {
+			names = append(names, v.Name)
+		}
+		if err := check("volume", names); err != nil {
+			return err
+		}
+	}
+	if b.Instances != nil {
+		var names []string
+		for _, inst := range *b.Instances {
+			names = append(names, inst.Name)
+		}
+		if err := check("instance", names); err != nil {
+			return err
+		}
+	}
+	if b.Ingresses != nil {
+		var names []string
+		for _, ing := range *b.Ingresses {
+			names = append(names, ing.Name)
+		}
+		if err := check("ingress", names); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// planApply diffs the bundle against the current state and returns the
+// changes to converge, in the order they must be made: networks, deletions
+// of ingresses and instances, images, volumes, then instances and ingresses.
+func (s *ApiService) planApply(ctx context.Context, b *oapi.ApplyBundle, prune bool) ([]applyStep, error) {
+	var steps []applyStep
+
+	if b.Networks != nil {
+		networkSteps, err := s.planNetworks(ctx, *b.Networks, prune)
+		if err != nil {
+			return nil, err
+		}
+		steps = append(steps, networkSteps...)
+	}
+
+	// Current state, limited to what the caller can see
+	var existingIngresses map[string]ingress.Ingress
+	if b.Ingresses != nil {
+		if s.IngressManager == nil {
+			return nil, errors.New("ingress is not available")
+		}
+		list, err := s.IngressManager.List(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("list ingresses: %w", err)
+		}
+		existingIngresses = make(map[string]ingress.Ingress)
+		for _, ing := range list {
+			if mw.TenantVisible(ctx, ing.Tenant) {
+				existingIngresses[ing.Name] = ing
+			}
+		}
+	}
+
+	var existingInstances map[string]instances.Instance
+	if b.Instances != nil {
+		list, err := s.InstanceManager.ListInstances(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("list instances: %w", err)
+		}
+		existingInstances = make(map[string]instances.Instance)
+		for _, inst := range list {
+			if mw.TenantVisible(ctx, inst.Tenant) {
+				existingInstances[inst.Name] = inst
+			}
+		}
+	}
+
+	// Volumes are also needed to resolve the instances' volume mounts
+	volList, err := s.VolumeManager.ListVolumes(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("list volumes: %w", err)
+	}
+	existingVolumes := make(map[string]volumes.Volume)
+	for _, vol := range volList {
+		if mw.TenantVisible(ctx, vol.Tenant) {
+			existingVolumes[vol.Name] = vol
+		}
+	}
+
+	// Deletions of dependents come first so what they use can be replaced
+	if b.Ingresses != nil && prune {
+		for _, name := range sortedKeys(existingIngresses) {
+			if !slices.ContainsFunc(*b.Ingresses, func(d oapi.CreateIngressRequest) bool { return d.Name == name }) {
+				steps = append(steps, s.deleteIngressStep(existingIngresses[name]))
+			}
+		}
+	}
+	if b.Instances != nil && prune {
+		for _, name := range sortedKeys(existingInstances) {
+			if !slices.ContainsFunc(*b.Instances, func(d oapi.CreateInstanceRequest) bool { return d.Name == name }) {
+				steps = append(steps, s.deleteInstanceStep(existingInstances[name]))
+			}
+		}
+	}
+
+	if b.Images != nil {
+		for _, desired := range *b.Images {
+			ref, _ := images.ParseNormalizedRef(desired.Name) // validated
+			img, err := s.ImageManager.GetImage(ctx, ref.String())
+			if err == nil && (img.Tenant == "" || mw.TenantVisible(ctx, img.Tenant)) {
+				continue
+			}
+			if err != nil && !errors.Is(err, images.ErrNotFound) {
+				return nil, fmt.Errorf("get image %s: %w", desired.Name, err)
+			}
+			steps = append(steps, s.createImageStep(desired))
+		}
+	}
+
+	if b.Volumes != nil {
+		if prune {
+			for _, name := range sortedKeys(existingVolumes) {
+				if !slices.ContainsFunc(*b.Volumes, func(d oapi.CreateVolumeRequest) bool { return d.Name == name }) {
+					steps = append(steps, s.deleteVolumeStep(existingVolumes[name]))
+				}
+			}
+		}
+		for _, desired := range *b.Volumes {
+			current, ok := existingVolumes[desired.Name]
+			if !ok {
+				steps = append(steps, s.createVolumeStep(desired, oapi.ApplyCreate, ""))
+				continue
+			}
+			// Volumes can't be resized, so a size change replaces the volume and its data
+			if diff := volumeDiff(desired, current); len(diff) > 0 {
+				step := s.createVolumeStep(desired, oapi.ApplyReplace, strings.Join(diff, ", "))
+				create := step.run
+				step.check, step.needs = s.deleteVolumeStep(current).check, nil
+				step.run = func(ctx context.Context) (string, *oapi.Error) {
+					if _, e := s.deleteVolumeStep(current).run(ctx); e != nil {
+						return "", e
+					}
+					return create(ctx)
+				}
+				steps = append(steps, step)
+			}
+		}
+	}
+
+	if b.Instances != nil {
+		for _, desired := range *b.Instances {
+			current, ok := existingInstances[desired.Name]
+			if !ok {
+				steps = append(steps, s.createInstanceStep(desired, oapi.ApplyCreate, ""))
+				continue
+			}
+			// Instances have no in-place update for their spec
+			if diff := instanceDiff(desired, current, existingVolumes); len(diff) > 0 {
+				step := s.createInstanceStep(desired, oapi.ApplyReplace, strings.Join(diff, ", "))
+				create := step.run
+				step.check, step.needs = s.deleteInstanceStep(current).check, nil
+				step.run = func(ctx context.Context) (string, *oapi.Error) {
+					if _, e := s.deleteInstanceStep(current).run(ctx); e != nil {
+						return "", e
+					}
+					return create(ctx)
+				}
+				steps = append(steps, step)
+			}
+		}
+	}
+
+	if b.Ingresses != nil {
+		for _, desired := range *b.Ingresses {
+			current, ok := existingIngresses[desired.Name]
+			if !ok {
+				steps = append(steps, s.createIngressStep(desired, oapi.ApplyCreate, ""))
+				continue
+			}
+			if !ingressRulesEqual(desired.Rules, current.Rules) {
+				step := s.createIngressStep(desired, oapi.ApplyReplace, "rules changed")
+				create := step.run
+				step.check, step.needs = s.deleteIngressStep(current).check, nil
+				step.run = func(ctx context.Context) (string, *oapi.Error) {
+					if _, e := s.deleteIngressStep(current).run(ctx); e != nil {
+						return "", e
+					}
+					return create(ctx)
+				}
+				steps = append(steps, step)
+			}
+		}
+	}
+
+	return steps, nil
+}
+
+// planNetworks diffs the search domains, domain, DHCP options and static records of each network
+func (s *ApiService) planNetworks(ctx context.Context, desired []oapi.ApplyNetwork, prune bool) ([]applyStep, error) {
+	if s.NetworkManager == nil {
+		return nil, errors.New("networking is not available")
+	}
+
+	var steps []applyStep
+	for _, n := range desired {
+		cfg, err := s.NetworkManager.GetDNSConfig(ctx, n.Name)
+		if err != nil {
+			return nil, fmt.Errorf("network %s: %w", n.Name, err)
+		}
+
+		if n.SearchDomains != nil && !slices.Equal(*n.SearchDomains, cfg.SearchDomains) {
+			domains := *n.SearchDomains
+			steps = append(steps, applyStep{
+				ApplyChange: change(oapi.ApplyResourceKindNetwork, n.Name, oapi.ApplyUpdate,
+					fmt.Sprintf("search_domains: %v -> %v", cfg.SearchDomains, domains)),
+				run: func(ctx context.Context) (string, *oapi.Error) {
+					resp, err := s.SetNetworkSearchDomains(ctx, oapi.SetNetworkSearchDomainsRequestObject{
+						Network: n.Name,
+						Body:    &oapi.SetSearchDomainsRequest{SearchDomains: domains},
+					})
+					return "", applyOutcome(resp, err)
+				},
+			})
+		}
+
+		if n.Domain != nil && *n.Domain != cfg.Domain {
+			domain := *n.Domain
+			steps = append(steps, applyStep{
+				ApplyChange: change(oapi.ApplyResourceKindNetwork, n.Name, oapi.ApplyUpdate,
+					fmt.Sprintf("domain: %s -> %s", cfg.Domain, domain)),
+				run: func(ctx context.Context) (string, *oapi.Error) {
+					resp, err := s.SetNetworkDNSDomain(ctx, oapi.SetNetworkDNSDomainRequestObject{
+						Network: n.Name,
+						Body:    &oapi.SetDNSDomainRequest{Domain: domain},
+					})
+					return "", applyOutcome(resp, err)
+				},
+			})
+		}
+
+		if n.Dhcp != nil {
+			step, err := s.dhcpStep(ctx, n.Name, *n.Dhcp)
+			if err != nil {
+				return nil, err
+			}
+			if step != nil {
+				steps = append(steps, *step)
+			}
+		}
+
+		if n.Records == nil {
+			continue
+		}
+		current := make(map[string]string, len(cfg.Records))
+		for _, r := range cfg.Records {
+			current[r.Name] = r.IP
+		}
+		wanted := make(map[string]bool, len(*n.Records))
+		for _, r := range *n.Records {
+			wanted[r.Name] = true
+			ip, ok := current[r.Name]
+			switch {
+			case !ok:
+				steps = append(steps, s.dnsRecordStep(n.Name, r, oapi.ApplyCreate, ""))
+			case ip != r.Ip:
+				steps = append(steps, s.dnsRecordStep(n.Name, r, oapi.ApplyUpdate, fmt.Sprintf("ip: %s -> %s", ip, r.Ip)))
+			}
+		}
+		if prune {
+			for _, name := range sortedKeys(current) {
+				if !wanted[name] {
+					steps = append(steps, s.dnsRecordStep(n.Name, oapi.DNSRecord{Name: name, Ip: current[name]}, oapi.ApplyDelete, ""))
+				}
+			}
+		}
+	}
+	return steps, nil
+}
+
+// dhcpStep replaces a network's DHCP options, or returns nil if they already
+// match. Options are compared after normalization, so "10.0.0.0/8" and
+// "10.1.0.0/8" describe the same route.
+func (s *ApiService) dhcpStep(ctx context.Context, networkName string, desired oapi.DHCPOptions) (*applyStep, error) {
+	current, err := s.NetworkManager.GetDHCPOptions(ctx, networkName)
+	if err != nil {
+		return nil, fmt.Errorf("network %s: %w", networkName, err)
+	}
+	want := dhcpOptionsFromOAPI(desired)
+	if normalized, err := network.NormalizeDHCPOptions(want); err == nil && normalized.MTU == current.MTU &&
+		slices.Equal(normalized.NTPServers, current.NTPServers) && slices.Equal(normalized.Routes, current.Routes) {
+		return nil, nil
+	}
+	return &applyStep{
+		ApplyChange: change(oapi.ApplyResourceKindNetwork, networkName, oapi.ApplyUpdate,
+			fmt.Sprintf("dhcp: mtu %d -> %d, ntp_servers %v -> %v, routes %d -> %d",
+				current.MTU, want.MTU, current.NTPServers, want.NTPServers, len(current.Routes), len(want.Routes))),
+		run: func(ctx context.Context) (string, *oapi.Error) {
+			resp, err := s.SetNetworkDHCP(ctx, oapi.SetNetworkDHCPRequestObject{Network: networkName, Body: &desired})
+			return "", applyOutcome(resp, err)
+		},
+	}, nil
+}
+
+// dnsRecordStep creates, updates (by recreating) or deletes a static record
+func (s *ApiService) dnsRecordStep(networkName string, record oapi.DNSRecord, action oapi.ApplyAction, reason string) applyStep {
+	return applyStep{
+		ApplyChange: change(oapi.ApplyResourceKindDnsRecord, networkName+"/"+record.Name, action, reason),
+		run: func(ctx context.Context) (string, *oapi.Error) {
+			if action != oapi.ApplyCreate {
+				resp, err := s.DeleteNetworkDNSRecord(ctx, oapi.DeleteNetworkDNSRecordRequestObject{Network: networkName, Name: record.Name})
+				if e := applyOutcome(resp, err); e != nil || action == oapi.ApplyDelete {
+					return "", e
+				}
+			}
+			resp, err := s.CreateNetworkDNSRecord(ctx, oapi.CreateNetworkDNSRecordRequestObject{Network: networkName, Body: &record})
+			return "", applyOutcome(resp, err)
+		},
+	}
+}
+
+func (s *ApiService) createImageStep(desired oapi.CreateImageRequest) applyStep {
+	return applyStep{
+		ApplyChange: change(oapi.ApplyResourceKindImage, desired.Name, oapi.ApplyCreate, ""),
+		run: func(ctx context.Context) (string, *oapi.Error) {
+			resp, err := s.CreateImage(ctx, oapi.CreateImageRequestObject{Body: &desired})
+			if e := applyOutcome(resp, err); e != nil {
+				return "", e
+			}
+			return resp.(oapi.CreateImage202JSONResponse).Name, nil
+		},
+		check: func(ctx context.Context) *oapi.Error {
+			resp, err := s.CreateImage(ctx, oapi.CreateImageRequestObject{Params: oapi.CreateImageParams{DryRun: lo.ToPtr(true)}, Body: &desired})
+			return applyOutcome(resp, err)
+		},
+	}
+}
+
+func (s *ApiService) createVolumeStep(desired oapi.CreateVolumeRequest, action oapi.ApplyAction, reason string) applyStep {
+	return applyStep{
+		ApplyChange: change(oapi.ApplyResourceKindVolume, desired.Name, action, reason),
+		run: func(ctx context.Context) (string, *oapi.Error) {
+			resp, err := s.CreateVolume(ctx, oapi.CreateVolumeRequestObject{JSONBody: &desired})
+			if e := applyOutcome(resp, err); e != nil {
+				return "", e
+			}
+			return resp.(oapi.CreateVolume201JSONResponse).Id, nil
+		},
+		check: func(ctx context.Context) *oapi.Error {
+			resp, err := s.CreateVolume(ctx, oapi.CreateVolumeRequestObject{Params: oapi.CreateVolumeParams{DryRun: lo.ToPtr(true)}, JSONBody: &desired})
+			return applyOutcome(resp, err)
+		},
+	}
+}
+
+func (s *ApiService) deleteVolumeStep(vol volumes.Volume) applyStep {
+	return applyStep{
+		ApplyChange: change(oapi.ApplyResourceKindVolume, vol.Name, oapi.ApplyDelete, ""),
+		run: func(ctx context.Context) (string, *oapi.Error) {
+			resp, err := s.DeleteVolume(mw.WithResolvedVolume(ctx, vol.Id, &vol), oapi.DeleteVolumeRequestObject{Id: vol.Id})
+			return vol.Id, applyOutcome(resp, err)
+		},
+		check: func(ctx context.Context) *oapi.Error {
+			resp, err := s.DeleteVolume(mw.WithResolvedVolume(ctx, vol.Id, &vol), oapi.DeleteVolumeRequestObject{Id: vol.Id, Params: oapi.DeleteVolumeParams{DryRun: lo.ToPtr(true)}})
+			return applyOutcome(resp, err)
+		},
+	}
+}
+
+func (s *ApiService) createInstanceStep(desired oapi.CreateInstanceRequest, action oapi.ApplyAction, reason string) applyStep {
+	var needs []string
+	if desired.Image != nil {
+		needs = append(needs, applyKey(oapi.ApplyResourceKindImage, *desired.Image))
+	}
+	if desired.RootVolume != nil {
+		needs = append(needs, applyKey(oapi.ApplyResourceKindVolume, *desired.RootVolume))
+	}
+	if desired.Volumes != nil {
+		for _, m := range *desired.Volumes {
+			needs = append(needs, applyKey(oapi.ApplyResourceKindVolume, m.VolumeId))
+		}
+	}
+	return applyStep{
+		ApplyChange: change(oapi.ApplyResourceKindInstance, desired.Name, action, reason),
+		run: func(ctx context.Context) (string, *oapi.Error) {
+			req := s.resolveBundleMounts(ctx, desired)
+			if desired.Image != nil {
+				s.waitForImage(ctx, *desired.Image)
+			}
+			resp, err := s.CreateInstance(ctx, oapi.CreateInstanceRequestObject{Body: &req})
+			if e := applyOutcome(resp, err); e != nil {
+				return "", e
+			}
+			return resp.(oapi.CreateInstance201JSONResponse).Id, nil
+		},
+		check: func(ctx context.Context) *oapi.Error {
+			req := s.resolveBundleMounts(ctx, desired)
+			resp, err := s.CreateInstance(ctx, oapi.CreateInstanceRequestObject{Params: oapi.CreateInstanceParams{DryRun: lo.ToPtr(true)}, Body: &req})
+			return applyOutcome(resp, err)
+		},
+		needs: needs,
+	}
+}
+
+// resolveBundleMounts replaces volume names in an instance's mounts and root
+// volume with their IDs, since volumes in the bundle are referenced by name
+func (s *ApiService) resolveBundleMounts(ctx context.Context, desired oapi.CreateInstanceRequest) oapi.CreateInstanceRequest {
+	resolve := func(ref string) string {
+		if _, err := s.VolumeManager.GetVolume(ctx, ref); errors.Is(err, volumes.ErrNotFound) {
+			if vol, err := s.VolumeManager.GetVolumeByName(ctx, ref); err == nil && mw.TenantVisible(ctx, vol.Tenant) {
+				return vol.Id
+			}
+		}
+		return ref
+	}
+	if desired.RootVolume != nil {
+		desired.RootVolume = lo.ToPtr(resolve(*desired.RootVolume))
+	}
+	if desired.Volumes == nil {
+		return desired
+	}
+	mounts := slices.Clone(*desired.Volumes)
+	for i, m := range mounts {
+		mounts[i].VolumeId = resolve(m.VolumeId)
+	}
+	desired.Volumes = &mounts
+	return desired
+}
+
+func (s *ApiService) deleteInstanceStep(inst instances.Instance) applyStep {
+	return applyStep{
+		ApplyChange: change(oapi.ApplyResourceKindInstance, inst.Name, oapi.ApplyDelete, ""),
+		run: func(ctx context.Context) (string, *oapi.Error) {
+			resp, err := s.DeleteInstance(mw.WithResolvedInstance(ctx, inst.Id, &inst), oapi.DeleteInstanceRequestObject{Id: inst.Id})
+			return inst.Id, applyOutcome(resp, err)
+		},
+		check: func(ctx context.Context) *oapi.Error {
+			resp, err := s.DeleteInstance(mw.WithResolvedInstance(ctx, inst.Id, &inst), oapi.DeleteInstanceRequestObject{Id: inst.Id, Params: oapi.DeleteInstanceParams{DryRun: lo.ToPtr(true)}})
+			return applyOutcome(resp, err)
+		},
+	}
+}
+
+func (s *ApiService) createIngressStep(desired oapi.CreateIngressRequest, action oapi.ApplyAction, reason string) applyStep {
+	var needs []string
+	for _, rule := range desired.Rules {
+		needs = append(needs, applyKey(oapi.ApplyResourceKindInstance, rule.Target.Instance))
+	}
+	return applyStep{
+		ApplyChange: change(oapi.ApplyResourceKindIngress, desired.Name, action, reason),
+		run: func(ctx context.Context) (string, *oapi.Error) {
+			resp, err := s.CreateIngress(ctx, oapi.CreateIngressRequestObject{Body: &desired})
+			if e := applyOutcome(resp, err); e != nil {
+				return "", e
+			}
+			return resp.(oapi.CreateIngress201JSONResponse).Id, nil
+		},
+		check: func(ctx context.Context) *oapi.Error {
+			resp, err := s.CreateIngress(ctx, oapi.CreateIngressRequestObject{Params: oapi.CreateIngressParams{DryRun: lo.ToPtr(true)}, Body: &desired})
+			return applyOutcome(resp, err)
+		},
+		needs: needs,
+	}
+}
+
+func (s *ApiService) deleteIngressStep(ing ingress.Ingress) applyStep {
+	return applyStep{
+		ApplyChange: change(oapi.ApplyResourceKindIngress, ing.Name, oapi.ApplyDelete, ""),
+		run: func(ctx context.Context) (string, *oapi.Error) {
+			resp, err := s.DeleteIngress(mw.WithResolvedIngress(ctx, ing.ID, &ing), oapi.DeleteIngressRequestObject{Id: ing.ID})
+			return ing.ID, applyOutcome(resp, err)
+		},
+		check: func(ctx context.Context) *oapi.Error {
+			resp, err := s.DeleteIngress(mw.WithResolvedIngress(ctx, ing.ID, &ing), oapi.DeleteIngressRequestObject{Id: ing.ID, Params: oapi.DeleteIngressParams{DryRun: lo.ToPtr(true)}})
+			return applyOutcome(resp, err)
+		},
+	}
+}
+
+// waitForImage waits until an image finishes building, so instances in the
+// same bundle as their image can be created. Build failures and timeouts are
+// reported by instance creation.
+func (s *ApiService) waitForImage(ctx context.Context, name string) {
+	ctx, cancel := context.WithTimeout(ctx, applyImageTimeout)
+	defer cancel()
+	s.ImageManager.WaitForImage(ctx, name)
+}
+
+// applyOutcome returns the problem of a failed handler call, or nil
+func applyOutcome(resp any, err error) *oapi.Error {
+	if err != nil {
+		e := problem.New(http.StatusInternalServerError, oapi.InternalError, err.Error())
+		return &e
+	}
+	if e, ok := problem.FromResponse(resp); ok {
+		return &e
+	}
+	return nil
+}
+
+func change(kind oapi.ApplyResourceKind, name string, action oapi.ApplyAction, reason string) oapi.ApplyChange {
+	c := oapi.ApplyChange{Kind: kind, Name: name, Action: action}
+	if reason != "" {
+		c.Reason = &reason
+	}
+	return c
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := slices.Collect(maps.Keys(m))
+	sort.Strings(keys)
+	return keys
+}
+
+// volumeDiff lists the differences between a desired and an existing volume
+func volumeDiff(desired oapi.CreateVolumeRequest, current volumes.Volume) []string {
+	var diff []string
+	if desired.Id != nil && *desired.Id != "" && *desired.Id != current.Id {
+		diff = append(diff, fmt.Sprintf("id: %s -> %s", current.Id, *desired.Id))
+	}
+	if desired.SizeGb != current.SizeGb {
+		diff = append(diff, fmt.Sprintf("size_gb: %d -> %d", current.SizeGb, desired.SizeGb))
+	}
+	if desired.Encrypted != nil && *desired.Encrypted != current.Encrypted {
+		diff = append(diff, fmt.Sprintf("encrypted: %t -> %t", current.Encrypted, *desired.Encrypted))
+	}
+	return diff
+}
+
+// instanceDiff lists the differences between a desired and an existing
+// instance. Only fields set in the bundle are compared, since defaults are
+// resolved at creation.
+func instanceDiff(desired oapi.CreateInstanceRequest, current instances.Instance, vols map[string]volumes.Volume) []string {
+	var diff []string
+	add := func(field string, from, to any) {
+		diff = append(diff, fmt.Sprintf("%s: %v -> %v", field, from, to))
+	}
+
+	if normalizedImage(lo.FromPtr(desired.Image)) != normalizedImage(current.Image) {
+		add("image", current.Image, lo.FromPtr(desired.Image))
+	}
+	if desired.RootVolume != nil {
+		id := *desired.RootVolume
+		if vol, ok := vols[id]; ok {
+			id = vol.Id
+		}
+		if id != current.RootVolume {
+			add("root_volume", current.RootVolume, *desired.RootVolume)
+		}
+	}
+	if desired.Vcpus != nil && *desired.Vcpus != current.Vcpus {
+		add("vcpus", current.Vcpus, *desired.Vcpus)
+	}
+	for _, f := range []struct {
+		name    string
+		desired *string
+		current int64
+	}{
+		{"size", desired.Size, current.Size},
+		{"hotplug_size", desired.HotplugSize, current.HotplugSize},
+		{"overlay_size", desired.OverlaySize, current.OverlaySize},
+	} {
+		if f.desired == nil || *f.desired == "" {
+			continue
+		}
+		var size datasize.ByteSize
+		if err := size.UnmarshalText([]byte(*f.desired)); err != nil || int64(size) != f.current {
+			add(f.name, datasize.ByteSize(f.current).HR(), *f.desired)
+		}
+	}
+	if desired.Env != nil && !maps.Equal(*desired.Env, current.Env) {
+		diff = append(diff, "env changed")
+	}
+	if desired.Labels != nil && !maps.Equal(*desired.Labels, current.Labels) {
+		diff = append(diff, "labels changed")
+	}
+	if desired.Entrypoint != nil && !slices.Equal(*desired.Entrypoint, current.Entrypoint) {
+		add("entrypoint", current.Entrypoint, *desired.Entrypoint)
+	}
+	if desired.Cmd != nil && !slices.Equal(*desired.Cmd, current.Cmd) {
+		add("cmd", current.Cmd, *desired.Cmd)
+	}
+	if desired.EnableNestedVirt != nil && *desired.EnableNestedVirt != current.NestedVirt {
+		add("enable_nested_virt", current.NestedVirt, *desired.EnableNestedVirt)
+	}
+	if desired.KernelArgs != nil && !slices.Equal(*desired.KernelArgs, current.KernelArgs) {
+		add("kernel_args", current.KernelArgs, *desired.KernelArgs)
+	}
+	if desired.Sysctls != nil && !maps.Equal(*desired.Sysctls, current.Sysctls) {
+		diff = append(diff, "sysctls changed")
+	}
+	if desired.Ulimits != nil {
+		var want []instances.Ulimit
+		for _, u := range *desired.Ulimits {
+			want = append(want, instances.Ulimit{Name: string(u.Name), Soft: u.Soft, Hard: u.Hard})
+		}
+		if !slices.Equal(want, current.Ulimits) {
+			diff = append(diff, "ulimits changed")
+		}
+	}
+	if desired.Workdir != nil && *desired.Workdir != current.Workdir {
+		add("workdir", current.Workdir, *desired.Workdir)
+	}
+	if desired.Hypervisor != nil && string(*desired.Hypervisor) != string(current.HypervisorType) {
+		add("hypervisor", current.HypervisorType, *desired.Hypervisor)
+	}
+	if desired.Network != nil && desired.Network.Enabled != nil && *desired.Network.Enabled != current.NetworkEnabled {
+		add("network.enabled", current.NetworkEnabled, *desired.Network.Enabled)
+	}
+
+	// Mounts are compared by volume ID, resolving bundle volume names
+	if desired.Volumes != nil {
+		key := func(id, path string, readonly bool) string {
+			return fmt.Sprintf("%s:%s:%t", id, path, readonly)
+		}
+		var want, have []string
+		for _, m := range *desired.Volumes {
+			id := m.VolumeId
+			if vol, ok := vols[id]; ok {
+				id = vol.Id
+			}
+			want = append(want, key(id, m.MountPath, m.Readonly != nil && *m.Readonly))
+		}
+		for _, a := range current.Volumes {
+			have = append(have, key(a.VolumeID, a.MountPath, a.Readonly))
+		}
+		sort.Strings(want)
+		sort.Strings(have)
+		if !slices.Equal(want, have) {
+			diff = append(diff, "volumes changed")
+		}
+	}
+
+	// Shared directories are compared with host paths resolved, as stored
+	if desired.SharedDirectories != nil {
+		key := func(hostPath, mountPath string, readonly bool) string {
+			return fmt.Sprintf("%s:%s:%t", hostPath, filepath.Clean(mountPath), readonly)
+		}
+		var want, have []string
+		for _, d := range *desired.SharedDirectories {
+			hostPath := d.HostPath
+			if resolved, err := filepath.EvalSymlinks(hostPath); err == nil {
+				hostPath = resolved
+			}
+			want = append(want, key(hostPath, d.MountPath, d.Readonly != nil && *d.Readonly))
+		}
+		for _, d := range current.SharedDirectories {
+			have = append(have, key(d.HostPath, d.MountPath, d.Readonly))
+		}
+		sort.Strings(want)
+		sort.Strings(have)
+		if !slices.Equal(want, have) {
+			diff = append(diff, "shared directories changed")
+		}
+	}
+
+	if desired.Secrets != nil {
+		key := func(id, envVar string) string {
+			return id + ":" + envVar
+		}
+		var want, have []string
+		for _, att := range *desired.Secrets {
+			want = append(want, key(att.SecretId, lo.FromPtr(att.EnvVar)))
+		}
+		for _, att := range current.Secrets {
+			have = append(have, key(att.SecretID, att.EnvVar))
+		}
+		sort.Strings(want)
+		sort.Strings(have)
+		if !slices.Equal(want, have) {
+			diff = append(diff, "secrets changed")
+		}
+	}
+	return diff
+}
+
+// ingressRulesEqual compares desired rules with an existing ingress's,
+// applying the same port defaults as creation
+func ingressRulesEqual(desired []oapi.IngressRule, current []ingress.IngressRule) bool {
+	if len(desired) != len(current) {
+		return false
+	}
+	effectivePort := func(port int, passthrough bool) int {
+		switch {
+		case port != 0:
+			return port
+		case passthrough:
+			return ingress.DefaultPassthroughPort
+		}
+		return 80
+	}
+	for i, d := range desired {
+		c := current[i]
+		passthrough := d.TlsPassthrough != nil && *d.TlsPassthrough
+		port := 0
+		if d.Match.Port != nil {
+			port = *d.Match.Port
+		}
+		if d.Match.Hostname != c.Match.Hostname ||
+			effectivePort(port, passthrough) != effectivePort(c.Match.Port, c.TLSPassthrough) ||
+			d.Target.Instance != c.Target.Instance ||
+			d.Target.Port != c.Target.Port ||
+			(d.Target.RestoreOnDemand != nil && *d.Target.RestoreOnDemand) != c.Target.RestoreOnDemand ||
+			(d.Tls != nil && *d.Tls) != c.TLS ||
+			(d.RedirectHttp != nil && *d.RedirectHttp) != c.RedirectHTTP ||
+			passthrough != c.TLSPassthrough {
+			return false
+		}
+	}
+	return true
+}
+
+// normalizedImage returns the normalized form of an image reference, or the
+// reference itself if it doesn't parse
+func normalizedImage(name string) string {
+	if ref, err := images.ParseNormalizedRef(name); err == nil {
+		return ref.String()
+	}
+	return name
+}
@@ -0,0 +1,211 @@
+package api
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/kernel/hypeman/lib/ingress"
+	"github.com/kernel/hypeman/lib/instances"
+	mw "github.com/kernel/hypeman/lib/middleware"
+	"github.com/kernel/hypeman/lib/oapi"
+	"github.com/kernel/hypeman/lib/volumes"
+	"github.com/samber/lo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func applyBundle(t *testing.T, svc *ApiService, bundle oapi.ApplyBundle, dryRun, prune bool) oapi.ApplyResponse {
+	t.Helper()
+	resp, err := svc.ApplyBundle(ctx(), oapi.ApplyBundleRequestObject{
+		Params:   oapi.ApplyBundleParams{DryRun: &dryRun, Prune: &prune},
+		JSONBody: &bundle,
+	})
+	require.NoError(t, err)
+	result, ok := resp.(oapi.ApplyBundle200JSONResponse)
+	require.True(t, ok, "expected 200 response, got %T", resp)
+	return oapi.ApplyResponse(result)
+}
+
+func TestApplyBundle_Volumes(t *testing.T) {
+	svc := newTestService(t)
+	bundle := oapi.ApplyBundle{Volumes: &[]oapi.CreateVolumeRequest{
+		{Name: "data", SizeGb: 1},
+		{Name: "cache", SizeGb: 1},
+	}}
+
+	// A dry run plans the creates without making them
+	plan := applyBundle(t, svc, bundle, true, false)
+	assert.True(t, plan.DryRun)
+	require.Len(t, plan.Plan, 2)
+	assert.Equal(t, oapi.ApplyCreate, plan.Plan[0].Action)
+	assert.Empty(t, plan.Results)
+	vols, err := svc.VolumeManager.ListVolumes(ctx())
+	require.NoError(t, err)
+	assert.Empty(t, vols)
+
+	applied := applyBundle(t, svc, bundle, false, false)
+	require.Len(t, applied.Results, 2)
+	for _, r := range applied.Results {
+		assert.Equal(t, oapi.ApplySucceeded, r.Status, "%s: %+v", r.Name, r.Error)
+		assert.NotNil(t, r.Id)
+	}
+
+	// Applying the same bundle again is a no-op
+	again := applyBundle(t, svc, bundle, false, false)
+	assert.Empty(t, again.Plan)
+	assert.Empty(t, again.Results)
+}
+
+func TestApplyBundle_ReplaceAndPrune(t *testing.T) {
+	svc := newTestService(t)
+	applyBundle(t, svc, oapi.ApplyBundle{Volumes: &[]oapi.CreateVolumeRequest{
+		{Name: "data", SizeGb: 1},
+		{Name: "old", SizeGb: 1},
+	}}, false, false)
+
+	bundle := oapi.ApplyBundle{Volumes: &[]oapi.CreateVolumeRequest{{Name: "data", SizeGb: 2}}}
+
+	// Without prune, volumes missing from the bundle are kept
+	plan := applyBundle(t, svc, bundle, true, false)
+	require.Len(t, plan.Plan, 1)
+	assert.Equal(t, oapi.ApplyReplace, plan.Plan[0].Action)
+	assert.Equal(t, "size_gb: 1 -> 2", *plan.Plan[0].Reason)
+
+	applied := applyBundle(t, svc, bundle, false, true)
+	require.Len(t, applied.Results, 2)
+	assert.Equal(t, "old", applied.Results[0].Name)
+	assert.Equal(t, oapi.ApplyDelete, applied.Results[0].Action)
+	assert.Equal(t, oapi.ApplySucceeded, applied.Results[0].Status)
+	assert.Equal(t, oapi.ApplyReplace, applied.Results[1].Action)
+	assert.Equal(t, oapi.ApplySucceeded, applied.Results[1].Status)
+
+	vols, err := svc.VolumeManager.ListVolumes(ctx())
+	require.NoError(t, err)
+	require.Len(t, vols, 1)
+	assert.Equal(t, "data", vols[0].Name)
+	assert.Equal(t, 2, vols[0].SizeGb)
+}
+
+func TestApplyBundle_YAML(t *testing.T) {
+	svc := newTestService(t)
+	body := `
+volumes:
+  - name: data
+    size_gb: 1
+`
+	resp, err := svc.ApplyBundle(ctx(), oapi.ApplyBundleRequestObject{Body: strings.NewReader(body)})
+	require.NoError(t, err)
+	result, ok := resp.(oapi.ApplyBundle200JSONResponse)
+	require.True(t, ok, "expected 200 response, got %T", resp)
+	require.Len(t, result.Results, 1)
+	assert.Equal(t, oapi.ApplySucceeded, result.Results[0].Status)
+}
+
+func TestApplyBundle_DryRunChecks(t *testing.T) {
+	svc := newTestService(t)
+	taken := "vol-taken"
+	applyBundle(t, svc, oapi.ApplyBundle{Volumes: &[]oapi.CreateVolumeRequest{
+		{Name: "existing", SizeGb: 1, Id: &taken},
+	}}, false, false)
+
+	// Each planned change is checked as its endpoint's dry run would
+	plan := applyBundle(t, svc, oapi.ApplyBundle{Volumes: &[]oapi.CreateVolumeRequest{
+		{Name: "existing", SizeGb: 1, Id: &taken},
+		{Name: "clash", SizeGb: 1, Id: &taken},
+		{Name: "fresh", SizeGb: 1},
+	}}, true, false)
+	require.Len(t, plan.Plan, 2)
+	assert.Equal(t, "clash", plan.Plan[0].Name)
+	require.NotNil(t, plan.Plan[0].Error)
+	assert.Equal(t, oapi.AlreadyExists, plan.Plan[0].Error.Code)
+	assert.Equal(t, "fresh", plan.Plan[1].Name)
+	assert.Nil(t, plan.Plan[1].Error)
+
+	// Changes relying on a resource created earlier in the plan aren't checked
+	plan = applyBundle(t, svc, oapi.ApplyBundle{
+		Volumes: &[]oapi.CreateVolumeRequest{{Name: "data", SizeGb: 1}},
+		Instances: &[]oapi.CreateInstanceRequest{{
+			Name:    "web",
+			Image:   lo.ToPtr("docker.io/library/alpine:latest"),
+			Volumes: &[]oapi.VolumeMount{{VolumeId: "data", MountPath: "/data"}},
+		}},
+	}, true, false)
+	require.Len(t, plan.Plan, 2)
+	assert.Equal(t, oapi.ApplyResourceKindInstance, plan.Plan[1].Kind)
+	assert.Nil(t, plan.Plan[1].Error)
+}
+
+func TestApplyBundle_Invalid(t *testing.T) {
+	svc := newTestService(t)
+
+	resp, err := svc.ApplyBundle(ctx(), oapi.ApplyBundleRequestObject{JSONBody: &oapi.ApplyBundle{
+		Volumes: &[]oapi.CreateVolumeRequest{{Name: "data", SizeGb: 1}, {Name: "data", SizeGb: 2}},
+	}})
+	require.NoError(t, err)
+	bad, ok := resp.(oapi.ApplyBundle400ApplicationProblemPlusJSONResponse)
+	require.True(t, ok, "expected 400 response, got %T", resp)
+	assert.Contains(t, bad.Message, `volume "data" appears more than once`)
+
+	resp, err = svc.ApplyBundle(ctx(), oapi.ApplyBundleRequestObject{Body: strings.NewReader("volumes: [")})
+	require.NoError(t, err)
+	_, ok = resp.(oapi.ApplyBundle400ApplicationProblemPlusJSONResponse)
+	assert.True(t, ok, "expected 400 response, got %T", resp)
+}
+
+func TestApplyBundle_NetworksRequireAdmin(t *testing.T) {
+	svc := newTestService(t)
+	operatorCtx := mw.WithClaims(ctx(), &mw.Claims{Subject: "ci", Role: mw.RoleOperator})
+
+	resp, err := svc.ApplyBundle(operatorCtx, oapi.ApplyBundleRequestObject{JSONBody: &oapi.ApplyBundle{
+		Networks: &[]oapi.ApplyNetwork{{Name: "default", SearchDomains: &[]string{"svc.internal"}}},
+	}})
+	require.NoError(t, err)
+	_, ok := resp.(oapi.ApplyBundle403ApplicationProblemPlusJSONResponse)
+	assert.True(t, ok, "expected 403 response, got %T", resp)
+}
+
+func TestInstanceDiff(t *testing.T) {
+	current := instances.Instance{StoredMetadata: instances.StoredMetadata{
+		Name:    "web",
+		Image:   "nginx",
+		Vcpus:   2,
+		Size:    1024 * 1024 * 1024,
+		Env:     map[string]string{"A": "1"},
+		Volumes: []instances.VolumeAttachment{{VolumeID: "vol-1", MountPath: "/data"}},
+		Ulimits: []instances.Ulimit{{Name: "nofile", Soft: 1024, Hard: 4096}},
+	}}
+	vols := map[string]volumes.Volume{"data": {Id: "vol-1", Name: "data"}}
+
+	// Unset fields and equivalent spellings don't count as changes
+	assert.Empty(t, instanceDiff(oapi.CreateInstanceRequest{
+		Name:    "web",
+		Image:   lo.ToPtr("docker.io/library/nginx:latest"),
+		Size:    lo.ToPtr("1GB"),
+		Volumes: &[]oapi.VolumeMount{{VolumeId: "data", MountPath: "/data"}},
+		Ulimits: &[]oapi.Ulimit{{Name: oapi.UlimitNofile, Soft: 1024, Hard: 4096}},
+	}, current, vols))
+
+	diff := instanceDiff(oapi.CreateInstanceRequest{
+		Name:    "web",
+		Image:   lo.ToPtr("nginx:1.27"),
+		Vcpus:   lo.ToPtr(4),
+		Env:     &map[string]string{"A": "2"},
+		Ulimits: &[]oapi.Ulimit{{Name: oapi.UlimitNofile, Soft: 65536, Hard: 65536}},
+	}, current, vols)
+	assert.Equal(t, []string{"image: nginx -> nginx:1.27", "vcpus: 2 -> 4", "env changed", "ulimits changed"}, diff)
+}
+
+func TestIngressRulesEqual(t *testing.T) {
+	current := []ingress.IngressRule{{
+		Match:  ingress.IngressMatch{Hostname: "api.example.com"},
+		Target: ingress.IngressTarget{Instance: "web", Port: 8080},
+	}}
+	desired := []oapi.IngressRule{{
+		Match:  oapi.IngressMatch{Hostname: "api.example.com", Port: lo.ToPtr(80)},
+		Target: oapi.IngressTarget{Instance: "web", Port: 8080},
+	}}
+	assert.True(t, ingressRulesEqual(desired, current))
+
+	desired[0].Target.Port = 9090
+	assert.False(t, ingressRulesEqual(desired, current))
+}
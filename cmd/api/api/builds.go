@@ -8,10 +8,13 @@ import (
 	"io"
 	"net/http"
 	"strconv"
+	"strings"
 
 	"github.com/kernel/hypeman/lib/builds"
 	"github.com/kernel/hypeman/lib/logger"
+	mw "github.com/kernel/hypeman/lib/middleware"
 	"github.com/kernel/hypeman/lib/oapi"
+	"github.com/kernel/hypeman/lib/quotas"
 )
 
 // ListBuilds returns all builds
@@ -21,15 +24,18 @@ func (s *ApiService) ListBuilds(ctx context.Context, request oapi.ListBuildsRequ
 	domainBuilds, err := s.BuildManager.ListBuilds(ctx)
 	if err != nil {
 		log.ErrorContext(ctx, "failed to list builds", "error", err)
-		return oapi.ListBuilds500JSONResponse{
-			Code:    "internal_error",
+		return oapi.ListBuilds500ApplicationProblemPlusJSONResponse{
+			Code:    oapi.InternalError,
 			Message: "failed to list builds",
 		}, nil
 	}
 
-	oapiBuilds := make([]oapi.Build, len(domainBuilds))
-	for i, b := range domainBuilds {
-		oapiBuilds[i] = buildToOAPI(b)
+	oapiBuilds := make([]oapi.Build, 0, len(domainBuilds))
+	for _, b := range domainBuilds {
+		if !mw.TenantVisible(ctx, b.Tenant) {
+			continue
+		}
+		oapiBuilds = append(oapiBuilds, buildToOAPI(b))
 	}
 
 	return oapi.ListBuilds200JSONResponse(oapiBuilds), nil
@@ -41,9 +47,11 @@ func (s *ApiService) CreateBuild(ctx context.Context, request oapi.CreateBuildRe
 
 	// Parse multipart form fields
 	var sourceData []byte
-	var baseImageDigest, cacheScope, dockerfile string
+	var baseImageDigest, cacheScope, dockerfile, artifactPath, networkMode string
+	var allowedHosts []string
 	var timeoutSeconds int
 	var secrets []builds.SecretRef
+	var tenant *string
 
 	for {
 		part, err := request.Body.NextPart()
@@ -51,8 +59,8 @@ func (s *ApiService) CreateBuild(ctx context.Context, request oapi.CreateBuildRe
 			break
 		}
 		if err != nil {
-			return oapi.CreateBuild400JSONResponse{
-				Code:    "invalid_request",
+			return oapi.CreateBuild400ApplicationProblemPlusJSONResponse{
+				Code:    oapi.InvalidRequest,
 				Message: "failed to parse multipart form",
 			}, nil
 		}
@@ -61,16 +69,16 @@ func (s *ApiService) CreateBuild(ctx context.Context, request oapi.CreateBuildRe
 		case "source":
 			sourceData, err = io.ReadAll(part)
 			if err != nil {
-				return oapi.CreateBuild400JSONResponse{
-					Code:    "invalid_source",
+				return oapi.CreateBuild400ApplicationProblemPlusJSONResponse{
+					Code:    oapi.InvalidRequest,
 					Message: "failed to read source data",
 				}, nil
 			}
 		case "base_image_digest":
 			data, err := io.ReadAll(part)
 			if err != nil {
-				return oapi.CreateBuild400JSONResponse{
-					Code:    "invalid_request",
+				return oapi.CreateBuild400ApplicationProblemPlusJSONResponse{
+					Code:    oapi.InvalidRequest,
 					Message: "failed to read base_image_digest field",
 				}, nil
 			}
@@ -78,8 +86,8 @@ func (s *ApiService) CreateBuild(ctx context.Context, request oapi.CreateBuildRe
 		case "cache_scope":
 			data, err := io.ReadAll(part)
 			if err != nil {
-				return oapi.CreateBuild400JSONResponse{
-					Code:    "invalid_request",
+				return oapi.CreateBuild400ApplicationProblemPlusJSONResponse{
+					Code:    oapi.InvalidRequest,
 					Message: "failed to read cache_scope field",
 				}, nil
 			}
@@ -87,8 +95,8 @@ func (s *ApiService) CreateBuild(ctx context.Context, request oapi.CreateBuildRe
 		case "dockerfile":
 			data, err := io.ReadAll(part)
 			if err != nil {
-				return oapi.CreateBuild400JSONResponse{
-					Code:    "invalid_request",
+				return oapi.CreateBuild400ApplicationProblemPlusJSONResponse{
+					Code:    oapi.InvalidRequest,
 					Message: "failed to read dockerfile field",
 				}, nil
 			}
@@ -96,8 +104,8 @@ func (s *ApiService) CreateBuild(ctx context.Context, request oapi.CreateBuildRe
 		case "timeout_seconds":
 			data, err := io.ReadAll(part)
 			if err != nil {
-				return oapi.CreateBuild400JSONResponse{
-					Code:    "invalid_request",
+				return oapi.CreateBuild400ApplicationProblemPlusJSONResponse{
+					Code:    oapi.InvalidRequest,
 					Message: "failed to read timeout_seconds field",
 				}, nil
 			}
@@ -107,28 +115,79 @@ func (s *ApiService) CreateBuild(ctx context.Context, request oapi.CreateBuildRe
 		case "secrets":
 			data, err := io.ReadAll(part)
 			if err != nil {
-				return oapi.CreateBuild400JSONResponse{
-					Code:    "invalid_request",
+				return oapi.CreateBuild400ApplicationProblemPlusJSONResponse{
+					Code:    oapi.InvalidRequest,
 					Message: "failed to read secrets field",
 				}, nil
 			}
 			if err := json.Unmarshal(data, &secrets); err != nil {
-				return oapi.CreateBuild400JSONResponse{
-					Code:    "invalid_request",
+				return oapi.CreateBuild400ApplicationProblemPlusJSONResponse{
+					Code:    oapi.InvalidRequest,
 					Message: "secrets must be a JSON array of {\"id\": \"...\", \"env_var\": \"...\"} objects",
 				}, nil
 			}
+		case "tenant":
+			data, err := io.ReadAll(part)
+			if err != nil {
+				return oapi.CreateBuild400ApplicationProblemPlusJSONResponse{
+					Code:    oapi.InvalidRequest,
+					Message: "failed to read tenant field",
+				}, nil
+			}
+			tenantStr := string(data)
+			tenant = &tenantStr
+		case "network_mode":
+			data, err := io.ReadAll(part)
+			if err != nil {
+				return oapi.CreateBuild400ApplicationProblemPlusJSONResponse{
+					Code:    oapi.InvalidRequest,
+					Message: "failed to read network_mode field",
+				}, nil
+			}
+			networkMode = string(data)
+		case "allowed_hosts":
+			data, err := io.ReadAll(part)
+			if err != nil {
+				return oapi.CreateBuild400ApplicationProblemPlusJSONResponse{
+					Code:    oapi.InvalidRequest,
+					Message: "failed to read allowed_hosts field",
+				}, nil
+			}
+			allowedHosts, err = parseAllowedHosts(string(data))
+			if err != nil {
+				return oapi.CreateBuild400ApplicationProblemPlusJSONResponse{
+					Code:    oapi.InvalidRequest,
+					Message: "allowed_hosts must be a JSON array or comma-separated list of hosts",
+				}, nil
+			}
+		case "artifact_path":
+			data, err := io.ReadAll(part)
+			if err != nil {
+				return oapi.CreateBuild400ApplicationProblemPlusJSONResponse{
+					Code:    oapi.InvalidRequest,
+					Message: "failed to read artifact_path field",
+				}, nil
+			}
+			artifactPath = string(data)
 		}
 		part.Close()
 	}
 
 	if len(sourceData) == 0 {
-		return oapi.CreateBuild400JSONResponse{
-			Code:    "invalid_request",
+		return oapi.CreateBuild400ApplicationProblemPlusJSONResponse{
+			Code:    oapi.InvalidRequest,
 			Message: "source is required",
 		}, nil
 	}
 
+	buildTenant, err := mw.TenantForCreate(ctx, tenant)
+	if err != nil {
+		return oapi.CreateBuild403ApplicationProblemPlusJSONResponse{
+			Code:    oapi.Forbidden,
+			Message: err.Error(),
+		}, nil
+	}
+
 	// Note: Dockerfile validation happens in the builder agent.
 	// It will check if Dockerfile is in the source tarball or provided via dockerfile parameter.
 
@@ -138,32 +197,50 @@ func (s *ApiService) CreateBuild(ctx context.Context, request oapi.CreateBuildRe
 		CacheScope:      cacheScope,
 		Dockerfile:      dockerfile,
 		Secrets:         secrets,
+		Tenant:          buildTenant,
+		CreatedBy:       mw.CreatorFromContext(ctx),
+		ArtifactPath:    artifactPath,
 	}
 
-	// Apply timeout if provided
-	if timeoutSeconds > 0 {
+	// Apply timeout and network policy if provided
+	if timeoutSeconds > 0 || networkMode != "" || len(allowedHosts) > 0 {
 		domainReq.BuildPolicy = &builds.BuildPolicy{
 			TimeoutSeconds: timeoutSeconds,
+			NetworkMode:    networkMode,
+			AllowedDomains: allowedHosts,
 		}
 	}
 
+	release, err := s.admitQuota(ctx, buildTenant, quotas.Resources{BuildsPerHour: 1})
+	if problem, ok := quotaProblem(err); ok {
+		return oapi.CreateBuild403ApplicationProblemPlusJSONResponse(problem), nil
+	}
+	if err != nil {
+		log.ErrorContext(ctx, "failed to check build quota", "error", err)
+		return oapi.CreateBuild500ApplicationProblemPlusJSONResponse{
+			Code:    oapi.InternalError,
+			Message: "failed to create build",
+		}, nil
+	}
+	defer release()
+
 	build, err := s.BuildManager.CreateBuild(ctx, domainReq, sourceData)
 	if err != nil {
 		switch {
 		case errors.Is(err, builds.ErrDockerfileRequired):
-			return oapi.CreateBuild400JSONResponse{
-				Code:    "dockerfile_required",
+			return oapi.CreateBuild400ApplicationProblemPlusJSONResponse{
+				Code:    oapi.InvalidRequest,
 				Message: err.Error(),
 			}, nil
-		case errors.Is(err, builds.ErrInvalidSource):
-			return oapi.CreateBuild400JSONResponse{
-				Code:    "invalid_source",
+		case errors.Is(err, builds.ErrInvalidSource), errors.Is(err, builds.ErrInvalidArtifactPath), errors.Is(err, builds.ErrInvalidNetworkPolicy):
+			return oapi.CreateBuild400ApplicationProblemPlusJSONResponse{
+				Code:    oapi.InvalidRequest,
 				Message: err.Error(),
 			}, nil
 		default:
 			log.ErrorContext(ctx, "failed to create build", "error", err)
-			return oapi.CreateBuild500JSONResponse{
-				Code:    "internal_error",
+			return oapi.CreateBuild500ApplicationProblemPlusJSONResponse{
+				Code:    oapi.InternalError,
 				Message: "failed to create build",
 			}, nil
 		}
@@ -179,17 +256,23 @@ func (s *ApiService) GetBuild(ctx context.Context, request oapi.GetBuildRequestO
 	build, err := s.BuildManager.GetBuild(ctx, request.Id)
 	if err != nil {
 		if errors.Is(err, builds.ErrNotFound) {
-			return oapi.GetBuild404JSONResponse{
-				Code:    "not_found",
+			return oapi.GetBuild404ApplicationProblemPlusJSONResponse{
+				Code:    oapi.NotFound,
 				Message: "build not found",
 			}, nil
 		}
 		log.ErrorContext(ctx, "failed to get build", "error", err, "id", request.Id)
-		return oapi.GetBuild500JSONResponse{
-			Code:    "internal_error",
+		return oapi.GetBuild500ApplicationProblemPlusJSONResponse{
+			Code:    oapi.InternalError,
 			Message: "failed to get build",
 		}, nil
 	}
+	if !mw.TenantVisible(ctx, build.Tenant) {
+		return oapi.GetBuild404ApplicationProblemPlusJSONResponse{
+			Code:    oapi.NotFound,
+			Message: "build not found",
+		}, nil
+	}
 
 	return oapi.GetBuild200JSONResponse(buildToOAPI(build)), nil
 }
@@ -198,23 +281,30 @@ func (s *ApiService) GetBuild(ctx context.Context, request oapi.GetBuildRequestO
 func (s *ApiService) CancelBuild(ctx context.Context, request oapi.CancelBuildRequestObject) (oapi.CancelBuildResponseObject, error) {
 	log := logger.FromContext(ctx)
 
+	if !s.buildVisible(ctx, request.Id) {
+		return oapi.CancelBuild404ApplicationProblemPlusJSONResponse{
+			Code:    oapi.NotFound,
+			Message: "build not found",
+		}, nil
+	}
+
 	err := s.BuildManager.CancelBuild(ctx, request.Id)
 	if err != nil {
 		switch {
 		case errors.Is(err, builds.ErrNotFound):
-			return oapi.CancelBuild404JSONResponse{
-				Code:    "not_found",
+			return oapi.CancelBuild404ApplicationProblemPlusJSONResponse{
+				Code:    oapi.NotFound,
 				Message: "build not found",
 			}, nil
 		case errors.Is(err, builds.ErrBuildInProgress):
-			return oapi.CancelBuild409JSONResponse{
-				Code:    "conflict",
+			return oapi.CancelBuild409ApplicationProblemPlusJSONResponse{
+				Code:    oapi.Conflict,
 				Message: "build already in progress",
 			}, nil
 		default:
 			log.ErrorContext(ctx, "failed to cancel build", "error", err, "id", request.Id)
-			return oapi.CancelBuild500JSONResponse{
-				Code:    "internal_error",
+			return oapi.CancelBuild500ApplicationProblemPlusJSONResponse{
+				Code:    oapi.InternalError,
 				Message: "failed to cancel build",
 			}, nil
 		}
@@ -223,6 +313,185 @@ func (s *ApiService) CancelBuild(ctx context.Context, request oapi.CancelBuildRe
 	return oapi.CancelBuild204Response{}, nil
 }
 
+// RetryBuild re-runs a failed or cancelled build as a new build
+func (s *ApiService) RetryBuild(ctx context.Context, request oapi.RetryBuildRequestObject) (oapi.RetryBuildResponseObject, error) {
+	log := logger.FromContext(ctx)
+
+	build, err := s.BuildManager.GetBuild(ctx, request.Id)
+	if errors.Is(err, builds.ErrNotFound) || (err == nil && !mw.TenantVisible(ctx, build.Tenant)) {
+		return oapi.RetryBuild404ApplicationProblemPlusJSONResponse{
+			Code:    oapi.NotFound,
+			Message: "build not found",
+		}, nil
+	}
+	if err != nil {
+		log.ErrorContext(ctx, "failed to get build", "error", err, "id", request.Id)
+		return oapi.RetryBuild500ApplicationProblemPlusJSONResponse{
+			Code:    oapi.InternalError,
+			Message: "failed to retry build",
+		}, nil
+	}
+
+	retryReq := builds.RetryBuildRequest{CreatedBy: mw.CreatorFromContext(ctx)}
+	if request.Body != nil && request.Body.BuildArgs != nil {
+		retryReq.BuildArgs = *request.Body.BuildArgs
+	}
+
+	// A retry counts against the quota like any other build
+	release, err := s.admitQuota(ctx, build.Tenant, quotas.Resources{BuildsPerHour: 1})
+	if problem, ok := quotaProblem(err); ok {
+		return oapi.RetryBuild403ApplicationProblemPlusJSONResponse(problem), nil
+	}
+	if err != nil {
+		log.ErrorContext(ctx, "failed to check build quota", "error", err)
+		return oapi.RetryBuild500ApplicationProblemPlusJSONResponse{
+			Code:    oapi.InternalError,
+			Message: "failed to retry build",
+		}, nil
+	}
+	defer release()
+
+	retried, err := s.BuildManager.RetryBuild(ctx, request.Id, retryReq)
+	if err != nil {
+		switch {
+		case errors.Is(err, builds.ErrNotFound):
+			return oapi.RetryBuild404ApplicationProblemPlusJSONResponse{
+				Code:    oapi.NotFound,
+				Message: "build not found",
+			}, nil
+		case errors.Is(err, builds.ErrNotRetryable):
+			return oapi.RetryBuild409ApplicationProblemPlusJSONResponse{
+				Code:    oapi.Conflict,
+				Message: err.Error(),
+			}, nil
+		case errors.Is(err, builds.ErrInvalidNetworkPolicy), errors.Is(err, builds.ErrInvalidArtifactPath):
+			return oapi.RetryBuild400ApplicationProblemPlusJSONResponse{
+				Code:    oapi.InvalidRequest,
+				Message: err.Error(),
+			}, nil
+		default:
+			log.ErrorContext(ctx, "failed to retry build", "error", err, "id", request.Id)
+			return oapi.RetryBuild500ApplicationProblemPlusJSONResponse{
+				Code:    oapi.InternalError,
+				Message: "failed to retry build",
+			}, nil
+		}
+	}
+
+	return oapi.RetryBuild202JSONResponse(buildToOAPI(retried)), nil
+}
+
+// GetBuildArtifacts downloads the artifact tarball of an artifact build
+func (s *ApiService) GetBuildArtifacts(ctx context.Context, request oapi.GetBuildArtifactsRequestObject) (oapi.GetBuildArtifactsResponseObject, error) {
+	log := logger.FromContext(ctx)
+
+	if !s.buildVisible(ctx, request.Id) {
+		return oapi.GetBuildArtifacts404ApplicationProblemPlusJSONResponse{
+			Code:    oapi.NotFound,
+			Message: "build not found",
+		}, nil
+	}
+
+	rc, artifact, err := s.BuildManager.GetBuildArtifact(ctx, request.Id)
+	if err != nil {
+		switch {
+		case errors.Is(err, builds.ErrNotFound):
+			return oapi.GetBuildArtifacts404ApplicationProblemPlusJSONResponse{
+				Code:    oapi.NotFound,
+				Message: "build not found",
+			}, nil
+		case errors.Is(err, builds.ErrNoArtifact):
+			return oapi.GetBuildArtifacts404ApplicationProblemPlusJSONResponse{
+				Code:    oapi.NotFound,
+				Message: err.Error(),
+			}, nil
+		default:
+			log.ErrorContext(ctx, "failed to get build artifact", "error", err, "id", request.Id)
+			return oapi.GetBuildArtifacts500ApplicationProblemPlusJSONResponse{
+				Code:    oapi.InternalError,
+				Message: "failed to get build artifact",
+			}, nil
+		}
+	}
+
+	return oapi.GetBuildArtifacts200ApplicationgzipResponse{
+		Body:          rc,
+		ContentLength: artifact.SizeBytes,
+	}, nil
+}
+
+// GetBuildSbom downloads the SBOM of a successful build
+func (s *ApiService) GetBuildSbom(ctx context.Context, request oapi.GetBuildSbomRequestObject) (oapi.GetBuildSbomResponseObject, error) {
+	log := logger.FromContext(ctx)
+
+	if !s.buildVisible(ctx, request.Id) {
+		return oapi.GetBuildSbom404ApplicationProblemPlusJSONResponse{
+			Code:    oapi.NotFound,
+			Message: "build not found",
+		}, nil
+	}
+
+	rc, doc, err := s.BuildManager.GetBuildDocument(ctx, request.Id, builds.DocumentSBOM)
+	if err != nil {
+		switch {
+		case errors.Is(err, builds.ErrNotFound):
+			return oapi.GetBuildSbom404ApplicationProblemPlusJSONResponse{
+				Code:    oapi.NotFound,
+				Message: "build not found",
+			}, nil
+		case errors.Is(err, builds.ErrNoDocument):
+			return oapi.GetBuildSbom404ApplicationProblemPlusJSONResponse{
+				Code:    oapi.NotFound,
+				Message: "build has no SBOM",
+			}, nil
+		default:
+			log.ErrorContext(ctx, "failed to get build SBOM", "error", err, "id", request.Id)
+			return oapi.GetBuildSbom500ApplicationProblemPlusJSONResponse{
+				Code:    oapi.InternalError,
+				Message: "failed to get build SBOM",
+			}, nil
+		}
+	}
+
+	return buildDocumentResponse{body: rc, doc: doc}, nil
+}
+
+// GetBuildProvenance downloads the SLSA provenance of a successful build
+func (s *ApiService) GetBuildProvenance(ctx context.Context, request oapi.GetBuildProvenanceRequestObject) (oapi.GetBuildProvenanceResponseObject, error) {
+	log := logger.FromContext(ctx)
+
+	if !s.buildVisible(ctx, request.Id) {
+		return oapi.GetBuildProvenance404ApplicationProblemPlusJSONResponse{
+			Code:    oapi.NotFound,
+			Message: "build not found",
+		}, nil
+	}
+
+	rc, doc, err := s.BuildManager.GetBuildDocument(ctx, request.Id, builds.DocumentSLSAProvenance)
+	if err != nil {
+		switch {
+		case errors.Is(err, builds.ErrNotFound):
+			return oapi.GetBuildProvenance404ApplicationProblemPlusJSONResponse{
+				Code:    oapi.NotFound,
+				Message: "build not found",
+			}, nil
+		case errors.Is(err, builds.ErrNoDocument):
+			return oapi.GetBuildProvenance404ApplicationProblemPlusJSONResponse{
+				Code:    oapi.NotFound,
+				Message: "build has no provenance",
+			}, nil
+		default:
+			log.ErrorContext(ctx, "failed to get build provenance", "error", err, "id", request.Id)
+			return oapi.GetBuildProvenance500ApplicationProblemPlusJSONResponse{
+				Code:    oapi.InternalError,
+				Message: "failed to get build provenance",
+			}, nil
+		}
+	}
+
+	return buildDocumentResponse{body: rc, doc: doc}, nil
+}
+
 // GetBuildEvents streams build events via SSE
 // With follow=false (default), streams existing logs then closes
 // With follow=true, continues streaming until build completes
@@ -235,17 +504,24 @@ func (s *ApiService) GetBuildEvents(ctx context.Context, request oapi.GetBuildEv
 		follow = *request.Params.Follow
 	}
 
+	if !s.buildVisible(ctx, request.Id) {
+		return oapi.GetBuildEvents404ApplicationProblemPlusJSONResponse{
+			Code:    oapi.NotFound,
+			Message: "build not found",
+		}, nil
+	}
+
 	eventChan, err := s.BuildManager.StreamBuildEvents(ctx, request.Id, follow)
 	if err != nil {
 		if errors.Is(err, builds.ErrNotFound) {
-			return oapi.GetBuildEvents404JSONResponse{
-				Code:    "not_found",
+			return oapi.GetBuildEvents404ApplicationProblemPlusJSONResponse{
+				Code:    oapi.NotFound,
 				Message: "build not found",
 			}, nil
 		}
 		log.ErrorContext(ctx, "failed to stream build events", "error", err, "id", request.Id)
-		return oapi.GetBuildEvents500JSONResponse{
-			Code:    "internal_error",
+		return oapi.GetBuildEvents500ApplicationProblemPlusJSONResponse{
+			Code:    oapi.InternalError,
 			Message: "failed to stream build events",
 		}, nil
 	}
@@ -253,6 +529,61 @@ func (s *ApiService) GetBuildEvents(ctx context.Context, request oapi.GetBuildEv
 	return buildEventsStreamResponse{eventChan: eventChan}, nil
 }
 
+// buildVisible reports whether the build exists in a tenant visible to the caller.
+// Lookup errors other than not-found are left for the caller's own call to surface.
+func (s *ApiService) buildVisible(ctx context.Context, id string) bool {
+	build, err := s.BuildManager.GetBuild(ctx, id)
+	if err != nil {
+		return !errors.Is(err, builds.ErrNotFound)
+	}
+	return mw.TenantVisible(ctx, build.Tenant)
+}
+
+// parseAllowedHosts parses the allowed_hosts form field, either a JSON array
+// or a comma-separated list
+func parseAllowedHosts(s string) ([]string, error) {
+	s = strings.TrimSpace(s)
+	if strings.HasPrefix(s, "[") {
+		var hosts []string
+		if err := json.Unmarshal([]byte(s), &hosts); err != nil {
+			return nil, err
+		}
+		return hosts, nil
+	}
+	var hosts []string
+	for _, host := range strings.Split(s, ",") {
+		if host = strings.TrimSpace(host); host != "" {
+			hosts = append(hosts, host)
+		}
+	}
+	return hosts, nil
+}
+
+// buildDocumentResponse serves a build's SBOM or provenance as stored. The
+// generated responses would re-encode these +json documents as a base64
+// string, so they are streamed verbatim instead.
+type buildDocumentResponse struct {
+	body io.ReadCloser
+	doc  *builds.BuildDocument
+}
+
+func (r buildDocumentResponse) VisitGetBuildSbomResponse(w http.ResponseWriter) error {
+	return r.write(w)
+}
+
+func (r buildDocumentResponse) VisitGetBuildProvenanceResponse(w http.ResponseWriter) error {
+	return r.write(w)
+}
+
+func (r buildDocumentResponse) write(w http.ResponseWriter) error {
+	defer r.body.Close()
+	w.Header().Set("Content-Type", r.doc.MediaType)
+	w.Header().Set("Content-Length", strconv.FormatInt(r.doc.SizeBytes, 10))
+	w.WriteHeader(200)
+	_, err := io.Copy(w, r.body)
+	return err
+}
+
 // buildEventsStreamResponse implements oapi.GetBuildEventsResponseObject with proper SSE streaming
 type buildEventsStreamResponse struct {
 	eventChan <-chan builds.BuildEvent
@@ -295,6 +626,27 @@ func buildToOAPI(b *builds.Build) oapi.Build {
 		CompletedAt:   b.CompletedAt,
 		DurationMs:    b.DurationMS,
 	}
+	if b.Tenant != "" {
+		oapiBuild.Tenant = &b.Tenant
+	}
+	if b.Artifact != nil {
+		oapiBuild.Artifact = &oapi.BuildArtifact{
+			Path:      b.Artifact.Path,
+			SizeBytes: b.Artifact.SizeBytes,
+			Sha256:    b.Artifact.SHA256,
+		}
+	}
+	if b.Resources != nil {
+		oapiBuild.Resources = &oapi.BuildResources{
+			CpuSeconds:      b.Resources.CPUSeconds,
+			PeakMemoryBytes: b.Resources.PeakMemoryBytes,
+			NetworkRxBytes:  b.Resources.NetworkRxBytes,
+			NetworkTxBytes:  b.Resources.NetworkTxBytes,
+		}
+	}
+	oapiBuild.RetryOf = b.RetryOf
+	oapiBuild.Sbom = buildDocumentToOAPI(b.SBOM)
+	oapiBuild.SlsaProvenance = buildDocumentToOAPI(b.SLSAProvenance)
 
 	if b.Provenance != nil {
 		oapiBuild.Provenance = &oapi.BuildProvenance{
@@ -311,3 +663,15 @@ func buildToOAPI(b *builds.Build) oapi.Build {
 	return oapiBuild
 }
 
+// buildDocumentToOAPI converts a build's attestation document description
+func buildDocumentToOAPI(d *builds.BuildDocument) *oapi.BuildDocument {
+	if d == nil {
+		return nil
+	}
+	return &oapi.BuildDocument{
+		MediaType:      d.MediaType,
+		SizeBytes:      d.SizeBytes,
+		Sha256:         d.SHA256,
+		ReferrerDigest: d.ReferrerDigest,
+	}
+}
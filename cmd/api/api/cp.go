@@ -15,6 +15,8 @@ import (
 	"github.com/kernel/hypeman/lib/instances"
 	"github.com/kernel/hypeman/lib/logger"
 	mw "github.com/kernel/hypeman/lib/middleware"
+	"github.com/kernel/hypeman/lib/oapi"
+	"github.com/kernel/hypeman/lib/problem"
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/codes"
@@ -48,8 +50,23 @@ type CpRequest struct {
 	Uid uint32 `json:"uid,omitempty"`
 	// Gid is the group ID (archive mode, for "to" direction)
 	Gid uint32 `json:"gid,omitempty"`
+	// Format is "files" (default, one header per file) or "tar" (a single tar stream)
+	Format string `json:"format,omitempty"`
+	// Include limits a tar copy to entries matching one of these globs
+	Include []string `json:"include,omitempty"`
+	// Exclude skips entries of a tar copy matching any of these globs
+	Exclude []string `json:"exclude,omitempty"`
+	// Offset resumes a single-file copy: for "to", the guest keeps the first Offset
+	// bytes of the existing file; for "from", the first Offset bytes are skipped
+	Offset int64 `json:"offset,omitempty"`
 }
 
+// Copy formats
+const (
+	cpFormatFiles = "files"
+	cpFormatTar   = "tar"
+)
+
 // CpFileHeader is sent before file data in WebSocket protocol
 type CpFileHeader struct {
 	Type       string `json:"type"` // "header"
@@ -66,8 +83,9 @@ type CpFileHeader struct {
 
 // CpEndMarker signals end of file or transfer
 type CpEndMarker struct {
-	Type  string `json:"type"` // "end"
-	Final bool   `json:"final"`
+	Type   string `json:"type"` // "end"
+	Final  bool   `json:"final"`
+	Sha256 string `json:"sha256,omitempty"` // SHA-256 of the complete file (regular files)
 }
 
 // CpError reports an error
@@ -83,6 +101,15 @@ type CpResult struct {
 	Success      bool   `json:"success"`
 	Error        string `json:"error,omitempty"`
 	BytesWritten int64  `json:"bytes_written,omitempty"`
+	Sha256       string `json:"sha256,omitempty"` // SHA-256 of the complete file (files format)
+	Files        int64  `json:"files,omitempty"`  // Entries extracted (tar format)
+}
+
+// CpProgress reports aggregate progress of a tar copy
+type CpProgress struct {
+	Type  string `json:"type"` // "progress"
+	Files int64  `json:"files"`
+	Bytes int64  `json:"bytes"`
 }
 
 // CpHandler handles file copy requests via WebSocket
@@ -94,17 +121,20 @@ func (s *ApiService) CpHandler(w http.ResponseWriter, r *http.Request) {
 	// Get instance resolved by middleware
 	inst := mw.GetResolvedInstance[instances.Instance](ctx)
 	if inst == nil {
-		http.Error(w, `{"code":"internal_error","message":"resource not resolved"}`, http.StatusInternalServerError)
+		problem.Write(w, http.StatusInternalServerError, oapi.InternalError, "resource not resolved")
 		return
 	}
 
 	if inst.State != instances.StateRunning {
-		http.Error(w, fmt.Sprintf(`{"code":"invalid_state","message":"instance must be running (current state: %s)"}`, inst.State), http.StatusConflict)
+		problem.Write(w, http.StatusConflict, oapi.InvalidState, fmt.Sprintf("instance must be running (current state: %s)", inst.State))
 		return
 	}
 
+	// Keep the instance from idling into standby during the session
+	defer s.InstanceManager.TrackActivity(inst.Id)()
+
 	// Upgrade to WebSocket
-	ws, err := upgrader.Upgrade(w, r, nil)
+	ws, err := upgrade(w, r)
 	if err != nil {
 		log.ErrorContext(ctx, "websocket upgrade failed", "error", err)
 		return
@@ -136,12 +166,10 @@ func (s *ApiService) CpHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Get JWT subject for audit logging
+	// Get authenticated subject for audit logging
 	subject := "unknown"
-	if claims, ok := r.Context().Value("claims").(map[string]interface{}); ok {
-		if sub, ok := claims["sub"].(string); ok {
-			subject = sub
-		}
+	if claims := mw.GetClaimsFromContext(ctx); claims != nil && claims.Subject != "" {
+		subject = claims.Subject
 	}
 
 	// Start OTEL span for tracing (WebSocket bypasses otelchi middleware)
@@ -150,6 +178,7 @@ func (s *ApiService) CpHandler(w http.ResponseWriter, r *http.Request) {
 		trace.WithAttributes(
 			attribute.String("instance_id", inst.Id),
 			attribute.String("direction", cpReq.Direction),
+			attribute.String("format", cpReq.Format),
 			attribute.String("guest_path", cpReq.GuestPath),
 			attribute.String("subject", subject),
 		),
@@ -160,15 +189,26 @@ func (s *ApiService) CpHandler(w http.ResponseWriter, r *http.Request) {
 		"instance_id", inst.Id,
 		"subject", subject,
 		"direction", cpReq.Direction,
+		"format", cpReq.Format,
 		"guest_path", cpReq.GuestPath,
 	)
 
 	var cpErr error
 	var bytesTransferred int64
-	switch cpReq.Direction {
-	case "to":
+	switch {
+	case cpReq.Offset < 0:
+		cpErr = fmt.Errorf("invalid offset: %d", cpReq.Offset)
+	case cpReq.Offset > 0 && (cpReq.IsDir || cpReq.Format == cpFormatTar):
+		cpErr = fmt.Errorf("offset is only supported for single files")
+	case cpReq.Format != "" && cpReq.Format != cpFormatFiles && cpReq.Format != cpFormatTar:
+		cpErr = fmt.Errorf("invalid format: %s (must be 'files' or 'tar')", cpReq.Format)
+	case cpReq.Direction == "to" && cpReq.Format == cpFormatTar:
+		bytesTransferred, cpErr = s.handleArchiveCopyTo(ctx, ws, inst, cpReq)
+	case cpReq.Direction == "from" && cpReq.Format == cpFormatTar:
+		bytesTransferred, cpErr = s.handleArchiveCopyFrom(ctx, ws, inst, cpReq)
+	case cpReq.Direction == "to":
 		bytesTransferred, cpErr = s.handleCopyTo(ctx, ws, inst, cpReq)
-	case "from":
+	case cpReq.Direction == "from":
 		bytesTransferred, cpErr = s.handleCopyFrom(ctx, ws, inst, cpReq)
 	default:
 		cpErr = fmt.Errorf("invalid direction: %s (must be 'to' or 'from')", cpReq.Direction)
@@ -218,7 +258,7 @@ func (s *ApiService) CpHandler(w http.ResponseWriter, r *http.Request) {
 
 // handleCopyTo handles copying files from client to guest
 // Returns the number of bytes transferred and any error.
-func (s *ApiService) handleCopyTo(ctx context.Context, ws *websocket.Conn, inst *instances.Instance, req CpRequest) (int64, error) {
+func (s *ApiService) handleCopyTo(ctx context.Context, ws wsConn, inst *instances.Instance, req CpRequest) (int64, error) {
 	// Create vsock dialer for this hypervisor type
 	dialer, err := hypervisor.NewVsockDialer(inst.HypervisorType, inst.VsockSocket, inst.VsockCID)
 	if err != nil {
@@ -248,11 +288,12 @@ func (s *ApiService) handleCopyTo(ctx context.Context, ws *websocket.Conn, inst
 	if err := stream.Send(&guest.CopyToGuestRequest{
 		Request: &guest.CopyToGuestRequest_Start{
 			Start: &guest.CopyToGuestStart{
-				Path:  req.GuestPath,
-				Mode:  mode,
-				IsDir: req.IsDir,
-				Uid:   req.Uid,
-				Gid:   req.Gid,
+				Path:   req.GuestPath,
+				Mode:   mode,
+				IsDir:  req.IsDir,
+				Uid:    req.Uid,
+				Gid:    req.Gid,
+				Offset: req.Offset,
 			},
 		},
 	}); err != nil {
@@ -261,6 +302,7 @@ func (s *ApiService) handleCopyTo(ctx context.Context, ws *websocket.Conn, inst
 
 	// Read data chunks from WebSocket and forward to guest
 	var receivedEndMessage bool
+	var expectedSha256 string
 	var bytesSent int64
 	for {
 		msgType, data, err := ws.ReadMessage()
@@ -272,10 +314,11 @@ func (s *ApiService) handleCopyTo(ctx context.Context, ws *websocket.Conn, inst
 		}
 
 		if msgType == websocket.TextMessage {
-			// Check for end message
+			// Check for end message, optionally carrying the file's checksum
 			var msg map[string]interface{}
 			if json.Unmarshal(data, &msg) == nil {
 				if msg["type"] == "end" {
+					expectedSha256, _ = msg["sha256"].(string)
 					receivedEndMessage = true
 					break
 				}
@@ -298,7 +341,7 @@ func (s *ApiService) handleCopyTo(ctx context.Context, ws *websocket.Conn, inst
 
 	// Send end message to guest
 	if err := stream.Send(&guest.CopyToGuestRequest{
-		Request: &guest.CopyToGuestRequest_End{End: &guest.CopyToGuestEnd{}},
+		Request: &guest.CopyToGuestRequest_End{End: &guest.CopyToGuestEnd{Sha256: expectedSha256}},
 	}); err != nil {
 		return bytesSent, fmt.Errorf("send end: %w", err)
 	}
@@ -315,6 +358,7 @@ func (s *ApiService) handleCopyTo(ctx context.Context, ws *websocket.Conn, inst
 		Success:      resp.Success,
 		Error:        resp.Error,
 		BytesWritten: resp.BytesWritten,
+		Sha256:       resp.Sha256,
 	}
 	resultJSON, _ := json.Marshal(result)
 	ws.WriteMessage(websocket.TextMessage, resultJSON)
@@ -328,7 +372,7 @@ func (s *ApiService) handleCopyTo(ctx context.Context, ws *websocket.Conn, inst
 
 // handleCopyFrom handles copying files from guest to client
 // Returns the number of bytes transferred and any error.
-func (s *ApiService) handleCopyFrom(ctx context.Context, ws *websocket.Conn, inst *instances.Instance, req CpRequest) (int64, error) {
+func (s *ApiService) handleCopyFrom(ctx context.Context, ws wsConn, inst *instances.Instance, req CpRequest) (int64, error) {
 	// Create vsock dialer for this hypervisor type
 	dialer, err := hypervisor.NewVsockDialer(inst.HypervisorType, inst.VsockSocket, inst.VsockCID)
 	if err != nil {
@@ -344,6 +388,7 @@ func (s *ApiService) handleCopyFrom(ctx context.Context, ws *websocket.Conn, ins
 	stream, err := client.CopyFromGuest(ctx, &guest.CopyFromGuestRequest{
 		Path:        req.GuestPath,
 		FollowLinks: req.FollowLinks,
+		Offset:      req.Offset,
 	})
 	if err != nil {
 		return 0, fmt.Errorf("start copy stream: %w", err)
@@ -389,8 +434,9 @@ func (s *ApiService) handleCopyFrom(ctx context.Context, ws *websocket.Conn, ins
 
 		case *guest.CopyFromGuestResponse_End:
 			endMarker := CpEndMarker{
-				Type:  "end",
-				Final: r.End.Final,
+				Type:   "end",
+				Final:  r.End.Final,
+				Sha256: r.End.Sha256,
 			}
 			endJSON, _ := json.Marshal(endMarker)
 			if err := ws.WriteMessage(websocket.TextMessage, endJSON); err != nil {
@@ -419,3 +465,119 @@ func (s *ApiService) handleCopyFrom(ctx context.Context, ws *websocket.Conn, ins
 	}
 	return bytesReceived, nil
 }
+
+// handleArchiveCopyTo extracts a tar stream sent by the client into a guest
+// directory, reporting progress as the guest extracts it.
+// Returns the number of file content bytes written and any error.
+func (s *ApiService) handleArchiveCopyTo(ctx context.Context, ws wsConn, inst *instances.Instance, req CpRequest) (int64, error) {
+	dialer, err := hypervisor.NewVsockDialer(inst.HypervisorType, inst.VsockSocket, inst.VsockCID)
+	if err != nil {
+		return 0, fmt.Errorf("create vsock dialer: %w", err)
+	}
+
+	progress, err := guest.CopyArchiveToInstance(ctx, dialer, guest.CopyArchiveOptions{
+		Path:    req.GuestPath,
+		Include: req.Include,
+		Exclude: req.Exclude,
+	}, &wsTarReader{ws: ws}, func(p guest.ArchiveProgress) {
+		writeCpProgress(ws, p)
+	})
+
+	result := CpResult{
+		Type:         "result",
+		Success:      err == nil,
+		BytesWritten: progress.Bytes,
+		Files:        progress.Files,
+	}
+	if err != nil {
+		result.Error = err.Error()
+	}
+	resultJSON, _ := json.Marshal(result)
+	ws.WriteMessage(websocket.TextMessage, resultJSON)
+
+	if err != nil {
+		// Return a wrapped error so the caller logs it correctly but doesn't send a duplicate
+		return progress.Bytes, &cpErrorSent{err: err}
+	}
+	return progress.Bytes, nil
+}
+
+// handleArchiveCopyFrom streams a guest path to the client as a tar archive,
+// interleaving progress messages with the binary data.
+// Returns the number of file content bytes read and any error.
+func (s *ApiService) handleArchiveCopyFrom(ctx context.Context, ws wsConn, inst *instances.Instance, req CpRequest) (int64, error) {
+	dialer, err := hypervisor.NewVsockDialer(inst.HypervisorType, inst.VsockSocket, inst.VsockCID)
+	if err != nil {
+		return 0, fmt.Errorf("create vsock dialer: %w", err)
+	}
+
+	progress, err := guest.CopyArchiveFromInstance(ctx, dialer, guest.CopyArchiveOptions{
+		Path:        req.GuestPath,
+		Include:     req.Include,
+		Exclude:     req.Exclude,
+		FollowLinks: req.FollowLinks,
+	}, wsBinaryWriter{ws: ws}, func(p guest.ArchiveProgress) {
+		writeCpProgress(ws, p)
+	})
+	if err != nil {
+		return progress.Bytes, err
+	}
+
+	endJSON, _ := json.Marshal(CpEndMarker{Type: "end", Final: true})
+	if err := ws.WriteMessage(websocket.TextMessage, endJSON); err != nil {
+		return progress.Bytes, fmt.Errorf("write end: %w", err)
+	}
+	return progress.Bytes, nil
+}
+
+func writeCpProgress(ws wsConn, p guest.ArchiveProgress) {
+	progressJSON, _ := json.Marshal(CpProgress{Type: "progress", Files: p.Files, Bytes: p.Bytes})
+	ws.WriteMessage(websocket.TextMessage, progressJSON)
+}
+
+// wsTarReader reads a tar stream sent as binary WebSocket messages, up to
+// the client's end message
+type wsTarReader struct {
+	ws   wsConn
+	buf  []byte
+	done bool
+}
+
+func (r *wsTarReader) Read(p []byte) (int, error) {
+	for len(r.buf) == 0 {
+		if r.done {
+			return 0, io.EOF
+		}
+		msgType, data, err := r.ws.ReadMessage()
+		if err != nil {
+			if websocket.IsCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway) {
+				return 0, fmt.Errorf("client disconnected before completing transfer")
+			}
+			return 0, fmt.Errorf("read websocket: %w", err)
+		}
+		switch msgType {
+		case websocket.BinaryMessage:
+			r.buf = data
+		case websocket.TextMessage:
+			var msg map[string]interface{}
+			if json.Unmarshal(data, &msg) == nil && msg["type"] == "end" {
+				r.done = true
+			}
+		}
+	}
+	n := copy(p, r.buf)
+	r.buf = r.buf[n:]
+	return n, nil
+}
+
+// wsBinaryWriter writes each chunk as a binary WebSocket message
+type wsBinaryWriter struct {
+	ws wsConn
+}
+
+func (w wsBinaryWriter) Write(p []byte) (int, error) {
+	if err := w.ws.WriteMessage(websocket.BinaryMessage, p); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
@@ -12,6 +12,7 @@ import (
 	"github.com/kernel/hypeman/lib/oapi"
 	"github.com/kernel/hypeman/lib/paths"
 	"github.com/kernel/hypeman/lib/system"
+	"github.com/samber/lo"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -44,11 +45,13 @@ func TestCpToAndFromInstance(t *testing.T) {
 	instResp, err := svc.CreateInstance(ctx(), oapi.CreateInstanceRequestObject{
 		Body: &oapi.CreateInstanceRequest{
 			Name:  "cp-test",
-			Image: "docker.io/library/nginx:alpine",
+			Image: lo.ToPtr("docker.io/library/nginx:alpine"),
 			Network: &struct {
-				BandwidthDownload *string `json:"bandwidth_download,omitempty"`
-				BandwidthUpload   *string `json:"bandwidth_upload,omitempty"`
-				Enabled           *bool   `json:"enabled,omitempty"`
+				BandwidthDownload      *string `json:"bandwidth_download,omitempty"`
+				BandwidthDownloadBurst *string `json:"bandwidth_download_burst,omitempty"`
+				BandwidthUpload        *string `json:"bandwidth_upload,omitempty"`
+				BandwidthUploadBurst   *string `json:"bandwidth_upload_burst,omitempty"`
+				Enabled                *bool   `json:"enabled,omitempty"`
 			}{
 				Enabled: &networkEnabled,
 			},
@@ -182,11 +185,13 @@ func TestCpDirectoryToInstance(t *testing.T) {
 	instResp, err := svc.CreateInstance(ctx(), oapi.CreateInstanceRequestObject{
 		Body: &oapi.CreateInstanceRequest{
 			Name:  "cp-dir-test",
-			Image: "docker.io/library/nginx:alpine",
+			Image: lo.ToPtr("docker.io/library/nginx:alpine"),
 			Network: &struct {
-				BandwidthDownload *string `json:"bandwidth_download,omitempty"`
-				BandwidthUpload   *string `json:"bandwidth_upload,omitempty"`
-				Enabled           *bool   `json:"enabled,omitempty"`
+				BandwidthDownload      *string `json:"bandwidth_download,omitempty"`
+				BandwidthDownloadBurst *string `json:"bandwidth_download_burst,omitempty"`
+				BandwidthUpload        *string `json:"bandwidth_upload,omitempty"`
+				BandwidthUploadBurst   *string `json:"bandwidth_upload_burst,omitempty"`
+				Enabled                *bool   `json:"enabled,omitempty"`
 			}{
 				Enabled: &networkEnabled,
 			},
@@ -0,0 +1,50 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"runtime"
+	"time"
+
+	"github.com/kernel/hypeman/lib/builds"
+	"github.com/kernel/hypeman/lib/guest"
+	"github.com/kernel/hypeman/lib/images"
+	"github.com/kernel/hypeman/lib/instances"
+)
+
+// DebugState is the response of GET /debug/state
+type DebugState struct {
+	Time          time.Time            `json:"time"`
+	Goroutines    int                  `json:"goroutines"`
+	Instances     instances.DebugState `json:"instances"`
+	ImageBuilds   *images.QueueState   `json:"image_builds,omitempty"`
+	Builds        *builds.QueueState   `json:"builds,omitempty"`
+	GuestConnPool int                  `json:"guest_conn_pool_size"`
+	GuestConns    []guest.ConnInfo     `json:"guest_conns"`
+}
+
+// DebugStateHandler dumps manager internals (queue depths, held instance locks,
+// in-flight builds, guest-agent connections) for diagnosing hangs.
+// Served outside the OpenAPI spec, only when debug endpoints are enabled.
+func (s *ApiService) DebugStateHandler(w http.ResponseWriter, r *http.Request) {
+	state := DebugState{
+		Time:          time.Now(),
+		Goroutines:    runtime.NumGoroutine(),
+		Instances:     s.InstanceManager.DebugState(),
+		GuestConnPool: guest.PoolSize(),
+		GuestConns:    guest.PoolConns(),
+	}
+	if s.ImageManager != nil {
+		q := s.ImageManager.BuildQueueState()
+		state.ImageBuilds = &q
+	}
+	if s.BuildManager != nil {
+		q := s.BuildManager.QueueState()
+		state.Builds = &q
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.Encode(state)
+}
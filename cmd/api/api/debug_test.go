@@ -0,0 +1,28 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDebugStateHandler(t *testing.T) {
+	svc := newTestService(t)
+
+	rec := httptest.NewRecorder()
+	svc.DebugStateHandler(rec, httptest.NewRequest(http.MethodGet, "/debug/state", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+
+	var state DebugState
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &state))
+	assert.Positive(t, state.Goroutines)
+	assert.Empty(t, state.Instances.Locks)
+	require.NotNil(t, state.ImageBuilds)
+	assert.Equal(t, 1, state.ImageBuilds.MaxConcurrent)
+	assert.Nil(t, state.Builds, "no build manager in the test service")
+}
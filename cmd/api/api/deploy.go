@@ -0,0 +1,195 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/kernel/hypeman/lib/builds"
+	"github.com/kernel/hypeman/lib/instances"
+	"github.com/kernel/hypeman/lib/logger"
+	mw "github.com/kernel/hypeman/lib/middleware"
+	"github.com/kernel/hypeman/lib/oapi"
+	"github.com/kernel/hypeman/lib/problem"
+)
+
+// deployBuildTimeout bounds how long a deploy waits for its build to finish
+const deployBuildTimeout = time.Hour
+
+// deployPollInterval is how often build status is checked while waiting
+var deployPollInterval = time.Second
+
+// DeployBuild creates an instance from the image a build produced, waiting
+// for the build and the image's conversion, and optionally rolls over an
+// existing instance with the same name
+func (s *ApiService) DeployBuild(ctx context.Context, request oapi.DeployBuildRequestObject) (oapi.DeployBuildResponseObject, error) {
+	log := logger.FromContext(ctx)
+
+	spec := request.Body.Instance
+	if spec.RootVolume != nil {
+		return oapi.DeployBuild400ApplicationProblemPlusJSONResponse{
+			Code:    oapi.InvalidRequest,
+			Message: "deployed instances boot the build's image; root_volume isn't allowed",
+		}, nil
+	}
+
+	build, err := s.waitForBuild(ctx, request.Id)
+	if errors.Is(err, builds.ErrNotFound) {
+		return oapi.DeployBuild404ApplicationProblemPlusJSONResponse{
+			Code:    oapi.NotFound,
+			Message: "build not found",
+		}, nil
+	}
+	if err != nil {
+		log.ErrorContext(ctx, "failed to get build", "error", err, "id", request.Id)
+		return oapi.DeployBuild500ApplicationProblemPlusJSONResponse{
+			Code:    oapi.InternalError,
+			Message: "failed to get build",
+		}, nil
+	}
+	if build.Status != builds.StatusReady {
+		return oapi.DeployBuild409ApplicationProblemPlusJSONResponse{
+			Code:    oapi.InvalidState,
+			Message: fmt.Sprintf("build is %s", build.Status),
+		}, nil
+	}
+	if build.ImageRef == nil || build.ImageDigest == nil {
+		return oapi.DeployBuild409ApplicationProblemPlusJSONResponse{
+			Code:    oapi.InvalidState,
+			Message: "build produced an artifact, not an image",
+		}, nil
+	}
+
+	image := *build.ImageRef + "@" + *build.ImageDigest
+	s.waitForImage(ctx, image)
+	spec.Image = &image
+
+	var existing *instances.Instance
+	list, err := s.InstanceManager.ListInstances(ctx)
+	if err != nil {
+		log.ErrorContext(ctx, "failed to list instances", "error", err)
+		return oapi.DeployBuild500ApplicationProblemPlusJSONResponse{
+			Code:    oapi.InternalError,
+			Message: "failed to list instances",
+		}, nil
+	}
+	for i := range list {
+		if list[i].Name == spec.Name && mw.TenantVisible(ctx, list[i].Tenant) {
+			existing = &list[i]
+		}
+	}
+	if existing == nil {
+		return deployOutcome(s.CreateInstance(ctx, oapi.CreateInstanceRequestObject{Body: &spec}))
+	}
+	if request.Body.Replace == nil || !*request.Body.Replace {
+		return oapi.DeployBuild409ApplicationProblemPlusJSONResponse{
+			Code:    oapi.AlreadyExists,
+			Message: fmt.Sprintf("instance %s already exists; set replace to roll it over", spec.Name),
+		}, nil
+	}
+
+	// Roll over: the replacement runs next to the old instance until it's
+	// up, then takes over its name
+	spec.Name = deployName(existing.Name, build.ID)
+	resp, err := s.CreateInstance(ctx, oapi.CreateInstanceRequestObject{Body: &spec})
+	created, ok := resp.(oapi.CreateInstance201JSONResponse)
+	if !ok || err != nil {
+		return deployOutcome(resp, err)
+	}
+	if created.State != oapi.InstanceStateRunning {
+		if err := s.InstanceManager.DeleteInstance(ctx, created.Id, instances.DeleteInstanceRequest{}); err != nil {
+			log.ErrorContext(ctx, "failed to delete replacement instance", "instance_id", created.Id, "error", err)
+		}
+		return oapi.DeployBuild409ApplicationProblemPlusJSONResponse{
+			Code:    oapi.InvalidState,
+			Message: fmt.Sprintf("replacement instance is %s; kept %s", created.State, existing.Name),
+		}, nil
+	}
+
+	if err := s.InstanceManager.DeleteInstance(ctx, existing.Id, instances.DeleteInstanceRequest{}); err != nil {
+		log.ErrorContext(ctx, "failed to delete replaced instance", "instance_id", existing.Id, "error", err)
+		return oapi.DeployBuild500ApplicationProblemPlusJSONResponse{
+			Code:    oapi.InternalError,
+			Message: fmt.Sprintf("failed to delete %s; its replacement runs as %s", existing.Name, created.Name),
+		}, nil
+	}
+	renamed, err := s.InstanceManager.RenameInstance(ctx, created.Id, existing.Name)
+	if err != nil {
+		log.ErrorContext(ctx, "failed to rename replacement instance", "instance_id", created.Id, "error", err)
+		return oapi.DeployBuild500ApplicationProblemPlusJSONResponse{
+			Code:    oapi.InternalError,
+			Message: fmt.Sprintf("failed to rename the replacement of %s; it runs as %s", existing.Name, created.Name),
+		}, nil
+	}
+	log.InfoContext(ctx, "rolled over instance", "name", existing.Name, "old_id", existing.Id, "new_id", renamed.Id, "build_id", build.ID)
+	return oapi.DeployBuild201JSONResponse(instanceToOAPI(*renamed)), nil
+}
+
+// waitForBuild waits until a build the caller can see finishes, or the
+// deploy's wait times out, and returns it
+func (s *ApiService) waitForBuild(ctx context.Context, id string) (*builds.Build, error) {
+	ctx, cancel := context.WithTimeout(ctx, deployBuildTimeout)
+	defer cancel()
+
+	ticker := time.NewTicker(deployPollInterval)
+	defer ticker.Stop()
+	for {
+		build, err := s.BuildManager.GetBuild(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		if !mw.TenantVisible(ctx, build.Tenant) {
+			return nil, builds.ErrNotFound
+		}
+		switch build.Status {
+		case builds.StatusReady, builds.StatusFailed, builds.StatusCancelled:
+			return build, nil
+		}
+		select {
+		case <-ctx.Done():
+			// Reported as a build that isn't ready
+			return build, nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// deployName is the temporary name of an instance replacing name, unique to
+// the build being deployed
+func deployName(name, buildID string) string {
+	suffix := "-" + buildID[:min(len(buildID), 8)]
+	if len(name)+len(suffix) > 63 {
+		name = strings.TrimRight(name[:63-len(suffix)], "-")
+	}
+	return name + suffix
+}
+
+// deployOutcome converts the outcome of creating the deployed instance
+func deployOutcome(resp oapi.CreateInstanceResponseObject, err error) (oapi.DeployBuildResponseObject, error) {
+	if err != nil {
+		return nil, err
+	}
+	if created, ok := resp.(oapi.CreateInstance201JSONResponse); ok {
+		return oapi.DeployBuild201JSONResponse(created), nil
+	}
+	e, _ := problem.FromResponse(resp)
+	status := 0
+	if e.Status != nil {
+		status = *e.Status
+	}
+	switch status {
+	case http.StatusBadRequest:
+		return oapi.DeployBuild400ApplicationProblemPlusJSONResponse(e), nil
+	case http.StatusForbidden:
+		return oapi.DeployBuild403ApplicationProblemPlusJSONResponse(e), nil
+	case http.StatusNotFound:
+		return oapi.DeployBuild404ApplicationProblemPlusJSONResponse(e), nil
+	case http.StatusConflict:
+		return oapi.DeployBuild409ApplicationProblemPlusJSONResponse(e), nil
+	default:
+		return oapi.DeployBuild500ApplicationProblemPlusJSONResponse(e), nil
+	}
+}
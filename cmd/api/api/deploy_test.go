@@ -0,0 +1,64 @@
+package api
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/kernel/hypeman/lib/builds"
+	"github.com/kernel/hypeman/lib/oapi"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// buildsStub implements builds.Manager, serving fixed builds
+type buildsStub struct {
+	builds.Manager
+	builds map[string]*builds.Build
+}
+
+func (b *buildsStub) GetBuild(ctx context.Context, id string) (*builds.Build, error) {
+	build, ok := b.builds[id]
+	if !ok {
+		return nil, builds.ErrNotFound
+	}
+	return build, nil
+}
+
+func TestDeployBuild_NotDeployable(t *testing.T) {
+	digest, ref := "sha256:abc", "10.102.0.1:8083/builds/artifact"
+	svc := &ApiService{BuildManager: &buildsStub{builds: map[string]*builds.Build{
+		"failed":   {ID: "failed", Status: builds.StatusFailed},
+		"artifact": {ID: "artifact", Status: builds.StatusReady, Artifact: &builds.BuildArtifact{Path: "/app/dist"}},
+		"image":    {ID: "image", Status: builds.StatusReady, ImageRef: &ref, ImageDigest: &digest},
+	}}}
+	deploy := func(id string, spec oapi.CreateInstanceRequest) oapi.DeployBuildResponseObject {
+		t.Helper()
+		resp, err := svc.DeployBuild(ctx(), oapi.DeployBuildRequestObject{Id: id, Body: &oapi.DeployBuildRequest{Instance: spec}})
+		require.NoError(t, err)
+		return resp
+	}
+
+	resp := deploy("missing", oapi.CreateInstanceRequest{Name: "app"})
+	assert.IsType(t, oapi.DeployBuild404ApplicationProblemPlusJSONResponse{}, resp)
+
+	resp = deploy("failed", oapi.CreateInstanceRequest{Name: "app"})
+	require.IsType(t, oapi.DeployBuild409ApplicationProblemPlusJSONResponse{}, resp)
+	assert.Equal(t, "build is failed", resp.(oapi.DeployBuild409ApplicationProblemPlusJSONResponse).Message)
+
+	resp = deploy("artifact", oapi.CreateInstanceRequest{Name: "app"})
+	require.IsType(t, oapi.DeployBuild409ApplicationProblemPlusJSONResponse{}, resp)
+	assert.Contains(t, resp.(oapi.DeployBuild409ApplicationProblemPlusJSONResponse).Message, "artifact")
+
+	volume := "data"
+	resp = deploy("image", oapi.CreateInstanceRequest{Name: "app", RootVolume: &volume})
+	assert.IsType(t, oapi.DeployBuild400ApplicationProblemPlusJSONResponse{}, resp)
+}
+
+func TestDeployName(t *testing.T) {
+	assert.Equal(t, "app-tz4a98xx", deployName("app", "tz4a98xxat96iws9zmbrgj3a"))
+
+	long := deployName(strings.Repeat("a", 53)+"-"+strings.Repeat("b", 9), "tz4a98xxat96iws9zmbrgj3a")
+	assert.Len(t, long, 62)
+	assert.Equal(t, strings.Repeat("a", 53)+"-tz4a98xx", long)
+}
@@ -6,14 +6,15 @@ import (
 
 	"github.com/kernel/hypeman/lib/devices"
 	"github.com/kernel/hypeman/lib/oapi"
+	"github.com/samber/lo"
 )
 
 // ListDevices returns all registered devices
 func (s *ApiService) ListDevices(ctx context.Context, request oapi.ListDevicesRequestObject) (oapi.ListDevicesResponseObject, error) {
 	deviceList, err := s.DeviceManager.ListDevices(ctx)
 	if err != nil {
-		return oapi.ListDevices500JSONResponse{
-			Code:    "internal_error",
+		return oapi.ListDevices500ApplicationProblemPlusJSONResponse{
+			Code:    oapi.InternalError,
 			Message: err.Error(),
 		}, nil
 	}
@@ -30,8 +31,8 @@ func (s *ApiService) ListDevices(ctx context.Context, request oapi.ListDevicesRe
 func (s *ApiService) ListAvailableDevices(ctx context.Context, request oapi.ListAvailableDevicesRequestObject) (oapi.ListAvailableDevicesResponseObject, error) {
 	available, err := s.DeviceManager.ListAvailableDevices(ctx)
 	if err != nil {
-		return oapi.ListAvailableDevices500JSONResponse{
-			Code:    "internal_error",
+		return oapi.ListAvailableDevices500ApplicationProblemPlusJSONResponse{
+			Code:    oapi.InternalError,
 			Message: err.Error(),
 		}, nil
 	}
@@ -44,6 +45,34 @@ func (s *ApiService) ListAvailableDevices(ctx context.Context, request oapi.List
 	return oapi.ListAvailableDevices200JSONResponse(result), nil
 }
 
+// ListUSBDevices discovers USB devices on the host
+func (s *ApiService) ListUSBDevices(ctx context.Context, request oapi.ListUSBDevicesRequestObject) (oapi.ListUSBDevicesResponseObject, error) {
+	usbDevices, err := s.DeviceManager.ListUSBDevices(ctx)
+	if err != nil {
+		return oapi.ListUSBDevices500ApplicationProblemPlusJSONResponse{
+			Code:    oapi.InternalError,
+			Message: err.Error(),
+		}, nil
+	}
+
+	result := make([]oapi.USBDevice, len(usbDevices))
+	for i, d := range usbDevices {
+		result[i] = oapi.USBDevice{
+			BusPort:      d.BusPort,
+			Bus:          d.Bus,
+			Port:         d.Port,
+			Address:      d.Address,
+			VendorId:     d.VendorID,
+			ProductId:    d.ProductID,
+			Manufacturer: lo.EmptyableToPtr(d.Manufacturer),
+			Product:      lo.EmptyableToPtr(d.Product),
+			Serial:       lo.EmptyableToPtr(d.Serial),
+		}
+	}
+
+	return oapi.ListUSBDevices200JSONResponse(result), nil
+}
+
 // CreateDevice registers a new device for passthrough
 func (s *ApiService) CreateDevice(ctx context.Context, request oapi.CreateDeviceRequestObject) (oapi.CreateDeviceResponseObject, error) {
 	var name string
@@ -59,28 +88,28 @@ func (s *ApiService) CreateDevice(ctx context.Context, request oapi.CreateDevice
 	if err != nil {
 		switch {
 		case errors.Is(err, devices.ErrInvalidName):
-			return oapi.CreateDevice400JSONResponse{
-				Code:    "invalid_name",
+			return oapi.CreateDevice400ApplicationProblemPlusJSONResponse{
+				Code:    oapi.InvalidRequest,
 				Message: err.Error(),
 			}, nil
 		case errors.Is(err, devices.ErrInvalidPCIAddress):
-			return oapi.CreateDevice400JSONResponse{
-				Code:    "invalid_pci_address",
+			return oapi.CreateDevice400ApplicationProblemPlusJSONResponse{
+				Code:    oapi.InvalidRequest,
 				Message: err.Error(),
 			}, nil
 		case errors.Is(err, devices.ErrDeviceNotFound):
-			return oapi.CreateDevice404JSONResponse{
-				Code:    "device_not_found",
+			return oapi.CreateDevice404ApplicationProblemPlusJSONResponse{
+				Code:    oapi.NotFound,
 				Message: err.Error(),
 			}, nil
 		case errors.Is(err, devices.ErrAlreadyExists), errors.Is(err, devices.ErrNameExists):
-			return oapi.CreateDevice409JSONResponse{
-				Code:    "conflict",
+			return oapi.CreateDevice409ApplicationProblemPlusJSONResponse{
+				Code:    oapi.Conflict,
 				Message: err.Error(),
 			}, nil
 		default:
-			return oapi.CreateDevice500JSONResponse{
-				Code:    "internal_error",
+			return oapi.CreateDevice500ApplicationProblemPlusJSONResponse{
+				Code:    oapi.InternalError,
 				Message: err.Error(),
 			}, nil
 		}
@@ -94,13 +123,13 @@ func (s *ApiService) GetDevice(ctx context.Context, request oapi.GetDeviceReques
 	device, err := s.DeviceManager.GetDevice(ctx, request.Id)
 	if err != nil {
 		if errors.Is(err, devices.ErrNotFound) {
-			return oapi.GetDevice404JSONResponse{
-				Code:    "not_found",
+			return oapi.GetDevice404ApplicationProblemPlusJSONResponse{
+				Code:    oapi.NotFound,
 				Message: "device not found",
 			}, nil
 		}
-		return oapi.GetDevice500JSONResponse{
-			Code:    "internal_error",
+		return oapi.GetDevice500ApplicationProblemPlusJSONResponse{
+			Code:    oapi.InternalError,
 			Message: err.Error(),
 		}, nil
 	}
@@ -114,18 +143,18 @@ func (s *ApiService) DeleteDevice(ctx context.Context, request oapi.DeleteDevice
 	if err != nil {
 		switch {
 		case errors.Is(err, devices.ErrNotFound):
-			return oapi.DeleteDevice404JSONResponse{
-				Code:    "not_found",
+			return oapi.DeleteDevice404ApplicationProblemPlusJSONResponse{
+				Code:    oapi.NotFound,
 				Message: "device not found",
 			}, nil
 		case errors.Is(err, devices.ErrInUse):
-			return oapi.DeleteDevice409JSONResponse{
-				Code:    "in_use",
+			return oapi.DeleteDevice409ApplicationProblemPlusJSONResponse{
+				Code:    oapi.InUse,
 				Message: "device is attached to an instance",
 			}, nil
 		default:
-			return oapi.DeleteDevice500JSONResponse{
-				Code:    "internal_error",
+			return oapi.DeleteDevice500ApplicationProblemPlusJSONResponse{
+				Code:    oapi.InternalError,
 				Message: err.Error(),
 			}, nil
 		}
@@ -134,11 +163,164 @@ func (s *ApiService) DeleteDevice(ctx context.Context, request oapi.DeleteDevice
 	return oapi.DeleteDevice204Response{}, nil
 }
 
+// UnbindDevice unbinds a device from vfio-pci and rebinds it to its original host driver
+func (s *ApiService) UnbindDevice(ctx context.Context, request oapi.UnbindDeviceRequestObject) (oapi.UnbindDeviceResponseObject, error) {
+	force := request.Params.Force != nil && *request.Params.Force
+	if err := s.DeviceManager.UnbindFromVFIO(ctx, request.Id, force); err != nil {
+		switch {
+		case errors.Is(err, devices.ErrNotFound):
+			return oapi.UnbindDevice404ApplicationProblemPlusJSONResponse{
+				Code:    oapi.NotFound,
+				Message: "device not found",
+			}, nil
+		case errors.Is(err, devices.ErrInUse):
+			return oapi.UnbindDevice409ApplicationProblemPlusJSONResponse{
+				Code:    oapi.InUse,
+				Message: "device is attached to an instance",
+			}, nil
+		case errors.Is(err, devices.ErrIOMMUGroupConflict):
+			return oapi.UnbindDevice409ApplicationProblemPlusJSONResponse{
+				Code:    oapi.InUse,
+				Message: err.Error(),
+			}, nil
+		case errors.Is(err, devices.ErrNotBound):
+			return oapi.UnbindDevice409ApplicationProblemPlusJSONResponse{
+				Code:    oapi.InvalidState,
+				Message: "device is not bound to vfio-pci",
+			}, nil
+		default:
+			return oapi.UnbindDevice500ApplicationProblemPlusJSONResponse{
+				Code:    oapi.InternalError,
+				Message: err.Error(),
+			}, nil
+		}
+	}
+
+	device, err := s.DeviceManager.GetDevice(ctx, request.Id)
+	if err != nil {
+		return oapi.UnbindDevice500ApplicationProblemPlusJSONResponse{
+			Code:    oapi.InternalError,
+			Message: err.Error(),
+		}, nil
+	}
+	return oapi.UnbindDevice200JSONResponse(deviceToOAPI(*device)), nil
+}
+
+// ListGPUHealth returns the health of each GPU
+func (s *ApiService) ListGPUHealth(ctx context.Context, request oapi.ListGPUHealthRequestObject) (oapi.ListGPUHealthResponseObject, error) {
+	health, err := s.DeviceManager.ListGPUHealth(ctx)
+	if err != nil {
+		return oapi.ListGPUHealth500ApplicationProblemPlusJSONResponse{
+			Code:    oapi.InternalError,
+			Message: err.Error(),
+		}, nil
+	}
+
+	result := make([]oapi.GPUHealth, len(health))
+	for i, h := range health {
+		result[i] = gpuHealthToOAPI(h)
+	}
+
+	return oapi.ListGPUHealth200JSONResponse(result), nil
+}
+
+// UncordonGPU marks a cordoned GPU healthy again
+func (s *ApiService) UncordonGPU(ctx context.Context, request oapi.UncordonGPURequestObject) (oapi.UncordonGPUResponseObject, error) {
+	health, err := s.DeviceManager.UncordonGPU(ctx, request.PciAddress)
+	if err != nil {
+		if errors.Is(err, devices.ErrGPUNotFound) {
+			return oapi.UncordonGPU404ApplicationProblemPlusJSONResponse{
+				Code:    oapi.NotFound,
+				Message: "GPU not found",
+			}, nil
+		}
+		return oapi.UncordonGPU500ApplicationProblemPlusJSONResponse{
+			Code:    oapi.InternalError,
+			Message: err.Error(),
+		}, nil
+	}
+
+	return oapi.UncordonGPU200JSONResponse(gpuHealthToOAPI(*health)), nil
+}
+
+// ListGPUReservations returns all vGPU reservations
+func (s *ApiService) ListGPUReservations(ctx context.Context, request oapi.ListGPUReservationsRequestObject) (oapi.ListGPUReservationsResponseObject, error) {
+	reservations, err := s.DeviceManager.ListGPUReservations(ctx)
+	if err != nil {
+		return oapi.ListGPUReservations500ApplicationProblemPlusJSONResponse{
+			Code:    oapi.InternalError,
+			Message: err.Error(),
+		}, nil
+	}
+
+	result := make([]oapi.GPUReservation, len(reservations))
+	for i, r := range reservations {
+		result[i] = gpuReservationToOAPI(r)
+	}
+
+	return oapi.ListGPUReservations200JSONResponse(result), nil
+}
+
+// CreateGPUReservation reserves vGPUs of a profile for a tenant
+func (s *ApiService) CreateGPUReservation(ctx context.Context, request oapi.CreateGPUReservationRequestObject) (oapi.CreateGPUReservationResponseObject, error) {
+	req := devices.CreateGPUReservationRequest{
+		Profile: request.Body.Profile,
+		Tenant:  request.Body.Tenant,
+	}
+	if request.Body.Count != nil {
+		req.Count = *request.Body.Count
+	}
+	if request.Body.Limit != nil {
+		req.Limit = *request.Body.Limit
+	}
+
+	reservation, err := s.DeviceManager.CreateGPUReservation(ctx, req)
+	if err != nil {
+		switch {
+		case errors.Is(err, devices.ErrInvalidReservation):
+			return oapi.CreateGPUReservation400ApplicationProblemPlusJSONResponse{
+				Code:    oapi.InvalidRequest,
+				Message: err.Error(),
+			}, nil
+		case errors.Is(err, devices.ErrReservationExists):
+			return oapi.CreateGPUReservation409ApplicationProblemPlusJSONResponse{
+				Code:    oapi.Conflict,
+				Message: err.Error(),
+			}, nil
+		default:
+			return oapi.CreateGPUReservation500ApplicationProblemPlusJSONResponse{
+				Code:    oapi.InternalError,
+				Message: err.Error(),
+			}, nil
+		}
+	}
+
+	return oapi.CreateGPUReservation201JSONResponse(gpuReservationToOAPI(*reservation)), nil
+}
+
+// DeleteGPUReservation removes a vGPU reservation
+func (s *ApiService) DeleteGPUReservation(ctx context.Context, request oapi.DeleteGPUReservationRequestObject) (oapi.DeleteGPUReservationResponseObject, error) {
+	if err := s.DeviceManager.DeleteGPUReservation(ctx, request.Id); err != nil {
+		if errors.Is(err, devices.ErrReservationNotFound) {
+			return oapi.DeleteGPUReservation404ApplicationProblemPlusJSONResponse{
+				Code:    oapi.NotFound,
+				Message: "reservation not found",
+			}, nil
+		}
+		return oapi.DeleteGPUReservation500ApplicationProblemPlusJSONResponse{
+			Code:    oapi.InternalError,
+			Message: err.Error(),
+		}, nil
+	}
+
+	return oapi.DeleteGPUReservation204Response{}, nil
+}
+
 // Helper functions
 
 func deviceToOAPI(d devices.Device) oapi.Device {
 	deviceType := oapi.DeviceType(d.Type)
-	return oapi.Device{
+	result := oapi.Device{
 		Id:          d.Id,
 		Name:        &d.Name,
 		Type:        deviceType,
@@ -150,6 +332,10 @@ func deviceToOAPI(d devices.Device) oapi.Device {
 		AttachedTo:  d.AttachedTo,
 		CreatedAt:   d.CreatedAt,
 	}
+	if d.OriginalDriver != "" {
+		result.OriginalDriver = &d.OriginalDriver
+	}
+	return result
 }
 
 func availableDeviceToOAPI(d devices.AvailableDevice) oapi.AvailableDevice {
@@ -164,4 +350,34 @@ func availableDeviceToOAPI(d devices.AvailableDevice) oapi.AvailableDevice {
 	}
 }
 
+func gpuReservationToOAPI(r devices.GPUReservation) oapi.GPUReservation {
+	res := oapi.GPUReservation{
+		Id:        r.Id,
+		Profile:   r.Profile,
+		Tenant:    r.Tenant,
+		Count:     r.Count,
+		CreatedAt: r.CreatedAt,
+	}
+	if r.Limit > 0 {
+		res.Limit = &r.Limit
+	}
+	return res
+}
 
+func gpuHealthToOAPI(h devices.GPUHealth) oapi.GPUHealth {
+	res := oapi.GPUHealth{
+		PciAddress:     h.PCIAddress,
+		Healthy:        h.Healthy,
+		UnhealthySince: h.UnhealthySince,
+		XidErrors:      h.XIDErrors,
+		EccUncorrected: h.ECCUncorrected,
+		CheckedAt:      h.CheckedAt,
+	}
+	if h.Reason != "" {
+		res.Reason = &h.Reason
+	}
+	if h.LastXID != 0 {
+		res.LastXid = &h.LastXID
+	}
+	return res
+}
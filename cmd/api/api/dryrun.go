@@ -0,0 +1,58 @@
+package api
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/c2h5oh/datasize"
+	"github.com/kernel/hypeman/lib/instances"
+	"github.com/kernel/hypeman/lib/oapi"
+)
+
+// dryRunResult builds the response of a create or delete endpoint called
+// with dry_run
+func dryRunResult(kind oapi.ApplyResourceKind, action oapi.ApplyAction, name, id string, details []string) oapi.DryRunResult {
+	r := oapi.DryRunResult{DryRun: true, Kind: kind, Action: action, Name: name}
+	if id != "" {
+		r.Id = &id
+	}
+	if len(details) > 0 {
+		r.Details = &details
+	}
+	return r
+}
+
+// instanceDryRunDetails describes an instance that would be created, with
+// its defaults resolved
+func instanceDryRunDetails(inst instances.Instance) []string {
+	details := []string{
+		fmt.Sprintf("image: %s", inst.Image),
+		fmt.Sprintf("hypervisor: %s", inst.HypervisorType),
+		fmt.Sprintf("vcpus: %d", inst.Vcpus),
+		fmt.Sprintf("memory: %s + %s hotplug", datasize.ByteSize(inst.Size).HR(), datasize.ByteSize(inst.HotplugSize).HR()),
+		fmt.Sprintf("overlay: %s", datasize.ByteSize(inst.OverlaySize).HR()),
+	}
+	if inst.NetworkEnabled {
+		details = append(details, "network: an IP address would be allocated")
+	}
+	for _, vol := range inst.Volumes {
+		details = append(details, fmt.Sprintf("volume %s mounted at %s", vol.VolumeID, vol.MountPath))
+	}
+	for _, dev := range inst.Devices {
+		details = append(details, fmt.Sprintf("device %s attached", dev))
+	}
+	if inst.NestedVirt {
+		details = append(details, "nested virtualization: enabled")
+	}
+	if len(inst.KernelArgs) > 0 {
+		details = append(details, fmt.Sprintf("kernel args: %s", strings.Join(inst.KernelArgs, " ")))
+	}
+	for _, att := range inst.Secrets {
+		if att.EnvVar != "" {
+			details = append(details, fmt.Sprintf("secret %s exposed as $%s", att.SecretID, att.EnvVar))
+		} else {
+			details = append(details, fmt.Sprintf("secret %s exposed as a file", att.SecretID))
+		}
+	}
+	return details
+}
@@ -16,6 +16,9 @@ import (
 	"github.com/kernel/hypeman/lib/instances"
 	"github.com/kernel/hypeman/lib/logger"
 	mw "github.com/kernel/hypeman/lib/middleware"
+	"github.com/kernel/hypeman/lib/oapi"
+	"github.com/kernel/hypeman/lib/problem"
+	"github.com/kernel/hypeman/lib/recordings"
 )
 
 var upgrader = websocket.Upgrader{
@@ -35,6 +38,44 @@ type ExecRequest struct {
 	Cwd          string            `json:"cwd,omitempty"`
 	Timeout      int32             `json:"timeout,omitempty"`       // seconds
 	WaitForAgent int32             `json:"wait_for_agent,omitempty"` // seconds to wait for guest agent to be ready
+	Rows         uint16            `json:"rows,omitempty"`           // initial terminal size (TTY only)
+	Cols         uint16            `json:"cols,omitempty"`
+}
+
+// execControlMessage is a text message steering a running session rather
+// than feeding its stdin: {"resize":{"rows":40,"cols":120}} when the
+// client's terminal changes size, or {"signal":"SIGINT"} to signal the
+// command, e.g. on Ctrl-C
+type execControlMessage struct {
+	Resize *execWindowSize `json:"resize,omitempty"`
+	Signal string          `json:"signal,omitempty"`
+}
+
+type execWindowSize struct {
+	Rows uint16 `json:"rows"`
+	Cols uint16 `json:"cols"`
+}
+
+// parseExecControl reports whether a text message is a control message.
+// Anything else stays stdin, as it was before control messages existed.
+func parseExecControl(data []byte) (*execControlMessage, bool) {
+	data = bytes.TrimSpace(data)
+	if len(data) == 0 || data[0] != '{' {
+		return nil, false
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	var msg execControlMessage
+	if err := dec.Decode(&msg); err != nil || dec.More() {
+		return nil, false
+	}
+	if msg.Resize != nil && (msg.Resize.Rows == 0 || msg.Resize.Cols == 0) {
+		return nil, false
+	}
+	if msg.Resize == nil && msg.Signal == "" {
+		return nil, false
+	}
+	return &msg, true
 }
 
 // ExecHandler handles exec requests via WebSocket for bidirectional streaming
@@ -47,17 +88,20 @@ func (s *ApiService) ExecHandler(w http.ResponseWriter, r *http.Request) {
 	// Get instance resolved by middleware
 	inst := mw.GetResolvedInstance[instances.Instance](ctx)
 	if inst == nil {
-		http.Error(w, `{"code":"internal_error","message":"resource not resolved"}`, http.StatusInternalServerError)
+		problem.Write(w, http.StatusInternalServerError, oapi.InternalError, "resource not resolved")
 		return
 	}
 
 	if inst.State != instances.StateRunning {
-		http.Error(w, fmt.Sprintf(`{"code":"invalid_state","message":"instance must be running (current state: %s)"}`, inst.State), http.StatusConflict)
+		problem.Write(w, http.StatusConflict, oapi.InvalidState, fmt.Sprintf("instance must be running (current state: %s)", inst.State))
 		return
 	}
 
+	// Keep the instance from idling into standby during the session
+	defer s.InstanceManager.TrackActivity(inst.Id)()
+
 	// Upgrade to WebSocket first
-	ws, err := upgrader.Upgrade(w, r, nil)
+	ws, err := upgrade(w, r)
 	if err != nil {
 		log.ErrorContext(ctx, "websocket upgrade failed", "error", err)
 		return
@@ -91,12 +135,10 @@ func (s *ApiService) ExecHandler(w http.ResponseWriter, r *http.Request) {
 		execReq.Command = []string{"/bin/sh"}
 	}
 
-	// Get JWT subject for audit logging (if available)
+	// Get authenticated subject for audit logging (if available)
 	subject := "unknown"
-	if claims, ok := r.Context().Value("claims").(map[string]interface{}); ok {
-		if sub, ok := claims["sub"].(string); ok {
-			subject = sub
-		}
+	if claims := mw.GetClaimsFromContext(ctx); claims != nil && claims.Subject != "" {
+		subject = claims.Subject
 	}
 
 	// Audit log: exec session started
@@ -110,8 +152,52 @@ func (s *ApiService) ExecHandler(w http.ResponseWriter, r *http.Request) {
 		"wait_for_agent", execReq.WaitForAgent,
 	)
 
-	// Create WebSocket read/writer wrapper
-	wsConn := &wsReadWriter{ws: ws, ctx: ctx}
+	// Create WebSocket read/writer wrapper, passing control messages on to
+	// the session
+	ctl := guest.NewExecControl()
+	var rec *recordings.Session
+	wsConn := &wsReadWriter{ws: ws, ctx: ctx, control: func(msg *execControlMessage) {
+		if msg.Resize != nil {
+			if rec != nil {
+				rec.Resize(int(msg.Resize.Cols), int(msg.Resize.Rows))
+			}
+			if err := ctl.Resize(msg.Resize.Rows, msg.Resize.Cols); err != nil {
+				log.DebugContext(ctx, "failed to resize exec session", "error", err, "instance_id", inst.Id)
+			}
+		}
+		if msg.Signal != "" {
+			log.InfoContext(ctx, "exec session signaled", "instance_id", inst.Id, "subject", subject, "signal", msg.Signal)
+			if err := ctl.Signal(msg.Signal); err != nil {
+				log.DebugContext(ctx, "failed to signal exec session", "error", err, "instance_id", inst.Id)
+			}
+		}
+	}}
+	var stdin io.Reader = wsConn
+	var stdout io.Writer = wsConn
+
+	// Record the session when required; sessions that can't be recorded
+	// aren't allowed
+	if s.Config.ExecRecording && s.Recordings != nil {
+		var err error
+		rec, err = s.Recordings.Start(inst.Id, inst.Tenant, subject, execReq.Command, execReq.TTY)
+		if err != nil {
+			log.ErrorContext(ctx, "failed to start exec recording", "error", err, "instance_id", inst.Id)
+			ws.WriteMessage(websocket.BinaryMessage, []byte("Error: failed to start session recording\r\n"))
+			ws.WriteMessage(websocket.TextMessage, []byte(`{"exitCode":127}`))
+			return
+		}
+		defer func() {
+			if err := rec.Close(); err != nil {
+				log.ErrorContext(ctx, "exec recording incomplete", "error", err, "instance_id", inst.Id, "recording_id", rec.ID)
+			}
+		}()
+		log.InfoContext(ctx, "recording exec session", "instance_id", inst.Id, "recording_id", rec.ID)
+		if execReq.TTY && execReq.Rows > 0 && execReq.Cols > 0 {
+			rec.Resize(int(execReq.Cols), int(execReq.Rows))
+		}
+		stdin = io.TeeReader(wsConn, rec.Input())
+		stdout = io.MultiWriter(wsConn, rec.Output())
+	}
 
 	// Create vsock dialer for this hypervisor type
 	dialer, err := hypervisor.NewVsockDialer(hypervisor.Type(inst.HypervisorType), inst.VsockSocket, inst.VsockCID)
@@ -125,14 +211,17 @@ func (s *ApiService) ExecHandler(w http.ResponseWriter, r *http.Request) {
 	// Execute via vsock
 	exit, err := guest.ExecIntoInstance(ctx, dialer, guest.ExecOptions{
 		Command:      execReq.Command,
-		Stdin:        wsConn,
-		Stdout:       wsConn,
-		Stderr:       wsConn,
+		Stdin:        stdin,
+		Stdout:       stdout,
+		Stderr:       stdout,
 		TTY:          execReq.TTY,
 		Env:          execReq.Env,
 		Cwd:          execReq.Cwd,
 		Timeout:      execReq.Timeout,
 		WaitForAgent: time.Duration(execReq.WaitForAgent) * time.Second,
+		Rows:         execReq.Rows,
+		Cols:         execReq.Cols,
+		Control:      ctl,
 	})
 
 	duration := time.Since(startTime)
@@ -168,10 +257,11 @@ func (s *ApiService) ExecHandler(w http.ResponseWriter, r *http.Request) {
 
 // wsReadWriter wraps a WebSocket connection to implement io.ReadWriter
 type wsReadWriter struct {
-	ws     *websocket.Conn
-	ctx    context.Context
-	reader io.Reader
-	mu     sync.Mutex
+	ws      wsConn
+	ctx     context.Context
+	reader  io.Reader
+	mu      sync.Mutex
+	control func(*execControlMessage) // Receives control messages instead of Read, if set
 }
 
 func (w *wsReadWriter) Read(p []byte) (n int, err error) {
@@ -188,15 +278,27 @@ func (w *wsReadWriter) Read(p []byte) (n int, err error) {
 		w.reader = nil
 	}
 
-	// Read next WebSocket message
-	messageType, data, err := w.ws.ReadMessage()
-	if err != nil {
-		return 0, err
-	}
+	// Read next WebSocket message, handing control messages off
+	var data []byte
+	for {
+		messageType, msg, err := w.ws.ReadMessage()
+		if err != nil {
+			return 0, err
+		}
 
-	// Only handle binary and text messages
-	if messageType != websocket.BinaryMessage && messageType != websocket.TextMessage {
-		return 0, fmt.Errorf("unexpected message type: %d", messageType)
+		// Only handle binary and text messages
+		if messageType != websocket.BinaryMessage && messageType != websocket.TextMessage {
+			return 0, fmt.Errorf("unexpected message type: %d", messageType)
+		}
+
+		if messageType == websocket.TextMessage && w.control != nil {
+			if ctl, ok := parseExecControl(msg); ok {
+				w.control(ctl)
+				continue
+			}
+		}
+		data = msg
+		break
 	}
 
 	// Create reader for this message
@@ -2,17 +2,20 @@ package api
 
 import (
 	"bytes"
+	"io"
 	"os"
 	"strings"
 	"testing"
 	"time"
 
+	"github.com/gorilla/websocket"
 	"github.com/kernel/hypeman/lib/guest"
 	"github.com/kernel/hypeman/lib/hypervisor"
 	"github.com/kernel/hypeman/lib/instances"
 	"github.com/kernel/hypeman/lib/oapi"
 	"github.com/kernel/hypeman/lib/paths"
 	"github.com/kernel/hypeman/lib/system"
+	"github.com/samber/lo"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -45,11 +48,13 @@ func TestExecInstanceNonTTY(t *testing.T) {
 	instResp, err := svc.CreateInstance(ctx(), oapi.CreateInstanceRequestObject{
 		Body: &oapi.CreateInstanceRequest{
 			Name:  "exec-test",
-			Image: "docker.io/library/nginx:alpine",
+			Image: lo.ToPtr("docker.io/library/nginx:alpine"),
 			Network: &struct {
-				BandwidthDownload *string `json:"bandwidth_download,omitempty"`
-				BandwidthUpload   *string `json:"bandwidth_upload,omitempty"`
-				Enabled           *bool   `json:"enabled,omitempty"`
+				BandwidthDownload      *string `json:"bandwidth_download,omitempty"`
+				BandwidthDownloadBurst *string `json:"bandwidth_download_burst,omitempty"`
+				BandwidthUpload        *string `json:"bandwidth_upload,omitempty"`
+				BandwidthUploadBurst   *string `json:"bandwidth_upload_burst,omitempty"`
+				Enabled                *bool   `json:"enabled,omitempty"`
 			}{
 				Enabled: &networkEnabled,
 			},
@@ -185,11 +190,13 @@ func TestExecWithDebianMinimal(t *testing.T) {
 	instResp, err := svc.CreateInstance(ctx(), oapi.CreateInstanceRequestObject{
 		Body: &oapi.CreateInstanceRequest{
 			Name:  "debian-exec-test",
-			Image: "docker.io/library/debian:12-slim",
+			Image: lo.ToPtr("docker.io/library/debian:12-slim"),
 			Network: &struct {
-				BandwidthDownload *string `json:"bandwidth_download,omitempty"`
-				BandwidthUpload   *string `json:"bandwidth_upload,omitempty"`
-				Enabled           *bool   `json:"enabled,omitempty"`
+				BandwidthDownload      *string `json:"bandwidth_download,omitempty"`
+				BandwidthDownloadBurst *string `json:"bandwidth_download_burst,omitempty"`
+				BandwidthUpload        *string `json:"bandwidth_upload,omitempty"`
+				BandwidthUploadBurst   *string `json:"bandwidth_upload_burst,omitempty"`
+				Enabled                *bool   `json:"enabled,omitempty"`
 			}{
 				Enabled: &networkEnabled,
 			},
@@ -302,3 +309,55 @@ func (b *outputBuffer) Write(p []byte) (n int, err error) {
 func (b *outputBuffer) String() string {
 	return b.buf.String()
 }
+
+// fakeWSConn replays client messages
+type fakeWSConn struct {
+	msgs []fakeWSMessage
+}
+
+type fakeWSMessage struct {
+	typ  int
+	data string
+}
+
+func (c *fakeWSConn) ReadMessage() (int, []byte, error) {
+	if len(c.msgs) == 0 {
+		return 0, nil, io.EOF
+	}
+	msg := c.msgs[0]
+	c.msgs = c.msgs[1:]
+	return msg.typ, []byte(msg.data), nil
+}
+
+func (c *fakeWSConn) WriteMessage(int, []byte) error { return nil }
+func (c *fakeWSConn) Close() error                   { return nil }
+
+func TestWSReadWriterControl(t *testing.T) {
+	var got []*execControlMessage
+	w := &wsReadWriter{
+		ws: &fakeWSConn{msgs: []fakeWSMessage{
+			{websocket.BinaryMessage, "ls\r"},
+			{websocket.TextMessage, `{"resize":{"rows":40,"cols":120}}`},
+			{websocket.TextMessage, `{"signal":"SIGINT"}`},
+			// Not control messages: stdin, as before
+			{websocket.TextMessage, `{"other":1}`},
+			{websocket.BinaryMessage, `{"signal":"SIGINT"}`},
+		}},
+		ctx:     ctx(),
+		control: func(msg *execControlMessage) { got = append(got, msg) },
+	}
+
+	stdin, err := io.ReadAll(w)
+	require.NoError(t, err)
+	assert.Equal(t, `ls`+"\r"+`{"other":1}{"signal":"SIGINT"}`, string(stdin))
+	require.Len(t, got, 2)
+	assert.Equal(t, &execWindowSize{Rows: 40, Cols: 120}, got[0].Resize)
+	assert.Equal(t, "SIGINT", got[1].Signal)
+}
+
+func TestParseExecControl(t *testing.T) {
+	for _, data := range []string{"", "ls", "{}", `{"resize":{"rows":0,"cols":80}}`, `{"signal":"SIGINT"} {}`, `{"signal":"SIGINT","x":1}`} {
+		_, ok := parseExecControl([]byte(data))
+		assert.False(t, ok, data)
+	}
+}
@@ -0,0 +1,458 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/c2h5oh/datasize"
+	"github.com/kernel/hypeman/lib/groups"
+	"github.com/kernel/hypeman/lib/hypervisor"
+	"github.com/kernel/hypeman/lib/logger"
+	mw "github.com/kernel/hypeman/lib/middleware"
+	"github.com/kernel/hypeman/lib/oapi"
+	"github.com/kernel/hypeman/lib/resources"
+	"github.com/samber/lo"
+)
+
+// ListInstanceGroups lists instance groups
+func (s *ApiService) ListInstanceGroups(ctx context.Context, request oapi.ListInstanceGroupsRequestObject) (oapi.ListInstanceGroupsResponseObject, error) {
+	log := logger.FromContext(ctx)
+
+	domainGroups, err := s.GroupManager.ListGroups(ctx)
+	if err != nil {
+		log.ErrorContext(ctx, "failed to list instance groups", "error", err)
+		return oapi.ListInstanceGroups500ApplicationProblemPlusJSONResponse{
+			Code:    oapi.InternalError,
+			Message: "failed to list instance groups",
+		}, nil
+	}
+
+	oapiGroups := make([]oapi.InstanceGroup, 0, len(domainGroups))
+	for _, group := range domainGroups {
+		if !mw.TenantVisible(ctx, group.Tenant) {
+			continue
+		}
+		oapiGroups = append(oapiGroups, groupToOAPI(group))
+	}
+
+	return oapi.ListInstanceGroups200JSONResponse(oapiGroups), nil
+}
+
+// CreateInstanceGroup creates an instance group; its members are created in the background
+func (s *ApiService) CreateInstanceGroup(ctx context.Context, request oapi.CreateInstanceGroupRequestObject) (oapi.CreateInstanceGroupResponseObject, error) {
+	tenant, err := mw.TenantForCreate(ctx, request.Body.Tenant)
+	if err != nil {
+		return oapi.CreateInstanceGroup403ApplicationProblemPlusJSONResponse{
+			Code:    oapi.Forbidden,
+			Message: err.Error(),
+		}, nil
+	}
+
+	template, err := s.groupTemplate(request.Body.Template)
+	if err != nil {
+		return oapi.CreateInstanceGroup400ApplicationProblemPlusJSONResponse{
+			Code:    oapi.InvalidRequest,
+			Message: err.Error(),
+		}, nil
+	}
+	if !s.imageVisible(ctx, template.Image) {
+		return oapi.CreateInstanceGroup403ApplicationProblemPlusJSONResponse{
+			Code:    oapi.Forbidden,
+			Message: fmt.Sprintf("image %s belongs to another tenant", template.Image),
+		}, nil
+	}
+
+	var autoscale *groups.Autoscale
+	if request.Body.Autoscale != nil {
+		autoscale, err = groupAutoscale(*request.Body.Autoscale)
+		if err != nil {
+			return oapi.CreateInstanceGroup400ApplicationProblemPlusJSONResponse{
+				Code:    oapi.InvalidRequest,
+				Message: err.Error(),
+			}, nil
+		}
+	}
+
+	group, err := s.GroupManager.CreateGroup(ctx, groups.CreateGroupRequest{
+		Name:      request.Body.Name,
+		Template:  template,
+		Replicas:  request.Body.Replicas,
+		Autoscale: autoscale,
+		Tenant:    tenant,
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, groups.ErrAlreadyExists):
+			return oapi.CreateInstanceGroup409ApplicationProblemPlusJSONResponse{
+				Code:    oapi.AlreadyExists,
+				Message: "instance group with this name already exists",
+			}, nil
+		case errors.Is(err, groups.ErrInvalidRequest):
+			return oapi.CreateInstanceGroup400ApplicationProblemPlusJSONResponse{
+				Code:    oapi.InvalidRequest,
+				Message: err.Error(),
+			}, nil
+		default:
+			logger.FromContext(ctx).ErrorContext(ctx, "failed to create instance group", "error", err, "name", request.Body.Name)
+			return oapi.CreateInstanceGroup500ApplicationProblemPlusJSONResponse{
+				Code:    oapi.InternalError,
+				Message: "failed to create instance group",
+			}, nil
+		}
+	}
+	return oapi.CreateInstanceGroup201JSONResponse(groupToOAPI(*group)), nil
+}
+
+// GetInstanceGroup gets an instance group with the live state of its members
+// Note: Resolution is handled by ResolveResource middleware
+func (s *ApiService) GetInstanceGroup(ctx context.Context, request oapi.GetInstanceGroupRequestObject) (oapi.GetInstanceGroupResponseObject, error) {
+	group := mw.GetResolvedGroup[groups.Group](ctx)
+	if group == nil {
+		return oapi.GetInstanceGroup500ApplicationProblemPlusJSONResponse{
+			Code:    oapi.InternalError,
+			Message: "resource not resolved",
+		}, nil
+	}
+	return oapi.GetInstanceGroup200JSONResponse(groupToOAPI(*group)), nil
+}
+
+// UpdateInstanceGroup scales an instance group or changes its template or autoscaling
+// Note: Resolution is handled by ResolveResource middleware
+func (s *ApiService) UpdateInstanceGroup(ctx context.Context, request oapi.UpdateInstanceGroupRequestObject) (oapi.UpdateInstanceGroupResponseObject, error) {
+	group := mw.GetResolvedGroup[groups.Group](ctx)
+	if group == nil {
+		return oapi.UpdateInstanceGroup500ApplicationProblemPlusJSONResponse{
+			Code:    oapi.InternalError,
+			Message: "resource not resolved",
+		}, nil
+	}
+
+	req := groups.UpdateGroupRequest{Replicas: request.Body.Replicas}
+	if request.Body.Template != nil {
+		template, err := s.groupTemplate(*request.Body.Template)
+		if err != nil {
+			return oapi.UpdateInstanceGroup400ApplicationProblemPlusJSONResponse{
+				Code:    oapi.InvalidRequest,
+				Message: err.Error(),
+			}, nil
+		}
+		if !s.imageVisible(ctx, template.Image) {
+			return oapi.UpdateInstanceGroup403ApplicationProblemPlusJSONResponse{
+				Code:    oapi.Forbidden,
+				Message: fmt.Sprintf("image %s belongs to another tenant", template.Image),
+			}, nil
+		}
+		req.Template = &template
+	}
+	if request.Body.Autoscale != nil {
+		autoscale, err := groupAutoscale(*request.Body.Autoscale)
+		if err != nil {
+			return oapi.UpdateInstanceGroup400ApplicationProblemPlusJSONResponse{
+				Code:    oapi.InvalidRequest,
+				Message: err.Error(),
+			}, nil
+		}
+		req.Autoscale = autoscale
+	}
+
+	updated, err := s.GroupManager.UpdateGroup(ctx, group.Id, req)
+	if err != nil {
+		switch {
+		case errors.Is(err, groups.ErrNotFound):
+			return oapi.UpdateInstanceGroup404ApplicationProblemPlusJSONResponse{
+				Code:    oapi.NotFound,
+				Message: "instance group not found",
+			}, nil
+		case errors.Is(err, groups.ErrInvalidRequest):
+			return oapi.UpdateInstanceGroup400ApplicationProblemPlusJSONResponse{
+				Code:    oapi.InvalidRequest,
+				Message: err.Error(),
+			}, nil
+		default:
+			logger.FromContext(ctx).ErrorContext(ctx, "failed to update instance group", "error", err)
+			return oapi.UpdateInstanceGroup500ApplicationProblemPlusJSONResponse{
+				Code:    oapi.InternalError,
+				Message: "failed to update instance group",
+			}, nil
+		}
+	}
+	return oapi.UpdateInstanceGroup200JSONResponse(groupToOAPI(*updated)), nil
+}
+
+// DeleteInstanceGroup deletes an instance group and its member instances
+// Note: Resolution is handled by ResolveResource middleware
+func (s *ApiService) DeleteInstanceGroup(ctx context.Context, request oapi.DeleteInstanceGroupRequestObject) (oapi.DeleteInstanceGroupResponseObject, error) {
+	group := mw.GetResolvedGroup[groups.Group](ctx)
+	if group == nil {
+		return oapi.DeleteInstanceGroup500ApplicationProblemPlusJSONResponse{
+			Code:    oapi.InternalError,
+			Message: "resource not resolved",
+		}, nil
+	}
+
+	if err := s.GroupManager.DeleteGroup(ctx, group.Id); err != nil {
+		if errors.Is(err, groups.ErrNotFound) {
+			return oapi.DeleteInstanceGroup404ApplicationProblemPlusJSONResponse{
+				Code:    oapi.NotFound,
+				Message: "instance group not found",
+			}, nil
+		}
+		logger.FromContext(ctx).ErrorContext(ctx, "failed to delete instance group", "error", err)
+		return oapi.DeleteInstanceGroup500ApplicationProblemPlusJSONResponse{
+			Code:    oapi.InternalError,
+			Message: "failed to delete instance group",
+		}, nil
+	}
+	return oapi.DeleteInstanceGroup204Response{}, nil
+}
+
+// ListInstanceGroupEvents lists an instance group's autoscaling decisions
+// Note: Resolution is handled by ResolveResource middleware
+func (s *ApiService) ListInstanceGroupEvents(ctx context.Context, request oapi.ListInstanceGroupEventsRequestObject) (oapi.ListInstanceGroupEventsResponseObject, error) {
+	group := mw.GetResolvedGroup[groups.Group](ctx)
+	if group == nil {
+		return oapi.ListInstanceGroupEvents500ApplicationProblemPlusJSONResponse{
+			Code:    oapi.InternalError,
+			Message: "resource not resolved",
+		}, nil
+	}
+
+	events := make([]oapi.InstanceGroupEvent, 0, len(group.Events))
+	for _, e := range group.Events {
+		events = append(events, oapi.InstanceGroupEvent{
+			Time:   e.Time,
+			From:   e.From,
+			To:     e.To,
+			Metric: e.Metric,
+			Value:  e.Value,
+			Reason: e.Reason,
+		})
+	}
+	return oapi.ListInstanceGroupEvents200JSONResponse(events), nil
+}
+
+// imageVisible reports whether the caller may use an image. Images that
+// can't be found are left for the group manager to reject.
+func (s *ApiService) imageVisible(ctx context.Context, name string) bool {
+	img, err := s.ImageManager.GetImage(ctx, name)
+	return err != nil || img.Tenant == "" || mw.TenantVisible(ctx, img.Tenant)
+}
+
+// groupTemplate parses a template like CreateInstance parses its request,
+// filling in the same CPU-proportional bandwidth defaults
+func (s *ApiService) groupTemplate(t oapi.InstanceGroupTemplate) (groups.Template, error) {
+	template := groups.Template{
+		Image:          t.Image,
+		Vcpus:          lo.FromPtrOr(t.Vcpus, 2),
+		Env:            lo.FromPtr(t.Env),
+		NetworkEnabled: true,
+	}
+	if t.Entrypoint != nil {
+		template.Entrypoint = *t.Entrypoint
+	}
+	if t.Cmd != nil {
+		template.Cmd = *t.Cmd
+	}
+	if t.Hypervisor != nil {
+		template.Hypervisor = hypervisor.Type(*t.Hypervisor)
+	}
+
+	sizes := []struct {
+		name  string
+		value *string
+		dst   *int64
+	}{
+		{"size", t.Size, &template.Size},
+		{"hotplug_size", t.HotplugSize, &template.HotplugSize},
+		{"overlay_size", t.OverlaySize, &template.OverlaySize},
+	}
+	for _, size := range sizes {
+		if size.value == nil || *size.value == "" {
+			continue
+		}
+		var bytes datasize.ByteSize
+		if err := bytes.UnmarshalText([]byte(*size.value)); err != nil {
+			return groups.Template{}, fmt.Errorf("invalid %s format: %v", size.name, err)
+		}
+		*size.dst = int64(bytes)
+	}
+
+	if t.DiskIoBps != nil && *t.DiskIoBps != "" {
+		ioStr := strings.TrimSuffix(strings.TrimSuffix(*t.DiskIoBps, "/s"), "ps")
+		var bytes datasize.ByteSize
+		if err := bytes.UnmarshalText([]byte(ioStr)); err != nil {
+			return groups.Template{}, fmt.Errorf("invalid disk_io_bps format: %v", err)
+		}
+		template.DiskIOBps = int64(bytes)
+	}
+
+	if n := t.Network; n != nil {
+		if n.Enabled != nil {
+			template.NetworkEnabled = *n.Enabled
+		}
+		if n.BandwidthDownload != nil && *n.BandwidthDownload != "" {
+			bw, err := resources.ParseBandwidth(*n.BandwidthDownload)
+			if err != nil {
+				return groups.Template{}, fmt.Errorf("invalid bandwidth_download: %v", err)
+			}
+			template.NetworkBandwidthDownload = bw
+		}
+		if n.BandwidthUpload != nil && *n.BandwidthUpload != "" {
+			bw, err := resources.ParseBandwidth(*n.BandwidthUpload)
+			if err != nil {
+				return groups.Template{}, fmt.Errorf("invalid bandwidth_upload: %v", err)
+			}
+			template.NetworkBandwidthUpload = bw
+		}
+	}
+
+	if template.DiskIOBps == 0 {
+		template.DiskIOBps, _ = s.ResourceManager.DefaultDiskIOBandwidth(template.Vcpus)
+	}
+	if template.NetworkBandwidthDownload == 0 || template.NetworkBandwidthUpload == 0 {
+		defaultDown, defaultUp := s.ResourceManager.DefaultNetworkBandwidth(template.Vcpus)
+		if template.NetworkBandwidthDownload == 0 {
+			template.NetworkBandwidthDownload = defaultDown
+		}
+		if template.NetworkBandwidthUpload == 0 {
+			template.NetworkBandwidthUpload = defaultUp
+		}
+	}
+	return template, nil
+}
+
+// groupAutoscale parses autoscaling settings, defaulting the cooldowns
+func groupAutoscale(a oapi.InstanceGroupAutoscale) (*groups.Autoscale, error) {
+	autoscale := &groups.Autoscale{
+		Enabled:           a.Enabled,
+		MinReplicas:       a.MinReplicas,
+		MaxReplicas:       a.MaxReplicas,
+		Metric:            string(a.Metric),
+		Target:            a.Target,
+		ScaleUpCooldown:   groups.DefaultScaleUpCooldown,
+		ScaleDownCooldown: groups.DefaultScaleDownCooldown,
+	}
+	cooldowns := []struct {
+		name  string
+		value *string
+		dst   *time.Duration
+	}{
+		{"scale_up_cooldown", a.ScaleUpCooldown, &autoscale.ScaleUpCooldown},
+		{"scale_down_cooldown", a.ScaleDownCooldown, &autoscale.ScaleDownCooldown},
+	}
+	for _, c := range cooldowns {
+		if c.value == nil || *c.value == "" {
+			continue
+		}
+		d, err := time.ParseDuration(*c.value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s: %v", c.name, err)
+		}
+		*c.dst = d
+	}
+	return autoscale, nil
+}
+
+func groupTemplateToOAPI(t groups.Template) oapi.InstanceGroupTemplate {
+	hrSize := func(v int64) *string {
+		if v <= 0 {
+			return nil
+		}
+		return lo.ToPtr(datasize.ByteSize(v).HR())
+	}
+	hrRate := func(v int64) *string {
+		if v <= 0 {
+			return nil
+		}
+		return lo.ToPtr(datasize.ByteSize(v).HR() + "/s")
+	}
+
+	out := oapi.InstanceGroupTemplate{
+		Image:       t.Image,
+		Size:        hrSize(t.Size),
+		HotplugSize: hrSize(t.HotplugSize),
+		OverlaySize: hrSize(t.OverlaySize),
+		DiskIoBps:   hrRate(t.DiskIOBps),
+		Vcpus:       lo.ToPtr(t.Vcpus),
+	}
+	out.Network = &struct {
+		BandwidthDownload *string `json:"bandwidth_download,omitempty"`
+		BandwidthUpload   *string `json:"bandwidth_upload,omitempty"`
+		Enabled           *bool   `json:"enabled,omitempty"`
+	}{
+		BandwidthDownload: hrRate(t.NetworkBandwidthDownload),
+		BandwidthUpload:   hrRate(t.NetworkBandwidthUpload),
+		Enabled:           lo.ToPtr(t.NetworkEnabled),
+	}
+	if len(t.Env) > 0 {
+		out.Env = lo.ToPtr(t.Env)
+	}
+	if t.Entrypoint != nil {
+		out.Entrypoint = lo.ToPtr(t.Entrypoint)
+	}
+	if t.Cmd != nil {
+		out.Cmd = lo.ToPtr(t.Cmd)
+	}
+	if t.Hypervisor != "" {
+		out.Hypervisor = lo.ToPtr(oapi.InstanceGroupTemplateHypervisor(t.Hypervisor))
+	}
+	return out
+}
+
+func groupToOAPI(group groups.Group) oapi.InstanceGroup {
+	st := group.Status
+	members := make([]oapi.InstanceGroupMember, 0, len(st.Members))
+	for _, m := range st.Members {
+		members = append(members, oapi.InstanceGroupMember{
+			InstanceId: m.InstanceID,
+			Name:       m.Name,
+			State:      oapi.InstanceState(m.State),
+			Digest:     m.Digest,
+			Generation: m.Generation,
+			Updated:    m.Updated,
+			CreatedAt:  m.CreatedAt,
+		})
+	}
+
+	out := oapi.InstanceGroup{
+		Id:         group.Id,
+		Name:       group.Name,
+		Template:   groupTemplateToOAPI(group.Template),
+		Replicas:   group.Replicas,
+		Generation: group.Generation,
+		CreatedAt:  group.CreatedAt,
+		UpdatedAt:  group.UpdatedAt,
+		Status: oapi.InstanceGroupStatus{
+			State:        oapi.InstanceGroupStatusState(st.State),
+			Members:      members,
+			Running:      st.Running,
+			Updated:      st.Updated,
+			MetricValue:  st.MetricValue,
+			ReconciledAt: st.ReconciledAt,
+		},
+	}
+	if a := group.Autoscale; a != nil {
+		out.Autoscale = &oapi.InstanceGroupAutoscale{
+			Enabled:           a.Enabled,
+			MinReplicas:       a.MinReplicas,
+			MaxReplicas:       a.MaxReplicas,
+			Metric:            oapi.InstanceGroupAutoscaleMetric(a.Metric),
+			Target:            a.Target,
+			ScaleUpCooldown:   lo.ToPtr(a.ScaleUpCooldown.String()),
+			ScaleDownCooldown: lo.ToPtr(a.ScaleDownCooldown.String()),
+		}
+	}
+	if st.Digest != "" {
+		out.Status.Digest = lo.ToPtr(st.Digest)
+	}
+	if st.Error != "" {
+		out.Status.Error = lo.ToPtr(st.Error)
+	}
+	if group.Tenant != "" {
+		out.Tenant = lo.ToPtr(group.Tenant)
+	}
+	return out
+}
@@ -0,0 +1,534 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/kernel/hypeman/lib/logger"
+	"github.com/kernel/hypeman/lib/mgmt"
+	mw "github.com/kernel/hypeman/lib/middleware"
+	"github.com/kernel/hypeman/lib/oapi"
+	"github.com/samber/lo"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// ManagementServer implements the gRPC management API (lib/mgmt).
+// Calls go through the same handlers as the REST endpoints, with request
+// bodies checked against the same OpenAPI schemas, so validation, tenant
+// scoping and errors match theirs.
+type ManagementServer struct {
+	mgmt.UnimplementedManagementServiceServer
+	api       *ApiService
+	resolvers mw.Resolvers
+	validator *mw.BodyValidator
+}
+
+var _ mgmt.ManagementServiceServer = (*ManagementServer)(nil)
+
+// NewManagementServer creates the gRPC management API on top of the ApiService
+func NewManagementServer(s *ApiService) (*ManagementServer, error) {
+	spec, err := oapi.GetSwagger()
+	if err != nil {
+		return nil, fmt.Errorf("load OpenAPI spec: %w", err)
+	}
+	spec.Servers = nil
+	validator, err := mw.NewBodyValidator(spec)
+	if err != nil {
+		return nil, err
+	}
+	return &ManagementServer{api: s, resolvers: s.NewResolvers(), validator: validator}, nil
+}
+
+// GRPCRequiredRole returns the minimum role needed to call a ManagementService
+// method: reads and streams need viewer, everything else operator, like the
+// matching REST endpoints.
+func GRPCRequiredRole(fullMethod string) mw.Role {
+	name := path.Base(fullMethod)
+	for _, prefix := range []string{"List", "Get", "Stream"} {
+		if strings.HasPrefix(name, prefix) {
+			return mw.RoleViewer
+		}
+	}
+	return mw.RoleOperator
+}
+
+// GRPCIsCreate matches the ManagementService methods that create resources,
+// which the create concurrency limit applies to like IsCreateRequest for REST
+func GRPCIsCreate(fullMethod string) bool {
+	return strings.HasPrefix(path.Base(fullMethod), "Create")
+}
+
+// validate checks a request body built from a gRPC call against the schema
+// of the REST operation at method and urlPath, since only REST requests pass
+// the OpenAPI request validator
+func (m *ManagementServer) validate(ctx context.Context, method, urlPath string, body any) error {
+	problem, err := m.validator.Validate(ctx, method, urlPath, body)
+	if err != nil {
+		logger.FromContext(ctx).ErrorContext(ctx, "failed to validate request", "path", urlPath, "error", err)
+		return status.Error(codes.Internal, "failed to validate request")
+	}
+	if problem != nil {
+		return status.Error(codes.InvalidArgument, problem.Message)
+	}
+	return nil
+}
+
+// resolve looks up a resource like the ResolveResource middleware and returns
+// a context carrying it
+func resolve(ctx context.Context, resolver mw.ResourceResolver, idOrName string, with func(context.Context, string, any) context.Context) (context.Context, error) {
+	id, resource, err := resolver.Resolve(ctx, idOrName)
+	if err != nil {
+		httpStatus, code, message := resolverProblem(err)
+		return nil, status.Error(grpcCode(httpStatus, code), message)
+	}
+	return with(ctx, id, resource), nil
+}
+
+// grpcOutcome converts the problem of a failed handler call to a gRPC status, or returns nil
+func grpcOutcome(resp any, err error) error {
+	e := applyOutcome(resp, err)
+	if e == nil {
+		return nil
+	}
+	return status.Error(grpcCode(lo.FromPtr(e.Status), e.Code), e.Message)
+}
+
+// grpcCode maps an HTTP problem status and code to the closest gRPC code
+func grpcCode(httpStatus int, code oapi.ErrorCode) codes.Code {
+	switch httpStatus {
+	case http.StatusBadRequest, http.StatusUnprocessableEntity, http.StatusRequestEntityTooLarge:
+		return codes.InvalidArgument
+	case http.StatusUnauthorized:
+		return codes.Unauthenticated
+	case http.StatusForbidden:
+		return codes.PermissionDenied
+	case http.StatusNotFound:
+		return codes.NotFound
+	case http.StatusConflict:
+		switch code {
+		case oapi.AlreadyExists:
+			return codes.AlreadyExists
+		case oapi.Ambiguous:
+			return codes.InvalidArgument
+		}
+		return codes.FailedPrecondition
+	case http.StatusTooManyRequests:
+		return codes.ResourceExhausted
+	case http.StatusNotImplemented:
+		return codes.Unimplemented
+	case http.StatusServiceUnavailable:
+		return codes.Unavailable
+	}
+	if httpStatus >= 500 {
+		return codes.Internal
+	}
+	return codes.FailedPrecondition
+}
+
+func (m *ManagementServer) resolveInstance(ctx context.Context, idOrName string) (context.Context, error) {
+	return resolve(ctx, m.resolvers.Instance, idOrName, mw.WithResolvedInstance)
+}
+
+func (m *ManagementServer) ListInstances(ctx context.Context, req *mgmt.ListInstancesRequest) (*mgmt.ListInstancesResponse, error) {
+	resp, err := m.api.ListInstances(ctx, oapi.ListInstancesRequestObject{})
+	if err := grpcOutcome(resp, err); err != nil {
+		return nil, err
+	}
+	list := resp.(oapi.ListInstances200JSONResponse)
+	out := &mgmt.ListInstancesResponse{Instances: make([]*mgmt.Instance, 0, len(list))}
+	for _, inst := range list {
+		out.Instances = append(out.Instances, instanceToProto(inst))
+	}
+	return out, nil
+}
+
+func (m *ManagementServer) GetInstance(ctx context.Context, req *mgmt.InstanceRequest) (*mgmt.Instance, error) {
+	ctx, err := m.resolveInstance(ctx, req.GetId())
+	if err != nil {
+		return nil, err
+	}
+	resp, err := m.api.GetInstance(ctx, oapi.GetInstanceRequestObject{Id: req.GetId()})
+	if err := grpcOutcome(resp, err); err != nil {
+		return nil, err
+	}
+	return instanceToProto(oapi.Instance(resp.(oapi.GetInstance200JSONResponse))), nil
+}
+
+func (m *ManagementServer) CreateInstance(ctx context.Context, req *mgmt.CreateInstanceRequest) (*mgmt.Instance, error) {
+	body := oapi.CreateInstanceRequest{
+		Name:                req.GetName(),
+		Image:               optional(req.GetImage()),
+		Size:                optional(req.GetSize()),
+		HotplugSize:         optional(req.GetHotplugSize()),
+		OverlaySize:         optional(req.GetOverlaySize()),
+		Workdir:             optional(req.GetWorkdir()),
+		ShutdownGracePeriod: optional(req.GetShutdownGracePeriod()),
+		Tenant:              optional(req.GetTenant()),
+	}
+	if req.GetVcpus() != 0 {
+		body.Vcpus = lo.ToPtr(int(req.GetVcpus()))
+	}
+	if len(req.GetEnv()) > 0 {
+		body.Env = lo.ToPtr(req.GetEnv())
+	}
+	if len(req.GetCmd()) > 0 {
+		body.Cmd = lo.ToPtr(req.GetCmd())
+	}
+	if len(req.GetEntrypoint()) > 0 {
+		body.Entrypoint = lo.ToPtr(req.GetEntrypoint())
+	}
+	if req.GetDisableNetwork() {
+		body.Network = &struct {
+			BandwidthDownload      *string `json:"bandwidth_download,omitempty"`
+			BandwidthDownloadBurst *string `json:"bandwidth_download_burst,omitempty"`
+			BandwidthUpload        *string `json:"bandwidth_upload,omitempty"`
+			BandwidthUploadBurst   *string `json:"bandwidth_upload_burst,omitempty"`
+			Enabled                *bool   `json:"enabled,omitempty"`
+		}{Enabled: lo.ToPtr(false)}
+	}
+	if len(req.GetVolumes()) > 0 {
+		mounts := make([]oapi.VolumeMount, 0, len(req.GetVolumes()))
+		for _, v := range req.GetVolumes() {
+			mounts = append(mounts, oapi.VolumeMount{VolumeId: v.GetVolumeId(), MountPath: v.GetMountPath(), Readonly: lo.ToPtr(v.GetReadonly())})
+		}
+		body.Volumes = &mounts
+	}
+
+	if err := m.validate(ctx, http.MethodPost, "/instances", body); err != nil {
+		return nil, err
+	}
+	resp, err := m.api.CreateInstance(ctx, oapi.CreateInstanceRequestObject{Body: &body})
+	if err := grpcOutcome(resp, err); err != nil {
+		return nil, err
+	}
+	return instanceToProto(oapi.Instance(resp.(oapi.CreateInstance201JSONResponse))), nil
+}
+
+func (m *ManagementServer) DeleteInstance(ctx context.Context, req *mgmt.DeleteInstanceRequest) (*mgmt.DeleteResponse, error) {
+	ctx, err := m.resolveInstance(ctx, req.GetId())
+	if err != nil {
+		return nil, err
+	}
+	resp, err := m.api.DeleteInstance(ctx, oapi.DeleteInstanceRequestObject{
+		Id:     req.GetId(),
+		Params: oapi.DeleteInstanceParams{GracePeriod: optional(req.GetGracePeriod())},
+	})
+	if err := grpcOutcome(resp, err); err != nil {
+		return nil, err
+	}
+	return &mgmt.DeleteResponse{}, nil
+}
+
+func (m *ManagementServer) StartInstance(ctx context.Context, req *mgmt.InstanceRequest) (*mgmt.Instance, error) {
+	ctx, err := m.resolveInstance(ctx, req.GetId())
+	if err != nil {
+		return nil, err
+	}
+	resp, err := m.api.StartInstance(ctx, oapi.StartInstanceRequestObject{Id: req.GetId()})
+	if err := grpcOutcome(resp, err); err != nil {
+		return nil, err
+	}
+	return instanceToProto(oapi.Instance(resp.(oapi.StartInstance200JSONResponse))), nil
+}
+
+func (m *ManagementServer) StopInstance(ctx context.Context, req *mgmt.InstanceRequest) (*mgmt.Instance, error) {
+	ctx, err := m.resolveInstance(ctx, req.GetId())
+	if err != nil {
+		return nil, err
+	}
+	resp, err := m.api.StopInstance(ctx, oapi.StopInstanceRequestObject{Id: req.GetId()})
+	if err := grpcOutcome(resp, err); err != nil {
+		return nil, err
+	}
+	return instanceToProto(oapi.Instance(resp.(oapi.StopInstance200JSONResponse))), nil
+}
+
+func (m *ManagementServer) StandbyInstance(ctx context.Context, req *mgmt.InstanceRequest) (*mgmt.Instance, error) {
+	ctx, err := m.resolveInstance(ctx, req.GetId())
+	if err != nil {
+		return nil, err
+	}
+	resp, err := m.api.StandbyInstance(ctx, oapi.StandbyInstanceRequestObject{Id: req.GetId()})
+	if err := grpcOutcome(resp, err); err != nil {
+		return nil, err
+	}
+	return instanceToProto(oapi.Instance(resp.(oapi.StandbyInstance200JSONResponse))), nil
+}
+
+func (m *ManagementServer) RestoreInstance(ctx context.Context, req *mgmt.InstanceRequest) (*mgmt.Instance, error) {
+	ctx, err := m.resolveInstance(ctx, req.GetId())
+	if err != nil {
+		return nil, err
+	}
+	resp, err := m.api.RestoreInstance(ctx, oapi.RestoreInstanceRequestObject{Id: req.GetId()})
+	if err := grpcOutcome(resp, err); err != nil {
+		return nil, err
+	}
+	return instanceToProto(oapi.Instance(resp.(oapi.RestoreInstance200JSONResponse))), nil
+}
+
+func (m *ManagementServer) StreamInstanceLogs(req *mgmt.StreamInstanceLogsRequest, stream grpc.ServerStreamingServer[mgmt.LogLine]) error {
+	ctx, err := m.resolveInstance(stream.Context(), req.GetId())
+	if err != nil {
+		return err
+	}
+	params := oapi.GetInstanceLogsParams{Follow: lo.ToPtr(req.GetFollow())}
+	if req.GetTail() > 0 {
+		params.Tail = lo.ToPtr(int(req.GetTail()))
+	}
+	if req.GetSource() != "" {
+		source := oapi.GetInstanceLogsParamsSource(req.GetSource())
+		switch source {
+		case oapi.App, oapi.Vmm, oapi.Hypeman:
+		default:
+			return status.Errorf(codes.InvalidArgument, "invalid log source %q: must be app, vmm or hypeman", req.GetSource())
+		}
+		params.Source = &source
+	}
+
+	resp, err := m.api.GetInstanceLogs(ctx, oapi.GetInstanceLogsRequestObject{Id: req.GetId(), Params: params})
+	if err := grpcOutcome(resp, err); err != nil {
+		return err
+	}
+	for line := range resp.(logsStreamResponse).logChan {
+		if err := stream.Send(&mgmt.LogLine{Line: line}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *ManagementServer) ListImages(ctx context.Context, req *mgmt.ListImagesRequest) (*mgmt.ListImagesResponse, error) {
+	resp, err := m.api.ListImages(ctx, oapi.ListImagesRequestObject{})
+	if err := grpcOutcome(resp, err); err != nil {
+		return nil, err
+	}
+	list := resp.(oapi.ListImages200JSONResponse)
+	out := &mgmt.ListImagesResponse{Images: make([]*mgmt.Image, 0, len(list))}
+	for _, img := range list {
+		out.Images = append(out.Images, imageToProto(img))
+	}
+	return out, nil
+}
+
+func (m *ManagementServer) GetImage(ctx context.Context, req *mgmt.ImageRequest) (*mgmt.Image, error) {
+	ctx, err := resolve(ctx, m.resolvers.Image, req.GetName(), mw.WithResolvedImage)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := m.api.GetImage(ctx, oapi.GetImageRequestObject{Name: req.GetName()})
+	if err := grpcOutcome(resp, err); err != nil {
+		return nil, err
+	}
+	return imageToProto(oapi.Image(resp.(oapi.GetImage200JSONResponse))), nil
+}
+
+func (m *ManagementServer) CreateImage(ctx context.Context, req *mgmt.CreateImageRequest) (*mgmt.Image, error) {
+	body := oapi.CreateImageRequest{Name: req.GetName(), Tenant: optional(req.GetTenant())}
+	if err := m.validate(ctx, http.MethodPost, "/images", body); err != nil {
+		return nil, err
+	}
+	resp, err := m.api.CreateImage(ctx, oapi.CreateImageRequestObject{Body: &body})
+	if err := grpcOutcome(resp, err); err != nil {
+		return nil, err
+	}
+	return imageToProto(oapi.Image(resp.(oapi.CreateImage202JSONResponse))), nil
+}
+
+func (m *ManagementServer) DeleteImage(ctx context.Context, req *mgmt.ImageRequest) (*mgmt.DeleteResponse, error) {
+	ctx, err := resolve(ctx, m.resolvers.Image, req.GetName(), mw.WithResolvedImage)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := m.api.DeleteImage(ctx, oapi.DeleteImageRequestObject{Name: req.GetName()})
+	if err := grpcOutcome(resp, err); err != nil {
+		return nil, err
+	}
+	return &mgmt.DeleteResponse{}, nil
+}
+
+func (m *ManagementServer) ListVolumes(ctx context.Context, req *mgmt.ListVolumesRequest) (*mgmt.ListVolumesResponse, error) {
+	resp, err := m.api.ListVolumes(ctx, oapi.ListVolumesRequestObject{})
+	if err := grpcOutcome(resp, err); err != nil {
+		return nil, err
+	}
+	list := resp.(oapi.ListVolumes200JSONResponse)
+	out := &mgmt.ListVolumesResponse{Volumes: make([]*mgmt.Volume, 0, len(list))}
+	for _, vol := range list {
+		out.Volumes = append(out.Volumes, volumeToProto(vol))
+	}
+	return out, nil
+}
+
+func (m *ManagementServer) GetVolume(ctx context.Context, req *mgmt.VolumeRequest) (*mgmt.Volume, error) {
+	ctx, err := resolve(ctx, m.resolvers.Volume, req.GetId(), mw.WithResolvedVolume)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := m.api.GetVolume(ctx, oapi.GetVolumeRequestObject{Id: req.GetId()})
+	if err := grpcOutcome(resp, err); err != nil {
+		return nil, err
+	}
+	return volumeToProto(oapi.Volume(resp.(oapi.GetVolume200JSONResponse))), nil
+}
+
+func (m *ManagementServer) CreateVolume(ctx context.Context, req *mgmt.CreateVolumeRequest) (*mgmt.Volume, error) {
+	body := oapi.CreateVolumeRequest{
+		Name:   req.GetName(),
+		SizeGb: int(req.GetSizeGb()),
+		Id:     optional(req.GetId()),
+		Tenant: optional(req.GetTenant()),
+	}
+	if err := m.validate(ctx, http.MethodPost, "/volumes", body); err != nil {
+		return nil, err
+	}
+	resp, err := m.api.CreateVolume(ctx, oapi.CreateVolumeRequestObject{JSONBody: &body})
+	if err := grpcOutcome(resp, err); err != nil {
+		return nil, err
+	}
+	return volumeToProto(oapi.Volume(resp.(oapi.CreateVolume201JSONResponse))), nil
+}
+
+func (m *ManagementServer) DeleteVolume(ctx context.Context, req *mgmt.VolumeRequest) (*mgmt.DeleteResponse, error) {
+	ctx, err := resolve(ctx, m.resolvers.Volume, req.GetId(), mw.WithResolvedVolume)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := m.api.DeleteVolume(ctx, oapi.DeleteVolumeRequestObject{Id: req.GetId()})
+	if err := grpcOutcome(resp, err); err != nil {
+		return nil, err
+	}
+	return &mgmt.DeleteResponse{}, nil
+}
+
+func (m *ManagementServer) ListBuilds(ctx context.Context, req *mgmt.ListBuildsRequest) (*mgmt.ListBuildsResponse, error) {
+	resp, err := m.api.ListBuilds(ctx, oapi.ListBuildsRequestObject{})
+	if err := grpcOutcome(resp, err); err != nil {
+		return nil, err
+	}
+	list := resp.(oapi.ListBuilds200JSONResponse)
+	out := &mgmt.ListBuildsResponse{Builds: make([]*mgmt.Build, 0, len(list))}
+	for _, b := range list {
+		out.Builds = append(out.Builds, buildToProto(b))
+	}
+	return out, nil
+}
+
+func (m *ManagementServer) GetBuild(ctx context.Context, req *mgmt.BuildRequest) (*mgmt.Build, error) {
+	resp, err := m.api.GetBuild(ctx, oapi.GetBuildRequestObject{Id: req.GetId()})
+	if err := grpcOutcome(resp, err); err != nil {
+		return nil, err
+	}
+	return buildToProto(oapi.Build(resp.(oapi.GetBuild200JSONResponse))), nil
+}
+
+func (m *ManagementServer) CancelBuild(ctx context.Context, req *mgmt.BuildRequest) (*mgmt.DeleteResponse, error) {
+	resp, err := m.api.CancelBuild(ctx, oapi.CancelBuildRequestObject{Id: req.GetId()})
+	if err := grpcOutcome(resp, err); err != nil {
+		return nil, err
+	}
+	return &mgmt.DeleteResponse{}, nil
+}
+
+func (m *ManagementServer) StreamBuildEvents(req *mgmt.StreamBuildEventsRequest, stream grpc.ServerStreamingServer[mgmt.BuildEvent]) error {
+	resp, err := m.api.GetBuildEvents(stream.Context(), oapi.GetBuildEventsRequestObject{
+		Id:     req.GetId(),
+		Params: oapi.GetBuildEventsParams{Follow: lo.ToPtr(req.GetFollow())},
+	})
+	if err := grpcOutcome(resp, err); err != nil {
+		return err
+	}
+	for event := range resp.(buildEventsStreamResponse).eventChan {
+		if err := stream.Send(&mgmt.BuildEvent{
+			Type:      event.Type,
+			Timestamp: event.Timestamp.Unix(),
+			Content:   event.Content,
+			Status:    event.Status,
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func instanceToProto(inst oapi.Instance) *mgmt.Instance {
+	out := &mgmt.Instance{
+		Id:         inst.Id,
+		Name:       inst.Name,
+		Image:      inst.Image,
+		State:      string(inst.State),
+		StateError: lo.FromPtr(inst.StateError),
+		Vcpus:      int32(lo.FromPtr(inst.Vcpus)),
+		Size:       lo.FromPtr(inst.Size),
+		CreatedAt:  inst.CreatedAt.Unix(),
+		StartedAt:  unixOrZero(inst.StartedAt),
+		StoppedAt:  unixOrZero(inst.StoppedAt),
+		Tenant:     lo.FromPtr(inst.Tenant),
+		Env:        lo.FromPtr(inst.Env),
+	}
+	if inst.Network != nil {
+		out.Ip = lo.FromPtr(inst.Network.Ip)
+	}
+	return out
+}
+
+func imageToProto(img oapi.Image) *mgmt.Image {
+	return &mgmt.Image{
+		Name:      img.Name,
+		Digest:    img.Digest,
+		Status:    string(img.Status),
+		Error:     lo.FromPtr(img.Error),
+		SizeBytes: lo.FromPtr(img.SizeBytes),
+		CreatedAt: img.CreatedAt.Unix(),
+		Tenant:    lo.FromPtr(img.Tenant),
+	}
+}
+
+func volumeToProto(vol oapi.Volume) *mgmt.Volume {
+	out := &mgmt.Volume{
+		Id:        vol.Id,
+		Name:      vol.Name,
+		SizeGb:    int32(vol.SizeGb),
+		CreatedAt: vol.CreatedAt.Unix(),
+		Tenant:    lo.FromPtr(vol.Tenant),
+	}
+	for _, a := range lo.FromPtr(vol.Attachments) {
+		out.InstanceIds = append(out.InstanceIds, a.InstanceId)
+	}
+	return out
+}
+
+func buildToProto(b oapi.Build) *mgmt.Build {
+	return &mgmt.Build{
+		Id:          b.Id,
+		Status:      string(b.Status),
+		ImageRef:    lo.FromPtr(b.ImageRef),
+		ImageDigest: lo.FromPtr(b.ImageDigest),
+		Error:       lo.FromPtr(b.Error),
+		CreatedAt:   b.CreatedAt.Unix(),
+		StartedAt:   unixOrZero(b.StartedAt),
+		CompletedAt: unixOrZero(b.CompletedAt),
+		Tenant:      lo.FromPtr(b.Tenant),
+	}
+}
+
+// optional returns nil for an unset (empty) proto3 string
+func optional(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
+
+func unixOrZero(t *time.Time) int64 {
+	if t == nil {
+		return 0
+	}
+	return t.Unix()
+}
@@ -0,0 +1,149 @@
+package api
+
+import (
+	"context"
+	"log/slog"
+	"net"
+	"net/http"
+	"testing"
+
+	"github.com/kernel/hypeman/lib/mgmt"
+	mw "github.com/kernel/hypeman/lib/middleware"
+	"github.com/kernel/hypeman/lib/oapi"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// newTestManagementClient serves the gRPC management API for svc in memory
+func newTestManagementClient(t *testing.T, svc *ApiService, opts ...grpc.ServerOption) mgmt.ManagementServiceClient {
+	auth := mw.NewAuthenticator(mw.AuthConfig{APIKeys: map[string]mw.APIKey{
+		"operator-key": {Subject: "ops", Role: mw.RoleOperator},
+		"viewer-key":   {Subject: "dashboard", Role: mw.RoleViewer},
+		"tenant-key":   {Subject: "team-a", Role: mw.RoleOperator, Tenant: "team-a"},
+	}})
+	srv := grpc.NewServer(append(auth.GRPCServerOptions(slog.Default(), GRPCRequiredRole), opts...)...)
+	mgmtServer, err := NewManagementServer(svc)
+	require.NoError(t, err)
+	mgmt.RegisterManagementServiceServer(srv, mgmtServer)
+
+	lis := bufconn.Listen(1 << 20)
+	go srv.Serve(lis)
+	t.Cleanup(srv.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	return mgmt.NewManagementServiceClient(conn)
+}
+
+func withKey(key string) context.Context {
+	return metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer "+key)
+}
+
+func TestManagementServer_Volumes(t *testing.T) {
+	svc := newTestService(t)
+	client := newTestManagementClient(t, svc)
+
+	_, err := client.ListVolumes(context.Background(), &mgmt.ListVolumesRequest{})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err), "credentials are required")
+
+	_, err = client.CreateVolume(withKey("viewer-key"), &mgmt.CreateVolumeRequest{Name: "data", SizeGb: 1})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err), "viewers can't create")
+
+	created, err := client.CreateVolume(withKey("operator-key"), &mgmt.CreateVolumeRequest{Name: "data", SizeGb: 1})
+	require.NoError(t, err)
+	assert.Equal(t, "data", created.GetName())
+	assert.Equal(t, int32(1), created.GetSizeGb())
+
+	// Volumes resolve by name, like the REST endpoints
+	got, err := client.GetVolume(withKey("viewer-key"), &mgmt.VolumeRequest{Id: "data"})
+	require.NoError(t, err)
+	assert.Equal(t, created.GetId(), got.GetId())
+
+	list, err := client.ListVolumes(withKey("viewer-key"), &mgmt.ListVolumesRequest{})
+	require.NoError(t, err)
+	assert.Len(t, list.GetVolumes(), 1)
+
+	// Other tenants' resources are reported as not found
+	_, err = client.GetVolume(withKey("tenant-key"), &mgmt.VolumeRequest{Id: "data"})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	_, err = client.DeleteVolume(withKey("operator-key"), &mgmt.VolumeRequest{Id: "data"})
+	require.NoError(t, err)
+	_, err = client.GetVolume(withKey("viewer-key"), &mgmt.VolumeRequest{Id: "data"})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+}
+
+func TestManagementServer_InvalidRequest(t *testing.T) {
+	svc := newTestService(t)
+	client := newTestManagementClient(t, svc)
+
+	_, err := client.CreateInstance(withKey("operator-key"), &mgmt.CreateInstanceRequest{Name: "web", Image: "docker.io/library/alpine:latest", ShutdownGracePeriod: "soon"})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	_, err = client.GetInstance(withKey("viewer-key"), &mgmt.InstanceRequest{Id: "missing"})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+}
+
+func TestManagementServer_ValidatesBodies(t *testing.T) {
+	svc := newTestService(t)
+	client := newTestManagementClient(t, svc)
+
+	// Rejected by the CreateInstance schema before the handler runs
+	_, err := client.CreateInstance(withKey("operator-key"), &mgmt.CreateInstanceRequest{Name: "Not A Valid Name", Image: "docker.io/library/alpine:latest"})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	assert.Contains(t, status.Convert(err).Message(), "regular expression")
+}
+
+func TestManagementServer_Limits(t *testing.T) {
+	svc := newTestService(t)
+	create := mw.NewConcurrencyLimiter("create requests", 1)
+	client := newTestManagementClient(t, svc, mw.GRPCLimitOptions(mw.NewRateLimiter(0.001, 2), create, GRPCIsCreate)...)
+
+	// Creates count against the limit the REST API shares
+	require.True(t, create.Acquire("api_key:ops"))
+	_, err := client.CreateVolume(withKey("operator-key"), &mgmt.CreateVolumeRequest{Name: "data", SizeGb: 1})
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+	create.Release("api_key:ops")
+
+	// The burst of 2 is spent by the create above and this call
+	_, err = client.ListVolumes(withKey("operator-key"), &mgmt.ListVolumesRequest{})
+	require.NoError(t, err)
+	_, err = client.ListVolumes(withKey("operator-key"), &mgmt.ListVolumesRequest{})
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+
+	// Limits are per client
+	_, err = client.ListVolumes(withKey("viewer-key"), &mgmt.ListVolumesRequest{})
+	assert.NoError(t, err)
+}
+
+func TestGRPCIsCreate(t *testing.T) {
+	assert.True(t, GRPCIsCreate(mgmt.ManagementService_CreateInstance_FullMethodName))
+	assert.True(t, GRPCIsCreate(mgmt.ManagementService_CreateVolume_FullMethodName))
+	assert.False(t, GRPCIsCreate(mgmt.ManagementService_ListInstances_FullMethodName))
+}
+
+func TestGRPCRequiredRole(t *testing.T) {
+	assert.Equal(t, mw.RoleViewer, GRPCRequiredRole(mgmt.ManagementService_ListInstances_FullMethodName))
+	assert.Equal(t, mw.RoleViewer, GRPCRequiredRole(mgmt.ManagementService_StreamBuildEvents_FullMethodName))
+	assert.Equal(t, mw.RoleOperator, GRPCRequiredRole(mgmt.ManagementService_DeleteInstance_FullMethodName))
+	assert.Equal(t, mw.RoleOperator, GRPCRequiredRole(mgmt.ManagementService_CancelBuild_FullMethodName))
+}
+
+func TestGRPCCode(t *testing.T) {
+	assert.Equal(t, codes.InvalidArgument, grpcCode(http.StatusBadRequest, oapi.InvalidRequest))
+	assert.Equal(t, codes.NotFound, grpcCode(http.StatusNotFound, oapi.NotFound))
+	assert.Equal(t, codes.AlreadyExists, grpcCode(http.StatusConflict, oapi.AlreadyExists))
+	assert.Equal(t, codes.FailedPrecondition, grpcCode(http.StatusConflict, oapi.InvalidState))
+	assert.Equal(t, codes.ResourceExhausted, grpcCode(http.StatusTooManyRequests, oapi.RateLimited))
+	assert.Equal(t, codes.Internal, grpcCode(http.StatusInternalServerError, oapi.InternalError))
+}
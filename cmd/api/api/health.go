@@ -3,13 +3,47 @@ package api
 import (
 	"context"
 
+	"github.com/kernel/hypeman/lib/health"
 	"github.com/kernel/hypeman/lib/oapi"
 )
 
 // GetHealth implements health check endpoint
 func (s *ApiService) GetHealth(ctx context.Context, request oapi.GetHealthRequestObject) (oapi.GetHealthResponseObject, error) {
 	return oapi.GetHealth200JSONResponse{
-		Status: oapi.Ok,
+		Status: oapi.HealthStatusOk,
 	}, nil
 }
 
+// GetHealthz reports subsystem status. It always responds 200: the server
+// is up if it can answer.
+func (s *ApiService) GetHealthz(ctx context.Context, request oapi.GetHealthzRequestObject) (oapi.GetHealthzResponseObject, error) {
+	report := s.HealthChecker.Run(ctx)
+	return oapi.GetHealthz200JSONResponse(healthReportToOAPI(report)), nil
+}
+
+// GetReadyz reports subsystem status, responding 503 when a required
+// subsystem failed
+func (s *ApiService) GetReadyz(ctx context.Context, request oapi.GetReadyzRequestObject) (oapi.GetReadyzResponseObject, error) {
+	report := s.HealthChecker.Run(ctx)
+	if report.Status == health.StatusUnavailable {
+		return oapi.GetReadyz503JSONResponse(healthReportToOAPI(report)), nil
+	}
+	return oapi.GetReadyz200JSONResponse(healthReportToOAPI(report)), nil
+}
+
+func healthReportToOAPI(report health.Report) oapi.HealthReport {
+	components := make([]oapi.HealthComponent, len(report.Components))
+	for i, c := range report.Components {
+		components[i] = oapi.HealthComponent{
+			Name:     c.Name,
+			Status:   oapi.HealthComponentStatus(c.Status),
+			Required: c.Required,
+			Detail:   c.Detail,
+		}
+	}
+	return oapi.HealthReport{
+		Status:     oapi.HealthReportStatus(report.Status),
+		Components: components,
+		CheckedAt:  report.CheckedAt,
+	}
+}
@@ -0,0 +1,47 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/kernel/hypeman/lib/health"
+	"github.com/kernel/hypeman/lib/oapi"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHealthzReadyz(t *testing.T) {
+	checker := health.NewChecker(0)
+	checker.Register("kvm", true, func(ctx context.Context) (string, error) {
+		return "/dev/kvm accessible", nil
+	})
+	svc := &ApiService{HealthChecker: checker}
+
+	resp, err := svc.GetReadyz(ctx(), oapi.GetReadyzRequestObject{})
+	require.NoError(t, err)
+	ready, ok := resp.(oapi.GetReadyz200JSONResponse)
+	require.True(t, ok, "expected 200, got %T", resp)
+	assert.Equal(t, oapi.Ok, ready.Status)
+	require.Len(t, ready.Components, 1)
+	assert.Equal(t, "kvm", ready.Components[0].Name)
+	assert.True(t, ready.Components[0].Required)
+
+	checker.Register("network", true, func(ctx context.Context) (string, error) {
+		return "", errors.New("bridge vmbr0: not found")
+	})
+
+	resp, err = svc.GetReadyz(ctx(), oapi.GetReadyzRequestObject{})
+	require.NoError(t, err)
+	notReady, ok := resp.(oapi.GetReadyz503JSONResponse)
+	require.True(t, ok, "expected 503, got %T", resp)
+	assert.Equal(t, oapi.Unavailable, notReady.Status)
+	assert.Equal(t, "bridge vmbr0: not found", notReady.Components[1].Detail)
+
+	// Liveness doesn't depend on subsystems
+	live, err := svc.GetHealthz(ctx(), oapi.GetHealthzRequestObject{})
+	require.NoError(t, err)
+	report, ok := live.(oapi.GetHealthz200JSONResponse)
+	require.True(t, ok, "expected 200, got %T", live)
+	assert.Equal(t, oapi.Unavailable, report.Status)
+}
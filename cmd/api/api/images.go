@@ -3,11 +3,15 @@ package api
 import (
 	"context"
 	"errors"
+	"fmt"
+	"io"
+	"slices"
 
 	"github.com/kernel/hypeman/lib/images"
 	"github.com/kernel/hypeman/lib/logger"
 	mw "github.com/kernel/hypeman/lib/middleware"
 	"github.com/kernel/hypeman/lib/oapi"
+	"github.com/kernel/hypeman/lib/system"
 )
 
 func (s *ApiService) ListImages(ctx context.Context, request oapi.ListImagesRequestObject) (oapi.ListImagesResponseObject, error) {
@@ -16,15 +20,19 @@ func (s *ApiService) ListImages(ctx context.Context, request oapi.ListImagesRequ
 	domainImages, err := s.ImageManager.ListImages(ctx)
 	if err != nil {
 		log.ErrorContext(ctx, "failed to list images", "error", err)
-		return oapi.ListImages500JSONResponse{
-			Code:    "internal_error",
+		return oapi.ListImages500ApplicationProblemPlusJSONResponse{
+			Code:    oapi.InternalError,
 			Message: "failed to list images",
 		}, nil
 	}
 
-	oapiImages := make([]oapi.Image, len(domainImages))
-	for i, img := range domainImages {
-		oapiImages[i] = imageToOAPI(img)
+	oapiImages := make([]oapi.Image, 0, len(domainImages))
+	for _, img := range domainImages {
+		// Untenanted images are shared by all tenants
+		if img.Tenant != "" && !mw.TenantVisible(ctx, img.Tenant) {
+			continue
+		}
+		oapiImages = append(oapiImages, imageToOAPI(img))
 	}
 
 	return oapi.ListImages200JSONResponse(oapiImages), nil
@@ -33,41 +41,286 @@ func (s *ApiService) ListImages(ctx context.Context, request oapi.ListImagesRequ
 func (s *ApiService) CreateImage(ctx context.Context, request oapi.CreateImageRequestObject) (oapi.CreateImageResponseObject, error) {
 	log := logger.FromContext(ctx)
 
+	tenant, err := mw.TenantForCreate(ctx, request.Body.Tenant)
+	if err != nil {
+		return oapi.CreateImage403ApplicationProblemPlusJSONResponse{
+			Code:    oapi.Forbidden,
+			Message: err.Error(),
+		}, nil
+	}
+
 	domainReq := images.CreateImageRequest{
-		Name: request.Body.Name,
+		Name:   request.Body.Name,
+		Tenant: tenant,
 	}
 
-	img, err := s.ImageManager.CreateImage(ctx, domainReq)
+	dryRun := request.Params.DryRun != nil && *request.Params.DryRun
+	var img *images.Image
+	if request.Body.Source != nil && *request.Body.Source == oapi.ImageSourceContainerd {
+		importReq := images.ContainerdImportRequest{
+			Name:      request.Body.Name,
+			Address:   s.Config.ContainerdAddress,
+			Namespace: s.Config.ContainerdNamespace,
+			Tenant:    tenant,
+		}
+		if request.Body.ContainerdNamespace != nil {
+			importReq.Namespace = *request.Body.ContainerdNamespace
+		}
+		if dryRun {
+			img, err = s.ImageManager.CheckImportFromContainerd(ctx, importReq)
+		} else {
+			img, err = s.ImageManager.ImportFromContainerd(ctx, importReq)
+		}
+	} else if dryRun {
+		img, err = s.ImageManager.CheckCreateImage(ctx, domainReq)
+	} else {
+		img, err = s.ImageManager.CreateImage(ctx, domainReq)
+	}
 	if err != nil {
 		switch {
 		case errors.Is(err, images.ErrInvalidName):
-			return oapi.CreateImage400JSONResponse{
-				Code:    "invalid_name",
+			return oapi.CreateImage400ApplicationProblemPlusJSONResponse{
+				Code:    oapi.InvalidRequest,
 				Message: err.Error(),
 			}, nil
 		case errors.Is(err, images.ErrNotFound):
-			return oapi.CreateImage404JSONResponse{
-				Code:    "not_found",
+			return oapi.CreateImage404ApplicationProblemPlusJSONResponse{
+				Code:    oapi.NotFound,
 				Message: "image not found",
 			}, nil
 		default:
 			log.ErrorContext(ctx, "failed to create image", "error", err, "name", request.Body.Name)
-			return oapi.CreateImage500JSONResponse{
-				Code:    "internal_error",
+			return oapi.CreateImage500ApplicationProblemPlusJSONResponse{
+				Code:    oapi.InternalError,
 				Message: "failed to create image",
 			}, nil
 		}
 	}
+	if dryRun {
+		details := []string{"digest: " + img.Digest}
+		if img.Status == images.StatusPending {
+			details = append(details, "the image would be pulled and converted")
+		} else {
+			details = append(details, fmt.Sprintf("the digest is already present (status %s); no build would be queued", img.Status))
+		}
+		return oapi.CreateImage200JSONResponse(dryRunResult(oapi.ApplyResourceKindImage, oapi.ApplyCreate, img.Name, "", details)), nil
+	}
 	return oapi.CreateImage202JSONResponse(imageToOAPI(*img)), nil
 }
 
+// ImportImage imports an image from an uploaded docker save or OCI archive
+// and queues its conversion. The archive is streamed, so it must be the last
+// part of the form.
+func (s *ApiService) ImportImage(ctx context.Context, request oapi.ImportImageRequestObject) (oapi.ImportImageResponseObject, error) {
+	log := logger.FromContext(ctx)
+
+	var req images.ArchiveImportRequest
+	var tenant *string
+	for {
+		part, err := request.Body.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return oapi.ImportImage400ApplicationProblemPlusJSONResponse{
+				Code:    oapi.InvalidRequest,
+				Message: "failed to parse multipart form: " + err.Error(),
+			}, nil
+		}
+
+		switch part.FormName() {
+		case "name", "tenant":
+			data, err := io.ReadAll(part)
+			if err != nil {
+				return oapi.ImportImage400ApplicationProblemPlusJSONResponse{
+					Code:    oapi.InvalidRequest,
+					Message: fmt.Sprintf("failed to read %s field", part.FormName()),
+				}, nil
+			}
+			if part.FormName() == "name" {
+				req.Name = string(data)
+			} else {
+				value := string(data)
+				tenant = &value
+			}
+		case "archive":
+			req.Tenant, err = mw.TenantForCreate(ctx, tenant)
+			if err != nil {
+				return oapi.ImportImage403ApplicationProblemPlusJSONResponse{
+					Code:    oapi.Forbidden,
+					Message: err.Error(),
+				}, nil
+			}
+
+			img, err := s.ImageManager.ImportArchive(ctx, req, part)
+			if err != nil {
+				switch {
+				case errors.Is(err, images.ErrInvalidName), errors.Is(err, images.ErrInvalidArchive):
+					return oapi.ImportImage400ApplicationProblemPlusJSONResponse{
+						Code:    oapi.InvalidRequest,
+						Message: err.Error(),
+					}, nil
+				case errors.Is(err, images.ErrNotFound):
+					return oapi.ImportImage404ApplicationProblemPlusJSONResponse{
+						Code:    oapi.NotFound,
+						Message: err.Error(),
+					}, nil
+				default:
+					log.ErrorContext(ctx, "failed to import image", "error", err, "name", req.Name)
+					return oapi.ImportImage500ApplicationProblemPlusJSONResponse{
+						Code:    oapi.InternalError,
+						Message: "failed to import image",
+					}, nil
+				}
+			}
+			log.InfoContext(ctx, "image imported from archive", "name", img.Name, "digest", img.Digest)
+			return oapi.ImportImage202JSONResponse(imageToOAPI(*img)), nil
+		}
+	}
+
+	return oapi.ImportImage400ApplicationProblemPlusJSONResponse{
+		Code:    oapi.InvalidRequest,
+		Message: "archive file is required",
+	}, nil
+}
+
+// ImportDiskImage imports a raw or qcow2 VM disk, uploaded as the last part
+// of the form or downloaded from the url field
+func (s *ApiService) ImportDiskImage(ctx context.Context, request oapi.ImportDiskImageRequestObject) (oapi.ImportDiskImageResponseObject, error) {
+	log := logger.FromContext(ctx)
+
+	var req images.DiskImportRequest
+	var tenant *string
+	var disk io.Reader
+	for disk == nil {
+		part, err := request.Body.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return oapi.ImportDiskImage400ApplicationProblemPlusJSONResponse{
+				Code:    oapi.InvalidRequest,
+				Message: "failed to parse multipart form: " + err.Error(),
+			}, nil
+		}
+
+		switch part.FormName() {
+		case "name", "url", "kernel_version", "firmware", "tenant":
+			data, err := io.ReadAll(part)
+			if err != nil {
+				return oapi.ImportDiskImage400ApplicationProblemPlusJSONResponse{
+					Code:    oapi.InvalidRequest,
+					Message: fmt.Sprintf("failed to read %s field", part.FormName()),
+				}, nil
+			}
+			value := string(data)
+			switch part.FormName() {
+			case "name":
+				req.Name = value
+			case "url":
+				req.URL = value
+			case "kernel_version":
+				req.KernelVersion = value
+			case "firmware":
+				req.Firmware = value
+			case "tenant":
+				tenant = &value
+			}
+		case "disk":
+			disk = part
+		}
+	}
+	if (disk == nil) == (req.URL == "") {
+		return oapi.ImportDiskImage400ApplicationProblemPlusJSONResponse{
+			Code:    oapi.InvalidRequest,
+			Message: "exactly one of a disk file or url is required",
+		}, nil
+	}
+	if req.KernelVersion != "" && !slices.Contains(system.SupportedKernelVersions, system.KernelVersion(req.KernelVersion)) {
+		return oapi.ImportDiskImage400ApplicationProblemPlusJSONResponse{
+			Code:    oapi.InvalidRequest,
+			Message: fmt.Sprintf("unsupported kernel version %q", req.KernelVersion),
+		}, nil
+	}
+
+	var err error
+	req.Tenant, err = mw.TenantForCreate(ctx, tenant)
+	if err != nil {
+		return oapi.ImportDiskImage403ApplicationProblemPlusJSONResponse{
+			Code:    oapi.Forbidden,
+			Message: err.Error(),
+		}, nil
+	}
+
+	img, err := s.ImageManager.ImportDisk(ctx, req, disk)
+	if err != nil {
+		if errors.Is(err, images.ErrInvalidName) || errors.Is(err, images.ErrInvalidDisk) {
+			return oapi.ImportDiskImage400ApplicationProblemPlusJSONResponse{
+				Code:    oapi.InvalidRequest,
+				Message: err.Error(),
+			}, nil
+		}
+		log.ErrorContext(ctx, "failed to import disk image", "error", err, "name", req.Name)
+		return oapi.ImportDiskImage500ApplicationProblemPlusJSONResponse{
+			Code:    oapi.InternalError,
+			Message: "failed to import disk image",
+		}, nil
+	}
+	log.InfoContext(ctx, "disk image imported", "name", img.Name, "digest", img.Digest)
+	return oapi.ImportDiskImage201JSONResponse(imageToOAPI(*img)), nil
+}
+
+// ExportImage streams an image as an OCI archive or as its converted disk
+// Note: Resolution is handled by ResolveResource middleware
+func (s *ApiService) ExportImage(ctx context.Context, request oapi.ExportImageRequestObject) (oapi.ExportImageResponseObject, error) {
+	img := mw.GetResolvedImage[images.Image](ctx)
+	if img == nil {
+		return oapi.ExportImage500ApplicationProblemPlusJSONResponse{
+			Code:    oapi.InternalError,
+			Message: "resource not resolved",
+		}, nil
+	}
+
+	exportType := images.ExportOCI
+	if request.Params.Format != nil && *request.Params.Format == oapi.ExportFormatDisk {
+		exportType = images.ExportDisk
+	}
+	rc, size, err := s.ImageManager.ExportImage(ctx, img.Name, exportType)
+	if err != nil {
+		switch {
+		case errors.Is(err, images.ErrNotFound):
+			return oapi.ExportImage404ApplicationProblemPlusJSONResponse{
+				Code:    oapi.NotFound,
+				Message: err.Error(),
+			}, nil
+		case errors.Is(err, images.ErrNotReady):
+			return oapi.ExportImage409ApplicationProblemPlusJSONResponse{
+				Code:    oapi.Conflict,
+				Message: err.Error(),
+			}, nil
+		default:
+			logger.FromContext(ctx).ErrorContext(ctx, "failed to export image", "error", err, "format", exportType)
+			return oapi.ExportImage500ApplicationProblemPlusJSONResponse{
+				Code:    oapi.InternalError,
+				Message: "failed to export image",
+			}, nil
+		}
+	}
+
+	if exportType == images.ExportDisk {
+		return oapi.ExportImage200ApplicationoctetStreamResponse{Body: rc, ContentLength: size}, nil
+	}
+	// The archive is built as it streams, so its length isn't known
+	return oapi.ExportImage200ApplicationxTarResponse{Body: rc}, nil
+}
+
 // GetImage gets image details by name
 // Note: Resolution is handled by ResolveResource middleware
 func (s *ApiService) GetImage(ctx context.Context, request oapi.GetImageRequestObject) (oapi.GetImageResponseObject, error) {
 	img := mw.GetResolvedImage[images.Image](ctx)
 	if img == nil {
-		return oapi.GetImage500JSONResponse{
-			Code:    "internal_error",
+		return oapi.GetImage500ApplicationProblemPlusJSONResponse{
+			Code:    oapi.InternalError,
 			Message: "resource not resolved",
 		}, nil
 	}
@@ -79,24 +332,197 @@ func (s *ApiService) GetImage(ctx context.Context, request oapi.GetImageRequestO
 func (s *ApiService) DeleteImage(ctx context.Context, request oapi.DeleteImageRequestObject) (oapi.DeleteImageResponseObject, error) {
 	img := mw.GetResolvedImage[images.Image](ctx)
 	if img == nil {
-		return oapi.DeleteImage500JSONResponse{
-			Code:    "internal_error",
+		return oapi.DeleteImage500ApplicationProblemPlusJSONResponse{
+			Code:    oapi.InternalError,
 			Message: "resource not resolved",
 		}, nil
 	}
 	log := logger.FromContext(ctx)
 
+	// Shared images may be in use by other tenants
+	if img.Tenant == "" && mw.TenantFromContext(ctx) != "" {
+		return oapi.DeleteImage403ApplicationProblemPlusJSONResponse{
+			Code:    oapi.Forbidden,
+			Message: "shared images can only be deleted by an unscoped caller",
+		}, nil
+	}
+
+	if request.Params.DryRun != nil && *request.Params.DryRun {
+		return oapi.DeleteImage200JSONResponse(dryRunResult(oapi.ApplyResourceKindImage, oapi.ApplyDelete, img.Name, img.Digest, nil)), nil
+	}
+
 	err := s.ImageManager.DeleteImage(ctx, img.Name)
 	if err != nil {
 		log.ErrorContext(ctx, "failed to delete image", "error", err)
-		return oapi.DeleteImage500JSONResponse{
-			Code:    "internal_error",
+		return oapi.DeleteImage500ApplicationProblemPlusJSONResponse{
+			Code:    oapi.InternalError,
 			Message: "failed to delete image",
 		}, nil
 	}
 	return oapi.DeleteImage204Response{}, nil
 }
 
+// ListStaleImages lists images whose disks should be re-converted
+func (s *ApiService) ListStaleImages(ctx context.Context, request oapi.ListStaleImagesRequestObject) (oapi.ListStaleImagesResponseObject, error) {
+	if mw.TenantFromContext(ctx) != "" {
+		return oapi.ListStaleImages403ApplicationProblemPlusJSONResponse{
+			Code:    oapi.Forbidden,
+			Message: "stale images span all tenants and are not listed for tenant-scoped principals",
+		}, nil
+	}
+
+	stale, err := s.ImageManager.ListStaleImages(ctx)
+	if err != nil {
+		logger.FromContext(ctx).ErrorContext(ctx, "failed to list stale images", "error", err)
+		return oapi.ListStaleImages500ApplicationProblemPlusJSONResponse{
+			Code:    oapi.InternalError,
+			Message: "failed to list stale images",
+		}, nil
+	}
+
+	out := make([]oapi.Image, len(stale))
+	for i, img := range stale {
+		out[i] = imageToOAPI(img)
+	}
+	return oapi.ListStaleImages200JSONResponse(out), nil
+}
+
+// ReconvertImages queues re-conversion of image disks in the background
+func (s *ApiService) ReconvertImages(ctx context.Context, request oapi.ReconvertImagesRequestObject) (oapi.ReconvertImagesResponseObject, error) {
+	log := logger.FromContext(ctx)
+
+	if mw.TenantFromContext(ctx) != "" {
+		return oapi.ReconvertImages403ApplicationProblemPlusJSONResponse{
+			Code:    oapi.Forbidden,
+			Message: "re-conversion applies to all tenants and is not permitted for tenant-scoped principals",
+		}, nil
+	}
+
+	var names []string
+	if request.Body != nil && request.Body.Images != nil {
+		names = *request.Body.Images
+	}
+	queued, err := s.ImageManager.ReconvertImages(ctx, names)
+	if err != nil {
+		switch {
+		case errors.Is(err, images.ErrInvalidName):
+			return oapi.ReconvertImages400ApplicationProblemPlusJSONResponse{
+				Code:    oapi.InvalidRequest,
+				Message: err.Error(),
+			}, nil
+		case errors.Is(err, images.ErrNotFound):
+			return oapi.ReconvertImages404ApplicationProblemPlusJSONResponse{
+				Code:    oapi.NotFound,
+				Message: err.Error(),
+			}, nil
+		case errors.Is(err, images.ErrNotReady):
+			return oapi.ReconvertImages409ApplicationProblemPlusJSONResponse{
+				Code:    oapi.Conflict,
+				Message: err.Error(),
+			}, nil
+		default:
+			log.ErrorContext(ctx, "failed to queue image re-conversion", "error", err)
+			return oapi.ReconvertImages500ApplicationProblemPlusJSONResponse{
+				Code:    oapi.InternalError,
+				Message: "failed to queue image re-conversion",
+			}, nil
+		}
+	}
+	log.InfoContext(ctx, "queued image re-conversion", "count", len(queued))
+
+	out := make([]oapi.Image, len(queued))
+	for i, img := range queued {
+		out[i] = imageToOAPI(img)
+	}
+	return oapi.ReconvertImages202JSONResponse(out), nil
+}
+
+// PrefetchImages resolves and queues a batch of images as one job
+func (s *ApiService) PrefetchImages(ctx context.Context, request oapi.PrefetchImagesRequestObject) (oapi.PrefetchImagesResponseObject, error) {
+	log := logger.FromContext(ctx)
+
+	tenant, err := mw.TenantForCreate(ctx, request.Body.Tenant)
+	if err != nil {
+		return oapi.PrefetchImages403ApplicationProblemPlusJSONResponse{
+			Code:    oapi.Forbidden,
+			Message: err.Error(),
+		}, nil
+	}
+
+	domainReq := images.PrefetchRequest{Tenant: tenant}
+	for _, img := range request.Body.Images {
+		item := images.PrefetchImageRequest{Name: img.Name}
+		if img.Priority != nil {
+			item.Priority = *img.Priority
+		}
+		domainReq.Images = append(domainReq.Images, item)
+	}
+
+	job, err := s.ImageManager.Prefetch(ctx, domainReq)
+	if err != nil {
+		if errors.Is(err, images.ErrInvalidName) {
+			return oapi.PrefetchImages400ApplicationProblemPlusJSONResponse{
+				Code:    oapi.InvalidRequest,
+				Message: err.Error(),
+			}, nil
+		}
+		log.ErrorContext(ctx, "failed to prefetch images", "error", err)
+		return oapi.PrefetchImages500ApplicationProblemPlusJSONResponse{
+			Code:    oapi.InternalError,
+			Message: "failed to prefetch images",
+		}, nil
+	}
+	return oapi.PrefetchImages202JSONResponse(prefetchJobToOAPI(*job)), nil
+}
+
+// GetPrefetchJob reports the progress of a prefetch job
+func (s *ApiService) GetPrefetchJob(ctx context.Context, request oapi.GetPrefetchJobRequestObject) (oapi.GetPrefetchJobResponseObject, error) {
+	job, err := s.ImageManager.GetPrefetchJob(ctx, request.Id)
+	if err == nil && !mw.TenantVisible(ctx, job.Tenant) {
+		err = images.ErrNotFound
+	}
+	if err != nil {
+		if errors.Is(err, images.ErrNotFound) {
+			return oapi.GetPrefetchJob404ApplicationProblemPlusJSONResponse{
+				Code:    oapi.NotFound,
+				Message: "prefetch job not found",
+			}, nil
+		}
+		logger.FromContext(ctx).ErrorContext(ctx, "failed to get prefetch job", "error", err, "id", request.Id)
+		return oapi.GetPrefetchJob500ApplicationProblemPlusJSONResponse{
+			Code:    oapi.InternalError,
+			Message: "failed to get prefetch job",
+		}, nil
+	}
+	return oapi.GetPrefetchJob200JSONResponse(prefetchJobToOAPI(*job)), nil
+}
+
+func prefetchJobToOAPI(job images.PrefetchJob) oapi.PrefetchJob {
+	out := oapi.PrefetchJob{
+		Id:        job.ID,
+		Status:    oapi.PrefetchJobStatus(job.Status),
+		Images:    make([]oapi.PrefetchImage, 0, len(job.Images)),
+		CreatedAt: job.CreatedAt,
+	}
+	if job.Tenant != "" {
+		out.Tenant = &job.Tenant
+	}
+	for _, img := range job.Images {
+		item := oapi.PrefetchImage{
+			Name:          img.Name,
+			Priority:      img.Priority,
+			Status:        oapi.PrefetchImageStatus(img.Status),
+			QueuePosition: img.QueuePosition,
+			Error:         img.Error,
+		}
+		if img.Digest != "" {
+			item.Digest = &img.Digest
+		}
+		out.Images = append(out.Images, item)
+	}
+	return out
+}
+
 func imageToOAPI(img images.Image) oapi.Image {
 	oapiImg := oapi.Image{
 		Name:          img.Name,
@@ -106,6 +532,9 @@ func imageToOAPI(img images.Image) oapi.Image {
 		Error:         img.Error,
 		SizeBytes:     img.SizeBytes,
 		CreatedAt:     img.CreatedAt,
+
+		LatestDigest:    img.LatestDigest,
+		UpdateCheckedAt: img.UpdateCheckedAt,
 	}
 
 	if len(img.Entrypoint) > 0 {
@@ -120,6 +549,20 @@ func imageToOAPI(img images.Image) oapi.Image {
 	if img.WorkingDir != "" {
 		oapiImg.WorkingDir = &img.WorkingDir
 	}
+	if img.Tenant != "" {
+		oapiImg.Tenant = &img.Tenant
+	}
+	if img.KernelVersion != "" {
+		oapiImg.KernelVersion = &img.KernelVersion
+	}
+	if img.Firmware != "" {
+		firmware := oapi.Firmware(img.Firmware)
+		oapiImg.Firmware = &firmware
+	}
+	if img.Status == images.StatusReady {
+		oapiImg.ConverterVersion = &img.ConverterVersion
+		oapiImg.Stale = &img.Stale
+	}
 
 	return oapiImg
 }
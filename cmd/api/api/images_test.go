@@ -1,13 +1,20 @@
 package api
 
 import (
+	"bytes"
 	"fmt"
+	"io"
+	"mime/multipart"
 	"strings"
 	"testing"
 	"time"
 
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/tarball"
 	"github.com/kernel/hypeman/lib/images"
 	"github.com/kernel/hypeman/lib/oapi"
+	"github.com/samber/lo"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -138,11 +145,11 @@ func TestCreateImage_InvalidTag(t *testing.T) {
 
 	// With go-containerregistry, manifest validation happens synchronously
 	// Invalid tags fail immediately with 404 (manifest not found)
-	errorResp, ok := createResp.(oapi.CreateImage404JSONResponse)
+	errorResp, ok := createResp.(oapi.CreateImage404ApplicationProblemPlusJSONResponse)
 	require.True(t, ok, "expected 404 not found response for invalid tag")
 
 	errObj := oapi.Error(errorResp)
-	require.Equal(t, "not_found", errObj.Code)
+	require.Equal(t, oapi.NotFound, errObj.Code)
 	t.Logf("Got expected error: code=%s message=%s", errObj.Code, errObj.Message)
 }
 
@@ -163,9 +170,9 @@ func TestCreateImage_InvalidName(t *testing.T) {
 			})
 			require.NoError(t, err)
 
-			badReq, ok := createResp.(oapi.CreateImage400JSONResponse)
+			badReq, ok := createResp.(oapi.CreateImage400ApplicationProblemPlusJSONResponse)
 			require.True(t, ok, "expected 400 bad request for invalid name: %s", name)
-			require.Equal(t, "invalid_name", badReq.Code)
+			require.Equal(t, oapi.InvalidRequest, badReq.Code)
 		})
 	}
 }
@@ -304,3 +311,65 @@ func formatQueuePos(pos *int) string {
 	}
 	return fmt.Sprintf("%d", *pos)
 }
+
+func TestImportAndExportImage(t *testing.T) {
+	svc := newTestService(t)
+
+	img, err := random.Image(1024, 1)
+	require.NoError(t, err)
+	var archive bytes.Buffer
+	require.NoError(t, tarball.Write(name.MustParseReference("app:v1"), img, &archive))
+
+	form := func(fields map[string]string, archive []byte) *multipart.Reader {
+		var body bytes.Buffer
+		w := multipart.NewWriter(&body)
+		for k, v := range fields {
+			require.NoError(t, w.WriteField(k, v))
+		}
+		if archive != nil {
+			part, err := w.CreateFormFile("archive", "app.tar")
+			require.NoError(t, err)
+			_, err = part.Write(archive)
+			require.NoError(t, err)
+		}
+		require.NoError(t, w.Close())
+		return multipart.NewReader(&body, w.Boundary())
+	}
+
+	resp, err := svc.ImportImage(ctx(), oapi.ImportImageRequestObject{Body: form(map[string]string{"name": "app:v1"}, nil)})
+	require.NoError(t, err)
+	assert.IsType(t, oapi.ImportImage400ApplicationProblemPlusJSONResponse{}, resp)
+
+	resp, err = svc.ImportImage(ctx(), oapi.ImportImageRequestObject{Body: form(nil, []byte("not a tarball"))})
+	require.NoError(t, err)
+	assert.IsType(t, oapi.ImportImage400ApplicationProblemPlusJSONResponse{}, resp)
+
+	resp, err = svc.ImportImage(ctx(), oapi.ImportImageRequestObject{Body: form(map[string]string{"name": "app:v1"}, archive.Bytes())})
+	require.NoError(t, err)
+	imported, ok := resp.(oapi.ImportImage202JSONResponse)
+	require.True(t, ok, "expected 202, got %T", resp)
+	assert.Equal(t, "docker.io/library/app:v1", imported.Name)
+
+	require.Eventually(t, func() bool {
+		img, err := svc.ImageManager.GetImage(ctx(), imported.Name)
+		return err == nil && img.Status == images.StatusReady
+	}, 30*time.Second, 100*time.Millisecond)
+
+	exportResp, err := svc.ExportImage(ctxWithImage(svc, imported.Name), oapi.ExportImageRequestObject{Name: imported.Name})
+	require.NoError(t, err)
+	exported, ok := exportResp.(oapi.ExportImage200ApplicationxTarResponse)
+	require.True(t, ok, "expected 200, got %T", exportResp)
+	data, err := io.ReadAll(exported.Body)
+	require.NoError(t, err)
+	assert.NotEmpty(t, data)
+
+	exportResp, err = svc.ExportImage(ctxWithImage(svc, imported.Name), oapi.ExportImageRequestObject{
+		Name:   imported.Name,
+		Params: oapi.ExportImageParams{Format: lo.ToPtr(oapi.ExportFormatDisk)},
+	})
+	require.NoError(t, err)
+	disk, ok := exportResp.(oapi.ExportImage200ApplicationoctetStreamResponse)
+	require.True(t, ok, "expected 200, got %T", exportResp)
+	assert.Positive(t, disk.ContentLength)
+	disk.Body.(io.Closer).Close()
+}
@@ -3,6 +3,7 @@ package api
 import (
 	"context"
 	"errors"
+	"fmt"
 
 	"github.com/kernel/hypeman/lib/ingress"
 	"github.com/kernel/hypeman/lib/logger"
@@ -17,15 +18,18 @@ func (s *ApiService) ListIngresses(ctx context.Context, request oapi.ListIngress
 	ingresses, err := s.IngressManager.List(ctx)
 	if err != nil {
 		log.ErrorContext(ctx, "failed to list ingresses", "error", err)
-		return oapi.ListIngresses500JSONResponse{
-			Code:    "internal_error",
+		return oapi.ListIngresses500ApplicationProblemPlusJSONResponse{
+			Code:    oapi.InternalError,
 			Message: "failed to list ingresses",
 		}, nil
 	}
 
-	oapiIngresses := make([]oapi.Ingress, len(ingresses))
-	for i, ing := range ingresses {
-		oapiIngresses[i] = ingressToOAPI(ing)
+	oapiIngresses := make([]oapi.Ingress, 0, len(ingresses))
+	for _, ing := range ingresses {
+		if !mw.TenantVisible(ctx, ing.Tenant) {
+			continue
+		}
+		oapiIngresses = append(oapiIngresses, ingressToOAPI(ing))
 	}
 
 	return oapi.ListIngresses200JSONResponse(oapiIngresses), nil
@@ -33,16 +37,24 @@ func (s *ApiService) ListIngresses(ctx context.Context, request oapi.ListIngress
 
 // CreateIngress creates a new ingress resource
 func (s *ApiService) CreateIngress(ctx context.Context, request oapi.CreateIngressRequestObject) (oapi.CreateIngressResponseObject, error) {
-	log := logger.FromContext(ctx)
+	tenant, err := mw.TenantForCreate(ctx, request.Body.Tenant)
+	if err != nil {
+		return oapi.CreateIngress403ApplicationProblemPlusJSONResponse{
+			Code:    oapi.Forbidden,
+			Message: err.Error(),
+		}, nil
+	}
 
 	// Convert OAPI request to domain request
 	domainReq := ingress.CreateIngressRequest{
-		Name:  request.Body.Name,
-		Rules: make([]ingress.IngressRule, len(request.Body.Rules)),
+		Name:   request.Body.Name,
+		Rules:  make([]ingress.IngressRule, len(request.Body.Rules)),
+		Tenant: tenant,
 	}
 
 	for i, rule := range request.Body.Rules {
-		matchPort := 80
+		// Left unset, the domain picks the default (80, or 443 for passthrough)
+		matchPort := 0
 		if rule.Match.Port != nil {
 			matchPort = *rule.Match.Port
 		}
@@ -54,78 +66,114 @@ func (s *ApiService) CreateIngress(ctx context.Context, request oapi.CreateIngre
 		if rule.RedirectHttp != nil {
 			redirectHTTP = *rule.RedirectHttp
 		}
+		restoreOnDemand := false
+		if rule.Target.RestoreOnDemand != nil {
+			restoreOnDemand = *rule.Target.RestoreOnDemand
+		}
+		tlsPassthrough := false
+		if rule.TlsPassthrough != nil {
+			tlsPassthrough = *rule.TlsPassthrough
+		}
 		domainReq.Rules[i] = ingress.IngressRule{
 			Match: ingress.IngressMatch{
 				Hostname: rule.Match.Hostname,
 				Port:     matchPort,
 			},
 			Target: ingress.IngressTarget{
-				Instance: rule.Target.Instance,
-				Port:     rule.Target.Port,
+				Instance:        rule.Target.Instance,
+				Port:            rule.Target.Port,
+				RestoreOnDemand: restoreOnDemand,
 			},
-			TLS:          tlsEnabled,
-			RedirectHTTP: redirectHTTP,
+			TLS:            tlsEnabled,
+			RedirectHTTP:   redirectHTTP,
+			TLSPassthrough: tlsPassthrough,
+		}
+
+		// Ingresses may only route to instances visible to the caller
+		if inst, err := s.InstanceManager.GetInstance(ctx, rule.Target.Instance); err == nil && !mw.TenantVisible(ctx, inst.Tenant) {
+			return oapi.CreateIngress403ApplicationProblemPlusJSONResponse{
+				Code:    oapi.Forbidden,
+				Message: "target instance " + rule.Target.Instance + " belongs to another tenant",
+			}, nil
 		}
 	}
 
+	if request.Params.DryRun != nil && *request.Params.DryRun {
+		preview, err := s.IngressManager.CheckCreate(ctx, domainReq)
+		if err != nil {
+			return createIngressError(ctx, err, request.Body.Name), nil
+		}
+		var details []string
+		for _, rule := range preview.Rules {
+			details = append(details, fmt.Sprintf("%s:%d -> %s:%d", rule.Match.Hostname, rule.Match.GetPort(), rule.Target.Instance, rule.Target.Port))
+		}
+		return oapi.CreateIngress200JSONResponse(dryRunResult(oapi.ApplyResourceKindIngress, oapi.ApplyCreate, preview.Name, "", details)), nil
+	}
+
 	ing, err := s.IngressManager.Create(ctx, domainReq)
 	if err != nil {
-		switch {
-		case errors.Is(err, ingress.ErrInvalidRequest):
-			return oapi.CreateIngress400JSONResponse{
-				Code:    "bad_request",
-				Message: err.Error(),
-			}, nil
-		case errors.Is(err, ingress.ErrAlreadyExists):
-			return oapi.CreateIngress409JSONResponse{
-				Code:    "already_exists",
-				Message: err.Error(),
-			}, nil
-		case errors.Is(err, ingress.ErrHostnameInUse):
-			return oapi.CreateIngress409JSONResponse{
-				Code:    "hostname_in_use",
-				Message: err.Error(),
-			}, nil
-		case errors.Is(err, ingress.ErrPortInUse):
-			return oapi.CreateIngress409JSONResponse{
-				Code:    "port_in_use",
-				Message: err.Error(),
-			}, nil
-		case errors.Is(err, ingress.ErrInstanceNotFound):
-			return oapi.CreateIngress400JSONResponse{
-				Code:    "instance_not_found",
-				Message: err.Error(),
-			}, nil
-		case errors.Is(err, ingress.ErrDomainNotAllowed):
-			return oapi.CreateIngress400JSONResponse{
-				Code:    "domain_not_allowed",
-				Message: err.Error(),
-			}, nil
-		case errors.Is(err, ingress.ErrConfigValidationFailed):
-			log.ErrorContext(ctx, "failed to create ingress", "error", err, "name", request.Body.Name)
-			return oapi.CreateIngress400JSONResponse{
-				Code:    "config_validation_failed",
-				Message: err.Error(),
-			}, nil
-		default:
-			log.ErrorContext(ctx, "failed to create ingress", "error", err, "name", request.Body.Name)
-			return oapi.CreateIngress500JSONResponse{
-				Code:    "internal_error",
-				Message: "failed to create ingress",
-			}, nil
-		}
+		return createIngressError(ctx, err, request.Body.Name), nil
 	}
 
 	return oapi.CreateIngress201JSONResponse(ingressToOAPI(*ing)), nil
 }
 
+// createIngressError maps an ingress creation or admission error to a response
+func createIngressError(ctx context.Context, err error, name string) oapi.CreateIngressResponseObject {
+	log := logger.FromContext(ctx)
+	switch {
+	case errors.Is(err, ingress.ErrInvalidRequest):
+		return oapi.CreateIngress400ApplicationProblemPlusJSONResponse{
+			Code:    oapi.InvalidRequest,
+			Message: err.Error(),
+		}
+	case errors.Is(err, ingress.ErrAlreadyExists):
+		return oapi.CreateIngress409ApplicationProblemPlusJSONResponse{
+			Code:    oapi.AlreadyExists,
+			Message: err.Error(),
+		}
+	case errors.Is(err, ingress.ErrHostnameInUse):
+		return oapi.CreateIngress409ApplicationProblemPlusJSONResponse{
+			Code:    oapi.Conflict,
+			Message: err.Error(),
+		}
+	case errors.Is(err, ingress.ErrPortInUse):
+		return oapi.CreateIngress409ApplicationProblemPlusJSONResponse{
+			Code:    oapi.Conflict,
+			Message: err.Error(),
+		}
+	case errors.Is(err, ingress.ErrInstanceNotFound):
+		return oapi.CreateIngress400ApplicationProblemPlusJSONResponse{
+			Code:    oapi.InvalidRequest,
+			Message: err.Error(),
+		}
+	case errors.Is(err, ingress.ErrDomainNotAllowed):
+		return oapi.CreateIngress400ApplicationProblemPlusJSONResponse{
+			Code:    oapi.InvalidRequest,
+			Message: err.Error(),
+		}
+	case errors.Is(err, ingress.ErrConfigValidationFailed):
+		log.ErrorContext(ctx, "failed to create ingress", "error", err, "name", name)
+		return oapi.CreateIngress400ApplicationProblemPlusJSONResponse{
+			Code:    oapi.InvalidRequest,
+			Message: err.Error(),
+		}
+	default:
+		log.ErrorContext(ctx, "failed to create ingress", "error", err, "name", name)
+		return oapi.CreateIngress500ApplicationProblemPlusJSONResponse{
+			Code:    oapi.InternalError,
+			Message: "failed to create ingress",
+		}
+	}
+}
+
 // GetIngress gets ingress details by ID, name, or ID prefix
 // Note: Resolution is handled by ResolveResource middleware
 func (s *ApiService) GetIngress(ctx context.Context, request oapi.GetIngressRequestObject) (oapi.GetIngressResponseObject, error) {
 	ing := mw.GetResolvedIngress[ingress.Ingress](ctx)
 	if ing == nil {
-		return oapi.GetIngress500JSONResponse{
-			Code:    "internal_error",
+		return oapi.GetIngress500ApplicationProblemPlusJSONResponse{
+			Code:    oapi.InternalError,
 			Message: "resource not resolved",
 		}, nil
 	}
@@ -137,18 +185,22 @@ func (s *ApiService) GetIngress(ctx context.Context, request oapi.GetIngressRequ
 func (s *ApiService) DeleteIngress(ctx context.Context, request oapi.DeleteIngressRequestObject) (oapi.DeleteIngressResponseObject, error) {
 	ing := mw.GetResolvedIngress[ingress.Ingress](ctx)
 	if ing == nil {
-		return oapi.DeleteIngress500JSONResponse{
-			Code:    "internal_error",
+		return oapi.DeleteIngress500ApplicationProblemPlusJSONResponse{
+			Code:    oapi.InternalError,
 			Message: "resource not resolved",
 		}, nil
 	}
 	log := logger.FromContext(ctx)
 
+	if request.Params.DryRun != nil && *request.Params.DryRun {
+		return oapi.DeleteIngress200JSONResponse(dryRunResult(oapi.ApplyResourceKindIngress, oapi.ApplyDelete, ing.Name, ing.ID, nil)), nil
+	}
+
 	err := s.IngressManager.Delete(ctx, ing.ID)
 	if err != nil {
 		log.ErrorContext(ctx, "failed to delete ingress", "error", err)
-		return oapi.DeleteIngress500JSONResponse{
-			Code:    "internal_error",
+		return oapi.DeleteIngress500ApplicationProblemPlusJSONResponse{
+			Code:    oapi.InternalError,
 			Message: "failed to delete ingress",
 		}, nil
 	}
@@ -163,24 +215,32 @@ func ingressToOAPI(ing ingress.Ingress) oapi.Ingress {
 		port := rule.Match.GetPort()
 		tls := rule.TLS
 		redirectHTTP := rule.RedirectHTTP
+		restoreOnDemand := rule.Target.RestoreOnDemand
+		tlsPassthrough := rule.TLSPassthrough
 		rules[i] = oapi.IngressRule{
 			Match: oapi.IngressMatch{
 				Hostname: rule.Match.Hostname,
 				Port:     &port,
 			},
 			Target: oapi.IngressTarget{
-				Instance: rule.Target.Instance,
-				Port:     rule.Target.Port,
+				Instance:        rule.Target.Instance,
+				Port:            rule.Target.Port,
+				RestoreOnDemand: &restoreOnDemand,
 			},
-			Tls:          &tls,
-			RedirectHttp: &redirectHTTP,
+			Tls:            &tls,
+			RedirectHttp:   &redirectHTTP,
+			TlsPassthrough: &tlsPassthrough,
 		}
 	}
 
-	return oapi.Ingress{
+	oapiIng := oapi.Ingress{
 		Id:        ing.ID,
 		Name:      ing.Name,
 		Rules:     rules,
 		CreatedAt: ing.CreatedAt,
 	}
+	if ing.Tenant != "" {
+		oapiIng.Tenant = &ing.Tenant
+	}
+	return oapiIng
 }
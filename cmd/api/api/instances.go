@@ -2,21 +2,30 @@ package api
 
 import (
 	"context"
+	"encoding/base64"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"net/http"
+	"os"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/c2h5oh/datasize"
+	"github.com/kernel/hypeman/lib/devices"
 	"github.com/kernel/hypeman/lib/guest"
 	"github.com/kernel/hypeman/lib/hypervisor"
+	"github.com/kernel/hypeman/lib/images"
 	"github.com/kernel/hypeman/lib/instances"
 	"github.com/kernel/hypeman/lib/logger"
 	mw "github.com/kernel/hypeman/lib/middleware"
 	"github.com/kernel/hypeman/lib/network"
+	"github.com/kernel/hypeman/lib/nodeagent"
 	"github.com/kernel/hypeman/lib/oapi"
 	"github.com/kernel/hypeman/lib/resources"
+	"github.com/kernel/hypeman/lib/secrets"
+	"github.com/kernel/hypeman/lib/volumes"
 	"github.com/samber/lo"
 )
 
@@ -27,15 +36,18 @@ func (s *ApiService) ListInstances(ctx context.Context, request oapi.ListInstanc
 	domainInsts, err := s.InstanceManager.ListInstances(ctx)
 	if err != nil {
 		log.ErrorContext(ctx, "failed to list instances", "error", err)
-		return oapi.ListInstances500JSONResponse{
-			Code:    "internal_error",
+		return oapi.ListInstances500ApplicationProblemPlusJSONResponse{
+			Code:    oapi.InternalError,
 			Message: "failed to list instances",
 		}, nil
 	}
 
-	oapiInsts := make([]oapi.Instance, len(domainInsts))
-	for i, inst := range domainInsts {
-		oapiInsts[i] = instanceToOAPI(inst)
+	oapiInsts := make([]oapi.Instance, 0, len(domainInsts))
+	for _, inst := range domainInsts {
+		if !mw.TenantVisible(ctx, inst.Tenant) {
+			continue
+		}
+		oapiInsts = append(oapiInsts, instanceToOAPI(inst))
 	}
 
 	return oapi.ListInstances200JSONResponse(oapiInsts), nil
@@ -43,15 +55,27 @@ func (s *ApiService) ListInstances(ctx context.Context, request oapi.ListInstanc
 
 // CreateInstance creates and starts a new instance
 func (s *ApiService) CreateInstance(ctx context.Context, request oapi.CreateInstanceRequestObject) (oapi.CreateInstanceResponseObject, error) {
-	log := logger.FromContext(ctx)
+	tenant, err := mw.TenantForCreate(ctx, request.Body.Tenant)
+	if err != nil {
+		return oapi.CreateInstance403ApplicationProblemPlusJSONResponse{
+			Code:    oapi.Forbidden,
+			Message: err.Error(),
+		}, nil
+	}
+	if request.Body.UsbDevices != nil && len(*request.Body.UsbDevices) > 0 && !mw.RoleAllowed(ctx, mw.RoleAdmin) {
+		return oapi.CreateInstance403ApplicationProblemPlusJSONResponse{
+			Code:    oapi.Forbidden,
+			Message: "USB device passthrough requires the admin role",
+		}, nil
+	}
 
 	// Parse size (default: 1GB)
 	size := int64(0)
 	if request.Body.Size != nil && *request.Body.Size != "" {
 		var sizeBytes datasize.ByteSize
 		if err := sizeBytes.UnmarshalText([]byte(*request.Body.Size)); err != nil {
-			return oapi.CreateInstance400JSONResponse{
-				Code:    "invalid_size",
+			return oapi.CreateInstance400ApplicationProblemPlusJSONResponse{
+				Code:    oapi.InvalidRequest,
 				Message: fmt.Sprintf("invalid size format: %v", err),
 			}, nil
 		}
@@ -63,8 +87,8 @@ func (s *ApiService) CreateInstance(ctx context.Context, request oapi.CreateInst
 	if request.Body.HotplugSize != nil && *request.Body.HotplugSize != "" {
 		var hotplugBytes datasize.ByteSize
 		if err := hotplugBytes.UnmarshalText([]byte(*request.Body.HotplugSize)); err != nil {
-			return oapi.CreateInstance400JSONResponse{
-				Code:    "invalid_hotplug_size",
+			return oapi.CreateInstance400ApplicationProblemPlusJSONResponse{
+				Code:    oapi.InvalidRequest,
 				Message: fmt.Sprintf("invalid hotplug_size format: %v", err),
 			}, nil
 		}
@@ -76,14 +100,27 @@ func (s *ApiService) CreateInstance(ctx context.Context, request oapi.CreateInst
 	if request.Body.OverlaySize != nil && *request.Body.OverlaySize != "" {
 		var overlayBytes datasize.ByteSize
 		if err := overlayBytes.UnmarshalText([]byte(*request.Body.OverlaySize)); err != nil {
-			return oapi.CreateInstance400JSONResponse{
-				Code:    "invalid_overlay_size",
+			return oapi.CreateInstance400ApplicationProblemPlusJSONResponse{
+				Code:    oapi.InvalidRequest,
 				Message: fmt.Sprintf("invalid overlay_size format: %v", err),
 			}, nil
 		}
 		overlaySize = int64(overlayBytes)
 	}
 
+	// Parse swap_size (0 = no swap)
+	swapSize := int64(0)
+	if request.Body.SwapSize != nil && *request.Body.SwapSize != "" {
+		var swapBytes datasize.ByteSize
+		if err := swapBytes.UnmarshalText([]byte(*request.Body.SwapSize)); err != nil {
+			return oapi.CreateInstance400ApplicationProblemPlusJSONResponse{
+				Code:    oapi.InvalidRequest,
+				Message: fmt.Sprintf("invalid swap_size format: %v", err),
+			}, nil
+		}
+		swapSize = int64(swapBytes)
+	}
+
 	// Parse disk_io_bps (0 = auto/unlimited)
 	diskIOBps := int64(0)
 	if request.Body.DiskIoBps != nil && *request.Body.DiskIoBps != "" {
@@ -93,8 +130,8 @@ func (s *ApiService) CreateInstance(ctx context.Context, request oapi.CreateInst
 		ioStr = strings.TrimSuffix(ioStr, "/s")
 		ioStr = strings.TrimSuffix(ioStr, "ps")
 		if err := ioBpsBytes.UnmarshalText([]byte(ioStr)); err != nil {
-			return oapi.CreateInstance400JSONResponse{
-				Code:    "invalid_disk_io_bps",
+			return oapi.CreateInstance400ApplicationProblemPlusJSONResponse{
+				Code:    oapi.InvalidRequest,
 				Message: fmt.Sprintf("invalid disk_io_bps format: %v", err),
 			}, nil
 		}
@@ -111,6 +148,16 @@ func (s *ApiService) CreateInstance(ctx context.Context, request oapi.CreateInst
 		env = *request.Body.Env
 	}
 
+	// Workload overrides (nil = use the image's values)
+	var entrypoint, cmd []string
+	if request.Body.Entrypoint != nil {
+		entrypoint = *request.Body.Entrypoint
+	}
+	if request.Body.Cmd != nil {
+		cmd = *request.Body.Cmd
+	}
+	workdir := lo.FromPtr(request.Body.Workdir)
+
 	// Parse network enabled (default: true)
 	networkEnabled := true
 	if request.Body.Network != nil && request.Body.Network.Enabled != nil {
@@ -125,8 +172,8 @@ func (s *ApiService) CreateInstance(ctx context.Context, request oapi.CreateInst
 		if request.Body.Network.BandwidthDownload != nil && *request.Body.Network.BandwidthDownload != "" {
 			bw, err := resources.ParseBandwidth(*request.Body.Network.BandwidthDownload)
 			if err != nil {
-				return oapi.CreateInstance400JSONResponse{
-					Code:    "invalid_bandwidth_download",
+				return oapi.CreateInstance400ApplicationProblemPlusJSONResponse{
+					Code:    oapi.InvalidRequest,
 					Message: fmt.Sprintf("invalid bandwidth_download format: %v", err),
 				}, nil
 			}
@@ -135,8 +182,8 @@ func (s *ApiService) CreateInstance(ctx context.Context, request oapi.CreateInst
 		if request.Body.Network.BandwidthUpload != nil && *request.Body.Network.BandwidthUpload != "" {
 			bw, err := resources.ParseBandwidth(*request.Body.Network.BandwidthUpload)
 			if err != nil {
-				return oapi.CreateInstance400JSONResponse{
-					Code:    "invalid_bandwidth_upload",
+				return oapi.CreateInstance400ApplicationProblemPlusJSONResponse{
+					Code:    oapi.InvalidRequest,
 					Message: fmt.Sprintf("invalid bandwidth_upload format: %v", err),
 				}, nil
 			}
@@ -144,6 +191,31 @@ func (s *ApiService) CreateInstance(ctx context.Context, request oapi.CreateInst
 		}
 	}
 
+	// Parse network burst sizes (0 = default)
+	var networkDownloadBurst, networkUploadBurst int64
+	if request.Body.Network != nil {
+		if request.Body.Network.BandwidthDownloadBurst != nil && *request.Body.Network.BandwidthDownloadBurst != "" {
+			var burst datasize.ByteSize
+			if err := burst.UnmarshalText([]byte(*request.Body.Network.BandwidthDownloadBurst)); err != nil {
+				return oapi.CreateInstance400ApplicationProblemPlusJSONResponse{
+					Code:    oapi.InvalidRequest,
+					Message: fmt.Sprintf("invalid bandwidth_download_burst format: %v", err),
+				}, nil
+			}
+			networkDownloadBurst = int64(burst)
+		}
+		if request.Body.Network.BandwidthUploadBurst != nil && *request.Body.Network.BandwidthUploadBurst != "" {
+			var burst datasize.ByteSize
+			if err := burst.UnmarshalText([]byte(*request.Body.Network.BandwidthUploadBurst)); err != nil {
+				return oapi.CreateInstance400ApplicationProblemPlusJSONResponse{
+					Code:    oapi.InvalidRequest,
+					Message: fmt.Sprintf("invalid bandwidth_upload_burst format: %v", err),
+				}, nil
+			}
+			networkUploadBurst = int64(burst)
+		}
+	}
+
 	// Parse devices (GPU passthrough)
 	var deviceRefs []string
 	if request.Body.Devices != nil {
@@ -167,8 +239,8 @@ func (s *ApiService) CreateInstance(ctx context.Context, request oapi.CreateInst
 			if vol.OverlaySize != nil && *vol.OverlaySize != "" {
 				var overlaySizeBytes datasize.ByteSize
 				if err := overlaySizeBytes.UnmarshalText([]byte(*vol.OverlaySize)); err != nil {
-					return oapi.CreateInstance400JSONResponse{
-						Code:    "invalid_overlay_size",
+					return oapi.CreateInstance400ApplicationProblemPlusJSONResponse{
+						Code:    oapi.InvalidRequest,
 						Message: fmt.Sprintf("invalid overlay_size for volume %s: %v", vol.VolumeId, err),
 					}, nil
 				}
@@ -184,6 +256,42 @@ func (s *ApiService) CreateInstance(ctx context.Context, request oapi.CreateInst
 		}
 	}
 
+	// Parse scratch disks
+	var scratchDisks *instances.ScratchDisks
+	if sd := request.Body.ScratchDisks; sd != nil {
+		var scratchSize datasize.ByteSize
+		if err := scratchSize.UnmarshalText([]byte(sd.Size)); err != nil {
+			return oapi.CreateInstance400ApplicationProblemPlusJSONResponse{
+				Code:    oapi.InvalidRequest,
+				Message: fmt.Sprintf("invalid scratch_disks size: %v", err),
+			}, nil
+		}
+		scratchDisks = &instances.ScratchDisks{Count: sd.Count, Size: int64(scratchSize)}
+	}
+
+	// Parse shared directories
+	var sharedDirectories []instances.SharedDirectory
+	if request.Body.SharedDirectories != nil {
+		for _, dir := range *request.Body.SharedDirectories {
+			sharedDirectories = append(sharedDirectories, instances.SharedDirectory{
+				HostPath:  dir.HostPath,
+				MountPath: dir.MountPath,
+				Readonly:  dir.Readonly != nil && *dir.Readonly,
+			})
+		}
+	}
+
+	// Parse secrets
+	var secretAttachments []instances.SecretAttachment
+	if request.Body.Secrets != nil {
+		for _, att := range *request.Body.Secrets {
+			secretAttachments = append(secretAttachments, instances.SecretAttachment{
+				SecretID: att.SecretId,
+				EnvVar:   lo.FromPtr(att.EnvVar),
+			})
+		}
+	}
+
 	// Convert hypervisor type from API enum to domain type
 	var hvType hypervisor.Type
 	if request.Body.Hypervisor != nil {
@@ -198,6 +306,112 @@ func (s *ApiService) CreateInstance(ctx context.Context, request oapi.CreateInst
 		}
 	}
 
+	// Parse placement hints
+	var placement *instances.PlacementHints
+	if p := request.Body.Placement; p != nil {
+		placement = &instances.PlacementHints{
+			GPUAffinity:     lo.FromPtr(p.GpuAffinity),
+			GPUAntiAffinity: lo.FromPtr(p.GpuAntiAffinity),
+			NUMANode:        p.NumaNode,
+		}
+		if p.GpuPolicy != nil {
+			placement.GPUPolicy = devices.GPUPlacementPolicy(*p.GpuPolicy)
+		}
+	}
+
+	// Parse user data
+	var userData *instances.UserData
+	if ud := request.Body.UserData; ud != nil {
+		userData = &instances.UserData{}
+		if ud.CloudConfig != nil {
+			userData.CloudConfig = *ud.CloudConfig
+		}
+		if ud.Env != nil {
+			userData.Env = *ud.Env
+		}
+		if ud.Files != nil {
+			for _, f := range *ud.Files {
+				var mode uint64
+				if f.Mode != nil && *f.Mode != "" {
+					var err error
+					if mode, err = strconv.ParseUint(*f.Mode, 8, 32); err != nil {
+						return oapi.CreateInstance400ApplicationProblemPlusJSONResponse{
+							Code:    oapi.InvalidRequest,
+							Message: fmt.Sprintf("invalid mode %q for file %s: must be octal", *f.Mode, f.Path),
+						}, nil
+					}
+				}
+				content := []byte(f.Content)
+				if f.Encoding != nil && *f.Encoding == oapi.Base64 {
+					decoded, err := base64.StdEncoding.DecodeString(f.Content)
+					if err != nil {
+						return oapi.CreateInstance400ApplicationProblemPlusJSONResponse{
+							Code:    oapi.InvalidRequest,
+							Message: fmt.Sprintf("invalid base64 content for file %s: %v", f.Path, err),
+						}, nil
+					}
+					content = decoded
+				}
+				userData.Files = append(userData.Files, instances.UserDataFile{
+					Path:    f.Path,
+					Content: content,
+					Mode:    os.FileMode(mode),
+				})
+			}
+		}
+	}
+
+	// Parse resolv.conf and /etc/hosts options
+	var resolver *instances.ResolverConfig
+	if rc := request.Body.Resolver; rc != nil {
+		resolver = &instances.ResolverConfig{
+			SkipResolvConf: rc.ManageResolvConf != nil && !*rc.ManageResolvConf,
+			SkipHosts:      rc.ManageHosts != nil && !*rc.ManageHosts,
+		}
+		if rc.Nameservers != nil {
+			resolver.Nameservers = *rc.Nameservers
+		}
+		if rc.ExtraHosts != nil {
+			for _, h := range *rc.ExtraHosts {
+				resolver.ExtraHosts = append(resolver.ExtraHosts, instances.HostEntry{Hostname: h.Hostname, IP: h.Ip})
+			}
+		}
+	}
+
+	var ulimits []instances.Ulimit
+	for _, u := range lo.FromPtr(request.Body.Ulimits) {
+		ulimits = append(ulimits, instances.Ulimit{Name: string(u.Name), Soft: u.Soft, Hard: u.Hard})
+	}
+
+	// Parse shutdown grace period override
+	var shutdownGracePeriod *time.Duration
+	if gp := request.Body.ShutdownGracePeriod; gp != nil {
+		d, err := time.ParseDuration(*gp)
+		if err != nil || d < 0 {
+			return oapi.CreateInstance400ApplicationProblemPlusJSONResponse{
+				Code:    oapi.InvalidRequest,
+				Message: fmt.Sprintf("shutdown_grace_period must be a non-negative duration, got %q", *gp),
+			}, nil
+		}
+		shutdownGracePeriod = &d
+	}
+
+	// Parse idle standby overrides
+	var idlePolicy *instances.IdlePolicy
+	if ip := request.Body.IdlePolicy; ip != nil {
+		idlePolicy = &instances.IdlePolicy{WakeOnIngress: ip.WakeOnIngress}
+		if ip.StandbyAfter != nil {
+			d, err := time.ParseDuration(*ip.StandbyAfter)
+			if err != nil {
+				return oapi.CreateInstance400ApplicationProblemPlusJSONResponse{
+					Code:    oapi.InvalidRequest,
+					Message: fmt.Sprintf("invalid standby_after: %v", err),
+				}, nil
+			}
+			idlePolicy.StandbyAfter = &d
+		}
+	}
+
 	// Calculate default resource limits when not specified (0 = auto)
 	// Uses proportional allocation based on CPU: (vcpus / cpuCapacity) * resourceCapacity
 	if diskIOBps == 0 {
@@ -213,61 +427,228 @@ func (s *ApiService) CreateInstance(ctx context.Context, request oapi.CreateInst
 		}
 	}
 
+	// Tenant-scoped callers may only use their own (or shared) images and volumes
+	image := lo.FromPtr(request.Body.Image)
+	if image != "" {
+		if img, err := s.imageForCreate(ctx, image, lo.FromPtr(request.Params.WaitForImage)); err == nil && img.Tenant != "" && !mw.TenantVisible(ctx, img.Tenant) {
+			return oapi.CreateInstance403ApplicationProblemPlusJSONResponse{
+				Code:    oapi.Forbidden,
+				Message: fmt.Sprintf("image %s belongs to another tenant", image),
+			}, nil
+		}
+	}
+	rootVolume := lo.FromPtr(request.Body.RootVolume)
+	if rootVolume != "" {
+		if v, err := s.VolumeManager.GetVolume(ctx, rootVolume); err == nil && !mw.TenantVisible(ctx, v.Tenant) {
+			return oapi.CreateInstance403ApplicationProblemPlusJSONResponse{
+				Code:    oapi.Forbidden,
+				Message: fmt.Sprintf("volume %s belongs to another tenant", rootVolume),
+			}, nil
+		}
+	}
+	for _, vol := range volumes {
+		if v, err := s.VolumeManager.GetVolume(ctx, vol.VolumeID); err == nil && !mw.TenantVisible(ctx, v.Tenant) {
+			return oapi.CreateInstance403ApplicationProblemPlusJSONResponse{
+				Code:    oapi.Forbidden,
+				Message: fmt.Sprintf("volume %s belongs to another tenant", vol.VolumeID),
+			}, nil
+		}
+	}
+	for _, att := range secretAttachments {
+		if secret, err := s.SecretManager.GetSecret(ctx, att.SecretID); err == nil && !mw.TenantVisible(ctx, secret.Tenant) {
+			return oapi.CreateInstance403ApplicationProblemPlusJSONResponse{
+				Code:    oapi.Forbidden,
+				Message: fmt.Sprintf("secret %s belongs to another tenant", att.SecretID),
+			}, nil
+		}
+	}
+
 	domainReq := instances.CreateInstanceRequest{
 		Name:                     request.Body.Name,
-		Image:                    request.Body.Image,
+		Image:                    image,
+		RootVolume:               rootVolume,
 		Size:                     size,
 		HotplugSize:              hotplugSize,
 		OverlaySize:              overlaySize,
+		SwapSize:                 swapSize,
 		Vcpus:                    vcpus,
 		DiskIOBps:                diskIOBps,
 		NetworkBandwidthDownload: networkBandwidthDownload,
 		NetworkBandwidthUpload:   networkBandwidthUpload,
+		NetworkDownloadBurst:     networkDownloadBurst,
+		NetworkUploadBurst:       networkUploadBurst,
 		Env:                      env,
+		Entrypoint:               entrypoint,
+		Cmd:                      cmd,
+		Workdir:                  workdir,
 		NetworkEnabled:           networkEnabled,
 		Devices:                  deviceRefs,
+		USBDevices:               lo.FromPtr(request.Body.UsbDevices),
 		Volumes:                  volumes,
+		ScratchDisks:             scratchDisks,
+		SharedDirectories:        sharedDirectories,
+		Secrets:                  secretAttachments,
+		KernelArgs:               lo.FromPtr(request.Body.KernelArgs),
+		Firmware:                 string(lo.FromPtr(request.Body.Firmware)),
+		Sysctls:                  lo.FromPtr(request.Body.Sysctls),
+		Labels:                   lo.FromPtr(request.Body.Labels),
+		Links:                    lo.FromPtr(request.Body.Links),
+		Ulimits:                  ulimits,
+		EnableNestedVirt:         lo.FromPtr(request.Body.EnableNestedVirt),
+		ShutdownGracePeriod:      shutdownGracePeriod,
 		Hypervisor:               hvType,
 		GPU:                      gpuConfig,
+		Placement:                placement,
+		Tenant:                   tenant,
+		CreatedBy:                mw.CreatorFromContext(ctx),
+		UserData:                 userData,
+		Resolver:                 resolver,
+		IdlePolicy:               idlePolicy,
+	}
+	if request.Body.Schedules != nil {
+		domainReq.Schedules = schedulesFromOAPI(*request.Body.Schedules)
 	}
 
-	inst, err := s.InstanceManager.CreateInstance(ctx, domainReq)
+	// Dry runs check the quota too, releasing it straight away
+	release, err := s.admitQuota(ctx, tenant, s.instanceQuotaRequest(ctx, domainReq))
 	if err != nil {
-		switch {
-		case errors.Is(err, instances.ErrImageNotReady):
-			return oapi.CreateInstance400JSONResponse{
-				Code:    "image_not_ready",
-				Message: err.Error(),
-			}, nil
-		case errors.Is(err, instances.ErrAlreadyExists):
-			return oapi.CreateInstance400JSONResponse{
-				Code:    "already_exists",
-				Message: "instance already exists",
-			}, nil
-		case errors.Is(err, network.ErrNameExists):
-			return oapi.CreateInstance400JSONResponse{
-				Code:    "name_conflict",
+		return createInstanceError(ctx, err, image), nil
+	}
+	defer release()
+
+	// A slot claimed by the control plane must still be held and cover the
+	// instance. It's taken here so concurrent creates can't both consume it,
+	// and handed back if the create fails.
+	dryRun := request.Params.DryRun != nil && *request.Params.DryRun
+	var takenSlot *nodeagent.Slot
+	if request.Body.SlotId != nil {
+		need := s.instanceSlotNeeds(ctx, domainReq)
+		var slot *nodeagent.Slot
+		if dryRun {
+			slot, err = s.NodeAgent.GetSlot(*request.Body.SlotId)
+			if err == nil {
+				err = slot.Fits(need)
+			}
+		} else {
+			slot, err = s.NodeAgent.TakeSlot(*request.Body.SlotId, need)
+		}
+		if err != nil {
+			return oapi.CreateInstance400ApplicationProblemPlusJSONResponse{
+				Code:    oapi.InvalidRequest,
 				Message: err.Error(),
 			}, nil
-		default:
-			log.ErrorContext(ctx, "failed to create instance", "error", err, "image", request.Body.Image)
-			return oapi.CreateInstance500JSONResponse{
-				Code:    "internal_error",
-				Message: "failed to create instance",
-			}, nil
 		}
+		if !dryRun {
+			takenSlot = slot
+		}
+	}
+
+	if dryRun {
+		preview, err := s.InstanceManager.CheckCreateInstance(ctx, domainReq)
+		if err != nil {
+			return createInstanceError(ctx, err, image), nil
+		}
+		return oapi.CreateInstance200JSONResponse(dryRunResult(oapi.ApplyResourceKindInstance, oapi.ApplyCreate, preview.Name, "", instanceDryRunDetails(*preview))), nil
+	}
+
+	inst, err := s.InstanceManager.CreateInstance(ctx, domainReq)
+	if err != nil {
+		if takenSlot != nil {
+			s.NodeAgent.RestoreSlot(*takenSlot)
+		}
+		return createInstanceError(ctx, err, image), nil
 	}
 	return oapi.CreateInstance201JSONResponse(instanceToOAPI(*inst)), nil
 }
 
+// imageForCreate looks up the image of a new instance, first waiting up to
+// wait seconds for it to finish pulling or converting. Images still not ready
+// are reported by instance creation.
+func (s *ApiService) imageForCreate(ctx context.Context, image string, wait int) (*images.Image, error) {
+	if wait <= 0 {
+		return s.ImageManager.GetImage(ctx, image)
+	}
+	ctx, cancel := context.WithTimeout(ctx, time.Duration(wait)*time.Second)
+	defer cancel()
+	return s.ImageManager.WaitForImage(ctx, image)
+}
+
+// createInstanceError maps an instance creation or admission error to a response
+func createInstanceError(ctx context.Context, err error, image string) oapi.CreateInstanceResponseObject {
+	if problem, ok := quotaProblem(err); ok {
+		return oapi.CreateInstance403ApplicationProblemPlusJSONResponse(problem)
+	}
+	switch {
+	case errors.Is(err, instances.ErrImageNotReady):
+		return oapi.CreateInstance400ApplicationProblemPlusJSONResponse{
+			Code:    oapi.ImageNotReady,
+			Message: err.Error(),
+		}
+	case errors.Is(err, instances.ErrAlreadyExists):
+		return oapi.CreateInstance400ApplicationProblemPlusJSONResponse{
+			Code:    oapi.AlreadyExists,
+			Message: "instance already exists",
+		}
+	case errors.Is(err, network.ErrNameExists):
+		return oapi.CreateInstance400ApplicationProblemPlusJSONResponse{
+			Code:    oapi.AlreadyExists,
+			Message: err.Error(),
+		}
+	case errors.Is(err, instances.ErrInvalidUserData):
+		return oapi.CreateInstance400ApplicationProblemPlusJSONResponse{
+			Code:    oapi.InvalidRequest,
+			Message: err.Error(),
+		}
+	case errors.Is(err, instances.ErrInvalidResolverConfig):
+		return oapi.CreateInstance400ApplicationProblemPlusJSONResponse{
+			Code:    oapi.InvalidRequest,
+			Message: err.Error(),
+		}
+	case errors.Is(err, instances.ErrInvalidIdlePolicy), errors.Is(err, instances.ErrInvalidSchedule), errors.Is(err, instances.ErrInvalidSharedDirectory),
+		errors.Is(err, instances.ErrInvalidSecret), errors.Is(err, instances.ErrInvalidKernelArgs), errors.Is(err, instances.ErrInvalidTuning),
+		errors.Is(err, instances.ErrInvalidLabels), errors.Is(err, instances.ErrInvalidLinks),
+		errors.Is(err, instances.ErrNestedVirtUnsupported), errors.Is(err, instances.ErrInvalidGracePeriod),
+		errors.Is(err, instances.ErrUSBNotSupported), errors.Is(err, devices.ErrInvalidUSBSelector),
+		errors.Is(err, instances.ErrInvalidPlacement), errors.Is(err, instances.ErrInvalidFirmware):
+		return oapi.CreateInstance400ApplicationProblemPlusJSONResponse{
+			Code:    oapi.InvalidRequest,
+			Message: err.Error(),
+		}
+	case errors.Is(err, images.ErrNotFound), errors.Is(err, volumes.ErrNotFound), errors.Is(err, devices.ErrNotFound),
+		errors.Is(err, volumes.ErrReadOnly), errors.Is(err, secrets.ErrNotFound):
+		return oapi.CreateInstance400ApplicationProblemPlusJSONResponse{
+			Code:    oapi.InvalidRequest,
+			Message: err.Error(),
+		}
+	case errors.Is(err, instances.ErrResourceLimit),
+		errors.Is(err, network.ErrNoAvailableIP),
+		errors.Is(err, devices.ErrProfileUnavailable),
+		errors.Is(err, devices.ErrInUse),
+		errors.Is(err, devices.ErrGPUQuotaExceeded),
+		errors.Is(err, devices.ErrGPUUnhealthy),
+		errors.Is(err, volumes.ErrInUse),
+		errors.Is(err, instances.ErrHookFailed):
+		return oapi.CreateInstance409ApplicationProblemPlusJSONResponse{
+			Code:    oapi.Conflict,
+			Message: err.Error(),
+		}
+	default:
+		logger.FromContext(ctx).ErrorContext(ctx, "failed to create instance", "error", err, "image", image)
+		return oapi.CreateInstance500ApplicationProblemPlusJSONResponse{
+			Code:    oapi.InternalError,
+			Message: "failed to create instance",
+		}
+	}
+}
+
 // GetInstance gets instance details
 // The id parameter can be an instance ID, name, or ID prefix
 // Note: Resolution is handled by ResolveResource middleware
 func (s *ApiService) GetInstance(ctx context.Context, request oapi.GetInstanceRequestObject) (oapi.GetInstanceResponseObject, error) {
 	inst := mw.GetResolvedInstance[instances.Instance](ctx)
 	if inst == nil {
-		return oapi.GetInstance500JSONResponse{
-			Code:    "internal_error",
+		return oapi.GetInstance500ApplicationProblemPlusJSONResponse{
+			Code:    oapi.InternalError,
 			Message: "resource not resolved",
 		}, nil
 	}
@@ -280,18 +661,50 @@ func (s *ApiService) GetInstance(ctx context.Context, request oapi.GetInstanceRe
 func (s *ApiService) DeleteInstance(ctx context.Context, request oapi.DeleteInstanceRequestObject) (oapi.DeleteInstanceResponseObject, error) {
 	inst := mw.GetResolvedInstance[instances.Instance](ctx)
 	if inst == nil {
-		return oapi.DeleteInstance500JSONResponse{
-			Code:    "internal_error",
+		return oapi.DeleteInstance500ApplicationProblemPlusJSONResponse{
+			Code:    oapi.InternalError,
 			Message: "resource not resolved",
 		}, nil
 	}
 	log := logger.FromContext(ctx)
 
-	err := s.InstanceManager.DeleteInstance(ctx, inst.Id)
+	var req instances.DeleteInstanceRequest
+	if request.Params.GracePeriod != nil {
+		d, err := time.ParseDuration(*request.Params.GracePeriod)
+		if err != nil || d < 0 {
+			return oapi.DeleteInstance400ApplicationProblemPlusJSONResponse{
+				Code:    oapi.InvalidRequest,
+				Message: fmt.Sprintf("grace_period must be a non-negative duration, got %q", *request.Params.GracePeriod),
+			}, nil
+		}
+		req.GracePeriod = &d
+	}
+
+	if request.Params.DryRun != nil && *request.Params.DryRun {
+		var details []string
+		if inst.State != instances.StateStopped && inst.State != instances.StateFailed {
+			details = append(details, fmt.Sprintf("state is %s; the VM would be stopped", inst.State))
+		}
+		if inst.IP != "" {
+			details = append(details, fmt.Sprintf("IP address %s would be released", inst.IP))
+		}
+		for _, vol := range inst.Volumes {
+			details = append(details, fmt.Sprintf("volume %s would be detached", vol.VolumeID))
+		}
+		return oapi.DeleteInstance200JSONResponse(dryRunResult(oapi.ApplyResourceKindInstance, oapi.ApplyDelete, inst.Name, inst.Id, details)), nil
+	}
+
+	err := s.InstanceManager.DeleteInstance(ctx, inst.Id, req)
+	if errors.Is(err, instances.ErrHookFailed) {
+		return oapi.DeleteInstance409ApplicationProblemPlusJSONResponse{
+			Code:    oapi.Conflict,
+			Message: err.Error(),
+		}, nil
+	}
 	if err != nil {
 		log.ErrorContext(ctx, "failed to delete instance", "error", err)
-		return oapi.DeleteInstance500JSONResponse{
-			Code:    "internal_error",
+		return oapi.DeleteInstance500ApplicationProblemPlusJSONResponse{
+			Code:    oapi.InternalError,
 			Message: "failed to delete instance",
 		}, nil
 	}
@@ -304,8 +717,8 @@ func (s *ApiService) DeleteInstance(ctx context.Context, request oapi.DeleteInst
 func (s *ApiService) StandbyInstance(ctx context.Context, request oapi.StandbyInstanceRequestObject) (oapi.StandbyInstanceResponseObject, error) {
 	inst := mw.GetResolvedInstance[instances.Instance](ctx)
 	if inst == nil {
-		return oapi.StandbyInstance500JSONResponse{
-			Code:    "internal_error",
+		return oapi.StandbyInstance500ApplicationProblemPlusJSONResponse{
+			Code:    oapi.InternalError,
 			Message: "resource not resolved",
 		}, nil
 	}
@@ -315,14 +728,19 @@ func (s *ApiService) StandbyInstance(ctx context.Context, request oapi.StandbyIn
 	if err != nil {
 		switch {
 		case errors.Is(err, instances.ErrInvalidState):
-			return oapi.StandbyInstance409JSONResponse{
-				Code:    "invalid_state",
+			return oapi.StandbyInstance409ApplicationProblemPlusJSONResponse{
+				Code:    oapi.InvalidState,
+				Message: err.Error(),
+			}, nil
+		case errors.Is(err, instances.ErrHookFailed):
+			return oapi.StandbyInstance409ApplicationProblemPlusJSONResponse{
+				Code:    oapi.Conflict,
 				Message: err.Error(),
 			}, nil
 		default:
 			log.ErrorContext(ctx, "failed to standby instance", "error", err)
-			return oapi.StandbyInstance500JSONResponse{
-				Code:    "internal_error",
+			return oapi.StandbyInstance500ApplicationProblemPlusJSONResponse{
+				Code:    oapi.InternalError,
 				Message: "failed to standby instance",
 			}, nil
 		}
@@ -336,8 +754,8 @@ func (s *ApiService) StandbyInstance(ctx context.Context, request oapi.StandbyIn
 func (s *ApiService) RestoreInstance(ctx context.Context, request oapi.RestoreInstanceRequestObject) (oapi.RestoreInstanceResponseObject, error) {
 	inst := mw.GetResolvedInstance[instances.Instance](ctx)
 	if inst == nil {
-		return oapi.RestoreInstance500JSONResponse{
-			Code:    "internal_error",
+		return oapi.RestoreInstance500ApplicationProblemPlusJSONResponse{
+			Code:    oapi.InternalError,
 			Message: "resource not resolved",
 		}, nil
 	}
@@ -347,14 +765,14 @@ func (s *ApiService) RestoreInstance(ctx context.Context, request oapi.RestoreIn
 	if err != nil {
 		switch {
 		case errors.Is(err, instances.ErrInvalidState):
-			return oapi.RestoreInstance409JSONResponse{
-				Code:    "invalid_state",
+			return oapi.RestoreInstance409ApplicationProblemPlusJSONResponse{
+				Code:    oapi.InvalidState,
 				Message: err.Error(),
 			}, nil
 		default:
 			log.ErrorContext(ctx, "failed to restore instance", "error", err)
-			return oapi.RestoreInstance500JSONResponse{
-				Code:    "internal_error",
+			return oapi.RestoreInstance500ApplicationProblemPlusJSONResponse{
+				Code:    oapi.InternalError,
 				Message: "failed to restore instance",
 			}, nil
 		}
@@ -368,8 +786,8 @@ func (s *ApiService) RestoreInstance(ctx context.Context, request oapi.RestoreIn
 func (s *ApiService) StopInstance(ctx context.Context, request oapi.StopInstanceRequestObject) (oapi.StopInstanceResponseObject, error) {
 	inst := mw.GetResolvedInstance[instances.Instance](ctx)
 	if inst == nil {
-		return oapi.StopInstance500JSONResponse{
-			Code:    "internal_error",
+		return oapi.StopInstance500ApplicationProblemPlusJSONResponse{
+			Code:    oapi.InternalError,
 			Message: "resource not resolved",
 		}, nil
 	}
@@ -379,14 +797,14 @@ func (s *ApiService) StopInstance(ctx context.Context, request oapi.StopInstance
 	if err != nil {
 		switch {
 		case errors.Is(err, instances.ErrInvalidState):
-			return oapi.StopInstance409JSONResponse{
-				Code:    "invalid_state",
+			return oapi.StopInstance409ApplicationProblemPlusJSONResponse{
+				Code:    oapi.InvalidState,
 				Message: err.Error(),
 			}, nil
 		default:
 			log.ErrorContext(ctx, "failed to stop instance", "error", err)
-			return oapi.StopInstance500JSONResponse{
-				Code:    "internal_error",
+			return oapi.StopInstance500ApplicationProblemPlusJSONResponse{
+				Code:    oapi.InternalError,
 				Message: "failed to stop instance",
 			}, nil
 		}
@@ -400,8 +818,8 @@ func (s *ApiService) StopInstance(ctx context.Context, request oapi.StopInstance
 func (s *ApiService) StartInstance(ctx context.Context, request oapi.StartInstanceRequestObject) (oapi.StartInstanceResponseObject, error) {
 	inst := mw.GetResolvedInstance[instances.Instance](ctx)
 	if inst == nil {
-		return oapi.StartInstance500JSONResponse{
-			Code:    "internal_error",
+		return oapi.StartInstance500ApplicationProblemPlusJSONResponse{
+			Code:    oapi.InternalError,
 			Message: "resource not resolved",
 		}, nil
 	}
@@ -411,14 +829,14 @@ func (s *ApiService) StartInstance(ctx context.Context, request oapi.StartInstan
 	if err != nil {
 		switch {
 		case errors.Is(err, instances.ErrInvalidState):
-			return oapi.StartInstance409JSONResponse{
-				Code:    "invalid_state",
+			return oapi.StartInstance409ApplicationProblemPlusJSONResponse{
+				Code:    oapi.InvalidState,
 				Message: err.Error(),
 			}, nil
 		default:
 			log.ErrorContext(ctx, "failed to start instance", "error", err)
-			return oapi.StartInstance500JSONResponse{
-				Code:    "internal_error",
+			return oapi.StartInstance500ApplicationProblemPlusJSONResponse{
+				Code:    oapi.InternalError,
 				Message: "failed to start instance",
 			}, nil
 		}
@@ -426,12 +844,164 @@ func (s *ApiService) StartInstance(ctx context.Context, request oapi.StartInstan
 	return oapi.StartInstance200JSONResponse(instanceToOAPI(*result)), nil
 }
 
+// UpdateInstance renames an instance. Ingress rules targeting it by name are
+// moved to the new name; if that fails, the rename is undone.
+func (s *ApiService) UpdateInstance(ctx context.Context, request oapi.UpdateInstanceRequestObject) (oapi.UpdateInstanceResponseObject, error) {
+	inst := mw.GetResolvedInstance[instances.Instance](ctx)
+	if inst == nil {
+		return oapi.UpdateInstance500ApplicationProblemPlusJSONResponse{
+			Code:    oapi.InternalError,
+			Message: "resource not resolved",
+		}, nil
+	}
+	log := logger.FromContext(ctx)
+
+	if request.Body.Name == nil || *request.Body.Name == inst.Name {
+		return oapi.UpdateInstance200JSONResponse(instanceToOAPI(*inst)), nil
+	}
+	oldName, name := inst.Name, *request.Body.Name
+
+	result, err := s.InstanceManager.RenameInstance(ctx, inst.Id, name)
+	if err != nil {
+		switch {
+		case errors.Is(err, instances.ErrInvalidName):
+			return oapi.UpdateInstance400ApplicationProblemPlusJSONResponse{
+				Code:    oapi.InvalidRequest,
+				Message: err.Error(),
+			}, nil
+		case errors.Is(err, instances.ErrNameInUse):
+			return oapi.UpdateInstance409ApplicationProblemPlusJSONResponse{
+				Code:    oapi.AlreadyExists,
+				Message: err.Error(),
+			}, nil
+		default:
+			log.ErrorContext(ctx, "failed to rename instance", "error", err)
+			return oapi.UpdateInstance500ApplicationProblemPlusJSONResponse{
+				Code:    oapi.InternalError,
+				Message: "failed to rename instance",
+			}, nil
+		}
+	}
+
+	if s.IngressManager == nil {
+		return oapi.UpdateInstance200JSONResponse(instanceToOAPI(*result)), nil
+	}
+	if _, err := s.IngressManager.RenameInstanceTarget(ctx, oldName, name); err != nil {
+		log.ErrorContext(ctx, "failed to update ingress targets, undoing rename", "instance_id", inst.Id, "error", err)
+		if _, undoErr := s.InstanceManager.RenameInstance(ctx, inst.Id, oldName); undoErr != nil {
+			log.ErrorContext(ctx, "failed to undo instance rename", "instance_id", inst.Id, "error", undoErr)
+		}
+		return oapi.UpdateInstance500ApplicationProblemPlusJSONResponse{
+			Code:    oapi.InternalError,
+			Message: "failed to update ingress rules targeting the instance",
+		}, nil
+	}
+	return oapi.UpdateInstance200JSONResponse(instanceToOAPI(*result)), nil
+}
+
+// UpdateInstanceNetwork changes an instance's bandwidth limits
+// The id parameter can be an instance ID, name, or ID prefix
+// Note: Resolution is handled by ResolveResource middleware
+func (s *ApiService) UpdateInstanceNetwork(ctx context.Context, request oapi.UpdateInstanceNetworkRequestObject) (oapi.UpdateInstanceNetworkResponseObject, error) {
+	inst := mw.GetResolvedInstance[instances.Instance](ctx)
+	if inst == nil {
+		return oapi.UpdateInstanceNetwork500ApplicationProblemPlusJSONResponse{
+			Code:    oapi.InternalError,
+			Message: "resource not resolved",
+		}, nil
+	}
+	log := logger.FromContext(ctx)
+
+	var req instances.UpdateNetworkBandwidthRequest
+
+	// Bandwidth: "0" resets to the proportional default, as on create
+	defaultDown, defaultUp := s.ResourceManager.DefaultNetworkBandwidth(inst.Vcpus)
+	for _, bw := range []struct {
+		field    string
+		value    *string
+		fallback int64
+		target   **int64
+	}{
+		{"bandwidth_download", request.Body.BandwidthDownload, defaultDown, &req.DownloadBps},
+		{"bandwidth_upload", request.Body.BandwidthUpload, defaultUp, &req.UploadBps},
+	} {
+		if bw.value == nil || *bw.value == "" {
+			continue
+		}
+		parsed, err := resources.ParseBandwidth(*bw.value)
+		if err != nil {
+			return oapi.UpdateInstanceNetwork400ApplicationProblemPlusJSONResponse{
+				Code:    oapi.InvalidRequest,
+				Message: fmt.Sprintf("invalid %s format: %v", bw.field, err),
+			}, nil
+		}
+		if parsed == 0 {
+			parsed = bw.fallback
+		}
+		*bw.target = &parsed
+	}
+
+	// Burst sizes: "0" resets to the default
+	for _, burst := range []struct {
+		field  string
+		value  *string
+		target **int64
+	}{
+		{"bandwidth_download_burst", request.Body.BandwidthDownloadBurst, &req.DownloadBurst},
+		{"bandwidth_upload_burst", request.Body.BandwidthUploadBurst, &req.UploadBurst},
+	} {
+		if burst.value == nil || *burst.value == "" {
+			continue
+		}
+		var size datasize.ByteSize
+		if err := size.UnmarshalText([]byte(*burst.value)); err != nil {
+			return oapi.UpdateInstanceNetwork400ApplicationProblemPlusJSONResponse{
+				Code:    oapi.InvalidRequest,
+				Message: fmt.Sprintf("invalid %s format: %v", burst.field, err),
+			}, nil
+		}
+		*burst.target = lo.ToPtr(int64(size))
+	}
+
+	result, err := s.InstanceManager.UpdateNetworkBandwidth(ctx, inst.Id, req)
+	if err != nil {
+		switch {
+		case errors.Is(err, instances.ErrNetworkDisabled):
+			return oapi.UpdateInstanceNetwork409ApplicationProblemPlusJSONResponse{
+				Code:    oapi.InvalidState,
+				Message: err.Error(),
+			}, nil
+		default:
+			log.ErrorContext(ctx, "failed to update instance network", "error", err)
+			return oapi.UpdateInstanceNetwork500ApplicationProblemPlusJSONResponse{
+				Code:    oapi.InternalError,
+				Message: "failed to update instance network",
+			}, nil
+		}
+	}
+	return oapi.UpdateInstanceNetwork200JSONResponse(instanceToOAPI(*result)), nil
+}
+
 // logsStreamResponse implements oapi.GetInstanceLogsResponseObject with proper SSE flushing
 type logsStreamResponse struct {
 	logChan <-chan string
 }
 
 func (r logsStreamResponse) VisitGetInstanceLogsResponse(w http.ResponseWriter) error {
+	return writeSSELines(w, r.logChan)
+}
+
+// fileTailStreamResponse implements oapi.TailInstanceFileResponseObject with proper SSE flushing
+type fileTailStreamResponse struct {
+	lines <-chan string
+}
+
+func (r fileTailStreamResponse) VisitTailInstanceFileResponse(w http.ResponseWriter) error {
+	return writeSSELines(w, r.lines)
+}
+
+// writeSSELines streams each line as a JSON-encoded SSE data event until the channel closes
+func writeSSELines(w http.ResponseWriter, lines <-chan string) error {
 	w.Header().Set("Content-Type", "text/event-stream")
 	w.Header().Set("Cache-Control", "no-cache")
 	w.Header().Set("Connection", "keep-alive")
@@ -443,7 +1013,7 @@ func (r logsStreamResponse) VisitGetInstanceLogsResponse(w http.ResponseWriter)
 		return fmt.Errorf("streaming not supported")
 	}
 
-	for line := range r.logChan {
+	for line := range lines {
 		jsonLine, _ := json.Marshal(line)
 		fmt.Fprintf(w, "data: %s\n\n", jsonLine)
 		flusher.Flush()
@@ -459,8 +1029,8 @@ func (r logsStreamResponse) VisitGetInstanceLogsResponse(w http.ResponseWriter)
 func (s *ApiService) GetInstanceLogs(ctx context.Context, request oapi.GetInstanceLogsRequestObject) (oapi.GetInstanceLogsResponseObject, error) {
 	inst := mw.GetResolvedInstance[instances.Instance](ctx)
 	if inst == nil {
-		return oapi.GetInstanceLogs500JSONResponse{
-			Code:    "internal_error",
+		return oapi.GetInstanceLogs500ApplicationProblemPlusJSONResponse{
+			Code:    oapi.InternalError,
 			Message: "resource not resolved",
 		}, nil
 	}
@@ -492,18 +1062,18 @@ func (s *ApiService) GetInstanceLogs(ctx context.Context, request oapi.GetInstan
 	if err != nil {
 		switch {
 		case errors.Is(err, instances.ErrTailNotFound):
-			return oapi.GetInstanceLogs500JSONResponse{
-				Code:    "dependency_missing",
+			return oapi.GetInstanceLogs500ApplicationProblemPlusJSONResponse{
+				Code:    oapi.InternalError,
 				Message: "tail command not found on server - required for log streaming",
 			}, nil
 		case errors.Is(err, instances.ErrLogNotFound):
-			return oapi.GetInstanceLogs404JSONResponse{
-				Code:    "log_not_found",
+			return oapi.GetInstanceLogs404ApplicationProblemPlusJSONResponse{
+				Code:    oapi.NotFound,
 				Message: "requested log file does not exist yet",
 			}, nil
 		default:
-			return oapi.GetInstanceLogs500JSONResponse{
-				Code:    "internal_error",
+			return oapi.GetInstanceLogs500ApplicationProblemPlusJSONResponse{
+				Code:    oapi.InternalError,
 				Message: "failed to stream logs",
 			}, nil
 		}
@@ -512,6 +1082,189 @@ func (s *ApiService) GetInstanceLogs(ctx context.Context, request oapi.GetInstan
 	return logsStreamResponse{logChan: logChan}, nil
 }
 
+// SearchInstanceLogs finds matching lines in an instance log and its rotated copies
+// The id parameter can be an instance ID, name, or ID prefix
+// Note: Resolution is handled by ResolveResource middleware
+func (s *ApiService) SearchInstanceLogs(ctx context.Context, request oapi.SearchInstanceLogsRequestObject) (oapi.SearchInstanceLogsResponseObject, error) {
+	inst := mw.GetResolvedInstance[instances.Instance](ctx)
+	if inst == nil {
+		return oapi.SearchInstanceLogs500ApplicationProblemPlusJSONResponse{
+			Code:    oapi.InternalError,
+			Message: "resource not resolved",
+		}, nil
+	}
+	log := logger.FromContext(ctx)
+
+	q := instances.LogSearchQuery{
+		Query:  request.Params.Q,
+		Regex:  lo.FromPtr(request.Params.Regex),
+		Source: instances.LogSourceApp,
+		Since:  lo.FromPtr(request.Params.Since),
+		Until:  lo.FromPtr(request.Params.Until),
+		Limit:  lo.FromPtr(request.Params.Limit),
+		Cursor: lo.FromPtr(request.Params.Cursor),
+	}
+	if request.Params.Source != nil {
+		q.Source = instances.LogSource(*request.Params.Source)
+	}
+
+	res, err := s.InstanceManager.SearchInstanceLogs(ctx, inst.Id, q)
+	if err != nil {
+		switch {
+		case errors.Is(err, instances.ErrInvalidLogSearch):
+			return oapi.SearchInstanceLogs400ApplicationProblemPlusJSONResponse{
+				Code:    oapi.InvalidRequest,
+				Message: err.Error(),
+			}, nil
+		case errors.Is(err, instances.ErrLogNotFound):
+			return oapi.SearchInstanceLogs404ApplicationProblemPlusJSONResponse{
+				Code:    oapi.NotFound,
+				Message: "requested log file does not exist yet",
+			}, nil
+		default:
+			log.ErrorContext(ctx, "failed to search logs", "error", err)
+			return oapi.SearchInstanceLogs500ApplicationProblemPlusJSONResponse{
+				Code:    oapi.InternalError,
+				Message: "failed to search logs",
+			}, nil
+		}
+	}
+
+	out := oapi.SearchInstanceLogs200JSONResponse{
+		Matches:    make([]oapi.LogMatch, len(res.Matches)),
+		NextCursor: lo.EmptyableToPtr(res.NextCursor),
+	}
+	for i, m := range res.Matches {
+		out.Matches[i] = oapi.LogMatch{File: m.File, Line: m.Line, Time: m.Time, Text: m.Text}
+	}
+	return out, nil
+}
+
+// GetInstanceDiagnostics reports how far an instance's last boot got
+// The id parameter can be an instance ID, name, or ID prefix
+// Note: Resolution is handled by ResolveResource middleware
+func (s *ApiService) GetInstanceDiagnostics(ctx context.Context, request oapi.GetInstanceDiagnosticsRequestObject) (oapi.GetInstanceDiagnosticsResponseObject, error) {
+	inst := mw.GetResolvedInstance[instances.Instance](ctx)
+	if inst == nil {
+		return oapi.GetInstanceDiagnostics500ApplicationProblemPlusJSONResponse{
+			Code:    oapi.InternalError,
+			Message: "resource not resolved",
+		}, nil
+	}
+	log := logger.FromContext(ctx)
+
+	diag, err := s.InstanceManager.GetBootDiagnostics(ctx, inst.Id)
+	if err != nil {
+		if errors.Is(err, instances.ErrNotFound) {
+			return oapi.GetInstanceDiagnostics404ApplicationProblemPlusJSONResponse{
+				Code:    oapi.NotFound,
+				Message: "instance not found",
+			}, nil
+		}
+		log.ErrorContext(ctx, "failed to get boot diagnostics", "error", err)
+		return oapi.GetInstanceDiagnostics500ApplicationProblemPlusJSONResponse{
+			Code:    oapi.InternalError,
+			Message: "failed to get boot diagnostics",
+		}, nil
+	}
+
+	resp := oapi.GetInstanceDiagnostics200JSONResponse{
+		StartedAt:    diag.StartedAt,
+		AgentReadyAt: diag.AgentReadyAt,
+		BootFailure:  bootFailureToOAPI(diag.BootFailure),
+		Milestones:   make([]oapi.BootMilestone, 0, len(diag.Milestones)),
+		Errors:       diag.Errors,
+	}
+	if resp.Errors == nil {
+		resp.Errors = []string{}
+	}
+	for _, ms := range diag.Milestones {
+		resp.Milestones = append(resp.Milestones, oapi.BootMilestone{
+			Name:    ms.Name,
+			At:      ms.At,
+			Message: ms.Message,
+		})
+	}
+	return resp, nil
+}
+
+// bootFailureToOAPI converts a recorded boot failure; nil stays nil
+func bootFailureToOAPI(f *instances.BootFailure) *oapi.BootFailure {
+	if f == nil {
+		return nil
+	}
+	return &oapi.BootFailure{
+		Reason:         f.Reason,
+		FailedAt:       f.FailedAt,
+		ConsoleExcerpt: f.ConsoleExcerpt,
+	}
+}
+
+// TailInstanceFile streams the end of a guest file via SSE
+// With follow=true, continues streaming lines appended to the file
+// The id parameter can be an instance ID, name, or ID prefix
+// Note: Resolution is handled by ResolveResource middleware
+func (s *ApiService) TailInstanceFile(ctx context.Context, request oapi.TailInstanceFileRequestObject) (oapi.TailInstanceFileResponseObject, error) {
+	log := logger.FromContext(ctx)
+
+	inst := mw.GetResolvedInstance[instances.Instance](ctx)
+	if inst == nil {
+		return oapi.TailInstanceFile500ApplicationProblemPlusJSONResponse{
+			Code:    oapi.InternalError,
+			Message: "resource not resolved",
+		}, nil
+	}
+
+	if inst.State != instances.StateRunning {
+		return oapi.TailInstanceFile409ApplicationProblemPlusJSONResponse{
+			Code:    oapi.InvalidState,
+			Message: fmt.Sprintf("instance must be running (current state: %s)", inst.State),
+		}, nil
+	}
+
+	tail := 100
+	if request.Params.Tail != nil {
+		tail = *request.Params.Tail
+	}
+	follow := false
+	if request.Params.Follow != nil {
+		follow = *request.Params.Follow
+	}
+
+	dialer, err := hypervisor.NewVsockDialer(inst.HypervisorType, inst.VsockSocket, inst.VsockCID)
+	if err != nil {
+		log.ErrorContext(ctx, "failed to create vsock dialer", "error", err)
+		return oapi.TailInstanceFile500ApplicationProblemPlusJSONResponse{
+			Code:    oapi.InternalError,
+			Message: "failed to create vsock dialer",
+		}, nil
+	}
+
+	lines, err := guest.TailFile(ctx, dialer, request.Params.Path, tail, follow)
+	if err != nil {
+		switch {
+		case errors.Is(err, guest.ErrFileNotFound):
+			return oapi.TailInstanceFile404ApplicationProblemPlusJSONResponse{
+				Code:    oapi.NotFound,
+				Message: err.Error(),
+			}, nil
+		case errors.Is(err, guest.ErrInvalidTailPath):
+			return oapi.TailInstanceFile400ApplicationProblemPlusJSONResponse{
+				Code:    oapi.InvalidRequest,
+				Message: err.Error(),
+			}, nil
+		default:
+			log.ErrorContext(ctx, "tail file failed", "error", err, "path", request.Params.Path)
+			return oapi.TailInstanceFile500ApplicationProblemPlusJSONResponse{
+				Code:    oapi.InternalError,
+				Message: "failed to tail file in guest",
+			}, nil
+		}
+	}
+
+	return fileTailStreamResponse{lines: lines}, nil
+}
+
 // StatInstancePath returns information about a path in the guest filesystem
 // The id parameter can be an instance ID, name, or ID prefix
 // Note: Resolution is handled by ResolveResource middleware
@@ -520,15 +1273,15 @@ func (s *ApiService) StatInstancePath(ctx context.Context, request oapi.StatInst
 
 	inst := mw.GetResolvedInstance[instances.Instance](ctx)
 	if inst == nil {
-		return oapi.StatInstancePath500JSONResponse{
-			Code:    "internal_error",
+		return oapi.StatInstancePath500ApplicationProblemPlusJSONResponse{
+			Code:    oapi.InternalError,
 			Message: "resource not resolved",
 		}, nil
 	}
 
 	if inst.State != instances.StateRunning {
-		return oapi.StatInstancePath409JSONResponse{
-			Code:    "invalid_state",
+		return oapi.StatInstancePath409ApplicationProblemPlusJSONResponse{
+			Code:    oapi.InvalidState,
 			Message: fmt.Sprintf("instance must be running (current state: %s)", inst.State),
 		}, nil
 	}
@@ -537,8 +1290,8 @@ func (s *ApiService) StatInstancePath(ctx context.Context, request oapi.StatInst
 	dialer, err := hypervisor.NewVsockDialer(inst.HypervisorType, inst.VsockSocket, inst.VsockCID)
 	if err != nil {
 		log.ErrorContext(ctx, "failed to create vsock dialer", "error", err)
-		return oapi.StatInstancePath500JSONResponse{
-			Code:    "internal_error",
+		return oapi.StatInstancePath500ApplicationProblemPlusJSONResponse{
+			Code:    oapi.InternalError,
 			Message: "failed to create vsock dialer",
 		}, nil
 	}
@@ -546,8 +1299,8 @@ func (s *ApiService) StatInstancePath(ctx context.Context, request oapi.StatInst
 	grpcConn, err := guest.GetOrCreateConn(ctx, dialer)
 	if err != nil {
 		log.ErrorContext(ctx, "failed to get grpc connection", "error", err)
-		return oapi.StatInstancePath500JSONResponse{
-			Code:    "internal_error",
+		return oapi.StatInstancePath500ApplicationProblemPlusJSONResponse{
+			Code:    oapi.InternalError,
 			Message: "failed to connect to guest agent",
 		}, nil
 	}
@@ -564,8 +1317,8 @@ func (s *ApiService) StatInstancePath(ctx context.Context, request oapi.StatInst
 	})
 	if err != nil {
 		log.ErrorContext(ctx, "stat path failed", "error", err, "path", request.Params.Path)
-		return oapi.StatInstancePath500JSONResponse{
-			Code:    "internal_error",
+		return oapi.StatInstancePath500ApplicationProblemPlusJSONResponse{
+			Code:    oapi.InternalError,
 			Message: "failed to stat path in guest",
 		}, nil
 	}
@@ -590,21 +1343,101 @@ func (s *ApiService) StatInstancePath(ctx context.Context, request oapi.StatInst
 
 // AttachVolume attaches a volume to an instance (not yet implemented)
 func (s *ApiService) AttachVolume(ctx context.Context, request oapi.AttachVolumeRequestObject) (oapi.AttachVolumeResponseObject, error) {
-	return oapi.AttachVolume500JSONResponse{
-		Code:    "not_implemented",
+	return oapi.AttachVolume500ApplicationProblemPlusJSONResponse{
+		Code:    oapi.NotImplemented,
 		Message: "volume attachment not yet implemented",
 	}, nil
 }
 
 // DetachVolume detaches a volume from an instance (not yet implemented)
 func (s *ApiService) DetachVolume(ctx context.Context, request oapi.DetachVolumeRequestObject) (oapi.DetachVolumeResponseObject, error) {
-	return oapi.DetachVolume500JSONResponse{
-		Code:    "not_implemented",
+	return oapi.DetachVolume500ApplicationProblemPlusJSONResponse{
+		Code:    oapi.NotImplemented,
 		Message: "volume detachment not yet implemented",
 	}, nil
 }
 
 // instanceToOAPI converts domain Instance to OAPI Instance
+// AttachUSBDevice passes a host USB device through to an instance
+// Note: Resolution is handled by ResolveResource middleware
+func (s *ApiService) AttachUSBDevice(ctx context.Context, request oapi.AttachUSBDeviceRequestObject) (oapi.AttachUSBDeviceResponseObject, error) {
+	inst := mw.GetResolvedInstance[instances.Instance](ctx)
+	if inst == nil {
+		return oapi.AttachUSBDevice500ApplicationProblemPlusJSONResponse{
+			Code:    oapi.InternalError,
+			Message: "resource not resolved",
+		}, nil
+	}
+
+	result, err := s.InstanceManager.AttachUSBDevice(ctx, inst.Id, request.Body.Device)
+	if err != nil {
+		switch {
+		case errors.Is(err, devices.ErrInvalidUSBSelector), errors.Is(err, instances.ErrUSBNotSupported):
+			return oapi.AttachUSBDevice400ApplicationProblemPlusJSONResponse{
+				Code:    oapi.InvalidRequest,
+				Message: err.Error(),
+			}, nil
+		case errors.Is(err, instances.ErrUSBDeviceAttached):
+			return oapi.AttachUSBDevice409ApplicationProblemPlusJSONResponse{
+				Code:    oapi.AlreadyExists,
+				Message: err.Error(),
+			}, nil
+		case errors.Is(err, instances.ErrInvalidState):
+			return oapi.AttachUSBDevice409ApplicationProblemPlusJSONResponse{
+				Code:    oapi.InvalidState,
+				Message: err.Error(),
+			}, nil
+		default:
+			logger.FromContext(ctx).ErrorContext(ctx, "failed to attach USB device", "error", err)
+			return oapi.AttachUSBDevice500ApplicationProblemPlusJSONResponse{
+				Code:    oapi.InternalError,
+				Message: "failed to attach USB device",
+			}, nil
+		}
+	}
+	return oapi.AttachUSBDevice200JSONResponse(instanceToOAPI(*result)), nil
+}
+
+// DetachUSBDevice removes a USB device from an instance
+// Note: Resolution is handled by ResolveResource middleware
+func (s *ApiService) DetachUSBDevice(ctx context.Context, request oapi.DetachUSBDeviceRequestObject) (oapi.DetachUSBDeviceResponseObject, error) {
+	inst := mw.GetResolvedInstance[instances.Instance](ctx)
+	if inst == nil {
+		return oapi.DetachUSBDevice500ApplicationProblemPlusJSONResponse{
+			Code:    oapi.InternalError,
+			Message: "resource not resolved",
+		}, nil
+	}
+
+	result, err := s.InstanceManager.DetachUSBDevice(ctx, inst.Id, request.Device)
+	if err != nil {
+		switch {
+		case errors.Is(err, devices.ErrInvalidUSBSelector):
+			return oapi.DetachUSBDevice400ApplicationProblemPlusJSONResponse{
+				Code:    oapi.InvalidRequest,
+				Message: err.Error(),
+			}, nil
+		case errors.Is(err, instances.ErrUSBDeviceNotAttached):
+			return oapi.DetachUSBDevice404ApplicationProblemPlusJSONResponse{
+				Code:    oapi.NotFound,
+				Message: err.Error(),
+			}, nil
+		case errors.Is(err, instances.ErrInvalidState):
+			return oapi.DetachUSBDevice409ApplicationProblemPlusJSONResponse{
+				Code:    oapi.InvalidState,
+				Message: err.Error(),
+			}, nil
+		default:
+			logger.FromContext(ctx).ErrorContext(ctx, "failed to detach USB device", "error", err)
+			return oapi.DetachUSBDevice500ApplicationProblemPlusJSONResponse{
+				Code:    oapi.InternalError,
+				Message: "failed to detach USB device",
+			}, nil
+		}
+	}
+	return oapi.DetachUSBDevice200JSONResponse(instanceToOAPI(*result)), nil
+}
+
 func instanceToOAPI(inst instances.Instance) oapi.Instance {
 	// Format sizes as human-readable strings with best precision
 	// HR() returns format like "1.5 GB" with 1 decimal place
@@ -623,18 +1456,30 @@ func instanceToOAPI(inst instances.Instance) oapi.Instance {
 		uploadBwStr = &s
 	}
 
+	var downloadBurstStr, uploadBurstStr *string
+	if inst.NetworkDownloadBurst > 0 {
+		downloadBurstStr = lo.ToPtr(datasize.ByteSize(inst.NetworkDownloadBurst).HR())
+	}
+	if inst.NetworkUploadBurst > 0 {
+		uploadBurstStr = lo.ToPtr(datasize.ByteSize(inst.NetworkUploadBurst).HR())
+	}
+
 	// Build network object with ip/mac and bandwidth nested inside
 	netObj := &struct {
-		BandwidthDownload *string `json:"bandwidth_download,omitempty"`
-		BandwidthUpload   *string `json:"bandwidth_upload,omitempty"`
-		Enabled           *bool   `json:"enabled,omitempty"`
-		Ip                *string `json:"ip"`
-		Mac               *string `json:"mac"`
-		Name              *string `json:"name,omitempty"`
+		BandwidthDownload      *string `json:"bandwidth_download,omitempty"`
+		BandwidthDownloadBurst *string `json:"bandwidth_download_burst,omitempty"`
+		BandwidthUpload        *string `json:"bandwidth_upload,omitempty"`
+		BandwidthUploadBurst   *string `json:"bandwidth_upload_burst,omitempty"`
+		Enabled                *bool   `json:"enabled,omitempty"`
+		Ip                     *string `json:"ip"`
+		Mac                    *string `json:"mac"`
+		Name                   *string `json:"name,omitempty"`
 	}{
-		Enabled:           lo.ToPtr(inst.NetworkEnabled),
-		BandwidthDownload: downloadBwStr,
-		BandwidthUpload:   uploadBwStr,
+		Enabled:                lo.ToPtr(inst.NetworkEnabled),
+		BandwidthDownload:      downloadBwStr,
+		BandwidthDownloadBurst: downloadBurstStr,
+		BandwidthUpload:        uploadBwStr,
+		BandwidthUploadBurst:   uploadBurstStr,
 	}
 	if inst.NetworkEnabled {
 		netObj.Name = lo.ToPtr("default")
@@ -645,6 +1490,12 @@ func instanceToOAPI(inst instances.Instance) oapi.Instance {
 	// Convert hypervisor type
 	hvType := oapi.InstanceHypervisor(inst.HypervisorType)
 
+	// Instances created before firmware was recorded boot directly
+	firmware := oapi.FirmwareDirect
+	if inst.Firmware != "" {
+		firmware = oapi.Firmware(inst.Firmware)
+	}
+
 	// Format disk I/O as human-readable
 	var diskIoBpsStr *string
 	if inst.DiskIOBps > 0 {
@@ -669,11 +1520,38 @@ func instanceToOAPI(inst instances.Instance) oapi.Instance {
 		StoppedAt:   inst.StoppedAt,
 		HasSnapshot: lo.ToPtr(inst.HasSnapshot),
 		Hypervisor:  &hvType,
+		Firmware:    &firmware,
+	}
+
+	if inst.Tenant != "" {
+		oapiInst.Tenant = lo.ToPtr(inst.Tenant)
+	}
+	if inst.RootVolume != "" {
+		oapiInst.RootVolume = lo.ToPtr(inst.RootVolume)
+	}
+
+	if ip := inst.IdlePolicy; ip != nil {
+		oapiInst.IdlePolicy = &oapi.IdlePolicy{WakeOnIngress: ip.WakeOnIngress}
+		if ip.StandbyAfter != nil {
+			oapiInst.IdlePolicy.StandbyAfter = lo.ToPtr(ip.StandbyAfter.String())
+		}
+	}
+	if len(inst.Schedules) > 0 {
+		oapiInst.Schedules = lo.ToPtr(schedulesToOAPI(inst.Schedules, time.Now()))
 	}
 
 	if len(inst.Env) > 0 {
 		oapiInst.Env = &inst.Env
 	}
+	if inst.Entrypoint != nil {
+		oapiInst.Entrypoint = &inst.Entrypoint
+	}
+	if inst.Cmd != nil {
+		oapiInst.Cmd = &inst.Cmd
+	}
+	if inst.Workdir != "" {
+		oapiInst.Workdir = lo.ToPtr(inst.Workdir)
+	}
 
 	// Convert volume attachments
 	if len(inst.Volumes) > 0 {
@@ -694,6 +1572,77 @@ func instanceToOAPI(inst instances.Instance) oapi.Instance {
 		oapiInst.Volumes = &oapiVolumes
 	}
 
+	if inst.SwapSize > 0 {
+		oapiInst.SwapSize = lo.ToPtr(datasize.ByteSize(inst.SwapSize).HR())
+	}
+	if sd := inst.ScratchDisks; sd != nil {
+		oapiInst.ScratchDisks = &oapi.ScratchDisks{Count: sd.Count, Size: datasize.ByteSize(sd.Size).HR()}
+	}
+
+	if len(inst.USBDevices) > 0 {
+		oapiInst.UsbDevices = lo.ToPtr(inst.USBDevices)
+	}
+
+	// Convert shared directories
+	if len(inst.SharedDirectories) > 0 {
+		oapiDirs := make([]oapi.SharedDirectory, len(inst.SharedDirectories))
+		for i, dir := range inst.SharedDirectories {
+			oapiDirs[i] = oapi.SharedDirectory{
+				HostPath:  dir.HostPath,
+				MountPath: dir.MountPath,
+				Readonly:  lo.ToPtr(dir.Readonly),
+			}
+		}
+		oapiInst.SharedDirectories = &oapiDirs
+	}
+
+	// Convert secret attachments (never their values)
+	if len(inst.Secrets) > 0 {
+		oapiSecrets := make([]oapi.SecretAttachment, len(inst.Secrets))
+		for i, att := range inst.Secrets {
+			oapiSecrets[i] = oapi.SecretAttachment{SecretId: att.SecretID}
+			if att.EnvVar != "" {
+				oapiSecrets[i].EnvVar = lo.ToPtr(att.EnvVar)
+			}
+		}
+		oapiInst.Secrets = &oapiSecrets
+	}
+
+	if len(inst.KernelArgs) > 0 {
+		oapiInst.KernelArgs = lo.ToPtr(inst.KernelArgs)
+	}
+	if len(inst.Sysctls) > 0 {
+		oapiInst.Sysctls = lo.ToPtr(inst.Sysctls)
+	}
+	if len(inst.Labels) > 0 {
+		oapiInst.Labels = lo.ToPtr(inst.Labels)
+	}
+	if len(inst.Links) > 0 {
+		oapiInst.Links = lo.ToPtr(inst.Links)
+	}
+	if len(inst.Ulimits) > 0 {
+		ulimits := make([]oapi.Ulimit, len(inst.Ulimits))
+		for i, u := range inst.Ulimits {
+			ulimits[i] = oapi.Ulimit{Name: oapi.UlimitName(u.Name), Soft: u.Soft, Hard: u.Hard}
+		}
+		oapiInst.Ulimits = &ulimits
+	}
+	if inst.NestedVirt {
+		oapiInst.NestedVirt = lo.ToPtr(true)
+	}
+	if inst.ShutdownGracePeriod != nil {
+		oapiInst.ShutdownGracePeriod = lo.ToPtr(inst.ShutdownGracePeriod.String())
+	}
+	oapiInst.BootFailure = bootFailureToOAPI(inst.BootFailure)
+	if inst.TerminationReason != "" {
+		oapiInst.TerminationReason = lo.ToPtr(oapi.InstanceTerminationReason(inst.TerminationReason))
+		oapiInst.ExitCode = inst.ExitCode
+		oapiInst.TerminatedAt = inst.TerminatedAt
+		if inst.ExitSignal != "" {
+			oapiInst.ExitSignal = lo.ToPtr(inst.ExitSignal)
+		}
+	}
+
 	// Convert GPU info
 	if inst.GPUProfile != "" {
 		gpu := &oapi.InstanceGPU{
@@ -706,5 +1655,19 @@ func instanceToOAPI(inst instances.Instance) oapi.Instance {
 		oapiInst.Gpu = gpu
 	}
 
+	if p := inst.Placement; p != nil {
+		placement := &oapi.PlacementHints{NumaNode: p.NUMANode}
+		if p.GPUPolicy != "" {
+			placement.GpuPolicy = lo.ToPtr(oapi.PlacementHintsGpuPolicy(p.GPUPolicy))
+		}
+		if len(p.GPUAffinity) > 0 {
+			placement.GpuAffinity = lo.ToPtr(p.GPUAffinity)
+		}
+		if len(p.GPUAntiAffinity) > 0 {
+			placement.GpuAntiAffinity = lo.ToPtr(p.GPUAntiAffinity)
+		}
+		oapiInst.Placement = placement
+	}
+
 	return oapiInst
 }
@@ -5,9 +5,12 @@ import (
 	"testing"
 	"time"
 
+	"github.com/kernel/hypeman/lib/instances"
+	mw "github.com/kernel/hypeman/lib/middleware"
 	"github.com/kernel/hypeman/lib/oapi"
 	"github.com/kernel/hypeman/lib/paths"
 	"github.com/kernel/hypeman/lib/system"
+	"github.com/samber/lo"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -32,6 +35,19 @@ func TestGetInstance_NotFound(t *testing.T) {
 	require.Error(t, err)
 }
 
+func TestTailInstanceFile_RequiresRunningInstance(t *testing.T) {
+	svc := newTestService(t)
+	inst := instances.Instance{State: instances.StateStopped}
+	inst.Id = "tail-stopped"
+
+	resp, err := svc.TailInstanceFile(mw.WithResolvedInstance(ctx(), inst.Id, inst), oapi.TailInstanceFileRequestObject{
+		Id:     inst.Id,
+		Params: oapi.TailInstanceFileParams{Path: "/var/log/app.log"},
+	})
+	require.NoError(t, err)
+	assert.IsType(t, oapi.TailInstanceFile409ApplicationProblemPlusJSONResponse{}, resp)
+}
+
 func TestCreateInstance_ParsesHumanReadableSizes(t *testing.T) {
 	// Require KVM access for VM creation
 	if _, err := os.Stat("/dev/kvm"); os.IsNotExist(err) {
@@ -60,14 +76,16 @@ func TestCreateInstance_ParsesHumanReadableSizes(t *testing.T) {
 	resp, err := svc.CreateInstance(ctx(), oapi.CreateInstanceRequestObject{
 		Body: &oapi.CreateInstanceRequest{
 			Name:        "test-sizes",
-			Image:       "docker.io/library/alpine:latest",
+			Image:       lo.ToPtr("docker.io/library/alpine:latest"),
 			Size:        &size,
 			HotplugSize: &hotplugSize,
 			OverlaySize: &overlaySize,
 			Network: &struct {
-				BandwidthDownload *string `json:"bandwidth_download,omitempty"`
-				BandwidthUpload   *string `json:"bandwidth_upload,omitempty"`
-				Enabled           *bool   `json:"enabled,omitempty"`
+				BandwidthDownload      *string `json:"bandwidth_download,omitempty"`
+				BandwidthDownloadBurst *string `json:"bandwidth_download_burst,omitempty"`
+				BandwidthUpload        *string `json:"bandwidth_upload,omitempty"`
+				BandwidthUploadBurst   *string `json:"bandwidth_upload_burst,omitempty"`
+				Enabled                *bool   `json:"enabled,omitempty"`
 			}{
 				Enabled: &networkEnabled,
 			},
@@ -108,12 +126,14 @@ func TestCreateInstance_InvalidSizeFormat(t *testing.T) {
 	resp, err := svc.CreateInstance(ctx(), oapi.CreateInstanceRequestObject{
 		Body: &oapi.CreateInstanceRequest{
 			Name:  "test-invalid",
-			Image: "docker.io/library/alpine:latest",
+			Image: lo.ToPtr("docker.io/library/alpine:latest"),
 			Size:  &invalidSize,
 			Network: &struct {
-				BandwidthDownload *string `json:"bandwidth_download,omitempty"`
-				BandwidthUpload   *string `json:"bandwidth_upload,omitempty"`
-				Enabled           *bool   `json:"enabled,omitempty"`
+				BandwidthDownload      *string `json:"bandwidth_download,omitempty"`
+				BandwidthDownloadBurst *string `json:"bandwidth_download_burst,omitempty"`
+				BandwidthUpload        *string `json:"bandwidth_upload,omitempty"`
+				BandwidthUploadBurst   *string `json:"bandwidth_upload_burst,omitempty"`
+				Enabled                *bool   `json:"enabled,omitempty"`
 			}{
 				Enabled: &networkEnabled,
 			},
@@ -122,9 +142,9 @@ func TestCreateInstance_InvalidSizeFormat(t *testing.T) {
 	require.NoError(t, err)
 
 	// Should get invalid_size error
-	badReq, ok := resp.(oapi.CreateInstance400JSONResponse)
+	badReq, ok := resp.(oapi.CreateInstance400ApplicationProblemPlusJSONResponse)
 	require.True(t, ok, "expected 400 response")
-	assert.Equal(t, "invalid_size", badReq.Code)
+	assert.Equal(t, oapi.InvalidRequest, badReq.Code)
 	assert.Contains(t, badReq.Message, "invalid size format")
 }
 
@@ -152,11 +172,13 @@ func TestInstanceLifecycle_StopStart(t *testing.T) {
 	createResp, err := svc.CreateInstance(ctx(), oapi.CreateInstanceRequestObject{
 		Body: &oapi.CreateInstanceRequest{
 			Name:  "test-lifecycle",
-			Image: "docker.io/library/nginx:alpine",
+			Image: lo.ToPtr("docker.io/library/nginx:alpine"),
 			Network: &struct {
-				BandwidthDownload *string `json:"bandwidth_download,omitempty"`
-				BandwidthUpload   *string `json:"bandwidth_upload,omitempty"`
-				Enabled           *bool   `json:"enabled,omitempty"`
+				BandwidthDownload      *string `json:"bandwidth_download,omitempty"`
+				BandwidthDownloadBurst *string `json:"bandwidth_download_burst,omitempty"`
+				BandwidthUpload        *string `json:"bandwidth_upload,omitempty"`
+				BandwidthUploadBurst   *string `json:"bandwidth_upload_burst,omitempty"`
+				Enabled                *bool   `json:"enabled,omitempty"`
 			}{
 				Enabled: &networkEnabled,
 			},
@@ -0,0 +1,39 @@
+package api
+
+import (
+	"context"
+
+	"github.com/kernel/hypeman/lib/logger"
+	"github.com/kernel/hypeman/lib/oapi"
+	"github.com/kernel/hypeman/lib/providers"
+)
+
+// RotateLogs runs instance log rotation and retention immediately
+func (s *ApiService) RotateLogs(ctx context.Context, _ oapi.RotateLogsRequestObject) (oapi.RotateLogsResponseObject, error) {
+	log := logger.FromContext(ctx)
+
+	policy, err := providers.ParseLogRetention(s.Config)
+	if err != nil {
+		return oapi.RotateLogs500ApplicationProblemPlusJSONResponse{
+			Code:    oapi.InternalError,
+			Message: err.Error(),
+		}, nil
+	}
+
+	res, err := s.InstanceManager.RotateLogs(ctx, policy)
+	if err != nil {
+		log.ErrorContext(ctx, "log rotation failed", "error", err)
+		return oapi.RotateLogs500ApplicationProblemPlusJSONResponse{
+			Code:    oapi.InternalError,
+			Message: "log rotation failed: " + err.Error(),
+		}, nil
+	}
+	log.InfoContext(ctx, "log rotation completed", "rotated", res.Rotated, "removed", res.Removed, "removed_bytes", res.RemovedBytes, "total_bytes", res.TotalBytes)
+
+	return oapi.RotateLogs200JSONResponse{
+		Rotated:      res.Rotated,
+		Removed:      res.Removed,
+		RemovedBytes: res.RemovedBytes,
+		TotalBytes:   res.TotalBytes,
+	}, nil
+}
@@ -0,0 +1,91 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/kernel/hypeman/lib/logger"
+	mw "github.com/kernel/hypeman/lib/middleware"
+	"github.com/kernel/hypeman/lib/oapi"
+	"github.com/kernel/hypeman/lib/scheduler"
+	"github.com/samber/lo"
+)
+
+// ListMaintenanceTasks lists the maintenance tasks and their last runs
+func (s *ApiService) ListMaintenanceTasks(ctx context.Context, _ oapi.ListMaintenanceTasksRequestObject) (oapi.ListMaintenanceTasksResponseObject, error) {
+	tasks := s.Scheduler.List()
+	resp := make(oapi.ListMaintenanceTasks200JSONResponse, 0, len(tasks))
+	for _, t := range tasks {
+		resp = append(resp, maintenanceTaskToOAPI(t))
+	}
+	return resp, nil
+}
+
+// RunMaintenanceTask runs a maintenance task now and waits for it to finish
+func (s *ApiService) RunMaintenanceTask(ctx context.Context, request oapi.RunMaintenanceTaskRequestObject) (oapi.RunMaintenanceTaskResponseObject, error) {
+	log := logger.FromContext(ctx)
+
+	// Tasks act on every tenant's resources
+	if mw.TenantFromContext(ctx) != "" {
+		return oapi.RunMaintenanceTask403ApplicationProblemPlusJSONResponse{
+			Code:    oapi.Forbidden,
+			Message: "maintenance tasks apply to all tenants and are not permitted for tenant-scoped principals",
+		}, nil
+	}
+
+	status, err := s.Scheduler.Trigger(ctx, request.Task)
+	if err != nil {
+		switch {
+		case errors.Is(err, scheduler.ErrTaskNotFound):
+			return oapi.RunMaintenanceTask404ApplicationProblemPlusJSONResponse{
+				Code:    oapi.NotFound,
+				Message: fmt.Sprintf("maintenance task %q not found", request.Task),
+			}, nil
+		case errors.Is(err, scheduler.ErrTaskRunning):
+			return oapi.RunMaintenanceTask409ApplicationProblemPlusJSONResponse{
+				Code:    oapi.Conflict,
+				Message: fmt.Sprintf("maintenance task %q is already running", request.Task),
+			}, nil
+		default:
+			log.ErrorContext(ctx, "failed to run maintenance task", "task", request.Task, "error", err)
+			return oapi.RunMaintenanceTask500ApplicationProblemPlusJSONResponse{
+				Code:    oapi.InternalError,
+				Message: "failed to run maintenance task",
+			}, nil
+		}
+	}
+	return oapi.RunMaintenanceTask200JSONResponse(maintenanceTaskToOAPI(status)), nil
+}
+
+// CollectGarbage prunes dangling images and orphan build volumes. It backs
+// the gc maintenance task.
+func (s *ApiService) CollectGarbage(ctx context.Context) (string, error) {
+	removed, reclaimed, err := s.prune(ctx, oapi.PruneRequest{
+		DanglingImages:     lo.ToPtr(true),
+		OrphanBuildVolumes: lo.ToPtr(true),
+	}, false)
+	summary := fmt.Sprintf("removed %d resources, reclaimed %d bytes", len(removed), reclaimed)
+	return summary, err
+}
+
+func maintenanceTaskToOAPI(t scheduler.TaskStatus) oapi.MaintenanceTask {
+	out := oapi.MaintenanceTask{
+		Name:        oapi.MaintenanceTaskName(t.Name),
+		Description: t.Description,
+		Running:     t.Running,
+		NextRunAt:   t.NextRunAt,
+		LastRunAt:   t.LastRunAt,
+	}
+	if t.Schedule != "" {
+		out.Schedule = lo.ToPtr(t.Schedule)
+	}
+	if t.LastRunAt != nil {
+		out.LastDurationMs = lo.ToPtr(t.LastDuration.Milliseconds())
+		out.LastResult = lo.ToPtr(t.LastResult)
+	}
+	if t.LastError != "" {
+		out.LastError = lo.ToPtr(t.LastError)
+	}
+	return out
+}
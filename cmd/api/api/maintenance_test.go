@@ -0,0 +1,63 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	mw "github.com/kernel/hypeman/lib/middleware"
+	"github.com/kernel/hypeman/lib/oapi"
+	"github.com/kernel/hypeman/lib/scheduler"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMaintenanceTasks(t *testing.T) {
+	sched := scheduler.New()
+	require.NoError(t, sched.Add("tap-cleanup", "Remove orphaned TAP devices", "*/30 * * * *", func(ctx context.Context) (string, error) {
+		return "removed 2 TAP devices", nil
+	}))
+	require.NoError(t, sched.Add("gc", "Prune unused data", "", func(ctx context.Context) (string, error) {
+		return "", errors.New("disk on fire")
+	}))
+	svc := &ApiService{Scheduler: sched}
+
+	listResp, err := svc.ListMaintenanceTasks(ctx(), oapi.ListMaintenanceTasksRequestObject{})
+	require.NoError(t, err)
+	list, ok := listResp.(oapi.ListMaintenanceTasks200JSONResponse)
+	require.True(t, ok, "expected 200, got %T", listResp)
+	require.Len(t, list, 2)
+	assert.Equal(t, oapi.MaintenanceTaskName("tap-cleanup"), list[0].Name)
+	require.NotNil(t, list[0].Schedule)
+	assert.Equal(t, "*/30 * * * *", *list[0].Schedule)
+	assert.NotNil(t, list[0].NextRunAt)
+	assert.Nil(t, list[0].LastRunAt)
+	assert.Nil(t, list[1].Schedule)
+	assert.Nil(t, list[1].NextRunAt)
+
+	resp, err := svc.RunMaintenanceTask(ctx(), oapi.RunMaintenanceTaskRequestObject{Task: "tap-cleanup"})
+	require.NoError(t, err)
+	ran, ok := resp.(oapi.RunMaintenanceTask200JSONResponse)
+	require.True(t, ok, "expected 200, got %T", resp)
+	require.NotNil(t, ran.LastResult)
+	assert.Equal(t, "removed 2 TAP devices", *ran.LastResult)
+	assert.NotNil(t, ran.LastRunAt)
+	assert.Nil(t, ran.LastError)
+
+	// A failed run is still a completed run
+	resp, err = svc.RunMaintenanceTask(ctx(), oapi.RunMaintenanceTaskRequestObject{Task: "gc"})
+	require.NoError(t, err)
+	ran, ok = resp.(oapi.RunMaintenanceTask200JSONResponse)
+	require.True(t, ok, "expected 200, got %T", resp)
+	require.NotNil(t, ran.LastError)
+	assert.Equal(t, "disk on fire", *ran.LastError)
+
+	resp, err = svc.RunMaintenanceTask(ctx(), oapi.RunMaintenanceTaskRequestObject{Task: "defrag"})
+	require.NoError(t, err)
+	assert.IsType(t, oapi.RunMaintenanceTask404ApplicationProblemPlusJSONResponse{}, resp)
+
+	tenantCtx := mw.WithClaims(ctx(), &mw.Claims{Subject: "ci", Role: mw.RoleAdmin, Tenant: "team-a"})
+	resp, err = svc.RunMaintenanceTask(tenantCtx, oapi.RunMaintenanceTaskRequestObject{Task: "gc"})
+	require.NoError(t, err)
+	assert.IsType(t, oapi.RunMaintenanceTask403ApplicationProblemPlusJSONResponse{}, resp)
+}
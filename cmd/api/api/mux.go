@@ -0,0 +1,323 @@
+package api
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/gorilla/websocket"
+	"github.com/kernel/hypeman/lib/logger"
+	"github.com/kernel/hypeman/lib/oapi"
+	"github.com/kernel/hypeman/lib/problem"
+)
+
+// Frame types of the multiplexed connection. Every frame is a binary
+// WebSocket message: a type byte, a big-endian uint32 channel ID chosen by
+// the client, then the payload. Text and binary match the WebSocket message
+// types they carry.
+const (
+	muxOpen     byte = 0 // client: open a channel, payload is a muxOpenRequest
+	muxText     byte = 1 // a text message of an exec or cp session
+	muxBinary   byte = 2 // a binary message of a session, or a chunk of a response body
+	muxResponse byte = 3 // server: response status and headers, 101 for sessions
+	muxClose    byte = 8 // either: close a channel, the server's payload may be a muxCloseReason
+)
+
+const (
+	muxHeaderSize = 5
+	// maxMuxChannels bounds the open channels of one connection
+	maxMuxChannels = 4096
+	// muxQueueSize is how many client messages a channel buffers before the
+	// connection's reads wait for it
+	muxQueueSize = 64
+)
+
+// muxOpenRequest opens a channel for a GET request, like
+// "/instances/web/logs?follow=true" or "/instances/web/exec"
+type muxOpenRequest struct {
+	Path string `json:"path"`
+}
+
+// muxResponseHeader is the status and headers a channel's request was
+// answered with
+type muxResponseHeader struct {
+	Status  int         `json:"status"`
+	Headers http.Header `json:"headers,omitempty"`
+}
+
+// muxCloseReason explains why the server closed a channel it couldn't open
+type muxCloseReason struct {
+	Error string `json:"error"`
+}
+
+// wsConn is the message-oriented connection exec and cp sessions run over:
+// a WebSocket, or a channel of a multiplexed connection
+type wsConn interface {
+	ReadMessage() (messageType int, p []byte, err error)
+	WriteMessage(messageType int, data []byte) error
+	Close() error
+}
+
+type muxChannelKey struct{}
+
+// upgrade starts a session on a WebSocket upgrade request, or on the
+// multiplexed channel the request was dispatched on
+func upgrade(w http.ResponseWriter, r *http.Request) (wsConn, error) {
+	if ch, ok := r.Context().Value(muxChannelKey{}).(*muxChannel); ok {
+		return ch, ch.accept()
+	}
+	return upgrader.Upgrade(w, r, nil)
+}
+
+// MuxHandler serves a WebSocket multiplexing many requests, so clients
+// watching many instances need one connection per host. Each channel is
+// dispatched through router as its own GET request with this connection's
+// credentials, so authentication, authorization and limits apply per
+// channel. Exec and cp sessions run over their channel; other responses,
+// like log and event streams, are sent as a response frame followed by
+// body chunks.
+func MuxHandler(router http.Handler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		log := logger.FromContext(ctx)
+
+		if ctx.Value(muxChannelKey{}) != nil {
+			problem.Write(w, http.StatusBadRequest, oapi.InvalidRequest, "multiplexed connections can't be nested")
+			return
+		}
+
+		ws, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			log.ErrorContext(ctx, "websocket upgrade failed", "error", err)
+			return
+		}
+		defer ws.Close()
+
+		ctx, cancel := context.WithCancel(ctx)
+		conn := &muxConn{ws: ws, channels: make(map[uint32]*muxChannel)}
+		defer conn.wg.Wait()
+		defer cancel()
+
+		// Channels carry the connection's credentials but not its upgrade
+		header := r.Header.Clone()
+		for name := range header {
+			if name == "Upgrade" || name == "Connection" || strings.HasPrefix(name, "Sec-Websocket-") {
+				header.Del(name)
+			}
+		}
+
+		for {
+			msgType, data, err := ws.ReadMessage()
+			if err != nil {
+				if !websocket.IsCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway) {
+					log.DebugContext(ctx, "multiplexed connection closed", "error", err)
+				}
+				return
+			}
+			if msgType != websocket.BinaryMessage || len(data) < muxHeaderSize {
+				ws.WriteMessage(websocket.CloseMessage,
+					websocket.FormatCloseMessage(websocket.CloseProtocolError, "frames must be binary with a 5 byte header"))
+				return
+			}
+			typ, id, payload := data[0], binary.BigEndian.Uint32(data[1:muxHeaderSize]), data[muxHeaderSize:]
+
+			switch typ {
+			case muxOpen:
+				var open muxOpenRequest
+				if err := json.Unmarshal(payload, &open); err != nil || !strings.HasPrefix(open.Path, "/") {
+					conn.reject(id, "open payload must be JSON with an absolute path")
+					continue
+				}
+				req, err := http.NewRequest(http.MethodGet, open.Path, nil)
+				if err != nil {
+					conn.reject(id, fmt.Sprintf("invalid path: %v", err))
+					continue
+				}
+				ch, err := conn.open(ctx, id)
+				if err != nil {
+					conn.reject(id, err.Error())
+					continue
+				}
+				req = req.WithContext(context.WithValue(ch.ctx, muxChannelKey{}, ch))
+				req.Header = header.Clone()
+				req.Host = r.Host
+				req.RemoteAddr = r.RemoteAddr
+				req.RequestURI = open.Path
+				conn.wg.Add(1)
+				go func() {
+					defer conn.wg.Done()
+					defer conn.close(ch)
+					router.ServeHTTP(ch, req)
+					ch.finish()
+				}()
+			case muxText, muxBinary:
+				if ch := conn.channel(id); ch != nil {
+					select {
+					case ch.in <- muxMessage{typ: int(typ), data: payload}:
+					case <-ch.ctx.Done():
+					}
+				}
+			case muxClose:
+				if ch := conn.channel(id); ch != nil {
+					ch.cancel()
+				}
+			default:
+				ws.WriteMessage(websocket.CloseMessage,
+					websocket.FormatCloseMessage(websocket.CloseProtocolError, fmt.Sprintf("unknown frame type %d", typ)))
+				return
+			}
+		}
+	}
+}
+
+// muxConn is a multiplexed connection and its open channels
+type muxConn struct {
+	ws      *websocket.Conn
+	writeMu sync.Mutex
+	wg      sync.WaitGroup
+
+	mu       sync.Mutex
+	channels map[uint32]*muxChannel
+}
+
+func (c *muxConn) send(typ byte, id uint32, payload []byte) error {
+	frame := make([]byte, muxHeaderSize+len(payload))
+	frame[0] = typ
+	binary.BigEndian.PutUint32(frame[1:muxHeaderSize], id)
+	copy(frame[muxHeaderSize:], payload)
+
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	return c.ws.WriteMessage(websocket.BinaryMessage, frame)
+}
+
+func (c *muxConn) reject(id uint32, reason string) {
+	payload, _ := json.Marshal(muxCloseReason{Error: reason})
+	c.send(muxClose, id, payload)
+}
+
+func (c *muxConn) open(ctx context.Context, id uint32) (*muxChannel, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.channels[id]; ok {
+		return nil, fmt.Errorf("channel %d is already open", id)
+	}
+	if len(c.channels) >= maxMuxChannels {
+		return nil, fmt.Errorf("too many open channels (max %d)", maxMuxChannels)
+	}
+	ch := &muxChannel{conn: c, id: id, header: http.Header{}, in: make(chan muxMessage, muxQueueSize)}
+	ch.ctx, ch.cancel = context.WithCancel(ctx)
+	c.channels[id] = ch
+	return ch, nil
+}
+
+func (c *muxConn) channel(id uint32) *muxChannel {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.channels[id]
+}
+
+// close forgets a channel whose request is done, and tells the client
+func (c *muxConn) close(ch *muxChannel) {
+	ch.cancel()
+	c.mu.Lock()
+	delete(c.channels, ch.id)
+	c.mu.Unlock()
+	c.send(muxClose, ch.id, nil)
+}
+
+type muxMessage struct {
+	typ  int
+	data []byte
+}
+
+// muxChannel is one request of a multiplexed connection. It is the
+// request's http.ResponseWriter, and the wsConn of exec and cp sessions.
+type muxChannel struct {
+	conn   *muxConn
+	id     uint32
+	ctx    context.Context
+	cancel context.CancelFunc
+	in     chan muxMessage
+
+	header      http.Header
+	wroteHeader bool
+}
+
+func (ch *muxChannel) Header() http.Header {
+	return ch.header
+}
+
+func (ch *muxChannel) WriteHeader(status int) {
+	if ch.wroteHeader {
+		return
+	}
+	ch.wroteHeader = true
+	payload, _ := json.Marshal(muxResponseHeader{Status: status, Headers: ch.header})
+	ch.conn.send(muxResponse, ch.id, payload)
+}
+
+func (ch *muxChannel) Write(p []byte) (int, error) {
+	if !ch.wroteHeader {
+		ch.WriteHeader(http.StatusOK)
+	}
+	if len(p) == 0 {
+		return 0, nil
+	}
+	if err := ch.WriteMessage(websocket.BinaryMessage, p); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// Flush is a no-op: every write is sent as a frame right away
+func (ch *muxChannel) Flush() {}
+
+// accept answers the channel's request as a session
+func (ch *muxChannel) accept() error {
+	if ch.wroteHeader {
+		return errors.New("response already started")
+	}
+	ch.WriteHeader(http.StatusSwitchingProtocols)
+	return nil
+}
+
+// finish answers requests whose handler wrote nothing
+func (ch *muxChannel) finish() {
+	if !ch.wroteHeader {
+		ch.WriteHeader(http.StatusOK)
+	}
+}
+
+// ReadMessage returns the client's next message, or a normal closure once
+// the client closed the channel
+func (ch *muxChannel) ReadMessage() (int, []byte, error) {
+	select {
+	case m := <-ch.in:
+		return m.typ, m.data, nil
+	default:
+	}
+	select {
+	case m := <-ch.in:
+		return m.typ, m.data, nil
+	case <-ch.ctx.Done():
+		return 0, nil, &websocket.CloseError{Code: websocket.CloseNormalClosure, Text: "channel closed"}
+	}
+}
+
+func (ch *muxChannel) WriteMessage(messageType int, data []byte) error {
+	if ch.ctx.Err() != nil {
+		return &websocket.CloseError{Code: websocket.CloseNormalClosure, Text: "channel closed"}
+	}
+	return ch.conn.send(byte(messageType), ch.id, data)
+}
+
+// Close is a no-op: the channel closes when its request is done
+func (ch *muxChannel) Close() error {
+	return nil
+}
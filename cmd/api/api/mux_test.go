@@ -0,0 +1,107 @@
+package api
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type muxFrame struct {
+	typ     byte
+	id      uint32
+	payload []byte
+}
+
+func sendMuxFrame(t *testing.T, ws *websocket.Conn, typ byte, id uint32, payload []byte) {
+	t.Helper()
+	frame := append([]byte{typ, 0, 0, 0, 0}, payload...)
+	binary.BigEndian.PutUint32(frame[1:5], id)
+	require.NoError(t, ws.WriteMessage(websocket.BinaryMessage, frame))
+}
+
+func readMuxFrame(t *testing.T, ws *websocket.Conn) muxFrame {
+	t.Helper()
+	msgType, data, err := ws.ReadMessage()
+	require.NoError(t, err)
+	require.Equal(t, websocket.BinaryMessage, msgType)
+	require.GreaterOrEqual(t, len(data), muxHeaderSize)
+	return muxFrame{typ: data[0], id: binary.BigEndian.Uint32(data[1:5]), payload: data[5:]}
+}
+
+func TestMuxHandler(t *testing.T) {
+	r := chi.NewRouter()
+	r.Get("/hello", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		w.Write([]byte("hello " + r.Header.Get("X-API-Key")))
+	})
+	r.Get("/echo", func(w http.ResponseWriter, r *http.Request) {
+		ws, err := upgrade(w, r)
+		if err != nil {
+			return
+		}
+		defer ws.Close()
+		for {
+			msgType, data, err := ws.ReadMessage()
+			if err != nil {
+				return
+			}
+			ws.WriteMessage(msgType, data)
+		}
+	})
+	srv := httptest.NewServer(MuxHandler(r))
+	defer srv.Close()
+
+	header := http.Header{"X-API-Key": {"secret"}}
+	ws, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http"), header)
+	require.NoError(t, err)
+	defer ws.Close()
+
+	// A plain request gets its response header, body and close
+	sendMuxFrame(t, ws, muxOpen, 1, []byte(`{"path":"/hello"}`))
+	f := readMuxFrame(t, ws)
+	require.Equal(t, muxResponse, f.typ)
+	var resp muxResponseHeader
+	require.NoError(t, json.Unmarshal(f.payload, &resp))
+	assert.Equal(t, http.StatusOK, resp.Status)
+	assert.Equal(t, "text/plain", resp.Headers.Get("Content-Type"))
+	f = readMuxFrame(t, ws)
+	assert.Equal(t, muxFrame{typ: muxBinary, id: 1, payload: []byte("hello secret")}, f)
+	f = readMuxFrame(t, ws)
+	assert.Equal(t, muxClose, f.typ)
+	assert.Equal(t, uint32(1), f.id)
+
+	// A session exchanges messages until the client closes it
+	sendMuxFrame(t, ws, muxOpen, 2, []byte(`{"path":"/echo"}`))
+	f = readMuxFrame(t, ws)
+	require.Equal(t, muxResponse, f.typ)
+	require.NoError(t, json.Unmarshal(f.payload, &resp))
+	assert.Equal(t, http.StatusSwitchingProtocols, resp.Status)
+
+	sendMuxFrame(t, ws, muxOpen, 2, []byte(`{"path":"/echo"}`))
+	f = readMuxFrame(t, ws)
+	assert.Equal(t, muxClose, f.typ)
+	assert.Contains(t, string(f.payload), "already open")
+
+	sendMuxFrame(t, ws, muxText, 2, []byte("ping"))
+	f = readMuxFrame(t, ws)
+	assert.Equal(t, muxFrame{typ: muxText, id: 2, payload: []byte("ping")}, f)
+
+	sendMuxFrame(t, ws, muxClose, 2, nil)
+	f = readMuxFrame(t, ws)
+	assert.Equal(t, muxClose, f.typ)
+	assert.Equal(t, uint32(2), f.id)
+
+	// Invalid opens are rejected without affecting the connection
+	sendMuxFrame(t, ws, muxOpen, 3, []byte(`{"path":"relative"}`))
+	f = readMuxFrame(t, ws)
+	assert.Equal(t, muxClose, f.typ)
+	assert.Contains(t, string(f.payload), "absolute path")
+}
@@ -0,0 +1,303 @@
+package api
+
+import (
+	"context"
+	"errors"
+
+	"github.com/kernel/hypeman/lib/network"
+	"github.com/kernel/hypeman/lib/oapi"
+)
+
+// GetNetworkDNS returns the custom DNS records and search domains of a network
+func (s *ApiService) GetNetworkDNS(ctx context.Context, request oapi.GetNetworkDNSRequestObject) (oapi.GetNetworkDNSResponseObject, error) {
+	cfg, err := s.NetworkManager.GetDNSConfig(ctx, request.Network)
+	if err != nil {
+		if errors.Is(err, network.ErrNotFound) {
+			return oapi.GetNetworkDNS404ApplicationProblemPlusJSONResponse{
+				Code:    oapi.NotFound,
+				Message: "network not found",
+			}, nil
+		}
+		return oapi.GetNetworkDNS500ApplicationProblemPlusJSONResponse{
+			Code:    oapi.InternalError,
+			Message: err.Error(),
+		}, nil
+	}
+
+	return oapi.GetNetworkDNS200JSONResponse(networkDNSToOAPI(request.Network, cfg)), nil
+}
+
+// SetNetworkSearchDomains replaces the search domains of a network
+func (s *ApiService) SetNetworkSearchDomains(ctx context.Context, request oapi.SetNetworkSearchDomainsRequestObject) (oapi.SetNetworkSearchDomainsResponseObject, error) {
+	cfg, err := s.NetworkManager.SetSearchDomains(ctx, request.Network, request.Body.SearchDomains)
+	if err != nil {
+		switch {
+		case errors.Is(err, network.ErrNotFound):
+			return oapi.SetNetworkSearchDomains404ApplicationProblemPlusJSONResponse{
+				Code:    oapi.NotFound,
+				Message: "network not found",
+			}, nil
+		case errors.Is(err, network.ErrInvalidSearchDomain):
+			return oapi.SetNetworkSearchDomains400ApplicationProblemPlusJSONResponse{
+				Code:    oapi.InvalidRequest,
+				Message: err.Error(),
+			}, nil
+		default:
+			return oapi.SetNetworkSearchDomains500ApplicationProblemPlusJSONResponse{
+				Code:    oapi.InternalError,
+				Message: err.Error(),
+			}, nil
+		}
+	}
+
+	return oapi.SetNetworkSearchDomains200JSONResponse(networkDNSToOAPI(request.Network, cfg)), nil
+}
+
+// SetNetworkDNSDomain sets the domain a network's instances are served under
+func (s *ApiService) SetNetworkDNSDomain(ctx context.Context, request oapi.SetNetworkDNSDomainRequestObject) (oapi.SetNetworkDNSDomainResponseObject, error) {
+	cfg, err := s.NetworkManager.SetDNSDomain(ctx, request.Network, request.Body.Domain)
+	if err != nil {
+		switch {
+		case errors.Is(err, network.ErrNotFound):
+			return oapi.SetNetworkDNSDomain404ApplicationProblemPlusJSONResponse{
+				Code:    oapi.NotFound,
+				Message: "network not found",
+			}, nil
+		case errors.Is(err, network.ErrInvalidDNSDomain):
+			return oapi.SetNetworkDNSDomain400ApplicationProblemPlusJSONResponse{
+				Code:    oapi.InvalidRequest,
+				Message: err.Error(),
+			}, nil
+		default:
+			return oapi.SetNetworkDNSDomain500ApplicationProblemPlusJSONResponse{
+				Code:    oapi.InternalError,
+				Message: err.Error(),
+			}, nil
+		}
+	}
+
+	return oapi.SetNetworkDNSDomain200JSONResponse(networkDNSToOAPI(request.Network, cfg)), nil
+}
+
+// CreateNetworkDNSRecord adds a static DNS record to a network
+func (s *ApiService) CreateNetworkDNSRecord(ctx context.Context, request oapi.CreateNetworkDNSRecordRequestObject) (oapi.CreateNetworkDNSRecordResponseObject, error) {
+	record, err := s.NetworkManager.AddDNSRecord(ctx, request.Network, network.DNSRecord{
+		Name: request.Body.Name,
+		IP:   request.Body.Ip,
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, network.ErrNotFound):
+			return oapi.CreateNetworkDNSRecord404ApplicationProblemPlusJSONResponse{
+				Code:    oapi.NotFound,
+				Message: "network not found",
+			}, nil
+		case errors.Is(err, network.ErrInvalidDNSRecord):
+			return oapi.CreateNetworkDNSRecord400ApplicationProblemPlusJSONResponse{
+				Code:    oapi.InvalidRequest,
+				Message: err.Error(),
+			}, nil
+		case errors.Is(err, network.ErrDNSRecordExists):
+			return oapi.CreateNetworkDNSRecord409ApplicationProblemPlusJSONResponse{
+				Code:    oapi.AlreadyExists,
+				Message: err.Error(),
+			}, nil
+		default:
+			return oapi.CreateNetworkDNSRecord500ApplicationProblemPlusJSONResponse{
+				Code:    oapi.InternalError,
+				Message: err.Error(),
+			}, nil
+		}
+	}
+
+	return oapi.CreateNetworkDNSRecord201JSONResponse(oapi.DNSRecord{Name: record.Name, Ip: record.IP}), nil
+}
+
+// DeleteNetworkDNSRecord removes a static DNS record from a network
+func (s *ApiService) DeleteNetworkDNSRecord(ctx context.Context, request oapi.DeleteNetworkDNSRecordRequestObject) (oapi.DeleteNetworkDNSRecordResponseObject, error) {
+	err := s.NetworkManager.DeleteDNSRecord(ctx, request.Network, request.Name)
+	if err != nil {
+		switch {
+		case errors.Is(err, network.ErrNotFound):
+			return oapi.DeleteNetworkDNSRecord404ApplicationProblemPlusJSONResponse{
+				Code:    oapi.NotFound,
+				Message: "network not found",
+			}, nil
+		case errors.Is(err, network.ErrDNSRecordNotFound):
+			return oapi.DeleteNetworkDNSRecord404ApplicationProblemPlusJSONResponse{
+				Code:    oapi.NotFound,
+				Message: "DNS record not found",
+			}, nil
+		default:
+			return oapi.DeleteNetworkDNSRecord500ApplicationProblemPlusJSONResponse{
+				Code:    oapi.InternalError,
+				Message: err.Error(),
+			}, nil
+		}
+	}
+
+	return oapi.DeleteNetworkDNSRecord204Response{}, nil
+}
+
+// GetNetworkDHCP returns the DHCP options of a network
+func (s *ApiService) GetNetworkDHCP(ctx context.Context, request oapi.GetNetworkDHCPRequestObject) (oapi.GetNetworkDHCPResponseObject, error) {
+	opts, err := s.NetworkManager.GetDHCPOptions(ctx, request.Network)
+	if err != nil {
+		if errors.Is(err, network.ErrNotFound) {
+			return oapi.GetNetworkDHCP404ApplicationProblemPlusJSONResponse{
+				Code:    oapi.NotFound,
+				Message: "network not found",
+			}, nil
+		}
+		return oapi.GetNetworkDHCP500ApplicationProblemPlusJSONResponse{
+			Code:    oapi.InternalError,
+			Message: err.Error(),
+		}, nil
+	}
+
+	return oapi.GetNetworkDHCP200JSONResponse(networkDHCPToOAPI(request.Network, opts)), nil
+}
+
+// SetNetworkDHCP replaces the DHCP options of a network
+func (s *ApiService) SetNetworkDHCP(ctx context.Context, request oapi.SetNetworkDHCPRequestObject) (oapi.SetNetworkDHCPResponseObject, error) {
+	opts, err := s.NetworkManager.SetDHCPOptions(ctx, request.Network, dhcpOptionsFromOAPI(*request.Body))
+	if err != nil {
+		switch {
+		case errors.Is(err, network.ErrNotFound):
+			return oapi.SetNetworkDHCP404ApplicationProblemPlusJSONResponse{
+				Code:    oapi.NotFound,
+				Message: "network not found",
+			}, nil
+		case errors.Is(err, network.ErrInvalidDHCPOptions):
+			return oapi.SetNetworkDHCP400ApplicationProblemPlusJSONResponse{
+				Code:    oapi.InvalidRequest,
+				Message: err.Error(),
+			}, nil
+		default:
+			return oapi.SetNetworkDHCP500ApplicationProblemPlusJSONResponse{
+				Code:    oapi.InternalError,
+				Message: err.Error(),
+			}, nil
+		}
+	}
+
+	return oapi.SetNetworkDHCP200JSONResponse(networkDHCPToOAPI(request.Network, opts)), nil
+}
+
+// GetNetworkUplink returns how a network reaches the host's network
+func (s *ApiService) GetNetworkUplink(ctx context.Context, request oapi.GetNetworkUplinkRequestObject) (oapi.GetNetworkUplinkResponseObject, error) {
+	uplink, err := s.NetworkManager.GetUplink(ctx, request.Network)
+	if err != nil {
+		if errors.Is(err, network.ErrNotFound) {
+			return oapi.GetNetworkUplink404ApplicationProblemPlusJSONResponse{
+				Code:    oapi.NotFound,
+				Message: err.Error(),
+			}, nil
+		}
+		return oapi.GetNetworkUplink500ApplicationProblemPlusJSONResponse{
+			Code:    oapi.InternalError,
+			Message: err.Error(),
+		}, nil
+	}
+
+	return oapi.GetNetworkUplink200JSONResponse(networkUplinkToOAPI(request.Network, uplink)), nil
+}
+
+// SetNetworkUplink changes how a network reaches the host's network
+func (s *ApiService) SetNetworkUplink(ctx context.Context, request oapi.SetNetworkUplinkRequestObject) (oapi.SetNetworkUplinkResponseObject, error) {
+	req := network.Uplink{Mode: string(request.Body.Mode)}
+	if request.Body.Interface != nil {
+		req.Interface = *request.Body.Interface
+	}
+	if request.Body.Vlan != nil {
+		req.VLAN = *request.Body.Vlan
+	}
+
+	uplink, err := s.NetworkManager.SetUplink(ctx, request.Network, req)
+	if err != nil {
+		switch {
+		case errors.Is(err, network.ErrNotFound):
+			return oapi.SetNetworkUplink404ApplicationProblemPlusJSONResponse{
+				Code:    oapi.NotFound,
+				Message: err.Error(),
+			}, nil
+		case errors.Is(err, network.ErrInvalidUplink):
+			return oapi.SetNetworkUplink400ApplicationProblemPlusJSONResponse{
+				Code:    oapi.InvalidRequest,
+				Message: err.Error(),
+			}, nil
+		case errors.Is(err, network.ErrUplinkInUse):
+			return oapi.SetNetworkUplink409ApplicationProblemPlusJSONResponse{
+				Code:    oapi.InUse,
+				Message: err.Error(),
+			}, nil
+		default:
+			return oapi.SetNetworkUplink500ApplicationProblemPlusJSONResponse{
+				Code:    oapi.InternalError,
+				Message: err.Error(),
+			}, nil
+		}
+	}
+
+	return oapi.SetNetworkUplink200JSONResponse(networkUplinkToOAPI(request.Network, uplink)), nil
+}
+
+func networkUplinkToOAPI(name string, uplink *network.Uplink) oapi.NetworkUplink {
+	out := oapi.NetworkUplink{
+		Network: name,
+		Mode:    oapi.UplinkMode(uplink.Mode),
+	}
+	if uplink.Interface != "" {
+		out.Interface = &uplink.Interface
+	}
+	if uplink.VLAN > 0 {
+		out.Vlan = &uplink.VLAN
+	}
+	return out
+}
+
+func dhcpOptionsFromOAPI(body oapi.DHCPOptions) network.DHCPOptions {
+	var opts network.DHCPOptions
+	if body.Mtu != nil {
+		opts.MTU = *body.Mtu
+	}
+	if body.NtpServers != nil {
+		opts.NTPServers = *body.NtpServers
+	}
+	if body.Routes != nil {
+		for _, r := range *body.Routes {
+			opts.Routes = append(opts.Routes, network.Route{Destination: r.Destination, Gateway: r.Gateway})
+		}
+	}
+	return opts
+}
+
+func networkDHCPToOAPI(name string, opts *network.DHCPOptions) oapi.NetworkDHCP {
+	routes := make([]oapi.NetworkRoute, len(opts.Routes))
+	for i, r := range opts.Routes {
+		routes[i] = oapi.NetworkRoute{Destination: r.Destination, Gateway: r.Gateway}
+	}
+	out := oapi.NetworkDHCP{
+		Network:    name,
+		NtpServers: opts.NTPServers,
+		Routes:     routes,
+	}
+	if opts.MTU > 0 {
+		out.Mtu = &opts.MTU
+	}
+	return out
+}
+
+func networkDNSToOAPI(name string, cfg *network.DNSConfig) oapi.NetworkDNS {
+	records := make([]oapi.DNSRecord, len(cfg.Records))
+	for i, r := range cfg.Records {
+		records[i] = oapi.DNSRecord{Name: r.Name, Ip: r.IP}
+	}
+	return oapi.NetworkDNS{
+		Network:       name,
+		Records:       records,
+		SearchDomains: cfg.SearchDomains,
+		Domain:        cfg.Domain,
+		ServiceDomain: cfg.ServiceDomain(name),
+	}
+}
@@ -0,0 +1,157 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/kernel/hypeman/lib/images"
+	"github.com/kernel/hypeman/lib/instances"
+	"github.com/kernel/hypeman/lib/logger"
+	"github.com/kernel/hypeman/lib/nodeagent"
+	"github.com/kernel/hypeman/lib/oapi"
+	"github.com/samber/lo"
+)
+
+// GetNode returns the node's control plane registration and reported capacity
+func (s *ApiService) GetNode(ctx context.Context, _ oapi.GetNodeRequestObject) (oapi.GetNodeResponseObject, error) {
+	status, err := s.NodeAgent.Status(ctx)
+	if err != nil {
+		logger.FromContext(ctx).ErrorContext(ctx, "failed to get node status", "error", err)
+		return oapi.GetNode500ApplicationProblemPlusJSONResponse{
+			Code:    oapi.InternalError,
+			Message: "failed to get node status",
+		}, nil
+	}
+
+	resp := oapi.GetNode200JSONResponse{
+		NodeId:          status.NodeID,
+		Address:         lo.EmptyableToPtr(status.Address),
+		ControlPlaneUrl: lo.EmptyableToPtr(status.ControlPlaneURL),
+		Registered:      status.Registered,
+		LastHeartbeat:   status.LastHeartbeat,
+		LastError:       lo.EmptyableToPtr(status.LastError),
+		Capacity:        nodeCapacityToOAPI(status.Capacity),
+		Allocated:       nodeCapacityToOAPI(status.Allocated),
+		Reserved:        nodeCapacityToOAPI(status.Reserved),
+		Available:       nodeCapacityToOAPI(status.Available),
+		Instances:       status.Instances,
+		Slots:           make([]oapi.NodeSlot, 0, len(status.Slots)),
+	}
+	for _, slot := range status.Slots {
+		resp.Slots = append(resp.Slots, nodeSlotToOAPI(slot))
+	}
+	return resp, nil
+}
+
+// ClaimNodeSlot reserves capacity for an instance the control plane is placing
+// on this node
+func (s *ApiService) ClaimNodeSlot(ctx context.Context, request oapi.ClaimNodeSlotRequestObject) (oapi.ClaimNodeSlotResponseObject, error) {
+	req := nodeagent.ClaimSlotRequest{
+		ID:          lo.FromPtr(request.Body.Id),
+		Vcpus:       request.Body.Vcpus,
+		MemoryBytes: request.Body.MemoryBytes,
+		DiskBytes:   lo.FromPtr(request.Body.DiskBytes),
+	}
+	if request.Body.Ttl != nil {
+		ttl, err := time.ParseDuration(*request.Body.Ttl)
+		if err != nil || ttl <= 0 {
+			return oapi.ClaimNodeSlot400ApplicationProblemPlusJSONResponse{
+				Code:    oapi.InvalidRequest,
+				Message: fmt.Sprintf("ttl must be a positive duration, got %q", *request.Body.Ttl),
+			}, nil
+		}
+		req.TTL = ttl
+	}
+
+	slot, err := s.NodeAgent.ClaimSlot(ctx, req)
+	if err != nil {
+		switch {
+		case errors.Is(err, nodeagent.ErrInvalidSlot):
+			return oapi.ClaimNodeSlot400ApplicationProblemPlusJSONResponse{
+				Code:    oapi.InvalidRequest,
+				Message: err.Error(),
+			}, nil
+		case errors.Is(err, nodeagent.ErrSlotExists):
+			return oapi.ClaimNodeSlot409ApplicationProblemPlusJSONResponse{
+				Code:    oapi.AlreadyExists,
+				Message: err.Error(),
+			}, nil
+		case errors.Is(err, nodeagent.ErrInsufficientCapacity):
+			return oapi.ClaimNodeSlot409ApplicationProblemPlusJSONResponse{
+				Code:    oapi.Conflict,
+				Message: err.Error(),
+			}, nil
+		default:
+			logger.FromContext(ctx).ErrorContext(ctx, "failed to claim node slot", "error", err)
+			return oapi.ClaimNodeSlot500ApplicationProblemPlusJSONResponse{
+				Code:    oapi.InternalError,
+				Message: "failed to claim node slot",
+			}, nil
+		}
+	}
+	logger.FromContext(ctx).InfoContext(ctx, "node slot claimed", "slot_id", slot.ID, "vcpus", slot.Vcpus, "memory_bytes", slot.MemoryBytes, "expires_at", slot.ExpiresAt)
+	return oapi.ClaimNodeSlot201JSONResponse(nodeSlotToOAPI(*slot)), nil
+}
+
+// ReleaseNodeSlot returns a claimed slot's capacity to the node
+func (s *ApiService) ReleaseNodeSlot(ctx context.Context, request oapi.ReleaseNodeSlotRequestObject) (oapi.ReleaseNodeSlotResponseObject, error) {
+	if err := s.NodeAgent.ReleaseSlot(request.Id); err != nil {
+		if errors.Is(err, nodeagent.ErrSlotNotFound) {
+			return oapi.ReleaseNodeSlot404ApplicationProblemPlusJSONResponse{
+				Code:    oapi.NotFound,
+				Message: err.Error(),
+			}, nil
+		}
+		logger.FromContext(ctx).ErrorContext(ctx, "failed to release node slot", "error", err, "slot_id", request.Id)
+		return oapi.ReleaseNodeSlot500ApplicationProblemPlusJSONResponse{
+			Code:    oapi.InternalError,
+			Message: "failed to release node slot",
+		}, nil
+	}
+	return oapi.ReleaseNodeSlot204Response{}, nil
+}
+
+// instanceSlotNeeds returns the node capacity an instance created by req
+// takes, to check against the slot it consumes: its vCPUs and memory as
+// counted for quotas, and its overlay, volume overlay, scratch and swap disks
+func (s *ApiService) instanceSlotNeeds(ctx context.Context, req instances.CreateInstanceRequest) nodeagent.Capacity {
+	res := s.instanceQuotaRequest(ctx, req)
+	overlay := req.OverlaySize
+	if overlay == 0 && req.RootVolume == "" {
+		overlay = instances.DefaultOverlaySize
+		if img, err := s.ImageManager.GetImage(ctx, req.Image); err == nil && img.Firmware == images.FirmwareUEFI && img.SizeBytes != nil {
+			overlay = *img.SizeBytes
+		}
+	}
+	disk := overlay + req.SwapSize
+	if req.ScratchDisks != nil {
+		disk += int64(req.ScratchDisks.Count) * req.ScratchDisks.Size
+	}
+	for _, vol := range req.Volumes {
+		if vol.Overlay {
+			disk += vol.OverlaySize
+		}
+	}
+	return nodeagent.Capacity{Vcpus: int64(res.Vcpus), MemoryBytes: res.Memory, DiskBytes: disk}
+}
+
+func nodeCapacityToOAPI(c nodeagent.Capacity) oapi.NodeCapacity {
+	return oapi.NodeCapacity{
+		Vcpus:       c.Vcpus,
+		MemoryBytes: c.MemoryBytes,
+		DiskBytes:   c.DiskBytes,
+	}
+}
+
+func nodeSlotToOAPI(slot nodeagent.Slot) oapi.NodeSlot {
+	return oapi.NodeSlot{
+		Id:          slot.ID,
+		Vcpus:       slot.Vcpus,
+		MemoryBytes: slot.MemoryBytes,
+		DiskBytes:   slot.DiskBytes,
+		ClaimedAt:   slot.ClaimedAt,
+		ExpiresAt:   slot.ExpiresAt,
+	}
+}
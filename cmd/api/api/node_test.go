@@ -0,0 +1,124 @@
+package api
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/kernel/hypeman/lib/nodeagent"
+	"github.com/kernel/hypeman/lib/oapi"
+	"github.com/kernel/hypeman/lib/resources"
+	"github.com/samber/lo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fixedCapacity reports a host with 4 vCPUs and 8GB of memory, none allocated
+type fixedCapacity struct{}
+
+func (fixedCapacity) GetFullStatus(ctx context.Context) (*resources.FullResourceStatus, error) {
+	return &resources.FullResourceStatus{
+		CPU:    resources.ResourceStatus{EffectiveLimit: 4},
+		Memory: resources.ResourceStatus{EffectiveLimit: 8 << 30},
+		Disk:   resources.ResourceStatus{EffectiveLimit: 100 << 30},
+	}, nil
+}
+
+func TestNodeSlots(t *testing.T) {
+	svc := &ApiService{NodeAgent: nodeagent.NewAgent(nodeagent.Config{NodeID: "node-1", SlotTTL: time.Minute}, fixedCapacity{})}
+
+	resp, err := svc.ClaimNodeSlot(ctx(), oapi.ClaimNodeSlotRequestObject{Body: &oapi.ClaimNodeSlotRequest{
+		Id: lo.ToPtr("placement-1"), Vcpus: 3, MemoryBytes: 4 << 30, Ttl: lo.ToPtr("30s"),
+	}})
+	require.NoError(t, err)
+	claimed, ok := resp.(oapi.ClaimNodeSlot201JSONResponse)
+	require.True(t, ok, "expected 201, got %T", resp)
+	assert.Equal(t, "placement-1", claimed.Id)
+	assert.WithinDuration(t, claimed.ClaimedAt.Add(30*time.Second), claimed.ExpiresAt, time.Second)
+
+	// The claimed capacity is reported as reserved
+	nodeResp, err := svc.GetNode(ctx(), oapi.GetNodeRequestObject{})
+	require.NoError(t, err)
+	node, ok := nodeResp.(oapi.GetNode200JSONResponse)
+	require.True(t, ok, "expected 200, got %T", nodeResp)
+	assert.Equal(t, "node-1", node.NodeId)
+	assert.False(t, node.Registered)
+	assert.Nil(t, node.ControlPlaneUrl)
+	assert.Equal(t, int64(3), node.Reserved.Vcpus)
+	assert.Equal(t, int64(1), node.Available.Vcpus)
+	require.Len(t, node.Slots, 1)
+
+	resp, err = svc.ClaimNodeSlot(ctx(), oapi.ClaimNodeSlotRequestObject{Body: &oapi.ClaimNodeSlotRequest{Vcpus: 2, MemoryBytes: 1 << 30}})
+	require.NoError(t, err)
+	conflict, ok := resp.(oapi.ClaimNodeSlot409ApplicationProblemPlusJSONResponse)
+	require.True(t, ok, "expected 409, got %T", resp)
+	assert.Equal(t, oapi.Conflict, conflict.Code)
+
+	resp, err = svc.ClaimNodeSlot(ctx(), oapi.ClaimNodeSlotRequestObject{Body: &oapi.ClaimNodeSlotRequest{Vcpus: 1, MemoryBytes: 1 << 30, Ttl: lo.ToPtr("soon")}})
+	require.NoError(t, err)
+	assert.IsType(t, oapi.ClaimNodeSlot400ApplicationProblemPlusJSONResponse{}, resp)
+
+	releaseResp, err := svc.ReleaseNodeSlot(ctx(), oapi.ReleaseNodeSlotRequestObject{Id: "placement-1"})
+	require.NoError(t, err)
+	assert.IsType(t, oapi.ReleaseNodeSlot204Response{}, releaseResp)
+
+	releaseResp, err = svc.ReleaseNodeSlot(ctx(), oapi.ReleaseNodeSlotRequestObject{Id: "placement-1"})
+	require.NoError(t, err)
+	assert.IsType(t, oapi.ReleaseNodeSlot404ApplicationProblemPlusJSONResponse{}, releaseResp)
+}
+
+func TestCreateInstance_UnknownSlot(t *testing.T) {
+	svc := newTestService(t)
+
+	resp, err := svc.CreateInstance(ctx(), oapi.CreateInstanceRequestObject{Body: &oapi.CreateInstanceRequest{
+		Name:   "web",
+		Image:  lo.ToPtr("docker.io/library/alpine:latest"),
+		SlotId: lo.ToPtr("missing"),
+	}})
+	require.NoError(t, err)
+	badReq, ok := resp.(oapi.CreateInstance400ApplicationProblemPlusJSONResponse)
+	require.True(t, ok, "expected 400, got %T", resp)
+	assert.Contains(t, badReq.Message, "slot not found")
+}
+
+func TestCreateInstance_SlotTooSmall(t *testing.T) {
+	svc := newTestService(t)
+	svc.NodeAgent = nodeagent.NewAgent(nodeagent.Config{NodeID: "node-1", SlotTTL: time.Minute}, fixedCapacity{})
+	_, err := svc.NodeAgent.ClaimSlot(ctx(), nodeagent.ClaimSlotRequest{ID: "placement-1", Vcpus: 1, MemoryBytes: 4 << 30})
+	require.NoError(t, err)
+
+	resp, err := svc.CreateInstance(ctx(), oapi.CreateInstanceRequestObject{Body: &oapi.CreateInstanceRequest{
+		Name:   "web",
+		Image:  lo.ToPtr("docker.io/library/alpine:latest"),
+		Vcpus:  lo.ToPtr(2),
+		SlotId: lo.ToPtr("placement-1"),
+	}})
+	require.NoError(t, err)
+	badReq, ok := resp.(oapi.CreateInstance400ApplicationProblemPlusJSONResponse)
+	require.True(t, ok, "expected 400, got %T", resp)
+	assert.Contains(t, badReq.Message, "request exceeds slot")
+
+	// The slot is still held for a request that fits
+	_, err = svc.NodeAgent.GetSlot("placement-1")
+	assert.NoError(t, err)
+}
+
+func TestCreateInstance_FailedCreateRestoresSlot(t *testing.T) {
+	svc := newTestService(t)
+	svc.NodeAgent = nodeagent.NewAgent(nodeagent.Config{NodeID: "node-1", SlotTTL: time.Minute}, fixedCapacity{})
+	_, err := svc.NodeAgent.ClaimSlot(ctx(), nodeagent.ClaimSlotRequest{ID: "placement-1", Vcpus: 2, MemoryBytes: 4 << 30})
+	require.NoError(t, err)
+
+	// The image was never pulled, so the create fails after taking the slot
+	resp, err := svc.CreateInstance(ctx(), oapi.CreateInstanceRequestObject{Body: &oapi.CreateInstanceRequest{
+		Name:   "web",
+		Image:  lo.ToPtr("docker.io/library/alpine:latest"),
+		SlotId: lo.ToPtr("placement-1"),
+	}})
+	require.NoError(t, err)
+	_, created := resp.(oapi.CreateInstance201JSONResponse)
+	require.False(t, created)
+
+	_, err = svc.NodeAgent.GetSlot("placement-1")
+	assert.NoError(t, err, "a failed create hands the slot back")
+}
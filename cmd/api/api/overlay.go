@@ -0,0 +1,145 @@
+package api
+
+import (
+	"context"
+	"errors"
+
+	"github.com/kernel/hypeman/lib/network/overlay"
+	"github.com/kernel/hypeman/lib/oapi"
+	"github.com/samber/lo"
+)
+
+// GetOverlay returns this host's side of the cross-host overlay
+func (s *ApiService) GetOverlay(ctx context.Context, request oapi.GetOverlayRequestObject) (oapi.GetOverlayResponseObject, error) {
+	status, err := s.OverlayManager.Status(ctx)
+	if err != nil {
+		return oapi.GetOverlay500ApplicationProblemPlusJSONResponse{
+			Code:    oapi.InternalError,
+			Message: err.Error(),
+		}, nil
+	}
+
+	return oapi.GetOverlay200JSONResponse(overlayStatusToOAPI(status)), nil
+}
+
+// CreateOverlayPeer registers another host in the overlay
+func (s *ApiService) CreateOverlayPeer(ctx context.Context, request oapi.CreateOverlayPeerRequestObject) (oapi.CreateOverlayPeerResponseObject, error) {
+	peer := overlay.Peer{
+		Name:      request.Body.Name,
+		PublicKey: request.Body.PublicKey,
+		Endpoint:  lo.FromPtr(request.Body.Endpoint),
+		Routes:    overlayRoutesFromOAPI(request.Body.Routes),
+	}
+
+	created, err := s.OverlayManager.AddPeer(ctx, peer)
+	if err != nil {
+		switch {
+		case errors.Is(err, overlay.ErrInvalidPeer):
+			return oapi.CreateOverlayPeer400ApplicationProblemPlusJSONResponse{
+				Code:    oapi.InvalidRequest,
+				Message: err.Error(),
+			}, nil
+		case errors.Is(err, overlay.ErrDisabled),
+			errors.Is(err, overlay.ErrPeerExists),
+			errors.Is(err, overlay.ErrRouteConflict):
+			return oapi.CreateOverlayPeer409ApplicationProblemPlusJSONResponse{
+				Code:    oapi.Conflict,
+				Message: err.Error(),
+			}, nil
+		default:
+			return oapi.CreateOverlayPeer500ApplicationProblemPlusJSONResponse{
+				Code:    oapi.InternalError,
+				Message: err.Error(),
+			}, nil
+		}
+	}
+
+	return oapi.CreateOverlayPeer201JSONResponse(overlayPeerToOAPI(overlay.PeerStatus{Peer: *created})), nil
+}
+
+// DeleteOverlayPeer removes a host from the overlay
+func (s *ApiService) DeleteOverlayPeer(ctx context.Context, request oapi.DeleteOverlayPeerRequestObject) (oapi.DeleteOverlayPeerResponseObject, error) {
+	if err := s.OverlayManager.DeletePeer(ctx, request.Name); err != nil {
+		if errors.Is(err, overlay.ErrPeerNotFound) || errors.Is(err, overlay.ErrDisabled) {
+			return oapi.DeleteOverlayPeer404ApplicationProblemPlusJSONResponse{
+				Code:    oapi.NotFound,
+				Message: "overlay peer not found",
+			}, nil
+		}
+		return oapi.DeleteOverlayPeer500ApplicationProblemPlusJSONResponse{
+			Code:    oapi.InternalError,
+			Message: err.Error(),
+		}, nil
+	}
+
+	return oapi.DeleteOverlayPeer204Response{}, nil
+}
+
+// RotateOverlayKey replaces this host's overlay key pair
+func (s *ApiService) RotateOverlayKey(ctx context.Context, request oapi.RotateOverlayKeyRequestObject) (oapi.RotateOverlayKeyResponseObject, error) {
+	status, err := s.OverlayManager.RotateKey(ctx)
+	if err != nil {
+		if errors.Is(err, overlay.ErrDisabled) {
+			return oapi.RotateOverlayKey409ApplicationProblemPlusJSONResponse{
+				Code:    oapi.Conflict,
+				Message: err.Error(),
+			}, nil
+		}
+		return oapi.RotateOverlayKey500ApplicationProblemPlusJSONResponse{
+			Code:    oapi.InternalError,
+			Message: err.Error(),
+		}, nil
+	}
+
+	return oapi.RotateOverlayKey200JSONResponse(overlayStatusToOAPI(status)), nil
+}
+
+func overlayStatusToOAPI(status *overlay.Status) oapi.OverlayStatus {
+	out := oapi.OverlayStatus{
+		Enabled: status.Enabled,
+		Routes:  overlayRoutesToOAPI(status.Routes),
+		Peers:   make([]oapi.OverlayPeer, 0, len(status.Peers)),
+	}
+	if status.Enabled {
+		out.Interface = lo.ToPtr(status.Interface)
+		out.PublicKey = lo.ToPtr(status.PublicKey)
+		out.ListenPort = lo.ToPtr(status.ListenPort)
+		out.Endpoint = lo.EmptyableToPtr(status.Endpoint)
+	}
+	for _, p := range status.Peers {
+		out.Peers = append(out.Peers, overlayPeerToOAPI(p))
+	}
+	return out
+}
+
+func overlayPeerToOAPI(p overlay.PeerStatus) oapi.OverlayPeer {
+	out := oapi.OverlayPeer{
+		Name:            p.Name,
+		PublicKey:       p.PublicKey,
+		Endpoint:        lo.EmptyableToPtr(p.Endpoint),
+		Routes:          overlayRoutesToOAPI(p.Routes),
+		CreatedAt:       p.CreatedAt,
+		LatestHandshake: p.LatestHandshake,
+	}
+	if p.RxBytes > 0 || p.TxBytes > 0 {
+		out.RxBytes = lo.ToPtr(p.RxBytes)
+		out.TxBytes = lo.ToPtr(p.TxBytes)
+	}
+	return out
+}
+
+func overlayRoutesToOAPI(routes []overlay.Route) []oapi.OverlayRoute {
+	out := make([]oapi.OverlayRoute, 0, len(routes))
+	for _, r := range routes {
+		out = append(out, oapi.OverlayRoute{Network: r.Network, Subnet: r.Subnet})
+	}
+	return out
+}
+
+func overlayRoutesFromOAPI(routes []oapi.OverlayRoute) []overlay.Route {
+	out := make([]overlay.Route, 0, len(routes))
+	for _, r := range routes {
+		out = append(out, overlay.Route{Network: r.Network, Subnet: r.Subnet})
+	}
+	return out
+}
@@ -0,0 +1,218 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/kernel/hypeman/lib/guest"
+	"github.com/kernel/hypeman/lib/hypervisor"
+	"github.com/kernel/hypeman/lib/instances"
+	"github.com/kernel/hypeman/lib/logger"
+	mw "github.com/kernel/hypeman/lib/middleware"
+	"github.com/kernel/hypeman/lib/oapi"
+)
+
+// ListInstanceProcesses lists the processes supervised by the guest-agent
+// Note: Resolution is handled by ResolveResource middleware
+func (s *ApiService) ListInstanceProcesses(ctx context.Context, request oapi.ListInstanceProcessesRequestObject) (oapi.ListInstanceProcessesResponseObject, error) {
+	log := logger.FromContext(ctx)
+
+	dialer, err := resolvedInstanceDialer(ctx)
+	if err != nil {
+		if errors.Is(err, instances.ErrInvalidState) {
+			return oapi.ListInstanceProcesses409ApplicationProblemPlusJSONResponse{Code: oapi.InvalidState, Message: err.Error()}, nil
+		}
+		log.ErrorContext(ctx, "failed to create vsock dialer", "error", err)
+		return oapi.ListInstanceProcesses500ApplicationProblemPlusJSONResponse{Code: oapi.InternalError, Message: "failed to connect to guest agent"}, nil
+	}
+
+	procs, err := guest.ListProcesses(ctx, dialer)
+	if err != nil {
+		log.ErrorContext(ctx, "list processes failed", "error", err)
+		return oapi.ListInstanceProcesses500ApplicationProblemPlusJSONResponse{Code: oapi.InternalError, Message: "failed to list processes"}, nil
+	}
+
+	resp := make(oapi.ListInstanceProcesses200JSONResponse, 0, len(procs))
+	for _, p := range procs {
+		resp = append(resp, processToOAPI(p))
+	}
+	return resp, nil
+}
+
+// ListGuestProcesses returns a snapshot of all processes running in the guest
+// Note: Resolution is handled by ResolveResource middleware
+func (s *ApiService) ListGuestProcesses(ctx context.Context, request oapi.ListGuestProcessesRequestObject) (oapi.ListGuestProcessesResponseObject, error) {
+	log := logger.FromContext(ctx)
+
+	dialer, err := resolvedInstanceDialer(ctx)
+	if err != nil {
+		if errors.Is(err, instances.ErrInvalidState) {
+			return oapi.ListGuestProcesses409ApplicationProblemPlusJSONResponse{Code: oapi.InvalidState, Message: err.Error()}, nil
+		}
+		log.ErrorContext(ctx, "failed to create vsock dialer", "error", err)
+		return oapi.ListGuestProcesses500ApplicationProblemPlusJSONResponse{Code: oapi.InternalError, Message: "failed to connect to guest agent"}, nil
+	}
+
+	procs, err := guest.ListGuestProcesses(ctx, dialer)
+	if err != nil {
+		log.ErrorContext(ctx, "list guest processes failed", "error", err)
+		return oapi.ListGuestProcesses500ApplicationProblemPlusJSONResponse{Code: oapi.InternalError, Message: "failed to list guest processes"}, nil
+	}
+
+	resp := make(oapi.ListGuestProcesses200JSONResponse, 0, len(procs))
+	for _, p := range procs {
+		resp = append(resp, oapi.GuestProcess{
+			Pid:        int(p.Pid),
+			Ppid:       int(p.Ppid),
+			Command:    p.Command,
+			CpuPercent: p.CpuPercent,
+			RssBytes:   p.RssBytes,
+		})
+	}
+	return resp, nil
+}
+
+// StartInstanceProcess starts a supervised process in the guest
+// Note: Resolution is handled by ResolveResource middleware
+func (s *ApiService) StartInstanceProcess(ctx context.Context, request oapi.StartInstanceProcessRequestObject) (oapi.StartInstanceProcessResponseObject, error) {
+	log := logger.FromContext(ctx)
+
+	if request.Body == nil {
+		return oapi.StartInstanceProcess400ApplicationProblemPlusJSONResponse{Code: oapi.InvalidRequest, Message: "request body is required"}, nil
+	}
+	dialer, err := resolvedInstanceDialer(ctx)
+	if err != nil {
+		if errors.Is(err, instances.ErrInvalidState) {
+			return oapi.StartInstanceProcess409ApplicationProblemPlusJSONResponse{Code: oapi.InvalidState, Message: err.Error()}, nil
+		}
+		log.ErrorContext(ctx, "failed to create vsock dialer", "error", err)
+		return oapi.StartInstanceProcess500ApplicationProblemPlusJSONResponse{Code: oapi.InternalError, Message: "failed to connect to guest agent"}, nil
+	}
+
+	spec := guest.ProcessSpec{
+		Name:    request.Body.Name,
+		Command: request.Body.Command,
+	}
+	if request.Body.Env != nil {
+		spec.Env = *request.Body.Env
+	}
+	if request.Body.Cwd != nil {
+		spec.Cwd = *request.Body.Cwd
+	}
+	if request.Body.RestartPolicy != nil {
+		spec.RestartPolicy = string(*request.Body.RestartPolicy)
+	}
+
+	proc, err := guest.StartProcess(ctx, dialer, spec)
+	if err != nil {
+		switch {
+		case errors.Is(err, guest.ErrInvalidProcess):
+			return oapi.StartInstanceProcess400ApplicationProblemPlusJSONResponse{Code: oapi.InvalidRequest, Message: err.Error()}, nil
+		case errors.Is(err, guest.ErrProcessRunning):
+			return oapi.StartInstanceProcess409ApplicationProblemPlusJSONResponse{Code: oapi.AlreadyExists, Message: err.Error()}, nil
+		default:
+			log.ErrorContext(ctx, "start process failed", "error", err, "name", spec.Name)
+			return oapi.StartInstanceProcess500ApplicationProblemPlusJSONResponse{Code: oapi.InternalError, Message: "failed to start process"}, nil
+		}
+	}
+	return oapi.StartInstanceProcess201JSONResponse(processToOAPI(proc)), nil
+}
+
+// StopInstanceProcess stops a supervised process in the guest
+// Note: Resolution is handled by ResolveResource middleware
+func (s *ApiService) StopInstanceProcess(ctx context.Context, request oapi.StopInstanceProcessRequestObject) (oapi.StopInstanceProcessResponseObject, error) {
+	log := logger.FromContext(ctx)
+
+	dialer, err := resolvedInstanceDialer(ctx)
+	if err != nil {
+		if errors.Is(err, instances.ErrInvalidState) {
+			return oapi.StopInstanceProcess409ApplicationProblemPlusJSONResponse{Code: oapi.InvalidState, Message: err.Error()}, nil
+		}
+		log.ErrorContext(ctx, "failed to create vsock dialer", "error", err)
+		return oapi.StopInstanceProcess500ApplicationProblemPlusJSONResponse{Code: oapi.InternalError, Message: "failed to connect to guest agent"}, nil
+	}
+
+	proc, err := guest.StopProcess(ctx, dialer, request.Name, processStopTimeout(request.Params.Timeout))
+	if err != nil {
+		if errors.Is(err, guest.ErrProcessNotFound) {
+			return oapi.StopInstanceProcess404ApplicationProblemPlusJSONResponse{Code: oapi.NotFound, Message: err.Error()}, nil
+		}
+		log.ErrorContext(ctx, "stop process failed", "error", err, "name", request.Name)
+		return oapi.StopInstanceProcess500ApplicationProblemPlusJSONResponse{Code: oapi.InternalError, Message: "failed to stop process"}, nil
+	}
+	return oapi.StopInstanceProcess200JSONResponse(processToOAPI(proc)), nil
+}
+
+// RestartInstanceProcess restarts a supervised process in the guest
+// Note: Resolution is handled by ResolveResource middleware
+func (s *ApiService) RestartInstanceProcess(ctx context.Context, request oapi.RestartInstanceProcessRequestObject) (oapi.RestartInstanceProcessResponseObject, error) {
+	log := logger.FromContext(ctx)
+
+	dialer, err := resolvedInstanceDialer(ctx)
+	if err != nil {
+		if errors.Is(err, instances.ErrInvalidState) {
+			return oapi.RestartInstanceProcess409ApplicationProblemPlusJSONResponse{Code: oapi.InvalidState, Message: err.Error()}, nil
+		}
+		log.ErrorContext(ctx, "failed to create vsock dialer", "error", err)
+		return oapi.RestartInstanceProcess500ApplicationProblemPlusJSONResponse{Code: oapi.InternalError, Message: "failed to connect to guest agent"}, nil
+	}
+
+	proc, err := guest.RestartProcess(ctx, dialer, request.Name, processStopTimeout(request.Params.Timeout))
+	if err != nil {
+		switch {
+		case errors.Is(err, guest.ErrProcessNotFound):
+			return oapi.RestartInstanceProcess404ApplicationProblemPlusJSONResponse{Code: oapi.NotFound, Message: err.Error()}, nil
+		case errors.Is(err, guest.ErrInvalidProcess):
+			return oapi.RestartInstanceProcess400ApplicationProblemPlusJSONResponse{Code: oapi.InvalidRequest, Message: err.Error()}, nil
+		default:
+			log.ErrorContext(ctx, "restart process failed", "error", err, "name", request.Name)
+			return oapi.RestartInstanceProcess500ApplicationProblemPlusJSONResponse{Code: oapi.InternalError, Message: "failed to restart process"}, nil
+		}
+	}
+	return oapi.RestartInstanceProcess200JSONResponse(processToOAPI(proc)), nil
+}
+
+// resolvedInstanceDialer returns a vsock dialer for the resolved instance,
+// which must be running
+func resolvedInstanceDialer(ctx context.Context) (hypervisor.VsockDialer, error) {
+	inst := mw.GetResolvedInstance[instances.Instance](ctx)
+	if inst == nil {
+		return nil, fmt.Errorf("resource not resolved")
+	}
+	if inst.State != instances.StateRunning {
+		return nil, fmt.Errorf("%w: instance must be running (current state: %s)", instances.ErrInvalidState, inst.State)
+	}
+	return hypervisor.NewVsockDialer(inst.HypervisorType, inst.VsockSocket, inst.VsockCID)
+}
+
+func processStopTimeout(seconds *int) time.Duration {
+	if seconds == nil {
+		return 0
+	}
+	return time.Duration(*seconds) * time.Second
+}
+
+func processToOAPI(p *guest.ProcessInfo) oapi.Process {
+	proc := oapi.Process{
+		Name:          p.Name,
+		Command:       p.Command,
+		RestartPolicy: oapi.ProcessRestartPolicy(p.RestartPolicy),
+		State:         oapi.ProcessState(p.State),
+		Restarts:      int(p.Restarts),
+		LogPath:       p.LogPath,
+	}
+	if proc.State == oapi.ProcessStateRunning {
+		pid := int(p.Pid)
+		proc.Pid = &pid
+	} else {
+		exitCode := int(p.ExitCode)
+		proc.ExitCode = &exitCode
+	}
+	if p.StartedAt > 0 {
+		startedAt := time.Unix(p.StartedAt, 0).UTC()
+		proc.StartedAt = &startedAt
+	}
+	return proc
+}
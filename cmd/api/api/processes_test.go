@@ -0,0 +1,58 @@
+package api
+
+import (
+	"testing"
+	"time"
+
+	"github.com/kernel/hypeman/lib/guest"
+	"github.com/kernel/hypeman/lib/instances"
+	mw "github.com/kernel/hypeman/lib/middleware"
+	"github.com/kernel/hypeman/lib/oapi"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInstanceProcesses_RequiresRunningInstance(t *testing.T) {
+	svc := newTestService(t)
+	inst := &instances.Instance{State: instances.StateStopped}
+	inst.Id = "proc-stopped"
+	reqCtx := mw.WithResolvedInstance(ctx(), inst.Id, *inst)
+
+	listResp, err := svc.ListInstanceProcesses(reqCtx, oapi.ListInstanceProcessesRequestObject{Id: inst.Id})
+	require.NoError(t, err)
+	assert.IsType(t, oapi.ListInstanceProcesses409ApplicationProblemPlusJSONResponse{}, listResp)
+
+	psResp, err := svc.ListGuestProcesses(reqCtx, oapi.ListGuestProcessesRequestObject{Id: inst.Id})
+	require.NoError(t, err)
+	assert.IsType(t, oapi.ListGuestProcesses409ApplicationProblemPlusJSONResponse{}, psResp)
+
+	startResp, err := svc.StartInstanceProcess(reqCtx, oapi.StartInstanceProcessRequestObject{
+		Id:   inst.Id,
+		Body: &oapi.StartProcessRequest{Name: "worker", Command: []string{"sleep", "60"}},
+	})
+	require.NoError(t, err)
+	assert.IsType(t, oapi.StartInstanceProcess409ApplicationProblemPlusJSONResponse{}, startResp)
+}
+
+func TestProcessToOAPI(t *testing.T) {
+	running := processToOAPI(&guest.ProcessInfo{
+		Name:          "worker",
+		Command:       []string{"sleep", "60"},
+		RestartPolicy: "always",
+		State:         "running",
+		Pid:           42,
+		StartedAt:     1736937000,
+		LogPath:       "/var/log/hypeman/worker.log",
+	})
+	require.NotNil(t, running.Pid)
+	assert.Equal(t, 42, *running.Pid)
+	assert.Nil(t, running.ExitCode)
+	require.NotNil(t, running.StartedAt)
+	assert.Equal(t, time.Unix(1736937000, 0).UTC(), *running.StartedAt)
+
+	exited := processToOAPI(&guest.ProcessInfo{Name: "job", State: "exited", ExitCode: 3})
+	assert.Nil(t, exited.Pid)
+	require.NotNil(t, exited.ExitCode)
+	assert.Equal(t, 3, *exited.ExitCode)
+	assert.Nil(t, exited.StartedAt)
+}
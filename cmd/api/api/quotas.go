@@ -0,0 +1,111 @@
+package api
+
+import (
+	"context"
+	"errors"
+
+	"github.com/kernel/hypeman/lib/images"
+	"github.com/kernel/hypeman/lib/instances"
+	"github.com/kernel/hypeman/lib/logger"
+	mw "github.com/kernel/hypeman/lib/middleware"
+	"github.com/kernel/hypeman/lib/oapi"
+	"github.com/kernel/hypeman/lib/quotas"
+)
+
+// ListQuotas lists the quotas covering the caller with their usage
+func (s *ApiService) ListQuotas(ctx context.Context, _ oapi.ListQuotasRequestObject) (oapi.ListQuotasResponseObject, error) {
+	statuses, err := s.QuotaManager.List(ctx)
+	if err != nil {
+		logger.FromContext(ctx).ErrorContext(ctx, "failed to list quotas", "error", err)
+		return oapi.ListQuotas500ApplicationProblemPlusJSONResponse{
+			Code:    oapi.InternalError,
+			Message: "failed to list quotas",
+		}, nil
+	}
+
+	creator := mw.CreatorFromContext(ctx)
+	unscoped := mw.TenantFromContext(ctx) == ""
+	out := make([]oapi.QuotaStatus, 0, len(statuses))
+	for _, st := range statuses {
+		switch st.Scope.Kind {
+		case quotas.ScopeTenant:
+			if !mw.TenantVisible(ctx, st.Scope.Name) {
+				continue
+			}
+		case quotas.ScopeKey:
+			if !unscoped && creator != mw.AuthMethodAPIKey+":"+st.Scope.Name {
+				continue
+			}
+		}
+		out = append(out, quotaStatusToOAPI(st))
+	}
+	return oapi.ListQuotas200JSONResponse(out), nil
+}
+
+// quotaStatusToOAPI converts a quota's status, listing only limited resources
+func quotaStatusToOAPI(st quotas.Status) oapi.QuotaStatus {
+	out := oapi.QuotaStatus{
+		Scope:     st.Scope.String(),
+		Kind:      oapi.QuotaStatusKind(st.Scope.Kind),
+		Name:      st.Scope.Name,
+		Resources: []oapi.QuotaResource{},
+	}
+	add := func(resource oapi.QuotaResourceResource, limit, used int64) {
+		if limit > 0 {
+			out.Resources = append(out.Resources, oapi.QuotaResource{Resource: resource, Limit: limit, Used: used})
+		}
+	}
+	add(oapi.QuotaInstances, int64(st.Limits.Instances), int64(st.Usage.Instances))
+	add(oapi.QuotaVcpus, int64(st.Limits.Vcpus), int64(st.Usage.Vcpus))
+	add(oapi.QuotaMemory, st.Limits.Memory, st.Usage.Memory)
+	add(oapi.QuotaVolumeSize, st.Limits.VolumeSize, st.Usage.VolumeSize)
+	add(oapi.QuotaBuildsPerHour, int64(st.Limits.BuildsPerHour), int64(st.Usage.BuildsPerHour))
+	return out
+}
+
+// admitQuota reserves quota for resources the caller creates in tenant.
+// release must be called once they're created or creation failed.
+func (s *ApiService) admitQuota(ctx context.Context, tenant string, req quotas.Resources) (release func(), err error) {
+	return s.QuotaManager.Admit(ctx, quotas.Principal{Tenant: tenant, Creator: mw.CreatorFromContext(ctx)}, req)
+}
+
+// instanceQuotaRequest returns the quota an instance will use, applying the
+// memory defaults of the firmware its image boots with
+func (s *ApiService) instanceQuotaRequest(ctx context.Context, req instances.CreateInstanceRequest) quotas.Resources {
+	firmware := req.Firmware
+	if firmware == "" && req.Image != "" {
+		if img, err := s.ImageManager.GetImage(ctx, req.Image); err == nil {
+			firmware = img.Firmware
+		}
+	}
+	if firmware == "" {
+		firmware = images.FirmwareDirect
+	}
+	size, hotplugSize := instances.DefaultMemory(firmware)
+	if req.Size != 0 {
+		size = req.Size
+	}
+	if req.HotplugSize != 0 {
+		hotplugSize = req.HotplugSize
+	}
+	return quotas.Resources{Instances: 1, Vcpus: req.Vcpus, Memory: size + hotplugSize}
+}
+
+// quotaProblem converts a quota error to a quota_exceeded problem with a
+// detail per exceeded limit
+func quotaProblem(err error) (oapi.Error, bool) {
+	var exceeded *quotas.ExceededError
+	if !errors.As(err, &exceeded) {
+		return oapi.Error{}, false
+	}
+	details := make([]oapi.ErrorDetail, 0, len(exceeded.Exceeded))
+	for _, x := range exceeded.Exceeded {
+		code, message := x.Resource, x.String()
+		details = append(details, oapi.ErrorDetail{Code: &code, Message: &message})
+	}
+	return oapi.Error{
+		Code:    oapi.QuotaExceeded,
+		Message: exceeded.Error(),
+		Details: &details,
+	}, true
+}
@@ -0,0 +1,53 @@
+package api
+
+import (
+	"context"
+	"testing"
+
+	"github.com/kernel/hypeman/lib/builds"
+	mw "github.com/kernel/hypeman/lib/middleware"
+	"github.com/kernel/hypeman/lib/oapi"
+	"github.com/kernel/hypeman/lib/quotas"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type noBuilds struct{}
+
+func (noBuilds) ListBuilds(ctx context.Context) ([]*builds.Build, error) { return nil, nil }
+
+func TestCreateVolume_QuotaExceeded(t *testing.T) {
+	svc := newTestService(t)
+	q, err := quotas.Parse("tenant:team-a:volume_size=3GB;tenant:team-b:instances=1;key:ci:volume_size=10GB")
+	require.NoError(t, err)
+	svc.QuotaManager = quotas.NewManager(q, svc.InstanceManager, svc.VolumeManager, noBuilds{})
+	tenantCtx := mw.WithClaims(ctx(), &mw.Claims{Subject: "ci", AuthMethod: mw.AuthMethodAPIKey, Role: mw.RoleAdmin, Tenant: "team-a"})
+
+	resp, err := svc.CreateVolume(tenantCtx, oapi.CreateVolumeRequestObject{
+		JSONBody: &oapi.CreateVolumeRequest{Name: "data", SizeGb: 2},
+	})
+	require.NoError(t, err)
+	require.IsType(t, oapi.CreateVolume201JSONResponse{}, resp)
+
+	resp, err = svc.CreateVolume(tenantCtx, oapi.CreateVolumeRequestObject{
+		JSONBody: &oapi.CreateVolumeRequest{Name: "more", SizeGb: 2},
+	})
+	require.NoError(t, err)
+	problem, ok := resp.(oapi.CreateVolume403ApplicationProblemPlusJSONResponse)
+	require.True(t, ok, "expected 403 response, got %T", resp)
+	assert.Equal(t, oapi.QuotaExceeded, problem.Code)
+	require.NotNil(t, problem.Details)
+	require.Len(t, *problem.Details, 1)
+	assert.Equal(t, "volume_size", *(*problem.Details)[0].Code)
+
+	// The caller sees its tenant's and its key's quotas, with usage
+	listResp, err := svc.ListQuotas(tenantCtx, oapi.ListQuotasRequestObject{})
+	require.NoError(t, err)
+	statuses, ok := listResp.(oapi.ListQuotas200JSONResponse)
+	require.True(t, ok, "expected 200 response, got %T", listResp)
+	require.Len(t, statuses, 2)
+	assert.Equal(t, "tenant:team-a", statuses[0].Scope)
+	assert.Equal(t, []oapi.QuotaResource{{Resource: oapi.QuotaVolumeSize, Limit: 3 << 30, Used: 2 << 30}}, statuses[0].Resources)
+	assert.Equal(t, "key:ci", statuses[1].Scope)
+	assert.Equal(t, oapi.QuotaKey, statuses[1].Kind)
+}
@@ -0,0 +1,79 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"io"
+
+	"github.com/kernel/hypeman/lib/logger"
+	mw "github.com/kernel/hypeman/lib/middleware"
+	"github.com/kernel/hypeman/lib/oapi"
+	"github.com/kernel/hypeman/lib/recordings"
+	"github.com/samber/lo"
+)
+
+// ListExecRecordings lists recorded exec sessions
+func (s *ApiService) ListExecRecordings(ctx context.Context, request oapi.ListExecRecordingsRequestObject) (oapi.ListExecRecordingsResponseObject, error) {
+	recs, err := s.Recordings.List(lo.FromPtr(request.Params.Instance))
+	if err != nil {
+		logger.FromContext(ctx).ErrorContext(ctx, "failed to list exec recordings", "error", err)
+		return oapi.ListExecRecordings500ApplicationProblemPlusJSONResponse{
+			Code:    oapi.InternalError,
+			Message: "failed to list exec recordings",
+		}, nil
+	}
+
+	out := make([]oapi.ExecRecording, 0, len(recs))
+	for _, rec := range recs {
+		if mw.TenantVisible(ctx, rec.Tenant) {
+			out = append(out, execRecordingToOAPI(rec))
+		}
+	}
+	return oapi.ListExecRecordings200JSONResponse(out), nil
+}
+
+// GetExecRecording downloads a recorded exec session
+func (s *ApiService) GetExecRecording(ctx context.Context, request oapi.GetExecRecordingRequestObject) (oapi.GetExecRecordingResponseObject, error) {
+	rec, f, err := s.Recordings.Open(request.Id)
+	if err == nil && !mw.TenantVisible(ctx, rec.Tenant) {
+		f.Close()
+		err = recordings.ErrNotFound
+	}
+	if errors.Is(err, recordings.ErrNotFound) {
+		return oapi.GetExecRecording404ApplicationProblemPlusJSONResponse{
+			Code:    oapi.NotFound,
+			Message: "recording not found",
+		}, nil
+	}
+	if err != nil {
+		logger.FromContext(ctx).ErrorContext(ctx, "failed to open exec recording", "error", err, "id", request.Id)
+		return oapi.GetExecRecording500ApplicationProblemPlusJSONResponse{
+			Code:    oapi.InternalError,
+			Message: "failed to open exec recording",
+		}, nil
+	}
+	// The recording may still grow; send what's there now
+	return oapi.GetExecRecording200ApplicationxAsciicastResponse{
+		Body:          limitedFile{Reader: io.LimitReader(f, rec.Size), Closer: f},
+		ContentLength: rec.Size,
+	}, nil
+}
+
+func execRecordingToOAPI(rec recordings.Recording) oapi.ExecRecording {
+	return oapi.ExecRecording{
+		Id:         rec.ID,
+		InstanceId: rec.InstanceID,
+		Tenant:     lo.EmptyableToPtr(rec.Tenant),
+		Subject:    rec.Subject,
+		Command:    rec.Command,
+		Tty:        rec.TTY,
+		StartedAt:  rec.StartedAt,
+		SizeBytes:  rec.Size,
+	}
+}
+
+// limitedFile reads part of a file and closes the whole
+type limitedFile struct {
+	io.Reader
+	io.Closer
+}
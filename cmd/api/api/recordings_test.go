@@ -0,0 +1,61 @@
+package api
+
+import (
+	"io"
+	"net/http/httptest"
+	"testing"
+
+	mw "github.com/kernel/hypeman/lib/middleware"
+	"github.com/kernel/hypeman/lib/oapi"
+	"github.com/kernel/hypeman/lib/paths"
+	"github.com/kernel/hypeman/lib/recordings"
+	"github.com/samber/lo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExecRecordings(t *testing.T) {
+	store := recordings.NewStore(paths.New(t.TempDir()), recordings.Config{})
+	for _, tenant := range []string{"team-a", "team-b"} {
+		rec, err := store.Start("inst-"+tenant, tenant, "alice", []string{"/bin/sh"}, true)
+		require.NoError(t, err)
+		io.WriteString(rec.Output(), "$ ")
+		require.NoError(t, rec.Close())
+	}
+	svc := &ApiService{Recordings: store}
+
+	resp, err := svc.ListExecRecordings(ctx(), oapi.ListExecRecordingsRequestObject{})
+	require.NoError(t, err)
+	list, ok := resp.(oapi.ListExecRecordings200JSONResponse)
+	require.True(t, ok, "expected 200 response, got %T", resp)
+	require.Len(t, list, 2)
+
+	resp, err = svc.ListExecRecordings(ctx(), oapi.ListExecRecordingsRequestObject{
+		Params: oapi.ListExecRecordingsParams{Instance: lo.ToPtr("inst-team-a")},
+	})
+	require.NoError(t, err)
+	require.Len(t, resp.(oapi.ListExecRecordings200JSONResponse), 1)
+
+	// Tenant-scoped callers only see their tenant's recordings
+	tenantCtx := mw.WithClaims(ctx(), &mw.Claims{Subject: "auditor", Role: mw.RoleAdmin, Tenant: "team-b"})
+	resp, err = svc.ListExecRecordings(tenantCtx, oapi.ListExecRecordingsRequestObject{})
+	require.NoError(t, err)
+	list = resp.(oapi.ListExecRecordings200JSONResponse)
+	require.Len(t, list, 1)
+	assert.Equal(t, "inst-team-b", list[0].InstanceId)
+	assert.Equal(t, "alice", list[0].Subject)
+
+	teamA, err := store.List("inst-team-a")
+	require.NoError(t, err)
+	getResp, err := svc.GetExecRecording(tenantCtx, oapi.GetExecRecordingRequestObject{Id: teamA[0].ID})
+	require.NoError(t, err)
+	assert.IsType(t, oapi.GetExecRecording404ApplicationProblemPlusJSONResponse{}, getResp)
+
+	getResp, err = svc.GetExecRecording(tenantCtx, oapi.GetExecRecordingRequestObject{Id: list[0].Id})
+	require.NoError(t, err)
+	w := httptest.NewRecorder()
+	require.NoError(t, getResp.VisitGetExecRecordingResponse(w))
+	assert.Equal(t, "application/x-asciicast", w.Header().Get("Content-Type"))
+	assert.Contains(t, w.Body.String(), `"version":2`)
+	assert.Contains(t, w.Body.String(), `"o","$ "`)
+}
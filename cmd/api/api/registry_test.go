@@ -7,6 +7,7 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"runtime"
 	"strings"
 	"testing"
 	"time"
@@ -135,11 +136,13 @@ func TestRegistryPushAndCreateInstance(t *testing.T) {
 	resp, err := svc.CreateInstance(ctx(), oapi.CreateInstanceRequestObject{
 		Body: &oapi.CreateInstanceRequest{
 			Name:  "test-pushed-image",
-			Image: imageName,
+			Image: &imageName,
 			Network: &struct {
-				BandwidthDownload *string `json:"bandwidth_download,omitempty"`
-				BandwidthUpload   *string `json:"bandwidth_upload,omitempty"`
-				Enabled           *bool   `json:"enabled,omitempty"`
+				BandwidthDownload      *string `json:"bandwidth_download,omitempty"`
+				BandwidthDownloadBurst *string `json:"bandwidth_download_burst,omitempty"`
+				BandwidthUpload        *string `json:"bandwidth_upload,omitempty"`
+				BandwidthUploadBurst   *string `json:"bandwidth_upload_burst,omitempty"`
+				Enabled                *bool   `json:"enabled,omitempty"`
 			}{
 				Enabled: &networkEnabled,
 			},
@@ -387,6 +390,58 @@ func TestRegistryTagPush(t *testing.T) {
 	assert.True(t, found, "pushed image should appear in ListImages response")
 }
 
+// TestRegistryIndexPush verifies that pushing a multi-platform index converts
+// the host platform's image under the index's tag, and that the index stays
+// pullable for other platforms.
+func TestRegistryIndexPush(t *testing.T) {
+	svc, serverHost := setupRegistryTest(t)
+
+	t.Log("Pulling alpine:latest index from Docker Hub...")
+	srcRef, err := name.ParseReference("docker.io/library/alpine:latest")
+	require.NoError(t, err)
+	idx, err := remote.Index(srcRef, remote.WithAuthFromKeychain(authn.DefaultKeychain))
+	require.NoError(t, err)
+	idxDigest, err := idx.Digest()
+	require.NoError(t, err)
+
+	indexManifest, err := idx.IndexManifest()
+	require.NoError(t, err)
+	var hostDigest v1.Hash
+	for _, desc := range indexManifest.Manifests {
+		if desc.Platform != nil && desc.Platform.Architecture == runtime.GOARCH && desc.Platform.OS == runtime.GOOS {
+			hostDigest = desc.Digest
+			break
+		}
+	}
+	require.NotEmpty(t, hostDigest.Hex, "alpine should have a manifest for the host platform")
+
+	dstRef, err := name.ParseReference(serverHost+"/index-test/alpine:latest", name.Insecure)
+	require.NoError(t, err)
+	require.NoError(t, remote.WriteIndex(dstRef, idx))
+
+	// The tag resolves to the host platform's image
+	img := waitForImageReady(t, svc, serverHost+"/index-test/alpine:latest", 60*time.Second)
+	assert.Equal(t, hostDigest.String(), img.Digest)
+
+	// The index is served as pushed
+	got, err := remote.Index(dstRef)
+	require.NoError(t, err)
+	gotDigest, err := got.Digest()
+	require.NoError(t, err)
+	assert.Equal(t, idxDigest, gotDigest)
+
+	// Other platforms' images aren't converted
+	listResp, err := svc.ListImages(ctx(), oapi.ListImagesRequestObject{})
+	require.NoError(t, err)
+	images, ok := listResp.(oapi.ListImages200JSONResponse)
+	require.True(t, ok, "expected ListImages 200 response")
+	for _, img := range images {
+		if strings.Contains(img.Name, "/index-test/alpine") {
+			assert.Equal(t, hostDigest.String(), img.Digest)
+		}
+	}
+}
+
 // TestRegistryDockerV2ManifestConversion verifies that pushing an image with a
 // Docker v2 manifest (as returned by local Docker daemon) is correctly converted
 // to OCI format and the image conversion succeeds.
@@ -5,10 +5,14 @@ import (
 	"errors"
 	"net/http"
 
+	"github.com/kernel/hypeman/lib/groups"
 	"github.com/kernel/hypeman/lib/images"
 	"github.com/kernel/hypeman/lib/ingress"
 	"github.com/kernel/hypeman/lib/instances"
 	"github.com/kernel/hypeman/lib/middleware"
+	"github.com/kernel/hypeman/lib/oapi"
+	"github.com/kernel/hypeman/lib/problem"
+	"github.com/kernel/hypeman/lib/secrets"
 	"github.com/kernel/hypeman/lib/volumes"
 )
 
@@ -22,6 +26,10 @@ func (r InstanceResolver) Resolve(ctx context.Context, idOrName string) (string,
 	if err != nil {
 		return "", nil, err
 	}
+	// Resources owned by other tenants are reported as not found
+	if !middleware.TenantVisible(ctx, inst.Tenant) {
+		return "", nil, instances.ErrNotFound
+	}
 	return inst.Id, inst, nil
 }
 
@@ -39,6 +47,9 @@ func (r VolumeResolver) Resolve(ctx context.Context, idOrName string) (string, a
 	if err != nil {
 		return "", nil, err
 	}
+	if !middleware.TenantVisible(ctx, vol.Tenant) {
+		return "", nil, volumes.ErrNotFound
+	}
 	return vol.Id, vol, nil
 }
 
@@ -52,6 +63,9 @@ func (r IngressResolver) Resolve(ctx context.Context, idOrName string) (string,
 	if err != nil {
 		return "", nil, err
 	}
+	if !middleware.TenantVisible(ctx, ing.Tenant) {
+		return "", nil, ingress.ErrNotFound
+	}
 	return ing.ID, ing, nil
 }
 
@@ -66,9 +80,49 @@ func (r ImageResolver) Resolve(ctx context.Context, name string) (string, any, e
 	if err != nil {
 		return "", nil, err
 	}
+	// Untenanted images are shared by all tenants
+	if img.Tenant != "" && !middleware.TenantVisible(ctx, img.Tenant) {
+		return "", nil, images.ErrNotFound
+	}
 	return img.Name, img, nil
 }
 
+// SecretResolver adapts secrets.Manager to middleware.ResourceResolver.
+type SecretResolver struct {
+	Manager secrets.Manager
+}
+
+func (r SecretResolver) Resolve(ctx context.Context, idOrName string) (string, any, error) {
+	// Try by ID first, then by name
+	secret, err := r.Manager.GetSecret(ctx, idOrName)
+	if errors.Is(err, secrets.ErrNotFound) {
+		secret, err = r.Manager.GetSecretByName(ctx, idOrName)
+	}
+	if err != nil {
+		return "", nil, err
+	}
+	if !middleware.TenantVisible(ctx, secret.Tenant) {
+		return "", nil, secrets.ErrNotFound
+	}
+	return secret.Id, secret, nil
+}
+
+// GroupResolver adapts groups.Manager to middleware.ResourceResolver.
+type GroupResolver struct {
+	Manager groups.Manager
+}
+
+func (r GroupResolver) Resolve(ctx context.Context, idOrName string) (string, any, error) {
+	group, err := r.Manager.GetGroup(ctx, idOrName)
+	if err != nil {
+		return "", nil, err
+	}
+	if !middleware.TenantVisible(ctx, group.Tenant) {
+		return "", nil, groups.ErrNotFound
+	}
+	return group.Id, group, nil
+}
+
 // NewResolvers creates Resolvers from the ApiService managers.
 func (s *ApiService) NewResolvers() middleware.Resolvers {
 	return middleware.Resolvers{
@@ -76,33 +130,39 @@ func (s *ApiService) NewResolvers() middleware.Resolvers {
 		Volume:   VolumeResolver{Manager: s.VolumeManager},
 		Ingress:  IngressResolver{Manager: s.IngressManager},
 		Image:    ImageResolver{Manager: s.ImageManager},
+		Secret:   SecretResolver{Manager: s.SecretManager},
+		Group:    GroupResolver{Manager: s.GroupManager},
 	}
 }
 
 // ResolverErrorResponder handles resolver errors by writing appropriate HTTP responses.
 func ResolverErrorResponder(w http.ResponseWriter, err error, lookup string) {
-	w.Header().Set("Content-Type", "application/json")
+	status, code, message := resolverProblem(err)
+	problem.Write(w, status, code, message)
+}
 
+// resolverProblem returns the status, code and message reported for a resolver error
+func resolverProblem(err error) (int, oapi.ErrorCode, string) {
 	switch {
 	case errors.Is(err, instances.ErrNotFound),
 		errors.Is(err, volumes.ErrNotFound),
 		errors.Is(err, ingress.ErrNotFound),
-		errors.Is(err, images.ErrNotFound):
-		w.WriteHeader(http.StatusNotFound)
-		w.Write([]byte(`{"code":"not_found","message":"resource not found"}`))
+		errors.Is(err, images.ErrNotFound),
+		errors.Is(err, secrets.ErrNotFound),
+		errors.Is(err, secrets.ErrDisabled),
+		errors.Is(err, groups.ErrNotFound):
+		return http.StatusNotFound, oapi.NotFound, "resource not found"
 
 	case errors.Is(err, instances.ErrAmbiguousName),
 		errors.Is(err, volumes.ErrAmbiguousName),
-		errors.Is(err, ingress.ErrAmbiguousName):
-		w.WriteHeader(http.StatusConflict)
-		w.Write([]byte(`{"code":"ambiguous","message":"multiple resources match, use full ID"}`))
+		errors.Is(err, ingress.ErrAmbiguousName),
+		errors.Is(err, secrets.ErrAmbiguousName):
+		return http.StatusConflict, oapi.Ambiguous, "multiple resources match, use full ID"
 
 	case errors.Is(err, images.ErrInvalidName):
-		w.WriteHeader(http.StatusBadRequest)
-		w.Write([]byte(`{"code":"invalid_name","message":"invalid image reference"}`))
+		return http.StatusBadRequest, oapi.InvalidRequest, "invalid image reference"
 
 	default:
-		w.WriteHeader(http.StatusInternalServerError)
-		w.Write([]byte(`{"code":"internal_error","message":"failed to resolve resource"}`))
+		return http.StatusInternalServerError, oapi.InternalError, "failed to resolve resource"
 	}
 }
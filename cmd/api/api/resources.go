@@ -10,16 +10,16 @@ import (
 // GetResources returns host resource capacity and allocations
 func (s *ApiService) GetResources(ctx context.Context, _ oapi.GetResourcesRequestObject) (oapi.GetResourcesResponseObject, error) {
 	if s.ResourceManager == nil {
-		return oapi.GetResources500JSONResponse{
-			Code:    "internal_error",
+		return oapi.GetResources500ApplicationProblemPlusJSONResponse{
+			Code:    oapi.InternalError,
 			Message: "Resource manager not initialized",
 		}, nil
 	}
 
 	status, err := s.ResourceManager.GetFullStatus(ctx)
 	if err != nil {
-		return oapi.GetResources500JSONResponse{
-			Code:    "internal_error",
+		return oapi.GetResources500ApplicationProblemPlusJSONResponse{
+			Code:    oapi.InternalError,
 			Message: err.Error(),
 		}, nil
 	}
@@ -97,6 +97,8 @@ func convertGPUResourceStatus(gs *resources.GPUResourceStatus) oapi.GPUResourceS
 				Name:          p.Name,
 				FramebufferMb: p.FramebufferMB,
 				Available:     p.Available,
+				Reserved:      p.Reserved,
+				Free:          p.Free,
 			}
 		}
 		result.Profiles = &profiles
@@ -114,5 +116,19 @@ func convertGPUResourceStatus(gs *resources.GPUResourceStatus) oapi.GPUResourceS
 		result.Devices = &devices
 	}
 
+	// Convert MIG-capable GPUs (vGPU mode)
+	if len(gs.MIGGPUs) > 0 {
+		migGPUs := make([]oapi.MIGGPU, len(gs.MIGGPUs))
+		for i, g := range gs.MIGGPUs {
+			migGPUs[i] = oapi.MIGGPU{
+				Index:      g.Index,
+				PciAddress: g.PCIAddress,
+				Name:       g.Name,
+				MigEnabled: g.MIGEnabled,
+			}
+		}
+		result.MigGpus = &migGPUs
+	}
+
 	return result
 }
@@ -0,0 +1,84 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/kernel/hypeman/lib/instances"
+	"github.com/kernel/hypeman/lib/logger"
+	mw "github.com/kernel/hypeman/lib/middleware"
+	"github.com/kernel/hypeman/lib/oapi"
+	"github.com/samber/lo"
+)
+
+// SetInstanceSchedules replaces an instance's scheduled start, standby and stop
+// Note: Resolution is handled by ResolveResource middleware
+func (s *ApiService) SetInstanceSchedules(ctx context.Context, request oapi.SetInstanceSchedulesRequestObject) (oapi.SetInstanceSchedulesResponseObject, error) {
+	inst := mw.GetResolvedInstance[instances.Instance](ctx)
+	if inst == nil {
+		return oapi.SetInstanceSchedules500ApplicationProblemPlusJSONResponse{
+			Code:    oapi.InternalError,
+			Message: "resource not resolved",
+		}, nil
+	}
+
+	result, err := s.InstanceManager.SetSchedules(ctx, inst.Id, schedulesFromOAPI(request.Body.Schedules))
+	if err != nil {
+		switch {
+		case errors.Is(err, instances.ErrInvalidSchedule):
+			return oapi.SetInstanceSchedules400ApplicationProblemPlusJSONResponse{
+				Code:    oapi.InvalidRequest,
+				Message: err.Error(),
+			}, nil
+		case errors.Is(err, instances.ErrNotFound):
+			return oapi.SetInstanceSchedules404ApplicationProblemPlusJSONResponse{
+				Code:    oapi.NotFound,
+				Message: "instance not found",
+			}, nil
+		default:
+			logger.FromContext(ctx).ErrorContext(ctx, "failed to set instance schedules", "error", err)
+			return oapi.SetInstanceSchedules500ApplicationProblemPlusJSONResponse{
+				Code:    oapi.InternalError,
+				Message: "failed to set instance schedules",
+			}, nil
+		}
+	}
+	return oapi.SetInstanceSchedules200JSONResponse(instanceToOAPI(*result)), nil
+}
+
+// schedulesFromOAPI converts requested schedules; the read-only run fields are ignored
+func schedulesFromOAPI(in []oapi.InstanceSchedule) []instances.Schedule {
+	out := make([]instances.Schedule, 0, len(in))
+	for _, s := range in {
+		out = append(out, instances.Schedule{
+			Action:   instances.ScheduleAction(s.Action),
+			Cron:     s.Cron,
+			Timezone: lo.FromPtr(s.Timezone),
+		})
+	}
+	return out
+}
+
+// schedulesToOAPI converts an instance's schedules, with when each next fires
+func schedulesToOAPI(in []instances.Schedule, now time.Time) []oapi.InstanceSchedule {
+	out := make([]oapi.InstanceSchedule, 0, len(in))
+	for _, s := range in {
+		sched := oapi.InstanceSchedule{
+			Action:    oapi.InstanceScheduleAction(s.Action),
+			Cron:      s.Cron,
+			LastRunAt: s.LastRunAt,
+		}
+		if s.Timezone != "" {
+			sched.Timezone = lo.ToPtr(s.Timezone)
+		}
+		if next := s.Next(now); !next.IsZero() {
+			sched.NextRunAt = &next
+		}
+		if s.LastError != "" {
+			sched.LastError = lo.ToPtr(s.LastError)
+		}
+		out = append(out, sched)
+	}
+	return out
+}
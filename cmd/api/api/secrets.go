@@ -0,0 +1,128 @@
+package api
+
+import (
+	"context"
+	"errors"
+
+	"github.com/kernel/hypeman/lib/logger"
+	mw "github.com/kernel/hypeman/lib/middleware"
+	"github.com/kernel/hypeman/lib/oapi"
+	"github.com/kernel/hypeman/lib/secrets"
+)
+
+// ListSecrets lists all secrets. Values are never returned.
+func (s *ApiService) ListSecrets(ctx context.Context, request oapi.ListSecretsRequestObject) (oapi.ListSecretsResponseObject, error) {
+	log := logger.FromContext(ctx)
+
+	domainSecrets, err := s.SecretManager.ListSecrets(ctx)
+	if err != nil {
+		log.ErrorContext(ctx, "failed to list secrets", "error", err)
+		return oapi.ListSecrets500ApplicationProblemPlusJSONResponse{
+			Code:    oapi.InternalError,
+			Message: "failed to list secrets",
+		}, nil
+	}
+
+	oapiSecrets := make([]oapi.Secret, 0, len(domainSecrets))
+	for _, secret := range domainSecrets {
+		if !mw.TenantVisible(ctx, secret.Tenant) {
+			continue
+		}
+		oapiSecrets = append(oapiSecrets, secretToOAPI(secret))
+	}
+
+	return oapi.ListSecrets200JSONResponse(oapiSecrets), nil
+}
+
+// CreateSecret stores a new secret
+func (s *ApiService) CreateSecret(ctx context.Context, request oapi.CreateSecretRequestObject) (oapi.CreateSecretResponseObject, error) {
+	tenant, err := mw.TenantForCreate(ctx, request.Body.Tenant)
+	if err != nil {
+		return oapi.CreateSecret403ApplicationProblemPlusJSONResponse{
+			Code:    oapi.Forbidden,
+			Message: err.Error(),
+		}, nil
+	}
+
+	secret, err := s.SecretManager.CreateSecret(ctx, secrets.CreateSecretRequest{
+		Name:   request.Body.Name,
+		Value:  []byte(request.Body.Value),
+		Id:     request.Body.Id,
+		Tenant: tenant,
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, secrets.ErrAlreadyExists):
+			return oapi.CreateSecret409ApplicationProblemPlusJSONResponse{
+				Code:    oapi.AlreadyExists,
+				Message: "secret with this ID already exists",
+			}, nil
+		case errors.Is(err, secrets.ErrInvalidName),
+			errors.Is(err, secrets.ErrTooLarge),
+			errors.Is(err, secrets.ErrDisabled):
+			return oapi.CreateSecret400ApplicationProblemPlusJSONResponse{
+				Code:    oapi.InvalidRequest,
+				Message: err.Error(),
+			}, nil
+		default:
+			logger.FromContext(ctx).ErrorContext(ctx, "failed to create secret", "error", err, "name", request.Body.Name)
+			return oapi.CreateSecret500ApplicationProblemPlusJSONResponse{
+				Code:    oapi.InternalError,
+				Message: "failed to create secret",
+			}, nil
+		}
+	}
+	return oapi.CreateSecret201JSONResponse(secretToOAPI(*secret)), nil
+}
+
+// GetSecret gets a secret's details by ID or name. The value is never returned.
+// Note: Resolution is handled by ResolveResource middleware
+func (s *ApiService) GetSecret(ctx context.Context, request oapi.GetSecretRequestObject) (oapi.GetSecretResponseObject, error) {
+	secret := mw.GetResolvedSecret[secrets.Secret](ctx)
+	if secret == nil {
+		return oapi.GetSecret500ApplicationProblemPlusJSONResponse{
+			Code:    oapi.InternalError,
+			Message: "resource not resolved",
+		}, nil
+	}
+	return oapi.GetSecret200JSONResponse(secretToOAPI(*secret)), nil
+}
+
+// DeleteSecret deletes a secret by ID or name
+// Note: Resolution is handled by ResolveResource middleware
+func (s *ApiService) DeleteSecret(ctx context.Context, request oapi.DeleteSecretRequestObject) (oapi.DeleteSecretResponseObject, error) {
+	secret := mw.GetResolvedSecret[secrets.Secret](ctx)
+	if secret == nil {
+		return oapi.DeleteSecret500ApplicationProblemPlusJSONResponse{
+			Code:    oapi.InternalError,
+			Message: "resource not resolved",
+		}, nil
+	}
+
+	if err := s.SecretManager.DeleteSecret(ctx, secret.Id); err != nil {
+		if errors.Is(err, secrets.ErrNotFound) {
+			return oapi.DeleteSecret404ApplicationProblemPlusJSONResponse{
+				Code:    oapi.NotFound,
+				Message: "secret not found",
+			}, nil
+		}
+		logger.FromContext(ctx).ErrorContext(ctx, "failed to delete secret", "error", err)
+		return oapi.DeleteSecret500ApplicationProblemPlusJSONResponse{
+			Code:    oapi.InternalError,
+			Message: "failed to delete secret",
+		}, nil
+	}
+	return oapi.DeleteSecret204Response{}, nil
+}
+
+func secretToOAPI(secret secrets.Secret) oapi.Secret {
+	oapiSecret := oapi.Secret{
+		Id:        secret.Id,
+		Name:      secret.Name,
+		CreatedAt: secret.CreatedAt,
+	}
+	if secret.Tenant != "" {
+		oapiSecret.Tenant = &secret.Tenant
+	}
+	return oapiSecret
+}
@@ -0,0 +1,66 @@
+package api
+
+import (
+	"encoding/json"
+	"testing"
+
+	mw "github.com/kernel/hypeman/lib/middleware"
+	"github.com/kernel/hypeman/lib/oapi"
+	"github.com/kernel/hypeman/lib/secrets"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSecrets_CreateGetDelete(t *testing.T) {
+	svc := newTestService(t)
+
+	createResp, err := svc.CreateSecret(ctx(), oapi.CreateSecretRequestObject{
+		Body: &oapi.CreateSecretRequest{Name: "api-token", Value: "s3cr3t"},
+	})
+	require.NoError(t, err)
+	created, ok := createResp.(oapi.CreateSecret201JSONResponse)
+	require.True(t, ok, "expected 201 response, got %T", createResp)
+	assert.Equal(t, "api-token", created.Name)
+
+	// The value is never part of a response
+	body, err := json.Marshal(created)
+	require.NoError(t, err)
+	assert.NotContains(t, string(body), "s3cr3t")
+
+	listResp, err := svc.ListSecrets(ctx(), oapi.ListSecretsRequestObject{})
+	require.NoError(t, err)
+	list, ok := listResp.(oapi.ListSecrets200JSONResponse)
+	require.True(t, ok, "expected 200 response")
+	require.Len(t, list, 1)
+
+	// Get by name - resolve as the middleware would
+	id, secret, err := SecretResolver{Manager: svc.SecretManager}.Resolve(ctx(), "api-token")
+	require.NoError(t, err)
+	assert.Equal(t, created.Id, id)
+	resolvedCtx := mw.WithResolvedSecret(ctx(), id, secret)
+
+	getResp, err := svc.GetSecret(resolvedCtx, oapi.GetSecretRequestObject{Id: "api-token"})
+	require.NoError(t, err)
+	got, ok := getResp.(oapi.GetSecret200JSONResponse)
+	require.True(t, ok, "expected 200 response")
+	assert.Equal(t, created.Id, got.Id)
+
+	deleteResp, err := svc.DeleteSecret(resolvedCtx, oapi.DeleteSecretRequestObject{Id: "api-token"})
+	require.NoError(t, err)
+	_, ok = deleteResp.(oapi.DeleteSecret204Response)
+	require.True(t, ok, "expected 204 response")
+
+	_, err = svc.SecretManager.GetSecret(ctx(), created.Id)
+	assert.ErrorIs(t, err, secrets.ErrNotFound)
+}
+
+func TestCreateSecret_Invalid(t *testing.T) {
+	svc := newTestService(t)
+
+	resp, err := svc.CreateSecret(ctx(), oapi.CreateSecretRequestObject{
+		Body: &oapi.CreateSecretRequest{Name: "../etc/passwd", Value: "x"},
+	})
+	require.NoError(t, err)
+	_, ok := resp.(oapi.CreateSecret400ApplicationProblemPlusJSONResponse)
+	assert.True(t, ok, "expected 400 response, got %T", resp)
+}
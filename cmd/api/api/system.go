@@ -0,0 +1,223 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/kernel/hypeman/lib/images"
+	"github.com/kernel/hypeman/lib/instances"
+	"github.com/kernel/hypeman/lib/logger"
+	mw "github.com/kernel/hypeman/lib/middleware"
+	"github.com/kernel/hypeman/lib/oapi"
+	"github.com/samber/lo"
+)
+
+// GetDiskUsage reports disk usage of the data directory by category
+func (s *ApiService) GetDiskUsage(ctx context.Context, _ oapi.GetDiskUsageRequestObject) (oapi.GetDiskUsageResponseObject, error) {
+	usage, err := s.ResourceManager.DiskUsage(ctx)
+	if err != nil {
+		return oapi.GetDiskUsage500ApplicationProblemPlusJSONResponse{
+			Code:    oapi.InternalError,
+			Message: fmt.Sprintf("measure disk usage: %v", err),
+		}, nil
+	}
+
+	// What a prune of dangling images and orphan build volumes would free
+	_, reclaimable, err := s.prune(ctx, oapi.PruneRequest{
+		DanglingImages:     lo.ToPtr(true),
+		OrphanBuildVolumes: lo.ToPtr(true),
+	}, true)
+	if err != nil {
+		return oapi.GetDiskUsage500ApplicationProblemPlusJSONResponse{
+			Code:    oapi.InternalError,
+			Message: err.Error(),
+		}, nil
+	}
+
+	return oapi.GetDiskUsage200JSONResponse{
+		ImagesBytes:      usage.Images,
+		OciCacheBytes:    usage.OCICache,
+		OverlaysBytes:    usage.Overlays,
+		SnapshotsBytes:   usage.Snapshots,
+		VolumesBytes:     usage.Volumes,
+		BuildsBytes:      usage.Builds,
+		TotalBytes:       usage.Total(),
+		ReclaimableBytes: reclaimable,
+	}, nil
+}
+
+// PruneSystem removes dangling images, old builds and orphan build volumes
+func (s *ApiService) PruneSystem(ctx context.Context, request oapi.PruneSystemRequestObject) (oapi.PruneSystemResponseObject, error) {
+	log := logger.FromContext(ctx)
+
+	// Prune spans all tenants, so a tenant-scoped principal can't run it
+	if mw.TenantFromContext(ctx) != "" {
+		return oapi.PruneSystem403ApplicationProblemPlusJSONResponse{
+			Code:    oapi.Forbidden,
+			Message: "prune applies to all tenants and is not permitted for tenant-scoped principals",
+		}, nil
+	}
+
+	req := request.Body
+	if !lo.FromPtr(req.DanglingImages) && req.BuildsOlderThan == nil && !lo.FromPtr(req.OrphanBuildVolumes) {
+		return oapi.PruneSystem400ApplicationProblemPlusJSONResponse{
+			Code:    oapi.InvalidRequest,
+			Message: "select at least one of dangling_images, builds_older_than or orphan_build_volumes",
+		}, nil
+	}
+	if req.BuildsOlderThan != nil {
+		if d, err := time.ParseDuration(*req.BuildsOlderThan); err != nil || d < 0 {
+			return oapi.PruneSystem400ApplicationProblemPlusJSONResponse{
+				Code:    oapi.InvalidRequest,
+				Message: fmt.Sprintf("builds_older_than must be a non-negative duration such as 168h, got %q", *req.BuildsOlderThan),
+			}, nil
+		}
+	}
+
+	dryRun := lo.FromPtr(request.Params.DryRun)
+	removed, reclaimed, err := s.prune(ctx, *req, dryRun)
+	if err != nil {
+		log.ErrorContext(ctx, "prune failed", "error", err, "removed", len(removed))
+		return oapi.PruneSystem500ApplicationProblemPlusJSONResponse{
+			Code:    oapi.InternalError,
+			Message: err.Error(),
+		}, nil
+	}
+	if !dryRun {
+		log.InfoContext(ctx, "pruned unused data", "removed", len(removed), "reclaimed_bytes", reclaimed)
+	}
+
+	return oapi.PruneSystem200JSONResponse{
+		DryRun:         dryRun,
+		Removed:        removed,
+		ReclaimedBytes: reclaimed,
+	}, nil
+}
+
+// prune removes what req selects, or only lists it with dryRun. The duration
+// in req must already be validated.
+func (s *ApiService) prune(ctx context.Context, req oapi.PruneRequest, dryRun bool) ([]oapi.PrunedResource, int64, error) {
+	removed := []oapi.PrunedResource{}
+	var reclaimed int64
+	add := func(kind oapi.PrunedResourceKind, id, name string, bytes int64) {
+		r := oapi.PrunedResource{Kind: kind, Id: id, Bytes: bytes}
+		if name != "" {
+			r.Name = &name
+		}
+		removed = append(removed, r)
+		reclaimed += bytes
+	}
+
+	if lo.FromPtr(req.DanglingImages) {
+		inUse, err := s.imagesInUse(ctx)
+		if err != nil {
+			return removed, reclaimed, err
+		}
+		images, err := s.ImageManager.PruneDanglingImages(ctx, inUse, dryRun)
+		for _, img := range images {
+			add(oapi.PrunedImage, img.Digest, img.Name, img.Bytes)
+		}
+		if err != nil {
+			return removed, reclaimed, fmt.Errorf("prune images: %w", err)
+		}
+	}
+
+	if req.BuildsOlderThan != nil {
+		olderThan, _ := time.ParseDuration(*req.BuildsOlderThan)
+		builds, err := s.BuildManager.PruneBuilds(ctx, time.Now().Add(-olderThan), dryRun)
+		for _, b := range builds {
+			add(oapi.PrunedBuild, b.ID, "", b.Bytes)
+		}
+		if err != nil {
+			return removed, reclaimed, fmt.Errorf("prune builds: %w", err)
+		}
+	}
+
+	if lo.FromPtr(req.OrphanBuildVolumes) {
+		vols, err := s.BuildManager.PruneOrphanVolumes(ctx, dryRun)
+		for _, v := range vols {
+			add(oapi.PrunedVolume, v.ID, "", v.Bytes)
+		}
+		if err != nil {
+			return removed, reclaimed, fmt.Errorf("prune build volumes: %w", err)
+		}
+	}
+
+	return removed, reclaimed, nil
+}
+
+// GetRetentionReport reports what the registry retention rules would remove
+func (s *ApiService) GetRetentionReport(ctx context.Context, _ oapi.GetRetentionReportRequestObject) (oapi.GetRetentionReportResponseObject, error) {
+	rules, res, err := s.ApplyRetention(ctx, true)
+	if err != nil {
+		return oapi.GetRetentionReport500ApplicationProblemPlusJSONResponse{
+			Code:    oapi.InternalError,
+			Message: err.Error(),
+		}, nil
+	}
+
+	report := oapi.GetRetentionReport200JSONResponse{
+		Rules:   make([]oapi.RetentionRule, 0, len(rules)),
+		Tags:    res.Tags,
+		Removed: make([]oapi.PrunedResource, 0, len(res.Images)),
+	}
+	for _, r := range rules {
+		rule := oapi.RetentionRule{Repository: r.Repository}
+		if r.KeepLast > 0 {
+			rule.KeepLast = lo.ToPtr(r.KeepLast)
+		}
+		if r.UntaggedOlderThan > 0 {
+			rule.UntaggedOlderThan = lo.ToPtr(r.UntaggedOlderThan.String())
+		}
+		report.Rules = append(report.Rules, rule)
+	}
+	for _, img := range res.Images {
+		report.Removed = append(report.Removed, oapi.PrunedResource{
+			Kind:  oapi.PrunedImage,
+			Id:    img.Digest,
+			Name:  lo.ToPtr(img.Name),
+			Bytes: img.Bytes,
+		})
+		report.ReclaimedBytes += img.Bytes
+	}
+	return report, nil
+}
+
+// ApplyRetention removes the images REGISTRY_RETENTION doesn't keep, with
+// their disks, or only reports them with dryRun. It returns the rules it
+// applied, and what was removed even when it fails partway.
+func (s *ApiService) ApplyRetention(ctx context.Context, dryRun bool) ([]images.RetentionRule, *images.RetentionResult, error) {
+	rules, err := images.ParseRetentionRules(s.Config.RegistryRetention)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid REGISTRY_RETENTION: %w", err)
+	}
+	inUse, err := s.imagesInUse(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	res, err := s.ImageManager.ApplyRetention(ctx, rules, inUse, dryRun)
+	if err != nil {
+		return rules, res, fmt.Errorf("apply retention: %w", err)
+	}
+	return rules, res, nil
+}
+
+// imagesInUse returns the image references whose disks instances may be
+// using. Stopped instances resolve their tag again when started, so only
+// their digest references pin an image.
+func (s *ApiService) imagesInUse(ctx context.Context) ([]string, error) {
+	insts, err := s.InstanceManager.ListInstances(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("list instances: %w", err)
+	}
+	var refs []string
+	for _, inst := range insts {
+		stopped := inst.State == instances.StateStopped || inst.State == instances.StateFailed
+		if !stopped || strings.Contains(inst.Image, "@") {
+			refs = append(refs, inst.Image)
+		}
+	}
+	return refs, nil
+}
@@ -0,0 +1,54 @@
+package api
+
+import (
+	"testing"
+
+	mw "github.com/kernel/hypeman/lib/middleware"
+	"github.com/kernel/hypeman/lib/oapi"
+	"github.com/samber/lo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPruneSystem_Validation(t *testing.T) {
+	svc := newTestService(t)
+
+	tests := []struct {
+		name string
+		body oapi.PruneRequest
+	}{
+		{"no selector", oapi.PruneRequest{}},
+		{"invalid duration", oapi.PruneRequest{BuildsOlderThan: lo.ToPtr("a week")}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := svc.PruneSystem(ctx(), oapi.PruneSystemRequestObject{Body: &tt.body})
+			require.NoError(t, err)
+			_, ok := resp.(oapi.PruneSystem400ApplicationProblemPlusJSONResponse)
+			assert.True(t, ok, "expected 400 response, got %T", resp)
+		})
+	}
+
+	tenantCtx := mw.WithClaims(ctx(), &mw.Claims{Subject: "ci", Role: mw.RoleAdmin, Tenant: "team-a"})
+	resp, err := svc.PruneSystem(tenantCtx, oapi.PruneSystemRequestObject{
+		Body: &oapi.PruneRequest{DanglingImages: lo.ToPtr(true)},
+	})
+	require.NoError(t, err)
+	_, ok := resp.(oapi.PruneSystem403ApplicationProblemPlusJSONResponse)
+	assert.True(t, ok, "expected 403 response, got %T", resp)
+}
+
+func TestPruneSystem_DanglingImagesDryRun(t *testing.T) {
+	svc := newTestService(t)
+
+	resp, err := svc.PruneSystem(ctx(), oapi.PruneSystemRequestObject{
+		Params: oapi.PruneSystemParams{DryRun: lo.ToPtr(true)},
+		Body:   &oapi.PruneRequest{DanglingImages: lo.ToPtr(true)},
+	})
+	require.NoError(t, err)
+	result, ok := resp.(oapi.PruneSystem200JSONResponse)
+	require.True(t, ok, "expected 200 response, got %T", resp)
+	assert.True(t, result.DryRun)
+	assert.Empty(t, result.Removed)
+	assert.Zero(t, result.ReclaimedBytes)
+}
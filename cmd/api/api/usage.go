@@ -0,0 +1,71 @@
+package api
+
+import (
+	"context"
+	"time"
+
+	"github.com/kernel/hypeman/lib/logger"
+	"github.com/kernel/hypeman/lib/metering"
+	mw "github.com/kernel/hypeman/lib/middleware"
+	"github.com/kernel/hypeman/lib/oapi"
+	"github.com/samber/lo"
+)
+
+// defaultUsageRange is how far back GET /usage goes without a from
+const defaultUsageRange = 24 * time.Hour
+
+// ListUsage lists hourly per-instance usage records
+func (s *ApiService) ListUsage(ctx context.Context, request oapi.ListUsageRequestObject) (oapi.ListUsageResponseObject, error) {
+	to := time.Now()
+	if request.Params.To != nil {
+		to = *request.Params.To
+	}
+	from := to.Add(-defaultUsageRange)
+	if request.Params.From != nil {
+		from = *request.Params.From
+	}
+	if !from.Before(to) {
+		return oapi.ListUsage400ApplicationProblemPlusJSONResponse{
+			Code:    oapi.InvalidRequest,
+			Message: "from must be before to",
+		}, nil
+	}
+
+	records, err := s.Meter.Query(from, to)
+	if err != nil {
+		logger.FromContext(ctx).ErrorContext(ctx, "failed to query usage", "error", err)
+		return oapi.ListUsage500ApplicationProblemPlusJSONResponse{
+			Code:    oapi.InternalError,
+			Message: "failed to query usage",
+		}, nil
+	}
+
+	out := make([]oapi.UsageRecord, 0, len(records))
+	for _, r := range records {
+		if !mw.TenantVisible(ctx, r.Tenant) {
+			continue
+		}
+		if request.Params.Instance != nil && r.InstanceID != *request.Params.Instance {
+			continue
+		}
+		out = append(out, usageRecordToOAPI(r))
+	}
+	return oapi.ListUsage200JSONResponse(out), nil
+}
+
+func usageRecordToOAPI(r metering.Record) oapi.UsageRecord {
+	out := oapi.UsageRecord{
+		Hour:              r.Hour,
+		InstanceId:        r.InstanceID,
+		InstanceName:      r.InstanceName,
+		Tenant:            lo.EmptyableToPtr(r.Tenant),
+		VcpuSeconds:       r.VcpuSeconds,
+		MemoryByteSeconds: r.MemoryByteSeconds,
+		DiskGbHours:       r.DiskGBHours,
+	}
+	if r.GPUProfile != "" {
+		out.GpuProfile = &r.GPUProfile
+		out.GpuProfileHours = &r.GPUProfileHours
+	}
+	return out
+}
@@ -0,0 +1,63 @@
+package api
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/kernel/hypeman/lib/instances"
+	"github.com/kernel/hypeman/lib/metering"
+	mw "github.com/kernel/hypeman/lib/middleware"
+	"github.com/kernel/hypeman/lib/oapi"
+	"github.com/kernel/hypeman/lib/paths"
+	"github.com/samber/lo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type staticInstances []instances.Instance
+
+func (s staticInstances) ListInstances(ctx context.Context) ([]instances.Instance, error) {
+	return s, nil
+}
+
+func TestListUsage(t *testing.T) {
+	var a, b instances.Instance
+	a.Id, a.Name, a.Tenant, a.State, a.Vcpus = "inst-a", "a", "team-a", instances.StateRunning, 2
+	b.Id, b.Name, b.Tenant, b.State, b.Vcpus = "inst-b", "b", "team-b", instances.StateRunning, 2
+	meter := metering.NewMeter(paths.New(t.TempDir()), staticInstances{a, b}, metering.Config{Interval: time.Minute})
+	require.NoError(t, meter.Sample(ctx()))
+	time.Sleep(10 * time.Millisecond)
+	require.NoError(t, meter.Sample(ctx()))
+	svc := &ApiService{Meter: meter}
+
+	resp, err := svc.ListUsage(ctx(), oapi.ListUsageRequestObject{})
+	require.NoError(t, err)
+	records, ok := resp.(oapi.ListUsage200JSONResponse)
+	require.True(t, ok, "expected 200 response, got %T", resp)
+	require.Len(t, records, 2)
+	assert.Greater(t, records[0].VcpuSeconds, 0.0)
+
+	// Tenant-scoped callers only see their tenant's instances
+	tenantCtx := mw.WithClaims(ctx(), &mw.Claims{Subject: "ci", Role: mw.RoleAdmin, Tenant: "team-b"})
+	resp, err = svc.ListUsage(tenantCtx, oapi.ListUsageRequestObject{})
+	require.NoError(t, err)
+	records = resp.(oapi.ListUsage200JSONResponse)
+	require.Len(t, records, 1)
+	assert.Equal(t, "inst-b", records[0].InstanceId)
+
+	resp, err = svc.ListUsage(ctx(), oapi.ListUsageRequestObject{Params: oapi.ListUsageParams{Instance: lo.ToPtr("inst-a")}})
+	require.NoError(t, err)
+	assert.Len(t, resp.(oapi.ListUsage200JSONResponse), 1)
+
+	// Ranges before the records are empty, and from must precede to
+	past := time.Now().Add(-48 * time.Hour)
+	resp, err = svc.ListUsage(ctx(), oapi.ListUsageRequestObject{Params: oapi.ListUsageParams{To: &past}})
+	require.NoError(t, err)
+	assert.Empty(t, resp.(oapi.ListUsage200JSONResponse))
+
+	now := time.Now()
+	resp, err = svc.ListUsage(ctx(), oapi.ListUsageRequestObject{Params: oapi.ListUsageParams{From: &now, To: &past}})
+	require.NoError(t, err)
+	assert.IsType(t, oapi.ListUsage400ApplicationProblemPlusJSONResponse{}, resp)
+}
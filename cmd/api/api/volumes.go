@@ -3,6 +3,7 @@ package api
 import (
 	"context"
 	"errors"
+	"fmt"
 	"io"
 	"mime/multipart"
 	"strconv"
@@ -10,7 +11,9 @@ import (
 	"github.com/kernel/hypeman/lib/logger"
 	mw "github.com/kernel/hypeman/lib/middleware"
 	"github.com/kernel/hypeman/lib/oapi"
+	"github.com/kernel/hypeman/lib/quotas"
 	"github.com/kernel/hypeman/lib/volumes"
+	"github.com/samber/lo"
 )
 
 // ListVolumes lists all volumes
@@ -20,15 +23,18 @@ func (s *ApiService) ListVolumes(ctx context.Context, request oapi.ListVolumesRe
 	domainVols, err := s.VolumeManager.ListVolumes(ctx)
 	if err != nil {
 		log.ErrorContext(ctx, "failed to list volumes", "error", err)
-		return oapi.ListVolumes500JSONResponse{
-			Code:    "internal_error",
+		return oapi.ListVolumes500ApplicationProblemPlusJSONResponse{
+			Code:    oapi.InternalError,
 			Message: "failed to list volumes",
 		}, nil
 	}
 
-	oapiVols := make([]oapi.Volume, len(domainVols))
-	for i, vol := range domainVols {
-		oapiVols[i] = volumeToOAPI(vol)
+	oapiVols := make([]oapi.Volume, 0, len(domainVols))
+	for _, vol := range domainVols {
+		if !mw.TenantVisible(ctx, vol.Tenant) {
+			continue
+		}
+		oapiVols = append(oapiVols, volumeToOAPI(vol))
 	}
 
 	return oapi.ListVolumes200JSONResponse(oapiVols), nil
@@ -39,44 +45,95 @@ func (s *ApiService) ListVolumes(ctx context.Context, request oapi.ListVolumesRe
 // - JSON body: Creates an empty volume of the specified size
 // - Multipart form: Creates a volume pre-populated with content from a tar.gz archive
 func (s *ApiService) CreateVolume(ctx context.Context, request oapi.CreateVolumeRequestObject) (oapi.CreateVolumeResponseObject, error) {
-	log := logger.FromContext(ctx)
-
 	// Handle JSON request (empty volume)
 	if request.JSONBody != nil {
+		tenant, err := mw.TenantForCreate(ctx, request.JSONBody.Tenant)
+		if err != nil {
+			return oapi.CreateVolume403ApplicationProblemPlusJSONResponse{
+				Code:    oapi.Forbidden,
+				Message: err.Error(),
+			}, nil
+		}
+
 		domainReq := volumes.CreateVolumeRequest{
-			Name:   request.JSONBody.Name,
-			SizeGb: request.JSONBody.SizeGb,
-			Id:     request.JSONBody.Id,
+			Name:      request.JSONBody.Name,
+			SizeGb:    request.JSONBody.SizeGb,
+			Id:        request.JSONBody.Id,
+			Tenant:    tenant,
+			CreatedBy: mw.CreatorFromContext(ctx),
+			Shared:    lo.FromPtr(request.JSONBody.Shared),
+			Encrypted: lo.FromPtr(request.JSONBody.Encrypted),
 		}
 
-		vol, err := s.VolumeManager.CreateVolume(ctx, domainReq)
+		// Dry runs check the quota too, releasing it straight away
+		release, err := s.admitQuota(ctx, tenant, quotas.Resources{VolumeSize: int64(domainReq.SizeGb) * 1024 * 1024 * 1024})
 		if err != nil {
-			if errors.Is(err, volumes.ErrAlreadyExists) {
-				return oapi.CreateVolume409JSONResponse{
-					Code:    "already_exists",
-					Message: "volume with this ID already exists",
-				}, nil
+			return createVolumeError(ctx, err, request.JSONBody.Name), nil
+		}
+		defer release()
+
+		if request.Params.DryRun != nil && *request.Params.DryRun {
+			if err := s.VolumeManager.CheckCreateVolume(ctx, domainReq); err != nil {
+				return createVolumeError(ctx, err, request.JSONBody.Name), nil
 			}
-			log.ErrorContext(ctx, "failed to create volume", "error", err, "name", request.JSONBody.Name)
-			return oapi.CreateVolume500JSONResponse{
-				Code:    "internal_error",
-				Message: "failed to create volume",
-			}, nil
+			details := []string{fmt.Sprintf("size: %dGB", domainReq.SizeGb)}
+			if domainReq.Shared {
+				details = append(details, "shared: read-only attachments only")
+			}
+			if domainReq.Encrypted {
+				details = append(details, "encrypted: LUKS2")
+			}
+			return oapi.CreateVolume200JSONResponse(dryRunResult(oapi.ApplyResourceKindVolume, oapi.ApplyCreate, domainReq.Name, lo.FromPtr(domainReq.Id), details)), nil
+		}
+
+		vol, err := s.VolumeManager.CreateVolume(ctx, domainReq)
+		if err != nil {
+			return createVolumeError(ctx, err, request.JSONBody.Name), nil
 		}
 		return oapi.CreateVolume201JSONResponse(volumeToOAPI(*vol)), nil
 	}
 
 	// Handle multipart request (volume with archive content)
 	if request.MultipartBody != nil {
+		if request.Params.DryRun != nil && *request.Params.DryRun {
+			return oapi.CreateVolume400ApplicationProblemPlusJSONResponse{
+				Code:    oapi.InvalidRequest,
+				Message: "dry_run is not supported for archive uploads",
+			}, nil
+		}
 		return s.createVolumeFromMultipart(ctx, request.MultipartBody)
 	}
 
-	return oapi.CreateVolume400JSONResponse{
-		Code:    "invalid_request",
+	return oapi.CreateVolume400ApplicationProblemPlusJSONResponse{
+		Code:    oapi.InvalidRequest,
 		Message: "request body is required",
 	}, nil
 }
 
+// createVolumeError maps a volume creation or admission error to a response
+func createVolumeError(ctx context.Context, err error, name string) oapi.CreateVolumeResponseObject {
+	if problem, ok := quotaProblem(err); ok {
+		return oapi.CreateVolume403ApplicationProblemPlusJSONResponse(problem)
+	}
+	if errors.Is(err, volumes.ErrAlreadyExists) {
+		return oapi.CreateVolume409ApplicationProblemPlusJSONResponse{
+			Code:    oapi.AlreadyExists,
+			Message: "volume with this ID already exists",
+		}
+	}
+	if errors.Is(err, volumes.ErrEncryptionDisabled) {
+		return oapi.CreateVolume400ApplicationProblemPlusJSONResponse{
+			Code:    oapi.InvalidRequest,
+			Message: err.Error(),
+		}
+	}
+	logger.FromContext(ctx).ErrorContext(ctx, "failed to create volume", "error", err, "name", name)
+	return oapi.CreateVolume500ApplicationProblemPlusJSONResponse{
+		Code:    oapi.InternalError,
+		Message: "failed to create volume",
+	}
+}
+
 // createVolumeFromMultipart handles creating a volume from multipart form data with archive content
 func (s *ApiService) createVolumeFromMultipart(ctx context.Context, multipartReader *multipart.Reader) (oapi.CreateVolumeResponseObject, error) {
 	log := logger.FromContext(ctx)
@@ -84,6 +141,8 @@ func (s *ApiService) createVolumeFromMultipart(ctx context.Context, multipartRea
 	var name string
 	var sizeGb int
 	var id *string
+	var tenant *string
+	var shared bool
 	var archiveReader io.Reader
 
 	for {
@@ -92,8 +151,8 @@ func (s *ApiService) createVolumeFromMultipart(ctx context.Context, multipartRea
 			break
 		}
 		if err != nil {
-			return oapi.CreateVolume400JSONResponse{
-				Code:    "invalid_form",
+			return oapi.CreateVolume400ApplicationProblemPlusJSONResponse{
+				Code:    oapi.InvalidRequest,
 				Message: "failed to parse multipart form: " + err.Error(),
 			}, nil
 		}
@@ -102,8 +161,8 @@ func (s *ApiService) createVolumeFromMultipart(ctx context.Context, multipartRea
 		case "name":
 			data, err := io.ReadAll(part)
 			if err != nil {
-				return oapi.CreateVolume400JSONResponse{
-					Code:    "invalid_field",
+				return oapi.CreateVolume400ApplicationProblemPlusJSONResponse{
+					Code:    oapi.InvalidRequest,
 					Message: "failed to read name field",
 				}, nil
 			}
@@ -111,23 +170,23 @@ func (s *ApiService) createVolumeFromMultipart(ctx context.Context, multipartRea
 		case "size_gb":
 			data, err := io.ReadAll(part)
 			if err != nil {
-				return oapi.CreateVolume400JSONResponse{
-					Code:    "invalid_field",
+				return oapi.CreateVolume400ApplicationProblemPlusJSONResponse{
+					Code:    oapi.InvalidRequest,
 					Message: "failed to read size_gb field",
 				}, nil
 			}
 			sizeGb, err = strconv.Atoi(string(data))
 			if err != nil || sizeGb <= 0 {
-				return oapi.CreateVolume400JSONResponse{
-					Code:    "invalid_field",
+				return oapi.CreateVolume400ApplicationProblemPlusJSONResponse{
+					Code:    oapi.InvalidRequest,
 					Message: "size_gb must be a positive integer",
 				}, nil
 			}
 		case "id":
 			data, err := io.ReadAll(part)
 			if err != nil {
-				return oapi.CreateVolume400JSONResponse{
-					Code:    "invalid_field",
+				return oapi.CreateVolume400ApplicationProblemPlusJSONResponse{
+					Code:    oapi.InvalidRequest,
 					Message: "failed to read id field",
 				}, nil
 			}
@@ -135,46 +194,88 @@ func (s *ApiService) createVolumeFromMultipart(ctx context.Context, multipartRea
 			if idStr != "" {
 				id = &idStr
 			}
+		case "tenant":
+			data, err := io.ReadAll(part)
+			if err != nil {
+				return oapi.CreateVolume400ApplicationProblemPlusJSONResponse{
+					Code:    oapi.InvalidRequest,
+					Message: "failed to read tenant field",
+				}, nil
+			}
+			tenantStr := string(data)
+			tenant = &tenantStr
+		case "shared":
+			data, err := io.ReadAll(part)
+			if err != nil {
+				return oapi.CreateVolume400ApplicationProblemPlusJSONResponse{
+					Code:    oapi.InvalidRequest,
+					Message: "failed to read shared field",
+				}, nil
+			}
+			shared, err = strconv.ParseBool(string(data))
+			if err != nil {
+				return oapi.CreateVolume400ApplicationProblemPlusJSONResponse{
+					Code:    oapi.InvalidRequest,
+					Message: "shared must be a boolean",
+				}, nil
+			}
 		case "content":
 			archiveReader = part
 			// Process the archive immediately while we have the reader
 			if name == "" {
-				return oapi.CreateVolume400JSONResponse{
-					Code:    "missing_field",
+				return oapi.CreateVolume400ApplicationProblemPlusJSONResponse{
+					Code:    oapi.InvalidRequest,
 					Message: "name is required",
 				}, nil
 			}
 			if sizeGb <= 0 {
-				return oapi.CreateVolume400JSONResponse{
-					Code:    "missing_field",
+				return oapi.CreateVolume400ApplicationProblemPlusJSONResponse{
+					Code:    oapi.InvalidRequest,
 					Message: "size_gb is required",
 				}, nil
 			}
 
+			volTenant, err := mw.TenantForCreate(ctx, tenant)
+			if err != nil {
+				return oapi.CreateVolume403ApplicationProblemPlusJSONResponse{
+					Code:    oapi.Forbidden,
+					Message: err.Error(),
+				}, nil
+			}
+
 			// Create the volume from archive
 			domainReq := volumes.CreateVolumeFromArchiveRequest{
-				Name:   name,
-				SizeGb: sizeGb,
-				Id:     id,
+				Name:      name,
+				SizeGb:    sizeGb,
+				Id:        id,
+				Tenant:    volTenant,
+				CreatedBy: mw.CreatorFromContext(ctx),
+				Shared:    shared,
+			}
+
+			release, err := s.admitQuota(ctx, volTenant, quotas.Resources{VolumeSize: int64(sizeGb) * 1024 * 1024 * 1024})
+			if err != nil {
+				return createVolumeError(ctx, err, name), nil
 			}
+			defer release()
 
 			vol, err := s.VolumeManager.CreateVolumeFromArchive(ctx, domainReq, archiveReader)
 			if err != nil {
 				if errors.Is(err, volumes.ErrArchiveTooLarge) {
-					return oapi.CreateVolume400JSONResponse{
-						Code:    "archive_too_large",
+					return oapi.CreateVolume400ApplicationProblemPlusJSONResponse{
+						Code:    oapi.InvalidRequest,
 						Message: err.Error(),
 					}, nil
 				}
 				if errors.Is(err, volumes.ErrAlreadyExists) {
-					return oapi.CreateVolume409JSONResponse{
-						Code:    "already_exists",
+					return oapi.CreateVolume409ApplicationProblemPlusJSONResponse{
+						Code:    oapi.AlreadyExists,
 						Message: "volume with this ID already exists",
 					}, nil
 				}
 				log.ErrorContext(ctx, "failed to create volume from archive", "error", err, "name", name)
-				return oapi.CreateVolume500JSONResponse{
-					Code:    "internal_error",
+				return oapi.CreateVolume500ApplicationProblemPlusJSONResponse{
+					Code:    oapi.InternalError,
 					Message: "failed to create volume",
 				}, nil
 			}
@@ -185,15 +286,15 @@ func (s *ApiService) createVolumeFromMultipart(ctx context.Context, multipartRea
 
 	// If we get here without processing content, it means content was not provided
 	if archiveReader == nil {
-		return oapi.CreateVolume400JSONResponse{
-			Code:    "missing_file",
+		return oapi.CreateVolume400ApplicationProblemPlusJSONResponse{
+			Code:    oapi.InvalidRequest,
 			Message: "content file is required for multipart requests",
 		}, nil
 	}
 
 	// Should not reach here
-	return oapi.CreateVolume500JSONResponse{
-		Code:    "internal_error",
+	return oapi.CreateVolume500ApplicationProblemPlusJSONResponse{
+		Code:    oapi.InternalError,
 		Message: "unexpected error processing request",
 	}, nil
 }
@@ -204,39 +305,88 @@ func (s *ApiService) createVolumeFromMultipart(ctx context.Context, multipartRea
 func (s *ApiService) GetVolume(ctx context.Context, request oapi.GetVolumeRequestObject) (oapi.GetVolumeResponseObject, error) {
 	vol := mw.GetResolvedVolume[volumes.Volume](ctx)
 	if vol == nil {
-		return oapi.GetVolume500JSONResponse{
-			Code:    "internal_error",
+		return oapi.GetVolume500ApplicationProblemPlusJSONResponse{
+			Code:    oapi.InternalError,
 			Message: "resource not resolved",
 		}, nil
 	}
 	return oapi.GetVolume200JSONResponse(volumeToOAPI(*vol)), nil
 }
 
+// UpdateVolume marks a volume as shared or unshares it
+// The id parameter can be either a volume ID or name
+// Note: Resolution is handled by ResolveResource middleware
+func (s *ApiService) UpdateVolume(ctx context.Context, request oapi.UpdateVolumeRequestObject) (oapi.UpdateVolumeResponseObject, error) {
+	vol := mw.GetResolvedVolume[volumes.Volume](ctx)
+	if vol == nil {
+		return oapi.UpdateVolume500ApplicationProblemPlusJSONResponse{
+			Code:    oapi.InternalError,
+			Message: "resource not resolved",
+		}, nil
+	}
+
+	updated, err := s.VolumeManager.UpdateVolume(ctx, vol.Id, volumes.UpdateVolumeRequest{
+		Shared: request.Body.Shared,
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, volumes.ErrNotFound):
+			return oapi.UpdateVolume404ApplicationProblemPlusJSONResponse{
+				Code:    oapi.NotFound,
+				Message: "volume not found",
+			}, nil
+		case errors.Is(err, volumes.ErrInUse):
+			return oapi.UpdateVolume409ApplicationProblemPlusJSONResponse{
+				Code:    oapi.Conflict,
+				Message: err.Error(),
+			}, nil
+		default:
+			logger.FromContext(ctx).ErrorContext(ctx, "failed to update volume", "error", err)
+			return oapi.UpdateVolume500ApplicationProblemPlusJSONResponse{
+				Code:    oapi.InternalError,
+				Message: "failed to update volume",
+			}, nil
+		}
+	}
+	return oapi.UpdateVolume200JSONResponse(volumeToOAPI(*updated)), nil
+}
+
 // DeleteVolume deletes a volume
 // The id parameter can be either a volume ID or name
 // Note: Resolution is handled by ResolveResource middleware
 func (s *ApiService) DeleteVolume(ctx context.Context, request oapi.DeleteVolumeRequestObject) (oapi.DeleteVolumeResponseObject, error) {
 	vol := mw.GetResolvedVolume[volumes.Volume](ctx)
 	if vol == nil {
-		return oapi.DeleteVolume500JSONResponse{
-			Code:    "internal_error",
+		return oapi.DeleteVolume500ApplicationProblemPlusJSONResponse{
+			Code:    oapi.InternalError,
 			Message: "resource not resolved",
 		}, nil
 	}
 	log := logger.FromContext(ctx)
 
+	if request.Params.DryRun != nil && *request.Params.DryRun {
+		if len(vol.Attachments) > 0 {
+			return oapi.DeleteVolume409ApplicationProblemPlusJSONResponse{
+				Code:    oapi.Conflict,
+				Message: "volume is in use by an instance",
+			}, nil
+		}
+		details := []string{fmt.Sprintf("%dGB of data would be deleted", vol.SizeGb)}
+		return oapi.DeleteVolume200JSONResponse(dryRunResult(oapi.ApplyResourceKindVolume, oapi.ApplyDelete, vol.Name, vol.Id, details)), nil
+	}
+
 	err := s.VolumeManager.DeleteVolume(ctx, vol.Id)
 	if err != nil {
 		switch {
 		case errors.Is(err, volumes.ErrInUse):
-			return oapi.DeleteVolume409JSONResponse{
-				Code:    "conflict",
+			return oapi.DeleteVolume409ApplicationProblemPlusJSONResponse{
+				Code:    oapi.Conflict,
 				Message: "volume is in use by an instance",
 			}, nil
 		default:
 			log.ErrorContext(ctx, "failed to delete volume", "error", err)
-			return oapi.DeleteVolume500JSONResponse{
-				Code:    "internal_error",
+			return oapi.DeleteVolume500ApplicationProblemPlusJSONResponse{
+				Code:    oapi.InternalError,
 				Message: "failed to delete volume",
 			}, nil
 		}
@@ -251,6 +401,15 @@ func volumeToOAPI(vol volumes.Volume) oapi.Volume {
 		SizeGb:    vol.SizeGb,
 		CreatedAt: vol.CreatedAt,
 	}
+	if vol.Tenant != "" {
+		oapiVol.Tenant = &vol.Tenant
+	}
+	if vol.Shared {
+		oapiVol.Shared = lo.ToPtr(true)
+	}
+	if vol.Encrypted {
+		oapiVol.Encrypted = lo.ToPtr(true)
+	}
 
 	// Convert attachments
 	if len(vol.Attachments) > 0 {
@@ -4,6 +4,8 @@ import (
 	"testing"
 
 	"github.com/kernel/hypeman/lib/oapi"
+	"github.com/kernel/hypeman/lib/volumes"
+	"github.com/samber/lo"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -73,3 +75,86 @@ func TestDeleteVolume_ByName(t *testing.T) {
 	_, ok := resp.(oapi.DeleteVolume204Response)
 	assert.True(t, ok, "expected 204 response")
 }
+
+func TestCreateVolume_DryRun(t *testing.T) {
+	svc := newTestService(t)
+
+	resp, err := svc.CreateVolume(ctx(), oapi.CreateVolumeRequestObject{
+		Params:   oapi.CreateVolumeParams{DryRun: lo.ToPtr(true)},
+		JSONBody: &oapi.CreateVolumeRequest{Name: "planned", SizeGb: 2},
+	})
+	require.NoError(t, err)
+	result, ok := resp.(oapi.CreateVolume200JSONResponse)
+	require.True(t, ok, "expected 200 response, got %T", resp)
+	assert.True(t, result.DryRun)
+	assert.Equal(t, oapi.ApplyCreate, result.Action)
+	assert.Equal(t, "planned", result.Name)
+
+	vols, err := svc.VolumeManager.ListVolumes(ctx())
+	require.NoError(t, err)
+	assert.Empty(t, vols)
+}
+
+func TestDeleteVolume_DryRunInUse(t *testing.T) {
+	svc := newTestService(t)
+
+	vol, err := svc.VolumeManager.CreateVolume(ctx(), volumes.CreateVolumeRequest{Name: "attached", SizeGb: 1})
+	require.NoError(t, err)
+	require.NoError(t, svc.VolumeManager.AttachVolume(ctx(), vol.Id, volumes.AttachVolumeRequest{
+		InstanceID: "instance-1",
+		MountPath:  "/data",
+	}))
+
+	resp, err := svc.DeleteVolume(ctxWithVolume(svc, "attached"), oapi.DeleteVolumeRequestObject{
+		Id:     "attached",
+		Params: oapi.DeleteVolumeParams{DryRun: lo.ToPtr(true)},
+	})
+	require.NoError(t, err)
+	_, ok := resp.(oapi.DeleteVolume409ApplicationProblemPlusJSONResponse)
+	assert.True(t, ok, "expected 409 response, got %T", resp)
+
+	_, err = svc.VolumeManager.GetVolume(ctx(), vol.Id)
+	assert.NoError(t, err)
+}
+
+func TestUpdateVolume_Shared(t *testing.T) {
+	svc := newTestService(t)
+
+	vol, err := svc.VolumeManager.CreateVolume(ctx(), volumes.CreateVolumeRequest{Name: "weights", SizeGb: 1})
+	require.NoError(t, err)
+
+	resp, err := svc.UpdateVolume(ctxWithVolume(svc, "weights"), oapi.UpdateVolumeRequestObject{
+		Id:   "weights",
+		Body: &oapi.UpdateVolumeRequest{Shared: lo.ToPtr(true)},
+	})
+	require.NoError(t, err)
+	updated, ok := resp.(oapi.UpdateVolume200JSONResponse)
+	require.True(t, ok, "expected 200 response, got %T", resp)
+	assert.Equal(t, lo.ToPtr(true), updated.Shared)
+
+	// Unsharing is rejected while shared attachments exist
+	require.NoError(t, svc.VolumeManager.AttachVolume(ctx(), vol.Id, volumes.AttachVolumeRequest{
+		InstanceID: "instance-1",
+		MountPath:  "/models",
+		Readonly:   true,
+	}))
+	resp, err = svc.UpdateVolume(ctxWithVolume(svc, "weights"), oapi.UpdateVolumeRequestObject{
+		Id:   "weights",
+		Body: &oapi.UpdateVolumeRequest{Shared: lo.ToPtr(false)},
+	})
+	require.NoError(t, err)
+	_, ok = resp.(oapi.UpdateVolume409ApplicationProblemPlusJSONResponse)
+	assert.True(t, ok, "expected 409 response, got %T", resp)
+}
+
+func TestCreateVolume_EncryptionDisabled(t *testing.T) {
+	svc := newTestService(t)
+
+	resp, err := svc.CreateVolume(ctx(), oapi.CreateVolumeRequestObject{
+		JSONBody: &oapi.CreateVolumeRequest{Name: "secret", SizeGb: 1, Encrypted: lo.ToPtr(true)},
+	})
+	require.NoError(t, err)
+	badReq, ok := resp.(oapi.CreateVolume400ApplicationProblemPlusJSONResponse)
+	require.True(t, ok, "expected 400 response, got %T", resp)
+	assert.Equal(t, oapi.InvalidRequest, badReq.Code)
+}
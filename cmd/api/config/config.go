@@ -2,11 +2,14 @@ package config
 
 import (
 	"fmt"
+	"net"
+	"net/url"
 	"os"
+	"path/filepath"
 	"runtime/debug"
 	"strconv"
-
-	"github.com/joho/godotenv"
+	"strings"
+	"time"
 )
 
 func getHostname() string {
@@ -51,19 +54,53 @@ func getBuildVersion() string {
 
 type Config struct {
 	Port                string
+	GRPCPort            string // Port for the gRPC management API (empty = disabled)
+	MetadataPort        string // Gateway port of the guest metadata service at 169.254.169.254 (empty = disabled)
+	UnprefixedAPISunset string // Date (YYYY-MM-DD) API paths without /v1 stop working, announced in Sunset headers (empty = not scheduled)
 	DataDir             string
 	BridgeName          string
 	SubnetCIDR          string
 	SubnetGateway       string
 	UplinkInterface     string
+	UplinkMode          string // How the default network reaches the host's network unless set through the API: nat, vlan or macvtap
+	UplinkVLAN          int    // VLAN ID on UPLINK_INTERFACE guests are put on (vlan mode; optional in macvtap mode)
+	OverlayListenPort   int    // WireGuard port of the cross-host overlay (0 = disabled)
+	OverlayEndpoint     string // host:port other hypeman hosts reach this one's overlay at
+	OverlayInterface    string // WireGuard interface of the overlay
 	JwtSecret           string
+	JwksURL             string // JWKS endpoint for verifying asymmetric JWTs (optional)
+	JwtIssuer           string // Required JWT "iss" claim (optional)
+	JwtAudience         string // Required JWT "aud" claim (optional)
+	ApiKeys             string // Comma-separated static API keys as subject:key[:role[:tenant]] entries (optional)
+	AuthDefaultRole     string // Role for credentials that don't specify one (viewer, operator, admin)
 	DNSServer           string
+	DnsmasqPIDFile      string // dnsmasq PID file; signalled (SIGHUP) when custom DNS records change (optional)
+	DnsRecordTTL        string // TTL dnsmasq serves instance and custom records with
 	MaxConcurrentBuilds int
 	MaxOverlaySize      string
-	LogMaxSize          string
-	LogMaxFiles         int
+	LogMaxSize          string // Rotate an instance log once it reaches this size
+	LogMaxFiles         int    // Rotated copies kept per instance log
+	LogMaxAge           string // Remove rotated instance logs older than this (0 = no limit)
+	LogMaxTotalSize     string // Cap on all instance logs together (0 = no limit)
 	LogRotateInterval   string
 
+	// Exec session recording, for compliance audits
+	ExecRecording             bool   // Record the input and output of exec sessions
+	ExecRecordingMaxAge       string // Remove recordings older than this (0 = no limit)
+	ExecRecordingMaxTotalSize string // Cap on all recordings together (0 = no limit)
+
+	// API server TLS (empty cert = plain HTTP)
+	TLSCertFile     string // PEM certificate (chain) for the API server
+	TLSKeyFile      string // PEM private key for TLSCertFile
+	TLSClientCAFile string // PEM CA bundle for verifying client certificates (enables mTLS)
+	TLSClientAuth   string // "require" (default) or "optional" client certificates when TLSClientCAFile is set
+
+	// API rate limiting - per client (API key / JWT subject, or IP if unauthenticated)
+	RateLimitRPS         float64 // Sustained requests per second (0 = unlimited)
+	RateLimitBurst       int     // Maximum burst above the sustained rate
+	MaxExecSessions      int     // Concurrent exec/cp sessions (0 = unlimited)
+	MaxConcurrentCreates int     // Concurrent create requests for instances, images, volumes and builds (0 = unlimited)
+
 	// Resource limits - per instance
 	MaxVcpusPerInstance  int    // Max vCPUs for a single VM (0 = unlimited)
 	MaxMemoryPerInstance string // Max memory for a single VM (0 = unlimited)
@@ -73,18 +110,56 @@ type Config struct {
 	MaxTotalMemory        string // Aggregate memory limit across all instances (0 = unlimited)
 	MaxTotalVolumeStorage string // Total volume storage limit (0 = unlimited)
 
+	// Quotas per tenant or API key, e.g. "tenant:team-a:instances=10,memory=64GB;key:ci:builds_per_hour=20"
+	Quotas string
+
+	// Hypervisor process confinement
+	VMMCgroup         string // cgroup v2 group (under /sys/fs/cgroup) holding a cgroup per hypervisor process (empty = disabled)
+	VMMMemoryOverhead string // Memory a hypervisor process may use beyond its guest's memory
+	VMMSandbox        bool   // Enable seccomp and Landlock for hypervisor processes
+
+	// Shared directories (virtiofs)
+	SharedDirectoryRoots string // Comma-separated host directories instances may share from (empty = disabled)
+	VirtiofsdBinary      string // Path to the virtiofsd binary
+
+	// UEFI guests (e.g. Windows)
+	UEFIFirmwareQEMU            string // OVMF firmware for QEMU (empty = UEFI guests disabled on QEMU)
+	UEFIFirmwareCloudHypervisor string // CLOUDHV firmware for Cloud Hypervisor (empty = disabled)
+	VirtioDriversISO            string // ISO attached as a CD-ROM to UEFI guests on QEMU, e.g. virtio-win (empty = none)
+
+	// Encrypted volumes and secrets
+	MasterKeyFile string // Hex-encoded master key sealing volume keys and secrets (empty = both disabled)
+
 	// OpenTelemetry configuration
 	OtelEnabled           bool   // Enable OpenTelemetry
 	OtelEndpoint          string // OTLP endpoint (gRPC)
 	OtelServiceName       string // Service name for tracing
 	OtelServiceInstanceID string // Service instance ID (default: hostname)
 	OtelInsecure          bool   // Disable TLS for OTLP
+	PrometheusEnabled     bool   // Serve Prometheus metrics on GET /metrics
 	Version               string // Application version for telemetry
 	Env                   string // Deployment environment (e.g., dev, staging, prod)
 
 	// Logging configuration
 	LogLevel string // Default log level (debug, info, warn, error)
 
+	// Console log shipping
+	ConsoleLogShipper       string // Where to ship instance console logs: "otel", "loki" (empty = disabled)
+	ConsoleLogLokiURL       string // Loki base URL (required for "loki")
+	ConsoleLogShipInterval  string // How often console logs are checked for new lines
+	ConsoleLogShipBatchSize int    // Max lines per batch sent to the shipper's backend
+
+	// Node agent (central scheduling)
+	ControlPlaneURL       string // Control plane to register with and heartbeat to (empty = disabled)
+	ControlPlaneToken     string // Bearer token for the control plane
+	NodeID                string // Node ID reported to the control plane (default: hostname)
+	NodeAddress           string // Address the control plane reaches this node's API at
+	NodeHeartbeatInterval string // How often capacity is reported to the control plane
+	NodeSlotTTL           string // Default lifetime of a claimed node slot
+
+	// Diagnostics
+	DebugEndpointsEnabled bool // Serve /debug/pprof and /debug/state (admin only)
+
 	// Caddy / Ingress configuration
 	CaddyListenAddress  string // Address for Caddy to listen on
 	CaddyAdminAddress   string // Address for Caddy admin API
@@ -103,16 +178,86 @@ type Config struct {
 	// Cloudflare configuration (if AcmeDnsProvider=cloudflare)
 	CloudflareApiToken string // Cloudflare API token
 
+	// External DNS: publish ingress hostnames to a public DNS provider
+	ExternalDnsProvider string // DNS provider: "cloudflare" (empty = disabled)
+	ExternalDnsTargets  string // Comma-separated public IPs records point at
+	ExternalDnsTTL      int    // Record TTL in seconds (1 = automatic)
+
 	// Build system configuration
 	MaxConcurrentSourceBuilds int    // Max concurrent source-to-image builds
 	BuilderImage              string // OCI image for builder VMs
 	RegistryURL               string // URL of registry for built images
 	BuildTimeout              int    // Default build timeout in seconds
 	BuildSecretsDir           string // Directory containing build secrets (optional)
+	BuildWarmPoolSize         int    // Builder VMs kept booted ahead of demand (0 = disabled)
+	BuildWarmPoolMaxUses      int    // Builds a warm builder runs before it is destroyed
+	BuildMaxArtifactSize      string // Size limit of artifact build tarballs (e.g. "1GB")
+	BuildPushAttestations     bool   // Push build SBOMs and provenance to the registry as OCI referrers
+	BuildEgressAllowlist      string // Comma-separated hosts, IPs or CIDRs egress builds may reach besides the registry (empty = unrestricted)
+	BuildPackageCacheVolume   string // Volume mounted read-only in offline builds as the "packages" build context (empty = none)
+
+	// Registry pull-through cache
+	RegistryProxyUpstreams string // Comma-separated upstream registries to cache, e.g. "docker.io,ghcr.io" (empty = disabled)
+	RegistryProxyTagTTL    string // How long resolved upstream tags are cached
+
+	// Retention of pushed images, e.g. "*/builds/*:keep=20,untagged=168h" (empty = kept forever)
+	RegistryRetention string
+
+	// containerd image import (images created with source "containerd")
+	ContainerdAddress   string // containerd API socket
+	ContainerdNamespace string // containerd namespace images are read from unless the request names one
+
+	// Base-image update tracking
+	ImageUpdateCheckInterval string // How often image tags are re-resolved upstream ("0" = disabled)
+	ImageAutoUpdate          bool   // Pull and convert an image's new digest when its tag moves
+	ImageUpdateWebhookURL    string // URL POSTed each newly detected image update (optional)
 
 	// Hypervisor configuration
 	DefaultHypervisor string // Default hypervisor type: "cloud-hypervisor" or "qemu"
 
+	// GPU configuration
+	GPUEnableMIG           bool   // Enable MIG mode on MIG-capable GPUs at startup (for MIG-backed vGPU profiles)
+	GPUHealthCheckInterval string // How often GPUs are checked for Xid and ECC errors ("0" = disabled)
+
+	// Guest agent
+	GuestAgentAutoUpdate bool   // Push the bundled guest-agent to running instances on startup
+	BootTimeout          string // Mark an instance Failed if its guest agent isn't up this long after boot ("0" = never)
+	ShutdownGracePeriod  string // Time an application gets to exit on stop or delete before the VM is powered off ("0" = none)
+
+	// Lifecycle hooks: comma-separated executables (absolute paths) or http(s) webhook URLs
+	HookPreCreate      string // Run before an instance is created
+	HookPostBoot       string // Run once an instance's guest agent is up after a boot
+	HookPreStandby     string // Run before an instance is put in standby
+	HookPostRestore    string // Run once an instance is restored from standby
+	HookPreDelete      string // Run before an instance is deleted
+	HookAbortOnFailure string // Comma-separated pre-* events whose hook failures abort the operation
+	HookTimeout        string // Time each hook may take ("0" = no limit)
+
+	// Maintenance schedules: cron expressions in server local time (empty = only when triggered)
+	ScheduleLogRotation string // Instance log rotation (empty = every LOG_ROTATE_INTERVAL)
+	ScheduleMdevCleanup string // Removal of orphaned vGPU mdevs and MIG instances
+	ScheduleTAPCleanup  string // Removal of TAP devices and HTB classes left by vanished instances
+	ScheduleGC          string // Removal of dangling images and orphan build volumes
+	ScheduleDiskTrim    string // Hole punching in the disk files of stopped and standby instances
+	ScheduleRetention   string // Removal of registry images beyond REGISTRY_RETENTION
+	ScheduleClockSync   string // Resync of running instances' guest clocks with the host
+	ScheduleRecordings  string // Removal of exec recordings beyond EXEC_RECORDING_MAX_*
+
+	// Idle standby (instances may override per instance)
+	IdleStandbyAfter  string // Put instances into standby after this long without activity ("0" = never)
+	IdleWakeOnIngress bool   // Restore standby instances when an ingress connection arrives
+	IdleCheckInterval string // How often instances are checked for idleness
+
+	// Instance schedules
+	ScheduleCheckInterval string // How often instance schedules are checked for runs that are due
+
+	// Instance groups
+	GroupReconcileInterval string // How often instance groups are reconciled
+
+	// Usage metering
+	MeteringInterval string // How often instance usage is sampled into hourly rollups ("0" = disabled)
+	UsageRetention   string // How long hourly usage rollups are kept ("0" = forever)
+
 	// Oversubscription ratios (1.0 = no oversubscription, 2.0 = 2x oversubscription)
 	OversubCPU     float64 // CPU oversubscription ratio
 	OversubMemory  float64 // Memory oversubscription ratio
@@ -135,23 +280,62 @@ type Config struct {
 // Automatically loads .env file if present
 func Load() *Config {
 	// Try to load .env file (fail silently if not present)
-	_ = godotenv.Load()
+	loadEnvFile()
 
+	return fromEnv()
+}
+
+// fromEnv builds the configuration from the environment
+func fromEnv() *Config {
 	cfg := &Config{
 		Port:                getEnv("PORT", "8080"),
+		GRPCPort:            getEnv("GRPC_PORT", ""),
+		MetadataPort:        getEnv("METADATA_PORT", ""),
+		UnprefixedAPISunset: getEnv("UNPREFIXED_API_SUNSET", ""),
 		DataDir:             getEnv("DATA_DIR", "/var/lib/hypeman"),
 		BridgeName:          getEnv("BRIDGE_NAME", "vmbr0"),
 		SubnetCIDR:          getEnv("SUBNET_CIDR", "10.100.0.0/16"),
 		SubnetGateway:       getEnv("SUBNET_GATEWAY", ""),   // empty = derived as first IP from subnet
 		UplinkInterface:     getEnv("UPLINK_INTERFACE", ""), // empty = auto-detect from default route
+		UplinkMode:          getEnv("UPLINK_MODE", "nat"),
+		UplinkVLAN:          getEnvInt("UPLINK_VLAN", 0),
+		OverlayListenPort:   getEnvInt("OVERLAY_LISTEN_PORT", 0),
+		OverlayEndpoint:     getEnv("OVERLAY_ENDPOINT", ""),
+		OverlayInterface:    getEnv("OVERLAY_INTERFACE", "hypeman-wg"),
 		JwtSecret:           getEnv("JWT_SECRET", ""),
+		JwksURL:             getEnv("JWKS_URL", ""),
+		JwtIssuer:           getEnv("JWT_ISSUER", ""),
+		JwtAudience:         getEnv("JWT_AUDIENCE", ""),
+		ApiKeys:             getEnv("API_KEYS", ""),
+		AuthDefaultRole:     getEnv("AUTH_DEFAULT_ROLE", "admin"),
 		DNSServer:           getEnv("DNS_SERVER", "1.1.1.1"),
+		DnsmasqPIDFile:      getEnv("DNSMASQ_PID_FILE", ""),
+		DnsRecordTTL:        getEnv("DNS_RECORD_TTL", "5s"),
 		MaxConcurrentBuilds: getEnvInt("MAX_CONCURRENT_BUILDS", 1),
 		MaxOverlaySize:      getEnv("MAX_OVERLAY_SIZE", "100GB"),
 		LogMaxSize:          getEnv("LOG_MAX_SIZE", "50MB"),
 		LogMaxFiles:         getEnvInt("LOG_MAX_FILES", 1),
+		LogMaxAge:           getEnv("LOG_MAX_AGE", "0"),
+		LogMaxTotalSize:     getEnv("LOG_MAX_TOTAL_SIZE", "0"),
 		LogRotateInterval:   getEnv("LOG_ROTATE_INTERVAL", "5m"),
 
+		// Exec session recording
+		ExecRecording:             getEnvBool("EXEC_RECORDING", false),
+		ExecRecordingMaxAge:       getEnv("EXEC_RECORDING_MAX_AGE", "0"),
+		ExecRecordingMaxTotalSize: getEnv("EXEC_RECORDING_MAX_TOTAL_SIZE", "0"),
+
+		// API server TLS
+		TLSCertFile:     getEnv("TLS_CERT_FILE", ""),
+		TLSKeyFile:      getEnv("TLS_KEY_FILE", ""),
+		TLSClientCAFile: getEnv("TLS_CLIENT_CA_FILE", ""),
+		TLSClientAuth:   getEnv("TLS_CLIENT_AUTH", "require"),
+
+		// API rate limiting - per client (0 = unlimited)
+		RateLimitRPS:         getEnvFloat("RATE_LIMIT_RPS", 0),
+		RateLimitBurst:       getEnvInt("RATE_LIMIT_BURST", 20),
+		MaxExecSessions:      getEnvInt("MAX_EXEC_SESSIONS", 0),
+		MaxConcurrentCreates: getEnvInt("MAX_CONCURRENT_CREATES", 0),
+
 		// Resource limits - per instance (0 = unlimited)
 		MaxVcpusPerInstance:  getEnvInt("MAX_VCPUS_PER_INSTANCE", 16),
 		MaxMemoryPerInstance: getEnv("MAX_MEMORY_PER_INSTANCE", "32GB"),
@@ -161,18 +345,56 @@ func Load() *Config {
 		MaxTotalMemory:        getEnv("MAX_TOTAL_MEMORY", ""),
 		MaxTotalVolumeStorage: getEnv("MAX_TOTAL_VOLUME_STORAGE", ""),
 
+		// Quotas per tenant or API key (empty = none)
+		Quotas: getEnv("QUOTAS", ""),
+
+		// Hypervisor process confinement
+		VMMCgroup:         getEnv("VMM_CGROUP", ""),
+		VMMMemoryOverhead: getEnv("VMM_MEMORY_OVERHEAD", "256MB"),
+		VMMSandbox:        getEnvBool("VMM_SANDBOX", false),
+
+		// Shared directories (virtiofs)
+		SharedDirectoryRoots: getEnv("SHARED_DIRECTORY_ROOTS", ""),
+		VirtiofsdBinary:      getEnv("VIRTIOFSD_BINARY", "/usr/libexec/virtiofsd"),
+
+		// UEFI guests
+		UEFIFirmwareQEMU:            getEnv("UEFI_FIRMWARE_QEMU", "/usr/share/ovmf/OVMF.fd"),
+		UEFIFirmwareCloudHypervisor: getEnv("UEFI_FIRMWARE_CLOUD_HYPERVISOR", ""),
+		VirtioDriversISO:            getEnv("VIRTIO_DRIVERS_ISO", ""),
+
+		// Encrypted volumes and secrets
+		MasterKeyFile: getEnv("MASTER_KEY_FILE", ""),
+
 		// OpenTelemetry configuration
 		OtelEnabled:           getEnvBool("OTEL_ENABLED", false),
 		OtelEndpoint:          getEnv("OTEL_ENDPOINT", "127.0.0.1:4317"),
 		OtelServiceName:       getEnv("OTEL_SERVICE_NAME", "hypeman"),
 		OtelServiceInstanceID: getEnv("OTEL_SERVICE_INSTANCE_ID", getHostname()),
 		OtelInsecure:          getEnvBool("OTEL_INSECURE", true),
+		PrometheusEnabled:     getEnvBool("PROMETHEUS_ENABLED", false),
 		Version:               getEnv("VERSION", getBuildVersion()),
 		Env:                   getEnv("ENV", "unset"),
 
 		// Logging configuration
 		LogLevel: getEnv("LOG_LEVEL", "info"),
 
+		// Console log shipping
+		ConsoleLogShipper:       getEnv("CONSOLE_LOG_SHIPPER", ""),
+		ConsoleLogLokiURL:       getEnv("CONSOLE_LOG_LOKI_URL", ""),
+		ConsoleLogShipInterval:  getEnv("CONSOLE_LOG_SHIP_INTERVAL", "1s"),
+		ConsoleLogShipBatchSize: getEnvInt("CONSOLE_LOG_SHIP_BATCH_SIZE", 1000),
+
+		// Node agent (central scheduling)
+		ControlPlaneURL:       getEnv("CONTROL_PLANE_URL", ""),
+		ControlPlaneToken:     getEnv("CONTROL_PLANE_TOKEN", ""),
+		NodeID:                getEnv("NODE_ID", getHostname()),
+		NodeAddress:           getEnv("NODE_ADDRESS", ""),
+		NodeHeartbeatInterval: getEnv("NODE_HEARTBEAT_INTERVAL", "10s"),
+		NodeSlotTTL:           getEnv("NODE_SLOT_TTL", "5m"),
+
+		// Diagnostics
+		DebugEndpointsEnabled: getEnvBool("DEBUG_ENDPOINTS_ENABLED", false),
+
 		// Caddy / Ingress configuration
 		CaddyListenAddress: getEnv("CADDY_LISTEN_ADDRESS", "0.0.0.0"),
 		CaddyAdminAddress:  getEnv("CADDY_ADMIN_ADDRESS", "127.0.0.1"),
@@ -192,16 +414,86 @@ func Load() *Config {
 		// Cloudflare configuration
 		CloudflareApiToken: getEnv("CLOUDFLARE_API_TOKEN", ""),
 
+		// External DNS
+		ExternalDnsProvider: getEnv("EXTERNAL_DNS_PROVIDER", ""),
+		ExternalDnsTargets:  getEnv("EXTERNAL_DNS_TARGETS", ""),
+		ExternalDnsTTL:      getEnvInt("EXTERNAL_DNS_TTL", 300),
+
 		// Build system configuration
 		MaxConcurrentSourceBuilds: getEnvInt("MAX_CONCURRENT_SOURCE_BUILDS", 2),
 		BuilderImage:              getEnv("BUILDER_IMAGE", "hypeman/builder:latest"),
 		RegistryURL:               getEnv("REGISTRY_URL", "localhost:8080"),
 		BuildTimeout:              getEnvInt("BUILD_TIMEOUT", 600),
 		BuildSecretsDir:           getEnv("BUILD_SECRETS_DIR", ""), // Optional: path to directory with build secrets
+		BuildWarmPoolSize:         getEnvInt("BUILD_WARM_POOL_SIZE", 0),
+		BuildWarmPoolMaxUses:      getEnvInt("BUILD_WARM_POOL_MAX_USES", 1),
+		BuildMaxArtifactSize:      getEnv("BUILD_MAX_ARTIFACT_SIZE", "1GB"),
+		BuildPushAttestations:     getEnvBool("BUILD_PUSH_ATTESTATIONS", false),
+		BuildEgressAllowlist:      getEnv("BUILD_EGRESS_ALLOWLIST", ""),
+		BuildPackageCacheVolume:   getEnv("BUILD_PACKAGE_CACHE_VOLUME", ""),
+
+		// Registry pull-through cache
+		RegistryProxyUpstreams: getEnv("REGISTRY_PROXY_UPSTREAMS", ""),
+		RegistryProxyTagTTL:    getEnv("REGISTRY_PROXY_TAG_TTL", "10m"),
+
+		// Retention of pushed images
+		RegistryRetention: getEnv("REGISTRY_RETENTION", ""),
+
+		// containerd image import
+		ContainerdAddress:   getEnv("CONTAINERD_ADDRESS", "/run/containerd/containerd.sock"),
+		ContainerdNamespace: getEnv("CONTAINERD_NAMESPACE", "default"),
+
+		// Base-image update tracking
+		ImageUpdateCheckInterval: getEnv("IMAGE_UPDATE_CHECK_INTERVAL", "0"),
+		ImageAutoUpdate:          getEnvBool("IMAGE_AUTO_UPDATE", false),
+		ImageUpdateWebhookURL:    getEnv("IMAGE_UPDATE_WEBHOOK_URL", ""),
 
 		// Hypervisor configuration
 		DefaultHypervisor: getEnv("DEFAULT_HYPERVISOR", "cloud-hypervisor"),
 
+		// GPU configuration
+		GPUEnableMIG:           getEnvBool("GPU_ENABLE_MIG", false),
+		GPUHealthCheckInterval: getEnv("GPU_HEALTH_CHECK_INTERVAL", "1m"),
+
+		// Guest agent
+		GuestAgentAutoUpdate: getEnvBool("GUEST_AGENT_AUTO_UPDATE", false),
+		BootTimeout:          getEnv("BOOT_TIMEOUT", "5m"),
+		ShutdownGracePeriod:  getEnv("SHUTDOWN_GRACE_PERIOD", "10s"),
+
+		// Lifecycle hooks
+		HookPreCreate:      getEnv("HOOK_PRE_CREATE", ""),
+		HookPostBoot:       getEnv("HOOK_POST_BOOT", ""),
+		HookPreStandby:     getEnv("HOOK_PRE_STANDBY", ""),
+		HookPostRestore:    getEnv("HOOK_POST_RESTORE", ""),
+		HookPreDelete:      getEnv("HOOK_PRE_DELETE", ""),
+		HookAbortOnFailure: getEnv("HOOK_ABORT_ON_FAILURE", ""),
+		HookTimeout:        getEnv("HOOK_TIMEOUT", "30s"),
+
+		// Maintenance schedules
+		ScheduleLogRotation: getEnv("SCHEDULE_LOG_ROTATION", ""),
+		ScheduleMdevCleanup: getEnv("SCHEDULE_MDEV_CLEANUP", ""),
+		ScheduleTAPCleanup:  getEnv("SCHEDULE_TAP_CLEANUP", ""),
+		ScheduleGC:          getEnv("SCHEDULE_GC", ""),
+		ScheduleDiskTrim:    getEnv("SCHEDULE_DISK_TRIM", ""),
+		ScheduleRetention:   getEnv("SCHEDULE_REGISTRY_RETENTION", "@hourly"),
+		ScheduleClockSync:   getEnv("SCHEDULE_CLOCK_SYNC", "@every 15m"),
+		ScheduleRecordings:  getEnv("SCHEDULE_RECORDING_RETENTION", "@hourly"),
+
+		// Idle standby
+		IdleStandbyAfter:  getEnv("IDLE_STANDBY_AFTER", "0"),
+		IdleWakeOnIngress: getEnvBool("IDLE_WAKE_ON_INGRESS", false),
+		IdleCheckInterval: getEnv("IDLE_CHECK_INTERVAL", "1m"),
+
+		// Instance schedules
+		ScheduleCheckInterval: getEnv("SCHEDULE_CHECK_INTERVAL", "30s"),
+
+		// Instance groups
+		GroupReconcileInterval: getEnv("GROUP_RECONCILE_INTERVAL", "15s"),
+
+		// Usage metering
+		MeteringInterval: getEnv("METERING_INTERVAL", "1m"),
+		UsageRetention:   getEnv("USAGE_RETENTION", "2160h"),
+
 		// Oversubscription ratios (1.0 = no oversubscription)
 		OversubCPU:     getEnvFloat("OVERSUB_CPU", 4.0),
 		OversubMemory:  getEnvFloat("OVERSUB_MEMORY", 1.0),
@@ -260,6 +552,12 @@ func getEnvFloat(key string, defaultValue float64) float64 {
 // Validate checks configuration values for correctness.
 // Returns an error if any configuration value is invalid.
 func (c *Config) Validate() error {
+	if c.UnprefixedAPISunset != "" {
+		if _, err := time.Parse(time.DateOnly, c.UnprefixedAPISunset); err != nil {
+			return fmt.Errorf("UNPREFIXED_API_SUNSET must be a date (YYYY-MM-DD), got %q", c.UnprefixedAPISunset)
+		}
+	}
+
 	// Validate oversubscription ratios are positive
 	if c.OversubCPU <= 0 {
 		return fmt.Errorf("OVERSUB_CPU must be positive, got %v", c.OversubCPU)
@@ -282,5 +580,167 @@ func (c *Config) Validate() error {
 	if c.DownloadBurstMultiplier < 1 {
 		return fmt.Errorf("DOWNLOAD_BURST_MULTIPLIER must be >= 1, got %v", c.DownloadBurstMultiplier)
 	}
+	if c.GRPCPort != "" && c.GRPCPort == c.Port {
+		return fmt.Errorf("GRPC_PORT must differ from PORT, both are %s", c.Port)
+	}
+	if c.MetadataPort != "" {
+		if port, err := strconv.Atoi(c.MetadataPort); err != nil || port < 1 || port > 65535 {
+			return fmt.Errorf("METADATA_PORT must be a port number, got %q", c.MetadataPort)
+		}
+		if c.MetadataPort == c.Port || c.MetadataPort == c.GRPCPort {
+			return fmt.Errorf("METADATA_PORT must differ from PORT and GRPC_PORT, got %s", c.MetadataPort)
+		}
+	}
+	switch c.UplinkMode {
+	case "nat":
+	case "vlan", "macvtap":
+		if c.UplinkInterface == "" {
+			return fmt.Errorf("UPLINK_MODE=%s requires UPLINK_INTERFACE", c.UplinkMode)
+		}
+		if c.UplinkMode == "vlan" && (c.UplinkVLAN < 1 || c.UplinkVLAN > 4094) {
+			return fmt.Errorf("UPLINK_VLAN must be between 1 and 4094 with UPLINK_MODE=vlan, got %d", c.UplinkVLAN)
+		}
+		if c.UplinkVLAN < 0 || c.UplinkVLAN > 4094 {
+			return fmt.Errorf("UPLINK_VLAN must be 0 (untagged) or between 1 and 4094 with UPLINK_MODE=macvtap, got %d", c.UplinkVLAN)
+		}
+		if c.MetadataPort != "" {
+			return fmt.Errorf("METADATA_PORT requires UPLINK_MODE=nat: the metadata service is served from the bridge gateway")
+		}
+	default:
+		return fmt.Errorf("UPLINK_MODE must be nat, vlan or macvtap, got %q", c.UplinkMode)
+	}
+	if c.OverlayListenPort < 0 || c.OverlayListenPort > 65535 {
+		return fmt.Errorf("OVERLAY_LISTEN_PORT must be a port number, got %d", c.OverlayListenPort)
+	}
+	if c.OverlayListenPort > 0 {
+		if c.OverlayInterface == "" || len(c.OverlayInterface) > 15 || strings.HasPrefix(c.OverlayInterface, "hype-") {
+			return fmt.Errorf("OVERLAY_INTERFACE must be an interface name of at most 15 characters not starting with hype-, got %q", c.OverlayInterface)
+		}
+		if c.OverlayEndpoint != "" {
+			if _, _, err := net.SplitHostPort(c.OverlayEndpoint); err != nil {
+				return fmt.Errorf("OVERLAY_ENDPOINT must be host:port, got %q", c.OverlayEndpoint)
+			}
+		}
+		if c.UplinkMode != "nat" {
+			return fmt.Errorf("OVERLAY_LISTEN_PORT requires UPLINK_MODE=nat: guests on a VLAN or macvtap don't route through the host")
+		}
+	}
+	if (c.TLSCertFile == "") != (c.TLSKeyFile == "") {
+		return fmt.Errorf("TLS_CERT_FILE and TLS_KEY_FILE must be set together")
+	}
+	if c.TLSClientCAFile != "" && c.TLSCertFile == "" {
+		return fmt.Errorf("TLS_CLIENT_CA_FILE requires TLS_CERT_FILE and TLS_KEY_FILE")
+	}
+	if c.TLSClientAuth != "require" && c.TLSClientAuth != "optional" {
+		return fmt.Errorf("TLS_CLIENT_AUTH must be require or optional, got %q", c.TLSClientAuth)
+	}
+	if d, err := time.ParseDuration(c.BootTimeout); err != nil || d < 0 {
+		return fmt.Errorf("BOOT_TIMEOUT must be a non-negative duration, got %q", c.BootTimeout)
+	}
+	if d, err := time.ParseDuration(c.ShutdownGracePeriod); err != nil || d < 0 {
+		return fmt.Errorf("SHUTDOWN_GRACE_PERIOD must be a non-negative duration, got %q", c.ShutdownGracePeriod)
+	}
+	if d, err := time.ParseDuration(c.HookTimeout); err != nil || d < 0 {
+		return fmt.Errorf("HOOK_TIMEOUT must be a non-negative duration, got %q", c.HookTimeout)
+	}
+	if d, err := time.ParseDuration(c.DnsRecordTTL); err != nil || d < 0 {
+		return fmt.Errorf("DNS_RECORD_TTL must be a non-negative duration, got %q", c.DnsRecordTTL)
+	}
+	for _, event := range strings.Split(c.HookAbortOnFailure, ",") {
+		switch strings.TrimSpace(event) {
+		case "", "pre-create", "pre-standby", "pre-delete":
+		default:
+			return fmt.Errorf("HOOK_ABORT_ON_FAILURE must list pre-create, pre-standby or pre-delete, got %q", event)
+		}
+	}
+	if d, err := time.ParseDuration(c.IdleStandbyAfter); err != nil || d < 0 {
+		return fmt.Errorf("IDLE_STANDBY_AFTER must be a non-negative duration, got %q", c.IdleStandbyAfter)
+	}
+	if d, err := time.ParseDuration(c.IdleCheckInterval); err != nil || d <= 0 {
+		return fmt.Errorf("IDLE_CHECK_INTERVAL must be a positive duration, got %q", c.IdleCheckInterval)
+	}
+	if d, err := time.ParseDuration(c.ScheduleCheckInterval); err != nil || d <= 0 {
+		return fmt.Errorf("SCHEDULE_CHECK_INTERVAL must be a positive duration, got %q", c.ScheduleCheckInterval)
+	}
+	if d, err := time.ParseDuration(c.GroupReconcileInterval); err != nil || d <= 0 {
+		return fmt.Errorf("GROUP_RECONCILE_INTERVAL must be a positive duration, got %q", c.GroupReconcileInterval)
+	}
+	if d, err := time.ParseDuration(c.MeteringInterval); err != nil || d < 0 {
+		return fmt.Errorf("METERING_INTERVAL must be a non-negative duration, got %q", c.MeteringInterval)
+	}
+	if d, err := time.ParseDuration(c.UsageRetention); err != nil || d < 0 {
+		return fmt.Errorf("USAGE_RETENTION must be a non-negative duration, got %q", c.UsageRetention)
+	}
+	for _, upstream := range strings.Split(c.RegistryProxyUpstreams, ",") {
+		if upstream = strings.TrimSpace(upstream); strings.ContainsAny(upstream, "/ ") {
+			return fmt.Errorf("REGISTRY_PROXY_UPSTREAMS entries must be registry hosts like docker.io, got %q", upstream)
+		}
+	}
+	if d, err := time.ParseDuration(c.RegistryProxyTagTTL); err != nil || d <= 0 {
+		return fmt.Errorf("REGISTRY_PROXY_TAG_TTL must be a positive duration, got %q", c.RegistryProxyTagTTL)
+	}
+	if c.RateLimitRPS < 0 {
+		return fmt.Errorf("RATE_LIMIT_RPS must be >= 0, got %v", c.RateLimitRPS)
+	}
+	if c.RateLimitRPS > 0 && c.RateLimitBurst < 1 {
+		return fmt.Errorf("RATE_LIMIT_BURST must be >= 1 when rate limiting is enabled, got %v", c.RateLimitBurst)
+	}
+	if c.VMMCgroup != "" && !filepath.IsLocal(c.VMMCgroup) {
+		return fmt.Errorf("VMM_CGROUP must be a path relative to /sys/fs/cgroup, got %q", c.VMMCgroup)
+	}
+	for _, root := range strings.Split(c.SharedDirectoryRoots, ",") {
+		if root = strings.TrimSpace(root); root != "" && !filepath.IsAbs(root) {
+			return fmt.Errorf("SHARED_DIRECTORY_ROOTS entries must be absolute paths, got %q", root)
+		}
+	}
+	if c.BuildWarmPoolSize < 0 {
+		return fmt.Errorf("BUILD_WARM_POOL_SIZE must be >= 0, got %v", c.BuildWarmPoolSize)
+	}
+	if c.BuildWarmPoolMaxUses < 1 {
+		return fmt.Errorf("BUILD_WARM_POOL_MAX_USES must be >= 1, got %v", c.BuildWarmPoolMaxUses)
+	}
+	switch c.ConsoleLogShipper {
+	case "":
+	case "otel":
+		if !c.OtelEnabled {
+			return fmt.Errorf("CONSOLE_LOG_SHIPPER=otel requires OTEL_ENABLED")
+		}
+	case "loki":
+		if c.ConsoleLogLokiURL == "" {
+			return fmt.Errorf("CONSOLE_LOG_SHIPPER=loki requires CONSOLE_LOG_LOKI_URL")
+		}
+	default:
+		return fmt.Errorf("CONSOLE_LOG_SHIPPER must be otel or loki, got %q", c.ConsoleLogShipper)
+	}
+	if c.ConsoleLogShipper != "" {
+		if d, err := time.ParseDuration(c.ConsoleLogShipInterval); err != nil || d <= 0 {
+			return fmt.Errorf("CONSOLE_LOG_SHIP_INTERVAL must be a positive duration, got %q", c.ConsoleLogShipInterval)
+		}
+		if c.ConsoleLogShipBatchSize < 1 {
+			return fmt.Errorf("CONSOLE_LOG_SHIP_BATCH_SIZE must be >= 1, got %v", c.ConsoleLogShipBatchSize)
+		}
+	}
+	if c.ControlPlaneURL != "" {
+		if u, err := url.Parse(c.ControlPlaneURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("CONTROL_PLANE_URL must be an http(s) URL, got %q", c.ControlPlaneURL)
+		}
+		if d, err := time.ParseDuration(c.NodeHeartbeatInterval); err != nil || d <= 0 {
+			return fmt.Errorf("NODE_HEARTBEAT_INTERVAL must be a positive duration, got %q", c.NodeHeartbeatInterval)
+		}
+	}
+	if d, err := time.ParseDuration(c.NodeSlotTTL); err != nil || d <= 0 {
+		return fmt.Errorf("NODE_SLOT_TTL must be a positive duration, got %q", c.NodeSlotTTL)
+	}
+	if d, err := time.ParseDuration(c.ImageUpdateCheckInterval); err != nil || d < 0 {
+		return fmt.Errorf("IMAGE_UPDATE_CHECK_INTERVAL must be a non-negative duration, got %q", c.ImageUpdateCheckInterval)
+	}
+	if c.ImageUpdateWebhookURL != "" {
+		if u, err := url.Parse(c.ImageUpdateWebhookURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("IMAGE_UPDATE_WEBHOOK_URL must be an http(s) URL, got %q", c.ImageUpdateWebhookURL)
+		}
+	}
+	if d, err := time.ParseDuration(c.GPUHealthCheckInterval); err != nil || d < 0 {
+		return fmt.Errorf("GPU_HEALTH_CHECK_INTERVAL must be a non-negative duration, got %q", c.GPUHealthCheckInterval)
+	}
 	return nil
 }
@@ -0,0 +1,150 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/joho/godotenv"
+)
+
+// envFile is the optional dotenv file Load and Reload read, relative to the
+// working directory
+const envFile = ".env"
+
+var (
+	envMu sync.Mutex
+	// fromEnvFile holds the variables set from envFile, so Reload can tell
+	// them from variables of the process environment, which take precedence
+	fromEnvFile = map[string]string{}
+)
+
+// reloadable lists the settings Reload can apply to a running server.
+// LOG_LEVEL_<SUBSYSTEM> is matched by prefix.
+var reloadable = []string{
+	// Instance resource limits
+	"MAX_OVERLAY_SIZE",
+	"MAX_VCPUS_PER_INSTANCE",
+	"MAX_MEMORY_PER_INSTANCE",
+	"MAX_TOTAL_VCPUS",
+	"MAX_TOTAL_MEMORY",
+
+	// Quotas
+	"QUOTAS",
+
+	// Log levels
+	"LOG_LEVEL",
+
+	// API rate limits
+	"RATE_LIMIT_RPS",
+	"RATE_LIMIT_BURST",
+
+	// ACME credentials
+	"ACME_EMAIL",
+	"ACME_DNS_PROVIDER",
+	"ACME_CA",
+	"DNS_PROPAGATION_TIMEOUT",
+	"DNS_RESOLVERS",
+	"TLS_ALLOWED_DOMAINS",
+	"CLOUDFLARE_API_TOKEN",
+
+	// Builds
+	"BUILDER_IMAGE",
+}
+
+// IsReloadable reports whether a setting can be changed without restarting
+// the server
+func IsReloadable(key string) bool {
+	return slices.Contains(reloadable, key) || strings.HasPrefix(key, "LOG_LEVEL_")
+}
+
+// loadEnvFile sets the variables of envFile that the process environment
+// doesn't already set
+func loadEnvFile() {
+	values, err := godotenv.Read(envFile)
+	if err != nil {
+		return
+	}
+
+	envMu.Lock()
+	defer envMu.Unlock()
+	for key, value := range values {
+		if _, set := os.LookupEnv(key); set {
+			continue
+		}
+		os.Setenv(key, value)
+		fromEnvFile[key] = value
+	}
+}
+
+// Reload reads the .env file again and returns the configuration it now
+// yields, with the names of the variables that changed, sorted. Variables
+// set in the process environment keep precedence over the file, so only
+// file changes take effect.
+//
+// The new configuration is validated, then passed to check, if not nil, so
+// callers can parse it further before anything is applied. If either fails,
+// the environment is restored and the error returned.
+func Reload(check func(*Config) error) (*Config, []string, error) {
+	values, err := godotenv.Read(envFile)
+	if errors.Is(err, fs.ErrNotExist) {
+		values = map[string]string{}
+	} else if err != nil {
+		return nil, nil, fmt.Errorf("read %s: %w", envFile, err)
+	}
+
+	envMu.Lock()
+	defer envMu.Unlock()
+
+	// Remember what the environment held, for rolling back
+	previous := make(map[string]string, len(fromEnvFile))
+	for key, value := range fromEnvFile {
+		previous[key] = value
+	}
+	var changed []string
+
+	for key := range fromEnvFile {
+		if _, ok := values[key]; !ok {
+			os.Unsetenv(key)
+			delete(fromEnvFile, key)
+			changed = append(changed, key)
+		}
+	}
+	for key, value := range values {
+		old, fromFile := fromEnvFile[key]
+		if !fromFile {
+			if _, set := os.LookupEnv(key); set {
+				continue // the process environment wins
+			}
+		} else if old == value {
+			continue
+		}
+		os.Setenv(key, value)
+		fromEnvFile[key] = value
+		changed = append(changed, key)
+	}
+	slices.Sort(changed)
+
+	cfg := fromEnv()
+	err = cfg.Validate()
+	if err == nil && check != nil {
+		err = check(cfg)
+	}
+	if err != nil {
+		for _, key := range changed {
+			if value, ok := previous[key]; ok {
+				os.Setenv(key, value)
+				fromEnvFile[key] = value
+			} else {
+				os.Unsetenv(key)
+				delete(fromEnvFile, key)
+			}
+		}
+		return nil, nil, err
+	}
+	return cfg, changed, nil
+}
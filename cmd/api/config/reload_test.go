@@ -0,0 +1,49 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReload(t *testing.T) {
+	t.Chdir(t.TempDir())
+	t.Setenv("MAX_VCPUS_PER_INSTANCE", "") // registers cleanup of the variables the file sets
+	t.Setenv("RATE_LIMIT_RPS", "")
+	t.Setenv("PORT", "")
+	for _, key := range []string{"MAX_VCPUS_PER_INSTANCE", "RATE_LIMIT_RPS", "PORT"} {
+		os.Unsetenv(key)
+	}
+	t.Setenv("LOG_LEVEL", "warn") // set by the process environment, so the file can't override it
+
+	writeEnv := func(content string) {
+		require.NoError(t, os.WriteFile(filepath.Join(".", envFile), []byte(content), 0644))
+	}
+	writeEnv("MAX_VCPUS_PER_INSTANCE=4\nRATE_LIMIT_RPS=10\nLOG_LEVEL=debug\n")
+	cfg := Load()
+	assert.Equal(t, 4, cfg.MaxVcpusPerInstance)
+	assert.Equal(t, "warn", os.Getenv("LOG_LEVEL"))
+
+	writeEnv("MAX_VCPUS_PER_INSTANCE=8\nLOG_LEVEL=error\nPORT=9090\n")
+	cfg, changed, err := Reload(nil)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"MAX_VCPUS_PER_INSTANCE", "PORT", "RATE_LIMIT_RPS"}, changed)
+	assert.Equal(t, 8, cfg.MaxVcpusPerInstance)
+	assert.Equal(t, float64(0), cfg.RateLimitRPS, "removed from the file")
+	assert.Equal(t, "9090", cfg.Port)
+	assert.Equal(t, "warn", os.Getenv("LOG_LEVEL"))
+
+	// A failed check rolls the environment back
+	writeEnv("MAX_VCPUS_PER_INSTANCE=16\nPORT=9090\n")
+	_, _, err = Reload(func(*Config) error { return errors.New("rejected") })
+	require.Error(t, err)
+	assert.Equal(t, "8", os.Getenv("MAX_VCPUS_PER_INSTANCE"))
+
+	assert.True(t, IsReloadable("MAX_VCPUS_PER_INSTANCE"))
+	assert.True(t, IsReloadable("LOG_LEVEL_NETWORK"))
+	assert.False(t, IsReloadable("PORT"))
+}
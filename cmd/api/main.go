@@ -6,14 +6,15 @@ import (
 	"errors"
 	"fmt"
 	"log/slog"
+	"net"
 	"net/http"
+	"net/http/pprof"
 	"os"
 	"os/signal"
 	"strings"
 	"syscall"
 	"time"
 
-	"github.com/c2h5oh/datasize"
 	"github.com/getkin/kin-openapi/openapi3filter"
 	"github.com/ghodss/yaml"
 	"github.com/go-chi/chi/v5"
@@ -25,13 +26,25 @@ import (
 	"github.com/kernel/hypeman/lib/devices"
 	"github.com/kernel/hypeman/lib/guest"
 	"github.com/kernel/hypeman/lib/hypervisor/qemu"
+	"github.com/kernel/hypeman/lib/images"
 	"github.com/kernel/hypeman/lib/instances"
+	"github.com/kernel/hypeman/lib/logship"
+	"github.com/kernel/hypeman/lib/metadata"
 	mw "github.com/kernel/hypeman/lib/middleware"
+	"github.com/kernel/hypeman/lib/mgmt"
+	"github.com/kernel/hypeman/lib/network"
 	"github.com/kernel/hypeman/lib/oapi"
 	"github.com/kernel/hypeman/lib/otel"
+	"github.com/kernel/hypeman/lib/paths"
+	"github.com/kernel/hypeman/lib/problem"
+	"github.com/kernel/hypeman/lib/providers"
+	"github.com/kernel/hypeman/lib/scheduler"
 	"github.com/kernel/hypeman/lib/vmm"
 	"github.com/riandyrn/otelchi"
+	"go.opentelemetry.io/otel/metric"
 	"golang.org/x/sync/errgroup"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
 )
 
 func main() {
@@ -58,6 +71,7 @@ func run() error {
 		ServiceName:       cfg.OtelServiceName,
 		ServiceInstanceID: cfg.OtelServiceInstanceID,
 		Insecure:          cfg.OtelInsecure,
+		Prometheus:        cfg.PrometheusEnabled,
 		Version:           cfg.Version,
 		Env:               cfg.Env,
 	}
@@ -79,7 +93,7 @@ func run() error {
 		}()
 	}
 
-	// Initialize guest and vmm metrics if OTel is enabled
+	// Initialize guest and vmm metrics if OTel or Prometheus is enabled
 	if otelProvider != nil && otelProvider.Meter != nil {
 		guestMetrics, err := guest.NewMetrics(otelProvider.Meter)
 		if err == nil {
@@ -120,11 +134,31 @@ func run() error {
 	if cfg.OtelEnabled {
 		logger.Info("OpenTelemetry enabled", "endpoint", cfg.OtelEndpoint, "service", cfg.OtelServiceName)
 	}
+	if otelProvider != nil && otelProvider.MetricsHandler != nil {
+		logger.Info("Prometheus metrics enabled", "path", "/metrics")
+	}
 
-	// Validate JWT secret is configured
-	if app.Config.JwtSecret == "" {
-		logger.Warn("JWT_SECRET not configured - API authentication will fail")
+	// Configure API authentication (static API keys, HMAC JWTs, JWKS-verified JWTs)
+	apiKeys, err := mw.ParseAPIKeys(app.Config.ApiKeys)
+	if err != nil {
+		return fmt.Errorf("invalid API_KEYS: %w", err)
+	}
+	defaultRole, err := mw.ParseRole(app.Config.AuthDefaultRole)
+	if err != nil {
+		return fmt.Errorf("invalid AUTH_DEFAULT_ROLE: %w", err)
 	}
+	if app.Config.JwtSecret == "" && app.Config.JwksURL == "" && len(apiKeys) == 0 && app.Config.TLSClientCAFile == "" {
+		logger.Warn("no JWT_SECRET, JWKS_URL, API_KEYS or TLS_CLIENT_CA_FILE configured - API authentication will fail")
+	}
+	authenticator := mw.NewAuthenticator(mw.AuthConfig{
+		JwtSecret:      app.Config.JwtSecret,
+		JwksURL:        app.Config.JwksURL,
+		Issuer:         app.Config.JwtIssuer,
+		Audience:       app.Config.JwtAudience,
+		APIKeys:        apiKeys,
+		DefaultRole:    defaultRole,
+		ClientCertAuth: app.Config.TLSClientCAFile != "", // verified against the CA during the TLS handshake
+	})
 
 	// Verify KVM access (required for VM creation)
 	if err := checkKVMAccess(); err != nil {
@@ -138,14 +172,61 @@ func run() error {
 	}
 
 	// Validate log rotation config
-	var logMaxSize datasize.ByteSize
-	if err := logMaxSize.UnmarshalText([]byte(app.Config.LogMaxSize)); err != nil {
-		return fmt.Errorf("invalid LOG_MAX_SIZE %q: %w", app.Config.LogMaxSize, err)
-	}
-	logRotateInterval, err := time.ParseDuration(app.Config.LogRotateInterval)
+	logRetention, err := providers.ParseLogRetention(app.Config)
 	if err != nil {
+		return err
+	}
+	if _, err := time.ParseDuration(app.Config.LogRotateInterval); err != nil {
 		return fmt.Errorf("invalid LOG_ROTATE_INTERVAL %q: %w", app.Config.LogRotateInterval, err)
 	}
+	logRotationSchedule := app.Config.ScheduleLogRotation
+	if logRotationSchedule == "" {
+		logRotationSchedule = "@every " + app.Config.LogRotateInterval
+	}
+
+	// Validate idle standby config
+	idleDefaults, err := providers.ParseIdleDefaults(app.Config)
+	if err != nil {
+		return err
+	}
+	idleCheckInterval, err := time.ParseDuration(app.Config.IdleCheckInterval)
+	if err != nil {
+		return fmt.Errorf("invalid IDLE_CHECK_INTERVAL %q: %w", app.Config.IdleCheckInterval, err)
+	}
+
+	scheduleCheckInterval, err := time.ParseDuration(app.Config.ScheduleCheckInterval)
+	if err != nil {
+		return fmt.Errorf("invalid SCHEDULE_CHECK_INTERVAL %q: %w", app.Config.ScheduleCheckInterval, err)
+	}
+
+	groupReconcileInterval, err := time.ParseDuration(app.Config.GroupReconcileInterval)
+	if err != nil {
+		return fmt.Errorf("invalid GROUP_RECONCILE_INTERVAL %q: %w", app.Config.GroupReconcileInterval, err)
+	}
+
+	imageUpdateCheckInterval, err := time.ParseDuration(app.Config.ImageUpdateCheckInterval)
+	if err != nil {
+		return fmt.Errorf("invalid IMAGE_UPDATE_CHECK_INTERVAL %q: %w", app.Config.ImageUpdateCheckInterval, err)
+	}
+
+	gpuHealthCheckInterval, err := time.ParseDuration(app.Config.GPUHealthCheckInterval)
+	if err != nil {
+		return fmt.Errorf("invalid GPU_HEALTH_CHECK_INTERVAL %q: %w", app.Config.GPUHealthCheckInterval, err)
+	}
+
+	meteringInterval, err := time.ParseDuration(app.Config.MeteringInterval)
+	if err != nil {
+		return fmt.Errorf("invalid METERING_INTERVAL %q: %w", app.Config.MeteringInterval, err)
+	}
+
+	// Set up console log shipping
+	var logShipper *logship.Shipper
+	if app.Config.ConsoleLogShipper != "" {
+		logShipper, err = newLogShipper(app, otelProvider)
+		if err != nil {
+			return fmt.Errorf("create console log shipper: %w", err)
+		}
+	}
 
 	// Ensure system files (kernel, initrd) exist before starting server
 	logger.Info("Ensuring system files...")
@@ -157,6 +238,23 @@ func run() error {
 	logger.Info("System files ready",
 		"kernel", kernelVer)
 
+	// Reconcile instance state with the host (orphaned VMM processes, vanished VMMs)
+	// before anything consults instance state, so TAP and device cleanup below see
+	// instances whose VMM died as stopped. The summary is logged once those are done.
+	logger.Info("Reconciling instance state...")
+	reconcileSummary, err := app.InstanceManager.ReconcileInstances(app.Ctx)
+	if err != nil {
+		// Best-effort: continue with whatever state is on disk
+		logger.Warn("failed to reconcile instance state", "error", err)
+		reconcileSummary = &instances.ReconcileSummary{}
+	}
+
+	// Bring guest agents of running instances up to date in the background;
+	// instances are independent and each update is verified by checksum.
+	if cfg.GuestAgentAutoUpdate {
+		go updateGuestAgents(app.Ctx, app.InstanceManager, logger)
+	}
+
 	// Initialize network manager (creates default network if needed)
 	// Get instance IDs that might have a running VMM for TAP cleanup safety.
 	// Include Unknown state: we couldn't confirm their state, but they might still
@@ -169,13 +267,7 @@ func run() error {
 		logger.Warn("failed to list instances for TAP cleanup, skipping cleanup", "error", err)
 		preserveTAPs = nil
 	} else {
-		// Initialize to empty slice (not nil) so cleanup runs even with no running VMs
-		preserveTAPs = []string{}
-		for _, inst := range allInstances {
-			if inst.State == instances.StateRunning || inst.State == instances.StateUnknown {
-				preserveTAPs = append(preserveTAPs, inst.Id)
-			}
-		}
+		preserveTAPs = runningInstanceIDs(allInstances)
 	}
 	logger.Info("Initializing network manager...")
 	if err := app.NetworkManager.Initialize(app.Ctx, preserveTAPs); err != nil {
@@ -183,12 +275,22 @@ func run() error {
 		return fmt.Errorf("initialize network manager: %w", err)
 	}
 
+	// Bring up the cross-host overlay (no-op unless OVERLAY_LISTEN_PORT is set)
+	if err := app.OverlayManager.Initialize(app.Ctx); err != nil {
+		logger.Error("failed to initialize overlay", "error", err)
+		return fmt.Errorf("initialize overlay: %w", err)
+	}
+
 	// Set up HTB qdisc on bridge for network fair sharing
 	networkCapacity := app.ResourceManager.NetworkCapacity()
 	if err := app.NetworkManager.SetupHTB(app.Ctx, networkCapacity); err != nil {
 		logger.Warn("failed to setup HTB on bridge (network rate limiting disabled)", "error", err)
 	}
 
+	// Remove TAP devices (and their HTB classes) left by instances that no
+	// longer run. Nothing is being created yet, so orphans need no grace.
+	reconcileSummary.TAPDevices, _ = app.NetworkManager.CleanupOrphans(app.Ctx, preserveTAPs, 0)
+
 	// Reconcile device state (clears orphaned attachments from crashed VMs)
 	// Set up liveness checker so device reconciliation can accurately detect orphaned attachments
 	logger.Info("Reconciling device state...")
@@ -201,24 +303,34 @@ func run() error {
 		return fmt.Errorf("reconcile device state: %w", err)
 	}
 
-	// Reconcile mdev devices (clears orphaned vGPUs from crashed VMs)
-	// Build mdev info from instances - only destroys mdevs tracked by hypeman
-	logger.Info("Reconciling mdev devices...")
-	var mdevInfos []devices.MdevReconcileInfo
-	if allInstances != nil {
-		for _, inst := range allInstances {
-			if inst.GPUMdevUUID != "" {
-				mdevInfos = append(mdevInfos, devices.MdevReconcileInfo{
-					InstanceID: inst.Id,
-					MdevUUID:   inst.GPUMdevUUID,
-					IsRunning:  inst.State == instances.StateRunning || inst.State == instances.StateUnknown,
-				})
-			}
+	// Enable MIG mode before vGPU profiles are discovered
+	if app.Config.GPUEnableMIG {
+		if err := devices.EnableMIGMode(app.Ctx); err != nil {
+			// Log but don't fail - time-sliced vGPU profiles still work
+			logger.Warn("failed to enable MIG mode", "error", err)
 		}
 	}
-	if err := devices.ReconcileMdevs(app.Ctx, mdevInfos); err != nil {
+
+	// Reconcile mdev devices (clears orphaned vGPUs from crashed VMs)
+	logger.Info("Reconciling mdev devices...")
+	_, reconcileSummary.Mdevs, err = reconcileGPUs(app.Ctx, allInstances)
+	if err != nil {
 		// Log but don't fail - mdev cleanup is best-effort
-		logger.Warn("failed to reconcile mdev devices", "error", err)
+		logger.Warn("failed to reconcile vGPU devices", "error", err)
+	}
+
+	logger.Info("instance reconciliation complete",
+		"instances", reconcileSummary.Instances,
+		"vanished_vmms", reconcileSummary.VanishedVMMs,
+		"orphan_processes_killed", reconcileSummary.OrphanProcesses,
+		"unresponsive_vmms", reconcileSummary.Unresponsive,
+		"usb_devices_reconciled", reconcileSummary.USBDevices,
+		"orphan_taps_deleted", reconcileSummary.TAPDevices,
+		"orphan_mdevs_destroyed", reconcileSummary.Mdevs)
+
+	// Register maintenance tasks; SCHEDULE_* settings choose when they run
+	if err := registerMaintenanceTasks(app, logRetention, logRotationSchedule); err != nil {
+		return err
 	}
 
 	// Initialize ingress manager (starts Caddy daemon and DNS server for dynamic upstreams)
@@ -229,8 +341,14 @@ func run() error {
 	}
 	logger.Info("Ingress manager initialized", "listen_addr", cfg.CaddyListenAddress, "admin", app.IngressManager.AdminURL())
 
-	// Create router
+	// Create router. The API is served under /v1; unprefixed paths are
+	// deprecated aliases.
 	r := chi.NewRouter()
+	var sunset time.Time
+	if cfg.UnprefixedAPISunset != "" {
+		sunset, _ = time.Parse(time.DateOnly, cfg.UnprefixedAPISunset) // Validated with the config
+	}
+	r.Use(mw.APIVersion(mw.VersionConfig{Revisions: mw.Revisions, Sunset: sunset}))
 
 	// Prepare HTTP metrics middleware (applied inside API group, not globally)
 	// Global application breaks WebSocket (Hijacker) and SSE (Flusher)
@@ -259,15 +377,28 @@ func run() error {
 	// See: https://github.com/oapi-codegen/nethttp-middleware#usage
 	spec.Servers = nil
 
+	// Per-client request rate and concurrency limits (pass-through when disabled).
+	// The rate limit can be changed by a config reload.
+	rateLimit := app.RateLimiter.Middleware()
+	execSessionLimit := mw.LimitConcurrency("exec sessions", cfg.MaxExecSessions, nil)
+	// The create limit is shared with the gRPC API, so a client can't double it
+	createLimiter := mw.NewConcurrencyLimiter("create requests", cfg.MaxConcurrentCreates)
+	createLimit := createLimiter.Middleware(mw.IsCreateRequest)
+
 	// Custom exec endpoint (outside OpenAPI spec, uses WebSocket)
-	// Note: No otelchi here as WebSocket doesn't work well with tracing middleware
+	// Note: No otelchi here as WebSocket doesn't work well with tracing middleware;
+	// the caller's trace context is still propagated to the handler
 	r.With(
 		middleware.RequestID,
 		middleware.RealIP,
 		middleware.Recoverer,
+		mw.PropagateTraceContext,
 		mw.InjectLogger(logger),
 		mw.AccessLogger(accessLogger),
-		mw.JwtAuth(app.Config.JwtSecret),
+		authenticator.Middleware(),
+		mw.Authorize(),
+		rateLimit,
+		execSessionLimit,
 		mw.ResolveResource(app.ApiService.NewResolvers(), api.ResolverErrorResponder),
 	).Get("/instances/{id}/exec", app.ApiService.ExecHandler)
 
@@ -276,19 +407,38 @@ func run() error {
 		middleware.RequestID,
 		middleware.RealIP,
 		middleware.Recoverer,
+		mw.PropagateTraceContext,
 		mw.InjectLogger(logger),
 		mw.AccessLogger(accessLogger),
-		mw.JwtAuth(app.Config.JwtSecret),
+		authenticator.Middleware(),
+		mw.Authorize(),
+		rateLimit,
+		execSessionLimit,
 		mw.ResolveResource(app.ApiService.NewResolvers(), api.ResolverErrorResponder),
 	).Get("/instances/{id}/cp", app.ApiService.CpHandler)
 
+	// Multiplexed control channel (outside OpenAPI spec, uses WebSocket). Each
+	// channel is dispatched through this router as its own request, with the
+	// connection's credentials, so the middleware above applies per channel
+	r.With(
+		middleware.RequestID,
+		middleware.RealIP,
+		middleware.Recoverer,
+		mw.PropagateTraceContext,
+		mw.InjectLogger(logger),
+		mw.AccessLogger(accessLogger),
+		authenticator.Middleware(),
+		mw.Authorize(),
+		rateLimit,
+	).Get("/mux", api.MuxHandler(r))
+
 	// OCI Distribution registry endpoints for image push (outside OpenAPI spec)
 	r.Route("/v2", func(r chi.Router) {
 		r.Use(middleware.RequestID)
 		r.Use(middleware.RealIP)
 		r.Use(middleware.Logger)
 		r.Use(middleware.Recoverer)
-		r.Use(mw.JwtAuth(app.Config.JwtSecret))
+		r.Use(authenticator.Middleware())
 		r.Mount("/", app.Registry.Handler())
 	})
 
@@ -325,21 +475,38 @@ func run() error {
 
 		r.Use(middleware.Timeout(60 * time.Second))
 
-		// OpenAPI request validation with authentication
+		// OpenAPI request validation with authentication; all violations are
+		// reported so clients can fix every field in one round trip
 		validatorOptions := &nethttpmiddleware.Options{
 			Options: openapi3filter.Options{
-				AuthenticationFunc: mw.OapiAuthenticationFunc(app.Config.JwtSecret),
+				AuthenticationFunc: authenticator.OapiAuthenticationFunc(),
+				MultiError:         true,
 			},
-			ErrorHandler: mw.OapiErrorHandler,
+			ErrorHandlerWithOpts: mw.OapiErrorHandlerWithOpts,
 		}
 		r.Use(nethttpmiddleware.OapiRequestValidatorWithOptions(spec, validatorOptions))
 
+		// Role-based authorization using the claims set during validation
+		r.Use(mw.Authorize())
+
+		// Per-client limits, applied after authentication so clients are keyed by principal
+		r.Use(rateLimit)
+		r.Use(createLimit)
+
+		// Sparse responses for ?fields=, validated against the spec above
+		r.Use(mw.SelectFields())
+
 		// Resource resolver middleware - resolves IDs/names/prefixes before handlers
 		// Enriches context with resolved resource and logger with resolved ID
 		r.Use(mw.ResolveResource(app.ApiService.NewResolvers(), api.ResolverErrorResponder))
 
-		// Setup strict handler
-		strictHandler := oapi.NewStrictHandler(app.ApiService, nil)
+		// Setup strict handler; error responses are completed as problem+json
+		strictHandler := oapi.NewStrictHandlerWithOptions(app.ApiService,
+			[]oapi.StrictMiddlewareFunc{problem.StrictMiddleware},
+			oapi.StrictHTTPServerOptions{
+				RequestErrorHandlerFunc:  problem.WriteError(http.StatusBadRequest),
+				ResponseErrorHandlerFunc: problem.WriteError(http.StatusInternalServerError),
+			})
 
 		// Mount API routes (authentication now handled by validation middleware)
 		oapi.HandlerWithOptions(strictHandler, oapi.ChiServerOptions{
@@ -348,6 +515,56 @@ func run() error {
 		})
 	})
 
+	// Prometheus scrape endpoint (outside OpenAPI spec, requires viewer role)
+	if otelProvider != nil && otelProvider.MetricsHandler != nil {
+		r.With(
+			middleware.RequestID,
+			middleware.RealIP,
+			middleware.Recoverer,
+			mw.InjectLogger(logger),
+			authenticator.Middleware(),
+			mw.Authorize(),
+		).Get("/metrics", otelProvider.MetricsHandler.ServeHTTP)
+	}
+
+	// Diagnostics endpoints (outside OpenAPI spec, opt-in, requires admin role)
+	if cfg.DebugEndpointsEnabled {
+		r.Group(func(r chi.Router) {
+			r.Use(
+				middleware.RequestID,
+				middleware.RealIP,
+				middleware.Recoverer,
+				mw.InjectLogger(logger),
+				mw.AccessLogger(accessLogger),
+				authenticator.Middleware(),
+				mw.Authorize(),
+			)
+			r.Get("/debug/state", app.ApiService.DebugStateHandler)
+			// pprof.Index also serves the named profiles (heap, goroutine, ...)
+			r.Get("/debug/pprof/*", pprof.Index)
+			r.Get("/debug/pprof/cmdline", pprof.Cmdline)
+			r.Get("/debug/pprof/profile", pprof.Profile)
+			r.Get("/debug/pprof/symbol", pprof.Symbol)
+			r.Get("/debug/pprof/trace", pprof.Trace)
+		})
+		logger.Warn("debug endpoints enabled", "paths", "/debug/pprof, /debug/state")
+	}
+
+	// Config reload endpoint (outside OpenAPI spec, requires admin role)
+	reloader, err := newConfigReloader(app, app.Config)
+	if err != nil {
+		return err
+	}
+	r.With(
+		middleware.RequestID,
+		middleware.RealIP,
+		middleware.Recoverer,
+		mw.InjectLogger(logger),
+		mw.AccessLogger(accessLogger),
+		authenticator.Middleware(),
+		mw.Authorize(),
+	).Post("/admin/reload", reloader.ServeHTTP)
+
 	// Unauthenticated endpoints (outside group)
 	r.Get("/spec.yaml", func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "application/vnd.oai.openapi")
@@ -357,7 +574,7 @@ func run() error {
 	r.Get("/spec.json", func(w http.ResponseWriter, r *http.Request) {
 		jsonData, err := yaml.YAMLToJSON(hypeman.OpenAPIYAML)
 		if err != nil {
-			http.Error(w, "Failed to convert YAML to JSON", http.StatusInternalServerError)
+			problem.Write(w, http.StatusInternalServerError, oapi.InternalError, "Failed to convert YAML to JSON")
 			logger.ErrorContext(r.Context(), "Failed to convert YAML to JSON", "error", err)
 			return
 		}
@@ -368,9 +585,49 @@ func run() error {
 	r.Get("/swagger", api.SwaggerUIHandler)
 
 	// Create HTTP server
+	tlsConfig, err := newTLSConfig(app.Config)
+	if err != nil {
+		return fmt.Errorf("failed to configure TLS: %w", err)
+	}
 	srv := &http.Server{
-		Addr:    fmt.Sprintf(":%s", app.Config.Port),
-		Handler: r,
+		Addr:      fmt.Sprintf(":%s", app.Config.Port),
+		Handler:   r,
+		TLSConfig: tlsConfig,
+	}
+
+	// gRPC management API (optional), with the same TLS, credentials and
+	// per-client limits as HTTP
+	var grpcServer *grpc.Server
+	if app.Config.GRPCPort != "" {
+		mgmtServer, err := api.NewManagementServer(app.ApiService)
+		if err != nil {
+			return fmt.Errorf("failed to create gRPC management API: %w", err)
+		}
+		opts := authenticator.GRPCServerOptions(logger, api.GRPCRequiredRole)
+		opts = append(opts, mw.GRPCLimitOptions(app.RateLimiter, createLimiter, api.GRPCIsCreate)...)
+		if tlsConfig != nil {
+			opts = append(opts, grpc.Creds(credentials.NewTLS(tlsConfig)))
+		}
+		grpcServer = grpc.NewServer(opts...)
+		mgmt.RegisterManagementServiceServer(grpcServer, mgmtServer)
+	}
+
+	// Guest metadata service (optional), on the gateway guests' requests to
+	// network.MetadataAddress are redirected to
+	var metadataServer *http.Server
+	if app.Config.MetadataPort != "" {
+		gateway := app.Config.SubnetGateway
+		if gateway == "" {
+			gateway, err = network.DeriveGateway(app.Config.SubnetCIDR)
+			if err != nil {
+				return fmt.Errorf("derive gateway for metadata service: %w", err)
+			}
+		}
+		metadataServer = &http.Server{
+			Addr:              net.JoinHostPort(gateway, app.Config.MetadataPort),
+			Handler:           metadata.NewServer(app.InstanceManager, logger).Handler(),
+			ReadHeaderTimeout: 10 * time.Second,
+		}
 	}
 
 	// Error group for coordinated shutdown
@@ -382,16 +639,54 @@ func run() error {
 		return err
 	}
 
+	// Reload the configuration on SIGHUP
+	go reloader.WatchSignals(gctx, logger)
+
 	// Run the server
 	grp.Go(func() error {
-		logger.Info("starting hypeman API", "port", app.Config.Port)
-		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		logger.Info("starting hypeman API", "port", app.Config.Port, "tls", tlsConfig != nil, "mtls", app.Config.TLSClientCAFile != "")
+		var err error
+		if tlsConfig != nil {
+			// Certificates are served by tlsConfig.GetCertificate
+			err = srv.ListenAndServeTLS("", "")
+		} else {
+			err = srv.ListenAndServe()
+		}
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
 			logger.Error("http server error", "error", err)
 			return err
 		}
 		return nil
 	})
 
+	// Run the gRPC server
+	if grpcServer != nil {
+		grp.Go(func() error {
+			lis, err := net.Listen("tcp", fmt.Sprintf(":%s", app.Config.GRPCPort))
+			if err != nil {
+				return fmt.Errorf("listen on GRPC_PORT: %w", err)
+			}
+			logger.Info("starting hypeman gRPC API", "port", app.Config.GRPCPort, "tls", tlsConfig != nil)
+			if err := grpcServer.Serve(lis); err != nil {
+				logger.Error("grpc server error", "error", err)
+				return err
+			}
+			return nil
+		})
+	}
+
+	// Run the metadata server
+	if metadataServer != nil {
+		grp.Go(func() error {
+			logger.Info("starting guest metadata service", "addr", metadataServer.Addr, "address", network.MetadataAddress)
+			if err := metadataServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				logger.Error("metadata server error", "error", err)
+				return err
+			}
+			return nil
+		})
+	}
+
 	// Shutdown handler
 	grp.Go(func() error {
 		<-gctx.Done()
@@ -408,6 +703,28 @@ func run() error {
 		}
 		logger.Info("http server shutdown complete")
 
+		// Followed log and event streams never end on their own, so stop
+		// waiting for them when the shutdown timeout expires
+		if grpcServer != nil {
+			stopped := make(chan struct{})
+			go func() {
+				grpcServer.GracefulStop()
+				close(stopped)
+			}()
+			select {
+			case <-stopped:
+			case <-shutdownCtx.Done():
+				grpcServer.Stop()
+			}
+			logger.Info("grpc server shutdown complete")
+		}
+
+		if metadataServer != nil {
+			if err := metadataServer.Shutdown(shutdownCtx); err != nil {
+				logger.Error("failed to shutdown metadata server", "error", err)
+			}
+		}
+
 		// Shutdown ingress manager (stops Caddy if CADDY_STOP_ON_SHUTDOWN=true)
 		if err := app.IngressManager.Shutdown(shutdownCtx); err != nil {
 			logger.Error("failed to shutdown ingress manager", "error", err)
@@ -419,31 +736,396 @@ func run() error {
 		return nil
 	})
 
-	// Log rotation scheduler
+	// Maintenance scheduler: log rotation, mdev, TAP and garbage cleanup
+	grp.Go(func() error {
+		for _, task := range app.Scheduler.List() {
+			logger.Info("maintenance task scheduled", "task", task.Name, "schedule", task.Schedule, "next_run", task.NextRunAt)
+		}
+		logger.Info("log rotation settings", "max_size", app.Config.LogMaxSize, "max_files", logRetention.MaxFiles,
+			"max_age", logRetention.MaxAge, "max_total_size", app.Config.LogMaxTotalSize)
+		app.Scheduler.Run(gctx)
+		return nil
+	})
+
+	// Base-image update watcher
+	if imageUpdateCheckInterval > 0 {
+		updatePolicy := images.UpdatePolicy{
+			AutoUpdate: app.Config.ImageAutoUpdate,
+			WebhookURL: app.Config.ImageUpdateWebhookURL,
+		}
+		grp.Go(func() error {
+			ticker := time.NewTicker(imageUpdateCheckInterval)
+			defer ticker.Stop()
+
+			logger.Info("image update watcher started", "interval", app.Config.ImageUpdateCheckInterval, "auto_update", updatePolicy.AutoUpdate, "webhook", updatePolicy.WebhookURL != "")
+			for {
+				select {
+				case <-gctx.Done():
+					return nil
+				case <-ticker.C:
+					updates, err := app.ImageManager.CheckForUpdates(gctx, updatePolicy)
+					if err != nil {
+						logger.Error("image update check failed", "error", err)
+					}
+					for _, u := range updates {
+						logger.Info("image update available", "image", u.Name, "current_digest", u.CurrentDigest, "latest_digest", u.LatestDigest, "rebuilding", u.Rebuilding)
+					}
+				}
+			}
+		})
+	}
+
+	// GPU health loop: cordons GPUs with critical Xid or uncorrectable ECC errors
+	if gpuHealthCheckInterval > 0 && devices.DetectHostGPUMode() != devices.GPUModeNone {
+		grp.Go(func() error {
+			ticker := time.NewTicker(gpuHealthCheckInterval)
+			defer ticker.Stop()
+
+			logger.Info("GPU health monitor started", "interval", app.Config.GPUHealthCheckInterval)
+			for {
+				if _, err := app.DeviceManager.CheckGPUHealth(gctx); err != nil {
+					logger.Error("GPU health check failed", "error", err)
+				}
+				select {
+				case <-gctx.Done():
+					return nil
+				case <-ticker.C:
+				}
+			}
+		})
+	}
+
+	// Idle standby scheduler. Runs even when IDLE_STANDBY_AFTER is 0, since
+	// instances may set their own idle policy.
+	grp.Go(func() error {
+		ticker := time.NewTicker(idleCheckInterval)
+		defer ticker.Stop()
+
+		logger.Info("idle standby scheduler started", "interval", app.Config.IdleCheckInterval, "standby_after", idleDefaults.StandbyAfter, "wake_on_ingress", idleDefaults.WakeOnIngress)
+		for {
+			select {
+			case <-gctx.Done():
+				return nil
+			case <-ticker.C:
+				standby, err := app.InstanceManager.StandbyIdleInstances(gctx, idleDefaults)
+				if err != nil {
+					logger.Error("idle standby check failed", "error", err)
+				} else if len(standby) > 0 {
+					logger.Info("idle instances put into standby", "instance_ids", standby)
+				}
+			}
+		}
+	})
+
+	// Instance schedules: scheduled start, standby and stop. Each check
+	// fires the schedules due since the previous one.
 	grp.Go(func() error {
-		ticker := time.NewTicker(logRotateInterval)
+		ticker := time.NewTicker(scheduleCheckInterval)
 		defer ticker.Stop()
 
-		logger.Info("log rotation scheduler started", "interval", app.Config.LogRotateInterval, "max_size", logMaxSize, "max_files", app.Config.LogMaxFiles)
+		logger.Info("instance schedule runner started", "interval", app.Config.ScheduleCheckInterval)
+		last := time.Now()
+		for {
+			select {
+			case <-gctx.Done():
+				return nil
+			case now := <-ticker.C:
+				changed, err := app.InstanceManager.RunSchedules(gctx, last, now)
+				last = now
+				if err != nil {
+					logger.Error("instance schedule check failed", "error", err)
+				} else if len(changed) > 0 {
+					logger.Info("instance schedules ran", "instance_ids", changed)
+				}
+			}
+		}
+	})
+
+	// Instance group reconciler: keeps groups at their replica count and
+	// rolls members onto new template image digests
+	grp.Go(func() error {
+		ticker := time.NewTicker(groupReconcileInterval)
+		defer ticker.Stop()
+
+		logger.Info("instance group reconciler started", "interval", app.Config.GroupReconcileInterval)
 		for {
 			select {
 			case <-gctx.Done():
 				return nil
 			case <-ticker.C:
-				if err := app.InstanceManager.RotateLogs(gctx, int64(logMaxSize), app.Config.LogMaxFiles); err != nil {
-					logger.Error("log rotation failed", "error", err)
-				} else {
-					logger.Info("log rotation completed", "max_size", logMaxSize, "max_files", app.Config.LogMaxFiles)
+				if err := app.GroupManager.Reconcile(gctx); err != nil {
+					logger.Warn("instance group reconcile failed", "error", err)
 				}
 			}
 		}
 	})
 
+	// Usage metering: hourly per-instance usage rollups for GET /usage
+	if meteringInterval > 0 {
+		grp.Go(func() error {
+			logger.Info("usage metering started", "interval", app.Config.MeteringInterval, "retention", app.Config.UsageRetention)
+			app.Meter.Run(gctx)
+			return nil
+		})
+	}
+
+	// Node agent: register with the control plane and send heartbeats
+	if app.Config.ControlPlaneURL != "" {
+		grp.Go(func() error {
+			logger.Info("node agent started", "control_plane", app.Config.ControlPlaneURL, "node_id", app.Config.NodeID, "interval", app.Config.NodeHeartbeatInterval)
+			app.NodeAgent.Run(gctx)
+			return nil
+		})
+	}
+
+	// Console log shipper
+	if logShipper != nil {
+		grp.Go(func() error {
+			logger.Info("console log shipper started", "backend", app.Config.ConsoleLogShipper, "interval", app.Config.ConsoleLogShipInterval)
+			logShipper.Run(gctx)
+			return nil
+		})
+	}
+
 	err = grp.Wait()
 	slog.Info("all goroutines finished")
 	return err
 }
 
+// orphanTAPGrace is how long the tap-cleanup task leaves a TAP device without
+// a running instance alone, since instances being created get their TAP
+// before their metadata is saved
+const orphanTAPGrace = 10 * time.Minute
+
+// registerMaintenanceTasks adds the reconciliation and cleanup tasks to the
+// scheduler. Tasks without a schedule only run when triggered through the API.
+func registerMaintenanceTasks(app *application, logRetention instances.LogRetention, logRotationSchedule string) error {
+	if _, err := images.ParseRetentionRules(app.Config.RegistryRetention); err != nil {
+		return fmt.Errorf("invalid REGISTRY_RETENTION: %w", err)
+	}
+	tasks := []struct {
+		name, description, schedule, env string
+		run                              scheduler.TaskFunc
+	}{
+		{
+			name:        "log-rotation",
+			description: "Rotate instance logs that reached LOG_MAX_SIZE and prune old copies",
+			schedule:    logRotationSchedule,
+			env:         "SCHEDULE_LOG_ROTATION",
+			run: func(ctx context.Context) (string, error) {
+				res, err := app.InstanceManager.RotateLogs(ctx, logRetention)
+				if err != nil {
+					return "", err
+				}
+				return fmt.Sprintf("rotated %d logs, removed %d copies (%d bytes), %d bytes of logs remain",
+					res.Rotated, res.Removed, res.RemovedBytes, res.TotalBytes), nil
+			},
+		},
+		{
+			name:        "mdev-cleanup",
+			description: "Destroy vGPU mdevs and MIG GPU instances left by instances that no longer run",
+			schedule:    app.Config.ScheduleMdevCleanup,
+			env:         "SCHEDULE_MDEV_CLEANUP",
+			run: func(ctx context.Context) (string, error) {
+				insts, err := app.InstanceManager.ListInstances(ctx)
+				if err != nil {
+					return "", fmt.Errorf("list instances: %w", err)
+				}
+				tracked, destroyed, err := reconcileGPUs(ctx, insts)
+				return fmt.Sprintf("checked %d vGPUs tracked by instances, destroyed %d orphaned mdevs", tracked, destroyed), err
+			},
+		},
+		{
+			name:        "tap-cleanup",
+			description: "Remove TAP devices and HTB classes left by instances that no longer run",
+			schedule:    app.Config.ScheduleTAPCleanup,
+			env:         "SCHEDULE_TAP_CLEANUP",
+			run: func(ctx context.Context) (string, error) {
+				insts, err := app.InstanceManager.ListInstances(ctx)
+				if err != nil {
+					return "", fmt.Errorf("list instances: %w", err)
+				}
+				taps, classes := app.NetworkManager.CleanupOrphans(ctx, runningInstanceIDs(insts), orphanTAPGrace)
+				return fmt.Sprintf("removed %d TAP devices, %d HTB classes", taps, classes), nil
+			},
+		},
+		{
+			name:        "gc",
+			description: "Prune dangling images and orphan build volumes",
+			schedule:    app.Config.ScheduleGC,
+			env:         "SCHEDULE_GC",
+			run:         app.ApiService.CollectGarbage,
+		},
+		{
+			name:        "registry-retention",
+			description: "Remove registry images beyond REGISTRY_RETENTION, along with their disks",
+			schedule:    app.Config.ScheduleRetention,
+			env:         "SCHEDULE_REGISTRY_RETENTION",
+			run: func(ctx context.Context) (string, error) {
+				_, res, err := app.ApiService.ApplyRetention(ctx, false)
+				if res == nil {
+					return "", err
+				}
+				app.Registry.ForgetTags(res.Tags)
+				var reclaimed int64
+				for _, img := range res.Images {
+					reclaimed += img.Bytes
+				}
+				return fmt.Sprintf("removed %d tags and %d images, reclaimed %d bytes",
+					len(res.Tags), len(res.Images), reclaimed), err
+			},
+		},
+		{
+			name:        "disk-trim",
+			description: "Punch holes in the zeroed blocks of stopped and standby instances' disk files",
+			schedule:    app.Config.ScheduleDiskTrim,
+			env:         "SCHEDULE_DISK_TRIM",
+			run: func(ctx context.Context) (string, error) {
+				res, err := app.InstanceManager.TrimDisks(ctx)
+				if res == nil {
+					return "", err
+				}
+				return fmt.Sprintf("checked %d disk files of %d instances, reclaimed %d bytes",
+					res.Files, res.Instances, res.ReclaimedBytes), err
+			},
+		},
+		{
+			name:        "clock-sync",
+			description: "Set running instances' guest clocks to the host's time",
+			schedule:    app.Config.ScheduleClockSync,
+			env:         "SCHEDULE_CLOCK_SYNC",
+			run: func(ctx context.Context) (string, error) {
+				res, err := app.InstanceManager.SyncClocks(ctx)
+				if res == nil {
+					return "", err
+				}
+				return fmt.Sprintf("synced %d instances, max skew %s", res.Instances, res.MaxSkew), err
+			},
+		},
+		{
+			name:        "recording-retention",
+			description: "Remove exec session recordings beyond EXEC_RECORDING_MAX_AGE and EXEC_RECORDING_MAX_TOTAL_SIZE",
+			schedule:    app.Config.ScheduleRecordings,
+			env:         "SCHEDULE_RECORDING_RETENTION",
+			run: func(ctx context.Context) (string, error) {
+				res, err := app.ApiService.Recordings.Prune()
+				if res == nil {
+					return "", err
+				}
+				return fmt.Sprintf("removed %d recordings, reclaimed %d bytes", res.Removed, res.ReclaimedBytes), err
+			},
+		},
+	}
+	for _, t := range tasks {
+		if err := app.Scheduler.Add(t.name, t.description, t.schedule, t.run); err != nil {
+			return fmt.Errorf("invalid %s: %w", t.env, err)
+		}
+	}
+	return nil
+}
+
+// runningInstanceIDs returns the IDs of instances that may have a VMM and so
+// need their TAP device. Unknown instances are included: better to leave a
+// stale TAP than break a running VM. The result is never nil.
+func runningInstanceIDs(insts []instances.Instance) []string {
+	ids := []string{}
+	for _, inst := range insts {
+		if inst.State.RequiresVMM() || inst.State == instances.StateUnknown {
+			ids = append(ids, inst.Id)
+		}
+	}
+	return ids
+}
+
+// reconcileGPUs destroys vGPU mdevs and the MIG GPU instances backing them
+// when their instances no longer run. Only devices recorded in instance
+// metadata are touched. It returns how many instances hold a vGPU and how
+// many mdevs were destroyed.
+func reconcileGPUs(ctx context.Context, insts []instances.Instance) (int, int, error) {
+	var mdevInfos []devices.MdevReconcileInfo
+	var migInfos []devices.MIGReconcileInfo
+	for _, inst := range insts {
+		running := inst.State == instances.StateRunning || inst.State == instances.StateUnknown
+		if inst.GPUMdevUUID != "" {
+			mdevInfos = append(mdevInfos, devices.MdevReconcileInfo{
+				InstanceID: inst.Id,
+				MdevUUID:   inst.GPUMdevUUID,
+				IsRunning:  running,
+			})
+		}
+		if inst.GPUMIG != nil {
+			migInfos = append(migInfos, devices.MIGReconcileInfo{
+				InstanceID:  inst.Id,
+				MIGInstance: *inst.GPUMIG,
+				IsRunning:   running,
+			})
+		}
+	}
+
+	var errs []error
+	destroyed, err := devices.ReconcileMdevs(ctx, mdevInfos)
+	if err != nil {
+		errs = append(errs, fmt.Errorf("mdevs: %w", err))
+	}
+	if err := devices.ReconcileMIGInstances(ctx, migInfos); err != nil {
+		errs = append(errs, fmt.Errorf("MIG GPU instances: %w", err))
+	}
+	return len(mdevInfos), destroyed, errors.Join(errs...)
+}
+
+// newLogShipper creates the shipper for the backend named by
+// CONSOLE_LOG_SHIPPER. The config has already been validated.
+func newLogShipper(app *application, otelProvider *otel.Provider) (*logship.Shipper, error) {
+	interval, err := time.ParseDuration(app.Config.ConsoleLogShipInterval)
+	if err != nil {
+		return nil, fmt.Errorf("invalid CONSOLE_LOG_SHIP_INTERVAL %q: %w", app.Config.ConsoleLogShipInterval, err)
+	}
+
+	var sink logship.Sink
+	switch app.Config.ConsoleLogShipper {
+	case "otel":
+		if otelProvider == nil || otelProvider.LoggerProvider == nil {
+			return nil, fmt.Errorf("OpenTelemetry is not initialized")
+		}
+		sink = logship.NewOTelSink(otelProvider.LoggerProvider)
+	case "loki":
+		sink = logship.NewLokiSink(app.Config.ConsoleLogLokiURL, map[string]string{
+			"service_name": app.Config.OtelServiceName,
+			"host":         app.Config.OtelServiceInstanceID,
+		})
+	default:
+		return nil, fmt.Errorf("unknown backend %q", app.Config.ConsoleLogShipper)
+	}
+
+	var meter metric.Meter
+	if otelProvider != nil {
+		meter = otelProvider.Meter
+	}
+	cfg := logship.Config{Interval: interval, BatchSize: app.Config.ConsoleLogShipBatchSize}
+	return logship.NewShipper(paths.New(app.Config.DataDir), app.InstanceManager, sink, cfg, meter)
+}
+
+// updateGuestAgents pushes the bundled guest-agent to every running instance
+// that runs a different one. Failures are logged and leave the old agent running.
+func updateGuestAgents(ctx context.Context, manager instances.Manager, logger *slog.Logger) {
+	all, err := manager.ListInstances(ctx)
+	if err != nil {
+		logger.Warn("guest agent auto-update: failed to list instances", "error", err)
+		return
+	}
+	for _, inst := range all {
+		if inst.State != instances.StateRunning {
+			continue
+		}
+		info, err := manager.UpdateGuestAgent(ctx, inst.Id)
+		if err != nil {
+			logger.Warn("guest agent auto-update failed", "instance_id", inst.Id, "error", err)
+			continue
+		}
+		logger.Debug("guest agent up to date", "instance_id", inst.Id, "sha256", info.Checksum)
+	}
+}
+
 // checkKVMAccess verifies KVM is available and the user has permission to use it
 func checkKVMAccess() error {
 	f, err := os.OpenFile("/dev/kvm", os.O_RDWR, 0)
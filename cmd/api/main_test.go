@@ -33,18 +33,24 @@ func setupTestRouter(t *testing.T) http.Handler {
 	spec.Servers = nil
 
 	r := chi.NewRouter()
+	r.Use(mw.APIVersion(mw.VersionConfig{Revisions: mw.Revisions}))
 	r.Use(nethttpmiddleware.OapiRequestValidatorWithOptions(spec, &nethttpmiddleware.Options{
 		Options: openapi3filter.Options{
 			AuthenticationFunc: mw.OapiAuthenticationFunc(testJWTSecret),
 		},
 		ErrorHandler: mw.OapiErrorHandler,
 	}))
+	r.Use(mw.SelectFields())
 
-	// Simple handler for testing
+	// Simple handlers for testing
 	r.Post("/images", func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusCreated)
 		w.Write([]byte(`{"id":"test"}`))
 	})
+	r.Get("/instances", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`[{"id":"abc","name":"web","state":"Running","vcpus":2}]`))
+	})
 
 	return r
 }
@@ -65,6 +71,51 @@ func TestMiddleware_InvalidPayload(t *testing.T) {
 	assert.Equal(t, http.StatusBadRequest, w.Code)
 }
 
+func TestMiddleware_VersionPrefix(t *testing.T) {
+	router := setupTestRouter(t)
+	token, err := generateValidJWT("user-123")
+	require.NoError(t, err)
+
+	// /v1 paths are validated and routed like the unprefixed ones
+	for _, body := range []string{`{}`, `{"name":"test"}`} {
+		req := httptest.NewRequest(http.MethodPost, "/v1/images", bytes.NewBufferString(body))
+		req.Header.Set("Authorization", "Bearer "+token)
+		req.Header.Set("Content-Type", "application/json")
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		if body == `{}` {
+			assert.Equal(t, http.StatusBadRequest, w.Code)
+		} else {
+			assert.Equal(t, http.StatusCreated, w.Code)
+			assert.Empty(t, w.Header().Get("Deprecation"))
+		}
+	}
+}
+
+func TestMiddleware_SelectFields(t *testing.T) {
+	router := setupTestRouter(t)
+	token, err := generateValidJWT("user-123")
+	require.NoError(t, err)
+
+	get := func(target string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := get("/v1/instances?fields=id,state")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `[{"id":"abc","state":"Running"}]`, w.Body.String())
+
+	// Malformed selections are rejected by the spec
+	w = get("/v1/instances?fields=id,,state")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
 func TestMiddleware_InvalidJWT(t *testing.T) {
 	router := setupTestRouter(t)
 
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+
+	"github.com/kernel/hypeman/cmd/api/config"
+	"github.com/kernel/hypeman/lib/ingress"
+	"github.com/kernel/hypeman/lib/instances"
+	"github.com/kernel/hypeman/lib/logger"
+	"github.com/kernel/hypeman/lib/oapi"
+	"github.com/kernel/hypeman/lib/problem"
+	"github.com/kernel/hypeman/lib/providers"
+	"github.com/kernel/hypeman/lib/quotas"
+)
+
+// ReloadResult reports what a config reload changed
+type ReloadResult struct {
+	// Applied lists the changed settings that took effect
+	Applied []string `json:"applied"`
+	// RestartRequired lists the changed settings that take effect on the next restart
+	RestartRequired []string `json:"restart_required"`
+}
+
+// configReloader applies the reloadable settings of the .env file to the
+// running server, on SIGHUP or POST /admin/reload
+type configReloader struct {
+	app *application
+
+	mu   sync.Mutex // Serializes reloads
+	acme ingress.ACMEConfig
+}
+
+// newConfigReloader creates a reloader for app, whose settings were loaded
+// from cfg at startup
+func newConfigReloader(app *application, cfg *config.Config) (*configReloader, error) {
+	acme, err := providers.ParseACMEConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return &configReloader{app: app, acme: acme}, nil
+}
+
+// Reload reads the configuration again and applies the reloadable settings.
+// Nothing is applied if the new configuration is invalid.
+func (c *configReloader) Reload(ctx context.Context) (*ReloadResult, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	log := logger.FromContext(ctx)
+
+	var (
+		limits instances.ResourceLimits
+		quota  []quotas.Quota
+		acme   ingress.ACMEConfig
+	)
+	cfg, changed, err := config.Reload(func(cfg *config.Config) error {
+		var err error
+		if limits, err = providers.ParseAdmissionLimits(cfg); err != nil {
+			return err
+		}
+		if quota, err = quotas.Parse(cfg.Quotas); err != nil {
+			return fmt.Errorf("invalid QUOTAS: %w", err)
+		}
+		acme, err = providers.ParseACMEConfig(cfg)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("invalid configuration: %w", err)
+	}
+
+	result := &ReloadResult{Applied: []string{}, RestartRequired: []string{}}
+	for _, key := range changed {
+		if config.IsReloadable(key) {
+			result.Applied = append(result.Applied, key)
+		} else {
+			result.RestartRequired = append(result.RestartRequired, key)
+		}
+	}
+
+	// ACME credentials go first: Caddy may reject them, and the other
+	// settings can't fail
+	if acme != c.acme {
+		if err := c.app.IngressManager.UpdateACME(ctx, acme); err != nil {
+			return nil, fmt.Errorf("apply ACME config: %w", err)
+		}
+		c.acme = acme
+	}
+
+	c.app.InstanceManager.SetAdmissionLimits(limits)
+	c.app.QuotaManager.SetQuotas(quota)
+	logger.SetLevels(logger.NewConfig())
+	c.app.RateLimiter.SetLimit(cfg.RateLimitRPS, cfg.RateLimitBurst)
+	if cfg.BuilderImage != "" {
+		c.app.BuildManager.SetBuilderImage(cfg.BuilderImage)
+	}
+
+	log.InfoContext(ctx, "configuration reloaded", "applied", result.Applied, "restart_required", result.RestartRequired)
+	if len(result.RestartRequired) > 0 {
+		log.WarnContext(ctx, "some changed settings take effect only after a restart", "settings", result.RestartRequired)
+	}
+	return result, nil
+}
+
+// WatchSignals reloads the configuration on every SIGHUP until ctx is done
+func (c *configReloader) WatchSignals(ctx context.Context, log *slog.Logger) {
+	sighup := make(chan os.Signal, 1)
+	signal.Notify(sighup, syscall.SIGHUP)
+	defer signal.Stop(sighup)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-sighup:
+			log.InfoContext(ctx, "SIGHUP received, reloading configuration")
+			if _, err := c.Reload(logger.AddToContext(ctx, log)); err != nil {
+				log.ErrorContext(ctx, "failed to reload configuration", "error", err)
+			}
+		}
+	}
+}
+
+// ServeHTTP handles POST /admin/reload
+func (c *configReloader) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	result, err := c.Reload(r.Context())
+	if err != nil {
+		logger.FromContext(r.Context()).ErrorContext(r.Context(), "failed to reload configuration", "error", err)
+		problem.Write(w, http.StatusUnprocessableEntity, oapi.InvalidRequest, err.Error())
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
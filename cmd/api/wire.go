@@ -11,13 +11,21 @@ import (
 	"github.com/kernel/hypeman/cmd/api/config"
 	"github.com/kernel/hypeman/lib/builds"
 	"github.com/kernel/hypeman/lib/devices"
+	"github.com/kernel/hypeman/lib/groups"
 	"github.com/kernel/hypeman/lib/images"
 	"github.com/kernel/hypeman/lib/ingress"
 	"github.com/kernel/hypeman/lib/instances"
+	"github.com/kernel/hypeman/lib/metering"
+	mw "github.com/kernel/hypeman/lib/middleware"
 	"github.com/kernel/hypeman/lib/network"
+	"github.com/kernel/hypeman/lib/network/overlay"
+	"github.com/kernel/hypeman/lib/nodeagent"
 	"github.com/kernel/hypeman/lib/providers"
+	"github.com/kernel/hypeman/lib/quotas"
 	"github.com/kernel/hypeman/lib/registry"
 	"github.com/kernel/hypeman/lib/resources"
+	"github.com/kernel/hypeman/lib/scheduler"
+	"github.com/kernel/hypeman/lib/secrets"
 	"github.com/kernel/hypeman/lib/system"
 	"github.com/kernel/hypeman/lib/volumes"
 )
@@ -30,12 +38,20 @@ type application struct {
 	ImageManager    images.Manager
 	SystemManager   system.Manager
 	NetworkManager  network.Manager
+	OverlayManager  overlay.Manager
 	DeviceManager   devices.Manager
 	InstanceManager instances.Manager
 	VolumeManager   volumes.Manager
+	SecretManager   secrets.Manager
 	IngressManager  ingress.Manager
+	GroupManager    groups.Manager
 	BuildManager    builds.Manager
+	QuotaManager    quotas.Manager
+	Meter           *metering.Meter
 	ResourceManager *resources.Manager
+	NodeAgent       *nodeagent.Agent
+	Scheduler       *scheduler.Scheduler
+	RateLimiter     *mw.RateLimiter
 	Registry        *registry.Registry
 	ApiService      *api.ApiService
 }
@@ -47,16 +63,27 @@ func initializeApp() (*application, func(), error) {
 		providers.ProvideContext,
 		providers.ProvideConfig,
 		providers.ProvidePaths,
+		providers.ProvideRegistryProxy,
 		providers.ProvideImageManager,
 		providers.ProvideSystemManager,
 		providers.ProvideNetworkManager,
+		providers.ProvideOverlayManager,
 		providers.ProvideDeviceManager,
 		providers.ProvideInstanceManager,
 		providers.ProvideVolumeManager,
+		providers.ProvideSecretManager,
 		providers.ProvideIngressManager,
+		providers.ProvideGroupManager,
 		providers.ProvideBuildManager,
+		providers.ProvideQuotaManager,
+		providers.ProvideMeter,
 		providers.ProvideResourceManager,
+		providers.ProvideNodeAgent,
+		providers.ProvideScheduler,
+		providers.ProvideRateLimiter,
 		providers.ProvideRegistry,
+		providers.ProvideHealthChecker,
+		providers.ProvideRecordingStore,
 		api.New,
 		wire.Struct(new(application), "*"),
 	))
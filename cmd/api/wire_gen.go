@@ -12,13 +12,21 @@ import (
 	"github.com/kernel/hypeman/cmd/api/config"
 	"github.com/kernel/hypeman/lib/builds"
 	"github.com/kernel/hypeman/lib/devices"
+	"github.com/kernel/hypeman/lib/groups"
 	"github.com/kernel/hypeman/lib/images"
 	"github.com/kernel/hypeman/lib/ingress"
 	"github.com/kernel/hypeman/lib/instances"
+	"github.com/kernel/hypeman/lib/metering"
+	mw "github.com/kernel/hypeman/lib/middleware"
 	"github.com/kernel/hypeman/lib/network"
+	"github.com/kernel/hypeman/lib/network/overlay"
+	"github.com/kernel/hypeman/lib/nodeagent"
 	"github.com/kernel/hypeman/lib/providers"
+	"github.com/kernel/hypeman/lib/quotas"
 	"github.com/kernel/hypeman/lib/registry"
 	"github.com/kernel/hypeman/lib/resources"
+	"github.com/kernel/hypeman/lib/scheduler"
+	"github.com/kernel/hypeman/lib/secrets"
 	"github.com/kernel/hypeman/lib/system"
 	"github.com/kernel/hypeman/lib/volumes"
 	"log/slog"
@@ -36,18 +44,27 @@ func initializeApp() (*application, func(), error) {
 	paths := providers.ProvidePaths(config)
 	logger := providers.ProvideLogger(paths)
 	context := providers.ProvideContext(logger)
-	manager, err := providers.ProvideImageManager(paths, config)
+	proxy, err := providers.ProvideRegistryProxy(paths, config)
+	if err != nil {
+		return nil, nil, err
+	}
+	manager, err := providers.ProvideImageManager(paths, config, proxy)
 	if err != nil {
 		return nil, nil, err
 	}
 	systemManager := providers.ProvideSystemManager(paths)
 	networkManager := providers.ProvideNetworkManager(paths, config)
+	overlayManager := providers.ProvideOverlayManager(paths, config)
 	devicesManager := providers.ProvideDeviceManager(paths)
 	volumesManager, err := providers.ProvideVolumeManager(paths, config)
 	if err != nil {
 		return nil, nil, err
 	}
-	instancesManager, err := providers.ProvideInstanceManager(paths, config, manager, systemManager, networkManager, devicesManager, volumesManager)
+	secretsManager, err := providers.ProvideSecretManager(paths, config)
+	if err != nil {
+		return nil, nil, err
+	}
+	instancesManager, err := providers.ProvideInstanceManager(paths, config, manager, systemManager, networkManager, devicesManager, volumesManager, secretsManager)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -55,19 +72,39 @@ func initializeApp() (*application, func(), error) {
 	if err != nil {
 		return nil, nil, err
 	}
-	buildsManager, err := providers.ProvideBuildManager(paths, config, instancesManager, volumesManager, logger)
+	groupsManager := providers.ProvideGroupManager(paths, instancesManager, manager, ingressManager)
+	buildsManager, err := providers.ProvideBuildManager(paths, config, instancesManager, volumesManager, networkManager, logger)
+	if err != nil {
+		return nil, nil, err
+	}
+	quotasManager, err := providers.ProvideQuotaManager(config, instancesManager, volumesManager, buildsManager)
+	if err != nil {
+		return nil, nil, err
+	}
+	meter, err := providers.ProvideMeter(paths, config, instancesManager)
+	if err != nil {
+		return nil, nil, err
+	}
+	resourcesManager, err := providers.ProvideResourceManager(context, config, paths, manager, instancesManager, volumesManager, devicesManager)
+	if err != nil {
+		return nil, nil, err
+	}
+	agent, err := providers.ProvideNodeAgent(config, resourcesManager)
 	if err != nil {
 		return nil, nil, err
 	}
-	resourcesManager, err := providers.ProvideResourceManager(context, config, paths, manager, instancesManager, volumesManager)
+	scheduler := providers.ProvideScheduler()
+	rateLimiter := providers.ProvideRateLimiter(config)
+	registry, err := providers.ProvideRegistry(paths, manager, proxy)
 	if err != nil {
 		return nil, nil, err
 	}
-	registry, err := providers.ProvideRegistry(paths, manager)
+	checker := providers.ProvideHealthChecker(manager, networkManager, ingressManager, registry)
+	store, err := providers.ProvideRecordingStore(paths, config)
 	if err != nil {
 		return nil, nil, err
 	}
-	apiService := api.New(config, manager, instancesManager, volumesManager, networkManager, devicesManager, ingressManager, buildsManager, resourcesManager)
+	apiService := api.New(config, manager, instancesManager, volumesManager, secretsManager, networkManager, overlayManager, devicesManager, ingressManager, groupsManager, buildsManager, quotasManager, meter, resourcesManager, agent, scheduler, checker, store)
 	mainApplication := &application{
 		Ctx:             context,
 		Logger:          logger,
@@ -75,12 +112,20 @@ func initializeApp() (*application, func(), error) {
 		ImageManager:    manager,
 		SystemManager:   systemManager,
 		NetworkManager:  networkManager,
+		OverlayManager:  overlayManager,
 		DeviceManager:   devicesManager,
 		InstanceManager: instancesManager,
 		VolumeManager:   volumesManager,
+		SecretManager:   secretsManager,
 		IngressManager:  ingressManager,
+		GroupManager:    groupsManager,
 		BuildManager:    buildsManager,
+		QuotaManager:    quotasManager,
+		Meter:           meter,
 		ResourceManager: resourcesManager,
+		NodeAgent:       agent,
+		Scheduler:       scheduler,
+		RateLimiter:     rateLimiter,
 		Registry:        registry,
 		ApiService:      apiService,
 	}
@@ -98,12 +143,20 @@ type application struct {
 	ImageManager    images.Manager
 	SystemManager   system.Manager
 	NetworkManager  network.Manager
+	OverlayManager  overlay.Manager
 	DeviceManager   devices.Manager
 	InstanceManager instances.Manager
 	VolumeManager   volumes.Manager
+	SecretManager   secrets.Manager
 	IngressManager  ingress.Manager
+	GroupManager    groups.Manager
 	BuildManager    builds.Manager
+	QuotaManager    quotas.Manager
+	Meter           *metering.Meter
 	ResourceManager *resources.Manager
+	NodeAgent       *nodeagent.Agent
+	Scheduler       *scheduler.Scheduler
+	RateLimiter     *mw.RateLimiter
 	Registry        *registry.Registry
 	ApiService      *api.ApiService
 }
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/kernel/hypeman/cmd/api/config"
+	"github.com/kernel/hypeman/lib/doctor"
+	"github.com/kernel/hypeman/lib/paths"
+)
+
+func main() {
+	jsonOutput := flag.Bool("json", false, "Print the report as JSON")
+	flag.Parse()
+
+	cfg := config.Load()
+	report := doctor.Run(context.Background(), doctor.Checks(paths.New(cfg.DataDir)))
+
+	if *jsonOutput {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(report); err != nil {
+			fmt.Fprintf(os.Stderr, "Error encoding report: %v\n", err)
+			os.Exit(1)
+		}
+	} else {
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		for _, r := range report.Results {
+			fmt.Fprintf(w, "%s\t%s\t%s\n", r.Status, r.Name, r.Detail)
+			if r.Fix != "" {
+				fmt.Fprintf(w, "\t\tfix: %s\n", r.Fix)
+			}
+		}
+		w.Flush()
+	}
+
+	if !report.Passed {
+		os.Exit(1)
+	}
+}
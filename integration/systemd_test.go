@@ -55,13 +55,13 @@ func TestSystemdMode(t *testing.T) {
 	}
 
 	// Create managers
-	imageManager, err := images.NewManager(p, 1, nil)
+	imageManager, err := images.NewManager(p, 1, nil, nil)
 	require.NoError(t, err)
 
 	systemManager := system.NewManager(p)
 	networkManager := network.NewManager(p, cfg, nil)
 	deviceManager := devices.NewManager(p)
-	volumeManager := volumes.NewManager(p, 0, nil)
+	volumeManager := volumes.NewManager(p, 0, nil, nil)
 
 	limits := instances.ResourceLimits{
 		MaxOverlaySize:       100 * 1024 * 1024 * 1024,
@@ -71,11 +71,11 @@ func TestSystemdMode(t *testing.T) {
 		MaxTotalMemory:       0,
 	}
 
-	instanceManager := instances.NewManager(p, imageManager, systemManager, networkManager, deviceManager, volumeManager, limits, "", nil, nil)
+	instanceManager := instances.NewManager(p, imageManager, systemManager, networkManager, deviceManager, volumeManager, nil, limits, "", nil, nil)
 
 	// Cleanup any orphaned instances
 	t.Cleanup(func() {
-		instanceManager.DeleteInstance(ctx, "systemd-test")
+		instanceManager.DeleteInstance(ctx, "systemd-test", instances.DeleteInstanceRequest{})
 	})
 
 	imageName := "docker.io/jrei/systemd-ubuntu:22.04"
@@ -64,13 +64,13 @@ func TestVGPU(t *testing.T) {
 	}
 
 	// Create managers
-	imageManager, err := images.NewManager(p, 1, nil)
+	imageManager, err := images.NewManager(p, 1, nil, nil)
 	require.NoError(t, err)
 
 	systemManager := system.NewManager(p)
 	networkManager := network.NewManager(p, cfg, nil)
 	deviceManager := devices.NewManager(p)
-	volumeManager := volumes.NewManager(p, 0, nil)
+	volumeManager := volumes.NewManager(p, 0, nil, nil)
 
 	limits := instances.ResourceLimits{
 		MaxOverlaySize:       100 * 1024 * 1024 * 1024,
@@ -80,7 +80,7 @@ func TestVGPU(t *testing.T) {
 		MaxTotalMemory:       0,
 	}
 
-	instanceManager := instances.NewManager(p, imageManager, systemManager, networkManager, deviceManager, volumeManager, limits, "", nil, nil)
+	instanceManager := instances.NewManager(p, imageManager, systemManager, networkManager, deviceManager, volumeManager, nil, limits, "", nil, nil)
 
 	// Track instance ID for cleanup
 	var instanceID string
@@ -89,7 +89,7 @@ func TestVGPU(t *testing.T) {
 	t.Cleanup(func() {
 		if instanceID != "" {
 			t.Log("Cleanup: Deleting instance...")
-			instanceManager.DeleteInstance(ctx, instanceID)
+			instanceManager.DeleteInstance(ctx, instanceID, instances.DeleteInstanceRequest{})
 		}
 	})
 
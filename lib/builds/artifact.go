@@ -0,0 +1,89 @@
+package builds
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+
+	"github.com/c2h5oh/datasize"
+)
+
+// DefaultMaxArtifactBytes is the default size limit of build artifacts
+const DefaultMaxArtifactBytes = 1 << 30 // 1GB
+
+// artifactUpload receives the artifact tarball a builder agent uploads in
+// chunks. It writes to a temp file that is only moved into place once the
+// agent's reported size and hash check out.
+type artifactUpload struct {
+	path string
+	max  int64
+	f    *os.File
+	n    int64
+	hash hash.Hash
+}
+
+func newArtifactUpload(path string, max int64) (*artifactUpload, error) {
+	f, err := os.Create(path + ".tmp")
+	if err != nil {
+		return nil, fmt.Errorf("create artifact file: %w", err)
+	}
+	return &artifactUpload{path: path, max: max, f: f, hash: sha256.New()}, nil
+}
+
+// write appends a chunk, failing once the artifact exceeds the size limit
+func (u *artifactUpload) write(chunk []byte) error {
+	u.n += int64(len(chunk))
+	if u.max > 0 && u.n > u.max {
+		return fmt.Errorf("artifact exceeds the %s size limit", datasize.ByteSize(u.max).HR())
+	}
+	if _, err := u.f.Write(chunk); err != nil {
+		return fmt.Errorf("write artifact: %w", err)
+	}
+	u.hash.Write(chunk)
+	return nil
+}
+
+// finish checks the upload against the size and hash the agent reported
+// and moves it into place
+func (u *artifactUpload) finish(result *BuildResult) error {
+	if err := u.f.Close(); err != nil {
+		return fmt.Errorf("close artifact file: %w", err)
+	}
+	if u.n != result.ArtifactBytes || hex.EncodeToString(u.hash.Sum(nil)) != result.ArtifactSHA256 {
+		return fmt.Errorf("artifact upload incomplete: received %d of %d bytes", u.n, result.ArtifactBytes)
+	}
+	if err := os.Rename(u.path+".tmp", u.path); err != nil {
+		return fmt.Errorf("store artifact: %w", err)
+	}
+	return nil
+}
+
+// abort discards a partial upload
+func (u *artifactUpload) abort() {
+	u.f.Close()
+	os.Remove(u.path + ".tmp")
+}
+
+// GetBuildArtifact opens the artifact tarball of a finished artifact build
+func (m *manager) GetBuildArtifact(ctx context.Context, id string) (io.ReadCloser, *BuildArtifact, error) {
+	meta, err := readMetadata(m.paths, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	if meta.Artifact == nil {
+		return nil, nil, ErrNoArtifact
+	}
+
+	f, err := os.Open(m.paths.BuildArtifact(id))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil, ErrNoArtifact
+		}
+		return nil, nil, fmt.Errorf("open artifact: %w", err)
+	}
+	return f, meta.Artifact, nil
+}
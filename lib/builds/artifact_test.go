@@ -0,0 +1,92 @@
+package builds
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestArtifactUpload(t *testing.T) {
+	path := t.TempDir() + "/artifact.tar.gz"
+	data := []byte("artifact contents")
+	sum := sha256.Sum256(data)
+	result := &BuildResult{Success: true, ArtifactBytes: int64(len(data)), ArtifactSHA256: hex.EncodeToString(sum[:])}
+
+	t.Run("stores complete uploads", func(t *testing.T) {
+		u, err := newArtifactUpload(path, 1024)
+		require.NoError(t, err)
+		require.NoError(t, u.write(data[:5]))
+		require.NoError(t, u.write(data[5:]))
+		require.NoError(t, u.finish(result))
+
+		got, err := os.ReadFile(path)
+		require.NoError(t, err)
+		assert.Equal(t, data, got)
+		assert.NoFileExists(t, path+".tmp")
+	})
+
+	t.Run("rejects oversized uploads", func(t *testing.T) {
+		u, err := newArtifactUpload(path, 8)
+		require.NoError(t, err)
+		assert.ErrorContains(t, u.write(data), "size limit")
+		u.abort()
+		assert.NoFileExists(t, path+".tmp")
+	})
+
+	t.Run("rejects incomplete uploads", func(t *testing.T) {
+		require.NoError(t, os.Remove(path))
+		u, err := newArtifactUpload(path, 1024)
+		require.NoError(t, err)
+		require.NoError(t, u.write(data[:5]))
+		assert.Error(t, u.finish(result))
+		assert.NoFileExists(t, path)
+	})
+}
+
+func TestCreateBuild_ArtifactPath(t *testing.T) {
+	mgr, _, _, tempDir := setupTestManager(t)
+	defer os.RemoveAll(tempDir)
+	mgr.config.MaxArtifactBytes = 1024
+	ctx := context.Background()
+
+	_, err := mgr.CreateBuild(ctx, CreateBuildRequest{Dockerfile: "FROM alpine", ArtifactPath: "dist"}, []byte("source"))
+	assert.ErrorIs(t, err, ErrInvalidArtifactPath)
+
+	build, err := mgr.CreateBuild(ctx, CreateBuildRequest{Dockerfile: "FROM alpine", ArtifactPath: "/app/dist"}, []byte("source"))
+	require.NoError(t, err)
+	config, err := readBuildConfig(mgr.paths, build.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "/app/dist", config.ArtifactPath)
+	assert.Equal(t, int64(1024), config.ArtifactMaxBytes)
+}
+
+func TestGetBuildArtifact(t *testing.T) {
+	mgr, _, _, tempDir := setupTestManager(t)
+	defer os.RemoveAll(tempDir)
+	ctx := context.Background()
+
+	_, _, err := mgr.GetBuildArtifact(ctx, "missing")
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	require.NoError(t, writeMetadata(mgr.paths, &buildMetadata{ID: "image-build", Status: StatusReady}))
+	_, _, err = mgr.GetBuildArtifact(ctx, "image-build")
+	assert.ErrorIs(t, err, ErrNoArtifact)
+
+	artifact := &BuildArtifact{Path: "/out", SizeBytes: 4, SHA256: "abc"}
+	require.NoError(t, writeMetadata(mgr.paths, &buildMetadata{ID: "artifact-build", Status: StatusReady, Artifact: artifact}))
+	require.NoError(t, os.WriteFile(mgr.paths.BuildArtifact("artifact-build"), []byte("tgz!"), 0644))
+
+	rc, got, err := mgr.GetBuildArtifact(ctx, "artifact-build")
+	require.NoError(t, err)
+	defer rc.Close()
+	data, err := io.ReadAll(rc)
+	require.NoError(t, err)
+	assert.Equal(t, "tgz!", string(data))
+	assert.Equal(t, artifact, got)
+}
@@ -0,0 +1,322 @@
+package builds
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/google/go-containerregistry/pkg/v1/static"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+)
+
+const (
+	// SBOMMediaType is the media type of build SBOMs (SPDX JSON)
+	SBOMMediaType = "application/spdx+json"
+
+	// SLSAProvenanceMediaType is the media type of SLSA provenance statements
+	SLSAProvenanceMediaType = "application/vnd.in-toto+json"
+
+	// slsaBuildType identifies how hypeman builds are run in SLSA provenance
+	slsaBuildType = "https://github.com/kernel/hypeman/builds/v1"
+
+	// slsaBuilderID identifies the builder in SLSA provenance
+	slsaBuilderID = "https://github.com/kernel/hypeman/builder"
+)
+
+// in-toto statement carrying SLSA v1 provenance
+// (https://slsa.dev/spec/v1.0/provenance)
+type slsaStatement struct {
+	Type          string         `json:"_type"`
+	Subject       []slsaSubject  `json:"subject"`
+	PredicateType string         `json:"predicateType"`
+	Predicate     slsaProvenance `json:"predicate"`
+}
+
+type slsaSubject struct {
+	Name   string            `json:"name"`
+	Digest map[string]string `json:"digest"`
+}
+
+type slsaProvenance struct {
+	BuildDefinition slsaBuildDefinition `json:"buildDefinition"`
+	RunDetails      slsaRunDetails      `json:"runDetails"`
+}
+
+type slsaBuildDefinition struct {
+	BuildType            string           `json:"buildType"`
+	ExternalParameters   map[string]any   `json:"externalParameters"`
+	InternalParameters   map[string]any   `json:"internalParameters,omitempty"`
+	ResolvedDependencies []slsaDependency `json:"resolvedDependencies,omitempty"`
+}
+
+type slsaDependency struct {
+	Name   string            `json:"name,omitempty"`
+	URI    string            `json:"uri,omitempty"`
+	Digest map[string]string `json:"digest"`
+}
+
+type slsaRunDetails struct {
+	Builder  slsaBuilder  `json:"builder"`
+	Metadata slsaMetadata `json:"metadata"`
+}
+
+type slsaBuilder struct {
+	ID      string            `json:"id"`
+	Version map[string]string `json:"version,omitempty"`
+}
+
+type slsaMetadata struct {
+	InvocationID string     `json:"invocationId"`
+	StartedOn    *time.Time `json:"startedOn,omitempty"`
+	FinishedOn   time.Time  `json:"finishedOn"`
+}
+
+// newSLSAProvenance builds the SLSA provenance statement of a successful build
+func (m *manager) newSLSAProvenance(meta *buildMetadata, req CreateBuildRequest, policy *BuildPolicy, result *BuildResult, finishedAt time.Time) ([]byte, error) {
+	var subject slsaSubject
+	if req.ArtifactPath != "" {
+		subject = slsaSubject{
+			Name:   filepath.Base(req.ArtifactPath) + ".tar.gz",
+			Digest: map[string]string{"sha256": result.ArtifactSHA256},
+		}
+	} else {
+		subject = slsaSubject{
+			Name:   fmt.Sprintf("%s/builds/%s", m.config.RegistryURL, meta.ID),
+			Digest: map[string]string{"sha256": strings.TrimPrefix(result.ImageDigest, "sha256:")},
+		}
+	}
+
+	external := map[string]any{}
+	if req.Dockerfile != "" {
+		sum := sha256.Sum256([]byte(req.Dockerfile))
+		external["dockerfile_sha256"] = hex.EncodeToString(sum[:])
+	}
+	if len(req.BuildArgs) > 0 {
+		external["build_args"] = req.BuildArgs
+	}
+	if req.BaseImageDigest != "" {
+		external["base_image_digest"] = req.BaseImageDigest
+	}
+	if req.CacheScope != "" {
+		external["cache_scope"] = req.CacheScope
+	}
+	if req.ArtifactPath != "" {
+		external["artifact_path"] = req.ArtifactPath
+	}
+	if len(req.Secrets) > 0 {
+		ids := make([]string, len(req.Secrets))
+		for i, s := range req.Secrets {
+			ids[i] = s.ID
+		}
+		external["secret_ids"] = ids
+	}
+
+	var deps []slsaDependency
+	if result.Provenance.SourceHash != "" {
+		deps = append(deps, slsaDependency{Name: "source", Digest: map[string]string{"sha256": result.Provenance.SourceHash}})
+	}
+	if d := result.Provenance.BaseImageDigest; d != "" {
+		deps = append(deps, slsaDependency{Name: "base_image", Digest: map[string]string{"sha256": strings.TrimPrefix(d, "sha256:")}})
+	}
+	for _, file := range slices.Sorted(maps.Keys(result.Provenance.LockfileHashes)) {
+		deps = append(deps, slsaDependency{Name: file, Digest: map[string]string{"sha256": result.Provenance.LockfileHashes[file]}})
+	}
+
+	builder := slsaBuilder{ID: slsaBuilderID}
+	if v := result.Provenance.BuildkitVersion; v != "" {
+		builder.Version = map[string]string{"buildkit": v}
+	}
+
+	statement := slsaStatement{
+		Type:          "https://in-toto.io/Statement/v1",
+		Subject:       []slsaSubject{subject},
+		PredicateType: "https://slsa.dev/provenance/v1",
+		Predicate: slsaProvenance{
+			BuildDefinition: slsaBuildDefinition{
+				BuildType:          slsaBuildType,
+				ExternalParameters: external,
+				InternalParameters: map[string]any{
+					"network_mode":    policy.NetworkMode,
+					"timeout_seconds": policy.TimeoutSeconds,
+					"memory_mb":       policy.MemoryMB,
+					"cpus":            policy.CPUs,
+				},
+				ResolvedDependencies: deps,
+			},
+			RunDetails: slsaRunDetails{
+				Builder: builder,
+				Metadata: slsaMetadata{
+					InvocationID: meta.ID,
+					StartedOn:    meta.StartedAt,
+					FinishedOn:   finishedAt,
+				},
+			},
+		},
+	}
+	return json.MarshalIndent(statement, "", "  ")
+}
+
+// recordAttestations stores the SBOM and SLSA provenance of a successful
+// build and, if configured, pushes them to the registry as OCI referrers of
+// the image. Failures are logged rather than failing the build.
+func (m *manager) recordAttestations(ctx context.Context, id string, req CreateBuildRequest, policy *BuildPolicy, result *BuildResult) {
+	meta, err := readMetadata(m.paths, id)
+	if err != nil {
+		m.logger.Warn("failed to read metadata for attestations", "id", id, "error", err)
+		return
+	}
+
+	provenance, err := m.newSLSAProvenance(meta, req, policy, result, time.Now())
+	if err != nil {
+		m.logger.Warn("failed to create SLSA provenance", "id", id, "error", err)
+	} else if meta.SLSAProvenance, err = writeDocument(m.paths.BuildSLSAProvenance(id), SLSAProvenanceMediaType, provenance); err != nil {
+		m.logger.Warn("failed to store SLSA provenance", "id", id, "error", err)
+	}
+
+	if len(result.SBOM) > 0 {
+		if meta.SBOM, err = writeDocument(m.paths.BuildSBOM(id), SBOMMediaType, result.SBOM); err != nil {
+			m.logger.Warn("failed to store SBOM", "id", id, "error", err)
+		}
+	}
+
+	if m.config.PushAttestations && req.ArtifactPath == "" {
+		docs := []struct {
+			doc     *BuildDocument
+			content []byte
+		}{{meta.SBOM, result.SBOM}, {meta.SLSAProvenance, provenance}}
+		for _, d := range docs {
+			if d.doc == nil {
+				continue
+			}
+			digest, err := m.pushReferrer(ctx, id, result.ImageDigest, d.doc.MediaType, d.content)
+			if err != nil {
+				m.logger.Warn("failed to push attestation to registry", "id", id, "media_type", d.doc.MediaType, "error", err)
+				continue
+			}
+			d.doc.ReferrerDigest = &digest
+		}
+	}
+
+	if err := writeMetadata(m.paths, meta); err != nil {
+		m.logger.Warn("failed to write metadata for attestations", "id", id, "error", err)
+	}
+}
+
+// writeDocument stores an attestation document and describes it
+func writeDocument(path, mediaType string, content []byte) (*BuildDocument, error) {
+	if err := os.WriteFile(path, content, 0644); err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(content)
+	return &BuildDocument{
+		MediaType: mediaType,
+		SizeBytes: int64(len(content)),
+		SHA256:    hex.EncodeToString(sum[:]),
+	}, nil
+}
+
+// pushReferrer pushes content to the build's repository as an OCI artifact
+// whose subject is the built image, and returns the artifact's digest. The
+// artifact type is carried as the config media type, as registries without
+// artifactType support expect.
+func (m *manager) pushReferrer(ctx context.Context, id, imageDigest, artifactType string, content []byte) (string, error) {
+	repo, err := name.NewRepository(fmt.Sprintf("%s/builds/%s", m.config.RegistryURL, id), name.Insecure)
+	if err != nil {
+		return "", fmt.Errorf("parse repository: %w", err)
+	}
+	token, err := m.tokenGenerator.GeneratePushToken(id, []string{"builds/" + id}, 5*time.Minute)
+	if err != nil {
+		return "", fmt.Errorf("generate registry token: %w", err)
+	}
+	opts := []remote.Option{
+		remote.WithContext(ctx),
+		remote.WithAuth(&authn.Basic{Username: token}),
+	}
+
+	subject, err := remote.Head(repo.Digest(imageDigest), opts...)
+	if err != nil {
+		return "", fmt.Errorf("resolve image: %w", err)
+	}
+
+	config := static.NewLayer([]byte("{}"), types.MediaType(artifactType))
+	layer := static.NewLayer(content, types.MediaType(artifactType))
+	for _, l := range []v1.Layer{config, layer} {
+		if err := remote.WriteLayer(repo, l, opts...); err != nil {
+			return "", fmt.Errorf("push blob: %w", err)
+		}
+	}
+
+	configDigest, _ := config.Digest()
+	layerDigest, _ := layer.Digest()
+	manifest, err := json.Marshal(v1.Manifest{
+		SchemaVersion: 2,
+		MediaType:     types.OCIManifestSchema1,
+		Config:        v1.Descriptor{MediaType: types.MediaType(artifactType), Size: 2, Digest: configDigest},
+		Layers:        []v1.Descriptor{{MediaType: types.MediaType(artifactType), Size: int64(len(content)), Digest: layerDigest}},
+		Subject:       subject,
+	})
+	if err != nil {
+		return "", fmt.Errorf("encode manifest: %w", err)
+	}
+
+	digest, _, err := v1.SHA256(bytes.NewReader(manifest))
+	if err != nil {
+		return "", err
+	}
+	if err := remote.Put(repo.Digest(digest.String()), rawManifest(manifest), opts...); err != nil {
+		return "", fmt.Errorf("push manifest: %w", err)
+	}
+	return digest.String(), nil
+}
+
+// rawManifest is an OCI image manifest ready to push
+type rawManifest []byte
+
+func (r rawManifest) RawManifest() ([]byte, error)        { return r, nil }
+func (r rawManifest) MediaType() (types.MediaType, error) { return types.OCIManifestSchema1, nil }
+
+// GetBuildDocument opens an attestation document of a build: its SBOM or
+// its SLSA provenance
+func (m *manager) GetBuildDocument(ctx context.Context, id string, kind DocumentKind) (io.ReadCloser, *BuildDocument, error) {
+	meta, err := readMetadata(m.paths, id)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var doc *BuildDocument
+	var path string
+	switch kind {
+	case DocumentSBOM:
+		doc, path = meta.SBOM, m.paths.BuildSBOM(id)
+	case DocumentSLSAProvenance:
+		doc, path = meta.SLSAProvenance, m.paths.BuildSLSAProvenance(id)
+	default:
+		return nil, nil, fmt.Errorf("unknown document kind %q", kind)
+	}
+	if doc == nil {
+		return nil, nil, ErrNoDocument
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil, ErrNoDocument
+		}
+		return nil, nil, fmt.Errorf("open document: %w", err)
+	}
+	return f, doc, nil
+}
@@ -0,0 +1,147 @@
+package builds
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/registry"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewSLSAProvenance(t *testing.T) {
+	mgr, _, _, tempDir := setupTestManager(t)
+	defer os.RemoveAll(tempDir)
+
+	started := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+	meta := &buildMetadata{ID: "build-1", StartedAt: &started}
+	req := CreateBuildRequest{
+		Dockerfile: "FROM alpine",
+		BuildArgs:  map[string]string{"VERSION": "1"},
+		Secrets:    []SecretRef{{ID: "npm_token"}},
+	}
+	policy := DefaultBuildPolicy()
+	result := &BuildResult{
+		ImageDigest: "sha256:abc",
+		Provenance: BuildProvenance{
+			SourceHash:      "src",
+			BaseImageDigest: "sha256:base",
+			LockfileHashes:  map[string]string{"package-lock.json": "lock2", "go.sum": "lock1"},
+			BuildkitVersion: "v0.12.0",
+		},
+	}
+
+	data, err := mgr.newSLSAProvenance(meta, req, &policy, result, started.Add(time.Minute))
+	require.NoError(t, err)
+
+	var statement slsaStatement
+	require.NoError(t, json.Unmarshal(data, &statement))
+	assert.Equal(t, "https://slsa.dev/provenance/v1", statement.PredicateType)
+	assert.Equal(t, []slsaSubject{{Name: "localhost:5000/builds/build-1", Digest: map[string]string{"sha256": "abc"}}}, statement.Subject)
+
+	def := statement.Predicate.BuildDefinition
+	assert.Equal(t, slsaBuildType, def.BuildType)
+	assert.Equal(t, []any{"npm_token"}, def.ExternalParameters["secret_ids"])
+	assert.Contains(t, def.ExternalParameters, "dockerfile_sha256")
+	assert.NotContains(t, string(data), "FROM alpine", "the Dockerfile is recorded by hash only")
+
+	var names []string
+	for _, d := range def.ResolvedDependencies {
+		names = append(names, d.Name)
+	}
+	assert.Equal(t, []string{"source", "base_image", "go.sum", "package-lock.json"}, names)
+
+	assert.Equal(t, "build-1", statement.Predicate.RunDetails.Metadata.InvocationID)
+	assert.Equal(t, "v0.12.0", statement.Predicate.RunDetails.Builder.Version["buildkit"])
+
+	t.Run("artifact builds attest the tarball", func(t *testing.T) {
+		req := CreateBuildRequest{ArtifactPath: "/app/dist"}
+		result := &BuildResult{ArtifactSHA256: "def"}
+		data, err := mgr.newSLSAProvenance(meta, req, &policy, result, time.Now())
+		require.NoError(t, err)
+		require.NoError(t, json.Unmarshal(data, &statement))
+		assert.Equal(t, []slsaSubject{{Name: "dist.tar.gz", Digest: map[string]string{"sha256": "def"}}}, statement.Subject)
+	})
+}
+
+func TestRecordAttestations(t *testing.T) {
+	mgr, _, _, tempDir := setupTestManager(t)
+	defer os.RemoveAll(tempDir)
+	ctx := context.Background()
+
+	require.NoError(t, writeMetadata(mgr.paths, &buildMetadata{ID: "build-1", Status: StatusBuilding}))
+	sbom := json.RawMessage(`{"spdxVersion":"SPDX-2.3"}`)
+	policy := DefaultBuildPolicy()
+	mgr.recordAttestations(ctx, "build-1", CreateBuildRequest{}, &policy, &BuildResult{ImageDigest: "sha256:abc", SBOM: sbom})
+
+	build, err := mgr.GetBuild(ctx, "build-1")
+	require.NoError(t, err)
+	require.NotNil(t, build.SBOM)
+	require.NotNil(t, build.SLSAProvenance)
+	assert.Equal(t, SBOMMediaType, build.SBOM.MediaType)
+	assert.Equal(t, int64(len(sbom)), build.SBOM.SizeBytes)
+	assert.Nil(t, build.SBOM.ReferrerDigest, "attestations aren't pushed unless configured")
+
+	rc, doc, err := mgr.GetBuildDocument(ctx, "build-1", DocumentSBOM)
+	require.NoError(t, err)
+	defer rc.Close()
+	data, err := io.ReadAll(rc)
+	require.NoError(t, err)
+	assert.JSONEq(t, string(sbom), string(data))
+	assert.Equal(t, build.SBOM, doc)
+
+	rc, doc, err = mgr.GetBuildDocument(ctx, "build-1", DocumentSLSAProvenance)
+	require.NoError(t, err)
+	rc.Close()
+	assert.Equal(t, SLSAProvenanceMediaType, doc.MediaType)
+}
+
+func TestGetBuildDocument_Missing(t *testing.T) {
+	mgr, _, _, tempDir := setupTestManager(t)
+	defer os.RemoveAll(tempDir)
+	ctx := context.Background()
+
+	_, _, err := mgr.GetBuildDocument(ctx, "missing", DocumentSBOM)
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	require.NoError(t, writeMetadata(mgr.paths, &buildMetadata{ID: "build-1", Status: StatusReady}))
+	_, _, err = mgr.GetBuildDocument(ctx, "build-1", DocumentSBOM)
+	assert.ErrorIs(t, err, ErrNoDocument)
+}
+
+func TestPushReferrer(t *testing.T) {
+	mgr, _, _, tempDir := setupTestManager(t)
+	defer os.RemoveAll(tempDir)
+
+	srv := httptest.NewServer(registry.New(registry.WithReferrersSupport(true)))
+	defer srv.Close()
+	mgr.config.RegistryURL = strings.TrimPrefix(srv.URL, "http://")
+
+	repo, err := name.NewRepository(mgr.config.RegistryURL+"/builds/build-1", name.Insecure)
+	require.NoError(t, err)
+	img, err := random.Image(64, 1)
+	require.NoError(t, err)
+	imgDigest, err := img.Digest()
+	require.NoError(t, err)
+	require.NoError(t, remote.Write(repo.Digest(imgDigest.String()), img))
+
+	digest, err := mgr.pushReferrer(context.Background(), "build-1", imgDigest.String(), SBOMMediaType, []byte(`{}`))
+	require.NoError(t, err)
+
+	index, err := remote.Referrers(repo.Digest(imgDigest.String()))
+	require.NoError(t, err)
+	manifest, err := index.IndexManifest()
+	require.NoError(t, err)
+	require.Len(t, manifest.Manifests, 1)
+	assert.Equal(t, digest, manifest.Manifests[0].Digest.String())
+	assert.Equal(t, SBOMMediaType, manifest.Manifests[0].ArtifactType)
+}
@@ -2,6 +2,10 @@
 // It reads build configuration from the config disk, runs BuildKit to build
 // the image, and reports results back to the host via vsock.
 //
+// Without a config disk the agent belongs to the warm builder pool: it waits
+// for the host to send a build_job with the config and source tarball, and
+// after reporting the result it cleans up and waits for the next one.
+//
 // Communication model:
 // - Agent LISTENS on vsock port 5001
 // - Host CONNECTS to the agent via the VM's vsock.sock file
@@ -9,8 +13,10 @@
 package main
 
 import (
+	"archive/tar"
 	"bufio"
 	"bytes"
+	"compress/gzip"
 	"context"
 	"crypto/sha256"
 	"encoding/base64"
@@ -33,21 +39,40 @@ import (
 const (
 	configPath = "/config/build.json"
 	vsockPort  = 5001 // Build agent port (different from exec agent)
+
+	// Artifact builds export the built filesystem here and pack the
+	// requested path into artifactPath
+	outputDir    = "/tmp/output"
+	artifactPath = "/tmp/artifact.tar.gz"
+
+	// artifactChunkSize is the size of the chunks artifacts are uploaded in
+	artifactChunkSize = 1 << 20
+
+	// maxSBOMBytes caps the SBOM sent back with the build result
+	maxSBOMBytes = 16 << 20
+
+	// sbomTimeout bounds SBOM generation, which never fails a build
+	sbomTimeout = 5 * time.Minute
 )
 
 // BuildConfig matches the BuildConfig type from lib/builds/types.go
 type BuildConfig struct {
-	JobID           string            `json:"job_id"`
-	BaseImageDigest string            `json:"base_image_digest,omitempty"`
-	RegistryURL     string            `json:"registry_url"`
-	RegistryToken   string            `json:"registry_token,omitempty"`
-	CacheScope      string            `json:"cache_scope,omitempty"`
-	SourcePath      string            `json:"source_path"`
-	Dockerfile      string            `json:"dockerfile,omitempty"`
-	BuildArgs       map[string]string `json:"build_args,omitempty"`
-	Secrets         []SecretRef       `json:"secrets,omitempty"`
-	TimeoutSeconds  int               `json:"timeout_seconds"`
-	NetworkMode     string            `json:"network_mode"`
+	JobID            string            `json:"job_id"`
+	BaseImageDigest  string            `json:"base_image_digest,omitempty"`
+	RegistryURL      string            `json:"registry_url"`
+	RegistryToken    string            `json:"registry_token,omitempty"`
+	RegistryMirrors  []string          `json:"registry_mirrors,omitempty"`
+	CacheScope       string            `json:"cache_scope,omitempty"`
+	SourcePath       string            `json:"source_path"`
+	Dockerfile       string            `json:"dockerfile,omitempty"`
+	BuildArgs        map[string]string `json:"build_args,omitempty"`
+	Secrets          []SecretRef       `json:"secrets,omitempty"`
+	TimeoutSeconds   int               `json:"timeout_seconds"`
+	NetworkMode      string            `json:"network_mode"`
+	AllowedHosts     []string          `json:"allowed_hosts,omitempty"`
+	PackageCachePath string            `json:"package_cache_path,omitempty"`
+	ArtifactPath     string            `json:"artifact_path,omitempty"`
+	ArtifactMaxBytes int64             `json:"artifact_max_bytes,omitempty"`
 }
 
 // SecretRef references a secret to inject during build
@@ -64,6 +89,11 @@ type BuildResult struct {
 	Logs        string          `json:"logs,omitempty"`
 	Provenance  BuildProvenance `json:"provenance"`
 	DurationMS  int64           `json:"duration_ms"`
+
+	ArtifactBytes  int64  `json:"artifact_bytes,omitempty"`
+	ArtifactSHA256 string `json:"artifact_sha256,omitempty"`
+
+	SBOM json.RawMessage `json:"sbom,omitempty"`
 }
 
 // BuildProvenance records build inputs
@@ -82,25 +112,52 @@ type VsockMessage struct {
 	Log       string            `json:"log,omitempty"`
 	SecretIDs []string          `json:"secret_ids,omitempty"` // For secrets request to host
 	Secrets   map[string]string `json:"secrets,omitempty"`    // For secrets response from host
+	Job       *BuildConfig      `json:"job,omitempty"`        // For build_job from host (pooled builders)
+	Source    []byte            `json:"source,omitempty"`     // Source tarball for build_job
+	Data      []byte            `json:"data,omitempty"`       // Artifact tarball chunk to host
 }
 
-// Global state for the result to send when host connects
-var (
-	buildResult     *BuildResult
-	buildResultLock sync.Mutex
-	buildDone       = make(chan struct{})
+// job is one build. An agent with a config disk runs a single job; a pooled
+// agent runs the jobs the host sends, one at a time.
+type job struct {
+	config     *BuildConfig
+	configLock sync.Mutex
+
+	result     *BuildResult
+	resultLock sync.Mutex
+	done       chan struct{}
 
 	// Secrets coordination
-	buildConfig     *BuildConfig
-	buildConfigLock sync.Mutex
-	secretsReady    = make(chan struct{})
-	secretsOnce     sync.Once
+	secretsReady chan struct{}
+	secretsOnce  sync.Once
+}
+
+func newJob() *job {
+	return &job{
+		done:         make(chan struct{}),
+		secretsReady: make(chan struct{}),
+	}
+}
+
+var (
+	// currentJob is the job the host is told about, nil while a pooled agent is idle
+	currentJob *job
+	jobLock    sync.Mutex
+
+	// pooled is set when the agent was booted without a config disk
+	pooled bool
 
 	// Encoder lock protects concurrent access to json.Encoder
 	// (the goroutine sending build_result and the main loop handling get_status)
 	encoderLock sync.Mutex
 )
 
+func getCurrentJob() *job {
+	jobLock.Lock()
+	defer jobLock.Unlock()
+	return currentJob
+}
+
 func main() {
 	log.Println("=== Builder Agent Starting ===")
 
@@ -115,8 +172,16 @@ func main() {
 	defer listener.Close()
 	log.Printf("Listening on vsock port %d", vsockPort)
 
-	// Run the build in background
-	go runBuildProcess()
+	if _, err := os.Stat(configPath); os.IsNotExist(err) {
+		// No config disk: wait for the host to send jobs
+		pooled = true
+		log.Println("No config disk, waiting for a build job from the host")
+	} else {
+		// Run the build in background
+		j := newJob()
+		currentJob = j
+		go runBuildProcess(j, loadConfig)
+	}
 
 	// Accept connections from host
 	for {
@@ -197,24 +262,56 @@ func handleHostConnection(conn net.Conn) {
 		}
 
 		switch msg.Type {
+		case "build_job":
+			// Host is handing a pooled builder its next build
+			if err := startPooledJob(msg.Job, msg.Source); err != nil {
+				log.Printf("Rejected build job: %v", err)
+				encoderLock.Lock()
+				encoder.Encode(VsockMessage{Type: "build_result", Result: &BuildResult{
+					Success: false,
+					Error:   fmt.Sprintf("start build job: %v", err),
+				}})
+				encoderLock.Unlock()
+				return
+			}
+
 		case "host_ready":
+			j := getCurrentJob()
+			if j == nil {
+				log.Printf("Host is ready but no build job was sent")
+				continue
+			}
+
 			// Host is ready to handle requests
 			// Request secrets if we have any configured
-			if err := handleSecretsRequest(encoder, decoder); err != nil {
+			if err := handleSecretsRequest(j, encoder, decoder); err != nil {
 				log.Printf("Failed to fetch secrets: %v", err)
 			}
 			// Signal that secrets are ready (even if failed, build can proceed)
-			secretsOnce.Do(func() {
-				close(secretsReady)
+			j.secretsOnce.Do(func() {
+				close(j.secretsReady)
 			})
 
 			// Wait for build to complete and send result to host
 			go func() {
-				<-buildDone
+				<-j.done
+
+				j.resultLock.Lock()
+				result := j.result
+				j.resultLock.Unlock()
 
-				buildResultLock.Lock()
-				result := buildResult
-				buildResultLock.Unlock()
+				// Artifacts are uploaded ahead of the result
+				if result.Success && result.ArtifactBytes > 0 {
+					if err := sendArtifact(encoder); err != nil {
+						log.Printf("Failed to upload artifact: %v", err)
+					}
+				}
+
+				// A pooled agent is ready for the next job before the host
+				// learns the result, so the host can hand it one right away
+				if pooled {
+					finishPooledJob(j)
+				}
 
 				log.Printf("Build completed, sending result to host")
 				encoderLock.Lock()
@@ -226,13 +323,21 @@ func handleHostConnection(conn net.Conn) {
 			}()
 
 		case "get_result":
+			j := getCurrentJob()
+			if j == nil {
+				encoderLock.Lock()
+				encoder.Encode(VsockMessage{Type: "status", Log: "idle"})
+				encoderLock.Unlock()
+				return
+			}
+
 			// Host is asking for the build result
 			// Wait for build to complete if not done yet
-			<-buildDone
+			<-j.done
 
-			buildResultLock.Lock()
-			result := buildResult
-			buildResultLock.Unlock()
+			j.resultLock.Lock()
+			result := j.result
+			j.resultLock.Unlock()
 
 			response := VsockMessage{
 				Type:   "build_result",
@@ -248,12 +353,17 @@ func handleHostConnection(conn net.Conn) {
 
 		case "get_status":
 			// Host is checking if build is still running
+			j := getCurrentJob()
 			encoderLock.Lock()
-			select {
-			case <-buildDone:
-				encoder.Encode(VsockMessage{Type: "status", Log: "completed"})
-			default:
-				encoder.Encode(VsockMessage{Type: "status", Log: "building"})
+			if j == nil {
+				encoder.Encode(VsockMessage{Type: "status", Log: "idle"})
+			} else {
+				select {
+				case <-j.done:
+					encoder.Encode(VsockMessage{Type: "status", Log: "completed"})
+				default:
+					encoder.Encode(VsockMessage{Type: "status", Log: "building"})
+				}
 			}
 			encoderLock.Unlock()
 
@@ -263,14 +373,125 @@ func handleHostConnection(conn net.Conn) {
 	}
 }
 
+// startPooledJob prepares the source of a job sent by the host and starts
+// building it
+func startPooledJob(config *BuildConfig, source []byte) error {
+	if !pooled {
+		return fmt.Errorf("agent was started with a config disk")
+	}
+	if config == nil {
+		return fmt.Errorf("build job has no config")
+	}
+
+	jobLock.Lock()
+	defer jobLock.Unlock()
+	if currentJob != nil {
+		return fmt.Errorf("agent is busy with job %s", currentJob.config.JobID)
+	}
+
+	if err := os.RemoveAll(config.SourcePath); err != nil {
+		return fmt.Errorf("clear source dir: %w", err)
+	}
+	if err := extractSource(source, config.SourcePath); err != nil {
+		return fmt.Errorf("extract source: %w", err)
+	}
+
+	j := newJob()
+	// Set the config up front so the busy check above can name the job
+	j.config = config
+	currentJob = j
+	go runBuildProcess(j, func() (*BuildConfig, error) { return config, nil })
+	return nil
+}
+
+// finishPooledJob removes what a job left behind so the next job starts
+// from a clean slate, and marks the agent idle
+func finishPooledJob(j *job) {
+	for _, path := range []string{
+		j.config.SourcePath,
+		"/run/secrets",
+		"/home/builder/.docker",
+		"/tmp/build-metadata.json",
+		outputDir,
+		artifactPath,
+	} {
+		if err := os.RemoveAll(path); err != nil {
+			log.Printf("Failed to clean up %s: %v", path, err)
+		}
+	}
+	log.SetOutput(os.Stdout)
+
+	jobLock.Lock()
+	currentJob = nil
+	jobLock.Unlock()
+	log.Printf("Job %s finished, waiting for the next one", j.config.JobID)
+}
+
+// extractSource unpacks a gzipped source tarball into dir
+func extractSource(data []byte, dir string) error {
+	gz, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	defer gz.Close()
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		target := filepath.Join(dir, hdr.Name)
+		if target != dir && !strings.HasPrefix(target, dir+string(os.PathSeparator)) {
+			return fmt.Errorf("invalid path in archive: %s", hdr.Name)
+		}
+
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(target, 0755); err != nil {
+				return err
+			}
+		case tar.TypeReg:
+			if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+				return err
+			}
+			f, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, os.FileMode(hdr.Mode)&0777)
+			if err != nil {
+				return err
+			}
+			_, err = io.Copy(f, tr)
+			f.Close()
+			if err != nil {
+				return err
+			}
+		case tar.TypeSymlink:
+			if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+				return err
+			}
+			if err := os.Symlink(hdr.Linkname, target); err != nil {
+				return err
+			}
+		default:
+			log.Printf("Skipping unsupported archive entry %s (type %c)", hdr.Name, hdr.Typeflag)
+		}
+	}
+}
+
 // handleSecretsRequest requests secrets from the host and writes them to /run/secrets/
-func handleSecretsRequest(encoder *json.Encoder, decoder *json.Decoder) error {
+func handleSecretsRequest(j *job, encoder *json.Encoder, decoder *json.Decoder) error {
 	// Wait for config to be loaded
 	var config *BuildConfig
 	for i := 0; i < 30; i++ {
-		buildConfigLock.Lock()
-		config = buildConfig
-		buildConfigLock.Unlock()
+		j.configLock.Lock()
+		config = j.config
+		j.configLock.Unlock()
 		if config != nil {
 			break
 		}
@@ -335,7 +556,7 @@ func handleSecretsRequest(encoder *json.Encoder, decoder *json.Decoder) error {
 }
 
 // runBuildProcess runs the actual build and stores the result
-func runBuildProcess() {
+func runBuildProcess(j *job, load func() (*BuildConfig, error)) {
 	start := time.Now()
 	var logs bytes.Buffer
 	logWriter := io.MultiWriter(os.Stdout, &logs)
@@ -343,13 +564,13 @@ func runBuildProcess() {
 	log.SetOutput(logWriter)
 
 	defer func() {
-		close(buildDone)
+		close(j.done)
 	}()
 
 	// Load build config
-	config, err := loadConfig()
+	config, err := load()
 	if err != nil {
-		setResult(BuildResult{
+		j.setResult(BuildResult{
 			Success:    false,
 			Error:      fmt.Sprintf("load config: %v", err),
 			Logs:       logs.String(),
@@ -359,14 +580,14 @@ func runBuildProcess() {
 	}
 	log.Printf("Job: %s", config.JobID)
 
-	// Store config globally so handleHostConnection can access it for secrets
-	buildConfigLock.Lock()
-	buildConfig = config
-	buildConfigLock.Unlock()
+	// Store config on the job so handleHostConnection can access it for secrets
+	j.configLock.Lock()
+	j.config = config
+	j.configLock.Unlock()
 
 	// Setup registry authentication before running the build
 	if err := setupRegistryAuth(config.RegistryURL, config.RegistryToken); err != nil {
-		setResult(BuildResult{
+		j.setResult(BuildResult{
 			Success:    false,
 			Error:      fmt.Sprintf("setup registry auth: %v", err),
 			Logs:       logs.String(),
@@ -375,6 +596,17 @@ func runBuildProcess() {
 		return
 	}
 
+	// Pull base images through the registry's pull-through cache
+	if err := setupRegistryMirrors(config.RegistryURL, config.RegistryMirrors); err != nil {
+		j.setResult(BuildResult{
+			Success:    false,
+			Error:      fmt.Sprintf("setup registry mirrors: %v", err),
+			Logs:       logs.String(),
+			DurationMS: time.Since(start).Milliseconds(),
+		})
+		return
+	}
+
 	// Setup timeout context
 	ctx := context.Background()
 	if config.TimeoutSeconds > 0 {
@@ -383,20 +615,37 @@ func runBuildProcess() {
 		defer cancel()
 	}
 
+	// With restricted egress, wait until the host has applied the
+	// restriction, which it does before sending host_ready
+	if config.NetworkMode == "offline" || len(config.AllowedHosts) > 0 {
+		log.Printf("Waiting for the host to restrict egress...")
+		select {
+		case <-j.secretsReady:
+		case <-ctx.Done():
+			j.setResult(BuildResult{
+				Success:    false,
+				Error:      "build timeout while waiting for the host",
+				Logs:       logs.String(),
+				DurationMS: time.Since(start).Milliseconds(),
+			})
+			return
+		}
+	}
+
 	// Wait for secrets if any are configured
 	if len(config.Secrets) > 0 {
 		log.Printf("Waiting for secrets from host...")
 		select {
-		case <-secretsReady:
+		case <-j.secretsReady:
 			log.Printf("Secrets ready, proceeding with build")
 		case <-time.After(30 * time.Second):
 			log.Printf("Warning: Timeout waiting for secrets, proceeding anyway")
 			// Signal secrets ready to avoid blocking other goroutines
-			secretsOnce.Do(func() {
-				close(secretsReady)
+			j.secretsOnce.Do(func() {
+				close(j.secretsReady)
 			})
 		case <-ctx.Done():
-			setResult(BuildResult{
+			j.setResult(BuildResult{
 				Success:    false,
 				Error:      "build timeout while waiting for secrets",
 				Logs:       logs.String(),
@@ -411,7 +660,7 @@ func runBuildProcess() {
 	if _, err := os.Stat(dockerfilePath); os.IsNotExist(err) {
 		// Check if Dockerfile was provided in config
 		if config.Dockerfile == "" {
-			setResult(BuildResult{
+			j.setResult(BuildResult{
 				Success:    false,
 				Error:      "Dockerfile required: provide dockerfile parameter or include Dockerfile in source tarball",
 				Logs:       logs.String(),
@@ -421,7 +670,7 @@ func runBuildProcess() {
 		}
 		// Write provided Dockerfile to source directory
 		if err := os.WriteFile(dockerfilePath, []byte(config.Dockerfile), 0644); err != nil {
-			setResult(BuildResult{
+			j.setResult(BuildResult{
 				Success:    false,
 				Error:      fmt.Sprintf("write dockerfile: %v", err),
 				Logs:       logs.String(),
@@ -445,7 +694,7 @@ func runBuildProcess() {
 	duration := time.Since(start).Milliseconds()
 
 	if err != nil {
-		setResult(BuildResult{
+		j.setResult(BuildResult{
 			Success:    false,
 			Error:      err.Error(),
 			Logs:       logs.String(),
@@ -455,24 +704,202 @@ func runBuildProcess() {
 		return
 	}
 
-	// Success!
-	log.Printf("=== Build Complete: %s ===", digest)
+	// Pack the artifact of artifact builds
+	var artifactBytes int64
+	var artifactSHA256 string
+	if config.ArtifactPath != "" {
+		artifactBytes, artifactSHA256, err = packArtifact(config)
+		if err != nil {
+			j.setResult(BuildResult{
+				Success:    false,
+				Error:      fmt.Sprintf("pack artifact: %v", err),
+				Logs:       logs.String(),
+				Provenance: provenance,
+				DurationMS: duration,
+			})
+			return
+		}
+		log.Printf("=== Build Complete: artifact %s (%d bytes) ===", config.ArtifactPath, artifactBytes)
+	} else {
+		// Success!
+		log.Printf("=== Build Complete: %s ===", digest)
+	}
 	provenance.Timestamp = time.Now()
 
-	setResult(BuildResult{
-		Success:     true,
-		ImageDigest: digest,
-		Logs:        logs.String(),
-		Provenance:  provenance,
-		DurationMS:  duration,
+	sbom := generateSBOM(config, digest)
+
+	j.setResult(BuildResult{
+		Success:        true,
+		ImageDigest:    digest,
+		Logs:           logs.String(),
+		Provenance:     provenance,
+		DurationMS:     duration,
+		ArtifactBytes:  artifactBytes,
+		ArtifactSHA256: artifactSHA256,
+		SBOM:           sbom,
 	})
 }
 
+// generateSBOM catalogs the packages of the built image, or of the artifact
+// path of artifact builds, as an SPDX JSON document. A missing or failing
+// syft only costs the SBOM, not the build.
+func generateSBOM(config *BuildConfig, digest string) json.RawMessage {
+	if _, err := exec.LookPath("syft"); err != nil {
+		log.Println("syft not found, skipping SBOM")
+		return nil
+	}
+
+	target := fmt.Sprintf("registry:%s/builds/%s@%s", config.RegistryURL, config.JobID, digest)
+	if config.ArtifactPath != "" {
+		target = "dir:" + filepath.Join(outputDir, config.ArtifactPath)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), sbomTimeout)
+	defer cancel()
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, "syft", "scan", target, "-o", "spdx-json", "-q")
+	cmd.Stderr = &stderr
+	cmd.Env = append(os.Environ(),
+		"SYFT_REGISTRY_INSECURE_USE_HTTP=true",
+		"SYFT_CHECK_FOR_APP_UPDATE=false",
+	)
+	out, err := cmd.Output()
+	switch {
+	case err != nil:
+		log.Printf("Warning: SBOM generation failed: %v: %s", err, strings.TrimSpace(stderr.String()))
+		return nil
+	case len(out) > maxSBOMBytes:
+		log.Printf("Warning: SBOM is %d bytes, over the %d byte limit; skipping it", len(out), maxSBOMBytes)
+		return nil
+	case !json.Valid(out):
+		log.Println("Warning: syft produced invalid JSON, skipping SBOM")
+		return nil
+	}
+	log.Printf("SBOM generated (%d bytes)", len(out))
+	return out
+}
+
+// packArtifact packs the artifact path of the exported filesystem into a
+// gzipped tarball, failing once it grows past the artifact size limit
+func packArtifact(config *BuildConfig) (int64, string, error) {
+	src := filepath.Join(outputDir, config.ArtifactPath)
+	if src != outputDir && !strings.HasPrefix(src, outputDir+string(os.PathSeparator)) {
+		return 0, "", fmt.Errorf("invalid artifact path %s", config.ArtifactPath)
+	}
+	info, err := os.Lstat(src)
+	if err != nil {
+		return 0, "", fmt.Errorf("artifact path %s not found in build output", config.ArtifactPath)
+	}
+
+	f, err := os.Create(artifactPath)
+	if err != nil {
+		return 0, "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	cw := &limitWriter{w: io.MultiWriter(f, h), max: config.ArtifactMaxBytes}
+	gz := gzip.NewWriter(cw)
+	tw := tar.NewWriter(gz)
+
+	// A directory is packed with its contents at the top level, a file on its own
+	root := src
+	if !info.IsDir() {
+		root = filepath.Dir(src)
+	}
+	err = filepath.Walk(src, func(path string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil || rel == "." {
+			return err
+		}
+		link := ""
+		if fi.Mode()&os.ModeSymlink != 0 {
+			if link, err = os.Readlink(path); err != nil {
+				return err
+			}
+		}
+		hdr, err := tar.FileInfoHeader(fi, link)
+		if err != nil {
+			return err
+		}
+		hdr.Name = filepath.ToSlash(rel)
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		if !fi.Mode().IsRegular() {
+			return nil
+		}
+		in, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer in.Close()
+		_, err = io.Copy(tw, in)
+		return err
+	})
+	if err == nil {
+		err = tw.Close()
+	}
+	if err == nil {
+		err = gz.Close()
+	}
+	if err != nil {
+		return 0, "", err
+	}
+	return cw.n, hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// limitWriter fails writes once more than max bytes were written (0 = no limit)
+type limitWriter struct {
+	w   io.Writer
+	max int64
+	n   int64
+}
+
+func (l *limitWriter) Write(p []byte) (int, error) {
+	l.n += int64(len(p))
+	if l.max > 0 && l.n > l.max {
+		return 0, fmt.Errorf("artifact is larger than the %d byte limit", l.max)
+	}
+	return l.w.Write(p)
+}
+
+// sendArtifact uploads the packed artifact to the host in chunks
+func sendArtifact(encoder *json.Encoder) error {
+	f, err := os.Open(artifactPath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	buf := make([]byte, artifactChunkSize)
+	for {
+		n, err := f.Read(buf)
+		if n > 0 {
+			encoderLock.Lock()
+			sendErr := encoder.Encode(VsockMessage{Type: "artifact_chunk", Data: buf[:n]})
+			encoderLock.Unlock()
+			if sendErr != nil {
+				return sendErr
+			}
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
 // setResult stores the build result for the host to retrieve
-func setResult(result BuildResult) {
-	buildResultLock.Lock()
-	defer buildResultLock.Unlock()
-	buildResult = &result
+func (j *job) setResult(result BuildResult) {
+	j.resultLock.Lock()
+	defer j.resultLock.Unlock()
+	j.result = &result
 }
 
 func loadConfig() (*BuildConfig, error) {
@@ -530,11 +957,43 @@ func setupRegistryAuth(registryURL, token string) error {
 	return nil
 }
 
+// setupRegistryMirrors writes a buildkitd.toml mirroring each upstream
+// registry to the host registry's pull-through cache. BuildKit falls back to
+// the upstream itself when the mirror fails.
+func setupRegistryMirrors(registryURL string, upstreams []string) error {
+	if len(upstreams) == 0 {
+		return nil
+	}
+
+	var cfg strings.Builder
+	for _, upstream := range upstreams {
+		fmt.Fprintf(&cfg, "[registry.%q]\n  mirrors = [%q]\n\n", upstream, registryURL+"/proxy/"+upstream)
+	}
+	// The host registry is served over plain HTTP
+	fmt.Fprintf(&cfg, "[registry.%q]\n  http = true\n  insecure = true\n", registryURL)
+
+	// Rootless buildkitd reads its config from $XDG_CONFIG_HOME/buildkit
+	configDir := "/home/builder/.config/buildkit"
+	if err := os.MkdirAll(configDir, 0700); err != nil {
+		return fmt.Errorf("create buildkit config dir: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(configDir, "buildkitd.toml"), []byte(cfg.String()), 0600); err != nil {
+		return fmt.Errorf("write buildkit config: %w", err)
+	}
+
+	log.Printf("Registry mirrors configured for %s", strings.Join(upstreams, ", "))
+	return nil
+}
+
 func runBuild(ctx context.Context, config *BuildConfig, logWriter io.Writer) (string, string, error) {
 	var buildLogs bytes.Buffer
 
-	// Build output reference
-	outputRef := fmt.Sprintf("%s/builds/%s", config.RegistryURL, config.JobID)
+	// Build output: push an image, or export the filesystem for artifact builds
+	output := fmt.Sprintf("type=local,dest=%s", outputDir)
+	if config.ArtifactPath == "" {
+		outputRef := fmt.Sprintf("%s/builds/%s", config.RegistryURL, config.JobID)
+		output = fmt.Sprintf("type=image,name=%s,push=true,registry.insecure=true,oci-mediatypes=true", outputRef)
+	}
 
 	// Build arguments
 	// Use registry.insecure=true for internal HTTP registries
@@ -543,10 +1002,17 @@ func runBuild(ctx context.Context, config *BuildConfig, logWriter io.Writer) (st
 		"--frontend", "dockerfile.v0",
 		"--local", "context=" + config.SourcePath,
 		"--local", "dockerfile=" + config.SourcePath,
-		"--output", fmt.Sprintf("type=image,name=%s,push=true,registry.insecure=true,oci-mediatypes=true", outputRef),
+		"--output", output,
 		"--metadata-file", "/tmp/build-metadata.json",
 	}
 
+	// Offline builds reach the package cache as the "packages" context,
+	// e.g. COPY --from=packages or RUN --mount=from=packages
+	if config.PackageCachePath != "" {
+		args = append(args, "--local", "packages="+config.PackageCachePath)
+		args = append(args, "--opt", "context:packages=local:packages")
+	}
+
 	// Add cache if scope is set
 	if config.CacheScope != "" {
 		cacheRef := fmt.Sprintf("%s/cache/%s", config.RegistryURL, config.CacheScope)
@@ -578,6 +1044,11 @@ func runBuild(ctx context.Context, config *BuildConfig, logWriter io.Writer) (st
 		return "", buildLogs.String(), fmt.Errorf("buildctl failed: %w", err)
 	}
 
+	// Artifact builds don't produce an image
+	if config.ArtifactPath != "" {
+		return "", buildLogs.String(), nil
+	}
+
 	// Extract digest from metadata
 	digest, err := extractDigest("/tmp/build-metadata.json")
 	if err != nil {
@@ -0,0 +1,157 @@
+package builds
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/netip"
+	"regexp"
+	"slices"
+	"strconv"
+
+	"github.com/kernel/hypeman/lib/network"
+)
+
+// PackageCachePath is where offline builders mount the package cache volume
+const PackageCachePath = "/packages"
+
+// hostnamePattern matches DNS names like "registry.npmjs.org"
+var hostnamePattern = regexp.MustCompile(`^([a-zA-Z0-9]([a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?\.)*[a-zA-Z0-9]([a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?$`)
+
+// egressHost is an allowlist entry: a hostname, IP or CIDR, with an
+// optional port
+type egressHost struct {
+	host   string       // Hostname, empty for IPs and CIDRs
+	prefix netip.Prefix // Addresses of IPs and CIDRs
+	port   uint16       // 0 = any port
+}
+
+// parseEgressHost parses an allowlist entry like "registry.npmjs.org",
+// "pypi.org:443", "151.101.0.0/16" or "[2a04:4e42::1]:443"
+func parseEgressHost(s string) (egressHost, error) {
+	if prefix, err := netip.ParsePrefix(s); err == nil {
+		return egressHost{prefix: prefix}, nil
+	}
+	if addr, err := netip.ParseAddr(s); err == nil {
+		return egressHost{prefix: netip.PrefixFrom(addr, addr.BitLen())}, nil
+	}
+
+	var h egressHost
+	host := s
+	if hostPart, portPart, err := net.SplitHostPort(s); err == nil {
+		port, err := strconv.ParseUint(portPart, 10, 16)
+		if err != nil || port == 0 {
+			return egressHost{}, fmt.Errorf("%w: invalid port in allowed host %q", ErrInvalidNetworkPolicy, s)
+		}
+		host, h.port = hostPart, uint16(port)
+	}
+	if addr, err := netip.ParseAddr(host); err == nil {
+		h.prefix = netip.PrefixFrom(addr, addr.BitLen())
+		return h, nil
+	}
+	if !hostnamePattern.MatchString(host) {
+		return egressHost{}, fmt.Errorf("%w: invalid allowed host %q: expected a hostname, IP or CIDR with an optional port", ErrInvalidNetworkPolicy, s)
+	}
+	h.host = host
+	return h, nil
+}
+
+// ParseEgressAllowlist validates an egress allowlist
+func ParseEgressAllowlist(hosts []string) error {
+	for _, host := range hosts {
+		if _, err := parseEgressHost(host); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validateNetworkPolicy checks a build's network mode, and that the hosts
+// it allows are within the server's allowlist
+func (m *manager) validateNetworkPolicy(policy *BuildPolicy) error {
+	switch policy.NetworkMode {
+	case NetworkModeIsolated, NetworkModeEgress, NetworkModeOffline:
+	default:
+		return fmt.Errorf("%w: unknown network mode %q", ErrInvalidNetworkPolicy, policy.NetworkMode)
+	}
+	if len(policy.AllowedDomains) > 0 && policy.NetworkMode != NetworkModeEgress {
+		return fmt.Errorf("%w: allowed hosts require the egress network mode", ErrInvalidNetworkPolicy)
+	}
+	if err := ParseEgressAllowlist(policy.AllowedDomains); err != nil {
+		return err
+	}
+	if len(m.config.EgressAllowlist) == 0 {
+		return nil
+	}
+	for _, host := range policy.AllowedDomains {
+		if !slices.Contains(m.config.EgressAllowlist, host) {
+			return fmt.Errorf("%w: %s is not in the server's egress allowlist", ErrInvalidNetworkPolicy, host)
+		}
+	}
+	return nil
+}
+
+// allowedHosts returns the hosts a build may reach besides the registry, and
+// whether its egress is restricted at all
+func (m *manager) allowedHosts(policy *BuildPolicy) ([]string, bool) {
+	switch policy.NetworkMode {
+	case NetworkModeOffline:
+		return nil, true
+	case NetworkModeEgress:
+		if len(policy.AllowedDomains) > 0 {
+			return policy.AllowedDomains, true
+		}
+		return m.config.EgressAllowlist, len(m.config.EgressAllowlist) > 0
+	}
+	return nil, false
+}
+
+// egressPolicy resolves what a builder may reach: the registry, and the
+// build's allowed hosts as they resolve now
+func (m *manager) egressPolicy(ctx context.Context, policy *BuildPolicy) (network.EgressPolicy, error) {
+	hosts, _ := m.allowedHosts(policy)
+	// DNS is only needed, and can only leak data, when hosts are reachable
+	egress := network.EgressPolicy{AllowDNS: policy.NetworkMode == NetworkModeEgress}
+	for _, entry := range append([]string{m.config.RegistryURL}, hosts...) {
+		h, err := parseEgressHost(entry)
+		if err != nil {
+			return network.EgressPolicy{}, err
+		}
+		if h.host == "" {
+			egress.Rules = append(egress.Rules, network.EgressRule{Prefix: h.prefix, Port: h.port})
+			continue
+		}
+		addrs, err := net.DefaultResolver.LookupNetIP(ctx, "ip", h.host)
+		if err != nil {
+			return network.EgressPolicy{}, fmt.Errorf("resolve allowed host %s: %w", h.host, err)
+		}
+		for _, addr := range addrs {
+			addr = addr.Unmap()
+			egress.Rules = append(egress.Rules, network.EgressRule{Prefix: netip.PrefixFrom(addr, addr.BitLen()), Port: h.port})
+		}
+	}
+	return egress, nil
+}
+
+// restrictEgress applies a build's egress restriction to its builder, if it
+// has one, and returns the function lifting it
+func (m *manager) restrictEgress(ctx context.Context, instanceID string, policy *BuildPolicy) (func(), error) {
+	if _, restricted := m.allowedHosts(policy); !restricted {
+		return func() {}, nil
+	}
+	if m.networkManager == nil {
+		return nil, fmt.Errorf("restrict builder egress: no network manager")
+	}
+	egress, err := m.egressPolicy(ctx, policy)
+	if err != nil {
+		return nil, err
+	}
+	if err := m.networkManager.RestrictEgress(ctx, instanceID, egress); err != nil {
+		return nil, fmt.Errorf("restrict builder egress: %w", err)
+	}
+	return func() {
+		if err := m.networkManager.ClearEgress(context.Background(), instanceID); err != nil {
+			m.logger.Warn("failed to clear builder egress restriction", "instance", instanceID, "error", err)
+		}
+	}, nil
+}
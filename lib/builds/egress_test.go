@@ -0,0 +1,77 @@
+package builds
+
+import (
+	"context"
+	"net/netip"
+	"testing"
+
+	"github.com/kernel/hypeman/lib/network"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseEgressHost(t *testing.T) {
+	h, err := parseEgressHost("pypi.org:443")
+	require.NoError(t, err)
+	assert.Equal(t, egressHost{host: "pypi.org", port: 443}, h)
+
+	h, err = parseEgressHost("151.101.0.0/16")
+	require.NoError(t, err)
+	assert.Equal(t, netip.MustParsePrefix("151.101.0.0/16"), h.prefix)
+
+	h, err = parseEgressHost("[2a04:4e42::1]:443")
+	require.NoError(t, err)
+	assert.Equal(t, netip.MustParsePrefix("2a04:4e42::1/128"), h.prefix)
+	assert.Equal(t, uint16(443), h.port)
+
+	for _, bad := range []string{"", "https://pypi.org", "pypi.org:0", "pypi.org:http", "bad_host"} {
+		_, err := parseEgressHost(bad)
+		assert.ErrorIs(t, err, ErrInvalidNetworkPolicy, bad)
+	}
+}
+
+func TestValidateNetworkPolicy(t *testing.T) {
+	m := &manager{config: Config{EgressAllowlist: []string{"registry.npmjs.org", "pypi.org:443"}}}
+
+	assert.NoError(t, m.validateNetworkPolicy(&BuildPolicy{NetworkMode: NetworkModeEgress, AllowedDomains: []string{"pypi.org:443"}}))
+	assert.NoError(t, m.validateNetworkPolicy(&BuildPolicy{NetworkMode: NetworkModeOffline}))
+	assert.ErrorIs(t, m.validateNetworkPolicy(&BuildPolicy{NetworkMode: "open"}), ErrInvalidNetworkPolicy)
+	assert.ErrorIs(t, m.validateNetworkPolicy(&BuildPolicy{NetworkMode: NetworkModeEgress, AllowedDomains: []string{"github.com"}}), ErrInvalidNetworkPolicy)
+	assert.ErrorIs(t, m.validateNetworkPolicy(&BuildPolicy{NetworkMode: NetworkModeOffline, AllowedDomains: []string{"pypi.org:443"}}), ErrInvalidNetworkPolicy)
+
+	// Without a server allowlist, builds may allow any host
+	m = &manager{}
+	assert.NoError(t, m.validateNetworkPolicy(&BuildPolicy{NetworkMode: NetworkModeEgress, AllowedDomains: []string{"github.com"}}))
+}
+
+func TestAllowedHosts(t *testing.T) {
+	m := &manager{}
+	_, restricted := m.allowedHosts(&BuildPolicy{NetworkMode: NetworkModeEgress})
+	assert.False(t, restricted)
+	_, restricted = m.allowedHosts(&BuildPolicy{NetworkMode: NetworkModeIsolated})
+	assert.False(t, restricted)
+	hosts, restricted := m.allowedHosts(&BuildPolicy{NetworkMode: NetworkModeOffline})
+	assert.True(t, restricted)
+	assert.Empty(t, hosts)
+
+	m = &manager{config: Config{EgressAllowlist: []string{"registry.npmjs.org", "pypi.org"}}}
+	hosts, restricted = m.allowedHosts(&BuildPolicy{NetworkMode: NetworkModeEgress})
+	assert.True(t, restricted)
+	assert.Equal(t, []string{"registry.npmjs.org", "pypi.org"}, hosts)
+	hosts, _ = m.allowedHosts(&BuildPolicy{NetworkMode: NetworkModeEgress, AllowedDomains: []string{"pypi.org"}})
+	assert.Equal(t, []string{"pypi.org"}, hosts)
+}
+
+func TestEgressPolicy(t *testing.T) {
+	m := &manager{config: Config{RegistryURL: "10.100.0.1:8080"}}
+	egress, err := m.egressPolicy(context.Background(), &BuildPolicy{NetworkMode: NetworkModeOffline})
+	require.NoError(t, err)
+	assert.Equal(t, network.EgressPolicy{
+		Rules: []network.EgressRule{{Prefix: netip.MustParsePrefix("10.100.0.1/32"), Port: 8080}},
+	}, egress)
+
+	egress, err = m.egressPolicy(context.Background(), &BuildPolicy{NetworkMode: NetworkModeEgress, AllowedDomains: []string{"151.101.0.0/16"}})
+	require.NoError(t, err)
+	assert.True(t, egress.AllowDNS)
+	assert.Len(t, egress.Rules, 2)
+}
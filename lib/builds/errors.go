@@ -32,4 +32,21 @@ var (
 
 	// ErrBuildInProgress is returned when trying to cancel a build that's already complete
 	ErrBuildInProgress = errors.New("build in progress")
+
+	// ErrNoArtifact is returned when a build has no artifact to download
+	ErrNoArtifact = errors.New("build has no artifact")
+
+	// ErrNoDocument is returned when a build has no SBOM or SLSA provenance
+	ErrNoDocument = errors.New("build has no such document")
+
+	// ErrInvalidArtifactPath is returned when an artifact path is not an absolute path
+	ErrInvalidArtifactPath = errors.New("artifact_path must be an absolute path")
+
+	// ErrInvalidNetworkPolicy is returned for unknown network modes and
+	// allowed hosts that are malformed or outside BUILD_EGRESS_ALLOWLIST
+	ErrInvalidNetworkPolicy = errors.New("invalid network policy")
+
+	// ErrNotRetryable is returned when retrying a build that didn't fail or
+	// get cancelled, or whose source is gone
+	ErrNotRetryable = errors.New("build cannot be retried")
 )
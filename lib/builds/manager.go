@@ -4,8 +4,11 @@ import (
 	"bufio"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"log/slog"
+	"maps"
 	"net"
 	"os"
 	"os/exec"
@@ -17,6 +20,7 @@ import (
 	"github.com/nrednav/cuid2"
 	"github.com/kernel/hypeman/lib/images"
 	"github.com/kernel/hypeman/lib/instances"
+	"github.com/kernel/hypeman/lib/network"
 	"github.com/kernel/hypeman/lib/paths"
 	"github.com/kernel/hypeman/lib/volumes"
 	"go.opentelemetry.io/otel/metric"
@@ -40,6 +44,10 @@ type Manager interface {
 	// CancelBuild cancels a pending or running build
 	CancelBuild(ctx context.Context, id string) error
 
+	// RetryBuild re-runs a failed or cancelled build with its stored source
+	// and config, as a new build linked to it by RetryOf
+	RetryBuild(ctx context.Context, id string, req RetryBuildRequest) (*Build, error)
+
 	// GetBuildLogs returns the logs for a build
 	GetBuildLogs(ctx context.Context, id string) ([]byte, error)
 
@@ -50,6 +58,25 @@ type Manager interface {
 
 	// RecoverPendingBuilds recovers builds that were interrupted on restart
 	RecoverPendingBuilds()
+
+	// QueueState returns a snapshot of in-flight and queued builds for diagnostics
+	QueueState() QueueState
+
+	// PruneBuilds removes builds that finished before completedBefore
+	PruneBuilds(ctx context.Context, completedBefore time.Time, dryRun bool) ([]Pruned, error)
+
+	// PruneOrphanVolumes removes source and config volumes of builds that are no longer running
+	PruneOrphanVolumes(ctx context.Context, dryRun bool) ([]Pruned, error)
+
+	// GetBuildArtifact opens the artifact tarball of a finished artifact build
+	GetBuildArtifact(ctx context.Context, id string) (io.ReadCloser, *BuildArtifact, error)
+
+	// GetBuildDocument opens the SBOM or SLSA provenance of a finished build
+	GetBuildDocument(ctx context.Context, id string, kind DocumentKind) (io.ReadCloser, *BuildDocument, error)
+
+	// SetBuilderImage changes the image new builder VMs boot from. Running
+	// builds are unaffected; idle warm builders are replaced.
+	SetBuilderImage(image string)
 }
 
 // Config holds configuration for the build manager
@@ -70,6 +97,34 @@ type Config struct {
 	// RegistrySecret is the secret used to sign registry access tokens
 	// This should be the same secret used by the registry middleware
 	RegistrySecret string
+
+	// WarmPoolSize is the number of builder VMs kept booted ahead of
+	// demand (0 disables the warm pool)
+	WarmPoolSize int
+
+	// WarmPoolMaxUses is how many builds a warm builder runs before it is
+	// destroyed (default: 1, a fresh VM per build)
+	WarmPoolMaxUses int
+
+	// MaxArtifactBytes is the size limit of artifact tarballs (default: 1GB)
+	MaxArtifactBytes int64
+
+	// RegistryMirrors are the upstream registries the registry's
+	// pull-through cache serves; builds pull their images through it
+	RegistryMirrors []string
+
+	// PushAttestations pushes the SBOM and SLSA provenance of image builds
+	// to the registry as OCI referrers of the image
+	PushAttestations bool
+
+	// EgressAllowlist limits what egress builds can reach besides the
+	// registry, and bounds the hosts a build may allow itself (empty =
+	// unrestricted egress)
+	EgressAllowlist []string
+
+	// PackageCacheVolume is the volume mounted read-only at PackageCachePath
+	// in offline builds (empty = none)
+	PackageCacheVolume string
 }
 
 // DefaultConfig returns the default build manager configuration
@@ -79,6 +134,7 @@ func DefaultConfig() Config {
 		BuilderImage:        "hypeman/builder:latest",
 		RegistryURL:         "localhost:8080",
 		DefaultTimeout:      600, // 10 minutes
+		MaxArtifactBytes:    DefaultMaxArtifactBytes,
 	}
 }
 
@@ -88,11 +144,14 @@ type manager struct {
 	queue           *BuildQueue
 	instanceManager instances.Manager
 	volumeManager   volumes.Manager
+	networkManager  network.Manager
 	secretProvider  SecretProvider
 	tokenGenerator  *RegistryTokenGenerator
 	logger          *slog.Logger
 	metrics         *Metrics
+	pool            *builderPool
 	createMu        sync.Mutex
+	imageMu         sync.RWMutex // Guards config.BuilderImage, which can be reloaded
 
 	// Status subscription system for SSE streaming
 	statusSubscribers map[string][]chan BuildEvent
@@ -105,6 +164,7 @@ func NewManager(
 	config Config,
 	instanceMgr instances.Manager,
 	volumeMgr volumes.Manager,
+	networkMgr network.Manager,
 	secretProvider SecretProvider,
 	logger *slog.Logger,
 	meter metric.Meter,
@@ -119,11 +179,13 @@ func NewManager(
 		queue:             NewBuildQueue(config.MaxConcurrentBuilds),
 		instanceManager:   instanceMgr,
 		volumeManager:     volumeMgr,
+		networkManager:    networkMgr,
 		secretProvider:    secretProvider,
 		tokenGenerator:    NewRegistryTokenGenerator(config.RegistrySecret),
 		logger:            logger,
 		statusSubscribers: make(map[string][]chan BuildEvent),
 	}
+	m.pool = newBuilderPool(config, instanceMgr, logger, m.waitForAgent)
 
 	// Initialize metrics if meter is provided
 	if meter != nil {
@@ -145,14 +207,27 @@ func (m *manager) Start(ctx context.Context) error {
 	// Note: We no longer use a global vsock listener.
 	// Instead, we connect TO each builder VM's vsock socket directly.
 	// This follows the Cloud Hypervisor vsock pattern where host initiates connections.
+	if m.pool != nil {
+		go m.pool.run(ctx)
+		m.logger.Info("warm builder pool enabled", "size", m.pool.size, "max_uses", m.pool.maxUses)
+	}
 	m.logger.Info("build manager started")
 	return nil
 }
 
 // CreateBuild starts a new build job
 func (m *manager) CreateBuild(ctx context.Context, req CreateBuildRequest, sourceData []byte) (*Build, error) {
+	return m.createBuild(ctx, req, sourceData, nil)
+}
+
+// createBuild starts a new build job, recording the build it retries if any
+func (m *manager) createBuild(ctx context.Context, req CreateBuildRequest, sourceData []byte, retryOf *string) (*Build, error) {
 	m.logger.Info("creating build")
 
+	if req.ArtifactPath != "" && !filepath.IsAbs(req.ArtifactPath) {
+		return nil, ErrInvalidArtifactPath
+	}
+
 	// Apply defaults to build policy
 	policy := req.BuildPolicy
 	if policy == nil {
@@ -161,6 +236,9 @@ func (m *manager) CreateBuild(ctx context.Context, req CreateBuildRequest, sourc
 	} else {
 		policy.ApplyDefaults()
 	}
+	if err := m.validateNetworkPolicy(policy); err != nil {
+		return nil, err
+	}
 
 	m.createMu.Lock()
 	defer m.createMu.Unlock()
@@ -173,6 +251,7 @@ func (m *manager) CreateBuild(ctx context.Context, req CreateBuildRequest, sourc
 		ID:        id,
 		Status:    StatusQueued,
 		Request:   &req,
+		RetryOf:   retryOf,
 		CreatedAt: time.Now(),
 	}
 
@@ -209,6 +288,7 @@ func (m *manager) CreateBuild(ctx context.Context, req CreateBuildRequest, sourc
 		BaseImageDigest: req.BaseImageDigest,
 		RegistryURL:     m.config.RegistryURL,
 		RegistryToken:   registryToken,
+		RegistryMirrors: m.config.RegistryMirrors,
 		CacheScope:      req.CacheScope,
 		SourcePath:      "/src",
 		Dockerfile:      req.Dockerfile,
@@ -217,6 +297,16 @@ func (m *manager) CreateBuild(ctx context.Context, req CreateBuildRequest, sourc
 		TimeoutSeconds:  policy.TimeoutSeconds,
 		NetworkMode:     policy.NetworkMode,
 	}
+	if hosts, restricted := m.allowedHosts(policy); restricted {
+		buildConfig.AllowedHosts = hosts
+	}
+	if policy.NetworkMode == NetworkModeOffline && m.config.PackageCacheVolume != "" {
+		buildConfig.PackageCachePath = PackageCachePath
+	}
+	if req.ArtifactPath != "" {
+		buildConfig.ArtifactPath = req.ArtifactPath
+		buildConfig.ArtifactMaxBytes = m.config.MaxArtifactBytes
+	}
 	if err := writeBuildConfig(m.paths, id, buildConfig); err != nil {
 		deleteBuild(m.paths, id)
 		return nil, fmt.Errorf("write build config: %w", err)
@@ -266,6 +356,9 @@ func (m *manager) runBuild(ctx context.Context, id string, req CreateBuildReques
 	duration := time.Since(start)
 	durationMS := duration.Milliseconds()
 
+	if err != nil && errors.Is(buildCtx.Err(), context.DeadlineExceeded) {
+		err = fmt.Errorf("build timed out after %ds: %w", policy.TimeoutSeconds, err)
+	}
 	if err != nil {
 		m.logger.Error("build failed", "id", id, "error", err, "duration", duration)
 		errMsg := err.Error()
@@ -292,14 +385,33 @@ func (m *manager) runBuild(ctx context.Context, id string, req CreateBuildReques
 		return
 	}
 
-	m.logger.Info("build succeeded", "id", id, "digest", result.ImageDigest, "duration", duration)
-	imageRef := fmt.Sprintf("%s/builds/%s", m.config.RegistryURL, id)
-	m.updateBuildComplete(id, StatusReady, &result.ImageDigest, nil, &result.Provenance, &durationMS)
+	// Attestations are recorded before the build is marked ready, so
+	// they're available as soon as it is
+	m.recordAttestations(ctx, id, req, policy, result)
 
-	// Update with image ref
-	if meta, err := readMetadata(m.paths, id); err == nil {
-		meta.ImageRef = &imageRef
-		writeMetadata(m.paths, meta)
+	if req.ArtifactPath != "" {
+		m.logger.Info("build succeeded", "id", id, "artifact_bytes", result.ArtifactBytes, "duration", duration)
+
+		// Record the artifact before marking the build ready
+		if meta, err := readMetadata(m.paths, id); err == nil {
+			meta.Artifact = &BuildArtifact{
+				Path:      req.ArtifactPath,
+				SizeBytes: result.ArtifactBytes,
+				SHA256:    result.ArtifactSHA256,
+			}
+			writeMetadata(m.paths, meta)
+		}
+		m.updateBuildComplete(id, StatusReady, nil, nil, &result.Provenance, &durationMS)
+	} else {
+		m.logger.Info("build succeeded", "id", id, "digest", result.ImageDigest, "duration", duration)
+		imageRef := fmt.Sprintf("%s/builds/%s", m.config.RegistryURL, id)
+		m.updateBuildComplete(id, StatusReady, &result.ImageDigest, nil, &result.Provenance, &durationMS)
+
+		// Update with image ref
+		if meta, err := readMetadata(m.paths, id); err == nil {
+			meta.ImageRef = &imageRef
+			writeMetadata(m.paths, meta)
+		}
 	}
 
 	if m.metrics != nil {
@@ -309,6 +421,12 @@ func (m *manager) runBuild(ctx context.Context, id string, req CreateBuildReques
 
 // executeBuild runs the build in a builder VM
 func (m *manager) executeBuild(ctx context.Context, id string, req CreateBuildRequest, policy *BuildPolicy) (*BuildResult, error) {
+	if b := m.claimWarmBuilder(ctx, id, policy); b != nil {
+		result, err := m.executeWarmBuild(ctx, id, b, policy)
+		m.pool.release(context.Background(), b, err == nil)
+		return result, err
+	}
+
 	// Create a volume with the source data
 	sourceVolID := fmt.Sprintf("build-source-%s", id)
 	sourcePath := m.paths.BuildSourceDir(id) + "/source.tar.gz"
@@ -364,26 +482,37 @@ func (m *manager) executeBuild(ctx context.Context, id string, req CreateBuildRe
 
 	// Create builder instance
 	builderName := fmt.Sprintf("builder-%s", id)
-	networkEnabled := policy.NetworkMode == "egress"
+	// Offline builders still need the network to reach the registry
+	networkEnabled := policy.NetworkMode != NetworkModeIsolated
+
+	attachments := []instances.VolumeAttachment{
+		{
+			VolumeID:  sourceVolID,
+			MountPath: "/src",
+			Readonly:  false, // Builder needs to write generated Dockerfile
+		},
+		{
+			VolumeID:  configVolID,
+			MountPath: "/config",
+			Readonly:  true,
+		},
+	}
+	if policy.NetworkMode == NetworkModeOffline && m.config.PackageCacheVolume != "" {
+		attachments = append(attachments, instances.VolumeAttachment{
+			VolumeID:  m.config.PackageCacheVolume,
+			MountPath: PackageCachePath,
+			Readonly:  true,
+		})
+	}
 
 	inst, err := m.instanceManager.CreateInstance(ctx, instances.CreateInstanceRequest{
 		Name:           builderName,
-		Image:          m.config.BuilderImage,
+		Image:          m.builderImage(),
 		Size:           int64(policy.MemoryMB) * 1024 * 1024,
 		Vcpus:          policy.CPUs,
+		FixedMemory:    true, // Caps the builder at policy.MemoryMB
 		NetworkEnabled: networkEnabled,
-		Volumes: []instances.VolumeAttachment{
-			{
-				VolumeID:  sourceVolID,
-				MountPath: "/src",
-				Readonly:  false, // Builder needs to write generated Dockerfile
-			},
-			{
-				VolumeID:  configVolID,
-				MountPath: "/config",
-				Readonly:  true,
-			},
-		},
+		Volumes:        attachments,
 	})
 	if err != nil {
 		return nil, fmt.Errorf("create builder instance: %w", err)
@@ -397,12 +526,23 @@ func (m *manager) executeBuild(ctx context.Context, id string, req CreateBuildRe
 
 	// Ensure cleanup
 	defer func() {
-		m.instanceManager.DeleteInstance(context.Background(), inst.Id)
+		m.instanceManager.DeleteInstance(context.Background(), inst.Id, instances.DeleteInstanceRequest{})
 	}()
 
+	// Measured before the instance is deleted
+	defer func() { m.recordResources(id, nil, m.builderUsage(context.Background(), inst.Id)) }()
+
+	// The agent waits for host_ready before building when egress is
+	// restricted, so the build can't get ahead of the restriction
+	lift, err := m.restrictEgress(ctx, inst.Id, policy)
+	if err != nil {
+		return nil, err
+	}
+	defer lift()
+
 	// Wait for build result via vsock
 	// The builder agent will send the result when complete
-	result, err := m.waitForResult(ctx, inst)
+	result, err := m.waitForResult(ctx, id, inst, nil)
 	if err != nil {
 		return nil, fmt.Errorf("wait for result: %w", err)
 	}
@@ -410,10 +550,16 @@ func (m *manager) executeBuild(ctx context.Context, id string, req CreateBuildRe
 	return result, nil
 }
 
-// waitForResult waits for the build result from the builder agent via vsock
-func (m *manager) waitForResult(ctx context.Context, inst *instances.Instance) (*BuildResult, error) {
+// waitForResult waits for the build result from the builder agent via vsock.
+// For warm builders, job is the build_job message handing the agent its build.
+// Artifact builds upload their artifact before the result, which is stored
+// at the build's artifact path.
+func (m *manager) waitForResult(ctx context.Context, id string, inst *instances.Instance, job *VsockMessage) (*BuildResult, error) {
 	// Wait a bit for the VM to start and the builder agent to listen on vsock
-	time.Sleep(3 * time.Second)
+	// (warm builders are already listening)
+	if job == nil {
+		time.Sleep(3 * time.Second)
+	}
 
 	// Try to connect to the builder agent with retries
 	var conn net.Conn
@@ -439,7 +585,7 @@ func (m *manager) waitForResult(ctx context.Context, inst *instances.Instance) (
 		if checkErr != nil {
 			return nil, fmt.Errorf("check instance: %w", checkErr)
 		}
-		if current.State == instances.StateStopped || current.State == instances.StateShutdown {
+		if current.State == instances.StateStopped || current.State == instances.StateShutdown || current.State == instances.StateFailed {
 			return &BuildResult{
 				Success: false,
 				Error:   "builder instance stopped unexpectedly",
@@ -457,6 +603,12 @@ func (m *manager) waitForResult(ctx context.Context, inst *instances.Instance) (
 	encoder := json.NewEncoder(conn)
 	decoder := json.NewDecoder(conn)
 
+	if job != nil {
+		if err := encoder.Encode(job); err != nil {
+			return nil, fmt.Errorf("send build job: %w", err)
+		}
+	}
+
 	// Tell the agent we're ready - it may request secrets
 	m.logger.Info("sending host_ready to agent", "instance", inst.Id)
 	if err := encoder.Encode(VsockMessage{Type: "host_ready"}); err != nil {
@@ -464,6 +616,13 @@ func (m *manager) waitForResult(ctx context.Context, inst *instances.Instance) (
 	}
 	m.logger.Info("host_ready sent, waiting for agent messages", "instance", inst.Id)
 
+	var artifact *artifactUpload
+	defer func() {
+		if artifact != nil {
+			artifact.abort()
+		}
+	}()
+
 	// Handle messages from agent until we get the build result
 	for {
 		// Use a goroutine for decoding so we can respect context cancellation.
@@ -512,11 +671,31 @@ func (m *manager) waitForResult(ctx context.Context, inst *instances.Instance) (
 			}
 			m.logger.Info("sent secrets to agent", "count", len(secrets), "instance", inst.Id)
 
+		case "artifact_chunk":
+			// Agent is uploading the artifact of an artifact build
+			if artifact == nil {
+				artifact, err = newArtifactUpload(m.paths.BuildArtifact(id), m.config.MaxArtifactBytes)
+				if err != nil {
+					return nil, err
+				}
+			}
+			if err := artifact.write(dr.response.Data); err != nil {
+				return nil, err
+			}
+
 		case "build_result":
 			// Build completed
 			if dr.response.Result == nil {
 				return nil, fmt.Errorf("received build_result with nil result")
 			}
+			if artifact != nil {
+				if err := artifact.finish(dr.response.Result); err != nil {
+					return nil, err
+				}
+				artifact = nil
+			} else if dr.response.Result.Success && dr.response.Result.ArtifactBytes > 0 {
+				return nil, fmt.Errorf("builder reported an artifact but didn't upload it")
+			}
 			return dr.response.Result, nil
 
 		default:
@@ -742,7 +921,7 @@ func (m *manager) CancelBuild(ctx context.Context, id string) error {
 		// Can't cancel a running build easily
 		// Would need to terminate the builder instance
 		if meta.BuilderInstance != nil {
-			m.instanceManager.DeleteInstance(ctx, *meta.BuilderInstance)
+			m.instanceManager.DeleteInstance(ctx, *meta.BuilderInstance, instances.DeleteInstanceRequest{})
 		}
 		m.updateStatus(id, StatusCancelled, nil)
 		return nil
@@ -755,6 +934,47 @@ func (m *manager) CancelBuild(ctx context.Context, id string) error {
 	}
 }
 
+// RetryBuild re-runs a failed or cancelled build with its stored source
+// and config, as a new build linked to it by RetryOf
+func (m *manager) RetryBuild(ctx context.Context, id string, retry RetryBuildRequest) (*Build, error) {
+	meta, err := readMetadata(m.paths, id)
+	if err != nil {
+		return nil, err
+	}
+	if meta.Status != StatusFailed && meta.Status != StatusCancelled {
+		return nil, fmt.Errorf("%w: build is %s", ErrNotRetryable, meta.Status)
+	}
+	if meta.Request == nil {
+		return nil, fmt.Errorf("%w: build has no stored request", ErrNotRetryable)
+	}
+	source, err := os.ReadFile(m.paths.BuildSourceDir(id) + "/source.tar.gz")
+	if err != nil {
+		return nil, fmt.Errorf("%w: read stored source: %v", ErrNotRetryable, err)
+	}
+
+	// The original's request is reused, with the overridden build args
+	// merged over its own
+	req := *meta.Request
+	if req.BuildPolicy != nil {
+		policy := *req.BuildPolicy
+		req.BuildPolicy = &policy
+	}
+	if len(retry.BuildArgs) > 0 {
+		buildArgs := maps.Clone(req.BuildArgs)
+		if buildArgs == nil {
+			buildArgs = make(map[string]string, len(retry.BuildArgs))
+		}
+		maps.Copy(buildArgs, retry.BuildArgs)
+		req.BuildArgs = buildArgs
+	}
+	if retry.CreatedBy != "" {
+		req.CreatedBy = retry.CreatedBy
+	}
+
+	m.logger.Info("retrying build", "id", id)
+	return m.createBuild(ctx, req, source, &id)
+}
+
 // GetBuildLogs returns the logs for a build
 func (m *manager) GetBuildLogs(ctx context.Context, id string) ([]byte, error) {
 	_, err := readMetadata(m.paths, id)
@@ -924,6 +1144,11 @@ func (m *manager) StreamBuildEvents(ctx context.Context, id string, follow bool)
 	return out, nil
 }
 
+// QueueState returns a snapshot of in-flight and queued builds
+func (m *manager) QueueState() QueueState {
+	return m.queue.State()
+}
+
 // RecoverPendingBuilds recovers builds that were interrupted on restart
 func (m *manager) RecoverPendingBuilds() {
 	pending, err := listPendingBuilds(m.paths)
@@ -69,7 +69,7 @@ func (m *mockInstanceManager) GetInstance(ctx context.Context, id string) (*inst
 	return nil, instances.ErrNotFound
 }
 
-func (m *mockInstanceManager) DeleteInstance(ctx context.Context, id string) error {
+func (m *mockInstanceManager) DeleteInstance(ctx context.Context, id string, req instances.DeleteInstanceRequest) error {
 	m.deleteCallCount++
 	if m.deleteFunc != nil {
 		return m.deleteFunc(ctx, id)
@@ -105,8 +105,16 @@ func (m *mockInstanceManager) StreamInstanceLogs(ctx context.Context, id string,
 	return nil, nil
 }
 
-func (m *mockInstanceManager) RotateLogs(ctx context.Context, maxBytes int64, maxFiles int) error {
-	return nil
+func (m *mockInstanceManager) SearchInstanceLogs(ctx context.Context, id string, q instances.LogSearchQuery) (*instances.LogSearchResult, error) {
+	return &instances.LogSearchResult{}, nil
+}
+
+func (m *mockInstanceManager) GetBootDiagnostics(ctx context.Context, id string) (*instances.BootDiagnostics, error) {
+	return nil, nil
+}
+
+func (m *mockInstanceManager) RotateLogs(ctx context.Context, policy instances.LogRetention) (*instances.LogRotationResult, error) {
+	return &instances.LogRotationResult{}, nil
 }
 
 func (m *mockInstanceManager) AttachVolume(ctx context.Context, id string, volumeId string, req instances.AttachVolumeRequest) (*instances.Instance, error) {
@@ -117,10 +125,80 @@ func (m *mockInstanceManager) DetachVolume(ctx context.Context, id string, volum
 	return nil, nil
 }
 
+func (m *mockInstanceManager) AttachUSBDevice(ctx context.Context, id string, selector string) (*instances.Instance, error) {
+	return nil, nil
+}
+
+func (m *mockInstanceManager) DetachUSBDevice(ctx context.Context, id string, selector string) (*instances.Instance, error) {
+	return nil, nil
+}
+
+func (m *mockInstanceManager) UpdateNetworkBandwidth(ctx context.Context, id string, req instances.UpdateNetworkBandwidthRequest) (*instances.Instance, error) {
+	return nil, nil
+}
+
 func (m *mockInstanceManager) ListInstanceAllocations(ctx context.Context) ([]resources.InstanceAllocation, error) {
 	return nil, nil
 }
 
+func (m *mockInstanceManager) ReconcileInstances(ctx context.Context) (*instances.ReconcileSummary, error) {
+	return &instances.ReconcileSummary{}, nil
+}
+
+func (m *mockInstanceManager) GetGuestAgentInfo(ctx context.Context, id string) (*instances.GuestAgentInfo, error) {
+	return nil, nil
+}
+
+func (m *mockInstanceManager) UpdateGuestAgent(ctx context.Context, id string) (*instances.GuestAgentInfo, error) {
+	return nil, nil
+}
+
+func (m *mockInstanceManager) TrackActivity(id string) func() {
+	return func() {}
+}
+
+func (m *mockInstanceManager) RenameInstance(ctx context.Context, id string, name string) (*instances.Instance, error) {
+	return nil, instances.ErrNotFound
+}
+
+func (m *mockInstanceManager) TrimDisks(ctx context.Context) (*instances.DiskTrimResult, error) {
+	return &instances.DiskTrimResult{}, nil
+}
+
+func (m *mockInstanceManager) SyncClocks(ctx context.Context) (*instances.ClockSyncResult, error) {
+	return &instances.ClockSyncResult{}, nil
+}
+
+func (m *mockInstanceManager) StandbyIdleInstances(ctx context.Context, defaults instances.IdleDefaults) ([]string, error) {
+	return nil, nil
+}
+
+func (m *mockInstanceManager) CheckCreateInstance(ctx context.Context, req instances.CreateInstanceRequest) (*instances.Instance, error) {
+	return &instances.Instance{StoredMetadata: instances.StoredMetadata{Name: req.Name, Image: req.Image}}, nil
+}
+
+func (m *mockInstanceManager) CPUTime(ctx context.Context, id string) (time.Duration, error) {
+	return 0, nil
+}
+
+func (m *mockInstanceManager) ResourceUsage(ctx context.Context, id string) (*instances.ResourceUsage, error) {
+	return &instances.ResourceUsage{}, nil
+}
+
+func (m *mockInstanceManager) SetSchedules(ctx context.Context, id string, schedules []instances.Schedule) (*instances.Instance, error) {
+	return nil, nil
+}
+
+func (m *mockInstanceManager) RunSchedules(ctx context.Context, since, now time.Time) ([]string, error) {
+	return nil, nil
+}
+
+func (m *mockInstanceManager) DebugState() instances.DebugState {
+	return instances.DebugState{}
+}
+
+func (m *mockInstanceManager) SetAdmissionLimits(limits instances.ResourceLimits) {}
+
 // mockVolumeManager implements volumes.Manager for testing
 type mockVolumeManager struct {
 	volumes               map[string]*volumes.Volume
@@ -171,6 +249,10 @@ func (m *mockVolumeManager) CreateVolumeFromArchive(ctx context.Context, req vol
 	return vol, nil
 }
 
+func (m *mockVolumeManager) CheckCreateVolume(ctx context.Context, req volumes.CreateVolumeRequest) error {
+	return nil
+}
+
 func (m *mockVolumeManager) GetVolume(ctx context.Context, id string) (*volumes.Volume, error) {
 	if vol, ok := m.volumes[id]; ok {
 		return vol, nil
@@ -196,10 +278,21 @@ func (m *mockVolumeManager) DeleteVolume(ctx context.Context, id string) error {
 	return nil
 }
 
+func (m *mockVolumeManager) UpdateVolume(ctx context.Context, id string, req volumes.UpdateVolumeRequest) (*volumes.Volume, error) {
+	if vol, ok := m.volumes[id]; ok {
+		return vol, nil
+	}
+	return nil, volumes.ErrNotFound
+}
+
 func (m *mockVolumeManager) AttachVolume(ctx context.Context, id string, req volumes.AttachVolumeRequest) error {
 	return nil
 }
 
+func (m *mockVolumeManager) CheckAttachVolume(ctx context.Context, id string, req volumes.AttachVolumeRequest) error {
+	return nil
+}
+
 func (m *mockVolumeManager) DetachVolume(ctx context.Context, volumeID string, instanceID string) error {
 	return nil
 }
@@ -208,6 +301,14 @@ func (m *mockVolumeManager) GetVolumePath(id string) string {
 	return "/tmp/volumes/" + id
 }
 
+func (m *mockVolumeManager) UnlockVolume(ctx context.Context, id string, instanceID string, readonly bool) (string, error) {
+	return "/tmp/volumes/" + id, nil
+}
+
+func (m *mockVolumeManager) LockVolume(ctx context.Context, id string, instanceID string) error {
+	return nil
+}
+
 func (m *mockVolumeManager) TotalVolumeBytes(ctx context.Context) (int64, error) {
 	return 0, nil
 }
@@ -311,7 +412,7 @@ func TestCreateBuild_WithBuildPolicy(t *testing.T) {
 		Dockerfile: "FROM alpine",
 		BuildPolicy: &BuildPolicy{
 			TimeoutSeconds: timeout,
-			NetworkMode:    "host",
+			NetworkMode:    NetworkModeOffline,
 		},
 	}
 
@@ -465,6 +566,60 @@ func TestCancelBuild_AlreadyCompleted(t *testing.T) {
 	assert.Contains(t, err.Error(), "already completed")
 }
 
+func TestRetryBuild(t *testing.T) {
+	mgr, _, _, tempDir := setupTestManager(t)
+	defer os.RemoveAll(tempDir)
+
+	ctx := context.Background()
+	require.NoError(t, writeMetadata(mgr.paths, &buildMetadata{
+		ID:     "failed-build",
+		Status: StatusFailed,
+		Request: &CreateBuildRequest{
+			Dockerfile: "FROM alpine",
+			BuildArgs:  map[string]string{"A": "1", "B": "2"},
+			CreatedBy:  "alice",
+		},
+	}))
+	require.NoError(t, mgr.storeSource("failed-build", []byte("source")))
+
+	build, err := mgr.RetryBuild(ctx, "failed-build", RetryBuildRequest{BuildArgs: map[string]string{"B": "3"}, CreatedBy: "bob"})
+	require.NoError(t, err)
+	require.NotNil(t, build.RetryOf)
+	assert.Equal(t, "failed-build", *build.RetryOf)
+	assert.Equal(t, "bob", build.CreatedBy)
+
+	meta, err := readMetadata(mgr.paths, build.ID)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"A": "1", "B": "3"}, meta.Request.BuildArgs)
+	assert.Equal(t, "FROM alpine", meta.Request.Dockerfile)
+	source, err := os.ReadFile(mgr.paths.BuildSourceDir(build.ID) + "/source.tar.gz")
+	require.NoError(t, err)
+	assert.Equal(t, "source", string(source))
+
+	// The original is left as it was
+	original, err := readMetadata(mgr.paths, "failed-build")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"A": "1", "B": "2"}, original.Request.BuildArgs)
+}
+
+func TestRetryBuild_NotRetryable(t *testing.T) {
+	mgr, _, _, tempDir := setupTestManager(t)
+	defer os.RemoveAll(tempDir)
+
+	ctx := context.Background()
+	require.NoError(t, writeMetadata(mgr.paths, &buildMetadata{ID: "ready-build", Status: StatusReady, Request: &CreateBuildRequest{}}))
+	_, err := mgr.RetryBuild(ctx, "ready-build", RetryBuildRequest{})
+	assert.ErrorIs(t, err, ErrNotRetryable)
+
+	// A build whose source is gone can't be retried either
+	require.NoError(t, writeMetadata(mgr.paths, &buildMetadata{ID: "cancelled-build", Status: StatusCancelled, Request: &CreateBuildRequest{}}))
+	_, err = mgr.RetryBuild(ctx, "cancelled-build", RetryBuildRequest{})
+	assert.ErrorIs(t, err, ErrNotRetryable)
+
+	_, err = mgr.RetryBuild(ctx, "nonexistent-id", RetryBuildRequest{})
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
 func TestGetBuildLogs_Empty(t *testing.T) {
 	mgr, _, _, tempDir := setupTestManager(t)
 	defer os.RemoveAll(tempDir)
@@ -0,0 +1,371 @@
+package builds
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kernel/hypeman/lib/instances"
+	"github.com/nrednav/cuid2"
+)
+
+const (
+	// poolInstancePrefix names warm builder instances, so builders left over
+	// from a previous run can be found and removed
+	poolInstancePrefix = "builder-pool-"
+
+	// poolRetryInterval is how often the pool tops itself up after a builder
+	// failed to boot
+	poolRetryInterval = 30 * time.Second
+
+	// poolBootTimeout bounds how long a warm builder may take to boot before
+	// it is given up on
+	poolBootTimeout = 2 * time.Minute
+
+	// maxPooledSourceBytes caps the source sent to a warm builder over vsock.
+	// Larger sources are built in a fresh VM with a source volume.
+	maxPooledSourceBytes = 64 * 1024 * 1024
+)
+
+// builderPool keeps builder VMs booted ahead of demand, so builds skip the
+// VM boot. Warm builders have no volumes attached: the build config and
+// source tarball are sent to the builder agent over vsock instead.
+//
+// Warm builders are booted with the default build policy, and only builds
+// with the same resources and network mode can claim one. A builder runs up
+// to maxUses builds before it is destroyed; builders that are reused share
+// BuildKit's local state between builds.
+type builderPool struct {
+	size            int
+	maxUses         int
+	policy          BuildPolicy
+	image           string
+	instanceManager instances.Manager
+	logger          *slog.Logger
+
+	// waitReady blocks until the builder agent in a new instance is reachable
+	waitReady func(ctx context.Context, inst *instances.Instance) error
+
+	mu      sync.Mutex
+	idle    []*pooledBuilder
+	booting int
+	leased  int // claimed builders that come back to the pool after their build
+	refill  chan struct{}
+}
+
+// pooledBuilder is a warm builder VM
+type pooledBuilder struct {
+	inst  *instances.Instance
+	image string // builder image it was booted from
+	uses  int
+}
+
+// newBuilderPool creates the warm builder pool, or returns nil if it's disabled
+func newBuilderPool(config Config, instanceMgr instances.Manager, logger *slog.Logger, waitReady func(context.Context, *instances.Instance) error) *builderPool {
+	if config.WarmPoolSize <= 0 {
+		return nil
+	}
+	maxUses := config.WarmPoolMaxUses
+	if maxUses <= 0 {
+		maxUses = 1
+	}
+	return &builderPool{
+		size:            config.WarmPoolSize,
+		maxUses:         maxUses,
+		policy:          DefaultBuildPolicy(),
+		image:           config.BuilderImage,
+		instanceManager: instanceMgr,
+		logger:          logger,
+		waitReady:       waitReady,
+		refill:          make(chan struct{}, 1),
+	}
+}
+
+// run keeps the pool topped up until ctx is done
+func (p *builderPool) run(ctx context.Context) {
+	p.removeLeftovers(ctx)
+
+	ticker := time.NewTicker(poolRetryInterval)
+	defer ticker.Stop()
+	for {
+		p.fill(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-p.refill:
+		case <-ticker.C:
+		}
+	}
+}
+
+// removeLeftovers deletes warm builders of a previous run. They can't be
+// reused, since nothing is known about the builds they ran.
+func (p *builderPool) removeLeftovers(ctx context.Context) {
+	insts, err := p.instanceManager.ListInstances(ctx)
+	if err != nil {
+		p.logger.Warn("failed to list leftover warm builders", "error", err)
+		return
+	}
+	for _, inst := range insts {
+		if strings.HasPrefix(inst.Name, poolInstancePrefix) {
+			p.logger.Info("removing leftover warm builder", "instance", inst.Id)
+			p.destroy(inst.Id)
+		}
+	}
+}
+
+// fill starts booting builders until the pool is full
+func (p *builderPool) fill(ctx context.Context) {
+	p.mu.Lock()
+	missing := p.size - len(p.idle) - p.booting - p.leased
+	p.booting += max(missing, 0)
+	p.mu.Unlock()
+
+	for range missing {
+		go p.boot(ctx)
+	}
+}
+
+// boot boots one builder and adds it to the pool. Failures are retried by
+// the next periodic fill rather than right away, so a broken builder image
+// doesn't turn into a boot loop.
+func (p *builderPool) boot(ctx context.Context) {
+	p.mu.Lock()
+	image := p.image
+	p.mu.Unlock()
+
+	inst, err := p.instanceManager.CreateInstance(ctx, instances.CreateInstanceRequest{
+		Name:           poolInstancePrefix + cuid2.Generate(),
+		Image:          image,
+		Size:           int64(p.policy.MemoryMB) * 1024 * 1024,
+		Vcpus:          p.policy.CPUs,
+		FixedMemory:    true,
+		NetworkEnabled: p.policy.NetworkMode == NetworkModeEgress,
+	})
+	if err == nil {
+		readyCtx, cancel := context.WithTimeout(ctx, poolBootTimeout)
+		err = p.waitReady(readyCtx, inst)
+		cancel()
+		if err != nil {
+			p.destroy(inst.Id)
+		}
+	}
+
+	p.mu.Lock()
+	p.booting--
+	stale := err == nil && image != p.image
+	if err == nil && !stale {
+		p.idle = append(p.idle, &pooledBuilder{inst: inst, image: image})
+	}
+	p.mu.Unlock()
+
+	if stale {
+		// The builder image changed while this one booted
+		p.destroy(inst.Id)
+		p.signal()
+		return
+	}
+
+	if err != nil {
+		if ctx.Err() == nil {
+			p.logger.Warn("failed to boot warm builder", "error", err)
+		}
+		return
+	}
+	p.logger.Info("warm builder ready", "instance", inst.Id)
+}
+
+// matches reports whether a build with the given policy can run on a warm builder
+func (p *builderPool) matches(policy *BuildPolicy) bool {
+	return policy.MemoryMB == p.policy.MemoryMB &&
+		policy.CPUs == p.policy.CPUs &&
+		policy.NetworkMode == p.policy.NetworkMode
+}
+
+// claim takes an idle builder that is still running, or returns nil if
+// there is none
+func (p *builderPool) claim(ctx context.Context) *pooledBuilder {
+	for {
+		p.mu.Lock()
+		if len(p.idle) == 0 {
+			p.mu.Unlock()
+			return nil
+		}
+		b := p.idle[0]
+		p.idle = p.idle[1:]
+		b.uses++
+		if b.uses < p.maxUses {
+			p.leased++
+		}
+		p.mu.Unlock()
+
+		if b.uses >= p.maxUses {
+			// This builder won't come back; start booting its replacement
+			p.signal()
+		}
+
+		if p.running(ctx, b) {
+			return b
+		}
+		p.logger.Warn("warm builder is no longer running, discarding it", "instance", b.inst.Id)
+		p.discard(b)
+	}
+}
+
+// release hands a builder back after a build. It is reused if the build
+// ran to completion and it has uses left, and destroyed otherwise.
+func (p *builderPool) release(ctx context.Context, b *pooledBuilder, completed bool) {
+	p.mu.Lock()
+	current := b.image == p.image
+	p.mu.Unlock()
+
+	if b.uses < p.maxUses && completed && current && p.running(ctx, b) {
+		p.mu.Lock()
+		p.leased--
+		p.idle = append(p.idle, b)
+		p.mu.Unlock()
+		return
+	}
+	p.discard(b)
+}
+
+// setImage switches the pool to a new builder image. Idle builders of the
+// old image are destroyed and replaced; leased ones are destroyed when they
+// come back.
+func (p *builderPool) setImage(image string) {
+	p.mu.Lock()
+	if image == p.image {
+		p.mu.Unlock()
+		return
+	}
+	p.image = image
+	stale := p.idle
+	p.idle = nil
+	p.mu.Unlock()
+
+	for _, b := range stale {
+		p.destroy(b.inst.Id)
+	}
+	p.signal()
+}
+
+// discard destroys a claimed builder and makes room for a new one
+func (p *builderPool) discard(b *pooledBuilder) {
+	if b.uses < p.maxUses {
+		p.mu.Lock()
+		p.leased--
+		p.mu.Unlock()
+		p.signal()
+	}
+	p.destroy(b.inst.Id)
+}
+
+// running reports whether a builder's VM is still up
+func (p *builderPool) running(ctx context.Context, b *pooledBuilder) bool {
+	inst, err := p.instanceManager.GetInstance(ctx, b.inst.Id)
+	return err == nil && inst.State == instances.StateRunning
+}
+
+func (p *builderPool) destroy(id string) {
+	if err := p.instanceManager.DeleteInstance(context.Background(), id, instances.DeleteInstanceRequest{}); err != nil && !errors.Is(err, instances.ErrNotFound) {
+		p.logger.Warn("failed to delete warm builder", "instance", id, "error", err)
+	}
+}
+
+func (p *builderPool) signal() {
+	select {
+	case p.refill <- struct{}{}:
+	default:
+	}
+}
+
+// SetBuilderImage changes the image new builder VMs boot from
+func (m *manager) SetBuilderImage(image string) {
+	m.imageMu.Lock()
+	m.config.BuilderImage = image
+	m.imageMu.Unlock()
+
+	if m.pool != nil {
+		m.pool.setImage(image)
+	}
+}
+
+// builderImage returns the image builder VMs boot from
+func (m *manager) builderImage() string {
+	m.imageMu.RLock()
+	defer m.imageMu.RUnlock()
+	return m.config.BuilderImage
+}
+
+// claimWarmBuilder returns a warm builder for the build, or nil if the build
+// needs a fresh VM
+func (m *manager) claimWarmBuilder(ctx context.Context, id string, policy *BuildPolicy) *pooledBuilder {
+	if m.pool == nil || !m.pool.matches(policy) {
+		return nil
+	}
+	info, err := os.Stat(m.paths.BuildSourceDir(id) + "/source.tar.gz")
+	if err != nil || info.Size() > maxPooledSourceBytes {
+		return nil
+	}
+	return m.pool.claim(ctx)
+}
+
+// executeWarmBuild runs a build on a warm builder, sending it the build
+// config and source over vsock
+func (m *manager) executeWarmBuild(ctx context.Context, id string, b *pooledBuilder, policy *BuildPolicy) (*BuildResult, error) {
+	config, err := readBuildConfig(m.paths, id)
+	if err != nil {
+		return nil, err
+	}
+	source, err := os.ReadFile(m.paths.BuildSourceDir(id) + "/source.tar.gz")
+	if err != nil {
+		return nil, fmt.Errorf("read source: %w", err)
+	}
+
+	// Update metadata with builder instance
+	if meta, err := readMetadata(m.paths, id); err == nil {
+		meta.BuilderInstance = &b.inst.Id
+		writeMetadata(m.paths, meta)
+	}
+
+	// Restrict egress before the agent gets the job, and lift it before the
+	// builder is reused
+	lift, err := m.restrictEgress(ctx, b.inst.Id, policy)
+	if err != nil {
+		return nil, err
+	}
+	defer lift()
+
+	// Warm builders have run earlier builds, so this one's usage is the
+	// difference
+	before := m.builderUsage(ctx, b.inst.Id)
+	defer func() { m.recordResources(id, before, m.builderUsage(context.Background(), b.inst.Id)) }()
+
+	m.logger.Info("running build on warm builder", "id", id, "instance", b.inst.Id, "use", b.uses)
+	result, err := m.waitForResult(ctx, id, b.inst, &VsockMessage{Type: "build_job", Job: config, Source: source})
+	if err != nil {
+		return nil, fmt.Errorf("wait for result: %w", err)
+	}
+	return result, nil
+}
+
+// waitForAgent waits until the builder agent in inst accepts connections
+func (m *manager) waitForAgent(ctx context.Context, inst *instances.Instance) error {
+	for {
+		conn, err := m.dialBuilderVsock(inst.VsockSocket)
+		if err == nil {
+			conn.Close()
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("builder agent not reachable: %w", err)
+		case <-time.After(time.Second):
+		}
+	}
+}
@@ -0,0 +1,143 @@
+package builds
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kernel/hypeman/lib/instances"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestPool(t *testing.T, size, maxUses int) (*builderPool, *mockInstanceManager) {
+	t.Helper()
+	instanceMgr := newMockInstanceManager()
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	config := Config{BuilderImage: "test/builder:latest", WarmPoolSize: size, WarmPoolMaxUses: maxUses}
+	ready := func(ctx context.Context, inst *instances.Instance) error { return nil }
+	p := newBuilderPool(config, instanceMgr, logger, ready)
+	require.NotNil(t, p)
+	return p, instanceMgr
+}
+
+func idleCount(p *builderPool) int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return len(p.idle)
+}
+
+func TestBuilderPool_Disabled(t *testing.T) {
+	assert.Nil(t, newBuilderPool(Config{}, newMockInstanceManager(), slog.Default(), nil))
+}
+
+func TestBuilderPool_ClaimAndRecycle(t *testing.T) {
+	p, instanceMgr := newTestPool(t, 1, 2)
+	ctx := context.Background()
+
+	p.fill(ctx)
+	require.Eventually(t, func() bool { return idleCount(p) == 1 }, 5*time.Second, 10*time.Millisecond)
+	assert.Equal(t, 1, instanceMgr.createCallCount)
+
+	b := p.claim(ctx)
+	require.NotNil(t, b)
+	assert.True(t, strings.HasPrefix(b.inst.Name, poolInstancePrefix))
+
+	// A builder with uses left isn't replaced while it's out
+	p.fill(ctx)
+	assert.Equal(t, 1, instanceMgr.createCallCount)
+	assert.Nil(t, p.claim(ctx), "the only builder is in use")
+
+	p.release(ctx, b, true)
+	assert.Equal(t, 1, idleCount(p))
+
+	// Its last use destroys it
+	b = p.claim(ctx)
+	require.NotNil(t, b)
+	assert.Equal(t, 2, b.uses)
+	p.release(ctx, b, true)
+	assert.Equal(t, 0, idleCount(p))
+	assert.Equal(t, 1, instanceMgr.deleteCallCount)
+}
+
+func TestBuilderPool_DiscardsBrokenBuilders(t *testing.T) {
+	p, instanceMgr := newTestPool(t, 1, 5)
+	ctx := context.Background()
+
+	p.fill(ctx)
+	require.Eventually(t, func() bool { return idleCount(p) == 1 }, 5*time.Second, 10*time.Millisecond)
+
+	// A build that didn't complete leaves the builder in an unknown state
+	b := p.claim(ctx)
+	require.NotNil(t, b)
+	p.release(ctx, b, false)
+	assert.Equal(t, 1, instanceMgr.deleteCallCount)
+
+	// Builders that stopped while idle are skipped
+	p.fill(ctx)
+	require.Eventually(t, func() bool { return idleCount(p) == 1 }, 5*time.Second, 10*time.Millisecond)
+	p.mu.Lock()
+	p.idle[0].inst.State = instances.StateStopped
+	p.mu.Unlock()
+	assert.Nil(t, p.claim(ctx))
+	assert.Equal(t, 2, instanceMgr.deleteCallCount)
+}
+
+func TestBuilderPool_SetImage(t *testing.T) {
+	p, instanceMgr := newTestPool(t, 1, 5)
+	ctx := context.Background()
+
+	p.fill(ctx)
+	require.Eventually(t, func() bool { return idleCount(p) == 1 }, 5*time.Second, 10*time.Millisecond)
+
+	// A builder leased when the image changes isn't reused
+	b := p.claim(ctx)
+	require.NotNil(t, b)
+	p.setImage("test/builder:v2")
+	p.release(ctx, b, true)
+	assert.Equal(t, 0, idleCount(p))
+	assert.Equal(t, 1, instanceMgr.deleteCallCount)
+
+	p.fill(ctx)
+	require.Eventually(t, func() bool { return idleCount(p) == 1 }, 5*time.Second, 10*time.Millisecond)
+	p.mu.Lock()
+	assert.Equal(t, "test/builder:v2", p.idle[0].image)
+	p.mu.Unlock()
+
+	// Idle builders of the old image are replaced right away
+	p.setImage("test/builder:v3")
+	assert.Equal(t, 0, idleCount(p))
+	assert.Equal(t, 2, instanceMgr.deleteCallCount)
+}
+
+func TestBuilderPool_Matches(t *testing.T) {
+	p, _ := newTestPool(t, 1, 1)
+
+	policy := DefaultBuildPolicy()
+	policy.TimeoutSeconds = 60
+	assert.True(t, p.matches(&policy))
+
+	policy.MemoryMB = 4096
+	assert.False(t, p.matches(&policy))
+
+	policy = DefaultBuildPolicy()
+	policy.NetworkMode = "isolated"
+	assert.False(t, p.matches(&policy))
+}
+
+func TestBuilderPool_RemoveLeftovers(t *testing.T) {
+	p, instanceMgr := newTestPool(t, 1, 1)
+	for _, name := range []string{poolInstancePrefix + "old", "builder-abc", "web"} {
+		_, err := instanceMgr.CreateInstance(context.Background(), instances.CreateInstanceRequest{Name: name})
+		require.NoError(t, err)
+	}
+
+	p.removeLeftovers(context.Background())
+
+	_, err := instanceMgr.GetInstance(context.Background(), "inst-"+poolInstancePrefix+"old")
+	assert.ErrorIs(t, err, instances.ErrNotFound)
+	assert.Len(t, instanceMgr.instances, 2)
+}
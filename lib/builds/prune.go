@@ -0,0 +1,98 @@
+package builds
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/kernel/hypeman/lib/diskusage"
+)
+
+// Prefixes of the IDs of the volumes executeBuild creates for a build
+var buildVolumePrefixes = []string{"build-source-", "build-config-"}
+
+// Pruned is a build or build volume removed by a prune, or that would be
+// removed in a dry run.
+type Pruned struct {
+	ID    string
+	Bytes int64 // Disk space reclaimed
+}
+
+// PruneBuilds removes the data (source, logs, config) of builds that finished
+// before completedBefore. Queued and running builds are never removed.
+func (m *manager) PruneBuilds(ctx context.Context, completedBefore time.Time, dryRun bool) ([]Pruned, error) {
+	metas, err := listAllBuilds(m.paths)
+	if err != nil {
+		return nil, err
+	}
+
+	var pruned []Pruned
+	for _, meta := range metas {
+		switch meta.Status {
+		case StatusReady, StatusFailed, StatusCancelled:
+		default:
+			continue
+		}
+		// Builds cancelled while queued have no completion time
+		finished := meta.CreatedAt
+		if meta.CompletedAt != nil {
+			finished = *meta.CompletedAt
+		}
+		if !finished.Before(completedBefore) {
+			continue
+		}
+
+		p := Pruned{ID: meta.ID, Bytes: diskusage.Bytes(m.paths.BuildDir(meta.ID))}
+		if !dryRun {
+			if err := deleteBuild(m.paths, meta.ID); err != nil {
+				return pruned, fmt.Errorf("delete build %s: %w", meta.ID, err)
+			}
+		}
+		pruned = append(pruned, p)
+	}
+	return pruned, nil
+}
+
+// PruneOrphanVolumes removes source and config volumes left behind by builds
+// that are no longer running, e.g. after hypeman was restarted mid-build.
+// Volumes still attached to an instance are kept.
+func (m *manager) PruneOrphanVolumes(ctx context.Context, dryRun bool) ([]Pruned, error) {
+	vols, err := m.volumeManager.ListVolumes(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("list volumes: %w", err)
+	}
+
+	var pruned []Pruned
+	for _, vol := range vols {
+		buildID, ok := buildVolumeOwner(vol.Id)
+		if !ok || len(vol.Attachments) > 0 {
+			continue
+		}
+		if meta, err := readMetadata(m.paths, buildID); err == nil {
+			switch meta.Status {
+			case StatusQueued, StatusBuilding, StatusPushing:
+				continue
+			}
+		}
+
+		p := Pruned{ID: vol.Id, Bytes: diskusage.Bytes(m.paths.VolumeDir(vol.Id))}
+		if !dryRun {
+			if err := m.volumeManager.DeleteVolume(ctx, vol.Id); err != nil {
+				return pruned, fmt.Errorf("delete volume %s: %w", vol.Id, err)
+			}
+		}
+		pruned = append(pruned, p)
+	}
+	return pruned, nil
+}
+
+// buildVolumeOwner returns the ID of the build a build volume belongs to
+func buildVolumeOwner(volumeID string) (string, bool) {
+	for _, prefix := range buildVolumePrefixes {
+		if id, ok := strings.CutPrefix(volumeID, prefix); ok && id != "" {
+			return id, true
+		}
+	}
+	return "", false
+}
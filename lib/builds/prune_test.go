@@ -0,0 +1,78 @@
+package builds
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/kernel/hypeman/lib/volumes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPruneBuilds(t *testing.T) {
+	mgr, _, _, tempDir := setupTestManager(t)
+	defer os.RemoveAll(tempDir)
+	ctx := context.Background()
+
+	now := time.Now()
+	old := now.Add(-48 * time.Hour)
+	recent := now.Add(-time.Hour)
+	for _, meta := range []*buildMetadata{
+		{ID: "old-ready", Status: StatusReady, CreatedAt: old, CompletedAt: &old},
+		{ID: "old-failed", Status: StatusFailed, CreatedAt: old, CompletedAt: &old},
+		{ID: "old-cancelled", Status: StatusCancelled, CreatedAt: old}, // cancelled while queued
+		{ID: "recent-ready", Status: StatusReady, CreatedAt: recent, CompletedAt: &recent},
+		{ID: "old-building", Status: StatusBuilding, CreatedAt: old},
+	} {
+		require.NoError(t, writeMetadata(mgr.paths, meta))
+	}
+
+	pruned, err := mgr.PruneBuilds(ctx, now.Add(-24*time.Hour), true)
+	require.NoError(t, err)
+	assert.Len(t, pruned, 3)
+	assert.DirExists(t, mgr.paths.BuildDir("old-ready"), "dry run removes nothing")
+
+	pruned, err = mgr.PruneBuilds(ctx, now.Add(-24*time.Hour), false)
+	require.NoError(t, err)
+	var ids []string
+	for _, p := range pruned {
+		ids = append(ids, p.ID)
+	}
+	assert.ElementsMatch(t, []string{"old-ready", "old-failed", "old-cancelled"}, ids)
+
+	remaining, err := mgr.ListBuilds(ctx)
+	require.NoError(t, err)
+	require.Len(t, remaining, 2)
+}
+
+func TestPruneOrphanVolumes(t *testing.T) {
+	mgr, _, volumeMgr, tempDir := setupTestManager(t)
+	defer os.RemoveAll(tempDir)
+	ctx := context.Background()
+
+	require.NoError(t, writeMetadata(mgr.paths, &buildMetadata{ID: "running", Status: StatusBuilding, CreatedAt: time.Now()}))
+	require.NoError(t, writeMetadata(mgr.paths, &buildMetadata{ID: "done", Status: StatusFailed, CreatedAt: time.Now()}))
+	for _, vol := range []volumes.Volume{
+		{Id: "build-source-running"},
+		{Id: "build-source-done"},
+		{Id: "build-config-done"},
+		{Id: "build-source-gone"}, // build already pruned
+		{Id: "build-source-attached", Attachments: []volumes.Attachment{{InstanceID: "builder"}}},
+		{Id: "data"},
+	} {
+		volumeMgr.volumes[vol.Id] = &vol
+	}
+
+	pruned, err := mgr.PruneOrphanVolumes(ctx, false)
+	require.NoError(t, err)
+	var ids []string
+	for _, p := range pruned {
+		ids = append(ids, p.ID)
+	}
+	assert.ElementsMatch(t, []string{"build-source-done", "build-config-done", "build-source-gone"}, ids)
+	assert.Contains(t, volumeMgr.volumes, "build-source-running")
+	assert.Contains(t, volumeMgr.volumes, "build-source-attached")
+	assert.Contains(t, volumeMgr.volumes, "data")
+}
@@ -1,6 +1,9 @@
 package builds
 
-import "sync"
+import (
+	"sort"
+	"sync"
+)
 
 // QueuedBuild represents a build waiting to be executed
 type QueuedBuild struct {
@@ -169,3 +172,29 @@ func (q *BuildQueue) QueueLength() int {
 	return len(q.active) + len(q.pending)
 }
 
+
+// QueueState is a snapshot of the build queue for diagnostics
+type QueueState struct {
+	MaxConcurrent int      `json:"max_concurrent"`
+	Active        []string `json:"active"`  // build IDs running
+	Pending       []string `json:"pending"` // build IDs in queue order
+}
+
+// State returns a snapshot of active and pending builds
+func (q *BuildQueue) State() QueueState {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	state := QueueState{
+		MaxConcurrent: q.maxConcurrent,
+		Active:        make([]string, 0, len(q.active)),
+		Pending:       make([]string, 0, len(q.pending)),
+	}
+	for id := range q.active {
+		state.Active = append(state.Active, id)
+	}
+	sort.Strings(state.Active)
+	for _, build := range q.pending {
+		state.Pending = append(state.Pending, build.BuildID)
+	}
+	return state
+}
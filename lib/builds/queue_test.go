@@ -228,3 +228,19 @@ func TestBuildQueue_Counts(t *testing.T) {
 	close(done)
 }
 
+
+func TestBuildQueue_State(t *testing.T) {
+	queue := NewBuildQueue(1)
+
+	done := make(chan struct{})
+	queue.Enqueue("build-1", CreateBuildRequest{}, func() { <-done })
+	queue.Enqueue("build-2", CreateBuildRequest{}, func() {})
+	queue.Enqueue("build-3", CreateBuildRequest{}, func() {})
+
+	state := queue.State()
+	assert.Equal(t, 1, state.MaxConcurrent)
+	assert.Equal(t, []string{"build-1"}, state.Active)
+	assert.Equal(t, []string{"build-2", "build-3"}, state.Pending)
+
+	close(done)
+}
@@ -0,0 +1,43 @@
+package builds
+
+import (
+	"context"
+
+	"github.com/kernel/hypeman/lib/instances"
+)
+
+// builderUsage returns what a builder VM has used since it booted, or nil if
+// it can't be measured
+func (m *manager) builderUsage(ctx context.Context, instanceID string) *instances.ResourceUsage {
+	usage, err := m.instanceManager.ResourceUsage(ctx, instanceID)
+	if err != nil {
+		m.logger.Warn("failed to read builder resource usage", "instance", instanceID, "error", err)
+		return nil
+	}
+	return usage
+}
+
+// recordResources records in a build's metadata what its builder used
+// between two measurements. A nil start means the builder booted for the
+// build.
+func (m *manager) recordResources(id string, start, end *instances.ResourceUsage) {
+	if end == nil {
+		return
+	}
+	if start == nil {
+		start = &instances.ResourceUsage{}
+	}
+	meta, err := readMetadata(m.paths, id)
+	if err != nil {
+		return
+	}
+	meta.Resources = &BuildResources{
+		CPUSeconds:      (end.CPUTime - start.CPUTime).Seconds(),
+		PeakMemoryBytes: end.PeakMemoryBytes,
+		NetworkRxBytes:  end.NetworkRxBytes - start.NetworkRxBytes,
+		NetworkTxBytes:  end.NetworkTxBytes - start.NetworkTxBytes,
+	}
+	if err := writeMetadata(m.paths, meta); err != nil {
+		m.logger.Warn("failed to record build resources", "id", id, "error", err)
+	}
+}
@@ -0,0 +1,33 @@
+package builds
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/kernel/hypeman/lib/instances"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecordResources(t *testing.T) {
+	mgr, _, _, tempDir := setupTestManager(t)
+	defer os.RemoveAll(tempDir)
+	require.NoError(t, writeMetadata(mgr.paths, &buildMetadata{ID: "warm", Status: StatusBuilding}))
+
+	// A warm builder's earlier builds are subtracted, except from the peak
+	start := &instances.ResourceUsage{CPUTime: 2 * time.Second, PeakMemoryBytes: 100, NetworkRxBytes: 1000, NetworkTxBytes: 10}
+	end := &instances.ResourceUsage{CPUTime: 5500 * time.Millisecond, PeakMemoryBytes: 300, NetworkRxBytes: 4000, NetworkTxBytes: 50}
+	mgr.recordResources("warm", start, end)
+
+	meta, err := readMetadata(mgr.paths, "warm")
+	require.NoError(t, err)
+	assert.Equal(t, &BuildResources{CPUSeconds: 3.5, PeakMemoryBytes: 300, NetworkRxBytes: 3000, NetworkTxBytes: 40}, meta.toBuild().Resources)
+
+	// Builders that couldn't be measured leave nothing behind
+	require.NoError(t, writeMetadata(mgr.paths, &buildMetadata{ID: "cold", Status: StatusBuilding}))
+	mgr.recordResources("cold", nil, nil)
+	meta, err = readMetadata(mgr.paths, "cold")
+	require.NoError(t, err)
+	assert.Nil(t, meta.Resources)
+}
@@ -23,12 +23,17 @@ type buildMetadata struct {
 	StartedAt       *time.Time          `json:"started_at,omitempty"`
 	CompletedAt     *time.Time          `json:"completed_at,omitempty"`
 	DurationMS      *int64              `json:"duration_ms,omitempty"`
+	Artifact        *BuildArtifact      `json:"artifact,omitempty"`
+	SBOM            *BuildDocument      `json:"sbom,omitempty"`
+	SLSAProvenance  *BuildDocument      `json:"slsa_provenance,omitempty"`
+	Resources       *BuildResources     `json:"resources,omitempty"`
+	RetryOf         *string             `json:"retry_of,omitempty"`
 	BuilderInstance *string             `json:"builder_instance,omitempty"` // Instance ID of builder VM
 }
 
 // toBuild converts internal metadata to the public Build type
 func (m *buildMetadata) toBuild() *Build {
-	return &Build{
+	b := &Build{
 		ID:          m.ID,
 		Status:      m.Status,
 		ImageDigest: m.ImageDigest,
@@ -39,7 +44,17 @@ func (m *buildMetadata) toBuild() *Build {
 		StartedAt:   m.StartedAt,
 		CompletedAt: m.CompletedAt,
 		DurationMS:  m.DurationMS,
+		Artifact:    m.Artifact,
+		SBOM:        m.SBOM,
+		Resources:   m.Resources,
+		RetryOf:     m.RetryOf,
 	}
+	b.SLSAProvenance = m.SLSAProvenance
+	if m.Request != nil {
+		b.Tenant = m.Request.Tenant
+		b.CreatedBy = m.Request.CreatedBy
+	}
+	return b
 }
 
 // writeMetadata writes build metadata to disk atomically
@@ -2,7 +2,10 @@
 // inside ephemeral Cloud Hypervisor microVMs for multi-tenant isolation.
 package builds
 
-import "time"
+import (
+	"encoding/json"
+	"time"
+)
 
 // Build status constants
 const (
@@ -16,17 +19,24 @@ const (
 
 // Build represents a source-to-image build job
 type Build struct {
-	ID            string           `json:"id"`
-	Status        string           `json:"status"`
-	QueuePosition *int             `json:"queue_position,omitempty"`
-	ImageDigest   *string          `json:"image_digest,omitempty"`
-	ImageRef      *string          `json:"image_ref,omitempty"`
-	Error         *string          `json:"error,omitempty"`
-	Provenance    *BuildProvenance `json:"provenance,omitempty"`
-	CreatedAt     time.Time        `json:"created_at"`
-	StartedAt     *time.Time       `json:"started_at,omitempty"`
-	CompletedAt   *time.Time       `json:"completed_at,omitempty"`
-	DurationMS    *int64           `json:"duration_ms,omitempty"`
+	ID             string           `json:"id"`
+	Status         string           `json:"status"`
+	QueuePosition  *int             `json:"queue_position,omitempty"`
+	ImageDigest    *string          `json:"image_digest,omitempty"`
+	ImageRef       *string          `json:"image_ref,omitempty"`
+	Error          *string          `json:"error,omitempty"`
+	Provenance     *BuildProvenance `json:"provenance,omitempty"`
+	Tenant         string           `json:"tenant,omitempty"`
+	CreatedBy      string           `json:"created_by,omitempty"`
+	Artifact       *BuildArtifact   `json:"artifact,omitempty"`
+	SBOM           *BuildDocument   `json:"sbom,omitempty"`
+	SLSAProvenance *BuildDocument   `json:"slsa_provenance,omitempty"`
+	CreatedAt      time.Time        `json:"created_at"`
+	StartedAt      *time.Time       `json:"started_at,omitempty"`
+	CompletedAt    *time.Time       `json:"completed_at,omitempty"`
+	DurationMS     *int64           `json:"duration_ms,omitempty"`
+	Resources      *BuildResources  `json:"resources,omitempty"`
+	RetryOf        *string          `json:"retry_of,omitempty"`
 }
 
 // CreateBuildRequest represents a request to create a new build
@@ -52,8 +62,95 @@ type CreateBuildRequest struct {
 
 	// Secrets are secret references to inject during build
 	Secrets []SecretRef `json:"secrets,omitempty"`
+
+	// Tenant is an optional label used to scope access to the build
+	Tenant string `json:"tenant,omitempty"`
+
+	// CreatedBy is the principal that requested the build, for quotas
+	CreatedBy string `json:"created_by,omitempty"`
+
+	// ArtifactPath makes this an artifact build: instead of pushing an image,
+	// the builder exports this path of the built filesystem as a tarball
+	ArtifactPath string `json:"artifact_path,omitempty"`
+}
+
+// RetryBuildRequest is a request to re-run a failed or cancelled build
+type RetryBuildRequest struct {
+	// BuildArgs override the original build's ARG values, which are kept
+	// otherwise
+	BuildArgs map[string]string `json:"build_args,omitempty"`
+
+	// CreatedBy is the principal retrying the build, for quotas (empty =
+	// the original's)
+	CreatedBy string `json:"created_by,omitempty"`
 }
 
+// BuildArtifact describes the tarball produced by an artifact build
+type BuildArtifact struct {
+	// Path is the exported path in the built filesystem
+	Path string `json:"path"`
+
+	// SizeBytes is the size of the tarball
+	SizeBytes int64 `json:"size_bytes"`
+
+	// SHA256 is the hex-encoded SHA256 of the tarball
+	SHA256 string `json:"sha256"`
+}
+
+// BuildResources is what a build's builder VM used, as measured on the host
+type BuildResources struct {
+	// CPUSeconds is the CPU time of the builder's hypervisor process
+	CPUSeconds float64 `json:"cpu_seconds"`
+
+	// PeakMemoryBytes is the peak resident memory of the builder's
+	// hypervisor process. Warm builders report their peak since they booted.
+	PeakMemoryBytes int64 `json:"peak_memory_bytes"`
+
+	// NetworkRxBytes and NetworkTxBytes are the bytes the builder received
+	// and sent, from hypervisor counters (0 where unsupported)
+	NetworkRxBytes int64 `json:"network_rx_bytes"`
+	NetworkTxBytes int64 `json:"network_tx_bytes"`
+}
+
+// DocumentKind identifies an attestation document of a build
+type DocumentKind string
+
+const (
+	// DocumentSBOM is the SPDX SBOM generated in the builder VM
+	DocumentSBOM DocumentKind = "sbom"
+
+	// DocumentSLSAProvenance is the SLSA v1 provenance statement
+	DocumentSLSAProvenance DocumentKind = "slsa_provenance"
+)
+
+// BuildDocument describes an attestation document stored with a build
+type BuildDocument struct {
+	// MediaType is the document's media type
+	MediaType string `json:"media_type"`
+
+	// SizeBytes is the size of the document
+	SizeBytes int64 `json:"size_bytes"`
+
+	// SHA256 is the hex-encoded SHA256 of the document
+	SHA256 string `json:"sha256"`
+
+	// ReferrerDigest is the digest of the OCI artifact the document was
+	// pushed as, attached to the built image (nil if it wasn't pushed)
+	ReferrerDigest *string `json:"referrer_digest,omitempty"`
+}
+
+// Network modes of a build
+const (
+	// NetworkModeIsolated builds without a network
+	NetworkModeIsolated = "isolated"
+	// NetworkModeEgress allows outbound traffic, limited to the allowlist
+	// if there is one
+	NetworkModeEgress = "egress"
+	// NetworkModeOffline only allows the registry, and mounts the package
+	// cache volume for dependencies
+	NetworkModeOffline = "offline"
+)
+
 // BuildPolicy defines resource limits and network policy for a build
 type BuildPolicy struct {
 	// TimeoutSeconds is the maximum build duration (default: 600)
@@ -65,11 +162,13 @@ type BuildPolicy struct {
 	// CPUs is the number of vCPUs for the builder VM (default: 2)
 	CPUs int `json:"cpus,omitempty"`
 
-	// NetworkMode controls network access during build
-	// "isolated" = no network, "egress" = outbound allowed
+	// NetworkMode controls network access during build: NetworkModeIsolated,
+	// NetworkModeEgress or NetworkModeOffline
 	NetworkMode string `json:"network_mode,omitempty"`
 
-	// AllowedDomains restricts egress to specific domains (only when NetworkMode="egress")
+	// AllowedDomains restricts egress to these hostnames, IPs or CIDRs, each
+	// with an optional port (only when NetworkMode="egress"). Empty uses the
+	// server's BUILD_EGRESS_ALLOWLIST.
 	AllowedDomains []string `json:"allowed_domains,omitempty"`
 }
 
@@ -120,6 +219,11 @@ type BuildConfig struct {
 	// The builder agent uses this token to authenticate with the registry.
 	RegistryToken string `json:"registry_token,omitempty"`
 
+	// RegistryMirrors are the upstream registries, like "docker.io", whose
+	// pulls go through the registry's pull-through cache at
+	// RegistryURL/proxy/{upstream}
+	RegistryMirrors []string `json:"registry_mirrors,omitempty"`
+
 	// CacheScope is the tenant-specific cache key prefix
 	CacheScope string `json:"cache_scope,omitempty"`
 
@@ -135,8 +239,23 @@ type BuildConfig struct {
 	// TimeoutSeconds is the build timeout
 	TimeoutSeconds int `json:"timeout_seconds"`
 
-	// NetworkMode is "isolated" or "egress"
+	// NetworkMode is "isolated", "egress" or "offline"
 	NetworkMode string `json:"network_mode"`
+
+	// AllowedHosts is the egress allowlist the host enforces, if any. The
+	// agent waits for host_ready before building when egress is restricted.
+	AllowedHosts []string `json:"allowed_hosts,omitempty"`
+
+	// PackageCachePath is where the package cache volume is mounted in
+	// offline builds, passed to the build as the "packages" context
+	PackageCachePath string `json:"package_cache_path,omitempty"`
+
+	// ArtifactPath is the path of the built filesystem to export as a
+	// tarball instead of pushing an image (artifact builds only)
+	ArtifactPath string `json:"artifact_path,omitempty"`
+
+	// ArtifactMaxBytes is the size limit of the artifact tarball
+	ArtifactMaxBytes int64 `json:"artifact_max_bytes,omitempty"`
 }
 
 // BuildEvent represents a typed SSE event for build streaming
@@ -180,6 +299,16 @@ type BuildResult struct {
 
 	// DurationMS is the build duration in milliseconds
 	DurationMS int64 `json:"duration_ms"`
+
+	// ArtifactBytes is the size of the uploaded artifact tarball (artifact builds only)
+	ArtifactBytes int64 `json:"artifact_bytes,omitempty"`
+
+	// ArtifactSHA256 is the hex-encoded SHA256 of the artifact tarball
+	ArtifactSHA256 string `json:"artifact_sha256,omitempty"`
+
+	// SBOM is the SPDX JSON SBOM of the build output, if the builder
+	// image ships syft
+	SBOM json.RawMessage `json:"sbom,omitempty"`
 }
 
 // DefaultBuildPolicy returns the default build policy
@@ -188,7 +317,7 @@ func DefaultBuildPolicy() BuildPolicy {
 		TimeoutSeconds: 600,  // 10 minutes
 		MemoryMB:       2048, // 2GB
 		CPUs:           2,
-		NetworkMode:    "egress", // Allow outbound for dependency downloads
+		NetworkMode:    NetworkModeEgress, // Allow outbound for dependency downloads
 	}
 }
 
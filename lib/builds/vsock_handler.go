@@ -19,6 +19,9 @@ type VsockMessage struct {
 	Log       string            `json:"log,omitempty"`
 	SecretIDs []string          `json:"secret_ids,omitempty"` // For secrets request
 	Secrets   map[string]string `json:"secrets,omitempty"`    // For secrets response
+	Job       *BuildConfig      `json:"job,omitempty"`        // For build_job to pooled builders
+	Source    []byte            `json:"source,omitempty"`     // Source tarball for build_job
+	Data      []byte            `json:"data,omitempty"`       // Artifact tarball chunk
 }
 
 // SecretsRequest is sent by the builder agent to fetch secrets
@@ -9,6 +9,39 @@ var (
 	// ErrInUse is returned when a device is currently attached to an instance
 	ErrInUse = errors.New("device is in use")
 
+	// ErrProfileUnavailable is returned when no VF can host another vGPU of a profile
+	ErrProfileUnavailable = errors.New("vGPU profile unavailable")
+
+	// ErrGPUQuotaExceeded is returned when a tenant is at its reservation's limit for a vGPU profile
+	ErrGPUQuotaExceeded = errors.New("vGPU quota exceeded")
+
+	// ErrInvalidReservation is returned when a vGPU reservation request fails validation
+	ErrInvalidReservation = errors.New("invalid vGPU reservation")
+
+	// ErrReservationExists is returned when a tenant already has a reservation for the profile
+	ErrReservationExists = errors.New("vGPU reservation already exists")
+
+	// ErrReservationNotFound is returned when a vGPU reservation is not found
+	ErrReservationNotFound = errors.New("vGPU reservation not found")
+
+	// ErrGPUNotFound is returned when the health loop hasn't seen a GPU
+	ErrGPUNotFound = errors.New("GPU not found")
+
+	// ErrGPUUnhealthy is returned when attaching a GPU the health loop cordoned
+	ErrGPUUnhealthy = errors.New("GPU is unhealthy")
+
+	// ErrNUMANodeNotFound is returned when a placement hint names a NUMA node the host doesn't have
+	ErrNUMANodeNotFound = errors.New("NUMA node not found on host")
+
+	// ErrInvalidUSBSelector is returned when a USB device selector is neither vendor:product nor bus-port
+	ErrInvalidUSBSelector = errors.New("invalid USB device selector")
+
+	// ErrUSBDeviceNotFound is returned when no host USB device matches a selector
+	ErrUSBDeviceNotFound = errors.New("USB device not found on host")
+
+	// ErrUSBDeviceAmbiguous is returned when several host USB devices match a vendor:product selector
+	ErrUSBDeviceAmbiguous = errors.New("USB device selector is ambiguous")
+
 	// ErrNotBound is returned when a VFIO operation requires the device to be bound
 	ErrNotBound = errors.New("device is not bound to VFIO")
 
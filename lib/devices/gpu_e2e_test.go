@@ -67,17 +67,17 @@ func TestGPUPassthrough(t *testing.T) {
 	}
 
 	// Initialize managers (nil meter/tracer disables metrics/tracing)
-	imageMgr, err := images.NewManager(p, 1, nil)
+	imageMgr, err := images.NewManager(p, 1, nil, nil)
 	require.NoError(t, err)
 
 	systemMgr := system.NewManager(p)
 	networkMgr := network.NewManager(p, cfg, nil)
 	deviceMgr := devices.NewManager(p)
-	volumeMgr := volumes.NewManager(p, 100*1024*1024*1024, nil) // 100GB max volume storage
+	volumeMgr := volumes.NewManager(p, 100*1024*1024*1024, nil, nil) // 100GB max volume storage
 	limits := instances.ResourceLimits{
 		MaxOverlaySize: 100 * 1024 * 1024 * 1024, // 100GB
 	}
-	instanceMgr := instances.NewManager(p, imageMgr, systemMgr, networkMgr, deviceMgr, volumeMgr, limits, "", nil, nil)
+	instanceMgr := instances.NewManager(p, imageMgr, systemMgr, networkMgr, deviceMgr, volumeMgr, nil, limits, "", nil, nil)
 
 	// Step 1: Discover available GPUs
 	t.Log("Step 1: Discovering available GPUs...")
@@ -211,7 +211,7 @@ func TestGPUPassthrough(t *testing.T) {
 	// Cleanup: always delete instance
 	t.Cleanup(func() {
 		t.Log("Cleanup: Deleting instance...")
-		instanceMgr.DeleteInstance(ctx, inst.Id)
+		instanceMgr.DeleteInstance(ctx, inst.Id, instances.DeleteInstanceRequest{})
 	})
 
 	// Step 6: Wait for instance to be ready
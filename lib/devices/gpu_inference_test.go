@@ -104,17 +104,17 @@ func TestGPUInference(t *testing.T) {
 	}
 
 	// Initialize managers
-	imageMgr, err := images.NewManager(p, 1, nil)
+	imageMgr, err := images.NewManager(p, 1, nil, nil)
 	require.NoError(t, err)
 
 	systemMgr := system.NewManager(p)
 	networkMgr := network.NewManager(p, cfg, nil)
 	deviceMgr := devices.NewManager(p)
-	volumeMgr := volumes.NewManager(p, 100*1024*1024*1024, nil)
+	volumeMgr := volumes.NewManager(p, 100*1024*1024*1024, nil, nil)
 	limits := instances.ResourceLimits{
 		MaxOverlaySize: 100 * 1024 * 1024 * 1024,
 	}
-	instanceMgr := instances.NewManager(p, imageMgr, systemMgr, networkMgr, deviceMgr, volumeMgr, limits, "", nil, nil)
+	instanceMgr := instances.NewManager(p, imageMgr, systemMgr, networkMgr, deviceMgr, volumeMgr, nil, limits, "", nil, nil)
 
 	// Step 1: Build custom CUDA+Ollama image
 	t.Log("Step 1: Building custom CUDA+Ollama Docker image...")
@@ -277,7 +277,7 @@ func TestGPUInference(t *testing.T) {
 
 	t.Cleanup(func() {
 		t.Log("Cleanup: Deleting instance...")
-		instanceMgr.DeleteInstance(ctx, inst.Id)
+		instanceMgr.DeleteInstance(ctx, inst.Id, instances.DeleteInstanceRequest{})
 	})
 
 	// Step 9: Wait for instance
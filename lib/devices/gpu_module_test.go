@@ -70,15 +70,15 @@ func TestNVIDIAModuleLoading(t *testing.T) {
 	}
 
 	// Initialize managers
-	imageMgr, err := images.NewManager(p, 1, nil)
+	imageMgr, err := images.NewManager(p, 1, nil, nil)
 	require.NoError(t, err)
 
 	systemMgr := system.NewManager(p)
 	networkMgr := network.NewManager(p, cfg, nil)
 	deviceMgr := devices.NewManager(p)
-	volumeMgr := volumes.NewManager(p, 10*1024*1024*1024, nil)
+	volumeMgr := volumes.NewManager(p, 10*1024*1024*1024, nil, nil)
 	limits := instances.ResourceLimits{MaxOverlaySize: 10 * 1024 * 1024 * 1024}
-	instanceMgr := instances.NewManager(p, imageMgr, systemMgr, networkMgr, deviceMgr, volumeMgr, limits, "", nil, nil)
+	instanceMgr := instances.NewManager(p, imageMgr, systemMgr, networkMgr, deviceMgr, volumeMgr, nil, limits, "", nil, nil)
 
 	// Step 1: Find an NVIDIA GPU
 	t.Log("Step 1: Discovering available GPUs...")
@@ -178,7 +178,7 @@ func TestNVIDIAModuleLoading(t *testing.T) {
 
 	t.Cleanup(func() {
 		t.Log("Cleanup: Deleting instance...")
-		instanceMgr.DeleteInstance(ctx, inst.Id)
+		instanceMgr.DeleteInstance(ctx, inst.Id, instances.DeleteInstanceRequest{})
 	})
 
 	// Wait for instance to be running
@@ -314,15 +314,15 @@ func TestNVMLDetection(t *testing.T) {
 		DNSServer:  "1.1.1.1",
 	}
 
-	imageMgr, err := images.NewManager(p, 1, nil)
+	imageMgr, err := images.NewManager(p, 1, nil, nil)
 	require.NoError(t, err)
 
 	systemMgr := system.NewManager(p)
 	networkMgr := network.NewManager(p, cfg, nil)
 	deviceMgr := devices.NewManager(p)
-	volumeMgr := volumes.NewManager(p, 10*1024*1024*1024, nil)
+	volumeMgr := volumes.NewManager(p, 10*1024*1024*1024, nil, nil)
 	limits := instances.ResourceLimits{MaxOverlaySize: 10 * 1024 * 1024 * 1024}
-	instanceMgr := instances.NewManager(p, imageMgr, systemMgr, networkMgr, deviceMgr, volumeMgr, limits, "", nil, nil)
+	instanceMgr := instances.NewManager(p, imageMgr, systemMgr, networkMgr, deviceMgr, volumeMgr, nil, limits, "", nil, nil)
 
 	// Step 1: Check if ollama-cuda:test image exists in Docker
 	t.Log("Step 1: Checking for ollama-cuda:test Docker image...")
@@ -424,7 +424,7 @@ func TestNVMLDetection(t *testing.T) {
 
 	t.Cleanup(func() {
 		t.Log("Cleanup: Deleting instance...")
-		instanceMgr.DeleteInstance(ctx, inst.Id)
+		instanceMgr.DeleteInstance(ctx, inst.Id, instances.DeleteInstanceRequest{})
 	})
 
 	err = waitForInstanceReady(ctx, t, instanceMgr, inst.Id, 60*time.Second)
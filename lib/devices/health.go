@@ -0,0 +1,310 @@
+package devices
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kernel/hypeman/lib/logger"
+)
+
+// criticalXIDs are the NVIDIA Xid errors after which a GPU needs a reset or
+// reboot before it can be trusted with new workloads.
+var criticalXIDs = map[int]string{
+	48:  "double bit ECC error",
+	62:  "internal micro-controller halt",
+	64:  "ECC page retirement or row remapping failure",
+	74:  "NVLink error",
+	79:  "GPU has fallen off the bus",
+	92:  "high single-bit ECC error rate",
+	95:  "uncontained ECC error",
+	119: "GSP RPC timeout",
+	120: "GSP error",
+}
+
+// xidPattern matches an Xid line in the kernel log, e.g.
+// [ 1234.567890] NVRM: Xid (PCI:0000:82:00): 79, pid=..., GPU has fallen off the bus.
+var xidPattern = regexp.MustCompile(`^\[\s*(\d+\.\d+)\]\s+NVRM: Xid \(PCI:([0-9a-fA-F]{4}:[0-9a-fA-F]{2}:[0-9a-fA-F]{2})\): (\d+)`)
+
+// runDmesg returns the kernel log. Replaced in tests.
+var runDmesg = func(ctx context.Context) (string, error) {
+	out, err := exec.CommandContext(ctx, "dmesg").Output()
+	return string(out), err
+}
+
+// readBootID returns the kernel's boot ID. Replaced in tests.
+var readBootID = func() string {
+	data, _ := os.ReadFile("/proc/sys/kernel/random/boot_id")
+	return strings.TrimSpace(string(data))
+}
+
+// cordonedGPUs holds the PCI addresses of unhealthy GPUs. No new vGPUs are
+// created on their VFs and they can't be attached for passthrough.
+var (
+	cordonedGPUs   = make(map[string]bool)
+	cordonedGPUsMu sync.RWMutex
+)
+
+func isGPUCordoned(pciAddress string) bool {
+	cordonedGPUsMu.RLock()
+	defer cordonedGPUsMu.RUnlock()
+	return cordonedGPUs[pciAddress]
+}
+
+func setCordonedGPUs(health map[string]*GPUHealth) {
+	cordoned := make(map[string]bool)
+	for addr, h := range health {
+		if !h.Healthy {
+			cordoned[addr] = true
+		}
+	}
+	cordonedGPUsMu.Lock()
+	cordonedGPUs = cordoned
+	cordonedGPUsMu.Unlock()
+}
+
+// gpuHealthState is the health loop's persisted state
+type gpuHealthState struct {
+	BootID  string                `json:"boot_id"`
+	XIDMark float64               `json:"xid_mark"` // kernel timestamp of the last Xid processed this boot
+	GPUs    map[string]*GPUHealth `json:"gpus"`
+}
+
+// xidEvent is an Xid error read from the kernel log
+type xidEvent struct {
+	Timestamp  float64
+	PCIAddress string
+	XID        int
+}
+
+func (m *manager) ListGPUHealth(ctx context.Context) ([]GPUHealth, error) {
+	m.healthMu.Lock()
+	defer m.healthMu.Unlock()
+
+	state, err := m.loadGPUHealth()
+	if err != nil {
+		return nil, err
+	}
+	return sortedGPUHealth(state.GPUs), nil
+}
+
+func (m *manager) CheckGPUHealth(ctx context.Context) ([]GPUHealth, error) {
+	log := logger.FromContext(ctx)
+
+	eccCounts, err := queryECCErrors(ctx)
+	if err != nil {
+		// A GPU that fell off the bus can fail the whole query: the kernel
+		// log's Xid errors are still checked
+		log.WarnContext(ctx, "failed to query GPU ECC errors", "error", err)
+		eccCounts = map[string]int64{}
+	} else if eccCounts == nil {
+		return nil, nil // No NVIDIA driver on this host
+	}
+	kernelLog, err := runDmesg(ctx)
+	if err != nil {
+		// ECC counters are still checked without the kernel log
+		log.WarnContext(ctx, "failed to read kernel log for GPU Xid errors", "error", err)
+	}
+
+	m.healthMu.Lock()
+	defer m.healthMu.Unlock()
+
+	state, err := m.loadGPUHealth()
+	if err != nil {
+		return nil, err
+	}
+	if bootID := readBootID(); bootID != state.BootID {
+		// Kernel log timestamps and volatile ECC counters restart with the host
+		state.BootID = bootID
+		state.XIDMark = 0
+		for _, h := range state.GPUs {
+			h.ECCBaseline = 0
+		}
+	}
+
+	newlyCordoned := evaluateGPUHealth(state, eccCounts, parseXIDs(kernelLog), time.Now())
+	if err := m.saveGPUHealth(state); err != nil {
+		return nil, fmt.Errorf("save GPU health: %w", err)
+	}
+	setCordonedGPUs(state.GPUs)
+
+	for _, h := range newlyCordoned {
+		log.WarnContext(ctx, "GPU unhealthy, cordoned", "event", "gpu_cordoned", "pci_address", h.PCIAddress, "reason", h.Reason)
+	}
+	return sortedGPUHealth(state.GPUs), nil
+}
+
+func (m *manager) UncordonGPU(ctx context.Context, pciAddress string) (*GPUHealth, error) {
+	m.healthMu.Lock()
+	defer m.healthMu.Unlock()
+
+	state, err := m.loadGPUHealth()
+	if err != nil {
+		return nil, err
+	}
+	h, ok := state.GPUs[pciAddress]
+	if !ok {
+		return nil, ErrGPUNotFound
+	}
+
+	// Errors seen so far are accepted; only new ones cordon the GPU again
+	h.Healthy = true
+	h.Reason = ""
+	h.UnhealthySince = nil
+	h.ECCBaseline = h.ECCUncorrected
+	if err := m.saveGPUHealth(state); err != nil {
+		return nil, fmt.Errorf("save GPU health: %w", err)
+	}
+	setCordonedGPUs(state.GPUs)
+
+	logger.FromContext(ctx).InfoContext(ctx, "GPU uncordoned", "event", "gpu_uncordoned", "pci_address", pciAddress)
+	result := *h
+	return &result, nil
+}
+
+// evaluateGPUHealth updates state with the current uncorrectable ECC counts
+// and the kernel log's Xid errors, cordoning GPUs with new critical Xids or
+// uncorrectable ECC errors, and returns the GPUs it cordoned.
+func evaluateGPUHealth(state *gpuHealthState, eccCounts map[string]int64, xids []xidEvent, now time.Time) []GPUHealth {
+	if state.GPUs == nil {
+		state.GPUs = make(map[string]*GPUHealth)
+	}
+	get := func(addr string) *GPUHealth {
+		h, ok := state.GPUs[addr]
+		if !ok {
+			h = &GPUHealth{PCIAddress: addr, Healthy: true}
+			state.GPUs[addr] = h
+		}
+		return h
+	}
+
+	var cordoned []GPUHealth
+	cordon := func(h *GPUHealth, reason string) {
+		if !h.Healthy {
+			return
+		}
+		h.Healthy = false
+		h.Reason = reason
+		h.UnhealthySince = &now
+		cordoned = append(cordoned, *h)
+	}
+
+	for addr, count := range eccCounts {
+		h := get(addr)
+		h.CheckedAt = now
+		if count < h.ECCBaseline {
+			// Volatile counters were reset by a driver reload
+			h.ECCBaseline = 0
+		}
+		h.ECCUncorrected = count
+		if count > h.ECCBaseline {
+			cordon(h, fmt.Sprintf("%d uncorrectable ECC errors", count-h.ECCBaseline))
+		}
+	}
+
+	for _, x := range xids {
+		if x.Timestamp <= state.XIDMark {
+			continue
+		}
+		state.XIDMark = x.Timestamp
+		desc, critical := criticalXIDs[x.XID]
+		if !critical {
+			continue
+		}
+		h := get(x.PCIAddress)
+		h.XIDErrors++
+		h.LastXID = x.XID
+		cordon(h, fmt.Sprintf("Xid %d: %s", x.XID, desc))
+	}
+	return cordoned
+}
+
+// queryECCErrors returns the volatile uncorrectable ECC error count of each
+// GPU by PCI address, or nil if nvidia-smi isn't installed. GPUs without ECC
+// report 0.
+func queryECCErrors(ctx context.Context) (map[string]int64, error) {
+	out, err := runNvidiaSMI(ctx, "--query-gpu=pci.bus_id,ecc.errors.uncorrected.volatile.total", "--format=csv,noheader,nounits")
+	if err != nil {
+		if errors.Is(err, exec.ErrNotFound) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("query GPU ECC errors: %w: %s", err, strings.TrimSpace(out))
+	}
+	return parseECCErrors(out), nil
+}
+
+func parseECCErrors(out string) map[string]int64 {
+	counts := make(map[string]int64)
+	for _, line := range strings.Split(strings.TrimSpace(out), "\n") {
+		busID, value, ok := strings.Cut(line, ",")
+		if !ok {
+			continue
+		}
+		count, _ := strconv.ParseInt(strings.TrimSpace(value), 10, 64) // "[N/A]" without ECC
+		counts[normalizePCIAddress(strings.TrimSpace(busID))] = count
+	}
+	return counts
+}
+
+// parseXIDs returns the Xid errors in the kernel log. The log names the GPU
+// by domain, bus and device only; GPUs are function 0.
+func parseXIDs(kernelLog string) []xidEvent {
+	var events []xidEvent
+	for _, line := range strings.Split(kernelLog, "\n") {
+		m := xidPattern.FindStringSubmatch(line)
+		if m == nil {
+			continue
+		}
+		ts, _ := strconv.ParseFloat(m[1], 64)
+		xid, _ := strconv.Atoi(m[3])
+		events = append(events, xidEvent{Timestamp: ts, PCIAddress: strings.ToLower(m[2]) + ".0", XID: xid})
+	}
+	return events
+}
+
+func sortedGPUHealth(gpus map[string]*GPUHealth) []GPUHealth {
+	result := make([]GPUHealth, 0, len(gpus))
+	for _, h := range gpus {
+		result = append(result, *h)
+	}
+	slices.SortFunc(result, func(a, b GPUHealth) int { return strings.Compare(a.PCIAddress, b.PCIAddress) })
+	return result
+}
+
+func (m *manager) loadGPUHealth() (*gpuHealthState, error) {
+	state := &gpuHealthState{GPUs: make(map[string]*GPUHealth)}
+	data, err := os.ReadFile(m.paths.GPUHealth())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return state, nil
+		}
+		return nil, fmt.Errorf("read GPU health: %w", err)
+	}
+	if err := json.Unmarshal(data, state); err != nil {
+		return nil, fmt.Errorf("parse GPU health: %w", err)
+	}
+	if state.GPUs == nil {
+		state.GPUs = make(map[string]*GPUHealth)
+	}
+	return state, nil
+}
+
+func (m *manager) saveGPUHealth(state *gpuHealthState) error {
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(m.paths.DevicesDir(), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(m.paths.GPUHealth(), data, 0644)
+}
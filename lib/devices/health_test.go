@@ -0,0 +1,106 @@
+package devices
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/kernel/hypeman/lib/paths"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testKernelLog = `[    5.123456] nvidia: loading out-of-tree module taints kernel.
+[ 1200.000001] NVRM: Xid (PCI:0000:82:00): 13, pid=4242, name=python, Graphics Exception
+[ 1300.500000] NVRM: Xid (PCI:0000:C1:00): 79, pid='<unknown>', name=<unknown>, GPU has fallen off the bus.
+`
+
+func TestParseXIDs(t *testing.T) {
+	assert.Equal(t, []xidEvent{
+		{Timestamp: 1200.000001, PCIAddress: "0000:82:00.0", XID: 13},
+		{Timestamp: 1300.5, PCIAddress: "0000:c1:00.0", XID: 79},
+	}, parseXIDs(testKernelLog))
+}
+
+func TestParseECCErrors(t *testing.T) {
+	out := `00000000:82:00.0, 0
+00000000:C1:00.0, 2
+00000000:03:00.0, [N/A]
+`
+	assert.Equal(t, map[string]int64{"0000:82:00.0": 0, "0000:c1:00.0": 2, "0000:03:00.0": 0}, parseECCErrors(out))
+}
+
+func TestEvaluateGPUHealth(t *testing.T) {
+	now := time.Now()
+	state := &gpuHealthState{}
+
+	cordoned := evaluateGPUHealth(state, map[string]int64{"0000:82:00.0": 0, "0000:c1:00.0": 0}, parseXIDs(testKernelLog), now)
+	require.Len(t, cordoned, 1, "Xid 13 is an application error, Xid 79 is critical")
+	assert.Equal(t, "0000:c1:00.0", cordoned[0].PCIAddress)
+	assert.Equal(t, "Xid 79: GPU has fallen off the bus", cordoned[0].Reason)
+	assert.True(t, state.GPUs["0000:82:00.0"].Healthy)
+	assert.Equal(t, 1300.5, state.XIDMark)
+
+	// The same kernel log again doesn't count its Xids twice
+	cordoned = evaluateGPUHealth(state, map[string]int64{"0000:82:00.0": 0}, parseXIDs(testKernelLog), now)
+	assert.Empty(t, cordoned)
+	assert.Equal(t, 1, state.GPUs["0000:c1:00.0"].XIDErrors)
+
+	// Uncorrectable ECC errors above the baseline cordon the GPU
+	state.GPUs["0000:82:00.0"].ECCBaseline = 1
+	assert.Empty(t, evaluateGPUHealth(state, map[string]int64{"0000:82:00.0": 1}, nil, now))
+	cordoned = evaluateGPUHealth(state, map[string]int64{"0000:82:00.0": 3}, nil, now)
+	require.Len(t, cordoned, 1)
+	assert.Equal(t, "2 uncorrectable ECC errors", cordoned[0].Reason)
+}
+
+func TestGPUHealthCordon(t *testing.T) {
+	ctx := context.Background()
+	t.Cleanup(func() { setCordonedGPUs(nil) })
+
+	eccCount := "0"
+	stubNvidiaSMI(t, func(args []string) (string, error) {
+		return "00000000:82:00.0, " + eccCount + "\n", nil
+	})
+	origDmesg, origBootID := runDmesg, readBootID
+	runDmesg = func(ctx context.Context) (string, error) { return "", nil }
+	readBootID = func() string { return "boot-1" }
+	t.Cleanup(func() { runDmesg, readBootID = origDmesg, origBootID })
+
+	p := paths.New(t.TempDir())
+	mgr := NewManager(p).(*manager)
+	device := &Device{Id: "gpu0", Name: "gpu0", Type: DeviceTypeGPU, PCIAddress: "0000:82:00.0"}
+	require.NoError(t, os.MkdirAll(p.DeviceDir(device.Id), 0755))
+	require.NoError(t, mgr.saveDevice(device))
+
+	health, err := mgr.CheckGPUHealth(ctx)
+	require.NoError(t, err)
+	require.Len(t, health, 1)
+	assert.True(t, health[0].Healthy)
+
+	eccCount = "1"
+	health, err = mgr.CheckGPUHealth(ctx)
+	require.NoError(t, err)
+	assert.False(t, health[0].Healthy)
+	assert.True(t, isGPUCordoned("0000:82:00.0"))
+	assert.ErrorIs(t, mgr.MarkAttached(ctx, "gpu0", "inst1"), ErrGPUUnhealthy)
+
+	// A restarted manager keeps the GPU cordoned
+	setCordonedGPUs(nil)
+	NewManager(p)
+	assert.True(t, isGPUCordoned("0000:82:00.0"))
+
+	_, err = mgr.UncordonGPU(ctx, "0000:99:00.0")
+	assert.ErrorIs(t, err, ErrGPUNotFound)
+	h, err := mgr.UncordonGPU(ctx, "0000:82:00.0")
+	require.NoError(t, err)
+	assert.True(t, h.Healthy)
+	assert.False(t, isGPUCordoned("0000:82:00.0"))
+	require.NoError(t, mgr.MarkAttached(ctx, "gpu0", "inst1"))
+
+	// The accepted ECC errors don't cordon it again
+	health, err = mgr.CheckGPUHealth(ctx)
+	require.NoError(t, err)
+	assert.True(t, health[0].Healthy)
+}
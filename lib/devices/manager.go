@@ -32,6 +32,9 @@ type InstanceLivenessChecker interface {
 	// DetectSuspiciousVMMProcesses finds cloud-hypervisor processes that don't match
 	// known instances and logs warnings. Returns the count of suspicious processes found.
 	DetectSuspiciousVMMProcesses(ctx context.Context) int
+
+	// ListVGPUAllocations returns the vGPU of every instance that has one.
+	ListVGPUAllocations(ctx context.Context) []VGPUAllocation
 }
 
 // Manager provides device management operations
@@ -54,8 +57,11 @@ type Manager interface {
 	// BindToVFIO binds a device to vfio-pci driver
 	BindToVFIO(ctx context.Context, id string) error
 
-	// UnbindFromVFIO unbinds a device from vfio-pci driver
-	UnbindFromVFIO(ctx context.Context, id string) error
+	// UnbindFromVFIO unbinds a device from vfio-pci driver and rebinds it to
+	// the host driver it had before BindToVFIO. Unless force is set, it refuses
+	// while the device or another device in its IOMMU group is attached to an
+	// instance.
+	UnbindFromVFIO(ctx context.Context, id string, force bool) error
 
 	// MarkAttached marks a device as attached to an instance
 	MarkAttached(ctx context.Context, deviceID, instanceID string) error
@@ -68,6 +74,41 @@ type Manager interface {
 	// and clears the orphaned attachment state.
 	ReconcileDevices(ctx context.Context) error
 
+	// ListGPUReservations returns all vGPU profile reservations
+	ListGPUReservations(ctx context.Context) ([]GPUReservation, error)
+
+	// CreateGPUReservation reserves vGPUs of a profile for a tenant and
+	// optionally caps how many the tenant may use
+	CreateGPUReservation(ctx context.Context, req CreateGPUReservationRequest) (*GPUReservation, error)
+
+	// DeleteGPUReservation removes a reservation
+	DeleteGPUReservation(ctx context.Context, id string) error
+
+	// CheckGPUReservations returns an error wrapping ErrGPUQuotaExceeded if
+	// tenant is at its limit for the profile, or ErrProfileUnavailable if the
+	// profile's free vGPUs are reserved for other tenants.
+	CheckGPUReservations(ctx context.Context, profile, tenant string) error
+
+	// ReservedGPUs returns, per profile, the reserved vGPUs their tenants
+	// have yet to use
+	ReservedGPUs(ctx context.Context) map[string]int
+
+	// ListUSBDevices discovers USB devices on the host that can be passed
+	// through to instances
+	ListUSBDevices(ctx context.Context) ([]USBDevice, error)
+
+	// ListGPUHealth returns the health of each GPU as last checked
+	ListGPUHealth(ctx context.Context) ([]GPUHealth, error)
+
+	// CheckGPUHealth checks each GPU's uncorrectable ECC counters and the
+	// kernel log's Xid errors, cordons GPUs with new errors, and returns the
+	// updated health
+	CheckGPUHealth(ctx context.Context) ([]GPUHealth, error)
+
+	// UncordonGPU marks a cordoned GPU healthy again, accepting the errors
+	// seen so far
+	UncordonGPU(ctx context.Context, pciAddress string) (*GPUHealth, error)
+
 	// SetLivenessChecker sets the instance liveness checker after construction.
 	// This allows breaking the circular dependency between device and instance managers.
 	SetLivenessChecker(checker InstanceLivenessChecker)
@@ -77,16 +118,25 @@ type manager struct {
 	paths           *paths.Paths
 	vfioBinder      *VFIOBinder
 	livenessChecker InstanceLivenessChecker
+	listProfiles    func() ([]GPUProfile, error) // ListGPUProfiles, replaced in tests
 	mu              sync.RWMutex
+	healthMu        sync.Mutex // guards the GPU health state file
 }
 
 // NewManager creates a new device manager.
 // Use SetLivenessChecker after construction to enable accurate orphan detection.
 func NewManager(p *paths.Paths) Manager {
-	return &manager{
-		paths:      p,
-		vfioBinder: NewVFIOBinder(),
+	m := &manager{
+		paths:        p,
+		vfioBinder:   NewVFIOBinder(),
+		listProfiles: ListGPUProfiles,
+	}
+
+	// GPUs cordoned before a restart stay cordoned
+	if state, err := m.loadGPUHealth(); err == nil {
+		setCordonedGPUs(state.GPUs)
 	}
+	return m
 }
 
 // SetLivenessChecker sets the instance liveness checker.
@@ -292,6 +342,11 @@ func (m *manager) BindToVFIO(ctx context.Context, id string) error {
 		return err
 	}
 
+	// Record the host driver to restore on unbind
+	if driver := readCurrentDriver(device.PCIAddress); driver != nil && *driver != "vfio-pci" {
+		device.OriginalDriver = *driver
+	}
+
 	// Bind to VFIO
 	if err := m.vfioBinder.BindToVFIO(device.PCIAddress); err != nil {
 		return err
@@ -307,12 +362,13 @@ func (m *manager) BindToVFIO(ctx context.Context, id string) error {
 		"id", device.Id,
 		"name", device.Name,
 		"pci_address", device.PCIAddress,
+		"original_driver", device.OriginalDriver,
 	)
 
 	return nil
 }
 
-func (m *manager) UnbindFromVFIO(ctx context.Context, id string) error {
+func (m *manager) UnbindFromVFIO(ctx context.Context, id string, force bool) error {
 	log := logger.FromContext(ctx)
 
 	m.mu.Lock()
@@ -327,13 +383,36 @@ func (m *manager) UnbindFromVFIO(ctx context.Context, id string) error {
 		}
 	}
 
-	// Check if device is attached
-	if device.AttachedTo != nil {
-		return ErrInUse
+	if !m.vfioBinder.IsDeviceBoundToVFIO(device.PCIAddress) {
+		return ErrNotBound
+	}
+
+	if !force {
+		// Check if device is attached
+		if device.AttachedTo != nil {
+			return ErrInUse
+		}
+
+		// A device whose IOMMU group peer is still passed through can't be
+		// handed back to the host: the VM owns the whole group
+		groupDevices, err := GetIOMMUGroupDevices(device.IOMMUGroup)
+		if err != nil {
+			return fmt.Errorf("get iommu group devices: %w", err)
+		}
+		if peers := attachedGroupPeers(device.PCIAddress, groupDevices, m.findByPCIAddress); len(peers) > 0 {
+			return fmt.Errorf("%w: IOMMU group %d has devices attached to instances: %s",
+				ErrIOMMUGroupConflict, device.IOMMUGroup, strings.Join(peers, ", "))
+		}
+	} else if device.AttachedTo != nil {
+		log.WarnContext(ctx, "force unbinding device attached to an instance",
+			"id", device.Id,
+			"pci_address", device.PCIAddress,
+			"instance_id", *device.AttachedTo,
+		)
 	}
 
-	// Unbind from VFIO
-	if err := m.vfioBinder.UnbindFromVFIO(device.PCIAddress); err != nil {
+	// Unbind from VFIO and rebind to the original driver
+	if err := m.vfioBinder.UnbindFromVFIO(device.PCIAddress, device.OriginalDriver); err != nil {
 		return err
 	}
 
@@ -343,10 +422,24 @@ func (m *manager) UnbindFromVFIO(ctx context.Context, id string) error {
 		return fmt.Errorf("save device: %w", err)
 	}
 
+	var driver string
+	if d := readCurrentDriver(device.PCIAddress); d != nil {
+		driver = *d
+	}
+	if device.OriginalDriver != "" && driver != device.OriginalDriver {
+		log.WarnContext(ctx, "device not rebound to its original driver",
+			"id", device.Id,
+			"pci_address", device.PCIAddress,
+			"original_driver", device.OriginalDriver,
+			"driver", driver,
+		)
+	}
+
 	log.InfoContext(ctx, "unbound device from VFIO",
 		"id", device.Id,
 		"name", device.Name,
 		"pci_address", device.PCIAddress,
+		"driver", driver,
 	)
 
 	return nil
@@ -367,6 +460,9 @@ func (m *manager) MarkAttached(ctx context.Context, deviceID, instanceID string)
 	if device.AttachedTo != nil {
 		return ErrInUse
 	}
+	if isGPUCordoned(device.PCIAddress) {
+		return fmt.Errorf("%w: %s", ErrGPUUnhealthy, device.PCIAddress)
+	}
 
 	device.AttachedTo = &instanceID
 	return m.saveDevice(device)
@@ -659,15 +755,9 @@ func (m *manager) resetOrphanedDevice(ctx context.Context, device *Device, stats
 		// Continue with other steps
 	}
 
-	// Step 3: Trigger driver probe to rebind to original driver
-	log.DebugContext(ctx, "triggering driver probe", "pci_address", device.PCIAddress)
-	if err := m.vfioBinder.triggerDriverProbe(device.PCIAddress); err != nil {
-		log.WarnContext(ctx, "failed to trigger driver probe during reset",
-			"device_id", device.Id,
-			"pci_address", device.PCIAddress,
-			"error", err,
-		)
-	}
+	// Step 3: Rebind to original driver (or probe for one)
+	log.DebugContext(ctx, "rebinding to original driver", "pci_address", device.PCIAddress, "original_driver", device.OriginalDriver)
+	m.vfioBinder.rebindToDriver(device.PCIAddress, device.OriginalDriver)
 
 	// Step 4: For NVIDIA devices, restart nvidia-persistenced
 	if device.VendorID == "10de" {
@@ -759,6 +849,23 @@ func (m *manager) findByName(name string) (*Device, error) {
 	return nil, ErrNotFound
 }
 
+// attachedGroupPeers returns the devices in an IOMMU group, other than
+// pciAddress, that are registered and attached to an instance.
+func attachedGroupPeers(pciAddress string, groupDevices []string, lookup func(pciAddress string) (*Device, error)) []string {
+	var peers []string
+	for _, addr := range groupDevices {
+		if addr == pciAddress {
+			continue
+		}
+		peer, err := lookup(addr)
+		if err != nil || peer.AttachedTo == nil {
+			continue
+		}
+		peers = append(peers, fmt.Sprintf("%s (instance %s)", addr, *peer.AttachedTo))
+	}
+	return peers
+}
+
 func (m *manager) findByPCIAddress(pciAddress string) (*Device, error) {
 	entries, err := os.ReadDir(m.paths.DevicesDir())
 	if err != nil {
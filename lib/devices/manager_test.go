@@ -141,6 +141,26 @@ func TestVFIOBinderIsVFIOAvailable(t *testing.T) {
 	_ = binder.IsVFIOAvailable()
 }
 
+func TestAttachedGroupPeers(t *testing.T) {
+	inst := "inst1"
+	registered := map[string]*Device{
+		"0000:a2:00.0": {PCIAddress: "0000:a2:00.0", AttachedTo: &inst},
+		"0000:a2:00.1": {PCIAddress: "0000:a2:00.1", AttachedTo: &inst},
+		"0000:a2:00.2": {PCIAddress: "0000:a2:00.2"},
+	}
+	lookup := func(addr string) (*Device, error) {
+		if d, ok := registered[addr]; ok {
+			return d, nil
+		}
+		return nil, ErrNotFound
+	}
+
+	// The device itself, unattached and unregistered peers don't conflict
+	group := []string{"0000:a2:00.0", "0000:a2:00.1", "0000:a2:00.2", "0000:a2:00.3"}
+	assert.Equal(t, []string{"0000:a2:00.1 (instance inst1)"}, attachedGroupPeers("0000:a2:00.0", group, lookup))
+	assert.Empty(t, attachedGroupPeers("0000:a2:00.2", []string{"0000:a2:00.2", "0000:a2:00.3"}, lookup))
+}
+
 func TestDeviceTypes(t *testing.T) {
 	t.Run("device type constants", func(t *testing.T) {
 		require.Equal(t, DeviceType("gpu"), DeviceTypeGPU)
@@ -76,6 +76,7 @@ func DiscoverVFs() ([]VirtualFunction, error) {
 			PCIAddress: vfAddr,
 			ParentGPU:  parentGPU,
 			HasMdev:    hasMdev,
+			NUMANode:   readNUMANode(vfAddr),
 		})
 	}
 
@@ -107,6 +108,7 @@ func ListGPUProfilesWithVFs(vfs []VirtualFunction) ([]GPUProfile, error) {
 
 	// Count availability for all profiles in parallel
 	availability := countAvailableVFsForProfilesParallel(vfs, cachedProfiles)
+	addMIGAvailability(vfs, cachedProfiles, availability)
 
 	// Build result with dynamic availability counts
 	profiles := make([]GPUProfile, 0, len(cachedProfiles))
@@ -204,7 +206,7 @@ func countAvailableVFsForProfilesParallel(vfs []VirtualFunction, profiles []prof
 	// Group free VFs by parent GPU (done once, shared by all goroutines)
 	freeVFsByParent := make(map[string][]VirtualFunction)
 	for _, vf := range vfs {
-		if vf.HasMdev {
+		if vf.HasMdev || isGPUCordoned(vf.ParentGPU) {
 			continue
 		}
 		freeVFsByParent[vf.ParentGPU] = append(freeVFsByParent[vf.ParentGPU], vf)
@@ -254,6 +256,42 @@ func countAvailableForSingleProfile(freeVFsByParent map[string][]VirtualFunction
 	return count
 }
 
+// addMIGAvailability adds to availability the vGPUs of MIG-backed profiles
+// that need a GPU instance created first, which available_instances doesn't
+// count.
+func addMIGAvailability(vfs []VirtualFunction, profiles []profileMetadata, availability map[string]int) {
+	var migProfiles []profileMetadata
+	for _, meta := range profiles {
+		if _, ok := MIGProfileForVGPU(meta.Name); ok {
+			migProfiles = append(migProfiles, meta)
+		}
+	}
+	if len(migProfiles) == 0 {
+		return
+	}
+
+	ctx := context.Background()
+	gpus, err := ListMIGGPUs(ctx)
+	if err != nil || len(gpus) == 0 {
+		return
+	}
+	capacity, err := freeMIGCapacity(ctx)
+	if err != nil {
+		return
+	}
+
+	freeVFsByParent := make(map[string][]VirtualFunction)
+	for _, vf := range vfs {
+		if !vf.HasMdev && !isGPUCordoned(vf.ParentGPU) {
+			freeVFsByParent[vf.ParentGPU] = append(freeVFsByParent[vf.ParentGPU], vf)
+		}
+	}
+	for _, meta := range migProfiles {
+		migProfile, _ := MIGProfileForVGPU(meta.Name)
+		availability[meta.TypeName] += migAvailability(freeVFsByParent, meta.TypeName, migProfile, gpus, capacity)
+	}
+}
+
 // findProfileType finds the internal type name (e.g., "nvidia-556") for a profile name (e.g., "L40S-1Q")
 func findProfileType(profileName string) (string, error) {
 	vfs, err := DiscoverVFs()
@@ -352,11 +390,30 @@ func getProfileNameFromType(profileType, vfAddress string) string {
 	return strings.TrimSpace(string(data))
 }
 
+// CheckProfileAvailable returns ErrProfileUnavailable unless a VF can
+// currently host a vGPU of the profile, as CreateMdev would require.
+func CheckProfileAvailable(profileName string) error {
+	if _, err := findProfileType(profileName); err != nil {
+		return err
+	}
+	profiles, err := ListGPUProfiles()
+	if err != nil {
+		return fmt.Errorf("list vGPU profiles: %w", err)
+	}
+	for _, p := range profiles {
+		if p.Name == profileName && p.Available > 0 {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: no available VF for profile %q", ErrProfileUnavailable, profileName)
+}
+
 // CreateMdev creates an mdev device for the given profile and instance.
-// It finds an available VF and creates the mdev, returning the device info.
+// It finds an available VF, preferring those that match the placement
+// hints, and creates the mdev, returning the device info.
 // This function is thread-safe and uses a mutex to prevent race conditions
 // when multiple instances request vGPUs concurrently.
-func CreateMdev(ctx context.Context, profileName, instanceID string) (*MdevDevice, error) {
+func CreateMdev(ctx context.Context, profileName, instanceID string, placement VFPlacement) (*MdevDevice, error) {
 	log := logger.FromContext(ctx)
 
 	// Lock to prevent race conditions when multiple instances request the same profile
@@ -375,28 +432,37 @@ func CreateMdev(ctx context.Context, profileName, instanceID string) (*MdevDevic
 		return nil, fmt.Errorf("discover VFs: %w", err)
 	}
 
-	var targetVF string
-	for _, vf := range vfs {
-		// Skip VFs that already have an mdev
-		if vf.HasMdev {
-			continue
+	// Order VFs by placement hints so the first free one is the best fit
+	var near, away map[string]bool
+	if len(placement.NearMdevs) > 0 || len(placement.AwayFromMdevs) > 0 {
+		mdevs, err := ListMdevDevices()
+		if err != nil {
+			return nil, fmt.Errorf("list mdevs: %w", err)
 		}
-		// Check if this VF can create the profile
-		availPath := filepath.Join(mdevBusPath, vf.PCIAddress, "mdev_supported_types", profileType, "available_instances")
-		data, err := os.ReadFile(availPath)
+		near = mdevParentGPUs(placement.NearMdevs, mdevs, vfs)
+		away = mdevParentGPUs(placement.AwayFromMdevs, mdevs, vfs)
+	}
+	vfs = orderVFs(vfs, placement, near, away)
+
+	targetVF := findFreeVF(vfs, profileType, "")
+
+	// A MIG-backed profile needs a free GPU instance of its MIG profile on
+	// the VF's parent GPU; create one if none is left
+	var mig *MIGInstance
+	if migProfile, ok := MIGProfileForVGPU(profileName); ok && targetVF == "" {
+		var parentGPU string
+		mig, parentGPU, err = createMIGInstanceForVF(ctx, vfs, profileType, migProfile)
 		if err != nil {
-			continue
+			return nil, err
 		}
-		instances, err := strconv.Atoi(strings.TrimSpace(string(data)))
-		if err != nil || instances < 1 {
-			continue
+		if targetVF = findFreeVF(vfs, profileType, parentGPU); targetVF == "" {
+			destroyMIGOnError(ctx, *mig)
+			return nil, fmt.Errorf("%w: no VF on GPU %s can use the new %s GPU instance", ErrProfileUnavailable, parentGPU, migProfile)
 		}
-		targetVF = vf.PCIAddress
-		break
 	}
 
 	if targetVF == "" {
-		return nil, fmt.Errorf("no available VF for profile %q", profileName)
+		return nil, fmt.Errorf("%w: no available VF for profile %q", ErrProfileUnavailable, profileName)
 	}
 
 	// Generate UUID for the mdev
@@ -407,6 +473,9 @@ func CreateMdev(ctx context.Context, profileName, instanceID string) (*MdevDevic
 	// Create mdev by writing UUID to create file
 	createPath := filepath.Join(mdevBusPath, targetVF, "mdev_supported_types", profileType, "create")
 	if err := os.WriteFile(createPath, []byte(mdevUUID), 0200); err != nil {
+		if mig != nil {
+			destroyMIGOnError(ctx, *mig)
+		}
 		return nil, fmt.Errorf("create mdev on VF %s: %w", targetVF, err)
 	}
 
@@ -419,9 +488,47 @@ func CreateMdev(ctx context.Context, profileName, instanceID string) (*MdevDevic
 		ProfileName: profileName,
 		SysfsPath:   filepath.Join(mdevDevices, mdevUUID),
 		InstanceID:  instanceID,
+		MIG:         mig,
 	}, nil
 }
 
+// findFreeVF returns a VF without an mdev that can currently create an mdev
+// of profileType, optionally only on the given parent GPU, or "" if none can.
+func findFreeVF(vfs []VirtualFunction, profileType, parentGPU string) string {
+	for _, vf := range vfs {
+		// Skip VFs that already have an mdev or whose GPU is cordoned
+		if vf.HasMdev || isGPUCordoned(vf.ParentGPU) || (parentGPU != "" && vf.ParentGPU != parentGPU) {
+			continue
+		}
+		// Check if this VF can create the profile
+		availPath := filepath.Join(mdevBusPath, vf.PCIAddress, "mdev_supported_types", profileType, "available_instances")
+		data, err := os.ReadFile(availPath)
+		if err != nil {
+			continue
+		}
+		instances, err := strconv.Atoi(strings.TrimSpace(string(data)))
+		if err != nil || instances < 1 {
+			continue
+		}
+		return vf.PCIAddress
+	}
+	return ""
+}
+
+// vfSupportsType reports whether a VF lists profileType among its mdev types
+func vfSupportsType(vfAddress, profileType string) bool {
+	_, err := os.Stat(filepath.Join(mdevBusPath, vfAddress, "mdev_supported_types", profileType))
+	return err == nil
+}
+
+// destroyMIGOnError destroys a GPU instance created for an mdev that
+// couldn't be created.
+func destroyMIGOnError(ctx context.Context, mig MIGInstance) {
+	if err := DestroyMIGInstance(ctx, mig); err != nil {
+		logger.FromContext(ctx).WarnContext(ctx, "failed to destroy MIG GPU instance after mdev creation failed", "gpu", mig.GPUIndex, "gi", mig.InstanceID, "error", err)
+	}
+}
+
 // DestroyMdev removes an mdev device.
 func DestroyMdev(ctx context.Context, mdevUUID string) error {
 	log := logger.FromContext(ctx)
@@ -475,17 +582,19 @@ type MdevReconcileInfo struct {
 //   - Never destroys mdevs created by other processes on the host
 //   - Skips mdevs that are currently bound to a driver (in use by a VM)
 //   - Skips mdevs for instances in Running or Unknown state
-func ReconcileMdevs(ctx context.Context, instanceInfos []MdevReconcileInfo) error {
+//
+// It returns the number of mdevs destroyed.
+func ReconcileMdevs(ctx context.Context, instanceInfos []MdevReconcileInfo) (int, error) {
 	log := logger.FromContext(ctx)
 
 	mdevs, err := ListMdevDevices()
 	if err != nil {
-		return fmt.Errorf("list mdevs: %w", err)
+		return 0, fmt.Errorf("list mdevs: %w", err)
 	}
 
 	if len(mdevs) == 0 {
 		log.DebugContext(ctx, "no mdev devices found to reconcile")
-		return nil
+		return 0, nil
 	}
 
 	// Build lookup maps from instance info
@@ -543,5 +652,5 @@ func ReconcileMdevs(ctx context.Context, instanceInfos []MdevReconcileInfo) erro
 		"skipped_running", skippedRunning,
 	)
 
-	return nil
+	return destroyed, nil
 }
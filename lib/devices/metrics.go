@@ -0,0 +1,60 @@
+package devices
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// RegisterGPUHealthMetrics registers gauges reporting each GPU's health as
+// last checked by the GPU health loop.
+func RegisterGPUHealthMetrics(meter metric.Meter, mgr Manager) error {
+	healthy, err := meter.Int64ObservableGauge(
+		"hypeman_gpu_healthy",
+		metric.WithDescription("Whether a GPU is healthy (1) or cordoned (0)"),
+	)
+	if err != nil {
+		return err
+	}
+
+	xidErrors, err := meter.Int64ObservableCounter(
+		"hypeman_gpu_xid_errors",
+		metric.WithDescription("Critical NVIDIA Xid errors seen on a GPU"),
+	)
+	if err != nil {
+		return err
+	}
+
+	eccErrors, err := meter.Int64ObservableGauge(
+		"hypeman_gpu_ecc_uncorrected_errors",
+		metric.WithDescription("Volatile uncorrectable ECC errors on a GPU"),
+	)
+	if err != nil {
+		return err
+	}
+
+	_, err = meter.RegisterCallback(
+		func(ctx context.Context, o metric.Observer) error {
+			gpus, err := mgr.ListGPUHealth(ctx)
+			if err != nil {
+				return nil
+			}
+			for _, h := range gpus {
+				attrs := metric.WithAttributes(attribute.String("pci_address", h.PCIAddress))
+				value := int64(0)
+				if h.Healthy {
+					value = 1
+				}
+				o.ObserveInt64(healthy, value, attrs)
+				o.ObserveInt64(xidErrors, int64(h.XIDErrors), attrs)
+				o.ObserveInt64(eccErrors, h.ECCUncorrected, attrs)
+			}
+			return nil
+		},
+		healthy,
+		xidErrors,
+		eccErrors,
+	)
+	return err
+}
@@ -0,0 +1,363 @@
+package devices
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/kernel/hypeman/lib/logger"
+)
+
+// MIG (Multi-Instance GPU) partitions an Ampere or newer GPU into GPU
+// instances (GIs). A MIG-backed vGPU profile such as "A100-1-5C" runs on a GI
+// of the matching MIG profile ("1g.5gb"): its mdev can only be created once a
+// free GI of that profile exists on the VF's parent GPU. hypeman creates the
+// GI right before the mdev and destroys it with the mdev, so MIG-backed
+// profiles are requested and passed to guests like any other vGPU profile.
+//
+// MIG is driven through nvidia-smi, which ships with the NVIDIA host driver.
+
+// runNvidiaSMI runs nvidia-smi and returns its combined output. Replaced in tests.
+var runNvidiaSMI = func(ctx context.Context, args ...string) (string, error) {
+	out, err := exec.CommandContext(ctx, "nvidia-smi", args...).CombinedOutput()
+	return string(out), err
+}
+
+var (
+	// migBackedProfilePattern matches MIG-backed vGPU profile names, e.g.
+	// "NVIDIA A100-1-5C" or "H100-1-10CME": <compute slices>-<memory GB>C
+	migBackedProfilePattern = regexp.MustCompile(`-(\d+)-(\d+)C(ME)?$`)
+
+	// migInstanceRow matches a row of `nvidia-smi mig -lgi`:
+	// |   0  MIG 1g.5gb          19        7          4:1     |
+	migInstanceRow = regexp.MustCompile(`^\|\s+(\d+)\s+MIG\s+(\S+)\s+\d+\s+(\d+)\s+\d+:\d+\s+\|$`)
+
+	// migProfileRow matches a row of `nvidia-smi mig -lgip`:
+	// |   0  MIG 1g.5gb        19     7/7        4.75       No     14     0     0   |
+	migProfileRow = regexp.MustCompile(`^\|\s+(\d+)\s+MIG\s+(\S+)\s+\d+\s+(\d+)/(\d+)\s`)
+
+	// migCreatedPattern matches the output of `nvidia-smi mig -cgi`
+	migCreatedPattern = regexp.MustCompile(`GPU instance ID\s+(\d+) on GPU\s+(\d+)`)
+)
+
+// MIGProfileForVGPU returns the MIG GPU instance profile a MIG-backed vGPU
+// profile runs on, e.g. "1g.5gb" for "A100-1-5C", or false if the profile
+// is time-sliced.
+func MIGProfileForVGPU(profileName string) (string, bool) {
+	m := migBackedProfilePattern.FindStringSubmatch(profileName)
+	if m == nil {
+		return "", false
+	}
+	profile := m[1] + "g." + m[2] + "gb"
+	if m[3] != "" {
+		profile += "+me"
+	}
+	return profile, true
+}
+
+// ListMIGGPUs returns the host's MIG-capable GPUs and their current MIG mode.
+// Returns nil if nvidia-smi isn't installed.
+func ListMIGGPUs(ctx context.Context) ([]MIGGPU, error) {
+	out, err := runNvidiaSMI(ctx, "--query-gpu=index,pci.bus_id,name,mig.mode.current", "--format=csv,noheader")
+	if err != nil {
+		if errors.Is(err, exec.ErrNotFound) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("query GPUs: %w: %s", err, strings.TrimSpace(out))
+	}
+	return parseMIGGPUs(out), nil
+}
+
+// parseMIGGPUs parses nvidia-smi's GPU query CSV, skipping GPUs without MIG
+// support (mig.mode.current "[N/A]").
+func parseMIGGPUs(out string) []MIGGPU {
+	var gpus []MIGGPU
+	for _, line := range strings.Split(strings.TrimSpace(out), "\n") {
+		fields := strings.Split(line, ",")
+		if len(fields) != 4 {
+			continue
+		}
+		for i := range fields {
+			fields[i] = strings.TrimSpace(fields[i])
+		}
+		index, err := strconv.Atoi(fields[0])
+		if err != nil {
+			continue
+		}
+		mode := fields[3]
+		if mode != "Enabled" && mode != "Disabled" {
+			continue
+		}
+		gpus = append(gpus, MIGGPU{
+			Index:      index,
+			PCIAddress: normalizePCIAddress(fields[1]),
+			Name:       fields[2],
+			MIGEnabled: mode == "Enabled",
+		})
+	}
+	return gpus
+}
+
+// normalizePCIAddress converts nvidia-smi's bus ID ("00000000:82:00.0") to
+// the sysfs form ("0000:82:00.0").
+func normalizePCIAddress(busID string) string {
+	busID = strings.ToLower(busID)
+	if domain, rest, ok := strings.Cut(busID, ":"); ok && len(domain) > 4 {
+		return domain[len(domain)-4:] + ":" + rest
+	}
+	return busID
+}
+
+// SetMIGMode enables or disables MIG mode on a GPU. The change needs the GPU
+// to be idle; nvidia-smi reports when a GPU reset or reboot is still pending.
+func SetMIGMode(ctx context.Context, gpuIndex int, enabled bool) error {
+	mode := "0"
+	if enabled {
+		mode = "1"
+	}
+	out, err := runNvidiaSMI(ctx, "-i", strconv.Itoa(gpuIndex), "-mig", mode)
+	if err != nil {
+		return fmt.Errorf("set MIG mode on GPU %d: %w: %s", gpuIndex, err, strings.TrimSpace(out))
+	}
+	logger.FromContext(ctx).InfoContext(ctx, "set MIG mode", "gpu", gpuIndex, "enabled", enabled, "output", strings.TrimSpace(out))
+	return nil
+}
+
+// EnableMIGMode enables MIG mode on every MIG-capable GPU that doesn't have
+// it enabled yet.
+func EnableMIGMode(ctx context.Context) error {
+	gpus, err := ListMIGGPUs(ctx)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, gpu := range gpus {
+		if gpu.MIGEnabled {
+			continue
+		}
+		if err := SetMIGMode(ctx, gpu.Index, true); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// ListMIGInstances returns the GPU instances on all MIG-enabled GPUs.
+func ListMIGInstances(ctx context.Context) ([]MIGInstance, error) {
+	gpus, err := ListMIGGPUs(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var instances []MIGInstance
+	for _, gpu := range gpus {
+		if !gpu.MIGEnabled {
+			continue
+		}
+		out, err := runNvidiaSMI(ctx, "mig", "-i", strconv.Itoa(gpu.Index), "-lgi")
+		if err != nil {
+			// nvidia-smi exits non-zero when a GPU has no GPU instances
+			if strings.Contains(out, "No GPU instances found") {
+				continue
+			}
+			return nil, fmt.Errorf("list GPU instances on GPU %d: %w: %s", gpu.Index, err, strings.TrimSpace(out))
+		}
+		instances = append(instances, parseMIGInstances(out)...)
+	}
+	return instances, nil
+}
+
+func parseMIGInstances(out string) []MIGInstance {
+	var instances []MIGInstance
+	for _, line := range strings.Split(out, "\n") {
+		m := migInstanceRow.FindStringSubmatch(strings.TrimSpace(line))
+		if m == nil {
+			continue
+		}
+		gpu, _ := strconv.Atoi(m[1])
+		id, _ := strconv.Atoi(m[3])
+		instances = append(instances, MIGInstance{GPUIndex: gpu, InstanceID: id, Profile: m[2]})
+	}
+	return instances
+}
+
+// freeMIGCapacity returns how many more GPU instances of each profile each
+// MIG-enabled GPU can host, keyed by GPU index then profile.
+func freeMIGCapacity(ctx context.Context) (map[int]map[string]int, error) {
+	out, err := runNvidiaSMI(ctx, "mig", "-lgip")
+	if err != nil {
+		return nil, fmt.Errorf("list GPU instance profiles: %w: %s", err, strings.TrimSpace(out))
+	}
+	return parseMIGCapacity(out), nil
+}
+
+func parseMIGCapacity(out string) map[int]map[string]int {
+	capacity := make(map[int]map[string]int)
+	for _, line := range strings.Split(out, "\n") {
+		m := migProfileRow.FindStringSubmatch(strings.TrimSpace(line))
+		if m == nil {
+			continue
+		}
+		gpu, _ := strconv.Atoi(m[1])
+		free, _ := strconv.Atoi(m[3])
+		if capacity[gpu] == nil {
+			capacity[gpu] = make(map[string]int)
+		}
+		capacity[gpu][m[2]] = free
+	}
+	return capacity
+}
+
+// CreateMIGInstance creates a GPU instance of the given MIG profile (e.g.
+// "1g.5gb") on a GPU. No compute instances are created: the whole GPU
+// instance backs the vGPU and the guest driver manages it.
+func CreateMIGInstance(ctx context.Context, gpuIndex int, profile string) (*MIGInstance, error) {
+	out, err := runNvidiaSMI(ctx, "mig", "-i", strconv.Itoa(gpuIndex), "-cgi", profile)
+	if err != nil {
+		return nil, fmt.Errorf("create %s GPU instance on GPU %d: %w: %s", profile, gpuIndex, err, strings.TrimSpace(out))
+	}
+	m := migCreatedPattern.FindStringSubmatch(out)
+	if m == nil {
+		return nil, fmt.Errorf("create %s GPU instance on GPU %d: unexpected output: %s", profile, gpuIndex, strings.TrimSpace(out))
+	}
+	id, _ := strconv.Atoi(m[1])
+
+	logger.FromContext(ctx).InfoContext(ctx, "created MIG GPU instance", "gpu", gpuIndex, "gi", id, "profile", profile)
+	return &MIGInstance{GPUIndex: gpuIndex, InstanceID: id, Profile: profile}, nil
+}
+
+// DestroyMIGInstance destroys a GPU instance. A GPU instance that no longer
+// exists is not an error.
+func DestroyMIGInstance(ctx context.Context, gi MIGInstance) error {
+	log := logger.FromContext(ctx)
+
+	out, err := runNvidiaSMI(ctx, "mig", "-i", strconv.Itoa(gi.GPUIndex), "-gi", strconv.Itoa(gi.InstanceID), "-dgi")
+	if err != nil {
+		if strings.Contains(out, "Not Found") {
+			log.DebugContext(ctx, "MIG GPU instance already destroyed", "gpu", gi.GPUIndex, "gi", gi.InstanceID)
+			return nil
+		}
+		return fmt.Errorf("destroy GPU instance %d on GPU %d: %w: %s", gi.InstanceID, gi.GPUIndex, err, strings.TrimSpace(out))
+	}
+
+	log.InfoContext(ctx, "destroyed MIG GPU instance", "gpu", gi.GPUIndex, "gi", gi.InstanceID, "profile", gi.Profile)
+	return nil
+}
+
+// createMIGInstanceForVF creates a GPU instance of migProfile on the parent
+// GPU of one of the free VFs supporting profileType, returning the GI and the
+// parent's PCI address. Must be called with mdevMu held.
+func createMIGInstanceForVF(ctx context.Context, vfs []VirtualFunction, profileType, migProfile string) (*MIGInstance, string, error) {
+	gpus, err := ListMIGGPUs(ctx)
+	if err != nil {
+		return nil, "", err
+	}
+	capacity, err := freeMIGCapacity(ctx)
+	if err != nil {
+		return nil, "", err
+	}
+
+	tried := make(map[string]bool)
+	for _, vf := range vfs {
+		if vf.HasMdev || tried[vf.ParentGPU] || isGPUCordoned(vf.ParentGPU) || !vfSupportsType(vf.PCIAddress, profileType) {
+			continue
+		}
+		tried[vf.ParentGPU] = true
+		for _, gpu := range gpus {
+			if gpu.PCIAddress != vf.ParentGPU || !gpu.MIGEnabled || capacity[gpu.Index][migProfile] < 1 {
+				continue
+			}
+			gi, err := CreateMIGInstance(ctx, gpu.Index, migProfile)
+			if err != nil {
+				logger.FromContext(ctx).WarnContext(ctx, "failed to create MIG GPU instance", "gpu", gpu.Index, "profile", migProfile, "error", err)
+				continue
+			}
+			return gi, gpu.PCIAddress, nil
+		}
+	}
+	return nil, "", fmt.Errorf("%w: no MIG-enabled GPU has room for a %s GPU instance", ErrProfileUnavailable, migProfile)
+}
+
+// migAvailability returns how many more vGPUs of a MIG-backed profile type
+// could be created by first creating a GPU instance: per parent GPU, the
+// lesser of its free VFs supporting the type and its free GI capacity.
+func migAvailability(freeVFsByParent map[string][]VirtualFunction, profileType, migProfile string, gpus []MIGGPU, capacity map[int]map[string]int) int {
+	count := 0
+	for _, gpu := range gpus {
+		if !gpu.MIGEnabled {
+			continue
+		}
+		freeVFs := 0
+		for _, vf := range freeVFsByParent[gpu.PCIAddress] {
+			if vfSupportsType(vf.PCIAddress, profileType) {
+				freeVFs++
+			}
+		}
+		count += min(freeVFs, capacity[gpu.Index][migProfile])
+	}
+	return count
+}
+
+// MIGReconcileInfo contains information needed to reconcile an instance's
+// MIG GPU instance
+type MIGReconcileInfo struct {
+	InstanceID  string
+	MIGInstance MIGInstance
+	IsRunning   bool // true if instance's VMM is running or state is unknown
+}
+
+// ReconcileMIGInstances destroys orphaned GPU instances hypeman created for
+// vGPUs. Called on server startup after ReconcileMdevs, with the same
+// guarantees: only GPU instances tracked by hypeman instances are destroyed,
+// never those of running or unknown-state instances, and nvidia-smi refuses
+// to destroy a GPU instance still backing a vGPU.
+func ReconcileMIGInstances(ctx context.Context, infos []MIGReconcileInfo) error {
+	log := logger.FromContext(ctx)
+
+	if len(infos) == 0 {
+		log.DebugContext(ctx, "no MIG GPU instances to reconcile")
+		return nil
+	}
+
+	existing, err := ListMIGInstances(ctx)
+	if err != nil {
+		return fmt.Errorf("list MIG GPU instances: %w", err)
+	}
+	exists := make(map[MIGInstance]bool, len(existing))
+	for _, gi := range existing {
+		exists[gi] = true
+	}
+
+	var destroyed, skippedRunning, failed int
+	for _, info := range infos {
+		if !exists[info.MIGInstance] {
+			continue
+		}
+		if info.IsRunning {
+			log.DebugContext(ctx, "skipping MIG GPU instance for running/unknown instance", "gpu", info.MIGInstance.GPUIndex, "gi", info.MIGInstance.InstanceID, "instance_id", info.InstanceID)
+			skippedRunning++
+			continue
+		}
+
+		log.InfoContext(ctx, "destroying orphaned MIG GPU instance", "gpu", info.MIGInstance.GPUIndex, "gi", info.MIGInstance.InstanceID, "instance_id", info.InstanceID)
+		if err := DestroyMIGInstance(ctx, info.MIGInstance); err != nil {
+			// Log error but continue - best effort cleanup
+			log.WarnContext(ctx, "failed to destroy orphaned MIG GPU instance", "gpu", info.MIGInstance.GPUIndex, "gi", info.MIGInstance.InstanceID, "error", err)
+			failed++
+			continue
+		}
+		destroyed++
+	}
+
+	log.InfoContext(ctx, "MIG reconciliation complete",
+		"destroyed", destroyed,
+		"skipped_running", skippedRunning,
+		"failed", failed,
+	)
+	return nil
+}
@@ -0,0 +1,148 @@
+package devices
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testLGIOutput = `+-------------------------------------------------------+
+| GPU instances:                                        |
+| GPU   Name             Profile  Instance   Placement  |
+|                          ID       ID       Start:Size |
+|=======================================================|
+|   0  MIG 1g.5gb          19        7          4:1     |
++-------------------------------------------------------+
+|   0  MIG 2g.10gb         14        3          0:2     |
++-------------------------------------------------------+
+`
+
+const testLGIPOutput = `+-----------------------------------------------------------------------------+
+| GPU instance profiles:                                                      |
+| GPU   Name             ID    Instances   Memory     P2P    SM    DEC   ENC  |
+|                              Free/Total   GiB              CE    JPEG  OFA  |
+|=============================================================================|
+|   0  MIG 1g.5gb        19     5/7        4.75       No     14     0     0   |
+|                                                             1     0     0   |
++-----------------------------------------------------------------------------+
+|   0  MIG 1g.5gb+me     20     1/1        4.75       No     14     1     0   |
+|                                                             1     1     1   |
++-----------------------------------------------------------------------------+
+|   1  MIG 1g.5gb        19     0/7        4.75       No     14     0     0   |
+|                                                             1     0     0   |
++-----------------------------------------------------------------------------+
+`
+
+// stubNvidiaSMI replaces runNvidiaSMI for the duration of a test, recording
+// each invocation's arguments.
+func stubNvidiaSMI(t *testing.T, fn func(args []string) (string, error)) *[]string {
+	t.Helper()
+	var calls []string
+	orig := runNvidiaSMI
+	runNvidiaSMI = func(ctx context.Context, args ...string) (string, error) {
+		calls = append(calls, strings.Join(args, " "))
+		return fn(args)
+	}
+	t.Cleanup(func() { runNvidiaSMI = orig })
+	return &calls
+}
+
+func TestMIGProfileForVGPU(t *testing.T) {
+	tests := []struct {
+		name    string
+		profile string
+		isMIG   bool
+	}{
+		{"NVIDIA A100-1-5C", "1g.5gb", true},
+		{"A100-7-40C", "7g.40gb", true},
+		{"H100-1-10CME", "1g.10gb+me", true},
+		{"A100-4C", "", false},
+		{"L40S-1Q", "", false},
+		{"NVIDIA L40S-1B", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			profile, ok := MIGProfileForVGPU(tt.name)
+			assert.Equal(t, tt.isMIG, ok)
+			assert.Equal(t, tt.profile, profile)
+		})
+	}
+}
+
+func TestParseMIGGPUs(t *testing.T) {
+	out := `0, 00000000:82:00.0, NVIDIA A100-SXM4-40GB, Enabled
+1, 00000000:C1:00.0, NVIDIA A100-SXM4-40GB, Disabled
+2, 00000000:03:00.0, NVIDIA L40S, [N/A]
+`
+	assert.Equal(t, []MIGGPU{
+		{Index: 0, PCIAddress: "0000:82:00.0", Name: "NVIDIA A100-SXM4-40GB", MIGEnabled: true},
+		{Index: 1, PCIAddress: "0000:c1:00.0", Name: "NVIDIA A100-SXM4-40GB", MIGEnabled: false},
+	}, parseMIGGPUs(out))
+}
+
+func TestParseMIGInstances(t *testing.T) {
+	assert.Equal(t, []MIGInstance{
+		{GPUIndex: 0, InstanceID: 7, Profile: "1g.5gb"},
+		{GPUIndex: 0, InstanceID: 3, Profile: "2g.10gb"},
+	}, parseMIGInstances(testLGIOutput))
+}
+
+func TestParseMIGCapacity(t *testing.T) {
+	assert.Equal(t, map[int]map[string]int{
+		0: {"1g.5gb": 5, "1g.5gb+me": 1},
+		1: {"1g.5gb": 0},
+	}, parseMIGCapacity(testLGIPOutput))
+}
+
+func TestCreateMIGInstance(t *testing.T) {
+	calls := stubNvidiaSMI(t, func(args []string) (string, error) {
+		return "Successfully created GPU instance ID  7 on GPU  0 using profile MIG 1g.5gb (ID 19)\n", nil
+	})
+
+	gi, err := CreateMIGInstance(context.Background(), 0, "1g.5gb")
+	require.NoError(t, err)
+	assert.Equal(t, MIGInstance{GPUIndex: 0, InstanceID: 7, Profile: "1g.5gb"}, *gi)
+	assert.Equal(t, []string{"mig -i 0 -cgi 1g.5gb"}, *calls)
+}
+
+func TestDestroyMIGInstanceAlreadyGone(t *testing.T) {
+	stubNvidiaSMI(t, func(args []string) (string, error) {
+		return "Unable to destroy GPU instance ID 7 from GPU 0: Not Found\n", errors.New("exit status 6")
+	})
+	assert.NoError(t, DestroyMIGInstance(context.Background(), MIGInstance{GPUIndex: 0, InstanceID: 7}))
+}
+
+func TestReconcileMIGInstances(t *testing.T) {
+	calls := stubNvidiaSMI(t, func(args []string) (string, error) {
+		switch {
+		case strings.HasPrefix(args[0], "--query-gpu"):
+			return "0, 00000000:82:00.0, NVIDIA A100-SXM4-40GB, Enabled\n", nil
+		case args[len(args)-1] == "-lgi":
+			return testLGIOutput, nil
+		case args[len(args)-1] == "-dgi":
+			return "Successfully destroyed GPU instance ID  7 from GPU  0\n", nil
+		}
+		return "", errors.New("unexpected call")
+	})
+
+	err := ReconcileMIGInstances(context.Background(), []MIGReconcileInfo{
+		{InstanceID: "stopped", MIGInstance: MIGInstance{GPUIndex: 0, InstanceID: 7, Profile: "1g.5gb"}},
+		{InstanceID: "running", MIGInstance: MIGInstance{GPUIndex: 0, InstanceID: 3, Profile: "2g.10gb"}, IsRunning: true},
+		{InstanceID: "gone", MIGInstance: MIGInstance{GPUIndex: 0, InstanceID: 9, Profile: "1g.5gb"}},
+	})
+	require.NoError(t, err)
+
+	// Only the stopped instance's GPU instance is destroyed: the running
+	// instance's is kept and the other one no longer exists
+	var destroyed []string
+	for _, c := range *calls {
+		if strings.HasSuffix(c, "-dgi") {
+			destroyed = append(destroyed, c)
+		}
+	}
+	assert.Equal(t, []string{"mig -i 0 -gi 7 -dgi"}, destroyed)
+}
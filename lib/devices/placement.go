@@ -0,0 +1,139 @@
+package devices
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// sysfsNodePath is where the kernel exposes host NUMA nodes
+var sysfsNodePath = "/sys/devices/system/node"
+
+// readNUMANode reads the host NUMA node of a PCI device, or -1 if the
+// platform doesn't report one
+func readNUMANode(pciAddress string) int {
+	value, err := readSysfsFile(filepath.Join(sysfsDevicesPath, pciAddress, "numa_node"))
+	if err != nil {
+		return -1
+	}
+	node, err := strconv.Atoi(value)
+	if err != nil {
+		return -1
+	}
+	return node
+}
+
+// NUMANodeCPUs returns the host CPUs of a NUMA node, or ErrNUMANodeNotFound
+// if the host doesn't have it.
+func NUMANodeCPUs(node int) ([]int, error) {
+	if node < 0 {
+		return nil, fmt.Errorf("%w: %d", ErrNUMANodeNotFound, node)
+	}
+	value, err := readSysfsFile(filepath.Join(sysfsNodePath, fmt.Sprintf("node%d", node), "cpulist"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("%w: %d", ErrNUMANodeNotFound, node)
+		}
+		return nil, fmt.Errorf("read NUMA node %d CPUs: %w", node, err)
+	}
+	cpus, err := parseCPUList(value)
+	if err != nil {
+		return nil, fmt.Errorf("parse NUMA node %d CPUs: %w", node, err)
+	}
+	if len(cpus) == 0 {
+		return nil, fmt.Errorf("%w: node %d has no CPUs", ErrNUMANodeNotFound, node)
+	}
+	return cpus, nil
+}
+
+// parseCPUList parses a kernel CPU list, e.g. "0-3,8-11"
+func parseCPUList(s string) ([]int, error) {
+	var cpus []int
+	for _, part := range strings.Split(strings.TrimSpace(s), ",") {
+		if part == "" {
+			continue
+		}
+		lo, hi, isRange := strings.Cut(part, "-")
+		first, err := strconv.Atoi(lo)
+		if err != nil {
+			return nil, fmt.Errorf("invalid CPU %q", part)
+		}
+		last := first
+		if isRange {
+			if last, err = strconv.Atoi(hi); err != nil || last < first {
+				return nil, fmt.Errorf("invalid CPU range %q", part)
+			}
+		}
+		for cpu := first; cpu <= last; cpu++ {
+			cpus = append(cpus, cpu)
+		}
+	}
+	return cpus, nil
+}
+
+// mdevParentGPUs returns the parent GPUs hosting the given mdevs
+func mdevParentGPUs(uuids []string, mdevs []MdevDevice, vfs []VirtualFunction) map[string]bool {
+	parentByVF := make(map[string]string, len(vfs))
+	for _, vf := range vfs {
+		parentByVF[vf.PCIAddress] = vf.ParentGPU
+	}
+	wanted := make(map[string]bool, len(uuids))
+	for _, id := range uuids {
+		wanted[id] = true
+	}
+	gpus := make(map[string]bool)
+	for _, mdev := range mdevs {
+		if wanted[mdev.UUID] && parentByVF[mdev.VFAddress] != "" {
+			gpus[parentByVF[mdev.VFAddress]] = true
+		}
+	}
+	return gpus
+}
+
+// orderVFs returns the VFs sorted by how well they match the placement
+// hints, best first. In order of precedence, a VF should be on the requested
+// NUMA node, not on a parent GPU to stay away from, on a preferred parent
+// GPU, then on the parent GPU the policy favors. Ties keep sysfs order.
+func orderVFs(vfs []VirtualFunction, p VFPlacement, near, away map[string]bool) []VirtualFunction {
+	load := make(map[string]int)
+	for _, vf := range vfs {
+		if vf.HasMdev {
+			load[vf.ParentGPU]++
+		}
+	}
+
+	score := func(vf VirtualFunction) [4]int {
+		var s [4]int
+		if p.NUMANode != nil && vf.NUMANode != *p.NUMANode {
+			s[0] = 1
+		}
+		if away[vf.ParentGPU] {
+			s[1] = 1
+		}
+		if len(near) > 0 && !near[vf.ParentGPU] {
+			s[2] = 1
+		}
+		switch p.Policy {
+		case GPUPlacementPack:
+			s[3] = -load[vf.ParentGPU]
+		case GPUPlacementSpread:
+			s[3] = load[vf.ParentGPU]
+		}
+		return s
+	}
+
+	ordered := append([]VirtualFunction(nil), vfs...)
+	sort.SliceStable(ordered, func(i, j int) bool {
+		a, b := score(ordered[i]), score(ordered[j])
+		for k := range a {
+			if a[k] != b[k] {
+				return a[k] < b[k]
+			}
+		}
+		return false
+	})
+	return ordered
+}
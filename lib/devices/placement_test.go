@@ -0,0 +1,106 @@
+package devices
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseCPUList(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []int
+		valid    bool
+	}{
+		{"0-3", []int{0, 1, 2, 3}, true},
+		{"0-1,8-9", []int{0, 1, 8, 9}, true},
+		{"5", []int{5}, true},
+		{"0,2,4\n", []int{0, 2, 4}, true},
+		{"", nil, true},
+		{"3-1", nil, false},
+		{"a-b", nil, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			cpus, err := parseCPUList(tt.input)
+			if !tt.valid {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, cpus)
+		})
+	}
+}
+
+func TestNUMANodeCPUs(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "node1"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "node1", "cpulist"), []byte("8-11\n"), 0644))
+	orig := sysfsNodePath
+	sysfsNodePath = dir
+	t.Cleanup(func() { sysfsNodePath = orig })
+
+	cpus, err := NUMANodeCPUs(1)
+	require.NoError(t, err)
+	assert.Equal(t, []int{8, 9, 10, 11}, cpus)
+
+	_, err = NUMANodeCPUs(2)
+	assert.ErrorIs(t, err, ErrNUMANodeNotFound)
+	_, err = NUMANodeCPUs(-1)
+	assert.ErrorIs(t, err, ErrNUMANodeNotFound)
+}
+
+func TestOrderVFs(t *testing.T) {
+	// GPU A hosts two vGPUs, GPU B one, GPU C none
+	vfs := []VirtualFunction{
+		{PCIAddress: "a.1", ParentGPU: "A", HasMdev: true, NUMANode: 0},
+		{PCIAddress: "a.2", ParentGPU: "A", HasMdev: true, NUMANode: 0},
+		{PCIAddress: "a.3", ParentGPU: "A", NUMANode: 0},
+		{PCIAddress: "b.1", ParentGPU: "B", HasMdev: true, NUMANode: 1},
+		{PCIAddress: "b.2", ParentGPU: "B", NUMANode: 1},
+		{PCIAddress: "c.1", ParentGPU: "C", NUMANode: 1},
+	}
+	first := func(p VFPlacement, near, away map[string]bool) string {
+		for _, vf := range orderVFs(vfs, p, near, away) {
+			if !vf.HasMdev {
+				return vf.PCIAddress
+			}
+		}
+		return ""
+	}
+	node1 := 1
+
+	assert.Equal(t, "a.3", first(VFPlacement{}, nil, nil), "no hints keeps sysfs order")
+	assert.Equal(t, "a.3", first(VFPlacement{Policy: GPUPlacementPack}, nil, nil))
+	assert.Equal(t, "c.1", first(VFPlacement{Policy: GPUPlacementSpread}, nil, nil))
+	assert.Equal(t, "b.2", first(VFPlacement{NUMANode: &node1}, nil, nil))
+	assert.Equal(t, "b.2", first(VFPlacement{}, map[string]bool{"B": true}, nil))
+	assert.Equal(t, "b.2", first(VFPlacement{}, nil, map[string]bool{"A": true}))
+
+	// NUMA node outranks affinity; anti-affinity outranks the policy
+	assert.Equal(t, "b.2", first(VFPlacement{NUMANode: &node1}, map[string]bool{"A": true}, nil))
+	assert.Equal(t, "b.2", first(VFPlacement{Policy: GPUPlacementSpread}, nil, map[string]bool{"C": true}))
+
+	// Affinity to a GPU with no free VF falls back to another GPU
+	assert.Equal(t, "a.3", first(VFPlacement{}, map[string]bool{"D": true}, nil))
+	assert.Len(t, vfs, 6, "input is not reordered")
+	assert.Equal(t, "a.1", vfs[0].PCIAddress)
+}
+
+func TestMdevParentGPUs(t *testing.T) {
+	vfs := []VirtualFunction{
+		{PCIAddress: "a.1", ParentGPU: "A"},
+		{PCIAddress: "b.1", ParentGPU: "B"},
+	}
+	mdevs := []MdevDevice{
+		{UUID: "m1", VFAddress: "a.1"},
+		{UUID: "m2", VFAddress: "b.1"},
+		{UUID: "m3", VFAddress: "gone"},
+	}
+	assert.Equal(t, map[string]bool{"B": true}, mdevParentGPUs([]string{"m2", "m3", "m4"}, mdevs, vfs))
+	assert.Empty(t, mdevParentGPUs(nil, mdevs, vfs))
+}
@@ -17,6 +17,7 @@ import (
 type mockLivenessChecker struct {
 	runningInstances map[string]bool      // instanceID -> isRunning
 	instanceDevices  map[string][]string  // instanceID -> deviceIDs
+	vgpus            []VGPUAllocation
 }
 
 func newMockLivenessChecker() *mockLivenessChecker {
@@ -42,6 +43,10 @@ func (m *mockLivenessChecker) DetectSuspiciousVMMProcesses(ctx context.Context)
 	return 0 // Mock returns no suspicious processes
 }
 
+func (m *mockLivenessChecker) ListVGPUAllocations(ctx context.Context) []VGPUAllocation {
+	return m.vgpus
+}
+
 func (m *mockLivenessChecker) setRunning(instanceID string, running bool) {
 	m.runningInstances[instanceID] = running
 }
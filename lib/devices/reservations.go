@@ -0,0 +1,235 @@
+package devices
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"time"
+
+	"github.com/kernel/hypeman/lib/logger"
+	"github.com/nrednav/cuid2"
+)
+
+// GPUReservation holds vGPUs of a profile for a tenant's instances: other
+// tenants can't take the last free vGPUs of the profile while the tenant has
+// reserved ones left to use. Limit optionally caps the vGPUs of the profile
+// the tenant may use at once.
+type GPUReservation struct {
+	Id        string    `json:"id"`
+	Profile   string    `json:"profile"`         // vGPU profile name, e.g., "L40S-1Q"
+	Tenant    string    `json:"tenant"`          // Tenant label of the instances the vGPUs are held for
+	Count     int       `json:"count"`           // vGPUs held for the tenant
+	Limit     int       `json:"limit,omitempty"` // Quota on the tenant's vGPUs of the profile (0 = none)
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// CreateGPUReservationRequest is the request to reserve vGPUs for a tenant
+type CreateGPUReservationRequest struct {
+	Profile string // Required: vGPU profile name
+	Tenant  string // Required: tenant label
+	Count   int    // vGPUs to hold for the tenant
+	Limit   int    // Optional quota (0 = none); at least Count when set
+}
+
+// VGPUAllocation is a vGPU held by an instance
+type VGPUAllocation struct {
+	InstanceID string
+	Profile    string
+	Tenant     string
+}
+
+func (m *manager) ListGPUReservations(ctx context.Context) ([]GPUReservation, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.loadReservations()
+}
+
+func (m *manager) CreateGPUReservation(ctx context.Context, req CreateGPUReservationRequest) (*GPUReservation, error) {
+	log := logger.FromContext(ctx)
+
+	if req.Profile == "" || req.Tenant == "" {
+		return nil, fmt.Errorf("%w: profile and tenant are required", ErrInvalidReservation)
+	}
+	if req.Count < 0 || req.Limit < 0 {
+		return nil, fmt.Errorf("%w: count and limit can't be negative", ErrInvalidReservation)
+	}
+	if req.Count == 0 && req.Limit == 0 {
+		return nil, fmt.Errorf("%w: set a count, a limit or both", ErrInvalidReservation)
+	}
+	if req.Limit > 0 && req.Limit < req.Count {
+		return nil, fmt.Errorf("%w: limit %d is below count %d", ErrInvalidReservation, req.Limit, req.Count)
+	}
+	profiles, err := m.listProfiles()
+	if err != nil {
+		return nil, fmt.Errorf("list vGPU profiles: %w", err)
+	}
+	if !slices.ContainsFunc(profiles, func(p GPUProfile) bool { return p.Name == req.Profile }) {
+		return nil, fmt.Errorf("%w: profile %q is not offered by this host's GPUs", ErrInvalidReservation, req.Profile)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	reservations, err := m.loadReservations()
+	if err != nil {
+		return nil, err
+	}
+	if slices.ContainsFunc(reservations, func(r GPUReservation) bool {
+		return r.Profile == req.Profile && r.Tenant == req.Tenant
+	}) {
+		return nil, fmt.Errorf("%w: tenant %q already has a reservation for %s", ErrReservationExists, req.Tenant, req.Profile)
+	}
+
+	reservation := GPUReservation{
+		Id:        cuid2.Generate(),
+		Profile:   req.Profile,
+		Tenant:    req.Tenant,
+		Count:     req.Count,
+		Limit:     req.Limit,
+		CreatedAt: time.Now(),
+	}
+	if err := m.saveReservations(append(reservations, reservation)); err != nil {
+		return nil, fmt.Errorf("save reservations: %w", err)
+	}
+
+	log.InfoContext(ctx, "reserved vGPUs", "id", reservation.Id, "profile", req.Profile, "tenant", req.Tenant, "count", req.Count, "limit", req.Limit)
+	return &reservation, nil
+}
+
+func (m *manager) DeleteGPUReservation(ctx context.Context, id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	reservations, err := m.loadReservations()
+	if err != nil {
+		return err
+	}
+	i := slices.IndexFunc(reservations, func(r GPUReservation) bool { return r.Id == id })
+	if i < 0 {
+		return ErrReservationNotFound
+	}
+	if err := m.saveReservations(slices.Delete(reservations, i, i+1)); err != nil {
+		return fmt.Errorf("save reservations: %w", err)
+	}
+
+	logger.FromContext(ctx).InfoContext(ctx, "removed vGPU reservation", "id", id)
+	return nil
+}
+
+func (m *manager) CheckGPUReservations(ctx context.Context, profile, tenant string) error {
+	m.mu.RLock()
+	reservations, err := m.loadReservations()
+	m.mu.RUnlock()
+	if err != nil {
+		return err
+	}
+	if !slices.ContainsFunc(reservations, func(r GPUReservation) bool { return r.Profile == profile }) {
+		return nil
+	}
+
+	profiles, err := m.listProfiles()
+	if err != nil {
+		return fmt.Errorf("list vGPU profiles: %w", err)
+	}
+	available := 0
+	for _, p := range profiles {
+		if p.Name == profile {
+			available = p.Available
+		}
+	}
+	return checkReservations(reservations, profile, tenant, available, m.vgpuUsage(ctx)[profile])
+}
+
+func (m *manager) ReservedGPUs(ctx context.Context) map[string]int {
+	m.mu.RLock()
+	reservations, err := m.loadReservations()
+	m.mu.RUnlock()
+	if err != nil || len(reservations) == 0 {
+		return nil
+	}
+
+	usage := m.vgpuUsage(ctx)
+	reserved := make(map[string]int)
+	for _, r := range reservations {
+		reserved[r.Profile] += max(0, r.Count-usage[r.Profile][r.Tenant])
+	}
+	return reserved
+}
+
+// checkReservations decides whether tenant may create another vGPU of
+// profile, given the profile's reservations, the VFs that can host it and the
+// vGPUs of the profile each tenant holds. The tenant's own reserved vGPUs are
+// always its to use; otherwise what other tenants have reserved and not yet
+// used must stay free.
+func checkReservations(reservations []GPUReservation, profile, tenant string, available int, usage map[string]int) error {
+	held := 0
+	ownRemaining := 0
+	for _, r := range reservations {
+		if r.Profile != profile {
+			continue
+		}
+		remaining := max(0, r.Count-usage[r.Tenant])
+		if r.Tenant != tenant {
+			held += remaining
+			continue
+		}
+		if r.Limit > 0 && usage[tenant] >= r.Limit {
+			return fmt.Errorf("%w: tenant %q is using %d of its %d %s vGPUs", ErrGPUQuotaExceeded, tenant, usage[tenant], r.Limit, profile)
+		}
+		ownRemaining = remaining
+	}
+
+	// With nothing free at all, CreateMdev reports the profile unavailable
+	if ownRemaining == 0 && available > 0 && available <= held {
+		return fmt.Errorf("%w: the %d free %s vGPUs are reserved for other tenants", ErrProfileUnavailable, available, profile)
+	}
+	return nil
+}
+
+// vgpuUsage returns the vGPUs instances hold by profile and tenant
+func (m *manager) vgpuUsage(ctx context.Context) map[string]map[string]int {
+	m.mu.RLock()
+	checker := m.livenessChecker
+	m.mu.RUnlock()
+
+	usage := make(map[string]map[string]int)
+	if checker == nil {
+		return usage
+	}
+	for _, a := range checker.ListVGPUAllocations(ctx) {
+		if usage[a.Profile] == nil {
+			usage[a.Profile] = make(map[string]int)
+		}
+		usage[a.Profile][a.Tenant]++
+	}
+	return usage
+}
+
+func (m *manager) loadReservations() ([]GPUReservation, error) {
+	data, err := os.ReadFile(m.paths.GPUReservations())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("read reservations: %w", err)
+	}
+
+	var reservations []GPUReservation
+	if err := json.Unmarshal(data, &reservations); err != nil {
+		return nil, fmt.Errorf("parse reservations: %w", err)
+	}
+	return reservations, nil
+}
+
+func (m *manager) saveReservations(reservations []GPUReservation) error {
+	data, err := json.MarshalIndent(reservations, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(m.paths.DevicesDir(), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(m.paths.GPUReservations(), data, 0644)
+}
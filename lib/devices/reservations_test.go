@@ -0,0 +1,88 @@
+package devices
+
+import (
+	"context"
+	"testing"
+
+	"github.com/kernel/hypeman/lib/paths"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckReservations(t *testing.T) {
+	reservations := []GPUReservation{
+		{Profile: "L40S-1Q", Tenant: "team-a", Count: 2, Limit: 3},
+		{Profile: "L40S-1Q", Tenant: "team-b", Count: 1},
+		{Profile: "L40S-2Q", Tenant: "team-c", Count: 4},
+	}
+
+	tests := []struct {
+		name      string
+		tenant    string
+		available int
+		usage     map[string]int
+		wantErr   error
+	}{
+		{"free vGPUs beyond reservations", "team-z", 4, nil, nil},
+		{"last free vGPUs are reserved", "team-z", 3, nil, ErrProfileUnavailable},
+		{"reservations used up", "team-z", 1, map[string]int{"team-a": 2, "team-b": 1}, nil},
+		{"own reservation", "team-a", 3, nil, nil},
+		{"own reservation used up", "team-a", 1, map[string]int{"team-a": 2}, ErrProfileUnavailable},
+		{"own reservation used up with spare", "team-a", 2, map[string]int{"team-a": 2}, nil},
+		{"quota", "team-a", 10, map[string]int{"team-a": 3}, ErrGPUQuotaExceeded},
+		{"untenanted", "", 3, nil, ErrProfileUnavailable},
+		{"nothing free is left to CreateMdev", "team-z", 0, nil, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkReservations(reservations, "L40S-1Q", tt.tenant, tt.available, tt.usage)
+			if tt.wantErr == nil {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorIs(t, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestGPUReservations(t *testing.T) {
+	ctx := context.Background()
+	liveness := newMockLivenessChecker()
+	mgr := &manager{
+		paths:           paths.New(t.TempDir()),
+		livenessChecker: liveness,
+		listProfiles: func() ([]GPUProfile, error) {
+			return []GPUProfile{{Name: "L40S-1Q", Available: 2}}, nil
+		},
+	}
+
+	_, err := mgr.CreateGPUReservation(ctx, CreateGPUReservationRequest{Profile: "L40S-8Q", Tenant: "team-a", Count: 1})
+	assert.ErrorIs(t, err, ErrInvalidReservation, "profile the host doesn't offer")
+	_, err = mgr.CreateGPUReservation(ctx, CreateGPUReservationRequest{Profile: "L40S-1Q", Tenant: "team-a", Count: 2, Limit: 1})
+	assert.ErrorIs(t, err, ErrInvalidReservation, "limit below count")
+	_, err = mgr.CreateGPUReservation(ctx, CreateGPUReservationRequest{Profile: "L40S-1Q", Count: 1})
+	assert.ErrorIs(t, err, ErrInvalidReservation, "no tenant")
+
+	r, err := mgr.CreateGPUReservation(ctx, CreateGPUReservationRequest{Profile: "L40S-1Q", Tenant: "team-a", Count: 2})
+	require.NoError(t, err)
+	_, err = mgr.CreateGPUReservation(ctx, CreateGPUReservationRequest{Profile: "L40S-1Q", Tenant: "team-a", Count: 1})
+	assert.ErrorIs(t, err, ErrReservationExists)
+
+	list, err := mgr.ListGPUReservations(ctx)
+	require.NoError(t, err)
+	require.Len(t, list, 1)
+	assert.Equal(t, r.Id, list[0].Id)
+
+	// Both free vGPUs are held for team-a until it uses them
+	assert.Equal(t, map[string]int{"L40S-1Q": 2}, mgr.ReservedGPUs(ctx))
+	assert.ErrorIs(t, mgr.CheckGPUReservations(ctx, "L40S-1Q", "team-b"), ErrProfileUnavailable)
+	assert.NoError(t, mgr.CheckGPUReservations(ctx, "L40S-1Q", "team-a"))
+	assert.NoError(t, mgr.CheckGPUReservations(ctx, "L40S-2Q", "team-b"), "profile without reservations")
+
+	liveness.vgpus = []VGPUAllocation{{InstanceID: "a1", Profile: "L40S-1Q", Tenant: "team-a"}}
+	assert.Equal(t, map[string]int{"L40S-1Q": 1}, mgr.ReservedGPUs(ctx))
+
+	require.NoError(t, mgr.DeleteGPUReservation(ctx, r.Id))
+	assert.ErrorIs(t, mgr.DeleteGPUReservation(ctx, r.Id), ErrReservationNotFound)
+	assert.NoError(t, mgr.CheckGPUReservations(ctx, "L40S-1Q", "team-b"))
+}
@@ -15,16 +15,17 @@ const (
 
 // Device represents a registered PCI device for passthrough
 type Device struct {
-	Id          string     `json:"id"`            // cuid2 identifier
-	Name        string     `json:"name"`          // user-provided globally unique name
-	Type        DeviceType `json:"type"`          // gpu or pci
-	PCIAddress  string     `json:"pci_address"`   // e.g., "0000:a2:00.0"
-	VendorID    string     `json:"vendor_id"`     // e.g., "10de"
-	DeviceID    string     `json:"device_id"`     // e.g., "27b8"
-	IOMMUGroup  int        `json:"iommu_group"`   // IOMMU group number
-	BoundToVFIO bool       `json:"bound_to_vfio"` // whether device is bound to vfio-pci
-	AttachedTo  *string    `json:"attached_to"`   // instance ID if attached, nil otherwise
-	CreatedAt   time.Time  `json:"created_at"`
+	Id             string     `json:"id"`                        // cuid2 identifier
+	Name           string     `json:"name"`                      // user-provided globally unique name
+	Type           DeviceType `json:"type"`                      // gpu or pci
+	PCIAddress     string     `json:"pci_address"`               // e.g., "0000:a2:00.0"
+	VendorID       string     `json:"vendor_id"`                 // e.g., "10de"
+	DeviceID       string     `json:"device_id"`                 // e.g., "27b8"
+	IOMMUGroup     int        `json:"iommu_group"`               // IOMMU group number
+	BoundToVFIO    bool       `json:"bound_to_vfio"`             // whether device is bound to vfio-pci
+	OriginalDriver string     `json:"original_driver,omitempty"` // host driver before hypeman bound it to vfio-pci
+	AttachedTo     *string    `json:"attached_to"`               // instance ID if attached, nil otherwise
+	CreatedAt      time.Time  `json:"created_at"`
 }
 
 // CreateDeviceRequest is the request to register a new device
@@ -44,6 +45,19 @@ type AvailableDevice struct {
 	CurrentDriver *string `json:"current_driver"` // nil if no driver bound
 }
 
+// USBDevice represents a USB device on the host that can be passed through
+type USBDevice struct {
+	BusPort      string `json:"bus_port"`     // sysfs name, e.g., "1-2.3"
+	Bus          int    `json:"bus"`          // e.g., 1
+	Port         string `json:"port"`         // port path on the bus, e.g., "2.3"
+	Address      int    `json:"address"`      // device number on the bus, changes on replug
+	VendorID     string `json:"vendor_id"`    // e.g., "1050"
+	ProductID    string `json:"product_id"`   // e.g., "0407"
+	Manufacturer string `json:"manufacturer"` // e.g., "Yubico"
+	Product      string `json:"product"`      // e.g., "YubiKey OTP+FIDO+CCID"
+	Serial       string `json:"serial"`       // empty if the device has none
+}
+
 // DeviceNamePattern is the regex pattern for valid device names
 // Must start with alphanumeric, followed by alphanumeric, underscore, dot, or dash
 var DeviceNamePattern = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_.-]+$`)
@@ -70,16 +84,50 @@ type VirtualFunction struct {
 	PCIAddress string `json:"pci_address"` // e.g., "0000:82:00.4"
 	ParentGPU  string `json:"parent_gpu"`  // e.g., "0000:82:00.0"
 	HasMdev    bool   `json:"has_mdev"`    // true if an mdev is created on this VF
+	NUMANode   int    `json:"numa_node"`   // host NUMA node, -1 if unknown
+}
+
+// GPUPlacementPolicy spreads vGPUs across parent GPUs or packs them together
+type GPUPlacementPolicy string
+
+const (
+	GPUPlacementPack   GPUPlacementPolicy = "pack"   // prefer the parent GPU hosting the most vGPUs
+	GPUPlacementSpread GPUPlacementPolicy = "spread" // prefer the parent GPU hosting the fewest vGPUs
+)
+
+// VFPlacement holds hints for choosing the VF a vGPU is created on. Hints
+// only order the free VFs: when no VF satisfies them, any free VF is used.
+type VFPlacement struct {
+	Policy        GPUPlacementPolicy // optional: pack or spread across parent GPUs
+	NearMdevs     []string           // prefer the parent GPUs of these mdevs
+	AwayFromMdevs []string           // avoid the parent GPUs of these mdevs
+	NUMANode      *int               // prefer VFs on this host NUMA node
 }
 
 // MdevDevice represents an active mediated device (vGPU instance)
 type MdevDevice struct {
-	UUID        string `json:"uuid"`         // e.g., "aa618089-8b16-4d01-a136-25a0f3c73123"
-	VFAddress   string `json:"vf_address"`   // VF this mdev resides on
-	ProfileType string `json:"profile_type"` // internal type name, e.g., "nvidia-556"
-	ProfileName string `json:"profile_name"` // user-facing name, e.g., "L40S-1Q"
-	SysfsPath   string `json:"sysfs_path"`   // path for VMM device attachment
-	InstanceID  string `json:"instance_id"`  // instance this mdev is attached to
+	UUID        string       `json:"uuid"`          // e.g., "aa618089-8b16-4d01-a136-25a0f3c73123"
+	VFAddress   string       `json:"vf_address"`    // VF this mdev resides on
+	ProfileType string       `json:"profile_type"`  // internal type name, e.g., "nvidia-556"
+	ProfileName string       `json:"profile_name"`  // user-facing name, e.g., "L40S-1Q"
+	SysfsPath   string       `json:"sysfs_path"`    // path for VMM device attachment
+	InstanceID  string       `json:"instance_id"`   // instance this mdev is attached to
+	MIG         *MIGInstance `json:"mig,omitempty"` // GPU instance created to back a MIG-backed vGPU
+}
+
+// MIGGPU describes a MIG-capable physical GPU
+type MIGGPU struct {
+	Index      int    `json:"index"`       // nvidia-smi GPU index
+	PCIAddress string `json:"pci_address"` // e.g., "0000:82:00.0"
+	Name       string `json:"name"`        // e.g., "NVIDIA A100-SXM4-40GB"
+	MIGEnabled bool   `json:"mig_enabled"` // current MIG mode
+}
+
+// MIGInstance identifies a MIG GPU instance (GI)
+type MIGInstance struct {
+	GPUIndex   int    `json:"gpu_index"`   // nvidia-smi index of the GPU it's on
+	InstanceID int    `json:"instance_id"` // GPU instance ID on that GPU
+	Profile    string `json:"profile"`     // MIG profile, e.g., "1g.5gb"
 }
 
 // GPUProfile describes an available vGPU profile type
@@ -87,6 +135,23 @@ type GPUProfile struct {
 	Name          string `json:"name"`           // user-facing name, e.g., "L40S-1Q"
 	FramebufferMB int    `json:"framebuffer_mb"` // frame buffer size in MB
 	Available     int    `json:"available"`      // number of VFs that can create this profile
+	Reserved      int    `json:"reserved"`       // reserved vGPUs their tenants have yet to use
+	Free          int    `json:"free"`           // available vGPUs not held by reservations
+}
+
+// GPUHealth is a physical GPU's health as last checked. An unhealthy GPU is
+// cordoned: no new vGPUs are created on it and it can't be attached, until an
+// operator uncordons it.
+type GPUHealth struct {
+	PCIAddress     string     `json:"pci_address"`               // e.g., "0000:82:00.0"
+	Healthy        bool       `json:"healthy"`                   // false once cordoned
+	Reason         string     `json:"reason,omitempty"`          // why the GPU was cordoned
+	UnhealthySince *time.Time `json:"unhealthy_since,omitempty"` // when the GPU was cordoned
+	XIDErrors      int        `json:"xid_errors"`                // critical Xid errors seen
+	LastXID        int        `json:"last_xid,omitempty"`        // most recent critical Xid
+	ECCUncorrected int64      `json:"ecc_uncorrected"`           // volatile uncorrectable ECC errors
+	ECCBaseline    int64      `json:"ecc_baseline"`              // ECC errors accepted when last uncordoned
+	CheckedAt      time.Time  `json:"checked_at"`                // last health check
 }
 
 // PassthroughDevice describes a physical GPU available for passthrough
@@ -0,0 +1,143 @@
+package devices
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// sysfsUSBDevicesPath is where the kernel lists USB devices. Replaced in tests.
+var sysfsUSBDevicesPath = "/sys/bus/usb/devices"
+
+// usbBusPortPattern matches a USB device's sysfs name, e.g. "1-2" or "3-1.4.2".
+// Root hubs ("usb1") and interfaces ("1-2:1.0") don't match.
+var usbBusPortPattern = regexp.MustCompile(`^(\d+)-(\d+(?:\.\d+)*)$`)
+
+// usbVendorProductPattern matches a vendor:product selector, e.g. "1050:0407"
+var usbVendorProductPattern = regexp.MustCompile(`^([0-9a-fA-F]{4}):([0-9a-fA-F]{4})$`)
+
+// usbClassHub is the USB device class of hubs, which aren't passed through
+const usbClassHub = "09"
+
+// USBSelector identifies a host USB device, either by vendor and product ID
+// (following the device to whichever port it is plugged into) or by bus and
+// port path (whatever is plugged into that port).
+type USBSelector struct {
+	VendorID  string // e.g., "1050"
+	ProductID string // e.g., "0407"
+	Bus       int    // e.g., 1
+	Port      string // port path on the bus, e.g., "2.3"
+}
+
+// ParseUSBSelector parses "vendor:product" (e.g., "1050:0407") or
+// "bus-port" (e.g., "1-2.3").
+func ParseUSBSelector(s string) (USBSelector, error) {
+	if m := usbVendorProductPattern.FindStringSubmatch(s); m != nil {
+		return USBSelector{VendorID: strings.ToLower(m[1]), ProductID: strings.ToLower(m[2])}, nil
+	}
+	if m := usbBusPortPattern.FindStringSubmatch(s); m != nil {
+		bus, _ := strconv.Atoi(m[1])
+		return USBSelector{Bus: bus, Port: m[2]}, nil
+	}
+	return USBSelector{}, fmt.Errorf("%w: %q is neither vendor:product nor bus-port", ErrInvalidUSBSelector, s)
+}
+
+// String returns the selector in the form ParseUSBSelector accepts
+func (s USBSelector) String() string {
+	if s.VendorID != "" {
+		return s.VendorID + ":" + s.ProductID
+	}
+	return fmt.Sprintf("%d-%s", s.Bus, s.Port)
+}
+
+// Matches reports whether the selector identifies d
+func (s USBSelector) Matches(d USBDevice) bool {
+	if s.VendorID != "" {
+		return d.VendorID == s.VendorID && d.ProductID == s.ProductID
+	}
+	return d.BusPort == s.String()
+}
+
+// DiscoverUSBDevices scans sysfs for USB devices that can be passed through,
+// skipping root hubs and hubs.
+func DiscoverUSBDevices() ([]USBDevice, error) {
+	entries, err := os.ReadDir(sysfsUSBDevicesPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []USBDevice{}, nil
+		}
+		return nil, fmt.Errorf("read sysfs usb devices: %w", err)
+	}
+
+	devices := []USBDevice{}
+	for _, entry := range entries {
+		m := usbBusPortPattern.FindStringSubmatch(entry.Name())
+		if m == nil {
+			continue
+		}
+		dir := filepath.Join(sysfsUSBDevicesPath, entry.Name())
+		if readUSBAttr(dir, "bDeviceClass") == usbClassHub {
+			continue
+		}
+		bus, _ := strconv.Atoi(m[1])
+		addr, _ := strconv.Atoi(readUSBAttr(dir, "devnum"))
+		devices = append(devices, USBDevice{
+			BusPort:      entry.Name(),
+			Bus:          bus,
+			Port:         m[2],
+			Address:      addr,
+			VendorID:     strings.ToLower(readUSBAttr(dir, "idVendor")),
+			ProductID:    strings.ToLower(readUSBAttr(dir, "idProduct")),
+			Manufacturer: readUSBAttr(dir, "manufacturer"),
+			Product:      readUSBAttr(dir, "product"),
+			Serial:       readUSBAttr(dir, "serial"),
+		})
+	}
+	slices.SortFunc(devices, func(a, b USBDevice) int {
+		if a.Bus != b.Bus {
+			return a.Bus - b.Bus
+		}
+		return strings.Compare(a.Port, b.Port)
+	})
+	return devices, nil
+}
+
+// FindUSBDevice returns the host USB device a selector identifies. A
+// vendor:product selector matching several devices is ambiguous: the device
+// must then be selected by bus-port.
+func FindUSBDevice(selector USBSelector) (*USBDevice, error) {
+	devices, err := DiscoverUSBDevices()
+	if err != nil {
+		return nil, err
+	}
+	var found []USBDevice
+	for _, d := range devices {
+		if selector.Matches(d) {
+			found = append(found, d)
+		}
+	}
+	switch len(found) {
+	case 0:
+		return nil, fmt.Errorf("%w: %s", ErrUSBDeviceNotFound, selector)
+	case 1:
+		return &found[0], nil
+	default:
+		return nil, fmt.Errorf("%w: %d devices match %s, select one by bus-port", ErrUSBDeviceAmbiguous, len(found), selector)
+	}
+}
+
+func (m *manager) ListUSBDevices(ctx context.Context) ([]USBDevice, error) {
+	return DiscoverUSBDevices()
+}
+
+// readUSBAttr reads a USB device's sysfs attribute, returning "" if the
+// device doesn't have it (e.g., no serial number)
+func readUSBAttr(dir, name string) string {
+	value, _ := readSysfsFile(filepath.Join(dir, name))
+	return value
+}
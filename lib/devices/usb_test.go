@@ -0,0 +1,98 @@
+package devices
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeUSBSysfs points sysfsUSBDevicesPath at a temporary directory holding
+// the given devices' attributes
+func fakeUSBSysfs(t *testing.T, devices map[string]map[string]string) {
+	t.Helper()
+	dir := t.TempDir()
+	for name, attrs := range devices {
+		require.NoError(t, os.MkdirAll(filepath.Join(dir, name), 0755))
+		for attr, value := range attrs {
+			require.NoError(t, os.WriteFile(filepath.Join(dir, name, attr), []byte(value+"\n"), 0644))
+		}
+	}
+	orig := sysfsUSBDevicesPath
+	sysfsUSBDevicesPath = dir
+	t.Cleanup(func() { sysfsUSBDevicesPath = orig })
+}
+
+func yubikey(devnum string) map[string]string {
+	return map[string]string{"idVendor": "1050", "idProduct": "0407", "bDeviceClass": "00", "devnum": devnum, "manufacturer": "Yubico", "product": "YubiKey OTP+FIDO+CCID"}
+}
+
+func TestParseUSBSelector(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected USBSelector
+		valid    bool
+	}{
+		{"1050:0407", USBSelector{VendorID: "1050", ProductID: "0407"}, true},
+		{"0403:6001", USBSelector{VendorID: "0403", ProductID: "6001"}, true},
+		{"0BDA:8153", USBSelector{VendorID: "0bda", ProductID: "8153"}, true},
+		{"1-2", USBSelector{Bus: 1, Port: "2"}, true},
+		{"3-1.4.2", USBSelector{Bus: 3, Port: "1.4.2"}, true},
+		{"1-2:1.0", USBSelector{}, false}, // interface, not a device
+		{"usb1", USBSelector{}, false},
+		{"1050:407", USBSelector{}, false},
+		{"", USBSelector{}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			sel, err := ParseUSBSelector(tt.input)
+			if !tt.valid {
+				assert.ErrorIs(t, err, ErrInvalidUSBSelector)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, sel)
+		})
+	}
+}
+
+func TestDiscoverUSBDevices(t *testing.T) {
+	fakeUSBSysfs(t, map[string]map[string]string{
+		"usb1":      {"idVendor": "1d6b", "idProduct": "0002", "bDeviceClass": "09"},
+		"1-1":       {"idVendor": "05e3", "idProduct": "0610", "bDeviceClass": "09"},
+		"1-1.2":     yubikey("7"),
+		"1-1.2:1.0": {"bInterfaceClass": "03"},
+		"2-3":       {"idVendor": "0403", "idProduct": "6001", "bDeviceClass": "00", "devnum": "2", "serial": "A50285BI"},
+	})
+
+	devices, err := DiscoverUSBDevices()
+	require.NoError(t, err)
+	assert.Equal(t, []USBDevice{
+		{BusPort: "1-1.2", Bus: 1, Port: "1.2", Address: 7, VendorID: "1050", ProductID: "0407", Manufacturer: "Yubico", Product: "YubiKey OTP+FIDO+CCID"},
+		{BusPort: "2-3", Bus: 2, Port: "3", Address: 2, VendorID: "0403", ProductID: "6001", Serial: "A50285BI"},
+	}, devices, "root hubs, hubs and interfaces are skipped")
+}
+
+func TestFindUSBDevice(t *testing.T) {
+	fakeUSBSysfs(t, map[string]map[string]string{
+		"1-2": yubikey("3"),
+		"1-3": yubikey("4"),
+		"2-1": {"idVendor": "0403", "idProduct": "6001", "devnum": "2"},
+	})
+
+	d, err := FindUSBDevice(USBSelector{VendorID: "0403", ProductID: "6001"})
+	require.NoError(t, err)
+	assert.Equal(t, "2-1", d.BusPort)
+
+	d, err = FindUSBDevice(USBSelector{Bus: 1, Port: "3"})
+	require.NoError(t, err)
+	assert.Equal(t, 4, d.Address)
+
+	_, err = FindUSBDevice(USBSelector{VendorID: "1050", ProductID: "0407"})
+	assert.ErrorIs(t, err, ErrUSBDeviceAmbiguous)
+
+	_, err = FindUSBDevice(USBSelector{Bus: 4, Port: "1"})
+	assert.ErrorIs(t, err, ErrUSBDeviceNotFound)
+}
@@ -100,8 +100,11 @@ func (v *VFIOBinder) BindToVFIO(pciAddress string) error {
 	return nil
 }
 
-// UnbindFromVFIO unbinds a device from vfio-pci and restores the original driver
-func (v *VFIOBinder) UnbindFromVFIO(pciAddress string) error {
+// UnbindFromVFIO unbinds a device from vfio-pci and restores the original
+// driver. With originalDriver set, the device is bound to it explicitly;
+// otherwise (or if that fails) the kernel probes for a driver, which may pick
+// a different one (e.g., nouveau instead of nvidia).
+func (v *VFIOBinder) UnbindFromVFIO(pciAddress, originalDriver string) error {
 	if !v.IsDeviceBoundToVFIO(pciAddress) {
 		return ErrNotBound
 	}
@@ -123,10 +126,8 @@ func (v *VFIOBinder) UnbindFromVFIO(pciAddress string) error {
 		return fmt.Errorf("unbind from vfio-pci: %w", err)
 	}
 
-	// Trigger driver probe to rebind to original driver
-	if err := v.triggerDriverProbe(pciAddress); err != nil {
-		slog.Warn("failed to trigger driver probe", "pci_address", pciAddress, "error", err)
-	}
+	// Rebind to the original driver
+	v.rebindToDriver(pciAddress, originalDriver)
 
 	// For NVIDIA GPUs, restart nvidia-persistenced after rebinding
 	if isNvidia {
@@ -164,6 +165,27 @@ func (v *VFIOBinder) bindDeviceToVFIO(pciAddress string) error {
 	return os.WriteFile(bindPath, []byte(pciAddress), 0200)
 }
 
+// bindToDriver binds a device to a specific host driver
+func (v *VFIOBinder) bindToDriver(pciAddress, driver string) error {
+	bindPath := filepath.Join(pciDriversPath, driver, "bind")
+	return os.WriteFile(bindPath, []byte(pciAddress), 0200)
+}
+
+// rebindToDriver binds an unbound device to originalDriver, falling back to
+// a driver probe if it's unknown or can't be bound.
+func (v *VFIOBinder) rebindToDriver(pciAddress, originalDriver string) {
+	if originalDriver != "" {
+		err := v.bindToDriver(pciAddress, originalDriver)
+		if err == nil {
+			return
+		}
+		slog.Warn("failed to rebind device to original driver, probing instead", "pci_address", pciAddress, "driver", originalDriver, "error", err)
+	}
+	if err := v.triggerDriverProbe(pciAddress); err != nil {
+		slog.Warn("failed to trigger driver probe", "pci_address", pciAddress, "error", err)
+	}
+}
+
 // triggerDriverProbe triggers the kernel to probe for drivers for a device
 func (v *VFIOBinder) triggerDriverProbe(pciAddress string) error {
 	probePath := "/sys/bus/pci/drivers_probe"
@@ -0,0 +1,79 @@
+// Package diskusage measures the disk space used by hypeman's data directory.
+package diskusage
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"syscall"
+
+	"github.com/kernel/hypeman/lib/paths"
+)
+
+// Usage is the disk space used by each kind of data, in bytes actually
+// allocated on disk. Sparse files such as overlays count only the blocks
+// written so far.
+type Usage struct {
+	Images    int64 // Image rootfs disks, including untagged digests
+	OCICache  int64 // Shared OCI layer cache
+	Overlays  int64 // Instance rootfs and volume overlays
+	Snapshots int64 // Standby snapshots (guest memory and device state)
+	Volumes   int64 // Volume disks, including build source volumes
+	Builds    int64 // Build sources, logs and configs
+}
+
+// Total returns the combined usage of all categories.
+func (u Usage) Total() int64 {
+	return u.Images + u.OCICache + u.Overlays + u.Snapshots + u.Volumes + u.Builds
+}
+
+// Measure walks the data directory and returns its usage by category.
+func Measure(p *paths.Paths) (*Usage, error) {
+	u := &Usage{
+		Images:   Bytes(p.ImagesDir()),
+		OCICache: Bytes(p.SystemOCICache()),
+		Volumes:  Bytes(p.VolumesDir()),
+		Builds:   Bytes(p.BuildsDir()),
+	}
+
+	entries, err := os.ReadDir(p.GuestsDir())
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	for _, e := range entries {
+		if !e.IsDir() {
+			continue
+		}
+		id := e.Name()
+		u.Overlays += Bytes(p.InstanceOverlay(id)) + Bytes(p.InstanceVolumeOverlaysDir(id))
+		u.Snapshots += Bytes(p.InstanceSnapshots(id))
+	}
+	return u, nil
+}
+
+// Bytes returns the disk space allocated to a file or directory tree. Missing
+// paths and unreadable entries count as zero.
+func Bytes(path string) int64 {
+	var total int64
+	filepath.WalkDir(path, func(_ string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return nil
+		}
+		total += allocated(info)
+		return nil
+	})
+	return total
+}
+
+// allocated returns the bytes allocated to a file, falling back to its
+// apparent size where block counts aren't available.
+func allocated(info fs.FileInfo) int64 {
+	if st, ok := info.Sys().(*syscall.Stat_t); ok {
+		return st.Blocks * 512 // Blocks are in 512-byte units
+	}
+	return info.Size()
+}
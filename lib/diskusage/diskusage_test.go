@@ -0,0 +1,34 @@
+package diskusage
+
+import (
+	"os"
+	"testing"
+
+	"github.com/kernel/hypeman/lib/paths"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMeasure(t *testing.T) {
+	p := paths.New(t.TempDir())
+
+	// A sparse overlay only counts the blocks written to it
+	require.NoError(t, os.MkdirAll(p.InstanceDir("inst-1"), 0755))
+	f, err := os.Create(p.InstanceOverlay("inst-1"))
+	require.NoError(t, err)
+	require.NoError(t, f.Truncate(1<<30))
+	_, err = f.WriteAt(make([]byte, 8192), 0)
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	require.NoError(t, os.MkdirAll(p.InstanceSnapshotLatest("inst-1"), 0755))
+	require.NoError(t, os.WriteFile(p.InstanceSnapshotConfig("inst-1"), make([]byte, 4096), 0644))
+
+	u, err := Measure(p)
+	require.NoError(t, err)
+	assert.GreaterOrEqual(t, u.Overlays, int64(8192))
+	assert.Less(t, u.Overlays, int64(1<<20))
+	assert.GreaterOrEqual(t, u.Snapshots, int64(4096))
+	assert.Zero(t, u.Images, "missing directories count as zero")
+	assert.Equal(t, u.Overlays+u.Snapshots+u.Builds+u.Volumes, u.Total())
+}
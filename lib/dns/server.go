@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"log/slog"
 	"net"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -32,13 +33,31 @@ const (
 	// Using a per-query timeout ensures DNS queries don't fail if the server
 	// is still running but the parent context is cancelled during shutdown.
 	resolverTimeout = 5 * time.Second
+
+	// WakeLabel marks queries that restore a standby instance before answering:
+	// "<instance>.<port>.wake.hypeman.internal".
+	WakeLabel = "wake"
+
+	// wakeTimeout bounds a restore plus readiness wait. DNS clients usually give
+	// up sooner and retry; the wake continues and a retry picks up the result.
+	wakeTimeout = 60 * time.Second
 )
 
+// WakeHostname returns the name that resolves to an instance once it is
+// running and accepting connections on port. instance may be a Caddy placeholder.
+func WakeHostname(instance string, port int) string {
+	return fmt.Sprintf("%s.%d.%s.%s", instance, port, WakeLabel, Suffix)
+}
+
 // InstanceResolver provides instance IP resolution.
 // This interface is implemented by the instances package.
 type InstanceResolver interface {
 	// ResolveInstanceIP resolves an instance name or ID to its IP address.
 	ResolveInstanceIP(ctx context.Context, nameOrID string) (string, error)
+
+	// WakeInstance restores the instance if it is in standby and waits until
+	// it accepts TCP connections on port, then returns its IP address.
+	WakeInstance(ctx context.Context, nameOrID string, port int) (string, error)
 }
 
 // Server provides DNS-based instance resolution for Caddy.
@@ -160,7 +179,7 @@ func (s *Server) handleQuery(w dns.ResponseWriter, r *dns.Msg) {
 // handleAQuery handles A record queries.
 func (s *Server) handleAQuery(m *dns.Msg, q dns.Question) {
 	// Parse instance name from query
-	// Query format: "<instance>.hypeman.internal."
+	// Query format: "<instance>.hypeman.internal." or "<instance>.<port>.wake.hypeman.internal."
 	name := strings.TrimSuffix(q.Name, ".")
 	suffix := "." + Suffix
 	if !strings.HasSuffix(name, suffix) {
@@ -174,13 +193,35 @@ func (s *Server) handleAQuery(m *dns.Msg, q dns.Question) {
 		return
 	}
 
+	wakePort := 0
+	if rest, ok := strings.CutSuffix(instanceName, "."+WakeLabel); ok {
+		inst, portStr, _ := strings.Cut(rest, ".")
+		port, err := strconv.Atoi(portStr)
+		if inst == "" || err != nil || port < 1 || port > 65535 {
+			s.log.Debug("DNS wake query is malformed", "name", name)
+			m.Rcode = dns.RcodeNameError
+			return
+		}
+		instanceName, wakePort = inst, port
+	}
+
 	// Use a fresh context with timeout for each DNS query.
 	// This ensures queries don't fail if the server is still running but
 	// a parent context was cancelled during shutdown.
-	ctx, cancel := context.WithTimeout(context.Background(), resolverTimeout)
+	timeout := resolverTimeout
+	if wakePort != 0 {
+		timeout = wakeTimeout
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
 	defer cancel()
 
-	ip, err := s.resolver.ResolveInstanceIP(ctx, instanceName)
+	var ip string
+	var err error
+	if wakePort != 0 {
+		ip, err = s.resolver.WakeInstance(ctx, instanceName, wakePort)
+	} else {
+		ip, err = s.resolver.ResolveInstanceIP(ctx, instanceName)
+	}
 	if err != nil {
 		s.log.Debug("DNS resolution failed", "instance", instanceName, "error", err)
 		// Return NXDOMAIN by not adding any answer records
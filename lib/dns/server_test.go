@@ -2,7 +2,9 @@ package dns
 
 import (
 	"context"
+	"fmt"
 	"net"
+	"strconv"
 	"testing"
 	"time"
 
@@ -14,6 +16,7 @@ import (
 // mockResolver implements InstanceResolver for testing
 type mockResolver struct {
 	instances map[string]string
+	wakes     []string // instance:port of WakeInstance calls
 }
 
 func newMockResolver() *mockResolver {
@@ -34,6 +37,11 @@ func (m *mockResolver) ResolveInstanceIP(ctx context.Context, nameOrID string) (
 	return ip, nil
 }
 
+func (m *mockResolver) WakeInstance(ctx context.Context, nameOrID string, port int) (string, error) {
+	m.wakes = append(m.wakes, fmt.Sprintf("%s:%d", nameOrID, port))
+	return m.ResolveInstanceIP(ctx, nameOrID)
+}
+
 // getFreePort returns a random available port
 func getFreePort(t *testing.T) int {
 	t.Helper()
@@ -130,3 +138,31 @@ func TestDNSServer_Port(t *testing.T) {
 		assert.Equal(t, 12345, server.Port())
 	})
 }
+
+func TestDNSServer_WakeQuery(t *testing.T) {
+	resolver := newMockResolver()
+	resolver.addInstance("my-api", "10.100.0.10")
+
+	server := NewServer(resolver, 0, nil)
+	require.NoError(t, server.Start(context.Background()))
+	defer server.Stop()
+
+	addr := net.JoinHostPort("127.0.0.1", strconv.Itoa(server.Port()))
+	client := &dns.Client{Net: "udp"}
+
+	m := new(dns.Msg)
+	m.SetQuestion(WakeHostname("my-api", 8080)+".", dns.TypeA)
+	r, _, err := client.Exchange(m, addr)
+	require.NoError(t, err)
+	require.Len(t, r.Answer, 1)
+	assert.Equal(t, "10.100.0.10", r.Answer[0].(*dns.A).A.String())
+	assert.Equal(t, []string{"my-api:8080"}, resolver.wakes)
+
+	// Malformed port
+	m = new(dns.Msg)
+	m.SetQuestion("my-api.http.wake.hypeman.internal.", dns.TypeA)
+	r, _, err = client.Exchange(m, addr)
+	require.NoError(t, err)
+	assert.Equal(t, dns.RcodeNameError, r.Rcode)
+	assert.Len(t, resolver.wakes, 1)
+}
@@ -0,0 +1,250 @@
+// Package doctor validates that a host meets hypeman's prerequisites, so
+// misconfigured hosts are caught before the first VM fails to boot.
+package doctor
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kernel/hypeman/lib/health"
+	"github.com/kernel/hypeman/lib/paths"
+	"github.com/kernel/hypeman/lib/vmm"
+)
+
+// Status of a check
+type Status string
+
+const (
+	StatusPass Status = "pass"
+	// StatusWarn means an optional feature won't work (e.g. device passthrough)
+	StatusWarn Status = "warn"
+	StatusFail Status = "fail"
+)
+
+// Result is the outcome of one check
+type Result struct {
+	Name   string `json:"name"`
+	Status Status `json:"status"`
+	// Detail describes what was found, or what is missing
+	Detail string `json:"detail"`
+	// Fix suggests how to resolve a warning or failure
+	Fix string `json:"fix,omitempty"`
+}
+
+// Report is the outcome of every check
+type Report struct {
+	// Passed is false if any check failed; warnings don't count
+	Passed  bool      `json:"passed"`
+	Results []Result  `json:"results"`
+	RanAt   time.Time `json:"ran_at"`
+}
+
+// Check validates one prerequisite
+type Check struct {
+	Name string
+	// Required checks fail the report; others only warn
+	Required bool
+	// Fix suggests how to resolve a failure
+	Fix string
+	Run func(ctx context.Context) (string, error)
+}
+
+// commandTimeout bounds external commands run by checks
+const commandTimeout = 10 * time.Second
+
+// capNetAdmin is the bit of CAP_NET_ADMIN in the capability sets
+const capNetAdmin = 12
+
+// Checks returns the host prerequisite checks. p locates the data directory
+// Cloud Hypervisor binaries are extracted to.
+func Checks(p *paths.Paths) []Check {
+	return []Check{
+		{
+			Name:     "kvm",
+			Required: true,
+			Fix:      "enable virtualization in the BIOS, load kvm_intel or kvm_amd, and add the user to the kvm group",
+			Run:      health.DeviceCheck("/dev/kvm"),
+		},
+		{
+			Name:     "vhost-vsock",
+			Required: true,
+			Fix:      "modprobe vhost_vsock",
+			Run:      vsockCheck,
+		},
+		{
+			Name:     "iproute2",
+			Required: true,
+			Fix:      "install iproute2",
+			Run:      commandsCheck("ip", "tc"),
+		},
+		{
+			Name:     "iptables",
+			Required: true,
+			Fix:      "install iptables (nftables backend or legacy)",
+			Run:      versionCheck("iptables", "--version"),
+		},
+		{
+			Name: "nftables",
+			Fix:  "install nftables (needed to enforce build network allowlists and to run the guest metadata service)",
+			Run:  versionCheck("nft", "--version"),
+		},
+		{
+			Name:     "bridge",
+			Required: true,
+			Fix:      "run as root or grant CAP_NET_ADMIN (sudo setcap 'cap_net_admin,cap_net_bind_service=+eip' <binary>)",
+			Run:      bridgeCheck,
+		},
+		{
+			Name:     "mkfs",
+			Required: true,
+			Fix:      "install e2fsprogs and erofs-utils",
+			Run:      commandsCheck("mkfs.ext4", "mkfs.erofs"),
+		},
+		{
+			Name:     "cloud-hypervisor",
+			Required: true,
+			Fix:      "check that DATA_DIR is writable and executable (not mounted noexec)",
+			Run:      cloudHypervisorCheck(p),
+		},
+		{
+			Name: "iommu",
+			Fix:  "enable VT-d/AMD-Vi in the BIOS and boot with intel_iommu=on or amd_iommu=on (needed for PCI/GPU passthrough only)",
+			Run:  iommuCheck,
+		},
+	}
+}
+
+// Run runs the checks in order and reports their results
+func Run(ctx context.Context, checks []Check) Report {
+	report := Report{Passed: true, RanAt: time.Now()}
+	for _, c := range checks {
+		res := Result{Name: c.Name, Status: StatusPass}
+		detail, err := c.Run(ctx)
+		res.Detail = detail
+		if err != nil {
+			res.Status, res.Detail, res.Fix = StatusWarn, err.Error(), c.Fix
+			if c.Required {
+				res.Status = StatusFail
+				report.Passed = false
+			}
+		}
+		report.Results = append(report.Results, res)
+	}
+	return report
+}
+
+// vsockCheck checks that the vhost_vsock module is available
+func vsockCheck(ctx context.Context) (string, error) {
+	if _, err := os.Stat("/dev/vhost-vsock"); err != nil {
+		return "", fmt.Errorf("/dev/vhost-vsock not found (vhost_vsock module not loaded)")
+	}
+	return health.DeviceCheck("/dev/vhost-vsock")(ctx)
+}
+
+// commandsCheck looks up commands in PATH
+func commandsCheck(names ...string) func(context.Context) (string, error) {
+	return func(ctx context.Context) (string, error) {
+		var found, missing []string
+		for _, name := range names {
+			path, err := exec.LookPath(name)
+			if err != nil {
+				missing = append(missing, name)
+				continue
+			}
+			found = append(found, path)
+		}
+		if len(missing) > 0 {
+			return "", fmt.Errorf("not found in PATH: %s", strings.Join(missing, ", "))
+		}
+		return strings.Join(found, ", "), nil
+	}
+}
+
+// versionCheck runs a command and reports the first line of its output
+func versionCheck(name string, args ...string) func(context.Context) (string, error) {
+	return func(ctx context.Context) (string, error) {
+		ctx, cancel := context.WithTimeout(ctx, commandTimeout)
+		defer cancel()
+		out, err := exec.CommandContext(ctx, name, args...).CombinedOutput()
+		if err != nil {
+			return "", fmt.Errorf("%s %s: %w", name, strings.Join(args, " "), err)
+		}
+		line, _, _ := strings.Cut(strings.TrimSpace(string(out)), "\n")
+		return line, nil
+	}
+}
+
+// bridgeCheck checks that the process can create bridges and TAP devices
+func bridgeCheck(ctx context.Context) (string, error) {
+	caps, err := effectiveCaps()
+	if err != nil {
+		return "", err
+	}
+	if caps&(1<<capNetAdmin) == 0 {
+		return "", fmt.Errorf("process lacks CAP_NET_ADMIN, needed to create bridges and TAP devices")
+	}
+	if _, err := os.Stat("/sys/module/bridge"); err != nil {
+		// The kernel loads it on demand when the first bridge is created
+		return "CAP_NET_ADMIN present (bridge module not loaded yet)", nil
+	}
+	return "CAP_NET_ADMIN present, bridge module loaded", nil
+}
+
+// effectiveCaps reads the process's effective capability set
+func effectiveCaps() (uint64, error) {
+	f, err := os.Open("/proc/self/status")
+	if err != nil {
+		return 0, fmt.Errorf("read process status: %w", err)
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if value, ok := strings.CutPrefix(scanner.Text(), "CapEff:"); ok {
+			caps, err := strconv.ParseUint(strings.TrimSpace(value), 16, 64)
+			if err != nil {
+				return 0, fmt.Errorf("parse CapEff: %w", err)
+			}
+			return caps, nil
+		}
+	}
+	return 0, fmt.Errorf("CapEff not found in /proc/self/status")
+}
+
+// cloudHypervisorCheck extracts each embedded Cloud Hypervisor version and
+// checks that it runs and reports the expected version
+func cloudHypervisorCheck(p *paths.Paths) func(context.Context) (string, error) {
+	return func(ctx context.Context) (string, error) {
+		var versions []string
+		for _, version := range vmm.SupportedVersions {
+			path, err := vmm.GetBinaryPath(p, version)
+			if err != nil {
+				return "", fmt.Errorf("extract cloud-hypervisor %s: %w", version, err)
+			}
+			detail, err := versionCheck(path, "--version")(ctx)
+			if err != nil {
+				return "", err
+			}
+			if !strings.Contains(detail, strings.TrimPrefix(string(version), "v")) {
+				return "", fmt.Errorf("cloud-hypervisor %s reports %q", version, detail)
+			}
+			versions = append(versions, string(version))
+		}
+		return "runs " + strings.Join(versions, ", "), nil
+	}
+}
+
+// iommuCheck checks that the IOMMU is enabled, which device passthrough needs
+func iommuCheck(ctx context.Context) (string, error) {
+	entries, err := os.ReadDir("/sys/kernel/iommu_groups")
+	if err != nil || len(entries) == 0 {
+		return "", fmt.Errorf("IOMMU not enabled (no groups in /sys/kernel/iommu_groups)")
+	}
+	return fmt.Sprintf("%d IOMMU groups", len(entries)), nil
+}
@@ -0,0 +1,40 @@
+package doctor
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRun(t *testing.T) {
+	pass := func(ctx context.Context) (string, error) { return "fine", nil }
+	missing := func(ctx context.Context) (string, error) { return "", errors.New("missing") }
+
+	report := Run(context.Background(), []Check{
+		{Name: "kvm", Required: true, Run: pass},
+		{Name: "iommu", Fix: "enable it", Run: missing},
+	})
+	assert.True(t, report.Passed, "warnings don't fail the report")
+	require.Len(t, report.Results, 2)
+	assert.Equal(t, Result{Name: "kvm", Status: StatusPass, Detail: "fine"}, report.Results[0])
+	assert.Equal(t, Result{Name: "iommu", Status: StatusWarn, Detail: "missing", Fix: "enable it"}, report.Results[1])
+
+	report = Run(context.Background(), []Check{
+		{Name: "mkfs", Required: true, Fix: "install it", Run: missing},
+	})
+	assert.False(t, report.Passed)
+	assert.Equal(t, StatusFail, report.Results[0].Status)
+}
+
+func TestCommandsCheck(t *testing.T) {
+	detail, err := commandsCheck("sh")(context.Background())
+	require.NoError(t, err)
+	assert.Contains(t, detail, "sh")
+
+	_, err = commandsCheck("sh", "hypeman-no-such-command")(context.Background())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "hypeman-no-such-command")
+}
@@ -0,0 +1,179 @@
+package groups
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/kernel/hypeman/lib/instances"
+	"github.com/kernel/hypeman/lib/logger"
+)
+
+// tolerance is how far the metric may be from its target, as a fraction of
+// it, before the replica count changes
+const tolerance = 0.1
+
+// minSampleInterval is the shortest interval a rate is measured over, so
+// passes started by API requests right after a periodic one don't read
+// noise
+const minSampleInterval = 5 * time.Second
+
+// sampleKey identifies a counter reading of one member
+type sampleKey struct {
+	instanceID string
+	metric     string
+}
+
+// sample is a reading of a member's cumulative counter: CPU time in
+// nanoseconds, or requests proxied by ingress
+type sample struct {
+	at    time.Time
+	value uint64
+}
+
+// autoscale measures a group's metric and sets its replica count from it,
+// recording an event for each change. The metric is a rate, so the first
+// pass after a member appears only takes a reading.
+func (m *manager) autoscale(ctx context.Context, meta *storedMetadata) error {
+	a := meta.Autoscale
+	if a == nil || !a.Enabled {
+		return nil
+	}
+
+	value, ok, err := m.measure(ctx, meta)
+	if err != nil {
+		return fmt.Errorf("autoscale: %w", err)
+	}
+	if !ok {
+		return nil
+	}
+	meta.MetricValue = &value
+
+	current := meta.Replicas
+	desired := desiredReplicas(current, value, a)
+	if desired == current {
+		return nil
+	}
+
+	now := time.Now()
+	cooldown := a.ScaleUpCooldown
+	direction := "above"
+	if desired < current {
+		cooldown = a.ScaleDownCooldown
+		direction = "below"
+	}
+	if meta.LastScaleAt != nil && now.Sub(*meta.LastScaleAt) < cooldown {
+		return nil
+	}
+
+	event := Event{
+		Time:   now,
+		From:   current,
+		To:     desired,
+		Metric: a.Metric,
+		Value:  value,
+		Reason: fmt.Sprintf("%s %.2f is %s target %.2f", a.Metric, value, direction, a.Target),
+	}
+	meta.Events = append(meta.Events, event)
+	if len(meta.Events) > maxEvents {
+		meta.Events = meta.Events[len(meta.Events)-maxEvents:]
+	}
+	meta.Replicas = desired
+	meta.LastScaleAt = &now
+
+	logger.FromContext(ctx).InfoContext(ctx, "autoscaling instance group",
+		"group_id", meta.Id, "from", current, "to", desired, "reason", event.Reason)
+	return saveMetadata(m.paths, meta)
+}
+
+// desiredReplicas scales the replica count in proportion to how far the
+// metric is from its target, within min and max
+func desiredReplicas(current int, value float64, a *Autoscale) int {
+	desired := current
+	ratio := value / a.Target
+	if math.Abs(ratio-1) > tolerance {
+		desired = int(math.Ceil(float64(current) * ratio))
+	}
+	return min(max(desired, a.MinReplicas), a.MaxReplicas)
+}
+
+// measure reads a group's metric per member. ok is false until some member
+// has two readings to compute a rate from.
+func (m *manager) measure(ctx context.Context, meta *storedMetadata) (value float64, ok bool, err error) {
+	metric := meta.Autoscale.Metric
+	now := time.Now()
+
+	var read func(mem storedMember) (uint64, bool)
+	switch metric {
+	case MetricCPU:
+		read = func(mem storedMember) (uint64, bool) {
+			inst, err := m.instances.GetInstance(ctx, mem.InstanceID)
+			if err != nil || inst.State != instances.StateRunning {
+				return 0, false
+			}
+			cpu, err := m.instances.CPUTime(ctx, mem.InstanceID)
+			if err != nil {
+				return 0, false
+			}
+			return uint64(cpu), true
+		}
+	case MetricIngressRPS:
+		if m.ingress == nil {
+			return 0, false, fmt.Errorf("ingress metrics are not available")
+		}
+		counts, err := m.ingress.RequestCounts(ctx)
+		if err != nil {
+			return 0, false, fmt.Errorf("read ingress request counts: %w", err)
+		}
+		// Rules may target a member by name or by ID
+		read = func(mem storedMember) (uint64, bool) {
+			return counts[mem.Name] + counts[mem.InstanceID], true
+		}
+	default:
+		return 0, false, fmt.Errorf("unknown metric %q", metric)
+	}
+
+	var total float64
+	sampled := 0
+	for _, mem := range meta.Members {
+		current, ok := read(mem)
+		if !ok {
+			continue
+		}
+		key := sampleKey{instanceID: mem.InstanceID, metric: metric}
+		prev, loaded := m.samples.Load(key)
+		if !loaded {
+			m.samples.Store(key, sample{at: now, value: current})
+			continue
+		}
+		last := prev.(sample)
+		elapsed := now.Sub(last.at)
+		if elapsed < minSampleInterval {
+			continue
+		}
+		m.samples.Store(key, sample{at: now, value: current})
+		if current < last.value {
+			// The counter was reset, e.g. by a restart
+			continue
+		}
+		total += float64(current-last.value) / elapsed.Seconds()
+		sampled++
+	}
+	if sampled == 0 {
+		return 0, false, nil
+	}
+
+	value = total / float64(sampled)
+	if metric == MetricCPU {
+		vcpus := max(meta.Template.Vcpus, 1)
+		value = value / float64(time.Second) / float64(vcpus) * 100
+	}
+	return value, true, nil
+}
+
+// forgetSamples drops the readings of a member that's been deleted
+func (m *manager) forgetSamples(instanceID string) {
+	m.samples.Delete(sampleKey{instanceID: instanceID, metric: MetricCPU})
+	m.samples.Delete(sampleKey{instanceID: instanceID, metric: MetricIngressRPS})
+}
@@ -0,0 +1,118 @@
+package groups
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// ageSamples moves every counter reading back in time, as if a pass ran
+// that long ago
+func ageSamples(m *manager, d time.Duration) {
+	m.samples.Range(func(key, value any) bool {
+		s := value.(sample)
+		s.at = s.at.Add(-d)
+		m.samples.Store(key, s)
+		return true
+	})
+}
+
+func TestDesiredReplicas(t *testing.T) {
+	a := &Autoscale{MinReplicas: 2, MaxReplicas: 10, Target: 50}
+	tests := []struct {
+		current int
+		value   float64
+		want    int
+	}{
+		{4, 50, 4},
+		{4, 53, 4},   // Within tolerance
+		{4, 75, 6},   // 4 * 1.5
+		{4, 20, 2},   // 4 * 0.4, rounded up
+		{4, 5, 2},    // Not below min
+		{4, 500, 10}, // Not above max
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, desiredReplicas(tt.current, tt.value, a), "%d at %.0f", tt.current, tt.value)
+	}
+}
+
+func TestAutoscaleCPU(t *testing.T) {
+	m, insts, _, meta := setupTestManager(t)
+	ctx := context.Background()
+	updateMetadata(t, m, meta.Id, func(meta *storedMetadata) {
+		meta.Autoscale = &Autoscale{
+			Enabled: true, MinReplicas: 1, MaxReplicas: 5, Metric: MetricCPU, Target: 50,
+			ScaleUpCooldown: time.Minute, ScaleDownCooldown: 5 * time.Minute,
+		}
+	})
+
+	// The first pass only takes a reading
+	require.NoError(t, m.reconcileGroup(ctx, meta.Id))
+	require.NoError(t, m.reconcileGroup(ctx, meta.Id))
+	g := getGroup(t, m, meta.Id)
+	assert.Nil(t, g.Status.MetricValue)
+	assert.Equal(t, 3, g.Replicas)
+
+	// 9s of CPU over 10s on one vCPU is 90%
+	ageSamples(m, 10*time.Second)
+	for _, mem := range g.Status.Members {
+		insts.addCPU(mem.InstanceID, 9*time.Second)
+	}
+	require.NoError(t, m.reconcileGroup(ctx, meta.Id))
+	g = getGroup(t, m, meta.Id)
+	require.NotNil(t, g.Status.MetricValue)
+	assert.InDelta(t, 90, *g.Status.MetricValue, 1)
+	assert.Equal(t, 5, g.Replicas)
+	assert.Len(t, g.Status.Members, 5)
+	require.Len(t, g.Events, 1)
+	assert.Equal(t, 3, g.Events[0].From)
+	assert.Equal(t, 5, g.Events[0].To)
+	assert.Equal(t, MetricCPU, g.Events[0].Metric)
+
+	// Idle, but within the scale down cooldown
+	ageSamples(m, 10*time.Second)
+	require.NoError(t, m.reconcileGroup(ctx, meta.Id))
+	g = getGroup(t, m, meta.Id)
+	assert.InDelta(t, 0, *g.Status.MetricValue, 1)
+	assert.Equal(t, 5, g.Replicas)
+
+	updateMetadata(t, m, meta.Id, func(meta *storedMetadata) {
+		past := time.Now().Add(-10 * time.Minute)
+		meta.LastScaleAt = &past
+	})
+	ageSamples(m, 10*time.Second)
+	require.NoError(t, m.reconcileGroup(ctx, meta.Id))
+	g = getGroup(t, m, meta.Id)
+	assert.Equal(t, 1, g.Replicas)
+	assert.Len(t, g.Status.Members, 1)
+	require.Len(t, g.Events, 2)
+	assert.Equal(t, 1, g.Events[1].To)
+}
+
+func TestAutoscaleIngressRPS(t *testing.T) {
+	m, _, _, meta := setupTestManager(t)
+	ctx := context.Background()
+	ing := m.ingress.(*fakeIngress)
+	updateMetadata(t, m, meta.Id, func(meta *storedMetadata) {
+		meta.Replicas = 2
+		meta.Autoscale = &Autoscale{Enabled: true, MinReplicas: 1, MaxReplicas: 8, Metric: MetricIngressRPS, Target: 10}
+	})
+	require.NoError(t, m.reconcileGroup(ctx, meta.Id))
+	members := getGroup(t, m, meta.Id).Status.Members
+	require.Len(t, members, 2)
+	require.NoError(t, m.reconcileGroup(ctx, meta.Id))
+
+	// 600 requests over 10s to two members, by name and by ID, is 30/s each
+	ageSamples(m, 10*time.Second)
+	ing.add(members[0].Name, 400)
+	ing.add(members[1].InstanceID, 200)
+	require.NoError(t, m.reconcileGroup(ctx, meta.Id))
+	g := getGroup(t, m, meta.Id)
+	require.NotNil(t, g.Status.MetricValue)
+	assert.InDelta(t, 30, *g.Status.MetricValue, 1)
+	assert.Equal(t, 6, g.Replicas)
+	assert.Len(t, g.Events, 1)
+}
@@ -0,0 +1,9 @@
+package groups
+
+import "errors"
+
+var (
+	ErrNotFound       = errors.New("instance group not found")
+	ErrAlreadyExists  = errors.New("instance group already exists")
+	ErrInvalidRequest = errors.New("invalid instance group")
+)
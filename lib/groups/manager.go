@@ -0,0 +1,341 @@
+package groups
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/kernel/hypeman/lib/images"
+	"github.com/kernel/hypeman/lib/instances"
+	"github.com/kernel/hypeman/lib/logger"
+	"github.com/kernel/hypeman/lib/paths"
+	"github.com/nrednav/cuid2"
+)
+
+// Manager keeps instance groups at their replica count and on their
+// template, replacing members one at a time when either changes. Groups
+// with autoscaling have their replica count set from a metric.
+type Manager interface {
+	ListGroups(ctx context.Context) ([]Group, error)
+	CreateGroup(ctx context.Context, req CreateGroupRequest) (*Group, error)
+	// GetGroup looks a group up by ID, then by name.
+	GetGroup(ctx context.Context, idOrName string) (*Group, error)
+	// UpdateGroup changes a group's replica count, template or autoscaling;
+	// members are added, removed or replaced in the background.
+	UpdateGroup(ctx context.Context, id string, req UpdateGroupRequest) (*Group, error)
+	// DeleteGroup deletes a group and its member instances.
+	DeleteGroup(ctx context.Context, id string) error
+	// Reconcile autoscales groups, then brings every group's members in line
+	// with its replica count, template and template image digest.
+	Reconcile(ctx context.Context) error
+}
+
+// Instances is the part of the instance manager groups use
+type Instances interface {
+	CreateInstance(ctx context.Context, req instances.CreateInstanceRequest) (*instances.Instance, error)
+	GetInstance(ctx context.Context, idOrName string) (*instances.Instance, error)
+	DeleteInstance(ctx context.Context, id string, req instances.DeleteInstanceRequest) error
+	CPUTime(ctx context.Context, id string) (time.Duration, error)
+}
+
+// Images is the part of the image manager groups use
+type Images interface {
+	GetImage(ctx context.Context, name string) (*images.Image, error)
+}
+
+// Ingress is the part of the ingress manager groups use to autoscale on
+// request rate
+type Ingress interface {
+	RequestCounts(ctx context.Context) (map[string]uint64, error)
+}
+
+type manager struct {
+	paths      *paths.Paths
+	instances  Instances
+	images     Images
+	ingress    Ingress    // nil disables autoscaling on ingress requests
+	mu         sync.Mutex // Serializes creates, keeping names unique
+	groupLocks sync.Map   // map[string]*sync.Mutex - per-group locks
+	samples    sync.Map   // map[sampleKey]sample - last counter reading per member
+}
+
+// NewManager creates a new instance group manager. ingressManager may be
+// nil.
+func NewManager(p *paths.Paths, instanceManager Instances, imageManager Images, ingressManager Ingress) Manager {
+	return &manager{
+		paths:     p,
+		instances: instanceManager,
+		images:    imageManager,
+		ingress:   ingressManager,
+	}
+}
+
+// maxNameLength leaves room for the suffix of member instance names
+const maxNameLength = 63 - 1 - memberSuffixLength
+
+var namePattern = regexp.MustCompile(`^[a-z0-9]([a-z0-9-]*[a-z0-9])?$`)
+
+// validateTemplate checks what can be checked before creating an instance
+func validateTemplate(t Template) error {
+	if t.Image == "" {
+		return fmt.Errorf("%w: template image is required", ErrInvalidRequest)
+	}
+	if t.Vcpus < 0 || t.Size < 0 || t.HotplugSize < 0 || t.OverlaySize < 0 || t.DiskIOBps < 0 ||
+		t.NetworkBandwidthDownload < 0 || t.NetworkBandwidthUpload < 0 {
+		return fmt.Errorf("%w: template resources cannot be negative", ErrInvalidRequest)
+	}
+	return nil
+}
+
+func validateReplicas(replicas int) error {
+	if replicas < 0 || replicas > MaxReplicas {
+		return fmt.Errorf("%w: replicas must be between 0 and %d", ErrInvalidRequest, MaxReplicas)
+	}
+	return nil
+}
+
+func (m *manager) validateAutoscale(a *Autoscale) error {
+	if a == nil || !a.Enabled {
+		return nil
+	}
+	if a.MinReplicas < 1 || a.MinReplicas > a.MaxReplicas || a.MaxReplicas > MaxReplicas {
+		return fmt.Errorf("%w: autoscale needs 1 <= min_replicas <= max_replicas <= %d", ErrInvalidRequest, MaxReplicas)
+	}
+	switch a.Metric {
+	case MetricCPU:
+		if a.Target <= 0 || a.Target > 100 {
+			return fmt.Errorf("%w: cpu target must be a percentage above 0", ErrInvalidRequest)
+		}
+	case MetricIngressRPS:
+		if m.ingress == nil {
+			return fmt.Errorf("%w: ingress metrics are not available", ErrInvalidRequest)
+		}
+		if a.Target <= 0 {
+			return fmt.Errorf("%w: ingress_rps target must be above 0", ErrInvalidRequest)
+		}
+	default:
+		return fmt.Errorf("%w: autoscale metric must be %s or %s", ErrInvalidRequest, MetricCPU, MetricIngressRPS)
+	}
+	if a.ScaleUpCooldown < 0 || a.ScaleDownCooldown < 0 {
+		return fmt.Errorf("%w: cooldowns cannot be negative", ErrInvalidRequest)
+	}
+	return nil
+}
+
+// clampReplicas keeps an autoscaled group's replica count within its bounds
+func clampReplicas(replicas int, a *Autoscale) int {
+	if a == nil || !a.Enabled {
+		return replicas
+	}
+	return min(max(replicas, a.MinReplicas), a.MaxReplicas)
+}
+
+// checkImage rejects template images that don't exist. Images still being
+// built are accepted; members are created once they're ready.
+func (m *manager) checkImage(ctx context.Context, name string) error {
+	if _, err := m.images.GetImage(ctx, name); err != nil {
+		if errors.Is(err, images.ErrNotFound) || errors.Is(err, images.ErrInvalidName) {
+			return fmt.Errorf("%w: template image: %v", ErrInvalidRequest, err)
+		}
+		return fmt.Errorf("get image: %w", err)
+	}
+	return nil
+}
+
+func (m *manager) ListGroups(ctx context.Context) ([]Group, error) {
+	ids, err := listGroupIDs(m.paths)
+	if err != nil {
+		return nil, err
+	}
+
+	groups := make([]Group, 0, len(ids))
+	for _, id := range ids {
+		meta, err := loadMetadata(m.paths, id)
+		if err != nil {
+			// Deleted since the directory was read
+			continue
+		}
+		groups = append(groups, *m.toGroup(ctx, meta))
+	}
+	return groups, nil
+}
+
+func (m *manager) CreateGroup(ctx context.Context, req CreateGroupRequest) (*Group, error) {
+	if req.Name == "" || len(req.Name) > maxNameLength || !namePattern.MatchString(req.Name) {
+		return nil, fmt.Errorf("%w: name must be 1-%d lowercase letters, digits and dashes, not starting or ending with a dash", ErrInvalidRequest, maxNameLength)
+	}
+	if err := validateReplicas(req.Replicas); err != nil {
+		return nil, err
+	}
+	if err := validateTemplate(req.Template); err != nil {
+		return nil, err
+	}
+	if err := m.validateAutoscale(req.Autoscale); err != nil {
+		return nil, err
+	}
+	if err := m.checkImage(ctx, req.Template.Image); err != nil {
+		return nil, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, err := m.findByName(req.Name); err == nil {
+		return nil, fmt.Errorf("%w: %s", ErrAlreadyExists, req.Name)
+	}
+
+	now := time.Now()
+	meta := &storedMetadata{
+		Id:         cuid2.Generate(),
+		Name:       req.Name,
+		Template:   req.Template,
+		Replicas:   clampReplicas(req.Replicas, req.Autoscale),
+		Tenant:     req.Tenant,
+		Generation: 1,
+		Autoscale:  req.Autoscale,
+		CreatedAt:  now,
+		UpdatedAt:  now,
+	}
+	if err := saveMetadata(m.paths, meta); err != nil {
+		return nil, err
+	}
+
+	m.reconcileAsync(ctx, meta.Id)
+	return m.toGroup(ctx, meta), nil
+}
+
+func (m *manager) GetGroup(ctx context.Context, idOrName string) (*Group, error) {
+	meta, err := loadMetadata(m.paths, idOrName)
+	if errors.Is(err, ErrNotFound) {
+		meta, err = m.findByName(idOrName)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return m.toGroup(ctx, meta), nil
+}
+
+// findByName returns the group with a name, or ErrNotFound
+func (m *manager) findByName(name string) (*storedMetadata, error) {
+	ids, err := listGroupIDs(m.paths)
+	if err != nil {
+		return nil, err
+	}
+	for _, id := range ids {
+		meta, err := loadMetadata(m.paths, id)
+		if err == nil && meta.Name == name {
+			return meta, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (m *manager) UpdateGroup(ctx context.Context, id string, req UpdateGroupRequest) (*Group, error) {
+	if req.Replicas != nil {
+		if err := validateReplicas(*req.Replicas); err != nil {
+			return nil, err
+		}
+	}
+	if req.Template != nil {
+		if err := validateTemplate(*req.Template); err != nil {
+			return nil, err
+		}
+		if err := m.checkImage(ctx, req.Template.Image); err != nil {
+			return nil, err
+		}
+	}
+	if err := m.validateAutoscale(req.Autoscale); err != nil {
+		return nil, err
+	}
+
+	unlock := m.lockGroup(id)
+	meta, err := loadMetadata(m.paths, id)
+	if err != nil {
+		unlock()
+		return nil, err
+	}
+	if req.Replicas != nil {
+		meta.Replicas = *req.Replicas
+	}
+	if req.Template != nil {
+		meta.Template = *req.Template
+		meta.Generation++
+	}
+	if req.Autoscale != nil {
+		meta.Autoscale = req.Autoscale
+		if !req.Autoscale.Enabled {
+			meta.MetricValue = nil
+		}
+	}
+	meta.Replicas = clampReplicas(meta.Replicas, meta.Autoscale)
+	meta.UpdatedAt = time.Now()
+	err = saveMetadata(m.paths, meta)
+	unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	m.reconcileAsync(ctx, id)
+	return m.toGroup(ctx, meta), nil
+}
+
+func (m *manager) DeleteGroup(ctx context.Context, id string) error {
+	unlock := m.lockGroup(id)
+	defer unlock()
+
+	meta, err := loadMetadata(m.paths, id)
+	if err != nil {
+		return err
+	}
+
+	// Delete members first, saving progress, so a failed delete can be retried
+	for len(meta.Members) > 0 {
+		if err := m.deleteMember(ctx, meta, 0); err != nil {
+			return err
+		}
+	}
+
+	if err := os.RemoveAll(m.paths.GroupDir(id)); err != nil {
+		return fmt.Errorf("remove group directory: %w", err)
+	}
+	m.groupLocks.Delete(id)
+	return nil
+}
+
+func (m *manager) Reconcile(ctx context.Context) error {
+	ids, err := listGroupIDs(m.paths)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, id := range ids {
+		if err := m.reconcileGroup(ctx, id); err != nil && !errors.Is(err, ErrNotFound) {
+			errs = append(errs, fmt.Errorf("group %s: %w", id, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// reconcileAsync reconciles a group in the background, so requests return
+// without waiting for instances to boot
+func (m *manager) reconcileAsync(ctx context.Context, id string) {
+	ctx = context.WithoutCancel(ctx)
+	go func() {
+		if err := m.reconcileGroup(ctx, id); err != nil && !errors.Is(err, ErrNotFound) {
+			logger.FromContext(ctx).WarnContext(ctx, "failed to reconcile instance group", "group_id", id, "error", err)
+		}
+	}()
+}
+
+// lockGroup takes a group's lock and returns its release
+func (m *manager) lockGroup(id string) func() {
+	lock, _ := m.groupLocks.LoadOrStore(id, &sync.Mutex{})
+	mu := lock.(*sync.Mutex)
+	mu.Lock()
+	return mu.Unlock
+}
@@ -0,0 +1,307 @@
+package groups
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/kernel/hypeman/lib/images"
+	"github.com/kernel/hypeman/lib/instances"
+	"github.com/kernel/hypeman/lib/paths"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeInstances struct {
+	mu        sync.Mutex
+	instances map[string]*instances.Instance
+	cpu       map[string]time.Duration
+	next      int
+}
+
+func (f *fakeInstances) CreateInstance(ctx context.Context, req instances.CreateInstanceRequest) (*instances.Instance, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.next++
+	inst := &instances.Instance{}
+	inst.Id = fmt.Sprintf("inst-%d", f.next)
+	inst.Name = req.Name
+	inst.Image = req.Image
+	inst.CreatedAt = time.Now()
+	inst.State = instances.StateRunning
+	f.instances[inst.Id] = inst
+	return inst, nil
+}
+
+func (f *fakeInstances) GetInstance(ctx context.Context, id string) (*instances.Instance, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	inst, ok := f.instances[id]
+	if !ok {
+		return nil, instances.ErrNotFound
+	}
+	cp := *inst
+	return &cp, nil
+}
+
+func (f *fakeInstances) DeleteInstance(ctx context.Context, id string, req instances.DeleteInstanceRequest) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if _, ok := f.instances[id]; !ok {
+		return instances.ErrNotFound
+	}
+	delete(f.instances, id)
+	return nil
+}
+
+func (f *fakeInstances) CPUTime(ctx context.Context, id string) (time.Duration, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.cpu[id], nil
+}
+
+func (f *fakeInstances) addCPU(id string, d time.Duration) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.cpu[id] += d
+}
+
+func (f *fakeInstances) setState(id string, state instances.State) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.instances[id].State = state
+}
+
+type fakeImages struct {
+	mu     sync.Mutex
+	images map[string]*images.Image
+}
+
+func (f *fakeImages) GetImage(ctx context.Context, name string) (*images.Image, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	img, ok := f.images[name]
+	if !ok {
+		return nil, images.ErrNotFound
+	}
+	cp := *img
+	return &cp, nil
+}
+
+func (f *fakeImages) set(name, digest, status string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.images[name] = &images.Image{Name: "docker.io/library/" + name, Digest: digest, Status: status}
+}
+
+type fakeIngress struct {
+	mu     sync.Mutex
+	counts map[string]uint64
+}
+
+func (f *fakeIngress) RequestCounts(ctx context.Context) (map[string]uint64, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	counts := make(map[string]uint64, len(f.counts))
+	for k, v := range f.counts {
+		counts[k] = v
+	}
+	return counts, nil
+}
+
+func (f *fakeIngress) add(target string, n uint64) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.counts[target] += n
+}
+
+func digest(c string) string {
+	return "sha256:" + strings.Repeat(c, 64)
+}
+
+// setupTestManager returns a manager whose groups are only reconciled when
+// the test calls reconcileGroup
+func setupTestManager(t *testing.T) (*manager, *fakeInstances, *fakeImages, *storedMetadata) {
+	insts := &fakeInstances{instances: map[string]*instances.Instance{}, cpu: map[string]time.Duration{}}
+	imgs := &fakeImages{images: map[string]*images.Image{}}
+	imgs.set("app:v1", digest("a"), images.StatusReady)
+	ing := &fakeIngress{counts: map[string]uint64{}}
+	m := NewManager(paths.New(t.TempDir()), insts, imgs, ing).(*manager)
+
+	now := time.Now()
+	meta := &storedMetadata{
+		Id:         "group-1",
+		Name:       "web",
+		Template:   Template{Image: "app:v1", Vcpus: 1},
+		Replicas:   3,
+		Generation: 1,
+		CreatedAt:  now,
+		UpdatedAt:  now,
+	}
+	require.NoError(t, saveMetadata(m.paths, meta))
+	return m, insts, imgs, meta
+}
+
+func getGroup(t *testing.T, m *manager, id string) *Group {
+	g, err := m.GetGroup(context.Background(), id)
+	require.NoError(t, err)
+	return g
+}
+
+// updateMetadata changes a group the way UpdateGroup does, without
+// starting a background reconcile
+func updateMetadata(t *testing.T, m *manager, id string, fn func(*storedMetadata)) {
+	meta, err := loadMetadata(m.paths, id)
+	require.NoError(t, err)
+	fn(meta)
+	require.NoError(t, saveMetadata(m.paths, meta))
+}
+
+func TestReconcileScales(t *testing.T) {
+	m, insts, _, meta := setupTestManager(t)
+	ctx := context.Background()
+
+	require.NoError(t, m.reconcileGroup(ctx, meta.Id))
+	g := getGroup(t, m, meta.Id)
+	assert.Equal(t, StateStable, g.Status.State)
+	assert.Equal(t, 3, g.Status.Running)
+	assert.Equal(t, digest("a"), g.Status.Digest)
+	require.Len(t, g.Status.Members, 3)
+	for _, mem := range g.Status.Members {
+		assert.True(t, strings.HasPrefix(mem.Name, "web-"))
+		assert.Equal(t, "docker.io/library/app@"+digest("a"), insts.instances[mem.InstanceID].Image)
+	}
+
+	// Members not running are removed first
+	notRunning := g.Status.Members[1].InstanceID
+	insts.setState(notRunning, instances.StateStopped)
+	assert.Equal(t, StateDegraded, getGroup(t, m, meta.Id).Status.State)
+
+	updateMetadata(t, m, meta.Id, func(meta *storedMetadata) { meta.Replicas = 2 })
+	require.NoError(t, m.reconcileGroup(ctx, meta.Id))
+	g = getGroup(t, m, meta.Id)
+	assert.Equal(t, StateStable, g.Status.State)
+	require.Len(t, g.Status.Members, 2)
+	assert.NotContains(t, insts.instances, notRunning)
+}
+
+func TestReconcileReplacesMissingMembers(t *testing.T) {
+	m, insts, _, meta := setupTestManager(t)
+	ctx := context.Background()
+	require.NoError(t, m.reconcileGroup(ctx, meta.Id))
+	members := getGroup(t, m, meta.Id).Status.Members
+
+	// Deleted out from under the group, and failed to boot
+	require.NoError(t, insts.DeleteInstance(ctx, members[0].InstanceID, instances.DeleteInstanceRequest{}))
+	insts.setState(members[1].InstanceID, instances.StateFailed)
+	assert.Equal(t, StateDegraded, getGroup(t, m, meta.Id).Status.State)
+
+	require.NoError(t, m.reconcileGroup(ctx, meta.Id))
+	g := getGroup(t, m, meta.Id)
+	assert.Equal(t, StateStable, g.Status.State)
+	require.Len(t, g.Status.Members, 3)
+	assert.Equal(t, members[2].InstanceID, g.Status.Members[0].InstanceID)
+	assert.Len(t, insts.instances, 3)
+}
+
+func TestReconcileRollsOnDigestChange(t *testing.T) {
+	m, insts, imgs, meta := setupTestManager(t)
+	ctx := context.Background()
+	require.NoError(t, m.reconcileGroup(ctx, meta.Id))
+	before := getGroup(t, m, meta.Id).Status.Members
+
+	// A new digest being built doesn't replace anything yet
+	imgs.set("app:v1", digest("b"), images.StatusConverting)
+	assert.ErrorIs(t, m.reconcileGroup(ctx, meta.Id), errImageNotReady)
+	g := getGroup(t, m, meta.Id)
+	assert.Equal(t, StateRolling, g.Status.State)
+	assert.NotEmpty(t, g.Status.Error)
+	assert.Len(t, insts.instances, 3)
+
+	imgs.set("app:v1", digest("b"), images.StatusReady)
+	g = getGroup(t, m, meta.Id)
+	assert.Equal(t, StateRolling, g.Status.State)
+	assert.Equal(t, 0, g.Status.Updated)
+
+	// One member per pass, oldest first, never below the replica count
+	for i := range before {
+		require.NoError(t, m.reconcileGroup(ctx, meta.Id))
+		g = getGroup(t, m, meta.Id)
+		assert.Len(t, g.Status.Members, 3)
+		assert.Equal(t, i+1, g.Status.Updated)
+		assert.NotContains(t, insts.instances, before[i].InstanceID)
+	}
+	assert.Equal(t, StateStable, g.Status.State)
+
+	// A template change rolls too
+	updateMetadata(t, m, meta.Id, func(meta *storedMetadata) {
+		meta.Template.Vcpus = 2
+		meta.Generation++
+	})
+	g = getGroup(t, m, meta.Id)
+	assert.Equal(t, 2, g.Generation)
+	assert.Equal(t, StateRolling, g.Status.State)
+}
+
+func TestReconcileRollingWaitsForRunningMembers(t *testing.T) {
+	m, insts, imgs, meta := setupTestManager(t)
+	ctx := context.Background()
+	require.NoError(t, m.reconcileGroup(ctx, meta.Id))
+	members := getGroup(t, m, meta.Id).Status.Members
+
+	imgs.set("app:v1", digest("b"), images.StatusReady)
+	insts.setState(members[2].InstanceID, instances.StatePaused)
+	require.NoError(t, m.reconcileGroup(ctx, meta.Id))
+	assert.Len(t, insts.instances, 3)
+	assert.Equal(t, 0, getGroup(t, m, meta.Id).Status.Updated)
+}
+
+func TestCreateGroupValidation(t *testing.T) {
+	m, _, _, _ := setupTestManager(t)
+	ctx := context.Background()
+
+	tests := []struct {
+		name string
+		req  CreateGroupRequest
+	}{
+		{"bad name", CreateGroupRequest{Name: "Web", Template: Template{Image: "app:v1"}}},
+		{"long name", CreateGroupRequest{Name: strings.Repeat("a", maxNameLength+1), Template: Template{Image: "app:v1"}}},
+		{"no image", CreateGroupRequest{Name: "api"}},
+		{"missing image", CreateGroupRequest{Name: "api", Template: Template{Image: "missing:v1"}}},
+		{"too many replicas", CreateGroupRequest{Name: "api", Template: Template{Image: "app:v1"}, Replicas: MaxReplicas + 1}},
+		{"autoscale min above max", CreateGroupRequest{Name: "api", Template: Template{Image: "app:v1"},
+			Autoscale: &Autoscale{Enabled: true, MinReplicas: 3, MaxReplicas: 2, Metric: MetricCPU, Target: 50}}},
+		{"autoscale bad metric", CreateGroupRequest{Name: "api", Template: Template{Image: "app:v1"},
+			Autoscale: &Autoscale{Enabled: true, MinReplicas: 1, MaxReplicas: 2, Metric: "memory", Target: 50}}},
+		{"autoscale cpu above 100", CreateGroupRequest{Name: "api", Template: Template{Image: "app:v1"},
+			Autoscale: &Autoscale{Enabled: true, MinReplicas: 1, MaxReplicas: 2, Metric: MetricCPU, Target: 150}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := m.CreateGroup(ctx, tt.req)
+			assert.True(t, errors.Is(err, ErrInvalidRequest), "got %v", err)
+		})
+	}
+
+	_, err := m.CreateGroup(ctx, CreateGroupRequest{Name: "web", Template: Template{Image: "app:v1"}})
+	assert.True(t, errors.Is(err, ErrAlreadyExists))
+}
+
+func TestDeleteGroup(t *testing.T) {
+	m, insts, _, meta := setupTestManager(t)
+	ctx := context.Background()
+	require.NoError(t, m.reconcileGroup(ctx, meta.Id))
+
+	g, err := m.GetGroup(ctx, "web")
+	require.NoError(t, err)
+	assert.Equal(t, meta.Id, g.Id)
+
+	require.NoError(t, m.DeleteGroup(ctx, meta.Id))
+	assert.Empty(t, insts.instances)
+	_, err = m.GetGroup(ctx, meta.Id)
+	assert.True(t, errors.Is(err, ErrNotFound))
+}
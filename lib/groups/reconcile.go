@@ -0,0 +1,274 @@
+package groups
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/kernel/hypeman/lib/images"
+	"github.com/kernel/hypeman/lib/instances"
+	"github.com/nrednav/cuid2"
+)
+
+// memberSuffixLength is the length of the random suffix of member names
+const memberSuffixLength = 6
+
+// errImageNotReady means the template image exists but is still being built
+var errImageNotReady = errors.New("template image is not ready")
+
+// target resolves a template image to the digest-pinned reference members
+// are created from
+func (m *manager) target(ctx context.Context, t Template) (ref string, digest string, err error) {
+	img, err := m.images.GetImage(ctx, t.Image)
+	if err != nil {
+		return "", "", fmt.Errorf("template image: %w", err)
+	}
+	switch img.Status {
+	case images.StatusReady:
+	case images.StatusFailed:
+		return "", "", fmt.Errorf("template image %s failed to build", img.Name)
+	default:
+		return "", "", fmt.Errorf("%w: %s is %s", errImageNotReady, img.Name, img.Status)
+	}
+
+	normalized, err := images.ParseNormalizedRef(img.Name)
+	if err != nil {
+		return "", "", fmt.Errorf("template image: %w", err)
+	}
+	return normalized.Repository() + "@" + img.Digest, img.Digest, nil
+}
+
+// reconcileGroup runs one reconcile pass over a group and records its outcome
+func (m *manager) reconcileGroup(ctx context.Context, id string) error {
+	unlock := m.lockGroup(id)
+	defer unlock()
+
+	meta, err := loadMetadata(m.paths, id)
+	if err != nil {
+		return err
+	}
+
+	// A metric that can't be read holds the replica count, but doesn't
+	// stop the group being reconciled
+	err = errors.Join(m.autoscale(ctx, meta), m.reconcile(ctx, meta))
+	now := time.Now()
+	meta.ReconciledAt = &now
+	meta.LastError = ""
+	if err != nil {
+		meta.LastError = err.Error()
+	}
+	if saveErr := saveMetadata(m.paths, meta); saveErr != nil {
+		return saveErr
+	}
+	return err
+}
+
+// reconcile makes one pass towards the desired state: forget deleted
+// members and replace failed ones, then scale, then replace one outdated
+// member. Replacements are created before the member they replace is
+// deleted, so a rolling update never drops below the replica count.
+// Rolling waits until every member is running.
+func (m *manager) reconcile(ctx context.Context, meta *storedMetadata) error {
+	states := make(map[string]instances.State, len(meta.Members))
+	for i := 0; i < len(meta.Members); {
+		mem := meta.Members[i]
+		inst, err := m.instances.GetInstance(ctx, mem.InstanceID)
+		if errors.Is(err, instances.ErrNotFound) {
+			meta.Members = slices.Delete(meta.Members, i, i+1)
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("get instance %s: %w", mem.Name, err)
+		}
+		if inst.State == instances.StateFailed {
+			if err := m.deleteMember(ctx, meta, i); err != nil {
+				return err
+			}
+			continue
+		}
+		states[mem.InstanceID] = inst.State
+		i++
+	}
+
+	// Scale down, removing outdated and not running members first
+	if extra := len(meta.Members) - meta.Replicas; extra > 0 {
+		digest := ""
+		if _, d, err := m.target(ctx, meta.Template); err == nil {
+			digest = d
+		}
+		for ; extra > 0; extra-- {
+			if err := m.deleteMember(ctx, meta, scaleDownVictim(meta, states, digest)); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	ref, digest, err := m.target(ctx, meta.Template)
+	if err != nil {
+		return err
+	}
+
+	for len(meta.Members) < meta.Replicas {
+		if err := m.addMember(ctx, meta, ref, digest); err != nil {
+			return err
+		}
+	}
+
+	for _, mem := range meta.Members {
+		if state, ok := states[mem.InstanceID]; ok && !isUp(state) {
+			return nil
+		}
+	}
+	idx := slices.IndexFunc(meta.Members, func(mem storedMember) bool { return !mem.updated(meta, digest) })
+	if idx < 0 {
+		return nil
+	}
+	old := meta.Members[idx].InstanceID
+	if err := m.addMember(ctx, meta, ref, digest); err != nil {
+		return err
+	}
+	return m.deleteMember(ctx, meta, slices.IndexFunc(meta.Members, func(mem storedMember) bool { return mem.InstanceID == old }))
+}
+
+// scaleDownVictim picks the member to remove: an outdated one if any, then
+// one that isn't running, then the newest
+func scaleDownVictim(meta *storedMetadata, states map[string]instances.State, digest string) int {
+	if idx := slices.IndexFunc(meta.Members, func(mem storedMember) bool { return !mem.updated(meta, digest) }); idx >= 0 {
+		return idx
+	}
+	if idx := slices.IndexFunc(meta.Members, func(mem storedMember) bool { return !isUp(states[mem.InstanceID]) }); idx >= 0 {
+		return idx
+	}
+	return len(meta.Members) - 1
+}
+
+// addMember creates a member instance from the template, pinned to a digest
+func (m *manager) addMember(ctx context.Context, meta *storedMetadata, ref, digest string) error {
+	t := meta.Template
+	name := meta.Name + "-" + cuid2.Generate()[:memberSuffixLength]
+	inst, err := m.instances.CreateInstance(ctx, instances.CreateInstanceRequest{
+		Name:                     name,
+		Image:                    ref,
+		Size:                     t.Size,
+		HotplugSize:              t.HotplugSize,
+		OverlaySize:              t.OverlaySize,
+		Vcpus:                    t.Vcpus,
+		NetworkBandwidthDownload: t.NetworkBandwidthDownload,
+		NetworkBandwidthUpload:   t.NetworkBandwidthUpload,
+		DiskIOBps:                t.DiskIOBps,
+		Env:                      t.Env,
+		Entrypoint:               t.Entrypoint,
+		Cmd:                      t.Cmd,
+		NetworkEnabled:           t.NetworkEnabled,
+		Hypervisor:               t.Hypervisor,
+		Tenant:                   meta.Tenant,
+	})
+	if err != nil {
+		return fmt.Errorf("create instance %s: %w", name, err)
+	}
+
+	meta.Members = append(meta.Members, storedMember{
+		InstanceID: inst.Id,
+		Name:       inst.Name,
+		Digest:     digest,
+		Generation: meta.Generation,
+		CreatedAt:  inst.CreatedAt,
+	})
+	return saveMetadata(m.paths, meta)
+}
+
+// deleteMember deletes the instance of the member at idx and forgets it
+func (m *manager) deleteMember(ctx context.Context, meta *storedMetadata, idx int) error {
+	mem := meta.Members[idx]
+	if err := m.instances.DeleteInstance(ctx, mem.InstanceID, instances.DeleteInstanceRequest{}); err != nil && !errors.Is(err, instances.ErrNotFound) {
+		return fmt.Errorf("delete instance %s: %w", mem.Name, err)
+	}
+	meta.Members = slices.Delete(meta.Members, idx, idx+1)
+	m.forgetSamples(mem.InstanceID)
+	return saveMetadata(m.paths, meta)
+}
+
+// updated reports whether a member runs the group's current template and
+// digest. With the digest unknown, only the template is compared.
+func (mem storedMember) updated(meta *storedMetadata, digest string) bool {
+	return mem.Generation == meta.Generation && (digest == "" || mem.Digest == digest)
+}
+
+// isUp reports whether a member instance counts as running
+func isUp(state instances.State) bool {
+	return state == instances.StateRunning || state == instances.StateStandby
+}
+
+// toGroup builds a group with its status read live from its members
+func (m *manager) toGroup(ctx context.Context, meta *storedMetadata) *Group {
+	g := &Group{
+		Id:         meta.Id,
+		Name:       meta.Name,
+		Template:   meta.Template,
+		Replicas:   meta.Replicas,
+		Tenant:     meta.Tenant,
+		Generation: meta.Generation,
+		Autoscale:  meta.Autoscale,
+		Events:     meta.Events,
+		CreatedAt:  meta.CreatedAt,
+		UpdatedAt:  meta.UpdatedAt,
+		Status: Status{
+			Members:      make([]Member, 0, len(meta.Members)),
+			Error:        meta.LastError,
+			MetricValue:  meta.MetricValue,
+			ReconciledAt: meta.ReconciledAt,
+		},
+	}
+	s := &g.Status
+
+	_, digest, targetErr := m.target(ctx, meta.Template)
+	s.Digest = digest
+
+	down := false
+	for _, mem := range meta.Members {
+		state := instances.StateUnknown
+		if inst, err := m.instances.GetInstance(ctx, mem.InstanceID); err == nil {
+			state = inst.State
+		} else if errors.Is(err, instances.ErrNotFound) {
+			// Replaced on the next reconcile
+			continue
+		}
+		member := Member{
+			InstanceID: mem.InstanceID,
+			Name:       mem.Name,
+			State:      state,
+			Digest:     mem.Digest,
+			Generation: mem.Generation,
+			Updated:    mem.updated(meta, digest),
+			CreatedAt:  mem.CreatedAt,
+		}
+		if isUp(state) {
+			s.Running++
+		} else {
+			down = true
+		}
+		if member.Updated {
+			s.Updated++
+		}
+		s.Members = append(s.Members, member)
+	}
+
+	switch {
+	case targetErr != nil && !errors.Is(targetErr, errImageNotReady), down:
+		s.State = StateDegraded
+	case len(s.Members) != meta.Replicas:
+		s.State = StateScaling
+	case s.Updated < len(s.Members), targetErr != nil:
+		// A template image still being built is rolled out once ready
+		s.State = StateRolling
+	default:
+		s.State = StateStable
+	}
+	if s.Error == "" && targetErr != nil {
+		s.Error = targetErr.Error()
+	}
+	return g
+}
@@ -0,0 +1,103 @@
+package groups
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/kernel/hypeman/lib/paths"
+)
+
+// Filesystem structure:
+// {dataDir}/groups/{group-id}/
+//   metadata.json   # Template, replicas, members and scaling events
+
+// storedMetadata represents group metadata that is persisted to disk
+type storedMetadata struct {
+	Id           string         `json:"id"`
+	Name         string         `json:"name"`
+	Template     Template       `json:"template"`
+	Replicas     int            `json:"replicas"`
+	Tenant       string         `json:"tenant,omitempty"`
+	Generation   int            `json:"generation"`
+	Autoscale    *Autoscale     `json:"autoscale,omitempty"`
+	Members      []storedMember `json:"members,omitempty"`
+	Events       []Event        `json:"events,omitempty"`
+	LastScaleAt  *time.Time     `json:"last_scale_at,omitempty"`
+	MetricValue  *float64       `json:"metric_value,omitempty"`
+	LastError    string         `json:"last_error,omitempty"`
+	ReconciledAt *time.Time     `json:"reconciled_at,omitempty"`
+	CreatedAt    time.Time      `json:"created_at"`
+	UpdatedAt    time.Time      `json:"updated_at"`
+}
+
+// storedMember is a member instance and what it was created from
+type storedMember struct {
+	InstanceID string    `json:"instance_id"`
+	Name       string    `json:"name"`
+	Digest     string    `json:"digest"`
+	Generation int       `json:"generation"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// loadMetadata loads group metadata from disk
+func loadMetadata(p *paths.Paths, id string) (*storedMetadata, error) {
+	data, err := os.ReadFile(p.GroupMetadata(id))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("read metadata: %w", err)
+	}
+
+	var meta storedMetadata
+	if err := json.Unmarshal(data, &meta); err != nil {
+		return nil, fmt.Errorf("unmarshal metadata: %w", err)
+	}
+	return &meta, nil
+}
+
+// saveMetadata saves group metadata to disk. It's written beside the old
+// file and renamed over it, so readers never see it half-written.
+func saveMetadata(p *paths.Paths, meta *storedMetadata) error {
+	if err := os.MkdirAll(p.GroupDir(meta.Id), 0755); err != nil {
+		return fmt.Errorf("create group directory: %w", err)
+	}
+
+	data, err := json.MarshalIndent(meta, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshal metadata: %w", err)
+	}
+	tmp := p.GroupMetadata(meta.Id) + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("write metadata: %w", err)
+	}
+	if err := os.Rename(tmp, p.GroupMetadata(meta.Id)); err != nil {
+		return fmt.Errorf("write metadata: %w", err)
+	}
+	return nil
+}
+
+// listGroupIDs returns all group IDs by scanning the groups directory
+func listGroupIDs(p *paths.Paths) ([]string, error) {
+	entries, err := os.ReadDir(p.GroupsDir())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("read groups directory: %w", err)
+	}
+
+	var ids []string
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		if _, err := os.Stat(filepath.Join(p.GroupsDir(), entry.Name(), "metadata.json")); err == nil {
+			ids = append(ids, entry.Name())
+		}
+	}
+	return ids, nil
+}
@@ -0,0 +1,129 @@
+package groups
+
+import (
+	"time"
+
+	"github.com/kernel/hypeman/lib/hypervisor"
+	"github.com/kernel/hypeman/lib/instances"
+)
+
+// MaxReplicas is the most instances a group may run
+const MaxReplicas = 64
+
+// Group states, derived from the members and the template image
+const (
+	StateStable   = "stable"   // Every replica runs the current template
+	StateScaling  = "scaling"  // Instances are being added or removed
+	StateRolling  = "rolling"  // Members on an old template or image digest are being replaced
+	StateDegraded = "degraded" // A member isn't running, or the template image can't be used
+)
+
+// Autoscaling metrics
+const (
+	MetricCPU        = "cpu"         // Average vCPU utilization of running members, in percent
+	MetricIngressRPS = "ingress_rps" // Ingress requests per second per member
+)
+
+// Default cooldowns after a scaling decision
+const (
+	DefaultScaleUpCooldown   = time.Minute
+	DefaultScaleDownCooldown = 5 * time.Minute
+)
+
+// maxEvents is how many scaling events a group keeps
+const maxEvents = 50
+
+// Autoscale drives a group's replica count from a metric. The count is set
+// so the metric per member approaches the target, within min and max.
+type Autoscale struct {
+	Enabled           bool          `json:"enabled"`
+	MinReplicas       int           `json:"min_replicas"`
+	MaxReplicas       int           `json:"max_replicas"`
+	Metric            string        `json:"metric"`
+	Target            float64       `json:"target"`
+	ScaleUpCooldown   time.Duration `json:"scale_up_cooldown"`   // Since the last scaling decision, before scaling up
+	ScaleDownCooldown time.Duration `json:"scale_down_cooldown"` // Since the last scaling decision, before scaling down
+}
+
+// Event records an autoscaling decision
+type Event struct {
+	Time   time.Time `json:"time"`
+	From   int       `json:"from"`
+	To     int       `json:"to"`
+	Metric string    `json:"metric"`
+	Value  float64   `json:"value"`
+	Reason string    `json:"reason"`
+}
+
+// Template is what a group's instances are created from. Its image is
+// resolved to a digest, and members are pinned to it.
+type Template struct {
+	Image                    string            `json:"image"`
+	Vcpus                    int               `json:"vcpus,omitempty"`
+	Size                     int64             `json:"size,omitempty"`
+	HotplugSize              int64             `json:"hotplug_size,omitempty"`
+	OverlaySize              int64             `json:"overlay_size,omitempty"`
+	DiskIOBps                int64             `json:"disk_io_bps,omitempty"`
+	NetworkEnabled           bool              `json:"network_enabled"`
+	NetworkBandwidthDownload int64             `json:"network_bandwidth_download,omitempty"`
+	NetworkBandwidthUpload   int64             `json:"network_bandwidth_upload,omitempty"`
+	Env                      map[string]string `json:"env,omitempty"`
+	Entrypoint               []string          `json:"entrypoint,omitempty"`
+	Cmd                      []string          `json:"cmd,omitempty"`
+	Hypervisor               hypervisor.Type   `json:"hypervisor,omitempty"`
+}
+
+// Group is a set of identical instances kept at a replica count
+type Group struct {
+	Id         string
+	Name       string
+	Template   Template
+	Replicas   int
+	Tenant     string // Optional tenant label for access scoping; members inherit it
+	Generation int    // Bumped on every template change
+	Autoscale  *Autoscale
+	Events     []Event // Autoscaling decisions, oldest first
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+	Status     Status
+}
+
+// Status is a group's aggregate state, read live from its members
+type Status struct {
+	State        string
+	Digest       string   // Digest the template image resolves to; empty if it can't be resolved
+	Members      []Member // Oldest first
+	Running      int      // Members running or in standby
+	Updated      int      // Members on the current generation and digest
+	Error        string   // Why the last reconcile couldn't finish, or why the image can't be used
+	MetricValue  *float64 // Autoscaling metric as last measured
+	ReconciledAt *time.Time
+}
+
+// Member is one of a group's instances
+type Member struct {
+	InstanceID string
+	Name       string
+	State      instances.State
+	Digest     string // Image digest the instance was created from
+	Generation int    // Template generation the instance was created from
+	Updated    bool   // On the group's current generation and digest
+	CreatedAt  time.Time
+}
+
+// CreateGroupRequest is the domain request for creating a group
+type CreateGroupRequest struct {
+	Name      string
+	Template  Template
+	Replicas  int
+	Autoscale *Autoscale
+	Tenant    string
+}
+
+// UpdateGroupRequest changes a group's replica count, template or
+// autoscaling. A new template rolls every member onto it.
+type UpdateGroupRequest struct {
+	Replicas  *int
+	Template  *Template
+	Autoscale *Autoscale
+}
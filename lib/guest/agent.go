@@ -0,0 +1,89 @@
+package guest
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+
+	"github.com/kernel/hypeman/lib/hypervisor"
+)
+
+// updateChunkSize is the size of binary chunks streamed to the guest
+const updateChunkSize = 256 * 1024
+
+// BinaryChecksum returns the hex-encoded SHA-256 of a guest-agent binary,
+// in the form reported by GetAgentChecksum.
+func BinaryChecksum(binary []byte) string {
+	sum := sha256.Sum256(binary)
+	return hex.EncodeToString(sum[:])
+}
+
+// GetAgentChecksum returns the SHA-256 of the guest-agent binary running in an instance.
+func GetAgentChecksum(ctx context.Context, dialer hypervisor.VsockDialer) (string, error) {
+	grpcConn, err := GetOrCreateConn(ctx, dialer)
+	if err != nil {
+		return "", fmt.Errorf("get grpc connection: %w", err)
+	}
+
+	resp, err := NewGuestServiceClient(grpcConn).GetAgentInfo(ctx, &GetAgentInfoRequest{})
+	if err != nil {
+		return "", fmt.Errorf("get agent info: %w", err)
+	}
+	return resp.Sha256, nil
+}
+
+// UpdateAgent pushes a guest-agent binary to an instance. The agent verifies the
+// checksum, installs the binary and restarts itself after responding, so the
+// pooled connection is dropped and the next call dials the new agent.
+func UpdateAgent(ctx context.Context, dialer hypervisor.VsockDialer, binary []byte) error {
+	grpcConn, err := GetOrCreateConn(ctx, dialer)
+	if err != nil {
+		return fmt.Errorf("get grpc connection: %w", err)
+	}
+
+	stream, err := NewGuestServiceClient(grpcConn).UpdateAgent(ctx)
+	if err != nil {
+		return fmt.Errorf("start update stream: %w", err)
+	}
+
+	if err := stream.Send(&UpdateAgentRequest{
+		Request: &UpdateAgentRequest_Start{
+			Start: &UpdateAgentStart{
+				Size:   int64(len(binary)),
+				Sha256: BinaryChecksum(binary),
+			},
+		},
+	}); err != nil {
+		return fmt.Errorf("send start: %w", err)
+	}
+
+	r := bytes.NewReader(binary)
+	buf := make([]byte, updateChunkSize)
+	for {
+		n, err := r.Read(buf)
+		if n > 0 {
+			if sendErr := stream.Send(&UpdateAgentRequest{
+				Request: &UpdateAgentRequest_Data{Data: buf[:n]},
+			}); sendErr != nil {
+				return fmt.Errorf("send data: %w", sendErr)
+			}
+		}
+		if err == io.EOF {
+			break
+		}
+	}
+
+	resp, err := stream.CloseAndRecv()
+	if err != nil {
+		return fmt.Errorf("receive response: %w", err)
+	}
+	if !resp.Success {
+		return fmt.Errorf("update failed: %s", resp.Error)
+	}
+
+	CloseConn(dialer.Key())
+	return nil
+}
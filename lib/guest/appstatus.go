@@ -0,0 +1,66 @@
+package guest
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/kernel/hypeman/lib/hypervisor"
+)
+
+// AppExitFile is where init records how the application exited, for the
+// guest agent to report. It's removed before each application start.
+const AppExitFile = "/run/hypeman/app-exit.json"
+
+// AppPidFile is where init records the application's PID in exec mode, so
+// the guest agent can signal it. It's removed before each application start.
+const AppPidFile = "/run/hypeman/app.pid"
+
+// AppExit is the content of AppExitFile
+type AppExit struct {
+	ExitCode int       `json:"exit_code"`        // -1 if killed by a signal
+	Signal   string    `json:"signal,omitempty"` // e.g. "SIGKILL"
+	ExitedAt time.Time `json:"exited_at"`
+}
+
+// GetAppStatus reports whether the instance's application has exited. It
+// returns nil if the application is still running, or if the guest runs in
+// systemd mode, where init doesn't supervise the application.
+func GetAppStatus(ctx context.Context, dialer hypervisor.VsockDialer) (*AppExit, error) {
+	grpcConn, err := GetOrCreateConn(ctx, dialer)
+	if err != nil {
+		return nil, fmt.Errorf("get grpc connection: %w", err)
+	}
+
+	resp, err := NewGuestServiceClient(grpcConn).GetAppStatus(ctx, &GetAppStatusRequest{})
+	if err != nil {
+		return nil, fmt.Errorf("get app status: %w", err)
+	}
+	if !resp.Exited {
+		return nil, nil
+	}
+	return &AppExit{
+		ExitCode: int(resp.ExitCode),
+		Signal:   resp.Signal,
+		ExitedAt: time.Unix(resp.ExitedAt, 0),
+	}, nil
+}
+
+// StopApp sends SIGTERM to the instance's application and waits up to
+// timeout for it to exit, reporting whether it did. The guest syncs its
+// filesystems before answering. It fails with codes.FailedPrecondition if the
+// guest runs in systemd mode, where init doesn't supervise the application.
+func StopApp(ctx context.Context, dialer hypervisor.VsockDialer, timeout time.Duration) (bool, error) {
+	grpcConn, err := GetOrCreateConn(ctx, dialer)
+	if err != nil {
+		return false, fmt.Errorf("get grpc connection: %w", err)
+	}
+
+	resp, err := NewGuestServiceClient(grpcConn).StopApp(ctx, &StopAppRequest{
+		TimeoutSeconds: int32((timeout + time.Second - 1) / time.Second),
+	})
+	if err != nil {
+		return false, fmt.Errorf("stop app: %w", err)
+	}
+	return resp.Exited, nil
+}
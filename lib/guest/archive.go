@@ -0,0 +1,230 @@
+package guest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/kernel/hypeman/lib/hypervisor"
+	"google.golang.org/grpc/status"
+)
+
+// archiveChunkSize is the size of tar data chunks sent over the stream
+const archiveChunkSize = 32 * 1024
+
+// ErrInvalidPattern is returned when an include or exclude glob is malformed
+var ErrInvalidPattern = errors.New("invalid pattern")
+
+// PathFilter selects archive entries by include and exclude globs.
+//
+// Patterns use filepath.Match syntax and are matched against an entry's name
+// in the archive (e.g. "app/src/main.go" when copying /srv/app) and against
+// each of its parent directories, both as a full path and by base name. An
+// excluded directory excludes everything below it; an included directory
+// includes everything below it. With no include patterns every entry that is
+// not excluded is selected.
+type PathFilter struct {
+	include []string
+	exclude []string
+}
+
+// NewPathFilter validates the patterns and returns a filter
+func NewPathFilter(include, exclude []string) (*PathFilter, error) {
+	for _, p := range append(append([]string(nil), include...), exclude...) {
+		if _, err := path.Match(p, ""); err != nil || p == "" {
+			return nil, fmt.Errorf("%w: %q", ErrInvalidPattern, p)
+		}
+	}
+	return &PathFilter{include: include, exclude: exclude}, nil
+}
+
+// Excluded reports whether name or one of its parents matches an exclude pattern
+func (f *PathFilter) Excluded(name string) bool {
+	return matchSelfOrParent(f.exclude, name)
+}
+
+// Match reports whether the entry name is selected
+func (f *PathFilter) Match(name string) bool {
+	if f.Excluded(name) {
+		return false
+	}
+	return len(f.include) == 0 || matchSelfOrParent(f.include, name)
+}
+
+func matchSelfOrParent(patterns []string, name string) bool {
+	if len(patterns) == 0 {
+		return false
+	}
+	name = path.Clean(filepath.ToSlash(name))
+	for p := name; p != "." && p != "/"; p = path.Dir(p) {
+		for _, pattern := range patterns {
+			if ok, _ := path.Match(pattern, p); ok {
+				return true
+			}
+			if ok, _ := path.Match(pattern, path.Base(p)); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// ArchiveProgress reports aggregate progress of an archive copy
+type ArchiveProgress struct {
+	Files int64 // Entries transferred so far
+	Bytes int64 // File content bytes transferred so far
+}
+
+// CopyArchiveOptions configures an archive copy to or from an instance
+type CopyArchiveOptions struct {
+	Path        string   // Destination directory (to instance) or source path (from instance)
+	Include     []string // Only copy entries matching one of these globs (see PathFilter)
+	Exclude     []string // Skip entries matching any of these globs
+	FollowLinks bool     // Archive symlink targets instead of the links (from instance only)
+}
+
+// CopyArchiveToInstance extracts the tar stream read from r into a directory
+// in the guest, preserving permissions, ownership, timestamps and xattrs.
+// progress, if set, is called on the caller's goroutine as the guest reports
+// progress; r is read on a separate goroutine.
+func CopyArchiveToInstance(ctx context.Context, dialer hypervisor.VsockDialer, opts CopyArchiveOptions, r io.Reader, progress func(ArchiveProgress)) (ArchiveProgress, error) {
+	if _, err := NewPathFilter(opts.Include, opts.Exclude); err != nil {
+		return ArchiveProgress{}, err
+	}
+	grpcConn, err := GetOrCreateConn(ctx, dialer)
+	if err != nil {
+		return ArchiveProgress{}, fmt.Errorf("get grpc connection: %w", err)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	client := NewGuestServiceClient(grpcConn)
+	stream, err := client.CopyArchiveToGuest(ctx)
+	if err != nil {
+		return ArchiveProgress{}, fmt.Errorf("start archive stream: %w", err)
+	}
+	if err := stream.Send(&CopyArchiveToGuestRequest{
+		Path:    opts.Path,
+		Include: opts.Include,
+		Exclude: opts.Exclude,
+	}); err != nil {
+		return ArchiveProgress{}, fmt.Errorf("send start: %w", err)
+	}
+
+	// Forward the archive while progress is received below
+	sendErr := make(chan error, 1)
+	go func() {
+		buf := make([]byte, archiveChunkSize)
+		for {
+			n, err := r.Read(buf)
+			if n > 0 {
+				if err := stream.Send(&CopyArchiveToGuestRequest{Data: buf[:n]}); err != nil {
+					// The guest ended the stream; its status is returned by Recv
+					sendErr <- nil
+					return
+				}
+			}
+			if err == io.EOF {
+				sendErr <- stream.CloseSend()
+				return
+			}
+			if err != nil {
+				sendErr <- fmt.Errorf("read archive: %w", err)
+				cancel()
+				return
+			}
+		}
+	}()
+
+	var last ArchiveProgress
+	for {
+		resp, err := stream.Recv()
+		if err != nil {
+			// A failed read cancels the stream; report it instead of the cancellation.
+			// Don't wait otherwise: the sender may be blocked reading r.
+			select {
+			case sErr := <-sendErr:
+				if sErr != nil {
+					return last, sErr
+				}
+			default:
+			}
+			if err == io.EOF {
+				return last, fmt.Errorf("archive stream ended without completion")
+			}
+			return last, archiveError("copy archive to guest", err)
+		}
+		last = ArchiveProgress{Files: resp.Files, Bytes: resp.Bytes}
+		if progress != nil {
+			progress(last)
+		}
+		if resp.Done {
+			return last, <-sendErr
+		}
+	}
+}
+
+// CopyArchiveFromInstance writes a guest file or directory to w as a tar
+// stream. Entry names are relative to the parent of opts.Path, so the
+// archive's top-level entry is the base name of the source.
+func CopyArchiveFromInstance(ctx context.Context, dialer hypervisor.VsockDialer, opts CopyArchiveOptions, w io.Writer, progress func(ArchiveProgress)) (ArchiveProgress, error) {
+	if _, err := NewPathFilter(opts.Include, opts.Exclude); err != nil {
+		return ArchiveProgress{}, err
+	}
+	grpcConn, err := GetOrCreateConn(ctx, dialer)
+	if err != nil {
+		return ArchiveProgress{}, fmt.Errorf("get grpc connection: %w", err)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	client := NewGuestServiceClient(grpcConn)
+	stream, err := client.CopyArchiveFromGuest(ctx, &CopyArchiveFromGuestRequest{
+		Path:        opts.Path,
+		Include:     opts.Include,
+		Exclude:     opts.Exclude,
+		FollowLinks: opts.FollowLinks,
+	})
+	if err != nil {
+		return ArchiveProgress{}, fmt.Errorf("start archive stream: %w", err)
+	}
+
+	var last ArchiveProgress
+	for {
+		resp, err := stream.Recv()
+		if err == io.EOF {
+			return last, fmt.Errorf("archive stream ended without completion")
+		}
+		if err != nil {
+			return last, archiveError("copy archive from guest", err)
+		}
+		if len(resp.Data) > 0 {
+			if _, err := w.Write(resp.Data); err != nil {
+				return last, fmt.Errorf("write archive: %w", err)
+			}
+		}
+		if p := resp.Progress; p != nil {
+			last = ArchiveProgress{Files: p.Files, Bytes: p.Bytes}
+			if progress != nil {
+				progress(last)
+			}
+			if p.Done {
+				return last, nil
+			}
+		}
+	}
+}
+
+// archiveError strips the gRPC framing from errors reported by the guest
+func archiveError(op string, err error) error {
+	if s, ok := status.FromError(err); ok {
+		return fmt.Errorf("%s: %s", op, strings.TrimSpace(s.Message()))
+	}
+	return fmt.Errorf("%s: %w", op, err)
+}
@@ -0,0 +1,44 @@
+package guest
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPathFilter(t *testing.T) {
+	tests := []struct {
+		name    string
+		include []string
+		exclude []string
+		entry   string
+		want    bool
+	}{
+		{name: "no patterns", entry: "app/main.go", want: true},
+		{name: "exclude by base name", exclude: []string{"node_modules"}, entry: "app/node_modules", want: false},
+		{name: "exclude parent directory", exclude: []string{"node_modules"}, entry: "app/node_modules/x/index.js", want: false},
+		{name: "exclude by path", exclude: []string{"app/tmp"}, entry: "app/tmp/cache", want: false},
+		{name: "exclude does not match sibling", exclude: []string{"app/tmp"}, entry: "app/tmpfile", want: true},
+		{name: "include glob by base name", include: []string{"*.go"}, entry: "app/src/main.go", want: true},
+		{name: "include skips others", include: []string{"*.go"}, entry: "app/README.md", want: false},
+		{name: "include skips unmatched directory", include: []string{"*.go"}, entry: "app/src", want: false},
+		{name: "include directory contents", include: []string{"app/src"}, entry: "app/src/pkg/file.txt", want: true},
+		{name: "exclude wins over include", include: []string{"*.go"}, exclude: []string{"vendor"}, entry: "app/vendor/dep.go", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := NewPathFilter(tt.include, tt.exclude)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, f.Match(tt.entry))
+		})
+	}
+}
+
+func TestNewPathFilter_InvalidPattern(t *testing.T) {
+	_, err := NewPathFilter([]string{"["}, nil)
+	assert.ErrorIs(t, err, ErrInvalidPattern)
+
+	_, err = NewPathFilter(nil, []string{""})
+	assert.ErrorIs(t, err, ErrInvalidPattern)
+}
@@ -2,12 +2,13 @@ package guest
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"fmt"
+	"hash"
 	"io"
 	"io/fs"
-	"log/slog"
-	"net"
 	"os"
 	"path/filepath"
 	"strings"
@@ -17,9 +18,7 @@ import (
 
 	securejoin "github.com/cyphar/filepath-securejoin"
 	"github.com/kernel/hypeman/lib/hypervisor"
-	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
-	"google.golang.org/grpc/credentials/insecure"
 	"google.golang.org/grpc/status"
 )
 
@@ -42,70 +41,6 @@ func (e *AgentVSockDialError) Unwrap() error {
 	return e.Err
 }
 
-// connPool manages reusable gRPC connections per vsock dialer key
-// This avoids the overhead and potential issues of rapidly creating/closing connections
-var connPool = struct {
-	sync.RWMutex
-	conns map[string]*grpc.ClientConn
-}{
-	conns: make(map[string]*grpc.ClientConn),
-}
-
-// GetOrCreateConn returns an existing connection or creates a new one using a VsockDialer.
-// This supports multiple hypervisor types (Cloud Hypervisor, QEMU, etc.).
-func GetOrCreateConn(ctx context.Context, dialer hypervisor.VsockDialer) (*grpc.ClientConn, error) {
-	key := dialer.Key()
-
-	// Try read lock first for existing connection
-	connPool.RLock()
-	if conn, ok := connPool.conns[key]; ok {
-		connPool.RUnlock()
-		return conn, nil
-	}
-	connPool.RUnlock()
-
-	// Need to create new connection - acquire write lock
-	connPool.Lock()
-	defer connPool.Unlock()
-
-	// Double-check after acquiring write lock
-	if conn, ok := connPool.conns[key]; ok {
-		return conn, nil
-	}
-
-	// Create new connection using the VsockDialer
-	conn, err := grpc.Dial("passthrough:///vsock",
-		grpc.WithContextDialer(func(ctx context.Context, addr string) (net.Conn, error) {
-			netConn, err := dialer.DialVsock(ctx, vsockGuestPort)
-			if err != nil {
-				return nil, &AgentVSockDialError{Err: err}
-			}
-			return netConn, nil
-		}),
-		grpc.WithTransportCredentials(insecure.NewCredentials()),
-	)
-	if err != nil {
-		return nil, fmt.Errorf("create grpc connection: %w", err)
-	}
-
-	connPool.conns[key] = conn
-	slog.Debug("created new gRPC connection", "key", key)
-	return conn, nil
-}
-
-// CloseConn removes a connection from the pool by key (call when VM is deleted).
-// We only remove from pool, not explicitly close - the connection will fail
-// naturally when the VM dies, and grpc will clean up.
-func CloseConn(dialerKey string) {
-	connPool.Lock()
-	defer connPool.Unlock()
-
-	if _, ok := connPool.conns[dialerKey]; ok {
-		delete(connPool.conns, dialerKey)
-		slog.Debug("removed gRPC connection from pool", "key", dialerKey)
-	}
-}
-
 // ExitStatus represents command exit information
 type ExitStatus struct {
 	Code int
@@ -122,6 +57,8 @@ type ExecOptions struct {
 	Cwd          string            // Working directory (optional)
 	Timeout      int32             // Execution timeout in seconds (0 = no timeout)
 	WaitForAgent time.Duration     // Max time to wait for agent to be ready (0 = no wait, fail immediately)
+	Rows, Cols   uint16            // Initial terminal size with TTY (0 = the guest's default)
+	Control      *ExecControl      // Resizes and signals during the session (optional)
 }
 
 // ExecIntoInstance executes command in instance via vsock using gRPC.
@@ -203,21 +140,43 @@ func execIntoInstanceOnce(ctx context.Context, dialer hypervisor.VsockDialer, op
 	// Ensure stream is properly closed when we're done
 	defer stream.CloseSend()
 
+	// Stdin and control messages are sent from different goroutines
+	var sendMu sync.Mutex
+	send := func(req *ExecRequest) error {
+		sendMu.Lock()
+		defer sendMu.Unlock()
+		return stream.Send(req)
+	}
+	closeSend := func() error {
+		sendMu.Lock()
+		defer sendMu.Unlock()
+		return stream.CloseSend()
+	}
+
 	// Send start request
-	if err := stream.Send(&ExecRequest{
-		Request: &ExecRequest_Start{
-			Start: &ExecStart{
-				Command:        opts.Command,
-				Tty:            opts.TTY,
-				Env:            opts.Env,
-				Cwd:            opts.Cwd,
-				TimeoutSeconds: opts.Timeout,
-			},
-		},
+	execStart := &ExecStart{
+		Command:        opts.Command,
+		Tty:            opts.TTY,
+		Env:            opts.Env,
+		Cwd:            opts.Cwd,
+		TimeoutSeconds: opts.Timeout,
+	}
+	if opts.Rows > 0 && opts.Cols > 0 {
+		execStart.Size = &WindowSize{Rows: uint32(opts.Rows), Cols: uint32(opts.Cols)}
+	}
+	if err := send(&ExecRequest{
+		Request: &ExecRequest_Start{Start: execStart},
 	}); err != nil {
 		return nil, fmt.Errorf("send start request: %w", err)
 	}
 
+	if opts.Control != nil {
+		defer opts.Control.detach()
+		if err := opts.Control.attach(send); err != nil {
+			return nil, fmt.Errorf("send control request: %w", err)
+		}
+	}
+
 	// Handle stdin in background
 	if opts.Stdin != nil {
 		go func() {
@@ -225,13 +184,13 @@ func execIntoInstanceOnce(ctx context.Context, dialer hypervisor.VsockDialer, op
 			for {
 				n, err := opts.Stdin.Read(buf)
 				if n > 0 {
-					stream.Send(&ExecRequest{
+					send(&ExecRequest{
 						Request: &ExecRequest_Stdin{Stdin: buf[:n]},
 					})
 					atomic.AddInt64(&bytesSent, int64(n))
 				}
 				if err != nil {
-					stream.CloseSend()
+					opts.Control.close(closeSend)
 					return
 				}
 			}
@@ -277,6 +236,7 @@ type CopyToInstanceOptions struct {
 	SrcPath string      // Local source path
 	DstPath string      // Destination path in guest
 	Mode    fs.FileMode // Optional: override file mode (0 = preserve source)
+	Resume  bool        // Single files: keep the bytes already in the guest file and send only the rest
 }
 
 // CopyToInstance copies a file or directory to an instance via vsock.
@@ -298,11 +258,24 @@ func CopyToInstance(ctx context.Context, dialer hypervisor.VsockDialer, opts Cop
 	if srcInfo.IsDir() {
 		return copyDirToInstance(ctx, client, opts.SrcPath, opts.DstPath)
 	}
-	return copyFileToInstance(ctx, client, opts.SrcPath, opts.DstPath, opts.Mode)
+
+	var offset int64
+	if opts.Resume {
+		stat, err := client.StatPath(ctx, &StatPathRequest{Path: opts.DstPath})
+		if err != nil {
+			return fmt.Errorf("stat destination: %w", err)
+		}
+		// A partial copy can't be longer than the source; anything else starts over
+		if stat.Exists && stat.IsFile && stat.Size <= srcInfo.Size() {
+			offset = stat.Size
+		}
+	}
+	return copyFileToInstance(ctx, client, opts.SrcPath, opts.DstPath, opts.Mode, offset)
 }
 
-// copyFileToInstance copies a single file to the instance
-func copyFileToInstance(ctx context.Context, client GuestServiceClient, srcPath, dstPath string, mode fs.FileMode) error {
+// copyFileToInstance copies a single file to the instance, starting at offset.
+// The guest verifies the complete file against the source's SHA-256.
+func copyFileToInstance(ctx context.Context, client GuestServiceClient, srcPath, dstPath string, mode fs.FileMode, offset int64) error {
 	srcInfo, err := os.Stat(srcPath)
 	if err != nil {
 		return fmt.Errorf("stat source: %w", err)
@@ -327,22 +300,28 @@ func copyFileToInstance(ctx context.Context, client GuestServiceClient, srcPath,
 	if err := stream.Send(&CopyToGuestRequest{
 		Request: &CopyToGuestRequest_Start{
 			Start: &CopyToGuestStart{
-				Path:  dstPath,
-				Mode:  uint32(mode),
-				IsDir: false,
-				Size:  srcInfo.Size(),
-				Mtime: srcInfo.ModTime().Unix(),
+				Path:   dstPath,
+				Mode:   uint32(mode),
+				IsDir:  false,
+				Size:   srcInfo.Size(),
+				Mtime:  srcInfo.ModTime().Unix(),
+				Offset: offset,
 			},
 		},
 	}); err != nil {
 		return fmt.Errorf("send start: %w", err)
 	}
 
-	// Stream file content
+	// Hash the part the guest already has, then stream the rest
+	hasher := sha256.New()
+	if _, err := io.CopyN(hasher, f, offset); err != nil {
+		return fmt.Errorf("read source: %w", err)
+	}
 	buf := make([]byte, 32*1024)
 	for {
 		n, err := f.Read(buf)
 		if n > 0 {
+			hasher.Write(buf[:n])
 			if sendErr := stream.Send(&CopyToGuestRequest{
 				Request: &CopyToGuestRequest_Data{Data: buf[:n]},
 			}); sendErr != nil {
@@ -359,7 +338,7 @@ func copyFileToInstance(ctx context.Context, client GuestServiceClient, srcPath,
 
 	// Send end marker
 	if err := stream.Send(&CopyToGuestRequest{
-		Request: &CopyToGuestRequest_End{End: &CopyToGuestEnd{}},
+		Request: &CopyToGuestRequest_End{End: &CopyToGuestEnd{Sha256: hex.EncodeToString(hasher.Sum(nil))}},
 	}); err != nil {
 		return fmt.Errorf("send end: %w", err)
 	}
@@ -476,7 +455,7 @@ func copyDirToInstance(ctx context.Context, client GuestServiceClient, srcPath,
 		}
 
 		// Copy file
-		return copyFileToInstance(ctx, client, path, targetPath, 0)
+		return copyFileToInstance(ctx, client, path, targetPath, 0, 0)
 	})
 }
 
@@ -485,6 +464,7 @@ type CopyFromInstanceOptions struct {
 	SrcPath     string // Source path in guest
 	DstPath     string // Local destination path
 	FollowLinks bool   // Follow symbolic links
+	Resume      bool   // Single files: keep the bytes already in the local file and fetch only the rest
 }
 
 // FileHandler is called for each file received from the instance
@@ -500,9 +480,17 @@ func CopyFromInstance(ctx context.Context, dialer hypervisor.VsockDialer, opts C
 
 	client := NewGuestServiceClient(grpcConn)
 
+	var offset int64
+	if opts.Resume {
+		if offset, err = copyFromInstanceOffset(ctx, client, opts); err != nil {
+			return err
+		}
+	}
+
 	stream, err := client.CopyFromGuest(ctx, &CopyFromGuestRequest{
 		Path:        opts.SrcPath,
 		FollowLinks: opts.FollowLinks,
+		Offset:      offset,
 	})
 	if err != nil {
 		return fmt.Errorf("start copy stream: %w", err)
@@ -510,6 +498,7 @@ func CopyFromInstance(ctx context.Context, dialer hypervisor.VsockDialer, opts C
 
 	var currentFile *os.File
 	var currentHeader *CopyFromGuestHeader
+	var currentHash hash.Hash
 	var receivedFinal bool
 
 	// Ensure file is closed on error paths
@@ -584,12 +573,12 @@ func CopyFromInstance(ctx context.Context, dialer hypervisor.VsockDialer, opts C
 				if err := os.MkdirAll(filepath.Dir(targetPath), 0755); err != nil {
 					return fmt.Errorf("create parent dir: %w", err)
 				}
-				// Create file
-				f, err := os.OpenFile(targetPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, fs.FileMode(r.Header.Mode))
+				// Create file, or continue the partial one (only the single file copy resumes)
+				f, h, err := openLocalCopyTarget(targetPath, fs.FileMode(r.Header.Mode), offset)
 				if err != nil {
-					return fmt.Errorf("create file %s: %w", targetPath, err)
+					return err
 				}
-				currentFile = f
+				currentFile, currentHash = f, h
 			}
 
 		case *CopyFromGuestResponse_Data:
@@ -597,12 +586,17 @@ func CopyFromInstance(ctx context.Context, dialer hypervisor.VsockDialer, opts C
 				if _, err := currentFile.Write(r.Data); err != nil {
 					return fmt.Errorf("write: %w", err)
 				}
+				currentHash.Write(r.Data)
 			}
 
 		case *CopyFromGuestResponse_End:
 			if currentFile != nil {
 				currentFile.Close()
 				currentFile = nil
+				// Older guest-agents don't send checksums
+				if sum := hex.EncodeToString(currentHash.Sum(nil)); r.End.Sha256 != "" && r.End.Sha256 != sum {
+					return fmt.Errorf("checksum mismatch for %s: expected %s, got %s", currentHeader.GetPath(), r.End.Sha256, sum)
+				}
 			}
 			// Set modification time for files and directories
 			if currentHeader != nil && currentHeader.Mtime > 0 {
@@ -628,3 +622,52 @@ func CopyFromInstance(ctx context.Context, dialer hypervisor.VsockDialer, opts C
 	}
 	return nil
 }
+
+// copyFromInstanceOffset returns how much of a single-file copy is already in
+// place locally. Directories and local files longer than the source start over.
+func copyFromInstanceOffset(ctx context.Context, client GuestServiceClient, opts CopyFromInstanceOptions) (int64, error) {
+	stat, err := client.StatPath(ctx, &StatPathRequest{Path: opts.SrcPath, FollowLinks: opts.FollowLinks})
+	if err != nil {
+		return 0, fmt.Errorf("stat source: %w", err)
+	}
+	if !stat.Exists || !stat.IsFile {
+		return 0, nil
+	}
+	targetPath, err := securejoin.SecureJoin(opts.DstPath, filepath.Base(opts.SrcPath))
+	if err != nil {
+		return 0, nil
+	}
+	info, err := os.Lstat(targetPath)
+	if err != nil || !info.Mode().IsRegular() || info.Size() > stat.Size {
+		return 0, nil
+	}
+	return info.Size(), nil
+}
+
+// openLocalCopyTarget creates a file for a copy from the instance. With an
+// offset, the existing file is kept up to offset and its prefix hashed, so
+// the returned hash covers the complete file once the rest has been written.
+func openLocalCopyTarget(path string, mode fs.FileMode, offset int64) (*os.File, hash.Hash, error) {
+	h := sha256.New()
+	if offset <= 0 {
+		f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, mode)
+		if err != nil {
+			return nil, nil, fmt.Errorf("create file %s: %w", path, err)
+		}
+		return f, h, nil
+	}
+
+	f, err := os.OpenFile(path, os.O_RDWR, 0)
+	if err != nil {
+		return nil, nil, fmt.Errorf("open file %s to resume: %w", path, err)
+	}
+	if _, err := io.CopyN(h, f, offset); err != nil {
+		f.Close()
+		return nil, nil, fmt.Errorf("read file %s to resume: %w", path, err)
+	}
+	if err := f.Truncate(offset); err != nil {
+		f.Close()
+		return nil, nil, fmt.Errorf("truncate file %s to resume: %w", path, err)
+	}
+	return f, h, nil
+}
@@ -0,0 +1,39 @@
+package guest
+
+import (
+	"crypto/sha256"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOpenLocalCopyTarget_Resume(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "file")
+	// The partial copy has a stale tail past the resume offset
+	require.NoError(t, os.WriteFile(path, []byte("hello stale"), 0644))
+
+	f, h, err := openLocalCopyTarget(path, 0644, 5)
+	require.NoError(t, err)
+	_, err = f.Write([]byte(" world"))
+	require.NoError(t, err)
+	h.Write([]byte(" world"))
+	require.NoError(t, f.Close())
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "hello world", string(data))
+
+	want := sha256.Sum256([]byte("hello world"))
+	assert.Equal(t, want[:], h.Sum(nil), "hash covers the kept prefix and the new data")
+}
+
+func TestOpenLocalCopyTarget_ResumePastEnd(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "file")
+	require.NoError(t, os.WriteFile(path, []byte("short"), 0644))
+
+	_, _, err := openLocalCopyTarget(path, 0644, 100)
+	assert.Error(t, err)
+}
@@ -0,0 +1,27 @@
+package guest
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/kernel/hypeman/lib/hypervisor"
+)
+
+// SyncClock sets the guest's wall clock to the host's and returns how far
+// the guest was ahead of the host (negative if behind) before the change.
+// The round trip to the guest is not compensated for.
+func SyncClock(ctx context.Context, dialer hypervisor.VsockDialer) (time.Duration, error) {
+	grpcConn, err := GetOrCreateConn(ctx, dialer)
+	if err != nil {
+		return 0, fmt.Errorf("get grpc connection: %w", err)
+	}
+
+	resp, err := NewGuestServiceClient(grpcConn).SyncClock(ctx, &SyncClockRequest{
+		HostTimeUnixNano: time.Now().UnixNano(),
+	})
+	if err != nil {
+		return 0, fmt.Errorf("sync clock: %w", err)
+	}
+	return time.Duration(resp.SkewNanos), nil
+}
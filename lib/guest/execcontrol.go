@@ -0,0 +1,90 @@
+package guest
+
+import (
+	"errors"
+	"sync"
+)
+
+// ErrExecInputClosed is returned by ExecControl once the session's stdin has
+// ended, as control messages travel on the same stream
+var ErrExecInputClosed = errors.New("exec input closed")
+
+// ExecControl sends terminal size changes and signals to a running exec
+// session. Pass one in ExecOptions.Control; calls made before the session
+// has started are sent once it does.
+type ExecControl struct {
+	mu      sync.Mutex
+	send    func(*ExecRequest) error // Set while a session runs
+	closed  bool                     // The session's stdin ended
+	pending []*ExecRequest
+}
+
+// NewExecControl creates a control for one exec session
+func NewExecControl() *ExecControl {
+	return &ExecControl{}
+}
+
+// Resize tells the session's terminal its new size, as a client terminal
+// does on SIGWINCH. It has no effect on sessions without a TTY.
+func (c *ExecControl) Resize(rows, cols uint16) error {
+	return c.sendOrQueue(&ExecRequest{
+		Request: &ExecRequest_Resize{Resize: &WindowSize{Rows: uint32(rows), Cols: uint32(cols)}},
+	})
+}
+
+// Signal sends a signal, named like "SIGINT", to the session's command and
+// the processes in its process group
+func (c *ExecControl) Signal(sig string) error {
+	return c.sendOrQueue(&ExecRequest{
+		Request: &ExecRequest_Signal{Signal: sig},
+	})
+}
+
+func (c *ExecControl) sendOrQueue(req *ExecRequest) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return ErrExecInputClosed
+	}
+	if c.send == nil {
+		c.pending = append(c.pending, req)
+		return nil
+	}
+	return c.send(req)
+}
+
+// attach routes control messages to a session's stream, flushing those
+// queued before it started. send must be safe to call with stdin sends.
+func (c *ExecControl) attach(send func(*ExecRequest) error) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.send = send
+	for len(c.pending) > 0 {
+		if err := send(c.pending[0]); err != nil {
+			return err
+		}
+		c.pending = c.pending[1:]
+	}
+	return nil
+}
+
+// close ends control once the session's stdin is done and runs closeSend,
+// so no control message can follow it
+func (c *ExecControl) close(closeSend func() error) error {
+	if c == nil {
+		return closeSend()
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.closed = true
+	c.send = nil
+	return closeSend()
+}
+
+// detach stops routing control messages to an ended session attempt, so a
+// retry can attach its own stream
+func (c *ExecControl) detach() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.send = nil
+}
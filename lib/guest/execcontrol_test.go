@@ -0,0 +1,34 @@
+package guest
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExecControl(t *testing.T) {
+	ctl := NewExecControl()
+	// Sent once the session starts
+	require.NoError(t, ctl.Resize(40, 120))
+
+	var sent []*ExecRequest
+	require.NoError(t, ctl.attach(func(req *ExecRequest) error {
+		sent = append(sent, req)
+		return nil
+	}))
+	require.NoError(t, ctl.Signal("SIGINT"))
+	require.Len(t, sent, 2)
+	assert.Equal(t, uint32(40), sent[0].GetResize().GetRows())
+	assert.Equal(t, uint32(120), sent[0].GetResize().GetCols())
+	assert.Equal(t, "SIGINT", sent[1].GetSignal())
+
+	closed := false
+	require.NoError(t, ctl.close(func() error {
+		closed = true
+		return nil
+	}))
+	assert.True(t, closed)
+	assert.ErrorIs(t, ctl.Signal("SIGTERM"), ErrExecInputClosed)
+	assert.Len(t, sent, 2)
+}
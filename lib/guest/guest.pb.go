@@ -26,6 +26,8 @@ type ExecRequest struct {
 	//
 	//	*ExecRequest_Start
 	//	*ExecRequest_Stdin
+	//	*ExecRequest_Resize
+	//	*ExecRequest_Signal
 	Request              isExecRequest_Request `protobuf_oneof:"request"`
 	XXX_NoUnkeyedLiteral struct{}              `json:"-"`
 	XXX_unrecognized     []byte                `json:"-"`
@@ -69,10 +71,22 @@ type ExecRequest_Stdin struct {
 	Stdin []byte `protobuf:"bytes,2,opt,name=stdin,proto3,oneof"`
 }
 
+type ExecRequest_Resize struct {
+	Resize *WindowSize `protobuf:"bytes,3,opt,name=resize,proto3,oneof"`
+}
+
+type ExecRequest_Signal struct {
+	Signal string `protobuf:"bytes,4,opt,name=signal,proto3,oneof"`
+}
+
 func (*ExecRequest_Start) isExecRequest_Request() {}
 
 func (*ExecRequest_Stdin) isExecRequest_Request() {}
 
+func (*ExecRequest_Resize) isExecRequest_Request() {}
+
+func (*ExecRequest_Signal) isExecRequest_Request() {}
+
 func (m *ExecRequest) GetRequest() isExecRequest_Request {
 	if m != nil {
 		return m.Request
@@ -94,11 +108,27 @@ func (m *ExecRequest) GetStdin() []byte {
 	return nil
 }
 
+func (m *ExecRequest) GetResize() *WindowSize {
+	if x, ok := m.GetRequest().(*ExecRequest_Resize); ok {
+		return x.Resize
+	}
+	return nil
+}
+
+func (m *ExecRequest) GetSignal() string {
+	if x, ok := m.GetRequest().(*ExecRequest_Signal); ok {
+		return x.Signal
+	}
+	return ""
+}
+
 // XXX_OneofWrappers is for the internal use of the proto package.
 func (*ExecRequest) XXX_OneofWrappers() []interface{} {
 	return []interface{}{
 		(*ExecRequest_Start)(nil),
 		(*ExecRequest_Stdin)(nil),
+		(*ExecRequest_Resize)(nil),
+		(*ExecRequest_Signal)(nil),
 	}
 }
 
@@ -109,6 +139,7 @@ type ExecStart struct {
 	Env                  map[string]string `protobuf:"bytes,3,rep,name=env,proto3" json:"env,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
 	Cwd                  string            `protobuf:"bytes,4,opt,name=cwd,proto3" json:"cwd,omitempty"`
 	TimeoutSeconds       int32             `protobuf:"varint,5,opt,name=timeout_seconds,json=timeoutSeconds,proto3" json:"timeout_seconds,omitempty"`
+	Size                 *WindowSize       `protobuf:"bytes,6,opt,name=size,proto3" json:"size,omitempty"`
 	XXX_NoUnkeyedLiteral struct{}          `json:"-"`
 	XXX_unrecognized     []byte            `json:"-"`
 	XXX_sizecache        int32             `json:"-"`
@@ -174,6 +205,13 @@ func (m *ExecStart) GetTimeoutSeconds() int32 {
 	return 0
 }
 
+func (m *ExecStart) GetSize() *WindowSize {
+	if m != nil {
+		return m.Size
+	}
+	return nil
+}
+
 // ExecResponse represents messages from server to client
 type ExecResponse struct {
 	// Types that are valid to be assigned to Response:
@@ -377,6 +415,7 @@ type CopyToGuestStart struct {
 	Mtime                int64    `protobuf:"varint,5,opt,name=mtime,proto3" json:"mtime,omitempty"`
 	Uid                  uint32   `protobuf:"varint,6,opt,name=uid,proto3" json:"uid,omitempty"`
 	Gid                  uint32   `protobuf:"varint,7,opt,name=gid,proto3" json:"gid,omitempty"`
+	Offset               int64    `protobuf:"varint,8,opt,name=offset,proto3" json:"offset,omitempty"`
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
 	XXX_unrecognized     []byte   `json:"-"`
 	XXX_sizecache        int32    `json:"-"`
@@ -456,8 +495,16 @@ func (m *CopyToGuestStart) GetGid() uint32 {
 	return 0
 }
 
+func (m *CopyToGuestStart) GetOffset() int64 {
+	if m != nil {
+		return m.Offset
+	}
+	return 0
+}
+
 // CopyToGuestEnd signals the end of a file transfer
 type CopyToGuestEnd struct {
+	Sha256               string   `protobuf:"bytes,1,opt,name=sha256,proto3" json:"sha256,omitempty"`
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
 	XXX_unrecognized     []byte   `json:"-"`
 	XXX_sizecache        int32    `json:"-"`
@@ -488,11 +535,19 @@ func (m *CopyToGuestEnd) XXX_DiscardUnknown() {
 
 var xxx_messageInfo_CopyToGuestEnd proto.InternalMessageInfo
 
+func (m *CopyToGuestEnd) GetSha256() string {
+	if m != nil {
+		return m.Sha256
+	}
+	return ""
+}
+
 // CopyToGuestResponse is the response after a copy-to-guest operation
 type CopyToGuestResponse struct {
 	Success              bool     `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
 	Error                string   `protobuf:"bytes,2,opt,name=error,proto3" json:"error,omitempty"`
 	BytesWritten         int64    `protobuf:"varint,3,opt,name=bytes_written,json=bytesWritten,proto3" json:"bytes_written,omitempty"`
+	Sha256               string   `protobuf:"bytes,4,opt,name=sha256,proto3" json:"sha256,omitempty"`
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
 	XXX_unrecognized     []byte   `json:"-"`
 	XXX_sizecache        int32    `json:"-"`
@@ -544,10 +599,18 @@ func (m *CopyToGuestResponse) GetBytesWritten() int64 {
 	return 0
 }
 
+func (m *CopyToGuestResponse) GetSha256() string {
+	if m != nil {
+		return m.Sha256
+	}
+	return ""
+}
+
 // CopyFromGuestRequest initiates a copy-from-guest operation
 type CopyFromGuestRequest struct {
 	Path                 string   `protobuf:"bytes,1,opt,name=path,proto3" json:"path,omitempty"`
 	FollowLinks          bool     `protobuf:"varint,2,opt,name=follow_links,json=followLinks,proto3" json:"follow_links,omitempty"`
+	Offset               int64    `protobuf:"varint,3,opt,name=offset,proto3" json:"offset,omitempty"`
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
 	XXX_unrecognized     []byte   `json:"-"`
 	XXX_sizecache        int32    `json:"-"`
@@ -592,6 +655,13 @@ func (m *CopyFromGuestRequest) GetFollowLinks() bool {
 	return false
 }
 
+func (m *CopyFromGuestRequest) GetOffset() int64 {
+	if m != nil {
+		return m.Offset
+	}
+	return 0
+}
+
 // CopyFromGuestResponse streams file data from guest
 type CopyFromGuestResponse struct {
 	// Types that are valid to be assigned to Response:
@@ -811,6 +881,7 @@ func (m *CopyFromGuestHeader) GetGid() uint32 {
 // CopyFromGuestEnd signals the end of a file or transfer
 type CopyFromGuestEnd struct {
 	Final                bool     `protobuf:"varint,1,opt,name=final,proto3" json:"final,omitempty"`
+	Sha256               string   `protobuf:"bytes,2,opt,name=sha256,proto3" json:"sha256,omitempty"`
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
 	XXX_unrecognized     []byte   `json:"-"`
 	XXX_sizecache        int32    `json:"-"`
@@ -848,6 +919,13 @@ func (m *CopyFromGuestEnd) GetFinal() bool {
 	return false
 }
 
+func (m *CopyFromGuestEnd) GetSha256() string {
+	if m != nil {
+		return m.Sha256
+	}
+	return ""
+}
+
 // CopyFromGuestError reports an error during copy
 type CopyFromGuestError struct {
 	Message              string   `protobuf:"bytes,1,opt,name=message,proto3" json:"message,omitempty"`
@@ -1040,6 +1118,1750 @@ func (m *StatPathResponse) GetError() string {
 	return ""
 }
 
+// UpdateAgentRequest represents messages for pushing a new guest-agent binary
+type UpdateAgentRequest struct {
+	// Types that are valid to be assigned to Request:
+	//
+	//	*UpdateAgentRequest_Start
+	//	*UpdateAgentRequest_Data
+	Request              isUpdateAgentRequest_Request `protobuf_oneof:"request"`
+	XXX_NoUnkeyedLiteral struct{}                     `json:"-"`
+	XXX_unrecognized     []byte                       `json:"-"`
+	XXX_sizecache        int32                        `json:"-"`
+}
+
+func (m *UpdateAgentRequest) Reset()         { *m = UpdateAgentRequest{} }
+func (m *UpdateAgentRequest) String() string { return proto.CompactTextString(m) }
+func (*UpdateAgentRequest) ProtoMessage()    {}
+func (*UpdateAgentRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_44c1cba55f3bcb29, []int{14}
+}
+
+func (m *UpdateAgentRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_UpdateAgentRequest.Unmarshal(m, b)
+}
+func (m *UpdateAgentRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_UpdateAgentRequest.Marshal(b, m, deterministic)
+}
+func (m *UpdateAgentRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_UpdateAgentRequest.Merge(m, src)
+}
+func (m *UpdateAgentRequest) XXX_Size() int {
+	return xxx_messageInfo_UpdateAgentRequest.Size(m)
+}
+func (m *UpdateAgentRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_UpdateAgentRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_UpdateAgentRequest proto.InternalMessageInfo
+
+type isUpdateAgentRequest_Request interface {
+	isUpdateAgentRequest_Request()
+}
+
+type UpdateAgentRequest_Start struct {
+	Start *UpdateAgentStart `protobuf:"bytes,1,opt,name=start,proto3,oneof"`
+}
+
+type UpdateAgentRequest_Data struct {
+	Data []byte `protobuf:"bytes,2,opt,name=data,proto3,oneof"`
+}
+
+func (*UpdateAgentRequest_Start) isUpdateAgentRequest_Request() {}
+
+func (*UpdateAgentRequest_Data) isUpdateAgentRequest_Request() {}
+
+func (m *UpdateAgentRequest) GetRequest() isUpdateAgentRequest_Request {
+	if m != nil {
+		return m.Request
+	}
+	return nil
+}
+
+func (m *UpdateAgentRequest) GetStart() *UpdateAgentStart {
+	if x, ok := m.GetRequest().(*UpdateAgentRequest_Start); ok {
+		return x.Start
+	}
+	return nil
+}
+
+func (m *UpdateAgentRequest) GetData() []byte {
+	if x, ok := m.GetRequest().(*UpdateAgentRequest_Data); ok {
+		return x.Data
+	}
+	return nil
+}
+
+// XXX_OneofWrappers is for the internal use of the proto package.
+func (*UpdateAgentRequest) XXX_OneofWrappers() []interface{} {
+	return []interface{}{
+		(*UpdateAgentRequest_Start)(nil),
+		(*UpdateAgentRequest_Data)(nil),
+	}
+}
+
+// UpdateAgentStart initiates a guest-agent update
+type UpdateAgentStart struct {
+	Size                 int64    `protobuf:"varint,1,opt,name=size,proto3" json:"size,omitempty"`
+	Sha256               string   `protobuf:"bytes,2,opt,name=sha256,proto3" json:"sha256,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *UpdateAgentStart) Reset()         { *m = UpdateAgentStart{} }
+func (m *UpdateAgentStart) String() string { return proto.CompactTextString(m) }
+func (*UpdateAgentStart) ProtoMessage()    {}
+func (*UpdateAgentStart) Descriptor() ([]byte, []int) {
+	return fileDescriptor_44c1cba55f3bcb29, []int{15}
+}
+
+func (m *UpdateAgentStart) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_UpdateAgentStart.Unmarshal(m, b)
+}
+func (m *UpdateAgentStart) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_UpdateAgentStart.Marshal(b, m, deterministic)
+}
+func (m *UpdateAgentStart) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_UpdateAgentStart.Merge(m, src)
+}
+func (m *UpdateAgentStart) XXX_Size() int {
+	return xxx_messageInfo_UpdateAgentStart.Size(m)
+}
+func (m *UpdateAgentStart) XXX_DiscardUnknown() {
+	xxx_messageInfo_UpdateAgentStart.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_UpdateAgentStart proto.InternalMessageInfo
+
+func (m *UpdateAgentStart) GetSize() int64 {
+	if m != nil {
+		return m.Size
+	}
+	return 0
+}
+
+func (m *UpdateAgentStart) GetSha256() string {
+	if m != nil {
+		return m.Sha256
+	}
+	return ""
+}
+
+// UpdateAgentResponse is the response after a guest-agent update
+type UpdateAgentResponse struct {
+	Success              bool     `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
+	Error                string   `protobuf:"bytes,2,opt,name=error,proto3" json:"error,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *UpdateAgentResponse) Reset()         { *m = UpdateAgentResponse{} }
+func (m *UpdateAgentResponse) String() string { return proto.CompactTextString(m) }
+func (*UpdateAgentResponse) ProtoMessage()    {}
+func (*UpdateAgentResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_44c1cba55f3bcb29, []int{16}
+}
+
+func (m *UpdateAgentResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_UpdateAgentResponse.Unmarshal(m, b)
+}
+func (m *UpdateAgentResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_UpdateAgentResponse.Marshal(b, m, deterministic)
+}
+func (m *UpdateAgentResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_UpdateAgentResponse.Merge(m, src)
+}
+func (m *UpdateAgentResponse) XXX_Size() int {
+	return xxx_messageInfo_UpdateAgentResponse.Size(m)
+}
+func (m *UpdateAgentResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_UpdateAgentResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_UpdateAgentResponse proto.InternalMessageInfo
+
+func (m *UpdateAgentResponse) GetSuccess() bool {
+	if m != nil {
+		return m.Success
+	}
+	return false
+}
+
+func (m *UpdateAgentResponse) GetError() string {
+	if m != nil {
+		return m.Error
+	}
+	return ""
+}
+
+// GetAgentInfoRequest requests information about the running guest-agent
+type GetAgentInfoRequest struct {
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *GetAgentInfoRequest) Reset()         { *m = GetAgentInfoRequest{} }
+func (m *GetAgentInfoRequest) String() string { return proto.CompactTextString(m) }
+func (*GetAgentInfoRequest) ProtoMessage()    {}
+func (*GetAgentInfoRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_44c1cba55f3bcb29, []int{17}
+}
+
+func (m *GetAgentInfoRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_GetAgentInfoRequest.Unmarshal(m, b)
+}
+func (m *GetAgentInfoRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_GetAgentInfoRequest.Marshal(b, m, deterministic)
+}
+func (m *GetAgentInfoRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_GetAgentInfoRequest.Merge(m, src)
+}
+func (m *GetAgentInfoRequest) XXX_Size() int {
+	return xxx_messageInfo_GetAgentInfoRequest.Size(m)
+}
+func (m *GetAgentInfoRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_GetAgentInfoRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_GetAgentInfoRequest proto.InternalMessageInfo
+
+// GetAgentInfoResponse contains information about the running guest-agent
+type GetAgentInfoResponse struct {
+	Sha256               string   `protobuf:"bytes,1,opt,name=sha256,proto3" json:"sha256,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *GetAgentInfoResponse) Reset()         { *m = GetAgentInfoResponse{} }
+func (m *GetAgentInfoResponse) String() string { return proto.CompactTextString(m) }
+func (*GetAgentInfoResponse) ProtoMessage()    {}
+func (*GetAgentInfoResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_44c1cba55f3bcb29, []int{18}
+}
+
+func (m *GetAgentInfoResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_GetAgentInfoResponse.Unmarshal(m, b)
+}
+func (m *GetAgentInfoResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_GetAgentInfoResponse.Marshal(b, m, deterministic)
+}
+func (m *GetAgentInfoResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_GetAgentInfoResponse.Merge(m, src)
+}
+func (m *GetAgentInfoResponse) XXX_Size() int {
+	return xxx_messageInfo_GetAgentInfoResponse.Size(m)
+}
+func (m *GetAgentInfoResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_GetAgentInfoResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_GetAgentInfoResponse proto.InternalMessageInfo
+
+func (m *GetAgentInfoResponse) GetSha256() string {
+	if m != nil {
+		return m.Sha256
+	}
+	return ""
+}
+
+// StartProcessRequest starts a supervised process
+type StartProcessRequest struct {
+	Name                 string            `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Command              []string          `protobuf:"bytes,2,rep,name=command,proto3" json:"command,omitempty"`
+	Env                  map[string]string `protobuf:"bytes,3,rep,name=env,proto3" json:"env,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+	Cwd                  string            `protobuf:"bytes,4,opt,name=cwd,proto3" json:"cwd,omitempty"`
+	RestartPolicy        string            `protobuf:"bytes,5,opt,name=restart_policy,json=restartPolicy,proto3" json:"restart_policy,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}          `json:"-"`
+	XXX_unrecognized     []byte            `json:"-"`
+	XXX_sizecache        int32             `json:"-"`
+}
+
+func (m *StartProcessRequest) Reset()         { *m = StartProcessRequest{} }
+func (m *StartProcessRequest) String() string { return proto.CompactTextString(m) }
+func (*StartProcessRequest) ProtoMessage()    {}
+func (*StartProcessRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_44c1cba55f3bcb29, []int{19}
+}
+
+func (m *StartProcessRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_StartProcessRequest.Unmarshal(m, b)
+}
+func (m *StartProcessRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_StartProcessRequest.Marshal(b, m, deterministic)
+}
+func (m *StartProcessRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_StartProcessRequest.Merge(m, src)
+}
+func (m *StartProcessRequest) XXX_Size() int {
+	return xxx_messageInfo_StartProcessRequest.Size(m)
+}
+func (m *StartProcessRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_StartProcessRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_StartProcessRequest proto.InternalMessageInfo
+
+func (m *StartProcessRequest) GetName() string {
+	if m != nil {
+		return m.Name
+	}
+	return ""
+}
+
+func (m *StartProcessRequest) GetCommand() []string {
+	if m != nil {
+		return m.Command
+	}
+	return nil
+}
+
+func (m *StartProcessRequest) GetEnv() map[string]string {
+	if m != nil {
+		return m.Env
+	}
+	return nil
+}
+
+func (m *StartProcessRequest) GetCwd() string {
+	if m != nil {
+		return m.Cwd
+	}
+	return ""
+}
+
+func (m *StartProcessRequest) GetRestartPolicy() string {
+	if m != nil {
+		return m.RestartPolicy
+	}
+	return ""
+}
+
+// StartProcessResponse contains the started process
+type StartProcessResponse struct {
+	Process              *ProcessInfo `protobuf:"bytes,1,opt,name=process,proto3" json:"process,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}     `json:"-"`
+	XXX_unrecognized     []byte       `json:"-"`
+	XXX_sizecache        int32        `json:"-"`
+}
+
+func (m *StartProcessResponse) Reset()         { *m = StartProcessResponse{} }
+func (m *StartProcessResponse) String() string { return proto.CompactTextString(m) }
+func (*StartProcessResponse) ProtoMessage()    {}
+func (*StartProcessResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_44c1cba55f3bcb29, []int{20}
+}
+
+func (m *StartProcessResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_StartProcessResponse.Unmarshal(m, b)
+}
+func (m *StartProcessResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_StartProcessResponse.Marshal(b, m, deterministic)
+}
+func (m *StartProcessResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_StartProcessResponse.Merge(m, src)
+}
+func (m *StartProcessResponse) XXX_Size() int {
+	return xxx_messageInfo_StartProcessResponse.Size(m)
+}
+func (m *StartProcessResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_StartProcessResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_StartProcessResponse proto.InternalMessageInfo
+
+func (m *StartProcessResponse) GetProcess() *ProcessInfo {
+	if m != nil {
+		return m.Process
+	}
+	return nil
+}
+
+// StopProcessRequest stops a supervised process
+type StopProcessRequest struct {
+	Name                 string   `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	TimeoutSeconds       int32    `protobuf:"varint,2,opt,name=timeout_seconds,json=timeoutSeconds,proto3" json:"timeout_seconds,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *StopProcessRequest) Reset()         { *m = StopProcessRequest{} }
+func (m *StopProcessRequest) String() string { return proto.CompactTextString(m) }
+func (*StopProcessRequest) ProtoMessage()    {}
+func (*StopProcessRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_44c1cba55f3bcb29, []int{21}
+}
+
+func (m *StopProcessRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_StopProcessRequest.Unmarshal(m, b)
+}
+func (m *StopProcessRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_StopProcessRequest.Marshal(b, m, deterministic)
+}
+func (m *StopProcessRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_StopProcessRequest.Merge(m, src)
+}
+func (m *StopProcessRequest) XXX_Size() int {
+	return xxx_messageInfo_StopProcessRequest.Size(m)
+}
+func (m *StopProcessRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_StopProcessRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_StopProcessRequest proto.InternalMessageInfo
+
+func (m *StopProcessRequest) GetName() string {
+	if m != nil {
+		return m.Name
+	}
+	return ""
+}
+
+func (m *StopProcessRequest) GetTimeoutSeconds() int32 {
+	if m != nil {
+		return m.TimeoutSeconds
+	}
+	return 0
+}
+
+// StopProcessResponse contains the stopped process
+type StopProcessResponse struct {
+	Process              *ProcessInfo `protobuf:"bytes,1,opt,name=process,proto3" json:"process,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}     `json:"-"`
+	XXX_unrecognized     []byte       `json:"-"`
+	XXX_sizecache        int32        `json:"-"`
+}
+
+func (m *StopProcessResponse) Reset()         { *m = StopProcessResponse{} }
+func (m *StopProcessResponse) String() string { return proto.CompactTextString(m) }
+func (*StopProcessResponse) ProtoMessage()    {}
+func (*StopProcessResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_44c1cba55f3bcb29, []int{22}
+}
+
+func (m *StopProcessResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_StopProcessResponse.Unmarshal(m, b)
+}
+func (m *StopProcessResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_StopProcessResponse.Marshal(b, m, deterministic)
+}
+func (m *StopProcessResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_StopProcessResponse.Merge(m, src)
+}
+func (m *StopProcessResponse) XXX_Size() int {
+	return xxx_messageInfo_StopProcessResponse.Size(m)
+}
+func (m *StopProcessResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_StopProcessResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_StopProcessResponse proto.InternalMessageInfo
+
+func (m *StopProcessResponse) GetProcess() *ProcessInfo {
+	if m != nil {
+		return m.Process
+	}
+	return nil
+}
+
+// RestartProcessRequest restarts a supervised process
+type RestartProcessRequest struct {
+	Name                 string   `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	TimeoutSeconds       int32    `protobuf:"varint,2,opt,name=timeout_seconds,json=timeoutSeconds,proto3" json:"timeout_seconds,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *RestartProcessRequest) Reset()         { *m = RestartProcessRequest{} }
+func (m *RestartProcessRequest) String() string { return proto.CompactTextString(m) }
+func (*RestartProcessRequest) ProtoMessage()    {}
+func (*RestartProcessRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_44c1cba55f3bcb29, []int{23}
+}
+
+func (m *RestartProcessRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_RestartProcessRequest.Unmarshal(m, b)
+}
+func (m *RestartProcessRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_RestartProcessRequest.Marshal(b, m, deterministic)
+}
+func (m *RestartProcessRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_RestartProcessRequest.Merge(m, src)
+}
+func (m *RestartProcessRequest) XXX_Size() int {
+	return xxx_messageInfo_RestartProcessRequest.Size(m)
+}
+func (m *RestartProcessRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_RestartProcessRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_RestartProcessRequest proto.InternalMessageInfo
+
+func (m *RestartProcessRequest) GetName() string {
+	if m != nil {
+		return m.Name
+	}
+	return ""
+}
+
+func (m *RestartProcessRequest) GetTimeoutSeconds() int32 {
+	if m != nil {
+		return m.TimeoutSeconds
+	}
+	return 0
+}
+
+// RestartProcessResponse contains the restarted process
+type RestartProcessResponse struct {
+	Process              *ProcessInfo `protobuf:"bytes,1,opt,name=process,proto3" json:"process,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}     `json:"-"`
+	XXX_unrecognized     []byte       `json:"-"`
+	XXX_sizecache        int32        `json:"-"`
+}
+
+func (m *RestartProcessResponse) Reset()         { *m = RestartProcessResponse{} }
+func (m *RestartProcessResponse) String() string { return proto.CompactTextString(m) }
+func (*RestartProcessResponse) ProtoMessage()    {}
+func (*RestartProcessResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_44c1cba55f3bcb29, []int{24}
+}
+
+func (m *RestartProcessResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_RestartProcessResponse.Unmarshal(m, b)
+}
+func (m *RestartProcessResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_RestartProcessResponse.Marshal(b, m, deterministic)
+}
+func (m *RestartProcessResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_RestartProcessResponse.Merge(m, src)
+}
+func (m *RestartProcessResponse) XXX_Size() int {
+	return xxx_messageInfo_RestartProcessResponse.Size(m)
+}
+func (m *RestartProcessResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_RestartProcessResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_RestartProcessResponse proto.InternalMessageInfo
+
+func (m *RestartProcessResponse) GetProcess() *ProcessInfo {
+	if m != nil {
+		return m.Process
+	}
+	return nil
+}
+
+// ListProcessesRequest lists the supervised processes
+type ListProcessesRequest struct {
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ListProcessesRequest) Reset()         { *m = ListProcessesRequest{} }
+func (m *ListProcessesRequest) String() string { return proto.CompactTextString(m) }
+func (*ListProcessesRequest) ProtoMessage()    {}
+func (*ListProcessesRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_44c1cba55f3bcb29, []int{25}
+}
+
+func (m *ListProcessesRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ListProcessesRequest.Unmarshal(m, b)
+}
+func (m *ListProcessesRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ListProcessesRequest.Marshal(b, m, deterministic)
+}
+func (m *ListProcessesRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ListProcessesRequest.Merge(m, src)
+}
+func (m *ListProcessesRequest) XXX_Size() int {
+	return xxx_messageInfo_ListProcessesRequest.Size(m)
+}
+func (m *ListProcessesRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_ListProcessesRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ListProcessesRequest proto.InternalMessageInfo
+
+// ListProcessesResponse contains the supervised processes
+type ListProcessesResponse struct {
+	Processes            []*ProcessInfo `protobuf:"bytes,1,rep,name=processes,proto3" json:"processes,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}       `json:"-"`
+	XXX_unrecognized     []byte         `json:"-"`
+	XXX_sizecache        int32          `json:"-"`
+}
+
+func (m *ListProcessesResponse) Reset()         { *m = ListProcessesResponse{} }
+func (m *ListProcessesResponse) String() string { return proto.CompactTextString(m) }
+func (*ListProcessesResponse) ProtoMessage()    {}
+func (*ListProcessesResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_44c1cba55f3bcb29, []int{26}
+}
+
+func (m *ListProcessesResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ListProcessesResponse.Unmarshal(m, b)
+}
+func (m *ListProcessesResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ListProcessesResponse.Marshal(b, m, deterministic)
+}
+func (m *ListProcessesResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ListProcessesResponse.Merge(m, src)
+}
+func (m *ListProcessesResponse) XXX_Size() int {
+	return xxx_messageInfo_ListProcessesResponse.Size(m)
+}
+func (m *ListProcessesResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_ListProcessesResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ListProcessesResponse proto.InternalMessageInfo
+
+func (m *ListProcessesResponse) GetProcesses() []*ProcessInfo {
+	if m != nil {
+		return m.Processes
+	}
+	return nil
+}
+
+// ProcessInfo describes a supervised process
+type ProcessInfo struct {
+	Name                 string   `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Command              []string `protobuf:"bytes,2,rep,name=command,proto3" json:"command,omitempty"`
+	RestartPolicy        string   `protobuf:"bytes,3,opt,name=restart_policy,json=restartPolicy,proto3" json:"restart_policy,omitempty"`
+	State                string   `protobuf:"bytes,4,opt,name=state,proto3" json:"state,omitempty"`
+	Pid                  int32    `protobuf:"varint,5,opt,name=pid,proto3" json:"pid,omitempty"`
+	ExitCode             int32    `protobuf:"varint,6,opt,name=exit_code,json=exitCode,proto3" json:"exit_code,omitempty"`
+	Restarts             int32    `protobuf:"varint,7,opt,name=restarts,proto3" json:"restarts,omitempty"`
+	StartedAt            int64    `protobuf:"varint,8,opt,name=started_at,json=startedAt,proto3" json:"started_at,omitempty"`
+	LogPath              string   `protobuf:"bytes,9,opt,name=log_path,json=logPath,proto3" json:"log_path,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ProcessInfo) Reset()         { *m = ProcessInfo{} }
+func (m *ProcessInfo) String() string { return proto.CompactTextString(m) }
+func (*ProcessInfo) ProtoMessage()    {}
+func (*ProcessInfo) Descriptor() ([]byte, []int) {
+	return fileDescriptor_44c1cba55f3bcb29, []int{27}
+}
+
+func (m *ProcessInfo) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ProcessInfo.Unmarshal(m, b)
+}
+func (m *ProcessInfo) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ProcessInfo.Marshal(b, m, deterministic)
+}
+func (m *ProcessInfo) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ProcessInfo.Merge(m, src)
+}
+func (m *ProcessInfo) XXX_Size() int {
+	return xxx_messageInfo_ProcessInfo.Size(m)
+}
+func (m *ProcessInfo) XXX_DiscardUnknown() {
+	xxx_messageInfo_ProcessInfo.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ProcessInfo proto.InternalMessageInfo
+
+func (m *ProcessInfo) GetName() string {
+	if m != nil {
+		return m.Name
+	}
+	return ""
+}
+
+func (m *ProcessInfo) GetCommand() []string {
+	if m != nil {
+		return m.Command
+	}
+	return nil
+}
+
+func (m *ProcessInfo) GetRestartPolicy() string {
+	if m != nil {
+		return m.RestartPolicy
+	}
+	return ""
+}
+
+func (m *ProcessInfo) GetState() string {
+	if m != nil {
+		return m.State
+	}
+	return ""
+}
+
+func (m *ProcessInfo) GetPid() int32 {
+	if m != nil {
+		return m.Pid
+	}
+	return 0
+}
+
+func (m *ProcessInfo) GetExitCode() int32 {
+	if m != nil {
+		return m.ExitCode
+	}
+	return 0
+}
+
+func (m *ProcessInfo) GetRestarts() int32 {
+	if m != nil {
+		return m.Restarts
+	}
+	return 0
+}
+
+func (m *ProcessInfo) GetStartedAt() int64 {
+	if m != nil {
+		return m.StartedAt
+	}
+	return 0
+}
+
+func (m *ProcessInfo) GetLogPath() string {
+	if m != nil {
+		return m.LogPath
+	}
+	return ""
+}
+
+// TailFileRequest requests the end of a file in the guest
+type TailFileRequest struct {
+	Path                 string   `protobuf:"bytes,1,opt,name=path,proto3" json:"path,omitempty"`
+	Lines                int32    `protobuf:"varint,2,opt,name=lines,proto3" json:"lines,omitempty"`
+	Follow               bool     `protobuf:"varint,3,opt,name=follow,proto3" json:"follow,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *TailFileRequest) Reset()         { *m = TailFileRequest{} }
+func (m *TailFileRequest) String() string { return proto.CompactTextString(m) }
+func (*TailFileRequest) ProtoMessage()    {}
+func (*TailFileRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_44c1cba55f3bcb29, []int{28}
+}
+
+func (m *TailFileRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_TailFileRequest.Unmarshal(m, b)
+}
+func (m *TailFileRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_TailFileRequest.Marshal(b, m, deterministic)
+}
+func (m *TailFileRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_TailFileRequest.Merge(m, src)
+}
+func (m *TailFileRequest) XXX_Size() int {
+	return xxx_messageInfo_TailFileRequest.Size(m)
+}
+func (m *TailFileRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_TailFileRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_TailFileRequest proto.InternalMessageInfo
+
+func (m *TailFileRequest) GetPath() string {
+	if m != nil {
+		return m.Path
+	}
+	return ""
+}
+
+func (m *TailFileRequest) GetLines() int32 {
+	if m != nil {
+		return m.Lines
+	}
+	return 0
+}
+
+func (m *TailFileRequest) GetFollow() bool {
+	if m != nil {
+		return m.Follow
+	}
+	return false
+}
+
+// TailFileResponse carries a batch of lines. The first response is sent once the
+// file is open and holds the initial tail (possibly empty).
+type TailFileResponse struct {
+	Lines                []string `protobuf:"bytes,1,rep,name=lines,proto3" json:"lines,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *TailFileResponse) Reset()         { *m = TailFileResponse{} }
+func (m *TailFileResponse) String() string { return proto.CompactTextString(m) }
+func (*TailFileResponse) ProtoMessage()    {}
+func (*TailFileResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_44c1cba55f3bcb29, []int{29}
+}
+
+func (m *TailFileResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_TailFileResponse.Unmarshal(m, b)
+}
+func (m *TailFileResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_TailFileResponse.Marshal(b, m, deterministic)
+}
+func (m *TailFileResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_TailFileResponse.Merge(m, src)
+}
+func (m *TailFileResponse) XXX_Size() int {
+	return xxx_messageInfo_TailFileResponse.Size(m)
+}
+func (m *TailFileResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_TailFileResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_TailFileResponse proto.InternalMessageInfo
+
+func (m *TailFileResponse) GetLines() []string {
+	if m != nil {
+		return m.Lines
+	}
+	return nil
+}
+
+// CopyArchiveToGuestRequest carries a tar stream to extract. The first message
+// sets the destination and filters; every message may carry archive data.
+type CopyArchiveToGuestRequest struct {
+	Path                 string   `protobuf:"bytes,1,opt,name=path,proto3" json:"path,omitempty"`
+	Include              []string `protobuf:"bytes,2,rep,name=include,proto3" json:"include,omitempty"`
+	Exclude              []string `protobuf:"bytes,3,rep,name=exclude,proto3" json:"exclude,omitempty"`
+	Data                 []byte   `protobuf:"bytes,4,opt,name=data,proto3" json:"data,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *CopyArchiveToGuestRequest) Reset()         { *m = CopyArchiveToGuestRequest{} }
+func (m *CopyArchiveToGuestRequest) String() string { return proto.CompactTextString(m) }
+func (*CopyArchiveToGuestRequest) ProtoMessage()    {}
+func (*CopyArchiveToGuestRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_44c1cba55f3bcb29, []int{30}
+}
+
+func (m *CopyArchiveToGuestRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_CopyArchiveToGuestRequest.Unmarshal(m, b)
+}
+func (m *CopyArchiveToGuestRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_CopyArchiveToGuestRequest.Marshal(b, m, deterministic)
+}
+func (m *CopyArchiveToGuestRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_CopyArchiveToGuestRequest.Merge(m, src)
+}
+func (m *CopyArchiveToGuestRequest) XXX_Size() int {
+	return xxx_messageInfo_CopyArchiveToGuestRequest.Size(m)
+}
+func (m *CopyArchiveToGuestRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_CopyArchiveToGuestRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_CopyArchiveToGuestRequest proto.InternalMessageInfo
+
+func (m *CopyArchiveToGuestRequest) GetPath() string {
+	if m != nil {
+		return m.Path
+	}
+	return ""
+}
+
+func (m *CopyArchiveToGuestRequest) GetInclude() []string {
+	if m != nil {
+		return m.Include
+	}
+	return nil
+}
+
+func (m *CopyArchiveToGuestRequest) GetExclude() []string {
+	if m != nil {
+		return m.Exclude
+	}
+	return nil
+}
+
+func (m *CopyArchiveToGuestRequest) GetData() []byte {
+	if m != nil {
+		return m.Data
+	}
+	return nil
+}
+
+// CopyArchiveFromGuestRequest requests a guest path as a tar archive
+type CopyArchiveFromGuestRequest struct {
+	Path                 string   `protobuf:"bytes,1,opt,name=path,proto3" json:"path,omitempty"`
+	Include              []string `protobuf:"bytes,2,rep,name=include,proto3" json:"include,omitempty"`
+	Exclude              []string `protobuf:"bytes,3,rep,name=exclude,proto3" json:"exclude,omitempty"`
+	FollowLinks          bool     `protobuf:"varint,4,opt,name=follow_links,json=followLinks,proto3" json:"follow_links,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *CopyArchiveFromGuestRequest) Reset()         { *m = CopyArchiveFromGuestRequest{} }
+func (m *CopyArchiveFromGuestRequest) String() string { return proto.CompactTextString(m) }
+func (*CopyArchiveFromGuestRequest) ProtoMessage()    {}
+func (*CopyArchiveFromGuestRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_44c1cba55f3bcb29, []int{31}
+}
+
+func (m *CopyArchiveFromGuestRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_CopyArchiveFromGuestRequest.Unmarshal(m, b)
+}
+func (m *CopyArchiveFromGuestRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_CopyArchiveFromGuestRequest.Marshal(b, m, deterministic)
+}
+func (m *CopyArchiveFromGuestRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_CopyArchiveFromGuestRequest.Merge(m, src)
+}
+func (m *CopyArchiveFromGuestRequest) XXX_Size() int {
+	return xxx_messageInfo_CopyArchiveFromGuestRequest.Size(m)
+}
+func (m *CopyArchiveFromGuestRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_CopyArchiveFromGuestRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_CopyArchiveFromGuestRequest proto.InternalMessageInfo
+
+func (m *CopyArchiveFromGuestRequest) GetPath() string {
+	if m != nil {
+		return m.Path
+	}
+	return ""
+}
+
+func (m *CopyArchiveFromGuestRequest) GetInclude() []string {
+	if m != nil {
+		return m.Include
+	}
+	return nil
+}
+
+func (m *CopyArchiveFromGuestRequest) GetExclude() []string {
+	if m != nil {
+		return m.Exclude
+	}
+	return nil
+}
+
+func (m *CopyArchiveFromGuestRequest) GetFollowLinks() bool {
+	if m != nil {
+		return m.FollowLinks
+	}
+	return false
+}
+
+// CopyArchiveFromGuestResponse carries a chunk of the tar stream and/or progress
+type CopyArchiveFromGuestResponse struct {
+	Data                 []byte               `protobuf:"bytes,1,opt,name=data,proto3" json:"data,omitempty"`
+	Progress             *CopyArchiveProgress `protobuf:"bytes,2,opt,name=progress,proto3" json:"progress,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}             `json:"-"`
+	XXX_unrecognized     []byte               `json:"-"`
+	XXX_sizecache        int32                `json:"-"`
+}
+
+func (m *CopyArchiveFromGuestResponse) Reset()         { *m = CopyArchiveFromGuestResponse{} }
+func (m *CopyArchiveFromGuestResponse) String() string { return proto.CompactTextString(m) }
+func (*CopyArchiveFromGuestResponse) ProtoMessage()    {}
+func (*CopyArchiveFromGuestResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_44c1cba55f3bcb29, []int{32}
+}
+
+func (m *CopyArchiveFromGuestResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_CopyArchiveFromGuestResponse.Unmarshal(m, b)
+}
+func (m *CopyArchiveFromGuestResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_CopyArchiveFromGuestResponse.Marshal(b, m, deterministic)
+}
+func (m *CopyArchiveFromGuestResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_CopyArchiveFromGuestResponse.Merge(m, src)
+}
+func (m *CopyArchiveFromGuestResponse) XXX_Size() int {
+	return xxx_messageInfo_CopyArchiveFromGuestResponse.Size(m)
+}
+func (m *CopyArchiveFromGuestResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_CopyArchiveFromGuestResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_CopyArchiveFromGuestResponse proto.InternalMessageInfo
+
+func (m *CopyArchiveFromGuestResponse) GetData() []byte {
+	if m != nil {
+		return m.Data
+	}
+	return nil
+}
+
+func (m *CopyArchiveFromGuestResponse) GetProgress() *CopyArchiveProgress {
+	if m != nil {
+		return m.Progress
+	}
+	return nil
+}
+
+// CopyArchiveProgress reports aggregate progress of an archive copy
+type CopyArchiveProgress struct {
+	Files                int64    `protobuf:"varint,1,opt,name=files,proto3" json:"files,omitempty"`
+	Bytes                int64    `protobuf:"varint,2,opt,name=bytes,proto3" json:"bytes,omitempty"`
+	Done                 bool     `protobuf:"varint,3,opt,name=done,proto3" json:"done,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *CopyArchiveProgress) Reset()         { *m = CopyArchiveProgress{} }
+func (m *CopyArchiveProgress) String() string { return proto.CompactTextString(m) }
+func (*CopyArchiveProgress) ProtoMessage()    {}
+func (*CopyArchiveProgress) Descriptor() ([]byte, []int) {
+	return fileDescriptor_44c1cba55f3bcb29, []int{33}
+}
+
+func (m *CopyArchiveProgress) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_CopyArchiveProgress.Unmarshal(m, b)
+}
+func (m *CopyArchiveProgress) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_CopyArchiveProgress.Marshal(b, m, deterministic)
+}
+func (m *CopyArchiveProgress) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_CopyArchiveProgress.Merge(m, src)
+}
+func (m *CopyArchiveProgress) XXX_Size() int {
+	return xxx_messageInfo_CopyArchiveProgress.Size(m)
+}
+func (m *CopyArchiveProgress) XXX_DiscardUnknown() {
+	xxx_messageInfo_CopyArchiveProgress.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_CopyArchiveProgress proto.InternalMessageInfo
+
+func (m *CopyArchiveProgress) GetFiles() int64 {
+	if m != nil {
+		return m.Files
+	}
+	return 0
+}
+
+func (m *CopyArchiveProgress) GetBytes() int64 {
+	if m != nil {
+		return m.Bytes
+	}
+	return 0
+}
+
+func (m *CopyArchiveProgress) GetDone() bool {
+	if m != nil {
+		return m.Done
+	}
+	return false
+}
+
+// PutSecretsRequest delivers an instance's secrets
+type PutSecretsRequest struct {
+	Files                []*SecretFile     `protobuf:"bytes,1,rep,name=files,proto3" json:"files,omitempty"`
+	Env                  map[string]string `protobuf:"bytes,2,rep,name=env,proto3" json:"env,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+	XXX_NoUnkeyedLiteral struct{}          `json:"-"`
+	XXX_unrecognized     []byte            `json:"-"`
+	XXX_sizecache        int32             `json:"-"`
+}
+
+func (m *PutSecretsRequest) Reset()         { *m = PutSecretsRequest{} }
+func (m *PutSecretsRequest) String() string { return proto.CompactTextString(m) }
+func (*PutSecretsRequest) ProtoMessage()    {}
+func (*PutSecretsRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_44c1cba55f3bcb29, []int{34}
+}
+
+func (m *PutSecretsRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_PutSecretsRequest.Unmarshal(m, b)
+}
+func (m *PutSecretsRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_PutSecretsRequest.Marshal(b, m, deterministic)
+}
+func (m *PutSecretsRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_PutSecretsRequest.Merge(m, src)
+}
+func (m *PutSecretsRequest) XXX_Size() int {
+	return xxx_messageInfo_PutSecretsRequest.Size(m)
+}
+func (m *PutSecretsRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_PutSecretsRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_PutSecretsRequest proto.InternalMessageInfo
+
+func (m *PutSecretsRequest) GetFiles() []*SecretFile {
+	if m != nil {
+		return m.Files
+	}
+	return nil
+}
+
+func (m *PutSecretsRequest) GetEnv() map[string]string {
+	if m != nil {
+		return m.Env
+	}
+	return nil
+}
+
+// SecretFile is a secret exposed as a file
+type SecretFile struct {
+	Name                 string   `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Value                []byte   `protobuf:"bytes,2,opt,name=value,proto3" json:"value,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *SecretFile) Reset()         { *m = SecretFile{} }
+func (m *SecretFile) String() string { return proto.CompactTextString(m) }
+func (*SecretFile) ProtoMessage()    {}
+func (*SecretFile) Descriptor() ([]byte, []int) {
+	return fileDescriptor_44c1cba55f3bcb29, []int{35}
+}
+
+func (m *SecretFile) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_SecretFile.Unmarshal(m, b)
+}
+func (m *SecretFile) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_SecretFile.Marshal(b, m, deterministic)
+}
+func (m *SecretFile) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_SecretFile.Merge(m, src)
+}
+func (m *SecretFile) XXX_Size() int {
+	return xxx_messageInfo_SecretFile.Size(m)
+}
+func (m *SecretFile) XXX_DiscardUnknown() {
+	xxx_messageInfo_SecretFile.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_SecretFile proto.InternalMessageInfo
+
+func (m *SecretFile) GetName() string {
+	if m != nil {
+		return m.Name
+	}
+	return ""
+}
+
+func (m *SecretFile) GetValue() []byte {
+	if m != nil {
+		return m.Value
+	}
+	return nil
+}
+
+// PutSecretsResponse acknowledges delivered secrets
+type PutSecretsResponse struct {
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *PutSecretsResponse) Reset()         { *m = PutSecretsResponse{} }
+func (m *PutSecretsResponse) String() string { return proto.CompactTextString(m) }
+func (*PutSecretsResponse) ProtoMessage()    {}
+func (*PutSecretsResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_44c1cba55f3bcb29, []int{36}
+}
+
+func (m *PutSecretsResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_PutSecretsResponse.Unmarshal(m, b)
+}
+func (m *PutSecretsResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_PutSecretsResponse.Marshal(b, m, deterministic)
+}
+func (m *PutSecretsResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_PutSecretsResponse.Merge(m, src)
+}
+func (m *PutSecretsResponse) XXX_Size() int {
+	return xxx_messageInfo_PutSecretsResponse.Size(m)
+}
+func (m *PutSecretsResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_PutSecretsResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_PutSecretsResponse proto.InternalMessageInfo
+
+// ListGuestProcessesRequest requests the guest's process table
+type ListGuestProcessesRequest struct {
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ListGuestProcessesRequest) Reset()         { *m = ListGuestProcessesRequest{} }
+func (m *ListGuestProcessesRequest) String() string { return proto.CompactTextString(m) }
+func (*ListGuestProcessesRequest) ProtoMessage()    {}
+func (*ListGuestProcessesRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_44c1cba55f3bcb29, []int{37}
+}
+
+func (m *ListGuestProcessesRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ListGuestProcessesRequest.Unmarshal(m, b)
+}
+func (m *ListGuestProcessesRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ListGuestProcessesRequest.Marshal(b, m, deterministic)
+}
+func (m *ListGuestProcessesRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ListGuestProcessesRequest.Merge(m, src)
+}
+func (m *ListGuestProcessesRequest) XXX_Size() int {
+	return xxx_messageInfo_ListGuestProcessesRequest.Size(m)
+}
+func (m *ListGuestProcessesRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_ListGuestProcessesRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ListGuestProcessesRequest proto.InternalMessageInfo
+
+// ListGuestProcessesResponse contains the guest's process table
+type ListGuestProcessesResponse struct {
+	Processes            []*GuestProcess `protobuf:"bytes,1,rep,name=processes,proto3" json:"processes,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}        `json:"-"`
+	XXX_unrecognized     []byte          `json:"-"`
+	XXX_sizecache        int32           `json:"-"`
+}
+
+func (m *ListGuestProcessesResponse) Reset()         { *m = ListGuestProcessesResponse{} }
+func (m *ListGuestProcessesResponse) String() string { return proto.CompactTextString(m) }
+func (*ListGuestProcessesResponse) ProtoMessage()    {}
+func (*ListGuestProcessesResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_44c1cba55f3bcb29, []int{38}
+}
+
+func (m *ListGuestProcessesResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ListGuestProcessesResponse.Unmarshal(m, b)
+}
+func (m *ListGuestProcessesResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ListGuestProcessesResponse.Marshal(b, m, deterministic)
+}
+func (m *ListGuestProcessesResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ListGuestProcessesResponse.Merge(m, src)
+}
+func (m *ListGuestProcessesResponse) XXX_Size() int {
+	return xxx_messageInfo_ListGuestProcessesResponse.Size(m)
+}
+func (m *ListGuestProcessesResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_ListGuestProcessesResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ListGuestProcessesResponse proto.InternalMessageInfo
+
+func (m *ListGuestProcessesResponse) GetProcesses() []*GuestProcess {
+	if m != nil {
+		return m.Processes
+	}
+	return nil
+}
+
+// GuestProcess describes a process running in the guest
+type GuestProcess struct {
+	Pid                  int32    `protobuf:"varint,1,opt,name=pid,proto3" json:"pid,omitempty"`
+	Ppid                 int32    `protobuf:"varint,2,opt,name=ppid,proto3" json:"ppid,omitempty"`
+	Command              string   `protobuf:"bytes,3,opt,name=command,proto3" json:"command,omitempty"`
+	CpuPercent           float64  `protobuf:"fixed64,4,opt,name=cpu_percent,json=cpuPercent,proto3" json:"cpu_percent,omitempty"`
+	RssBytes             int64    `protobuf:"varint,5,opt,name=rss_bytes,json=rssBytes,proto3" json:"rss_bytes,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *GuestProcess) Reset()         { *m = GuestProcess{} }
+func (m *GuestProcess) String() string { return proto.CompactTextString(m) }
+func (*GuestProcess) ProtoMessage()    {}
+func (*GuestProcess) Descriptor() ([]byte, []int) {
+	return fileDescriptor_44c1cba55f3bcb29, []int{39}
+}
+
+func (m *GuestProcess) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_GuestProcess.Unmarshal(m, b)
+}
+func (m *GuestProcess) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_GuestProcess.Marshal(b, m, deterministic)
+}
+func (m *GuestProcess) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_GuestProcess.Merge(m, src)
+}
+func (m *GuestProcess) XXX_Size() int {
+	return xxx_messageInfo_GuestProcess.Size(m)
+}
+func (m *GuestProcess) XXX_DiscardUnknown() {
+	xxx_messageInfo_GuestProcess.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_GuestProcess proto.InternalMessageInfo
+
+func (m *GuestProcess) GetPid() int32 {
+	if m != nil {
+		return m.Pid
+	}
+	return 0
+}
+
+func (m *GuestProcess) GetPpid() int32 {
+	if m != nil {
+		return m.Ppid
+	}
+	return 0
+}
+
+func (m *GuestProcess) GetCommand() string {
+	if m != nil {
+		return m.Command
+	}
+	return ""
+}
+
+func (m *GuestProcess) GetCpuPercent() float64 {
+	if m != nil {
+		return m.CpuPercent
+	}
+	return 0
+}
+
+func (m *GuestProcess) GetRssBytes() int64 {
+	if m != nil {
+		return m.RssBytes
+	}
+	return 0
+}
+
+// GetAppStatusRequest requests the application's exit status
+type GetAppStatusRequest struct {
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *GetAppStatusRequest) Reset()         { *m = GetAppStatusRequest{} }
+func (m *GetAppStatusRequest) String() string { return proto.CompactTextString(m) }
+func (*GetAppStatusRequest) ProtoMessage()    {}
+func (*GetAppStatusRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_44c1cba55f3bcb29, []int{40}
+}
+
+func (m *GetAppStatusRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_GetAppStatusRequest.Unmarshal(m, b)
+}
+func (m *GetAppStatusRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_GetAppStatusRequest.Marshal(b, m, deterministic)
+}
+func (m *GetAppStatusRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_GetAppStatusRequest.Merge(m, src)
+}
+func (m *GetAppStatusRequest) XXX_Size() int {
+	return xxx_messageInfo_GetAppStatusRequest.Size(m)
+}
+func (m *GetAppStatusRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_GetAppStatusRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_GetAppStatusRequest proto.InternalMessageInfo
+
+// GetAppStatusResponse describes how the application exited. Only exec mode
+// reports exits; in systemd mode exited is always false.
+type GetAppStatusResponse struct {
+	Exited               bool     `protobuf:"varint,1,opt,name=exited,proto3" json:"exited,omitempty"`
+	ExitCode             int32    `protobuf:"varint,2,opt,name=exit_code,json=exitCode,proto3" json:"exit_code,omitempty"`
+	Signal               string   `protobuf:"bytes,3,opt,name=signal,proto3" json:"signal,omitempty"`
+	ExitedAt             int64    `protobuf:"varint,4,opt,name=exited_at,json=exitedAt,proto3" json:"exited_at,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *GetAppStatusResponse) Reset()         { *m = GetAppStatusResponse{} }
+func (m *GetAppStatusResponse) String() string { return proto.CompactTextString(m) }
+func (*GetAppStatusResponse) ProtoMessage()    {}
+func (*GetAppStatusResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_44c1cba55f3bcb29, []int{41}
+}
+
+func (m *GetAppStatusResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_GetAppStatusResponse.Unmarshal(m, b)
+}
+func (m *GetAppStatusResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_GetAppStatusResponse.Marshal(b, m, deterministic)
+}
+func (m *GetAppStatusResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_GetAppStatusResponse.Merge(m, src)
+}
+func (m *GetAppStatusResponse) XXX_Size() int {
+	return xxx_messageInfo_GetAppStatusResponse.Size(m)
+}
+func (m *GetAppStatusResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_GetAppStatusResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_GetAppStatusResponse proto.InternalMessageInfo
+
+func (m *GetAppStatusResponse) GetExited() bool {
+	if m != nil {
+		return m.Exited
+	}
+	return false
+}
+
+func (m *GetAppStatusResponse) GetExitCode() int32 {
+	if m != nil {
+		return m.ExitCode
+	}
+	return 0
+}
+
+func (m *GetAppStatusResponse) GetSignal() string {
+	if m != nil {
+		return m.Signal
+	}
+	return ""
+}
+
+func (m *GetAppStatusResponse) GetExitedAt() int64 {
+	if m != nil {
+		return m.ExitedAt
+	}
+	return 0
+}
+
+// StopAppRequest asks the agent to stop the application. Only exec mode
+// supervises the application; in systemd mode the call fails with
+// FailedPrecondition.
+type StopAppRequest struct {
+	TimeoutSeconds       int32    `protobuf:"varint,1,opt,name=timeout_seconds,json=timeoutSeconds,proto3" json:"timeout_seconds,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *StopAppRequest) Reset()         { *m = StopAppRequest{} }
+func (m *StopAppRequest) String() string { return proto.CompactTextString(m) }
+func (*StopAppRequest) ProtoMessage()    {}
+func (*StopAppRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_44c1cba55f3bcb29, []int{42}
+}
+
+func (m *StopAppRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_StopAppRequest.Unmarshal(m, b)
+}
+func (m *StopAppRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_StopAppRequest.Marshal(b, m, deterministic)
+}
+func (m *StopAppRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_StopAppRequest.Merge(m, src)
+}
+func (m *StopAppRequest) XXX_Size() int {
+	return xxx_messageInfo_StopAppRequest.Size(m)
+}
+func (m *StopAppRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_StopAppRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_StopAppRequest proto.InternalMessageInfo
+
+func (m *StopAppRequest) GetTimeoutSeconds() int32 {
+	if m != nil {
+		return m.TimeoutSeconds
+	}
+	return 0
+}
+
+// StopAppResponse reports whether the application exited in time. Filesystems
+// are synced either way.
+type StopAppResponse struct {
+	Exited               bool     `protobuf:"varint,1,opt,name=exited,proto3" json:"exited,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *StopAppResponse) Reset()         { *m = StopAppResponse{} }
+func (m *StopAppResponse) String() string { return proto.CompactTextString(m) }
+func (*StopAppResponse) ProtoMessage()    {}
+func (*StopAppResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_44c1cba55f3bcb29, []int{43}
+}
+
+func (m *StopAppResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_StopAppResponse.Unmarshal(m, b)
+}
+func (m *StopAppResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_StopAppResponse.Marshal(b, m, deterministic)
+}
+func (m *StopAppResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_StopAppResponse.Merge(m, src)
+}
+func (m *StopAppResponse) XXX_Size() int {
+	return xxx_messageInfo_StopAppResponse.Size(m)
+}
+func (m *StopAppResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_StopAppResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_StopAppResponse proto.InternalMessageInfo
+
+func (m *StopAppResponse) GetExited() bool {
+	if m != nil {
+		return m.Exited
+	}
+	return false
+}
+
+// SyncClockRequest carries the host's time when the request was sent
+type SyncClockRequest struct {
+	HostTimeUnixNano     int64    `protobuf:"varint,1,opt,name=host_time_unix_nano,json=hostTimeUnixNano,proto3" json:"host_time_unix_nano,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *SyncClockRequest) Reset()         { *m = SyncClockRequest{} }
+func (m *SyncClockRequest) String() string { return proto.CompactTextString(m) }
+func (*SyncClockRequest) ProtoMessage()    {}
+func (*SyncClockRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_44c1cba55f3bcb29, []int{44}
+}
+
+func (m *SyncClockRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_SyncClockRequest.Unmarshal(m, b)
+}
+func (m *SyncClockRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_SyncClockRequest.Marshal(b, m, deterministic)
+}
+func (m *SyncClockRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_SyncClockRequest.Merge(m, src)
+}
+func (m *SyncClockRequest) XXX_Size() int {
+	return xxx_messageInfo_SyncClockRequest.Size(m)
+}
+func (m *SyncClockRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_SyncClockRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_SyncClockRequest proto.InternalMessageInfo
+
+func (m *SyncClockRequest) GetHostTimeUnixNano() int64 {
+	if m != nil {
+		return m.HostTimeUnixNano
+	}
+	return 0
+}
+
+// SyncClockResponse reports the guest's clock skew before it was set
+type SyncClockResponse struct {
+	SkewNanos            int64    `protobuf:"varint,1,opt,name=skew_nanos,json=skewNanos,proto3" json:"skew_nanos,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *SyncClockResponse) Reset()         { *m = SyncClockResponse{} }
+func (m *SyncClockResponse) String() string { return proto.CompactTextString(m) }
+func (*SyncClockResponse) ProtoMessage()    {}
+func (*SyncClockResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_44c1cba55f3bcb29, []int{45}
+}
+
+func (m *SyncClockResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_SyncClockResponse.Unmarshal(m, b)
+}
+func (m *SyncClockResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_SyncClockResponse.Marshal(b, m, deterministic)
+}
+func (m *SyncClockResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_SyncClockResponse.Merge(m, src)
+}
+func (m *SyncClockResponse) XXX_Size() int {
+	return xxx_messageInfo_SyncClockResponse.Size(m)
+}
+func (m *SyncClockResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_SyncClockResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_SyncClockResponse proto.InternalMessageInfo
+
+func (m *SyncClockResponse) GetSkewNanos() int64 {
+	if m != nil {
+		return m.SkewNanos
+	}
+	return 0
+}
+
+// WindowSize is a terminal's size in characters
+type WindowSize struct {
+	Rows                 uint32   `protobuf:"varint,1,opt,name=rows,proto3" json:"rows,omitempty"`
+	Cols                 uint32   `protobuf:"varint,2,opt,name=cols,proto3" json:"cols,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *WindowSize) Reset()         { *m = WindowSize{} }
+func (m *WindowSize) String() string { return proto.CompactTextString(m) }
+func (*WindowSize) ProtoMessage()    {}
+func (*WindowSize) Descriptor() ([]byte, []int) {
+	return fileDescriptor_44c1cba55f3bcb29, []int{46}
+}
+
+func (m *WindowSize) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_WindowSize.Unmarshal(m, b)
+}
+func (m *WindowSize) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_WindowSize.Marshal(b, m, deterministic)
+}
+func (m *WindowSize) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_WindowSize.Merge(m, src)
+}
+func (m *WindowSize) XXX_Size() int {
+	return xxx_messageInfo_WindowSize.Size(m)
+}
+func (m *WindowSize) XXX_DiscardUnknown() {
+	xxx_messageInfo_WindowSize.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_WindowSize proto.InternalMessageInfo
+
+func (m *WindowSize) GetRows() uint32 {
+	if m != nil {
+		return m.Rows
+	}
+	return 0
+}
+
+func (m *WindowSize) GetCols() uint32 {
+	if m != nil {
+		return m.Cols
+	}
+	return 0
+}
+
+// UpdateLinksRequest lists the addresses of an instance's linked peers
+type UpdateLinksRequest struct {
+	Links                []*LinkEntry `protobuf:"bytes,1,rep,name=links,proto3" json:"links,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}     `json:"-"`
+	XXX_unrecognized     []byte       `json:"-"`
+	XXX_sizecache        int32        `json:"-"`
+}
+
+func (m *UpdateLinksRequest) Reset()         { *m = UpdateLinksRequest{} }
+func (m *UpdateLinksRequest) String() string { return proto.CompactTextString(m) }
+func (*UpdateLinksRequest) ProtoMessage()    {}
+func (*UpdateLinksRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_44c1cba55f3bcb29, []int{47}
+}
+
+func (m *UpdateLinksRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_UpdateLinksRequest.Unmarshal(m, b)
+}
+func (m *UpdateLinksRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_UpdateLinksRequest.Marshal(b, m, deterministic)
+}
+func (m *UpdateLinksRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_UpdateLinksRequest.Merge(m, src)
+}
+func (m *UpdateLinksRequest) XXX_Size() int {
+	return xxx_messageInfo_UpdateLinksRequest.Size(m)
+}
+func (m *UpdateLinksRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_UpdateLinksRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_UpdateLinksRequest proto.InternalMessageInfo
+
+func (m *UpdateLinksRequest) GetLinks() []*LinkEntry {
+	if m != nil {
+		return m.Links
+	}
+	return nil
+}
+
+// UpdateLinksResponse acknowledges an UpdateLinksRequest
+type UpdateLinksResponse struct {
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *UpdateLinksResponse) Reset()         { *m = UpdateLinksResponse{} }
+func (m *UpdateLinksResponse) String() string { return proto.CompactTextString(m) }
+func (*UpdateLinksResponse) ProtoMessage()    {}
+func (*UpdateLinksResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_44c1cba55f3bcb29, []int{48}
+}
+
+func (m *UpdateLinksResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_UpdateLinksResponse.Unmarshal(m, b)
+}
+func (m *UpdateLinksResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_UpdateLinksResponse.Marshal(b, m, deterministic)
+}
+func (m *UpdateLinksResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_UpdateLinksResponse.Merge(m, src)
+}
+func (m *UpdateLinksResponse) XXX_Size() int {
+	return xxx_messageInfo_UpdateLinksResponse.Size(m)
+}
+func (m *UpdateLinksResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_UpdateLinksResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_UpdateLinksResponse proto.InternalMessageInfo
+
+// LinkEntry maps a linked peer's name to its address
+type LinkEntry struct {
+	Hostname             string   `protobuf:"bytes,1,opt,name=hostname,proto3" json:"hostname,omitempty"`
+	Ip                   string   `protobuf:"bytes,2,opt,name=ip,proto3" json:"ip,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *LinkEntry) Reset()         { *m = LinkEntry{} }
+func (m *LinkEntry) String() string { return proto.CompactTextString(m) }
+func (*LinkEntry) ProtoMessage()    {}
+func (*LinkEntry) Descriptor() ([]byte, []int) {
+	return fileDescriptor_44c1cba55f3bcb29, []int{49}
+}
+
+func (m *LinkEntry) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_LinkEntry.Unmarshal(m, b)
+}
+func (m *LinkEntry) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_LinkEntry.Marshal(b, m, deterministic)
+}
+func (m *LinkEntry) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_LinkEntry.Merge(m, src)
+}
+func (m *LinkEntry) XXX_Size() int {
+	return xxx_messageInfo_LinkEntry.Size(m)
+}
+func (m *LinkEntry) XXX_DiscardUnknown() {
+	xxx_messageInfo_LinkEntry.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_LinkEntry proto.InternalMessageInfo
+
+func (m *LinkEntry) GetHostname() string {
+	if m != nil {
+		return m.Hostname
+	}
+	return ""
+}
+
+func (m *LinkEntry) GetIp() string {
+	if m != nil {
+		return m.Ip
+	}
+	return ""
+}
+
 func init() {
 	proto.RegisterType((*ExecRequest)(nil), "guest.ExecRequest")
 	proto.RegisterType((*ExecStart)(nil), "guest.ExecStart")
@@ -1056,6 +2878,44 @@ func init() {
 	proto.RegisterType((*CopyFromGuestError)(nil), "guest.CopyFromGuestError")
 	proto.RegisterType((*StatPathRequest)(nil), "guest.StatPathRequest")
 	proto.RegisterType((*StatPathResponse)(nil), "guest.StatPathResponse")
+	proto.RegisterType((*UpdateAgentRequest)(nil), "guest.UpdateAgentRequest")
+	proto.RegisterType((*UpdateAgentStart)(nil), "guest.UpdateAgentStart")
+	proto.RegisterType((*UpdateAgentResponse)(nil), "guest.UpdateAgentResponse")
+	proto.RegisterType((*GetAgentInfoRequest)(nil), "guest.GetAgentInfoRequest")
+	proto.RegisterType((*GetAgentInfoResponse)(nil), "guest.GetAgentInfoResponse")
+	proto.RegisterType((*StartProcessRequest)(nil), "guest.StartProcessRequest")
+	proto.RegisterMapType((map[string]string)(nil), "guest.StartProcessRequest.EnvEntry")
+	proto.RegisterType((*StartProcessResponse)(nil), "guest.StartProcessResponse")
+	proto.RegisterType((*StopProcessRequest)(nil), "guest.StopProcessRequest")
+	proto.RegisterType((*StopProcessResponse)(nil), "guest.StopProcessResponse")
+	proto.RegisterType((*RestartProcessRequest)(nil), "guest.RestartProcessRequest")
+	proto.RegisterType((*RestartProcessResponse)(nil), "guest.RestartProcessResponse")
+	proto.RegisterType((*ListProcessesRequest)(nil), "guest.ListProcessesRequest")
+	proto.RegisterType((*ListProcessesResponse)(nil), "guest.ListProcessesResponse")
+	proto.RegisterType((*ProcessInfo)(nil), "guest.ProcessInfo")
+	proto.RegisterType((*TailFileRequest)(nil), "guest.TailFileRequest")
+	proto.RegisterType((*TailFileResponse)(nil), "guest.TailFileResponse")
+	proto.RegisterType((*CopyArchiveToGuestRequest)(nil), "guest.CopyArchiveToGuestRequest")
+	proto.RegisterType((*CopyArchiveFromGuestRequest)(nil), "guest.CopyArchiveFromGuestRequest")
+	proto.RegisterType((*CopyArchiveFromGuestResponse)(nil), "guest.CopyArchiveFromGuestResponse")
+	proto.RegisterType((*CopyArchiveProgress)(nil), "guest.CopyArchiveProgress")
+	proto.RegisterType((*PutSecretsRequest)(nil), "guest.PutSecretsRequest")
+	proto.RegisterMapType((map[string]string)(nil), "guest.PutSecretsRequest.EnvEntry")
+	proto.RegisterType((*SecretFile)(nil), "guest.SecretFile")
+	proto.RegisterType((*PutSecretsResponse)(nil), "guest.PutSecretsResponse")
+	proto.RegisterType((*ListGuestProcessesRequest)(nil), "guest.ListGuestProcessesRequest")
+	proto.RegisterType((*ListGuestProcessesResponse)(nil), "guest.ListGuestProcessesResponse")
+	proto.RegisterType((*GuestProcess)(nil), "guest.GuestProcess")
+	proto.RegisterType((*GetAppStatusRequest)(nil), "guest.GetAppStatusRequest")
+	proto.RegisterType((*GetAppStatusResponse)(nil), "guest.GetAppStatusResponse")
+	proto.RegisterType((*StopAppRequest)(nil), "guest.StopAppRequest")
+	proto.RegisterType((*StopAppResponse)(nil), "guest.StopAppResponse")
+	proto.RegisterType((*SyncClockRequest)(nil), "guest.SyncClockRequest")
+	proto.RegisterType((*SyncClockResponse)(nil), "guest.SyncClockResponse")
+	proto.RegisterType((*WindowSize)(nil), "guest.WindowSize")
+	proto.RegisterType((*UpdateLinksRequest)(nil), "guest.UpdateLinksRequest")
+	proto.RegisterType((*UpdateLinksResponse)(nil), "guest.UpdateLinksResponse")
+	proto.RegisterType((*LinkEntry)(nil), "guest.LinkEntry")
 }
 
 func init() {
@@ -1063,62 +2923,134 @@ func init() {
 }
 
 var fileDescriptor_44c1cba55f3bcb29 = []byte{
-	// 897 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xac, 0x56, 0x5d, 0x6f, 0xe3, 0x44,
-	0x14, 0xad, 0xe3, 0xc4, 0xb1, 0x6f, 0xd2, 0xdd, 0x68, 0xb6, 0x1f, 0x6e, 0x60, 0x45, 0x30, 0x42,
-	0x6b, 0xb4, 0x52, 0xb3, 0x74, 0x11, 0x42, 0xf0, 0xd6, 0xa5, 0x25, 0x0f, 0x8b, 0x84, 0xa6, 0x2b,
-	0x21, 0xed, 0x4b, 0xe4, 0xda, 0xd3, 0x64, 0xa8, 0x3f, 0xc2, 0xcc, 0xa4, 0x6d, 0xf8, 0x17, 0xbc,
-	0xf0, 0xca, 0x4f, 0xe2, 0x11, 0x9e, 0xf9, 0x25, 0xe8, 0xce, 0xd8, 0xa9, 0x9d, 0x86, 0xa7, 0xee,
-	0x4b, 0x3b, 0xf7, 0xf8, 0xfa, 0xcc, 0xf5, 0x39, 0x67, 0x1c, 0xc3, 0x7e, 0xca, 0x2f, 0xc7, 0xb3,
-	0x25, 0x93, 0xca, 0xfc, 0x3d, 0x5e, 0x88, 0x42, 0x15, 0xa4, 0xa3, 0x8b, 0xe0, 0x3d, 0xf4, 0xce,
-	0xee, 0x58, 0x4c, 0xd9, 0xaf, 0x58, 0x92, 0x10, 0x3a, 0x52, 0x45, 0x42, 0xf9, 0xd6, 0xc8, 0x0a,
-	0x7b, 0x27, 0x83, 0x63, 0x73, 0x0b, 0xb6, 0x5c, 0x20, 0x3e, 0xd9, 0xa1, 0xa6, 0x81, 0x1c, 0x60,
-	0x67, 0xc2, 0x73, 0xbf, 0x35, 0xb2, 0xc2, 0xbe, 0xc1, 0x13, 0x9e, 0x9f, 0x7a, 0xd0, 0x15, 0x86,
-	0x2c, 0xf8, 0xdb, 0x02, 0x6f, 0x7d, 0x27, 0xf1, 0xa1, 0x1b, 0x17, 0x59, 0x16, 0xe5, 0x89, 0x6f,
-	0x8d, 0xec, 0xd0, 0xa3, 0x55, 0x49, 0x06, 0x60, 0x2b, 0xb5, 0xd2, 0x44, 0x2e, 0xc5, 0x25, 0x79,
-	0x09, 0x36, 0xcb, 0x6f, 0x7c, 0x7b, 0x64, 0x87, 0xbd, 0x93, 0xa3, 0xcd, 0x21, 0x8e, 0xcf, 0xf2,
-	0x9b, 0xb3, 0x5c, 0x89, 0x15, 0xc5, 0x2e, 0xbc, 0x3d, 0xbe, 0x4d, 0xfc, 0xf6, 0xc8, 0x0a, 0x3d,
-	0x8a, 0x4b, 0xf2, 0x02, 0x9e, 0x2a, 0x9e, 0xb1, 0x62, 0xa9, 0xa6, 0x92, 0xc5, 0x45, 0x9e, 0x48,
-	0xbf, 0x33, 0xb2, 0xc2, 0x0e, 0x7d, 0x52, 0xc2, 0x17, 0x06, 0x1d, 0x7e, 0x0d, 0x6e, 0xc5, 0x85,
-	0x34, 0xd7, 0x6c, 0xa5, 0x1f, 0xdc, 0xa3, 0xb8, 0x24, 0x7b, 0xd0, 0xb9, 0x89, 0xd2, 0x25, 0xd3,
-	0x93, 0x79, 0xd4, 0x14, 0xdf, 0xb6, 0xbe, 0xb1, 0x82, 0x0c, 0xfa, 0x46, 0x35, 0xb9, 0x28, 0x72,
-	0xc9, 0x88, 0x0f, 0x8e, 0x54, 0x49, 0xb1, 0x34, 0xba, 0xa1, 0x1a, 0x65, 0x5d, 0x5e, 0x61, 0x42,
-	0xac, 0x75, 0x2a, 0x6b, 0xf2, 0x1c, 0x3c, 0x76, 0xc7, 0xd5, 0x34, 0x2e, 0x12, 0xe6, 0xdb, 0x38,
-	0xde, 0x64, 0x87, 0xba, 0x08, 0xbd, 0x29, 0x12, 0x76, 0x0a, 0xe0, 0x8a, 0x92, 0x3e, 0xf8, 0xdd,
-	0x02, 0xf2, 0xa6, 0x58, 0xac, 0xde, 0x15, 0x3f, 0xa0, 0x12, 0x95, 0x59, 0xe3, 0xa6, 0x59, 0x87,
-	0xa5, 0x4e, 0xb5, 0xce, 0x0d, 0xcf, 0xf6, 0xa0, 0x9d, 0x44, 0x2a, 0x5a, 0x8f, 0xa2, 0x2b, 0xf2,
-	0x05, 0x8a, 0x9d, 0xe8, 0x11, 0x7a, 0x27, 0xfb, 0x0f, 0x49, 0xce, 0xf2, 0x64, 0xb2, 0x83, 0x52,
-	0x27, 0x75, 0x73, 0xff, 0xb4, 0x60, 0xb0, 0xb9, 0x13, 0x21, 0xd0, 0x5e, 0x44, 0x6a, 0x5e, 0x8a,
-	0xa8, 0xd7, 0x88, 0x65, 0xf8, 0x88, 0xb8, 0xe9, 0x2e, 0xd5, 0x6b, 0xb2, 0x0f, 0x0e, 0x97, 0xd3,
-	0x84, 0x0b, 0xbd, 0xab, 0x4b, 0x3b, 0x5c, 0x7e, 0xcf, 0x05, 0xb6, 0x4a, 0xfe, 0x1b, 0xd3, 0x56,
-	0xda, 0x54, 0xaf, 0xd1, 0x84, 0x0c, 0x5d, 0xd3, 0x0e, 0xda, 0xd4, 0x14, 0x68, 0xd6, 0x92, 0x27,
-	0xbe, 0xa3, 0x39, 0x71, 0x89, 0xc8, 0x8c, 0x27, 0x7e, 0xd7, 0x20, 0x33, 0x9e, 0x04, 0x03, 0x78,
-	0xd2, 0x7c, 0x8a, 0xe0, 0x17, 0x78, 0xd6, 0x90, 0x71, 0xed, 0x5e, 0x57, 0x2e, 0xe3, 0x98, 0x49,
-	0xa9, 0x07, 0x77, 0x69, 0x55, 0xe2, 0xe6, 0x4c, 0x88, 0x42, 0x54, 0x09, 0xd0, 0x05, 0xf9, 0x0c,
-	0x76, 0x2f, 0x57, 0x8a, 0xc9, 0xe9, 0xad, 0xe0, 0x4a, 0xb1, 0x5c, 0x3f, 0x84, 0x4d, 0xfb, 0x1a,
-	0xfc, 0xd9, 0x60, 0xc1, 0x8f, 0xb0, 0x87, 0x7b, 0x9d, 0x8b, 0x22, 0x6b, 0x98, 0xb6, 0x4d, 0xa2,
-	0x4f, 0xa1, 0x7f, 0x55, 0xa4, 0x69, 0x71, 0x3b, 0x4d, 0x79, 0x7e, 0x2d, 0xcb, 0x93, 0xd0, 0x33,
-	0xd8, 0x5b, 0x84, 0x82, 0xbf, 0x2c, 0xd8, 0xdf, 0xe0, 0x2b, 0xa7, 0xff, 0x0a, 0x9c, 0x39, 0x8b,
-	0x12, 0x26, 0xca, 0x18, 0x0c, 0x6b, 0x0e, 0xae, 0xbb, 0x27, 0xba, 0x03, 0xd3, 0x67, 0x7a, 0xff,
-	0x27, 0x0a, 0x2f, 0xeb, 0x51, 0x38, 0xdc, 0x46, 0x74, 0x1f, 0x06, 0xf2, 0x65, 0x25, 0x4e, 0x5b,
-	0xb7, 0x1f, 0x6d, 0x6d, 0xc7, 0x06, 0x0c, 0xa0, 0xee, 0x6c, 0x84, 0xfa, 0x5f, 0xcb, 0xb8, 0xb1,
-	0x31, 0xe3, 0x63, 0x33, 0xf4, 0x1c, 0x80, 0xcb, 0xa9, 0x5c, 0x65, 0x28, 0xa5, 0x1e, 0xcd, 0xa5,
-	0x1e, 0x97, 0x17, 0x06, 0x20, 0x9f, 0x40, 0x0f, 0xff, 0x4f, 0x55, 0x24, 0x66, 0x4c, 0xe9, 0x50,
-	0x79, 0x14, 0x10, 0x7a, 0xa7, 0x91, 0x75, 0x06, 0x9d, 0x6d, 0x19, 0xec, 0x6e, 0xc9, 0xa0, 0xfb,
-	0x20, 0x83, 0xde, 0x7d, 0x06, 0x43, 0x73, 0x48, 0xea, 0xf2, 0x21, 0xdb, 0x15, 0xcf, 0xa3, 0xb4,
-	0x0c, 0x9b, 0x29, 0x82, 0x53, 0x73, 0xc4, 0x9b, 0xca, 0x61, 0x34, 0x33, 0x26, 0x65, 0x34, 0x63,
-	0xa5, 0x1e, 0x55, 0xb9, 0x96, 0xa9, 0x75, 0x2f, 0x53, 0x30, 0x81, 0xa7, 0x17, 0x2a, 0x52, 0x3f,
-	0x45, 0x6a, 0xfe, 0xc8, 0xb8, 0xfd, 0x63, 0xc1, 0xe0, 0x9e, 0xaa, 0x4c, 0xda, 0x01, 0x38, 0xec,
-	0x8e, 0x4b, 0x55, 0x1d, 0x93, 0xb2, 0xaa, 0x39, 0xd1, 0xaa, 0x3b, 0x71, 0x08, 0x5d, 0x2e, 0xa7,
-	0x57, 0x3c, 0x65, 0xa5, 0x43, 0x0e, 0x97, 0xe7, 0x3c, 0x65, 0x1f, 0xc2, 0x22, 0x9d, 0x06, 0xa7,
-	0x96, 0x86, 0xca, 0xb6, 0x6e, 0xd3, 0x36, 0x13, 0x50, 0xb7, 0x76, 0x7a, 0x4f, 0xfe, 0x68, 0x41,
-	0xdf, 0xbc, 0xb2, 0x98, 0xb8, 0xe1, 0x31, 0x23, 0xaf, 0xa1, 0x8d, 0x2f, 0x73, 0x42, 0x6a, 0xbf,
-	0x33, 0xa5, 0x7c, 0xc3, 0x67, 0x0d, 0xcc, 0xe8, 0x10, 0x5a, 0xaf, 0x2c, 0x72, 0x0e, 0xbd, 0xda,
-	0xab, 0x84, 0x1c, 0x3d, 0x7c, 0x6d, 0x56, 0x14, 0xc3, 0x6d, 0x97, 0x2a, 0x26, 0xf2, 0x16, 0x76,
-	0x1b, 0xb6, 0x93, 0x8f, 0xb6, 0x1d, 0xa3, 0x8a, 0xeb, 0xe3, 0xed, 0x17, 0x0d, 0xdb, 0x2b, 0x8b,
-	0x7c, 0x07, 0x6e, 0xe5, 0x1a, 0x39, 0x28, 0x7b, 0x37, 0x12, 0x31, 0x3c, 0x7c, 0x80, 0x9b, 0xdb,
-	0x4f, 0x5f, 0xbc, 0xff, 0x7c, 0xc6, 0xd5, 0x7c, 0x79, 0x79, 0x1c, 0x17, 0xd9, 0xb8, 0xc8, 0xaf,
-	0x99, 0xc8, 0x59, 0x3a, 0x9e, 0xaf, 0x16, 0x2c, 0x8b, 0xf2, 0xf1, 0xfa, 0x33, 0xe2, 0xd2, 0xd1,
-	0x5f, 0x10, 0xaf, 0xff, 0x0b, 0x00, 0x00, 0xff, 0xff, 0x85, 0xa3, 0x7e, 0x65, 0x5a, 0x08, 0x00,
-	0x00,
+	// 2062 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xac, 0x59, 0x4b, 0x73, 0xdb, 0xc8,
+	0x11, 0x36, 0x48, 0xf1, 0xd5, 0xa4, 0x64, 0x79, 0x44, 0x49, 0x14, 0x64, 0x57, 0x64, 0xb8, 0x1c,
+	0x73, 0xcb, 0x89, 0xe4, 0x95, 0x77, 0x9d, 0xf7, 0x56, 0x24, 0x5b, 0xb2, 0x36, 0xe5, 0x24, 0x0a,
+	0x24, 0x67, 0x2b, 0xb9, 0xb0, 0x60, 0x60, 0x44, 0x4d, 0x04, 0x02, 0x08, 0x66, 0xa8, 0xc7, 0x9e,
+	0xb6, 0x72, 0xca, 0x21, 0x97, 0xfc, 0x84, 0x1c, 0xf3, 0x0b, 0xf2, 0x33, 0x72, 0xcd, 0x39, 0xd7,
+	0xfc, 0x86, 0xa4, 0x52, 0xf3, 0x02, 0x06, 0x20, 0xa4, 0xf5, 0x6a, 0x7d, 0x91, 0xa6, 0x1f, 0xd3,
+	0xd3, 0x33, 0xfd, 0x4d, 0x77, 0x0f, 0x08, 0xcb, 0x21, 0x79, 0xb7, 0x35, 0x9e, 0x62, 0xca, 0xe4,
+	0xdf, 0xcd, 0x24, 0x8d, 0x59, 0x8c, 0x1a, 0x82, 0x70, 0xfe, 0x66, 0x41, 0x77, 0xef, 0x12, 0xfb,
+	0x2e, 0xfe, 0x23, 0xa7, 0xd1, 0x10, 0x1a, 0x94, 0x79, 0x29, 0x1b, 0x58, 0x1b, 0xd6, 0xb0, 0xbb,
+	0xbd, 0xb8, 0x29, 0xe7, 0x70, 0x95, 0x23, 0xce, 0x3f, 0xb8, 0xe3, 0x4a, 0x05, 0xb4, 0xc2, 0x35,
+	0x03, 0x12, 0x0d, 0x6a, 0x1b, 0xd6, 0xb0, 0x27, 0xf9, 0x01, 0x89, 0xd0, 0x53, 0x68, 0xa6, 0x98,
+	0x92, 0x2f, 0xf1, 0xa0, 0x2e, 0x4c, 0xdc, 0x53, 0x26, 0xbe, 0x20, 0x51, 0x10, 0x5f, 0x1c, 0x91,
+	0x2f, 0xf1, 0xc1, 0x1d, 0x57, 0xa9, 0xa0, 0x01, 0x34, 0x29, 0x19, 0x47, 0x5e, 0x38, 0x98, 0xdb,
+	0xb0, 0x86, 0x1d, 0x2e, 0x91, 0xf4, 0x6e, 0x07, 0x5a, 0xa9, 0xf4, 0xc9, 0xf9, 0x9f, 0x05, 0x9d,
+	0xcc, 0x01, 0x34, 0x80, 0x96, 0x1f, 0x4f, 0x26, 0x5e, 0x14, 0x0c, 0xac, 0x8d, 0xfa, 0xb0, 0xe3,
+	0x6a, 0x12, 0x2d, 0x42, 0x9d, 0xb1, 0x2b, 0xe1, 0x4f, 0xdb, 0xe5, 0x43, 0xf4, 0x14, 0xea, 0x38,
+	0x3a, 0x1f, 0xd4, 0x37, 0xea, 0xc3, 0xee, 0xf6, 0x5a, 0x79, 0x2f, 0x9b, 0x7b, 0xd1, 0xf9, 0x5e,
+	0xc4, 0xd2, 0x2b, 0x97, 0x6b, 0xf1, 0xe9, 0xfe, 0x45, 0x20, 0x1d, 0x71, 0xf9, 0x10, 0x3d, 0x81,
+	0xbb, 0x8c, 0x4c, 0x70, 0x3c, 0x65, 0x23, 0x8a, 0xfd, 0x38, 0x0a, 0xe8, 0xa0, 0xb1, 0x61, 0x0d,
+	0x1b, 0xee, 0x82, 0x62, 0x1f, 0x49, 0x2e, 0x7a, 0x0c, 0x73, 0x62, 0xc7, 0xcd, 0x6b, 0x76, 0xec,
+	0x0a, 0xb1, 0xfd, 0x02, 0xda, 0x7a, 0x49, 0xbe, 0xda, 0x19, 0xbe, 0x12, 0xc7, 0xdc, 0x71, 0xf9,
+	0x10, 0xf5, 0xa1, 0x71, 0xee, 0x85, 0x53, 0x2c, 0x36, 0xd0, 0x71, 0x25, 0xf1, 0xe3, 0xda, 0x0f,
+	0x2d, 0x67, 0x02, 0x3d, 0x19, 0x23, 0x9a, 0xc4, 0x11, 0x95, 0xa7, 0xc6, 0x82, 0x78, 0x2a, 0xa3,
+	0xd4, 0x13, 0xa7, 0x26, 0x68, 0x25, 0xc1, 0x69, 0x9a, 0x45, 0x45, 0xd1, 0xe8, 0x01, 0x74, 0xf0,
+	0x25, 0x61, 0x23, 0x3f, 0x0e, 0x64, 0x64, 0x1a, 0x07, 0x77, 0xdc, 0x36, 0x67, 0xbd, 0x8c, 0x03,
+	0xbc, 0x0b, 0xd0, 0x4e, 0x95, 0x79, 0xe7, 0xaf, 0x16, 0xa0, 0x97, 0x71, 0x72, 0x75, 0x1c, 0xbf,
+	0xe6, 0xfb, 0xd0, 0xd0, 0xd8, 0x2a, 0x42, 0x63, 0x55, 0xed, 0xd2, 0xd0, 0x2c, 0x21, 0xa4, 0x0f,
+	0x73, 0x81, 0xc7, 0xbc, 0xcc, 0x15, 0x41, 0xa1, 0x8f, 0x78, 0x4c, 0x02, 0x05, 0x8e, 0xe5, 0x59,
+	0x23, 0x7b, 0x51, 0x70, 0x70, 0x87, 0x47, 0x24, 0x30, 0x31, 0xf0, 0x0f, 0x0b, 0x16, 0xcb, 0x2b,
+	0x21, 0x04, 0x73, 0x89, 0xc7, 0x4e, 0xd5, 0x21, 0x8a, 0x31, 0xe7, 0x4d, 0xf8, 0x16, 0xf9, 0xa2,
+	0xf3, 0xae, 0x18, 0xa3, 0x65, 0x68, 0x12, 0x3a, 0x0a, 0x48, 0x2a, 0x56, 0x6d, 0xbb, 0x0d, 0x42,
+	0x5f, 0x91, 0x94, 0xab, 0x8a, 0xa8, 0xf1, 0x88, 0xd7, 0x65, 0x88, 0x78, 0x10, 0x26, 0x3c, 0xb8,
+	0x22, 0xd0, 0x75, 0x57, 0x12, 0x3c, 0x58, 0x53, 0x12, 0x88, 0xf0, 0xce, 0xbb, 0x7c, 0xc8, 0x39,
+	0x63, 0x12, 0x0c, 0x5a, 0x92, 0x33, 0x26, 0x01, 0x5a, 0x81, 0x66, 0x7c, 0x72, 0x42, 0x31, 0x1b,
+	0xb4, 0xc5, 0x54, 0x45, 0x39, 0x43, 0x58, 0x28, 0xee, 0x8e, 0x6b, 0xd2, 0x53, 0x6f, 0xfb, 0xd3,
+	0x17, 0xca, 0x71, 0x45, 0x39, 0x7f, 0xb2, 0x60, 0xa9, 0x70, 0xee, 0x59, 0xb8, 0x5b, 0x74, 0xea,
+	0xfb, 0x98, 0x52, 0x31, 0xa1, 0xed, 0x6a, 0x92, 0x7b, 0x8b, 0xd3, 0x34, 0x4e, 0x35, 0x64, 0x04,
+	0x81, 0x1e, 0xc1, 0xfc, 0xbb, 0x2b, 0x86, 0xe9, 0xe8, 0x22, 0x25, 0x8c, 0xe1, 0x48, 0xec, 0xba,
+	0xee, 0xf6, 0x04, 0xf3, 0x0b, 0xc9, 0x33, 0x9c, 0x98, 0x2b, 0x38, 0x81, 0xa1, 0xcf, 0x7d, 0xd8,
+	0x4f, 0xe3, 0x49, 0x21, 0xfa, 0x55, 0x67, 0xfd, 0x10, 0x7a, 0x27, 0x71, 0x18, 0xc6, 0x17, 0xa3,
+	0x90, 0x44, 0x67, 0x54, 0xdd, 0xbc, 0xae, 0xe4, 0xbd, 0xe1, 0x2c, 0xe3, 0x54, 0xea, 0x85, 0x53,
+	0xf9, 0xa7, 0x05, 0xcb, 0xa5, 0x75, 0xd4, 0x6e, 0x3f, 0x81, 0xe6, 0x29, 0xf6, 0x02, 0x9c, 0x2a,
+	0x9c, 0xd9, 0x06, 0x44, 0x32, 0xed, 0x03, 0xa1, 0xc1, 0xe1, 0x2d, 0x75, 0xaf, 0xc1, 0xda, 0x53,
+	0x13, 0x6b, 0xab, 0x55, 0x86, 0x72, 0xb4, 0xa1, 0x8f, 0xf5, 0x61, 0xce, 0x6d, 0x58, 0x46, 0xba,
+	0x28, 0xaa, 0x73, 0x05, 0x8e, 0x70, 0xa1, 0x59, 0xb8, 0x35, 0xff, 0x56, 0xd1, 0x2b, 0xf9, 0xf8,
+	0x6d, 0x41, 0xfa, 0x00, 0x80, 0xd0, 0x11, 0xbd, 0x9a, 0xf0, 0x23, 0x16, 0xae, 0xb5, 0xdd, 0x0e,
+	0xa1, 0x47, 0x92, 0x81, 0xbe, 0x03, 0x5d, 0xfe, 0x7f, 0xc4, 0xbc, 0x74, 0x8c, 0x99, 0x40, 0x6d,
+	0xc7, 0x05, 0xce, 0x3a, 0x16, 0x9c, 0x0c, 0xe4, 0xcd, 0x2a, 0x90, 0xb7, 0x2a, 0x40, 0xde, 0x9e,
+	0x01, 0x79, 0x27, 0x03, 0xb9, 0xf3, 0x73, 0x58, 0x2c, 0xec, 0x91, 0xc3, 0xb9, 0x0f, 0x8d, 0x13,
+	0xc2, 0x53, 0xb8, 0x04, 0xa7, 0x24, 0x0c, 0x7c, 0xd5, 0x0a, 0xf8, 0xda, 0x05, 0x54, 0xb4, 0x20,
+	0x20, 0x3b, 0x80, 0xd6, 0x04, 0x53, 0xea, 0x8d, 0xb1, 0x3a, 0x27, 0x4d, 0x66, 0xc7, 0x57, 0xcb,
+	0x8f, 0xcf, 0x39, 0x80, 0xbb, 0x47, 0xcc, 0x63, 0x87, 0x1e, 0x3b, 0xfd, 0x76, 0xf0, 0x74, 0xfe,
+	0x65, 0xc1, 0x62, 0x6e, 0x4a, 0x21, 0x70, 0x05, 0x9a, 0xf8, 0x92, 0x50, 0xa6, 0xaf, 0x9b, 0xa2,
+	0x8c, 0x08, 0xd5, 0xcc, 0x08, 0xad, 0x42, 0x8b, 0xd0, 0xd1, 0x09, 0x09, 0xb1, 0x8a, 0x5c, 0x93,
+	0xd0, 0x7d, 0x12, 0xe2, 0x0f, 0x11, 0x3a, 0x81, 0x92, 0xa6, 0x81, 0x12, 0x1d, 0xce, 0x56, 0x31,
+	0x9c, 0x12, 0xb8, 0x6d, 0x23, 0x0b, 0x38, 0x27, 0x80, 0xde, 0x26, 0x81, 0xc7, 0xf0, 0xce, 0x18,
+	0x47, 0x5f, 0x97, 0xc4, 0x0d, 0xcd, 0xf7, 0x49, 0xe2, 0x66, 0x66, 0xfe, 0x0c, 0x16, 0xcb, 0xb3,
+	0x33, 0x2f, 0x2d, 0xc3, 0xcb, 0xeb, 0x00, 0xb1, 0x07, 0x4b, 0x05, 0x3f, 0x6f, 0x97, 0xf4, 0x9c,
+	0x65, 0x58, 0x7a, 0x8d, 0x99, 0xb0, 0xf1, 0x79, 0x74, 0x12, 0xab, 0xfd, 0x3a, 0x9b, 0xd0, 0x2f,
+	0xb2, 0xf3, 0x18, 0x57, 0xe6, 0xe0, 0xff, 0x58, 0xb0, 0x24, 0xf6, 0x70, 0x98, 0xc6, 0x7c, 0x35,
+	0x03, 0x5f, 0x91, 0x37, 0xd1, 0xe8, 0x14, 0x63, 0xb3, 0x13, 0xa9, 0x15, 0x3b, 0x91, 0x4f, 0xcd,
+	0xbe, 0xe3, 0x91, 0x3a, 0xe3, 0x0a, 0xb3, 0x5f, 0xdb, 0x81, 0x3c, 0x86, 0x85, 0x14, 0x8b, 0x40,
+	0x8c, 0x92, 0x38, 0x24, 0xfe, 0x95, 0x82, 0xc9, 0xbc, 0xe2, 0x1e, 0x0a, 0xe6, 0xad, 0x1b, 0x8b,
+	0x57, 0xd0, 0x2f, 0x7a, 0xa5, 0x4e, 0xe7, 0x7b, 0xd0, 0x4a, 0x24, 0x4b, 0xe1, 0x04, 0xa9, 0x3d,
+	0x28, 0x45, 0x71, 0x94, 0x5a, 0xc5, 0xf9, 0x0d, 0xa0, 0x23, 0x16, 0x27, 0xef, 0x71, 0x62, 0x15,
+	0x0d, 0x55, 0xad, 0xaa, 0xa1, 0x72, 0x5e, 0xc2, 0x52, 0xc1, 0xe4, 0xad, 0xfc, 0x3a, 0x86, 0x65,
+	0x57, 0x1d, 0xd3, 0x07, 0x74, 0x6d, 0x1f, 0x56, 0xca, 0x56, 0x6f, 0xe5, 0xdd, 0x0a, 0xf4, 0xdf,
+	0x10, 0xaa, 0x8d, 0x60, 0xed, 0x9c, 0xf3, 0x39, 0x2c, 0x97, 0xf8, 0xca, 0xfc, 0x33, 0xe8, 0x24,
+	0x9a, 0x29, 0x5a, 0xdf, 0xea, 0x05, 0x72, 0x25, 0xe7, 0xbf, 0x16, 0x74, 0x0d, 0xd1, 0x37, 0x04,
+	0xf1, 0x2c, 0xf6, 0xea, 0x15, 0xd8, 0xe3, 0xe8, 0xa2, 0xcc, 0x63, 0x58, 0xc1, 0x56, 0x12, 0x1c,
+	0x85, 0x09, 0x09, 0x54, 0xbb, 0xcc, 0x87, 0x68, 0xdd, 0x6c, 0x40, 0x9b, 0x82, 0x9f, 0xb5, 0x9f,
+	0xc8, 0x86, 0xb6, 0xb2, 0x4a, 0x45, 0x6a, 0x6b, 0xb8, 0x19, 0xcd, 0xd3, 0xa8, 0x18, 0xe1, 0x60,
+	0xe4, 0xe9, 0xe6, 0xaa, 0xa3, 0x38, 0x3b, 0x0c, 0xad, 0x41, 0x3b, 0x8c, 0xc7, 0x23, 0x91, 0xfd,
+	0x3b, 0xb2, 0x76, 0x84, 0xf1, 0x98, 0x27, 0x74, 0xe7, 0x08, 0xee, 0x1e, 0x7b, 0x24, 0xe4, 0xc9,
+	0xf8, 0xa6, 0x3a, 0xd1, 0x87, 0x46, 0x48, 0x22, 0xac, 0x03, 0x2e, 0x09, 0x9e, 0x21, 0x64, 0xa5,
+	0xd0, 0x59, 0x5d, 0x52, 0xce, 0x10, 0x16, 0x73, 0xa3, 0x2a, 0x34, 0x99, 0x05, 0xf9, 0x22, 0x91,
+	0x84, 0x73, 0x01, 0x6b, 0xbc, 0xd4, 0xed, 0xa4, 0xfe, 0x29, 0x39, 0xc7, 0xa5, 0x6e, 0xba, 0xca,
+	0x91, 0x01, 0xb4, 0x48, 0xe4, 0x87, 0x53, 0xd1, 0x19, 0x88, 0x58, 0x28, 0x92, 0x4b, 0xf0, 0xa5,
+	0x94, 0xd4, 0xa5, 0x44, 0x91, 0xdc, 0x8e, 0xc8, 0xcf, 0xfc, 0xf4, 0x7b, 0x32, 0x3b, 0x3b, 0x7f,
+	0xb6, 0x60, 0xdd, 0x58, 0xf9, 0xbd, 0x7a, 0xb9, 0xdb, 0xac, 0x5d, 0x2e, 0xb0, 0x73, 0xb3, 0x05,
+	0xf6, 0x0f, 0x70, 0xbf, 0xda, 0x13, 0x75, 0x72, 0xda, 0x7d, 0x2b, 0x77, 0x1f, 0xbd, 0x80, 0x76,
+	0x92, 0xc6, 0xe3, 0x14, 0x53, 0x19, 0x92, 0x62, 0x0f, 0xa8, 0x4c, 0x1d, 0x2a, 0x0d, 0x37, 0xd3,
+	0x75, 0xde, 0xc2, 0x52, 0x85, 0x82, 0xec, 0x4f, 0x42, 0x4c, 0x55, 0x35, 0x92, 0x04, 0xe7, 0x8a,
+	0x7e, 0x58, 0xac, 0x50, 0x77, 0x25, 0x21, 0xdc, 0x89, 0x23, 0x5d, 0xc8, 0xc5, 0xd8, 0xf9, 0xbb,
+	0x05, 0xf7, 0x0e, 0xc5, 0xfd, 0x4f, 0x31, 0xcb, 0x72, 0xc8, 0x93, 0xdc, 0x6a, 0xdd, 0x78, 0xf3,
+	0x49, 0x2d, 0x01, 0x0e, 0xb5, 0xd0, 0x73, 0x59, 0x0b, 0x6a, 0x42, 0xed, 0xa1, 0xbe, 0xb0, 0x65,
+	0x7b, 0xc5, 0x4a, 0x70, 0xeb, 0x84, 0xfe, 0x02, 0x20, 0xf7, 0xa0, 0xf2, 0xbe, 0x17, 0xe6, 0xf6,
+	0xd4, 0x5c, 0xa7, 0x0f, 0xc8, 0x74, 0x49, 0xb5, 0xb4, 0xeb, 0xb0, 0xc6, 0x53, 0x91, 0x88, 0xd8,
+	0x4c, 0x9e, 0xfa, 0x35, 0xd8, 0x55, 0x42, 0x15, 0xd7, 0x8f, 0x67, 0x93, 0xd5, 0x92, 0xda, 0xbb,
+	0x39, 0xc3, 0xcc, 0x56, 0x7f, 0xb1, 0xa0, 0x67, 0xca, 0x74, 0x0e, 0xb1, 0xf2, 0x1c, 0xc2, 0x81,
+	0xcb, 0x59, 0xf2, 0xa2, 0x8a, 0xb1, 0x99, 0xc0, 0x64, 0x7e, 0xd2, 0x24, 0x6f, 0xb0, 0xfc, 0x64,
+	0x3a, 0x4a, 0x70, 0xea, 0xe3, 0x88, 0x09, 0x74, 0x5a, 0x2e, 0xf8, 0xc9, 0xf4, 0x50, 0x72, 0x78,
+	0x4a, 0x4a, 0x29, 0x1d, 0x49, 0x1c, 0xc8, 0x07, 0x5f, 0x3b, 0xa5, 0x74, 0x97, 0xd3, 0xba, 0xa1,
+	0x48, 0x12, 0xde, 0x1f, 0x4e, 0xb3, 0x6d, 0x7f, 0x65, 0x41, 0xbf, 0xc8, 0x2f, 0x74, 0x8d, 0x0c,
+	0x07, 0x46, 0xd7, 0xc8, 0x70, 0x29, 0xef, 0xd5, 0x4a, 0x79, 0x6f, 0x25, 0xfb, 0xfe, 0x51, 0x57,
+	0x6d, 0x88, 0xa0, 0xf4, 0x24, 0x99, 0xf2, 0xe4, 0xfb, 0xb4, 0x2d, 0x19, 0x3b, 0xcc, 0xf9, 0x11,
+	0x2c, 0xf0, 0xe2, 0xb8, 0x93, 0x24, 0x39, 0x18, 0x67, 0x8a, 0x97, 0x55, 0x59, 0xbc, 0x3e, 0x82,
+	0xbb, 0xd9, 0xd4, 0x9b, 0xfd, 0x76, 0x76, 0x60, 0xf1, 0xe8, 0x2a, 0xf2, 0x5f, 0x86, 0xb1, 0x7f,
+	0xa6, 0xd7, 0xf9, 0x3e, 0x2c, 0x9d, 0xc6, 0x94, 0x8d, 0xb8, 0xd5, 0xd1, 0x34, 0x22, 0x97, 0xa3,
+	0xc8, 0x8b, 0x62, 0x75, 0xb1, 0x16, 0xb9, 0xe8, 0x98, 0x4c, 0xf0, 0xdb, 0x88, 0x5c, 0xfe, 0xca,
+	0x8b, 0x62, 0x67, 0x1b, 0xee, 0x19, 0x26, 0xd4, 0x7a, 0x3c, 0x9d, 0x9f, 0xe1, 0x0b, 0x31, 0x53,
+	0xdf, 0xc9, 0x0e, 0xe7, 0xf0, 0x29, 0xd4, 0xf9, 0x04, 0x20, 0xff, 0x6e, 0xc2, 0x03, 0x9e, 0xc6,
+	0x17, 0x52, 0x6d, 0xde, 0x15, 0x63, 0xce, 0xf3, 0xe3, 0x90, 0xea, 0xc7, 0x13, 0x1f, 0x3b, 0x3f,
+	0xd5, 0xcd, 0xae, 0xc8, 0x3a, 0xda, 0xdd, 0xef, 0x8a, 0xb4, 0x7c, 0xa6, 0x01, 0xa8, 0x3f, 0x66,
+	0x71, 0x1d, 0x79, 0xd7, 0xa4, 0x98, 0x87, 0xba, 0x30, 0x5b, 0xc1, 0xff, 0x07, 0xd0, 0xc9, 0x54,
+	0x79, 0x85, 0xe2, 0xfb, 0x33, 0xee, 0x53, 0x46, 0xa3, 0x05, 0xa8, 0x91, 0x44, 0x5d, 0xc6, 0x1a,
+	0x49, 0xb6, 0xbf, 0xea, 0x2a, 0x24, 0x1f, 0xe1, 0xf4, 0x9c, 0xf8, 0x18, 0x3d, 0x87, 0x39, 0xfe,
+	0x01, 0x07, 0x21, 0xe3, 0x13, 0x94, 0x72, 0xd2, 0x5e, 0x2a, 0xf0, 0xe4, 0xd2, 0x43, 0xeb, 0x99,
+	0x85, 0xf6, 0xa1, 0x6b, 0x7c, 0x0d, 0x40, 0x6b, 0xb3, 0x9f, 0x4a, 0xb4, 0x09, 0xbb, 0x4a, 0xa4,
+	0x2d, 0xa1, 0x37, 0x30, 0x5f, 0x78, 0x71, 0xa1, 0xf5, 0xaa, 0x97, 0xad, 0xb6, 0x75, 0xbf, 0x5a,
+	0x28, 0xad, 0x3d, 0xb3, 0xd0, 0x4f, 0xa0, 0xad, 0x1f, 0x4c, 0x68, 0x25, 0xef, 0x6c, 0xcd, 0xc7,
+	0x98, 0xbd, 0x3a, 0xc3, 0x57, 0xb1, 0xdf, 0x87, 0xae, 0xd1, 0xeb, 0x67, 0x5b, 0x9a, 0x7d, 0xa7,
+	0xd8, 0x76, 0x95, 0x28, 0xdb, 0xd2, 0x6b, 0xe8, 0x99, 0x5d, 0x3d, 0xd2, 0xda, 0x15, 0x2f, 0x00,
+	0x7b, 0xbd, 0x52, 0xa6, 0x1c, 0x7a, 0x0d, 0x3d, 0xb3, 0x01, 0xce, 0x0c, 0x55, 0xf4, 0xea, 0xf6,
+	0x7a, 0xa5, 0x4c, 0x19, 0x7a, 0x05, 0x5d, 0xa3, 0x61, 0xcd, 0x76, 0x36, 0xdb, 0x17, 0xdb, 0x76,
+	0x95, 0x48, 0x59, 0xf9, 0x25, 0x2c, 0x14, 0x7b, 0x4b, 0xa4, 0xc3, 0x51, 0xd9, 0xc8, 0xda, 0x0f,
+	0xae, 0x91, 0x2a, 0x73, 0xbf, 0x80, 0xf9, 0x42, 0x2b, 0x99, 0x45, 0xbe, 0xaa, 0xf1, 0xb4, 0xef,
+	0x57, 0x0b, 0x95, 0xad, 0x9f, 0x41, 0x5b, 0xb7, 0x3d, 0x59, 0xdc, 0x4b, 0xcd, 0x95, 0xbd, 0x3a,
+	0xc3, 0xcf, 0x60, 0xf3, 0x5b, 0x40, 0x46, 0x6d, 0xd6, 0x98, 0xde, 0x98, 0xad, 0xeb, 0x37, 0x40,
+	0xbb, 0x54, 0xd8, 0xc5, 0x25, 0xf1, 0xa0, 0x6f, 0x88, 0x72, 0x8c, 0x3b, 0xb3, 0xf3, 0x66, 0xa0,
+	0xfe, 0xe8, 0x46, 0x9d, 0xcc, 0xf5, 0x1d, 0x80, 0xbc, 0x36, 0xa2, 0xc1, 0x75, 0x15, 0xdc, 0x5e,
+	0xab, 0x90, 0xa8, 0xc3, 0xfb, 0x1d, 0xa0, 0xd9, 0x5a, 0x99, 0xed, 0xfe, 0xda, 0x1a, 0x6b, 0x3f,
+	0xbc, 0x41, 0x23, 0x47, 0xb0, 0x59, 0x8e, 0x0a, 0x57, 0xa1, 0x54, 0xbb, 0xec, 0xf5, 0x4a, 0x59,
+	0x76, 0x37, 0x5b, 0xaa, 0x34, 0x64, 0xa0, 0x33, 0x90, 0x9a, 0x17, 0x1b, 0xfb, 0xc1, 0x35, 0x52,
+	0x65, 0xe7, 0x33, 0xe8, 0x64, 0x49, 0x1f, 0x65, 0x99, 0xa0, 0x54, 0x49, 0xec, 0xc1, 0xac, 0x20,
+	0xbf, 0x49, 0x46, 0x32, 0x2e, 0xe5, 0x08, 0x33, 0xbd, 0xdb, 0x76, 0x95, 0x48, 0x5a, 0xd9, 0x7d,
+	0xf2, 0xfb, 0xc7, 0x63, 0xc2, 0x4e, 0xa7, 0xef, 0x36, 0xfd, 0x78, 0xb2, 0x15, 0x47, 0x67, 0x38,
+	0x8d, 0x70, 0xb8, 0x75, 0x7a, 0x95, 0xe0, 0x89, 0x17, 0x6d, 0x65, 0xbf, 0x89, 0xbc, 0x6b, 0x8a,
+	0x9f, 0x43, 0x9e, 0xff, 0x7f, 0x00, 0xad, 0x4c, 0x54, 0x00, 0x27, 0x19, 0x00, 0x00,
 }
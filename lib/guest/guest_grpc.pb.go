@@ -19,10 +19,25 @@ import (
 const _ = grpc.SupportPackageIsVersion9
 
 const (
-	GuestService_Exec_FullMethodName          = "/guest.GuestService/Exec"
-	GuestService_CopyToGuest_FullMethodName   = "/guest.GuestService/CopyToGuest"
-	GuestService_CopyFromGuest_FullMethodName = "/guest.GuestService/CopyFromGuest"
-	GuestService_StatPath_FullMethodName      = "/guest.GuestService/StatPath"
+	GuestService_Exec_FullMethodName                 = "/guest.GuestService/Exec"
+	GuestService_CopyToGuest_FullMethodName          = "/guest.GuestService/CopyToGuest"
+	GuestService_CopyFromGuest_FullMethodName        = "/guest.GuestService/CopyFromGuest"
+	GuestService_StatPath_FullMethodName             = "/guest.GuestService/StatPath"
+	GuestService_UpdateAgent_FullMethodName          = "/guest.GuestService/UpdateAgent"
+	GuestService_GetAgentInfo_FullMethodName         = "/guest.GuestService/GetAgentInfo"
+	GuestService_StartProcess_FullMethodName         = "/guest.GuestService/StartProcess"
+	GuestService_StopProcess_FullMethodName          = "/guest.GuestService/StopProcess"
+	GuestService_RestartProcess_FullMethodName       = "/guest.GuestService/RestartProcess"
+	GuestService_ListProcesses_FullMethodName        = "/guest.GuestService/ListProcesses"
+	GuestService_TailFile_FullMethodName             = "/guest.GuestService/TailFile"
+	GuestService_CopyArchiveToGuest_FullMethodName   = "/guest.GuestService/CopyArchiveToGuest"
+	GuestService_CopyArchiveFromGuest_FullMethodName = "/guest.GuestService/CopyArchiveFromGuest"
+	GuestService_PutSecrets_FullMethodName           = "/guest.GuestService/PutSecrets"
+	GuestService_ListGuestProcesses_FullMethodName   = "/guest.GuestService/ListGuestProcesses"
+	GuestService_GetAppStatus_FullMethodName         = "/guest.GuestService/GetAppStatus"
+	GuestService_StopApp_FullMethodName              = "/guest.GuestService/StopApp"
+	GuestService_SyncClock_FullMethodName            = "/guest.GuestService/SyncClock"
+	GuestService_UpdateLinks_FullMethodName          = "/guest.GuestService/UpdateLinks"
 )
 
 // GuestServiceClient is the client API for GuestService service.
@@ -39,6 +54,36 @@ type GuestServiceClient interface {
 	CopyFromGuest(ctx context.Context, in *CopyFromGuestRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[CopyFromGuestResponse], error)
 	// StatPath returns information about a path in the guest filesystem
 	StatPath(ctx context.Context, in *StatPathRequest, opts ...grpc.CallOption) (*StatPathResponse, error)
+	// UpdateAgent replaces the guest-agent binary and restarts the agent
+	UpdateAgent(ctx context.Context, opts ...grpc.CallOption) (grpc.ClientStreamingClient[UpdateAgentRequest, UpdateAgentResponse], error)
+	// GetAgentInfo returns information about the running guest-agent
+	GetAgentInfo(ctx context.Context, in *GetAgentInfoRequest, opts ...grpc.CallOption) (*GetAgentInfoResponse, error)
+	// StartProcess starts a supervised long-running process
+	StartProcess(ctx context.Context, in *StartProcessRequest, opts ...grpc.CallOption) (*StartProcessResponse, error)
+	// StopProcess stops a supervised process
+	StopProcess(ctx context.Context, in *StopProcessRequest, opts ...grpc.CallOption) (*StopProcessResponse, error)
+	// RestartProcess stops and starts a supervised process
+	RestartProcess(ctx context.Context, in *RestartProcessRequest, opts ...grpc.CallOption) (*RestartProcessResponse, error)
+	// ListProcesses lists the supervised processes
+	ListProcesses(ctx context.Context, in *ListProcessesRequest, opts ...grpc.CallOption) (*ListProcessesResponse, error)
+	// TailFile streams the last lines of a guest file, optionally following new output
+	TailFile(ctx context.Context, in *TailFileRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[TailFileResponse], error)
+	// CopyArchiveToGuest extracts a tar stream into a guest directory, reporting progress
+	CopyArchiveToGuest(ctx context.Context, opts ...grpc.CallOption) (grpc.BidiStreamingClient[CopyArchiveToGuestRequest, CopyArchiveProgress], error)
+	// CopyArchiveFromGuest streams a guest path as a tar archive
+	CopyArchiveFromGuest(ctx context.Context, in *CopyArchiveFromGuestRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[CopyArchiveFromGuestResponse], error)
+	// PutSecrets writes an instance's secrets to a tmpfs and hands their environment variables to the application
+	PutSecrets(ctx context.Context, in *PutSecretsRequest, opts ...grpc.CallOption) (*PutSecretsResponse, error)
+	// ListGuestProcesses returns a snapshot of all processes in the guest, like ps
+	ListGuestProcesses(ctx context.Context, in *ListGuestProcessesRequest, opts ...grpc.CallOption) (*ListGuestProcessesResponse, error)
+	// GetAppStatus reports whether the instance's application (the entrypoint) has exited, and how
+	GetAppStatus(ctx context.Context, in *GetAppStatusRequest, opts ...grpc.CallOption) (*GetAppStatusResponse, error)
+	// StopApp sends SIGTERM to the instance's application and waits for it to exit
+	StopApp(ctx context.Context, in *StopAppRequest, opts ...grpc.CallOption) (*StopAppResponse, error)
+	// SyncClock sets the guest's clock to the host's and reports how far off it was
+	SyncClock(ctx context.Context, in *SyncClockRequest, opts ...grpc.CallOption) (*SyncClockResponse, error)
+	// UpdateLinks replaces the linked peers' entries in the guest's /etc/hosts
+	UpdateLinks(ctx context.Context, in *UpdateLinksRequest, opts ...grpc.CallOption) (*UpdateLinksResponse, error)
 }
 
 type guestServiceClient struct {
@@ -104,6 +149,180 @@ func (c *guestServiceClient) StatPath(ctx context.Context, in *StatPathRequest,
 	return out, nil
 }
 
+func (c *guestServiceClient) UpdateAgent(ctx context.Context, opts ...grpc.CallOption) (grpc.ClientStreamingClient[UpdateAgentRequest, UpdateAgentResponse], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &GuestService_ServiceDesc.Streams[3], GuestService_UpdateAgent_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[UpdateAgentRequest, UpdateAgentResponse]{ClientStream: stream}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type GuestService_UpdateAgentClient = grpc.ClientStreamingClient[UpdateAgentRequest, UpdateAgentResponse]
+
+func (c *guestServiceClient) GetAgentInfo(ctx context.Context, in *GetAgentInfoRequest, opts ...grpc.CallOption) (*GetAgentInfoResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetAgentInfoResponse)
+	err := c.cc.Invoke(ctx, GuestService_GetAgentInfo_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *guestServiceClient) StartProcess(ctx context.Context, in *StartProcessRequest, opts ...grpc.CallOption) (*StartProcessResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(StartProcessResponse)
+	err := c.cc.Invoke(ctx, GuestService_StartProcess_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *guestServiceClient) StopProcess(ctx context.Context, in *StopProcessRequest, opts ...grpc.CallOption) (*StopProcessResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(StopProcessResponse)
+	err := c.cc.Invoke(ctx, GuestService_StopProcess_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *guestServiceClient) RestartProcess(ctx context.Context, in *RestartProcessRequest, opts ...grpc.CallOption) (*RestartProcessResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(RestartProcessResponse)
+	err := c.cc.Invoke(ctx, GuestService_RestartProcess_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *guestServiceClient) ListProcesses(ctx context.Context, in *ListProcessesRequest, opts ...grpc.CallOption) (*ListProcessesResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListProcessesResponse)
+	err := c.cc.Invoke(ctx, GuestService_ListProcesses_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *guestServiceClient) TailFile(ctx context.Context, in *TailFileRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[TailFileResponse], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &GuestService_ServiceDesc.Streams[4], GuestService_TailFile_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[TailFileRequest, TailFileResponse]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type GuestService_TailFileClient = grpc.ServerStreamingClient[TailFileResponse]
+
+func (c *guestServiceClient) CopyArchiveToGuest(ctx context.Context, opts ...grpc.CallOption) (grpc.BidiStreamingClient[CopyArchiveToGuestRequest, CopyArchiveProgress], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &GuestService_ServiceDesc.Streams[5], GuestService_CopyArchiveToGuest_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[CopyArchiveToGuestRequest, CopyArchiveProgress]{ClientStream: stream}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type GuestService_CopyArchiveToGuestClient = grpc.BidiStreamingClient[CopyArchiveToGuestRequest, CopyArchiveProgress]
+
+func (c *guestServiceClient) CopyArchiveFromGuest(ctx context.Context, in *CopyArchiveFromGuestRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[CopyArchiveFromGuestResponse], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &GuestService_ServiceDesc.Streams[6], GuestService_CopyArchiveFromGuest_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[CopyArchiveFromGuestRequest, CopyArchiveFromGuestResponse]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type GuestService_CopyArchiveFromGuestClient = grpc.ServerStreamingClient[CopyArchiveFromGuestResponse]
+
+func (c *guestServiceClient) PutSecrets(ctx context.Context, in *PutSecretsRequest, opts ...grpc.CallOption) (*PutSecretsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(PutSecretsResponse)
+	err := c.cc.Invoke(ctx, GuestService_PutSecrets_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *guestServiceClient) ListGuestProcesses(ctx context.Context, in *ListGuestProcessesRequest, opts ...grpc.CallOption) (*ListGuestProcessesResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListGuestProcessesResponse)
+	err := c.cc.Invoke(ctx, GuestService_ListGuestProcesses_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *guestServiceClient) GetAppStatus(ctx context.Context, in *GetAppStatusRequest, opts ...grpc.CallOption) (*GetAppStatusResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetAppStatusResponse)
+	err := c.cc.Invoke(ctx, GuestService_GetAppStatus_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *guestServiceClient) StopApp(ctx context.Context, in *StopAppRequest, opts ...grpc.CallOption) (*StopAppResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(StopAppResponse)
+	err := c.cc.Invoke(ctx, GuestService_StopApp_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *guestServiceClient) SyncClock(ctx context.Context, in *SyncClockRequest, opts ...grpc.CallOption) (*SyncClockResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(SyncClockResponse)
+	err := c.cc.Invoke(ctx, GuestService_SyncClock_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *guestServiceClient) UpdateLinks(ctx context.Context, in *UpdateLinksRequest, opts ...grpc.CallOption) (*UpdateLinksResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(UpdateLinksResponse)
+	err := c.cc.Invoke(ctx, GuestService_UpdateLinks_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // GuestServiceServer is the server API for GuestService service.
 // All implementations must embed UnimplementedGuestServiceServer
 // for forward compatibility.
@@ -118,6 +337,36 @@ type GuestServiceServer interface {
 	CopyFromGuest(*CopyFromGuestRequest, grpc.ServerStreamingServer[CopyFromGuestResponse]) error
 	// StatPath returns information about a path in the guest filesystem
 	StatPath(context.Context, *StatPathRequest) (*StatPathResponse, error)
+	// UpdateAgent replaces the guest-agent binary and restarts the agent
+	UpdateAgent(grpc.ClientStreamingServer[UpdateAgentRequest, UpdateAgentResponse]) error
+	// GetAgentInfo returns information about the running guest-agent
+	GetAgentInfo(context.Context, *GetAgentInfoRequest) (*GetAgentInfoResponse, error)
+	// StartProcess starts a supervised long-running process
+	StartProcess(context.Context, *StartProcessRequest) (*StartProcessResponse, error)
+	// StopProcess stops a supervised process
+	StopProcess(context.Context, *StopProcessRequest) (*StopProcessResponse, error)
+	// RestartProcess stops and starts a supervised process
+	RestartProcess(context.Context, *RestartProcessRequest) (*RestartProcessResponse, error)
+	// ListProcesses lists the supervised processes
+	ListProcesses(context.Context, *ListProcessesRequest) (*ListProcessesResponse, error)
+	// TailFile streams the last lines of a guest file, optionally following new output
+	TailFile(*TailFileRequest, grpc.ServerStreamingServer[TailFileResponse]) error
+	// CopyArchiveToGuest extracts a tar stream into a guest directory, reporting progress
+	CopyArchiveToGuest(grpc.BidiStreamingServer[CopyArchiveToGuestRequest, CopyArchiveProgress]) error
+	// CopyArchiveFromGuest streams a guest path as a tar archive
+	CopyArchiveFromGuest(*CopyArchiveFromGuestRequest, grpc.ServerStreamingServer[CopyArchiveFromGuestResponse]) error
+	// PutSecrets writes an instance's secrets to a tmpfs and hands their environment variables to the application
+	PutSecrets(context.Context, *PutSecretsRequest) (*PutSecretsResponse, error)
+	// ListGuestProcesses returns a snapshot of all processes in the guest, like ps
+	ListGuestProcesses(context.Context, *ListGuestProcessesRequest) (*ListGuestProcessesResponse, error)
+	// GetAppStatus reports whether the instance's application (the entrypoint) has exited, and how
+	GetAppStatus(context.Context, *GetAppStatusRequest) (*GetAppStatusResponse, error)
+	// StopApp sends SIGTERM to the instance's application and waits for it to exit
+	StopApp(context.Context, *StopAppRequest) (*StopAppResponse, error)
+	// SyncClock sets the guest's clock to the host's and reports how far off it was
+	SyncClock(context.Context, *SyncClockRequest) (*SyncClockResponse, error)
+	// UpdateLinks replaces the linked peers' entries in the guest's /etc/hosts
+	UpdateLinks(context.Context, *UpdateLinksRequest) (*UpdateLinksResponse, error)
 	mustEmbedUnimplementedGuestServiceServer()
 }
 
@@ -140,6 +389,51 @@ func (UnimplementedGuestServiceServer) CopyFromGuest(*CopyFromGuestRequest, grpc
 func (UnimplementedGuestServiceServer) StatPath(context.Context, *StatPathRequest) (*StatPathResponse, error) {
 	return nil, status.Error(codes.Unimplemented, "method StatPath not implemented")
 }
+func (UnimplementedGuestServiceServer) UpdateAgent(grpc.ClientStreamingServer[UpdateAgentRequest, UpdateAgentResponse]) error {
+	return status.Error(codes.Unimplemented, "method UpdateAgent not implemented")
+}
+func (UnimplementedGuestServiceServer) GetAgentInfo(context.Context, *GetAgentInfoRequest) (*GetAgentInfoResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method GetAgentInfo not implemented")
+}
+func (UnimplementedGuestServiceServer) StartProcess(context.Context, *StartProcessRequest) (*StartProcessResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method StartProcess not implemented")
+}
+func (UnimplementedGuestServiceServer) StopProcess(context.Context, *StopProcessRequest) (*StopProcessResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method StopProcess not implemented")
+}
+func (UnimplementedGuestServiceServer) RestartProcess(context.Context, *RestartProcessRequest) (*RestartProcessResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method RestartProcess not implemented")
+}
+func (UnimplementedGuestServiceServer) ListProcesses(context.Context, *ListProcessesRequest) (*ListProcessesResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method ListProcesses not implemented")
+}
+func (UnimplementedGuestServiceServer) TailFile(*TailFileRequest, grpc.ServerStreamingServer[TailFileResponse]) error {
+	return status.Error(codes.Unimplemented, "method TailFile not implemented")
+}
+func (UnimplementedGuestServiceServer) CopyArchiveToGuest(grpc.BidiStreamingServer[CopyArchiveToGuestRequest, CopyArchiveProgress]) error {
+	return status.Error(codes.Unimplemented, "method CopyArchiveToGuest not implemented")
+}
+func (UnimplementedGuestServiceServer) CopyArchiveFromGuest(*CopyArchiveFromGuestRequest, grpc.ServerStreamingServer[CopyArchiveFromGuestResponse]) error {
+	return status.Error(codes.Unimplemented, "method CopyArchiveFromGuest not implemented")
+}
+func (UnimplementedGuestServiceServer) PutSecrets(context.Context, *PutSecretsRequest) (*PutSecretsResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method PutSecrets not implemented")
+}
+func (UnimplementedGuestServiceServer) ListGuestProcesses(context.Context, *ListGuestProcessesRequest) (*ListGuestProcessesResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method ListGuestProcesses not implemented")
+}
+func (UnimplementedGuestServiceServer) GetAppStatus(context.Context, *GetAppStatusRequest) (*GetAppStatusResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method GetAppStatus not implemented")
+}
+func (UnimplementedGuestServiceServer) StopApp(context.Context, *StopAppRequest) (*StopAppResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method StopApp not implemented")
+}
+func (UnimplementedGuestServiceServer) SyncClock(context.Context, *SyncClockRequest) (*SyncClockResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method SyncClock not implemented")
+}
+func (UnimplementedGuestServiceServer) UpdateLinks(context.Context, *UpdateLinksRequest) (*UpdateLinksResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method UpdateLinks not implemented")
+}
 func (UnimplementedGuestServiceServer) mustEmbedUnimplementedGuestServiceServer() {}
 func (UnimplementedGuestServiceServer) testEmbeddedByValue()                      {}
 
@@ -204,6 +498,240 @@ func _GuestService_StatPath_Handler(srv interface{}, ctx context.Context, dec fu
 	return interceptor(ctx, in, info, handler)
 }
 
+func _GuestService_UpdateAgent_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(GuestServiceServer).UpdateAgent(&grpc.GenericServerStream[UpdateAgentRequest, UpdateAgentResponse]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type GuestService_UpdateAgentServer = grpc.ClientStreamingServer[UpdateAgentRequest, UpdateAgentResponse]
+
+func _GuestService_GetAgentInfo_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetAgentInfoRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GuestServiceServer).GetAgentInfo(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: GuestService_GetAgentInfo_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GuestServiceServer).GetAgentInfo(ctx, req.(*GetAgentInfoRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _GuestService_StartProcess_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(StartProcessRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GuestServiceServer).StartProcess(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: GuestService_StartProcess_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GuestServiceServer).StartProcess(ctx, req.(*StartProcessRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _GuestService_StopProcess_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(StopProcessRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GuestServiceServer).StopProcess(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: GuestService_StopProcess_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GuestServiceServer).StopProcess(ctx, req.(*StopProcessRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _GuestService_RestartProcess_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RestartProcessRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GuestServiceServer).RestartProcess(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: GuestService_RestartProcess_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GuestServiceServer).RestartProcess(ctx, req.(*RestartProcessRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _GuestService_ListProcesses_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListProcessesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GuestServiceServer).ListProcesses(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: GuestService_ListProcesses_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GuestServiceServer).ListProcesses(ctx, req.(*ListProcessesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _GuestService_TailFile_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(TailFileRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(GuestServiceServer).TailFile(m, &grpc.GenericServerStream[TailFileRequest, TailFileResponse]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type GuestService_TailFileServer = grpc.ServerStreamingServer[TailFileResponse]
+
+func _GuestService_CopyArchiveToGuest_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(GuestServiceServer).CopyArchiveToGuest(&grpc.GenericServerStream[CopyArchiveToGuestRequest, CopyArchiveProgress]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type GuestService_CopyArchiveToGuestServer = grpc.BidiStreamingServer[CopyArchiveToGuestRequest, CopyArchiveProgress]
+
+func _GuestService_CopyArchiveFromGuest_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(CopyArchiveFromGuestRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(GuestServiceServer).CopyArchiveFromGuest(m, &grpc.GenericServerStream[CopyArchiveFromGuestRequest, CopyArchiveFromGuestResponse]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type GuestService_CopyArchiveFromGuestServer = grpc.ServerStreamingServer[CopyArchiveFromGuestResponse]
+
+func _GuestService_PutSecrets_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PutSecretsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GuestServiceServer).PutSecrets(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: GuestService_PutSecrets_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GuestServiceServer).PutSecrets(ctx, req.(*PutSecretsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _GuestService_ListGuestProcesses_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListGuestProcessesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GuestServiceServer).ListGuestProcesses(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: GuestService_ListGuestProcesses_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GuestServiceServer).ListGuestProcesses(ctx, req.(*ListGuestProcessesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _GuestService_GetAppStatus_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetAppStatusRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GuestServiceServer).GetAppStatus(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: GuestService_GetAppStatus_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GuestServiceServer).GetAppStatus(ctx, req.(*GetAppStatusRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _GuestService_StopApp_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(StopAppRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GuestServiceServer).StopApp(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: GuestService_StopApp_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GuestServiceServer).StopApp(ctx, req.(*StopAppRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _GuestService_SyncClock_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SyncClockRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GuestServiceServer).SyncClock(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: GuestService_SyncClock_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GuestServiceServer).SyncClock(ctx, req.(*SyncClockRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _GuestService_UpdateLinks_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UpdateLinksRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GuestServiceServer).UpdateLinks(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: GuestService_UpdateLinks_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GuestServiceServer).UpdateLinks(ctx, req.(*UpdateLinksRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // GuestService_ServiceDesc is the grpc.ServiceDesc for GuestService service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -215,6 +743,50 @@ var GuestService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "StatPath",
 			Handler:    _GuestService_StatPath_Handler,
 		},
+		{
+			MethodName: "GetAgentInfo",
+			Handler:    _GuestService_GetAgentInfo_Handler,
+		},
+		{
+			MethodName: "StartProcess",
+			Handler:    _GuestService_StartProcess_Handler,
+		},
+		{
+			MethodName: "StopProcess",
+			Handler:    _GuestService_StopProcess_Handler,
+		},
+		{
+			MethodName: "RestartProcess",
+			Handler:    _GuestService_RestartProcess_Handler,
+		},
+		{
+			MethodName: "ListProcesses",
+			Handler:    _GuestService_ListProcesses_Handler,
+		},
+		{
+			MethodName: "PutSecrets",
+			Handler:    _GuestService_PutSecrets_Handler,
+		},
+		{
+			MethodName: "ListGuestProcesses",
+			Handler:    _GuestService_ListGuestProcesses_Handler,
+		},
+		{
+			MethodName: "GetAppStatus",
+			Handler:    _GuestService_GetAppStatus_Handler,
+		},
+		{
+			MethodName: "StopApp",
+			Handler:    _GuestService_StopApp_Handler,
+		},
+		{
+			MethodName: "SyncClock",
+			Handler:    _GuestService_SyncClock_Handler,
+		},
+		{
+			MethodName: "UpdateLinks",
+			Handler:    _GuestService_UpdateLinks_Handler,
+		},
 	},
 	Streams: []grpc.StreamDesc{
 		{
@@ -233,6 +805,27 @@ var GuestService_ServiceDesc = grpc.ServiceDesc{
 			Handler:       _GuestService_CopyFromGuest_Handler,
 			ServerStreams: true,
 		},
+		{
+			StreamName:    "UpdateAgent",
+			Handler:       _GuestService_UpdateAgent_Handler,
+			ClientStreams: true,
+		},
+		{
+			StreamName:    "TailFile",
+			Handler:       _GuestService_TailFile_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "CopyArchiveToGuest",
+			Handler:       _GuestService_CopyArchiveToGuest_Handler,
+			ServerStreams: true,
+			ClientStreams: true,
+		},
+		{
+			StreamName:    "CopyArchiveFromGuest",
+			Handler:       _GuestService_CopyArchiveFromGuest_Handler,
+			ServerStreams: true,
+		},
 	},
 	Metadata: "lib/guest/guest.proto",
 }
@@ -0,0 +1,26 @@
+package guest
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/kernel/hypeman/lib/hypervisor"
+	"github.com/kernel/hypeman/lib/vmconfig"
+)
+
+// UpdateLinks replaces the linked peers' entries in the guest's /etc/hosts
+func UpdateLinks(ctx context.Context, dialer hypervisor.VsockDialer, links []vmconfig.HostEntry) error {
+	grpcConn, err := GetOrCreateConn(ctx, dialer)
+	if err != nil {
+		return fmt.Errorf("get grpc connection: %w", err)
+	}
+
+	req := &UpdateLinksRequest{}
+	for _, l := range links {
+		req.Links = append(req.Links, &LinkEntry{Hostname: l.Hostname, Ip: l.IP})
+	}
+	if _, err := NewGuestServiceClient(grpcConn).UpdateLinks(ctx, req); err != nil {
+		return fmt.Errorf("update links: %w", err)
+	}
+	return nil
+}
@@ -18,6 +18,9 @@ type Metrics struct {
 	cpSessionsTotal metric.Int64Counter
 	cpDuration      metric.Float64Histogram
 	cpBytesTotal    metric.Int64Counter
+
+	connsCreatedTotal     metric.Int64Counter
+	connsInvalidatedTotal metric.Int64Counter
 }
 
 // GuestMetrics is the global metrics instance for the guest package.
@@ -97,6 +100,48 @@ func NewMetrics(meter metric.Meter) (*Metrics, error) {
 		return nil, err
 	}
 
+	connsCreatedTotal, err := meter.Int64Counter(
+		"hypeman_guest_connections_created_total",
+		metric.WithDescription("Total number of guest-agent connections created"),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	connsInvalidatedTotal, err := meter.Int64Counter(
+		"hypeman_guest_connections_invalidated_total",
+		metric.WithDescription("Total number of guest-agent connections dropped from the pool"),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	// Register observable gauges
+	conns, err := meter.Int64ObservableGauge(
+		"hypeman_guest_connections",
+		metric.WithDescription("Pooled guest-agent connections by gRPC connectivity state"),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = meter.RegisterCallback(
+		func(ctx context.Context, o metric.Observer) error {
+			counts := make(map[string]int64)
+			for _, c := range PoolConns() {
+				counts[c.State]++
+			}
+			for state, n := range counts {
+				o.ObserveInt64(conns, n, metric.WithAttributes(attribute.String("state", state)))
+			}
+			return nil
+		},
+		conns,
+	)
+	if err != nil {
+		return nil, err
+	}
+
 	return &Metrics{
 		execSessionsTotal:      execSessionsTotal,
 		execDuration:           execDuration,
@@ -105,6 +150,8 @@ func NewMetrics(meter metric.Meter) (*Metrics, error) {
 		cpSessionsTotal:        cpSessionsTotal,
 		cpDuration:             cpDuration,
 		cpBytesTotal:           cpBytesTotal,
+		connsCreatedTotal:      connsCreatedTotal,
+		connsInvalidatedTotal:  connsInvalidatedTotal,
 	}, nil
 }
 
@@ -170,3 +217,20 @@ func (m *Metrics) RecordCpSession(ctx context.Context, start time.Time, directio
 	}
 }
 
+// recordConnCreated records a new pooled guest-agent connection.
+func (m *Metrics) recordConnCreated(ctx context.Context) {
+	if m == nil {
+		return
+	}
+	m.connsCreatedTotal.Add(ctx, 1)
+}
+
+// recordConnInvalidated records a guest-agent connection dropped from the
+// pool, with the reason (InvalidateClosed or InvalidateUnhealthy).
+func (m *Metrics) recordConnInvalidated(ctx context.Context, reason string) {
+	if m == nil {
+		return
+	}
+	m.connsInvalidatedTotal.Add(ctx, 1,
+		metric.WithAttributes(attribute.String("reason", reason)))
+}
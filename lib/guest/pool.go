@@ -0,0 +1,162 @@
+package guest
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/kernel/hypeman/lib/hypervisor"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/connectivity"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/keepalive"
+)
+
+const (
+	// keepaliveTime is how often a pooled connection pings the guest agent,
+	// so a connection broken by standby, restore or a guest reboot is noticed
+	// before the next call rather than by it. The agent permits pings this
+	// often (see lib/system/guest_agent/main.go).
+	keepaliveTime = 30 * time.Second
+
+	// keepaliveTimeout is how long a ping may go unanswered before the
+	// connection is closed
+	keepaliveTimeout = 10 * time.Second
+)
+
+// Reasons a pooled connection is dropped, reported in metrics
+const (
+	InvalidateClosed    = "closed"    // The instance stopped, went to standby, restarted or was deleted
+	InvalidateUnhealthy = "unhealthy" // Found broken when checked out
+)
+
+// connPool manages reusable gRPC connections per vsock dialer key
+// This avoids the overhead and potential issues of rapidly creating/closing connections
+var connPool = struct {
+	sync.RWMutex
+	conns map[string]*pooledConn
+}{
+	conns: make(map[string]*pooledConn),
+}
+
+// pooledConn is a gRPC connection held by the pool
+type pooledConn struct {
+	conn    *grpc.ClientConn
+	created time.Time
+}
+
+// healthy reports whether a pooled connection can still be used. A
+// connection in TRANSIENT_FAILURE would only be retried after gRPC's
+// reconnect backoff, which grows up to minutes, so it's replaced instead.
+func (p *pooledConn) healthy() bool {
+	switch p.conn.GetState() {
+	case connectivity.TransientFailure, connectivity.Shutdown:
+		return false
+	default:
+		return true
+	}
+}
+
+// ConnInfo describes a pooled guest-agent connection
+type ConnInfo struct {
+	Key     string    `json:"key"`     // Dialer key (vsock socket path or CID)
+	State   string    `json:"state"`   // gRPC connectivity state, e.g. "READY", "IDLE"
+	Created time.Time `json:"created"` // When the connection was created
+}
+
+// GetOrCreateConn returns an existing connection or creates a new one using a VsockDialer.
+// This supports multiple hypervisor types (Cloud Hypervisor, QEMU, etc.).
+// A pooled connection found broken is replaced by a new one.
+func GetOrCreateConn(ctx context.Context, dialer hypervisor.VsockDialer) (*grpc.ClientConn, error) {
+	key := dialer.Key()
+
+	// Try read lock first for existing connection
+	connPool.RLock()
+	if pc, ok := connPool.conns[key]; ok && pc.healthy() {
+		connPool.RUnlock()
+		return pc.conn, nil
+	}
+	connPool.RUnlock()
+
+	// Need to create new connection - acquire write lock
+	connPool.Lock()
+	defer connPool.Unlock()
+
+	// Double-check after acquiring write lock
+	if pc, ok := connPool.conns[key]; ok {
+		if pc.healthy() {
+			return pc.conn, nil
+		}
+		slog.Debug("replacing unhealthy gRPC connection", "key", key, "state", pc.conn.GetState().String())
+		dropConn(ctx, key, pc, InvalidateUnhealthy)
+	}
+
+	// Create new connection using the VsockDialer
+	conn, err := grpc.Dial("passthrough:///vsock",
+		grpc.WithContextDialer(func(ctx context.Context, addr string) (net.Conn, error) {
+			netConn, err := dialer.DialVsock(ctx, vsockGuestPort)
+			if err != nil {
+				return nil, &AgentVSockDialError{Err: err}
+			}
+			return netConn, nil
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithKeepaliveParams(keepalive.ClientParameters{
+			Time:                keepaliveTime,
+			Timeout:             keepaliveTimeout,
+			PermitWithoutStream: true,
+		}),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("create grpc connection: %w", err)
+	}
+
+	connPool.conns[key] = &pooledConn{conn: conn, created: time.Now()}
+	GuestMetrics.recordConnCreated(ctx)
+	slog.Debug("created new gRPC connection", "key", key)
+	return conn, nil
+}
+
+// CloseConn removes a connection from the pool by key and closes it. Call it
+// whenever the guest agent at the other end goes away or is replaced: when
+// the instance stops, goes to standby, is restored, restarts or is deleted.
+// Calls still in flight on the connection fail.
+func CloseConn(dialerKey string) {
+	connPool.Lock()
+	defer connPool.Unlock()
+
+	if pc, ok := connPool.conns[dialerKey]; ok {
+		dropConn(context.Background(), dialerKey, pc, InvalidateClosed)
+		slog.Debug("removed gRPC connection from pool", "key", dialerKey)
+	}
+}
+
+// dropConn removes a connection from the pool and closes it. The caller
+// holds the pool's write lock.
+func dropConn(ctx context.Context, key string, pc *pooledConn, reason string) {
+	delete(connPool.conns, key)
+	pc.conn.Close()
+	GuestMetrics.recordConnInvalidated(ctx, reason)
+}
+
+// PoolSize returns the number of pooled guest-agent connections
+func PoolSize() int {
+	connPool.RLock()
+	defer connPool.RUnlock()
+	return len(connPool.conns)
+}
+
+// PoolConns describes the pooled guest-agent connections
+func PoolConns() []ConnInfo {
+	connPool.RLock()
+	defer connPool.RUnlock()
+
+	conns := make([]ConnInfo, 0, len(connPool.conns))
+	for key, pc := range connPool.conns {
+		conns = append(conns, ConnInfo{Key: key, State: pc.conn.GetState().String(), Created: pc.created})
+	}
+	return conns
+}
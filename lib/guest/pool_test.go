@@ -0,0 +1,57 @@
+package guest
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/connectivity"
+)
+
+// unreachableDialer is a VsockDialer whose guest never answers
+type unreachableDialer struct{ key string }
+
+func (d unreachableDialer) DialVsock(ctx context.Context, port int) (net.Conn, error) {
+	return nil, errors.New("connection refused")
+}
+
+func (d unreachableDialer) Key() string { return d.key }
+
+func TestConnPool(t *testing.T) {
+	ctx := context.Background()
+	dialer := unreachableDialer{key: "test-pool"}
+	t.Cleanup(func() { CloseConn(dialer.Key()) })
+
+	conn, err := GetOrCreateConn(ctx, dialer)
+	require.NoError(t, err)
+	again, err := GetOrCreateConn(ctx, dialer)
+	require.NoError(t, err)
+	assert.Same(t, conn, again, "connection is reused")
+
+	var found bool
+	for _, c := range PoolConns() {
+		found = found || c.Key == dialer.Key()
+	}
+	assert.True(t, found)
+
+	// A connection that failed is replaced instead of waiting out gRPC's backoff
+	conn.Connect()
+	require.Eventually(t, func() bool {
+		return conn.GetState() == connectivity.TransientFailure
+	}, 5*time.Second, 10*time.Millisecond)
+	replaced, err := GetOrCreateConn(ctx, dialer)
+	require.NoError(t, err)
+	assert.NotSame(t, conn, replaced)
+	assert.Equal(t, connectivity.Shutdown, conn.GetState(), "replaced connection is closed")
+
+	// Closing drops and closes the connection
+	CloseConn(dialer.Key())
+	assert.Equal(t, connectivity.Shutdown, replaced.GetState())
+	for _, c := range PoolConns() {
+		assert.NotEqual(t, dialer.Key(), c.Key)
+	}
+}
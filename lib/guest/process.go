@@ -0,0 +1,132 @@
+package guest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/kernel/hypeman/lib/hypervisor"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var (
+	// ErrProcessNotFound is returned when no supervised process has the given name
+	ErrProcessNotFound = errors.New("process not found")
+	// ErrProcessRunning is returned when starting a process whose name is already running
+	ErrProcessRunning = errors.New("process already running")
+	// ErrInvalidProcess is returned when the guest rejects a process spec or cannot start it
+	ErrInvalidProcess = errors.New("invalid process")
+)
+
+// ProcessSpec describes a supervised process to start in the guest
+type ProcessSpec struct {
+	Name          string            // Unique process name
+	Command       []string          // Command and arguments
+	Env           map[string]string // Environment variables, merged over the agent's
+	Cwd           string            // Working directory (optional)
+	RestartPolicy string            // "never" (default), "on-failure" or "always"
+}
+
+// StartProcess starts a supervised process in the guest
+func StartProcess(ctx context.Context, dialer hypervisor.VsockDialer, spec ProcessSpec) (*ProcessInfo, error) {
+	client, err := processClient(ctx, dialer)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := client.StartProcess(ctx, &StartProcessRequest{
+		Name:          spec.Name,
+		Command:       spec.Command,
+		Env:           spec.Env,
+		Cwd:           spec.Cwd,
+		RestartPolicy: spec.RestartPolicy,
+	})
+	if err != nil {
+		return nil, processError("start process", err)
+	}
+	return resp.Process, nil
+}
+
+// StopProcess stops a supervised process, sending SIGKILL if it is still
+// running after timeout (0 = guest default)
+func StopProcess(ctx context.Context, dialer hypervisor.VsockDialer, name string, timeout time.Duration) (*ProcessInfo, error) {
+	client, err := processClient(ctx, dialer)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := client.StopProcess(ctx, &StopProcessRequest{
+		Name:           name,
+		TimeoutSeconds: int32(timeout / time.Second),
+	})
+	if err != nil {
+		return nil, processError("stop process", err)
+	}
+	return resp.Process, nil
+}
+
+// RestartProcess stops a supervised process and starts it again
+func RestartProcess(ctx context.Context, dialer hypervisor.VsockDialer, name string, timeout time.Duration) (*ProcessInfo, error) {
+	client, err := processClient(ctx, dialer)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := client.RestartProcess(ctx, &RestartProcessRequest{
+		Name:           name,
+		TimeoutSeconds: int32(timeout / time.Second),
+	})
+	if err != nil {
+		return nil, processError("restart process", err)
+	}
+	return resp.Process, nil
+}
+
+// ListProcesses lists the supervised processes in the guest
+func ListProcesses(ctx context.Context, dialer hypervisor.VsockDialer) ([]*ProcessInfo, error) {
+	client, err := processClient(ctx, dialer)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := client.ListProcesses(ctx, &ListProcessesRequest{})
+	if err != nil {
+		return nil, processError("list processes", err)
+	}
+	return resp.Processes, nil
+}
+
+// ListGuestProcesses returns a snapshot of all processes running in the
+// guest, supervised or not
+func ListGuestProcesses(ctx context.Context, dialer hypervisor.VsockDialer) ([]*GuestProcess, error) {
+	client, err := processClient(ctx, dialer)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := client.ListGuestProcesses(ctx, &ListGuestProcessesRequest{})
+	if err != nil {
+		return nil, fmt.Errorf("list guest processes: %w", err)
+	}
+	return resp.Processes, nil
+}
+
+func processClient(ctx context.Context, dialer hypervisor.VsockDialer) (GuestServiceClient, error) {
+	grpcConn, err := GetOrCreateConn(ctx, dialer)
+	if err != nil {
+		return nil, fmt.Errorf("get grpc connection: %w", err)
+	}
+	return NewGuestServiceClient(grpcConn), nil
+}
+
+// processError maps guest status codes to the package's process errors
+func processError(op string, err error) error {
+	if s, ok := status.FromError(err); ok {
+		switch s.Code() {
+		case codes.NotFound:
+			return fmt.Errorf("%w: %s", ErrProcessNotFound, s.Message())
+		case codes.AlreadyExists:
+			return fmt.Errorf("%w: %s", ErrProcessRunning, s.Message())
+		case codes.InvalidArgument, codes.FailedPrecondition:
+			return fmt.Errorf("%w: %s", ErrInvalidProcess, s.Message())
+		}
+	}
+	return fmt.Errorf("%s: %w", op, err)
+}
@@ -0,0 +1,51 @@
+package guest
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/kernel/hypeman/lib/hypervisor"
+)
+
+// SecretsDir is the tmpfs in the guest holding secrets exposed as files
+const SecretsDir = "/run/secrets"
+
+// SecretsEnvFile is where the guest agent leaves secrets exposed as
+// environment variables for init to pick up and remove
+const SecretsEnvFile = SecretsDir + "/.env.json"
+
+// PutSecrets delivers an instance's secrets to its guest agent. Files are
+// written under SecretsDir; env is handed to the application. If
+// waitForAgent is set, it retries on connection errors until the timeout,
+// since secrets are delivered while the guest is still booting.
+func PutSecrets(ctx context.Context, dialer hypervisor.VsockDialer, files map[string][]byte, env map[string]string, waitForAgent time.Duration) error {
+	deadline := time.Now().Add(waitForAgent)
+	for {
+		err := putSecretsOnce(ctx, dialer, files, env)
+		if err == nil || !isRetryableConnectionError(err) || time.Now().After(deadline) {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(500 * time.Millisecond):
+		}
+	}
+}
+
+func putSecretsOnce(ctx context.Context, dialer hypervisor.VsockDialer, files map[string][]byte, env map[string]string) error {
+	grpcConn, err := GetOrCreateConn(ctx, dialer)
+	if err != nil {
+		return fmt.Errorf("get grpc connection: %w", err)
+	}
+
+	req := &PutSecretsRequest{Env: env}
+	for name, value := range files {
+		req.Files = append(req.Files, &SecretFile{Name: name, Value: value})
+	}
+	if _, err := NewGuestServiceClient(grpcConn).PutSecrets(ctx, req); err != nil {
+		return fmt.Errorf("put secrets: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,76 @@
+package guest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+
+	"github.com/kernel/hypeman/lib/hypervisor"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var (
+	// ErrFileNotFound is returned when the file to tail does not exist in the guest
+	ErrFileNotFound = errors.New("file not found")
+	// ErrInvalidTailPath is returned when the path is not absolute or not a regular file
+	ErrInvalidTailPath = errors.New("invalid path")
+)
+
+// TailFile streams the last lines of a guest file. With follow, lines appended
+// later are streamed until ctx is cancelled. The channel is closed when the
+// stream ends. Errors opening the file are returned before streaming starts.
+func TailFile(ctx context.Context, dialer hypervisor.VsockDialer, path string, lines int, follow bool) (<-chan string, error) {
+	grpcConn, err := GetOrCreateConn(ctx, dialer)
+	if err != nil {
+		return nil, fmt.Errorf("get grpc connection: %w", err)
+	}
+
+	stream, err := NewGuestServiceClient(grpcConn).TailFile(ctx, &TailFileRequest{
+		Path:   path,
+		Lines:  int32(lines),
+		Follow: follow,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("start tail stream: %w", err)
+	}
+
+	// The guest answers once the file is open, so open errors surface here
+	first, err := stream.Recv()
+	if err != nil {
+		if s, ok := status.FromError(err); ok {
+			switch s.Code() {
+			case codes.NotFound:
+				return nil, fmt.Errorf("%w: %s", ErrFileNotFound, path)
+			case codes.InvalidArgument, codes.FailedPrecondition:
+				return nil, fmt.Errorf("%w: %s", ErrInvalidTailPath, s.Message())
+			}
+		}
+		return nil, fmt.Errorf("tail file: %w", err)
+	}
+
+	out := make(chan string, 100)
+	go func() {
+		defer close(out)
+		resp := first
+		for {
+			for _, line := range resp.Lines {
+				select {
+				case out <- line:
+				case <-ctx.Done():
+					return
+				}
+			}
+			resp, err = stream.Recv()
+			if err != nil {
+				if err != io.EOF && ctx.Err() == nil {
+					slog.Warn("tail stream ended", "path", path, "error", err)
+				}
+				return
+			}
+		}
+	}()
+	return out, nil
+}
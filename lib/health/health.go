@@ -0,0 +1,180 @@
+// Package health checks the subsystems the API server depends on, for the
+// liveness and readiness endpoints.
+package health
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// DefaultTimeout bounds each check, so one hung subsystem doesn't hold up
+// the whole report
+const DefaultTimeout = 2 * time.Second
+
+// Status of a component or of the server as a whole
+type Status string
+
+const (
+	// StatusOK means every check passed
+	StatusOK Status = "ok"
+	// StatusDegraded means an optional component failed; the server still
+	// serves requests, but some features don't work
+	StatusDegraded Status = "degraded"
+	// StatusUnavailable means a required component failed and the server
+	// shouldn't receive traffic
+	StatusUnavailable Status = "unavailable"
+)
+
+// CheckFunc checks a component and returns a short description of its state
+type CheckFunc func(ctx context.Context) (string, error)
+
+// Component is the result of one check
+type Component struct {
+	Name     string
+	Status   Status
+	Required bool
+	// Detail describes the component's state, or why the check failed
+	Detail string
+}
+
+// Report is the result of running every check
+type Report struct {
+	Status     Status
+	Components []Component // in registration order
+	CheckedAt  time.Time
+}
+
+type check struct {
+	name     string
+	required bool
+	run      CheckFunc
+}
+
+// Checker runs the registered checks
+type Checker struct {
+	timeout time.Duration
+
+	mu     sync.Mutex
+	checks []check
+}
+
+// NewChecker creates a checker with no checks. timeout <= 0 uses DefaultTimeout.
+func NewChecker(timeout time.Duration) *Checker {
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	return &Checker{timeout: timeout}
+}
+
+// Register adds a check. A failed required check makes the server
+// unavailable; a failed optional one only degrades it.
+func (c *Checker) Register(name string, required bool, run CheckFunc) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.checks = append(c.checks, check{name: name, required: required, run: run})
+}
+
+// Run runs every check concurrently and reports the results
+func (c *Checker) Run(ctx context.Context) Report {
+	c.mu.Lock()
+	checks := append([]check(nil), c.checks...)
+	c.mu.Unlock()
+
+	components := make([]Component, len(checks))
+	var wg sync.WaitGroup
+	for i, chk := range checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			components[i] = c.runCheck(ctx, chk)
+		}()
+	}
+	wg.Wait()
+
+	report := Report{Status: StatusOK, Components: components, CheckedAt: time.Now()}
+	for _, comp := range components {
+		switch {
+		case comp.Status == StatusOK:
+		case comp.Required:
+			report.Status = StatusUnavailable
+		case report.Status == StatusOK:
+			report.Status = StatusDegraded
+		}
+	}
+	return report
+}
+
+// runCheck runs one check within the timeout
+func (c *Checker) runCheck(ctx context.Context, chk check) Component {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	type result struct {
+		detail string
+		err    error
+	}
+	done := make(chan result, 1)
+	go func() {
+		detail, err := chk.run(ctx)
+		done <- result{detail, err}
+	}()
+
+	comp := Component{Name: chk.name, Required: chk.required}
+	select {
+	case res := <-done:
+		comp.Status, comp.Detail = StatusOK, res.detail
+		if res.err != nil {
+			comp.Status, comp.Detail = failedStatus(chk.required), res.err.Error()
+		}
+	case <-ctx.Done():
+		comp.Status = failedStatus(chk.required)
+		comp.Detail = fmt.Sprintf("check timed out after %s", c.timeout)
+	}
+	return comp
+}
+
+func failedStatus(required bool) Status {
+	if required {
+		return StatusUnavailable
+	}
+	return StatusDegraded
+}
+
+// DeviceCheck returns a check that opens a device node read-write, e.g.
+// /dev/kvm
+func DeviceCheck(path string) CheckFunc {
+	return func(ctx context.Context) (string, error) {
+		f, err := os.OpenFile(path, os.O_RDWR, 0)
+		if err != nil {
+			if os.IsNotExist(err) {
+				return "", fmt.Errorf("%s not found", path)
+			}
+			if os.IsPermission(err) {
+				return "", fmt.Errorf("permission denied accessing %s", path)
+			}
+			return "", fmt.Errorf("cannot access %s: %w", path, err)
+		}
+		f.Close()
+		return path + " accessible", nil
+	}
+}
+
+// WritableCheck returns a check that creates and removes a file in dir
+func WritableCheck(dir string) CheckFunc {
+	return func(ctx context.Context) (string, error) {
+		f, err := os.CreateTemp(dir, ".health-*")
+		if err != nil {
+			return "", fmt.Errorf("%s not writable: %w", dir, err)
+		}
+		name := f.Name()
+		f.Close()
+		if err := os.Remove(name); err != nil {
+			return "", fmt.Errorf("remove %s: %w", filepath.Base(name), err)
+		}
+		return dir + " writable", nil
+	}
+}
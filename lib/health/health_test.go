@@ -0,0 +1,67 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func ok(detail string) CheckFunc {
+	return func(ctx context.Context) (string, error) { return detail, nil }
+}
+
+func fail(msg string) CheckFunc {
+	return func(ctx context.Context) (string, error) { return "", errors.New(msg) }
+}
+
+func TestRun(t *testing.T) {
+	c := NewChecker(0)
+	c.Register("kvm", true, ok("/dev/kvm accessible"))
+	c.Register("ingress", false, ok("caddy up"))
+
+	report := c.Run(context.Background())
+	assert.Equal(t, StatusOK, report.Status)
+	require.Len(t, report.Components, 2)
+	assert.Equal(t, Component{Name: "kvm", Status: StatusOK, Required: true, Detail: "/dev/kvm accessible"}, report.Components[0])
+	assert.Equal(t, "ingress", report.Components[1].Name)
+
+	// Optional failures degrade the server
+	c.Register("vsock", false, fail("/dev/vhost-vsock not found"))
+	report = c.Run(context.Background())
+	assert.Equal(t, StatusDegraded, report.Status)
+	assert.Equal(t, Component{Name: "vsock", Status: StatusDegraded, Detail: "/dev/vhost-vsock not found"}, report.Components[2])
+
+	// Required failures make it unavailable
+	c.Register("network", true, fail("bridge vmbr0: not found"))
+	report = c.Run(context.Background())
+	assert.Equal(t, StatusUnavailable, report.Status)
+	assert.Equal(t, StatusUnavailable, report.Components[3].Status)
+}
+
+func TestRun_Timeout(t *testing.T) {
+	c := NewChecker(20 * time.Millisecond)
+	c.Register("ingress", false, func(ctx context.Context) (string, error) {
+		time.Sleep(time.Second) // ignores ctx, like a hung syscall
+		return "late", nil
+	})
+
+	start := time.Now()
+	report := c.Run(context.Background())
+	assert.Less(t, time.Since(start), 500*time.Millisecond)
+	assert.Equal(t, StatusDegraded, report.Status)
+	assert.Contains(t, report.Components[0].Detail, "timed out")
+}
+
+func TestWritableCheck(t *testing.T) {
+	dir := t.TempDir()
+	detail, err := WritableCheck(dir)(context.Background())
+	require.NoError(t, err)
+	assert.Contains(t, detail, "writable")
+
+	_, err = WritableCheck(dir + "/missing")(context.Background())
+	assert.Error(t, err)
+}
@@ -39,6 +39,8 @@ func (c *CloudHypervisor) Capabilities() hypervisor.Capabilities {
 		SupportsVsock:          true,
 		SupportsGPUPassthrough: true,
 		SupportsDiskIOLimit:    true,
+		SupportsUSBPassthrough: false, // No USB controller emulation
+		SupportsCounters:       true,
 	}
 }
 
@@ -54,6 +56,18 @@ func (c *CloudHypervisor) DeleteVM(ctx context.Context) error {
 	return nil
 }
 
+// PowerButton presses the VM's ACPI power button.
+func (c *CloudHypervisor) PowerButton(ctx context.Context) error {
+	resp, err := c.client.PowerButtonVMWithResponse(ctx)
+	if err != nil {
+		return fmt.Errorf("power button: %w", err)
+	}
+	if resp.StatusCode() != 204 {
+		return fmt.Errorf("power button failed with status %d: %s", resp.StatusCode(), string(resp.Body))
+	}
+	return nil
+}
+
 // Shutdown stops the VMM process gracefully.
 func (c *CloudHypervisor) Shutdown(ctx context.Context) error {
 	resp, err := c.client.ShutdownVMMWithResponse(ctx)
@@ -201,3 +215,30 @@ func (c *CloudHypervisor) ResizeMemoryAndWait(ctx context.Context, bytes int64,
 	// Timeout reached, but resize was requested successfully
 	return nil
 }
+
+// AttachUSBDevice is not supported: Cloud Hypervisor has no USB controller.
+func (c *CloudHypervisor) AttachUSBDevice(ctx context.Context, device hypervisor.USBDeviceConfig) error {
+	return fmt.Errorf("USB passthrough not supported by Cloud Hypervisor")
+}
+
+// DetachUSBDevice is not supported: Cloud Hypervisor has no USB controller.
+func (c *CloudHypervisor) DetachUSBDevice(ctx context.Context, id string) error {
+	return fmt.Errorf("USB passthrough not supported by Cloud Hypervisor")
+}
+
+// ListUSBDevices is not supported: Cloud Hypervisor has no USB controller.
+func (c *CloudHypervisor) ListUSBDevices(ctx context.Context) ([]string, error) {
+	return nil, fmt.Errorf("USB passthrough not supported by Cloud Hypervisor")
+}
+
+// Counters returns the VM's device counters.
+func (c *CloudHypervisor) Counters(ctx context.Context) (hypervisor.Counters, error) {
+	resp, err := c.client.GetVmCountersWithResponse(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("get vm counters: %w", err)
+	}
+	if resp.StatusCode() != 200 || resp.JSON200 == nil {
+		return nil, fmt.Errorf("get vm counters failed with status %d", resp.StatusCode())
+	}
+	return hypervisor.Counters(*resp.JSON200), nil
+}
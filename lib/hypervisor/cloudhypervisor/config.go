@@ -7,17 +7,23 @@ import (
 
 // ToVMConfig converts hypervisor.VMConfig to Cloud Hypervisor's vmm.VmConfig.
 func ToVMConfig(cfg hypervisor.VMConfig) vmm.VmConfig {
-	// Payload configuration (kernel + initramfs)
+	// Payload configuration (kernel + initramfs, or UEFI firmware)
 	payload := vmm.PayloadConfig{
 		Kernel:    ptr(cfg.KernelPath),
 		Cmdline:   ptr(cfg.KernelArgs),
 		Initramfs: ptr(cfg.InitrdPath),
 	}
+	if cfg.Firmware != "" {
+		payload = vmm.PayloadConfig{Firmware: ptr(cfg.Firmware)}
+	}
 
 	// CPU configuration
 	cpus := vmm.CpusConfig{
 		BootVcpus: cfg.VCPUs,
 		MaxVcpus:  cfg.VCPUs,
+		// Cloud Hypervisor passes VMX/SVM through whenever the host nests
+		// unless told not to
+		Nested: ptr(cfg.NestedVirt),
 	}
 
 	// Add topology if provided
@@ -30,6 +36,15 @@ func ToVMConfig(cfg hypervisor.VMConfig) vmm.VmConfig {
 		}
 	}
 
+	// Pin each vCPU to the host CPUs
+	if len(cfg.HostCPUs) > 0 {
+		affinity := make([]vmm.CpuAffinity, cfg.VCPUs)
+		for i := range affinity {
+			affinity[i] = vmm.CpuAffinity{Vcpu: i, HostCpus: cfg.HostCPUs}
+		}
+		cpus.Affinity = &affinity
+	}
+
 	// Memory configuration
 	memory := vmm.MemoryConfig{
 		Size: cfg.MemoryBytes,
@@ -38,6 +53,10 @@ func ToVMConfig(cfg hypervisor.VMConfig) vmm.VmConfig {
 		memory.HotplugSize = &cfg.HotplugBytes
 		memory.HotplugMethod = ptr("VirtioMem")
 	}
+	// virtiofsd maps guest memory, so it must be shared
+	if len(cfg.FileSystems) > 0 {
+		memory.Shared = ptr(true)
+	}
 
 	// Disk configuration
 	disks := make([]vmm.DiskConfig, 0, len(cfg.Disks))
@@ -45,9 +64,13 @@ func ToVMConfig(cfg hypervisor.VMConfig) vmm.VmConfig {
 		disk := vmm.DiskConfig{
 			Path: ptr(d.Path),
 		}
-		if d.Readonly {
+		// Cloud Hypervisor has no CD-ROM device; d.CDROM disks are attached
+		// as read-only virtio disks
+		if d.Readonly || d.CDROM {
 			disk.Readonly = ptr(true)
 		}
+		// d.Discard isn't mapped: this API version has no discard setting,
+		// so space freed in the guest is reclaimed by the disk-trim task
 		if d.IOBps > 0 {
 			// Token bucket: Size is refilled every RefillTime ms
 			// Rate = Size / RefillTime * 1000 = Size bytes/sec (when RefillTime = 1000)
@@ -66,6 +89,21 @@ func ToVMConfig(cfg hypervisor.VMConfig) vmm.VmConfig {
 		disks = append(disks, disk)
 	}
 
+	// Shared directory configuration
+	var fs *[]vmm.FsConfig
+	if len(cfg.FileSystems) > 0 {
+		fsConfigs := make([]vmm.FsConfig, 0, len(cfg.FileSystems))
+		for _, f := range cfg.FileSystems {
+			fsConfigs = append(fsConfigs, vmm.FsConfig{
+				Tag:       f.Tag,
+				Socket:    f.SocketPath,
+				NumQueues: 1,
+				QueueSize: 1024,
+			})
+		}
+		fs = &fsConfigs
+	}
+
 	// Serial console configuration
 	serial := vmm.ConsoleConfig{
 		Mode: vmm.ConsoleConfigMode("File"),
@@ -77,18 +115,22 @@ func ToVMConfig(cfg hypervisor.VMConfig) vmm.VmConfig {
 		Mode: vmm.ConsoleConfigMode("Off"),
 	}
 
-	// Network configuration
+	// Network configuration. Macvtap devices are added separately, with
+	// their file descriptors (see macvtapNetConfig).
 	var nets *[]vmm.NetConfig
-	if len(cfg.Networks) > 0 {
-		netConfigs := make([]vmm.NetConfig, 0, len(cfg.Networks))
-		for _, n := range cfg.Networks {
-			netConfigs = append(netConfigs, vmm.NetConfig{
-				Tap:  ptr(n.TAPDevice),
-				Ip:   ptr(n.IP),
-				Mac:  ptr(n.MAC),
-				Mask: ptr(n.Netmask),
-			})
+	var netConfigs []vmm.NetConfig
+	for _, n := range cfg.Networks {
+		if n.Macvtap {
+			continue
 		}
+		netConfigs = append(netConfigs, vmm.NetConfig{
+			Tap:  ptr(n.TAPDevice),
+			Ip:   ptr(n.IP),
+			Mac:  ptr(n.MAC),
+			Mask: ptr(n.Netmask),
+		})
+	}
+	if len(netConfigs) > 0 {
 		nets = &netConfigs
 	}
 
@@ -118,6 +160,7 @@ func ToVMConfig(cfg hypervisor.VMConfig) vmm.VmConfig {
 		Cpus:    &cpus,
 		Memory:  &memory,
 		Disks:   &disks,
+		Fs:      fs,
 		Serial:  &serial,
 		Console: &console,
 		Net:     nets,
@@ -125,3 +168,15 @@ func ToVMConfig(cfg hypervisor.VMConfig) vmm.VmConfig {
 		Devices: devices,
 	}
 }
+
+// macvtapNetConfig is the config of a network on a macvtap device, added with
+// vm.add-net along with the device's file descriptor. The device is named
+// after the macvtap interface, so a restore can reopen it (see snapshotMacvtaps).
+func macvtapNetConfig(n hypervisor.NetworkConfig) vmm.NetConfig {
+	return vmm.NetConfig{
+		Id:   ptr(n.TAPDevice),
+		Ip:   ptr(n.IP),
+		Mac:  ptr(n.MAC),
+		Mask: ptr(n.Netmask),
+	}
+}
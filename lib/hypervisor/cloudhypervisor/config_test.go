@@ -0,0 +1,38 @@
+package cloudhypervisor
+
+import (
+	"testing"
+
+	"github.com/kernel/hypeman/lib/hypervisor"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestToVMConfig_NestedVirt(t *testing.T) {
+	for _, nested := range []bool{true, false} {
+		cfg := ToVMConfig(hypervisor.VMConfig{VCPUs: 2, MemoryBytes: 1 << 30, NestedVirt: nested})
+		require.NotNil(t, cfg.Cpus)
+		require.NotNil(t, cfg.Cpus.Nested, "nested must be explicit: Cloud Hypervisor defaults to exposing VMX/SVM")
+		assert.Equal(t, nested, *cfg.Cpus.Nested)
+	}
+}
+
+func TestToVMConfig_Macvtap(t *testing.T) {
+	tap := hypervisor.NetworkConfig{TAPDevice: "hype-tap", MAC: "02:00:00:00:00:01", IP: "10.100.0.2", Netmask: "255.255.0.0"}
+	macvtap := hypervisor.NetworkConfig{TAPDevice: "hype-mvtap", MAC: "02:00:00:00:00:02", IP: "192.168.40.10", Netmask: "255.255.255.0", Macvtap: true}
+
+	// Macvtap devices are left for vm.add-net, which takes their descriptors
+	cfg := ToVMConfig(hypervisor.VMConfig{VCPUs: 1, MemoryBytes: 1 << 30, Networks: []hypervisor.NetworkConfig{tap, macvtap}})
+	require.NotNil(t, cfg.Net)
+	require.Len(t, *cfg.Net, 1)
+	assert.Equal(t, "hype-tap", *(*cfg.Net)[0].Tap)
+
+	cfg = ToVMConfig(hypervisor.VMConfig{VCPUs: 1, MemoryBytes: 1 << 30, Networks: []hypervisor.NetworkConfig{macvtap}})
+	assert.Nil(t, cfg.Net)
+
+	// Named after the device, so a restore can reopen it
+	net := macvtapNetConfig(macvtap)
+	assert.Equal(t, "hype-mvtap", *net.Id)
+	assert.Nil(t, net.Tap)
+	assert.Equal(t, "02:00:00:00:00:02", *net.Mac)
+}
@@ -2,7 +2,10 @@ package cloudhypervisor
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"os"
+	"path/filepath"
 	"syscall"
 	"time"
 
@@ -50,7 +53,7 @@ func (s *Starter) GetVersion(p *paths.Paths) (string, error) {
 
 // StartVM launches Cloud Hypervisor, configures the VM, and boots it.
 // Returns the process ID and a Hypervisor client for subsequent operations.
-func (s *Starter) StartVM(ctx context.Context, p *paths.Paths, version string, socketPath string, config hypervisor.VMConfig) (int, hypervisor.Hypervisor, error) {
+func (s *Starter) StartVM(ctx context.Context, p *paths.Paths, version string, socketPath string, config hypervisor.VMConfig, opts hypervisor.ProcessOptions) (int, hypervisor.Hypervisor, error) {
 	// Validate version
 	chVersion := vmm.CHVersion(version)
 	if !vmm.IsVersionSupported(chVersion) {
@@ -58,7 +61,7 @@ func (s *Starter) StartVM(ctx context.Context, p *paths.Paths, version string, s
 	}
 
 	// 1. Start the Cloud Hypervisor process
-	pid, err := vmm.StartProcess(ctx, p, chVersion, socketPath)
+	pid, err := vmm.StartProcessInCgroup(ctx, p, chVersion, socketPath, opts.CgroupDir, processArgs(socketPath, opts))
 	if err != nil {
 		return 0, nil, fmt.Errorf("start process: %w", err)
 	}
@@ -85,7 +88,17 @@ func (s *Starter) StartVM(ctx context.Context, p *paths.Paths, version string, s
 		return 0, nil, fmt.Errorf("create vm failed with status %d: %s", resp.StatusCode(), string(resp.Body))
 	}
 
-	// 4. Boot the VM via HTTP API
+	// 4. Attach macvtap devices, which Cloud Hypervisor only takes as file
+	// descriptors. Before boot they're cold-added to the VM config.
+	for _, n := range config.Networks {
+		if n.Macvtap {
+			if err := addMacvtap(ctx, hv, n); err != nil {
+				return 0, nil, err
+			}
+		}
+	}
+
+	// 5. Boot the VM via HTTP API
 	bootResp, err := hv.client.BootVMWithResponse(ctx)
 	if err != nil {
 		return 0, nil, fmt.Errorf("boot vm: %w", err)
@@ -101,7 +114,7 @@ func (s *Starter) StartVM(ctx context.Context, p *paths.Paths, version string, s
 
 // RestoreVM starts Cloud Hypervisor and restores VM state from a snapshot.
 // The VM is in paused state after restore; caller should call Resume() to continue execution.
-func (s *Starter) RestoreVM(ctx context.Context, p *paths.Paths, version string, socketPath string, snapshotPath string) (int, hypervisor.Hypervisor, error) {
+func (s *Starter) RestoreVM(ctx context.Context, p *paths.Paths, version string, socketPath string, snapshotPath string, opts hypervisor.ProcessOptions) (int, hypervisor.Hypervisor, error) {
 	log := logger.FromContext(ctx)
 	startTime := time.Now()
 
@@ -113,7 +126,7 @@ func (s *Starter) RestoreVM(ctx context.Context, p *paths.Paths, version string,
 
 	// 1. Start the Cloud Hypervisor process
 	processStartTime := time.Now()
-	pid, err := vmm.StartProcess(ctx, p, chVersion, socketPath)
+	pid, err := vmm.StartProcessInCgroup(ctx, p, chVersion, socketPath, opts.CgroupDir, processArgs(socketPath, opts))
 	if err != nil {
 		return 0, nil, fmt.Errorf("start process: %w", err)
 	}
@@ -138,12 +151,35 @@ func (s *Starter) RestoreVM(ctx context.Context, p *paths.Paths, version string,
 		SourceUrl: sourceURL,
 		Prefault:  ptr(false),
 	}
-	resp, err := hv.client.PutVmRestoreWithResponse(ctx, restoreConfig)
+	// Macvtap devices were recreated for the restore and need new descriptors
+	macvtaps, err := snapshotMacvtaps(snapshotPath)
 	if err != nil {
-		return 0, nil, fmt.Errorf("restore: %w", err)
+		return 0, nil, err
 	}
-	if resp.StatusCode() != 204 {
-		return 0, nil, fmt.Errorf("restore failed with status %d: %s", resp.StatusCode(), string(resp.Body))
+	if len(macvtaps) > 0 {
+		var files []*os.File
+		defer func() { hypervisor.CloseFiles(files) }()
+		netFds := make([]vmm.RestoredNetConfig, 0, len(macvtaps))
+		for _, name := range macvtaps {
+			f, err := hypervisor.OpenMacvtap(name)
+			if err != nil {
+				return 0, nil, err
+			}
+			files = append(files, f)
+			netFds = append(netFds, vmm.RestoredNetConfig{Id: name, NumFds: 1})
+		}
+		restoreConfig.NetFds = &netFds
+		if err := hv.client.PutWithFiles(ctx, "/vm.restore", restoreConfig, files); err != nil {
+			return 0, nil, fmt.Errorf("restore: %w", err)
+		}
+	} else {
+		resp, err := hv.client.PutVmRestoreWithResponse(ctx, restoreConfig)
+		if err != nil {
+			return 0, nil, fmt.Errorf("restore: %w", err)
+		}
+		if resp.StatusCode() != 204 {
+			return 0, nil, fmt.Errorf("restore failed with status %d: %s", resp.StatusCode(), string(resp.Body))
+		}
 	}
 	log.DebugContext(ctx, "CH restore API complete", "duration_ms", time.Since(restoreAPIStart).Milliseconds())
 
@@ -153,6 +189,73 @@ func (s *Starter) RestoreVM(ctx context.Context, p *paths.Paths, version string,
 	return pid, hv, nil
 }
 
+// addMacvtap adds a network on a macvtap device to the VM, sending the
+// device's file descriptor with the request.
+func addMacvtap(ctx context.Context, hv *CloudHypervisor, n hypervisor.NetworkConfig) error {
+	f, err := hypervisor.OpenMacvtap(n.TAPDevice)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if err := hv.client.PutWithFiles(ctx, "/vm.add-net", macvtapNetConfig(n), []*os.File{f}); err != nil {
+		return fmt.Errorf("add macvtap %s: %w", n.TAPDevice, err)
+	}
+	return nil
+}
+
+// snapshotMacvtaps returns the names of the macvtap devices a snapshot's VM
+// was attached to: the network devices in its config without a TAP device or
+// vhost-user socket, which were handed over as file descriptors.
+func snapshotMacvtaps(snapshotDir string) ([]string, error) {
+	data, err := os.ReadFile(filepath.Join(snapshotDir, "config.json"))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read snapshot config: %w", err)
+	}
+	var cfg struct {
+		Net []struct {
+			ID        string  `json:"id"`
+			Tap       *string `json:"tap"`
+			VhostUser bool    `json:"vhost_user"`
+		} `json:"net"`
+	}
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("parse snapshot config: %w", err)
+	}
+	var names []string
+	for _, n := range cfg.Net {
+		if (n.Tap == nil || *n.Tap == "") && !n.VhostUser && n.ID != "" {
+			names = append(names, n.ID)
+		}
+	}
+	return names, nil
+}
+
+// processArgs returns the sandboxing flags of a Cloud Hypervisor process.
+// Seccomp is Cloud Hypervisor's default, but is set explicitly so it can't be
+// lost to a default change. Landlock limits the process to the files in its VM
+// config, which Cloud Hypervisor adds rules for itself, plus the instance
+// directory (sockets, logs, snapshots) and the devices it opens by name.
+func processArgs(socketPath string, opts hypervisor.ProcessOptions) []string {
+	if !opts.Sandbox {
+		return nil
+	}
+	args := []string{"--seccomp", "true", "--landlock"}
+	rules := []string{
+		fmt.Sprintf("path=%s,access=rw", filepath.Dir(socketPath)),
+		"path=/dev/net/tun,access=rw",
+	}
+	if _, err := os.Stat("/dev/vfio"); err == nil {
+		rules = append(rules, "path=/dev/vfio,access=rw")
+	}
+	for _, rule := range rules {
+		args = append(args, "--landlock-rules", rule)
+	}
+	return args
+}
+
 func ptr[T any](v T) *T {
 	return &v
 }
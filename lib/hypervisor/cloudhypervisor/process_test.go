@@ -0,0 +1,29 @@
+package cloudhypervisor
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSnapshotMacvtaps(t *testing.T) {
+	dir := t.TempDir()
+
+	// No config, e.g. a snapshot of another hypervisor version
+	names, err := snapshotMacvtaps(dir)
+	require.NoError(t, err)
+	assert.Empty(t, names)
+
+	config := `{"net": [
+		{"id": "_net0", "tap": "hype-abc", "vhost_user": false},
+		{"id": "hype-def", "tap": null, "fds": [-1], "vhost_user": false},
+		{"id": "_net2", "vhost_user": true, "vhost_socket": "/run/net.sock"}
+	]}`
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "config.json"), []byte(config), 0644))
+	names, err = snapshotMacvtaps(dir)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"hype-def"}, names)
+}
@@ -9,9 +9,20 @@ type VMConfig struct {
 	HotplugBytes int64
 	Topology     *CPUTopology
 
+	// NestedVirt exposes hardware virtualization (Intel VMX, AMD SVM) to the
+	// guest. Otherwise it's masked, even on hosts that allow nesting.
+	NestedVirt bool
+
+	// HostCPUs pins every vCPU to this set of host CPUs, e.g. those of
+	// one NUMA node. Empty leaves vCPUs unpinned.
+	HostCPUs []int
+
 	// Storage
 	Disks []DiskConfig
 
+	// Shared directories (virtio-fs, served by virtiofsd)
+	FileSystems []FileSystemConfig
+
 	// Network
 	Networks []NetworkConfig
 
@@ -25,10 +36,20 @@ type VMConfig struct {
 	// PCI device passthrough (GPU, etc.)
 	PCIDevices []string
 
+	// USB device passthrough (hardware keys, serial adapters, etc.).
+	// USBController adds the controller USB devices attach to, so they can
+	// also be hot-plugged later.
+	USBController bool
+	USBDevices    []USBDeviceConfig
+
 	// Boot configuration
 	KernelPath string
 	InitrdPath string
 	KernelArgs string
+
+	// Firmware boots the VM through UEFI firmware at this path instead of
+	// a kernel, e.g. for Windows guests. Kernel settings are ignored.
+	Firmware string
 }
 
 // CPUTopology defines the virtual CPU topology
@@ -45,6 +66,26 @@ type DiskConfig struct {
 	Readonly   bool
 	IOBps      int64 // Sustained I/O rate limit in bytes/sec (0 = unlimited)
 	IOBurstBps int64 // Burst I/O rate in bytes/sec (0 = same as IOBps)
+	Discard    bool  // Pass guest TRIM through, punching holes in the backing file
+	CDROM      bool  // Attach as a read-only CD-ROM (e.g. a drivers ISO) where supported
+}
+
+// FileSystemConfig represents a virtio-fs device backed by a vhost-user
+// daemon. Guest memory must be shared with the daemon.
+type FileSystemConfig struct {
+	Tag        string // Mount tag in the guest
+	SocketPath string // vhost-user socket of the daemon
+}
+
+// USBDeviceConfig represents a host USB device passed through to the VM,
+// selected either by VendorID and ProductID or by HostBus and HostPort.
+// The device may be absent: it's connected whenever it's plugged in.
+type USBDeviceConfig struct {
+	ID        string // Device ID in the hypervisor, used to detach it
+	VendorID  string // e.g., "1050"
+	ProductID string // e.g., "0407"
+	HostBus   int    // e.g., 1
+	HostPort  string // port path on the bus, e.g., "2.3"
 }
 
 // NetworkConfig represents a network interface attached to the VM
@@ -53,6 +94,11 @@ type NetworkConfig struct {
 	IP        string
 	MAC       string
 	Netmask   string
+
+	// Macvtap means TAPDevice is a macvtap device on a host NIC rather than
+	// a TAP device. It can't be attached by name: the hypervisor is handed
+	// its file descriptor (see OpenMacvtap).
+	Macvtap bool
 }
 
 // VMInfo contains current VM state information
@@ -60,14 +60,27 @@ type VMStarter interface {
 
 	// StartVM launches the hypervisor process and boots the VM.
 	// Returns the process ID and a Hypervisor client for subsequent operations.
-	StartVM(ctx context.Context, p *paths.Paths, version string, socketPath string, config VMConfig) (pid int, hv Hypervisor, err error)
+	StartVM(ctx context.Context, p *paths.Paths, version string, socketPath string, config VMConfig, opts ProcessOptions) (pid int, hv Hypervisor, err error)
 
 	// RestoreVM starts the hypervisor and restores VM state from a snapshot.
 	// Each hypervisor implements its own restore flow:
 	// - Cloud Hypervisor: starts process, calls Restore API
 	// - QEMU: would start with -incoming or -loadvm flags (not yet implemented)
 	// Returns the process ID and a Hypervisor client. The VM is in paused state after restore.
-	RestoreVM(ctx context.Context, p *paths.Paths, version string, socketPath string, snapshotPath string) (pid int, hv Hypervisor, err error)
+	RestoreVM(ctx context.Context, p *paths.Paths, version string, socketPath string, snapshotPath string, opts ProcessOptions) (pid int, hv Hypervisor, err error)
+}
+
+// ProcessOptions confines a hypervisor process, so a compromised VMM can't
+// starve the host or reach files beyond its own VM's.
+type ProcessOptions struct {
+	// CgroupDir is the cgroup v2 directory the process is started in. The
+	// process is cloned straight into it, so none of its usage is accounted
+	// elsewhere. Empty starts it in hypeman's own cgroup.
+	CgroupDir string
+
+	// Sandbox enables the hypervisor's own sandboxing: seccomp and Landlock
+	// for Cloud Hypervisor, -sandbox for QEMU.
+	Sandbox bool
 }
 
 // Hypervisor defines the interface for VM control operations.
@@ -79,6 +92,10 @@ type Hypervisor interface {
 	// Shutdown stops the VMM process gracefully.
 	Shutdown(ctx context.Context) error
 
+	// PowerButton presses the VM's ACPI power button, asking the guest OS to
+	// shut down. It returns once the event is delivered; the guest may ignore it.
+	PowerButton(ctx context.Context) error
+
 	// GetVMInfo returns current VM state information.
 	GetVMInfo(ctx context.Context) (*VMInfo, error)
 
@@ -103,10 +120,30 @@ type Hypervisor interface {
 	// Check Capabilities().SupportsHotplugMemory before calling.
 	ResizeMemoryAndWait(ctx context.Context, bytes int64, timeout time.Duration) error
 
+	// AttachUSBDevice hot-plugs a host USB device into the VM.
+	// Check Capabilities().SupportsUSBPassthrough before calling.
+	AttachUSBDevice(ctx context.Context, device USBDeviceConfig) error
+
+	// DetachUSBDevice hot-unplugs a USB device attached with the given ID.
+	// Check Capabilities().SupportsUSBPassthrough before calling.
+	DetachUSBDevice(ctx context.Context, id string) error
+
+	// ListUSBDevices returns the IDs of the USB devices attached to the VM.
+	// Check Capabilities().SupportsUSBPassthrough before calling.
+	ListUSBDevices(ctx context.Context) ([]string, error)
+
+	// Counters returns the VM's device counters.
+	// Check Capabilities().SupportsCounters before calling.
+	Counters(ctx context.Context) (Counters, error)
+
 	// Capabilities returns what features this hypervisor supports.
 	Capabilities() Capabilities
 }
 
+// Counters are a VM's device counters since it started, by device ID and
+// counter name, e.g. the rx_bytes and tx_bytes of network devices.
+type Counters map[string]map[string]int64
+
 // Capabilities indicates which optional features a hypervisor supports.
 // Callers should check these before calling optional methods.
 type Capabilities struct {
@@ -127,6 +164,13 @@ type Capabilities struct {
 
 	// SupportsDiskIOLimit indicates if disk I/O rate limiting is available
 	SupportsDiskIOLimit bool
+
+	// SupportsUSBPassthrough indicates if USB device passthrough and
+	// AttachUSBDevice/DetachUSBDevice are available
+	SupportsUSBPassthrough bool
+
+	// SupportsCounters indicates if Counters is available
+	SupportsCounters bool
 }
 
 // VsockDialer provides vsock connectivity to a guest VM.
@@ -0,0 +1,66 @@
+package hypervisor
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+
+	"golang.org/x/sys/unix"
+)
+
+// OpenMacvtap opens the character device of a macvtap interface, /dev/tapN
+// where N is its index. The node is created from the device number in sysfs
+// if udev hasn't created it.
+func OpenMacvtap(name string) (*os.File, error) {
+	iface, err := net.InterfaceByName(name)
+	if err != nil {
+		return nil, fmt.Errorf("find macvtap %s: %w", name, err)
+	}
+	path := fmt.Sprintf("/dev/tap%d", iface.Index)
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		data, err := os.ReadFile(fmt.Sprintf("/sys/class/net/%s/macvtap/tap%d/dev", name, iface.Index))
+		if err != nil {
+			return nil, fmt.Errorf("read device number of macvtap %s: %w", name, err)
+		}
+		var major, minor uint32
+		if _, err := fmt.Sscanf(strings.TrimSpace(string(data)), "%d:%d", &major, &minor); err != nil {
+			return nil, fmt.Errorf("parse device number of macvtap %s: %w", name, err)
+		}
+		if err := unix.Mknod(path, unix.S_IFCHR|0600, int(unix.Mkdev(major, minor))); err != nil && !errors.Is(err, unix.EEXIST) {
+			return nil, fmt.Errorf("create %s: %w", path, err)
+		}
+	}
+	f, err := os.OpenFile(path, os.O_RDWR, 0)
+	if err != nil {
+		return nil, fmt.Errorf("open macvtap %s: %w", name, err)
+	}
+	return f, nil
+}
+
+// OpenMacvtaps opens the macvtap devices of networks, in order. Networks on
+// TAP devices are skipped. The caller closes the files once the hypervisor
+// has them.
+func OpenMacvtaps(networks []NetworkConfig) ([]*os.File, error) {
+	var files []*os.File
+	for _, n := range networks {
+		if !n.Macvtap {
+			continue
+		}
+		f, err := OpenMacvtap(n.TAPDevice)
+		if err != nil {
+			CloseFiles(files)
+			return nil, err
+		}
+		files = append(files, f)
+	}
+	return files, nil
+}
+
+// CloseFiles closes files, ignoring errors.
+func CloseFiles(files []*os.File) {
+	for _, f := range files {
+		f.Close()
+	}
+}
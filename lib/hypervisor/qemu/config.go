@@ -11,6 +11,8 @@ import (
 )
 
 // BuildArgs converts hypervisor.VMConfig to QEMU command-line arguments.
+// Macvtap devices are referred to by file descriptor, starting at 3 in the
+// order of cfg.Networks: the process inherits them as its extra files.
 func BuildArgs(cfg hypervisor.VMConfig) []string {
 	args := make([]string, 0, 64)
 
@@ -18,30 +20,50 @@ func BuildArgs(cfg hypervisor.VMConfig) []string {
 	args = append(args, "-machine", machineType())
 
 	// CPU configuration
-	args = append(args, "-cpu", "host")
+	args = append(args, "-cpu", cpuModel(cfg.NestedVirt))
 	args = append(args, "-smp", strconv.Itoa(cfg.VCPUs))
 
 	// Memory configuration
 	memMB := cfg.MemoryBytes / (1024 * 1024)
 	args = append(args, "-m", fmt.Sprintf("%dM", memMB))
 
-	// Kernel and initrd
-	if cfg.KernelPath != "" {
-		args = append(args, "-kernel", cfg.KernelPath)
+	// virtiofsd maps guest memory, so it must be backed by shared memory
+	if len(cfg.FileSystems) > 0 {
+		args = append(args, "-object", fmt.Sprintf("memory-backend-memfd,id=mem,size=%dM,share=on", memMB))
+		args = append(args, "-numa", "node,memdev=mem")
 	}
-	if cfg.InitrdPath != "" {
-		args = append(args, "-initrd", cfg.InitrdPath)
-	}
-	if cfg.KernelArgs != "" {
-		args = append(args, "-append", cfg.KernelArgs)
+
+	// UEFI firmware, or kernel and initrd
+	if cfg.Firmware != "" {
+		args = append(args, "-bios", cfg.Firmware)
+	} else {
+		if cfg.KernelPath != "" {
+			args = append(args, "-kernel", cfg.KernelPath)
+		}
+		if cfg.InitrdPath != "" {
+			args = append(args, "-initrd", cfg.InitrdPath)
+		}
+		if cfg.KernelArgs != "" {
+			args = append(args, "-append", cfg.KernelArgs)
+		}
 	}
 
 	// Disk configuration
 	for i, disk := range cfg.Disks {
+		// CD-ROMs go on the machine's built-in AHCI controller, which guests
+		// without virtio drivers can read
+		if disk.CDROM {
+			args = append(args, "-drive", fmt.Sprintf("file=%s,format=raw,if=none,id=drive%d,media=cdrom,readonly=on", disk.Path, i))
+			args = append(args, "-device", fmt.Sprintf("ide-cd,drive=drive%d", i))
+			continue
+		}
 		driveOpts := fmt.Sprintf("file=%s,format=raw,if=none,id=drive%d", disk.Path, i)
 		if disk.Readonly {
 			driveOpts += ",readonly=on"
 		}
+		if disk.Discard {
+			driveOpts += ",discard=unmap,detect-zeroes=unmap"
+		}
 		if disk.IOBps > 0 {
 			driveOpts += fmt.Sprintf(",throttling.bps-total=%d", disk.IOBps)
 			if disk.IOBurstBps > 0 && disk.IOBurstBps > disk.IOBps {
@@ -52,9 +74,20 @@ func BuildArgs(cfg hypervisor.VMConfig) []string {
 		args = append(args, "-device", fmt.Sprintf("virtio-blk-pci,drive=drive%d", i))
 	}
 
+	// Shared directory configuration
+	for i, fs := range cfg.FileSystems {
+		args = append(args, "-chardev", fmt.Sprintf("socket,id=fs%d,path=%s", i, fs.SocketPath))
+		args = append(args, "-device", fmt.Sprintf("vhost-user-fs-pci,chardev=fs%d,tag=%s", i, fs.Tag))
+	}
+
 	// Network configuration
+	macvtapFD := 3
 	for i, net := range cfg.Networks {
 		netdevOpts := fmt.Sprintf("tap,id=net%d,ifname=%s,script=no,downscript=no", i, net.TAPDevice)
+		if net.Macvtap {
+			netdevOpts = fmt.Sprintf("tap,id=net%d,fd=%d", i, macvtapFD)
+			macvtapFD++
+		}
 		args = append(args, "-netdev", netdevOpts)
 
 		deviceOpts := fmt.Sprintf("virtio-net-pci,netdev=net%d,mac=%s", i, net.MAC)
@@ -84,6 +117,14 @@ func BuildArgs(cfg hypervisor.VMConfig) []string {
 		args = append(args, "-device", deviceArg)
 	}
 
+	// USB controller and device passthrough
+	if cfg.USBController || len(cfg.USBDevices) > 0 {
+		args = append(args, "-device", "qemu-xhci,id="+usbControllerID)
+	}
+	for _, dev := range cfg.USBDevices {
+		args = append(args, "-device", usbHostDeviceArg(dev))
+	}
+
 	// Serial console output to file
 	if cfg.SerialLogPath != "" {
 		args = append(args, "-serial", fmt.Sprintf("file:%s", cfg.SerialLogPath))
@@ -100,6 +141,28 @@ func BuildArgs(cfg hypervisor.VMConfig) []string {
 	return args
 }
 
+// usbControllerID is the ID of the xHCI controller USB devices attach to
+const usbControllerID = "usb"
+
+// usbHostDeviceArg returns the -device argument passing a host USB device through
+func usbHostDeviceArg(dev hypervisor.USBDeviceConfig) string {
+	arg := fmt.Sprintf("usb-host,bus=%s.0,id=%s", usbControllerID, dev.ID)
+	if dev.VendorID != "" {
+		return arg + fmt.Sprintf(",vendorid=0x%s,productid=0x%s", dev.VendorID, dev.ProductID)
+	}
+	return arg + fmt.Sprintf(",hostbus=%d,hostport=%s", dev.HostBus, dev.HostPort)
+}
+
+// cpuModel returns the -cpu model: the host CPU, with VMX and SVM masked on
+// x86 unless the guest may run nested VMs. The host model passes them
+// through whenever the host's KVM module allows nesting.
+func cpuModel(nested bool) string {
+	if nested || runtime.GOARCH != "amd64" {
+		return "host"
+	}
+	return "host,-vmx,-svm"
+}
+
 // machineType returns the QEMU machine type for the host architecture.
 func machineType() string {
 	switch runtime.GOARCH {
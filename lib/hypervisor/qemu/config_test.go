@@ -1,6 +1,7 @@
 package qemu
 
 import (
+	"runtime"
 	"testing"
 
 	"github.com/kernel/hypeman/lib/hypervisor"
@@ -24,7 +25,7 @@ func TestBuildArgs_Basic(t *testing.T) {
 
 	// Check CPU
 	assert.Contains(t, args, "-cpu")
-	assert.Contains(t, args, "host")
+	assert.Contains(t, args, cpuModel(false))
 	assert.Contains(t, args, "-smp")
 	assert.Contains(t, args, "2")
 
@@ -48,6 +49,25 @@ func TestBuildArgs_Basic(t *testing.T) {
 	assert.Contains(t, args, "-nographic")
 }
 
+func TestBuildArgs_NestedVirt(t *testing.T) {
+	if runtime.GOARCH != "amd64" {
+		t.Skip("VMX/SVM are x86 features")
+	}
+	cpuArg := func(nested bool) string {
+		args := BuildArgs(hypervisor.VMConfig{VCPUs: 1, MemoryBytes: 512 * 1024 * 1024, NestedVirt: nested})
+		for i, arg := range args {
+			if arg == "-cpu" && i+1 < len(args) {
+				return args[i+1]
+			}
+		}
+		t.Fatal("no -cpu argument")
+		return ""
+	}
+
+	assert.Equal(t, "host", cpuArg(true))
+	assert.Equal(t, "host,-vmx,-svm", cpuArg(false))
+}
+
 func TestBuildArgs_Disks(t *testing.T) {
 	cfg := hypervisor.VMConfig{
 		VCPUs:       1,
@@ -80,6 +100,19 @@ func TestBuildArgs_Disks(t *testing.T) {
 	assert.Contains(t, args, "virtio-blk-pci,drive=drive1")
 }
 
+func TestBuildArgs_DiskDiscard(t *testing.T) {
+	cfg := hypervisor.VMConfig{
+		VCPUs:       1,
+		MemoryBytes: 512 * 1024 * 1024,
+		Disks: []hypervisor.DiskConfig{
+			{Path: "/path/to/overlay.raw", Discard: true},
+		},
+	}
+
+	args := BuildArgs(cfg)
+	assert.Contains(t, args, "file=/path/to/overlay.raw,format=raw,if=none,id=drive0,discard=unmap,detect-zeroes=unmap")
+}
+
 func TestBuildArgs_Network(t *testing.T) {
 	cfg := hypervisor.VMConfig{
 		VCPUs:       1,
@@ -109,6 +142,26 @@ func TestBuildArgs_Network(t *testing.T) {
 	assert.Contains(t, args, "virtio-net-pci,netdev=net0,mac=02:00:00:ab:cd:ef")
 }
 
+func TestBuildArgs_Macvtap(t *testing.T) {
+	cfg := hypervisor.VMConfig{
+		VCPUs:       1,
+		MemoryBytes: 512 * 1024 * 1024,
+		Networks: []hypervisor.NetworkConfig{
+			{TAPDevice: "tap0", MAC: "02:00:00:00:00:01"},
+			{TAPDevice: "mvtap0", MAC: "02:00:00:00:00:02", Macvtap: true},
+			{TAPDevice: "mvtap1", MAC: "02:00:00:00:00:03", Macvtap: true},
+		},
+	}
+
+	args := BuildArgs(cfg)
+
+	// Macvtap devices are passed as the process's extra files, from fd 3
+	assert.Contains(t, args, "tap,id=net0,ifname=tap0,script=no,downscript=no")
+	assert.Contains(t, args, "tap,id=net1,fd=3")
+	assert.Contains(t, args, "tap,id=net2,fd=4")
+	assert.Contains(t, args, "virtio-net-pci,netdev=net1,mac=02:00:00:00:00:02")
+}
+
 func TestBuildArgs_Vsock(t *testing.T) {
 	cfg := hypervisor.VMConfig{
 		VCPUs:       1,
@@ -122,6 +175,25 @@ func TestBuildArgs_Vsock(t *testing.T) {
 	assert.Contains(t, args, "vhost-vsock-pci,guest-cid=123")
 }
 
+func TestBuildArgs_FileSystems(t *testing.T) {
+	cfg := hypervisor.VMConfig{
+		VCPUs:       1,
+		MemoryBytes: 512 * 1024 * 1024,
+		FileSystems: []hypervisor.FileSystemConfig{
+			{Tag: "fs0", SocketPath: "/var/lib/hypeman/guests/x/fs0.sock"},
+		},
+	}
+
+	args := BuildArgs(cfg)
+
+	// Guest memory is shared with virtiofsd
+	assert.Contains(t, args, "memory-backend-memfd,id=mem,size=512M,share=on")
+	assert.Contains(t, args, "node,memdev=mem")
+
+	assert.Contains(t, args, "socket,id=fs0,path=/var/lib/hypeman/guests/x/fs0.sock")
+	assert.Contains(t, args, "vhost-user-fs-pci,chardev=fs0,tag=fs0")
+}
+
 func TestBuildArgs_PCIPassthrough(t *testing.T) {
 	cfg := hypervisor.VMConfig{
 		VCPUs:       1,
@@ -135,6 +207,28 @@ func TestBuildArgs_PCIPassthrough(t *testing.T) {
 	assert.Contains(t, args, "vfio-pci,host=0000:02:00.0")
 }
 
+func TestBuildArgs_USBPassthrough(t *testing.T) {
+	cfg := hypervisor.VMConfig{
+		VCPUs:       1,
+		MemoryBytes: 512 * 1024 * 1024,
+		USBDevices: []hypervisor.USBDeviceConfig{
+			{ID: "usb-1050-0407", VendorID: "1050", ProductID: "0407"},
+			{ID: "usb-1-2.3", HostBus: 1, HostPort: "2.3"},
+		},
+	}
+
+	args := BuildArgs(cfg)
+
+	assert.Contains(t, args, "qemu-xhci,id=usb")
+	assert.Contains(t, args, "usb-host,bus=usb.0,id=usb-1050-0407,vendorid=0x1050,productid=0x0407")
+	assert.Contains(t, args, "usb-host,bus=usb.0,id=usb-1-2.3,hostbus=1,hostport=2.3")
+
+	// Without devices, the controller is only added when asked for, so
+	// snapshots taken without it restore with the same devices
+	assert.NotContains(t, BuildArgs(hypervisor.VMConfig{VCPUs: 1, MemoryBytes: 512 * 1024 * 1024}), "qemu-xhci,id=usb")
+	assert.Contains(t, BuildArgs(hypervisor.VMConfig{VCPUs: 1, MemoryBytes: 512 * 1024 * 1024, USBController: true}), "qemu-xhci,id=usb")
+}
+
 func TestBuildArgs_SerialLog(t *testing.T) {
 	cfg := hypervisor.VMConfig{
 		VCPUs:         1,
@@ -159,3 +253,29 @@ func TestBuildArgs_NoSerialLog(t *testing.T) {
 	assert.Contains(t, args, "-serial")
 	assert.Contains(t, args, "stdio")
 }
+
+func TestBuildArgs_Firmware(t *testing.T) {
+	cfg := hypervisor.VMConfig{
+		VCPUs:       2,
+		MemoryBytes: 4096 * 1024 * 1024,
+		Firmware:    "/usr/share/ovmf/OVMF.fd",
+		KernelPath:  "/path/to/kernel",
+		KernelArgs:  "console=ttyS0",
+		Disks: []hypervisor.DiskConfig{
+			{Path: "/path/to/disk.raw"},
+			{Path: "/path/to/virtio-win.iso", CDROM: true},
+		},
+	}
+
+	args := BuildArgs(cfg)
+
+	assert.Contains(t, args, "-bios")
+	assert.Contains(t, args, "/usr/share/ovmf/OVMF.fd")
+	assert.NotContains(t, args, "-kernel")
+	assert.NotContains(t, args, "-append")
+
+	assert.Contains(t, args, "virtio-blk-pci,drive=drive0")
+	assert.Contains(t, args, "file=/path/to/virtio-win.iso,format=raw,if=none,id=drive1,media=cdrom,readonly=on")
+	assert.Contains(t, args, "ide-cd,drive=drive1")
+	assert.NotContains(t, args, "virtio-blk-pci,drive=drive1")
+}
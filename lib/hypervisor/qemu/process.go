@@ -4,6 +4,7 @@ package qemu
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net"
 	"os"
@@ -11,12 +12,14 @@ import (
 	"path/filepath"
 	"regexp"
 	"runtime"
+	"strconv"
 	"syscall"
 	"time"
 
 	"github.com/kernel/hypeman/lib/hypervisor"
 	"github.com/kernel/hypeman/lib/logger"
 	"github.com/kernel/hypeman/lib/paths"
+	"golang.org/x/sys/unix"
 	"gvisor.dev/gvisor/pkg/cleanup"
 )
 
@@ -114,9 +117,10 @@ func buildQMPArgs(socketPath string) []string {
 }
 
 // startQEMUProcess handles the common QEMU process startup logic.
+// The macvtap devices of networks are passed to the process as its extra files.
 // Returns the PID, hypervisor client, and a cleanup function.
 // The cleanup function must be called on error; call cleanup.Release() on success.
-func (s *Starter) startQEMUProcess(ctx context.Context, p *paths.Paths, version string, socketPath string, args []string) (int, *QEMU, *cleanup.Cleanup, error) {
+func (s *Starter) startQEMUProcess(ctx context.Context, p *paths.Paths, version string, socketPath string, args []string, networks []hypervisor.NetworkConfig, opts hypervisor.ProcessOptions) (int, *QEMU, *cleanup.Cleanup, error) {
 	log := logger.FromContext(ctx)
 
 	// Get binary path
@@ -133,6 +137,12 @@ func (s *Starter) startQEMUProcess(ctx context.Context, p *paths.Paths, version
 	// Remove stale socket if exists
 	os.Remove(socketPath)
 
+	// Seccomp sandbox. Spawning stays allowed: restores read the migration
+	// stream through an exec: URI.
+	if opts.Sandbox {
+		args = append(args, "-sandbox", "on,obsolete=deny,elevateprivileges=deny,resourcecontrol=deny")
+	}
+
 	// Create command
 	cmd := exec.Command(binaryPath, args...)
 
@@ -141,6 +151,17 @@ func (s *Starter) startQEMUProcess(ctx context.Context, p *paths.Paths, version
 		Setpgid: true,
 	}
 
+	// Clone into the cgroup rather than moving the process after it started
+	if opts.CgroupDir != "" {
+		cgroup, err := os.Open(opts.CgroupDir)
+		if err != nil {
+			return 0, nil, nil, fmt.Errorf("open cgroup: %w", err)
+		}
+		defer cgroup.Close()
+		cmd.SysProcAttr.UseCgroupFD = true
+		cmd.SysProcAttr.CgroupFD = int(cgroup.Fd())
+	}
+
 	// Redirect stdout/stderr to VMM log file
 	instanceDir := filepath.Dir(socketPath)
 	logsDir := filepath.Join(instanceDir, "logs")
@@ -161,6 +182,14 @@ func (s *Starter) startQEMUProcess(ctx context.Context, p *paths.Paths, version
 	cmd.Stdout = vmmLogFile
 	cmd.Stderr = vmmLogFile
 
+	// QEMU keeps its own copies; ours are closed once it has started
+	macvtaps, err := hypervisor.OpenMacvtaps(networks)
+	if err != nil {
+		return 0, nil, nil, err
+	}
+	defer hypervisor.CloseFiles(macvtaps)
+	cmd.ExtraFiles = macvtaps
+
 	processStartTime := time.Now()
 	if err := cmd.Start(); err != nil {
 		return 0, nil, nil, fmt.Errorf("start qemu: %w", err)
@@ -198,19 +227,23 @@ func (s *Starter) startQEMUProcess(ctx context.Context, p *paths.Paths, version
 
 // StartVM launches QEMU with the VM configuration and returns a Hypervisor client.
 // QEMU receives all configuration via command-line arguments at process start.
-func (s *Starter) StartVM(ctx context.Context, p *paths.Paths, version string, socketPath string, config hypervisor.VMConfig) (int, hypervisor.Hypervisor, error) {
+func (s *Starter) StartVM(ctx context.Context, p *paths.Paths, version string, socketPath string, config hypervisor.VMConfig, opts hypervisor.ProcessOptions) (int, hypervisor.Hypervisor, error) {
 	log := logger.FromContext(ctx)
 
 	// Build command arguments: QMP socket + VM configuration
 	args := buildQMPArgs(socketPath)
 	args = append(args, BuildArgs(config)...)
 
-	pid, hv, cu, err := s.startQEMUProcess(ctx, p, version, socketPath, args)
+	pid, hv, cu, err := s.startQEMUProcess(ctx, p, version, socketPath, args, config.Networks, opts)
 	if err != nil {
 		return 0, nil, err
 	}
 	defer cu.Clean()
 
+	if err := pinThreads(pid, config.HostCPUs); err != nil {
+		return 0, nil, fmt.Errorf("pin vCPUs: %w", err)
+	}
+
 	// Save config for potential restore later
 	// QEMU migration files only contain memory state, not device config
 	instanceDir := filepath.Dir(socketPath)
@@ -225,7 +258,7 @@ func (s *Starter) StartVM(ctx context.Context, p *paths.Paths, version string, s
 
 // RestoreVM starts QEMU and restores VM state from a snapshot.
 // The VM is in paused state after restore; caller should call Resume() to continue execution.
-func (s *Starter) RestoreVM(ctx context.Context, p *paths.Paths, version string, socketPath string, snapshotPath string) (int, hypervisor.Hypervisor, error) {
+func (s *Starter) RestoreVM(ctx context.Context, p *paths.Paths, version string, socketPath string, snapshotPath string, opts hypervisor.ProcessOptions) (int, hypervisor.Hypervisor, error) {
 	log := logger.FromContext(ctx)
 	startTime := time.Now()
 
@@ -248,12 +281,16 @@ func (s *Starter) RestoreVM(ctx context.Context, p *paths.Paths, version string,
 	incomingURI := "exec:cat < " + memoryFile
 	args = append(args, "-incoming", incomingURI)
 
-	pid, hv, cu, err := s.startQEMUProcess(ctx, p, version, socketPath, args)
+	pid, hv, cu, err := s.startQEMUProcess(ctx, p, version, socketPath, args, config.Networks, opts)
 	if err != nil {
 		return 0, nil, err
 	}
 	defer cu.Clean()
 
+	if err := pinThreads(pid, config.HostCPUs); err != nil {
+		return 0, nil, fmt.Errorf("pin vCPUs: %w", err)
+	}
+
 	// Wait for VM to be ready after loading migration data
 	// QEMU transitions from "inmigrate" to "paused" when loading completes
 	migrationWaitStart := time.Now()
@@ -267,6 +304,34 @@ func (s *Starter) RestoreVM(ctx context.Context, p *paths.Paths, version string,
 	return pid, hv, nil
 }
 
+// pinThreads restricts every thread of the QEMU process to the host CPUs.
+// QEMU has no option to pin vCPUs, but its vCPU threads exist once QMP is
+// up, so pinning the whole process covers them. Threads created later
+// inherit the affinity of their creator.
+func pinThreads(pid int, cpus []int) error {
+	if len(cpus) == 0 {
+		return nil
+	}
+	var set unix.CPUSet
+	for _, cpu := range cpus {
+		set.Set(cpu)
+	}
+	tasks, err := os.ReadDir(fmt.Sprintf("/proc/%d/task", pid))
+	if err != nil {
+		return err
+	}
+	for _, task := range tasks {
+		tid, err := strconv.Atoi(task.Name())
+		if err != nil {
+			continue
+		}
+		if err := unix.SchedSetaffinity(tid, &set); err != nil && !errors.Is(err, unix.ESRCH) {
+			return fmt.Errorf("set affinity of thread %d: %w", tid, err)
+		}
+	}
+	return nil
+}
+
 // vmConfigFile is the name of the file where VM config is saved for restore.
 const vmConfigFile = "qemu-config.json"
 
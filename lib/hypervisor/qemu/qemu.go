@@ -5,6 +5,8 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
+	"strconv"
 	"time"
 
 	"github.com/digitalocean/go-qemu/qemu"
@@ -44,6 +46,8 @@ func (q *QEMU) Capabilities() hypervisor.Capabilities {
 		SupportsVsock:          true,
 		SupportsGPUPassthrough: true,
 		SupportsDiskIOLimit:    true,
+		SupportsUSBPassthrough: true,
+		SupportsCounters:       false, // Not implemented
 	}
 }
 
@@ -57,6 +61,15 @@ func (q *QEMU) DeleteVM(ctx context.Context) error {
 	return nil
 }
 
+// PowerButton presses the VM's ACPI power button.
+func (q *QEMU) PowerButton(ctx context.Context) error {
+	if err := q.client.SystemPowerdown(); err != nil {
+		Remove(q.socketPath)
+		return fmt.Errorf("system_powerdown: %w", err)
+	}
+	return nil
+}
+
 // Shutdown stops the QEMU process.
 func (q *QEMU) Shutdown(ctx context.Context) error {
 	if err := q.client.Quit(); err != nil {
@@ -169,3 +182,73 @@ func (q *QEMU) ResizeMemory(ctx context.Context, bytes int64) error {
 func (q *QEMU) ResizeMemoryAndWait(ctx context.Context, bytes int64, timeout time.Duration) error {
 	return fmt.Errorf("memory resize not supported by QEMU implementation")
 }
+
+// AttachUSBDevice hot-plugs a host USB device into the VM's xHCI controller.
+// The saved VM config is updated too, so snapshots restore with the device.
+func (q *QEMU) AttachUSBDevice(ctx context.Context, device hypervisor.USBDeviceConfig) error {
+	if err := q.client.DeviceAdd(usbHostDeviceProps(device)); err != nil {
+		Remove(q.socketPath)
+		return fmt.Errorf("device_add: %w", err)
+	}
+	return q.updateSavedUSBDevices(func(devices []hypervisor.USBDeviceConfig) []hypervisor.USBDeviceConfig {
+		return append(devices, device)
+	})
+}
+
+// DetachUSBDevice hot-unplugs a USB device.
+func (q *QEMU) DetachUSBDevice(ctx context.Context, id string) error {
+	if err := q.client.DeviceDel(id); err != nil {
+		Remove(q.socketPath)
+		return fmt.Errorf("device_del: %w", err)
+	}
+	return q.updateSavedUSBDevices(func(devices []hypervisor.USBDeviceConfig) []hypervisor.USBDeviceConfig {
+		return slices.DeleteFunc(devices, func(d hypervisor.USBDeviceConfig) bool { return d.ID == id })
+	})
+}
+
+// ListUSBDevices returns the IDs of the VM's usb-host devices.
+func (q *QEMU) ListUSBDevices(ctx context.Context) ([]string, error) {
+	ids, err := q.client.ListDevices("usb-host")
+	if err != nil {
+		Remove(q.socketPath)
+		return nil, fmt.Errorf("list usb devices: %w", err)
+	}
+	return ids, nil
+}
+
+// Counters is not implemented for QEMU.
+func (q *QEMU) Counters(ctx context.Context) (hypervisor.Counters, error) {
+	return nil, fmt.Errorf("counters not supported by QEMU")
+}
+
+// updateSavedUSBDevices rewrites the USB devices in the saved VM config that
+// snapshots copy for restore.
+func (q *QEMU) updateSavedUSBDevices(update func([]hypervisor.USBDeviceConfig) []hypervisor.USBDeviceConfig) error {
+	instanceDir := filepath.Dir(q.socketPath)
+	config, err := loadVMConfig(instanceDir)
+	if err != nil {
+		return err
+	}
+	config.USBDevices = update(config.USBDevices)
+	return saveVMConfig(instanceDir, config)
+}
+
+// usbHostDeviceProps returns the device_add properties passing a host USB
+// device through, matching usbHostDeviceArg.
+func usbHostDeviceProps(dev hypervisor.USBDeviceConfig) map[string]any {
+	props := map[string]any{
+		"driver": "usb-host",
+		"bus":    usbControllerID + ".0",
+		"id":     dev.ID,
+	}
+	if dev.VendorID != "" {
+		vendor, _ := strconv.ParseUint(dev.VendorID, 16, 16)
+		product, _ := strconv.ParseUint(dev.ProductID, 16, 16)
+		props["vendorid"] = vendor
+		props["productid"] = product
+	} else {
+		props["hostbus"] = dev.HostBus
+		props["hostport"] = dev.HostPort
+	}
+	return props
+}
@@ -2,6 +2,7 @@ package qemu
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"time"
 
@@ -105,6 +106,49 @@ func (c *Client) Run(cmd qmp.Command) ([]byte, error) {
 	return c.domain.Run(cmd)
 }
 
+// DeviceAdd hot-plugs a device (QMP 'device_add' command). props holds the
+// driver and its properties, as in a -device argument.
+func (c *Client) DeviceAdd(props map[string]any) error {
+	_, err := c.Run(qmp.Command{Execute: "device_add", Args: props})
+	return err
+}
+
+// DeviceDel hot-unplugs a device by ID (QMP 'device_del' command).
+func (c *Client) DeviceDel(id string) error {
+	return c.raw.DeviceDel(id)
+}
+
+// ListDevices returns the IDs of the user-created devices of a driver,
+// e.g. "usb-host".
+func (c *Client) ListDevices(driver string) ([]string, error) {
+	data, err := c.Run(qmp.Command{Execute: "qom-list", Args: map[string]any{"path": "/machine/peripheral"}})
+	if err != nil {
+		return nil, err
+	}
+	return parsePeripherals(data, driver)
+}
+
+// parsePeripherals returns the IDs of the devices of a driver in a qom-list
+// response for /machine/peripheral.
+func parsePeripherals(data []byte, driver string) ([]string, error) {
+	var resp struct {
+		Return []struct {
+			Name string `json:"name"`
+			Type string `json:"type"`
+		} `json:"return"`
+	}
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return nil, fmt.Errorf("parse qom-list response: %w", err)
+	}
+	ids := []string{}
+	for _, p := range resp.Return {
+		if p.Type == "child<"+driver+">" {
+			ids = append(ids, p.Name)
+		}
+	}
+	return ids, nil
+}
+
 // Migrate initiates a migration to the given URI (typically "file:///path").
 // This is used for saving VM state to a file for snapshot/standby.
 func (c *Client) Migrate(uri string) error {
@@ -6,6 +6,7 @@ import (
 	"github.com/digitalocean/go-qemu/qemu"
 	"github.com/digitalocean/go-qemu/qmp/raw"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestStatusMapping(t *testing.T) {
@@ -77,3 +78,15 @@ func TestStatusInfoFields(t *testing.T) {
 	assert.False(t, info.Singlestep)
 	assert.Equal(t, raw.RunStateRunning, info.Status)
 }
+
+func TestParsePeripherals(t *testing.T) {
+	data := []byte(`{"return": [
+		{"name": "usb", "type": "child<qemu-xhci>"},
+		{"name": "usb-1050-0407", "type": "child<usb-host>"},
+		{"name": "usb-1-2.3", "type": "child<usb-host>"}
+	]}`)
+
+	ids, err := parsePeripherals(data, "usb-host")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"usb-1050-0407", "usb-1-2.3"}, ids)
+}
@@ -0,0 +1,261 @@
+package images
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	gcr "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/tarball"
+)
+
+const (
+	// containerdImageNameAnnotation holds the full image name in index.json
+	// of archives written by docker save (25+), ctr and nerdctl
+	containerdImageNameAnnotation = "io.containerd.image.name"
+	// ociRefNameAnnotation is either a full image name or, from tools like
+	// skopeo and buildah, just a tag
+	ociRefNameAnnotation = "org.opencontainers.image.ref.name"
+)
+
+// imageArchive is a docker-save or oci-archive tarball spooled to disk, with
+// the position of each file in it so blobs can be read without extracting
+type imageArchive struct {
+	file    *os.File
+	size    int64
+	entries map[string]archiveEntry
+}
+
+type archiveEntry struct {
+	offset int64
+	size   int64
+}
+
+// readArchive copies a docker-save or oci-archive tarball, optionally
+// gzipped, from r to a file at dst and indexes it in the same pass
+func readArchive(r io.Reader, dst string) (*imageArchive, error) {
+	br := bufio.NewReader(r)
+	var src io.Reader = br
+	if magic, _ := br.Peek(2); bytes.Equal(magic, []byte{0x1f, 0x8b}) {
+		gz, err := gzip.NewReader(br)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidArchive, err)
+		}
+		defer gz.Close()
+		src = gz
+	}
+
+	f, err := os.Create(dst)
+	if err != nil {
+		return nil, fmt.Errorf("create archive file: %w", err)
+	}
+	a := &imageArchive{file: f, entries: make(map[string]archiveEntry)}
+
+	// The tar reader reads exactly up to the start of an entry's data before
+	// returning its header, so the bytes written so far are its offset
+	w := &countingWriter{w: f}
+	tr := tar.NewReader(io.TeeReader(src, w))
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			a.Close()
+			return nil, fmt.Errorf("%w: %v", ErrInvalidArchive, err)
+		}
+		if hdr.Typeflag == tar.TypeReg {
+			a.entries[strings.TrimPrefix(path.Clean("/"+hdr.Name), "/")] = archiveEntry{offset: w.n, size: hdr.Size}
+		}
+	}
+	a.size = w.n
+	if len(a.entries) == 0 {
+		a.Close()
+		return nil, fmt.Errorf("%w: not a tar archive, or empty", ErrInvalidArchive)
+	}
+	return a, nil
+}
+
+func (a *imageArchive) Close() error {
+	return a.file.Close()
+}
+
+func (a *imageArchive) has(name string) bool {
+	_, ok := a.entries[name]
+	return ok
+}
+
+func (a *imageArchive) open(name string) (io.ReadCloser, error) {
+	entry, ok := a.entries[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s is not in the archive", ErrInvalidArchive, name)
+	}
+	return io.NopCloser(io.NewSectionReader(a.file, entry.offset, entry.size)), nil
+}
+
+func (a *imageArchive) readFile(name string) ([]byte, error) {
+	rc, err := a.open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	return io.ReadAll(rc)
+}
+
+// blobPath is where an OCI layout keeps the blob with the given digest
+func blobPath(digest string) string {
+	algorithm, hex, _ := strings.Cut(digest, ":")
+	return path.Join("blobs", algorithm, hex)
+}
+
+func (a *imageArchive) openBlob(ctx context.Context, digest string) (io.ReadCloser, error) {
+	return a.open(blobPath(digest))
+}
+
+func (a *imageArchive) statBlob(ctx context.Context, digest string) error {
+	if !a.has(blobPath(digest)) {
+		return fmt.Errorf("%w: blob %s is not in the archive", ErrInvalidArchive, digest)
+	}
+	return nil
+}
+
+// image returns the image in the archive named imageName along with the name
+// to import it as. imageName may be empty if the archive holds a single named
+// image, and renames the image if the archive holds just one.
+func (a *imageArchive) image(ctx context.Context, imageName string) (*NormalizedRef, gcr.Image, error) {
+	// docker save writes an OCI layout alongside manifest.json since Docker
+	// 25, and it describes the image as stored rather than recompressed
+	if a.has("oci-layout") && a.has("index.json") {
+		return a.ociImage(ctx, imageName)
+	}
+	if a.has("manifest.json") {
+		return a.dockerImage(imageName)
+	}
+	return nil, nil, fmt.Errorf("%w: no index.json or manifest.json; expected a docker save or OCI archive", ErrInvalidArchive)
+}
+
+func (a *imageArchive) ociImage(ctx context.Context, imageName string) (*NormalizedRef, gcr.Image, error) {
+	raw, err := a.readFile("index.json")
+	if err != nil {
+		return nil, nil, err
+	}
+	index, err := gcr.ParseIndexManifest(bytes.NewReader(raw))
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: parse index.json: %v", ErrInvalidArchive, err)
+	}
+
+	names := make([][]string, len(index.Manifests))
+	for i, desc := range index.Manifests {
+		if n := desc.Annotations[containerdImageNameAnnotation]; n != "" {
+			names[i] = append(names[i], n)
+		}
+		// A bare tag isn't a name
+		if n := desc.Annotations[ociRefNameAnnotation]; strings.ContainsAny(n, ":/") {
+			names[i] = append(names[i], n)
+		}
+	}
+	i, ref, err := selectArchiveImage(names, imageName)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	desc := index.Manifests[i]
+	img, _, err := loadImage(ctx, a, ref.String(), desc.MediaType, desc.Digest.String())
+	if err != nil {
+		return nil, nil, err
+	}
+	return ref, img, nil
+}
+
+func (a *imageArchive) dockerImage(imageName string) (*NormalizedRef, gcr.Image, error) {
+	opener := func() (io.ReadCloser, error) {
+		return io.NopCloser(io.NewSectionReader(a.file, 0, a.size)), nil
+	}
+	manifest, err := tarball.LoadManifest(opener)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: parse manifest.json: %v", ErrInvalidArchive, err)
+	}
+
+	names := make([][]string, len(manifest))
+	for i, desc := range manifest {
+		names[i] = desc.RepoTags
+	}
+	i, ref, err := selectArchiveImage(names, imageName)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// tarball.Image picks an image by one of its tags, or takes the only one
+	var tag *name.Tag
+	if len(manifest) > 1 {
+		if len(manifest[i].RepoTags) == 0 {
+			return nil, nil, fmt.Errorf("%w: the image has no tag in manifest.json", ErrInvalidArchive)
+		}
+		t, err := name.NewTag(manifest[i].RepoTags[0])
+		if err != nil {
+			return nil, nil, fmt.Errorf("%w: %v", ErrInvalidArchive, err)
+		}
+		tag = &t
+	}
+	img, err := tarball.Image(opener, tag)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %v", ErrInvalidArchive, err)
+	}
+	return ref, img, nil
+}
+
+// selectArchiveImage picks the image to import from the names of each image
+// in an archive: the one named imageName, or the only image when imageName
+// is empty or the archive holds just one
+func selectArchiveImage(names [][]string, imageName string) (int, *NormalizedRef, error) {
+	if imageName != "" {
+		ref, err := ParseNormalizedRef(imageName)
+		if err != nil {
+			return 0, nil, fmt.Errorf("%w: %s", ErrInvalidName, err.Error())
+		}
+		for i, imageNames := range names {
+			if slices.ContainsFunc(imageNames, func(n string) bool {
+				r, err := ParseNormalizedRef(n)
+				return err == nil && r.String() == ref.String()
+			}) {
+				return i, ref, nil
+			}
+		}
+		if len(names) == 1 {
+			return 0, ref, nil
+		}
+		return 0, nil, fmt.Errorf("%w: %s is not in the archive", ErrNotFound, ref.String())
+	}
+
+	if len(names) != 1 {
+		return 0, nil, fmt.Errorf("%w: the archive holds %d images; name the one to import", ErrInvalidArchive, len(names))
+	}
+	for _, n := range names[0] {
+		if ref, err := ParseNormalizedRef(n); err == nil {
+			return 0, ref, nil
+		}
+	}
+	return 0, nil, fmt.Errorf("%w: the image in the archive has no name; name it in the request", ErrInvalidArchive)
+}
+
+// countingWriter counts the bytes written through it
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,125 @@
+package images
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	gcr "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/layout"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/tarball"
+	"github.com/kernel/hypeman/lib/paths"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// ociArchive returns img as a gzipped oci-archive, as skopeo or buildah
+// write it
+func ociArchive(t *testing.T, img gcr.Image, refName string) []byte {
+	dir := t.TempDir()
+	p, err := layout.Write(dir, empty.Index)
+	require.NoError(t, err)
+	require.NoError(t, p.AppendImage(img, layout.WithAnnotations(map[string]string{ociRefNameAnnotation: refName})))
+
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	require.NoError(t, filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		rel, _ := filepath.Rel(dir, path)
+		if err := tw.WriteHeader(&tar.Header{Name: rel, Mode: 0644, Size: int64(len(data)), Typeflag: tar.TypeReg}); err != nil {
+			return err
+		}
+		_, err = tw.Write(data)
+		return err
+	}))
+	require.NoError(t, tw.Close())
+	require.NoError(t, gz.Close())
+	return buf.Bytes()
+}
+
+func TestImportArchive(t *testing.T) {
+	ctx := context.Background()
+	mgr, err := NewManager(paths.New(t.TempDir()), 1, nil, nil)
+	require.NoError(t, err)
+
+	t.Run("oci archive", func(t *testing.T) {
+		img, err := random.Image(1024, 2)
+		require.NoError(t, err)
+		digest, err := img.Digest()
+		require.NoError(t, err)
+
+		imported, err := mgr.ImportArchive(ctx, ArchiveImportRequest{}, bytes.NewReader(ociArchive(t, img, "docker.io/library/app:v1")))
+		require.NoError(t, err)
+		assert.Equal(t, "docker.io/library/app:v1", imported.Name)
+		assert.Equal(t, digest.String(), imported.Digest, "the archive's blobs are kept as they are")
+
+		waitForReady(t, mgr, ctx, imported.Name)
+	})
+
+	t.Run("docker save", func(t *testing.T) {
+		web, err := random.Image(1024, 1)
+		require.NoError(t, err)
+		worker, err := random.Image(1024, 1)
+		require.NoError(t, err)
+		var buf bytes.Buffer
+		require.NoError(t, tarball.MultiWrite(map[name.Tag]gcr.Image{
+			name.MustParseReference("web:v1").(name.Tag):    web,
+			name.MustParseReference("worker:v1").(name.Tag): worker,
+		}, &buf))
+
+		_, err = mgr.ImportArchive(ctx, ArchiveImportRequest{}, bytes.NewReader(buf.Bytes()))
+		require.ErrorIs(t, err, ErrInvalidArchive, "an archive with several images needs a name")
+
+		_, err = mgr.ImportArchive(ctx, ArchiveImportRequest{Name: "api:v1"}, bytes.NewReader(buf.Bytes()))
+		require.ErrorIs(t, err, ErrNotFound)
+
+		imported, err := mgr.ImportArchive(ctx, ArchiveImportRequest{Name: "worker:v1", Tenant: "team-a"}, bytes.NewReader(buf.Bytes()))
+		require.NoError(t, err)
+		assert.Equal(t, "docker.io/library/worker:v1", imported.Name)
+		assert.Equal(t, "team-a", imported.Tenant)
+
+		waitForReady(t, mgr, ctx, imported.Name)
+	})
+
+	t.Run("not an archive", func(t *testing.T) {
+		_, err := mgr.ImportArchive(ctx, ArchiveImportRequest{Name: "app:v1"}, strings.NewReader("not a tarball"))
+		require.ErrorIs(t, err, ErrInvalidArchive)
+	})
+}
+
+func TestSelectArchiveImage(t *testing.T) {
+	names := [][]string{{"docker.io/library/web:v1"}, {"worker:v1", "worker:latest"}}
+
+	i, ref, err := selectArchiveImage(names, "worker")
+	require.NoError(t, err)
+	assert.Equal(t, 1, i)
+	assert.Equal(t, "docker.io/library/worker:latest", ref.String())
+
+	_, _, err = selectArchiveImage(names, "")
+	assert.ErrorIs(t, err, ErrInvalidArchive)
+
+	// A lone image is imported under the requested name
+	i, ref, err = selectArchiveImage([][]string{nil}, "app:v2")
+	require.NoError(t, err)
+	assert.Equal(t, 0, i)
+	assert.Equal(t, "docker.io/library/app:v2", ref.String())
+
+	_, _, err = selectArchiveImage([][]string{nil}, "")
+	assert.ErrorIs(t, err, ErrInvalidArchive)
+}
@@ -0,0 +1,148 @@
+package images
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+
+	gcr "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/partial"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+)
+
+// blobSource is a content-addressed store images are read from without a
+// registry: containerd's content store or an uploaded OCI archive
+type blobSource interface {
+	// openBlob streams the blob with the given digest
+	openBlob(ctx context.Context, digest string) (io.ReadCloser, error)
+	// statBlob returns an error wrapping ErrNotFound if the blob is missing
+	statBlob(ctx context.Context, digest string) error
+}
+
+func readBlob(ctx context.Context, src blobSource, digest string) ([]byte, error) {
+	rc, err := src.openBlob(ctx, digest)
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	return io.ReadAll(rc)
+}
+
+// loadImage returns the image a manifest or index descriptor in src points
+// at, picking the host platform's manifest from an index the same way a
+// registry pull does, along with its manifest digest. Blobs are read when the
+// image is written to a layout, so ctx must stay valid until then.
+func loadImage(ctx context.Context, src blobSource, name string, mediaType types.MediaType, digest string) (gcr.Image, string, error) {
+	if mediaType.IsIndex() {
+		raw, err := readBlob(ctx, src, digest)
+		if err != nil {
+			return nil, "", fmt.Errorf("read index: %w", err)
+		}
+		index, err := gcr.ParseIndexManifest(bytes.NewReader(raw))
+		if err != nil {
+			return nil, "", fmt.Errorf("parse index: %w", err)
+		}
+		desc, err := platformManifest(index)
+		if err != nil {
+			return nil, "", fmt.Errorf("%s: %w", name, err)
+		}
+		mediaType = desc.MediaType
+		digest = desc.Digest.String()
+	}
+	if !mediaType.IsImage() {
+		return nil, "", fmt.Errorf("image %s has unsupported media type %s", name, mediaType)
+	}
+
+	rawManifest, err := readBlob(ctx, src, digest)
+	if err != nil {
+		return nil, "", fmt.Errorf("read manifest: %w", err)
+	}
+	manifest, err := gcr.ParseManifest(bytes.NewReader(rawManifest))
+	if err != nil {
+		return nil, "", fmt.Errorf("parse manifest: %w", err)
+	}
+
+	// Fail now rather than partway through the copy when a layer is missing
+	for _, layer := range manifest.Layers {
+		if err := src.statBlob(ctx, layer.Digest.String()); err != nil {
+			return nil, "", fmt.Errorf("layer of %s: %w", name, err)
+		}
+	}
+
+	img, err := partial.CompressedToImage(&blobImage{
+		src:         src,
+		ctx:         ctx,
+		mediaType:   mediaType,
+		rawManifest: rawManifest,
+		manifest:    manifest,
+	})
+	if err != nil {
+		return nil, "", fmt.Errorf("load image: %w", err)
+	}
+	return img, digest, nil
+}
+
+// platformManifest picks the host platform's manifest from an index, the
+// same way a registry pull does
+func platformManifest(index *gcr.IndexManifest) (*gcr.Descriptor, error) {
+	platform := currentPlatform()
+	for i, desc := range index.Manifests {
+		if desc.Platform != nil && desc.Platform.Satisfies(platform) {
+			return &index.Manifests[i], nil
+		}
+	}
+	return nil, fmt.Errorf("%w: no manifest for platform %s", ErrNotFound, platform.String())
+}
+
+// blobImage implements partial.CompressedImageCore over blobs in a blobSource
+type blobImage struct {
+	src         blobSource
+	ctx         context.Context
+	mediaType   types.MediaType
+	rawManifest []byte
+	manifest    *gcr.Manifest
+}
+
+func (i *blobImage) RawConfigFile() ([]byte, error) {
+	return readBlob(i.ctx, i.src, i.manifest.Config.Digest.String())
+}
+
+func (i *blobImage) MediaType() (types.MediaType, error) {
+	return i.mediaType, nil
+}
+
+func (i *blobImage) RawManifest() ([]byte, error) {
+	return i.rawManifest, nil
+}
+
+func (i *blobImage) LayerByDigest(h gcr.Hash) (partial.CompressedLayer, error) {
+	for _, desc := range i.manifest.Layers {
+		if desc.Digest == h {
+			return &blobLayer{image: i, desc: desc}, nil
+		}
+	}
+	return nil, fmt.Errorf("layer %s not in manifest", h)
+}
+
+// blobLayer is a compressed layer blob in a blobSource
+type blobLayer struct {
+	image *blobImage
+	desc  gcr.Descriptor
+}
+
+func (l *blobLayer) Digest() (gcr.Hash, error) {
+	return l.desc.Digest, nil
+}
+
+func (l *blobLayer) Compressed() (io.ReadCloser, error) {
+	return l.image.src.openBlob(l.image.ctx, l.desc.Digest.String())
+}
+
+func (l *blobLayer) Size() (int64, error) {
+	return l.desc.Size, nil
+}
+
+func (l *blobLayer) MediaType() (types.MediaType, error) {
+	return l.desc.MediaType, nil
+}
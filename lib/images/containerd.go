@@ -0,0 +1,148 @@
+package images
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	contentapi "github.com/containerd/containerd/api/services/content/v1"
+	imagesapi "github.com/containerd/containerd/api/services/images/v1"
+	gcr "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// DefaultContainerdAddress is containerd's default API socket
+	DefaultContainerdAddress = "/run/containerd/containerd.sock"
+	// DefaultContainerdNamespace is the namespace ctr and nerdctl use.
+	// Kubernetes uses "k8s.io" and Docker's containerd image store "moby".
+	DefaultContainerdNamespace = "default"
+
+	// containerdNamespaceHeader is the gRPC metadata key containerd reads the
+	// namespace of a request from
+	containerdNamespaceHeader = "containerd-namespace"
+)
+
+// containerdStore reads images from a containerd content store over
+// containerd's gRPC API
+type containerdStore struct {
+	conn      *grpc.ClientConn
+	images    imagesapi.ImagesClient
+	content   contentapi.ContentClient
+	namespace string
+}
+
+// dialContainerd connects to the containerd API socket at address, which is
+// a socket path or a unix:// URL
+func dialContainerd(address, namespace string) (*containerdStore, error) {
+	if address == "" {
+		address = DefaultContainerdAddress
+	}
+	if namespace == "" {
+		namespace = DefaultContainerdNamespace
+	}
+	if !strings.Contains(address, "://") {
+		address = "unix://" + address
+	}
+	conn, err := grpc.NewClient(address, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		return nil, fmt.Errorf("connect to containerd at %s: %w", address, err)
+	}
+	return &containerdStore{
+		conn:      conn,
+		images:    imagesapi.NewImagesClient(conn),
+		content:   contentapi.NewContentClient(conn),
+		namespace: namespace,
+	}, nil
+}
+
+func (s *containerdStore) Close() error {
+	return s.conn.Close()
+}
+
+func (s *containerdStore) withNamespace(ctx context.Context) context.Context {
+	return metadata.AppendToOutgoingContext(ctx, containerdNamespaceHeader, s.namespace)
+}
+
+// resolve looks up the image named name and returns it for the host
+// platform, reading its blobs from the content store, along with its
+// manifest digest. Blobs are read when the image is written to a layout, so
+// ctx must stay valid until then.
+func (s *containerdStore) resolve(ctx context.Context, name string) (gcr.Image, string, error) {
+	ctx = s.withNamespace(ctx)
+	resp, err := s.images.Get(ctx, &imagesapi.GetImageRequest{Name: name})
+	if err != nil {
+		if status.Code(err) == codes.NotFound {
+			return nil, "", fmt.Errorf("%w: %s is not in containerd namespace %s", ErrNotFound, name, s.namespace)
+		}
+		return nil, "", fmt.Errorf("get image from containerd: %w", err)
+	}
+	target := resp.GetImage().GetTarget()
+	if target == nil {
+		return nil, "", fmt.Errorf("containerd image %s has no target", name)
+	}
+	return loadImage(ctx, s, name, types.MediaType(target.GetMediaType()), target.GetDigest())
+}
+
+// statBlob checks the blob is in the content store. Layers may be missing
+// when containerd only kept the unpacked snapshots (e.g. with
+// discard_unpacked_layers).
+func (s *containerdStore) statBlob(ctx context.Context, digest string) error {
+	if _, err := s.content.Info(ctx, &contentapi.InfoRequest{Digest: digest}); err != nil {
+		if status.Code(err) == codes.NotFound {
+			return fmt.Errorf("%w: blob %s is not in containerd's content store", ErrNotFound, digest)
+		}
+		return fmt.Errorf("stat %s: %w", digest, err)
+	}
+	return nil
+}
+
+// openBlob streams a blob from the content store
+func (s *containerdStore) openBlob(ctx context.Context, digest string) (io.ReadCloser, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	stream, err := s.content.Read(ctx, &contentapi.ReadContentRequest{Digest: digest})
+	if err != nil {
+		cancel()
+		return nil, fmt.Errorf("read %s: %w", digest, err)
+	}
+	return &contentReader{stream: stream, cancel: cancel, digest: digest}, nil
+}
+
+// contentReader is an io.ReadCloser over a content Read stream
+type contentReader struct {
+	stream contentapi.Content_ReadClient
+	cancel context.CancelFunc
+	digest string
+	buf    []byte
+}
+
+func (r *contentReader) Read(p []byte) (int, error) {
+	for len(r.buf) == 0 {
+		resp, err := r.stream.Recv()
+		if errors.Is(err, io.EOF) {
+			return 0, io.EOF
+		}
+		if err != nil {
+			if status.Code(err) == codes.NotFound {
+				return 0, fmt.Errorf("%w: blob %s is not in containerd's content store", ErrNotFound, r.digest)
+			}
+			return 0, fmt.Errorf("read %s: %w", r.digest, err)
+		}
+		r.buf = resp.GetData()
+	}
+	n := copy(p, r.buf)
+	r.buf = r.buf[n:]
+	return n, nil
+}
+
+func (r *contentReader) Close() error {
+	r.cancel()
+	return nil
+}
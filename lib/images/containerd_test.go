@@ -0,0 +1,219 @@
+package images
+
+import (
+	"context"
+	"io"
+	"net"
+	"path/filepath"
+	"testing"
+
+	contentapi "github.com/containerd/containerd/api/services/content/v1"
+	imagesapi "github.com/containerd/containerd/api/services/images/v1"
+	containerdtypes "github.com/containerd/containerd/api/types"
+	gcr "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+	"github.com/kernel/hypeman/lib/paths"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// fakeContainerd serves images over containerd's images API, and their blobs
+// over the content API (fakeContent), in a single namespace
+type fakeContainerd struct {
+	imagesapi.UnimplementedImagesServer
+	namespace string
+	images    map[string]*containerdtypes.Descriptor
+	blobs     map[string][]byte
+}
+
+func (f *fakeContainerd) checkNamespace(ctx context.Context) error {
+	md, _ := metadata.FromIncomingContext(ctx)
+	if ns := md.Get(containerdNamespaceHeader); len(ns) == 0 || ns[0] != f.namespace {
+		return status.Error(codes.NotFound, "not found")
+	}
+	return nil
+}
+
+func (f *fakeContainerd) Get(ctx context.Context, req *imagesapi.GetImageRequest) (*imagesapi.GetImageResponse, error) {
+	if err := f.checkNamespace(ctx); err != nil {
+		return nil, err
+	}
+	target, ok := f.images[req.GetName()]
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "image %q: not found", req.GetName())
+	}
+	return &imagesapi.GetImageResponse{Image: &imagesapi.Image{Name: req.GetName(), Target: target}}, nil
+}
+
+type fakeContent struct {
+	contentapi.UnimplementedContentServer
+	*fakeContainerd
+}
+
+func (f fakeContent) Info(ctx context.Context, req *contentapi.InfoRequest) (*contentapi.InfoResponse, error) {
+	blob, ok := f.blobs[req.GetDigest()]
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "content digest %s: not found", req.GetDigest())
+	}
+	return &contentapi.InfoResponse{Info: &contentapi.Info{Digest: req.GetDigest(), Size: int64(len(blob))}}, nil
+}
+
+func (f fakeContent) Read(req *contentapi.ReadContentRequest, stream contentapi.Content_ReadServer) error {
+	blob, ok := f.blobs[req.GetDigest()]
+	if !ok {
+		return status.Errorf(codes.NotFound, "content digest %s: not found", req.GetDigest())
+	}
+	// Send in small chunks to exercise reassembly
+	for offset := 0; offset < len(blob); offset += 1000 {
+		end := min(offset+1000, len(blob))
+		if err := stream.Send(&contentapi.ReadContentResponse{Offset: int64(offset), Data: blob[offset:end]}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// addImage stores img's manifest, config and layers
+func (f *fakeContainerd) addImage(t *testing.T, img gcr.Image) {
+	raw, err := img.RawManifest()
+	require.NoError(t, err)
+	digest, err := img.Digest()
+	require.NoError(t, err)
+	f.blobs[digest.String()] = raw
+
+	config, err := img.RawConfigFile()
+	require.NoError(t, err)
+	configName, err := img.ConfigName()
+	require.NoError(t, err)
+	f.blobs[configName.String()] = config
+
+	layers, err := img.Layers()
+	require.NoError(t, err)
+	for _, layer := range layers {
+		layerDigest, err := layer.Digest()
+		require.NoError(t, err)
+		rc, err := layer.Compressed()
+		require.NoError(t, err)
+		blob, err := io.ReadAll(rc)
+		rc.Close()
+		require.NoError(t, err)
+		f.blobs[layerDigest.String()] = blob
+	}
+}
+
+// startFakeContainerd serves f on a unix socket and returns its path
+func startFakeContainerd(t *testing.T, f *fakeContainerd) string {
+	socket := filepath.Join(t.TempDir(), "containerd.sock")
+	lis, err := net.Listen("unix", socket)
+	require.NoError(t, err)
+	srv := grpc.NewServer()
+	imagesapi.RegisterImagesServer(srv, f)
+	contentapi.RegisterContentServer(srv, fakeContent{fakeContainerd: f})
+	go srv.Serve(lis)
+	t.Cleanup(srv.Stop)
+	return socket
+}
+
+func TestImportFromContainerd(t *testing.T) {
+	ctx := context.Background()
+
+	// A multi-platform image whose index only has the host's platform pulled,
+	// as containerd stores images pulled for one platform
+	img, err := random.Image(1024, 2)
+	require.NoError(t, err)
+	platform := currentPlatform()
+	index := mutate.AppendManifests(empty.Index, mutate.IndexAddendum{
+		Add:        img,
+		Descriptor: gcr.Descriptor{Platform: &platform},
+	})
+	rawIndex, err := index.RawManifest()
+	require.NoError(t, err)
+	indexDigest, err := index.Digest()
+	require.NoError(t, err)
+	manifestDigest, err := img.Digest()
+	require.NoError(t, err)
+
+	fake := &fakeContainerd{
+		namespace: "k8s.io",
+		images:    make(map[string]*containerdtypes.Descriptor),
+		blobs:     map[string][]byte{indexDigest.String(): rawIndex},
+	}
+	fake.addImage(t, img)
+	fake.images["docker.io/library/app:v1"] = &containerdtypes.Descriptor{
+		MediaType: string(mustMediaType(t, index)),
+		Digest:    indexDigest.String(),
+		Size:      int64(len(rawIndex)),
+	}
+	socket := startFakeContainerd(t, fake)
+
+	dataDir := t.TempDir()
+	mgr, err := NewManager(paths.New(dataDir), 1, nil, nil)
+	require.NoError(t, err)
+	req := ContainerdImportRequest{Name: "app:v1", Address: socket, Namespace: "k8s.io"}
+
+	t.Run("dry run resolves without copying", func(t *testing.T) {
+		preview, err := mgr.CheckImportFromContainerd(ctx, req)
+		require.NoError(t, err)
+		assert.Equal(t, manifestDigest.String(), preview.Digest)
+		assert.Equal(t, StatusPending, preview.Status)
+		assert.False(t, mgr.(*manager).ociClient.existsInLayout(manifestDigest.Hex))
+	})
+
+	t.Run("missing image", func(t *testing.T) {
+		_, err := mgr.ImportFromContainerd(ctx, ContainerdImportRequest{Name: "app:v2", Address: socket, Namespace: "k8s.io"})
+		assert.ErrorIs(t, err, ErrNotFound)
+
+		_, err = mgr.ImportFromContainerd(ctx, ContainerdImportRequest{Name: "app:v1", Address: socket, Namespace: "default"})
+		assert.ErrorIs(t, err, ErrNotFound, "images are looked up in the requested namespace")
+	})
+
+	t.Run("imports and converts", func(t *testing.T) {
+		imported, err := mgr.ImportFromContainerd(ctx, req)
+		require.NoError(t, err)
+		assert.Equal(t, "docker.io/library/app:v1", imported.Name)
+		assert.Equal(t, manifestDigest.String(), imported.Digest)
+		assert.True(t, mgr.(*manager).ociClient.existsInLayout(manifestDigest.Hex), "blobs are copied into the OCI cache")
+
+		waitForReady(t, mgr, ctx, imported.Name)
+	})
+}
+
+func TestImportFromContainerd_MissingLayer(t *testing.T) {
+	img, err := random.Image(1024, 1)
+	require.NoError(t, err)
+	manifestDigest, err := img.Digest()
+	require.NoError(t, err)
+	raw, err := img.RawManifest()
+	require.NoError(t, err)
+
+	// Only the manifest is in the store, as when unpacked layers are discarded
+	fake := &fakeContainerd{
+		namespace: DefaultContainerdNamespace,
+		images: map[string]*containerdtypes.Descriptor{
+			"docker.io/library/app:v1": {MediaType: string(mustMediaType(t, img)), Digest: manifestDigest.String(), Size: int64(len(raw))},
+		},
+		blobs: map[string][]byte{manifestDigest.String(): raw},
+	}
+	socket := startFakeContainerd(t, fake)
+
+	mgr, err := NewManager(paths.New(t.TempDir()), 1, nil, nil)
+	require.NoError(t, err)
+	_, err = mgr.ImportFromContainerd(context.Background(), ContainerdImportRequest{Name: "app:v1", Address: "unix://" + socket})
+	require.ErrorIs(t, err, ErrNotFound)
+	assert.Contains(t, err.Error(), "not in containerd's content store")
+}
+
+func mustMediaType(t *testing.T, m interface {
+	MediaType() (types.MediaType, error)
+}) types.MediaType {
+	mt, err := m.MediaType()
+	require.NoError(t, err)
+	return mt
+}
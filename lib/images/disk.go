@@ -16,11 +16,18 @@ const (
 	FormatExt4  ExportFormat = "ext4"  // Read-only ext4 (app images, default)
 	FormatErofs ExportFormat = "erofs" // Read-only compressed (future: when kernel supports it)
 	FormatCpio  ExportFormat = "cpio"  // Uncompressed archive (initrd, fast boot)
+	FormatDisk  ExportFormat = "disk"  // Whole partitioned disk booted by UEFI firmware (imported VM disks)
 )
 
 // DefaultImageFormat is the default export format for OCI images
 const DefaultImageFormat = FormatExt4
 
+// ConverterVersion identifies the conversion logic image disks are built
+// with. Bump it whenever a change to conversion (e.g. new mkfs options)
+// should reach existing images: disks built by an older version are stale
+// and get re-converted.
+const ConverterVersion = 1
+
 // ExportRootfs exports rootfs directory in specified format (public for system manager)
 func ExportRootfs(rootfsDir, outputPath string, format ExportFormat) (int64, error) {
 	switch format {
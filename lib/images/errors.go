@@ -7,8 +7,11 @@ import (
 )
 
 var (
-	ErrNotFound    = errors.New("image not found")
-	ErrInvalidName = errors.New("invalid image name")
+	ErrNotFound       = errors.New("image not found")
+	ErrInvalidName    = errors.New("invalid image name")
+	ErrInvalidArchive = errors.New("invalid image archive")
+	ErrNotReady       = errors.New("image is not ready")
+	ErrInvalidDisk    = errors.New("invalid disk image")
 )
 
 // wrapRegistryError checks if the error is a registry 404 error and wraps it as ErrNotFound.
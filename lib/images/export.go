@@ -0,0 +1,179 @@
+package images
+
+import (
+	"archive/tar"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	gcr "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/layout"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+)
+
+// ExportType is the form an image is exported in
+type ExportType string
+
+const (
+	ExportOCI  ExportType = "oci"  // OCI archive rebuilt from the OCI cache
+	ExportDisk ExportType = "disk" // The converted rootfs disk
+)
+
+func (m *manager) ExportImage(ctx context.Context, name string, exportType ExportType) (io.ReadCloser, int64, error) {
+	img, err := m.GetImage(ctx, name)
+	if err != nil {
+		return nil, 0, err
+	}
+	ref, err := ParseNormalizedRef(img.Name)
+	if err != nil {
+		return nil, 0, fmt.Errorf("%w: %s", ErrInvalidName, err.Error())
+	}
+
+	switch exportType {
+	case ExportDisk:
+		if img.Status != StatusReady {
+			return nil, 0, fmt.Errorf("%w: %s is %s", ErrNotReady, img.Name, img.Status)
+		}
+		f, err := os.Open(digestPath(m.paths, ref.Repository(), strings.TrimPrefix(img.Digest, "sha256:")))
+		if err != nil {
+			return nil, 0, fmt.Errorf("open disk: %w", err)
+		}
+		info, err := f.Stat()
+		if err != nil {
+			f.Close()
+			return nil, 0, fmt.Errorf("stat disk: %w", err)
+		}
+		return f, info.Size(), nil
+
+	case ExportOCI:
+		// Look the image up now so a missing image fails the request instead
+		// of the stream
+		cache, err := layout.FromPath(m.paths.SystemOCICache())
+		if err != nil {
+			return nil, 0, fmt.Errorf("%w: %s is not in the OCI cache", ErrNotFound, img.Name)
+		}
+		cached, err := imageByAnnotation(cache, digestToLayoutTag(img.Digest))
+		if err != nil {
+			return nil, 0, fmt.Errorf("%w: %s is not in the OCI cache", ErrNotFound, img.Name)
+		}
+		pr, pw := io.Pipe()
+		go func() {
+			pw.CloseWithError(writeOCIArchive(pw, cached, ref))
+		}()
+		return pr, -1, nil
+
+	default:
+		return nil, 0, fmt.Errorf("unknown export type %q", exportType)
+	}
+}
+
+// writeOCIArchive writes img as an OCI archive holding just that image,
+// annotated with its name the way containerd and docker save do, so
+// ImportArchive (or ctr, docker load, skopeo) can load it elsewhere
+func writeOCIArchive(w io.Writer, img gcr.Image, ref *NormalizedRef) error {
+	tw := tar.NewWriter(w)
+
+	rawManifest, err := img.RawManifest()
+	if err != nil {
+		return fmt.Errorf("read manifest: %w", err)
+	}
+	digest, err := img.Digest()
+	if err != nil {
+		return fmt.Errorf("digest manifest: %w", err)
+	}
+	mediaType, err := img.MediaType()
+	if err != nil {
+		return fmt.Errorf("read media type: %w", err)
+	}
+
+	annotations := map[string]string{containerdImageNameAnnotation: ref.String()}
+	if tag := ref.Tag(); tag != "" {
+		annotations[ociRefNameAnnotation] = tag
+	}
+	index, err := json.Marshal(gcr.IndexManifest{
+		SchemaVersion: 2,
+		MediaType:     types.OCIImageIndex,
+		Manifests: []gcr.Descriptor{{
+			MediaType:   mediaType,
+			Size:        int64(len(rawManifest)),
+			Digest:      digest,
+			Annotations: annotations,
+		}},
+	})
+	if err != nil {
+		return fmt.Errorf("marshal index: %w", err)
+	}
+
+	if err := writeTarFile(tw, "oci-layout", []byte(`{"imageLayoutVersion":"1.0.0"}`)); err != nil {
+		return err
+	}
+	if err := writeTarFile(tw, "index.json", index); err != nil {
+		return err
+	}
+	if err := writeTarFile(tw, blobPath(digest.String()), rawManifest); err != nil {
+		return err
+	}
+
+	configName, err := img.ConfigName()
+	if err != nil {
+		return fmt.Errorf("read config digest: %w", err)
+	}
+	config, err := img.RawConfigFile()
+	if err != nil {
+		return fmt.Errorf("read config: %w", err)
+	}
+	if err := writeTarFile(tw, blobPath(configName.String()), config); err != nil {
+		return err
+	}
+
+	layers, err := img.Layers()
+	if err != nil {
+		return fmt.Errorf("read layers: %w", err)
+	}
+	written := make(map[gcr.Hash]bool)
+	for _, layer := range layers {
+		if err := writeLayer(tw, layer, written); err != nil {
+			return err
+		}
+	}
+	return tw.Close()
+}
+
+func writeLayer(tw *tar.Writer, layer gcr.Layer, written map[gcr.Hash]bool) error {
+	digest, err := layer.Digest()
+	if err != nil {
+		return fmt.Errorf("read layer digest: %w", err)
+	}
+	if written[digest] {
+		return nil
+	}
+	size, err := layer.Size()
+	if err != nil {
+		return fmt.Errorf("read layer %s size: %w", digest, err)
+	}
+	rc, err := layer.Compressed()
+	if err != nil {
+		return fmt.Errorf("open layer %s: %w", digest, err)
+	}
+	defer rc.Close()
+
+	if err := tw.WriteHeader(&tar.Header{Name: blobPath(digest.String()), Mode: 0644, Size: size, Typeflag: tar.TypeReg}); err != nil {
+		return err
+	}
+	if _, err := io.Copy(tw, rc); err != nil {
+		return fmt.Errorf("copy layer %s: %w", digest, err)
+	}
+	written[digest] = true
+	return nil
+}
+
+func writeTarFile(tw *tar.Writer, name string, data []byte) error {
+	if err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(data)), Typeflag: tar.TypeReg}); err != nil {
+		return err
+	}
+	_, err := tw.Write(data)
+	return err
+}
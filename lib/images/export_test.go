@@ -0,0 +1,72 @@
+package images
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/kernel/hypeman/lib/paths"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExportImage(t *testing.T) {
+	ctx := context.Background()
+	mgr, err := NewManager(paths.New(t.TempDir()), 1, nil, nil)
+	require.NoError(t, err)
+	waitForIdleOnCleanup(t, mgr)
+
+	img, err := random.Image(1024, 2)
+	require.NoError(t, err)
+	imported, err := mgr.ImportArchive(ctx, ArchiveImportRequest{}, bytes.NewReader(ociArchive(t, img, "docker.io/library/app:v1")))
+	require.NoError(t, err)
+	waitForReady(t, mgr, ctx, imported.Name)
+
+	t.Run("oci archive round-trips", func(t *testing.T) {
+		rc, size, err := mgr.ExportImage(ctx, "app:v1", ExportOCI)
+		require.NoError(t, err)
+		assert.Equal(t, int64(-1), size)
+		archive, err := io.ReadAll(rc)
+		require.NoError(t, rc.Close())
+		require.NoError(t, err)
+
+		// Another host loads it under the same name and digest
+		other, err := NewManager(paths.New(t.TempDir()), 1, nil, nil)
+		require.NoError(t, err)
+		waitForIdleOnCleanup(t, other)
+		reimported, err := other.ImportArchive(ctx, ArchiveImportRequest{}, bytes.NewReader(archive))
+		require.NoError(t, err)
+		assert.Equal(t, imported.Name, reimported.Name)
+		assert.Equal(t, imported.Digest, reimported.Digest)
+	})
+
+	t.Run("disk", func(t *testing.T) {
+		rc, size, err := mgr.ExportImage(ctx, "app:v1", ExportDisk)
+		require.NoError(t, err)
+		defer rc.Close()
+		n, err := io.Copy(io.Discard, rc)
+		require.NoError(t, err)
+		assert.Equal(t, size, n)
+		assert.Positive(t, n)
+	})
+
+	t.Run("unknown image", func(t *testing.T) {
+		_, _, err := mgr.ExportImage(ctx, "app:v2", ExportOCI)
+		assert.ErrorIs(t, err, ErrNotFound)
+	})
+}
+
+// waitForIdleOnCleanup waits for the conversions queued on mgr to finish
+// before the test's temp dirs are removed: registered after them, it runs
+// first.
+func waitForIdleOnCleanup(t *testing.T, mgr Manager) {
+	queue := mgr.(*manager).queue
+	t.Cleanup(func() {
+		require.Eventually(t, func() bool {
+			return queue.ActiveCount() == 0 && queue.PendingCount() == 0
+		}, 60*time.Second, 10*time.Millisecond, "conversions still running")
+	})
+}
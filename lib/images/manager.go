@@ -2,17 +2,25 @@ package images
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
+	"net/http"
 	"os"
 	"path/filepath"
+	"slices"
 	"sort"
 	"strings"
 	"sync"
 	"time"
 
+	gcr "github.com/google/go-containerregistry/pkg/v1"
 	"github.com/google/go-containerregistry/pkg/v1/layout"
 	"github.com/kernel/hypeman/lib/paths"
+	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
 )
 
 const (
@@ -26,10 +34,38 @@ const (
 type Manager interface {
 	ListImages(ctx context.Context) ([]Image, error)
 	CreateImage(ctx context.Context, req CreateImageRequest) (*Image, error)
+	// CheckCreateImage resolves the image's manifest without pulling it. It
+	// returns the existing image if its digest is already present, otherwise
+	// the pending image CreateImage would queue.
+	CheckCreateImage(ctx context.Context, req CreateImageRequest) (*Image, error)
+	// ImportFromContainerd copies an image from the local containerd content
+	// store into the OCI cache and queues its conversion, instead of pulling
+	// it from a registry.
+	ImportFromContainerd(ctx context.Context, req ContainerdImportRequest) (*Image, error)
+	// CheckImportFromContainerd resolves the image in containerd without
+	// copying it. It returns the existing image if its digest is already
+	// present, otherwise the pending image ImportFromContainerd would queue.
+	CheckImportFromContainerd(ctx context.Context, req ContainerdImportRequest) (*Image, error)
+	// ImportArchive reads a docker-save or OCI archive tarball, optionally
+	// gzipped, from r, copies the image into the OCI cache and queues its
+	// conversion.
+	ImportArchive(ctx context.Context, req ArchiveImportRequest, r io.Reader) (*Image, error)
+	// ImportDisk imports a raw or qcow2 VM disk, read from r or downloaded
+	// from req.URL, using its root filesystem as the image's disk.
+	ImportDisk(ctx context.Context, req DiskImportRequest, r io.Reader) (*Image, error)
+	// ExportImage streams an image as an OCI archive rebuilt from the OCI
+	// cache, or as its converted disk, along with its size (-1 when not known
+	// up front). The caller closes the stream.
+	ExportImage(ctx context.Context, name string, exportType ExportType) (io.ReadCloser, int64, error)
 	// ImportLocalImage imports an image that was pushed to the local OCI cache.
 	// Unlike CreateImage, it does not resolve from a remote registry.
 	ImportLocalImage(ctx context.Context, repo, reference, digest string) (*Image, error)
 	GetImage(ctx context.Context, name string) (*Image, error)
+	// WaitForImage blocks until the named image is ready or failed, or ctx
+	// is done, and returns it as last seen. Images not found yet, like tags
+	// whose first build is in progress, are waited for too; ErrNotFound is
+	// returned right away when no build for the name is queued or running.
+	WaitForImage(ctx context.Context, name string) (*Image, error)
 	DeleteImage(ctx context.Context, name string) error
 	RecoverInterruptedBuilds()
 	// TotalImageBytes returns the total size of all ready images on disk.
@@ -38,6 +74,36 @@ type Manager interface {
 	// TotalOCICacheBytes returns the total size of the OCI layer cache.
 	// Used by the resource manager for disk capacity tracking.
 	TotalOCICacheBytes(ctx context.Context) (int64, error)
+	// BuildQueueState returns a snapshot of the image build queue for diagnostics.
+	BuildQueueState() QueueState
+	// PruneDanglingImages removes untagged image digests that no instance in
+	// inUse may be using. With dryRun, it only reports what would be removed.
+	PruneDanglingImages(ctx context.Context, inUse []string, dryRun bool) ([]PrunedImage, error)
+	// ApplyRetention removes the tags and digests retention rules don't
+	// keep, along with their disks, sparing images in inUse. With dryRun, it
+	// only reports what would be removed.
+	ApplyRetention(ctx context.Context, rules []RetentionRule, inUse []string, dryRun bool) (*RetentionResult, error)
+	// CheckForUpdates re-resolves the tags of pulled images, records on each
+	// image whether its tag has moved upstream, and applies policy to newly
+	// detected updates. It returns all pending updates.
+	CheckForUpdates(ctx context.Context, policy UpdatePolicy) ([]ImageUpdate, error)
+	// Prefetch resolves and queues a batch of images, deduplicated and in
+	// priority order, tracking them as one job.
+	Prefetch(ctx context.Context, req PrefetchRequest) (*PrefetchJob, error)
+	// GetPrefetchJob reports the progress of a prefetch job.
+	GetPrefetchJob(ctx context.Context, id string) (*PrefetchJob, error)
+	// ListStaleImages returns ready images whose disks were built in another
+	// format or by an older converter version.
+	ListStaleImages(ctx context.Context) ([]Image, error)
+	// ReconvertImages queues re-conversion of the named images, or of every
+	// stale image if names is empty, in the background.
+	ReconvertImages(ctx context.Context, names []string) ([]Image, error)
+	// ReconvertImage re-converts a stale image's disk (or rebuilds a missing
+	// one) and waits for it. Images that aren't stale are returned as is.
+	ReconvertImage(ctx context.Context, name string) (*Image, error)
+	// SetPullTransport routes the registry requests of later pulls through
+	// rt, like the registry's pull-through cache.
+	SetPullTransport(rt http.RoundTripper)
 }
 
 type manager struct {
@@ -46,11 +112,20 @@ type manager struct {
 	queue     *BuildQueue
 	createMu  sync.Mutex
 	metrics   *Metrics
+	tracer    trace.Tracer
+
+	reconvertMu sync.Mutex // Serializes disk re-conversions
+
+	prefetchMu   sync.Mutex
+	prefetchJobs []*prefetchJob // Oldest first, capped at maxPrefetchJobs
+
+	statusMu sync.Mutex
+	statusCh chan struct{} // Closed and replaced whenever a build changes status
 }
 
 // NewManager creates a new image manager.
-// If meter is nil, metrics are disabled.
-func NewManager(p *paths.Paths, maxConcurrentBuilds int, meter metric.Meter) (Manager, error) {
+// If meter is nil, metrics are disabled. If tracer is nil, tracing is disabled.
+func NewManager(p *paths.Paths, maxConcurrentBuilds int, meter metric.Meter, tracer trace.Tracer) (Manager, error) {
 	// Create cache directory under dataDir for OCI layouts
 	cacheDir := p.SystemOCICache()
 	ociClient, err := newOCIClient(cacheDir)
@@ -62,6 +137,11 @@ func NewManager(p *paths.Paths, maxConcurrentBuilds int, meter metric.Meter) (Ma
 		paths:     p,
 		ociClient: ociClient,
 		queue:     NewBuildQueue(maxConcurrentBuilds),
+		tracer:    tracer,
+		statusCh:  make(chan struct{}),
+	}
+	if m.tracer == nil {
+		m.tracer = noop.NewTracerProvider().Tracer("")
 	}
 
 	// Initialize metrics if meter is provided
@@ -77,6 +157,12 @@ func NewManager(p *paths.Paths, maxConcurrentBuilds int, meter metric.Meter) (Ma
 	return m, nil
 }
 
+func (m *manager) SetPullTransport(rt http.RoundTripper) {
+	m.ociClient.mu.Lock()
+	defer m.ociClient.mu.Unlock()
+	m.ociClient.transport = rt
+}
+
 func (m *manager) ListImages(ctx context.Context) ([]Image, error) {
 	metas, err := listAllTags(m.paths)
 	if err != nil {
@@ -92,43 +178,191 @@ func (m *manager) ListImages(ctx context.Context) ([]Image, error) {
 }
 
 func (m *manager) CreateImage(ctx context.Context, req CreateImageRequest) (*Image, error) {
-	// Parse and normalize
+	ref, err := m.resolveRef(ctx, req.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	m.createMu.Lock()
+	defer m.createMu.Unlock()
+
+	// Check if we already have this digest (deduplication)
+	if img, ok, err := m.existingImage(ref, req.Tenant); ok || err != nil {
+		return img, err
+	}
+
+	// Don't have this digest yet, queue the build
+	return m.createAndQueueImage(ctx, ref, req.Tenant, false, 0)
+}
+
+// existingImage returns the image for ref's digest if it is already present.
+// Requires createMu.
+func (m *manager) existingImage(ref *ResolvedRef, tenant string) (*Image, bool, error) {
+	meta, err := readMetadata(m.paths, ref.Repository(), ref.DigestHex())
+	if err != nil {
+		return nil, false, nil
+	}
+
+	// We have this digest already
+	if meta.Status == StatusReady && ref.Tag() != "" {
+		// Update tag symlink to point to current digest
+		// (handles case where tag moved to new digest)
+		createTagSymlink(m.paths, ref.Repository(), ref.Tag(), ref.DigestHex())
+	}
+	// Images are content-addressed: once a second tenant pulls the same
+	// digest it is shared rather than owned by either of them
+	if meta.Tenant != "" && meta.Tenant != tenant {
+		meta.Tenant = ""
+		if err := writeMetadata(m.paths, ref.Repository(), ref.DigestHex(), meta); err != nil {
+			return nil, false, fmt.Errorf("update metadata: %w", err)
+		}
+	}
+	img := meta.toImage()
+	// Add queue position if pending
+	if meta.Status == StatusPending {
+		img.QueuePosition = m.queue.GetPosition(meta.Digest)
+	}
+	return img, true, nil
+}
+
+func (m *manager) ImportFromContainerd(ctx context.Context, req ContainerdImportRequest) (*Image, error) {
 	normalized, err := ParseNormalizedRef(req.Name)
 	if err != nil {
 		return nil, fmt.Errorf("%w: %s", ErrInvalidName, err.Error())
 	}
+	store, err := dialContainerd(req.Address, req.Namespace)
+	if err != nil {
+		return nil, err
+	}
+	defer store.Close()
 
-	// Resolve to get digest (validates existence)
-	// Add a 2-second timeout to ensure fast failure on rate limits or errors
-	resolveCtx, cancel := context.WithTimeout(ctx, 2*time.Second)
-	defer cancel()
-
-	ref, err := normalized.Resolve(resolveCtx, m.ociClient)
+	resolveCtx, endSpan := m.startSpan(ctx, "ResolveContainerdImage", trace.WithAttributes(attribute.String("image", normalized.String())))
+	img, digest, err := store.resolve(resolveCtx, normalized.String())
+	endSpan(err)
 	if err != nil {
-		return nil, fmt.Errorf("resolve manifest: %w", err)
+		return nil, err
+	}
+	return m.importImage(ctx, NewResolvedRef(normalized, digest), img, req.Tenant)
+}
+
+// importImage copies img, read from somewhere other than a registry, into the
+// OCI cache and queues its conversion. The copy is made now so the queued
+// build finds the blobs there (and so does a build recovered after a restart)
+// instead of pulling from the registry.
+func (m *manager) importImage(ctx context.Context, ref *ResolvedRef, img gcr.Image, tenant string) (*Image, error) {
+	layoutTag := digestToLayoutTag(ref.Digest())
+	if !m.ociClient.existsInLayout(layoutTag) {
+		_, endSpan := m.startSpan(ctx, "CopyImage", trace.WithAttributes(attribute.String("digest", ref.Digest())))
+		err := m.ociClient.writeToLayout(img, layoutTag)
+		endSpan(err)
+		if err != nil {
+			return nil, fmt.Errorf("copy image to cache: %w", err)
+		}
 	}
 
 	m.createMu.Lock()
 	defer m.createMu.Unlock()
 
-	// Check if we already have this digest (deduplication)
+	if existing, ok, err := m.existingImage(ref, tenant); ok || err != nil {
+		return existing, err
+	}
+	return m.createAndQueueImage(ctx, ref, tenant, true, 0)
+}
+
+func (m *manager) ImportArchive(ctx context.Context, req ArchiveImportRequest, r io.Reader) (*Image, error) {
+	// Spool next to the OCI cache; archives can be larger than /tmp
+	tmpDir, err := os.MkdirTemp(filepath.Dir(m.paths.SystemOCICache()), "image-import-*")
+	if err != nil {
+		return nil, fmt.Errorf("create temp dir: %w", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	readCtx, endSpan := m.startSpan(ctx, "ReadImageArchive")
+	archive, err := readArchive(r, filepath.Join(tmpDir, "archive.tar"))
+	endSpan(err)
+	if err != nil {
+		return nil, err
+	}
+	defer archive.Close()
+
+	normalized, img, err := archive.image(readCtx, req.Name)
+	if err != nil {
+		return nil, err
+	}
+	digest, err := img.Digest()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidArchive, err)
+	}
+	if normalized.IsDigest() && normalized.Digest() != digest.String() {
+		return nil, fmt.Errorf("%w: the archive's image has digest %s", ErrNotFound, digest)
+	}
+	return m.importImage(ctx, NewResolvedRef(normalized, digest.String()), img, req.Tenant)
+}
+
+func (m *manager) CheckImportFromContainerd(ctx context.Context, req ContainerdImportRequest) (*Image, error) {
+	normalized, err := ParseNormalizedRef(req.Name)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidName, err.Error())
+	}
+	store, err := dialContainerd(req.Address, req.Namespace)
+	if err != nil {
+		return nil, err
+	}
+	defer store.Close()
+
+	_, digest, err := store.resolve(ctx, normalized.String())
+	if err != nil {
+		return nil, err
+	}
+	ref := NewResolvedRef(normalized, digest)
+
 	if meta, err := readMetadata(m.paths, ref.Repository(), ref.DigestHex()); err == nil {
-		// We have this digest already
-		if meta.Status == StatusReady && ref.Tag() != "" {
-			// Update tag symlink to point to current digest
-			// (handles case where tag moved to new digest)
-			createTagSymlink(m.paths, ref.Repository(), ref.Tag(), ref.DigestHex())
-		}
-		img := meta.toImage()
-		// Add queue position if pending
-		if meta.Status == StatusPending {
-			img.QueuePosition = m.queue.GetPosition(meta.Digest)
-		}
-		return img, nil
+		return meta.toImage(), nil
+	}
+	return &Image{
+		Name:   ref.String(),
+		Digest: ref.Digest(),
+		Status: StatusPending,
+		Tenant: req.Tenant,
+	}, nil
+}
+
+func (m *manager) CheckCreateImage(ctx context.Context, req CreateImageRequest) (*Image, error) {
+	ref, err := m.resolveRef(ctx, req.Name)
+	if err != nil {
+		return nil, err
 	}
 
-	// Don't have this digest yet, queue the build
-	return m.createAndQueueImage(ref)
+	if meta, err := readMetadata(m.paths, ref.Repository(), ref.DigestHex()); err == nil {
+		return meta.toImage(), nil
+	}
+	return &Image{
+		Name:   ref.String(),
+		Digest: ref.Digest(),
+		Status: StatusPending,
+		Tenant: req.Tenant,
+	}, nil
+}
+
+// resolveRef parses and normalizes an image name and resolves its manifest
+// digest, which also validates that the image exists.
+func (m *manager) resolveRef(ctx context.Context, name string) (*ResolvedRef, error) {
+	normalized, err := ParseNormalizedRef(name)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidName, err.Error())
+	}
+
+	// Add a 2-second timeout to ensure fast failure on rate limits or errors
+	resolveCtx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+
+	resolveCtx, endSpan := m.startSpan(resolveCtx, "ResolveManifest", trace.WithAttributes(attribute.String("image", normalized.String())))
+	ref, err := normalized.Resolve(resolveCtx, m.ociClient)
+	endSpan(err)
+	if err != nil {
+		return nil, fmt.Errorf("resolve manifest: %w", err)
+	}
+	return ref, nil
 }
 
 // ImportLocalImage imports an image from the local OCI cache without resolving from a remote registry.
@@ -160,6 +394,14 @@ func (m *manager) ImportLocalImage(ctx context.Context, repo, reference, digest
 		if meta.Status == StatusReady && ref.Tag() != "" {
 			createTagSymlink(m.paths, ref.Repository(), ref.Tag(), ref.DigestHex())
 		}
+		// A tag pushed while its digest builds, like a multi-platform index
+		// right after its child manifests, is linked once the build is done
+		if meta.Status != StatusReady && meta.Status != StatusFailed && ref.Tag() != "" && !slices.Contains(meta.PendingTags, ref.Tag()) {
+			meta.PendingTags = append(meta.PendingTags, ref.Tag())
+			if err := writeMetadata(m.paths, ref.Repository(), ref.DigestHex(), meta); err != nil {
+				return nil, fmt.Errorf("write metadata: %w", err)
+			}
+		}
 		img := meta.toImage()
 		if meta.Status == StatusPending {
 			img.QueuePosition = m.queue.GetPosition(meta.Digest)
@@ -168,15 +410,20 @@ func (m *manager) ImportLocalImage(ctx context.Context, repo, reference, digest
 	}
 
 	// Don't have this digest yet, queue the build
-	return m.createAndQueueImage(ref)
+	return m.createAndQueueImage(ctx, ref, "", true, 0)
 }
 
-func (m *manager) createAndQueueImage(ref *ResolvedRef) (*Image, error) {
+// createAndQueueImage writes the pending image's metadata and queues its
+// build. imported marks images that weren't pulled from a registry; higher
+// priorities are built first.
+func (m *manager) createAndQueueImage(ctx context.Context, ref *ResolvedRef, tenant string, imported bool, priority int) (*Image, error) {
 	meta := &imageMetadata{
 		Name:      ref.String(),
 		Digest:    ref.Digest(),
 		Status:    StatusPending,
-		Request:   &CreateImageRequest{Name: ref.String()},
+		Request:   &CreateImageRequest{Name: ref.String(), Tenant: tenant},
+		Tenant:    tenant,
+		Imported:  imported,
 		CreatedAt: time.Now(),
 	}
 
@@ -185,9 +432,11 @@ func (m *manager) createAndQueueImage(ref *ResolvedRef) (*Image, error) {
 		return nil, fmt.Errorf("write initial metadata: %w", err)
 	}
 
-	// Enqueue the build using digest as the queue key for deduplication
-	queuePos := m.queue.Enqueue(ref.Digest(), CreateImageRequest{Name: ref.String()}, func() {
-		m.buildImage(context.Background(), ref)
+	// Enqueue the build using digest as the queue key for deduplication.
+	// The build outlives the request, so its trace links back to the request's.
+	link := trace.LinkFromContext(ctx)
+	queuePos := m.queue.EnqueuePriority(ref.Digest(), CreateImageRequest{Name: ref.String()}, priority, func() {
+		m.buildImage(context.Background(), ref, link)
 	})
 
 	img := meta.toImage()
@@ -197,13 +446,25 @@ func (m *manager) createAndQueueImage(ref *ResolvedRef) (*Image, error) {
 	return img, nil
 }
 
-func (m *manager) buildImage(ctx context.Context, ref *ResolvedRef) {
+func (m *manager) buildImage(ctx context.Context, ref *ResolvedRef, link trace.Link) {
 	buildStart := time.Now()
+
+	var buildErr error
+	ctx, endSpan := m.startSpan(ctx, "BuildImage",
+		trace.WithLinks(link),
+		trace.WithAttributes(
+			attribute.String("image", ref.String()),
+			attribute.String("digest", ref.Digest()),
+		),
+	)
+	defer func() { endSpan(buildErr) }()
+
 	buildDir := m.paths.SystemBuild(ref.String())
 	tempDir := filepath.Join(buildDir, "rootfs")
 
 	if err := os.MkdirAll(buildDir, 0755); err != nil {
-		m.updateStatusByDigest(ref, StatusFailed, fmt.Errorf("create build dir: %w", err))
+		buildErr = fmt.Errorf("create build dir: %w", err)
+		m.updateStatusByDigest(ref, StatusFailed, buildErr)
 		m.recordBuildMetrics(ctx, buildStart, "failed")
 		return
 	}
@@ -216,9 +477,12 @@ func (m *manager) buildImage(ctx context.Context, ref *ResolvedRef) {
 	m.updateStatusByDigest(ref, StatusPulling, nil)
 
 	// Pull the image (digest is always known, uses cache if already pulled)
-	result, err := m.ociClient.pullAndExport(ctx, ref.String(), ref.Digest(), tempDir)
+	pullCtx, endPullSpan := m.startSpan(ctx, "PullImage")
+	result, err := m.ociClient.pullAndExport(pullCtx, ref.String(), ref.Digest(), tempDir)
+	endPullSpan(err)
 	if err != nil {
-		m.updateStatusByDigest(ref, StatusFailed, fmt.Errorf("pull and export: %w", err))
+		buildErr = fmt.Errorf("pull and export: %w", err)
+		m.updateStatusByDigest(ref, StatusFailed, buildErr)
 		m.recordPullMetrics(ctx, "failed")
 		m.recordBuildMetrics(ctx, buildStart, "failed")
 		return
@@ -240,13 +504,19 @@ func (m *manager) buildImage(ctx context.Context, ref *ResolvedRef) {
 
 	diskPath := digestPath(m.paths, ref.Repository(), ref.DigestHex())
 	// Use default image format (ext4 for now, easy to switch to erofs later)
+	_, endConvertSpan := m.startSpan(ctx, "ConvertImage", trace.WithAttributes(attribute.String("format", string(DefaultImageFormat))))
 	diskSize, err := ExportRootfs(tempDir, diskPath, DefaultImageFormat)
+	endConvertSpan(err)
 	if err != nil {
-		m.updateStatusByDigest(ref, StatusFailed, fmt.Errorf("convert to %s: %w", DefaultImageFormat, err))
+		buildErr = fmt.Errorf("convert to %s: %w", DefaultImageFormat, err)
+		m.updateStatusByDigest(ref, StatusFailed, buildErr)
 		return
 	}
 
-	// Read current metadata to preserve request info
+	// Read current metadata to preserve request info. createMu keeps tags
+	// imported meanwhile from being lost.
+	m.createMu.Lock()
+	defer m.createMu.Unlock()
 	meta, err := readMetadata(m.paths, ref.Repository(), ref.DigestHex())
 	if err != nil {
 		// Create new metadata if it doesn't exist
@@ -265,11 +535,17 @@ func (m *manager) buildImage(ctx context.Context, ref *ResolvedRef) {
 	meta.Cmd = result.Metadata.Cmd
 	meta.Env = result.Metadata.Env
 	meta.WorkingDir = result.Metadata.WorkingDir
+	meta.Format = DefaultImageFormat
+	meta.ConverterVersion = ConverterVersion
+	pendingTags := meta.PendingTags
+	meta.PendingTags = nil
 
 	if err := writeMetadata(m.paths, ref.Repository(), ref.DigestHex(), meta); err != nil {
-		m.updateStatusByDigest(ref, StatusFailed, fmt.Errorf("write final metadata: %w", err))
+		buildErr = fmt.Errorf("write final metadata: %w", err)
+		m.setStatusLocked(ref, StatusFailed, buildErr)
 		return
 	}
+	defer m.notifyStatus()
 
 	// Only create/update tag symlink on successful completion
 	if ref.Tag() != "" {
@@ -278,11 +554,23 @@ func (m *manager) buildImage(ctx context.Context, ref *ResolvedRef) {
 			fmt.Fprintf(os.Stderr, "Warning: failed to create tag symlink: %v\n", err)
 		}
 	}
+	for _, tag := range pendingTags {
+		if err := createTagSymlink(m.paths, ref.Repository(), tag, ref.DigestHex()); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to create tag symlink: %v\n", err)
+		}
+	}
 
 	m.recordBuildMetrics(ctx, buildStart, "success")
 }
 
 func (m *manager) updateStatusByDigest(ref *ResolvedRef, status string, err error) {
+	m.createMu.Lock()
+	defer m.createMu.Unlock()
+	m.setStatusLocked(ref, status, err)
+}
+
+// setStatusLocked is updateStatusByDigest for callers holding createMu
+func (m *manager) setStatusLocked(ref *ResolvedRef, status string, err error) {
 	meta, readErr := readMetadata(m.paths, ref.Repository(), ref.DigestHex())
 	if readErr != nil {
 		// Create new metadata if it doesn't exist
@@ -302,6 +590,51 @@ func (m *manager) updateStatusByDigest(ref *ResolvedRef, status string, err erro
 	}
 
 	writeMetadata(m.paths, ref.Repository(), ref.DigestHex(), meta)
+	m.notifyStatus()
+}
+
+// statusChanged returns a channel closed on the next status change
+func (m *manager) statusChanged() <-chan struct{} {
+	m.statusMu.Lock()
+	defer m.statusMu.Unlock()
+	return m.statusCh
+}
+
+// notifyStatus wakes everyone waiting on a status change
+func (m *manager) notifyStatus() {
+	m.statusMu.Lock()
+	defer m.statusMu.Unlock()
+	close(m.statusCh)
+	m.statusCh = make(chan struct{})
+}
+
+func (m *manager) WaitForImage(ctx context.Context, name string) (*Image, error) {
+	ref, err := ParseNormalizedRef(name)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidName, err.Error())
+	}
+	for {
+		// Taken before reading the status so a change in between isn't missed
+		changed := m.statusChanged()
+		img, err := m.GetImage(ctx, name)
+		// Tags are linked once their build finishes, so a missing image may
+		// still be on its way, but only if a build for it is queued
+		if errors.Is(err, ErrNotFound) && !m.queue.HasBuildFor(ref.String()) {
+			// The build may have finished between the two checks
+			return m.GetImage(ctx, name)
+		}
+		if err != nil && !errors.Is(err, ErrNotFound) {
+			return nil, err
+		}
+		if err == nil && (img.Status == StatusReady || img.Status == StatusFailed) {
+			return img, nil
+		}
+		select {
+		case <-ctx.Done():
+			return img, err
+		case <-changed:
+		}
+	}
 }
 
 func (m *manager) RecoverInterruptedBuilds() {
@@ -327,7 +660,7 @@ func (m *manager) RecoverInterruptedBuilds() {
 				// Create a ResolvedRef since we already have the digest from metadata
 				ref := NewResolvedRef(normalized, metaCopy.Digest)
 				m.queue.Enqueue(metaCopy.Digest, *metaCopy.Request, func() {
-					m.buildImage(context.Background(), ref)
+					m.buildImage(context.Background(), ref, trace.Link{})
 				})
 			}
 		}
@@ -358,7 +691,9 @@ func (m *manager) GetImage(ctx context.Context, name string) (*Image, error) {
 		}
 	}
 
-	meta, err := readMetadata(m.paths, repository, digestHex)
+	// A missing disk is reported as stale rather than an error, so callers
+	// can re-convert it
+	meta, err := readMetadataFile(m.paths, repository, digestHex)
 	if err != nil {
 		return nil, err
 	}
@@ -462,3 +797,8 @@ func (m *manager) TotalOCICacheBytes(ctx context.Context) (int64, error) {
 	}
 	return total, nil
 }
+
+// BuildQueueState returns a snapshot of the image build queue
+func (m *manager) BuildQueueState() QueueState {
+	return m.queue.State()
+}
@@ -14,7 +14,7 @@ import (
 
 func TestCreateImage(t *testing.T) {
 	dataDir := t.TempDir()
-	mgr, err := NewManager(paths.New(dataDir), 1, nil)
+	mgr, err := NewManager(paths.New(dataDir), 1, nil, nil)
 	require.NoError(t, err)
 
 	ctx := context.Background()
@@ -84,7 +84,7 @@ func TestCreateImage(t *testing.T) {
 
 func TestCreateImageDifferentTag(t *testing.T) {
 	dataDir := t.TempDir()
-	mgr, err := NewManager(paths.New(dataDir), 1, nil)
+	mgr, err := NewManager(paths.New(dataDir), 1, nil, nil)
 	require.NoError(t, err)
 
 	ctx := context.Background()
@@ -106,7 +106,7 @@ func TestCreateImageDifferentTag(t *testing.T) {
 
 func TestCreateImageDuplicate(t *testing.T) {
 	dataDir := t.TempDir()
-	mgr, err := NewManager(paths.New(dataDir), 1, nil)
+	mgr, err := NewManager(paths.New(dataDir), 1, nil, nil)
 	require.NoError(t, err)
 
 	ctx := context.Background()
@@ -135,7 +135,7 @@ func TestCreateImageDuplicate(t *testing.T) {
 
 func TestListImages(t *testing.T) {
 	dataDir := t.TempDir()
-	mgr, err := NewManager(paths.New(dataDir), 1, nil)
+	mgr, err := NewManager(paths.New(dataDir), 1, nil, nil)
 	require.NoError(t, err)
 
 	ctx := context.Background()
@@ -164,7 +164,7 @@ func TestListImages(t *testing.T) {
 
 func TestGetImage(t *testing.T) {
 	dataDir := t.TempDir()
-	mgr, err := NewManager(paths.New(dataDir), 1, nil)
+	mgr, err := NewManager(paths.New(dataDir), 1, nil, nil)
 	require.NoError(t, err)
 
 	ctx := context.Background()
@@ -188,7 +188,7 @@ func TestGetImage(t *testing.T) {
 
 func TestGetImageNotFound(t *testing.T) {
 	dataDir := t.TempDir()
-	mgr, err := NewManager(paths.New(dataDir), 1, nil)
+	mgr, err := NewManager(paths.New(dataDir), 1, nil, nil)
 	require.NoError(t, err)
 
 	ctx := context.Background()
@@ -199,7 +199,7 @@ func TestGetImageNotFound(t *testing.T) {
 
 func TestDeleteImage(t *testing.T) {
 	dataDir := t.TempDir()
-	mgr, err := NewManager(paths.New(dataDir), 1, nil)
+	mgr, err := NewManager(paths.New(dataDir), 1, nil, nil)
 	require.NoError(t, err)
 
 	ctx := context.Background()
@@ -234,7 +234,7 @@ func TestDeleteImage(t *testing.T) {
 
 func TestDeleteImageNotFound(t *testing.T) {
 	dataDir := t.TempDir()
-	mgr, err := NewManager(paths.New(dataDir), 1, nil)
+	mgr, err := NewManager(paths.New(dataDir), 1, nil, nil)
 	require.NoError(t, err)
 
 	ctx := context.Background()
@@ -271,7 +271,7 @@ func TestNormalizedRefParsing(t *testing.T) {
 
 func TestLayerCaching(t *testing.T) {
 	dataDir := t.TempDir()
-	mgr, err := NewManager(paths.New(dataDir), 1, nil)
+	mgr, err := NewManager(paths.New(dataDir), 1, nil, nil)
 	require.NoError(t, err)
 	ctx := context.Background()
 
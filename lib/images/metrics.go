@@ -5,7 +5,9 @@ import (
 	"time"
 
 	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
 	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // Metrics holds the metrics instruments for image operations.
@@ -101,3 +103,16 @@ func (m *manager) recordPullMetrics(ctx context.Context, status string) {
 	m.metrics.pullsTotal.Add(ctx, 1,
 		metric.WithAttributes(attribute.String("status", status)))
 }
+
+// startSpan starts a tracing span. The returned function ends it, marking it
+// failed if err is non-nil.
+func (m *manager) startSpan(ctx context.Context, name string, opts ...trace.SpanStartOption) (context.Context, func(err error)) {
+	ctx, span := m.tracer.Start(ctx, name, opts...)
+	return ctx, func(err error) {
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+	}
+}
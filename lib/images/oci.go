@@ -3,9 +3,11 @@ package images
 import (
 	"context"
 	"fmt"
+	"net/http"
 	"os"
 	"runtime"
 	"strings"
+	"sync"
 
 	"github.com/google/go-containerregistry/pkg/authn"
 	"github.com/google/go-containerregistry/pkg/name"
@@ -13,6 +15,7 @@ import (
 	"github.com/google/go-containerregistry/pkg/v1/empty"
 	"github.com/google/go-containerregistry/pkg/v1/layout"
 	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/google/go-containerregistry/pkg/v1/types"
 	digest "github.com/opencontainers/go-digest"
 	"github.com/opencontainers/image-spec/specs-go"
 	v1 "github.com/opencontainers/image-spec/specs-go/v1"
@@ -25,6 +28,9 @@ import (
 // ociClient handles OCI image operations without requiring Docker daemon
 type ociClient struct {
 	cacheDir string
+
+	mu        sync.Mutex
+	transport http.RoundTripper // Registry transport, nil for the default
 }
 
 // digestToLayoutTag converts a digest to a valid OCI layout tag.
@@ -64,6 +70,23 @@ func newOCIClient(cacheDir string) (*ociClient, error) {
 	return &ociClient{cacheDir: cacheDir}, nil
 }
 
+// remoteOptions returns the options registry requests are made with: system
+// authentication (~/.docker/config.json, etc.) and the current platform,
+// which selects the image of multi-arch indexes
+func (c *ociClient) remoteOptions(ctx context.Context) []remote.Option {
+	opts := []remote.Option{
+		remote.WithContext(ctx),
+		remote.WithAuthFromKeychain(authn.DefaultKeychain),
+		remote.WithPlatform(currentPlatform()),
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.transport != nil {
+		opts = append(opts, remote.WithTransport(c.transport))
+	}
+	return opts
+}
+
 // currentPlatform returns the platform for the current host
 func currentPlatform() gcr.Platform {
 	return gcr.Platform{
@@ -86,10 +109,7 @@ func (c *ociClient) inspectManifest(ctx context.Context, imageRef string) (strin
 	// For multi-arch images, this resolves the manifest index to the correct platform.
 	// This matches what pullToOCILayout does to ensure cache key consistency.
 	// Note: remote.Image is lazy - it only fetches the manifest, not layer blobs.
-	img, err := remote.Image(ref,
-		remote.WithContext(ctx),
-		remote.WithAuthFromKeychain(authn.DefaultKeychain),
-		remote.WithPlatform(currentPlatform()))
+	img, err := remote.Image(ref, c.remoteOptions(ctx)...)
 	if err != nil {
 		return "", fmt.Errorf("fetch manifest: %w", wrapRegistryError(err))
 	}
@@ -149,15 +169,24 @@ func (c *ociClient) pullToOCILayout(ctx context.Context, imageRef, layoutTag str
 	// Use system authentication (reads from ~/.docker/config.json, etc.)
 	// Default retry: only on network errors, max ~1.3s total
 	// WithPlatform ensures we pull the correct architecture for multi-arch images
-	img, err := remote.Image(ref,
-		remote.WithContext(ctx),
-		remote.WithAuthFromKeychain(authn.DefaultKeychain),
-		remote.WithPlatform(currentPlatform()))
+	img, err := remote.Image(ref, c.remoteOptions(ctx)...)
 	if err != nil {
 		// Rate limits fail here immediately (429 is not retried by default)
 		return fmt.Errorf("fetch image manifest: %w", wrapRegistryError(err))
 	}
 
+	// Rate limits during layer download also fail immediately (no retries)
+	if err := c.writeToLayout(img, layoutTag); err != nil {
+		return fmt.Errorf("download and write image layers: %w", err)
+	}
+	return nil
+}
+
+// writeToLayout appends img to the shared OCI layout under layoutTag. This is
+// where layer data is read from the image's source (a registry, or the
+// containerd content store) and written to blobs/sha256/; layers shared with
+// other images are only written once.
+func (c *ociClient) writeToLayout(img gcr.Image, layoutTag string) error {
 	// Open or create OCI layout directory
 	path, err := layout.FromPath(c.cacheDir)
 	if err != nil {
@@ -168,18 +197,9 @@ func (c *ociClient) pullToOCILayout(ctx context.Context, imageRef, layoutTag str
 		}
 	}
 
-	// Append image to layout - THIS is where actual layer data is downloaded
-	// Streams layers from registry and writes to blobs/sha256/ directory
-	// Automatically deduplicates shared layers across images
-	// Rate limits during layer download also fail immediately (no retries)
-	err = path.AppendImage(img, layout.WithAnnotations(map[string]string{
+	return path.AppendImage(img, layout.WithAnnotations(map[string]string{
 		"org.opencontainers.image.ref.name": layoutTag,
 	}))
-	if err != nil {
-		return fmt.Errorf("download and write image layers: %w", err)
-	}
-
-	return nil
 }
 
 // extractDigest gets the manifest digest from the OCI layout
@@ -349,7 +369,7 @@ func (c *ociClient) unpackLayers(ctx context.Context, layoutTag, targetDir strin
 func convertToOCIManifest(gcrManifest *gcr.Manifest) v1.Manifest {
 	// Convert config descriptor
 	configDesc := v1.Descriptor{
-		MediaType:   string(gcrManifest.Config.MediaType),
+		MediaType:   ociMediaType(gcrManifest.Config.MediaType),
 		Digest:      gcrDigestToOCI(gcrManifest.Config.Digest),
 		Size:        gcrManifest.Config.Size,
 		Annotations: gcrManifest.Config.Annotations,
@@ -359,7 +379,7 @@ func convertToOCIManifest(gcrManifest *gcr.Manifest) v1.Manifest {
 	layers := make([]v1.Descriptor, len(gcrManifest.Layers))
 	for i, layer := range gcrManifest.Layers {
 		layers[i] = v1.Descriptor{
-			MediaType:   string(layer.MediaType),
+			MediaType:   ociMediaType(layer.MediaType),
 			Digest:      gcrDigestToOCI(layer.Digest),
 			Size:        layer.Size,
 			Annotations: layer.Annotations,
@@ -377,6 +397,23 @@ func convertToOCIManifest(gcrManifest *gcr.Manifest) v1.Manifest {
 	}
 }
 
+// ociMediaType maps Docker v2 config and layer media types to their OCI
+// equivalents, which are the only ones umoci unpacks. Docker (and images it
+// pushes or stores in containerd) still uses the Docker types.
+func ociMediaType(mt types.MediaType) string {
+	switch mt {
+	case types.DockerConfigJSON:
+		return v1.MediaTypeImageConfig
+	case types.DockerLayer:
+		return v1.MediaTypeImageLayerGzip
+	case types.DockerUncompressedLayer:
+		return v1.MediaTypeImageLayer
+	case types.DockerForeignLayer:
+		return v1.MediaTypeImageLayerNonDistributableGzip //nolint:staticcheck // foreign layers are still in use
+	}
+	return string(mt)
+}
+
 // gcrDigestToOCI converts a go-containerregistry digest to OCI digest
 func gcrDigestToOCI(d gcr.Hash) digest.Digest {
 	return digest.NewDigestFromEncoded(digest.Algorithm(d.Algorithm), d.Hex)
@@ -0,0 +1,248 @@
+package images
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/nrednav/cuid2"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Prefetch job statuses
+const (
+	PrefetchResolving = "resolving" // Some references are still being resolved
+	PrefetchRunning   = "running"   // All resolved; some images are still pending or building
+	PrefetchReady     = "ready"     // Every image is ready
+	PrefetchFailed    = "failed"    // Done, but at least one image failed
+)
+
+const (
+	// MaxPrefetchImages is the most references a prefetch request may list
+	MaxPrefetchImages = 100
+
+	// maxPrefetchJobs is how many prefetch jobs are kept for status queries
+	maxPrefetchJobs = 50
+
+	// prefetchResolveWorkers bounds concurrent manifest resolutions per job
+	prefetchResolveWorkers = 4
+)
+
+// PrefetchImageRequest is one image to prefetch
+type PrefetchImageRequest struct {
+	Name     string
+	Priority int // Higher priorities are built first
+}
+
+// PrefetchRequest is a batch of images to pull ahead of time
+type PrefetchRequest struct {
+	Images []PrefetchImageRequest
+	Tenant string // Optional tenant label for access scoping
+}
+
+// PrefetchJob reports the progress of a prefetch request
+type PrefetchJob struct {
+	ID        string
+	Status    string
+	Images    []PrefetchImage
+	Tenant    string
+	CreatedAt time.Time
+}
+
+// PrefetchImage is the progress of one image of a prefetch job
+type PrefetchImage struct {
+	Name          string // Normalized reference
+	Priority      int
+	Digest        string // Empty until resolved
+	Status        string // PrefetchResolving, then the image's status
+	QueuePosition *int
+	Error         *string
+}
+
+// prefetchJob is a prefetch request being worked on
+type prefetchJob struct {
+	id        string
+	tenant    string
+	createdAt time.Time
+
+	mu    sync.Mutex
+	items []*prefetchItem
+}
+
+type prefetchItem struct {
+	name     string
+	priority int
+	resolved bool
+	ref      *ResolvedRef
+	err      error
+}
+
+// Prefetch validates and deduplicates the references, then resolves and
+// queues them in the background, highest priority first. Images already
+// present are not pulled again; pending ones are moved up the queue if the
+// request gives them a higher priority.
+func (m *manager) Prefetch(ctx context.Context, req PrefetchRequest) (*PrefetchJob, error) {
+	if len(req.Images) == 0 {
+		return nil, fmt.Errorf("%w: no images to prefetch", ErrInvalidName)
+	}
+	if len(req.Images) > MaxPrefetchImages {
+		return nil, fmt.Errorf("%w: at most %d images can be prefetched at once", ErrInvalidName, MaxPrefetchImages)
+	}
+
+	job := &prefetchJob{id: cuid2.Generate(), tenant: req.Tenant, createdAt: time.Now()}
+	byName := make(map[string]*prefetchItem)
+	for _, img := range req.Images {
+		normalized, err := ParseNormalizedRef(img.Name)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s: %s", ErrInvalidName, img.Name, err.Error())
+		}
+		if item, ok := byName[normalized.String()]; ok {
+			item.priority = max(item.priority, img.Priority)
+			continue
+		}
+		item := &prefetchItem{name: normalized.String(), priority: img.Priority}
+		byName[item.name] = item
+		job.items = append(job.items, item)
+	}
+
+	m.prefetchMu.Lock()
+	m.prefetchJobs = append(m.prefetchJobs, job)
+	if len(m.prefetchJobs) > maxPrefetchJobs {
+		m.prefetchJobs = m.prefetchJobs[len(m.prefetchJobs)-maxPrefetchJobs:]
+	}
+	m.prefetchMu.Unlock()
+
+	// The job outlives the request; keep its span context so builds link back
+	bgCtx := trace.ContextWithSpanContext(context.Background(), trace.SpanContextFromContext(ctx))
+	go m.runPrefetch(bgCtx, job)
+
+	return m.prefetchStatus(job), nil
+}
+
+// GetPrefetchJob reports the progress of a prefetch job
+func (m *manager) GetPrefetchJob(ctx context.Context, id string) (*PrefetchJob, error) {
+	m.prefetchMu.Lock()
+	idx := slices.IndexFunc(m.prefetchJobs, func(j *prefetchJob) bool { return j.id == id })
+	var job *prefetchJob
+	if idx >= 0 {
+		job = m.prefetchJobs[idx]
+	}
+	m.prefetchMu.Unlock()
+
+	if job == nil {
+		return nil, fmt.Errorf("%w: prefetch job %s", ErrNotFound, id)
+	}
+	return m.prefetchStatus(job), nil
+}
+
+// runPrefetch resolves every reference of a job, then queues the images in
+// priority order, so a low-priority image resolved first doesn't take a
+// build slot ahead of a high-priority one
+func (m *manager) runPrefetch(ctx context.Context, job *prefetchJob) {
+	sem := make(chan struct{}, prefetchResolveWorkers)
+	var wg sync.WaitGroup
+	for _, item := range job.items {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			ref, err := m.resolveRef(ctx, item.name)
+			job.mu.Lock()
+			item.ref, item.err = ref, wrapRegistryError(err)
+			job.mu.Unlock()
+		}()
+	}
+	wg.Wait()
+
+	order := slices.Clone(job.items)
+	slices.SortStableFunc(order, func(a, b *prefetchItem) int { return b.priority - a.priority })
+	for _, item := range order {
+		if item.err == nil {
+			item.err = m.queuePrefetch(ctx, item.ref, job.tenant, item.priority)
+		}
+		job.mu.Lock()
+		item.resolved = true
+		job.mu.Unlock()
+	}
+}
+
+// queuePrefetch queues one resolved image, like CreateImage does
+func (m *manager) queuePrefetch(ctx context.Context, ref *ResolvedRef, tenant string, priority int) error {
+	m.createMu.Lock()
+	defer m.createMu.Unlock()
+
+	img, ok, err := m.existingImage(ref, tenant)
+	if err != nil {
+		return err
+	}
+	if ok {
+		if img.Status == StatusPending {
+			m.queue.Prioritize(ref.Digest(), priority)
+		}
+		return nil
+	}
+	_, err = m.createAndQueueImage(ctx, ref, tenant, false, priority)
+	return err
+}
+
+// prefetchStatus snapshots a job, reading each image's current status
+func (m *manager) prefetchStatus(job *prefetchJob) *PrefetchJob {
+	job.mu.Lock()
+	defer job.mu.Unlock()
+
+	out := &PrefetchJob{ID: job.id, Tenant: job.tenant, CreatedAt: job.createdAt}
+	var resolving, building, failed bool
+	for _, item := range job.items {
+		img := PrefetchImage{Name: item.name, Priority: item.priority, Status: PrefetchResolving}
+		if item.ref != nil {
+			img.Digest = item.ref.Digest()
+		}
+		switch {
+		case item.err != nil:
+			img.Status = StatusFailed
+			img.Error = ptr(item.err.Error())
+		case !item.resolved:
+		default:
+			meta, err := readMetadata(m.paths, item.ref.Repository(), item.ref.DigestHex())
+			if err != nil {
+				img.Status = StatusFailed
+				img.Error = ptr("image was deleted")
+				break
+			}
+			img.Status, img.Error = meta.Status, meta.Error
+			if meta.Status == StatusPending {
+				img.QueuePosition = m.queue.GetPosition(meta.Digest)
+			}
+		}
+
+		switch img.Status {
+		case PrefetchResolving:
+			resolving = true
+		case StatusFailed:
+			failed = true
+		case StatusReady:
+		default:
+			building = true
+		}
+		out.Images = append(out.Images, img)
+	}
+
+	switch {
+	case resolving:
+		out.Status = PrefetchResolving
+	case building:
+		out.Status = PrefetchRunning
+	case failed:
+		out.Status = PrefetchFailed
+	default:
+		out.Status = PrefetchReady
+	}
+	return out
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
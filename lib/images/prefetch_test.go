@@ -0,0 +1,96 @@
+package images
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/kernel/hypeman/lib/paths"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuildQueuePriority(t *testing.T) {
+	q := NewBuildQueue(1)
+	block := make(chan struct{})
+	defer close(block)
+	wait := func() { <-block }
+
+	assert.Equal(t, 0, q.Enqueue("running", CreateImageRequest{}, wait))
+	assert.Equal(t, 1, q.EnqueuePriority("low", CreateImageRequest{}, 0, wait))
+	assert.Equal(t, 2, q.EnqueuePriority("low-2", CreateImageRequest{}, 0, wait))
+	assert.Equal(t, 1, q.EnqueuePriority("high", CreateImageRequest{}, 10, wait))
+	assert.Equal(t, 2, q.EnqueuePriority("mid", CreateImageRequest{}, 5, wait))
+
+	// Re-enqueueing with a higher priority moves the build up
+	assert.Equal(t, 3, q.EnqueuePriority("low-2", CreateImageRequest{}, 5, wait))
+	// A lower priority leaves it where it is
+	assert.Equal(t, 3, q.EnqueuePriority("low-2", CreateImageRequest{}, 0, wait))
+
+	q.Prioritize("low", 20)
+	assert.Equal(t, 1, *q.GetPosition("low"))
+	assert.Equal(t, 2, *q.GetPosition("high"))
+	assert.Equal(t, 3, *q.GetPosition("mid"))
+	assert.Equal(t, 4, *q.GetPosition("low-2"))
+}
+
+func TestPrefetchValidation(t *testing.T) {
+	mgr, err := NewManager(paths.New(t.TempDir()), 1, nil, nil)
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	_, err = mgr.Prefetch(ctx, PrefetchRequest{})
+	assert.True(t, errors.Is(err, ErrInvalidName))
+
+	_, err = mgr.Prefetch(ctx, PrefetchRequest{Images: []PrefetchImageRequest{
+		{Name: "docker.io/library/alpine:latest"},
+		{Name: "not a valid ref!"},
+	}})
+	assert.True(t, errors.Is(err, ErrInvalidName))
+
+	_, err = mgr.GetPrefetchJob(ctx, "missing")
+	assert.True(t, errors.Is(err, ErrNotFound))
+}
+
+func TestPrefetchStatus(t *testing.T) {
+	p := paths.New(t.TempDir())
+	mgr, err := NewManager(p, 1, nil, nil)
+	require.NoError(t, err)
+	m := mgr.(*manager)
+
+	resolved := func(name, hex string) *ResolvedRef {
+		normalized, err := ParseNormalizedRef(name)
+		require.NoError(t, err)
+		return NewResolvedRef(normalized, "sha256:"+hex)
+	}
+	alpine := resolved("docker.io/library/alpine:latest", strings.Repeat("a", 64))
+	nginx := resolved("docker.io/library/nginx:latest", strings.Repeat("b", 64))
+	addDigest(t, p, alpine.Repository(), alpine.DigestHex(), "latest", StatusReady)
+	addDigest(t, p, nginx.Repository(), nginx.DigestHex(), "latest", StatusConverting)
+
+	job := &prefetchJob{id: "job", items: []*prefetchItem{
+		{name: alpine.String(), priority: 1, resolved: true, ref: alpine},
+		{name: nginx.String(), priority: 2},
+	}}
+	got := m.prefetchStatus(job)
+	assert.Equal(t, PrefetchResolving, got.Status)
+	assert.Equal(t, StatusReady, got.Images[0].Status)
+	assert.Equal(t, PrefetchResolving, got.Images[1].Status)
+	assert.Empty(t, got.Images[1].Digest)
+
+	job.items[1].ref, job.items[1].resolved = nginx, true
+	got = m.prefetchStatus(job)
+	assert.Equal(t, PrefetchRunning, got.Status)
+	assert.Equal(t, StatusConverting, got.Images[1].Status)
+	assert.Equal(t, nginx.Digest(), got.Images[1].Digest)
+
+	job.items[1].err = errors.New("manifest unknown")
+	got = m.prefetchStatus(job)
+	assert.Equal(t, PrefetchFailed, got.Status)
+	require.NotNil(t, got.Images[1].Error)
+
+	job.items[1].err = nil
+	addDigest(t, p, nginx.Repository(), nginx.DigestHex(), "latest", StatusReady)
+	assert.Equal(t, PrefetchReady, m.prefetchStatus(job).Status)
+}
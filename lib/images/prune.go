@@ -0,0 +1,138 @@
+package images
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/kernel/hypeman/lib/diskusage"
+	"github.com/kernel/hypeman/lib/paths"
+)
+
+// PrunedImage is an image digest removed by PruneDanglingImages, or that
+// would be removed in a dry run.
+type PrunedImage struct {
+	Name   string // Reference the digest was pulled as
+	Digest string
+	Bytes  int64 // Disk space reclaimed
+}
+
+// PruneDanglingImages removes image digests no tag points at, such as the old
+// versions left behind when a tag is pulled again, and failed pulls.
+//
+// inUse lists the image references instances may be using. A digest
+// reference keeps that digest. A tag reference keeps every digest of its
+// repository, since an instance started before the tag moved still runs on
+// the old digest. Pulls in progress are never removed.
+func (m *manager) PruneDanglingImages(ctx context.Context, inUse []string, dryRun bool) ([]PrunedImage, error) {
+	pins := pinImages(inUse)
+
+	// Hold off new pulls so a digest can't be reused while it's removed
+	m.createMu.Lock()
+	defer m.createMu.Unlock()
+
+	building := m.buildingDigests()
+
+	var pruned []PrunedImage
+	imagesDir := m.paths.ImagesDir()
+	err := filepath.WalkDir(imagesDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || d.Name() != "metadata.json" {
+			return nil
+		}
+		dir := filepath.Dir(path)
+		repository, err := filepath.Rel(imagesDir, filepath.Dir(dir))
+		if err != nil {
+			return nil
+		}
+		digestHex := filepath.Base(dir)
+
+		if pins.keepsDigest(repository, digestHex) {
+			return nil
+		}
+		meta, err := readMetadata(m.paths, repository, digestHex)
+		if err != nil || building[meta.Digest] {
+			return nil
+		}
+		if meta.Status != StatusReady && meta.Status != StatusFailed {
+			return nil
+		}
+		if tagged, err := isTagged(m.paths, repository, digestHex); err != nil || tagged {
+			return nil
+		}
+
+		img := PrunedImage{Name: meta.Name, Digest: meta.Digest, Bytes: diskusage.Bytes(dir)}
+		if !dryRun {
+			if err := os.RemoveAll(dir); err != nil {
+				return fmt.Errorf("remove image %s: %w", meta.Digest, err)
+			}
+		}
+		pruned = append(pruned, img)
+		return filepath.SkipDir
+	})
+	if err != nil && !os.IsNotExist(err) {
+		return pruned, err
+	}
+
+	sort.Slice(pruned, func(i, j int) bool { return pruned[i].Name < pruned[j].Name })
+	return pruned, nil
+}
+
+// imagePins are the images instances may be using, which GC keeps
+type imagePins struct {
+	digests map[string]bool // repository@digestHex
+	repos   map[string]bool // Repositories referenced by tag
+	tags    map[string]bool // repository:tag
+}
+
+// pinImages returns the pins of image references. A digest reference pins
+// that digest. A tag reference pins the tag and every digest of its
+// repository, since an instance started before the tag moved still runs on
+// the old digest.
+func pinImages(inUse []string) imagePins {
+	pins := imagePins{digests: make(map[string]bool), repos: make(map[string]bool), tags: make(map[string]bool)}
+	for _, name := range inUse {
+		ref, err := ParseNormalizedRef(name)
+		if err != nil {
+			continue
+		}
+		if ref.IsDigest() {
+			pins.digests[ref.Repository()+"@"+ref.DigestHex()] = true
+		} else {
+			pins.repos[ref.Repository()] = true
+			pins.tags[ref.Repository()+":"+ref.Tag()] = true
+		}
+	}
+	return pins
+}
+
+func (p imagePins) keepsDigest(repository, digestHex string) bool {
+	return p.repos[repository] || p.digests[repository+"@"+digestHex]
+}
+
+// buildingDigests returns the digests being pulled or converted, or queued
+// to be
+func (m *manager) buildingDigests() map[string]bool {
+	building := make(map[string]bool)
+	state := m.queue.State()
+	for _, d := range append(state.Active, state.Pending...) {
+		building[d] = true
+	}
+	return building
+}
+
+// isTagged reports whether any tag of the repository points at the digest
+func isTagged(p *paths.Paths, repository, digestHex string) (bool, error) {
+	tags, err := listTags(p, repository)
+	if err != nil {
+		return false, err
+	}
+	for _, tag := range tags {
+		if target, err := resolveTag(p, repository, tag); err == nil && target == digestHex {
+			return true, nil
+		}
+	}
+	return false, nil
+}
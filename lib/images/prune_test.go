@@ -0,0 +1,80 @@
+package images
+
+import (
+	"context"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kernel/hypeman/lib/paths"
+	"github.com/stretchr/testify/require"
+)
+
+// addDigest stores an image digest as a finished pull would, tagged if tag is set
+func addDigest(t *testing.T, p *paths.Paths, repository, digestHex, tag, status string) {
+	t.Helper()
+	require.NoError(t, writeMetadata(p, repository, digestHex, &imageMetadata{
+		Name:      repository + ":latest",
+		Digest:    "sha256:" + digestHex,
+		Status:    status,
+		SizeBytes: 4096,
+		CreatedAt: time.Now(),
+	}))
+	if status == StatusReady {
+		require.NoError(t, os.WriteFile(digestPath(p, repository, digestHex), make([]byte, 4096), 0644))
+	}
+	if tag != "" {
+		require.NoError(t, createTagSymlink(p, repository, tag, digestHex))
+	}
+}
+
+func TestPruneDanglingImages(t *testing.T) {
+	p := paths.New(t.TempDir())
+	mgr, err := NewManager(p, 1, nil, nil)
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	hex := func(c string) string { return strings.Repeat(c, 64) }
+	addDigest(t, p, "docker.io/library/alpine", hex("a"), "latest", StatusReady)
+	addDigest(t, p, "docker.io/library/alpine", hex("b"), "", StatusReady)  // old version
+	addDigest(t, p, "docker.io/library/alpine", hex("c"), "", StatusFailed) // failed pull
+	addDigest(t, p, "docker.io/library/nginx", hex("d"), "", StatusReady)   // in use by a running instance
+	addDigest(t, p, "docker.io/library/redis", hex("e"), "", StatusReady)   // pinned by digest
+	addDigest(t, p, "docker.io/library/redis", hex("f"), "", StatusPulling) // still pulling
+
+	inUse := []string{
+		"docker.io/library/nginx:1.25",
+		"docker.io/library/redis@sha256:" + hex("e"),
+	}
+
+	// A dry run reports without removing anything
+	pruned, err := mgr.PruneDanglingImages(ctx, inUse, true)
+	require.NoError(t, err)
+	require.Len(t, pruned, 2)
+	require.DirExists(t, digestDir(p, "docker.io/library/alpine", hex("b")))
+
+	pruned, err = mgr.PruneDanglingImages(ctx, inUse, false)
+	require.NoError(t, err)
+	var digests []string
+	for _, img := range pruned {
+		digests = append(digests, img.Digest)
+	}
+	require.ElementsMatch(t, []string{"sha256:" + hex("b"), "sha256:" + hex("c")}, digests)
+	require.NoDirExists(t, digestDir(p, "docker.io/library/alpine", hex("b")))
+	require.NoDirExists(t, digestDir(p, "docker.io/library/alpine", hex("c")))
+
+	for _, kept := range []struct{ repo, hex string }{
+		{"docker.io/library/alpine", hex("a")},
+		{"docker.io/library/nginx", hex("d")},
+		{"docker.io/library/redis", hex("e")},
+		{"docker.io/library/redis", hex("f")},
+	} {
+		require.DirExists(t, digestDir(p, kept.repo, kept.hex))
+	}
+
+	// The tagged image still resolves
+	img, err := mgr.GetImage(ctx, "docker.io/library/alpine:latest")
+	require.NoError(t, err)
+	require.Equal(t, "sha256:"+hex("a"), img.Digest)
+}
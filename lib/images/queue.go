@@ -1,17 +1,21 @@
 package images
 
-import "sync"
+import (
+	"sort"
+	"sync"
+)
 
 type QueuedBuild struct {
 	ImageName string
 	Request   CreateImageRequest
+	Priority  int // Higher priorities start first; equal priorities in FIFO order
 	StartFn   func()
 }
 
 // BuildQueue manages concurrent image builds with a configurable limit
 type BuildQueue struct {
 	maxConcurrent int
-	active        map[string]bool
+	active        map[string]CreateImageRequest // Requests of running builds by digest
 	pending       []QueuedBuild
 	mu            sync.Mutex
 }
@@ -22,7 +26,7 @@ func NewBuildQueue(maxConcurrent int) *BuildQueue {
 	}
 	return &BuildQueue{
 		maxConcurrent: maxConcurrent,
-		active:        make(map[string]bool),
+		active:        make(map[string]CreateImageRequest),
 		pending:       make([]QueuedBuild, 0),
 	}
 }
@@ -30,19 +34,24 @@ func NewBuildQueue(maxConcurrent int) *BuildQueue {
 // Enqueue adds a build to the queue. Returns queue position (0 if started immediately, >0 if queued).
 // If the image is already building or queued, returns its current position without re-enqueueing.
 func (q *BuildQueue) Enqueue(imageName string, req CreateImageRequest, startFn func()) int {
+	return q.EnqueuePriority(imageName, req, 0, startFn)
+}
+
+// EnqueuePriority is Enqueue with a priority: the build is queued behind
+// pending builds of the same or higher priority. An image already queued
+// with a lower priority is moved up instead of being enqueued again.
+func (q *BuildQueue) EnqueuePriority(imageName string, req CreateImageRequest, priority int, startFn func()) int {
 	q.mu.Lock()
 	defer q.mu.Unlock()
 
 	// Check if already building (position 0, actively running)
-	if q.active[imageName] {
+	if _, ok := q.active[imageName]; ok {
 		return 0
 	}
 
 	// Check if already in pending queue
-	for i, build := range q.pending {
-		if build.ImageName == imageName {
-			return i + 1 // Return existing queue position
-		}
+	if pos := q.raise(imageName, priority); pos > 0 {
+		return pos
 	}
 
 	// Wrap the function to auto-complete
@@ -54,17 +63,53 @@ func (q *BuildQueue) Enqueue(imageName string, req CreateImageRequest, startFn f
 	build := QueuedBuild{
 		ImageName: imageName,
 		Request:   req,
+		Priority:  priority,
 		StartFn:   wrappedFn,
 	}
 
 	if len(q.active) < q.maxConcurrent {
-		q.active[imageName] = true
+		q.active[imageName] = req
 		go wrappedFn()
 		return 0
 	}
 
-	q.pending = append(q.pending, build)
-	return len(q.pending)
+	return q.insert(build) + 1
+}
+
+// Prioritize raises the priority of a pending build, moving it up the
+// queue. Builds already running or queued with a higher priority are left
+// alone.
+func (q *BuildQueue) Prioritize(imageName string, priority int) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.raise(imageName, priority)
+}
+
+// raise moves a pending build up to priority if it is lower, and returns
+// its queue position (0 if not pending). Requires mu.
+func (q *BuildQueue) raise(imageName string, priority int) int {
+	for i, build := range q.pending {
+		if build.ImageName != imageName {
+			continue
+		}
+		if build.Priority >= priority {
+			return i + 1
+		}
+		q.pending = append(q.pending[:i], q.pending[i+1:]...)
+		build.Priority = priority
+		return q.insert(build) + 1
+	}
+	return 0
+}
+
+// insert adds a build to the pending queue behind builds of the same or
+// higher priority, and returns its index. Requires mu.
+func (q *BuildQueue) insert(build QueuedBuild) int {
+	i := sort.Search(len(q.pending), func(i int) bool { return q.pending[i].Priority < build.Priority })
+	q.pending = append(q.pending, QueuedBuild{})
+	copy(q.pending[i+1:], q.pending[i:])
+	q.pending[i] = build
+	return i
 }
 
 func (q *BuildQueue) MarkComplete(imageName string) {
@@ -76,7 +121,7 @@ func (q *BuildQueue) MarkComplete(imageName string) {
 	if len(q.pending) > 0 && len(q.active) < q.maxConcurrent {
 		next := q.pending[0]
 		q.pending = q.pending[1:]
-		q.active[next.ImageName] = true
+		q.active[next.ImageName] = next.Request
 		go next.StartFn()
 	}
 }
@@ -85,7 +130,7 @@ func (q *BuildQueue) GetPosition(imageName string) *int {
 	q.mu.Lock()
 	defer q.mu.Unlock()
 
-	if q.active[imageName] {
+	if _, ok := q.active[imageName]; ok {
 		return nil
 	}
 
@@ -99,6 +144,25 @@ func (q *BuildQueue) GetPosition(imageName string) *int {
 	return nil
 }
 
+// HasBuildFor reports whether a build requested for the image named name
+// (a normalized reference) is running or pending
+func (q *BuildQueue) HasBuildFor(name string) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for _, req := range q.active {
+		if req.Name == name {
+			return true
+		}
+	}
+	for _, build := range q.pending {
+		if build.Request.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
 // ActiveCount returns number of actively building images
 func (q *BuildQueue) ActiveCount() int {
 	q.mu.Lock()
@@ -119,3 +183,29 @@ func (q *BuildQueue) QueueLength() int {
 	defer q.mu.Unlock()
 	return len(q.active) + len(q.pending)
 }
+
+// QueueState is a snapshot of the build queue for diagnostics
+type QueueState struct {
+	MaxConcurrent int      `json:"max_concurrent"`
+	Active        []string `json:"active"`  // digests being built
+	Pending       []string `json:"pending"` // digests in queue order
+}
+
+// State returns a snapshot of active and pending builds
+func (q *BuildQueue) State() QueueState {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	state := QueueState{
+		MaxConcurrent: q.maxConcurrent,
+		Active:        make([]string, 0, len(q.active)),
+		Pending:       make([]string, 0, len(q.pending)),
+	}
+	for name := range q.active {
+		state.Active = append(state.Active, name)
+	}
+	sort.Strings(state.Active)
+	for _, build := range q.pending {
+		state.Pending = append(state.Pending, build.ImageName)
+	}
+	return state
+}
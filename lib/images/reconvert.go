@@ -0,0 +1,185 @@
+package images
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// ListStaleImages returns the ready images whose disks were built in another
+// format or by an older converter.
+func (m *manager) ListStaleImages(ctx context.Context) ([]Image, error) {
+	metas, err := listAllTags(m.paths)
+	if err != nil {
+		return nil, fmt.Errorf("list tags: %w", err)
+	}
+
+	stale := make([]Image, 0)
+	for _, meta := range metas {
+		if meta.stale() {
+			stale = append(stale, *meta.toImage())
+		}
+	}
+	return stale, nil
+}
+
+// ReconvertImages queues re-conversion of the named ready images, stale or
+// not, or of every stale image if names is empty, and returns them. Images
+// keep serving their current disk until the new one replaces it.
+func (m *manager) ReconvertImages(ctx context.Context, names []string) ([]Image, error) {
+	type target struct {
+		meta *imageMetadata
+		ref  *ResolvedRef
+	}
+	var targets []target
+	if len(names) == 0 {
+		metas, err := listAllTags(m.paths)
+		if err != nil {
+			return nil, fmt.Errorf("list tags: %w", err)
+		}
+		for _, meta := range metas {
+			if !meta.stale() {
+				continue
+			}
+			normalized, err := ParseNormalizedRef(meta.Name)
+			if err != nil {
+				continue
+			}
+			targets = append(targets, target{meta, NewResolvedRef(normalized, meta.Digest)})
+		}
+	} else {
+		// Check every name before queueing any
+		for _, name := range names {
+			meta, ref, err := m.findImage(name)
+			if err != nil {
+				return nil, err
+			}
+			if meta.Status != StatusReady {
+				return nil, fmt.Errorf("%w: %s status is %s", ErrNotReady, name, meta.Status)
+			}
+			targets = append(targets, target{meta, ref})
+		}
+	}
+
+	force := len(names) > 0
+	link := trace.LinkFromContext(ctx)
+	queued := make([]Image, 0, len(targets))
+	for _, t := range targets {
+		ref := t.ref
+		// Keyed apart from builds, so an image can be built and re-converted
+		// independently; repeated requests for one digest are deduplicated
+		m.queue.Enqueue("reconvert:"+ref.Digest(), CreateImageRequest{Name: ref.String()}, func() {
+			if err := m.reconvert(context.Background(), ref, force, link); err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: failed to re-convert image %s: %v\n", ref.String(), err)
+			}
+		})
+		queued = append(queued, *t.meta.toImage())
+	}
+	return queued, nil
+}
+
+// ReconvertImage re-converts a stale image's disk now and returns the
+// updated image. Images that aren't stale are returned as they are.
+func (m *manager) ReconvertImage(ctx context.Context, name string) (*Image, error) {
+	meta, ref, err := m.findImage(name)
+	if err != nil {
+		return nil, err
+	}
+	if !meta.stale() {
+		return meta.toImage(), nil
+	}
+	if err := m.reconvert(ctx, ref, false, trace.Link{}); err != nil {
+		return nil, err
+	}
+	return m.GetImage(ctx, name)
+}
+
+// findImage reads the metadata of a tag or digest reference
+func (m *manager) findImage(name string) (*imageMetadata, *ResolvedRef, error) {
+	normalized, err := ParseNormalizedRef(name)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %s", ErrInvalidName, err.Error())
+	}
+	digestHex := normalized.DigestHex()
+	if !normalized.IsDigest() {
+		digestHex, err = resolveTag(m.paths, normalized.Repository(), normalized.Tag())
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	meta, err := readMetadataFile(m.paths, normalized.Repository(), digestHex)
+	if err != nil {
+		return nil, nil, err
+	}
+	return meta, NewResolvedRef(normalized, meta.Digest), nil
+}
+
+// reconvert rebuilds a ready image's disk from the OCI cache with the
+// current converter and swaps it in. Instances already using the old disk
+// keep it open until they stop. Reconversions run one at a time; without
+// force, an image re-converted meanwhile is skipped.
+func (m *manager) reconvert(ctx context.Context, ref *ResolvedRef, force bool, link trace.Link) (err error) {
+	m.reconvertMu.Lock()
+	defer m.reconvertMu.Unlock()
+
+	meta, err := readMetadataFile(m.paths, ref.Repository(), ref.DigestHex())
+	if err != nil {
+		return err
+	}
+	if meta.Status != StatusReady {
+		return fmt.Errorf("%w: image status is %s", ErrNotReady, meta.Status)
+	}
+	if !force && !meta.stale() {
+		return nil
+	}
+	if meta.SourceFormat != "" {
+		return fmt.Errorf("%s was imported from a %s disk and can't be re-converted; import it again", meta.Name, meta.SourceFormat)
+	}
+
+	ctx, endSpan := m.startSpan(ctx, "ReconvertImage",
+		trace.WithLinks(link),
+		trace.WithAttributes(
+			attribute.String("image", ref.String()),
+			attribute.String("digest", ref.Digest()),
+			attribute.String("format", string(DefaultImageFormat)),
+		),
+	)
+	defer func() { endSpan(err) }()
+
+	buildDir := m.paths.SystemBuild(filepath.Join("reconvert", ref.DigestHex()))
+	tempDir := filepath.Join(buildDir, "rootfs")
+	if err := os.MkdirAll(buildDir, 0755); err != nil {
+		return fmt.Errorf("create build dir: %w", err)
+	}
+	defer os.RemoveAll(buildDir)
+
+	if _, err := m.ociClient.pullAndExport(ctx, ref.String(), ref.Digest(), tempDir); err != nil {
+		return fmt.Errorf("pull and export: %w", err)
+	}
+
+	// Build beside the old disk and rename over it, so the image stays
+	// usable throughout
+	diskPath := digestPath(m.paths, ref.Repository(), ref.DigestHex())
+	newPath := diskPath + ".reconvert"
+	diskSize, err := ExportRootfs(tempDir, newPath, DefaultImageFormat)
+	if err != nil {
+		os.Remove(newPath)
+		return fmt.Errorf("convert to %s: %w", DefaultImageFormat, err)
+	}
+	if err := os.Rename(newPath, diskPath); err != nil {
+		os.Remove(newPath)
+		return fmt.Errorf("replace disk: %w", err)
+	}
+
+	meta.SizeBytes = diskSize
+	meta.Format = DefaultImageFormat
+	meta.ConverterVersion = ConverterVersion
+	if err := writeMetadata(m.paths, ref.Repository(), ref.DigestHex(), meta); err != nil {
+		return fmt.Errorf("write metadata: %w", err)
+	}
+	return nil
+}
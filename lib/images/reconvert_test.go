@@ -0,0 +1,57 @@
+package images
+
+import (
+	"context"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/kernel/hypeman/lib/paths"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStaleImages(t *testing.T) {
+	p := paths.New(t.TempDir())
+	mgr, err := NewManager(p, 1, nil, nil)
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	hex := func(c string) string { return strings.Repeat(c, 64) }
+	addDigest(t, p, "docker.io/library/alpine", hex("a"), "latest", StatusReady)
+	addDigest(t, p, "docker.io/library/nginx", hex("b"), "latest", StatusReady)
+	addDigest(t, p, "docker.io/library/redis", hex("c"), "latest", StatusPending)
+
+	// Metadata written before the converter version was recorded counts as version 1
+	img, err := mgr.GetImage(ctx, "alpine:latest")
+	require.NoError(t, err)
+	assert.Equal(t, 1, img.ConverterVersion)
+	assert.False(t, img.Stale)
+
+	// A disk built in another format is stale
+	meta, err := readMetadata(p, "docker.io/library/nginx", hex("b"))
+	require.NoError(t, err)
+	meta.Format = FormatErofs
+	require.NoError(t, writeMetadata(p, "docker.io/library/nginx", hex("b"), meta))
+
+	stale, err := mgr.ListStaleImages(ctx)
+	require.NoError(t, err)
+	require.Len(t, stale, 1)
+	assert.Equal(t, "docker.io/library/nginx:latest", stale[0].Name)
+
+	// A missing disk is reported as stale rather than failing the lookup
+	require.NoError(t, os.Remove(digestPath(p, "docker.io/library/alpine", hex("a"))))
+	img, err = mgr.GetImage(ctx, "alpine:latest")
+	require.NoError(t, err)
+	assert.True(t, img.Stale)
+	_, err = readMetadata(p, "docker.io/library/alpine", hex("a"))
+	assert.Error(t, err)
+
+	// Named images must exist and be ready
+	_, err = mgr.ReconvertImages(ctx, []string{"missing:latest"})
+	assert.ErrorIs(t, err, ErrNotFound)
+	_, err = mgr.ReconvertImages(ctx, []string{"redis:latest"})
+	assert.ErrorIs(t, err, ErrNotReady)
+	_, err = mgr.ReconvertImages(ctx, []string{"Not A Ref"})
+	assert.ErrorIs(t, err, ErrInvalidName)
+}
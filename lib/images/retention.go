@@ -0,0 +1,272 @@
+package images
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kernel/hypeman/lib/diskusage"
+)
+
+// RetentionRule limits the images kept in the repositories it matches
+type RetentionRule struct {
+	// Repository is a glob of normalized repositories, where * doesn't
+	// cross a "/", like "*/builds/*" for the images builds push
+	Repository string
+	// KeepLast keeps the N most recently written tags across all matching
+	// repositories and removes the rest (0 = keep every tag)
+	KeepLast int
+	// UntaggedOlderThan removes digests no tag points at once they were
+	// created this long ago (0 = leave them to the dangling image GC)
+	UntaggedOlderThan time.Duration
+}
+
+// RetentionResult is what applying retention rules removed, or would remove
+// in a dry run
+type RetentionResult struct {
+	Tags   []string      // Tag references removed, like "10.102.0.1:8083/builds/abc:latest"
+	Images []PrunedImage // Digests removed along with their disks
+}
+
+// ParseRetentionRules parses retention rules from a semicolon-separated list
+// of "<repository>:keep=<n>,untagged=<duration>" entries, e.g.
+// "*/builds/*:keep=20,untagged=168h;10.102.0.1:8083/myapp:keep=5". The
+// first rule matching a repository applies to it.
+func ParseRetentionRules(s string) ([]RetentionRule, error) {
+	var rules []RetentionRule
+	seen := make(map[string]bool)
+	for _, entry := range strings.Split(s, ";") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		// Repositories may contain a registry port, options never a colon
+		i := strings.LastIndex(entry, ":")
+		if i <= 0 {
+			return nil, fmt.Errorf("invalid retention rule %q: expected <repository>:keep=<n>,untagged=<duration>", entry)
+		}
+		rule := RetentionRule{Repository: entry[:i]}
+		if _, err := path.Match(rule.Repository, ""); err != nil {
+			return nil, fmt.Errorf("retention rule %s: invalid repository pattern: %v", rule.Repository, err)
+		}
+		if seen[rule.Repository] {
+			return nil, fmt.Errorf("duplicate retention rule for %s", rule.Repository)
+		}
+		seen[rule.Repository] = true
+
+		for _, opt := range strings.Split(entry[i+1:], ",") {
+			name, value, ok := strings.Cut(strings.TrimSpace(opt), "=")
+			if !ok {
+				return nil, fmt.Errorf("retention rule %s: invalid option %q: expected <option>=<value>", rule.Repository, opt)
+			}
+			switch name {
+			case "keep":
+				n, err := strconv.Atoi(value)
+				if err != nil || n < 1 {
+					return nil, fmt.Errorf("retention rule %s: invalid keep %q: expected a positive count", rule.Repository, value)
+				}
+				rule.KeepLast = n
+			case "untagged":
+				d, err := time.ParseDuration(value)
+				if err != nil || d <= 0 {
+					return nil, fmt.Errorf("retention rule %s: invalid untagged %q: expected a positive duration", rule.Repository, value)
+				}
+				rule.UntaggedOlderThan = d
+			default:
+				return nil, fmt.Errorf("retention rule %s: unknown option %q", rule.Repository, name)
+			}
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+// retentionRepo is a repository's tags and digests
+type retentionRepo struct {
+	rule    int                       // Index of the rule matching it
+	tags    map[string]string         // Digest hex by tag
+	tagTime map[string]time.Time      // When each tag was last written
+	digests map[string]*imageMetadata // By digest hex
+}
+
+// retentionTag is a tag a KeepLast rule counts
+type retentionTag struct {
+	repository, tag string
+	written         time.Time
+}
+
+// ApplyRetention removes the tags and digests rules don't keep, along with
+// their disks. Tags beyond a rule's KeepLast are removed, then the digests
+// no tag points at anymore, and untagged digests older than the rule's
+// UntaggedOlderThan. Like PruneDanglingImages, images in inUse and pulls in
+// progress are kept.
+func (m *manager) ApplyRetention(ctx context.Context, rules []RetentionRule, inUse []string, dryRun bool) (*RetentionResult, error) {
+	res := &RetentionResult{Tags: []string{}, Images: []PrunedImage{}}
+	if len(rules) == 0 {
+		return res, nil
+	}
+	pins := pinImages(inUse)
+
+	// Hold off new pulls so a digest can't be reused while it's removed
+	m.createMu.Lock()
+	defer m.createMu.Unlock()
+
+	building := m.buildingDigests()
+	repos, err := m.retentionRepos(rules)
+	if err != nil {
+		return nil, err
+	}
+
+	// Tags beyond KeepLast, counted across every repository of the rule
+	untagged := make(map[string]bool) // repository@digestHex left without a tag
+	for i, rule := range rules {
+		if rule.KeepLast == 0 {
+			continue
+		}
+		var tags []retentionTag
+		for repository, repo := range repos {
+			if repo.rule != i {
+				continue
+			}
+			for tag := range repo.tags {
+				tags = append(tags, retentionTag{repository: repository, tag: tag, written: repo.tagTime[tag]})
+			}
+		}
+		sort.Slice(tags, func(a, b int) bool { return tags[a].written.After(tags[b].written) })
+		for _, t := range tags[min(rule.KeepLast, len(tags)):] {
+			ref := t.repository + ":" + t.tag
+			if pins.tags[ref] {
+				continue
+			}
+			if !dryRun {
+				if err := deleteTag(m.paths, t.repository, t.tag); err != nil && !errors.Is(err, ErrNotFound) {
+					return res, fmt.Errorf("remove tag %s: %w", ref, err)
+				}
+			}
+			res.Tags = append(res.Tags, ref)
+			repo := repos[t.repository]
+			untagged[t.repository+"@"+repo.tags[t.tag]] = true
+			delete(repo.tags, t.tag)
+		}
+	}
+
+	// Digests no tag points at
+	now := time.Now()
+	for repository, repo := range repos {
+		rule := rules[repo.rule]
+		tagged := make(map[string]bool)
+		for _, digestHex := range repo.tags {
+			tagged[digestHex] = true
+		}
+		for digestHex, meta := range repo.digests {
+			if tagged[digestHex] || pins.keepsDigest(repository, digestHex) || building[meta.Digest] {
+				continue
+			}
+			if meta.Status != StatusReady && meta.Status != StatusFailed {
+				continue
+			}
+			expired := rule.UntaggedOlderThan > 0 && now.Sub(meta.CreatedAt) > rule.UntaggedOlderThan
+			if !untagged[repository+"@"+digestHex] && !expired {
+				continue
+			}
+			dir := digestDir(m.paths, repository, digestHex)
+			img := PrunedImage{Name: meta.Name, Digest: meta.Digest, Bytes: diskusage.Bytes(dir)}
+			if !dryRun {
+				if err := os.RemoveAll(dir); err != nil {
+					return res, fmt.Errorf("remove image %s: %w", meta.Digest, err)
+				}
+			}
+			res.Images = append(res.Images, img)
+		}
+	}
+
+	sort.Strings(res.Tags)
+	sort.Slice(res.Images, func(i, j int) bool { return res.Images[i].Name < res.Images[j].Name })
+	return res, nil
+}
+
+// retentionRepos returns the tags and digests of the repositories a rule
+// matches
+func (m *manager) retentionRepos(rules []RetentionRule) (map[string]*retentionRepo, error) {
+	imagesDir := m.paths.ImagesDir()
+	repos := make(map[string]*retentionRepo)
+	repoFor := func(repository string) *retentionRepo {
+		if repo, ok := repos[repository]; ok {
+			return repo
+		}
+		for i, rule := range rules {
+			if ok, _ := path.Match(rule.Repository, repository); ok {
+				repo := &retentionRepo{
+					rule:    i,
+					tags:    make(map[string]string),
+					tagTime: make(map[string]time.Time),
+					digests: make(map[string]*imageMetadata),
+				}
+				repos[repository] = repo
+				return repo
+			}
+		}
+		repos[repository] = nil
+		return nil
+	}
+
+	err := filepath.WalkDir(imagesDir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		switch {
+		case d.Type()&fs.ModeSymlink != 0:
+			repository, err := filepath.Rel(imagesDir, filepath.Dir(p))
+			if err != nil {
+				return nil
+			}
+			repo := repoFor(repository)
+			if repo == nil {
+				return nil
+			}
+			digestHex, err := resolveTag(m.paths, repository, d.Name())
+			if err != nil {
+				return nil
+			}
+			info, err := os.Lstat(p)
+			if err != nil {
+				return nil
+			}
+			repo.tags[d.Name()] = digestHex
+			repo.tagTime[d.Name()] = info.ModTime()
+		case !d.IsDir() && d.Name() == "metadata.json":
+			dir := filepath.Dir(p)
+			repository, err := filepath.Rel(imagesDir, filepath.Dir(dir))
+			if err != nil {
+				return nil
+			}
+			repo := repoFor(repository)
+			if repo == nil {
+				return nil
+			}
+			meta, err := readMetadataFile(m.paths, repository, filepath.Base(dir))
+			if err != nil {
+				return nil
+			}
+			repo.digests[filepath.Base(dir)] = meta
+		}
+		return nil
+	})
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("walk images directory: %w", err)
+	}
+	for repository, repo := range repos {
+		if repo == nil {
+			delete(repos, repository)
+		}
+	}
+	return repos, nil
+}
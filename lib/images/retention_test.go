@@ -0,0 +1,106 @@
+package images
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kernel/hypeman/lib/paths"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/sys/unix"
+)
+
+// setTagTime sets when a tag was last written
+func setTagTime(t *testing.T, p *paths.Paths, repository, tag string, at time.Time) {
+	t.Helper()
+	ts := []unix.Timespec{unix.NsecToTimespec(at.UnixNano()), unix.NsecToTimespec(at.UnixNano())}
+	require.NoError(t, unix.UtimesNanoAt(unix.AT_FDCWD, tagSymlinkPath(p, repository, tag), ts, unix.AT_SYMLINK_NOFOLLOW))
+}
+
+func TestParseRetentionRules(t *testing.T) {
+	rules, err := ParseRetentionRules(" */builds/*:keep=20,untagged=168h ; 10.102.0.1:8083/app:keep=5;")
+	require.NoError(t, err)
+	assert.Equal(t, []RetentionRule{
+		{Repository: "*/builds/*", KeepLast: 20, UntaggedOlderThan: 168 * time.Hour},
+		{Repository: "10.102.0.1:8083/app", KeepLast: 5},
+	}, rules)
+
+	for _, bad := range []string{
+		"keep=5",
+		"app:keep=0",
+		"app:untagged=soon",
+		"app:size=5",
+		"app:keep",
+		"[:keep=1",
+		"app:keep=1;app:keep=2",
+	} {
+		_, err := ParseRetentionRules(bad)
+		assert.Error(t, err, bad)
+	}
+}
+
+func TestApplyRetention(t *testing.T) {
+	p := paths.New(t.TempDir())
+	mgr, err := NewManager(p, 1, nil, nil)
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	hex := func(c string) string { return strings.Repeat(c, 64) }
+	const reg = "10.102.0.1:8083"
+	now := time.Now()
+	// Three builds, oldest first
+	for i, c := range []string{"a", "b", "c"} {
+		repo := reg + "/builds/" + c
+		addDigest(t, p, repo, hex(c), "latest", StatusReady)
+		setTagTime(t, p, repo, "latest", now.Add(time.Duration(i-3)*time.Hour))
+	}
+	// An app with two tags and an old untagged digest
+	addDigest(t, p, reg+"/app", hex("d"), "v2", StatusReady)
+	addDigest(t, p, reg+"/app", hex("e"), "v1", StatusReady)
+	setTagTime(t, p, reg+"/app", "v1", now.Add(-time.Hour))
+	addDigest(t, p, reg+"/app", hex("f"), "", StatusReady)
+	meta, err := readMetadata(p, reg+"/app", hex("f"))
+	require.NoError(t, err)
+	meta.CreatedAt = now.Add(-48 * time.Hour)
+	require.NoError(t, writeMetadata(p, reg+"/app", hex("f"), meta))
+	// Repositories without a rule are left alone
+	addDigest(t, p, "docker.io/library/alpine", hex("0"), "", StatusReady)
+
+	rules := []RetentionRule{
+		{Repository: "*/builds/*", KeepLast: 1},
+		{Repository: reg + "/app", UntaggedOlderThan: 24 * time.Hour},
+	}
+	// The oldest build is still running an instance
+	inUse := []string{reg + "/builds/a:latest"}
+
+	res, err := mgr.ApplyRetention(ctx, rules, inUse, true)
+	require.NoError(t, err)
+	assert.Equal(t, []string{reg + "/builds/b:latest"}, res.Tags)
+	require.Len(t, res.Images, 2)
+	assert.DirExists(t, digestDir(p, reg+"/builds/b", hex("b")))
+
+	res, err = mgr.ApplyRetention(ctx, rules, inUse, false)
+	require.NoError(t, err)
+	assert.Equal(t, []string{reg + "/builds/b:latest"}, res.Tags)
+	var digests []string
+	for _, img := range res.Images {
+		digests = append(digests, img.Digest)
+	}
+	assert.ElementsMatch(t, []string{"sha256:" + hex("b"), "sha256:" + hex("f")}, digests)
+	assert.NoDirExists(t, digestDir(p, reg+"/builds/b", hex("b")))
+	assert.NoDirExists(t, digestDir(p, reg+"/app", hex("f")))
+	_, err = mgr.GetImage(ctx, reg+"/builds/b:latest")
+	assert.Error(t, err)
+
+	for _, kept := range []struct{ repo, hex string }{
+		{reg + "/builds/a", hex("a")},
+		{reg + "/builds/c", hex("c")},
+		{reg + "/app", hex("d")},
+		{reg + "/app", hex("e")},
+		{"docker.io/library/alpine", hex("0")},
+	} {
+		assert.DirExists(t, digestDir(p, kept.repo, kept.hex))
+	}
+}
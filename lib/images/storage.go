@@ -22,7 +22,68 @@ type imageMetadata struct {
 	Cmd        []string            `json:"cmd,omitempty"`
 	Env        map[string]string   `json:"env,omitempty"`
 	WorkingDir string              `json:"working_dir,omitempty"`
+	Tenant     string              `json:"tenant,omitempty"`
 	CreatedAt  time.Time           `json:"created_at"`
+
+	// PendingTags are tags imported while the image was still building,
+	// pointed at it once it's ready
+	PendingTags []string `json:"pending_tags,omitempty"`
+
+	// Imported images came from containerd, an archive or a push to the
+	// built-in registry rather than a pull, so have no upstream tag to track
+	Imported        bool       `json:"imported,omitempty"`
+	LatestDigest    string     `json:"latest_digest,omitempty"`     // Digest the tag resolves to upstream, if it has moved
+	UpdateCheckedAt *time.Time `json:"update_checked_at,omitempty"` // Last time the tag was re-resolved
+
+	// Format and ConverterVersion record how the disk was built. Disks
+	// built before they were recorded are ext4 from converter version 1.
+	Format           ExportFormat `json:"format,omitempty"`
+	ConverterVersion int          `json:"converter_version,omitempty"`
+
+	// VM disk imports record the disk's format and how to boot them: with a
+	// kernel, or through UEFI firmware for whole disks
+	SourceFormat  string `json:"source_format,omitempty"`
+	KernelVersion string `json:"kernel_version,omitempty"`
+	Firmware      string `json:"firmware,omitempty"`
+
+	diskMissing bool // Ready, but the disk file is gone (set by readMetadataFile)
+}
+
+// format returns the format the disk was built in
+func (m *imageMetadata) format() ExportFormat {
+	if m.Format == "" {
+		return FormatExt4
+	}
+	return m.Format
+}
+
+// firmware returns how the image's instances boot
+func (m *imageMetadata) firmware() string {
+	if m.Firmware == "" {
+		return FirmwareDirect
+	}
+	return m.Firmware
+}
+
+// converterVersion returns the converter version the disk was built with
+func (m *imageMetadata) converterVersion() int {
+	if m.ConverterVersion == 0 {
+		return 1
+	}
+	return m.ConverterVersion
+}
+
+// stale reports whether a ready image's disk is missing or was built in
+// another format or by an older converter, and so should be re-converted
+func (m *imageMetadata) stale() bool {
+	if m.Status != StatusReady {
+		return false
+	}
+	// Imported VM disks aren't converted from the OCI cache
+	if m.SourceFormat != "" {
+		return m.diskMissing
+	}
+	return m.diskMissing || m.format() != DefaultImageFormat || m.converterVersion() < ConverterVersion
 }
 
 func (m *imageMetadata) toImage() *Image {
@@ -31,7 +92,21 @@ func (m *imageMetadata) toImage() *Image {
 		Digest:    m.Digest,
 		Status:    m.Status,
 		Error:     m.Error,
+		Tenant:    m.Tenant,
 		CreatedAt: m.CreatedAt,
+
+		KernelVersion: m.KernelVersion,
+		Firmware:      m.firmware(),
+
+		UpdateCheckedAt: m.UpdateCheckedAt,
+	}
+	if m.Status == StatusReady {
+		img.ConverterVersion = m.converterVersion()
+		img.Stale = m.stale()
+	}
+	if m.LatestDigest != "" {
+		latest := m.LatestDigest
+		img.LatestDigest = &latest
 	}
 
 	if m.Status == StatusReady && m.SizeBytes > 0 {
@@ -118,8 +193,22 @@ func writeMetadata(p *paths.Paths, repository, digestHex string, meta *imageMeta
 	return nil
 }
 
-// readMetadata reads metadata for a digest
+// readMetadata reads metadata for a digest. A ready image whose disk is
+// missing is an error.
 func readMetadata(p *paths.Paths, repository, digestHex string) (*imageMetadata, error) {
+	meta, err := readMetadataFile(p, repository, digestHex)
+	if err != nil {
+		return nil, err
+	}
+	if meta.diskMissing {
+		return nil, fmt.Errorf("disk image missing: %s", digestPath(p, repository, digestHex))
+	}
+	return meta, nil
+}
+
+// readMetadataFile reads metadata for a digest, marking a ready image whose
+// disk is missing instead of failing, so it can be re-converted
+func readMetadataFile(p *paths.Paths, repository, digestHex string) (*imageMetadata, error) {
 	path := metadataPath(p, repository, digestHex)
 	data, err := os.ReadFile(path)
 	if err != nil {
@@ -138,7 +227,8 @@ func readMetadata(p *paths.Paths, repository, digestHex string) (*imageMetadata,
 		diskPath := digestPath(p, repository, digestHex)
 		if _, err := os.Stat(diskPath); err != nil {
 			if os.IsNotExist(err) {
-				return nil, fmt.Errorf("disk image missing: %s", diskPath)
+				meta.diskMissing = true
+				return &meta, nil
 			}
 			return nil, fmt.Errorf("stat disk image: %w", err)
 		}
@@ -14,11 +14,43 @@ type Image struct {
 	Cmd           []string
 	Env           map[string]string
 	WorkingDir    string
+	Tenant        string // Owning tenant; empty when shared by all tenants
 	CreatedAt     time.Time
+
+	LatestDigest    *string    // Digest the tag now resolves to upstream, when it has moved off Digest
+	UpdateCheckedAt *time.Time // Last time the tag was checked for updates
+
+	ConverterVersion int  // Converter version the disk was built with (0 until ready)
+	Stale            bool // Ready, but the disk is missing or outdated and should be re-converted
+
+	KernelVersion string // Kernel instances boot with; empty for the default
+	Firmware      string // How instances boot: FirmwareDirect or FirmwareUEFI
 }
 
+// How an image's instances boot
+const (
+	FirmwareDirect = "direct" // hypeman's kernel and initrd, with the image as the root filesystem
+	FirmwareUEFI   = "uefi"   // UEFI firmware, from a copy of the image's whole disk (e.g. Windows)
+)
+
 // CreateImageRequest represents a request to create an image
 type CreateImageRequest struct {
-	Name string
+	Name   string
+	Tenant string // Optional tenant label for access scoping
 }
 
+// ContainerdImportRequest represents a request to import an image from the
+// local containerd content store
+type ContainerdImportRequest struct {
+	Name      string // Image reference, as containerd names it once normalized
+	Address   string // containerd API socket (default DefaultContainerdAddress)
+	Namespace string // containerd namespace (default DefaultContainerdNamespace)
+	Tenant    string // Optional tenant label for access scoping
+}
+
+// ArchiveImportRequest represents a request to import an image from a
+// docker-save or OCI archive
+type ArchiveImportRequest struct {
+	Name   string // Image in the archive to import, and its name (optional if the archive holds one named image)
+	Tenant string // Optional tenant label for access scoping
+}
@@ -0,0 +1,154 @@
+package images
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// UpdatePolicy controls what CheckForUpdates does when an image's tag has
+// moved upstream
+type UpdatePolicy struct {
+	// AutoUpdate pulls and converts the new digest under the same name, so
+	// new instances boot the updated image. Running instances keep theirs.
+	AutoUpdate bool
+	// WebhookURL, if set, is POSTed each ImageUpdate as JSON when it's first
+	// detected
+	WebhookURL string
+}
+
+// ImageUpdate is an image whose tag now resolves to a different digest
+// upstream than the one pulled
+type ImageUpdate struct {
+	Name          string    `json:"name"`
+	CurrentDigest string    `json:"current_digest"`
+	LatestDigest  string    `json:"latest_digest"`
+	DetectedAt    time.Time `json:"detected_at"`
+	Rebuilding    bool      `json:"rebuilding"` // A pull of LatestDigest was queued
+}
+
+var webhookClient = &http.Client{Timeout: 10 * time.Second}
+
+// CheckForUpdates re-resolves the tag of each ready image pulled from a
+// registry, records on the image whether it has moved upstream, and applies
+// policy to updates not seen before. It returns every pending update, and
+// keeps checking the other images when one fails.
+func (m *manager) CheckForUpdates(ctx context.Context, policy UpdatePolicy) ([]ImageUpdate, error) {
+	metas, err := listAllTags(m.paths)
+	if err != nil {
+		return nil, fmt.Errorf("list tags: %w", err)
+	}
+
+	var updates []ImageUpdate
+	var errs []error
+	seen := make(map[string]bool)
+	for _, meta := range metas {
+		if seen[meta.Digest] || meta.Status != StatusReady || meta.Imported {
+			continue
+		}
+		seen[meta.Digest] = true
+
+		update, isNew, err := m.checkForUpdate(ctx, meta)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", meta.Name, err))
+			continue
+		}
+		if update == nil {
+			continue
+		}
+		if isNew {
+			if err := m.applyUpdatePolicy(ctx, policy, meta, update); err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", meta.Name, err))
+			}
+		}
+		updates = append(updates, *update)
+	}
+	return updates, errors.Join(errs...)
+}
+
+// checkForUpdate re-resolves meta's tag and records the result in its
+// metadata. It returns nil if the tag is still current, and whether the
+// update wasn't already recorded by an earlier check.
+func (m *manager) checkForUpdate(ctx context.Context, meta *imageMetadata) (*ImageUpdate, bool, error) {
+	ref, err := ParseNormalizedRef(meta.Name)
+	if err != nil || ref.IsDigest() {
+		return nil, false, nil
+	}
+	digestHex := strings.TrimPrefix(meta.Digest, "sha256:")
+
+	// Only the digest the tag points at locally is tracked; older digests
+	// left behind by a re-pull have already been superseded
+	if current, err := resolveTag(m.paths, ref.Repository(), ref.Tag()); err != nil || current != digestHex {
+		return nil, false, nil
+	}
+
+	latest, err := m.resolveRef(ctx, ref.String())
+	if err != nil {
+		return nil, false, err
+	}
+
+	now := time.Now()
+	previous := meta.LatestDigest
+	meta.UpdateCheckedAt = &now
+	meta.LatestDigest = ""
+	if latest.Digest() != meta.Digest {
+		meta.LatestDigest = latest.Digest()
+	}
+	if err := writeMetadata(m.paths, ref.Repository(), digestHex, meta); err != nil {
+		return nil, false, fmt.Errorf("write metadata: %w", err)
+	}
+	if meta.LatestDigest == "" {
+		return nil, false, nil
+	}
+
+	return &ImageUpdate{
+		Name:          meta.Name,
+		CurrentDigest: meta.Digest,
+		LatestDigest:  meta.LatestDigest,
+		DetectedAt:    now,
+	}, meta.LatestDigest != previous, nil
+}
+
+func (m *manager) applyUpdatePolicy(ctx context.Context, policy UpdatePolicy, meta *imageMetadata, update *ImageUpdate) error {
+	var errs []error
+	if policy.AutoUpdate {
+		// The tag moves to the new digest once its build completes
+		if _, err := m.CreateImage(ctx, CreateImageRequest{Name: meta.Name, Tenant: meta.Tenant}); err != nil {
+			errs = append(errs, fmt.Errorf("queue rebuild: %w", err))
+		} else {
+			update.Rebuilding = true
+		}
+	}
+	if policy.WebhookURL != "" {
+		if err := postWebhook(ctx, policy.WebhookURL, update); err != nil {
+			errs = append(errs, fmt.Errorf("notify webhook: %w", err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func postWebhook(ctx context.Context, url string, update *ImageUpdate) error {
+	body, err := json.Marshal(update)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := webhookClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return nil
+}
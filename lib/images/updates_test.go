@@ -0,0 +1,128 @@
+package images
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/registry"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/kernel/hypeman/lib/paths"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckForUpdates(t *testing.T) {
+	ctx := context.Background()
+	reg := httptest.NewServer(registry.New())
+	defer reg.Close()
+	tag, err := name.NewTag(strings.TrimPrefix(reg.URL, "http://") + "/app:v1")
+	require.NoError(t, err)
+	push := func() string {
+		img, err := random.Image(1024, 1)
+		require.NoError(t, err)
+		require.NoError(t, remote.Write(tag, img))
+		digest, err := img.Digest()
+		require.NoError(t, err)
+		return digest.String()
+	}
+
+	var mu sync.Mutex
+	var notified []ImageUpdate
+	hook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var u ImageUpdate
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&u))
+		mu.Lock()
+		notified = append(notified, u)
+		mu.Unlock()
+	}))
+	defer hook.Close()
+
+	mgr, err := NewManager(paths.New(t.TempDir()), 1, nil, nil)
+	require.NoError(t, err)
+
+	original := push()
+	img, err := mgr.CreateImage(ctx, CreateImageRequest{Name: tag.String()})
+	require.NoError(t, err)
+	waitForReady(t, mgr, ctx, img.Name)
+
+	// Imported images have no upstream tag and are never checked
+	imported, err := random.Image(1024, 1)
+	require.NoError(t, err)
+	_, err = mgr.ImportArchive(ctx, ArchiveImportRequest{}, bytes.NewReader(ociArchive(t, imported, "docker.io/library/local:v1")))
+	require.NoError(t, err)
+	waitForReady(t, mgr, ctx, "local:v1")
+
+	policy := UpdatePolicy{WebhookURL: hook.URL}
+	updates, err := mgr.CheckForUpdates(ctx, policy)
+	require.NoError(t, err)
+	assert.Empty(t, updates)
+	img, err = mgr.GetImage(ctx, img.Name)
+	require.NoError(t, err)
+	assert.NotNil(t, img.UpdateCheckedAt)
+	assert.Nil(t, img.LatestDigest)
+	local, err := mgr.GetImage(ctx, "local:v1")
+	require.NoError(t, err)
+	assert.Nil(t, local.UpdateCheckedAt)
+
+	t.Run("drift is recorded and notified once", func(t *testing.T) {
+		latest := push()
+		for range 2 {
+			updates, err := mgr.CheckForUpdates(ctx, policy)
+			require.NoError(t, err)
+			require.Len(t, updates, 1)
+			assert.Equal(t, original, updates[0].CurrentDigest)
+			assert.Equal(t, latest, updates[0].LatestDigest)
+			assert.False(t, updates[0].Rebuilding)
+		}
+
+		img, err := mgr.GetImage(ctx, img.Name)
+		require.NoError(t, err)
+		assert.Equal(t, original, img.Digest)
+		require.NotNil(t, img.LatestDigest)
+		assert.Equal(t, latest, *img.LatestDigest)
+
+		mu.Lock()
+		defer mu.Unlock()
+		require.Len(t, notified, 1)
+		assert.Equal(t, img.Name, notified[0].Name)
+		assert.Equal(t, latest, notified[0].LatestDigest)
+	})
+
+	t.Run("auto update rebuilds under the same name", func(t *testing.T) {
+		latest := push()
+		updates, err := mgr.CheckForUpdates(ctx, UpdatePolicy{AutoUpdate: true})
+		require.NoError(t, err)
+		require.Len(t, updates, 1)
+		assert.True(t, updates[0].Rebuilding)
+
+		require.Eventually(t, func() bool {
+			img, err := mgr.GetImage(ctx, img.Name)
+			return err == nil && img.Digest == latest && img.Status == StatusReady
+		}, 60*time.Second, 100*time.Millisecond)
+
+		updates, err = mgr.CheckForUpdates(ctx, policy)
+		require.NoError(t, err)
+		assert.Empty(t, updates)
+	})
+
+	t.Run("failing webhook is reported", func(t *testing.T) {
+		failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusInternalServerError)
+		}))
+		defer failing.Close()
+
+		push()
+		updates, err := mgr.CheckForUpdates(ctx, UpdatePolicy{WebhookURL: failing.URL})
+		assert.ErrorContains(t, err, "notify webhook")
+		assert.Len(t, updates, 1)
+	})
+}
@@ -0,0 +1,441 @@
+package images
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// DiskImageRegistry is the registry host of the synthetic names VM disk
+// imports are registered under
+const DiskImageRegistry = "disks.hypeman.local"
+
+// Source formats of imported VM disks
+const (
+	DiskFormatRaw   = "raw"
+	DiskFormatQcow2 = "qcow2"
+)
+
+const (
+	sectorSize = 512
+
+	// ext4 superblocks sit 1024 bytes into the filesystem, with the magic
+	// 56 bytes into the superblock
+	ext4MagicOffset = 1024 + 56
+	ext4Magic       = 0xef53
+
+	diskCopyChunk = 1 << 20
+)
+
+var qcow2Magic = []byte{'Q', 'F', 'I', 0xfb}
+
+// DiskImportRequest represents a request to import a raw or qcow2 VM disk
+type DiskImportRequest struct {
+	Name          string // Short name and optional tag, registered under DiskImageRegistry (default disk:<digest prefix>)
+	URL           string // Download the disk from this http(s) URL instead of reading it from the request
+	KernelVersion string // Kernel instances of the image boot (default: the server's default kernel)
+	Firmware      string // FirmwareDirect (default) or FirmwareUEFI to keep the whole disk and boot it through UEFI
+	Tenant        string // Optional tenant label for access scoping
+}
+
+// ImportDisk imports a raw or qcow2 VM disk, read from r or downloaded from
+// req.URL. The disk's root filesystem (the largest ext4 partition, or the
+// whole disk if it's a bare filesystem) becomes the image's disk, so it
+// boots like any other image; its command is /sbin/init, which runs the
+// guest's own init under systemd mode. With FirmwareUEFI, the whole disk is
+// kept instead and instances boot it through UEFI firmware, which is how
+// Windows guests run. The image is ready when this returns.
+func (m *manager) ImportDisk(ctx context.Context, req DiskImportRequest, r io.Reader) (*Image, error) {
+	firmware := req.Firmware
+	switch firmware {
+	case "":
+		firmware = FirmwareDirect
+	case FirmwareDirect:
+	case FirmwareUEFI:
+		if req.KernelVersion != "" {
+			return nil, fmt.Errorf("%w: UEFI disks boot their own kernel; kernel_version can't be set", ErrInvalidDisk)
+		}
+	default:
+		return nil, fmt.Errorf("%w: unknown firmware %q", ErrInvalidDisk, req.Firmware)
+	}
+	if strings.Contains(req.Name, "@") {
+		return nil, fmt.Errorf("%w: disk image names can't hold a digest", ErrInvalidName)
+	}
+	if req.Name != "" {
+		if _, err := ParseNormalizedRef(DiskImageRegistry + "/" + req.Name); err != nil {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidName, err.Error())
+		}
+	}
+
+	// Spool next to the OCI cache; disks can be larger than /tmp
+	tmpDir, err := os.MkdirTemp(filepath.Dir(m.paths.SystemOCICache()), "disk-import-*")
+	if err != nil {
+		return nil, fmt.Errorf("create temp dir: %w", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	spoolCtx, endSpan := m.startSpan(ctx, "ReadDiskImage")
+	srcPath := filepath.Join(tmpDir, "source.img")
+	err = spoolDisk(spoolCtx, req.URL, r, srcPath)
+	endSpan(err)
+	if err != nil {
+		return nil, err
+	}
+
+	_, endSpan = m.startSpan(ctx, "ConvertDiskImage")
+	rootfsPath := filepath.Join(tmpDir, "rootfs.ext4")
+	sourceFormat, digestHex, size, err := convertVMDisk(srcPath, filepath.Join(tmpDir, "disk.raw"), rootfsPath, firmware == FirmwareUEFI)
+	endSpan(err)
+	if err != nil {
+		return nil, err
+	}
+
+	name := req.Name
+	if name == "" {
+		name = "disk:" + digestHex[:12]
+	}
+	normalized, err := ParseNormalizedRef(DiskImageRegistry + "/" + name)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidName, err.Error())
+	}
+	ref := NewResolvedRef(normalized, "sha256:"+digestHex)
+
+	m.createMu.Lock()
+	defer m.createMu.Unlock()
+
+	if img, ok, err := m.existingImage(ref, req.Tenant); ok || err != nil {
+		return img, err
+	}
+
+	if err := os.MkdirAll(digestDir(m.paths, ref.Repository(), digestHex), 0755); err != nil {
+		return nil, fmt.Errorf("create digest directory: %w", err)
+	}
+	if err := os.Rename(rootfsPath, digestPath(m.paths, ref.Repository(), digestHex)); err != nil {
+		return nil, fmt.Errorf("move disk: %w", err)
+	}
+	meta := &imageMetadata{
+		Name:             ref.String(),
+		Digest:           ref.Digest(),
+		Status:           StatusReady,
+		SizeBytes:        size,
+		Cmd:              []string{"/sbin/init"},
+		Tenant:           req.Tenant,
+		CreatedAt:        time.Now(),
+		Imported:         true,
+		Format:           FormatExt4,
+		ConverterVersion: ConverterVersion,
+		SourceFormat:     sourceFormat,
+		KernelVersion:    req.KernelVersion,
+		Firmware:         firmware,
+	}
+	if firmware == FirmwareUEFI {
+		// The guest's own bootloader picks what to run
+		meta.Cmd = nil
+		meta.Format = FormatDisk
+	}
+	if err := writeMetadata(m.paths, ref.Repository(), digestHex, meta); err != nil {
+		return nil, fmt.Errorf("write metadata: %w", err)
+	}
+	if err := createTagSymlink(m.paths, ref.Repository(), ref.Tag(), digestHex); err != nil {
+		return nil, fmt.Errorf("create tag: %w", err)
+	}
+	return meta.toImage(), nil
+}
+
+// spoolDisk copies the disk from r, or from rawURL if set, to dst
+func spoolDisk(ctx context.Context, rawURL string, r io.Reader, dst string) error {
+	if rawURL != "" {
+		u, err := url.Parse(rawURL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+			return fmt.Errorf("%w: url must be http or https", ErrInvalidDisk)
+		}
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+		if err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidDisk, err)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return fmt.Errorf("download disk: %w", err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return fmt.Errorf("%w: download returned %s", ErrInvalidDisk, resp.Status)
+		}
+		r = resp.Body
+	}
+	if r == nil {
+		return fmt.Errorf("%w: no disk provided", ErrInvalidDisk)
+	}
+
+	f, err := os.Create(dst)
+	if err != nil {
+		return fmt.Errorf("create disk file: %w", err)
+	}
+	defer f.Close()
+	if _, err := copySparse(f, r); err != nil {
+		return fmt.Errorf("read disk: %w", err)
+	}
+	return nil
+}
+
+// convertVMDisk converts the disk at src to raw (at rawPath, if it's qcow2),
+// extracts its root filesystem to rootfsPath, or the whole disk if
+// wholeDisk is set, and returns the source format and the extracted data's
+// digest and size
+func convertVMDisk(src, rawPath, rootfsPath string, wholeDisk bool) (string, string, int64, error) {
+	format, err := detectDiskFormat(src)
+	if err != nil {
+		return "", "", 0, err
+	}
+	if format == DiskFormatQcow2 {
+		if err := qcow2ToRaw(src, rawPath); err != nil {
+			return "", "", 0, err
+		}
+		src = rawPath
+	}
+
+	disk, err := os.Open(src)
+	if err != nil {
+		return "", "", 0, fmt.Errorf("open disk: %w", err)
+	}
+	defer disk.Close()
+
+	var offset, size int64
+	if wholeDisk {
+		size, err = checkBootDisk(disk)
+	} else {
+		offset, size, err = findRootFilesystem(disk)
+	}
+	if err != nil {
+		return "", "", 0, err
+	}
+
+	out, err := os.Create(rootfsPath)
+	if err != nil {
+		return "", "", 0, fmt.Errorf("create rootfs: %w", err)
+	}
+	defer out.Close()
+	h := sha256.New()
+	n, err := copySparse(out, io.TeeReader(io.NewSectionReader(disk, offset, size), h))
+	if err != nil {
+		return "", "", 0, fmt.Errorf("extract root filesystem: %w", err)
+	}
+	if n != size {
+		return "", "", 0, fmt.Errorf("%w: root filesystem extends past the end of the disk", ErrInvalidDisk)
+	}
+	return format, hex.EncodeToString(h.Sum(nil)), size, nil
+}
+
+// detectDiskFormat tells qcow2 disks from raw ones by their magic
+func detectDiskFormat(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("open disk: %w", err)
+	}
+	defer f.Close()
+
+	magic := make([]byte, len(qcow2Magic))
+	if _, err := io.ReadFull(f, magic); err != nil {
+		return "", fmt.Errorf("%w: disk is too small", ErrInvalidDisk)
+	}
+	if bytes.Equal(magic, qcow2Magic) {
+		return DiskFormatQcow2, nil
+	}
+	return DiskFormatRaw, nil
+}
+
+// qcow2ToRaw converts a standalone qcow2 disk to raw with qemu-img. Disks
+// with a backing file are refused, since it would name a host path.
+func qcow2ToRaw(src, dst string) error {
+	qemuImg, err := exec.LookPath("qemu-img")
+	if err != nil {
+		return fmt.Errorf("qemu-img is required to import qcow2 disks: %w", err)
+	}
+
+	out, err := exec.Command(qemuImg, "info", "--output=json", "-f", "qcow2", src).Output()
+	if err != nil {
+		return fmt.Errorf("%w: not a valid qcow2 disk: %v", ErrInvalidDisk, err)
+	}
+	var info struct {
+		BackingFilename string `json:"backing-filename"`
+	}
+	if err := json.Unmarshal(out, &info); err != nil {
+		return fmt.Errorf("parse qemu-img info: %w", err)
+	}
+	if info.BackingFilename != "" {
+		return fmt.Errorf("%w: qcow2 disks with a backing file are not supported", ErrInvalidDisk)
+	}
+
+	if out, err := exec.Command(qemuImg, "convert", "-f", "qcow2", "-O", "raw", src, dst).CombinedOutput(); err != nil {
+		return fmt.Errorf("%w: qemu-img convert failed: %v, output: %s", ErrInvalidDisk, err, out)
+	}
+	return nil
+}
+
+// findRootFilesystem returns the byte range of the disk's root filesystem:
+// the whole disk if it's a bare ext4 filesystem, otherwise its largest ext4
+// partition from the GPT or MBR partition table
+func findRootFilesystem(disk *os.File) (int64, int64, error) {
+	info, err := disk.Stat()
+	if err != nil {
+		return 0, 0, fmt.Errorf("stat disk: %w", err)
+	}
+	if isExt4At(disk, 0) {
+		return 0, info.Size(), nil
+	}
+
+	parts, err := readPartitions(disk)
+	if err != nil {
+		return 0, 0, err
+	}
+	var best partition
+	for _, p := range parts {
+		if p.size > best.size && p.offset+p.size <= info.Size() && isExt4At(disk, p.offset) {
+			best = p
+		}
+	}
+	if best.size == 0 {
+		return 0, 0, fmt.Errorf("%w: no ext4 root filesystem found", ErrInvalidDisk)
+	}
+	return best.offset, best.size, nil
+}
+
+// checkBootDisk checks a disk booted whole through firmware has a partition
+// table, and returns its size
+func checkBootDisk(disk *os.File) (int64, error) {
+	info, err := disk.Stat()
+	if err != nil {
+		return 0, fmt.Errorf("stat disk: %w", err)
+	}
+	parts, err := readPartitions(disk)
+	if err != nil {
+		return 0, err
+	}
+	if len(parts) == 0 {
+		return 0, fmt.Errorf("%w: disk has no partitions", ErrInvalidDisk)
+	}
+	return info.Size(), nil
+}
+
+type partition struct {
+	offset int64
+	size   int64
+}
+
+// readPartitions reads the GPT partition table, or the primary MBR
+// partitions when there's no protective MBR
+func readPartitions(disk io.ReaderAt) ([]partition, error) {
+	mbr := make([]byte, sectorSize)
+	if _, err := disk.ReadAt(mbr, 0); err != nil {
+		return nil, fmt.Errorf("%w: read partition table: %v", ErrInvalidDisk, err)
+	}
+	if mbr[510] != 0x55 || mbr[511] != 0xaa {
+		return nil, fmt.Errorf("%w: no partition table or ext4 filesystem found", ErrInvalidDisk)
+	}
+
+	var parts []partition
+	gpt := false
+	for i := range 4 {
+		entry := mbr[446+16*i : 446+16*(i+1)]
+		if entry[4] == 0xee {
+			gpt = true
+			break
+		}
+		start := int64(binary.LittleEndian.Uint32(entry[8:]))
+		sectors := int64(binary.LittleEndian.Uint32(entry[12:]))
+		if entry[4] != 0 && sectors > 0 {
+			parts = append(parts, partition{offset: start * sectorSize, size: sectors * sectorSize})
+		}
+	}
+	if !gpt {
+		return parts, nil
+	}
+
+	header := make([]byte, 92)
+	if _, err := disk.ReadAt(header, sectorSize); err != nil || string(header[:8]) != "EFI PART" {
+		return nil, fmt.Errorf("%w: invalid GPT header", ErrInvalidDisk)
+	}
+	entriesLBA := int64(binary.LittleEndian.Uint64(header[72:]))
+	count := binary.LittleEndian.Uint32(header[80:])
+	entrySize := binary.LittleEndian.Uint32(header[84:])
+	if entrySize < 128 || count > 1024 {
+		return nil, fmt.Errorf("%w: invalid GPT header", ErrInvalidDisk)
+	}
+
+	entries := make([]byte, int(count)*int(entrySize))
+	if _, err := disk.ReadAt(entries, entriesLBA*sectorSize); err != nil {
+		return nil, fmt.Errorf("%w: read GPT entries: %v", ErrInvalidDisk, err)
+	}
+	parts = nil
+	for i := range int(count) {
+		entry := entries[i*int(entrySize):]
+		if isZero(entry[:16]) { // Unused entry
+			continue
+		}
+		first := int64(binary.LittleEndian.Uint64(entry[32:]))
+		last := int64(binary.LittleEndian.Uint64(entry[40:]))
+		if last >= first {
+			parts = append(parts, partition{offset: first * sectorSize, size: (last - first + 1) * sectorSize})
+		}
+	}
+	return parts, nil
+}
+
+// isExt4At reports whether an ext2/3/4 superblock starts 1024 bytes past offset
+func isExt4At(disk io.ReaderAt, offset int64) bool {
+	magic := make([]byte, 2)
+	if _, err := disk.ReadAt(magic, offset+ext4MagicOffset); err != nil {
+		return false
+	}
+	return binary.LittleEndian.Uint16(magic) == ext4Magic
+}
+
+// copySparse copies r to f, seeking over all-zero chunks instead of writing
+// them so the copy stays sparse, and returns the bytes copied
+func copySparse(f *os.File, r io.Reader) (int64, error) {
+	buf := make([]byte, diskCopyChunk)
+	var n int64
+	for {
+		read, err := io.ReadFull(r, buf)
+		if read > 0 {
+			if isZero(buf[:read]) {
+				if _, err := f.Seek(int64(read), io.SeekCurrent); err != nil {
+					return n, err
+				}
+			} else if _, err := f.Write(buf[:read]); err != nil {
+				return n, err
+			}
+			n += int64(read)
+		}
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			break
+		}
+		if err != nil {
+			return n, err
+		}
+	}
+	// Trailing zero chunks were only seeked over
+	return n, f.Truncate(n)
+}
+
+// isZero reports whether b holds only zero bytes
+func isZero(b []byte) bool {
+	for _, c := range b {
+		if c != 0 {
+			return false
+		}
+	}
+	return true
+}
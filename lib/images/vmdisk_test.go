@@ -0,0 +1,156 @@
+package images
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/kernel/hypeman/lib/paths"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeExt4 returns a filesystem of size bytes with just an ext4 superblock magic
+func fakeExt4(size int, fill byte) []byte {
+	fs := bytes.Repeat([]byte{fill}, size)
+	binary.LittleEndian.PutUint16(fs[ext4MagicOffset:], ext4Magic)
+	return fs
+}
+
+// mbrDisk lays out filesystems as primary MBR partitions, starting at sector 2048
+func mbrDisk(fss ...[]byte) []byte {
+	disk := make([]byte, 2048*sectorSize)
+	disk[510], disk[511] = 0x55, 0xaa
+	for i, fs := range fss {
+		entry := disk[446+16*i:]
+		entry[4] = 0x83
+		binary.LittleEndian.PutUint32(entry[8:], uint32(len(disk)/sectorSize))
+		binary.LittleEndian.PutUint32(entry[12:], uint32(len(fs)/sectorSize))
+		disk = append(disk, fs...)
+	}
+	return disk
+}
+
+// gptDisk lays out filesystems as GPT partitions, starting at sector 2048
+func gptDisk(fss ...[]byte) []byte {
+	disk := make([]byte, 2048*sectorSize)
+	disk[446+4] = 0xee
+	disk[510], disk[511] = 0x55, 0xaa
+	header := disk[sectorSize:]
+	copy(header, "EFI PART")
+	binary.LittleEndian.PutUint64(header[72:], 2)
+	binary.LittleEndian.PutUint32(header[80:], 128)
+	binary.LittleEndian.PutUint32(header[84:], 128)
+	for i, fs := range fss {
+		entry := disk[2*sectorSize+128*i:]
+		entry[0] = 0xaf // Any non-zero type GUID
+		first := uint64(len(disk) / sectorSize)
+		binary.LittleEndian.PutUint64(entry[32:], first)
+		binary.LittleEndian.PutUint64(entry[40:], first+uint64(len(fs)/sectorSize)-1)
+		disk = append(disk, fs...)
+	}
+	return disk
+}
+
+func TestFindRootFilesystem(t *testing.T) {
+	boot := fakeExt4(64*sectorSize, 1)
+	root := fakeExt4(256*sectorSize, 2)
+	swap := bytes.Repeat([]byte{3}, 512*sectorSize)
+
+	tests := []struct {
+		name string
+		disk []byte
+		want []byte
+	}{
+		{"bare filesystem", root, root},
+		{"mbr", mbrDisk(boot, root, swap), root},
+		{"gpt", gptDisk(boot, swap, root), root},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "disk.raw")
+			require.NoError(t, os.WriteFile(path, tt.disk, 0644))
+			f, err := os.Open(path)
+			require.NoError(t, err)
+			defer f.Close()
+
+			offset, size, err := findRootFilesystem(f)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, tt.disk[offset:offset+size])
+		})
+	}
+
+	path := filepath.Join(t.TempDir(), "disk.raw")
+	require.NoError(t, os.WriteFile(path, mbrDisk(swap), 0644))
+	f, err := os.Open(path)
+	require.NoError(t, err)
+	defer f.Close()
+	_, _, err = findRootFilesystem(f)
+	assert.ErrorIs(t, err, ErrInvalidDisk)
+}
+
+func TestImportDisk(t *testing.T) {
+	p := paths.New(t.TempDir())
+	mgr, err := NewManager(p, 1, nil, nil)
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	root := fakeExt4(256*sectorSize, 0)
+	img, err := mgr.ImportDisk(ctx, DiskImportRequest{Name: "linux:1.0", KernelVersion: "ch-test"}, bytes.NewReader(mbrDisk(root)))
+	require.NoError(t, err)
+	assert.Equal(t, DiskImageRegistry+"/linux:1.0", img.Name)
+	assert.Equal(t, StatusReady, img.Status)
+	assert.Equal(t, []string{"/sbin/init"}, img.Cmd)
+	assert.Equal(t, "ch-test", img.KernelVersion)
+	assert.Equal(t, FirmwareDirect, img.Firmware)
+	assert.False(t, img.Stale)
+
+	got, err := mgr.GetImage(ctx, DiskImageRegistry+"/linux:1.0")
+	require.NoError(t, err)
+	assert.Equal(t, img.Digest, got.Digest)
+	disk, err := os.ReadFile(digestPath(p, DiskImageRegistry+"/linux", got.Digest[len("sha256:"):]))
+	require.NoError(t, err)
+	assert.Equal(t, root, disk)
+
+	// A bare filesystem with the same contents has the same digest; unnamed
+	// imports are named after it
+	bare, err := mgr.ImportDisk(ctx, DiskImportRequest{}, bytes.NewReader(root))
+	require.NoError(t, err)
+	assert.Equal(t, img.Digest, bare.Digest)
+	assert.Equal(t, DiskImageRegistry+"/disk:"+img.Digest[len("sha256:"):][:12], bare.Name)
+
+	_, err = mgr.ImportDisk(ctx, DiskImportRequest{}, bytes.NewReader(make([]byte, 4096)))
+	assert.ErrorIs(t, err, ErrInvalidDisk)
+	_, err = mgr.ImportDisk(ctx, DiskImportRequest{Name: "linux@sha256:abc"}, bytes.NewReader(root))
+	assert.ErrorIs(t, err, ErrInvalidName)
+}
+
+func TestImportDiskUEFI(t *testing.T) {
+	p := paths.New(t.TempDir())
+	mgr, err := NewManager(p, 1, nil, nil)
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	// Whole disks are kept as they are, whatever their filesystems
+	ntfs := bytes.Repeat([]byte{7}, 256*sectorSize)
+	whole := gptDisk(ntfs)
+	img, err := mgr.ImportDisk(ctx, DiskImportRequest{Name: "windows:2022", Firmware: FirmwareUEFI}, bytes.NewReader(whole))
+	require.NoError(t, err)
+	assert.Equal(t, FirmwareUEFI, img.Firmware)
+	assert.Empty(t, img.Cmd)
+	assert.False(t, img.Stale)
+	disk, err := os.ReadFile(digestPath(p, DiskImageRegistry+"/windows", img.Digest[len("sha256:"):]))
+	require.NoError(t, err)
+	assert.Equal(t, whole, disk)
+
+	// They need a partition table, and boot their own kernel
+	_, err = mgr.ImportDisk(ctx, DiskImportRequest{Firmware: FirmwareUEFI}, bytes.NewReader(ntfs))
+	assert.ErrorIs(t, err, ErrInvalidDisk)
+	_, err = mgr.ImportDisk(ctx, DiskImportRequest{Firmware: FirmwareUEFI, KernelVersion: "ch-test"}, bytes.NewReader(whole))
+	assert.ErrorIs(t, err, ErrInvalidDisk)
+	_, err = mgr.ImportDisk(ctx, DiskImportRequest{Firmware: "bios"}, bytes.NewReader(whole))
+	assert.ErrorIs(t, err, ErrInvalidDisk)
+}
@@ -0,0 +1,60 @@
+package images
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kernel/hypeman/lib/paths"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWaitForImage(t *testing.T) {
+	p := paths.New(t.TempDir())
+	mgr, err := NewManager(p, 1, nil, nil)
+	require.NoError(t, err)
+	m := mgr.(*manager)
+	ctx := context.Background()
+
+	hex := strings.Repeat("a", 64)
+	name := "docker.io/library/alpine@sha256:" + hex
+	addDigest(t, p, "docker.io/library/alpine", hex, "", StatusConverting)
+
+	// Times out with the image as last seen
+	waitCtx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	img, err := mgr.WaitForImage(waitCtx, name)
+	cancel()
+	require.NoError(t, err)
+	require.Equal(t, StatusConverting, img.Status)
+
+	// Wakes up as soon as the build finishes
+	ref, err := ParseNormalizedRef(name)
+	require.NoError(t, err)
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		m.updateStatusByDigest(NewResolvedRef(ref, "sha256:"+hex), StatusFailed, nil)
+	}()
+	waitCtx, cancel = context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	img, err = mgr.WaitForImage(waitCtx, name)
+	require.NoError(t, err)
+	require.Equal(t, StatusFailed, img.Status)
+
+	// Unknown images are reported right away
+	start := time.Now()
+	_, err = mgr.WaitForImage(ctx, "nginx:latest")
+	require.ErrorIs(t, err, ErrNotFound)
+	require.Less(t, time.Since(start), time.Second)
+
+	// Tags whose first build is running are waited for
+	done := make(chan struct{})
+	defer close(done)
+	m.queue.Enqueue("sha256:"+strings.Repeat("b", 64), CreateImageRequest{Name: "docker.io/library/nginx:latest"}, func() { <-done })
+	waitCtx, cancel = context.WithTimeout(ctx, 50*time.Millisecond)
+	defer cancel()
+	start = time.Now()
+	_, err = mgr.WaitForImage(waitCtx, "nginx:latest")
+	require.ErrorIs(t, err, ErrNotFound)
+	require.GreaterOrEqual(t, time.Since(start), 50*time.Millisecond)
+}
@@ -32,6 +32,13 @@ const (
 	caddyProviderCloudflare = "cloudflare"
 )
 
+// How long Caddy holds a request to a RestoreOnDemand target while the
+// instance is restored, and how often it retries in the meantime.
+const (
+	restoreTryDuration = "60s"
+	restoreTryInterval = "250ms"
+)
+
 // SupportedDNSProviders returns a comma-separated list of supported DNS provider names.
 // Used in error messages to keep them in sync as new providers are added.
 func SupportedDNSProviders() string {
@@ -183,6 +190,11 @@ func (g *CaddyConfigGenerator) buildConfig(ctx context.Context, ingresses []Ingr
 
 	for _, ingress := range ingresses {
 		for _, rule := range ingress.Rules {
+			// Passthrough rules are served by hypeman's SNI proxy, not Caddy
+			if rule.TLSPassthrough {
+				continue
+			}
+
 			port := rule.Match.GetPort()
 			listenPorts[port] = true
 
@@ -213,6 +225,10 @@ func (g *CaddyConfigGenerator) buildConfig(ctx context.Context, ingresses []Ingr
 			// The instance expression may be a Caddy placeholder like {http.request.host.labels.2}
 			// This becomes e.g., "my-api.hypeman.internal" or "{http.request.host.labels.2}.hypeman.internal"
 			dnsHostname := fmt.Sprintf("%s.%s", instanceExpr, dns.Suffix)
+			if rule.Target.RestoreOnDemand {
+				// Resolves only once the instance is restored and its port is ready
+				dnsHostname = dns.WakeHostname(instanceExpr, rule.Target.Port)
+			}
 
 			// Build the route with DNS-based dynamic upstreams using the "a" module
 			reverseProxy := map[string]interface{}{
@@ -224,9 +240,21 @@ func (g *CaddyConfigGenerator) buildConfig(ctx context.Context, ingresses []Ingr
 					"resolver": map[string]interface{}{
 						"addresses": []string{fmt.Sprintf("127.0.0.1:%d", g.dnsResolverPort)},
 					},
+					// Re-resolve at the DNS TTL so a standby instance is woken by
+					// its next connection rather than after Caddy's default 1m
+					"refresh": fmt.Sprintf("%ds", dns.DefaultTTL),
 				},
 			}
 
+			if rule.Target.RestoreOnDemand {
+				// Hold the request while the instance is restored: failed lookups
+				// and refused dials are retried instead of answered with a 502
+				reverseProxy["load_balancing"] = map[string]interface{}{
+					"try_duration": restoreTryDuration,
+					"try_interval": restoreTryInterval,
+				}
+			}
+
 			route := map[string]interface{}{
 				"match": []interface{}{
 					map[string]interface{}{
@@ -341,6 +369,10 @@ func (g *CaddyConfigGenerator) buildConfig(ctx context.Context, ingresses []Ingr
 				"servers": map[string]interface{}{
 					"ingress": server,
 				},
+				// Request counts by host, read to autoscale instance groups
+				"metrics": map[string]interface{}{
+					"per_host": true,
+				},
 			},
 		}
 	}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"os"
+	"strings"
 	"testing"
 
 	"github.com/kernel/hypeman/lib/paths"
@@ -852,3 +853,39 @@ func TestGenerateConfig_DynamicUpstreams(t *testing.T) {
 	assert.Contains(t, configStr, "resolver")
 	assert.Contains(t, configStr, "127.0.0.1:5353")
 }
+
+func TestGenerateConfig_RestoreOnDemand(t *testing.T) {
+	generator, _, cleanup := setupTestGenerator(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	ingresses := []Ingress{
+		{
+			ID:   "ing-123",
+			Name: "scale-from-zero",
+			Rules: []IngressRule{
+				{
+					Match:  IngressMatch{Hostname: "{instance}.example.com"},
+					Target: IngressTarget{Instance: "{instance}", Port: 8080, RestoreOnDemand: true},
+				},
+				{
+					Match:  IngressMatch{Hostname: "api.example.com"},
+					Target: IngressTarget{Instance: "my-api", Port: 3000},
+				},
+			},
+		},
+	}
+
+	data, err := generator.GenerateConfig(ctx, ingresses)
+	require.NoError(t, err)
+
+	configStr := string(data)
+
+	// The restore-on-demand rule resolves through a wake name and holds requests
+	assert.Contains(t, configStr, "{http.request.host.labels.2}.8080.wake.hypeman.internal")
+	assert.Contains(t, configStr, `"try_duration": "60s"`)
+	assert.Equal(t, 1, strings.Count(configStr, "try_duration"), "only the restore-on-demand rule should retry")
+
+	// Other rules resolve normally
+	assert.Contains(t, configStr, `"my-api.hypeman.internal"`)
+}
@@ -0,0 +1,290 @@
+package ingress
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// externalDNSComment marks DNS records created by hypeman. Records without it
+// are never modified or deleted.
+const externalDNSComment = "managed by hypeman"
+
+// ExternalDNSConfig configures publishing ingress hostnames to a public DNS
+// provider. Provider credentials are taken from ACMEConfig.
+type ExternalDNSConfig struct {
+	// Provider is the DNS provider to publish to. DNSProviderNone disables publishing.
+	Provider DNSProvider
+
+	// Targets are the public IP addresses records point at: IPv4 addresses
+	// become A records and IPv6 addresses AAAA records.
+	Targets []string
+
+	// TTL is the record TTL in seconds (1 = provider automatic).
+	TTL int
+}
+
+// IsEnabled returns true if hostnames should be published.
+func (c *ExternalDNSConfig) IsEnabled() bool {
+	return c.Provider != DNSProviderNone
+}
+
+// Validate checks that publishing is fully configured.
+func (c *ExternalDNSConfig) Validate(acme ACMEConfig) error {
+	if !c.IsEnabled() {
+		return nil
+	}
+	if len(c.Targets) == 0 {
+		return fmt.Errorf("external DNS requires at least one target IP")
+	}
+	for _, t := range c.Targets {
+		if net.ParseIP(t) == nil {
+			return fmt.Errorf("invalid external DNS target IP %q", t)
+		}
+	}
+	switch c.Provider {
+	case DNSProviderCloudflare:
+		if acme.CloudflareAPIToken == "" {
+			return fmt.Errorf("external DNS with cloudflare requires CLOUDFLARE_API_TOKEN")
+		}
+	default:
+		return fmt.Errorf("unknown DNS provider %q: supported providers are: %s", c.Provider, SupportedDNSProviders())
+	}
+	return nil
+}
+
+// DNSRecordProvider manages public address records for ingress hostnames.
+type DNSRecordProvider interface {
+	// UpsertRecords makes the A/AAAA records for hostname point at exactly ips.
+	UpsertRecords(ctx context.Context, hostname string, ips []net.IP, ttl int) error
+
+	// DeleteRecords removes the A/AAAA records for hostname created by hypeman.
+	DeleteRecords(ctx context.Context, hostname string) error
+}
+
+// newDNSRecordProvider returns the record provider for cfg, or nil if publishing is disabled.
+func newDNSRecordProvider(cfg ExternalDNSConfig, acme ACMEConfig) DNSRecordProvider {
+	switch cfg.Provider {
+	case DNSProviderCloudflare:
+		return newCloudflareProvider(acme.CloudflareAPIToken)
+	default:
+		return nil
+	}
+}
+
+// externalHostname returns the public DNS name for a rule: the literal
+// hostname, or the wildcard for pattern hostnames.
+func externalHostname(rule IngressRule) string {
+	if rule.Match.IsPattern() {
+		if pattern, err := rule.Match.ParsePattern(); err == nil {
+			return pattern.Wildcard
+		}
+		return ""
+	}
+	return rule.Match.Hostname
+}
+
+// externalHostnames returns the unique public DNS names used by ingresses.
+func externalHostnames(ingresses ...Ingress) []string {
+	var names []string
+	for _, ing := range ingresses {
+		for _, rule := range ing.Rules {
+			if name := externalHostname(rule); name != "" && !slices.Contains(names, name) {
+				names = append(names, name)
+			}
+		}
+	}
+	return names
+}
+
+// cloudflareAPI is the Cloudflare v4 API base URL.
+const cloudflareAPI = "https://api.cloudflare.com/client/v4"
+
+// cloudflareProvider manages records through the Cloudflare v4 API.
+type cloudflareProvider struct {
+	baseURL string
+	token   string
+	client  *http.Client
+
+	mu    sync.Mutex
+	zones map[string]string // zone name -> zone ID
+}
+
+func newCloudflareProvider(token string) *cloudflareProvider {
+	return &cloudflareProvider{
+		baseURL: cloudflareAPI,
+		token:   token,
+		client:  &http.Client{Timeout: 30 * time.Second},
+		zones:   make(map[string]string),
+	}
+}
+
+type cloudflareRecord struct {
+	ID      string `json:"id,omitempty"`
+	Type    string `json:"type"`
+	Name    string `json:"name"`
+	Content string `json:"content"`
+	TTL     int    `json:"ttl"`
+	Comment string `json:"comment,omitempty"`
+}
+
+// do performs an API call and decodes the result into out (if non-nil).
+func (p *cloudflareProvider) do(ctx context.Context, method, path string, body, out any) error {
+	var reqBody io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reqBody = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, p.baseURL+path, reqBody)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+p.token)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("cloudflare %s %s: %w", method, path, err)
+	}
+	defer resp.Body.Close()
+
+	var envelope struct {
+		Success bool `json:"success"`
+		Errors  []struct {
+			Code    int    `json:"code"`
+			Message string `json:"message"`
+		} `json:"errors"`
+		Result json.RawMessage `json:"result"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&envelope); err != nil {
+		return fmt.Errorf("cloudflare %s %s: status %d: decode response: %w", method, path, resp.StatusCode, err)
+	}
+	if !envelope.Success {
+		msgs := make([]string, 0, len(envelope.Errors))
+		for _, e := range envelope.Errors {
+			msgs = append(msgs, fmt.Sprintf("%d: %s", e.Code, e.Message))
+		}
+		return fmt.Errorf("cloudflare %s %s: status %d: %s", method, path, resp.StatusCode, strings.Join(msgs, "; "))
+	}
+	if out != nil {
+		return json.Unmarshal(envelope.Result, out)
+	}
+	return nil
+}
+
+// zoneID finds the zone containing hostname, trying each parent domain in turn.
+func (p *cloudflareProvider) zoneID(ctx context.Context, hostname string) (string, error) {
+	labels := strings.Split(strings.TrimPrefix(hostname, "*."), ".")
+	for i := 0; i < len(labels)-1; i++ {
+		name := strings.Join(labels[i:], ".")
+
+		p.mu.Lock()
+		id, ok := p.zones[name]
+		p.mu.Unlock()
+		if ok {
+			return id, nil
+		}
+
+		var zones []struct {
+			ID string `json:"id"`
+		}
+		if err := p.do(ctx, http.MethodGet, "/zones?name="+url.QueryEscape(name), nil, &zones); err != nil {
+			return "", err
+		}
+		if len(zones) > 0 {
+			p.mu.Lock()
+			p.zones[name] = zones[0].ID
+			p.mu.Unlock()
+			return zones[0].ID, nil
+		}
+	}
+	return "", fmt.Errorf("no cloudflare zone found for %s", hostname)
+}
+
+// records lists the A and AAAA records named hostname.
+func (p *cloudflareProvider) records(ctx context.Context, zoneID, hostname string) ([]cloudflareRecord, error) {
+	var all []cloudflareRecord
+	for _, typ := range []string{"A", "AAAA"} {
+		var recs []cloudflareRecord
+		path := fmt.Sprintf("/zones/%s/dns_records?type=%s&name=%s", zoneID, typ, url.QueryEscape(hostname))
+		if err := p.do(ctx, http.MethodGet, path, nil, &recs); err != nil {
+			return nil, err
+		}
+		all = append(all, recs...)
+	}
+	return all, nil
+}
+
+func (p *cloudflareProvider) UpsertRecords(ctx context.Context, hostname string, ips []net.IP, ttl int) error {
+	zoneID, err := p.zoneID(ctx, hostname)
+	if err != nil {
+		return err
+	}
+	existing, err := p.records(ctx, zoneID, hostname)
+	if err != nil {
+		return err
+	}
+
+	want := make(map[string]string) // content -> type
+	for _, ip := range ips {
+		if ip.To4() != nil {
+			want[ip.String()] = "A"
+		} else {
+			want[ip.String()] = "AAAA"
+		}
+	}
+
+	for _, rec := range existing {
+		if rec.Comment != externalDNSComment {
+			return fmt.Errorf("%s record for %s exists and is not managed by hypeman", rec.Type, hostname)
+		}
+	}
+	for _, rec := range existing {
+		if typ, ok := want[rec.Content]; ok && typ == rec.Type && rec.TTL == ttl {
+			delete(want, rec.Content)
+			continue
+		}
+		if err := p.do(ctx, http.MethodDelete, fmt.Sprintf("/zones/%s/dns_records/%s", zoneID, rec.ID), nil, nil); err != nil {
+			return err
+		}
+	}
+	for content, typ := range want {
+		rec := cloudflareRecord{Type: typ, Name: hostname, Content: content, TTL: ttl, Comment: externalDNSComment}
+		if err := p.do(ctx, http.MethodPost, fmt.Sprintf("/zones/%s/dns_records", zoneID), rec, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *cloudflareProvider) DeleteRecords(ctx context.Context, hostname string) error {
+	zoneID, err := p.zoneID(ctx, hostname)
+	if err != nil {
+		return err
+	}
+	existing, err := p.records(ctx, zoneID, hostname)
+	if err != nil {
+		return err
+	}
+	for _, rec := range existing {
+		if rec.Comment != externalDNSComment {
+			continue
+		}
+		if err := p.do(ctx, http.MethodDelete, fmt.Sprintf("/zones/%s/dns_records/%s", zoneID, rec.ID), nil, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
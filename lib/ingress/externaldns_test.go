@@ -0,0 +1,214 @@
+package ingress
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeCloudflare is a minimal in-memory Cloudflare v4 DNS API
+type fakeCloudflare struct {
+	mu      sync.Mutex
+	zones   map[string]string // name -> id
+	records map[string]cloudflareRecord
+	nextID  int
+}
+
+func newFakeCloudflare(t *testing.T) (*fakeCloudflare, *cloudflareProvider) {
+	t.Helper()
+	f := &fakeCloudflare{
+		zones:   map[string]string{"example.com": "zone-1"},
+		records: make(map[string]cloudflareRecord),
+	}
+	srv := httptest.NewServer(f)
+	t.Cleanup(srv.Close)
+
+	p := newCloudflareProvider("test-token")
+	p.baseURL = srv.URL
+	return f, p
+}
+
+func (f *fakeCloudflare) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	reply := func(result any) {
+		json.NewEncoder(w).Encode(map[string]any{"success": true, "errors": []any{}, "result": result})
+	}
+	if r.Header.Get("Authorization") != "Bearer test-token" {
+		w.WriteHeader(http.StatusForbidden)
+		json.NewEncoder(w).Encode(map[string]any{"success": false, "errors": []any{map[string]any{"code": 9109, "message": "Invalid access token"}}})
+		return
+	}
+
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	switch {
+	case r.Method == http.MethodGet && r.URL.Path == "/zones":
+		var zones []map[string]string
+		if id, ok := f.zones[r.URL.Query().Get("name")]; ok {
+			zones = append(zones, map[string]string{"id": id})
+		}
+		reply(zones)
+	case r.Method == http.MethodGet && len(parts) == 3:
+		recs := []cloudflareRecord{}
+		for _, rec := range f.records {
+			if rec.Name == r.URL.Query().Get("name") && rec.Type == r.URL.Query().Get("type") {
+				recs = append(recs, rec)
+			}
+		}
+		reply(recs)
+	case r.Method == http.MethodPost && len(parts) == 3:
+		var rec cloudflareRecord
+		json.NewDecoder(r.Body).Decode(&rec)
+		f.nextID++
+		rec.ID = fmt.Sprintf("rec-%d", f.nextID)
+		f.records[rec.ID] = rec
+		reply(rec)
+	case r.Method == http.MethodDelete && len(parts) == 4:
+		delete(f.records, parts[3])
+		reply(map[string]string{"id": parts[3]})
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func (f *fakeCloudflare) contents(name string) []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var out []string
+	for _, rec := range f.records {
+		if rec.Name == name {
+			out = append(out, rec.Type+" "+rec.Content)
+		}
+	}
+	return out
+}
+
+func TestCloudflareProvider_UpsertAndDelete(t *testing.T) {
+	f, p := newFakeCloudflare(t)
+	ctx := context.Background()
+
+	ips := []net.IP{net.ParseIP("203.0.113.10"), net.ParseIP("2001:db8::10")}
+	require.NoError(t, p.UpsertRecords(ctx, "api.example.com", ips, 300))
+	assert.ElementsMatch(t, []string{"A 203.0.113.10", "AAAA 2001:db8::10"}, f.contents("api.example.com"))
+
+	// Idempotent
+	require.NoError(t, p.UpsertRecords(ctx, "api.example.com", ips, 300))
+	assert.Len(t, f.contents("api.example.com"), 2)
+
+	// Changing targets replaces stale records
+	require.NoError(t, p.UpsertRecords(ctx, "api.example.com", []net.IP{net.ParseIP("203.0.113.20")}, 300))
+	assert.Equal(t, []string{"A 203.0.113.20"}, f.contents("api.example.com"))
+
+	// Wildcards resolve to the parent zone
+	require.NoError(t, p.UpsertRecords(ctx, "*.dev.example.com", ips[:1], 300))
+	assert.Equal(t, []string{"A 203.0.113.10"}, f.contents("*.dev.example.com"))
+
+	require.NoError(t, p.DeleteRecords(ctx, "api.example.com"))
+	assert.Empty(t, f.contents("api.example.com"))
+}
+
+func TestCloudflareProvider_UnmanagedRecords(t *testing.T) {
+	f, p := newFakeCloudflare(t)
+	ctx := context.Background()
+	f.records["manual"] = cloudflareRecord{ID: "manual", Type: "A", Name: "www.example.com", Content: "198.51.100.1", TTL: 1}
+
+	err := p.UpsertRecords(ctx, "www.example.com", []net.IP{net.ParseIP("203.0.113.10")}, 300)
+	assert.ErrorContains(t, err, "not managed by hypeman")
+
+	require.NoError(t, p.DeleteRecords(ctx, "www.example.com"))
+	assert.Equal(t, []string{"A 198.51.100.1"}, f.contents("www.example.com"))
+}
+
+func TestCloudflareProvider_NoZone(t *testing.T) {
+	_, p := newFakeCloudflare(t)
+	err := p.UpsertRecords(context.Background(), "api.other.org", []net.IP{net.ParseIP("203.0.113.10")}, 300)
+	assert.ErrorContains(t, err, "no cloudflare zone found")
+}
+
+func TestExternalHostnames(t *testing.T) {
+	ingresses := []Ingress{
+		{Rules: []IngressRule{
+			{Match: IngressMatch{Hostname: "api.example.com"}},
+			{Match: IngressMatch{Hostname: "api.example.com", Port: 8443}},
+			{Match: IngressMatch{Hostname: "{instance}.dev.example.com"}},
+		}},
+	}
+	assert.Equal(t, []string{"api.example.com", "*.dev.example.com"}, externalHostnames(ingresses...))
+}
+
+func TestExternalDNSConfig_Validate(t *testing.T) {
+	acme := ACMEConfig{CloudflareAPIToken: "token"}
+
+	disabled := ExternalDNSConfig{}
+	require.NoError(t, disabled.Validate(ACMEConfig{}))
+
+	valid := ExternalDNSConfig{Provider: DNSProviderCloudflare, Targets: []string{"203.0.113.10"}}
+	require.NoError(t, valid.Validate(acme))
+	assert.Error(t, valid.Validate(ACMEConfig{}), "missing token")
+
+	noTargets := ExternalDNSConfig{Provider: DNSProviderCloudflare}
+	assert.Error(t, noTargets.Validate(acme))
+
+	badTarget := ExternalDNSConfig{Provider: DNSProviderCloudflare, Targets: []string{"example.com"}}
+	assert.Error(t, badTarget.Validate(acme))
+}
+
+// recordingDNSProvider records published and removed hostnames
+type recordingDNSProvider struct {
+	published []string
+	removed   []string
+}
+
+func (r *recordingDNSProvider) UpsertRecords(ctx context.Context, hostname string, ips []net.IP, ttl int) error {
+	r.published = append(r.published, hostname)
+	return nil
+}
+
+func (r *recordingDNSProvider) DeleteRecords(ctx context.Context, hostname string) error {
+	r.removed = append(r.removed, hostname)
+	return nil
+}
+
+func TestManager_ExternalDNS(t *testing.T) {
+	mgr, _, _, cleanup := setupTestManager(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	rec := &recordingDNSProvider{}
+	m := mgr.(*manager)
+	m.externalDNS = rec
+	m.config.ExternalDNS = ExternalDNSConfig{Provider: DNSProviderCloudflare, Targets: []string{"203.0.113.10"}, TTL: 300}
+
+	_, err := mgr.Create(ctx, CreateIngressRequest{
+		Name: "http",
+		Rules: []IngressRule{
+			{Match: IngressMatch{Hostname: "api.example.com"}, Target: IngressTarget{Instance: "my-api", Port: 8080}},
+			{Match: IngressMatch{Hostname: "{instance}.dev.example.com"}, Target: IngressTarget{Instance: "{instance}", Port: 8080}},
+		},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"api.example.com", "*.dev.example.com"}, rec.published)
+
+	// Same hostname on another port keeps the record alive
+	_, err = mgr.Create(ctx, CreateIngressRequest{
+		Name:  "alt-port",
+		Rules: []IngressRule{{Match: IngressMatch{Hostname: "api.example.com", Port: 8443}, Target: IngressTarget{Instance: "my-api", Port: 8443}}},
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, mgr.Delete(ctx, "http"))
+	assert.Equal(t, []string{"*.dev.example.com"}, rec.removed)
+
+	require.NoError(t, mgr.Delete(ctx, "alt-port"))
+	assert.Equal(t, []string{"*.dev.example.com", "api.example.com"}, rec.removed)
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"log/slog"
+	"net"
 	"regexp"
 	"slices"
 	"strings"
@@ -30,6 +31,11 @@ type InstanceResolver interface {
 	// ResolveInstance resolves an instance name, ID, or ID prefix to its canonical name and ID.
 	// Returns (name, id, nil) if found, or an error if the instance doesn't exist.
 	ResolveInstance(ctx context.Context, nameOrID string) (name string, id string, err error)
+
+	// WakeInstance restores the instance if it is in standby and waits until
+	// it accepts TCP connections on port, then returns its IP address.
+	// Used for rules with RestoreOnDemand.
+	WakeInstance(ctx context.Context, nameOrID string, port int) (string, error)
 }
 
 // Manager is the interface for managing ingress resources.
@@ -41,6 +47,10 @@ type Manager interface {
 	// Create creates a new ingress resource.
 	Create(ctx context.Context, req CreateIngressRequest) (*Ingress, error)
 
+	// CheckCreate runs Create's validation and conflict checks without
+	// creating anything, returning the ingress as it would be created.
+	CheckCreate(ctx context.Context, req CreateIngressRequest) (*Ingress, error)
+
 	// Get retrieves an ingress by ID, name, or ID prefix.
 	// Lookup order: exact ID match -> exact name match -> ID prefix match.
 	// Returns ErrAmbiguousName if prefix matches multiple ingresses.
@@ -54,12 +64,29 @@ type Manager interface {
 	// Returns ErrAmbiguousName if prefix matches multiple ingresses.
 	Delete(ctx context.Context, idOrName string) error
 
+	// RenameInstanceTarget points rules targeting an instance by oldName at
+	// newName instead, and reloads Caddy. Returns the number of ingresses
+	// changed; nothing is saved if Caddy rejects the new config.
+	RenameInstanceTarget(ctx context.Context, oldName, newName string) (int, error)
+
 	// Shutdown gracefully stops the ingress subsystem.
 	Shutdown(ctx context.Context) error
 
 	// AdminURL returns the Caddy admin API URL.
 	// Only valid after Initialize() has been called.
 	AdminURL() string
+
+	// CheckHealth reports whether the Caddy admin API is reachable.
+	CheckHealth(ctx context.Context) (string, error)
+
+	// RequestCounts returns how many requests have been proxied to each rule
+	// target since Caddy started, keyed by the target as rules name it.
+	RequestCounts(ctx context.Context) (map[string]uint64, error)
+
+	// UpdateACME replaces the ACME settings (credentials, CA, allowed
+	// domains) and reloads Caddy with them. The running config is kept if
+	// Caddy rejects the new one.
+	UpdateACME(ctx context.Context, acme ACMEConfig) error
 }
 
 // DefaultDNSPort is the default port for the internal DNS server.
@@ -86,6 +113,9 @@ type Config struct {
 
 	// ACME configuration for TLS certificates
 	ACME ACMEConfig
+
+	// ExternalDNS publishes ingress hostnames to a public DNS provider (optional)
+	ExternalDNS ExternalDNSConfig
 }
 
 // DefaultConfig returns the default ingress configuration.
@@ -107,6 +137,8 @@ type manager struct {
 	configGenerator  *CaddyConfigGenerator
 	logForwarder     *CaddyLogForwarder
 	dnsServer        *dns.Server
+	externalDNS      DNSRecordProvider // nil when external DNS is disabled
+	passthrough      *passthroughProxy
 	mu               sync.RWMutex
 }
 
@@ -145,6 +177,8 @@ func NewManager(p *paths.Paths, config Config, instanceResolver InstanceResolver
 		configGenerator:  configGenerator,
 		logForwarder:     logForwarder,
 		dnsServer:        dnsServer,
+		externalDNS:      newDNSRecordProvider(config.ExternalDNS, config.ACME),
+		passthrough:      newPassthroughProxy(config.ListenAddress, instanceResolver, otelLogger),
 	}
 }
 
@@ -195,13 +229,49 @@ func (m *manager) Initialize(ctx context.Context) error {
 		return fmt.Errorf("load ingresses: %w", err)
 	}
 
+	validIngresses := m.servableIngresses(ctx, ingresses)
+
+	// Generate and write config with only valid ingresses
+	if err := m.regenerateConfig(ctx, validIngresses); err != nil {
+		return fmt.Errorf("regenerate config: %w", err)
+	}
+
+	// Start Caddy daemon
+	_, err = m.daemon.Start(ctx)
+	if err != nil {
+		return fmt.Errorf("start caddy: %w", err)
+	}
+
+	// Start TLS passthrough listeners
+	if err := m.passthrough.Update(validIngresses); err != nil {
+		return fmt.Errorf("start TLS passthrough: %w", err)
+	}
+
+	// Start log forwarder (if configured) to forward Caddy system logs to OTEL
+	if m.logForwarder != nil {
+		if err := m.logForwarder.Start(ctx); err != nil {
+			log.WarnContext(ctx, "failed to start caddy log forwarder", "error", err)
+			// Non-fatal - continue without log forwarding
+		}
+	}
+
+	// Bring public DNS records in line with the configured targets
+	m.publishHostnames(ctx, externalHostnames(validIngresses...))
+
+	return nil
+}
+
+// servableIngresses returns the ingresses with the rules the ACME config
+// allows. TLS rules for hostnames outside the allowed domains are left out,
+// so Caddy doesn't try to obtain certificates for them.
+func (m *manager) servableIngresses(ctx context.Context, ingresses []Ingress) []Ingress {
+	log := logger.FromContext(ctx)
+
 	// Check if any TLS ingresses exist but TLS isn't configured
 	if HasTLSRules(ingresses) && !m.config.ACME.IsTLSConfigured() {
 		log.WarnContext(ctx, "TLS ingresses exist but ACME is not configured - TLS will not work")
 	}
 
-	// Filter out TLS ingresses with hostnames not in the allowed domains list
-	// to prevent Caddy from trying to obtain certificates for invalid domains
 	var validIngresses []Ingress
 	for _, ing := range ingresses {
 		var validRules []IngressRule
@@ -225,26 +295,47 @@ func (m *manager) Initialize(ctx context.Context) error {
 			)
 		}
 	}
+	return validIngresses
+}
 
-	// Generate and write config with only valid ingresses
-	if err := m.regenerateConfig(ctx, validIngresses); err != nil {
-		return fmt.Errorf("regenerate config: %w", err)
+// UpdateACME replaces the ACME settings and applies them to Caddy.
+func (m *manager) UpdateACME(ctx context.Context, acme ACMEConfig) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	log := logger.FromContext(ctx)
+
+	ingresses, err := m.loadAllIngresses()
+	if err != nil {
+		return fmt.Errorf("load ingresses: %w", err)
 	}
 
-	// Start Caddy daemon
-	_, err = m.daemon.Start(ctx)
+	previous := m.config.ACME
+	m.config.ACME = acme
+	generator := *m.configGenerator
+	generator.acme = acme
+	valid := m.servableIngresses(ctx, ingresses)
+
+	configData, err := generator.GenerateConfig(ctx, valid)
+	if err == nil && m.daemon.IsRunning() {
+		err = m.daemon.ReloadConfig(configData)
+	}
 	if err != nil {
-		return fmt.Errorf("start caddy: %w", err)
+		m.config.ACME = previous
+		log.ErrorContext(ctx, "failed to apply ACME config", "error", err)
+		return fmt.Errorf("%w: %v", ErrConfigValidationFailed, err)
 	}
 
-	// Start log forwarder (if configured) to forward Caddy system logs to OTEL
-	if m.logForwarder != nil {
-		if err := m.logForwarder.Start(ctx); err != nil {
-			log.WarnContext(ctx, "failed to start caddy log forwarder", "error", err)
-			// Non-fatal - continue without log forwarding
-		}
+	m.configGenerator = &generator
+	m.externalDNS = newDNSRecordProvider(m.config.ExternalDNS, acme)
+	if err := m.configGenerator.WriteConfig(ctx, valid); err != nil {
+		log.ErrorContext(ctx, "failed to write config after ACME update", "error", err)
+	}
+	if err := m.passthrough.Update(valid); err != nil {
+		log.ErrorContext(ctx, "failed to update TLS passthrough after ACME update", "error", err)
 	}
 
+	log.InfoContext(ctx, "ACME config updated", "email", acme.Email, "dns_provider", acme.DNSProvider, "ca", acme.CA)
 	return nil
 }
 
@@ -255,77 +346,9 @@ func (m *manager) Create(ctx context.Context, req CreateIngressRequest) (*Ingres
 
 	log := logger.FromContext(ctx)
 
-	// Validate request
-	if err := req.Validate(); err != nil {
-		return nil, fmt.Errorf("%w: %v", ErrInvalidRequest, err)
-	}
-
-	// Validate name format
-	if !isValidName(req.Name) {
-		return nil, fmt.Errorf("%w: name must be lowercase letters, digits, and dashes only; cannot start or end with a dash", ErrInvalidRequest)
-	}
-
-	// Check if name already exists
-	if _, err := findIngressByName(m.paths, req.Name); err == nil {
-		return nil, fmt.Errorf("%w: ingress with name %q already exists", ErrAlreadyExists, req.Name)
-	}
-
-	// Check if TLS is requested but ACME isn't configured, and validate allowed domains
-	for _, rule := range req.Rules {
-		if rule.TLS {
-			if !m.config.ACME.IsTLSConfigured() {
-				return nil, fmt.Errorf("%w: TLS requested but ACME is not configured (set ACME_EMAIL and ACME_DNS_PROVIDER)", ErrInvalidRequest)
-			}
-			// Check if domain is in the allowed list
-			// For pattern hostnames, check the wildcard pattern (e.g., "*.example.com")
-			domainToCheck := rule.Match.Hostname
-			if rule.Match.IsPattern() {
-				pattern, err := rule.Match.ParsePattern()
-				if err != nil {
-					return nil, fmt.Errorf("invalid hostname pattern: %w", err)
-				}
-				domainToCheck = pattern.Wildcard
-			}
-			if !m.config.ACME.IsDomainAllowed(domainToCheck) {
-				return nil, fmt.Errorf("%w: %q is not in TLS_ALLOWED_DOMAINS (allowed: %s)", ErrDomainNotAllowed, domainToCheck, m.config.ACME.AllowedDomains)
-			}
-		}
-	}
-
-	// Validate that all target instances exist and resolve their names (only for literal hostnames)
-	// Pattern hostnames have dynamic target instances that can't be validated at creation time
-	var resolvedInstanceIDs []string // Track IDs for logging (used for hypeman.log routing)
-	for i, rule := range req.Rules {
-		if !rule.Match.IsPattern() {
-			// Literal hostname - validate instance exists and resolve to canonical name + ID
-			resolvedName, resolvedID, err := m.instanceResolver.ResolveInstance(ctx, rule.Target.Instance)
-			if err != nil {
-				return nil, fmt.Errorf("%w: instance %q not found", ErrInstanceNotFound, rule.Target.Instance)
-			}
-			// Update the rule with the resolved instance name (human-readable for config)
-			req.Rules[i].Target.Instance = resolvedName
-			// Track ID for logging (instance directories are by ID)
-			resolvedInstanceIDs = append(resolvedInstanceIDs, resolvedID)
-		}
-		// For pattern hostnames, instance validation happens at request time via the upstream resolver
-	}
-
-	// Check for hostname conflicts (hostname + port must be unique)
-	existingIngresses, err := m.loadAllIngresses()
+	existingIngresses, resolvedInstanceIDs, err := m.admitCreate(ctx, &req)
 	if err != nil {
-		return nil, fmt.Errorf("load existing ingresses: %w", err)
-	}
-
-	for _, rule := range req.Rules {
-		newPort := rule.Match.GetPort()
-		for _, existing := range existingIngresses {
-			for _, existingRule := range existing.Rules {
-				existingPort := existingRule.Match.GetPort()
-				if existingRule.Match.Hostname == rule.Match.Hostname && existingPort == newPort {
-					return nil, fmt.Errorf("%w: hostname %q on port %d is already used by ingress %q", ErrHostnameInUse, rule.Match.Hostname, newPort, existing.Name)
-				}
-			}
-		}
+		return nil, err
 	}
 
 	// Generate ID
@@ -336,6 +359,7 @@ func (m *manager) Create(ctx context.Context, req CreateIngressRequest) (*Ingres
 		ID:        id,
 		Name:      req.Name,
 		Rules:     req.Rules,
+		Tenant:    req.Tenant,
 		CreatedAt: time.Now().UTC(),
 	}
 
@@ -348,10 +372,16 @@ func (m *manager) Create(ctx context.Context, req CreateIngressRequest) (*Ingres
 		return nil, fmt.Errorf("generate config: %w", err)
 	}
 
+	// Open any new passthrough listeners first so a busy port fails the create
+	if err := m.passthrough.Update(allIngresses); err != nil {
+		return nil, err
+	}
+
 	// Apply config to Caddy - this validates and applies atomically
 	// If Caddy rejects the config, we don't persist the ingress
 	if m.daemon.IsRunning() {
 		if err := m.daemon.ReloadConfig(configData); err != nil {
+			m.passthrough.Update(existingIngresses)
 			return nil, fmt.Errorf("%w: %v", ErrConfigValidationFailed, err)
 		}
 	}
@@ -376,6 +406,9 @@ func (m *manager) Create(ctx context.Context, req CreateIngressRequest) (*Ingres
 		return nil, fmt.Errorf("write config: %w", err)
 	}
 
+	// Publish hostnames to external DNS (best-effort; the ingress is already live)
+	m.publishHostnames(ctx, externalHostnames(ingress))
+
 	// Log creation with ingress_id and instance_id(s) for audit trail
 	// Each resolved instance gets the log in their hypeman.log (routed by instance_id)
 	for _, instanceID := range resolvedInstanceIDs {
@@ -396,6 +429,123 @@ func (m *manager) Create(ctx context.Context, req CreateIngressRequest) (*Ingres
 	return &ingress, nil
 }
 
+// CheckCreate runs Create's validation and conflict checks without creating
+// anything, returning the ingress as it would be created (without an ID).
+func (m *manager) CheckCreate(ctx context.Context, req CreateIngressRequest) (*Ingress, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	req.Rules = slices.Clone(req.Rules)
+	if _, _, err := m.admitCreate(ctx, &req); err != nil {
+		return nil, err
+	}
+	return &Ingress{
+		Name:   req.Name,
+		Rules:  req.Rules,
+		Tenant: req.Tenant,
+	}, nil
+}
+
+// admitCreate validates a create request and checks it against existing
+// ingresses. It fills in default match ports and resolves target instance
+// names in req, and returns the existing ingresses and the IDs of the
+// resolved target instances. Callers must hold m.mu.
+func (m *manager) admitCreate(ctx context.Context, req *CreateIngressRequest) ([]Ingress, []string, error) {
+	// Validate request
+	if err := req.Validate(); err != nil {
+		return nil, nil, fmt.Errorf("%w: %v", ErrInvalidRequest, err)
+	}
+
+	// Passthrough rules listen on the HTTPS port unless told otherwise
+	for i, rule := range req.Rules {
+		if rule.TLSPassthrough && rule.Match.Port == 0 {
+			req.Rules[i].Match.Port = DefaultPassthroughPort
+		}
+	}
+
+	// Validate name format
+	if !isValidName(req.Name) {
+		return nil, nil, fmt.Errorf("%w: name must be lowercase letters, digits, and dashes only; cannot start or end with a dash", ErrInvalidRequest)
+	}
+
+	// Check if name already exists
+	if _, err := findIngressByName(m.paths, req.Name); err == nil {
+		return nil, nil, fmt.Errorf("%w: ingress with name %q already exists", ErrAlreadyExists, req.Name)
+	}
+
+	// Check if TLS is requested but ACME isn't configured, and validate allowed domains
+	for _, rule := range req.Rules {
+		if rule.TLS {
+			if !m.config.ACME.IsTLSConfigured() {
+				return nil, nil, fmt.Errorf("%w: TLS requested but ACME is not configured (set ACME_EMAIL and ACME_DNS_PROVIDER)", ErrInvalidRequest)
+			}
+			// Check if domain is in the allowed list
+			// For pattern hostnames, check the wildcard pattern (e.g., "*.example.com")
+			domainToCheck := rule.Match.Hostname
+			if rule.Match.IsPattern() {
+				pattern, err := rule.Match.ParsePattern()
+				if err != nil {
+					return nil, nil, fmt.Errorf("invalid hostname pattern: %w", err)
+				}
+				domainToCheck = pattern.Wildcard
+			}
+			if !m.config.ACME.IsDomainAllowed(domainToCheck) {
+				return nil, nil, fmt.Errorf("%w: %q is not in TLS_ALLOWED_DOMAINS (allowed: %s)", ErrDomainNotAllowed, domainToCheck, m.config.ACME.AllowedDomains)
+			}
+		}
+	}
+
+	// Validate that all target instances exist and resolve their names (only for literal hostnames)
+	// Pattern hostnames have dynamic target instances that can't be validated at creation time
+	var resolvedInstanceIDs []string // Track IDs for logging (used for hypeman.log routing)
+	for i, rule := range req.Rules {
+		if !rule.Match.IsPattern() {
+			// Literal hostname - validate instance exists and resolve to canonical name + ID
+			resolvedName, resolvedID, err := m.instanceResolver.ResolveInstance(ctx, rule.Target.Instance)
+			if err != nil {
+				return nil, nil, fmt.Errorf("%w: instance %q not found", ErrInstanceNotFound, rule.Target.Instance)
+			}
+			// Update the rule with the resolved instance name (human-readable for config)
+			req.Rules[i].Target.Instance = resolvedName
+			// Track ID for logging (instance directories are by ID)
+			resolvedInstanceIDs = append(resolvedInstanceIDs, resolvedID)
+		}
+		// For pattern hostnames, instance validation happens at request time via the upstream resolver
+	}
+
+	// Check for hostname conflicts (hostname + port must be unique)
+	existingIngresses, err := m.loadAllIngresses()
+	if err != nil {
+		return nil, nil, fmt.Errorf("load existing ingresses: %w", err)
+	}
+
+	for _, rule := range req.Rules {
+		newPort := rule.Match.GetPort()
+		for _, existing := range existingIngresses {
+			for _, existingRule := range existing.Rules {
+				existingPort := existingRule.Match.GetPort()
+				if existingRule.Match.Hostname == rule.Match.Hostname && existingPort == newPort {
+					return nil, nil, fmt.Errorf("%w: hostname %q on port %d is already used by ingress %q", ErrHostnameInUse, rule.Match.Hostname, newPort, existing.Name)
+				}
+				// A port is served either by Caddy or by the passthrough proxy
+				if existingPort == newPort && existingRule.TLSPassthrough != rule.TLSPassthrough {
+					return nil, nil, fmt.Errorf("%w: port %d is used by ingress %q and can't mix TLS passthrough and proxied rules", ErrPortInUse, newPort, existing.Name)
+				}
+			}
+		}
+	}
+
+	for i, rule := range req.Rules {
+		for _, other := range req.Rules[:i] {
+			if other.Match.GetPort() == rule.Match.GetPort() && other.TLSPassthrough != rule.TLSPassthrough {
+				return nil, nil, fmt.Errorf("%w: port %d can't mix TLS passthrough and proxied rules", ErrInvalidRequest, rule.Match.GetPort())
+			}
+		}
+	}
+
+	return existingIngresses, resolvedInstanceIDs, nil
+}
+
 // Get retrieves an ingress by ID, name, or ID prefix.
 // Lookup order: exact ID match -> exact name match -> ID prefix match.
 // Returns ErrAmbiguousName if prefix matches multiple ingresses.
@@ -504,6 +654,21 @@ func (m *manager) Delete(ctx context.Context, idOrName string) error {
 		log.ErrorContext(ctx, "failed to write config after delete", "error", err)
 	}
 
+	// Close passthrough listeners no longer in use (can't fail when only removing)
+	if err := m.passthrough.Update(ingresses); err != nil {
+		log.ErrorContext(ctx, "failed to update TLS passthrough after delete", "error", err)
+	}
+
+	// Unpublish hostnames no remaining ingress uses
+	remaining := externalHostnames(ingresses...)
+	var unused []string
+	for _, name := range externalHostnames(*ingress) {
+		if !slices.Contains(remaining, name) {
+			unused = append(unused, name)
+		}
+	}
+	m.unpublishHostnames(ctx, unused)
+
 	// Log deletion with instance_id(s) for audit trail
 	// Resolve instance names to IDs for hypeman.log routing
 	hasLiteralHostname := false
@@ -539,6 +704,108 @@ func (m *manager) Delete(ctx context.Context, idOrName string) error {
 	return nil
 }
 
+// RenameInstanceTarget updates the rules that target an instance by name
+// after the instance is renamed. Rules targeting it by ID or through a
+// hostname pattern need no change.
+func (m *manager) RenameInstanceTarget(ctx context.Context, oldName, newName string) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	log := logger.FromContext(ctx)
+
+	storedList, err := loadAllIngresses(m.paths)
+	if err != nil {
+		return 0, fmt.Errorf("load ingresses: %w", err)
+	}
+
+	var changed []*storedIngress
+	ingresses := make([]Ingress, 0, len(storedList))
+	for i := range storedList {
+		stored := &storedList[i]
+		renamed := false
+		for j := range stored.Rules {
+			if stored.Rules[j].Target.Instance == oldName {
+				stored.Rules[j].Target.Instance = newName
+				renamed = true
+			}
+		}
+		if renamed {
+			changed = append(changed, stored)
+		}
+		ingresses = append(ingresses, *storedToIngress(stored))
+	}
+	if len(changed) == 0 {
+		return 0, nil
+	}
+
+	configData, err := m.configGenerator.GenerateConfig(ctx, ingresses)
+	if err != nil {
+		return 0, fmt.Errorf("generate config: %w", err)
+	}
+	if m.daemon.IsRunning() {
+		if err := m.daemon.ReloadConfig(configData); err != nil {
+			return 0, fmt.Errorf("%w: %v", ErrConfigValidationFailed, err)
+		}
+	}
+
+	for _, stored := range changed {
+		if err := saveIngress(m.paths, stored); err != nil {
+			return 0, fmt.Errorf("save ingress %s: %w", stored.ID, err)
+		}
+		log.InfoContext(ctx, "ingress target renamed",
+			"ingress_id", stored.ID,
+			"ingress_name", stored.Name,
+			"old_instance_name", oldName,
+			"instance_name", newName)
+	}
+	if err := m.configGenerator.WriteConfig(ctx, ingresses); err != nil {
+		log.ErrorContext(ctx, "failed to write config after instance rename", "error", err)
+	}
+	// Same listeners, new targets
+	if err := m.passthrough.Update(ingresses); err != nil {
+		log.ErrorContext(ctx, "failed to update TLS passthrough after instance rename", "error", err)
+	}
+	return len(changed), nil
+}
+
+// publishHostnames points external DNS records for hostnames at the configured
+// targets. Failures are logged: routing works without them.
+func (m *manager) publishHostnames(ctx context.Context, hostnames []string) {
+	if m.externalDNS == nil || len(hostnames) == 0 {
+		return
+	}
+	log := logger.FromContext(ctx)
+
+	ips := make([]net.IP, 0, len(m.config.ExternalDNS.Targets))
+	for _, t := range m.config.ExternalDNS.Targets {
+		if ip := net.ParseIP(t); ip != nil {
+			ips = append(ips, ip)
+		}
+	}
+	for _, hostname := range hostnames {
+		if err := m.externalDNS.UpsertRecords(ctx, hostname, ips, m.config.ExternalDNS.TTL); err != nil {
+			log.ErrorContext(ctx, "failed to publish external DNS records", "hostname", hostname, "error", err)
+			continue
+		}
+		log.InfoContext(ctx, "published external DNS records", "hostname", hostname, "targets", m.config.ExternalDNS.Targets)
+	}
+}
+
+// unpublishHostnames removes hypeman's external DNS records for hostnames.
+func (m *manager) unpublishHostnames(ctx context.Context, hostnames []string) {
+	if m.externalDNS == nil {
+		return
+	}
+	log := logger.FromContext(ctx)
+	for _, hostname := range hostnames {
+		if err := m.externalDNS.DeleteRecords(ctx, hostname); err != nil {
+			log.ErrorContext(ctx, "failed to remove external DNS records", "hostname", hostname, "error", err)
+			continue
+		}
+		log.InfoContext(ctx, "removed external DNS records", "hostname", hostname)
+	}
+}
+
 // Shutdown gracefully stops the ingress subsystem.
 func (m *manager) Shutdown(ctx context.Context) error {
 	m.mu.Lock()
@@ -551,6 +818,9 @@ func (m *manager) Shutdown(ctx context.Context) error {
 		m.logForwarder.Stop()
 	}
 
+	// Stop TLS passthrough listeners; established connections are left open
+	m.passthrough.Close()
+
 	// Stop DNS server
 	if m.dnsServer != nil {
 		log.InfoContext(ctx, "stopping DNS server")
@@ -581,6 +851,14 @@ func (m *manager) AdminURL() string {
 	return m.daemon.AdminURL()
 }
 
+// CheckHealth reports whether the Caddy admin API is reachable.
+func (m *manager) CheckHealth(ctx context.Context) (string, error) {
+	if !m.daemon.isAdminResponding() {
+		return "", fmt.Errorf("caddy admin API at %s not responding", m.daemon.AdminURL())
+	}
+	return fmt.Sprintf("caddy admin API responding at %s", m.daemon.AdminURL()), nil
+}
+
 // loadAllIngresses loads all ingresses and converts them to the Ingress type.
 func (m *manager) loadAllIngresses() ([]Ingress, error) {
 	storedList, err := loadAllIngresses(m.paths)
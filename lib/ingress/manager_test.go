@@ -47,6 +47,10 @@ func (m *mockInstanceResolver) ResolveInstanceIP(ctx context.Context, nameOrID s
 	return inst.ip, nil
 }
 
+func (m *mockInstanceResolver) WakeInstance(ctx context.Context, nameOrID string, port int) (string, error) {
+	return m.ResolveInstanceIP(ctx, nameOrID)
+}
+
 func (m *mockInstanceResolver) InstanceExists(ctx context.Context, nameOrID string) (bool, error) {
 	_, ok := m.instances[nameOrID]
 	return ok, nil
@@ -462,6 +466,42 @@ func TestDeleteIngress_NotFound(t *testing.T) {
 	assert.ErrorIs(t, err, ErrNotFound)
 }
 
+func TestRenameInstanceTarget(t *testing.T) {
+	manager, _, _, cleanup := setupTestManager(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	api, err := manager.Create(ctx, CreateIngressRequest{
+		Name: "api",
+		Rules: []IngressRule{
+			{Match: IngressMatch{Hostname: "api.example.com"}, Target: IngressTarget{Instance: "my-api", Port: 8080}},
+		},
+	})
+	require.NoError(t, err)
+	web, err := manager.Create(ctx, CreateIngressRequest{
+		Name: "web",
+		Rules: []IngressRule{
+			{Match: IngressMatch{Hostname: "web.example.com"}, Target: IngressTarget{Instance: "web-app", Port: 80}},
+		},
+	})
+	require.NoError(t, err)
+
+	changed, err := manager.RenameInstanceTarget(ctx, "my-api", "my-api-v2")
+	require.NoError(t, err)
+	assert.Equal(t, 1, changed)
+
+	got, err := manager.Get(ctx, api.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "my-api-v2", got.Rules[0].Target.Instance)
+	got, err = manager.Get(ctx, web.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "web-app", got.Rules[0].Target.Instance)
+
+	changed, err = manager.RenameInstanceTarget(ctx, "my-api", "other")
+	require.NoError(t, err)
+	assert.Zero(t, changed)
+}
+
 func TestValidateName(t *testing.T) {
 	validNames := []string{
 		"a",
@@ -677,6 +717,44 @@ func TestCreateIngress_TLSWithoutACME(t *testing.T) {
 	assert.Contains(t, err.Error(), "ACME is not configured")
 }
 
+func TestUpdateACME(t *testing.T) {
+	manager, _, p, cleanup := setupTestManager(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	req := CreateIngressRequest{
+		Name: "tls-ingress",
+		Rules: []IngressRule{
+			{
+				Match:  IngressMatch{Hostname: "secure.example.com", Port: 443},
+				Target: IngressTarget{Instance: "my-api", Port: 8080},
+				TLS:    true,
+			},
+		},
+	}
+	_, err := manager.Create(ctx, req)
+	require.ErrorIs(t, err, ErrInvalidRequest)
+
+	// Credentials added later take effect without a restart
+	err = manager.UpdateACME(ctx, ACMEConfig{
+		Email:              "ops@example.com",
+		DNSProvider:        DNSProviderCloudflare,
+		CloudflareAPIToken: "token",
+		AllowedDomains:     "*.example.com",
+	})
+	require.NoError(t, err)
+
+	data, err := os.ReadFile(p.CaddyConfig())
+	require.NoError(t, err)
+	assert.NotContains(t, string(data), "ops@example.com", "no TLS ingress yet, so no issuer")
+
+	_, err = manager.Create(ctx, req)
+	require.NoError(t, err)
+	data, err = os.ReadFile(p.CaddyConfig())
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "ops@example.com")
+}
+
 func TestGetIngress_Resolution(t *testing.T) {
 	// Create temp dir
 	tmpDir, err := os.MkdirTemp("", "ingress-resolution-test-*")
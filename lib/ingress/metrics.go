@@ -0,0 +1,161 @@
+package ingress
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// requestsMetric counts requests by server, handler and (with per_host) host
+const requestsMetric = "caddy_http_requests_total"
+
+// RequestCounts returns how many requests Caddy has proxied for each rule
+// target since it started, keyed by the target instance as the rule names
+// it (name or ID). Requests are attributed to targets by their Host header;
+// for hostname patterns, the captures are substituted into the target.
+// Counters reset when Caddy restarts.
+func (m *manager) RequestCounts(ctx context.Context) (map[string]uint64, error) {
+	byHost, err := m.daemon.requestsByHost(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ingresses, err := m.loadAllIngresses()
+	if err != nil {
+		return nil, err
+	}
+
+	counts := make(map[string]uint64)
+	for host, n := range byHost {
+		for _, ing := range ingresses {
+			if target, ok := targetForHost(ing.Rules, host); ok {
+				counts[target] += n
+				break
+			}
+		}
+	}
+	return counts, nil
+}
+
+// targetForHost returns the target instance of the first rule matching a
+// request host
+func targetForHost(rules []IngressRule, host string) (string, bool) {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	for _, rule := range rules {
+		if rule.TLSPassthrough {
+			continue
+		}
+		captures, ok := rule.Match.matchHost(host)
+		if !ok {
+			continue
+		}
+		target := rule.Target.Instance
+		for name, value := range captures {
+			target = strings.ReplaceAll(target, "{"+name+"}", value)
+		}
+		return target, true
+	}
+	return "", false
+}
+
+// matchHost reports whether a request host matches the hostname, returning
+// the values of its captures
+func (m *IngressMatch) matchHost(host string) (map[string]string, bool) {
+	if !m.IsPattern() {
+		return nil, strings.EqualFold(m.Hostname, host)
+	}
+	parts := strings.Split(m.canonicalHostname(), ".")
+	labels := strings.Split(strings.ToLower(host), ".")
+	if len(parts) != len(labels) {
+		return nil, false
+	}
+	captures := make(map[string]string)
+	for i, part := range parts {
+		if match := captureRegex.FindStringSubmatch(part); match != nil && match[0] == part {
+			captures[match[1]] = labels[i]
+		} else if !strings.EqualFold(part, labels[i]) {
+			return nil, false
+		}
+	}
+	return captures, true
+}
+
+// requestsByHost reads the ingress server's proxied request counts by host
+// from Caddy's metrics endpoint
+func (d *CaddyDaemon) requestsByHost(ctx context.Context) (map[string]uint64, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, d.AdminURL()+"/metrics", nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("get caddy metrics: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("get caddy metrics: status %d", resp.StatusCode)
+	}
+	return parseRequestsByHost(resp.Body)
+}
+
+// parseRequestsByHost sums the reverse_proxy request counters of the
+// ingress server by host, from Prometheus text exposition
+func parseRequestsByHost(r io.Reader) (map[string]uint64, error) {
+	counts := make(map[string]uint64)
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if !strings.HasPrefix(line, requestsMetric+"{") {
+			continue
+		}
+		end := strings.LastIndexByte(line, '}')
+		if end < 0 {
+			continue
+		}
+		labels := parseLabels(line[len(requestsMetric)+1 : end])
+		if labels["server"] != "ingress" || labels["handler"] != "reverse_proxy" || labels["host"] == "" {
+			continue
+		}
+		value, err := strconv.ParseFloat(strings.Fields(line[end+1:])[0], 64)
+		if err != nil {
+			continue
+		}
+		counts[labels["host"]] += uint64(value)
+	}
+	return counts, scanner.Err()
+}
+
+// parseLabels parses the label pairs of a metric sample, e.g.
+// code="200",host="api.example.com"
+func parseLabels(s string) map[string]string {
+	labels := make(map[string]string)
+	for s != "" {
+		eq := strings.IndexByte(s, '=')
+		if eq < 0 || eq+1 >= len(s) || s[eq+1] != '"' {
+			break
+		}
+		name := strings.TrimSpace(s[:eq])
+		s = s[eq+2:]
+		var value strings.Builder
+		i := 0
+		for ; i < len(s) && s[i] != '"'; i++ {
+			if s[i] == '\\' && i+1 < len(s) {
+				i++
+			}
+			value.WriteByte(s[i])
+		}
+		labels[name] = value.String()
+		s = strings.TrimPrefix(s[min(i+1, len(s)):], ",")
+	}
+	return labels
+}
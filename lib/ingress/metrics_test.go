@@ -0,0 +1,55 @@
+package ingress
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseRequestsByHost(t *testing.T) {
+	metrics := `# HELP caddy_http_requests_total Counter of HTTP(S) requests made.
+# TYPE caddy_http_requests_total counter
+caddy_http_requests_total{handler="reverse_proxy",host="api.example.com",server="ingress"} 120
+caddy_http_requests_total{handler="reverse_proxy",host="api.example.com:8080",server="ingress"} 5
+caddy_http_requests_total{handler="static_response",host="api.example.com",server="ingress"} 7
+caddy_http_requests_total{handler="reverse_proxy",host="web-1.apps.example.com",server="ingress"} 3e+01
+caddy_http_requests_total{handler="reverse_proxy",host="other.example.com",server="srv1"} 9
+caddy_http_request_duration_seconds_count{handler="reverse_proxy",host="api.example.com",server="ingress"} 120
+`
+	counts, err := parseRequestsByHost(strings.NewReader(metrics))
+	require.NoError(t, err)
+	assert.Equal(t, map[string]uint64{
+		"api.example.com":        120,
+		"api.example.com:8080":   5,
+		"web-1.apps.example.com": 30,
+	}, counts)
+}
+
+func TestTargetForHost(t *testing.T) {
+	rules := []IngressRule{
+		{Match: IngressMatch{Hostname: "api.example.com"}, Target: IngressTarget{Instance: "api", Port: 80}},
+		{Match: IngressMatch{Hostname: "{instance}.apps.example.com"}, Target: IngressTarget{Instance: "{instance}", Port: 80}},
+		{Match: IngressMatch{Hostname: "*.dev.example.com"}, Target: IngressTarget{Instance: "{instance}-dev", Port: 80}},
+		{Match: IngressMatch{Hostname: "tls.example.com"}, Target: IngressTarget{Instance: "tls", Port: 443}, TLSPassthrough: true},
+	}
+
+	tests := []struct {
+		host   string
+		target string
+		ok     bool
+	}{
+		{"api.example.com", "api", true},
+		{"API.example.com:8080", "api", true},
+		{"web-1.apps.example.com", "web-1", true},
+		{"web.dev.example.com", "web-dev", true},
+		{"a.b.apps.example.com", "", false},
+		{"tls.example.com", "", false},
+	}
+	for _, tt := range tests {
+		target, ok := targetForHost(rules, tt.host)
+		assert.Equal(t, tt.ok, ok, tt.host)
+		assert.Equal(t, tt.target, target, tt.host)
+	}
+}
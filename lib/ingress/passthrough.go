@@ -0,0 +1,296 @@
+package ingress
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultPassthroughPort is the listen port for TLS passthrough rules without match.port.
+const DefaultPassthroughPort = 443
+
+const (
+	// clientHelloTimeout bounds reading the TLS ClientHello from a new connection
+	clientHelloTimeout = 10 * time.Second
+
+	// passthroughDialTimeout bounds resolving and dialing the target instance.
+	// Generous so restore-on-demand targets can wake.
+	passthroughDialTimeout = 60 * time.Second
+)
+
+// errClientHelloRead aborts the handshake once the ClientHello has been seen
+var errClientHelloRead = errors.New("client hello read")
+
+// passthroughRoute routes TLS connections by SNI for one rule
+type passthroughRoute struct {
+	rule    IngressRule
+	pattern *HostnamePattern // nil for literal hostnames
+}
+
+// instanceFor returns the target instance for a server name, if the route matches it.
+func (r passthroughRoute) instanceFor(serverName string) (string, bool) {
+	if r.pattern == nil {
+		return r.rule.Target.Instance, strings.EqualFold(serverName, r.rule.Match.Hostname)
+	}
+
+	want := strings.Split(r.rule.Match.canonicalHostname(), ".")
+	got := strings.Split(strings.ToLower(serverName), ".")
+	if len(want) != len(got) {
+		return "", false
+	}
+	instance := r.rule.Target.Instance
+	for i, part := range want {
+		if m := captureRegex.FindStringSubmatch(part); m != nil {
+			if got[i] == "" {
+				return "", false
+			}
+			instance = strings.ReplaceAll(instance, "{"+m[1]+"}", got[i])
+		} else if !strings.EqualFold(part, got[i]) {
+			return "", false
+		}
+	}
+	return instance, true
+}
+
+// passthroughProxy forwards TLS connections to instances by SNI without
+// terminating TLS. It owns the listeners for ports used by passthrough rules;
+// Caddy does not listen on those ports.
+type passthroughProxy struct {
+	listenAddress string
+	resolver      InstanceResolver
+	log           *slog.Logger
+
+	mu        sync.Mutex
+	listeners map[int]net.Listener
+	routes    map[int][]passthroughRoute
+	wg        sync.WaitGroup
+}
+
+func newPassthroughProxy(listenAddress string, resolver InstanceResolver, log *slog.Logger) *passthroughProxy {
+	if log == nil {
+		log = slog.Default()
+	}
+	return &passthroughProxy{
+		listenAddress: listenAddress,
+		resolver:      resolver,
+		log:           log,
+		listeners:     make(map[int]net.Listener),
+		routes:        make(map[int][]passthroughRoute),
+	}
+}
+
+// passthroughRoutes groups the passthrough rules of ingresses by listen port.
+// Literal hostnames are ordered before patterns so they take precedence.
+func passthroughRoutes(ingresses []Ingress) map[int][]passthroughRoute {
+	routes := make(map[int][]passthroughRoute)
+	var patterns []passthroughRoute
+	for _, ing := range ingresses {
+		for _, rule := range ing.Rules {
+			if !rule.TLSPassthrough {
+				continue
+			}
+			route := passthroughRoute{rule: rule}
+			if rule.Match.IsPattern() {
+				pattern, err := rule.Match.ParsePattern()
+				if err != nil {
+					continue
+				}
+				route.pattern = pattern
+				patterns = append(patterns, route)
+				continue
+			}
+			routes[rule.Match.GetPort()] = append(routes[rule.Match.GetPort()], route)
+		}
+	}
+	for _, route := range patterns {
+		port := route.rule.Match.GetPort()
+		routes[port] = append(routes[port], route)
+	}
+	return routes
+}
+
+// Update replaces the routing table and opens or closes listeners so that
+// exactly the ports of passthrough rules are served. If a port can't be
+// opened, nothing is changed.
+func (p *passthroughProxy) Update(ingresses []Ingress) error {
+	routes := passthroughRoutes(ingresses)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	opened := make(map[int]net.Listener)
+	for port := range routes {
+		if _, ok := p.listeners[port]; ok {
+			continue
+		}
+		ln, err := net.Listen("tcp", net.JoinHostPort(p.listenAddress, strconv.Itoa(port)))
+		if err != nil {
+			for _, l := range opened {
+				l.Close()
+			}
+			return fmt.Errorf("%w: passthrough port %d: %v", ErrPortInUse, port, err)
+		}
+		opened[port] = ln
+	}
+
+	for port, ln := range p.listeners {
+		if _, ok := routes[port]; !ok {
+			ln.Close()
+			delete(p.listeners, port)
+		}
+	}
+	for port, ln := range opened {
+		p.listeners[port] = ln
+		p.wg.Add(1)
+		go p.serve(port, ln)
+	}
+	p.routes = routes
+	return nil
+}
+
+// Close stops all listeners and waits for their accept loops to exit.
+// Established connections are left to finish.
+func (p *passthroughProxy) Close() {
+	p.mu.Lock()
+	for port, ln := range p.listeners {
+		ln.Close()
+		delete(p.listeners, port)
+	}
+	p.routes = make(map[int][]passthroughRoute)
+	p.mu.Unlock()
+	p.wg.Wait()
+}
+
+func (p *passthroughProxy) serve(port int, ln net.Listener) {
+	defer p.wg.Done()
+	p.log.Info("TLS passthrough listening", "port", port)
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return
+			}
+			p.log.Warn("TLS passthrough accept failed", "port", port, "error", err)
+			continue
+		}
+		go p.handle(port, conn)
+	}
+}
+
+// route finds the target instance and port for a server name on a listen port
+func (p *passthroughProxy) route(port int, serverName string) (passthroughRoute, string, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, r := range p.routes[port] {
+		if instance, ok := r.instanceFor(serverName); ok {
+			return r, instance, true
+		}
+	}
+	return passthroughRoute{}, "", false
+}
+
+func (p *passthroughProxy) handle(port int, conn net.Conn) {
+	defer conn.Close()
+
+	conn.SetReadDeadline(time.Now().Add(clientHelloTimeout))
+	serverName, hello, err := peekClientHello(conn)
+	if err != nil {
+		p.log.Debug("TLS passthrough: no client hello", "remote", conn.RemoteAddr(), "error", err)
+		return
+	}
+	conn.SetReadDeadline(time.Time{})
+
+	route, instance, ok := p.route(port, serverName)
+	if !ok {
+		p.log.Debug("TLS passthrough: no route for server name", "port", port, "server_name", serverName)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), passthroughDialTimeout)
+	defer cancel()
+
+	var ip string
+	if route.rule.Target.RestoreOnDemand {
+		ip, err = p.resolver.WakeInstance(ctx, instance, route.rule.Target.Port)
+	} else {
+		ip, err = p.resolver.ResolveInstanceIP(ctx, instance)
+	}
+	if err != nil {
+		p.log.Debug("TLS passthrough: resolve instance failed", "server_name", serverName, "instance", instance, "error", err)
+		return
+	}
+
+	var d net.Dialer
+	backend, err := d.DialContext(ctx, "tcp", net.JoinHostPort(ip, strconv.Itoa(route.rule.Target.Port)))
+	if err != nil {
+		p.log.Debug("TLS passthrough: dial instance failed", "server_name", serverName, "instance", instance, "error", err)
+		return
+	}
+	defer backend.Close()
+
+	if _, err := backend.Write(hello); err != nil {
+		return
+	}
+	pipe(conn, backend)
+}
+
+// pipe copies in both directions until both sides are done
+func pipe(a, b net.Conn) {
+	done := make(chan struct{}, 2)
+	copyHalf := func(dst, src net.Conn) {
+		io.Copy(dst, src)
+		if cw, ok := dst.(interface{ CloseWrite() error }); ok {
+			cw.CloseWrite()
+		} else {
+			dst.Close()
+		}
+		done <- struct{}{}
+	}
+	go copyHalf(a, b)
+	go copyHalf(b, a)
+	<-done
+	<-done
+}
+
+// peekClientHello reads the TLS ClientHello from conn and returns its server
+// name along with the bytes consumed, which must be replayed to the backend.
+func peekClientHello(conn net.Conn) (string, []byte, error) {
+	var buf bytes.Buffer
+	var serverName string
+	err := tls.Server(helloConn{r: io.TeeReader(conn, &buf)}, &tls.Config{
+		GetConfigForClient: func(hello *tls.ClientHelloInfo) (*tls.Config, error) {
+			serverName = hello.ServerName
+			return nil, errClientHelloRead
+		},
+	}).Handshake()
+	if !errors.Is(err, errClientHelloRead) {
+		return "", nil, fmt.Errorf("read client hello: %w", err)
+	}
+	if serverName == "" {
+		return "", nil, fmt.Errorf("client hello has no server name")
+	}
+	return serverName, buf.Bytes(), nil
+}
+
+// helloConn is a read-only net.Conn used to parse a ClientHello
+type helloConn struct {
+	r io.Reader
+}
+
+func (c helloConn) Read(b []byte) (int, error)         { return c.r.Read(b) }
+func (c helloConn) Write(b []byte) (int, error)        { return 0, io.ErrClosedPipe }
+func (c helloConn) Close() error                       { return nil }
+func (c helloConn) LocalAddr() net.Addr                { return nil }
+func (c helloConn) RemoteAddr() net.Addr               { return nil }
+func (c helloConn) SetDeadline(t time.Time) error      { return nil }
+func (c helloConn) SetReadDeadline(t time.Time) error  { return nil }
+func (c helloConn) SetWriteDeadline(t time.Time) error { return nil }
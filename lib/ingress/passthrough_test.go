@@ -0,0 +1,202 @@
+package ingress
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPassthroughRoute_InstanceFor(t *testing.T) {
+	routes := passthroughRoutes([]Ingress{{Rules: []IngressRule{
+		{Match: IngressMatch{Hostname: "*.apps.example.com", Port: 443}, Target: IngressTarget{Instance: "{instance}", Port: 8443}, TLSPassthrough: true},
+		{Match: IngressMatch{Hostname: "db.apps.example.com", Port: 443}, Target: IngressTarget{Instance: "postgres", Port: 5432}, TLSPassthrough: true},
+		{Match: IngressMatch{Hostname: "{app}.{env}.example.com", Port: 8443}, Target: IngressTarget{Instance: "{app}-{env}", Port: 443}, TLSPassthrough: true},
+		{Match: IngressMatch{Hostname: "api.example.com"}, Target: IngressTarget{Instance: "my-api", Port: 8080}},
+	}}})
+	require.Len(t, routes, 2, "only passthrough rules are routed")
+
+	lookup := func(port int, serverName string) (string, bool) {
+		for _, r := range routes[port] {
+			if instance, ok := r.instanceFor(serverName); ok {
+				return instance, true
+			}
+		}
+		return "", false
+	}
+
+	tests := []struct {
+		port       int
+		serverName string
+		instance   string
+		ok         bool
+	}{
+		{443, "web.apps.example.com", "web", true},
+		{443, "WEB.Apps.Example.com", "web", true},
+		{443, "db.apps.example.com", "postgres", true}, // literal beats wildcard
+		{443, "a.b.apps.example.com", "", false},       // single label only
+		{443, "apps.example.com", "", false},
+		{443, "web.other.com", "", false},
+		{8443, "shop.staging.example.com", "shop-staging", true},
+		{8443, "web.example.com", "", false},
+	}
+	for _, tc := range tests {
+		instance, ok := lookup(tc.port, tc.serverName)
+		assert.Equal(t, tc.ok, ok, "%s:%d", tc.serverName, tc.port)
+		if tc.ok {
+			assert.Equal(t, tc.instance, instance, "%s:%d", tc.serverName, tc.port)
+		}
+	}
+}
+
+func TestPeekClientHello(t *testing.T) {
+	client, server := net.Pipe()
+	defer server.Close()
+
+	go func() {
+		tls.Client(client, &tls.Config{ServerName: "web.apps.example.com", InsecureSkipVerify: true}).Handshake()
+		client.Close()
+	}()
+
+	serverName, hello, err := peekClientHello(server)
+	require.NoError(t, err)
+	assert.Equal(t, "web.apps.example.com", serverName)
+	require.NotEmpty(t, hello)
+	assert.Equal(t, byte(0x16), hello[0], "replayed bytes start with a TLS handshake record")
+}
+
+func TestPeekClientHello_NotTLS(t *testing.T) {
+	client, server := net.Pipe()
+	defer server.Close()
+
+	go func() {
+		client.Write([]byte("GET / HTTP/1.1\r\nHost: web.apps.example.com\r\n\r\n"))
+		client.Close()
+	}()
+
+	_, _, err := peekClientHello(server)
+	assert.Error(t, err)
+}
+
+func TestPassthroughProxy(t *testing.T) {
+	// Backend terminates TLS itself with its own certificate
+	backend := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "hello from %s", r.Host)
+	}))
+	defer backend.Close()
+	backendPort := backend.Listener.Addr().(*net.TCPAddr).Port
+
+	resolver := newMockResolver()
+	resolver.AddInstance("web", "127.0.0.1")
+
+	port := getFreePort(t)
+	proxy := newPassthroughProxy("127.0.0.1", resolver, nil)
+	defer proxy.Close()
+	require.NoError(t, proxy.Update([]Ingress{{Rules: []IngressRule{{
+		Match:          IngressMatch{Hostname: "*.apps.example.com", Port: port},
+		Target:         IngressTarget{Instance: "{instance}", Port: backendPort},
+		TLSPassthrough: true,
+	}}}}))
+
+	conn, err := tls.Dial("tcp", net.JoinHostPort("127.0.0.1", strconv.Itoa(port)), &tls.Config{
+		ServerName:         "web.apps.example.com",
+		InsecureSkipVerify: true,
+	})
+	require.NoError(t, err)
+	fmt.Fprintf(conn, "GET / HTTP/1.1\r\nHost: web.apps.example.com\r\nConnection: close\r\n\r\n")
+	body, err := io.ReadAll(conn)
+	conn.Close()
+	require.NoError(t, err)
+	assert.Contains(t, string(body), "hello from web.apps.example.com")
+
+	// The backend's own certificate is presented, so TLS wasn't terminated
+	state := tlsState(t, port, "web.apps.example.com")
+	assert.Equal(t, backend.Certificate().Raw, state.PeerCertificates[0].Raw)
+
+	// Unknown server names are dropped
+	_, err = tls.Dial("tcp", net.JoinHostPort("127.0.0.1", strconv.Itoa(port)), &tls.Config{
+		ServerName:         "web.other.com",
+		InsecureSkipVerify: true,
+	})
+	assert.Error(t, err)
+
+	// Removing the rule closes the listener
+	require.NoError(t, proxy.Update(nil))
+	_, err = net.DialTimeout("tcp", net.JoinHostPort("127.0.0.1", strconv.Itoa(port)), time.Second)
+	assert.Error(t, err)
+}
+
+// tlsState completes a handshake through the proxy and returns the connection state
+func tlsState(t *testing.T, port int, serverName string) tls.ConnectionState {
+	t.Helper()
+	conn, err := tls.Dial("tcp", net.JoinHostPort("127.0.0.1", strconv.Itoa(port)), &tls.Config{
+		ServerName:         serverName,
+		InsecureSkipVerify: true,
+	})
+	require.NoError(t, err)
+	defer conn.Close()
+	return conn.ConnectionState()
+}
+
+func TestCreateIngress_TLSPassthrough(t *testing.T) {
+	mgr, _, _, cleanup := setupTestManager(t)
+	defer cleanup()
+	ctx := context.Background()
+	m := mgr.(*manager)
+	m.passthrough.listenAddress = "127.0.0.1"
+	defer m.passthrough.Close()
+
+	port := getFreePort(t)
+	ing, err := mgr.Create(ctx, CreateIngressRequest{
+		Name: "passthrough",
+		Rules: []IngressRule{{
+			Match:          IngressMatch{Hostname: "*.apps.example.com", Port: port},
+			Target:         IngressTarget{Instance: "{instance}", Port: 8443},
+			TLSPassthrough: true,
+		}},
+	})
+	require.NoError(t, err)
+	assert.True(t, ing.Rules[0].TLSPassthrough)
+
+	// Passthrough rules aren't part of Caddy's config
+	data, err := m.configGenerator.GenerateConfig(ctx, []Ingress{*ing})
+	require.NoError(t, err)
+	assert.NotContains(t, string(data), "apps.example.com")
+
+	// A proxied rule can't share the passthrough port
+	_, err = mgr.Create(ctx, CreateIngressRequest{
+		Name:  "proxied",
+		Rules: []IngressRule{{Match: IngressMatch{Hostname: "api.example.com", Port: port}, Target: IngressTarget{Instance: "my-api", Port: 8080}}},
+	})
+	assert.ErrorIs(t, err, ErrPortInUse)
+
+	// Deleting the ingress closes its listener
+	require.NoError(t, mgr.Delete(ctx, "passthrough"))
+	_, err = net.DialTimeout("tcp", net.JoinHostPort("127.0.0.1", strconv.Itoa(port)), time.Second)
+	assert.Error(t, err)
+}
+
+func TestCreateIngress_TLSPassthroughDefaultPort(t *testing.T) {
+	mgr, _, _, cleanup := setupTestManager(t)
+	defer cleanup()
+
+	// Mixing in one request is rejected before any listener is opened
+	_, err := mgr.Create(context.Background(), CreateIngressRequest{
+		Name: "mixed",
+		Rules: []IngressRule{
+			{Match: IngressMatch{Hostname: "*.apps.example.com"}, Target: IngressTarget{Instance: "{instance}", Port: 8443}, TLSPassthrough: true},
+			{Match: IngressMatch{Hostname: "api.example.com", Port: DefaultPassthroughPort}, Target: IngressTarget{Instance: "my-api", Port: 8080}},
+		},
+	})
+	assert.ErrorIs(t, err, ErrInvalidRequest)
+	assert.Contains(t, err.Error(), fmt.Sprintf("port %d", DefaultPassthroughPort))
+}
@@ -20,6 +20,9 @@ type Ingress struct {
 	// Rules define the routing rules for this ingress.
 	Rules []IngressRule `json:"rules"`
 
+	// Tenant is an optional label used to scope access to this ingress.
+	Tenant string `json:"tenant,omitempty"`
+
 	// CreatedAt is the timestamp when this ingress was created.
 	CreatedAt time.Time `json:"created_at"`
 }
@@ -39,6 +42,11 @@ type IngressRule struct {
 	// RedirectHTTP creates an automatic HTTP to HTTPS redirect for this hostname.
 	// Only applies when TLS is enabled.
 	RedirectHTTP bool `json:"redirect_http,omitempty"`
+
+	// TLSPassthrough routes TLS connections by SNI without terminating TLS,
+	// for workloads that manage their own certificates. The raw TCP stream is
+	// forwarded to the target. Incompatible with TLS and RedirectHTTP.
+	TLSPassthrough bool `json:"tls_passthrough,omitempty"`
 }
 
 // IngressMatch specifies the conditions for matching incoming requests.
@@ -46,11 +54,12 @@ type IngressMatch struct {
 	// Hostname is the hostname to match. Can be:
 	// - Literal: "api.example.com" (exact match on Host header)
 	// - Pattern: "{instance}.example.com" (dynamic, extracts subdomain as instance name)
+	// - Wildcard: "*.example.com" (shorthand for "{instance}.example.com")
 	// This is required.
 	Hostname string `json:"hostname"`
 
 	// Port is the host port to listen on for this rule.
-	// If not specified, defaults to 80.
+	// If not specified, defaults to 80 (443 for TLS passthrough rules).
 	Port int `json:"port,omitempty"`
 
 	// PathPrefix is the path prefix to match (optional, for future L7 routing).
@@ -61,9 +70,21 @@ type IngressMatch struct {
 // captureRegex matches {name} captures in hostname patterns
 var captureRegex = regexp.MustCompile(`\{([a-zA-Z_][a-zA-Z0-9_]*)\}`)
 
-// IsPattern returns true if the hostname contains {name} captures.
+// wildcardCapture is the capture a leading "*" hostname label stands for
+const wildcardCapture = "{instance}"
+
+// IsPattern returns true if the hostname contains {name} captures or a leading wildcard label.
 func (m *IngressMatch) IsPattern() bool {
-	return captureRegex.MatchString(m.Hostname)
+	return captureRegex.MatchString(m.canonicalHostname())
+}
+
+// canonicalHostname returns the hostname with a leading "*" label written as
+// the {instance} capture, e.g. "*.apps.example.com" → "{instance}.apps.example.com".
+func (m *IngressMatch) canonicalHostname() string {
+	if strings.HasPrefix(m.Hostname, "*.") {
+		return wildcardCapture + m.Hostname[1:]
+	}
+	return m.Hostname
 }
 
 // HostnamePattern represents a parsed hostname pattern with captures.
@@ -96,7 +117,7 @@ func (m *IngressMatch) ParsePattern() (*HostnamePattern, error) {
 	}
 
 	// Split hostname into parts
-	parts := strings.Split(m.Hostname, ".")
+	parts := strings.Split(m.canonicalHostname(), ".")
 	if len(parts) < 2 {
 		return nil, fmt.Errorf("hostname pattern %q must have at least two parts", m.Hostname)
 	}
@@ -165,6 +186,11 @@ type IngressTarget struct {
 
 	// Port is the port on the target instance.
 	Port int `json:"port"`
+
+	// RestoreOnDemand restores the instance when a request arrives while it
+	// is in standby. The request is held until the instance accepts
+	// connections on Port, then proxied.
+	RestoreOnDemand bool `json:"restore_on_demand,omitempty"`
 }
 
 // CreateIngressRequest is the request body for creating a new ingress.
@@ -174,6 +200,9 @@ type CreateIngressRequest struct {
 
 	// Rules define the routing rules for this ingress.
 	Rules []IngressRule `json:"rules"`
+
+	// Tenant is an optional label used to scope access to this ingress.
+	Tenant string `json:"tenant,omitempty"`
 }
 
 // Validate validates the CreateIngressRequest.
@@ -191,6 +220,11 @@ func (r *CreateIngressRequest) Validate() error {
 			return &ValidationError{Field: "rules", Message: "hostname is required in rule " + strconv.Itoa(i)}
 		}
 
+		// Wildcards are only supported as a whole leading label
+		if strings.Contains(rule.Match.canonicalHostname(), "*") {
+			return &ValidationError{Field: "rules", Message: "wildcards are only supported as the first label, like *.example.com, in rule " + strconv.Itoa(i)}
+		}
+
 		// Check if hostname is a pattern or literal
 		if rule.Match.IsPattern() {
 			// Validate pattern syntax
@@ -212,11 +246,6 @@ func (r *CreateIngressRequest) Validate() error {
 					return &ValidationError{Field: "rules", Message: fmt.Sprintf("target.instance in rule %d references unknown capture {%s}", i, captureName)}
 				}
 			}
-		} else {
-			// Literal hostname - disallow raw wildcards (only patterns supported)
-			if strings.HasPrefix(rule.Match.Hostname, "*") {
-				return &ValidationError{Field: "rules", Message: "wildcard hostnames are not supported, use pattern syntax like {instance}.example.com in rule " + strconv.Itoa(i)}
-			}
 		}
 
 		// Port is optional (defaults to 80), but if specified must be valid
@@ -233,6 +262,10 @@ func (r *CreateIngressRequest) Validate() error {
 		if rule.RedirectHTTP && !rule.TLS {
 			return &ValidationError{Field: "rules", Message: "redirect_http requires tls to be enabled in rule " + strconv.Itoa(i)}
 		}
+		// Passthrough forwards TLS untouched, so hypeman can't terminate it
+		if rule.TLSPassthrough && rule.TLS {
+			return &ValidationError{Field: "rules", Message: "tls_passthrough cannot be combined with tls in rule " + strconv.Itoa(i)}
+		}
 	}
 
 	return nil
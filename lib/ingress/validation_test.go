@@ -22,7 +22,7 @@ func TestPatternParsing(t *testing.T) {
 			{"api.example.com", false},
 			{"{instance}.example.com", true},
 			{"{app}-{env}.example.com", true},
-			{"*.example.com", false}, // Raw wildcards are not patterns
+			{"*.example.com", true}, // Leading wildcard is shorthand for {instance}
 			{"foo.bar.example.com", false},
 		}
 
@@ -67,6 +67,17 @@ func TestPatternParsing(t *testing.T) {
 		assert.Equal(t, "{http.request.host.labels.2}", pattern.CaddyLabels["env"])
 	})
 
+	t.Run("ParsePattern_Wildcard", func(t *testing.T) {
+		match := IngressMatch{Hostname: "*.apps.example.com"}
+		pattern, err := match.ParsePattern()
+		require.NoError(t, err)
+
+		assert.Equal(t, "*.apps.example.com", pattern.Original)
+		assert.Equal(t, "*.apps.example.com", pattern.Wildcard)
+		assert.Equal(t, []string{"instance"}, pattern.Captures)
+		assert.Equal(t, "{http.request.host.labels.3}", pattern.CaddyLabels["instance"])
+	})
+
 	t.Run("ParsePattern_NotAPattern", func(t *testing.T) {
 		match := IngressMatch{Hostname: "api.example.com"}
 		_, err := match.ParsePattern()
@@ -152,19 +163,57 @@ func TestValidation_PatternHostnames(t *testing.T) {
 		assert.Contains(t, err.Error(), "unknown capture")
 	})
 
-	t.Run("RawWildcardNotAllowed", func(t *testing.T) {
+	t.Run("WildcardHostname", func(t *testing.T) {
 		req := CreateIngressRequest{
 			Name: "wildcard-ingress",
 			Rules: []IngressRule{
 				{
-					Match:  IngressMatch{Hostname: "*.example.com"},
-					Target: IngressTarget{Instance: "my-api", Port: 8080},
+					Match:  IngressMatch{Hostname: "*.apps.example.com"},
+					Target: IngressTarget{Instance: "{instance}", Port: 8080},
+				},
+			},
+		}
+		assert.NoError(t, req.Validate())
+
+		// The wildcard label picks the instance, so a literal target is rejected
+		req.Rules[0].Target.Instance = "my-api"
+		err := req.Validate()
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "reference a capture")
+	})
+
+	t.Run("WildcardOnlyAsFirstLabel", func(t *testing.T) {
+		for _, hostname := range []string{"*api.example.com", "api.*.example.com", "*"} {
+			req := CreateIngressRequest{
+				Name: "wildcard-ingress",
+				Rules: []IngressRule{
+					{
+						Match:  IngressMatch{Hostname: hostname},
+						Target: IngressTarget{Instance: "{instance}", Port: 8080},
+					},
+				},
+			}
+			err := req.Validate()
+			assert.Error(t, err, hostname)
+			assert.Contains(t, err.Error(), "only supported as the first label", hostname)
+		}
+	})
+
+	t.Run("PassthroughWithTLS", func(t *testing.T) {
+		req := CreateIngressRequest{
+			Name: "passthrough-ingress",
+			Rules: []IngressRule{
+				{
+					Match:          IngressMatch{Hostname: "*.apps.example.com"},
+					Target:         IngressTarget{Instance: "{instance}", Port: 8443},
+					TLS:            true,
+					TLSPassthrough: true,
 				},
 			},
 		}
 		err := req.Validate()
 		assert.Error(t, err)
-		assert.Contains(t, err.Error(), "wildcard hostnames are not supported")
+		assert.Contains(t, err.Error(), "cannot be combined with tls")
 	})
 }
 
@@ -0,0 +1,112 @@
+package instances
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/kernel/hypeman/lib/guest"
+	"github.com/kernel/hypeman/lib/hypervisor"
+	"github.com/kernel/hypeman/lib/logger"
+	"github.com/kernel/hypeman/lib/system"
+)
+
+// agentRestartTimeout is how long to wait for an updated guest-agent to come back
+const agentRestartTimeout = 15 * time.Second
+
+// GuestAgentInfo describes the guest-agent running in an instance relative to
+// the agent bundled with this host.
+type GuestAgentInfo struct {
+	Checksum       string // SHA-256 of the running agent binary
+	LatestChecksum string // SHA-256 of the host's bundled agent binary
+	UpToDate       bool
+}
+
+// getGuestAgentInfo queries the running guest-agent's checksum.
+func (m *manager) getGuestAgentInfo(ctx context.Context, id string) (*GuestAgentInfo, error) {
+	dialer, err := m.runningAgentDialer(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	sum, err := guest.GetAgentChecksum(ctx, dialer)
+	if err != nil {
+		return nil, err
+	}
+	return newGuestAgentInfo(sum), nil
+}
+
+// updateGuestAgent pushes the bundled guest-agent to a running instance, then
+// waits for the restarted agent to report the new checksum.
+// Instances already running the bundled agent are left alone.
+func (m *manager) updateGuestAgent(ctx context.Context, id string) (*GuestAgentInfo, error) {
+	log := logger.FromContext(ctx)
+
+	dialer, err := m.runningAgentDialer(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	current, err := guest.GetAgentChecksum(ctx, dialer)
+	if err != nil {
+		return nil, err
+	}
+	info := newGuestAgentInfo(current)
+	if info.UpToDate {
+		return info, nil
+	}
+
+	log.InfoContext(ctx, "updating guest agent", "instance_id", id, "from", current, "to", info.LatestChecksum)
+	if err := guest.UpdateAgent(ctx, dialer, system.GuestAgentBinary); err != nil {
+		return nil, fmt.Errorf("update guest agent: %w", err)
+	}
+
+	// The agent re-executes itself after responding; poll until the new binary answers
+	deadline := time.Now().Add(agentRestartTimeout)
+	for {
+		sum, err := guest.GetAgentChecksum(ctx, dialer)
+		if err == nil && sum == info.LatestChecksum {
+			log.InfoContext(ctx, "guest agent updated", "instance_id", id, "sha256", sum)
+			return newGuestAgentInfo(sum), nil
+		}
+		if time.Now().After(deadline) {
+			if err == nil {
+				err = fmt.Errorf("agent reports checksum %s", sum)
+			}
+			return nil, fmt.Errorf("updated guest agent did not come back within %s: %w", agentRestartTimeout, err)
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(500 * time.Millisecond):
+		}
+	}
+}
+
+// runningAgentDialer returns a vsock dialer for a running instance's guest-agent.
+func (m *manager) runningAgentDialer(ctx context.Context, id string) (hypervisor.VsockDialer, error) {
+	inst, err := m.getInstance(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if inst.State != StateRunning {
+		return nil, fmt.Errorf("%w: instance must be running (current state: %s)", ErrInvalidState, inst.State)
+	}
+	return hypervisor.NewVsockDialer(inst.HypervisorType, inst.VsockSocket, inst.VsockCID)
+}
+
+// closeGuestConn drops the pooled guest-agent connection of an instance whose
+// agent is going away or being replaced, so the next call dials the new one
+// instead of failing on the old
+func closeGuestConn(stored *StoredMetadata) {
+	if dialer, err := hypervisor.NewVsockDialer(stored.HypervisorType, stored.VsockSocket, stored.VsockCID); err == nil {
+		guest.CloseConn(dialer.Key())
+	}
+}
+
+func newGuestAgentInfo(checksum string) *GuestAgentInfo {
+	latest := guest.BinaryChecksum(system.GuestAgentBinary)
+	return &GuestAgentInfo{
+		Checksum:       checksum,
+		LatestChecksum: latest,
+		UpToDate:       checksum == latest,
+	}
+}
@@ -0,0 +1,47 @@
+package instances
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/kernel/hypeman/lib/guest"
+	"github.com/kernel/hypeman/lib/hypervisor"
+	"github.com/kernel/hypeman/lib/system"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGuestAgent_RequiresRunningInstance(t *testing.T) {
+	mgr, _ := setupTestManager(t)
+	ctx := context.Background()
+
+	id := "agent-stopped"
+	require.NoError(t, mgr.ensureDirectories(id))
+	require.NoError(t, mgr.saveMetadata(&metadata{StoredMetadata: StoredMetadata{
+		Id:             id,
+		Name:           id,
+		HypervisorType: hypervisor.TypeCloudHypervisor,
+		SocketPath:     mgr.paths.InstanceSocket(id, hypervisor.SocketNameForType(hypervisor.TypeCloudHypervisor)),
+		VsockSocket:    mgr.paths.InstanceVsockSocket(id),
+		DataDir:        mgr.paths.InstanceDir(id),
+		CreatedAt:      time.Now(),
+	}}))
+
+	_, err := mgr.GetGuestAgentInfo(ctx, id)
+	assert.ErrorIs(t, err, ErrInvalidState)
+	_, err = mgr.UpdateGuestAgent(ctx, id)
+	assert.ErrorIs(t, err, ErrInvalidState)
+}
+
+func TestNewGuestAgentInfo(t *testing.T) {
+	latest := guest.BinaryChecksum(system.GuestAgentBinary)
+
+	info := newGuestAgentInfo(latest)
+	assert.True(t, info.UpToDate)
+	assert.Equal(t, latest, info.LatestChecksum)
+
+	info = newGuestAgentInfo("0000")
+	assert.False(t, info.UpToDate)
+	assert.Equal(t, "0000", info.Checksum)
+}
@@ -0,0 +1,79 @@
+package instances
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/kernel/hypeman/lib/logger"
+	"github.com/kernel/hypeman/lib/network"
+)
+
+// bandwidthLimits builds the TAP shaping configuration from instance metadata.
+func (m *manager) bandwidthLimits(stored *StoredMetadata) network.BandwidthLimits {
+	return network.BandwidthLimits{
+		DownloadBps:        stored.NetworkBandwidthDownload,
+		DownloadBurstBytes: stored.NetworkDownloadBurst,
+		UploadBps:          stored.NetworkBandwidthUpload,
+		UploadCeilBps:      stored.NetworkBandwidthUpload * int64(m.networkManager.GetUploadBurstMultiplier()),
+		UploadBurstBytes:   stored.NetworkUploadBurst,
+	}
+}
+
+// updateNetworkBandwidth changes an instance's bandwidth limits.
+// Running instances are reshaped in place; for stopped and standby instances the
+// limits are stored and applied when the TAP device is next created.
+func (m *manager) updateNetworkBandwidth(ctx context.Context, id string, req UpdateNetworkBandwidthRequest) (*Instance, error) {
+	log := logger.FromContext(ctx)
+
+	for name, v := range map[string]*int64{
+		"bandwidth_download":       req.DownloadBps,
+		"bandwidth_upload":         req.UploadBps,
+		"bandwidth_download_burst": req.DownloadBurst,
+		"bandwidth_upload_burst":   req.UploadBurst,
+	} {
+		if v != nil && *v < 0 {
+			return nil, fmt.Errorf("%s cannot be negative", name)
+		}
+	}
+
+	meta, err := m.loadMetadata(id)
+	if err != nil {
+		return nil, err
+	}
+	stored := &meta.StoredMetadata
+	if !stored.NetworkEnabled {
+		return nil, ErrNetworkDisabled
+	}
+
+	if req.DownloadBps != nil {
+		stored.NetworkBandwidthDownload = *req.DownloadBps
+	}
+	if req.UploadBps != nil {
+		stored.NetworkBandwidthUpload = *req.UploadBps
+	}
+	if req.DownloadBurst != nil {
+		stored.NetworkDownloadBurst = *req.DownloadBurst
+	}
+	if req.UploadBurst != nil {
+		stored.NetworkUploadBurst = *req.UploadBurst
+	}
+
+	// Apply before persisting so a tc failure leaves the stored limits matching the TAP
+	if inst := m.toInstance(ctx, meta); inst.State == StateRunning {
+		if err := m.networkManager.UpdateBandwidth(ctx, id, m.bandwidthLimits(stored)); err != nil {
+			log.ErrorContext(ctx, "failed to update network bandwidth", "instance_id", id, "error", err)
+			return nil, fmt.Errorf("update network bandwidth: %w", err)
+		}
+	}
+
+	if err := m.saveMetadata(meta); err != nil {
+		return nil, fmt.Errorf("save metadata: %w", err)
+	}
+
+	log.InfoContext(ctx, "instance network bandwidth updated", "instance_id", id,
+		"download_bps", stored.NetworkBandwidthDownload, "upload_bps", stored.NetworkBandwidthUpload,
+		"download_burst", stored.NetworkDownloadBurst, "upload_burst", stored.NetworkUploadBurst)
+
+	inst := m.toInstance(ctx, meta)
+	return &inst, nil
+}
@@ -0,0 +1,66 @@
+package instances
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/kernel/hypeman/lib/hypervisor"
+	"github.com/samber/lo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUpdateNetworkBandwidth(t *testing.T) {
+	mgr, _ := setupTestManager(t)
+	ctx := context.Background()
+
+	saveStopped := func(id string, networkEnabled bool) {
+		require.NoError(t, mgr.ensureDirectories(id))
+		require.NoError(t, mgr.saveMetadata(&metadata{StoredMetadata: StoredMetadata{
+			Id:                       id,
+			Name:                     id,
+			HypervisorType:           hypervisor.TypeCloudHypervisor,
+			NetworkEnabled:           networkEnabled,
+			NetworkBandwidthDownload: 125000000,
+			NetworkBandwidthUpload:   125000000,
+			DataDir:                  mgr.paths.InstanceDir(id),
+			CreatedAt:                time.Now(),
+		}}))
+	}
+
+	t.Run("stopped instance stores new limits", func(t *testing.T) {
+		saveStopped("bw-stopped", true)
+
+		inst, err := mgr.UpdateNetworkBandwidth(ctx, "bw-stopped", UpdateNetworkBandwidthRequest{
+			UploadBps:     lo.ToPtr(int64(62500000)),
+			DownloadBurst: lo.ToPtr(int64(2 * 1024 * 1024)),
+		})
+		require.NoError(t, err)
+		assert.Equal(t, StateStopped, inst.State)
+		assert.Equal(t, int64(125000000), inst.NetworkBandwidthDownload, "unset fields are unchanged")
+		assert.Equal(t, int64(62500000), inst.NetworkBandwidthUpload)
+		assert.Equal(t, int64(2*1024*1024), inst.NetworkDownloadBurst)
+
+		meta, err := mgr.loadMetadata("bw-stopped")
+		require.NoError(t, err)
+		assert.Equal(t, int64(62500000), meta.NetworkBandwidthUpload)
+		assert.Equal(t, int64(2*1024*1024), meta.NetworkDownloadBurst)
+	})
+
+	t.Run("negative values are rejected", func(t *testing.T) {
+		_, err := mgr.UpdateNetworkBandwidth(ctx, "bw-stopped", UpdateNetworkBandwidthRequest{
+			UploadBurst: lo.ToPtr(int64(-1)),
+		})
+		assert.Error(t, err)
+	})
+
+	t.Run("network disabled", func(t *testing.T) {
+		saveStopped("bw-no-network", false)
+
+		_, err := mgr.UpdateNetworkBandwidth(ctx, "bw-no-network", UpdateNetworkBandwidthRequest{
+			DownloadBps: lo.ToPtr(int64(1000)),
+		})
+		assert.ErrorIs(t, err, ErrNetworkDisabled)
+	})
+}
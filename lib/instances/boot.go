@@ -0,0 +1,331 @@
+package instances
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/kernel/hypeman/lib/guest"
+	"github.com/kernel/hypeman/lib/hypervisor"
+	"github.com/kernel/hypeman/lib/images"
+	"github.com/kernel/hypeman/lib/logger"
+)
+
+const (
+	// bootPollInterval is how often the boot watchdog checks the guest agent
+	bootPollInterval = time.Second
+
+	// bootProbeTimeout bounds a single guest agent check
+	bootProbeTimeout = 2 * time.Second
+
+	// consoleExcerptBytes caps the console log excerpt kept on boot failure
+	consoleExcerptBytes = 8 * 1024
+)
+
+// BootMilestone is one step of the guest boot, as seen on the serial console
+type BootMilestone struct {
+	Name    string     // Milestone name, e.g. "kernel", "init", "agent_ready"
+	At      *time.Time // When it was reached; nil for kernel messages, which carry no wall-clock time
+	Message string     // Console line the milestone was parsed from
+}
+
+// BootDiagnostics describes an instance's last boot
+type BootDiagnostics struct {
+	StartedAt    *time.Time      // When the VM was last started
+	AgentReadyAt *time.Time      // When the guest agent first answered (nil = not yet)
+	BootFailure  *BootFailure    // Set if the boot timed out
+	Milestones   []BootMilestone // Milestones reached, in console order
+	Errors       []string        // Errors logged by init during the boot
+}
+
+// bootMilestones maps init's console messages to milestones. Init logs
+// "<RFC3339> [INFO] [<phase>] <message>"; a milestone matches on the phase
+// and a message prefix. Exec and systemd mode report the agent and the
+// application differently, so those milestones have an entry per mode.
+var bootMilestones = []struct {
+	name   string
+	phase  string
+	prefix string
+}{
+	{"init", "boot", "init starting"},
+	{"config", "config", "parsed configuration"},
+	{"network", "network", "configured eth0"},
+	{"rootfs", "overlay", "created overlay filesystem"},
+	{"rootfs", "overlay", "mounted root volume"},
+	{"mode", "mode", "entering "},
+	{"agent_started", "exec", "starting guest-agent"},
+	{"agent_started", "systemd", "injecting hypeman-agent.service"},
+	{"app_started", "exec", "container app started"},
+	{"app_started", "systemd", "exec "},
+	{"app_exited", "exec", "app exited"},
+	{"app_exited", "exec", "app killed by signal"},
+}
+
+// startWatchdog watches a running instance in the background: until its
+// guest agent answers, failing the boot after BootTimeout, and then until its
+// application exits. Callers start it after saving the hypervisor PID, which
+// the watchdog uses to tell the boot apart from later ones.
+func (m *manager) startWatchdog(ctx context.Context, stored *StoredMetadata) {
+	// UEFI guests have no agent to wait for
+	if stored.HypervisorPID == nil || stored.Firmware == images.FirmwareUEFI {
+		return
+	}
+	go m.watch(context.WithoutCancel(ctx), *stored, *stored.HypervisorPID)
+}
+
+// watch runs the watchdog for the boot identified by pid: if the instance is
+// stopped, restarted or deleted meanwhile, the watchdog gives up
+func (m *manager) watch(ctx context.Context, stored StoredMetadata, pid int) {
+	dialer, err := hypervisor.NewVsockDialer(stored.HypervisorType, stored.VsockSocket, stored.VsockCID)
+	if err != nil {
+		logger.FromContext(ctx).WarnContext(ctx, "watchdog: failed to create vsock dialer", "instance_id", stored.Id, "error", err)
+		return
+	}
+
+	if stored.AgentReadyAt == nil {
+		var deadline time.Time
+		if m.limits.BootTimeout > 0 {
+			started := time.Now()
+			if stored.StartedAt != nil {
+				started = *stored.StartedAt
+			}
+			deadline = started.Add(m.limits.BootTimeout)
+		}
+		if !m.watchBoot(ctx, stored.Id, pid, dialer, deadline) {
+			return
+		}
+	}
+	if stored.TerminationReason == "" {
+		m.watchApp(ctx, stored.Id, pid, dialer)
+	}
+}
+
+// watchBoot waits for the guest agent to answer and records when it does,
+// or marks the instance Failed if it doesn't by the deadline (zero = no
+// deadline). The agent is always checked at least once, so a watchdog
+// resumed after the deadline doesn't fail a healthy instance. It reports
+// whether the agent came up.
+func (m *manager) watchBoot(ctx context.Context, id string, pid int, dialer hypervisor.VsockDialer, deadline time.Time) bool {
+	for {
+		probeCtx, cancel := context.WithTimeout(ctx, bootProbeTimeout)
+		_, err := guest.GetAgentChecksum(probeCtx, dialer)
+		cancel()
+		if err == nil {
+			if !m.recordAgentReady(ctx, id, pid) {
+				return false
+			}
+			m.runPostHooks(ctx, HookPostBoot, id)
+			return true
+		}
+		if !m.isCurrentBoot(id, pid) {
+			return false
+		}
+		if !deadline.IsZero() && time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(bootPollInterval)
+	}
+
+	m.failBoot(ctx, id, pid, fmt.Sprintf("guest agent not ready after %s", m.limits.BootTimeout))
+	return false
+}
+
+// isCurrentBoot reports whether the boot started with hypervisor pid is
+// still the instance's current one
+func (m *manager) isCurrentBoot(id string, pid int) bool {
+	meta, err := m.loadMetadata(id)
+	if err != nil {
+		return false
+	}
+	return meta.HypervisorPID != nil && *meta.HypervisorPID == pid
+}
+
+// recordAgentReady stores when the guest agent of the watched boot answered.
+// It reports whether the boot is still the instance's current one.
+func (m *manager) recordAgentReady(ctx context.Context, id string, pid int) bool {
+	lock := m.getInstanceLock(id)
+	lock.acquire("watchdog")
+	defer lock.release()
+
+	meta, err := m.loadMetadata(id)
+	if err != nil || meta.HypervisorPID == nil || *meta.HypervisorPID != pid {
+		return false
+	}
+	now := time.Now()
+	meta.AgentReadyAt = &now
+	if err := m.saveMetadata(meta); err != nil {
+		logger.FromContext(ctx).WarnContext(ctx, "failed to record guest agent readiness", "instance_id", id, "error", err)
+	}
+	return true
+}
+
+// failBoot stops an instance whose boot timed out and records the failure,
+// with the end of its console log, in its metadata
+func (m *manager) failBoot(ctx context.Context, id string, pid int, reason string) {
+	log := logger.FromContext(ctx)
+
+	lock := m.getInstanceLock(id)
+	lock.acquire("watchdog")
+	defer lock.release()
+
+	meta, err := m.loadMetadata(id)
+	if err != nil || meta.HypervisorPID == nil || *meta.HypervisorPID != pid || meta.AgentReadyAt != nil {
+		return
+	}
+	// A paused instance was paused on purpose; leave it to the user
+	if inst := m.toInstance(ctx, meta); inst.State != StateRunning {
+		log.WarnContext(ctx, "boot timed out, leaving instance as is", "instance_id", id, "state", inst.State)
+		return
+	}
+
+	// Capture the console before stopping, while it shows the stuck boot
+	excerpt, err := readLogTail(m.paths.InstanceAppLog(id), consoleExcerptBytes)
+	if err != nil {
+		log.WarnContext(ctx, "failed to read console log", "instance_id", id, "error", err)
+	}
+
+	log.ErrorContext(ctx, "boot timed out, stopping instance", "instance_id", id, "reason", reason)
+	if _, err := m.stopInstance(ctx, id); err != nil {
+		log.ErrorContext(ctx, "failed to stop instance after boot timeout", "instance_id", id, "error", err)
+		return
+	}
+
+	meta, err = m.loadMetadata(id)
+	if err != nil {
+		return
+	}
+	now := time.Now()
+	meta.BootFailure = &BootFailure{
+		Reason:         reason,
+		FailedAt:       now,
+		ConsoleExcerpt: excerpt,
+	}
+	meta.TerminationReason = TerminationBootTimeout
+	meta.TerminatedAt = &now
+	if err := m.saveMetadata(meta); err != nil {
+		log.ErrorContext(ctx, "failed to record boot failure", "instance_id", id, "error", err)
+	}
+}
+
+// readLogTail returns up to n bytes from the end of a log file, starting
+// at a line boundary. A missing file yields an empty string.
+func readLogTail(path string, n int64) (string, error) {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return "", err
+	}
+	offset := max(info.Size()-n, 0)
+	data, err := io.ReadAll(io.NewSectionReader(f, offset, info.Size()-offset))
+	if err != nil {
+		return "", err
+	}
+	if offset > 0 {
+		if i := bytes.IndexByte(data, '\n'); i >= 0 {
+			data = data[i+1:]
+		}
+	}
+	return string(data), nil
+}
+
+// getBootDiagnostics returns the boot milestones of an instance's last boot
+func (m *manager) getBootDiagnostics(ctx context.Context, id string) (*BootDiagnostics, error) {
+	meta, err := m.loadMetadata(id)
+	if err != nil {
+		return nil, err
+	}
+
+	diag := &BootDiagnostics{
+		StartedAt:    meta.StartedAt,
+		AgentReadyAt: meta.AgentReadyAt,
+		BootFailure:  meta.BootFailure,
+	}
+
+	f, err := os.Open(m.paths.InstanceAppLog(id))
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("open console log: %w", err)
+	}
+	if err == nil {
+		defer f.Close()
+		diag.Milestones, diag.Errors, err = parseBootLog(f)
+		if err != nil {
+			return nil, fmt.Errorf("read console log: %w", err)
+		}
+	}
+
+	if meta.AgentReadyAt != nil {
+		diag.Milestones = append(diag.Milestones, BootMilestone{Name: "agent_ready", At: meta.AgentReadyAt, Message: "guest agent answered"})
+	}
+	return diag, nil
+}
+
+// parseBootLog extracts the milestones and init errors of the last boot
+// recorded in a serial console log. Each boot starts with the kernel banner,
+// so everything before the last one belongs to earlier boots.
+func parseBootLog(r io.Reader) ([]BootMilestone, []string, error) {
+	var milestones []BootMilestone
+	var errs []string
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+
+		if i := strings.Index(line, "Linux version "); i >= 0 {
+			milestones = []BootMilestone{{Name: "kernel", Message: line[i:]}}
+			errs = nil
+			continue
+		}
+
+		ts, level, phase, msg, ok := parseInitLogLine(line)
+		if !ok {
+			continue
+		}
+		if level == "ERROR" {
+			errs = append(errs, fmt.Sprintf("[%s] %s", phase, msg))
+			continue
+		}
+		for _, ms := range bootMilestones {
+			if ms.phase == phase && strings.HasPrefix(msg, ms.prefix) {
+				milestones = append(milestones, BootMilestone{Name: ms.name, At: &ts, Message: msg})
+				break
+			}
+		}
+	}
+	return milestones, errs, scanner.Err()
+}
+
+// parseInitLogLine splits a line logged by init:
+// "2024-12-23T10:15:30Z [INFO] [phase] message"
+func parseInitLogLine(line string) (ts time.Time, level, phase, msg string, ok bool) {
+	tsStr, rest, found := strings.Cut(line, " [")
+	if !found {
+		return
+	}
+	ts, err := time.Parse(time.RFC3339, tsStr)
+	if err != nil {
+		return
+	}
+	level, rest, found = strings.Cut(rest, "] [")
+	if !found {
+		return
+	}
+	phase, msg, found = strings.Cut(rest, "] ")
+	if !found {
+		return
+	}
+	return ts, level, phase, msg, true
+}
@@ -0,0 +1,130 @@
+package instances
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kernel/hypeman/lib/hypervisor"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const sampleConsoleLog = `[    0.000000] Linux version 6.12.9 (builder@hypeman) #1 SMP
+2025-01-15T10:00:01Z [INFO] [boot] init starting
+2025-01-15T10:00:02Z [INFO] [mode] entering exec mode
+2025-01-15T10:00:03Z [INFO] [exec] app exited with code 1
+[    0.000000] Linux version 6.12.9 (builder@hypeman) #1 SMP
+[    0.512345] Run /init as init process
+2025-01-15T10:30:01Z [INFO] [boot] init starting
+2025-01-15T10:30:01Z [INFO] [config] mounted config disk
+2025-01-15T10:30:01Z [INFO] [config] parsed configuration
+2025-01-15T10:30:02Z [INFO] [overlay] created overlay filesystem
+2025-01-15T10:30:02Z [INFO] [network] configured eth0 with 10.100.0.2/16
+2025-01-15T10:30:02Z [ERROR] [volumes] failed to mount volumes: mount /dev/vdc: no such device
+2025-01-15T10:30:03Z [INFO] [mode] entering exec mode
+2025-01-15T10:30:03Z [INFO] [exec] starting guest-agent in background
+2025-01-15T10:30:04Z [INFO] [exec] container app started (PID 42)
+hello from the app
+`
+
+func TestParseBootLog(t *testing.T) {
+	milestones, errs, err := parseBootLog(strings.NewReader(sampleConsoleLog))
+	require.NoError(t, err)
+
+	// Only the last boot is reported
+	var names []string
+	for _, ms := range milestones {
+		names = append(names, ms.Name)
+	}
+	assert.Equal(t, []string{"kernel", "init", "config", "rootfs", "network", "mode", "agent_started", "app_started"}, names)
+
+	assert.Nil(t, milestones[0].At)
+	assert.Equal(t, "Linux version 6.12.9 (builder@hypeman) #1 SMP", milestones[0].Message)
+	require.NotNil(t, milestones[1].At)
+	assert.Equal(t, time.Date(2025, 1, 15, 10, 30, 1, 0, time.UTC), *milestones[1].At)
+	assert.Equal(t, "container app started (PID 42)", milestones[7].Message)
+
+	assert.Equal(t, []string{"[volumes] failed to mount volumes: mount /dev/vdc: no such device"}, errs)
+}
+
+func TestParseInitLogLine(t *testing.T) {
+	ts, level, phase, msg, ok := parseInitLogLine("2025-01-15T10:30:01Z [INFO] [exec] workdir=/ entrypoint=[a] cmd=[b]")
+	require.True(t, ok)
+	assert.Equal(t, time.Date(2025, 1, 15, 10, 30, 1, 0, time.UTC), ts)
+	assert.Equal(t, "INFO", level)
+	assert.Equal(t, "exec", phase)
+	assert.Equal(t, "workdir=/ entrypoint=[a] cmd=[b]", msg)
+
+	for _, line := range []string{"", "hello [world]", "[    0.512345] Run /init as init process", "2025-01-15T10:30:01Z [INFO] no phase"} {
+		_, _, _, _, ok := parseInitLogLine(line)
+		assert.False(t, ok, line)
+	}
+}
+
+func TestReadLogTail(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+
+	tail, err := readLogTail(path, 16)
+	require.NoError(t, err)
+	assert.Empty(t, tail, "missing log")
+
+	require.NoError(t, os.WriteFile(path, []byte("first line\nsecond line\nthird\n"), 0644))
+	tail, err = readLogTail(path, 16)
+	require.NoError(t, err)
+	assert.Equal(t, "third\n", tail, "partial first line is dropped")
+
+	tail, err = readLogTail(path, 1024)
+	require.NoError(t, err)
+	assert.Equal(t, "first line\nsecond line\nthird\n", tail)
+}
+
+func TestBootFailureState(t *testing.T) {
+	mgr, _ := setupTestManager(t)
+	ctx := context.Background()
+
+	id := "boot-failed"
+	require.NoError(t, mgr.ensureDirectories(id))
+	require.NoError(t, os.WriteFile(mgr.paths.InstanceAppLog(id), []byte(sampleConsoleLog), 0644))
+	readyAt := time.Date(2025, 1, 15, 10, 30, 5, 0, time.UTC)
+	stored := StoredMetadata{
+		Id:             id,
+		Name:           id,
+		HypervisorType: hypervisor.TypeCloudHypervisor,
+		SocketPath:     mgr.paths.InstanceSocket(id, hypervisor.SocketNameForType(hypervisor.TypeCloudHypervisor)),
+		DataDir:        mgr.paths.InstanceDir(id),
+		CreatedAt:      time.Now(),
+		AgentReadyAt:   &readyAt,
+	}
+	require.NoError(t, mgr.saveMetadata(&metadata{StoredMetadata: stored}))
+
+	inst, err := mgr.getInstance(ctx, id)
+	require.NoError(t, err)
+	assert.Equal(t, StateStopped, inst.State)
+
+	diag, err := mgr.GetBootDiagnostics(ctx, id)
+	require.NoError(t, err)
+	require.Len(t, diag.Milestones, 9)
+	last := diag.Milestones[len(diag.Milestones)-1]
+	assert.Equal(t, "agent_ready", last.Name)
+	assert.Equal(t, &readyAt, last.At)
+	assert.Nil(t, diag.BootFailure)
+
+	stored.AgentReadyAt = nil
+	stored.BootFailure = &BootFailure{Reason: "guest agent not ready after 5m0s", FailedAt: time.Now()}
+	require.NoError(t, mgr.saveMetadata(&metadata{StoredMetadata: stored}))
+
+	inst, err = mgr.getInstance(ctx, id)
+	require.NoError(t, err)
+	assert.Equal(t, StateFailed, inst.State)
+	assert.NoError(t, inst.State.CanTransitionTo(StateCreated))
+	assert.False(t, inst.State.RequiresVMM())
+
+	diag, err = mgr.GetBootDiagnostics(ctx, id)
+	require.NoError(t, err)
+	require.NotNil(t, diag.BootFailure)
+	assert.Equal(t, "guest agent not ready after 5m0s", diag.BootFailure.Reason)
+}
@@ -0,0 +1,114 @@
+package instances
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/kernel/hypeman/lib/hypervisor"
+)
+
+const (
+	// cgroupRoot is where the cgroup v2 hierarchy is mounted
+	cgroupRoot = "/sys/fs/cgroup"
+
+	// cgroupCPUPeriod is the cpu.max period in microseconds
+	cgroupCPUPeriod = 100000
+
+	// cgroupRemoveTimeout bounds the wait for a cgroup's processes to exit
+	// before it's removed
+	cgroupRemoveTimeout = 2 * time.Second
+)
+
+// hypervisorProcessOptions returns how an instance's hypervisor process is
+// confined, creating its cgroup if cgroup confinement is enabled
+func (m *manager) hypervisorProcessOptions(stored *StoredMetadata) (hypervisor.ProcessOptions, error) {
+	opts := hypervisor.ProcessOptions{Sandbox: m.limits.VMMSandbox}
+	if m.limits.VMMCgroup == "" {
+		return opts, nil
+	}
+	dir, err := m.ensureHypervisorCgroup(stored)
+	if err != nil {
+		return opts, fmt.Errorf("create hypervisor cgroup: %w", err)
+	}
+	opts.CgroupDir = dir
+	return opts, nil
+}
+
+// hypervisorCgroupDir is the cgroup of an instance's hypervisor process
+func (m *manager) hypervisorCgroupDir(id string) string {
+	return filepath.Join(m.cgroupRoot, m.limits.VMMCgroup, id)
+}
+
+// ensureHypervisorCgroup creates the cgroup of an instance's hypervisor
+// process, or updates it if it's left from an earlier run. cpu.max caps the
+// process at the instance's vCPUs; memory.max at its guest memory, hotplug
+// included, plus the VMM's own overhead.
+func (m *manager) ensureHypervisorCgroup(stored *StoredMetadata) (string, error) {
+	if err := m.enableCgroupControllers(); err != nil {
+		return "", err
+	}
+
+	dir := m.hypervisorCgroupDir(stored.Id)
+	if err := os.Mkdir(dir, 0755); err != nil && !errors.Is(err, os.ErrExist) {
+		return "", err
+	}
+
+	cpuMax := fmt.Sprintf("%d %d", stored.Vcpus*cgroupCPUPeriod, cgroupCPUPeriod)
+	memoryMax := fmt.Sprintf("%d", stored.Size+stored.HotplugSize+m.limits.VMMMemoryOverhead)
+	for file, value := range map[string]string{"cpu.max": cpuMax, "memory.max": memoryMax} {
+		if err := os.WriteFile(filepath.Join(dir, file), []byte(value), 0644); err != nil {
+			return "", fmt.Errorf("set %s: %w", file, err)
+		}
+	}
+	return dir, nil
+}
+
+// enableCgroupControllers creates the parent cgroup of hypervisor processes
+// and delegates the cpu and memory controllers down to it, from the root.
+// Cgroups with controllers enabled for children can't hold processes
+// themselves, so the parent must be dedicated to hypeman.
+func (m *manager) enableCgroupControllers() error {
+	if _, err := os.Stat(filepath.Join(m.cgroupRoot, "cgroup.controllers")); err != nil {
+		return fmt.Errorf("cgroup v2 not mounted at %s", m.cgroupRoot)
+	}
+
+	dir := m.cgroupRoot
+	for _, part := range strings.Split(filepath.Clean(m.limits.VMMCgroup), "/") {
+		if err := os.WriteFile(filepath.Join(dir, "cgroup.subtree_control"), []byte("+cpu +memory"), 0644); err != nil {
+			return fmt.Errorf("enable cpu and memory controllers in %s: %w", dir, err)
+		}
+		dir = filepath.Join(dir, part)
+		if err := os.Mkdir(dir, 0755); err != nil && !errors.Is(err, os.ErrExist) {
+			return err
+		}
+	}
+	if err := os.WriteFile(filepath.Join(dir, "cgroup.subtree_control"), []byte("+cpu +memory"), 0644); err != nil {
+		return fmt.Errorf("enable cpu and memory controllers in %s: %w", dir, err)
+	}
+	return nil
+}
+
+// removeHypervisorCgroup removes an instance's hypervisor cgroup. A cgroup
+// can only be removed once its processes are gone, so this waits briefly
+// for a just-killed hypervisor to be torn down.
+func (m *manager) removeHypervisorCgroup(id string) error {
+	if m.limits.VMMCgroup == "" {
+		return nil
+	}
+	deadline := time.Now().Add(cgroupRemoveTimeout)
+	for {
+		err := os.Remove(m.hypervisorCgroupDir(id))
+		if err == nil || errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		if !errors.Is(err, syscall.EBUSY) || time.Now().After(deadline) {
+			return err
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
@@ -0,0 +1,77 @@
+package instances
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func readCgroupFile(t *testing.T, path string) string {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	return string(data)
+}
+
+func TestHypervisorProcessOptions(t *testing.T) {
+	root := t.TempDir()
+	stored := &StoredMetadata{Id: "inst-1", Vcpus: 2, Size: 1 << 30, HotplugSize: 1 << 30}
+
+	t.Run("disabled", func(t *testing.T) {
+		m := &manager{cgroupRoot: root, limits: ResourceLimits{VMMSandbox: true}}
+		opts, err := m.hypervisorProcessOptions(stored)
+		require.NoError(t, err)
+		assert.Empty(t, opts.CgroupDir)
+		assert.True(t, opts.Sandbox)
+		assert.NoError(t, m.removeHypervisorCgroup(stored.Id))
+	})
+
+	t.Run("requires cgroup v2", func(t *testing.T) {
+		m := &manager{cgroupRoot: root, limits: ResourceLimits{VMMCgroup: "hypeman"}}
+		_, err := m.hypervisorProcessOptions(stored)
+		assert.ErrorContains(t, err, "cgroup v2 not mounted")
+	})
+
+	t.Run("creates a cgroup sized to the instance", func(t *testing.T) {
+		require.NoError(t, os.WriteFile(filepath.Join(root, "cgroup.controllers"), []byte("cpu memory pids"), 0644))
+		m := &manager{cgroupRoot: root, limits: ResourceLimits{VMMCgroup: "hypeman/vmm", VMMMemoryOverhead: 256 << 20}}
+
+		opts, err := m.hypervisorProcessOptions(stored)
+		require.NoError(t, err)
+		assert.Equal(t, filepath.Join(root, "hypeman", "vmm", "inst-1"), opts.CgroupDir)
+		assert.Equal(t, "200000 100000", readCgroupFile(t, filepath.Join(opts.CgroupDir, "cpu.max")))
+		assert.Equal(t, "2415919104", readCgroupFile(t, filepath.Join(opts.CgroupDir, "memory.max")))
+
+		// Controllers are delegated down to the parent of instance cgroups
+		for _, dir := range []string{root, filepath.Join(root, "hypeman"), filepath.Join(root, "hypeman", "vmm")} {
+			assert.Equal(t, "+cpu +memory", readCgroupFile(t, filepath.Join(dir, "cgroup.subtree_control")))
+		}
+
+		// Restarting the instance with a new size updates its cgroup
+		resized := *stored
+		resized.Vcpus = 4
+		_, err = m.hypervisorProcessOptions(&resized)
+		require.NoError(t, err)
+		assert.Equal(t, "400000 100000", readCgroupFile(t, filepath.Join(opts.CgroupDir, "cpu.max")))
+	})
+
+	t.Run("fails when the group can't be delegated", func(t *testing.T) {
+		failRoot := t.TempDir()
+		require.NoError(t, os.WriteFile(filepath.Join(failRoot, "cgroup.controllers"), []byte("cpu memory"), 0644))
+		// A file where the parent cgroup should be, as on a host whose tree isn't delegated to hypeman
+		require.NoError(t, os.WriteFile(filepath.Join(failRoot, "hypeman"), nil, 0644))
+		m := &manager{cgroupRoot: failRoot, limits: ResourceLimits{VMMCgroup: "hypeman"}}
+
+		opts, err := m.hypervisorProcessOptions(stored)
+		assert.ErrorContains(t, err, "create hypervisor cgroup")
+		assert.Empty(t, opts.CgroupDir)
+	})
+
+	t.Run("removing a missing cgroup succeeds", func(t *testing.T) {
+		m := &manager{cgroupRoot: root, limits: ResourceLimits{VMMCgroup: "hypeman"}}
+		assert.NoError(t, m.removeHypervisorCgroup("never-started"))
+	})
+}
@@ -0,0 +1,102 @@
+package instances
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/kernel/hypeman/lib/guest"
+	"github.com/kernel/hypeman/lib/hypervisor"
+	"github.com/kernel/hypeman/lib/images"
+	"github.com/kernel/hypeman/lib/logger"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// clockSyncTimeout bounds one instance's clock sync
+const clockSyncTimeout = 10 * time.Second
+
+// Clock sync triggers, reported as the metric's trigger attribute
+const (
+	clockSyncRestore  = "restore"
+	clockSyncPeriodic = "periodic"
+)
+
+// ClockSyncResult summarizes a SyncClocks run
+type ClockSyncResult struct {
+	Instances int           // Instances whose clocks were set
+	MaxSkew   time.Duration // Largest skew corrected, in either direction
+}
+
+// SyncClocks sets the wall clocks of running instances to the host's time
+// through their guest agents. Instances without an agent (UEFI firmware)
+// are skipped.
+func (m *manager) SyncClocks(ctx context.Context) (*ClockSyncResult, error) {
+	insts, err := m.listInstances(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("list instances: %w", err)
+	}
+
+	res := &ClockSyncResult{}
+	var errs []error
+	for _, inst := range insts {
+		if inst.State != StateRunning || inst.Firmware == images.FirmwareUEFI {
+			continue
+		}
+		if err := ctx.Err(); err != nil {
+			return res, err
+		}
+		skew, err := m.syncClock(ctx, &inst.StoredMetadata, clockSyncPeriodic)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("instance %s: %w", inst.Id, err))
+			continue
+		}
+		res.Instances++
+		res.MaxSkew = max(res.MaxSkew, skew.Abs())
+	}
+	return res, errors.Join(errs...)
+}
+
+// syncClock sets one instance's guest clock to the host's and records the
+// skew it corrected
+func (m *manager) syncClock(ctx context.Context, stored *StoredMetadata, trigger string) (time.Duration, error) {
+	dialer, err := hypervisor.NewVsockDialer(stored.HypervisorType, stored.VsockSocket, stored.VsockCID)
+	if err != nil {
+		return 0, err
+	}
+	ctx, cancel := context.WithTimeout(ctx, clockSyncTimeout)
+	defer cancel()
+	skew, err := guest.SyncClock(ctx, dialer)
+	if err != nil {
+		return 0, err
+	}
+	m.recordClockSkew(ctx, skew, trigger, stored.HypervisorType)
+	logger.FromContext(ctx).DebugContext(ctx, "synced guest clock", "instance_id", stored.Id, "skew", skew, "trigger", trigger)
+	return skew, nil
+}
+
+// syncClockAfterRestore corrects the time a restored guest lost while in
+// standby. It runs in the background after a restore; failures are logged.
+func (m *manager) syncClockAfterRestore(ctx context.Context, stored StoredMetadata) {
+	if stored.Firmware == images.FirmwareUEFI {
+		return
+	}
+	if _, err := m.syncClock(ctx, &stored, clockSyncRestore); err != nil {
+		logger.FromContext(ctx).WarnContext(ctx, "failed to sync guest clock after restore", "instance_id", stored.Id, "error", err)
+	}
+}
+
+// recordClockSkew records the size of a corrected guest clock skew
+func (m *manager) recordClockSkew(ctx context.Context, skew time.Duration, trigger string, hvType hypervisor.Type) {
+	if m.metrics == nil {
+		return
+	}
+	attrs := []attribute.KeyValue{
+		attribute.String("trigger", trigger),
+	}
+	if hvType != "" {
+		attrs = append(attrs, attribute.String("hypervisor", string(hvType)))
+	}
+	m.metrics.clockSkew.Record(ctx, skew.Abs().Seconds(), metric.WithAttributes(attrs...))
+}
@@ -0,0 +1,53 @@
+package instances
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/kernel/hypeman/lib/hypervisor"
+	"github.com/kernel/hypeman/lib/paths"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+)
+
+func TestRecordClockSkew(t *testing.T) {
+	reader := sdkmetric.NewManualReader()
+	meter := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader)).Meter("test")
+	metrics, err := newInstanceMetrics(meter, nil, &manager{paths: paths.New(t.TempDir())})
+	require.NoError(t, err)
+	m := &manager{metrics: metrics}
+
+	ctx := context.Background()
+	m.recordClockSkew(ctx, -90*time.Second, clockSyncRestore, hypervisor.TypeCloudHypervisor)
+	m.recordClockSkew(ctx, 250*time.Millisecond, clockSyncPeriodic, hypervisor.TypeCloudHypervisor)
+
+	var rm metricdata.ResourceMetrics
+	require.NoError(t, reader.Collect(ctx, &rm))
+	var hist *metricdata.Histogram[float64]
+	for _, sm := range rm.ScopeMetrics {
+		for _, md := range sm.Metrics {
+			if md.Name == "hypeman_instances_clock_skew_seconds" {
+				h := md.Data.(metricdata.Histogram[float64])
+				hist = &h
+			}
+		}
+	}
+	require.NotNil(t, hist)
+	require.Len(t, hist.DataPoints, 2)
+
+	sums := map[string]float64{}
+	for _, dp := range hist.DataPoints {
+		trigger, _ := dp.Attributes.Value("trigger")
+		sums[trigger.AsString()] = dp.Sum
+	}
+	// Skew is recorded as a magnitude
+	assert.Equal(t, map[string]float64{clockSyncRestore: 90, clockSyncPeriodic: 0.25}, sums)
+}
+
+func TestRecordClockSkew_NoMetrics(t *testing.T) {
+	m := &manager{}
+	m.recordClockSkew(context.Background(), time.Second, clockSyncPeriodic, "")
+}
@@ -15,8 +15,13 @@ import (
 )
 
 // createConfigDisk generates an ext4 disk with instance configuration.
-// The disk contains /config.json read by the guest init binary.
+// The disk contains /config.json read by the guest init binary. UEFI
+// instances don't run it, so get none.
 func (m *manager) createConfigDisk(ctx context.Context, inst *Instance, imageInfo *images.Image, netConfig *network.NetworkConfig) error {
+	if inst.Firmware == images.FirmwareUEFI {
+		return nil
+	}
+
 	// Create temporary directory for config files
 	tmpDir, err := os.MkdirTemp("", "hypeman-config-*")
 	if err != nil {
@@ -47,12 +52,38 @@ func (m *manager) createConfigDisk(ctx context.Context, inst *Instance, imageInf
 
 // buildGuestConfig creates the vmconfig.Config struct for the guest init binary.
 func (m *manager) buildGuestConfig(ctx context.Context, inst *Instance, imageInfo *images.Image, netConfig *network.NetworkConfig) *vmconfig.Config {
+	// A root volume has no image config: unless the instance overrides the
+	// workload, boot its init system
+	if imageInfo == nil {
+		imageInfo = &images.Image{Entrypoint: []string{"/sbin/init"}}
+	}
+
+	// User data env sits between image defaults and instance overrides
+	baseEnv := imageInfo.Env
+	if inst.UserData != nil {
+		baseEnv = mergeEnv(imageInfo.Env, inst.UserData.Env)
+	}
+
 	cfg := &vmconfig.Config{
 		Entrypoint: imageInfo.Entrypoint,
 		Cmd:        imageInfo.Cmd,
 		Workdir:    imageInfo.WorkingDir,
-		Env:        mergeEnv(imageInfo.Env, inst.Env),
+		Env:        mergeEnv(baseEnv, inst.Env),
 		InitMode:   "exec",
+		Hostname:   inst.Name,
+		UserData:   guestUserData(inst),
+	}
+
+	// Workload overrides. Like Docker, overriding the entrypoint drops the image's cmd
+	if inst.Entrypoint != nil {
+		cfg.Entrypoint = inst.Entrypoint
+		cfg.Cmd = nil
+	}
+	if inst.Cmd != nil {
+		cfg.Cmd = inst.Cmd
+	}
+	if inst.Workdir != "" {
+		cfg.Workdir = inst.Workdir
 	}
 
 	if cfg.Workdir == "" {
@@ -66,20 +97,35 @@ func (m *manager) buildGuestConfig(ctx context.Context, inst *Instance, imageInf
 		cfg.GuestCIDR = netmaskToCIDR(netConfig.Netmask)
 		cfg.GuestGW = netConfig.Gateway
 		cfg.GuestDNS = netConfig.DNS
+		cfg.GuestSearchDomains = netConfig.SearchDomains
+		cfg.GuestDomain = netConfig.ServiceDomain
+		cfg.GuestMTU = netConfig.MTU
+		cfg.GuestNTPServers = netConfig.NTPServers
+		for _, r := range netConfig.Routes {
+			cfg.GuestRoutes = append(cfg.GuestRoutes, vmconfig.Route{Destination: r.Destination, Gateway: r.Gateway})
+		}
 	}
+	applyResolverConfig(cfg, inst.Resolver)
+	cfg.Links = m.guestLinks(ctx, inst)
+	applyTuning(cfg, inst)
 
 	// Volume mounts
-	// Volumes are attached as /dev/vdd, /dev/vde, etc. (after vda=rootfs, vdb=overlay, vdc=config)
+	// Volumes are attached as /dev/vdd, /dev/vde, etc. (after vda=rootfs, vdb=overlay, vdc=config),
+	// or from /dev/vdc when booting from a root volume (vda=root volume, vdb=config)
+	firstDevice := 'd'
+	if inst.RootVolume != "" {
+		firstDevice = 'c'
+	}
 	deviceIdx := 0
 	for _, vol := range inst.Volumes {
-		device := fmt.Sprintf("/dev/vd%c", 'd'+deviceIdx)
+		device := fmt.Sprintf("/dev/vd%c", firstDevice+rune(deviceIdx))
 		mount := vmconfig.VolumeMount{
 			Device: device,
 			Path:   vol.MountPath,
 		}
 		if vol.Overlay {
 			mount.Mode = "overlay"
-			mount.OverlayDevice = fmt.Sprintf("/dev/vd%c", 'd'+deviceIdx+1)
+			mount.OverlayDevice = fmt.Sprintf("/dev/vd%c", firstDevice+rune(deviceIdx)+1)
 			deviceIdx += 2
 		} else {
 			if vol.Readonly {
@@ -92,8 +138,37 @@ func (m *manager) buildGuestConfig(ctx context.Context, inst *Instance, imageInf
 		cfg.VolumeMounts = append(cfg.VolumeMounts, mount)
 	}
 
-	// Determine init mode based on image CMD
-	if images.IsSystemdImage(imageInfo.Entrypoint, imageInfo.Cmd) {
+	// Scratch disks are attached after the volumes
+	if inst.ScratchDisks != nil {
+		for i := range inst.ScratchDisks.Count {
+			cfg.VolumeMounts = append(cfg.VolumeMounts, vmconfig.VolumeMount{
+				Device: fmt.Sprintf("/dev/vd%c", firstDevice+rune(deviceIdx)),
+				Path:   scratchMountPath(i),
+				Mode:   "rw",
+			})
+			deviceIdx++
+		}
+	}
+
+	// Swap disk is attached last
+	if inst.SwapSize > 0 {
+		cfg.SwapDevice = fmt.Sprintf("/dev/vd%c", firstDevice+rune(deviceIdx))
+	}
+
+	// Shared directories, tagged in the order their devices are attached
+	for i, dir := range inst.SharedDirectories {
+		cfg.SharedMounts = append(cfg.SharedMounts, vmconfig.SharedMount{
+			Tag:      virtiofsTag(i),
+			Path:     dir.MountPath,
+			Readonly: dir.Readonly,
+		})
+	}
+
+	// Secrets themselves are delivered through the guest agent after boot
+	cfg.WaitForSecrets = len(inst.Secrets) > 0
+
+	// Determine init mode based on the effective entrypoint and CMD
+	if images.IsSystemdImage(cfg.Entrypoint, cfg.Cmd) {
 		cfg.InitMode = "systemd"
 	}
 
@@ -0,0 +1,138 @@
+package instances
+
+import (
+	"context"
+	"testing"
+
+	"github.com/kernel/hypeman/lib/images"
+	"github.com/kernel/hypeman/lib/vmconfig"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBuildGuestConfig_WorkloadOverrides(t *testing.T) {
+	m := &manager{}
+	imageInfo := &images.Image{
+		Entrypoint: []string{"/docker-entrypoint.sh"},
+		Cmd:        []string{"nginx", "-g", "daemon off;"},
+		WorkingDir: "/srv",
+	}
+
+	tests := []struct {
+		name           string
+		entrypoint     []string
+		cmd            []string
+		workdir        string
+		wantEntrypoint []string
+		wantCmd        []string
+		wantWorkdir    string
+	}{
+		{
+			name:           "image defaults",
+			wantEntrypoint: []string{"/docker-entrypoint.sh"},
+			wantCmd:        []string{"nginx", "-g", "daemon off;"},
+			wantWorkdir:    "/srv",
+		},
+		{
+			name:           "cmd only",
+			cmd:            []string{"nginx", "-t"},
+			wantEntrypoint: []string{"/docker-entrypoint.sh"},
+			wantCmd:        []string{"nginx", "-t"},
+			wantWorkdir:    "/srv",
+		},
+		{
+			name:           "entrypoint clears image cmd",
+			entrypoint:     []string{"/bin/sh"},
+			workdir:        "/tmp",
+			wantEntrypoint: []string{"/bin/sh"},
+			wantWorkdir:    "/tmp",
+		},
+		{
+			name:        "empty entrypoint with cmd",
+			entrypoint:  []string{},
+			cmd:         []string{"/bin/echo", "hi"},
+			wantCmd:     []string{"/bin/echo", "hi"},
+			wantWorkdir: "/srv",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			inst := &Instance{StoredMetadata: StoredMetadata{
+				Id:         "inst-1",
+				Name:       "web",
+				Entrypoint: tt.entrypoint,
+				Cmd:        tt.cmd,
+				Workdir:    tt.workdir,
+			}}
+			cfg := m.buildGuestConfig(context.Background(), inst, imageInfo, nil)
+			assert.Equal(t, tt.wantEntrypoint, nonEmpty(cfg.Entrypoint))
+			assert.Equal(t, tt.wantCmd, nonEmpty(cfg.Cmd))
+			assert.Equal(t, tt.wantWorkdir, cfg.Workdir)
+			assert.Equal(t, "exec", cfg.InitMode)
+		})
+	}
+}
+
+func TestBuildGuestConfig_SystemdOverride(t *testing.T) {
+	m := &manager{}
+	imageInfo := &images.Image{Cmd: []string{"/bin/bash"}}
+	inst := &Instance{StoredMetadata: StoredMetadata{Id: "inst-1", Name: "vm", Cmd: []string{"/sbin/init"}}}
+
+	cfg := m.buildGuestConfig(context.Background(), inst, imageInfo, nil)
+	assert.Equal(t, "systemd", cfg.InitMode)
+}
+
+func TestBuildGuestConfig_SharedDirectories(t *testing.T) {
+	m := &manager{}
+	imageInfo := &images.Image{Cmd: []string{"/bin/sh"}}
+	inst := &Instance{StoredMetadata: StoredMetadata{
+		Id:   "inst-1",
+		Name: "vm",
+		SharedDirectories: []SharedDirectory{
+			{HostPath: "/srv/shared/a", MountPath: "/mnt/a", Readonly: true},
+			{HostPath: "/srv/shared/b", MountPath: "/mnt/b"},
+		},
+	}}
+
+	cfg := m.buildGuestConfig(context.Background(), inst, imageInfo, nil)
+	assert.Equal(t, []vmconfig.SharedMount{
+		{Tag: "fs0", Path: "/mnt/a", Readonly: true},
+		{Tag: "fs1", Path: "/mnt/b"},
+	}, cfg.SharedMounts)
+}
+
+func TestBuildGuestConfig_RootVolume(t *testing.T) {
+	m := &manager{}
+	inst := &Instance{StoredMetadata: StoredMetadata{
+		Id:         "inst-1",
+		Name:       "vm",
+		RootVolume: "vol-root",
+		Volumes: []VolumeAttachment{
+			{VolumeID: "vol-data", MountPath: "/data"},
+			{VolumeID: "vol-base", MountPath: "/base", Readonly: true, Overlay: true, OverlaySize: 1 << 30},
+		},
+	}}
+
+	// Without an image, the volume's init system runs
+	cfg := m.buildGuestConfig(context.Background(), inst, nil, nil)
+	assert.Equal(t, []string{"/sbin/init"}, cfg.Entrypoint)
+	assert.Equal(t, "systemd", cfg.InitMode)
+
+	// vda is the root volume and vdb the config disk, so volumes start at vdc
+	assert.Equal(t, []vmconfig.VolumeMount{
+		{Device: "/dev/vdc", Path: "/data", Mode: "rw"},
+		{Device: "/dev/vdd", Path: "/base", Mode: "overlay", OverlayDevice: "/dev/vde"},
+	}, cfg.VolumeMounts)
+
+	inst.Entrypoint = []string{"/usr/bin/app"}
+	cfg = m.buildGuestConfig(context.Background(), inst, nil, nil)
+	assert.Equal(t, []string{"/usr/bin/app"}, cfg.Entrypoint)
+	assert.Equal(t, "exec", cfg.InitMode)
+}
+
+// nonEmpty normalizes empty slices to nil for comparison.
+func nonEmpty(s []string) []string {
+	if len(s) == 0 {
+		return nil
+	}
+	return s
+}
@@ -3,6 +3,7 @@ package instances
 import (
 	"context"
 	"fmt"
+	"os"
 	"path/filepath"
 	"regexp"
 	"strings"
@@ -25,8 +26,18 @@ const (
 	// MaxVolumesPerInstance is the maximum number of volumes that can be attached
 	// to a single instance. This limit exists because volume devices are named
 	// /dev/vdd, /dev/vde, ... /dev/vdz (letters d-z = 23 devices).
-	// Devices a-c are reserved for rootfs, overlay, and config disk.
+	// Devices a-c are reserved for rootfs, overlay, and config disk. An
+	// instance booted from a root volume has no overlay, so it starts at vdc.
 	MaxVolumesPerInstance = 23
+
+	// MaxSharedDirectoriesPerInstance is the maximum number of host directories
+	// that can be shared with a single instance, each served by its own
+	// virtiofsd process
+	MaxSharedDirectoriesPerInstance = 8
+
+	// DefaultOverlaySize is the writable overlay of an instance booted from
+	// an image that doesn't set overlay_size
+	DefaultOverlaySize = 10 * 1024 * 1024 * 1024 // 10GB
 )
 
 // systemDirectories are paths that cannot be used as volume mount points
@@ -95,43 +106,29 @@ func generateVsockCID(instanceID string) int64 {
 func (m *manager) createInstance(
 	ctx context.Context,
 	req CreateInstanceRequest,
-) (*Instance, error) {
+) (_ *Instance, retErr error) {
 	start := time.Now()
 	log := logger.FromContext(ctx)
-	log.InfoContext(ctx, "creating instance", "name", req.Name, "image", req.Image, "vcpus", req.Vcpus)
+	log.InfoContext(ctx, "creating instance", "name", req.Name, "image", req.Image, "root_volume", req.RootVolume, "vcpus", req.Vcpus)
 
 	// Start tracing span if tracer is available
-	if m.metrics != nil && m.metrics.tracer != nil {
-		var span trace.Span
-		ctx, span = m.metrics.tracer.Start(ctx, "CreateInstance")
-		defer span.End()
-	}
-
-	// 1. Validate request
-	if err := validateCreateRequest(req); err != nil {
-		log.ErrorContext(ctx, "invalid create request", "error", err)
-		return nil, err
-	}
-
-	// 2. Validate image exists and is ready
-	log.DebugContext(ctx, "validating image", "image", req.Image)
-	imageInfo, err := m.imageManager.GetImage(ctx, req.Image)
+	ctx, endSpan := m.startSpan(ctx, "CreateInstance",
+		attribute.String("instance_name", req.Name),
+		attribute.String("image", req.Image),
+	)
+	defer func() { endSpan(retErr) }()
+
+	// 1-2. Validate request, image and resource limits
+	adm, err := m.admitCreate(ctx, req)
 	if err != nil {
-		log.ErrorContext(ctx, "failed to get image", "image", req.Image, "error", err)
-		if err == images.ErrNotFound {
-			return nil, fmt.Errorf("image %s: %w", req.Image, err)
-		}
-		return nil, fmt.Errorf("get image: %w", err)
-	}
-
-	if imageInfo.Status != images.StatusReady {
-		log.ErrorContext(ctx, "image not ready", "image", req.Image, "status", imageInfo.Status)
-		return nil, fmt.Errorf("%w: image status is %s", ErrImageNotReady, imageInfo.Status)
+		return nil, err
 	}
+	imageInfo := adm.image
 
 	// 3. Generate instance ID (CUID2 for secure, collision-resistant IDs)
 	id := cuid2.Generate()
 	log.DebugContext(ctx, "generated instance ID", "instance_id", id)
+	trace.SpanFromContext(ctx).SetAttributes(attribute.String("instance_id", id))
 
 	// 4. Generate vsock configuration
 	vsockCID := generateVsockCID(id)
@@ -143,51 +140,8 @@ func (m *manager) createInstance(
 		return nil, ErrAlreadyExists
 	}
 
-	// 6. Apply defaults
-	size := req.Size
-	if size == 0 {
-		size = 1 * 1024 * 1024 * 1024 // 1GB default
-	}
-	hotplugSize := req.HotplugSize
-	if hotplugSize == 0 {
-		hotplugSize = 3 * 1024 * 1024 * 1024 // 3GB default
-	}
-	overlaySize := req.OverlaySize
-	if overlaySize == 0 {
-		overlaySize = 10 * 1024 * 1024 * 1024 // 10GB default
-	}
-	// Validate overlay size against max
-	if overlaySize > m.limits.MaxOverlaySize {
-		return nil, fmt.Errorf("overlay size %d exceeds maximum allowed size %d", overlaySize, m.limits.MaxOverlaySize)
-	}
-	vcpus := req.Vcpus
-	if vcpus == 0 {
-		vcpus = 2
-	}
-
-	// Validate per-instance resource limits
-	if m.limits.MaxVcpusPerInstance > 0 && vcpus > m.limits.MaxVcpusPerInstance {
-		return nil, fmt.Errorf("vcpus %d exceeds maximum allowed %d per instance", vcpus, m.limits.MaxVcpusPerInstance)
-	}
-	totalMemory := size + hotplugSize
-	if m.limits.MaxMemoryPerInstance > 0 && totalMemory > m.limits.MaxMemoryPerInstance {
-		return nil, fmt.Errorf("total memory %d (size + hotplug_size) exceeds maximum allowed %d per instance", totalMemory, m.limits.MaxMemoryPerInstance)
-	}
-
-	// Validate aggregate resource limits
-	if m.limits.MaxTotalVcpus > 0 || m.limits.MaxTotalMemory > 0 {
-		usage, err := m.calculateAggregateUsage(ctx)
-		if err != nil {
-			log.WarnContext(ctx, "failed to calculate aggregate usage, skipping limit check", "error", err)
-		} else {
-			if m.limits.MaxTotalVcpus > 0 && usage.TotalVcpus+vcpus > m.limits.MaxTotalVcpus {
-				return nil, fmt.Errorf("total vcpus would be %d, exceeds aggregate limit of %d", usage.TotalVcpus+vcpus, m.limits.MaxTotalVcpus)
-			}
-			if m.limits.MaxTotalMemory > 0 && usage.TotalMemory+totalMemory > m.limits.MaxTotalMemory {
-				return nil, fmt.Errorf("total memory would be %d, exceeds aggregate limit of %d", usage.TotalMemory+totalMemory, m.limits.MaxTotalMemory)
-			}
-		}
-	}
+	// 6. Defaults were applied during admission
+	size, hotplugSize, overlaySize, vcpus := adm.size, adm.hotplugSize, adm.overlaySize, adm.vcpus
 
 	if req.Env == nil {
 		req.Env = make(map[string]string)
@@ -199,8 +153,16 @@ func (m *manager) createInstance(
 		networkName = "default"
 	}
 
-	// 8. Get default kernel version
+	// 8. Get kernel version: the image's, if it names one (VM disk imports
+	// can), otherwise the default
 	kernelVer := m.systemManager.GetDefaultKernelVersion()
+	if imageInfo != nil && imageInfo.KernelVersion != "" {
+		kernelVer = system.KernelVersion(imageInfo.KernelVersion)
+		if _, err := m.systemManager.EnsureKernel(kernelVer); err != nil {
+			log.ErrorContext(ctx, "failed to get image kernel", "image", imageInfo.Name, "kernel_version", kernelVer, "error", err)
+			return nil, fmt.Errorf("image kernel %s: %w", kernelVer, err)
+		}
+	}
 
 	// 9. Get process manager for hypervisor type (needed for socket name)
 	hvType := req.Hypervisor
@@ -218,6 +180,15 @@ func (m *manager) createInstance(
 		}
 	}
 
+	// Pre-create hooks may veto the instance before anything is allocated
+	hookInst := StoredMetadata{Id: id, Name: req.Name, Image: req.Image, Tenant: req.Tenant, Size: size, HotplugSize: hotplugSize, Vcpus: vcpus, Devices: req.Devices, HypervisorType: hvType, CreatedAt: time.Now()}
+	if req.GPU != nil {
+		hookInst.GPUProfile = req.GPU.Profile
+	}
+	if err := m.runHooks(ctx, HookPreCreate, newHookInstance(&hookInst, "")); err != nil {
+		return nil, err
+	}
+
 	starter, err := m.getVMStarter(hvType)
 	if err != nil {
 		log.ErrorContext(ctx, "failed to get vm starter", "error", err)
@@ -239,11 +210,13 @@ func (m *manager) createInstance(
 	var resolvedDeviceIDs []string
 	var gpuProfile string
 	var gpuMdevUUID string
+	var gpuMIG *devices.MIGInstance
 
 	// Setup cleanup stack early so device attachment errors trigger cleanup
 	cu := cleanup.Make(func() {
 		log.DebugContext(ctx, "cleaning up instance on error", "instance_id", id)
 		m.deleteInstanceData(id)
+		m.removeHypervisorCgroup(id)
 	})
 	defer cu.Clean()
 
@@ -260,13 +233,14 @@ func (m *manager) createInstance(
 	// Handle vGPU profile request - create mdev device
 	if req.GPU != nil && req.GPU.Profile != "" {
 		log.InfoContext(ctx, "creating vGPU mdev", "instance_id", id, "profile", req.GPU.Profile)
-		mdev, err := devices.CreateMdev(ctx, req.GPU.Profile, id)
+		mdev, err := devices.CreateMdev(ctx, req.GPU.Profile, id, adm.vfPlacement)
 		if err != nil {
 			log.ErrorContext(ctx, "failed to create mdev", "profile", req.GPU.Profile, "error", err)
 			return nil, fmt.Errorf("create vGPU mdev for profile %s: %w", req.GPU.Profile, err)
 		}
 		gpuProfile = req.GPU.Profile
 		gpuMdevUUID = mdev.UUID
+		gpuMIG = mdev.MIG
 		log.InfoContext(ctx, "created vGPU mdev", "instance_id", id, "profile", gpuProfile, "uuid", gpuMdevUUID)
 
 		// Add mdev cleanup to stack
@@ -275,6 +249,11 @@ func (m *manager) createInstance(
 			if err := devices.DestroyMdev(ctx, gpuMdevUUID); err != nil {
 				log.WarnContext(ctx, "failed to destroy mdev on cleanup", "instance_id", id, "uuid", gpuMdevUUID, "error", err)
 			}
+			if gpuMIG != nil {
+				if err := devices.DestroyMIGInstance(ctx, *gpuMIG); err != nil {
+					log.WarnContext(ctx, "failed to destroy MIG GPU instance on cleanup", "instance_id", id, "gpu", gpuMIG.GPUIndex, "gi", gpuMIG.InstanceID, "error", err)
+				}
+			}
 		})
 	}
 
@@ -287,7 +266,7 @@ func (m *manager) createInstance(
 			}
 			if device.AttachedTo != nil {
 				log.ErrorContext(ctx, "device already attached", "device", deviceRef, "instance", *device.AttachedTo)
-				return nil, fmt.Errorf("device %s is already attached to instance %s", deviceRef, *device.AttachedTo)
+				return nil, fmt.Errorf("%w: device %s is already attached to instance %s", devices.ErrInUse, deviceRef, *device.AttachedTo)
 			}
 			// Auto-bind to VFIO if not already bound
 			if !device.BoundToVFIO {
@@ -313,14 +292,35 @@ func (m *manager) createInstance(
 		Id:                       id,
 		Name:                     req.Name,
 		Image:                    req.Image,
+		RootVolume:               req.RootVolume,
+		Tenant:                   req.Tenant,
+		CreatedBy:                req.CreatedBy,
 		Size:                     size,
 		HotplugSize:              hotplugSize,
 		OverlaySize:              overlaySize,
+		SwapSize:                 req.SwapSize,
 		Vcpus:                    vcpus,
 		NetworkBandwidthDownload: req.NetworkBandwidthDownload, // Will be set by caller if using resource manager
 		NetworkBandwidthUpload:   req.NetworkBandwidthUpload,   // Will be set by caller if using resource manager
 		DiskIOBps:                req.DiskIOBps,                // Will be set by caller if using resource manager
+		NetworkDownloadBurst:     req.NetworkDownloadBurst,
+		NetworkUploadBurst:       req.NetworkUploadBurst,
 		Env:                      req.Env,
+		Entrypoint:               req.Entrypoint,
+		Cmd:                      req.Cmd,
+		Workdir:                  req.Workdir,
+		ScratchDisks:             req.ScratchDisks,
+		SharedDirectories:        adm.sharedDirectories,
+		USBDevices:               adm.usbDevices,
+		Placement:                adm.placement,
+		Secrets:                  req.Secrets,
+		KernelArgs:               req.KernelArgs,
+		Firmware:                 adm.firmware,
+		Sysctls:                  req.Sysctls,
+		Labels:                   req.Labels,
+		Ulimits:                  req.Ulimits,
+		NestedVirt:               req.EnableNestedVirt,
+		ShutdownGracePeriod:      req.ShutdownGracePeriod,
 		NetworkEnabled:           req.NetworkEnabled,
 		CreatedAt:                time.Now(),
 		StartedAt:                nil,
@@ -335,6 +335,12 @@ func (m *manager) createInstance(
 		Devices:                  resolvedDeviceIDs,
 		GPUProfile:               gpuProfile,
 		GPUMdevUUID:              gpuMdevUUID,
+		GPUMIG:                   gpuMIG,
+		UserData:                 req.UserData,
+		Resolver:                 req.Resolver,
+		Links:                    req.Links,
+		IdlePolicy:               req.IdlePolicy,
+		Schedules:                req.Schedules,
 	}
 
 	// 12. Ensure directories
@@ -344,11 +350,44 @@ func (m *manager) createInstance(
 		return nil, fmt.Errorf("ensure directories: %w", err)
 	}
 
-	// 13. Create overlay disk with specified size
-	log.DebugContext(ctx, "creating overlay disk", "instance_id", id, "size_bytes", stored.OverlaySize)
-	if err := m.createOverlayDisk(id, stored.OverlaySize); err != nil {
-		log.ErrorContext(ctx, "failed to create overlay disk", "instance_id", id, "error", err)
-		return nil, fmt.Errorf("create overlay disk: %w", err)
+	// 13. Create overlay disk with specified size, or attach the root volume
+	// that takes the place of the image and overlay
+	if stored.RootVolume != "" {
+		log.DebugContext(ctx, "attaching root volume", "instance_id", id, "volume_id", stored.RootVolume)
+		if err := m.volumeManager.AttachVolume(ctx, stored.RootVolume, volumes.AttachVolumeRequest{
+			InstanceID: id,
+			MountPath:  "/",
+		}); err != nil {
+			log.ErrorContext(ctx, "failed to attach root volume", "instance_id", id, "volume_id", stored.RootVolume, "error", err)
+			return nil, fmt.Errorf("attach root volume %s: %w", stored.RootVolume, err)
+		}
+		cu.Add(func() {
+			m.volumeManager.DetachVolume(ctx, stored.RootVolume, id)
+		})
+	} else if stored.Firmware == images.FirmwareUEFI {
+		// UEFI instances boot a writable copy of the image's whole disk
+		// in place of the overlay
+		log.DebugContext(ctx, "copying image disk", "instance_id", id, "size_bytes", stored.OverlaySize)
+		imagePath, err := images.GetDiskPath(m.paths, imageInfo.Name, imageInfo.Digest)
+		if err != nil {
+			return nil, err
+		}
+		_, endDiskSpan := m.startSpan(ctx, "CreateUEFIDisk", attribute.Int64("size_bytes", stored.OverlaySize))
+		err = m.createUEFIDisk(id, imagePath, stored.OverlaySize)
+		endDiskSpan(err)
+		if err != nil {
+			log.ErrorContext(ctx, "failed to create disk", "instance_id", id, "error", err)
+			return nil, fmt.Errorf("create disk: %w", err)
+		}
+	} else {
+		log.DebugContext(ctx, "creating overlay disk", "instance_id", id, "size_bytes", stored.OverlaySize)
+		_, endOverlaySpan := m.startSpan(ctx, "CreateOverlayDisk", attribute.Int64("size_bytes", stored.OverlaySize))
+		err = m.createOverlayDisk(id, stored.OverlaySize)
+		endOverlaySpan(err)
+		if err != nil {
+			log.ErrorContext(ctx, "failed to create overlay disk", "instance_id", id, "error", err)
+			return nil, fmt.Errorf("create overlay disk: %w", err)
+		}
 	}
 
 	// 14. Allocate network (if network enabled)
@@ -356,13 +395,13 @@ func (m *manager) createInstance(
 	if networkName != "" {
 		log.DebugContext(ctx, "allocating network", "instance_id", id, "network", networkName,
 			"download_bps", stored.NetworkBandwidthDownload, "upload_bps", stored.NetworkBandwidthUpload)
-		netConfig, err = m.networkManager.CreateAllocation(ctx, network.AllocateRequest{
-			InstanceID:    id,
-			InstanceName:  req.Name,
-			DownloadBps:   stored.NetworkBandwidthDownload,
-			UploadBps:     stored.NetworkBandwidthUpload,
-			UploadCeilBps: stored.NetworkBandwidthUpload * int64(m.networkManager.GetUploadBurstMultiplier()),
+		netCtx, endNetworkSpan := m.startSpan(ctx, "AllocateNetwork", attribute.String("network", networkName))
+		netConfig, err = m.networkManager.CreateAllocation(netCtx, network.AllocateRequest{
+			InstanceID:      id,
+			InstanceName:    req.Name,
+			BandwidthLimits: m.bandwidthLimits(stored),
 		})
+		endNetworkSpan(err)
 		if err != nil {
 			log.ErrorContext(ctx, "failed to allocate network", "instance_id", id, "network", networkName, "error", err)
 			return nil, fmt.Errorf("allocate network: %w", err)
@@ -377,16 +416,19 @@ func (m *manager) createInstance(
 			if netAlloc, err := m.networkManager.GetAllocation(ctx, id); err == nil {
 				m.networkManager.ReleaseAllocation(ctx, netAlloc)
 			}
+			m.networkManager.DeleteAllocation(ctx, id)
 		})
 	}
 
 	// 15. Validate and attach volumes
 	if len(req.Volumes) > 0 {
 		log.DebugContext(ctx, "validating volumes", "instance_id", id, "count", len(req.Volumes))
+		_, endVolumeSpan := m.startSpan(ctx, "AttachVolumes", attribute.Int("count", len(req.Volumes)))
 		for _, volAttach := range req.Volumes {
 			// Check volume exists
 			_, err := m.volumeManager.GetVolume(ctx, volAttach.VolumeID)
 			if err != nil {
+				endVolumeSpan(err)
 				log.ErrorContext(ctx, "volume not found", "instance_id", id, "volume_id", volAttach.VolumeID, "error", err)
 				return nil, fmt.Errorf("volume %s: %w", volAttach.VolumeID, err)
 			}
@@ -397,6 +439,7 @@ func (m *manager) createInstance(
 				MountPath:  volAttach.MountPath,
 				Readonly:   volAttach.Readonly,
 			}); err != nil {
+				endVolumeSpan(err)
 				log.ErrorContext(ctx, "failed to attach volume", "instance_id", id, "volume_id", volAttach.VolumeID, "error", err)
 				return nil, fmt.Errorf("attach volume %s: %w", volAttach.VolumeID, err)
 			}
@@ -411,19 +454,42 @@ func (m *manager) createInstance(
 			if volAttach.Overlay {
 				log.DebugContext(ctx, "creating volume overlay disk", "instance_id", id, "volume_id", volAttach.VolumeID, "size", volAttach.OverlaySize)
 				if err := m.createVolumeOverlayDisk(id, volAttach.VolumeID, volAttach.OverlaySize); err != nil {
+					endVolumeSpan(err)
 					log.ErrorContext(ctx, "failed to create volume overlay disk", "instance_id", id, "volume_id", volAttach.VolumeID, "error", err)
 					return nil, fmt.Errorf("create volume overlay disk %s: %w", volAttach.VolumeID, err)
 				}
 			}
 		}
+		endVolumeSpan(nil)
 		// Store volume attachments in metadata
 		stored.Volumes = req.Volumes
 	}
 
+	// 15b. Create scratch disks (removed with the instance directory)
+	if stored.ScratchDisks != nil {
+		log.DebugContext(ctx, "creating scratch disks", "instance_id", id, "count", stored.ScratchDisks.Count, "size_bytes", stored.ScratchDisks.Size)
+		if err := m.createScratchDisks(id, stored.ScratchDisks); err != nil {
+			log.ErrorContext(ctx, "failed to create scratch disks", "instance_id", id, "error", err)
+			return nil, err
+		}
+	}
+
+	// 15c. Create swap disk (removed with the instance directory)
+	if stored.SwapSize > 0 {
+		log.DebugContext(ctx, "creating swap disk", "instance_id", id, "size_bytes", stored.SwapSize)
+		if err := m.createSwapDisk(id, stored.SwapSize); err != nil {
+			log.ErrorContext(ctx, "failed to create swap disk", "instance_id", id, "error", err)
+			return nil, err
+		}
+	}
+
 	// 16. Create config disk (needs Instance for buildVMConfig)
 	inst := &Instance{StoredMetadata: *stored}
 	log.DebugContext(ctx, "creating config disk", "instance_id", id)
-	if err := m.createConfigDisk(ctx, inst, imageInfo, netConfig); err != nil {
+	configCtx, endConfigSpan := m.startSpan(ctx, "CreateConfigDisk")
+	err = m.createConfigDisk(configCtx, inst, imageInfo, netConfig)
+	endConfigSpan(err)
+	if err != nil {
 		log.ErrorContext(ctx, "failed to create config disk", "instance_id", id, "error", err)
 		return nil, fmt.Errorf("create config disk: %w", err)
 	}
@@ -438,7 +504,10 @@ func (m *manager) createInstance(
 
 	// 18. Start VMM and boot VM
 	log.InfoContext(ctx, "starting VMM and booting VM", "instance_id", id)
-	if err := m.startAndBootVM(ctx, stored, imageInfo, netConfig); err != nil {
+	bootCtx, endBootSpan := m.startSpan(ctx, "BootVM", attribute.String("hypervisor", string(hvType)))
+	err = m.startAndBootVM(bootCtx, stored, imageInfo, netConfig)
+	endBootSpan(err)
+	if err != nil {
 		log.ErrorContext(ctx, "failed to start and boot VM", "instance_id", id, "error", err)
 		return nil, err
 	}
@@ -456,6 +525,12 @@ func (m *manager) createInstance(
 
 	// Success - release cleanup stack (prevent cleanup)
 	cu.Release()
+	m.startWatchdog(ctx, stored)
+	if stored.NetworkEnabled {
+		if err := m.networkManager.RefreshInstanceDNS(ctx); err != nil {
+			log.WarnContext(ctx, "failed to add instance DNS record", "instance_id", id, "error", err)
+		}
+	}
 
 	// Record metrics
 	if m.metrics != nil {
@@ -469,23 +544,388 @@ func (m *manager) createInstance(
 	return &finalInst, nil
 }
 
-// validateCreateRequest validates the create instance request
-func validateCreateRequest(req CreateInstanceRequest) error {
-	if req.Name == "" {
+// createAdmission holds what admitCreate resolved for a create request
+type createAdmission struct {
+	image             *images.Image // nil when booting from a root volume
+	firmware          string        // images.FirmwareDirect or images.FirmwareUEFI
+	size              int64
+	hotplugSize       int64
+	overlaySize       int64
+	vcpus             int
+	sharedDirectories []SharedDirectory // with host paths resolved
+	usbDevices        []string          // normalized USB device selectors
+	placement         *PlacementHints   // with affinity instances resolved to IDs
+	vfPlacement       devices.VFPlacement
+}
+
+// admitCreate validates a create request, checks that its image is ready (or
+// that its root volume exists), applies size defaults and checks resource
+// limits. It has no side effects.
+func (m *manager) admitCreate(ctx context.Context, req CreateInstanceRequest) (*createAdmission, error) {
+	log := logger.FromContext(ctx)
+
+	// Validate request
+	if err := validateCreateRequest(req); err != nil {
+		log.ErrorContext(ctx, "invalid create request", "error", err)
+		return nil, err
+	}
+	sharedDirectories, err := m.resolveSharedDirectories(req.SharedDirectories, req.Volumes)
+	if err != nil {
+		log.ErrorContext(ctx, "invalid shared directories", "error", err)
+		return nil, err
+	}
+	if err := m.validateSecretAttachments(ctx, req.Secrets); err != nil {
+		log.ErrorContext(ctx, "invalid secret attachments", "error", err)
+		return nil, err
+	}
+	if err := m.checkLinkTenants(ctx, req.Tenant, req.Links); err != nil {
+		log.ErrorContext(ctx, "invalid links", "error", err)
+		return nil, err
+	}
+	hvType := req.Hypervisor
+	if hvType == "" {
+		hvType = m.defaultHypervisor
+	}
+	usbDevices, err := normalizeUSBDevices(req.USBDevices, hvType)
+	if err != nil {
+		log.ErrorContext(ctx, "invalid USB devices", "error", err)
+		return nil, err
+	}
+	placement, vfPlacement, err := m.resolvePlacement(ctx, req)
+	if err != nil {
+		log.ErrorContext(ctx, "invalid placement hints", "error", err)
+		return nil, err
+	}
+	if req.EnableNestedVirt {
+		if err := checkNestedVirt(); err != nil {
+			log.ErrorContext(ctx, "nested virtualization unavailable", "error", err)
+			return nil, err
+		}
+	}
+
+	// Validate image exists and is ready. A root volume replaces the image.
+	var imageInfo *images.Image
+	if req.RootVolume != "" {
+		if _, err := m.volumeManager.GetVolume(ctx, req.RootVolume); err != nil {
+			log.ErrorContext(ctx, "failed to get root volume", "volume_id", req.RootVolume, "error", err)
+			return nil, fmt.Errorf("root volume %s: %w", req.RootVolume, err)
+		}
+	} else {
+		log.DebugContext(ctx, "validating image", "image", req.Image)
+		imageCtx, endImageSpan := m.startSpan(ctx, "ResolveImage", attribute.String("image", req.Image))
+		imageInfo, err = m.imageManager.GetImage(imageCtx, req.Image)
+		endImageSpan(err)
+		if err != nil {
+			log.ErrorContext(ctx, "failed to get image", "image", req.Image, "error", err)
+			if err == images.ErrNotFound {
+				return nil, fmt.Errorf("image %s: %w", req.Image, err)
+			}
+			return nil, fmt.Errorf("get image: %w", err)
+		}
+
+		if imageInfo.Status != images.StatusReady {
+			log.ErrorContext(ctx, "image not ready", "image", req.Image, "status", imageInfo.Status)
+			return nil, fmt.Errorf("%w: image status is %s", ErrImageNotReady, imageInfo.Status)
+		}
+		if imageInfo, err = m.refreshStaleImage(ctx, imageInfo); err != nil {
+			return nil, err
+		}
+	}
+	firmware, err := m.resolveFirmware(req, imageInfo, hvType)
+	if err != nil {
+		log.ErrorContext(ctx, "invalid firmware", "firmware", req.Firmware, "error", err)
+		return nil, err
+	}
+
+	// Apply defaults
+	adm := &createAdmission{
+		image:             imageInfo,
+		firmware:          firmware,
+		size:              req.Size,
+		hotplugSize:       req.HotplugSize,
+		overlaySize:       req.OverlaySize,
+		vcpus:             req.Vcpus,
+		sharedDirectories: sharedDirectories,
+		usbDevices:        usbDevices,
+		placement:         placement,
+		vfPlacement:       vfPlacement,
+	}
+	if firmware == images.FirmwareUEFI {
+		// A UEFI guest's disk is a copy of the image's that can only grow
+		var imageSize int64
+		if imageInfo.SizeBytes != nil {
+			imageSize = *imageInfo.SizeBytes
+		}
+		if adm.overlaySize != 0 && adm.overlaySize < imageSize {
+			return nil, fmt.Errorf("%w: overlay_size %d is smaller than the image disk (%d bytes)", ErrInvalidFirmware, adm.overlaySize, imageSize)
+		}
+		if adm.overlaySize == 0 {
+			adm.overlaySize = imageSize
+		}
+	}
+	defaultSize, defaultHotplugSize := DefaultMemory(firmware)
+	if adm.size == 0 {
+		adm.size = defaultSize
+	}
+	if adm.hotplugSize == 0 && !req.FixedMemory {
+		adm.hotplugSize = defaultHotplugSize
+	}
+	if adm.overlaySize == 0 && req.RootVolume == "" {
+		adm.overlaySize = DefaultOverlaySize
+	}
+	if adm.vcpus == 0 {
+		adm.vcpus = 2
+	}
+
+	if err := m.checkResourceAvailability(ctx, adm); err != nil {
+		return nil, err
+	}
+	if req.GPU != nil && req.GPU.Profile != "" && m.deviceManager != nil {
+		if err := m.deviceManager.CheckGPUReservations(ctx, req.GPU.Profile, req.Tenant); err != nil {
+			log.ErrorContext(ctx, "vGPU reservations deny instance", "profile", req.GPU.Profile, "tenant", req.Tenant, "error", err)
+			return nil, fmt.Errorf("vGPU profile %s: %w", req.GPU.Profile, err)
+		}
+	}
+	return adm, nil
+}
+
+// SetAdmissionLimits replaces the limits checkResourceAvailability applies
+func (m *manager) SetAdmissionLimits(limits ResourceLimits) {
+	m.limitsMu.Lock()
+	defer m.limitsMu.Unlock()
+	m.limits.MaxOverlaySize = limits.MaxOverlaySize
+	m.limits.MaxVcpusPerInstance = limits.MaxVcpusPerInstance
+	m.limits.MaxMemoryPerInstance = limits.MaxMemoryPerInstance
+	m.limits.MaxTotalVcpus = limits.MaxTotalVcpus
+	m.limits.MaxTotalMemory = limits.MaxTotalMemory
+}
+
+// admissionLimits returns a copy of the limits for admission checks
+func (m *manager) admissionLimits() ResourceLimits {
+	m.limitsMu.RLock()
+	defer m.limitsMu.RUnlock()
+	return m.limits
+}
+
+// checkResourceAvailability checks an admitted instance against the
+// per-instance and aggregate resource limits
+func (m *manager) checkResourceAvailability(ctx context.Context, adm *createAdmission) error {
+	log := logger.FromContext(ctx)
+	limits := m.admissionLimits()
+
+	// Validate overlay size against max
+	if adm.overlaySize > limits.MaxOverlaySize {
+		return fmt.Errorf("%w: overlay size %d exceeds maximum allowed size %d", ErrResourceLimit, adm.overlaySize, limits.MaxOverlaySize)
+	}
+
+	// Validate per-instance resource limits
+	if limits.MaxVcpusPerInstance > 0 && adm.vcpus > limits.MaxVcpusPerInstance {
+		return fmt.Errorf("%w: vcpus %d exceeds maximum allowed %d per instance", ErrResourceLimit, adm.vcpus, limits.MaxVcpusPerInstance)
+	}
+	totalMemory := adm.size + adm.hotplugSize
+	if limits.MaxMemoryPerInstance > 0 && totalMemory > limits.MaxMemoryPerInstance {
+		return fmt.Errorf("%w: total memory %d (size + hotplug_size) exceeds maximum allowed %d per instance", ErrResourceLimit, totalMemory, limits.MaxMemoryPerInstance)
+	}
+
+	// Validate aggregate resource limits
+	if limits.MaxTotalVcpus > 0 || limits.MaxTotalMemory > 0 {
+		usage, err := m.calculateAggregateUsage(ctx)
+		if err != nil {
+			log.WarnContext(ctx, "failed to calculate aggregate usage, skipping limit check", "error", err)
+		} else {
+			if limits.MaxTotalVcpus > 0 && usage.TotalVcpus+adm.vcpus > limits.MaxTotalVcpus {
+				return fmt.Errorf("%w: total vcpus would be %d, exceeds aggregate limit of %d", ErrResourceLimit, usage.TotalVcpus+adm.vcpus, limits.MaxTotalVcpus)
+			}
+			if limits.MaxTotalMemory > 0 && usage.TotalMemory+totalMemory > limits.MaxTotalMemory {
+				return fmt.Errorf("%w: total memory would be %d, exceeds aggregate limit of %d", ErrResourceLimit, usage.TotalMemory+totalMemory, limits.MaxTotalMemory)
+			}
+		}
+	}
+	return nil
+}
+
+// CheckCreateInstance runs the admission checks of CreateInstance without
+// creating anything: request validation, image readiness, resource limits,
+// hypervisor, device and vGPU profile availability, IP availability and
+// volume attachment rules. It returns the instance as it would be created,
+// without an ID or network address.
+func (m *manager) CheckCreateInstance(ctx context.Context, req CreateInstanceRequest) (*Instance, error) {
+	adm, err := m.admitCreate(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	hvType := req.Hypervisor
+	if hvType == "" {
+		hvType = m.defaultHypervisor
+	}
+	if _, err := m.getVMStarter(hvType); err != nil {
+		return nil, fmt.Errorf("get vm starter for %s: %w", hvType, err)
+	}
+
+	if req.GPU != nil && req.GPU.Profile != "" {
+		if err := devices.CheckProfileAvailable(req.GPU.Profile); err != nil {
+			return nil, fmt.Errorf("vGPU profile %s: %w", req.GPU.Profile, err)
+		}
+	}
+
+	var deviceIDs []string
+	if len(req.Devices) > 0 && m.deviceManager != nil {
+		for _, deviceRef := range req.Devices {
+			device, err := m.deviceManager.GetDevice(ctx, deviceRef)
+			if err != nil {
+				return nil, fmt.Errorf("device %s: %w", deviceRef, err)
+			}
+			if device.AttachedTo != nil {
+				return nil, fmt.Errorf("%w: device %s is already attached to instance %s", devices.ErrInUse, deviceRef, *device.AttachedTo)
+			}
+			deviceIDs = append(deviceIDs, device.Id)
+		}
+	}
+
+	if req.NetworkEnabled {
+		if err := m.networkManager.CheckAllocation(ctx, req.Name); err != nil {
+			return nil, fmt.Errorf("allocate network: %w", err)
+		}
+	}
+
+	if req.RootVolume != "" {
+		if err := m.volumeManager.CheckAttachVolume(ctx, req.RootVolume, volumes.AttachVolumeRequest{MountPath: "/"}); err != nil {
+			return nil, fmt.Errorf("attach root volume %s: %w", req.RootVolume, err)
+		}
+	}
+	for _, volAttach := range req.Volumes {
+		vol, err := m.volumeManager.GetVolume(ctx, volAttach.VolumeID)
+		if err != nil {
+			return nil, fmt.Errorf("volume %s: %w", volAttach.VolumeID, err)
+		}
+		// The overlay disk isn't encrypted, so writes would land on the host in the clear
+		if vol.Encrypted && volAttach.Overlay {
+			return nil, fmt.Errorf("volume %s: overlay mode is not supported for encrypted volumes", volAttach.VolumeID)
+		}
+		if err := m.volumeManager.CheckAttachVolume(ctx, volAttach.VolumeID, volumes.AttachVolumeRequest{
+			MountPath: volAttach.MountPath,
+			Readonly:  volAttach.Readonly,
+		}); err != nil {
+			return nil, fmt.Errorf("attach volume %s: %w", volAttach.VolumeID, err)
+		}
+	}
+
+	env := req.Env
+	if env == nil {
+		env = make(map[string]string)
+	}
+	return &Instance{
+		StoredMetadata: StoredMetadata{
+			Name:                     req.Name,
+			Image:                    req.Image,
+			RootVolume:               req.RootVolume,
+			Tenant:                   req.Tenant,
+			CreatedBy:                req.CreatedBy,
+			Size:                     adm.size,
+			HotplugSize:              adm.hotplugSize,
+			OverlaySize:              adm.overlaySize,
+			SwapSize:                 req.SwapSize,
+			Vcpus:                    adm.vcpus,
+			NetworkBandwidthDownload: req.NetworkBandwidthDownload,
+			NetworkBandwidthUpload:   req.NetworkBandwidthUpload,
+			NetworkDownloadBurst:     req.NetworkDownloadBurst,
+			NetworkUploadBurst:       req.NetworkUploadBurst,
+			DiskIOBps:                req.DiskIOBps,
+			Env:                      env,
+			NetworkEnabled:           req.NetworkEnabled,
+			Entrypoint:               req.Entrypoint,
+			Cmd:                      req.Cmd,
+			Workdir:                  req.Workdir,
+			Volumes:                  req.Volumes,
+			ScratchDisks:             req.ScratchDisks,
+			SharedDirectories:        adm.sharedDirectories,
+			USBDevices:               adm.usbDevices,
+			Placement:                adm.placement,
+			Secrets:                  req.Secrets,
+			KernelArgs:               req.KernelArgs,
+			Firmware:                 adm.firmware,
+			Sysctls:                  req.Sysctls,
+			Labels:                   req.Labels,
+			Ulimits:                  req.Ulimits,
+			NestedVirt:               req.EnableNestedVirt,
+			ShutdownGracePeriod:      req.ShutdownGracePeriod,
+			HypervisorType:           hvType,
+			Devices:                  deviceIDs,
+			UserData:                 req.UserData,
+			Resolver:                 req.Resolver,
+			Links:                    req.Links,
+			IdlePolicy:               req.IdlePolicy,
+			Schedules:                req.Schedules,
+		},
+		State: StateStopped,
+	}, nil
+}
+
+// refreshStaleImage re-converts an image whose disk is outdated or missing
+// before an instance boots from it. If that fails, an existing outdated
+// disk is still used; only a missing one is an error.
+func (m *manager) refreshStaleImage(ctx context.Context, img *images.Image) (*images.Image, error) {
+	if !img.Stale {
+		return img, nil
+	}
+	log := logger.FromContext(ctx)
+	log.InfoContext(ctx, "re-converting stale image", "image", img.Name, "converter_version", img.ConverterVersion)
+
+	fresh, err := m.imageManager.ReconvertImage(ctx, img.Name)
+	if err == nil {
+		return fresh, nil
+	}
+	diskPath, pathErr := images.GetDiskPath(m.paths, img.Name, img.Digest)
+	if pathErr == nil {
+		if _, statErr := os.Stat(diskPath); statErr == nil {
+			log.WarnContext(ctx, "failed to re-convert stale image, using its current disk", "image", img.Name, "error", err)
+			return img, nil
+		}
+	}
+	log.ErrorContext(ctx, "failed to rebuild missing image disk", "image", img.Name, "error", err)
+	return nil, fmt.Errorf("%w: rebuild disk: %v", ErrImageNotReady, err)
+}
+
+// validateInstanceName checks an instance name: lowercase letters, digits and
+// dashes, not starting or ending with a dash, at most 63 characters. Names
+// double as DNS labels, so they must be valid hostnames.
+func validateInstanceName(name string) error {
+	if name == "" {
 		return fmt.Errorf("name is required")
 	}
-	// Validate name format: lowercase letters, digits, dashes only
-	// No starting/ending with dashes, max 63 characters
-	if len(req.Name) > 63 {
+	if len(name) > 63 {
 		return fmt.Errorf("name must be 63 characters or less")
 	}
-	namePattern := regexp.MustCompile(`^[a-z0-9]([a-z0-9-]*[a-z0-9])?$`)
-	if !namePattern.MatchString(req.Name) {
+	if !instanceNamePattern.MatchString(name) {
 		return fmt.Errorf("name must contain only lowercase letters, digits, and dashes; cannot start or end with a dash")
 	}
-	if req.Image == "" {
+	return nil
+}
+
+var instanceNamePattern = regexp.MustCompile(`^[a-z0-9]([a-z0-9-]*[a-z0-9])?$`)
+
+// validateCreateRequest validates the create instance request
+func validateCreateRequest(req CreateInstanceRequest) error {
+	if err := validateInstanceName(req.Name); err != nil {
+		return err
+	}
+	if req.Image == "" && req.RootVolume == "" {
 		return fmt.Errorf("image is required")
 	}
+	if req.RootVolume != "" {
+		if req.Image != "" {
+			return fmt.Errorf("image and root_volume are mutually exclusive")
+		}
+		if req.OverlaySize != 0 {
+			return fmt.Errorf("overlay_size does not apply when booting from a root volume")
+		}
+		for _, vol := range req.Volumes {
+			if vol.VolumeID == req.RootVolume {
+				return fmt.Errorf("volume %s: already attached as the root volume", vol.VolumeID)
+			}
+		}
+	}
 	if req.Size < 0 {
 		return fmt.Errorf("size cannot be negative")
 	}
@@ -498,11 +938,53 @@ func validateCreateRequest(req CreateInstanceRequest) error {
 	if req.Vcpus < 0 {
 		return fmt.Errorf("vcpus cannot be negative")
 	}
+	if req.NetworkDownloadBurst < 0 || req.NetworkUploadBurst < 0 {
+		return fmt.Errorf("network burst cannot be negative")
+	}
+	if req.Entrypoint != nil && len(req.Entrypoint) == 0 && len(req.Cmd) == 0 {
+		return fmt.Errorf("entrypoint override is empty and no cmd is given")
+	}
+	if req.Workdir != "" && !filepath.IsAbs(req.Workdir) {
+		return fmt.Errorf("workdir %q must be an absolute path", req.Workdir)
+	}
+	if err := validateUserData(req.UserData); err != nil {
+		return err
+	}
+	if err := validateResolverConfig(req.Resolver); err != nil {
+		return err
+	}
+	if err := validateLinks(req.Name, req.Links, req.Resolver); err != nil {
+		return err
+	}
+	if err := validateIdlePolicy(req.IdlePolicy); err != nil {
+		return err
+	}
+	if err := validateSchedules(req.Schedules); err != nil {
+		return err
+	}
+	if err := validateKernelArgs(req.KernelArgs); err != nil {
+		return err
+	}
+	if err := validateTuning(req.Sysctls, req.Ulimits); err != nil {
+		return err
+	}
+	if err := validateLabels(req.Labels); err != nil {
+		return err
+	}
+	if err := validateGracePeriod(req.ShutdownGracePeriod); err != nil {
+		return err
+	}
 
 	// Validate volume attachments
 	if err := validateVolumeAttachments(req.Volumes); err != nil {
 		return err
 	}
+	if err := validateScratchDisks(req.ScratchDisks, req.Volumes, req.SharedDirectories); err != nil {
+		return err
+	}
+	if err := validateSwapSize(req.SwapSize, req.Volumes, req.ScratchDisks); err != nil {
+		return err
+	}
 
 	return nil
 }
@@ -590,18 +1072,42 @@ func (m *manager) startAndBootVM(
 	inst := &Instance{StoredMetadata: *stored}
 	vmConfig, err := m.buildHypervisorConfig(ctx, inst, imageInfo, netConfig)
 	if err != nil {
+		m.lockVolumes(ctx, stored)
 		return fmt.Errorf("build vm config: %w", err)
 	}
 
+	// Confine the hypervisor process
+	opts, err := m.hypervisorProcessOptions(stored)
+	if err != nil {
+		m.lockVolumes(ctx, stored)
+		return err
+	}
+
+	// Read secrets before booting, so a deleted secret fails the boot
+	secretPayload, err := m.loadSecretPayload(ctx, stored)
+	if err != nil {
+		m.lockVolumes(ctx, stored)
+		return err
+	}
+
+	// Serve shared directories before the hypervisor connects to them
+	if err := m.startVirtiofsd(ctx, stored, opts.CgroupDir); err != nil {
+		m.lockVolumes(ctx, stored)
+		return fmt.Errorf("start virtiofsd: %w", err)
+	}
+
 	// Start VM (handles process start, configuration, and boot)
 	log.DebugContext(ctx, "starting VM", "instance_id", stored.Id, "hypervisor", stored.HypervisorType, "version", stored.HypervisorVersion)
-	pid, hv, err := starter.StartVM(ctx, m.paths, stored.HypervisorVersion, stored.SocketPath, vmConfig)
+	pid, hv, err := starter.StartVM(ctx, m.paths, stored.HypervisorVersion, stored.SocketPath, vmConfig, opts)
 	if err != nil {
+		m.stopVirtiofsd(ctx, stored)
+		m.lockVolumes(ctx, stored)
 		return fmt.Errorf("start vm: %w", err)
 	}
 
 	// Store the PID for later cleanup
 	stored.HypervisorPID = &pid
+	stored.USBController = vmConfig.USBController
 	log.DebugContext(ctx, "VM started", "instance_id", stored.Id, "pid", pid)
 
 	// Optional: Expand memory to max if hotplug configured
@@ -614,6 +1120,15 @@ func (m *manager) startAndBootVM(
 		}
 	}
 
+	// The guest holds its application back until the secrets arrive
+	if secretPayload != nil {
+		go m.deliverSecrets(context.WithoutCancel(ctx), *stored, secretPayload)
+	}
+	// Peers linking to this instance learn its new address
+	if stored.NetworkEnabled {
+		go m.updateLinkingPeers(context.WithoutCancel(ctx), *stored)
+	}
+
 	return nil
 }
 
@@ -623,13 +1138,6 @@ func (m *manager) buildHypervisorConfig(ctx context.Context, inst *Instance, ima
 	kernelPath, _ := m.systemManager.GetKernelPath(system.KernelVersion(inst.KernelVersion))
 	initrdPath, _ := m.systemManager.GetInitrdPath()
 
-	// Disk configuration
-	// Get rootfs disk path from image manager
-	rootfsPath, err := images.GetDiskPath(m.paths, imageInfo.Name, imageInfo.Digest)
-	if err != nil {
-		return hypervisor.VMConfig{}, err
-	}
-
 	// Get disk I/O limits (same for all disks in this VM)
 	ioBps := inst.DiskIOBps
 	burstBps := ioBps * 4 // Burst is 4x sustained
@@ -637,18 +1145,53 @@ func (m *manager) buildHypervisorConfig(ctx context.Context, inst *Instance, ima
 		burstBps = 0
 	}
 
-	disks := []hypervisor.DiskConfig{
-		// Rootfs (from image, read-only)
-		{Path: rootfsPath, Readonly: true, IOBps: ioBps, IOBurstBps: burstBps},
-		// Overlay disk (writable)
-		{Path: m.paths.InstanceOverlay(inst.Id), Readonly: false, IOBps: ioBps, IOBurstBps: burstBps},
-		// Config disk (read-only)
-		{Path: m.paths.InstanceConfigDisk(inst.Id), Readonly: true, IOBps: ioBps, IOBurstBps: burstBps},
+	// Disk configuration
+	var disks []hypervisor.DiskConfig
+	if inst.RootVolume != "" {
+		// Root volume (writable) replaces the image rootfs and overlay
+		rootPath, err := m.volumeManager.UnlockVolume(ctx, inst.RootVolume, inst.Id, false)
+		if err != nil {
+			return hypervisor.VMConfig{}, fmt.Errorf("unlock root volume %s: %w", inst.RootVolume, err)
+		}
+		disks = []hypervisor.DiskConfig{
+			{Path: rootPath, Readonly: false, IOBps: ioBps, IOBurstBps: burstBps},
+			// Config disk (read-only)
+			{Path: m.paths.InstanceConfigDisk(inst.Id), Readonly: true, IOBps: ioBps, IOBurstBps: burstBps},
+		}
+	} else if inst.Firmware == images.FirmwareUEFI {
+		// The instance's copy of the image's whole disk, with no config disk:
+		// UEFI guests don't run hypeman's init
+		disks = []hypervisor.DiskConfig{
+			{Path: m.paths.InstanceOverlay(inst.Id), Readonly: false, IOBps: ioBps, IOBurstBps: burstBps},
+		}
+		// Drivers for guests that don't ship virtio ones, on a CD-ROM they
+		// can read without them
+		if m.limits.VirtioDriversISO != "" && inst.HypervisorType == hypervisor.TypeQEMU {
+			disks = append(disks, hypervisor.DiskConfig{Path: m.limits.VirtioDriversISO, Readonly: true, CDROM: true})
+		}
+	} else {
+		// Get rootfs disk path from image manager
+		rootfsPath, err := images.GetDiskPath(m.paths, imageInfo.Name, imageInfo.Digest)
+		if err != nil {
+			return hypervisor.VMConfig{}, err
+		}
+		disks = []hypervisor.DiskConfig{
+			// Rootfs (from image, read-only)
+			{Path: rootfsPath, Readonly: true, IOBps: ioBps, IOBurstBps: burstBps},
+			// Overlay disk (writable)
+			{Path: m.paths.InstanceOverlay(inst.Id), Readonly: false, IOBps: ioBps, IOBurstBps: burstBps},
+			// Config disk (read-only)
+			{Path: m.paths.InstanceConfigDisk(inst.Id), Readonly: true, IOBps: ioBps, IOBurstBps: burstBps},
+		}
 	}
 
-	// Add attached volumes as additional disks
+	// Add attached volumes as additional disks. Encrypted volumes are
+	// unlocked here and attached as their decrypted mappings.
 	for _, volAttach := range inst.Volumes {
-		volumePath := m.volumeManager.GetVolumePath(volAttach.VolumeID)
+		volumePath, err := m.volumeManager.UnlockVolume(ctx, volAttach.VolumeID, inst.Id, volAttach.Readonly)
+		if err != nil {
+			return hypervisor.VMConfig{}, fmt.Errorf("unlock volume %s: %w", volAttach.VolumeID, err)
+		}
 		if volAttach.Overlay {
 			// Base volume is always read-only when overlay is enabled
 			disks = append(disks, hypervisor.DiskConfig{
@@ -675,11 +1218,49 @@ func (m *manager) buildHypervisorConfig(ctx context.Context, inst *Instance, ima
 		}
 	}
 
+	// Scratch disks follow the volumes
+	if inst.ScratchDisks != nil {
+		for i := range inst.ScratchDisks.Count {
+			disks = append(disks, hypervisor.DiskConfig{
+				Path:       m.paths.InstanceScratchDisk(inst.Id, i),
+				Readonly:   false,
+				IOBps:      ioBps,
+				IOBurstBps: burstBps,
+			})
+		}
+	}
+
+	// Swap disk comes last
+	if inst.SwapSize > 0 {
+		disks = append(disks, hypervisor.DiskConfig{
+			Path:       m.paths.InstanceSwapDisk(inst.Id),
+			Readonly:   false,
+			IOBps:      ioBps,
+			IOBurstBps: burstBps,
+		})
+	}
+
+	// Pass guest TRIM through on writable disks so blocks freed in the guest
+	// are punched out of the sparse files backing them
+	for i := range disks {
+		disks[i].Discard = !disks[i].Readonly
+	}
+
+	// Shared directories, served by the instance's virtiofsd processes
+	var fileSystems []hypervisor.FileSystemConfig
+	for i := range inst.SharedDirectories {
+		fileSystems = append(fileSystems, hypervisor.FileSystemConfig{
+			Tag:        virtiofsTag(i),
+			SocketPath: m.paths.InstanceVirtiofsSocket(inst.Id, i),
+		})
+	}
+
 	// Network configuration
 	var networks []hypervisor.NetworkConfig
 	if netConfig != nil {
 		networks = append(networks, hypervisor.NetworkConfig{
 			TAPDevice: netConfig.TAPDevice,
+			Macvtap:   netConfig.Macvtap,
 			IP:        netConfig.IP,
 			MAC:       netConfig.MAC,
 			Netmask:   netConfig.Netmask,
@@ -704,6 +1285,21 @@ func (m *manager) buildHypervisorConfig(ctx context.Context, inst *Instance, ima
 		pciDevices = append(pciDevices, mdevPath)
 	}
 
+	// USB device passthrough. The controller lets devices be hot-plugged later.
+	usbDevices, err := usbDeviceConfigs(inst.USBDevices)
+	if err != nil {
+		return hypervisor.VMConfig{}, fmt.Errorf("usb devices: %w", err)
+	}
+	for _, s := range inst.USBDevices {
+		warnMissingUSBDevice(ctx, inst.Id, s)
+	}
+
+	// Pin vCPUs to the CPUs of the requested NUMA node
+	hostCPUs, err := placementHostCPUs(inst)
+	if err != nil {
+		return hypervisor.VMConfig{}, fmt.Errorf("placement: %w", err)
+	}
+
 	// Build topology if available
 	var topology *hypervisor.CPUTopology
 	if hostTopo := calculateGuestTopology(inst.Vcpus, m.hostTopology); hostTopo != nil {
@@ -722,21 +1318,35 @@ func (m *manager) buildHypervisorConfig(ctx context.Context, inst *Instance, ima
 		}
 	}
 
-	return hypervisor.VMConfig{
+	cfg := hypervisor.VMConfig{
 		VCPUs:         inst.Vcpus,
 		MemoryBytes:   inst.Size,
 		HotplugBytes:  inst.HotplugSize,
 		Topology:      topology,
+		NestedVirt:    inst.NestedVirt,
+		HostCPUs:      hostCPUs,
 		Disks:         disks,
+		FileSystems:   fileSystems,
 		Networks:      networks,
 		SerialLogPath: m.paths.InstanceAppLog(inst.Id),
 		VsockCID:      inst.VsockCID,
 		VsockSocket:   inst.VsockSocket,
 		PCIDevices:    pciDevices,
+		USBController: supportsUSBPassthrough(inst.HypervisorType),
+		USBDevices:    usbDevices,
 		KernelPath:    kernelPath,
 		InitrdPath:    initrdPath,
-		KernelArgs:    "console=ttyS0",
-	}, nil
+		KernelArgs:    kernelCmdline(inst),
+	}
+	if inst.Firmware == images.FirmwareUEFI {
+		firmware := m.limits.UEFIFirmware[inst.HypervisorType]
+		if firmware == "" {
+			return hypervisor.VMConfig{}, fmt.Errorf("%w: no UEFI firmware is configured for %s", ErrInvalidFirmware, inst.HypervisorType)
+		}
+		cfg.Firmware = firmware
+		cfg.KernelPath, cfg.InitrdPath, cfg.KernelArgs = "", "", ""
+	}
+	return cfg, nil
 }
 
 func ptr[T any](v T) *T {
@@ -0,0 +1,88 @@
+package instances
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// instanceLock is a per-instance RWMutex that records its exclusive holder,
+// so operations stuck holding or waiting on a lock show up in DebugState.
+type instanceLock struct {
+	sync.RWMutex
+
+	mu      sync.Mutex // guards the fields below
+	holder  string     // operation holding the write lock, "" if none
+	since   time.Time
+	waiting int // operations blocked in acquire
+}
+
+// acquire takes the write lock on behalf of op
+func (l *instanceLock) acquire(op string) {
+	l.mu.Lock()
+	l.waiting++
+	l.mu.Unlock()
+
+	l.Lock()
+
+	l.mu.Lock()
+	l.waiting--
+	l.holder = op
+	l.since = time.Now()
+	l.mu.Unlock()
+}
+
+// release gives up the write lock taken by acquire
+func (l *instanceLock) release() {
+	l.mu.Lock()
+	l.holder = ""
+	l.since = time.Time{}
+	l.mu.Unlock()
+
+	l.Unlock()
+}
+
+// InstanceLockState describes a contended or held per-instance lock
+type InstanceLockState struct {
+	InstanceID string     `json:"instance_id"`
+	Holder     string     `json:"holder,omitempty"`
+	HeldSince  *time.Time `json:"held_since,omitempty"`
+	Waiting    int        `json:"waiting"`
+}
+
+// DebugState is a snapshot of instance manager internals for diagnostics
+type DebugState struct {
+	Locks          []InstanceLockState `json:"locks"`
+	TrackedLocks   int                 `json:"tracked_locks"`
+	ActiveSessions int                 `json:"active_sessions"` // exec, cp and similar sessions across instances
+}
+
+// DebugState returns the per-instance locks that are currently held or
+// waited on, ordered by instance ID.
+func (m *manager) DebugState() DebugState {
+	state := DebugState{Locks: []InstanceLockState{}}
+	m.instanceLocks.Range(func(key, value any) bool {
+		state.TrackedLocks++
+		l := value.(*instanceLock)
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		if l.holder == "" && l.waiting == 0 {
+			return true
+		}
+		ls := InstanceLockState{InstanceID: key.(string), Holder: l.holder, Waiting: l.waiting}
+		if l.holder != "" {
+			since := l.since
+			ls.HeldSince = &since
+		}
+		state.Locks = append(state.Locks, ls)
+		return true
+	})
+	sort.Slice(state.Locks, func(i, j int) bool { return state.Locks[i].InstanceID < state.Locks[j].InstanceID })
+
+	m.activity.mu.Lock()
+	for _, e := range m.activity.entries {
+		state.ActiveSessions += e.sessions
+	}
+	m.activity.mu.Unlock()
+	return state
+}
@@ -0,0 +1,55 @@
+package instances
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDebugState_Locks(t *testing.T) {
+	m := &manager{}
+
+	// Idle locks are tracked but not reported
+	m.getInstanceLock("idle")
+
+	held := m.getInstanceLock("busy")
+	held.acquire("standby_instance")
+
+	acquired := make(chan struct{})
+	go func() {
+		held.acquire("delete_instance")
+		close(acquired)
+	}()
+	require.Eventually(t, func() bool {
+		state := m.DebugState()
+		return len(state.Locks) == 1 && state.Locks[0].Waiting == 1
+	}, time.Second, 10*time.Millisecond)
+
+	state := m.DebugState()
+	assert.Equal(t, 2, state.TrackedLocks)
+	assert.Equal(t, "busy", state.Locks[0].InstanceID)
+	assert.Equal(t, "standby_instance", state.Locks[0].Holder)
+	require.NotNil(t, state.Locks[0].HeldSince)
+
+	// The waiter takes over once the holder releases
+	held.release()
+	<-acquired
+	state = m.DebugState()
+	require.Len(t, state.Locks, 1)
+	assert.Equal(t, "delete_instance", state.Locks[0].Holder)
+	assert.Zero(t, state.Locks[0].Waiting)
+
+	held.release()
+	assert.Empty(t, m.DebugState().Locks)
+}
+
+func TestDebugState_Sessions(t *testing.T) {
+	m := &manager{}
+	done := m.TrackActivity("a")
+	defer done()
+	m.TrackActivity("b")()
+
+	assert.Equal(t, 1, m.DebugState().ActiveSessions)
+}
@@ -8,8 +8,6 @@ import (
 	"time"
 
 	"github.com/kernel/hypeman/lib/devices"
-	"github.com/kernel/hypeman/lib/guest"
-	"github.com/kernel/hypeman/lib/hypervisor"
 	"github.com/kernel/hypeman/lib/logger"
 	"github.com/kernel/hypeman/lib/network"
 )
@@ -18,10 +16,15 @@ import (
 func (m *manager) deleteInstance(
 	ctx context.Context,
 	id string,
+	req DeleteInstanceRequest,
 ) error {
 	log := logger.FromContext(ctx)
 	log.InfoContext(ctx, "deleting instance", "instance_id", id)
 
+	if err := validateGracePeriod(req.GracePeriod); err != nil {
+		return err
+	}
+
 	// 1. Load instance
 	meta, err := m.loadMetadata(id)
 	if err != nil {
@@ -31,6 +34,9 @@ func (m *manager) deleteInstance(
 
 	inst := m.toInstance(ctx, meta)
 	log.DebugContext(ctx, "loaded instance", "instance_id", id, "state", inst.State)
+	if err := m.runHooks(ctx, HookPreDelete, newHookInstance(&inst.StoredMetadata, inst.State)); err != nil {
+		return err
+	}
 
 	// 2. Get network allocation BEFORE killing VMM (while we can still query it)
 	var networkAlloc *network.Allocation
@@ -42,14 +48,19 @@ func (m *manager) deleteInstance(
 		}
 	}
 
-	// 3. Close exec gRPC connection before killing hypervisor to prevent panic
-	if dialer, err := hypervisor.NewVsockDialer(inst.HypervisorType, inst.VsockSocket, inst.VsockCID); err == nil {
-		guest.CloseConn(dialer.Key())
+	// 3. A running instance's application gets its grace period to exit
+	// before the VM is powered off
+	if inst.State == StateRunning {
+		grace := m.gracePeriod(&meta.StoredMetadata, req.GracePeriod)
+		log.DebugContext(ctx, "shutting down VM", "instance_id", id, "grace_period", grace)
+		m.shutdownVM(ctx, &inst, grace)
 	}
 
-	// 4. If hypervisor might be running, force kill it
+	// 4. Otherwise, if hypervisor might be running, force kill it
 	// Also attempt kill for StateUnknown since we can't be sure if hypervisor is running
-	if inst.State.RequiresVMM() || inst.State == StateUnknown {
+	if inst.State != StateRunning && (inst.State.RequiresVMM() || inst.State == StateUnknown) {
+		// Close exec gRPC connection before killing hypervisor to prevent panic
+		closeGuestConn(&inst.StoredMetadata)
 		log.DebugContext(ctx, "stopping hypervisor", "instance_id", id, "state", inst.State)
 		if err := m.killHypervisor(ctx, &inst); err != nil {
 			// Log error but continue with cleanup
@@ -57,6 +68,11 @@ func (m *manager) deleteInstance(
 			log.WarnContext(ctx, "failed to kill hypervisor, continuing with cleanup", "instance_id", id, "error", err)
 		}
 	}
+	m.stopVirtiofsd(ctx, &meta.StoredMetadata)
+	m.lockVolumes(ctx, &meta.StoredMetadata)
+	if err := m.removeHypervisorCgroup(id); err != nil {
+		log.WarnContext(ctx, "failed to remove hypervisor cgroup, continuing with cleanup", "instance_id", id, "error", err)
+	}
 
 	// 5. Release network allocation
 	if inst.NetworkEnabled {
@@ -69,15 +85,19 @@ func (m *manager) deleteInstance(
 
 	// 6. Detach and auto-unbind devices from VFIO
 	if len(inst.Devices) > 0 && m.deviceManager != nil {
+		// Detach all devices first: unbinding checks that no other device in
+		// the IOMMU group is still attached, which includes this instance's own
 		for _, deviceID := range inst.Devices {
 			log.DebugContext(ctx, "detaching device", "id", id, "device", deviceID)
 			// Mark device as detached
 			if err := m.deviceManager.MarkDetached(ctx, deviceID); err != nil {
 				log.WarnContext(ctx, "failed to mark device as detached", "id", id, "device", deviceID, "error", err)
 			}
+		}
+		for _, deviceID := range inst.Devices {
 			// Auto-unbind from VFIO so native driver can reclaim it
 			log.InfoContext(ctx, "auto-unbinding device from VFIO", "id", id, "device", deviceID)
-			if err := m.deviceManager.UnbindFromVFIO(ctx, deviceID); err != nil {
+			if err := m.deviceManager.UnbindFromVFIO(ctx, deviceID, false); err != nil {
 				// Log but continue - device might already be unbound or in use by another instance
 				log.WarnContext(ctx, "failed to unbind device from VFIO", "id", id, "device", deviceID, "error", err)
 			}
@@ -85,6 +105,12 @@ func (m *manager) deleteInstance(
 	}
 
 	// 6b. Detach volumes
+	if inst.RootVolume != "" {
+		if err := m.volumeManager.DetachVolume(ctx, inst.RootVolume, id); err != nil {
+			// Log error but continue with cleanup
+			log.WarnContext(ctx, "failed to detach root volume, continuing with cleanup", "instance_id", id, "volume_id", inst.RootVolume, "error", err)
+		}
+	}
 	if len(inst.Volumes) > 0 {
 		log.DebugContext(ctx, "detaching volumes", "instance_id", id, "count", len(inst.Volumes))
 		for _, volAttach := range inst.Volumes {
@@ -95,7 +121,7 @@ func (m *manager) deleteInstance(
 		}
 	}
 
-	// 6c. Destroy vGPU mdev device if present
+	// 6c. Destroy vGPU mdev device (and its MIG GPU instance) if present
 	if inst.GPUMdevUUID != "" {
 		log.InfoContext(ctx, "destroying vGPU mdev", "instance_id", id, "uuid", inst.GPUMdevUUID)
 		if err := devices.DestroyMdev(ctx, inst.GPUMdevUUID); err != nil {
@@ -103,6 +129,13 @@ func (m *manager) deleteInstance(
 			log.WarnContext(ctx, "failed to destroy mdev, continuing with cleanup", "instance_id", id, "uuid", inst.GPUMdevUUID, "error", err)
 		}
 	}
+	if inst.GPUMIG != nil {
+		log.InfoContext(ctx, "destroying MIG GPU instance", "instance_id", id, "gpu", inst.GPUMIG.GPUIndex, "gi", inst.GPUMIG.InstanceID)
+		if err := devices.DestroyMIGInstance(ctx, *inst.GPUMIG); err != nil {
+			// Log error but continue with cleanup
+			log.WarnContext(ctx, "failed to destroy MIG GPU instance, continuing with cleanup", "instance_id", id, "gpu", inst.GPUMIG.GPUIndex, "gi", inst.GPUMIG.InstanceID, "error", err)
+		}
+	}
 
 	// 7. Delete all instance data
 	log.DebugContext(ctx, "deleting instance data", "instance_id", id)
@@ -111,12 +144,22 @@ func (m *manager) deleteInstance(
 		return fmt.Errorf("delete instance data: %w", err)
 	}
 
+	// 8. Free the instance's reserved address and drop its DNS record now that its metadata is gone
+	if inst.NetworkEnabled {
+		if err := m.networkManager.DeleteAllocation(ctx, id); err != nil {
+			log.WarnContext(ctx, "failed to free network reservation", "instance_id", id, "error", err)
+		}
+		if err := m.networkManager.RefreshInstanceDNS(ctx); err != nil {
+			log.WarnContext(ctx, "failed to remove instance DNS record", "instance_id", id, "error", err)
+		}
+	}
+
 	log.InfoContext(ctx, "instance deleted successfully", "instance_id", id)
 	return nil
 }
 
 // killHypervisor force kills the hypervisor process without graceful shutdown
-// Used only for delete operations where we're removing all data anyway.
+// Used for deleting instances that aren't running, and as the last resort of shutdownVM.
 // For operations that need graceful shutdown (like standby), use the hypervisor API directly.
 func (m *manager) killHypervisor(ctx context.Context, inst *Instance) error {
 	log := logger.FromContext(ctx)
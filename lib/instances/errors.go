@@ -12,9 +12,78 @@ var (
 	// ErrAlreadyExists is returned when creating an instance that already exists
 	ErrAlreadyExists = errors.New("instance already exists")
 
+	// ErrNameInUse is returned when renaming an instance to another instance's name
+	ErrNameInUse = errors.New("instance name already in use")
+
+	// ErrInvalidName is returned when an instance name fails validation
+	ErrInvalidName = errors.New("invalid instance name")
+
 	// ErrImageNotReady is returned when the image is not ready for use
 	ErrImageNotReady = errors.New("image not ready")
 
 	// ErrAmbiguousName is returned when multiple instances have the same name
 	ErrAmbiguousName = errors.New("multiple instances with the same name")
+
+	// ErrNetworkDisabled is returned for network operations on an instance without networking
+	ErrNetworkDisabled = errors.New("instance networking is disabled")
+
+	// ErrInvalidUserData is returned when user data fails validation
+	ErrInvalidUserData = errors.New("invalid user data")
+
+	// ErrInvalidResolverConfig is returned when resolv.conf/hosts options fail validation
+	ErrInvalidResolverConfig = errors.New("invalid resolver config")
+
+	// ErrInvalidLinks is returned when an instance's links fail validation
+	ErrInvalidLinks = errors.New("invalid links")
+
+	// ErrInvalidIdlePolicy is returned when an idle policy fails validation
+	ErrInvalidIdlePolicy = errors.New("invalid idle policy")
+
+	// ErrInvalidSchedule is returned when an instance schedule fails validation
+	ErrInvalidSchedule = errors.New("invalid schedule")
+
+	// ErrInvalidSharedDirectory is returned when a shared directory fails validation
+	ErrInvalidSharedDirectory = errors.New("invalid shared directory")
+
+	// ErrInvalidSecret is returned when a secret attachment fails validation
+	ErrInvalidSecret = errors.New("invalid secret attachment")
+
+	// ErrInvalidKernelArgs is returned when extra kernel parameters fail validation
+	ErrInvalidKernelArgs = errors.New("invalid kernel args")
+
+	// ErrInvalidTuning is returned when sysctls or ulimits fail validation
+	ErrInvalidTuning = errors.New("invalid sysctls or ulimits")
+
+	// ErrInvalidLabels is returned when instance labels fail validation
+	ErrInvalidLabels = errors.New("invalid labels")
+
+	// ErrNestedVirtUnsupported is returned when nested virtualization is requested on a host without it
+	ErrNestedVirtUnsupported = errors.New("nested virtualization not supported")
+
+	// ErrInvalidLogSearch is returned when a log search query or cursor is invalid
+	ErrInvalidLogSearch = errors.New("invalid log search")
+
+	// ErrInvalidGracePeriod is returned when a shutdown grace period is negative
+	ErrInvalidGracePeriod = errors.New("invalid shutdown grace period")
+
+	// ErrUSBNotSupported is returned when USB passthrough is requested on a hypervisor without it
+	ErrUSBNotSupported = errors.New("USB passthrough not supported")
+
+	// ErrUSBDeviceAttached is returned when attaching a USB device an instance already has
+	ErrUSBDeviceAttached = errors.New("USB device already attached")
+
+	// ErrUSBDeviceNotAttached is returned when detaching a USB device an instance doesn't have
+	ErrUSBDeviceNotAttached = errors.New("USB device not attached")
+
+	// ErrInvalidPlacement is returned when placement hints fail validation
+	ErrInvalidPlacement = errors.New("invalid placement hints")
+
+	// ErrInvalidFirmware is returned when an instance's firmware doesn't suit its image, hypervisor or options
+	ErrInvalidFirmware = errors.New("invalid firmware")
+
+	// ErrHookFailed is returned when a lifecycle hook that aborts on failure fails
+	ErrHookFailed = errors.New("lifecycle hook failed")
+
+	// ErrResourceLimit is returned when an instance would exceed a configured resource limit
+	ErrResourceLimit = errors.New("resource limit exceeded")
 )
@@ -48,7 +48,7 @@ func TestExecConcurrent(t *testing.T) {
 	p := paths.New(tmpDir)
 
 	// Setup image
-	imageManager, err := images.NewManager(p, 1, nil)
+	imageManager, err := images.NewManager(p, 1, nil, nil)
 	require.NoError(t, err)
 
 	t.Log("Pulling nginx:alpine image...")
@@ -87,7 +87,7 @@ func TestExecConcurrent(t *testing.T) {
 
 	t.Cleanup(func() {
 		t.Log("Cleaning up...")
-		manager.DeleteInstance(ctx, inst.Id)
+		manager.DeleteInstance(ctx, inst.Id, DeleteInstanceRequest{})
 	})
 
 	// Wait for exec-agent to be ready (retry here is OK - we're just waiting for startup)
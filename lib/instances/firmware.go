@@ -0,0 +1,106 @@
+package instances
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+
+	"github.com/kernel/hypeman/lib/hypervisor"
+	"github.com/kernel/hypeman/lib/images"
+)
+
+// defaultUEFIMemory is the base memory of UEFI instances that don't set
+// one; Windows needs more than hypeman's 1GB default to boot
+const defaultUEFIMemory = 4 * 1024 * 1024 * 1024
+
+// DefaultMemory returns the base and hotplug memory of an instance booting
+// firmware that doesn't set them. UEFI guests get no memory hotplug (Windows
+// has no virtio-mem driver by default).
+func DefaultMemory(firmware string) (size, hotplugSize int64) {
+	if firmware == images.FirmwareUEFI {
+		return defaultUEFIMemory, 0
+	}
+	return 1 * 1024 * 1024 * 1024, 3 * 1024 * 1024 * 1024 // 1GB, 3GB
+}
+
+// resolveFirmware returns how an instance boots: the requested firmware, or
+// its image's. UEFI instances boot a copy of a whole-disk image through the
+// hypervisor's firmware without hypeman's init, so nothing that init sets
+// up in the guest can be asked for.
+func (m *manager) resolveFirmware(req CreateInstanceRequest, imageInfo *images.Image, hvType hypervisor.Type) (string, error) {
+	imageFirmware := images.FirmwareDirect
+	if imageInfo != nil && imageInfo.Firmware != "" {
+		imageFirmware = imageInfo.Firmware
+	}
+	firmware := req.Firmware
+	if firmware == "" {
+		firmware = imageFirmware
+	}
+
+	switch firmware {
+	case images.FirmwareDirect:
+		if imageFirmware == images.FirmwareUEFI {
+			return "", fmt.Errorf("%w: image %s is a whole disk and can only boot with uefi firmware", ErrInvalidFirmware, imageInfo.Name)
+		}
+		return firmware, nil
+	case images.FirmwareUEFI:
+	default:
+		return "", fmt.Errorf("%w: unknown firmware %q", ErrInvalidFirmware, firmware)
+	}
+
+	if imageFirmware != images.FirmwareUEFI {
+		return "", fmt.Errorf("%w: uefi firmware boots whole-disk images (imported with firmware=uefi)", ErrInvalidFirmware)
+	}
+	if m.limits.UEFIFirmware[hvType] == "" {
+		return "", fmt.Errorf("%w: no UEFI firmware is configured for %s", ErrInvalidFirmware, hvType)
+	}
+	var unsupported string
+	switch {
+	case len(req.Volumes) > 0:
+		unsupported = "volumes"
+	case req.ScratchDisks != nil:
+		unsupported = "scratch_disks"
+	case req.SwapSize > 0:
+		unsupported = "swap_size"
+	case len(req.SharedDirectories) > 0:
+		unsupported = "shared_directories"
+	case len(req.Secrets) > 0:
+		unsupported = "secrets"
+	case len(req.KernelArgs) > 0:
+		unsupported = "kernel_args"
+	case len(req.Sysctls) > 0 || len(req.Ulimits) > 0:
+		unsupported = "sysctls and ulimits"
+	case req.UserData != nil:
+		unsupported = "user_data"
+	case req.Resolver != nil:
+		unsupported = "resolver"
+	case len(req.Links) > 0:
+		unsupported = "links"
+	case req.Entrypoint != nil || req.Cmd != nil || req.Workdir != "" || len(req.Env) > 0:
+		unsupported = "entrypoint, cmd, workdir and env"
+	}
+	if unsupported != "" {
+		return "", fmt.Errorf("%w: %s can't be used with uefi firmware", ErrInvalidFirmware, unsupported)
+	}
+	return firmware, nil
+}
+
+// createUEFIDisk copies a whole-disk image to the instance's overlay path,
+// where it's booted writable, and grows it to sizeBytes. The copy is sparse
+// and shares blocks with the image where the filesystem supports it.
+func (m *manager) createUEFIDisk(id, imagePath string, sizeBytes int64) error {
+	diskPath := m.paths.InstanceOverlay(id)
+	if out, err := exec.Command("cp", "--sparse=always", "--reflink=auto", imagePath, diskPath).CombinedOutput(); err != nil {
+		return fmt.Errorf("copy image disk: %w: %s", err, out)
+	}
+	info, err := os.Stat(diskPath)
+	if err != nil {
+		return fmt.Errorf("stat disk: %w", err)
+	}
+	if sizeBytes > info.Size() {
+		if err := os.Truncate(diskPath, sizeBytes); err != nil {
+			return fmt.Errorf("grow disk: %w", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,69 @@
+package instances
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/kernel/hypeman/lib/hypervisor"
+	"github.com/kernel/hypeman/lib/images"
+	"github.com/kernel/hypeman/lib/paths"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolveFirmware(t *testing.T) {
+	m := &manager{limits: ResourceLimits{UEFIFirmware: map[hypervisor.Type]string{
+		hypervisor.TypeQEMU: "/usr/share/ovmf/OVMF.fd",
+	}}}
+	linux := &images.Image{Name: "docker.io/library/alpine:latest", Firmware: images.FirmwareDirect}
+	windows := &images.Image{Name: "disks.hypeman.local/windows:2022", Firmware: images.FirmwareUEFI}
+
+	tests := []struct {
+		name    string
+		req     CreateInstanceRequest
+		image   *images.Image
+		hv      hypervisor.Type
+		want    string
+		wantErr string
+	}{
+		{name: "defaults to the image's", image: windows, hv: hypervisor.TypeQEMU, want: images.FirmwareUEFI},
+		{name: "direct image", image: linux, hv: hypervisor.TypeQEMU, want: images.FirmwareDirect},
+		{name: "root volume", hv: hypervisor.TypeQEMU, want: images.FirmwareDirect},
+		{name: "unknown", req: CreateInstanceRequest{Firmware: "bios"}, image: linux, hv: hypervisor.TypeQEMU, wantErr: "unknown firmware"},
+		{name: "uefi for a filesystem image", req: CreateInstanceRequest{Firmware: images.FirmwareUEFI}, image: linux, hv: hypervisor.TypeQEMU, wantErr: "whole-disk images"},
+		{name: "direct for a whole disk", req: CreateInstanceRequest{Firmware: images.FirmwareDirect}, image: windows, hv: hypervisor.TypeQEMU, wantErr: "only boot with uefi"},
+		{name: "no firmware configured", image: windows, hv: hypervisor.TypeCloudHypervisor, wantErr: "no UEFI firmware"},
+		{name: "kernel args", req: CreateInstanceRequest{KernelArgs: []string{"quiet"}}, image: windows, hv: hypervisor.TypeQEMU, wantErr: "kernel_args"},
+		{name: "env", req: CreateInstanceRequest{Env: map[string]string{"A": "b"}}, image: windows, hv: hypervisor.TypeQEMU, wantErr: "env"},
+		{name: "volumes", req: CreateInstanceRequest{Volumes: []VolumeAttachment{{VolumeID: "vol-1", MountPath: "/data"}}}, image: windows, hv: hypervisor.TypeQEMU, wantErr: "volumes"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := m.resolveFirmware(tt.req, tt.image, tt.hv)
+			if tt.wantErr != "" {
+				assert.ErrorIs(t, err, ErrInvalidFirmware)
+				assert.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestCreateUEFIDisk(t *testing.T) {
+	p := paths.New(t.TempDir())
+	m := &manager{paths: p}
+	require.NoError(t, m.ensureDirectories("inst-1"))
+
+	imagePath := filepath.Join(t.TempDir(), "disk.raw")
+	image := append([]byte("bootloader"), make([]byte, 4096)...)
+	require.NoError(t, os.WriteFile(imagePath, image, 0644))
+
+	require.NoError(t, m.createUEFIDisk("inst-1", imagePath, 1<<20))
+	disk, err := os.ReadFile(p.InstanceOverlay("inst-1"))
+	require.NoError(t, err)
+	assert.Len(t, disk, 1<<20)
+	assert.Equal(t, image, disk[:len(image)])
+}
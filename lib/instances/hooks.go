@@ -0,0 +1,221 @@
+package instances
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/kernel/hypeman/lib/logger"
+)
+
+// HookEvent is a point in an instance's lifecycle where hooks run
+type HookEvent string
+
+const (
+	HookPreCreate   HookEvent = "pre-create"   // before resources are allocated
+	HookPostBoot    HookEvent = "post-boot"    // once the guest agent answers after a boot
+	HookPreStandby  HookEvent = "pre-standby"  // before the VM is paused for standby
+	HookPostRestore HookEvent = "post-restore" // once the VM resumes from standby
+	HookPreDelete   HookEvent = "pre-delete"   // before the VM is stopped for deletion
+)
+
+// IsPre reports whether the event's hooks run before its operation, so that
+// their failure can abort it. Hooks of other events run in the background.
+func (e HookEvent) IsPre() bool {
+	return strings.HasPrefix(string(e), "pre-")
+}
+
+// Hook is a host-side executable or a webhook run at a lifecycle event
+type Hook struct {
+	Command string // Absolute path of an executable, given the payload on stdin
+	URL     string // http(s) URL the payload is POSTed to
+}
+
+func (h Hook) String() string {
+	if h.URL != "" {
+		return h.URL
+	}
+	return h.Command
+}
+
+// ParseHook parses a hook, given as an http(s) URL or the absolute path of
+// an executable
+func ParseHook(s string) (Hook, error) {
+	if strings.HasPrefix(s, "http://") || strings.HasPrefix(s, "https://") {
+		u, err := url.Parse(s)
+		if err != nil || u.Host == "" {
+			return Hook{}, fmt.Errorf("invalid webhook URL %q", s)
+		}
+		return Hook{URL: s}, nil
+	}
+	if !filepath.IsAbs(s) {
+		return Hook{}, fmt.Errorf("hook %q must be an absolute path or an http(s) URL", s)
+	}
+	return Hook{Command: filepath.Clean(s)}, nil
+}
+
+// HookConfig configures lifecycle hooks
+type HookConfig struct {
+	Hooks   map[HookEvent][]Hook // Hooks run at each event, in order
+	Abort   map[HookEvent]bool   // Pre-events whose hook failures abort the operation
+	Timeout time.Duration        // Time each hook may take (0 = no limit)
+}
+
+// hookPayload is what a hook receives as JSON, on stdin or as the webhook body
+type hookPayload struct {
+	Event    HookEvent    `json:"event"`
+	Time     time.Time    `json:"time"`
+	Instance hookInstance `json:"instance"`
+}
+
+// hookInstance is the instance metadata passed to hooks. Env and secrets
+// are left out.
+type hookInstance struct {
+	ID          string    `json:"id"`
+	Name        string    `json:"name"`
+	Image       string    `json:"image"`
+	Tenant      string    `json:"tenant,omitempty"`
+	State       State     `json:"state,omitempty"`
+	Hypervisor  string    `json:"hypervisor"`
+	Vcpus       int       `json:"vcpus"`
+	Size        int64     `json:"size"`
+	HotplugSize int64     `json:"hotplug_size"`
+	IP          string    `json:"ip,omitempty"`
+	GPUProfile  string    `json:"gpu_profile,omitempty"`
+	Devices     []string  `json:"devices,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// newHookInstance describes an instance to hooks. state is empty before the
+// instance exists.
+func newHookInstance(stored *StoredMetadata, state State) hookInstance {
+	return hookInstance{
+		ID:          stored.Id,
+		Name:        stored.Name,
+		Image:       stored.Image,
+		Tenant:      stored.Tenant,
+		State:       state,
+		Hypervisor:  string(stored.HypervisorType),
+		Vcpus:       stored.Vcpus,
+		Size:        stored.Size,
+		HotplugSize: stored.HotplugSize,
+		IP:          stored.IP,
+		GPUProfile:  stored.GPUProfile,
+		Devices:     stored.Devices,
+		CreatedAt:   stored.CreatedAt,
+	}
+}
+
+// runHooks runs the hooks of a pre-event in order. If the event aborts on
+// failure, the first failure stops the remaining hooks and is returned
+// wrapping ErrHookFailed; otherwise failures are only logged.
+func (m *manager) runHooks(ctx context.Context, event HookEvent, inst hookInstance) error {
+	hooks := m.limits.Hooks.Hooks[event]
+	if len(hooks) == 0 {
+		return nil
+	}
+	log := logger.FromContext(ctx)
+
+	payload, err := json.Marshal(hookPayload{Event: event, Time: time.Now(), Instance: inst})
+	if err != nil {
+		return fmt.Errorf("marshal hook payload: %w", err)
+	}
+	for _, hook := range hooks {
+		start := time.Now()
+		err := m.runHook(ctx, event, hook, inst.ID, payload)
+		if err == nil {
+			log.DebugContext(ctx, "lifecycle hook succeeded", "event", event, "hook", hook.String(), "instance_id", inst.ID, "duration_ms", time.Since(start).Milliseconds())
+			continue
+		}
+		if event.IsPre() && m.limits.Hooks.Abort[event] {
+			log.ErrorContext(ctx, "lifecycle hook failed, aborting", "event", event, "hook", hook.String(), "instance_id", inst.ID, "error", err)
+			return fmt.Errorf("%w: %s hook %s: %v", ErrHookFailed, event, hook, err)
+		}
+		log.WarnContext(ctx, "lifecycle hook failed", "event", event, "hook", hook.String(), "instance_id", inst.ID, "error", err)
+	}
+	return nil
+}
+
+// runPostHooks runs the hooks of a post-event in the background, with the
+// instance as it is stored then
+func (m *manager) runPostHooks(ctx context.Context, event HookEvent, id string) {
+	if len(m.limits.Hooks.Hooks[event]) == 0 {
+		return
+	}
+	ctx = context.WithoutCancel(ctx)
+	go func() {
+		meta, err := m.loadMetadata(id)
+		if err != nil {
+			logger.FromContext(ctx).WarnContext(ctx, "lifecycle hooks skipped: failed to load instance", "event", event, "instance_id", id, "error", err)
+			return
+		}
+		inst := m.toInstance(ctx, meta)
+		m.runHooks(ctx, event, newHookInstance(&inst.StoredMetadata, inst.State))
+	}()
+}
+
+// runHook runs one hook with the payload, within the hook timeout
+func (m *manager) runHook(ctx context.Context, event HookEvent, hook Hook, id string, payload []byte) error {
+	if m.limits.Hooks.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, m.limits.Hooks.Timeout)
+		defer cancel()
+	}
+	if hook.URL != "" {
+		return postHookWebhook(ctx, hook.URL, payload)
+	}
+	return execHook(ctx, event, hook.Command, id, payload)
+}
+
+// hookOutputBytes caps the hook output kept in an error
+const hookOutputBytes = 1024
+
+// execHook runs an executable hook with the payload on stdin. The hook
+// fails if it exits non-zero; the end of its output is kept in the error.
+func execHook(ctx context.Context, event HookEvent, command, id string, payload []byte) error {
+	cmd := exec.CommandContext(ctx, command)
+	cmd.Stdin = bytes.NewReader(payload)
+	cmd.Env = append(os.Environ(), "HYPEMAN_HOOK_EVENT="+string(event), "HYPEMAN_INSTANCE_ID="+id)
+	output, err := cmd.CombinedOutput()
+	if err == nil {
+		return nil
+	}
+	if ctx.Err() != nil {
+		return fmt.Errorf("timed out: %w", ctx.Err())
+	}
+	out := strings.TrimSpace(string(output))
+	if len(out) > hookOutputBytes {
+		out = out[len(out)-hookOutputBytes:]
+	}
+	if out == "" {
+		return err
+	}
+	return fmt.Errorf("%w: %s", err, out)
+}
+
+// postHookWebhook POSTs the payload to a webhook hook. The hook fails unless
+// it answers 2xx.
+func postHookWebhook(ctx context.Context, url string, payload []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,122 @@
+package instances
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeHookScript writes an executable hook script to a temporary directory
+func writeHookScript(t *testing.T, body string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "hook.sh")
+	require.NoError(t, os.WriteFile(path, []byte("#!/bin/sh\n"+body+"\n"), 0755))
+	return path
+}
+
+func TestParseHook(t *testing.T) {
+	hook, err := ParseHook("https://hooks.example.com/hypeman")
+	require.NoError(t, err)
+	assert.Equal(t, Hook{URL: "https://hooks.example.com/hypeman"}, hook)
+
+	hook, err = ParseHook("/usr/local/bin/../bin/check-quota")
+	require.NoError(t, err)
+	assert.Equal(t, Hook{Command: "/usr/local/bin/check-quota"}, hook)
+
+	_, err = ParseHook("check-quota")
+	assert.Error(t, err)
+	_, err = ParseHook("https://")
+	assert.Error(t, err)
+}
+
+func TestRunHooksExecutable(t *testing.T) {
+	ctx := context.Background()
+	out := filepath.Join(t.TempDir(), "payload.json")
+	record := writeHookScript(t, `cat > `+out+`; echo "$HYPEMAN_HOOK_EVENT $HYPEMAN_INSTANCE_ID" >> `+out)
+	fail := writeHookScript(t, `echo "quota exceeded" >&2; exit 3`)
+	stored := &StoredMetadata{Id: "inst-1", Name: "web", Image: "docker.io/library/nginx:latest", Vcpus: 2}
+
+	m := &manager{limits: ResourceLimits{Hooks: HookConfig{
+		Hooks: map[HookEvent][]Hook{HookPreCreate: {{Command: record}}},
+	}}}
+	require.NoError(t, m.runHooks(ctx, HookPreCreate, newHookInstance(stored, "")))
+	data, err := os.ReadFile(out)
+	require.NoError(t, err)
+	lines := string(data)
+	var payload hookPayload
+	require.NoError(t, json.NewDecoder(strings.NewReader(lines)).Decode(&payload))
+	assert.Equal(t, HookPreCreate, payload.Event)
+	assert.Equal(t, "inst-1", payload.Instance.ID)
+	assert.Equal(t, "web", payload.Instance.Name)
+	assert.Equal(t, 2, payload.Instance.Vcpus)
+	assert.Contains(t, lines, "pre-create inst-1")
+
+	t.Run("failure is logged unless the event aborts", func(t *testing.T) {
+		m := &manager{limits: ResourceLimits{Hooks: HookConfig{
+			Hooks: map[HookEvent][]Hook{HookPreDelete: {{Command: fail}}},
+		}}}
+		assert.NoError(t, m.runHooks(ctx, HookPreDelete, newHookInstance(stored, StateRunning)))
+
+		m.limits.Hooks.Abort = map[HookEvent]bool{HookPreDelete: true}
+		err := m.runHooks(ctx, HookPreDelete, newHookInstance(stored, StateRunning))
+		assert.ErrorIs(t, err, ErrHookFailed)
+		assert.Contains(t, err.Error(), "quota exceeded")
+	})
+
+	t.Run("timeout", func(t *testing.T) {
+		slow := writeHookScript(t, "exec sleep 5")
+		m := &manager{limits: ResourceLimits{Hooks: HookConfig{
+			Hooks:   map[HookEvent][]Hook{HookPreStandby: {{Command: slow}}},
+			Abort:   map[HookEvent]bool{HookPreStandby: true},
+			Timeout: 100 * time.Millisecond,
+		}}}
+		err := m.runHooks(ctx, HookPreStandby, newHookInstance(stored, StateRunning))
+		assert.ErrorIs(t, err, ErrHookFailed)
+		assert.Contains(t, err.Error(), "timed out")
+	})
+}
+
+func TestRunHooksWebhook(t *testing.T) {
+	var got hookPayload
+	status := http.StatusOK
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		assert.NoError(t, json.Unmarshal(body, &got))
+		w.WriteHeader(status)
+	}))
+	defer srv.Close()
+
+	m := &manager{limits: ResourceLimits{Hooks: HookConfig{
+		Hooks: map[HookEvent][]Hook{HookPreStandby: {{URL: srv.URL}}},
+		Abort: map[HookEvent]bool{HookPreStandby: true},
+	}}}
+	stored := &StoredMetadata{Id: "inst-2", Name: "api", IP: "10.100.0.5"}
+	require.NoError(t, m.runHooks(context.Background(), HookPreStandby, newHookInstance(stored, StateRunning)))
+	assert.Equal(t, HookPreStandby, got.Event)
+	assert.Equal(t, StateRunning, got.Instance.State)
+	assert.Equal(t, "10.100.0.5", got.Instance.IP)
+
+	status = http.StatusForbidden
+	err := m.runHooks(context.Background(), HookPreStandby, newHookInstance(stored, StateRunning))
+	assert.ErrorIs(t, err, ErrHookFailed)
+}
+
+func TestRunHooksPostEventsDontAbort(t *testing.T) {
+	fail := writeHookScript(t, "exit 1")
+	m := &manager{limits: ResourceLimits{Hooks: HookConfig{
+		Hooks: map[HookEvent][]Hook{HookPostBoot: {{Command: fail}}},
+		Abort: map[HookEvent]bool{HookPostBoot: true},
+	}}}
+	assert.NoError(t, m.runHooks(context.Background(), HookPostBoot, newHookInstance(&StoredMetadata{Id: "inst-3"}, StateRunning)))
+}
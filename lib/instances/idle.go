@@ -0,0 +1,206 @@
+package instances
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kernel/hypeman/lib/logger"
+)
+
+// IdlePolicy configures automatic standby of an idle instance.
+// Nil fields fall back to the server's IdleDefaults.
+type IdlePolicy struct {
+	StandbyAfter  *time.Duration // Standby after this long without activity (0 = never)
+	WakeOnIngress *bool          // Restore from standby when an ingress connection arrives
+}
+
+// IdleDefaults are the server-wide idle policy settings
+type IdleDefaults struct {
+	StandbyAfter  time.Duration // 0 = instances are never put into standby for idleness
+	WakeOnIngress bool
+}
+
+// Resolve applies an instance's idle policy over the defaults
+func (d IdleDefaults) Resolve(p *IdlePolicy) IdleDefaults {
+	if p == nil {
+		return d
+	}
+	if p.StandbyAfter != nil {
+		d.StandbyAfter = *p.StandbyAfter
+	}
+	if p.WakeOnIngress != nil {
+		d.WakeOnIngress = *p.WakeOnIngress
+	}
+	return d
+}
+
+// validateIdlePolicy checks the per-instance idle policy
+func validateIdlePolicy(p *IdlePolicy) error {
+	if p != nil && p.StandbyAfter != nil && *p.StandbyAfter < 0 {
+		return fmt.Errorf("%w: standby_after cannot be negative", ErrInvalidIdlePolicy)
+	}
+	return nil
+}
+
+// activityTracker records when each instance was last active. Activity is
+// either an open session (exec, cp, ...) or a change in the TAP device's
+// traffic counters between two observations. The zero value is ready to use.
+type activityTracker struct {
+	mu      sync.Mutex
+	entries map[string]*activityEntry
+}
+
+type activityEntry struct {
+	lastActive time.Time
+	sessions   int
+	traffic    uint64 // rx+tx bytes at the last observation
+	seen       bool   // traffic holds a previous observation
+}
+
+func (t *activityTracker) entry(id string, now time.Time) *activityEntry {
+	if t.entries == nil {
+		t.entries = make(map[string]*activityEntry)
+	}
+	e, ok := t.entries[id]
+	if !ok {
+		e = &activityEntry{lastActive: now}
+		t.entries[id] = e
+	}
+	return e
+}
+
+// begin opens a session, which keeps the instance active until it is ended
+func (t *activityTracker) begin(id string, now time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	e := t.entry(id, now)
+	e.sessions++
+	e.lastActive = now
+}
+
+// end closes a session opened by begin
+func (t *activityTracker) end(id string, now time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	e := t.entry(id, now)
+	if e.sessions > 0 {
+		e.sessions--
+	}
+	e.lastActive = now
+}
+
+// observe records the instance's current traffic counter and returns how long
+// it has been idle. An instance first observed now is treated as just active.
+func (t *activityTracker) observe(id string, traffic uint64, now time.Time) time.Duration {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	e := t.entry(id, now)
+	if e.seen && traffic != e.traffic {
+		e.lastActive = now
+	}
+	e.traffic = traffic
+	e.seen = true
+	if e.sessions > 0 {
+		e.lastActive = now
+		return 0
+	}
+	return now.Sub(e.lastActive)
+}
+
+// forget drops an instance's history so its idle time restarts when it runs again
+func (t *activityTracker) forget(id string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if e, ok := t.entries[id]; ok && e.sessions == 0 {
+		delete(t.entries, id)
+	}
+}
+
+// TrackActivity marks an instance active and keeps it from going idle until
+// the returned function is called.
+func (m *manager) TrackActivity(id string) func() {
+	m.activity.begin(id, time.Now())
+	var once sync.Once
+	return func() {
+		once.Do(func() { m.activity.end(id, time.Now()) })
+	}
+}
+
+// StandbyIdleInstances puts running instances into standby once they have
+// been idle for longer than their idle policy allows. It returns the IDs of
+// the instances put into standby.
+func (m *manager) StandbyIdleInstances(ctx context.Context, defaults IdleDefaults) ([]string, error) {
+	log := logger.FromContext(ctx)
+
+	all, err := m.listInstances(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("list instances: %w", err)
+	}
+
+	// TAP devices by instance; allocations only exist for running instances
+	taps := make(map[string]string)
+	if allocs, err := m.networkManager.ListAllocations(ctx); err == nil {
+		for _, a := range allocs {
+			taps[a.InstanceID] = a.TAPDevice
+		}
+	} else {
+		log.WarnContext(ctx, "idle check: failed to list network allocations", "error", err)
+	}
+
+	now := time.Now()
+	var standby []string
+	for _, inst := range all {
+		if inst.State != StateRunning {
+			m.activity.forget(inst.Id)
+			continue
+		}
+		policy := defaults.Resolve(inst.IdlePolicy)
+		if policy.StandbyAfter <= 0 || len(inst.SharedDirectories) > 0 || nestedBlocksStandby(&inst.StoredMetadata) {
+			continue
+		}
+
+		var traffic uint64
+		if tap := taps[inst.Id]; tap != "" {
+			traffic, err = readTAPTraffic(tap)
+			if err != nil {
+				log.DebugContext(ctx, "idle check: failed to read TAP counters", "instance_id", inst.Id, "tap", tap, "error", err)
+			}
+		}
+		idle := m.activity.observe(inst.Id, traffic, now)
+		if idle < policy.StandbyAfter {
+			continue
+		}
+
+		log.InfoContext(ctx, "putting idle instance in standby", "instance_id", inst.Id, "idle_for", idle.Round(time.Second))
+		if _, err := m.StandbyInstance(ctx, inst.Id); err != nil {
+			log.WarnContext(ctx, "failed to standby idle instance", "instance_id", inst.Id, "error", err)
+			continue
+		}
+		m.activity.forget(inst.Id)
+		standby = append(standby, inst.Id)
+	}
+	return standby, nil
+}
+
+// readTAPTraffic returns the sum of a TAP device's rx and tx byte counters
+func readTAPTraffic(tap string) (uint64, error) {
+	var total uint64
+	for _, name := range []string{"rx_bytes", "tx_bytes"} {
+		data, err := os.ReadFile(filepath.Join("/sys/class/net", tap, "statistics", name))
+		if err != nil {
+			return 0, err
+		}
+		n, err := strconv.ParseUint(strings.TrimSpace(string(data)), 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("parse %s: %w", name, err)
+		}
+		total += n
+	}
+	return total, nil
+}
@@ -0,0 +1,66 @@
+package instances
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIdleDefaultsResolve(t *testing.T) {
+	defaults := IdleDefaults{StandbyAfter: 30 * time.Minute, WakeOnIngress: true}
+
+	assert.Equal(t, defaults, defaults.Resolve(nil))
+	assert.Equal(t, defaults, defaults.Resolve(&IdlePolicy{}))
+
+	never := time.Duration(0)
+	noWake := false
+	assert.Equal(t, IdleDefaults{StandbyAfter: 0, WakeOnIngress: false},
+		defaults.Resolve(&IdlePolicy{StandbyAfter: &never, WakeOnIngress: &noWake}))
+}
+
+func TestValidateIdlePolicy(t *testing.T) {
+	require.NoError(t, validateIdlePolicy(nil))
+	negative := -time.Minute
+	assert.ErrorIs(t, validateIdlePolicy(&IdlePolicy{StandbyAfter: &negative}), ErrInvalidIdlePolicy)
+}
+
+func TestActivityTracker(t *testing.T) {
+	var tr activityTracker
+	t0 := time.Now()
+
+	// First observation starts the idle clock
+	assert.Equal(t, time.Duration(0), tr.observe("a", 100, t0))
+	assert.Equal(t, 5*time.Minute, tr.observe("a", 100, t0.Add(5*time.Minute)))
+
+	// Traffic resets it
+	assert.Equal(t, time.Duration(0), tr.observe("a", 150, t0.Add(6*time.Minute)))
+	assert.Equal(t, time.Minute, tr.observe("a", 150, t0.Add(7*time.Minute)))
+
+	// An open session keeps the instance active
+	tr.begin("a", t0.Add(8*time.Minute))
+	assert.Equal(t, time.Duration(0), tr.observe("a", 150, t0.Add(20*time.Minute)))
+	tr.end("a", t0.Add(21*time.Minute))
+	assert.Equal(t, 4*time.Minute, tr.observe("a", 150, t0.Add(25*time.Minute)))
+
+	// Forgetting restarts the clock on the next observation
+	tr.forget("a")
+	assert.Equal(t, time.Duration(0), tr.observe("a", 0, t0.Add(30*time.Minute)))
+
+	// Sessions survive forget
+	tr.begin("b", t0)
+	tr.forget("b")
+	assert.Equal(t, time.Duration(0), tr.observe("b", 0, t0.Add(time.Hour)))
+}
+
+func TestTrackActivity_ReleaseOnce(t *testing.T) {
+	m := &manager{}
+	release := m.TrackActivity("a")
+	release()
+	release()
+
+	m.activity.mu.Lock()
+	defer m.activity.mu.Unlock()
+	assert.Equal(t, 0, m.activity.entries["a"].sessions)
+}
@@ -3,6 +3,20 @@ package instances
 import (
 	"context"
 	"fmt"
+	"net"
+	"strconv"
+	"time"
+
+	"github.com/kernel/hypeman/lib/logger"
+	"golang.org/x/sync/singleflight"
+)
+
+const (
+	// wakeReadyTimeout bounds restoring an instance and waiting for its port
+	wakeReadyTimeout = 60 * time.Second
+
+	// wakePollInterval is how often a waking instance's port is probed
+	wakePollInterval = 100 * time.Millisecond
 )
 
 // IngressResolver provides instance resolution for the ingress package.
@@ -10,11 +24,14 @@ import (
 // to avoid import cycles.
 type IngressResolver struct {
 	manager Manager
+	idle    IdleDefaults
+	wakes   singleflight.Group // in-flight WakeInstance calls by instance and port
 }
 
 // NewIngressResolver creates a new IngressResolver that wraps an instance manager.
-func NewIngressResolver(manager Manager) *IngressResolver {
-	return &IngressResolver{manager: manager}
+// idle supplies the server's wake-on-ingress default for instances without an idle policy.
+func NewIngressResolver(manager Manager, idle IdleDefaults) *IngressResolver {
+	return &IngressResolver{manager: manager, idle: idle}
 }
 
 // ResolveInstanceIP resolves an instance name, ID, or ID prefix to its IP address.
@@ -34,9 +51,100 @@ func (r *IngressResolver) ResolveInstanceIP(ctx context.Context, nameOrID string
 		return "", fmt.Errorf("instance %s has no IP assigned", nameOrID)
 	}
 
+	// Lazily wake standby instances so the connection can proceed
+	if inst.State == StateStandby && r.idle.Resolve(inst.IdlePolicy).WakeOnIngress {
+		if err := r.wake(ctx, inst.Id); err != nil {
+			return "", err
+		}
+	}
+
 	return inst.IP, nil
 }
 
+// wake restores a standby instance for an incoming ingress connection.
+// Concurrent connections race to restore; losers see the instance running.
+func (r *IngressResolver) wake(ctx context.Context, id string) error {
+	log := logger.FromContext(ctx)
+	log.InfoContext(ctx, "waking standby instance for ingress", "instance_id", id)
+
+	// Finish the restore even if the lookup that triggered it gives up
+	_, err := r.manager.RestoreInstance(context.WithoutCancel(ctx), id)
+	if err == nil {
+		return nil
+	}
+	if inst, getErr := r.manager.GetInstance(ctx, id); getErr == nil && inst.State == StateRunning {
+		return nil
+	}
+	log.WarnContext(ctx, "failed to wake instance for ingress", "instance_id", id, "error", err)
+	return fmt.Errorf("wake instance %s: %w", id, err)
+}
+
+// WakeInstance restores a standby instance and waits until it accepts TCP
+// connections on port, then returns its IP address. Concurrent calls for the
+// same instance and port share one wake, which outlives a caller that gives
+// up so that a retried lookup picks up its result.
+func (r *IngressResolver) WakeInstance(ctx context.Context, nameOrID string, port int) (string, error) {
+	inst, err := r.manager.GetInstance(ctx, nameOrID)
+	if err != nil {
+		return "", fmt.Errorf("instance not found: %s", nameOrID)
+	}
+	if !inst.NetworkEnabled {
+		return "", fmt.Errorf("instance %s has no network configured", nameOrID)
+	}
+	if inst.IP == "" {
+		return "", fmt.Errorf("instance %s has no IP assigned", nameOrID)
+	}
+
+	id, ip := inst.Id, inst.IP
+	ch := r.wakes.DoChan(id+":"+strconv.Itoa(port), func() (any, error) {
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), wakeReadyTimeout)
+		defer cancel()
+
+		inst, err := r.manager.GetInstance(ctx, id)
+		if err != nil {
+			return nil, fmt.Errorf("instance not found: %s", id)
+		}
+		if inst.State == StateStandby {
+			if err := r.wake(ctx, id); err != nil {
+				return nil, err
+			}
+		} else if inst.State != StateRunning {
+			return nil, fmt.Errorf("instance %s is %s, not running or in standby", id, inst.State)
+		}
+		return nil, waitForPort(ctx, ip, port)
+	})
+
+	select {
+	case res := <-ch:
+		if res.Err != nil {
+			return "", res.Err
+		}
+		return ip, nil
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+}
+
+// waitForPort polls until ip:port accepts a TCP connection
+func waitForPort(ctx context.Context, ip string, port int) error {
+	addr := net.JoinHostPort(ip, strconv.Itoa(port))
+	var d net.Dialer
+	for {
+		dialCtx, cancel := context.WithTimeout(ctx, time.Second)
+		conn, err := d.DialContext(dialCtx, "tcp", addr)
+		cancel()
+		if err == nil {
+			conn.Close()
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("wait for %s: %w", addr, ctx.Err())
+		case <-time.After(wakePollInterval):
+		}
+	}
+}
+
 // InstanceExists checks if an instance with the given name, ID, or ID prefix exists.
 func (r *IngressResolver) InstanceExists(ctx context.Context, nameOrID string) (bool, error) {
 	_, err := r.manager.GetInstance(ctx, nameOrID)
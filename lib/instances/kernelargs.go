@@ -0,0 +1,83 @@
+package instances
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	// baseKernelArgs are the guest kernel parameters hypeman always sets
+	baseKernelArgs = "console=ttyS0"
+
+	// rootVolumeKernelArg tells the guest init to boot the root volume on
+	// /dev/vda instead of assembling the image overlay
+	rootVolumeKernelArg = "hypeman.root=volume"
+
+	// MaxKernelArgs is the maximum number of extra kernel parameters per instance
+	MaxKernelArgs = 32
+
+	// maxKernelArgsLen bounds the extra parameters' total length, leaving room
+	// for baseKernelArgs within the kernel's 2048-byte command line
+	maxKernelArgsLen = 1024
+)
+
+// blockedKernelParams are parameters hypeman's boot depends on: the serial
+// console carries the instance logs, and init, the initrd and the root
+// filesystem are set up by hypeman's initrd.
+var blockedKernelParams = map[string]bool{
+	"console":      true,
+	"earlycon":     true,
+	"init":         true,
+	"rdinit":       true,
+	"root":         true,
+	"rootflags":    true,
+	"rootfstype":   true,
+	"ro":           true,
+	"rw":           true,
+	"noinitrd":     true,
+	"initrd":       true,
+	"hypeman.root": true,
+}
+
+// validateKernelArgs checks extra kernel parameters against the blocklist.
+// Each entry is a single parameter such as "hugepages=64" or "debug".
+func validateKernelArgs(args []string) error {
+	if len(args) > MaxKernelArgs {
+		return fmt.Errorf("%w: at most %d parameters are supported, got %d", ErrInvalidKernelArgs, MaxKernelArgs, len(args))
+	}
+
+	total := 0
+	for _, arg := range args {
+		total += len(arg) + 1
+		if arg == "" {
+			return fmt.Errorf("%w: empty parameter", ErrInvalidKernelArgs)
+		}
+		// Quoting and whitespace would let one entry smuggle in several
+		// parameters, and "--" hands the rest of the command line to init
+		for _, c := range arg {
+			if c <= ' ' || c > '~' || c == '"' {
+				return fmt.Errorf("%w: parameter %q contains whitespace, quotes or non-ASCII characters", ErrInvalidKernelArgs, arg)
+			}
+		}
+		if arg == "--" {
+			return fmt.Errorf("%w: %q is not allowed", ErrInvalidKernelArgs, arg)
+		}
+		name, _, _ := strings.Cut(arg, "=")
+		if blockedKernelParams[name] {
+			return fmt.Errorf("%w: %q is managed by hypeman", ErrInvalidKernelArgs, name)
+		}
+	}
+	if total > maxKernelArgsLen {
+		return fmt.Errorf("%w: parameters exceed %d bytes", ErrInvalidKernelArgs, maxKernelArgsLen)
+	}
+	return nil
+}
+
+// kernelCmdline returns the guest kernel command line for an instance
+func kernelCmdline(inst *Instance) string {
+	args := []string{baseKernelArgs}
+	if inst.RootVolume != "" {
+		args = append(args, rootVolumeKernelArg)
+	}
+	return strings.Join(append(args, inst.KernelArgs...), " ")
+}
@@ -0,0 +1,45 @@
+package instances
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateKernelArgs(t *testing.T) {
+	require.NoError(t, validateKernelArgs(nil))
+	require.NoError(t, validateKernelArgs([]string{"hugepages=64", "isolcpus=1-3", "debug", "module.param=a,b"}))
+
+	tests := []struct {
+		name string
+		args []string
+	}{
+		{"empty", []string{""}},
+		{"whitespace", []string{"quiet init=/bin/sh"}},
+		{"quotes", []string{`dyndbg="file foo.c +p"`}},
+		{"init args separator", []string{"--"}},
+		{"console", []string{"console=tty0"}},
+		{"init", []string{"init=/bin/sh"}},
+		{"root flag", []string{"rw"}},
+		{"too many", strings.Fields(strings.Repeat("debug ", MaxKernelArgs+1))},
+		{"too long", []string{"x=" + strings.Repeat("a", maxKernelArgsLen)}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.ErrorIs(t, validateKernelArgs(tt.args), ErrInvalidKernelArgs)
+		})
+	}
+}
+
+func TestKernelCmdline(t *testing.T) {
+	inst := &Instance{}
+	assert.Equal(t, "console=ttyS0", kernelCmdline(inst))
+
+	inst.KernelArgs = []string{"hugepages=64", "debug"}
+	assert.Equal(t, "console=ttyS0 hugepages=64 debug", kernelCmdline(inst))
+
+	inst.RootVolume = "vol-1"
+	assert.Equal(t, "console=ttyS0 hypeman.root=volume hugepages=64 debug", kernelCmdline(inst))
+}
@@ -0,0 +1,35 @@
+package instances
+
+import (
+	"fmt"
+	"regexp"
+)
+
+const (
+	// MaxLabels is the maximum number of labels per instance
+	MaxLabels = 64
+
+	// maxLabelValueLen bounds a label's value
+	maxLabelValueLen = 256
+)
+
+// labelKeyPattern matches a label key: an optional DNS-style prefix and a
+// name of letters, digits, '-', '_' and '.', like app or example.com/tier.
+var labelKeyPattern = regexp.MustCompile(`^([a-z0-9]([a-z0-9.-]{0,251}[a-z0-9])?/)?[A-Za-z0-9]([A-Za-z0-9._-]{0,61}[A-Za-z0-9])?$`)
+
+// validateLabels checks label keys and values. Labels are free-form metadata
+// guests can read from the metadata service.
+func validateLabels(labels map[string]string) error {
+	if len(labels) > MaxLabels {
+		return fmt.Errorf("%w: at most %d labels are supported, got %d", ErrInvalidLabels, MaxLabels, len(labels))
+	}
+	for key, value := range labels {
+		if !labelKeyPattern.MatchString(key) {
+			return fmt.Errorf("%w: invalid label key %q", ErrInvalidLabels, key)
+		}
+		if len(value) > maxLabelValueLen {
+			return fmt.Errorf("%w: value of label %s is longer than %d characters", ErrInvalidLabels, key, maxLabelValueLen)
+		}
+	}
+	return nil
+}
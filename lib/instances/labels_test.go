@@ -0,0 +1,24 @@
+package instances
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateLabels(t *testing.T) {
+	assert.NoError(t, validateLabels(nil))
+	assert.NoError(t, validateLabels(map[string]string{"app": "web", "example.com/tier": "frontend", "empty": ""}))
+
+	for _, key := range []string{"", "-app", "app-", "Example.com/tier", "a/b/c", strings.Repeat("a", 64)} {
+		assert.ErrorIs(t, validateLabels(map[string]string{key: "x"}), ErrInvalidLabels, key)
+	}
+	assert.ErrorIs(t, validateLabels(map[string]string{"app": strings.Repeat("x", 257)}), ErrInvalidLabels)
+
+	many := make(map[string]string)
+	for i := range MaxLabels + 1 {
+		many["l"+strings.Repeat("x", i)] = ""
+	}
+	assert.ErrorIs(t, validateLabels(many), ErrInvalidLabels)
+}
@@ -0,0 +1,172 @@
+package instances
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/kernel/hypeman/lib/guest"
+	"github.com/kernel/hypeman/lib/hypervisor"
+	"github.com/kernel/hypeman/lib/logger"
+	"github.com/kernel/hypeman/lib/vmconfig"
+)
+
+const (
+	// MaxLinks is the maximum number of peers an instance links to
+	MaxLinks = 32
+
+	// linkUpdateTimeout bounds pushing one instance's links to its guest agent
+	linkUpdateTimeout = 10 * time.Second
+)
+
+// validateLinks checks the names of the peers an instance links to. Peers
+// needn't exist yet: they're listed once they do.
+func validateLinks(name string, links []string, rc *ResolverConfig) error {
+	if len(links) == 0 {
+		return nil
+	}
+	if rc != nil && rc.SkipHosts {
+		return fmt.Errorf("%w: links are written to /etc/hosts, which manage_hosts: false leaves alone", ErrInvalidLinks)
+	}
+	if len(links) > MaxLinks {
+		return fmt.Errorf("%w: at most %d links are supported, got %d", ErrInvalidLinks, MaxLinks, len(links))
+	}
+	for i, link := range links {
+		if err := validateInstanceName(link); err != nil {
+			return fmt.Errorf("%w: link %q: %v", ErrInvalidLinks, link, err)
+		}
+		if link == name {
+			return fmt.Errorf("%w: an instance can't link to itself", ErrInvalidLinks)
+		}
+		if slices.Contains(links[:i], link) {
+			return fmt.Errorf("%w: duplicate link %q", ErrInvalidLinks, link)
+		}
+	}
+	return nil
+}
+
+// validateLinkTenants rejects links to peers that only exist in another
+// tenant, since links resolve within the linking instance's tenant
+func validateLinkTenants(tenant string, links []string, insts []Instance) error {
+	for _, link := range links {
+		own, foreign := false, false
+		for _, inst := range insts {
+			if inst.Name == link {
+				own = own || inst.Tenant == tenant
+				foreign = foreign || inst.Tenant != tenant
+			}
+		}
+		if foreign && !own {
+			return fmt.Errorf("%w: link %q is an instance of another tenant", ErrInvalidLinks, link)
+		}
+	}
+	return nil
+}
+
+// checkLinkTenants applies validateLinkTenants to the existing instances
+func (m *manager) checkLinkTenants(ctx context.Context, tenant string, links []string) error {
+	if len(links) == 0 {
+		return nil
+	}
+	insts, err := m.listInstances(ctx)
+	if err != nil {
+		return fmt.Errorf("list instances: %w", err)
+	}
+	return validateLinkTenants(tenant, links, insts)
+}
+
+// resolveLinks returns the /etc/hosts entries of an instance's links: the
+// address of each linked peer in its tenant that holds one. Stopped peers
+// have given theirs up, and peers that don't exist yet have none; both are
+// left out.
+func resolveLinks(tenant string, links []string, insts []Instance) []vmconfig.HostEntry {
+	var entries []vmconfig.HostEntry
+	for _, link := range links {
+		for _, inst := range insts {
+			if inst.Name != link || inst.Tenant != tenant || !inst.NetworkEnabled || inst.IP == "" ||
+				inst.State == StateStopped || inst.State == StateFailed {
+				continue
+			}
+			entries = append(entries, vmconfig.HostEntry{Hostname: link, IP: inst.IP})
+			break
+		}
+	}
+	return entries
+}
+
+// guestLinks resolves an instance's links for its config disk
+func (m *manager) guestLinks(ctx context.Context, inst *Instance) []vmconfig.HostEntry {
+	if len(inst.Links) == 0 {
+		return nil
+	}
+	insts, err := m.listInstances(ctx)
+	if err != nil {
+		logger.FromContext(ctx).WarnContext(ctx, "failed to resolve links, booting without them", "instance_id", inst.Id, "error", err)
+		return nil
+	}
+	return resolveLinks(inst.Tenant, inst.Links, insts)
+}
+
+// pushLinks sends an instance's current links to its guest agent
+func (m *manager) pushLinks(ctx context.Context, stored *StoredMetadata, insts []Instance) error {
+	dialer, err := hypervisor.NewVsockDialer(stored.HypervisorType, stored.VsockSocket, stored.VsockCID)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(ctx, linkUpdateTimeout)
+	defer cancel()
+	return guest.UpdateLinks(ctx, dialer, resolveLinks(stored.Tenant, stored.Links, insts))
+}
+
+// updateLinkingPeers pushes fresh links to the running instances that link
+// to one that just booted, as it boots with a new address. It runs in the
+// background after boot; failures are logged.
+func (m *manager) updateLinkingPeers(ctx context.Context, booted StoredMetadata) {
+	log := logger.FromContext(ctx)
+	insts, err := m.listInstances(ctx)
+	if err != nil {
+		log.WarnContext(ctx, "failed to list instances to update links", "instance_id", booted.Id, "error", err)
+		return
+	}
+	// Its metadata on disk may not have the new address yet
+	idx := slices.IndexFunc(insts, func(inst Instance) bool { return inst.Id == booted.Id })
+	if idx < 0 {
+		insts = append(insts, Instance{})
+		idx = len(insts) - 1
+	}
+	insts[idx] = Instance{StoredMetadata: booted, State: StateRunning}
+
+	var errs []error
+	for _, inst := range insts {
+		if inst.Id == booted.Id || inst.State != StateRunning || inst.Tenant != booted.Tenant ||
+			!slices.Contains(inst.Links, booted.Name) {
+			continue
+		}
+		if err := m.pushLinks(ctx, &inst.StoredMetadata, insts); err != nil {
+			errs = append(errs, fmt.Errorf("instance %s: %w", inst.Id, err))
+			continue
+		}
+		log.DebugContext(ctx, "updated links", "instance_id", inst.Id, "peer", booted.Name)
+	}
+	if err := errors.Join(errs...); err != nil {
+		log.WarnContext(ctx, "failed to update links to booted instance", "instance_id", booted.Id, "error", err)
+	}
+}
+
+// refreshLinksAfterRestore catches a restored instance's links up with
+// peers that restarted while it was in standby. It runs in the background
+// after a restore; failures are logged.
+func (m *manager) refreshLinksAfterRestore(ctx context.Context, stored StoredMetadata) {
+	if len(stored.Links) == 0 {
+		return
+	}
+	insts, err := m.listInstances(ctx)
+	if err == nil {
+		err = m.pushLinks(ctx, &stored, insts)
+	}
+	if err != nil {
+		logger.FromContext(ctx).WarnContext(ctx, "failed to refresh links after restore", "instance_id", stored.Id, "error", err)
+	}
+}
@@ -0,0 +1,69 @@
+package instances
+
+import (
+	"testing"
+
+	"github.com/kernel/hypeman/lib/vmconfig"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateLinks(t *testing.T) {
+	require.NoError(t, validateLinks("web", nil, nil))
+	require.NoError(t, validateLinks("web", []string{"db", "cache"}, &ResolverConfig{SkipResolvConf: true}))
+
+	tooMany := make([]string, MaxLinks+1)
+	for i := range tooMany {
+		tooMany[i] = "peer-" + string(rune('a'+i%26)) + string(rune('a'+i/26))
+	}
+	tests := []struct {
+		name  string
+		links []string
+		rc    *ResolverConfig
+	}{
+		{"invalid name", []string{"DB"}, nil},
+		{"self", []string{"web"}, nil},
+		{"duplicate", []string{"db", "db"}, nil},
+		{"too many", tooMany, nil},
+		{"unmanaged hosts", []string{"db"}, &ResolverConfig{SkipHosts: true}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.ErrorIs(t, validateLinks("web", tt.links, tt.rc), ErrInvalidLinks)
+		})
+	}
+}
+
+func TestResolveLinks(t *testing.T) {
+	peer := func(name, ip string, state State) Instance {
+		return Instance{StoredMetadata: StoredMetadata{Name: name, IP: ip, NetworkEnabled: ip != ""}, State: state}
+	}
+	insts := []Instance{
+		peer("db", "10.100.0.3", StateRunning),
+		peer("cache", "10.100.0.4", StateStandby),
+		peer("queue", "10.100.0.5", StateStopped),
+		peer("offline", "", StateRunning),
+		{StoredMetadata: StoredMetadata{Name: "other", Tenant: "acme", IP: "10.100.0.6", NetworkEnabled: true}, State: StateRunning},
+	}
+
+	// In link order; stopped, unnetworked, missing and other tenants' peers left out
+	assert.Equal(t, []vmconfig.HostEntry{
+		{Hostname: "cache", IP: "10.100.0.4"},
+		{Hostname: "db", IP: "10.100.0.3"},
+	}, resolveLinks("", []string{"cache", "queue", "offline", "missing", "other", "db"}, insts))
+	assert.Empty(t, resolveLinks("", nil, insts))
+	assert.Equal(t, []vmconfig.HostEntry{{Hostname: "other", IP: "10.100.0.6"}}, resolveLinks("acme", []string{"db", "other"}, insts))
+}
+
+func TestValidateLinkTenants(t *testing.T) {
+	insts := []Instance{
+		{StoredMetadata: StoredMetadata{Name: "db", Tenant: "acme"}},
+		{StoredMetadata: StoredMetadata{Name: "cache", Tenant: "acme"}},
+		{StoredMetadata: StoredMetadata{Name: "cache", Tenant: "globex"}},
+	}
+
+	require.NoError(t, validateLinkTenants("acme", []string{"db", "cache", "missing"}, insts))
+	require.NoError(t, validateLinkTenants("globex", []string{"cache"}, insts))
+	assert.ErrorIs(t, validateLinkTenants("globex", []string{"db"}, insts), ErrInvalidLinks)
+	assert.ErrorIs(t, validateLinkTenants("", []string{"db"}, insts), ErrInvalidLinks)
+}
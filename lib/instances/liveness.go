@@ -3,6 +3,7 @@ package instances
 import (
 	"context"
 	"os/exec"
+	"path/filepath"
 	"strings"
 
 	"github.com/kernel/hypeman/lib/devices"
@@ -45,7 +46,7 @@ func (a *instanceLivenessAdapter) IsInstanceRunning(ctx context.Context, instanc
 	case StateRunning, StatePaused, StateCreated:
 		return true
 	default:
-		// StateStopped, StateStandby, StateShutdown, StateUnknown
+		// StateStopped, StateStandby, StateFailed, StateShutdown, StateUnknown
 		return false
 	}
 }
@@ -82,6 +83,33 @@ func (a *instanceLivenessAdapter) ListAllInstanceDevices(ctx context.Context) ma
 	return result
 }
 
+// ListVGPUAllocations returns the vGPU of every instance that has one. An
+// instance holds its vGPU from creation until it is deleted, whatever its state,
+// so only metadata is read.
+func (a *instanceLivenessAdapter) ListVGPUAllocations(ctx context.Context) []devices.VGPUAllocation {
+	if a.manager == nil {
+		return nil
+	}
+	files, err := a.manager.listMetadataFiles()
+	if err != nil {
+		return nil
+	}
+
+	var result []devices.VGPUAllocation
+	for _, file := range files {
+		meta, err := a.manager.loadMetadata(filepath.Base(filepath.Dir(file)))
+		if err != nil || meta.GPUProfile == "" {
+			continue
+		}
+		result = append(result, devices.VGPUAllocation{
+			InstanceID: meta.Id,
+			Profile:    meta.GPUProfile,
+			Tenant:     meta.Tenant,
+		})
+	}
+	return result
+}
+
 // DetectSuspiciousVMMProcesses finds cloud-hypervisor processes that don't match
 // known instances and logs warnings. Returns the count of suspicious processes found.
 // This uses ListInstances (all instances) rather than ListAllInstanceDevices to avoid
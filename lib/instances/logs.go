@@ -7,7 +7,11 @@ import (
 	"io"
 	"os"
 	"os/exec"
+	"path/filepath"
+	"sort"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/kernel/hypeman/lib/logger"
 )
@@ -45,19 +49,7 @@ func (m *manager) streamInstanceLogs(ctx context.Context, id string, tail int, f
 		return nil, err
 	}
 
-	// Determine log path based on source
-	var logPath string
-	switch source {
-	case LogSourceApp:
-		logPath = m.paths.InstanceAppLog(id)
-	case LogSourceVMM:
-		logPath = m.paths.InstanceVMMLog(id)
-	case LogSourceHypeman:
-		logPath = m.paths.InstanceHypemanLog(id)
-	default:
-		// Default to app log for backwards compatibility
-		logPath = m.paths.InstanceAppLog(id)
-	}
+	logPath := m.logPath(id, source)
 
 	// Check if log file exists before starting tail
 	if _, err := os.Stat(logPath); os.IsNotExist(err) {
@@ -109,19 +101,48 @@ func (m *manager) streamInstanceLogs(ctx context.Context, id string, tail int, f
 	return out, nil
 }
 
+// logPath returns the path of an instance's log from source
+func (m *manager) logPath(id string, source LogSource) string {
+	switch source {
+	case LogSourceVMM:
+		return m.paths.InstanceVMMLog(id)
+	case LogSourceHypeman:
+		return m.paths.InstanceHypemanLog(id)
+	default:
+		// Default to app log for backwards compatibility
+		return m.paths.InstanceAppLog(id)
+	}
+}
+
+// LogRetention bounds the disk space used by instance logs.
+type LogRetention struct {
+	MaxBytes      int64         // Rotate a log once it reaches this size
+	MaxFiles      int           // Rotated copies kept per log (.1, .2, etc.)
+	MaxAge        time.Duration // Remove rotated copies older than this (0 = no limit)
+	MaxTotalBytes int64         // Cap on all instance logs together; oldest rotated copies are removed first (0 = no limit)
+}
+
+// LogRotationResult summarizes one RotateLogs run.
+type LogRotationResult struct {
+	Rotated      int   // Logs rotated
+	Removed      int   // Rotated copies removed by MaxAge or MaxTotalBytes
+	RemovedBytes int64 // Size of the removed copies
+	TotalBytes   int64 // Size of all instance logs afterwards
+}
+
 // rotateLogIfNeeded performs copytruncate rotation if file exceeds maxBytes
-// Keeps up to maxFiles old backups (.1, .2, etc.)
-func rotateLogIfNeeded(path string, maxBytes int64, maxFiles int) error {
+// Keeps up to maxFiles old backups (.1, .2, etc.) and reports whether it rotated
+func rotateLogIfNeeded(path string, maxBytes int64, maxFiles int) (bool, error) {
 	info, err := os.Stat(path)
 	if err != nil {
 		if os.IsNotExist(err) {
-			return nil // Nothing to rotate
+			return false, nil // Nothing to rotate
 		}
-		return fmt.Errorf("stat log file: %w", err)
+		return false, fmt.Errorf("stat log file: %w", err)
 	}
 
 	if info.Size() < maxBytes {
-		return nil // Under limit, nothing to do
+		return false, nil // Under limit, nothing to do
 	}
 
 	// Shift old backups (.1 -> .2, .2 -> .3, etc.)
@@ -141,26 +162,108 @@ func rotateLogIfNeeded(path string, maxBytes int64, maxFiles int) error {
 	// Copy current log to .1
 	src, err := os.Open(path)
 	if err != nil {
-		return fmt.Errorf("open log for rotation: %w", err)
+		return false, fmt.Errorf("open log for rotation: %w", err)
 	}
 
 	dst, err := os.Create(path + ".1")
 	if err != nil {
 		src.Close()
-		return fmt.Errorf("create backup: %w", err)
+		return false, fmt.Errorf("create backup: %w", err)
 	}
 
 	_, err = io.Copy(dst, src)
 	src.Close()
 	dst.Close()
 	if err != nil {
-		return fmt.Errorf("copy to backup: %w", err)
+		return false, fmt.Errorf("copy to backup: %w", err)
 	}
 
 	// Truncate original (keeps file descriptor valid for writers)
 	if err := os.Truncate(path, 0); err != nil {
-		return fmt.Errorf("truncate log: %w", err)
+		return false, fmt.Errorf("truncate log: %w", err)
 	}
 
-	return nil
+	return true, nil
+}
+
+// logFile is a log or rotated copy in an instance's log directory
+type logFile struct {
+	path    string
+	size    int64
+	modTime time.Time
+	rotated bool
+}
+
+// listLogFiles returns the logs and rotated copies (e.g. app.log.1) in dir
+func listLogFiles(dir string) ([]logFile, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("read log directory: %w", err)
+	}
+
+	var files []logFile
+	for _, e := range entries {
+		if !e.Type().IsRegular() {
+			continue
+		}
+		name := e.Name()
+		rotated := false
+		if i := strings.LastIndexByte(name, '.'); i >= 0 {
+			if n, err := strconv.Atoi(name[i+1:]); err == nil && n > 0 {
+				rotated = true
+				name = name[:i]
+			}
+		}
+		if !strings.HasSuffix(name, ".log") {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil {
+			continue // Removed concurrently
+		}
+		files = append(files, logFile{
+			path:    filepath.Join(dir, e.Name()),
+			size:    info.Size(),
+			modTime: info.ModTime(),
+			rotated: rotated,
+		})
+	}
+	return files, nil
+}
+
+// pruneLogs removes rotated copies older than policy.MaxAge, then the oldest
+// remaining copies until all files fit in policy.MaxTotalBytes. Active logs are
+// never removed; rotation bounds them instead.
+func pruneLogs(files []logFile, policy LogRetention, now time.Time, res *LogRotationResult) error {
+	var total int64
+	var rotated []logFile
+	for _, f := range files {
+		total += f.size
+		if f.rotated {
+			rotated = append(rotated, f)
+		}
+	}
+	sort.Slice(rotated, func(i, j int) bool { return rotated[i].modTime.Before(rotated[j].modTime) })
+
+	var lastErr error
+	for _, f := range rotated {
+		expired := policy.MaxAge > 0 && now.Sub(f.modTime) > policy.MaxAge
+		overCap := policy.MaxTotalBytes > 0 && total > policy.MaxTotalBytes
+		if !expired && !overCap {
+			// Sorted oldest first, so no later copy is expired either
+			break
+		}
+		if err := os.Remove(f.path); err != nil && !os.IsNotExist(err) {
+			lastErr = fmt.Errorf("remove rotated log: %w", err)
+			continue
+		}
+		total -= f.size
+		res.Removed++
+		res.RemovedBytes += f.size
+	}
+	res.TotalBytes = total
+	return lastErr
 }
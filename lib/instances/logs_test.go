@@ -0,0 +1,89 @@
+package instances
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeLog(t *testing.T, path string, size int, modTime time.Time) {
+	t.Helper()
+	require.NoError(t, os.WriteFile(path, make([]byte, size), 0644))
+	require.NoError(t, os.Chtimes(path, modTime, modTime))
+}
+
+func TestRotateLogIfNeeded(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	writeLog(t, path, 10, time.Now())
+
+	rotated, err := rotateLogIfNeeded(path, 100, 2)
+	require.NoError(t, err)
+	assert.False(t, rotated, "log under the limit")
+
+	for range 3 {
+		writeLog(t, path, 100, time.Now())
+		rotated, err = rotateLogIfNeeded(path, 100, 2)
+		require.NoError(t, err)
+		assert.True(t, rotated)
+	}
+
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Zero(t, info.Size(), "active log is truncated in place")
+	assert.FileExists(t, path+".1")
+	assert.FileExists(t, path+".2")
+	assert.NoFileExists(t, path+".3", "only LOG_MAX_FILES copies are kept")
+}
+
+func TestPruneLogs(t *testing.T) {
+	now := time.Now()
+	dirA, dirB := t.TempDir(), t.TempDir()
+	writeLog(t, filepath.Join(dirA, "app.log"), 100, now)
+	writeLog(t, filepath.Join(dirA, "app.log.1"), 100, now.Add(-1*time.Hour))
+	writeLog(t, filepath.Join(dirA, "app.log.2"), 100, now.Add(-48*time.Hour))
+	writeLog(t, filepath.Join(dirB, "vmm.log"), 100, now)
+	writeLog(t, filepath.Join(dirB, "vmm.log.1"), 100, now.Add(-2*time.Hour))
+	writeLog(t, filepath.Join(dirB, "notes.txt"), 100, now.Add(-48*time.Hour))
+
+	var files []logFile
+	for _, dir := range []string{dirA, dirB} {
+		f, err := listLogFiles(dir)
+		require.NoError(t, err)
+		files = append(files, f...)
+	}
+	require.Len(t, files, 5, "non-log files are ignored")
+
+	// The expired copy goes first, then the oldest copy across instances
+	// until the logs fit in the cap
+	policy := LogRetention{MaxAge: 24 * time.Hour, MaxTotalBytes: 300}
+	res := &LogRotationResult{}
+	require.NoError(t, pruneLogs(files, policy, now, res))
+
+	assert.Equal(t, 2, res.Removed)
+	assert.Equal(t, int64(200), res.RemovedBytes)
+	assert.Equal(t, int64(300), res.TotalBytes)
+	assert.NoFileExists(t, filepath.Join(dirA, "app.log.2"))
+	assert.NoFileExists(t, filepath.Join(dirB, "vmm.log.1"))
+	assert.FileExists(t, filepath.Join(dirA, "app.log.1"))
+	assert.FileExists(t, filepath.Join(dirB, "notes.txt"))
+}
+
+func TestPruneLogs_KeepsActiveLogs(t *testing.T) {
+	dir := t.TempDir()
+	writeLog(t, filepath.Join(dir, "app.log"), 500, time.Now().Add(-48*time.Hour))
+	writeLog(t, filepath.Join(dir, "app.log.1"), 100, time.Now())
+
+	files, err := listLogFiles(dir)
+	require.NoError(t, err)
+
+	res := &LogRotationResult{}
+	require.NoError(t, pruneLogs(files, LogRetention{MaxAge: time.Hour, MaxTotalBytes: 100}, time.Now(), res))
+
+	assert.Equal(t, 1, res.Removed)
+	assert.Equal(t, int64(500), res.TotalBytes, "the cap can't be met by removing active logs")
+	assert.FileExists(t, filepath.Join(dir, "app.log"))
+}
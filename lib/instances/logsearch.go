@@ -0,0 +1,320 @@
+package instances
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultLogSearchLimit is the page size of a log search without a limit
+	DefaultLogSearchLimit = 100
+	// MaxLogSearchLimit caps the page size of a log search
+	MaxLogSearchLimit = 1000
+	// maxLogMatchBytes is where matched lines are cut in search results
+	maxLogMatchBytes = 16 * 1024
+)
+
+// LogSearchQuery searches an instance's log and its rotated copies
+type LogSearchQuery struct {
+	Query  string    // Substring to find, or a regular expression if Regex is set
+	Regex  bool      // Interpret Query as a regular expression (RE2 syntax)
+	Source LogSource // Log to search (default app)
+	Since  time.Time // Skip lines before this time (zero = no bound)
+	Until  time.Time // Skip lines after this time (zero = no bound)
+	Limit  int       // Matches per page (0 = DefaultLogSearchLimit)
+	Cursor string    // NextCursor of the previous page, empty for the first
+}
+
+// LogMatch is a line matching a log search
+type LogMatch struct {
+	File string     // Log file the line is in, like "app.log" or "app.log.2"
+	Line int        // 1-based line number within File
+	Time *time.Time // Timestamp the line starts with, if any
+	Text string     // The line, cut at 16 KiB
+}
+
+// LogSearchResult is a page of log search matches, oldest first
+type LogSearchResult struct {
+	Matches []LogMatch
+	// NextCursor continues the search after the last match; empty once
+	// every file has been searched
+	NextCursor string
+}
+
+// logCursor is where a log search stopped. Rotated copies are named by
+// their modification time, which renames on rotation keep. Rotation copies
+// the active log whole, so an offset into it carries over to the newest
+// rotated copy; the checksum of the bytes before the offset tells which of
+// the two holds them.
+type logCursor struct {
+	File   int64  `json:"f"` // Rotated copy's modification time (Unix ns), 0 for the active log
+	Offset int64  `json:"o"`
+	Line   int    `json:"l"`
+	Check  uint64 `json:"c"` // logChecksum before Offset
+}
+
+// logChecksumBytes is how many bytes before a cursor's offset are checked
+const logChecksumBytes = 256
+
+// logChecksum hashes the bytes of path before offset
+func logChecksum(path string, offset int64) (uint64, bool) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, false
+	}
+	defer f.Close()
+	start := max(offset-logChecksumBytes, 0)
+	buf := make([]byte, offset-start)
+	if _, err := f.ReadAt(buf, start); err != nil {
+		return 0, false
+	}
+	h := fnv.New64a()
+	h.Write(buf)
+	return h.Sum64(), true
+}
+
+// logSearchFile is a log or rotated copy to search, with the span of time its
+// lines were written in
+type logSearchFile struct {
+	logFile
+	id    int64 // logCursor.File
+	start time.Time
+}
+
+// searchInstanceLogs searches an instance's log and its rotated copies.
+// Since and Until filter by the RFC 3339 timestamp lines start with; lines
+// without one take the previous line's, or are kept if their file was
+// written during the span.
+func (m *manager) searchInstanceLogs(ctx context.Context, id string, q LogSearchQuery) (*LogSearchResult, error) {
+	if _, err := m.loadMetadata(id); err != nil {
+		return nil, err
+	}
+	return searchLogs(ctx, m.logPath(id, q.Source), q)
+}
+
+func searchLogs(ctx context.Context, logPath string, q LogSearchQuery) (*LogSearchResult, error) {
+	if q.Query == "" {
+		return nil, fmt.Errorf("%w: query is required", ErrInvalidLogSearch)
+	}
+	match := func(line []byte) bool { return bytes.Contains(line, []byte(q.Query)) }
+	if q.Regex {
+		re, err := regexp.Compile(q.Query)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidLogSearch, err)
+		}
+		match = re.Match
+	}
+	if !q.Since.IsZero() && !q.Until.IsZero() && q.Until.Before(q.Since) {
+		return nil, fmt.Errorf("%w: until is before since", ErrInvalidLogSearch)
+	}
+	limit := q.Limit
+	if limit <= 0 {
+		limit = DefaultLogSearchLimit
+	}
+	if limit > MaxLogSearchLimit {
+		return nil, fmt.Errorf("%w: limit must be at most %d", ErrInvalidLogSearch, MaxLogSearchLimit)
+	}
+	var cursor logCursor
+	if q.Cursor != "" {
+		data, err := base64.RawURLEncoding.DecodeString(q.Cursor)
+		if err != nil || json.Unmarshal(data, &cursor) != nil {
+			return nil, fmt.Errorf("%w: malformed cursor", ErrInvalidLogSearch)
+		}
+	}
+
+	files, err := searchFiles(logPath)
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		return nil, ErrLogNotFound
+	}
+	first, offset, line := resumeAt(files, cursor)
+
+	res := &LogSearchResult{Matches: []LogMatch{}}
+	for i := first; i < len(files); i++ {
+		f := files[i]
+		if !q.Until.IsZero() && f.start.After(q.Until) {
+			break // This and later files were written after the span
+		}
+		if !q.Since.IsZero() && f.modTime.Before(q.Since) {
+			offset, line = 0, 0
+			continue // Written before the span
+		}
+		next, err := searchFile(ctx, f, offset, line, q, match, limit, res)
+		if err != nil {
+			return nil, err
+		}
+		if next != nil {
+			data, _ := json.Marshal(next)
+			res.NextCursor = base64.RawURLEncoding.EncodeToString(data)
+			return res, nil
+		}
+		offset, line = 0, 0
+	}
+	return res, nil
+}
+
+// searchFiles returns the log at logPath and its rotated copies, oldest
+// first
+func searchFiles(logPath string) ([]logSearchFile, error) {
+	all, err := listLogFiles(filepath.Dir(logPath))
+	if err != nil {
+		return nil, err
+	}
+	base := filepath.Base(logPath)
+	var files []logSearchFile
+	for _, f := range all {
+		name := filepath.Base(f.path)
+		if name == base {
+			files = append(files, logSearchFile{logFile: f})
+			continue
+		}
+		if n, ok := strings.CutPrefix(name, base+"."); ok {
+			if _, err := strconv.Atoi(n); err == nil {
+				files = append(files, logSearchFile{logFile: f, id: f.modTime.UnixNano()})
+			}
+		}
+	}
+	// The active log is newest; rotated copies are ordered by age
+	sort.SliceStable(files, func(i, j int) bool {
+		if files[i].rotated != files[j].rotated {
+			return files[i].rotated
+		}
+		return files[i].modTime.Before(files[j].modTime)
+	})
+	for i := 1; i < len(files); i++ {
+		files[i].start = files[i-1].modTime
+	}
+	return files, nil
+}
+
+// resumeAt returns the file index, offset and line count a cursor continues
+// from. A rotated copy removed since is skipped, continuing from the oldest
+// remaining file.
+func resumeAt(files []logSearchFile, c logCursor) (int, int64, int) {
+	if c.File == 0 && c.Offset == 0 {
+		return 0, 0, 0
+	}
+	active := len(files) - 1
+	if c.File == 0 && files[active].rotated {
+		// The active log is gone; only rotated copies are left
+		return 0, 0, 0
+	}
+	if c.File == 0 {
+		// Rotated since: the searched part moved to the newest copy
+		for _, i := range []int{active, active - 1} {
+			if i < 0 || files[i].size < c.Offset {
+				continue
+			}
+			if sum, ok := logChecksum(files[i].path, c.Offset); ok && sum == c.Check {
+				return i, c.Offset, c.Line
+			}
+		}
+		return active, 0, 0
+	}
+	for i, f := range files {
+		if f.rotated && f.id == c.File {
+			return i, c.Offset, c.Line
+		}
+	}
+	return 0, 0, 0
+}
+
+// searchFile appends f's matches from offset to res. It returns where to
+// continue once res holds limit matches, or nil if f was searched to the end.
+func searchFile(ctx context.Context, f logSearchFile, offset int64, line int, q LogSearchQuery, match func([]byte) bool, limit int, res *LogSearchResult) (*logCursor, error) {
+	file, err := os.Open(f.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil // Rotated or removed concurrently
+		}
+		return nil, fmt.Errorf("open log: %w", err)
+	}
+	defer file.Close()
+	if _, err := file.Seek(offset, io.SeekStart); err != nil {
+		return nil, fmt.Errorf("seek log: %w", err)
+	}
+
+	r := bufio.NewReaderSize(file, 64*1024)
+	var last *time.Time
+	for {
+		if line%10000 == 0 && ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		text, n, err := readLogLine(r)
+		if n == 0 {
+			if err != nil && err != io.EOF {
+				return nil, fmt.Errorf("read log: %w", err)
+			}
+			return nil, nil
+		}
+		offset += n
+		line++
+
+		if t, ok := lineTime(text); ok {
+			last = &t
+		}
+		if last != nil && (last.Before(q.Since) || (!q.Until.IsZero() && last.After(q.Until))) {
+			continue
+		}
+		if !match(text) {
+			continue
+		}
+		if len(text) > maxLogMatchBytes {
+			text = text[:maxLogMatchBytes]
+		}
+		res.Matches = append(res.Matches, LogMatch{
+			File: filepath.Base(f.path),
+			Line: line,
+			Time: last,
+			Text: string(text),
+		})
+		if len(res.Matches) == limit {
+			sum, _ := logChecksum(f.path, offset)
+			return &logCursor{File: f.id, Offset: offset, Line: line, Check: sum}, nil
+		}
+	}
+}
+
+// readLogLine reads a line without its line ending, returning it and the
+// bytes consumed. Lines longer than the reader's buffer are cut; the rest
+// is skipped.
+func readLogLine(r *bufio.Reader) ([]byte, int64, error) {
+	line, err := r.ReadSlice('\n')
+	n := int64(len(line))
+	text := line
+	if err == bufio.ErrBufferFull {
+		text = bytes.Clone(line)
+		for err == bufio.ErrBufferFull {
+			line, err = r.ReadSlice('\n')
+			n += int64(len(line))
+		}
+	}
+	text = bytes.TrimRight(text, "\r\n")
+	return text, n, err
+}
+
+// lineTime parses the RFC 3339 timestamp a log line starts with, like the
+// lines of the hypeman log
+func lineTime(line []byte) (time.Time, bool) {
+	token, _, _ := bytes.Cut(line, []byte(" "))
+	if len(token) < len("2006-01-02T15:04:05Z") || token[4] != '-' || token[10] != 'T' {
+		return time.Time{}, false
+	}
+	t, err := time.Parse(time.RFC3339Nano, string(token))
+	return t, err == nil
+}
@@ -0,0 +1,114 @@
+package instances
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeSearchLog(t *testing.T, path, content string, modTime time.Time) {
+	t.Helper()
+	require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+	require.NoError(t, os.Chtimes(path, modTime, modTime))
+}
+
+func matchTexts(res *LogSearchResult) []string {
+	var texts []string
+	for _, m := range res.Matches {
+		texts = append(texts, m.File+":"+m.Text)
+	}
+	return texts
+}
+
+func TestSearchLogs(t *testing.T) {
+	dir := t.TempDir()
+	logPath := filepath.Join(dir, "app.log")
+	now := time.Now()
+	writeSearchLog(t, logPath+".2", "boot\nerror: disk\nok\n", now.Add(-2*time.Hour))
+	writeSearchLog(t, logPath+".1", "error: net\n", now.Add(-time.Hour))
+	writeSearchLog(t, logPath, "ok\nerror: oom\r\n", now)
+	writeSearchLog(t, filepath.Join(dir, "vmm.log"), "error: vmm\n", now)
+	ctx := context.Background()
+
+	// Oldest first, across rotated copies, from the log's files only
+	res, err := searchLogs(ctx, logPath, LogSearchQuery{Query: "error"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"app.log.2:error: disk", "app.log.1:error: net", "app.log:error: oom"}, matchTexts(res))
+	assert.Equal(t, 2, res.Matches[0].Line)
+	assert.Empty(t, res.NextCursor)
+
+	res, err = searchLogs(ctx, logPath, LogSearchQuery{Query: `error: (disk|oom)$`, Regex: true})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"app.log.2:error: disk", "app.log:error: oom"}, matchTexts(res))
+
+	// Pages continue where the previous one stopped
+	res, err = searchLogs(ctx, logPath, LogSearchQuery{Query: "error", Limit: 2})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"app.log.2:error: disk", "app.log.1:error: net"}, matchTexts(res))
+	require.NotEmpty(t, res.NextCursor)
+	res, err = searchLogs(ctx, logPath, LogSearchQuery{Query: "error", Limit: 2, Cursor: res.NextCursor})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"app.log:error: oom"}, matchTexts(res))
+
+	// Files written before since are skipped
+	res, err = searchLogs(ctx, logPath, LogSearchQuery{Query: "error", Since: now.Add(-90 * time.Minute)})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"app.log.1:error: net", "app.log:error: oom"}, matchTexts(res))
+
+	_, err = searchLogs(ctx, logPath, LogSearchQuery{Query: "(", Regex: true})
+	assert.ErrorIs(t, err, ErrInvalidLogSearch)
+	_, err = searchLogs(ctx, logPath, LogSearchQuery{Query: "x", Cursor: "!"})
+	assert.ErrorIs(t, err, ErrInvalidLogSearch)
+	_, err = searchLogs(ctx, filepath.Join(dir, "missing.log"), LogSearchQuery{Query: "x"})
+	assert.ErrorIs(t, err, ErrLogNotFound)
+}
+
+func TestSearchLogs_RotationBetweenPages(t *testing.T) {
+	dir := t.TempDir()
+	logPath := filepath.Join(dir, "app.log")
+	writeSearchLog(t, logPath, "match 1\nmatch 2\nmatch 3\n", time.Now())
+	ctx := context.Background()
+
+	res, err := searchLogs(ctx, logPath, LogSearchQuery{Query: "match", Limit: 1})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"app.log:match 1"}, matchTexts(res))
+
+	rotated, err := rotateLogIfNeeded(logPath, 1, 3)
+	require.NoError(t, err)
+	require.True(t, rotated)
+	f, err := os.OpenFile(logPath, os.O_APPEND|os.O_WRONLY, 0)
+	require.NoError(t, err)
+	f.WriteString("match 4\n")
+	f.Close()
+
+	// The rest of the page's file is found in its rotated copy
+	res, err = searchLogs(ctx, logPath, LogSearchQuery{Query: "match", Cursor: res.NextCursor})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"app.log.1:match 2", "app.log.1:match 3", "app.log:match 4"}, matchTexts(res))
+	assert.Equal(t, 2, res.Matches[0].Line)
+}
+
+func TestSearchLogs_LineTimes(t *testing.T) {
+	dir := t.TempDir()
+	logPath := filepath.Join(dir, "hypeman.log")
+	writeSearchLog(t, logPath, "2026-10-17T10:00:00Z INFO starting\n"+
+		"2026-10-17T11:00:00Z ERROR failed\n"+
+		"  stack trace\n"+
+		"2026-10-17T12:00:00Z ERROR failed again\n", time.Now())
+
+	res, err := searchLogs(context.Background(), logPath, LogSearchQuery{
+		Query: "a",
+		Since: time.Date(2026, 10, 17, 10, 30, 0, 0, time.UTC),
+		Until: time.Date(2026, 10, 17, 11, 30, 0, 0, time.UTC),
+	})
+	require.NoError(t, err)
+	// Lines without a timestamp take the previous line's
+	assert.Equal(t, []string{"hypeman.log:2026-10-17T11:00:00Z ERROR failed", "hypeman.log:  stack trace"}, matchTexts(res))
+	require.NotNil(t, res.Matches[1].Time)
+	assert.Equal(t, 11, res.Matches[1].Time.Hour())
+}
@@ -4,15 +4,18 @@ import (
 	"context"
 	"fmt"
 	"sync"
+	"time"
 
 	"github.com/kernel/hypeman/lib/devices"
 	"github.com/kernel/hypeman/lib/hypervisor"
 	"github.com/kernel/hypeman/lib/hypervisor/cloudhypervisor"
 	"github.com/kernel/hypeman/lib/hypervisor/qemu"
 	"github.com/kernel/hypeman/lib/images"
+	"github.com/kernel/hypeman/lib/logger"
 	"github.com/kernel/hypeman/lib/network"
 	"github.com/kernel/hypeman/lib/paths"
 	"github.com/kernel/hypeman/lib/resources"
+	"github.com/kernel/hypeman/lib/secrets"
 	"github.com/kernel/hypeman/lib/system"
 	"github.com/kernel/hypeman/lib/volumes"
 	"go.opentelemetry.io/otel/metric"
@@ -22,22 +25,87 @@ import (
 type Manager interface {
 	ListInstances(ctx context.Context) ([]Instance, error)
 	CreateInstance(ctx context.Context, req CreateInstanceRequest) (*Instance, error)
+	// CheckCreateInstance runs CreateInstance's validation, admission and
+	// availability checks without creating anything, returning the instance
+	// as it would be created (without an ID or network address).
+	CheckCreateInstance(ctx context.Context, req CreateInstanceRequest) (*Instance, error)
 	// GetInstance returns an instance by ID, name, or ID prefix.
 	// Lookup order: exact ID match -> exact name match -> ID prefix match.
 	// Returns ErrAmbiguousName if prefix matches multiple instances.
 	GetInstance(ctx context.Context, idOrName string) (*Instance, error)
-	DeleteInstance(ctx context.Context, id string) error
+	// DeleteInstance stops and deletes an instance. A running instance's
+	// application gets a grace period to exit before the VM is killed.
+	DeleteInstance(ctx context.Context, id string, req DeleteInstanceRequest) error
+	// RenameInstance changes an instance's name, moving its DNS record with
+	// it. Returns ErrNameInUse if another instance has the name.
+	RenameInstance(ctx context.Context, id string, name string) (*Instance, error)
 	StandbyInstance(ctx context.Context, id string) (*Instance, error)
 	RestoreInstance(ctx context.Context, id string) (*Instance, error)
 	StopInstance(ctx context.Context, id string) (*Instance, error)
 	StartInstance(ctx context.Context, id string) (*Instance, error)
 	StreamInstanceLogs(ctx context.Context, id string, tail int, follow bool, source LogSource) (<-chan string, error)
-	RotateLogs(ctx context.Context, maxBytes int64, maxFiles int) error
+	// SearchInstanceLogs finds the lines of an instance's log and its
+	// rotated copies containing a substring or matching a regular
+	// expression, oldest first, a page at a time.
+	SearchInstanceLogs(ctx context.Context, id string, q LogSearchQuery) (*LogSearchResult, error)
+	// GetBootDiagnostics reports the milestones of an instance's last boot,
+	// parsed from its serial console log, and any recorded boot failure.
+	GetBootDiagnostics(ctx context.Context, id string) (*BootDiagnostics, error)
+	RotateLogs(ctx context.Context, policy LogRetention) (*LogRotationResult, error)
+	// TrimDisks punches holes in the zeroed blocks of stopped and standby
+	// instances' writable disk files, returning host disk space to the pool.
+	TrimDisks(ctx context.Context) (*DiskTrimResult, error)
+	// SyncClocks sets running instances' guest clocks to the host's time.
+	SyncClocks(ctx context.Context) (*ClockSyncResult, error)
 	AttachVolume(ctx context.Context, id string, volumeId string, req AttachVolumeRequest) (*Instance, error)
 	DetachVolume(ctx context.Context, id string, volumeId string) (*Instance, error)
+	// AttachUSBDevice passes a host USB device (vendor:product or bus-port)
+	// through to an instance, hot-plugging it if the instance is running.
+	AttachUSBDevice(ctx context.Context, id string, selector string) (*Instance, error)
+	// DetachUSBDevice removes a USB device from an instance, hot-unplugging
+	// it if the instance is running.
+	DetachUSBDevice(ctx context.Context, id string, selector string) (*Instance, error)
+	// UpdateNetworkBandwidth changes an instance's bandwidth limits, applying
+	// them immediately if it is running.
+	UpdateNetworkBandwidth(ctx context.Context, id string, req UpdateNetworkBandwidthRequest) (*Instance, error)
 	// ListInstanceAllocations returns resource allocations for all instances.
 	// Used by the resource manager for capacity tracking.
 	ListInstanceAllocations(ctx context.Context) ([]resources.InstanceAllocation, error)
+	// ReconcileInstances reconciles on-disk instance state with running
+	// hypervisor processes. Called once on startup, before serving requests.
+	ReconcileInstances(ctx context.Context) (*ReconcileSummary, error)
+	// GetGuestAgentInfo reports the guest-agent running in an instance.
+	GetGuestAgentInfo(ctx context.Context, id string) (*GuestAgentInfo, error)
+	// UpdateGuestAgent pushes the host's bundled guest-agent to a running
+	// instance and restarts it, without rebooting the VM.
+	UpdateGuestAgent(ctx context.Context, id string) (*GuestAgentInfo, error)
+	// TrackActivity marks an instance active for idle standby purposes until
+	// the returned function is called. Used for exec, cp and similar sessions.
+	TrackActivity(id string) func()
+	// StandbyIdleInstances puts running instances that have been idle longer
+	// than their idle policy allows into standby, returning their IDs.
+	StandbyIdleInstances(ctx context.Context, defaults IdleDefaults) ([]string, error)
+	// SetSchedules replaces an instance's scheduled start, standby and stop
+	// times.
+	SetSchedules(ctx context.Context, id string, schedules []Schedule) (*Instance, error)
+	// RunSchedules fires the instance schedules due in (since, now],
+	// returning the IDs of the instances whose state changed.
+	RunSchedules(ctx context.Context, since, now time.Time) ([]string, error)
+	// CPUTime returns the CPU time a running instance's hypervisor process
+	// has used since it started. Used to autoscale instance groups.
+	CPUTime(ctx context.Context, id string) (time.Duration, error)
+	// ResourceUsage returns the CPU time, peak memory and network traffic of
+	// a running instance's hypervisor process since it started. Used to
+	// report the resources builds used.
+	ResourceUsage(ctx context.Context, id string) (*ResourceUsage, error)
+	// DebugState returns a snapshot of manager internals (held and contended
+	// instance locks, active sessions) for diagnosing hangs.
+	DebugState() DebugState
+	// SetAdmissionLimits replaces the per-instance and aggregate limits
+	// (MaxOverlaySize, MaxVcpusPerInstance, MaxMemoryPerInstance,
+	// MaxTotalVcpus, MaxTotalMemory) new instances are admitted against.
+	// Other fields of limits are ignored.
+	SetAdmissionLimits(limits ResourceLimits)
 }
 
 // ResourceLimits contains configurable resource limits for instances
@@ -47,6 +115,28 @@ type ResourceLimits struct {
 	MaxMemoryPerInstance int64 // Maximum memory in bytes per instance (0 = unlimited)
 	MaxTotalVcpus        int   // Maximum total vCPUs across all instances (0 = unlimited)
 	MaxTotalMemory       int64 // Maximum total memory in bytes across all instances (0 = unlimited)
+
+	// Hypervisor process confinement
+	VMMCgroup         string // cgroup v2 group, relative to /sys/fs/cgroup, holding a cgroup per hypervisor process (empty = disabled)
+	VMMMemoryOverhead int64  // Memory a hypervisor process may use beyond its guest's memory
+	VMMSandbox        bool   // Enable seccomp and Landlock (QEMU: -sandbox) for hypervisor processes
+
+	// Shared directories (virtiofs)
+	SharedDirectoryRoots []string // Host directories instances may share from (empty = shared directories disabled)
+	VirtiofsdBinary      string   // Path to the virtiofsd binary
+
+	// UEFI guests
+	UEFIFirmware     map[hypervisor.Type]string // Firmware per hypervisor booting FirmwareUEFI instances (missing = unsupported)
+	VirtioDriversISO string                     // Attached as a CD-ROM to FirmwareUEFI instances on QEMU (empty = none)
+
+	// Boot watchdog
+	BootTimeout time.Duration // Mark an instance Failed if its guest agent isn't up this long after boot (0 = never)
+
+	// Graceful shutdown
+	ShutdownGracePeriod time.Duration // Time an application gets to exit on stop or delete, unless the instance overrides it (0 = kill immediately)
+
+	// Lifecycle hooks
+	Hooks HookConfig
 }
 
 type manager struct {
@@ -56,10 +146,14 @@ type manager struct {
 	networkManager network.Manager
 	deviceManager  devices.Manager
 	volumeManager  volumes.Manager
+	secretManager  secrets.Manager // nil = secrets can't be attached
 	limits         ResourceLimits
-	instanceLocks  sync.Map      // map[string]*sync.RWMutex - per-instance locks
+	limitsMu       sync.RWMutex  // Guards the admission limits in limits, which can be reloaded
+	cgroupRoot     string        // cgroup v2 mount point, for hypervisor process cgroups
+	instanceLocks  sync.Map      // map[string]*instanceLock - per-instance locks
 	hostTopology   *HostTopology // Cached host CPU topology
 	metrics        *Metrics
+	activity       activityTracker // Last activity per instance, for idle standby
 
 	// Hypervisor support
 	vmStarters        map[hypervisor.Type]hypervisor.VMStarter
@@ -69,7 +163,7 @@ type manager struct {
 // NewManager creates a new instances manager.
 // If meter is nil, metrics are disabled.
 // defaultHypervisor specifies which hypervisor to use when not specified in requests.
-func NewManager(p *paths.Paths, imageManager images.Manager, systemManager system.Manager, networkManager network.Manager, deviceManager devices.Manager, volumeManager volumes.Manager, limits ResourceLimits, defaultHypervisor hypervisor.Type, meter metric.Meter, tracer trace.Tracer) Manager {
+func NewManager(p *paths.Paths, imageManager images.Manager, systemManager system.Manager, networkManager network.Manager, deviceManager devices.Manager, volumeManager volumes.Manager, secretManager secrets.Manager, limits ResourceLimits, defaultHypervisor hypervisor.Type, meter metric.Meter, tracer trace.Tracer) Manager {
 	// Validate and default the hypervisor type
 	if defaultHypervisor == "" {
 		defaultHypervisor = hypervisor.TypeCloudHypervisor
@@ -82,7 +176,9 @@ func NewManager(p *paths.Paths, imageManager images.Manager, systemManager syste
 		networkManager: networkManager,
 		deviceManager:  deviceManager,
 		volumeManager:  volumeManager,
+		secretManager:  secretManager,
 		limits:         limits,
+		cgroupRoot:     cgroupRoot,
 		instanceLocks:  sync.Map{},
 		hostTopology:   detectHostTopology(), // Detect and cache host topology
 		vmStarters: map[hypervisor.Type]hypervisor.VMStarter{
@@ -126,9 +222,9 @@ func (m *manager) getVMStarter(hvType hypervisor.Type) (hypervisor.VMStarter, er
 }
 
 // getInstanceLock returns or creates a lock for a specific instance
-func (m *manager) getInstanceLock(id string) *sync.RWMutex {
-	lock, _ := m.instanceLocks.LoadOrStore(id, &sync.RWMutex{})
-	return lock.(*sync.RWMutex)
+func (m *manager) getInstanceLock(id string) *instanceLock {
+	lock, _ := m.instanceLocks.LoadOrStore(id, &instanceLock{})
+	return lock.(*instanceLock)
 }
 
 // CreateInstance creates and starts a new instance
@@ -142,12 +238,12 @@ func (m *manager) CreateInstance(ctx context.Context, req CreateInstanceRequest)
 }
 
 // DeleteInstance stops and deletes an instance
-func (m *manager) DeleteInstance(ctx context.Context, id string) error {
+func (m *manager) DeleteInstance(ctx context.Context, id string, req DeleteInstanceRequest) error {
 	lock := m.getInstanceLock(id)
-	lock.Lock()
-	defer lock.Unlock()
+	lock.acquire("delete_instance")
+	defer lock.release()
 
-	err := m.deleteInstance(ctx, id)
+	err := m.deleteInstance(ctx, id, req)
 	if err == nil {
 		// Clean up the lock after successful deletion
 		m.instanceLocks.Delete(id)
@@ -155,35 +251,43 @@ func (m *manager) DeleteInstance(ctx context.Context, id string) error {
 	return err
 }
 
+// RenameInstance changes an instance's name
+func (m *manager) RenameInstance(ctx context.Context, id string, name string) (*Instance, error) {
+	lock := m.getInstanceLock(id)
+	lock.acquire("rename_instance")
+	defer lock.release()
+	return m.renameInstance(ctx, id, name)
+}
+
 // StandbyInstance puts an instance in standby (pause, snapshot, delete VMM)
 func (m *manager) StandbyInstance(ctx context.Context, id string) (*Instance, error) {
 	lock := m.getInstanceLock(id)
-	lock.Lock()
-	defer lock.Unlock()
+	lock.acquire("standby_instance")
+	defer lock.release()
 	return m.standbyInstance(ctx, id)
 }
 
 // RestoreInstance restores an instance from standby
 func (m *manager) RestoreInstance(ctx context.Context, id string) (*Instance, error) {
 	lock := m.getInstanceLock(id)
-	lock.Lock()
-	defer lock.Unlock()
+	lock.acquire("restore_instance")
+	defer lock.release()
 	return m.restoreInstance(ctx, id)
 }
 
 // StopInstance gracefully stops a running instance
 func (m *manager) StopInstance(ctx context.Context, id string) (*Instance, error) {
 	lock := m.getInstanceLock(id)
-	lock.Lock()
-	defer lock.Unlock()
+	lock.acquire("stop_instance")
+	defer lock.release()
 	return m.stopInstance(ctx, id)
 }
 
 // StartInstance starts a stopped instance
 func (m *manager) StartInstance(ctx context.Context, id string) (*Instance, error) {
 	lock := m.getInstanceLock(id)
-	lock.Lock()
-	defer lock.Unlock()
+	lock.acquire("start_instance")
+	defer lock.release()
 	return m.startInstance(ctx, id)
 }
 
@@ -252,13 +356,23 @@ func (m *manager) StreamInstanceLogs(ctx context.Context, id string, tail int, f
 	return m.streamInstanceLogs(ctx, id, tail, follow, source)
 }
 
-// RotateLogs rotates all instance logs (app, vmm, hypeman) that exceed maxBytes
-func (m *manager) RotateLogs(ctx context.Context, maxBytes int64, maxFiles int) error {
+// SearchInstanceLogs searches an instance's log and its rotated copies
+func (m *manager) SearchInstanceLogs(ctx context.Context, id string, q LogSearchQuery) (*LogSearchResult, error) {
+	// Like streaming, no lock is held: logs are append-only, and a cursor
+	// survives rotation between pages
+	return m.searchInstanceLogs(ctx, id, q)
+}
+
+// RotateLogs rotates all instance logs (app, vmm, hypeman) that exceed
+// policy.MaxBytes, then removes rotated copies by age and total size
+func (m *manager) RotateLogs(ctx context.Context, policy LogRetention) (*LogRotationResult, error) {
 	instances, err := m.listInstances(ctx)
 	if err != nil {
-		return fmt.Errorf("list instances for rotation: %w", err)
+		return nil, fmt.Errorf("list instances for rotation: %w", err)
 	}
 
+	res := &LogRotationResult{}
+	var files []logFile
 	var lastErr error
 	for _, inst := range instances {
 		// Rotate all three log types
@@ -268,12 +382,67 @@ func (m *manager) RotateLogs(ctx context.Context, maxBytes int64, maxFiles int)
 			m.paths.InstanceHypemanLog(inst.Id),
 		}
 		for _, logPath := range logPaths {
-			if err := rotateLogIfNeeded(logPath, maxBytes, maxFiles); err != nil {
+			rotated, err := rotateLogIfNeeded(logPath, policy.MaxBytes, policy.MaxFiles)
+			if err != nil {
 				lastErr = err // Continue with other logs, but track error
+			} else if rotated {
+				res.Rotated++
 			}
 		}
+
+		instFiles, err := listLogFiles(m.paths.InstanceLogs(inst.Id))
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		files = append(files, instFiles...)
+	}
+
+	if err := pruneLogs(files, policy, time.Now(), res); err != nil {
+		lastErr = err
 	}
-	return lastErr
+	if policy.MaxTotalBytes > 0 && res.TotalBytes > policy.MaxTotalBytes {
+		logger.FromContext(ctx).WarnContext(ctx, "instance logs exceed the total size cap with no rotated copies left to remove",
+			"total_bytes", res.TotalBytes, "max_total_bytes", policy.MaxTotalBytes)
+	}
+	return res, lastErr
+}
+
+// UpdateNetworkBandwidth changes an instance's bandwidth limits
+func (m *manager) UpdateNetworkBandwidth(ctx context.Context, id string, req UpdateNetworkBandwidthRequest) (*Instance, error) {
+	lock := m.getInstanceLock(id)
+	lock.acquire("update_network_bandwidth")
+	defer lock.release()
+	return m.updateNetworkBandwidth(ctx, id, req)
+}
+
+// SetSchedules replaces an instance's schedules
+func (m *manager) SetSchedules(ctx context.Context, id string, schedules []Schedule) (*Instance, error) {
+	lock := m.getInstanceLock(id)
+	lock.acquire("set_schedules")
+	defer lock.release()
+	return m.setSchedules(ctx, id, schedules)
+}
+
+// GetBootDiagnostics reports the milestones of an instance's last boot
+func (m *manager) GetBootDiagnostics(ctx context.Context, id string) (*BootDiagnostics, error) {
+	lock := m.getInstanceLock(id)
+	lock.RLock()
+	defer lock.RUnlock()
+	return m.getBootDiagnostics(ctx, id)
+}
+
+// GetGuestAgentInfo reports the guest-agent running in an instance
+func (m *manager) GetGuestAgentInfo(ctx context.Context, id string) (*GuestAgentInfo, error) {
+	return m.getGuestAgentInfo(ctx, id)
+}
+
+// UpdateGuestAgent pushes the bundled guest-agent to a running instance
+func (m *manager) UpdateGuestAgent(ctx context.Context, id string) (*GuestAgentInfo, error) {
+	lock := m.getInstanceLock(id)
+	lock.acquire("update_guest_agent")
+	defer lock.release()
+	return m.updateGuestAgent(ctx, id)
 }
 
 // AttachVolume attaches a volume to an instance (not yet implemented)
@@ -286,6 +455,22 @@ func (m *manager) DetachVolume(ctx context.Context, id string, volumeId string)
 	return nil, fmt.Errorf("detach volume not yet implemented")
 }
 
+// AttachUSBDevice passes a host USB device through to an instance
+func (m *manager) AttachUSBDevice(ctx context.Context, id string, selector string) (*Instance, error) {
+	lock := m.getInstanceLock(id)
+	lock.acquire("attach_usb_device")
+	defer lock.release()
+	return m.attachUSBDevice(ctx, id, selector)
+}
+
+// DetachUSBDevice removes a USB device from an instance
+func (m *manager) DetachUSBDevice(ctx context.Context, id string, selector string) (*Instance, error) {
+	lock := m.getInstanceLock(id)
+	lock.acquire("detach_usb_device")
+	defer lock.release()
+	return m.detachUSBDevice(ctx, id, selector)
+}
+
 // ListInstanceAllocations returns resource allocations for all instances.
 // Used by the resource manager for capacity tracking.
 func (m *manager) ListInstanceAllocations(ctx context.Context) ([]resources.InstanceAllocation, error) {
@@ -311,6 +496,11 @@ func (m *manager) ListInstanceAllocations(ctx context.Context) ([]resources.Inst
 				volumeOverlayBytes += vol.OverlaySize
 			}
 		}
+		if inst.RootVolume != "" && m.volumeManager != nil {
+			if volume, err := m.volumeManager.GetVolume(ctx, inst.RootVolume); err == nil {
+				volumeBytes += int64(volume.SizeGb) * 1024 * 1024 * 1024
+			}
+		}
 
 		allocations = append(allocations, resources.InstanceAllocation{
 			ID:                 inst.Id,
@@ -319,6 +509,7 @@ func (m *manager) ListInstanceAllocations(ctx context.Context) ([]resources.Inst
 			MemoryBytes:        inst.Size + inst.HotplugSize,
 			OverlayBytes:       inst.OverlaySize,
 			VolumeOverlayBytes: volumeOverlayBytes,
+			ScratchBytes:       scratchBytes(inst.ScratchDisks) + inst.SwapSize,
 			NetworkDownloadBps: inst.NetworkBandwidthDownload,
 			NetworkUploadBps:   inst.NetworkBandwidthUpload,
 			State:              string(inst.State),
@@ -43,13 +43,13 @@ func setupTestManager(t *testing.T) (*manager, string) {
 	}
 
 	p := paths.New(tmpDir)
-	imageManager, err := images.NewManager(p, 1, nil)
+	imageManager, err := images.NewManager(p, 1, nil, nil)
 	require.NoError(t, err)
 
 	systemManager := system.NewManager(p)
 	networkManager := network.NewManager(p, cfg, nil)
 	deviceManager := devices.NewManager(p)
-	volumeManager := volumes.NewManager(p, 0, nil) // 0 = unlimited storage
+	volumeManager := volumes.NewManager(p, 0, nil, nil) // 0 = unlimited storage
 	limits := ResourceLimits{
 		MaxOverlaySize:       100 * 1024 * 1024 * 1024, // 100GB
 		MaxVcpusPerInstance:  0,                        // unlimited
@@ -57,7 +57,7 @@ func setupTestManager(t *testing.T) (*manager, string) {
 		MaxTotalVcpus:        0,                        // unlimited
 		MaxTotalMemory:       0,                        // unlimited
 	}
-	mgr := NewManager(p, imageManager, systemManager, networkManager, deviceManager, volumeManager, limits, "", nil, nil).(*manager)
+	mgr := NewManager(p, imageManager, systemManager, networkManager, deviceManager, volumeManager, nil, limits, "", nil, nil).(*manager)
 
 	// Register cleanup to kill any orphaned Cloud Hypervisor processes
 	t.Cleanup(func() {
@@ -183,7 +183,7 @@ func TestBasicEndToEnd(t *testing.T) {
 	ctx := context.Background()
 
 	// Get the image manager from the manager (we need it for image operations)
-	imageManager, err := images.NewManager(paths.New(tmpDir), 1, nil)
+	imageManager, err := images.NewManager(paths.New(tmpDir), 1, nil, nil)
 	require.NoError(t, err)
 
 	// Pull nginx image (runs a daemon, won't exit)
@@ -219,7 +219,7 @@ func TestBasicEndToEnd(t *testing.T) {
 
 	// Create a volume to attach
 	p := paths.New(tmpDir)
-	volumeManager := volumes.NewManager(p, 0, nil) // 0 = unlimited storage
+	volumeManager := volumes.NewManager(p, 0, nil, nil) // 0 = unlimited storage
 	t.Log("Creating volume...")
 	vol, err := volumeManager.CreateVolume(ctx, volumes.CreateVolumeRequest{
 		Name:   "test-data",
@@ -719,7 +719,7 @@ func TestBasicEndToEnd(t *testing.T) {
 
 	// Delete instance
 	t.Log("Deleting instance...")
-	err = manager.DeleteInstance(ctx, inst.Id)
+	err = manager.DeleteInstance(ctx, inst.Id, DeleteInstanceRequest{})
 	require.NoError(t, err)
 
 	// Verify cleanup
@@ -759,11 +759,11 @@ func TestStorageOperations(t *testing.T) {
 	}
 
 	p := paths.New(tmpDir)
-	imageManager, _ := images.NewManager(p, 1, nil)
+	imageManager, _ := images.NewManager(p, 1, nil, nil)
 	systemManager := system.NewManager(p)
 	networkManager := network.NewManager(p, cfg, nil)
 	deviceManager := devices.NewManager(p)
-	volumeManager := volumes.NewManager(p, 0, nil) // 0 = unlimited storage
+	volumeManager := volumes.NewManager(p, 0, nil, nil) // 0 = unlimited storage
 	limits := ResourceLimits{
 		MaxOverlaySize:       100 * 1024 * 1024 * 1024, // 100GB
 		MaxVcpusPerInstance:  0,                        // unlimited
@@ -771,7 +771,7 @@ func TestStorageOperations(t *testing.T) {
 		MaxTotalVcpus:        0,                        // unlimited
 		MaxTotalMemory:       0,                        // unlimited
 	}
-	manager := NewManager(p, imageManager, systemManager, networkManager, deviceManager, volumeManager, limits, "", nil, nil).(*manager)
+	manager := NewManager(p, imageManager, systemManager, networkManager, deviceManager, volumeManager, nil, limits, "", nil, nil).(*manager)
 
 	// Test metadata doesn't exist initially
 	_, err := manager.loadMetadata("nonexistent")
@@ -834,7 +834,7 @@ func TestStandbyAndRestore(t *testing.T) {
 	ctx := context.Background()
 
 	// Create image manager for pulling nginx
-	imageManager, err := images.NewManager(paths.New(tmpDir), 1, nil)
+	imageManager, err := images.NewManager(paths.New(tmpDir), 1, nil, nil)
 	require.NoError(t, err)
 
 	// Pull nginx image (reuse if already pulled in previous test)
@@ -936,7 +936,7 @@ func TestStandbyAndRestore(t *testing.T) {
 
 	// Cleanup (no sleep needed - DeleteInstance handles process cleanup)
 	t.Log("Cleaning up...")
-	err = manager.DeleteInstance(ctx, inst.Id)
+	err = manager.DeleteInstance(ctx, inst.Id, DeleteInstanceRequest{})
 	require.NoError(t, err)
 
 	t.Log("Standby/restore test complete!")
@@ -990,6 +990,10 @@ func (r *testInstanceResolver) ResolveInstanceIP(ctx context.Context, nameOrID s
 	return r.ip, nil
 }
 
+func (r *testInstanceResolver) WakeInstance(ctx context.Context, nameOrID string, port int) (string, error) {
+	return r.ResolveInstanceIP(ctx, nameOrID)
+}
+
 func (r *testInstanceResolver) InstanceExists(ctx context.Context, nameOrID string) (bool, error) {
 	return r.exists, nil
 }
@@ -7,6 +7,7 @@ import (
 	"github.com/kernel/hypeman/lib/hypervisor"
 	mw "github.com/kernel/hypeman/lib/middleware"
 	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
 	"go.opentelemetry.io/otel/metric"
 	"go.opentelemetry.io/otel/trace"
 )
@@ -19,6 +20,7 @@ type Metrics struct {
 	stopDuration     metric.Float64Histogram
 	startDuration    metric.Float64Histogram
 	stateTransitions metric.Int64Counter
+	clockSkew        metric.Float64Histogram
 	tracer           trace.Tracer
 }
 
@@ -77,6 +79,15 @@ func newInstanceMetrics(meter metric.Meter, tracer trace.Tracer, m *manager) (*M
 		return nil, err
 	}
 
+	clockSkew, err := meter.Float64Histogram(
+		"hypeman_instances_clock_skew_seconds",
+		metric.WithDescription("Guest clock skew corrected by a clock sync, in either direction"),
+		metric.WithUnit("s"),
+	)
+	if err != nil {
+		return nil, err
+	}
+
 	// Register observable gauge for instance counts by state
 	instancesTotal, err := meter.Int64ObservableGauge(
 		"hypeman_instances_total",
@@ -127,6 +138,7 @@ func newInstanceMetrics(meter metric.Meter, tracer trace.Tracer, m *manager) (*M
 		stopDuration:     stopDuration,
 		startDuration:    startDuration,
 		stateTransitions: stateTransitions,
+		clockSkew:        clockSkew,
 		tracer:           tracer,
 	}, nil
 }
@@ -169,3 +181,21 @@ func (m *manager) recordStateTransition(ctx context.Context, fromState, toState
 	}
 	m.metrics.stateTransitions.Add(ctx, 1, metric.WithAttributes(attrs...))
 }
+
+// startSpan starts a tracing span if a tracer is available. The returned
+// function ends the span, marking it failed if err is non-nil. Steps of an
+// operation should start their spans from the operation's context, not from
+// each other's, so they show up as siblings.
+func (m *manager) startSpan(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, func(err error)) {
+	if m.metrics == nil || m.metrics.tracer == nil {
+		return ctx, func(error) {}
+	}
+	ctx, span := m.metrics.tracer.Start(ctx, name, trace.WithAttributes(attrs...))
+	return ctx, func(err error) {
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+	}
+}
@@ -0,0 +1,47 @@
+package instances
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestStartSpan(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	m := &manager{metrics: &Metrics{tracer: tp.Tracer("test")}}
+
+	ctx, endSpan := m.startSpan(context.Background(), "CreateInstance")
+	_, endOK := m.startSpan(ctx, "AllocateNetwork")
+	endOK(nil)
+	_, endFailed := m.startSpan(ctx, "BootVM")
+	endFailed(errors.New("boot failed"))
+	endSpan(nil)
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 3)
+	parent := spans[2]
+	assert.Equal(t, "CreateInstance", parent.Name())
+
+	// Steps are siblings under the operation span
+	for _, s := range spans[:2] {
+		assert.Equal(t, parent.SpanContext().SpanID(), s.Parent().SpanID(), s.Name())
+	}
+	assert.Equal(t, codes.Unset, spans[0].Status().Code)
+	assert.Equal(t, codes.Error, spans[1].Status().Code)
+	assert.Equal(t, "boot failed", spans[1].Status().Description)
+}
+
+func TestStartSpan_NoTracer(t *testing.T) {
+	m := &manager{}
+	ctx := context.Background()
+	spanCtx, end := m.startSpan(ctx, "CreateInstance")
+	assert.Equal(t, ctx, spanCtx)
+	end(errors.New("ignored"))
+}
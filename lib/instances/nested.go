@@ -0,0 +1,41 @@
+package instances
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/kernel/hypeman/lib/hypervisor"
+)
+
+// kvmNestedParams are the KVM module parameters reporting whether the host
+// lets guests use hardware virtualization themselves (Intel VMX, AMD SVM)
+var kvmNestedParams = []string{
+	"/sys/module/kvm_intel/parameters/nested",
+	"/sys/module/kvm_amd/parameters/nested",
+}
+
+// checkNestedVirt returns an error unless the host's KVM module has nested
+// virtualization enabled. Only then can VMX/SVM be exposed to guests; other
+// instances have them masked (see hypervisor.VMConfig.NestedVirt).
+func checkNestedVirt() error {
+	for _, path := range kvmNestedParams {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		switch strings.TrimSpace(string(data)) {
+		case "Y", "1":
+			return nil
+		}
+		return fmt.Errorf("%w: nested virtualization is disabled on this host (%s)", ErrNestedVirtUnsupported, path)
+	}
+	return fmt.Errorf("%w: no KVM module with nested virtualization support is loaded", ErrNestedVirtUnsupported)
+}
+
+// nestedBlocksStandby reports whether an instance can't be put in standby
+// because of nested virtualization: Cloud Hypervisor snapshots don't include
+// the vCPUs' nested state, so nested guests wouldn't survive a restore.
+func nestedBlocksStandby(stored *StoredMetadata) bool {
+	return stored.NestedVirt && stored.HypervisorType == hypervisor.TypeCloudHypervisor
+}
@@ -0,0 +1,38 @@
+package instances
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/kernel/hypeman/lib/hypervisor"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckNestedVirt(t *testing.T) {
+	dir := t.TempDir()
+	intel := filepath.Join(dir, "kvm_intel")
+	amd := filepath.Join(dir, "kvm_amd")
+	orig := kvmNestedParams
+	kvmNestedParams = []string{intel, amd}
+	t.Cleanup(func() { kvmNestedParams = orig })
+
+	assert.ErrorIs(t, checkNestedVirt(), ErrNestedVirtUnsupported, "no KVM module loaded")
+
+	require.NoError(t, os.WriteFile(intel, []byte("N\n"), 0644))
+	assert.ErrorIs(t, checkNestedVirt(), ErrNestedVirtUnsupported, "nesting disabled")
+
+	require.NoError(t, os.WriteFile(intel, []byte("Y\n"), 0644))
+	assert.NoError(t, checkNestedVirt())
+
+	require.NoError(t, os.Remove(intel))
+	require.NoError(t, os.WriteFile(amd, []byte("1\n"), 0644))
+	assert.NoError(t, checkNestedVirt())
+}
+
+func TestNestedBlocksStandby(t *testing.T) {
+	assert.True(t, nestedBlocksStandby(&StoredMetadata{NestedVirt: true, HypervisorType: hypervisor.TypeCloudHypervisor}))
+	assert.False(t, nestedBlocksStandby(&StoredMetadata{NestedVirt: true, HypervisorType: hypervisor.TypeQEMU}))
+	assert.False(t, nestedBlocksStandby(&StoredMetadata{HypervisorType: hypervisor.TypeCloudHypervisor}))
+}
@@ -205,7 +205,7 @@ func TestCreateInstanceWithNetwork(t *testing.T) {
 
 	// Cleanup
 	t.Log("Cleaning up instance...")
-	err = manager.DeleteInstance(ctx, inst.Id)
+	err = manager.DeleteInstance(ctx, inst.Id, DeleteInstanceRequest{})
 	require.NoError(t, err)
 
 	// Verify TAP deleted after instance cleanup
@@ -0,0 +1,94 @@
+package instances
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/kernel/hypeman/lib/devices"
+)
+
+// resolvePlacement validates a create request's placement hints. It returns
+// the hints to store, with affinity instances resolved to IDs, and the VF
+// placement the vGPU is created with.
+func (m *manager) resolvePlacement(ctx context.Context, req CreateInstanceRequest) (*PlacementHints, devices.VFPlacement, error) {
+	p := req.Placement
+	if p == nil {
+		return nil, devices.VFPlacement{}, nil
+	}
+
+	switch p.GPUPolicy {
+	case "", devices.GPUPlacementPack, devices.GPUPlacementSpread:
+	default:
+		return nil, devices.VFPlacement{}, fmt.Errorf("%w: gpu policy must be %q or %q, got %q", ErrInvalidPlacement, devices.GPUPlacementPack, devices.GPUPlacementSpread, p.GPUPolicy)
+	}
+	hasGPUHints := p.GPUPolicy != "" || len(p.GPUAffinity) > 0 || len(p.GPUAntiAffinity) > 0
+	if hasGPUHints && (req.GPU == nil || req.GPU.Profile == "") {
+		return nil, devices.VFPlacement{}, fmt.Errorf("%w: gpu hints require a vGPU profile", ErrInvalidPlacement)
+	}
+	if p.NUMANode != nil {
+		if _, err := devices.NUMANodeCPUs(*p.NUMANode); err != nil {
+			if errors.Is(err, devices.ErrNUMANodeNotFound) {
+				return nil, devices.VFPlacement{}, fmt.Errorf("%w: %v", ErrInvalidPlacement, err)
+			}
+			return nil, devices.VFPlacement{}, err
+		}
+	}
+
+	affinity, nearMdevs, err := m.resolveGPUPeers(ctx, p.GPUAffinity)
+	if err != nil {
+		return nil, devices.VFPlacement{}, err
+	}
+	antiAffinity, awayMdevs, err := m.resolveGPUPeers(ctx, p.GPUAntiAffinity)
+	if err != nil {
+		return nil, devices.VFPlacement{}, err
+	}
+
+	hints := &PlacementHints{
+		GPUPolicy:       p.GPUPolicy,
+		GPUAffinity:     affinity,
+		GPUAntiAffinity: antiAffinity,
+		NUMANode:        p.NUMANode,
+	}
+	return hints, devices.VFPlacement{
+		Policy:        p.GPUPolicy,
+		NearMdevs:     nearMdevs,
+		AwayFromMdevs: awayMdevs,
+		NUMANode:      p.NUMANode,
+	}, nil
+}
+
+// resolveGPUPeers resolves the instances named in a GPU affinity hint to
+// their IDs and the mdevs of their vGPUs. Each must exist and have a vGPU.
+func (m *manager) resolveGPUPeers(ctx context.Context, refs []string) ([]string, []string, error) {
+	var ids, mdevs []string
+	seen := make(map[string]bool, len(refs))
+	for _, ref := range refs {
+		inst, err := m.GetInstance(ctx, ref)
+		if err != nil {
+			if errors.Is(err, ErrNotFound) || errors.Is(err, ErrAmbiguousName) {
+				return nil, nil, fmt.Errorf("%w: instance %s: %v", ErrInvalidPlacement, ref, err)
+			}
+			return nil, nil, fmt.Errorf("get instance %s: %w", ref, err)
+		}
+		if inst.GPUMdevUUID == "" {
+			return nil, nil, fmt.Errorf("%w: instance %s has no vGPU", ErrInvalidPlacement, ref)
+		}
+		if seen[inst.Id] {
+			continue
+		}
+		seen[inst.Id] = true
+		ids = append(ids, inst.Id)
+		mdevs = append(mdevs, inst.GPUMdevUUID)
+	}
+	return ids, mdevs, nil
+}
+
+// placementHostCPUs returns the host CPUs an instance's vCPUs are pinned
+// to, or nil if it has no NUMA node
+func placementHostCPUs(inst *Instance) ([]int, error) {
+	if inst.Placement == nil || inst.Placement.NUMANode == nil {
+		return nil, nil
+	}
+	return devices.NUMANodeCPUs(*inst.Placement.NUMANode)
+}
@@ -0,0 +1,36 @@
+package instances
+
+import (
+	"context"
+	"testing"
+
+	"github.com/kernel/hypeman/lib/devices"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolvePlacement(t *testing.T) {
+	m := &manager{}
+	ctx := context.Background()
+	gpu := &GPUConfig{Profile: "L40S-1Q"}
+
+	hints, vf, err := m.resolvePlacement(ctx, CreateInstanceRequest{})
+	require.NoError(t, err)
+	assert.Nil(t, hints)
+	assert.Equal(t, devices.VFPlacement{}, vf)
+
+	hints, vf, err = m.resolvePlacement(ctx, CreateInstanceRequest{GPU: gpu, Placement: &PlacementHints{GPUPolicy: devices.GPUPlacementSpread}})
+	require.NoError(t, err)
+	assert.Equal(t, devices.GPUPlacementSpread, hints.GPUPolicy)
+	assert.Equal(t, devices.GPUPlacementSpread, vf.Policy)
+
+	_, _, err = m.resolvePlacement(ctx, CreateInstanceRequest{GPU: gpu, Placement: &PlacementHints{GPUPolicy: "scatter"}})
+	assert.ErrorIs(t, err, ErrInvalidPlacement)
+
+	_, _, err = m.resolvePlacement(ctx, CreateInstanceRequest{Placement: &PlacementHints{GPUPolicy: devices.GPUPlacementPack}})
+	assert.ErrorIs(t, err, ErrInvalidPlacement, "gpu hints require a vGPU")
+
+	node := -1
+	_, _, err = m.resolvePlacement(ctx, CreateInstanceRequest{Placement: &PlacementHints{NUMANode: &node}})
+	assert.ErrorIs(t, err, ErrInvalidPlacement)
+}
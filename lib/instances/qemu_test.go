@@ -41,13 +41,13 @@ func setupTestManagerForQEMU(t *testing.T) (*manager, string) {
 	}
 
 	p := paths.New(tmpDir)
-	imageManager, err := images.NewManager(p, 1, nil)
+	imageManager, err := images.NewManager(p, 1, nil, nil)
 	require.NoError(t, err)
 
 	systemManager := system.NewManager(p)
 	networkManager := network.NewManager(p, cfg, nil)
 	deviceManager := devices.NewManager(p)
-	volumeManager := volumes.NewManager(p, 0, nil) // 0 = unlimited storage
+	volumeManager := volumes.NewManager(p, 0, nil, nil) // 0 = unlimited storage
 	limits := ResourceLimits{
 		MaxOverlaySize:       100 * 1024 * 1024 * 1024, // 100GB
 		MaxVcpusPerInstance:  0,                        // unlimited
@@ -55,7 +55,7 @@ func setupTestManagerForQEMU(t *testing.T) (*manager, string) {
 		MaxTotalVcpus:        0,                        // unlimited
 		MaxTotalMemory:       0,                        // unlimited
 	}
-	mgr := NewManager(p, imageManager, systemManager, networkManager, deviceManager, volumeManager, limits, hypervisor.TypeQEMU, nil, nil).(*manager)
+	mgr := NewManager(p, imageManager, systemManager, networkManager, deviceManager, volumeManager, nil, limits, hypervisor.TypeQEMU, nil, nil).(*manager)
 
 	// Register cleanup to kill any orphaned QEMU processes
 	t.Cleanup(func() {
@@ -145,6 +145,10 @@ func (r *qemuInstanceResolver) ResolveInstanceIP(ctx context.Context, nameOrID s
 	return r.ip, nil
 }
 
+func (r *qemuInstanceResolver) WakeInstance(ctx context.Context, nameOrID string, port int) (string, error) {
+	return r.ResolveInstanceIP(ctx, nameOrID)
+}
+
 func (r *qemuInstanceResolver) InstanceExists(ctx context.Context, nameOrID string) (bool, error) {
 	return r.exists, nil
 }
@@ -176,7 +180,7 @@ func TestQEMUBasicEndToEnd(t *testing.T) {
 	ctx := context.Background()
 
 	// Get the image manager for image operations
-	imageManager, err := images.NewManager(paths.New(tmpDir), 1, nil)
+	imageManager, err := images.NewManager(paths.New(tmpDir), 1, nil, nil)
 	require.NoError(t, err)
 
 	// Pull nginx image
@@ -212,7 +216,7 @@ func TestQEMUBasicEndToEnd(t *testing.T) {
 
 	// Create a volume to attach
 	p := paths.New(tmpDir)
-	volumeManager := volumes.NewManager(p, 0, nil)
+	volumeManager := volumes.NewManager(p, 0, nil, nil)
 	t.Log("Creating volume...")
 	vol, err := volumeManager.CreateVolume(ctx, volumes.CreateVolumeRequest{
 		Name:   "test-data",
@@ -509,7 +513,7 @@ func TestQEMUBasicEndToEnd(t *testing.T) {
 
 	// Delete instance
 	t.Log("Deleting instance...")
-	err = manager.DeleteInstance(ctx, inst.Id)
+	err = manager.DeleteInstance(ctx, inst.Id, DeleteInstanceRequest{})
 	require.NoError(t, err)
 
 	// Verify cleanup
@@ -556,7 +560,7 @@ func TestQEMUStandbyAndRestore(t *testing.T) {
 	p := paths.New(tmpDir)
 
 	// Get the image manager for image operations
-	imageManager, err := images.NewManager(p, 1, nil)
+	imageManager, err := images.NewManager(p, 1, nil, nil)
 	require.NoError(t, err)
 
 	// Pull nginx image
@@ -650,7 +654,7 @@ func TestQEMUStandbyAndRestore(t *testing.T) {
 
 	// Cleanup
 	t.Log("Cleaning up...")
-	err = manager.DeleteInstance(ctx, inst.Id)
+	err = manager.DeleteInstance(ctx, inst.Id, DeleteInstanceRequest{})
 	require.NoError(t, err)
 
 	// Verify cleanup
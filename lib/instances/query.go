@@ -23,10 +23,14 @@ func (m *manager) deriveState(ctx context.Context, stored *StoredMetadata) state
 
 	// 1. Check if socket exists
 	if _, err := os.Stat(stored.SocketPath); err != nil {
-		// No socket - check for snapshot to distinguish Stopped vs Standby
+		// No socket - check for snapshot to distinguish Stopped vs Standby,
+		// and for a recorded boot failure to distinguish Stopped vs Failed
 		if m.hasSnapshot(stored.DataDir) {
 			return stateResult{State: StateStandby}
 		}
+		if stored.BootFailure != nil {
+			return stateResult{State: StateFailed}
+		}
 		return stateResult{State: StateStopped}
 	}
 
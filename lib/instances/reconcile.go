@@ -0,0 +1,218 @@
+package instances
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"github.com/kernel/hypeman/lib/logger"
+)
+
+// procDir is the proc filesystem scanned for hypervisor processes (overridden in tests)
+var procDir = "/proc"
+
+// ReconcileSummary reports the changes made by startup reconciliation.
+type ReconcileSummary struct {
+	Instances       int // Instances checked
+	VanishedVMMs    int // Instances whose VMM was gone; stale sockets removed so they report Stopped/Standby
+	OrphanProcesses int // Hypervisor processes killed because their instance no longer exists
+	Unresponsive    int // Instances with a live but unresponsive VMM (left untouched)
+	USBDevices      int // USB devices attached to or detached from running instances to match their metadata
+	TAPDevices      int // Orphaned TAP devices deleted (counted by the caller, after the network is set up)
+	Mdevs           int // Orphaned vGPU mdevs destroyed (counted by the caller, after devices are set up)
+}
+
+// ReconcileInstances brings on-disk instance state in line with the host after
+// a crash or restart. It must run before the API starts serving requests.
+//
+//   - Hypervisor processes running for instances that no longer exist are killed.
+//   - Running instances still booting or running their application have their
+//     watchdog resumed.
+//   - Running instances have their USB devices reattached, or detached, to
+//     match their metadata.
+//   - Instances whose VMM process vanished (socket left behind, no process) have
+//     their stale sockets removed, so they derive as Stopped (or Standby if a
+//     snapshot exists) instead of Unknown.
+//
+// Stale TAP devices and vGPU mdevs are cleaned up afterwards by the network
+// and devices managers, using the reconciled instance states; the caller adds
+// them to the summary.
+func (m *manager) ReconcileInstances(ctx context.Context) (*ReconcileSummary, error) {
+	log := logger.FromContext(ctx)
+	summary := &ReconcileSummary{}
+
+	procs, err := findHypervisorProcesses(procDir, m.paths.GuestsDir())
+	if err != nil {
+		// Without a process list we can't tell a vanished VMM from a hung one,
+		// so leave everything as is rather than risk removing a live VM's sockets
+		log.WarnContext(ctx, "failed to scan hypervisor processes, skipping instance reconciliation", "error", err)
+		return summary, nil
+	}
+
+	instances, err := m.listInstances(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	known := make(map[string]bool, len(instances))
+	for _, inst := range instances {
+		known[inst.Id] = true
+		summary.Instances++
+
+		// Running instances get back the watchdog lost with the server
+		if inst.State == StateRunning && (inst.AgentReadyAt == nil || inst.TerminationReason == "") {
+			m.startWatchdog(ctx, &inst.StoredMetadata)
+		}
+		if inst.State == StateRunning {
+			changed, err := m.reconcileUSBDevices(ctx, &inst)
+			if err != nil {
+				log.WarnContext(ctx, "failed to reconcile USB devices", "instance_id", inst.Id, "error", err)
+			}
+			summary.USBDevices += changed
+		}
+		if inst.State != StateUnknown {
+			continue
+		}
+		if pids := procs[inst.Id]; len(pids) > 0 {
+			log.WarnContext(ctx, "instance VMM is running but not responding", "instance_id", inst.Id, "pids", pids)
+			summary.Unresponsive++
+			continue
+		}
+
+		log.InfoContext(ctx, "instance VMM vanished, removing stale sockets", "instance_id", inst.Id)
+		for _, path := range []string{inst.SocketPath, inst.VsockSocket} {
+			if path == "" {
+				continue
+			}
+			if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+				log.WarnContext(ctx, "failed to remove stale socket", "instance_id", inst.Id, "path", path, "error", err)
+			}
+		}
+		summary.VanishedVMMs++
+	}
+
+	for id, pids := range procs {
+		if known[id] {
+			continue
+		}
+		for _, pid := range pids {
+			log.InfoContext(ctx, "killing orphaned hypervisor process", "instance_id", id, "pid", pid)
+			if err := syscall.Kill(pid, syscall.SIGKILL); err != nil {
+				log.WarnContext(ctx, "failed to kill orphaned hypervisor process", "instance_id", id, "pid", pid, "error", err)
+				continue
+			}
+			summary.OrphanProcesses++
+		}
+	}
+
+	return summary, nil
+}
+
+// findHypervisorProcesses returns the PIDs of hypervisor and virtiofsd
+// processes, keyed by instance ID. A process counts only if its binary is one
+// hypeman launches and its socket argument (--api-socket, the QMP chardev or
+// --socket-path) is a socket directly inside an instance directory, so other
+// processes that merely mention a guest path (an operator's tail, a backup
+// job) are never taken for a VMM.
+func findHypervisorProcesses(procDir, guestsDir string) (map[string][]int, error) {
+	entries, err := os.ReadDir(procDir)
+	if err != nil {
+		return nil, err
+	}
+
+	guestsDir = filepath.Clean(guestsDir)
+	self := os.Getpid()
+	result := make(map[string][]int)
+
+	for _, entry := range entries {
+		pid, err := strconv.Atoi(entry.Name())
+		if err != nil || pid == self {
+			continue
+		}
+		kind := processKind(filepath.Join(procDir, entry.Name()))
+		if kind == "" {
+			continue
+		}
+		cmdline, err := os.ReadFile(filepath.Join(procDir, entry.Name(), "cmdline"))
+		if err != nil || len(cmdline) == 0 {
+			// Process exited or is a kernel thread
+			continue
+		}
+		args := strings.Split(strings.TrimRight(string(cmdline), "\x00"), "\x00")
+		if id := instanceIDFromSocket(socketArg(kind, args), guestsDir); id != "" {
+			result[id] = append(result[id], pid)
+		}
+	}
+	return result, nil
+}
+
+// Process kinds hypeman launches for instances
+const (
+	kindCloudHypervisor = "cloud-hypervisor"
+	kindQEMU            = "qemu"
+	kindVirtiofsd       = "virtiofsd"
+)
+
+// processKind identifies a process by its executable, falling back to its
+// comm when /proc/<pid>/exe can't be read. comm is truncated to 15
+// characters ("cloud-hyperviso"). It returns "" for any other binary.
+func processKind(procPID string) string {
+	var name string
+	if exe, err := os.Readlink(filepath.Join(procPID, "exe")); err == nil {
+		name = filepath.Base(strings.TrimSuffix(exe, " (deleted)"))
+	} else if comm, err := os.ReadFile(filepath.Join(procPID, "comm")); err == nil {
+		name = strings.TrimSpace(string(comm))
+	}
+
+	switch {
+	case name == "cloud-hypervisor" || name == "cloud-hyperviso":
+		return kindCloudHypervisor
+	case strings.HasPrefix(name, "qemu-system-") || name == "qemu-kvm":
+		return kindQEMU
+	case name == "virtiofsd":
+		return kindVirtiofsd
+	}
+	return ""
+}
+
+// socketArg returns the socket a process of the given kind was started with,
+// as hypeman passes it: --api-socket <path> for Cloud Hypervisor, the QMP
+// chardev (socket,id=qmp,path=<path>,...) for QEMU, --socket-path <path> for
+// virtiofsd.
+func socketArg(kind string, args []string) string {
+	for i := 1; i+1 < len(args); i++ {
+		switch {
+		case kind == kindCloudHypervisor && args[i] == "--api-socket",
+			kind == kindVirtiofsd && args[i] == "--socket-path":
+			return args[i+1]
+		case kind == kindQEMU && args[i] == "-chardev":
+			opts := strings.Split(args[i+1], ",")
+			if opts[0] != "socket" || !slices.Contains(opts, "id=qmp") {
+				continue
+			}
+			for _, opt := range opts {
+				if path, ok := strings.CutPrefix(opt, "path="); ok {
+					return path
+				}
+			}
+		}
+	}
+	return ""
+}
+
+// instanceIDFromSocket returns the instance ID of a socket path of the form
+// {guestsDir}/{id}/{socket}, or "" for any other path.
+func instanceIDFromSocket(socket, guestsDir string) string {
+	if socket == "" || !filepath.IsAbs(socket) {
+		return ""
+	}
+	dir := filepath.Dir(filepath.Clean(socket))
+	if filepath.Dir(dir) != guestsDir {
+		return ""
+	}
+	return filepath.Base(dir)
+}
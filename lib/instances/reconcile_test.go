@@ -0,0 +1,133 @@
+package instances
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/kernel/hypeman/lib/hypervisor"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFindHypervisorProcesses(t *testing.T) {
+	fakeProc := t.TempDir()
+	guestsDir := "/var/lib/hypeman/guests"
+
+	// writeProc fakes a process; exe is its executable, or "" to leave only its comm
+	writeProc := func(pid int, exe, comm string, args ...string) {
+		dir := filepath.Join(fakeProc, strconv.Itoa(pid))
+		require.NoError(t, os.MkdirAll(dir, 0755))
+		var cmdline []byte
+		for _, arg := range args {
+			cmdline = append(cmdline, arg...)
+			cmdline = append(cmdline, 0)
+		}
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "cmdline"), cmdline, 0644))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "comm"), []byte(comm+"\n"), 0644))
+		if exe != "" {
+			require.NoError(t, os.Symlink(exe, filepath.Join(dir, "exe")))
+		}
+	}
+
+	chBin := "/var/lib/hypeman/system/binaries/ch/v49.0/x86_64/cloud-hypervisor"
+	writeProc(100, chBin, "cloud-hyperviso", chBin, "--api-socket", guestsDir+"/inst-a/ch.sock")
+	writeProc(101, "/usr/bin/qemu-system-x86_64", "qemu-system-x86", "qemu-system-x86_64",
+		"-chardev", "socket,id=fs0,path="+guestsDir+"/inst-x/fs0.sock",
+		"-chardev", "socket,id=qmp,path="+guestsDir+"/inst-b/qemu.sock,server=on,wait=off")
+	writeProc(102, "", "virtiofsd", "/usr/libexec/virtiofsd", "--socket-path", guestsDir+"/inst-a/fs0.sock", "--shared-dir", "/data")
+	// Replaced binary of a process that kept running
+	writeProc(103, chBin+" (deleted)", "cloud-hyperviso", chBin, "--api-socket", guestsDir+"/inst-c/ch.sock")
+	writeProc(104, "/usr/sbin/sshd", "sshd", "/usr/sbin/sshd", "-D")
+	writeProc(105, "", "kthreadd") // kernel thread: empty cmdline
+	// Other processes mentioning guest paths are never taken for VMMs
+	writeProc(106, "/usr/bin/tail", "tail", "tail", "-f", guestsDir+"/inst-d/logs/app.log")
+	writeProc(107, "/usr/bin/rsync", "rsync", "rsync", "-a", guestsDir+"/inst-d/", "/backup/")
+	// A hypervisor whose socket isn't directly in an instance directory
+	writeProc(108, chBin, "cloud-hyperviso", chBin, "--api-socket", "/tmp/ch.sock")
+	writeProc(109, chBin, "cloud-hyperviso", chBin, "--api-socket", guestsDir+"/inst-e/sub/ch.sock")
+	require.NoError(t, os.MkdirAll(filepath.Join(fakeProc, "self"), 0755))
+
+	procs, err := findHypervisorProcesses(fakeProc, guestsDir)
+	require.NoError(t, err)
+	assert.Equal(t, map[string][]int{
+		"inst-a": {100, 102},
+		"inst-b": {101},
+		"inst-c": {103},
+	}, procs)
+}
+
+func TestReconcileInstances(t *testing.T) {
+	mgr, _ := setupTestManager(t)
+	ctx := context.Background()
+
+	// Instance whose VMM died and left its sockets behind
+	id := "reconcile-vanished"
+	require.NoError(t, mgr.ensureDirectories(id))
+	socketPath := mgr.paths.InstanceSocket(id, hypervisor.SocketNameForType(hypervisor.TypeCloudHypervisor))
+	vsockPath := mgr.paths.InstanceVsockSocket(id)
+	require.NoError(t, os.WriteFile(socketPath, nil, 0644))
+	require.NoError(t, os.WriteFile(vsockPath, nil, 0644))
+	require.NoError(t, mgr.saveMetadata(&metadata{StoredMetadata: StoredMetadata{
+		Id:             id,
+		Name:           id,
+		HypervisorType: hypervisor.TypeCloudHypervisor,
+		SocketPath:     socketPath,
+		VsockSocket:    vsockPath,
+		DataDir:        mgr.paths.InstanceDir(id),
+		CreatedAt:      time.Now(),
+	}}))
+
+	inst, err := mgr.getInstance(ctx, id)
+	require.NoError(t, err)
+	require.Equal(t, StateUnknown, inst.State)
+
+	// Hypervisor process left running for an instance that no longer exists: a
+	// copy of sh named cloud-hypervisor, started with an instance's API socket.
+	// The trailing ":" keeps sh from exec'ing sleep, so the process stays sh.
+	orphanSocket := filepath.Join(mgr.paths.GuestsDir(), "reconcile-deleted", "ch.sock")
+	fakeCH := filepath.Join(t.TempDir(), "cloud-hypervisor")
+	copyExecutable(t, "/bin/sh", fakeCH)
+	orphan := exec.Command(fakeCH, "-c", "sleep 60; :", "cloud-hypervisor", "--api-socket", orphanSocket)
+	orphan.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	require.NoError(t, orphan.Start())
+	exited := make(chan error, 1)
+	go func() { exited <- orphan.Wait() }()
+	t.Cleanup(func() { syscall.Kill(-orphan.Process.Pid, syscall.SIGKILL) })
+
+	// An operator's process reading the deleted instance's files is left alone
+	bystander := exec.Command("sleep", "60")
+	bystander.Args[0] = orphanSocket
+	require.NoError(t, bystander.Start())
+	t.Cleanup(func() { bystander.Process.Kill(); bystander.Wait() })
+
+	summary, err := mgr.ReconcileInstances(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, summary.VanishedVMMs)
+	assert.Equal(t, 1, summary.OrphanProcesses)
+	assert.Equal(t, 0, summary.Unresponsive)
+
+	inst, err = mgr.getInstance(ctx, id)
+	require.NoError(t, err)
+	assert.Equal(t, StateStopped, inst.State)
+	assert.NoFileExists(t, vsockPath)
+
+	select {
+	case <-exited:
+	case <-time.After(5 * time.Second):
+		t.Fatal("orphaned hypervisor process was not killed")
+	}
+	assert.NoError(t, bystander.Process.Signal(syscall.Signal(0)), "non-hypervisor process was killed")
+}
+
+func copyExecutable(t *testing.T, src, dst string) {
+	t.Helper()
+	data, err := os.ReadFile(src)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(dst, data, 0755))
+}
@@ -0,0 +1,68 @@
+package instances
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/kernel/hypeman/lib/logger"
+	"github.com/kernel/hypeman/lib/network"
+)
+
+// renameInstance changes an instance's name. For networked instances the
+// uniqueness check and the metadata update happen under the network's
+// allocation lock, so a concurrent create can't take the name in between,
+// and the dnsmasq records move to the new name. A running guest keeps its
+// hostname until it next boots.
+func (m *manager) renameInstance(ctx context.Context, id string, name string) (*Instance, error) {
+	log := logger.FromContext(ctx)
+
+	if err := validateInstanceName(name); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidName, err)
+	}
+
+	meta, err := m.loadMetadata(id)
+	if err != nil {
+		return nil, err
+	}
+	stored := &meta.StoredMetadata
+	oldName := stored.Name
+	if oldName == name {
+		inst := m.toInstance(ctx, meta)
+		return &inst, nil
+	}
+
+	// Instances without networking aren't covered by the network's check
+	all, err := m.listInstances(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("list instances: %w", err)
+	}
+	for _, other := range all {
+		if other.Id != id && other.Name == name {
+			return nil, fmt.Errorf("%w: %s", ErrNameInUse, name)
+		}
+	}
+
+	stored.Name = name
+	commit := func() error {
+		if err := m.saveMetadata(meta); err != nil {
+			return fmt.Errorf("save metadata: %w", err)
+		}
+		return nil
+	}
+	if stored.NetworkEnabled {
+		err = m.networkManager.RenameAllocation(ctx, id, name, commit)
+		if errors.Is(err, network.ErrNameExists) {
+			err = fmt.Errorf("%w: %s", ErrNameInUse, name)
+		}
+	} else {
+		err = commit()
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	log.InfoContext(ctx, "instance renamed", "instance_id", id, "old_name", oldName, "name", name)
+	inst := m.toInstance(ctx, meta)
+	return &inst, nil
+}
@@ -0,0 +1,54 @@
+package instances
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/kernel/hypeman/lib/hypervisor"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRenameInstance(t *testing.T) {
+	mgr, _ := setupTestManager(t)
+	ctx := context.Background()
+
+	saveStopped := func(id, name string) {
+		require.NoError(t, mgr.ensureDirectories(id))
+		require.NoError(t, mgr.saveMetadata(&metadata{StoredMetadata: StoredMetadata{
+			Id:             id,
+			Name:           name,
+			HypervisorType: hypervisor.TypeCloudHypervisor,
+			DataDir:        mgr.paths.InstanceDir(id),
+			CreatedAt:      time.Now(),
+		}}))
+	}
+	saveStopped("rename-a", "api")
+	saveStopped("rename-b", "worker")
+
+	inst, err := mgr.RenameInstance(ctx, "rename-a", "api-v2")
+	require.NoError(t, err)
+	assert.Equal(t, "api-v2", inst.Name)
+
+	meta, err := mgr.loadMetadata("rename-a")
+	require.NoError(t, err)
+	assert.Equal(t, "api-v2", meta.Name)
+
+	got, err := mgr.GetInstance(ctx, "api-v2")
+	require.NoError(t, err)
+	assert.Equal(t, "rename-a", got.Id)
+
+	// Renaming to the current name is a no-op
+	_, err = mgr.RenameInstance(ctx, "rename-a", "api-v2")
+	assert.NoError(t, err)
+
+	_, err = mgr.RenameInstance(ctx, "rename-a", "worker")
+	assert.ErrorIs(t, err, ErrNameInUse)
+
+	_, err = mgr.RenameInstance(ctx, "rename-a", "Not_Valid")
+	assert.ErrorIs(t, err, ErrInvalidName)
+
+	_, err = mgr.RenameInstance(ctx, "missing", "anything")
+	assert.Error(t, err)
+}
@@ -0,0 +1,61 @@
+package instances
+
+import (
+	"fmt"
+	"net"
+	"regexp"
+	"strings"
+
+	"github.com/kernel/hypeman/lib/vmconfig"
+)
+
+// MaxNameservers is the maximum number of nameservers in the guest's resolv.conf.
+// glibc's resolver ignores nameservers beyond the third.
+const MaxNameservers = 3
+
+// hostnamePattern matches an RFC 1123 hostname (dot-separated labels).
+var hostnamePattern = regexp.MustCompile(`^[a-zA-Z0-9]([a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?(\.[a-zA-Z0-9]([a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?)*$`)
+
+// validateResolverConfig checks nameservers and extra hosts entries.
+func validateResolverConfig(rc *ResolverConfig) error {
+	if rc == nil {
+		return nil
+	}
+
+	if len(rc.Nameservers) > MaxNameservers {
+		return fmt.Errorf("%w: at most %d nameservers are supported, got %d",
+			ErrInvalidResolverConfig, MaxNameservers, len(rc.Nameservers))
+	}
+	for _, ns := range rc.Nameservers {
+		if net.ParseIP(ns) == nil {
+			return fmt.Errorf("%w: invalid nameserver %q", ErrInvalidResolverConfig, ns)
+		}
+	}
+	for _, h := range rc.ExtraHosts {
+		if len(h.Hostname) > 253 || !hostnamePattern.MatchString(h.Hostname) {
+			return fmt.Errorf("%w: invalid hostname %q", ErrInvalidResolverConfig, h.Hostname)
+		}
+		if net.ParseIP(h.IP) == nil {
+			return fmt.Errorf("%w: invalid IP address %q for %s", ErrInvalidResolverConfig, h.IP, h.Hostname)
+		}
+	}
+	return nil
+}
+
+// applyResolverConfig sets the guest config's resolv.conf and hosts options.
+func applyResolverConfig(cfg *vmconfig.Config, rc *ResolverConfig) {
+	if rc == nil {
+		return
+	}
+	cfg.SkipResolvConf = rc.SkipResolvConf
+	cfg.SkipHosts = rc.SkipHosts
+	if len(rc.Nameservers) > 0 {
+		cfg.GuestNameservers = rc.Nameservers
+	}
+	for _, h := range rc.ExtraHosts {
+		cfg.ExtraHosts = append(cfg.ExtraHosts, vmconfig.HostEntry{
+			Hostname: strings.ToLower(h.Hostname),
+			IP:       h.IP,
+		})
+	}
+}
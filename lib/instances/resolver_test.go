@@ -0,0 +1,56 @@
+package instances
+
+import (
+	"context"
+	"testing"
+
+	"github.com/kernel/hypeman/lib/images"
+	"github.com/kernel/hypeman/lib/vmconfig"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateResolverConfig(t *testing.T) {
+	require.NoError(t, validateResolverConfig(nil))
+	require.NoError(t, validateResolverConfig(&ResolverConfig{
+		Nameservers: []string{"1.1.1.1", "2606:4700:4700::1111"},
+		ExtraHosts:  []HostEntry{{Hostname: "db.internal", IP: "10.100.5.42"}},
+	}))
+
+	tests := []struct {
+		name string
+		rc   *ResolverConfig
+	}{
+		{"too many nameservers", &ResolverConfig{Nameservers: []string{"1.1.1.1", "1.0.0.1", "8.8.8.8", "8.8.4.4"}}},
+		{"invalid nameserver", &ResolverConfig{Nameservers: []string{"dns.example.com"}}},
+		{"invalid hostname", &ResolverConfig{ExtraHosts: []HostEntry{{Hostname: "db internal", IP: "10.0.0.1"}}}},
+		{"invalid host IP", &ResolverConfig{ExtraHosts: []HostEntry{{Hostname: "db", IP: "10.0.0"}}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.ErrorIs(t, validateResolverConfig(tt.rc), ErrInvalidResolverConfig)
+		})
+	}
+}
+
+func TestBuildGuestConfig_Resolver(t *testing.T) {
+	m := &manager{}
+	imageInfo := &images.Image{}
+	inst := &Instance{StoredMetadata: StoredMetadata{Id: "inst-1", Name: "web"}}
+
+	// Defaults: hypeman manages both files
+	cfg := m.buildGuestConfig(context.Background(), inst, imageInfo, nil)
+	assert.Equal(t, "web", cfg.Hostname)
+	assert.False(t, cfg.SkipResolvConf)
+	assert.False(t, cfg.SkipHosts)
+
+	inst.Resolver = &ResolverConfig{
+		SkipHosts:   true,
+		Nameservers: []string{"1.1.1.1"},
+		ExtraHosts:  []HostEntry{{Hostname: "DB.internal", IP: "10.100.5.42"}},
+	}
+	cfg = m.buildGuestConfig(context.Background(), inst, imageInfo, nil)
+	assert.True(t, cfg.SkipHosts)
+	assert.Equal(t, []string{"1.1.1.1"}, cfg.GuestNameservers)
+	assert.Equal(t, []vmconfig.HostEntry{{Hostname: "db.internal", IP: "10.100.5.42"}}, cfg.ExtraHosts)
+}
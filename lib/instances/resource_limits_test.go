@@ -155,15 +155,15 @@ func createTestManager(t *testing.T, limits ResourceLimits) *manager {
 	cfg := &config.Config{DataDir: tmpDir}
 	p := paths.New(cfg.DataDir)
 
-	imageMgr, err := images.NewManager(p, 1, nil)
+	imageMgr, err := images.NewManager(p, 1, nil, nil)
 	require.NoError(t, err)
 
 	systemMgr := system.NewManager(p)
 	networkMgr := network.NewManager(p, cfg, nil)
 	deviceMgr := devices.NewManager(p)
-	volumeMgr := volumes.NewManager(p, 0, nil)
+	volumeMgr := volumes.NewManager(p, 0, nil, nil)
 
-	return NewManager(p, imageMgr, systemMgr, networkMgr, deviceMgr, volumeMgr, limits, "", nil, nil).(*manager)
+	return NewManager(p, imageMgr, systemMgr, networkMgr, deviceMgr, volumeMgr, nil, limits, "", nil, nil).(*manager)
 }
 
 func TestResourceLimits_StructValues(t *testing.T) {
@@ -248,13 +248,13 @@ func TestAggregateLimits_EnforcedAtRuntime(t *testing.T) {
 	}
 
 	p := paths.New(tmpDir)
-	imageManager, err := images.NewManager(p, 1, nil)
+	imageManager, err := images.NewManager(p, 1, nil, nil)
 	require.NoError(t, err)
 
 	systemManager := system.NewManager(p)
 	networkManager := network.NewManager(p, cfg, nil)
 	deviceManager := devices.NewManager(p)
-	volumeManager := volumes.NewManager(p, 0, nil)
+	volumeManager := volumes.NewManager(p, 0, nil, nil)
 
 	// Set small aggregate limits:
 	// - MaxTotalVcpus: 2 (first VM gets 1, second wants 2 -> denied)
@@ -267,7 +267,7 @@ func TestAggregateLimits_EnforcedAtRuntime(t *testing.T) {
 		MaxTotalMemory:       6 * 1024 * 1024 * 1024,   // aggregate: only 6GB total (allows first 2.5GB VM)
 	}
 
-	mgr := NewManager(p, imageManager, systemManager, networkManager, deviceManager, volumeManager, limits, "", nil, nil).(*manager)
+	mgr := NewManager(p, imageManager, systemManager, networkManager, deviceManager, volumeManager, nil, limits, "", nil, nil).(*manager)
 
 	// Cleanup any orphaned processes on test end
 	t.Cleanup(func() {
@@ -342,8 +342,20 @@ func TestAggregateLimits_EnforcedAtRuntime(t *testing.T) {
 	})
 	require.Error(t, err, "Should deny creation due to aggregate vCPU limit")
 	assert.Contains(t, err.Error(), "exceeds aggregate limit")
+	assert.ErrorIs(t, err, ErrResourceLimit)
 	t.Logf("Second instance correctly denied: %v", err)
 
+	// A dry run reports the same denial
+	_, err = mgr.CheckCreateInstance(ctx, CreateInstanceRequest{
+		Name:        "big-vm-2",
+		Image:       "docker.io/library/alpine:latest",
+		Vcpus:       2,
+		Size:        256 * 1024 * 1024,
+		HotplugSize: 256 * 1024 * 1024,
+		OverlaySize: 1 * 1024 * 1024 * 1024,
+	})
+	assert.ErrorIs(t, err, ErrResourceLimit)
+
 	// Verify aggregate usage didn't change (failed creation shouldn't affect it)
 	usage, err = mgr.calculateAggregateUsage(ctx)
 	require.NoError(t, err)
@@ -351,7 +363,7 @@ func TestAggregateLimits_EnforcedAtRuntime(t *testing.T) {
 
 	// Clean up first instance
 	t.Log("Deleting first instance...")
-	err = mgr.DeleteInstance(ctx, inst1.Id)
+	err = mgr.DeleteInstance(ctx, inst1.Id, DeleteInstanceRequest{})
 	require.NoError(t, err)
 
 	// Verify aggregate usage is back to 0
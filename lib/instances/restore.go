@@ -8,7 +8,7 @@ import (
 
 	"github.com/kernel/hypeman/lib/hypervisor"
 	"github.com/kernel/hypeman/lib/logger"
-	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 // RestoreInstance restores an instance from standby
@@ -17,17 +17,14 @@ func (m *manager) restoreInstance(
 	ctx context.Context,
 
 	id string,
-) (*Instance, error) {
+) (_ *Instance, retErr error) {
 	start := time.Now()
 	log := logger.FromContext(ctx)
 	log.InfoContext(ctx, "restoring instance from standby", "instance_id", id)
 
 	// Start tracing span if tracer is available
-	if m.metrics != nil && m.metrics.tracer != nil {
-		var span trace.Span
-		ctx, span = m.metrics.tracer.Start(ctx, "RestoreInstance")
-		defer span.End()
-	}
+	ctx, endSpan := m.startSpan(ctx, "RestoreInstance", attribute.String("instance_id", id))
+	defer func() { endSpan(retErr) }()
 
 	// 1. Load instance
 	meta, err := m.loadMetadata(id)
@@ -56,36 +53,37 @@ func (m *manager) restoreInstance(
 
 	// 4. Recreate TAP device if network enabled
 	if stored.NetworkEnabled {
-		var networkSpan trace.Span
-		if m.metrics != nil && m.metrics.tracer != nil {
-			ctx, networkSpan = m.metrics.tracer.Start(ctx, "RestoreNetwork")
-		}
 		log.InfoContext(ctx, "recreating network for restore", "instance_id", id, "network", "default",
 			"download_bps", stored.NetworkBandwidthDownload, "upload_bps", stored.NetworkBandwidthUpload)
-		if err := m.networkManager.RecreateAllocation(ctx, id, stored.NetworkBandwidthDownload, stored.NetworkBandwidthUpload); err != nil {
-			if networkSpan != nil {
-				networkSpan.End()
-			}
+		netCtx, endNetworkSpan := m.startSpan(ctx, "RestoreNetwork")
+		err := m.networkManager.RecreateAllocation(netCtx, id, m.bandwidthLimits(stored))
+		endNetworkSpan(err)
+		if err != nil {
 			log.ErrorContext(ctx, "failed to recreate network", "instance_id", id, "error", err)
 			return nil, fmt.Errorf("recreate network: %w", err)
 		}
-		if networkSpan != nil {
-			networkSpan.End()
-		}
 	}
 
-	// 5. Transition: Standby → Paused (start hypervisor + restore)
-	var restoreSpan trace.Span
-	if m.metrics != nil && m.metrics.tracer != nil {
-		ctx, restoreSpan = m.metrics.tracer.Start(ctx, "RestoreFromSnapshot")
+	// 5. Reopen decrypted mappings of encrypted volumes, which the snapshot
+	// references by their device paths
+	if err := m.unlockVolumes(ctx, stored); err != nil {
+		log.ErrorContext(ctx, "failed to unlock volumes", "instance_id", id, "error", err)
+		if stored.NetworkEnabled {
+			netAlloc, _ := m.networkManager.GetAllocation(ctx, id)
+			m.networkManager.ReleaseAllocation(ctx, netAlloc)
+		}
+		return nil, err
 	}
+
+	// 6. Transition: Standby → Paused (start hypervisor + restore)
+	closeGuestConn(stored)
 	log.InfoContext(ctx, "restoring from snapshot", "instance_id", id, "snapshot_dir", snapshotDir, "hypervisor", stored.HypervisorType)
-	pid, hv, err := m.restoreFromSnapshot(ctx, stored, snapshotDir)
-	if restoreSpan != nil {
-		restoreSpan.End()
-	}
+	snapshotCtx, endSnapshotSpan := m.startSpan(ctx, "RestoreFromSnapshot", attribute.String("hypervisor", string(stored.HypervisorType)))
+	pid, hv, err := m.restoreFromSnapshot(snapshotCtx, stored, snapshotDir)
+	endSnapshotSpan(err)
 	if err != nil {
 		log.ErrorContext(ctx, "failed to restore from snapshot", "instance_id", id, "error", err)
+		m.lockVolumes(ctx, stored)
 		// Cleanup network on failure
 		if stored.NetworkEnabled {
 			netAlloc, _ := m.networkManager.GetAllocation(ctx, id)
@@ -97,28 +95,22 @@ func (m *manager) restoreInstance(
 	// Store the PID for later cleanup
 	stored.HypervisorPID = &pid
 
-	// 6. Transition: Paused → Running (resume)
-	var resumeSpan trace.Span
-	if m.metrics != nil && m.metrics.tracer != nil {
-		ctx, resumeSpan = m.metrics.tracer.Start(ctx, "ResumeVM")
-	}
+	// 7. Transition: Paused → Running (resume)
 	log.InfoContext(ctx, "resuming VM", "instance_id", id)
-	if err := hv.Resume(ctx); err != nil {
-		if resumeSpan != nil {
-			resumeSpan.End()
-		}
+	resumeCtx, endResumeSpan := m.startSpan(ctx, "ResumeVM")
+	err = hv.Resume(resumeCtx)
+	endResumeSpan(err)
+	if err != nil {
 		log.ErrorContext(ctx, "failed to resume VM", "instance_id", id, "error", err)
 		// Cleanup on failure
 		hv.Shutdown(ctx)
+		m.lockVolumes(ctx, stored)
 		if stored.NetworkEnabled {
 			netAlloc, _ := m.networkManager.GetAllocation(ctx, id)
 			m.networkManager.ReleaseAllocation(ctx, netAlloc)
 		}
 		return nil, fmt.Errorf("resume vm failed: %w", err)
 	}
-	if resumeSpan != nil {
-		resumeSpan.End()
-	}
 
 	// 8. Delete snapshot after successful restore
 	log.InfoContext(ctx, "deleting snapshot after successful restore", "instance_id", id)
@@ -133,6 +125,10 @@ func (m *manager) restoreInstance(
 		// VM is running but metadata failed
 		log.WarnContext(ctx, "failed to update metadata after restore", "instance_id", id, "error", err)
 	}
+	m.startWatchdog(ctx, stored)
+	go m.syncClockAfterRestore(context.WithoutCancel(ctx), *stored)
+	go m.refreshLinksAfterRestore(context.WithoutCancel(ctx), *stored)
+	m.runPostHooks(ctx, HookPostRestore, id)
 
 	// Record metrics
 	if m.metrics != nil {
@@ -160,9 +156,15 @@ func (m *manager) restoreFromSnapshot(
 		return 0, nil, fmt.Errorf("get vm starter: %w", err)
 	}
 
+	// Confine the hypervisor process
+	opts, err := m.hypervisorProcessOptions(stored)
+	if err != nil {
+		return 0, nil, err
+	}
+
 	// Restore VM from snapshot (handles process start + restore)
 	log.DebugContext(ctx, "restoring VM from snapshot", "instance_id", stored.Id, "hypervisor", stored.HypervisorType, "version", stored.HypervisorVersion, "snapshot_dir", snapshotDir)
-	pid, hv, err := starter.RestoreVM(ctx, m.paths, stored.HypervisorVersion, stored.SocketPath, snapshotDir)
+	pid, hv, err := starter.RestoreVM(ctx, m.paths, stored.HypervisorVersion, stored.SocketPath, snapshotDir, opts)
 	if err != nil {
 		return 0, nil, fmt.Errorf("restore vm: %w", err)
 	}
@@ -0,0 +1,206 @@
+package instances
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/kernel/hypeman/lib/logger"
+	"github.com/kernel/hypeman/lib/scheduler"
+)
+
+// ScheduleAction is what an instance schedule does when it fires
+type ScheduleAction string
+
+const (
+	ScheduleActionStart   ScheduleAction = "start"   // Start a stopped instance, or restore one from standby
+	ScheduleActionStandby ScheduleAction = "standby" // Put a running instance in standby
+	ScheduleActionStop    ScheduleAction = "stop"    // Stop a running instance
+)
+
+// MaxSchedules is the most schedules an instance may have
+const MaxSchedules = 16
+
+// Schedule changes an instance's state whenever a cron expression fires.
+// An action that doesn't apply to the instance's state at the time, such as
+// starting one that's already running, does nothing.
+type Schedule struct {
+	Action   ScheduleAction
+	Cron     string // Cron expression, see lib/scheduler
+	Timezone string // IANA time zone the expression is evaluated in (empty = server local time)
+
+	// Outcome of the last run, kept across updates that leave the schedule unchanged
+	LastRunAt *time.Time
+	LastError string
+}
+
+// parse returns the schedule's cron expression and time zone
+func (s Schedule) parse() (*scheduler.Schedule, *time.Location, error) {
+	cron, err := scheduler.Parse(s.Cron)
+	if err != nil {
+		return nil, nil, err
+	}
+	loc := time.Local
+	if s.Timezone != "" {
+		if loc, err = time.LoadLocation(s.Timezone); err != nil {
+			return nil, nil, fmt.Errorf("unknown timezone %q", s.Timezone)
+		}
+	}
+	return cron, loc, nil
+}
+
+// Next returns the first time after t the schedule fires, or the zero time
+// if it never does
+func (s Schedule) Next(t time.Time) time.Time {
+	cron, loc, err := s.parse()
+	if err != nil {
+		return time.Time{}
+	}
+	return cron.Next(t.In(loc))
+}
+
+// validateSchedules checks an instance's schedules
+func validateSchedules(schedules []Schedule) error {
+	if len(schedules) > MaxSchedules {
+		return fmt.Errorf("%w: at most %d schedules", ErrInvalidSchedule, MaxSchedules)
+	}
+	for i, s := range schedules {
+		switch s.Action {
+		case ScheduleActionStart, ScheduleActionStandby, ScheduleActionStop:
+		default:
+			return fmt.Errorf("%w: schedule %d: action must be start, standby or stop", ErrInvalidSchedule, i)
+		}
+		if _, _, err := s.parse(); err != nil {
+			return fmt.Errorf("%w: schedule %d: %v", ErrInvalidSchedule, i, err)
+		}
+	}
+	return nil
+}
+
+// sameSchedule reports whether two schedules fire the same action at the same times
+func sameSchedule(a, b Schedule) bool {
+	return a.Action == b.Action && a.Cron == b.Cron && a.Timezone == b.Timezone
+}
+
+// setSchedules replaces an instance's schedules. Schedules that are
+// unchanged keep the outcome of their last run.
+func (m *manager) setSchedules(ctx context.Context, id string, schedules []Schedule) (*Instance, error) {
+	if err := validateSchedules(schedules); err != nil {
+		return nil, err
+	}
+
+	meta, err := m.loadMetadata(id)
+	if err != nil {
+		return nil, err
+	}
+
+	updated := make([]Schedule, 0, len(schedules))
+	for _, s := range schedules {
+		s.LastRunAt, s.LastError = nil, ""
+		for _, old := range meta.Schedules {
+			if sameSchedule(old, s) {
+				s.LastRunAt, s.LastError = old.LastRunAt, old.LastError
+				break
+			}
+		}
+		updated = append(updated, s)
+	}
+	meta.Schedules = updated
+
+	if err := m.saveMetadata(meta); err != nil {
+		return nil, fmt.Errorf("save metadata: %w", err)
+	}
+	logger.FromContext(ctx).InfoContext(ctx, "instance schedules updated", "instance_id", id, "schedules", len(updated))
+
+	inst := m.toInstance(ctx, meta)
+	return &inst, nil
+}
+
+// RunSchedules fires the instance schedules due in (since, now], returning
+// the IDs of the instances whose state was changed. Schedules due while the
+// server was down are not caught up.
+func (m *manager) RunSchedules(ctx context.Context, since, now time.Time) ([]string, error) {
+	log := logger.FromContext(ctx)
+
+	all, err := m.listInstances(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("list instances: %w", err)
+	}
+
+	var changed []string
+	for _, inst := range all {
+		state := inst.State
+		acted := false
+		for _, s := range inst.Schedules {
+			next := s.Next(since)
+			if next.IsZero() || next.After(now) {
+				continue
+			}
+
+			log.InfoContext(ctx, "running instance schedule", "instance_id", inst.Id, "action", s.Action, "cron", s.Cron, "state", state)
+			newState, err := m.runScheduleAction(ctx, inst.Id, s.Action, state)
+			if err != nil {
+				log.WarnContext(ctx, "instance schedule failed", "instance_id", inst.Id, "action", s.Action, "error", err)
+			} else if newState != state {
+				state = newState
+				acted = true
+			}
+			m.recordScheduleRun(ctx, inst.Id, s, now, err)
+		}
+		if acted {
+			changed = append(changed, inst.Id)
+		}
+	}
+	return changed, nil
+}
+
+// runScheduleAction applies an action to an instance in a state, returning
+// its new state
+func (m *manager) runScheduleAction(ctx context.Context, id string, action ScheduleAction, state State) (State, error) {
+	var (
+		inst *Instance
+		err  error
+	)
+	switch {
+	case action == ScheduleActionStart && state == StateStopped:
+		inst, err = m.StartInstance(ctx, id)
+	case action == ScheduleActionStart && state == StateStandby:
+		inst, err = m.RestoreInstance(ctx, id)
+	case action == ScheduleActionStandby && state == StateRunning:
+		inst, err = m.StandbyInstance(ctx, id)
+	case action == ScheduleActionStop && state == StateRunning:
+		inst, err = m.StopInstance(ctx, id)
+	default:
+		// Already in the state the action leads to, or in one it can't leave
+		return state, nil
+	}
+	if err != nil {
+		return state, err
+	}
+	return inst.State, nil
+}
+
+// recordScheduleRun stores when a schedule last ran and how it went
+func (m *manager) recordScheduleRun(ctx context.Context, id string, s Schedule, at time.Time, runErr error) {
+	lock := m.getInstanceLock(id)
+	lock.acquire("record_schedule_run")
+	defer lock.release()
+
+	meta, err := m.loadMetadata(id)
+	if err != nil {
+		return
+	}
+	for i := range meta.Schedules {
+		if !sameSchedule(meta.Schedules[i], s) {
+			continue
+		}
+		meta.Schedules[i].LastRunAt = &at
+		meta.Schedules[i].LastError = ""
+		if runErr != nil {
+			meta.Schedules[i].LastError = runErr.Error()
+		}
+	}
+	if err := m.saveMetadata(meta); err != nil {
+		logger.FromContext(ctx).WarnContext(ctx, "failed to record instance schedule run", "instance_id", id, "error", err)
+	}
+}
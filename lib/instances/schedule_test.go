@@ -0,0 +1,92 @@
+package instances
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/kernel/hypeman/lib/hypervisor"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestScheduleNext(t *testing.T) {
+	ny, err := time.LoadLocation("America/New_York")
+	require.NoError(t, err)
+
+	// Weekdays at 8:00 New York time
+	s := Schedule{Action: ScheduleActionStart, Cron: "0 8 * * 1-5", Timezone: "America/New_York"}
+
+	// Friday 20:00 in New York is Saturday 00:00 UTC; the next run is Monday
+	friday := time.Date(2025, 1, 17, 20, 0, 0, 0, ny).UTC()
+	next := s.Next(friday)
+	assert.Equal(t, time.Date(2025, 1, 20, 8, 0, 0, 0, ny), next.In(ny))
+
+	assert.True(t, Schedule{Cron: "0 8 * * *", Timezone: "Nowhere/Special"}.Next(friday).IsZero())
+}
+
+func TestValidateSchedules(t *testing.T) {
+	valid := []Schedule{
+		{Action: ScheduleActionStart, Cron: "0 8 * * 1-5", Timezone: "Europe/Berlin"},
+		{Action: ScheduleActionStandby, Cron: "0 20 * * 1-5"},
+		{Action: ScheduleActionStop, Cron: "@daily"},
+	}
+	assert.NoError(t, validateSchedules(valid))
+
+	invalid := map[string][]Schedule{
+		"action":   {{Action: "reboot", Cron: "@daily"}},
+		"cron":     {{Action: ScheduleActionStart, Cron: "0 25 * * *"}},
+		"timezone": {{Action: ScheduleActionStart, Cron: "@daily", Timezone: "Mars/Olympus"}},
+		"too many": make([]Schedule, MaxSchedules+1),
+	}
+	for name, schedules := range invalid {
+		assert.ErrorIs(t, validateSchedules(schedules), ErrInvalidSchedule, name)
+	}
+}
+
+func TestSchedules(t *testing.T) {
+	mgr, _ := setupTestManager(t)
+	ctx := context.Background()
+
+	id := "sched-stopped"
+	require.NoError(t, mgr.ensureDirectories(id))
+	require.NoError(t, mgr.saveMetadata(&metadata{StoredMetadata: StoredMetadata{
+		Id:             id,
+		Name:           id,
+		HypervisorType: hypervisor.TypeCloudHypervisor,
+		DataDir:        mgr.paths.InstanceDir(id),
+		CreatedAt:      time.Now(),
+	}}))
+
+	stop := Schedule{Action: ScheduleActionStop, Cron: "0 20 * * *", Timezone: "UTC"}
+	inst, err := mgr.SetSchedules(ctx, id, []Schedule{stop})
+	require.NoError(t, err)
+	require.Len(t, inst.Schedules, 1)
+
+	// Stopping a stopped instance does nothing, but the run is recorded
+	since := time.Date(2025, 1, 17, 19, 59, 30, 0, time.UTC)
+	changed, err := mgr.RunSchedules(ctx, since, since.Add(time.Minute))
+	require.NoError(t, err)
+	assert.Empty(t, changed)
+
+	meta, err := mgr.loadMetadata(id)
+	require.NoError(t, err)
+	require.NotNil(t, meta.Schedules[0].LastRunAt)
+	assert.Empty(t, meta.Schedules[0].LastError)
+
+	// Not due in a window without 20:00
+	changed, err = mgr.RunSchedules(ctx, since.Add(time.Hour), since.Add(2*time.Hour))
+	require.NoError(t, err)
+	assert.Empty(t, changed)
+
+	// An unchanged schedule keeps its last run; a new one starts fresh
+	start := Schedule{Action: ScheduleActionStart, Cron: "0 8 * * *", Timezone: "UTC"}
+	inst, err = mgr.SetSchedules(ctx, id, []Schedule{start, stop})
+	require.NoError(t, err)
+	require.Len(t, inst.Schedules, 2)
+	assert.Nil(t, inst.Schedules[0].LastRunAt)
+	assert.NotNil(t, inst.Schedules[1].LastRunAt)
+
+	_, err = mgr.SetSchedules(ctx, id, []Schedule{{Action: ScheduleActionStart, Cron: "bogus"}})
+	assert.ErrorIs(t, err, ErrInvalidSchedule)
+}
@@ -0,0 +1,102 @@
+package instances
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/kernel/hypeman/lib/images"
+)
+
+const (
+	// MaxScratchDisksPerInstance is the maximum number of scratch disks per instance
+	MaxScratchDisksPerInstance = 8
+
+	// minScratchDiskSize is the smallest scratch disk mkfs.ext4 formats reliably
+	minScratchDiskSize = 16 * 1024 * 1024
+
+	// scratchMountRoot is where scratch disks are mounted in the guest
+	scratchMountRoot = "/scratch"
+)
+
+// validateScratchDisks checks a scratch disk spec, and that the disks fit in
+// the device letters left by the volumes and don't collide with their mounts
+func validateScratchDisks(spec *ScratchDisks, volumes []VolumeAttachment, sharedDirs []SharedDirectory) error {
+	if spec == nil {
+		return nil
+	}
+	if spec.Count < 1 || spec.Count > MaxScratchDisksPerInstance {
+		return fmt.Errorf("scratch_disks: count must be between 1 and %d", MaxScratchDisksPerInstance)
+	}
+	if spec.Size < minScratchDiskSize {
+		return fmt.Errorf("scratch_disks: size must be at least %dMB", minScratchDiskSize/(1024*1024))
+	}
+
+	if dataDeviceCount(volumes, spec) > MaxVolumesPerInstance {
+		return fmt.Errorf("cannot attach more than %d volume and scratch devices per instance (overlay volumes count as 2)", MaxVolumesPerInstance)
+	}
+
+	mountPaths := make([]string, 0, len(volumes)+len(sharedDirs))
+	for _, vol := range volumes {
+		mountPaths = append(mountPaths, vol.MountPath)
+	}
+	for _, dir := range sharedDirs {
+		mountPaths = append(mountPaths, dir.MountPath)
+	}
+	for _, p := range mountPaths {
+		clean := filepath.Clean(p)
+		if clean == scratchMountRoot || strings.HasPrefix(clean, scratchMountRoot+"/") {
+			return fmt.Errorf("mount path %q is reserved for scratch disks", clean)
+		}
+	}
+	return nil
+}
+
+// dataDeviceCount returns the number of block devices the volumes and
+// scratch disks take up after the system disks
+func dataDeviceCount(volumes []VolumeAttachment, scratch *ScratchDisks) int {
+	devices := 0
+	for _, vol := range volumes {
+		devices++
+		if vol.Overlay {
+			devices++
+		}
+	}
+	if scratch != nil {
+		devices += scratch.Count
+	}
+	return devices
+}
+
+// scratchMountPath returns where the scratch disk with the given index is
+// mounted in the guest
+func scratchMountPath(index int) string {
+	return scratchMountRoot + "/" + strconv.Itoa(index)
+}
+
+// scratchBytes returns the total size of an instance's scratch disks
+func scratchBytes(spec *ScratchDisks) int64 {
+	if spec == nil {
+		return 0
+	}
+	return int64(spec.Count) * spec.Size
+}
+
+// createScratchDisks creates an instance's sparse scratch disks. They live in
+// the instance directory, so deleteInstanceData removes them with it.
+func (m *manager) createScratchDisks(id string, spec *ScratchDisks) error {
+	if spec == nil {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(m.paths.InstanceScratchDisk(id, 0)), 0755); err != nil {
+		return fmt.Errorf("create scratch directory: %w", err)
+	}
+	for i := range spec.Count {
+		if err := images.CreateEmptyExt4Disk(m.paths.InstanceScratchDisk(id, i), spec.Size); err != nil {
+			return fmt.Errorf("create scratch disk %d: %w", i, err)
+		}
+	}
+	return nil
+}
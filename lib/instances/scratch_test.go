@@ -0,0 +1,53 @@
+package instances
+
+import (
+	"context"
+	"testing"
+
+	"github.com/kernel/hypeman/lib/images"
+	"github.com/kernel/hypeman/lib/vmconfig"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateScratchDisks(t *testing.T) {
+	const gb = 1024 * 1024 * 1024
+	assert.NoError(t, validateScratchDisks(nil, nil, nil))
+	assert.NoError(t, validateScratchDisks(&ScratchDisks{Count: 2, Size: gb}, []VolumeAttachment{{VolumeID: "v", MountPath: "/data"}}, nil))
+
+	tests := []struct {
+		name    string
+		spec    ScratchDisks
+		volumes []VolumeAttachment
+		dirs    []SharedDirectory
+	}{
+		{name: "no disks", spec: ScratchDisks{Count: 0, Size: gb}},
+		{name: "too many disks", spec: ScratchDisks{Count: MaxScratchDisksPerInstance + 1, Size: gb}},
+		{name: "too small", spec: ScratchDisks{Count: 1, Size: 1024}},
+		{name: "out of devices", spec: ScratchDisks{Count: 2, Size: gb}, volumes: make([]VolumeAttachment, MaxVolumesPerInstance-1)},
+		{name: "volume under /scratch", spec: ScratchDisks{Count: 1, Size: gb}, volumes: []VolumeAttachment{{VolumeID: "v", MountPath: "/scratch/0"}}},
+		{name: "shared directory at /scratch", spec: ScratchDisks{Count: 1, Size: gb}, dirs: []SharedDirectory{{HostPath: "/srv/a", MountPath: "/scratch/"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Error(t, validateScratchDisks(&tt.spec, tt.volumes, tt.dirs))
+		})
+	}
+}
+
+func TestBuildGuestConfig_ScratchDisks(t *testing.T) {
+	m := &manager{}
+	imageInfo := &images.Image{Cmd: []string{"/bin/sh"}}
+	inst := &Instance{StoredMetadata: StoredMetadata{
+		Id:           "inst-1",
+		Name:         "vm",
+		Volumes:      []VolumeAttachment{{VolumeID: "vol-data", MountPath: "/data"}},
+		ScratchDisks: &ScratchDisks{Count: 2, Size: 1 << 30},
+	}}
+
+	cfg := m.buildGuestConfig(context.Background(), inst, imageInfo, nil)
+	assert.Equal(t, []vmconfig.VolumeMount{
+		{Device: "/dev/vdd", Path: "/data", Mode: "rw"},
+		{Device: "/dev/vde", Path: "/scratch/0", Mode: "rw"},
+		{Device: "/dev/vdf", Path: "/scratch/1", Mode: "rw"},
+	}, cfg.VolumeMounts)
+}
@@ -0,0 +1,119 @@
+package instances
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/kernel/hypeman/lib/guest"
+	"github.com/kernel/hypeman/lib/hypervisor"
+	"github.com/kernel/hypeman/lib/logger"
+	"github.com/kernel/hypeman/lib/secrets"
+)
+
+const (
+	// MaxSecretsPerInstance is the maximum number of secrets attached to an instance
+	MaxSecretsPerInstance = 32
+
+	// secretDeliveryTimeout bounds how long delivery waits for the guest
+	// agent to come up after boot
+	secretDeliveryTimeout = 90 * time.Second
+)
+
+// validEnvVar matches environment variable names secrets can be exposed as
+var validEnvVar = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// secretPayload holds an instance's secret values, ready for delivery
+type secretPayload struct {
+	files map[string][]byte // by file name under /run/secrets
+	env   map[string]string
+}
+
+// validateSecretAttachments checks that the attached secrets exist and that
+// no two of them are exposed under the same file or environment variable name
+func (m *manager) validateSecretAttachments(ctx context.Context, attachments []SecretAttachment) error {
+	if len(attachments) == 0 {
+		return nil
+	}
+	if m.secretManager == nil {
+		return fmt.Errorf("%w: secrets are not enabled on this host", ErrInvalidSecret)
+	}
+	if len(attachments) > MaxSecretsPerInstance {
+		return fmt.Errorf("%w: cannot attach more than %d secrets per instance", ErrInvalidSecret, MaxSecretsPerInstance)
+	}
+
+	seenFiles := make(map[string]bool)
+	seenEnv := make(map[string]bool)
+	for _, att := range attachments {
+		secret, err := m.secretManager.GetSecret(ctx, att.SecretID)
+		if err != nil {
+			if errors.Is(err, secrets.ErrDisabled) {
+				return fmt.Errorf("%w: secrets are not enabled on this host", ErrInvalidSecret)
+			}
+			return fmt.Errorf("secret %s: %w", att.SecretID, err)
+		}
+		if att.EnvVar == "" {
+			if seenFiles[secret.Name] {
+				return fmt.Errorf("%w: duplicate secret file %q", ErrInvalidSecret, secret.Name)
+			}
+			seenFiles[secret.Name] = true
+			continue
+		}
+		if !validEnvVar.MatchString(att.EnvVar) {
+			return fmt.Errorf("%w: invalid environment variable name %q", ErrInvalidSecret, att.EnvVar)
+		}
+		if seenEnv[att.EnvVar] {
+			return fmt.Errorf("%w: duplicate environment variable %q", ErrInvalidSecret, att.EnvVar)
+		}
+		seenEnv[att.EnvVar] = true
+	}
+	return nil
+}
+
+// loadSecretPayload reads the values of an instance's secrets, so that a
+// secret deleted since the instance was created fails the boot up front
+func (m *manager) loadSecretPayload(ctx context.Context, stored *StoredMetadata) (*secretPayload, error) {
+	if len(stored.Secrets) == 0 {
+		return nil, nil
+	}
+	if m.secretManager == nil {
+		return nil, fmt.Errorf("%w: secrets are not enabled on this host", ErrInvalidSecret)
+	}
+
+	payload := &secretPayload{files: make(map[string][]byte), env: make(map[string]string)}
+	for _, att := range stored.Secrets {
+		secret, err := m.secretManager.GetSecret(ctx, att.SecretID)
+		if err != nil {
+			return nil, fmt.Errorf("secret %s: %w", att.SecretID, err)
+		}
+		value, err := m.secretManager.GetSecretValue(ctx, att.SecretID)
+		if err != nil {
+			return nil, fmt.Errorf("secret %s: %w", att.SecretID, err)
+		}
+		if att.EnvVar == "" {
+			payload.files[secret.Name] = value
+		} else {
+			payload.env[att.EnvVar] = string(value)
+		}
+	}
+	return payload, nil
+}
+
+// deliverSecrets hands an instance's secrets to its guest agent once the
+// agent is up. Guest init holds the application back until they arrive.
+// It runs in the background after boot; failures are logged.
+func (m *manager) deliverSecrets(ctx context.Context, stored StoredMetadata, payload *secretPayload) {
+	log := logger.FromContext(ctx)
+
+	dialer, err := hypervisor.NewVsockDialer(stored.HypervisorType, stored.VsockSocket, stored.VsockCID)
+	if err == nil {
+		err = guest.PutSecrets(ctx, dialer, payload.files, payload.env, secretDeliveryTimeout)
+	}
+	if err != nil {
+		log.ErrorContext(ctx, "failed to deliver secrets", "instance_id", stored.Id, "error", err)
+		return
+	}
+	log.InfoContext(ctx, "delivered secrets", "instance_id", stored.Id, "files", len(payload.files), "env", len(payload.env))
+}
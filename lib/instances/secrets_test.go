@@ -0,0 +1,66 @@
+package instances
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/kernel/hypeman/lib/keyring"
+	"github.com/kernel/hypeman/lib/paths"
+	"github.com/kernel/hypeman/lib/secrets"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSecretAttachments(t *testing.T) {
+	ctx := context.Background()
+	p := paths.New(t.TempDir())
+	keys, err := keyring.NewFileKeyring(p.SecretKeyringDir(), bytes.Repeat([]byte{1}, keyring.MasterKeySize))
+	require.NoError(t, err)
+	secretManager := secrets.NewManager(p, keys)
+	m := &manager{secretManager: secretManager}
+
+	token, err := secretManager.CreateSecret(ctx, secrets.CreateSecretRequest{Name: "token", Value: []byte("t0k3n")})
+	require.NoError(t, err)
+	password, err := secretManager.CreateSecret(ctx, secrets.CreateSecretRequest{Name: "password", Value: []byte("hunter2")})
+	require.NoError(t, err)
+
+	t.Run("loads files and env", func(t *testing.T) {
+		attachments := []SecretAttachment{
+			{SecretID: token.Id},
+			{SecretID: password.Id, EnvVar: "DB_PASSWORD"},
+		}
+		require.NoError(t, m.validateSecretAttachments(ctx, attachments))
+
+		payload, err := m.loadSecretPayload(ctx, &StoredMetadata{Secrets: attachments})
+		require.NoError(t, err)
+		assert.Equal(t, map[string][]byte{"token": []byte("t0k3n")}, payload.files)
+		assert.Equal(t, map[string]string{"DB_PASSWORD": "hunter2"}, payload.env)
+	})
+
+	t.Run("rejects invalid attachments", func(t *testing.T) {
+		for name, attachments := range map[string][]SecretAttachment{
+			"duplicate file": {{SecretID: token.Id}, {SecretID: token.Id}},
+			"duplicate env":  {{SecretID: token.Id, EnvVar: "X"}, {SecretID: password.Id, EnvVar: "X"}},
+			"invalid env":    {{SecretID: token.Id, EnvVar: "1X"}},
+		} {
+			assert.ErrorIs(t, m.validateSecretAttachments(ctx, attachments), ErrInvalidSecret, name)
+		}
+		err := m.validateSecretAttachments(ctx, []SecretAttachment{{SecretID: "missing"}})
+		assert.ErrorIs(t, err, secrets.ErrNotFound)
+	})
+
+	t.Run("rejects secrets when disabled", func(t *testing.T) {
+		disabled := &manager{}
+		err := disabled.validateSecretAttachments(ctx, []SecretAttachment{{SecretID: token.Id}})
+		assert.ErrorIs(t, err, ErrInvalidSecret)
+	})
+
+	t.Run("fails the boot for a deleted secret", func(t *testing.T) {
+		doomed, err := secretManager.CreateSecret(ctx, secrets.CreateSecretRequest{Name: "doomed", Value: []byte("x")})
+		require.NoError(t, err)
+		require.NoError(t, secretManager.DeleteSecret(ctx, doomed.Id))
+		_, err = m.loadSecretPayload(ctx, &StoredMetadata{Secrets: []SecretAttachment{{SecretID: doomed.Id}}})
+		assert.ErrorIs(t, err, secrets.ErrNotFound)
+	})
+}
@@ -0,0 +1,122 @@
+package instances
+
+import (
+	"context"
+	"fmt"
+	"syscall"
+	"time"
+
+	"github.com/kernel/hypeman/lib/guest"
+	"github.com/kernel/hypeman/lib/hypervisor"
+	"github.com/kernel/hypeman/lib/logger"
+)
+
+// powerOffPollInterval is how often a graceful shutdown checks whether the
+// guest powered off after the ACPI power button
+const powerOffPollInterval = 100 * time.Millisecond
+
+// gracePeriod returns how long an instance's application gets to exit on
+// stop or delete: the call's override, else the instance's own setting, else
+// the server default
+func (m *manager) gracePeriod(stored *StoredMetadata, override *time.Duration) time.Duration {
+	if override != nil {
+		return *override
+	}
+	if stored.ShutdownGracePeriod != nil {
+		return *stored.ShutdownGracePeriod
+	}
+	return m.limits.ShutdownGracePeriod
+}
+
+// validateGracePeriod rejects a negative grace period; nil means the default
+func validateGracePeriod(d *time.Duration) error {
+	if d != nil && *d < 0 {
+		return fmt.Errorf("%w: cannot be negative", ErrInvalidGracePeriod)
+	}
+	return nil
+}
+
+// shutdownVM takes down a running instance's VM, giving its application up
+// to grace to exit first:
+//  1. The guest agent sends SIGTERM to the application and waits for it,
+//     then syncs the guest's filesystems (exec mode only)
+//  2. Unless the application exited, the ACPI power button is pressed and the
+//     guest gets the rest of the grace period to power off. systemd mode
+//     guests shut down cleanly on it.
+//  3. The VMM is shut down, and killed if it doesn't exit
+//
+// With a zero grace period it goes straight to step 3.
+func (m *manager) shutdownVM(ctx context.Context, inst *Instance, grace time.Duration) {
+	log := logger.FromContext(ctx)
+	deadline := time.Now().Add(grace)
+
+	dialer, err := hypervisor.NewVsockDialer(inst.HypervisorType, inst.VsockSocket, inst.VsockCID)
+	if err != nil {
+		log.WarnContext(ctx, "failed to create vsock dialer", "instance_id", inst.Id, "error", err)
+	}
+
+	appExited := false
+	if grace > 0 && dialer != nil {
+		stopCtx, cancel := context.WithTimeout(ctx, grace+appStatusTimeout)
+		appExited, err = guest.StopApp(stopCtx, dialer, grace)
+		cancel()
+		switch {
+		case err != nil:
+			log.DebugContext(ctx, "guest agent could not stop application", "instance_id", inst.Id, "error", err)
+		case !appExited:
+			log.WarnContext(ctx, "application did not exit within grace period", "instance_id", inst.Id, "grace_period", grace)
+		default:
+			log.DebugContext(ctx, "application exited", "instance_id", inst.Id)
+		}
+	}
+
+	// Close the exec gRPC connection before the VM goes away to prevent panic
+	if dialer != nil {
+		guest.CloseConn(dialer.Key())
+	}
+
+	if grace > 0 && !appExited && m.pressPowerButton(ctx, inst, deadline) {
+		log.DebugContext(ctx, "guest powered off", "instance_id", inst.Id)
+	}
+
+	log.DebugContext(ctx, "shutting down hypervisor", "instance_id", inst.Id)
+	if err := m.shutdownHypervisor(ctx, inst); err != nil {
+		log.WarnContext(ctx, "failed to shutdown hypervisor gracefully", "instance_id", inst.Id, "error", err)
+	}
+	if inst.HypervisorPID != nil && syscall.Kill(*inst.HypervisorPID, 0) == nil {
+		if err := m.killHypervisor(ctx, inst); err != nil {
+			log.WarnContext(ctx, "failed to kill hypervisor", "instance_id", inst.Id, "error", err)
+		}
+	}
+}
+
+// pressPowerButton presses the VM's ACPI power button and waits until the
+// deadline for the guest to power off, reporting whether it did
+func (m *manager) pressPowerButton(ctx context.Context, inst *Instance, deadline time.Time) bool {
+	log := logger.FromContext(ctx)
+
+	hv, err := m.getHypervisor(inst.SocketPath, inst.HypervisorType)
+	if err != nil {
+		return false
+	}
+	log.DebugContext(ctx, "pressing ACPI power button", "instance_id", inst.Id)
+	if err := hv.PowerButton(ctx); err != nil {
+		log.WarnContext(ctx, "failed to press ACPI power button", "instance_id", inst.Id, "error", err)
+		return false
+	}
+
+	for {
+		if inst.HypervisorPID != nil && syscall.Kill(*inst.HypervisorPID, 0) != nil {
+			return true
+		}
+		// An unreachable VMM went away with the guest
+		if info, err := hv.GetVMInfo(ctx); err != nil || info.State == hypervisor.StateShutdown {
+			return true
+		}
+		if time.Now().After(deadline) {
+			log.WarnContext(ctx, "guest did not power off within grace period", "instance_id", inst.Id)
+			return false
+		}
+		time.Sleep(powerOffPollInterval)
+	}
+}
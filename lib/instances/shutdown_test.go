@@ -0,0 +1,54 @@
+package instances
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/kernel/hypeman/lib/hypervisor"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGracePeriod(t *testing.T) {
+	m := &manager{limits: ResourceLimits{ShutdownGracePeriod: 10 * time.Second}}
+	instanceGrace := 30 * time.Second
+	callGrace := time.Duration(0)
+
+	var stored StoredMetadata
+	assert.Equal(t, 10*time.Second, m.gracePeriod(&stored, nil), "server default")
+
+	stored.ShutdownGracePeriod = &instanceGrace
+	assert.Equal(t, 30*time.Second, m.gracePeriod(&stored, nil), "instance override")
+	assert.Equal(t, time.Duration(0), m.gracePeriod(&stored, &callGrace), "call override")
+}
+
+func TestValidateGracePeriod(t *testing.T) {
+	valid := []time.Duration{0, 5 * time.Second}
+	for _, d := range valid {
+		assert.NoError(t, validateGracePeriod(&d))
+	}
+	assert.NoError(t, validateGracePeriod(nil))
+
+	negative := -time.Second
+	assert.ErrorIs(t, validateGracePeriod(&negative), ErrInvalidGracePeriod)
+}
+
+func TestShutdownVMUnreachable(t *testing.T) {
+	mgr, _ := setupTestManager(t)
+	dir := t.TempDir()
+
+	// Neither the guest agent nor the VMM answers: every step fails fast
+	// and the shutdown doesn't wait out the grace period
+	inst := &Instance{StoredMetadata: StoredMetadata{
+		Id:             "shutdown-unreachable",
+		HypervisorType: hypervisor.TypeCloudHypervisor,
+		SocketPath:     filepath.Join(dir, "ch.sock"),
+		VsockSocket:    filepath.Join(dir, "vsock.sock"),
+		VsockCID:       3,
+	}}
+	start := time.Now()
+	mgr.shutdownVM(context.Background(), inst, time.Minute)
+	require.Less(t, time.Since(start), 10*time.Second)
+}
@@ -9,7 +9,7 @@ import (
 	"github.com/kernel/hypeman/lib/hypervisor"
 	"github.com/kernel/hypeman/lib/logger"
 	"github.com/kernel/hypeman/lib/network"
-	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 // StandbyInstance puts an instance in standby state
@@ -18,17 +18,14 @@ func (m *manager) standbyInstance(
 	ctx context.Context,
 
 	id string,
-) (*Instance, error) {
+) (_ *Instance, retErr error) {
 	start := time.Now()
 	log := logger.FromContext(ctx)
 	log.InfoContext(ctx, "putting instance in standby", "instance_id", id)
 
 	// Start tracing span if tracer is available
-	if m.metrics != nil && m.metrics.tracer != nil {
-		var span trace.Span
-		ctx, span = m.metrics.tracer.Start(ctx, "StandbyInstance")
-		defer span.End()
-	}
+	ctx, endSpan := m.startSpan(ctx, "StandbyInstance", attribute.String("instance_id", id))
+	defer func() { endSpan(retErr) }()
 
 	// 1. Load instance
 	meta, err := m.loadMetadata(id)
@@ -47,6 +44,19 @@ func (m *manager) standbyInstance(
 		return nil, fmt.Errorf("%w: cannot standby from state %s", ErrInvalidState, inst.State)
 	}
 
+	// virtiofsd state can't be snapshotted with the VM
+	if len(inst.SharedDirectories) > 0 {
+		log.ErrorContext(ctx, "standby not supported with shared directories", "instance_id", id)
+		return nil, fmt.Errorf("%w: cannot standby an instance with shared directories", ErrInvalidState)
+	}
+	if nestedBlocksStandby(&inst.StoredMetadata) {
+		log.ErrorContext(ctx, "standby not supported with nested virtualization", "instance_id", id)
+		return nil, fmt.Errorf("%w: cannot standby a cloud-hypervisor instance with nested virtualization", ErrInvalidState)
+	}
+	if err := m.runHooks(ctx, HookPreStandby, newHookInstance(stored, inst.State)); err != nil {
+		return nil, err
+	}
+
 	// 3. Get network allocation BEFORE killing VMM (while we can still query it)
 	// This is needed to delete the TAP device after VMM shuts down
 	var networkAlloc *network.Allocation
@@ -73,7 +83,10 @@ func (m *manager) standbyInstance(
 
 	// 6. Transition: Running → Paused
 	log.DebugContext(ctx, "pausing VM", "instance_id", id)
-	if err := hv.Pause(ctx); err != nil {
+	pauseCtx, endPauseSpan := m.startSpan(ctx, "PauseVM")
+	err = hv.Pause(pauseCtx)
+	endPauseSpan(err)
+	if err != nil {
 		log.ErrorContext(ctx, "failed to pause VM", "instance_id", id, "error", err)
 		return nil, fmt.Errorf("pause vm failed: %w", err)
 	}
@@ -81,26 +94,38 @@ func (m *manager) standbyInstance(
 	// 7. Create snapshot
 	snapshotDir := m.paths.InstanceSnapshotLatest(id)
 	log.DebugContext(ctx, "creating snapshot", "instance_id", id, "snapshot_dir", snapshotDir)
-	if err := createSnapshot(ctx, hv, snapshotDir); err != nil {
+	snapshotCtx, endSnapshotSpan := m.startSpan(ctx, "CreateSnapshot")
+	err = createSnapshot(snapshotCtx, hv, snapshotDir)
+	endSnapshotSpan(err)
+	if err != nil {
 		// Snapshot failed - try to resume VM
 		log.ErrorContext(ctx, "snapshot failed, attempting to resume VM", "instance_id", id, "error", err)
 		hv.Resume(ctx)
 		return nil, fmt.Errorf("create snapshot: %w", err)
 	}
 
-	// 8. Stop VMM gracefully (snapshot is complete)
+	// 8. Stop VMM gracefully (snapshot is complete). The guest agent's
+	// connection doesn't survive the snapshot, so drop it
+	closeGuestConn(stored)
 	log.DebugContext(ctx, "shutting down hypervisor", "instance_id", id)
-	if err := m.shutdownHypervisor(ctx, &inst); err != nil {
+	shutdownCtx, endShutdownSpan := m.startSpan(ctx, "ShutdownVMM")
+	err = m.shutdownHypervisor(shutdownCtx, &inst)
+	endShutdownSpan(err)
+	if err != nil {
 		// Log but continue - snapshot was created successfully
 		log.WarnContext(ctx, "failed to shutdown hypervisor gracefully, snapshot still valid", "instance_id", id, "error", err)
 	}
+	m.lockVolumes(ctx, stored)
 
 	// 9. Release network allocation (delete TAP device)
 	// TAP devices with explicit Owner/Group fields do NOT auto-delete when VMM exits
 	// They must be explicitly deleted
 	if inst.NetworkEnabled {
 		log.DebugContext(ctx, "releasing network", "instance_id", id, "network", "default")
-		if err := m.networkManager.ReleaseAllocation(ctx, networkAlloc); err != nil {
+		netCtx, endNetworkSpan := m.startSpan(ctx, "ReleaseNetwork")
+		err := m.networkManager.ReleaseAllocation(netCtx, networkAlloc)
+		endNetworkSpan(err)
+		if err != nil {
 			// Log error but continue - snapshot was created successfully
 			log.WarnContext(ctx, "failed to release network, continuing with standby", "instance_id", id, "error", err)
 		}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/kernel/hypeman/lib/images"
 	"github.com/kernel/hypeman/lib/logger"
 	"github.com/kernel/hypeman/lib/network"
 	"go.opentelemetry.io/otel/trace"
@@ -39,37 +40,49 @@ func (m *manager) startInstance(
 	stored := &meta.StoredMetadata
 	log.DebugContext(ctx, "loaded instance", "instance_id", id, "state", inst.State)
 
-	// 2. Validate state (must be Stopped to start)
-	if inst.State != StateStopped {
+	// 2. Validate state (must be Stopped or Failed to start)
+	if inst.State != StateStopped && inst.State != StateFailed {
 		log.ErrorContext(ctx, "invalid state for start", "instance_id", id, "state", inst.State)
-		return nil, fmt.Errorf("%w: cannot start from state %s, must be Stopped", ErrInvalidState, inst.State)
+		return nil, fmt.Errorf("%w: cannot start from state %s, must be Stopped or Failed", ErrInvalidState, inst.State)
 	}
-
-	// 3. Get image info (needed for buildHypervisorConfig)
-	log.DebugContext(ctx, "getting image info", "instance_id", id, "image", stored.Image)
-	imageInfo, err := m.imageManager.GetImage(ctx, stored.Image)
-	if err != nil {
-		log.ErrorContext(ctx, "failed to get image", "instance_id", id, "image", stored.Image, "error", err)
-		return nil, fmt.Errorf("get image: %w", err)
+	// A new boot gets a fresh watchdog
+	stored.BootFailure = nil
+	stored.AgentReadyAt = nil
+	clearTermination(stored)
+
+	// 3. Get image info (needed for buildHypervisorConfig). A root volume
+	// replaces the image.
+	var imageInfo *images.Image
+	if stored.RootVolume == "" {
+		log.DebugContext(ctx, "getting image info", "instance_id", id, "image", stored.Image)
+		imageInfo, err = m.imageManager.GetImage(ctx, stored.Image)
+		if err != nil {
+			log.ErrorContext(ctx, "failed to get image", "instance_id", id, "image", stored.Image, "error", err)
+			return nil, fmt.Errorf("get image: %w", err)
+		}
+		if imageInfo, err = m.refreshStaleImage(ctx, imageInfo); err != nil {
+			return nil, err
+		}
 	}
 
 	// Setup cleanup stack for automatic rollback on errors
 	cu := cleanup.Make(func() {})
 	defer cu.Clean()
 
-	// 4. Allocate fresh network if network enabled
+	// 4. Allocate network if network enabled (reuses the instance's reserved IP/MAC)
 	var netConfig *network.NetworkConfig
 	if stored.NetworkEnabled {
 		log.DebugContext(ctx, "allocating network for start", "instance_id", id, "network", "default")
 		netConfig, err = m.networkManager.CreateAllocation(ctx, network.AllocateRequest{
-			InstanceID:   id,
-			InstanceName: stored.Name,
+			InstanceID:      id,
+			InstanceName:    stored.Name,
+			BandwidthLimits: m.bandwidthLimits(stored),
 		})
 		if err != nil {
 			log.ErrorContext(ctx, "failed to allocate network", "instance_id", id, "error", err)
 			return nil, fmt.Errorf("allocate network: %w", err)
 		}
-		// Update stored metadata with new IP/MAC
+		// Update stored metadata with the allocated IP/MAC
 		stored.IP = netConfig.IP
 		stored.MAC = netConfig.MAC
 		// Add network cleanup to stack
@@ -90,6 +103,7 @@ func (m *manager) startInstance(
 	}
 
 	// 6. Start hypervisor and boot VM (reuses logic from create)
+	closeGuestConn(stored)
 	log.InfoContext(ctx, "starting hypervisor and booting VM", "instance_id", id)
 	if err := m.startAndBootVM(ctx, stored, imageInfo, netConfig); err != nil {
 		log.ErrorContext(ctx, "failed to start and boot VM", "instance_id", id, "error", err)
@@ -108,11 +122,12 @@ func (m *manager) startInstance(
 		// VM is running but metadata failed - log but don't fail
 		log.WarnContext(ctx, "failed to update metadata after VM start", "instance_id", id, "error", err)
 	}
+	m.startWatchdog(ctx, stored)
 
 	// Record metrics
 	if m.metrics != nil {
 		m.recordDuration(ctx, m.metrics.startDuration, start, "success", stored.HypervisorType)
-		m.recordStateTransition(ctx, string(StateStopped), string(StateRunning), stored.HypervisorType)
+		m.recordStateTransition(ctx, string(inst.State), string(StateRunning), stored.HypervisorType)
 	}
 
 	// Return instance with derived state (should be Running now)
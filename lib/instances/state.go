@@ -28,6 +28,9 @@ var ValidTransitions = map[State][]State{
 	StateStopped: {
 		StateCreated, // start VMM process
 	},
+	StateFailed: {
+		StateCreated, // start VMM process (retry boot)
+	},
 	StateStandby: {
 		StatePaused,  // start VMM + restore (atomic operation)
 		StateStopped, // delete snapshot + cleanup (terminal)
@@ -70,7 +73,7 @@ func (s State) RequiresVMM() bool {
 	switch s {
 	case StateCreated, StateRunning, StatePaused, StateShutdown:
 		return true
-	case StateStopped, StateStandby, StateUnknown:
+	case StateStopped, StateStandby, StateFailed, StateUnknown:
 		return false
 	default:
 		return false
@@ -54,13 +54,14 @@ func (m *manager) stopInstance(
 		}
 	}
 
-	// 4. Shutdown hypervisor process
-	// TODO: Add graceful shutdown via vsock signal to allow app to clean up
-	log.DebugContext(ctx, "shutting down hypervisor", "instance_id", id)
-	if err := m.shutdownHypervisor(ctx, &inst); err != nil {
-		// Log but continue - try to clean up anyway
-		log.WarnContext(ctx, "failed to shutdown hypervisor gracefully", "instance_id", id, "error", err)
-	}
+	// 4. Record how the application ended, then give it its grace period to
+	// exit and shut the VM down
+	m.recordStopTermination(ctx, stored)
+	grace := m.gracePeriod(stored, nil)
+	log.DebugContext(ctx, "shutting down VM", "instance_id", id, "grace_period", grace)
+	m.shutdownVM(ctx, &inst, grace)
+	m.stopVirtiofsd(ctx, stored)
+	m.lockVolumes(ctx, stored)
 
 	// 5. Release network allocation (delete TAP device)
 	if inst.NetworkEnabled && networkAlloc != nil {
@@ -14,6 +14,8 @@ import (
 //   metadata.json      # Instance metadata
 //   overlay.raw        # Configurable sparse overlay disk (default 10GB)
 //   config.ext4        # Read-only config disk (generated)
+//   scratch/{N}.raw    # Sparse scratch disks, deleted with the instance
+//   swap.raw           # Sparse swap disk (unformatted, mkswap runs in the guest)
 //   ch.sock            # Hypervisor API socket (abbreviated name for SUN_LEN limit)
 //   logs/
 //     app.log          # Guest application log (serial console output)
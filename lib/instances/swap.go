@@ -0,0 +1,43 @@
+package instances
+
+import (
+	"fmt"
+	"os"
+)
+
+const (
+	// minSwapSize is the smallest swap disk accepted; mkswap rejects tiny devices
+	minSwapSize = 16 * 1024 * 1024
+
+	// MaxSwapSize is the largest swap disk per instance
+	MaxSwapSize = 64 * 1024 * 1024 * 1024
+)
+
+// validateSwapSize checks a requested swap disk size, and that the disk fits
+// in the device letters left by the volumes and scratch disks
+func validateSwapSize(size int64, volumes []VolumeAttachment, scratch *ScratchDisks) error {
+	if size == 0 {
+		return nil
+	}
+	if size < minSwapSize || size > MaxSwapSize {
+		return fmt.Errorf("swap_size must be between %dMB and %dGB", minSwapSize/(1024*1024), MaxSwapSize/(1024*1024*1024))
+	}
+	if dataDeviceCount(volumes, scratch)+1 > MaxVolumesPerInstance {
+		return fmt.Errorf("cannot attach a swap disk: volumes and scratch disks use all %d devices", MaxVolumesPerInstance)
+	}
+	return nil
+}
+
+// createSwapDisk creates the instance's sparse swap disk. The guest init
+// formats it with mkswap on every boot, so it's left unformatted here.
+func (m *manager) createSwapDisk(id string, sizeBytes int64) error {
+	f, err := os.OpenFile(m.paths.InstanceSwapDisk(id), os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("create swap disk: %w", err)
+	}
+	defer f.Close()
+	if err := f.Truncate(sizeBytes); err != nil {
+		return fmt.Errorf("size swap disk: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,47 @@
+package instances
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/kernel/hypeman/lib/images"
+	"github.com/kernel/hypeman/lib/paths"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateSwapSize(t *testing.T) {
+	const gb = 1024 * 1024 * 1024
+	assert.NoError(t, validateSwapSize(0, nil, nil))
+	assert.NoError(t, validateSwapSize(4*gb, nil, &ScratchDisks{Count: 2, Size: gb}))
+
+	assert.Error(t, validateSwapSize(1024, nil, nil), "too small")
+	assert.Error(t, validateSwapSize(MaxSwapSize+1, nil, nil), "too large")
+	assert.Error(t, validateSwapSize(gb, make([]VolumeAttachment, MaxVolumesPerInstance), nil), "out of devices")
+}
+
+func TestSwapDisk(t *testing.T) {
+	m := &manager{paths: paths.New(t.TempDir())}
+	require.NoError(t, os.MkdirAll(m.paths.InstanceDir("inst-1"), 0755))
+
+	require.NoError(t, m.createSwapDisk("inst-1", 1<<30))
+	info, err := os.Stat(m.paths.InstanceSwapDisk("inst-1"))
+	require.NoError(t, err)
+	assert.Equal(t, int64(1<<30), info.Size())
+
+	// The swap disk follows the volumes and scratch disks
+	inst := &Instance{StoredMetadata: StoredMetadata{
+		Id:           "inst-1",
+		Name:         "vm",
+		SwapSize:     1 << 30,
+		Volumes:      []VolumeAttachment{{VolumeID: "vol-data", MountPath: "/data"}},
+		ScratchDisks: &ScratchDisks{Count: 1, Size: 1 << 30},
+	}}
+	cfg := m.buildGuestConfig(context.Background(), inst, &images.Image{Cmd: []string{"/bin/sh"}}, nil)
+	assert.Equal(t, "/dev/vdf", cfg.SwapDevice)
+
+	inst.SwapSize = 0
+	cfg = m.buildGuestConfig(context.Background(), inst, &images.Image{Cmd: []string{"/bin/sh"}}, nil)
+	assert.Empty(t, cfg.SwapDevice)
+}
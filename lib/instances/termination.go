@@ -0,0 +1,108 @@
+package instances
+
+import (
+	"context"
+	"time"
+
+	"github.com/kernel/hypeman/lib/guest"
+	"github.com/kernel/hypeman/lib/hypervisor"
+	"github.com/kernel/hypeman/lib/logger"
+)
+
+const (
+	// appPollInterval is how often the watchdog asks the guest agent whether
+	// the application has exited
+	appPollInterval = 5 * time.Second
+
+	// appStatusTimeout bounds a single application status check
+	appStatusTimeout = 2 * time.Second
+)
+
+// watchApp waits for the application of the boot identified by pid to exit
+// and records how it did. Init keeps the VM running after the application
+// exits, so the instance stays Running with a termination reason.
+func (m *manager) watchApp(ctx context.Context, id string, pid int, dialer hypervisor.VsockDialer) {
+	for {
+		time.Sleep(appPollInterval)
+
+		statusCtx, cancel := context.WithTimeout(ctx, appStatusTimeout)
+		exit, err := guest.GetAppStatus(statusCtx, dialer)
+		cancel()
+		if err == nil && exit != nil {
+			m.recordAppExit(ctx, id, pid, exit)
+			return
+		}
+		// Errors are expected while the instance is paused or being stopped
+		if !m.isCurrentBoot(id, pid) {
+			return
+		}
+	}
+}
+
+// recordAppExit stores the application's exit in the metadata of the boot
+// identified by pid
+func (m *manager) recordAppExit(ctx context.Context, id string, pid int, exit *guest.AppExit) {
+	lock := m.getInstanceLock(id)
+	lock.acquire("watchdog")
+	defer lock.release()
+
+	meta, err := m.loadMetadata(id)
+	if err != nil || meta.HypervisorPID == nil || *meta.HypervisorPID != pid || meta.TerminationReason != "" {
+		return
+	}
+	setAppExit(&meta.StoredMetadata, exit)
+	if err := m.saveMetadata(meta); err != nil {
+		logger.FromContext(ctx).WarnContext(ctx, "failed to record application exit", "instance_id", id, "error", err)
+		return
+	}
+	logger.FromContext(ctx).InfoContext(ctx, "application exited", "instance_id", id,
+		"reason", meta.TerminationReason, "exit_code", exit.ExitCode, "signal", exit.Signal)
+}
+
+// recordStopTermination sets the termination of an instance about to be
+// stopped: the application's exit if it already exited, or
+// TerminationStopped. Called before the VM goes away, while the guest agent
+// can still be asked.
+func (m *manager) recordStopTermination(ctx context.Context, stored *StoredMetadata) {
+	if stored.TerminationReason != "" {
+		return
+	}
+	dialer, err := hypervisor.NewVsockDialer(stored.HypervisorType, stored.VsockSocket, stored.VsockCID)
+	if err == nil {
+		statusCtx, cancel := context.WithTimeout(ctx, appStatusTimeout)
+		var exit *guest.AppExit
+		exit, err = guest.GetAppStatus(statusCtx, dialer)
+		cancel()
+		if err == nil && exit != nil {
+			setAppExit(stored, exit)
+			return
+		}
+	}
+	if err != nil {
+		logger.FromContext(ctx).DebugContext(ctx, "failed to get application status before stop", "instance_id", stored.Id, "error", err)
+	}
+	now := time.Now()
+	stored.TerminationReason = TerminationStopped
+	stored.TerminatedAt = &now
+}
+
+// setAppExit records an application exit reported by the guest agent
+func setAppExit(stored *StoredMetadata, exit *guest.AppExit) {
+	stored.TerminationReason = TerminationExited
+	if exit.Signal != "" {
+		stored.TerminationReason = TerminationSignaled
+	}
+	code := exit.ExitCode
+	stored.ExitCode = &code
+	stored.ExitSignal = exit.Signal
+	exitedAt := exit.ExitedAt
+	stored.TerminatedAt = &exitedAt
+}
+
+// clearTermination resets the termination fields for a new boot
+func clearTermination(stored *StoredMetadata) {
+	stored.TerminationReason = ""
+	stored.ExitCode = nil
+	stored.ExitSignal = ""
+	stored.TerminatedAt = nil
+}
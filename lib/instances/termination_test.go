@@ -0,0 +1,85 @@
+package instances
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/kernel/hypeman/lib/guest"
+	"github.com/kernel/hypeman/lib/hypervisor"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSetAppExit(t *testing.T) {
+	exitedAt := time.Date(2025, 1, 15, 11, 30, 0, 0, time.UTC)
+
+	var stored StoredMetadata
+	setAppExit(&stored, &guest.AppExit{ExitCode: 3, ExitedAt: exitedAt})
+	assert.Equal(t, TerminationExited, stored.TerminationReason)
+	require.NotNil(t, stored.ExitCode)
+	assert.Equal(t, 3, *stored.ExitCode)
+	assert.Empty(t, stored.ExitSignal)
+	assert.Equal(t, &exitedAt, stored.TerminatedAt)
+
+	setAppExit(&stored, &guest.AppExit{ExitCode: -1, Signal: "SIGKILL", ExitedAt: exitedAt})
+	assert.Equal(t, TerminationSignaled, stored.TerminationReason)
+	assert.Equal(t, -1, *stored.ExitCode)
+	assert.Equal(t, "SIGKILL", stored.ExitSignal)
+
+	clearTermination(&stored)
+	assert.Equal(t, StoredMetadata{}, stored)
+}
+
+func TestRecordStopTermination(t *testing.T) {
+	ctx := context.Background()
+	m := &manager{}
+
+	// Guest agent unreachable: the application is taken to be running
+	stored := StoredMetadata{
+		Id:             "stop-termination",
+		HypervisorType: hypervisor.TypeCloudHypervisor,
+		VsockSocket:    filepath.Join(t.TempDir(), "vsock.sock"),
+		VsockCID:       3,
+	}
+	m.recordStopTermination(ctx, &stored)
+	assert.Equal(t, TerminationStopped, stored.TerminationReason)
+	assert.Nil(t, stored.ExitCode)
+	assert.NotNil(t, stored.TerminatedAt)
+
+	// An exit recorded earlier is kept
+	code := 0
+	stored = StoredMetadata{TerminationReason: TerminationExited, ExitCode: &code}
+	m.recordStopTermination(ctx, &stored)
+	assert.Equal(t, TerminationExited, stored.TerminationReason)
+}
+
+func TestRecordAppExit(t *testing.T) {
+	mgr, _ := setupTestManager(t)
+	ctx := context.Background()
+
+	id := "app-exit"
+	pid := 4242
+	require.NoError(t, mgr.ensureDirectories(id))
+	require.NoError(t, mgr.saveMetadata(&metadata{StoredMetadata: StoredMetadata{
+		Id:            id,
+		Name:          id,
+		DataDir:       mgr.paths.InstanceDir(id),
+		CreatedAt:     time.Now(),
+		HypervisorPID: &pid,
+	}}))
+
+	// Exit of an earlier boot is ignored
+	mgr.recordAppExit(ctx, id, pid+1, &guest.AppExit{ExitCode: 1, ExitedAt: time.Now()})
+	meta, err := mgr.loadMetadata(id)
+	require.NoError(t, err)
+	assert.Empty(t, meta.TerminationReason)
+
+	mgr.recordAppExit(ctx, id, pid, &guest.AppExit{ExitCode: 1, ExitedAt: time.Now()})
+	meta, err = mgr.loadMetadata(id)
+	require.NoError(t, err)
+	assert.Equal(t, TerminationExited, meta.TerminationReason)
+	require.NotNil(t, meta.ExitCode)
+	assert.Equal(t, 1, *meta.ExitCode)
+}
@@ -0,0 +1,206 @@
+package instances
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"syscall"
+
+	"github.com/kernel/hypeman/lib/logger"
+	"golang.org/x/sys/unix"
+)
+
+// holeChunkSize is the granularity at which zeroed data is punched out
+const holeChunkSize = 64 * 1024
+
+// DiskTrimResult summarizes a TrimDisks run
+type DiskTrimResult struct {
+	Instances      int   // Instances whose disks were checked
+	Files          int   // Disk files checked
+	ReclaimedBytes int64 // Host disk space freed
+}
+
+// TrimDisks punches holes where the writable disk files of stopped and
+// standby instances (overlay, volume overlays, scratch and swap disks) hold
+// only zeroes, so blocks the guest freed and zeroed stop taking host space.
+// Running instances are skipped; their guests trim through discard instead.
+func (m *manager) TrimDisks(ctx context.Context) (*DiskTrimResult, error) {
+	insts, err := m.listInstances(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("list instances: %w", err)
+	}
+
+	res := &DiskTrimResult{}
+	var errs []error
+	for _, inst := range insts {
+		if inst.State.RequiresVMM() || inst.State == StateUnknown {
+			continue
+		}
+		if err := ctx.Err(); err != nil {
+			return res, err
+		}
+		if err := m.trimInstanceDisks(ctx, inst.Id, res); err != nil {
+			errs = append(errs, fmt.Errorf("instance %s: %w", inst.Id, err))
+		}
+	}
+	return res, errors.Join(errs...)
+}
+
+// trimInstanceDisks punches holes in one instance's writable disk files,
+// holding its lock so it can't start meanwhile
+func (m *manager) trimInstanceDisks(ctx context.Context, id string, res *DiskTrimResult) error {
+	lock := m.getInstanceLock(id)
+	lock.acquire("trim_disks")
+	defer lock.release()
+
+	inst, err := m.getInstance(ctx, id)
+	if err != nil {
+		return err
+	}
+	if inst.State.RequiresVMM() || inst.State == StateUnknown {
+		return nil
+	}
+
+	res.Instances++
+	var errs []error
+	for _, path := range m.writableDiskFiles(inst) {
+		reclaimed, err := digHoles(path)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		res.Files++
+		res.ReclaimedBytes += reclaimed
+		if reclaimed > 0 {
+			logger.FromContext(ctx).DebugContext(ctx, "punched holes in disk file", "instance_id", id, "path", path, "reclaimed_bytes", reclaimed)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// writableDiskFiles returns the instance-owned disk files the guest writes
+// to. Volumes belong to the volume manager and are left alone.
+func (m *manager) writableDiskFiles(inst *Instance) []string {
+	var files []string
+	if inst.RootVolume == "" {
+		files = append(files, m.paths.InstanceOverlay(inst.Id))
+	}
+	for _, vol := range inst.Volumes {
+		if vol.Overlay {
+			files = append(files, m.paths.InstanceVolumeOverlay(inst.Id, vol.VolumeID))
+		}
+	}
+	if inst.ScratchDisks != nil {
+		for i := range inst.ScratchDisks.Count {
+			files = append(files, m.paths.InstanceScratchDisk(inst.Id, i))
+		}
+	}
+	if inst.SwapSize > 0 {
+		files = append(files, m.paths.InstanceSwapDisk(inst.Id))
+	}
+	return files
+}
+
+// digHoles deallocates the all-zero chunks of a sparse file without changing
+// its size or contents, and returns how many bytes of host disk it freed
+func digHoles(path string) (int64, error) {
+	f, err := os.OpenFile(path, os.O_RDWR, 0)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	before, err := allocatedBytes(f)
+	if err != nil {
+		return 0, err
+	}
+
+	fd := int(f.Fd())
+	buf := make([]byte, holeChunkSize)
+	var off int64
+	for {
+		// Only data extents need scanning; holes are already free
+		start, err := unix.Seek(fd, off, unix.SEEK_DATA)
+		if errors.Is(err, unix.ENXIO) {
+			break
+		}
+		if err != nil {
+			return 0, fmt.Errorf("seek data in %s: %w", path, err)
+		}
+		end, err := unix.Seek(fd, start, unix.SEEK_HOLE)
+		if err != nil {
+			return 0, fmt.Errorf("seek hole in %s: %w", path, err)
+		}
+		if err := punchZeroChunks(f, start, end, buf); err != nil {
+			return 0, fmt.Errorf("punch holes in %s: %w", path, err)
+		}
+		off = end
+	}
+
+	after, err := allocatedBytes(f)
+	if err != nil {
+		return 0, err
+	}
+	return max(before-after, 0), nil
+}
+
+// punchZeroChunks punches out runs of all-zero chunks in [start, end)
+func punchZeroChunks(f *os.File, start, end int64, buf []byte) error {
+	runStart := int64(-1)
+	flush := func(at int64) error {
+		if runStart < 0 {
+			return nil
+		}
+		err := unix.Fallocate(int(f.Fd()), unix.FALLOC_FL_PUNCH_HOLE|unix.FALLOC_FL_KEEP_SIZE, runStart, at-runStart)
+		runStart = -1
+		return err
+	}
+
+	off := start
+	for off < end {
+		n, err := f.ReadAt(buf[:min(int64(len(buf)), end-off)], off)
+		if err != nil && err != io.EOF {
+			return err
+		}
+		if n == 0 {
+			break
+		}
+		if isZero(buf[:n]) {
+			if runStart < 0 {
+				runStart = off
+			}
+		} else if err := flush(off); err != nil {
+			return err
+		}
+		off += int64(n)
+	}
+	return flush(off)
+}
+
+// allocatedBytes returns the host disk space a file takes up
+func allocatedBytes(f *os.File) (int64, error) {
+	info, err := f.Stat()
+	if err != nil {
+		return 0, err
+	}
+	st, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return 0, fmt.Errorf("stat %s: no block count", f.Name())
+	}
+	return st.Blocks * 512, nil
+}
+
+// isZero reports whether b holds only zero bytes
+func isZero(b []byte) bool {
+	for _, c := range b {
+		if c != 0 {
+			return false
+		}
+	}
+	return true
+}
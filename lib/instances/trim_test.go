@@ -0,0 +1,69 @@
+package instances
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/kernel/hypeman/lib/paths"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDigHoles(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "overlay.raw")
+	data := bytes.Repeat([]byte{0xab}, holeChunkSize)
+	zeroes := make([]byte, 4*holeChunkSize)
+
+	// data | zeroes | data, fully allocated
+	content := append(append(append([]byte{}, data...), zeroes...), data...)
+	require.NoError(t, os.WriteFile(path, content, 0644))
+
+	f, err := os.Open(path)
+	require.NoError(t, err)
+	before, err := allocatedBytes(f)
+	f.Close()
+	require.NoError(t, err)
+	if before < int64(len(content)) {
+		t.Skip("filesystem did not allocate the written zeroes")
+	}
+
+	reclaimed, err := digHoles(path)
+	if err != nil {
+		t.Skipf("hole punching unsupported here: %v", err)
+	}
+	assert.Equal(t, int64(len(zeroes)), reclaimed)
+
+	got, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, content, got, "contents must not change")
+
+	// A second pass finds nothing left to punch
+	reclaimed, err = digHoles(path)
+	require.NoError(t, err)
+	assert.Zero(t, reclaimed)
+}
+
+func TestWritableDiskFiles(t *testing.T) {
+	m := &manager{paths: paths.New("/data")}
+	inst := &Instance{StoredMetadata: StoredMetadata{
+		Id: "inst-1",
+		Volumes: []VolumeAttachment{
+			{VolumeID: "vol-a", MountPath: "/a"},
+			{VolumeID: "vol-b", MountPath: "/b", Overlay: true},
+		},
+		ScratchDisks: &ScratchDisks{Count: 1, Size: 1 << 30},
+		SwapSize:     1 << 30,
+	}}
+	assert.Equal(t, []string{
+		m.paths.InstanceOverlay("inst-1"),
+		m.paths.InstanceVolumeOverlay("inst-1", "vol-b"),
+		m.paths.InstanceScratchDisk("inst-1", 0),
+		m.paths.InstanceSwapDisk("inst-1"),
+	}, m.writableDiskFiles(inst))
+
+	// Root volumes belong to the volume manager
+	inst.RootVolume = "vol-root"
+	assert.NotContains(t, m.writableDiskFiles(inst), m.paths.InstanceOverlay("inst-1"))
+}
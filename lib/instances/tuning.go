@@ -0,0 +1,88 @@
+package instances
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/kernel/hypeman/lib/vmconfig"
+)
+
+const (
+	// MaxSysctls is the maximum number of kernel parameters per instance
+	MaxSysctls = 64
+
+	// maxSysctlValueLen bounds a kernel parameter's value
+	maxSysctlValueLen = 256
+)
+
+// sysctlPattern matches a dotted sysctl name such as net.core.somaxconn or
+// net.ipv4.conf.eth0.rp_filter. The slash form and ".." are not accepted, so
+// the name always maps to a file under /proc/sys.
+var sysctlPattern = regexp.MustCompile(`^[a-z0-9_]+(\.[a-zA-Z0-9_-]+)+$`)
+
+// ulimitNames are the resources a Ulimit may set, named as in docker run
+// --ulimit and limits.conf
+var ulimitNames = map[string]bool{
+	"as":         true,
+	"core":       true,
+	"cpu":        true,
+	"data":       true,
+	"fsize":      true,
+	"locks":      true,
+	"memlock":    true,
+	"msgqueue":   true,
+	"nice":       true,
+	"nofile":     true,
+	"nproc":      true,
+	"rss":        true,
+	"rtprio":     true,
+	"rttime":     true,
+	"sigpending": true,
+	"stack":      true,
+}
+
+// validateTuning checks sysctl names and values and ulimit resources and
+// values. Whether the guest kernel knows a sysctl is only found out at boot,
+// where init logs the parameters it couldn't set.
+func validateTuning(sysctls map[string]string, ulimits []Ulimit) error {
+	if len(sysctls) > MaxSysctls {
+		return fmt.Errorf("%w: at most %d sysctls are supported, got %d", ErrInvalidTuning, MaxSysctls, len(sysctls))
+	}
+	for name, value := range sysctls {
+		if !sysctlPattern.MatchString(name) {
+			return fmt.Errorf("%w: invalid sysctl name %q", ErrInvalidTuning, name)
+		}
+		if value == "" || len(value) > maxSysctlValueLen || strings.ContainsAny(value, "\n\r\x00") {
+			return fmt.Errorf("%w: invalid value %q for sysctl %s", ErrInvalidTuning, value, name)
+		}
+	}
+
+	seen := make(map[string]bool, len(ulimits))
+	for _, u := range ulimits {
+		if !ulimitNames[u.Name] {
+			return fmt.Errorf("%w: unknown ulimit %q", ErrInvalidTuning, u.Name)
+		}
+		if seen[u.Name] {
+			return fmt.Errorf("%w: ulimit %s is set more than once", ErrInvalidTuning, u.Name)
+		}
+		seen[u.Name] = true
+		if u.Soft < -1 || u.Hard < -1 {
+			return fmt.Errorf("%w: ulimit %s must be -1 (unlimited) or non-negative", ErrInvalidTuning, u.Name)
+		}
+		if u.Hard != -1 && (u.Soft == -1 || u.Soft > u.Hard) {
+			return fmt.Errorf("%w: ulimit %s soft limit %d exceeds hard limit %d", ErrInvalidTuning, u.Name, u.Soft, u.Hard)
+		}
+	}
+	return nil
+}
+
+// applyTuning sets the guest config's sysctls and resource limits.
+func applyTuning(cfg *vmconfig.Config, inst *Instance) {
+	if len(inst.Sysctls) > 0 {
+		cfg.Sysctls = inst.Sysctls
+	}
+	for _, u := range inst.Ulimits {
+		cfg.Rlimits = append(cfg.Rlimits, vmconfig.Rlimit{Name: u.Name, Soft: u.Soft, Hard: u.Hard})
+	}
+}
@@ -0,0 +1,62 @@
+package instances
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/kernel/hypeman/lib/vmconfig"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateTuning(t *testing.T) {
+	require.NoError(t, validateTuning(nil, nil))
+	require.NoError(t, validateTuning(
+		map[string]string{"net.core.somaxconn": "4096", "fs.file-max": "2097152", "net.ipv4.ip_local_port_range": "1024 65000", "net.ipv4.conf.eth0.rp_filter": "0"},
+		[]Ulimit{{Name: "nofile", Soft: 65536, Hard: 65536}, {Name: "memlock", Soft: -1, Hard: -1}, {Name: "core", Soft: 0, Hard: -1}},
+	))
+
+	tooMany := make(map[string]string)
+	for i := range MaxSysctls + 1 {
+		tooMany[fmt.Sprintf("net.core.p%d", i)] = "1"
+	}
+	tests := []struct {
+		name    string
+		sysctls map[string]string
+		ulimits []Ulimit
+	}{
+		{"slash form", map[string]string{"net/core/somaxconn": "1"}, nil},
+		{"path traversal", map[string]string{"net..core": "1"}, nil},
+		{"no dot", map[string]string{"somaxconn": "1"}, nil},
+		{"empty value", map[string]string{"net.core.somaxconn": ""}, nil},
+		{"newline in value", map[string]string{"net.core.somaxconn": "1\n2"}, nil},
+		{"long value", map[string]string{"kernel.core_pattern": strings.Repeat("a", maxSysctlValueLen+1)}, nil},
+		{"too many sysctls", tooMany, nil},
+		{"unknown ulimit", nil, []Ulimit{{Name: "files", Soft: 1, Hard: 1}}},
+		{"duplicate ulimit", nil, []Ulimit{{Name: "nofile", Soft: 1, Hard: 1}, {Name: "nofile", Soft: 2, Hard: 2}}},
+		{"soft above hard", nil, []Ulimit{{Name: "nofile", Soft: 2, Hard: 1}}},
+		{"unlimited soft below finite hard", nil, []Ulimit{{Name: "nofile", Soft: -1, Hard: 1}}},
+		{"negative", nil, []Ulimit{{Name: "nofile", Soft: -2, Hard: -1}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.ErrorIs(t, validateTuning(tt.sysctls, tt.ulimits), ErrInvalidTuning)
+		})
+	}
+}
+
+func TestApplyTuning(t *testing.T) {
+	cfg := &vmconfig.Config{}
+	applyTuning(cfg, &Instance{})
+	assert.Nil(t, cfg.Sysctls)
+	assert.Nil(t, cfg.Rlimits)
+
+	inst := &Instance{StoredMetadata: StoredMetadata{
+		Sysctls: map[string]string{"net.core.somaxconn": "4096"},
+		Ulimits: []Ulimit{{Name: "nofile", Soft: 1024, Hard: -1}},
+	}}
+	applyTuning(cfg, inst)
+	assert.Equal(t, map[string]string{"net.core.somaxconn": "4096"}, cfg.Sysctls)
+	assert.Equal(t, []vmconfig.Rlimit{{Name: "nofile", Soft: 1024, Hard: -1}}, cfg.Rlimits)
+}
@@ -1,8 +1,10 @@
 package instances
 
 import (
+	"os"
 	"time"
 
+	"github.com/kernel/hypeman/lib/devices"
 	"github.com/kernel/hypeman/lib/hypervisor"
 )
 
@@ -16,6 +18,7 @@ const (
 	StatePaused   State = "Paused"   // VM paused (CH native)
 	StateShutdown State = "Shutdown" // VM shutdown, VMM exists (CH native)
 	StateStandby  State = "Standby"  // No VMM, snapshot exists
+	StateFailed   State = "Failed"   // No VMM, guest agent never came up within the boot timeout
 	StateUnknown  State = "Unknown"  // Failed to determine state (VMM query failed)
 )
 
@@ -28,20 +31,63 @@ type VolumeAttachment struct {
 	OverlaySize int64  // Size of overlay disk in bytes (max diff from base)
 }
 
+// ScratchDisks are throwaway sparse disks that live and die with an instance,
+// mounted at /scratch/0, /scratch/1, ...
+type ScratchDisks struct {
+	Count int   // Number of disks
+	Size  int64 // Size of each disk in bytes
+}
+
+// SharedDirectory is a host directory exposed to the guest over virtiofs
+type SharedDirectory struct {
+	HostPath  string // Directory on the host (must be under a configured shared directory root)
+	MountPath string // Mount path in guest
+	Readonly  bool   // Whether mounted read-only
+}
+
+// SecretAttachment exposes a secret to the guest. Secrets are delivered
+// through the guest agent at boot and never written to the config disk.
+type SecretAttachment struct {
+	SecretID string // Secret ID
+	EnvVar   string // Environment variable to expose the secret as; empty = file /run/secrets/<secret name>
+}
+
+// TerminationReason says why an instance's application last stopped running
+type TerminationReason string
+
+const (
+	TerminationExited      TerminationReason = "exited"       // Application exited on its own (see ExitCode)
+	TerminationSignaled    TerminationReason = "signaled"     // Application was killed by a signal (see ExitSignal)
+	TerminationStopped     TerminationReason = "stopped"      // Instance was stopped while the application ran
+	TerminationBootTimeout TerminationReason = "boot_timeout" // Guest agent didn't come up within the boot timeout
+)
+
+// BootFailure records why an instance's last boot was abandoned
+type BootFailure struct {
+	Reason         string    // Human-readable reason, e.g. "guest agent not ready after 5m0s"
+	FailedAt       time.Time // When the boot was abandoned
+	ConsoleExcerpt string    // Tail of the serial console log at the time of failure
+}
+
 // StoredMetadata represents instance metadata that is persisted to disk
 type StoredMetadata struct {
 	// Identification
-	Id    string // Auto-generated CUID2
-	Name  string
-	Image string // OCI reference
+	Id        string // Auto-generated CUID2
+	Name      string
+	Image     string // OCI reference (empty when booting from RootVolume)
+	Tenant    string // Optional tenant label for access scoping
+	CreatedBy string // Principal that created the instance, for quotas (empty = unknown)
 
 	// Resources (matching Cloud Hypervisor terminology)
 	Size                     int64 // Base memory in bytes
 	HotplugSize              int64 // Hotplug memory in bytes
 	OverlaySize              int64 // Overlay disk size in bytes
+	SwapSize                 int64 // Swap disk size in bytes, 0 = no swap
 	Vcpus                    int
 	NetworkBandwidthDownload int64 // Download rate limit in bytes/sec (external→VM), 0 = auto
 	NetworkBandwidthUpload   int64 // Upload rate limit in bytes/sec (VM→external), 0 = auto
+	NetworkDownloadBurst     int64 // Download burst in bytes (TBF bucket), 0 = derived from rate
+	NetworkUploadBurst       int64 // Upload burst in bytes (HTB burst), 0 = tc default
 	DiskIOBps                int64 // Disk I/O rate limit in bytes/sec, 0 = auto
 
 	// Configuration
@@ -50,14 +96,60 @@ type StoredMetadata struct {
 	IP             string // Assigned IP address (empty if NetworkEnabled=false)
 	MAC            string // Assigned MAC address (empty if NetworkEnabled=false)
 
+	// Free-form key/value metadata, readable by the guest
+	Labels map[string]string
+
+	// Workload overrides (image values are used when unset)
+	Entrypoint []string // Overrides the image entrypoint when non-nil
+	Cmd        []string // Overrides the image cmd when non-nil
+	Workdir    string   // Overrides the image working directory when set
+
 	// Attached volumes
-	Volumes []VolumeAttachment // Volumes attached to this instance
+	RootVolume   string             // Volume booted as the writable root filesystem instead of an image
+	Volumes      []VolumeAttachment // Volumes attached to this instance
+	ScratchDisks *ScratchDisks      // Throwaway disks deleted with the instance
+
+	// Shared host directories (virtiofs)
+	SharedDirectories []SharedDirectory // Host directories shared with this instance
+	VirtiofsdPIDs     []int             // virtiofsd process IDs, one per shared directory (may be stale)
+
+	// Attached secrets
+	Secrets []SecretAttachment // Secrets delivered to the guest at boot
+
+	// Extra guest kernel parameters, appended to hypeman's own
+	KernelArgs []string
+
+	// How the instance boots: images.FirmwareDirect (hypeman's kernel and
+	// init; empty for instances created before firmware was recorded) or
+	// images.FirmwareUEFI (the hypervisor's UEFI firmware, from a copy of
+	// the image's whole disk, without the guest agent)
+	Firmware string
+
+	// Guest tuning applied by init before the application starts
+	Sysctls map[string]string // Kernel parameters by dotted name, e.g. net.core.somaxconn
+	Ulimits []Ulimit          // Resource limits the application inherits
+
+	// Nested virtualization: the guest can run its own KVM VMs
+	NestedVirt bool
+
+	// Graceful shutdown
+	ShutdownGracePeriod *time.Duration // Time the application gets to exit on stop or delete (nil = server default)
 
 	// Timestamps (stored for historical tracking)
 	CreatedAt time.Time
 	StartedAt *time.Time // Last time VM was started
 	StoppedAt *time.Time // Last time VM was stopped
 
+	// Boot watchdog
+	AgentReadyAt *time.Time   // When the guest agent first answered after the last start
+	BootFailure  *BootFailure // Set when the last boot timed out; cleared on start
+
+	// Application termination (cleared on start)
+	TerminationReason TerminationReason // Why the application last stopped ("" = running or never started)
+	ExitCode          *int              // Application exit code (-1 if killed by a signal); nil unless it exited
+	ExitSignal        string            // Signal that killed the application, e.g. "SIGKILL"
+	TerminatedAt      *time.Time        // When the application stopped
+
 	// Versions
 	KernelVersion string // Kernel version (e.g., "ch-v6.12.9")
 
@@ -77,9 +169,28 @@ type StoredMetadata struct {
 	// Attached devices (GPU passthrough)
 	Devices []string // Device IDs attached to this instance
 
+	// USB device passthrough (QEMU only)
+	USBDevices    []string // Host USB devices, as vendor:product or bus-port selectors
+	USBController bool     // Whether the running VM has a USB controller to hot-plug devices into
+
 	// GPU configuration (vGPU mode)
-	GPUProfile  string // vGPU profile name (e.g., "L40S-1Q")
-	GPUMdevUUID string // mdev device UUID
+	GPUProfile  string               // vGPU profile name (e.g., "L40S-1Q")
+	GPUMdevUUID string               // mdev device UUID
+	GPUMIG      *devices.MIGInstance // GPU instance backing a MIG-backed vGPU
+
+	// Host placement (nil = no hints)
+	Placement *PlacementHints
+
+	// First-boot guest configuration
+	UserData *UserData
+	Resolver *ResolverConfig // nil = hypeman manages resolv.conf and hosts
+	Links    []string        // Names of peers listed in /etc/hosts
+
+	// Idle standby
+	IdlePolicy *IdlePolicy // nil = server defaults
+
+	// Scheduled state changes, e.g. start on weekday mornings
+	Schedules []Schedule
 }
 
 // Instance represents a virtual machine instance with derived runtime state
@@ -103,23 +214,110 @@ type GPUConfig struct {
 	Profile string // vGPU profile name (e.g., "L40S-1Q")
 }
 
+// PlacementHints guide where an instance's vGPU and vCPUs land on the host.
+// GPU hints only order the VFs a vGPU may use; a NUMA node also pins vCPUs.
+type PlacementHints struct {
+	GPUPolicy       devices.GPUPlacementPolicy // Optional: pack or spread vGPUs across parent GPUs
+	GPUAffinity     []string                   // Instance IDs whose parent GPU the vGPU should share
+	GPUAntiAffinity []string                   // Instance IDs whose parent GPUs the vGPU should avoid
+	NUMANode        *int                       // Optional: host NUMA node for the vGPU and vCPUs
+}
+
+// UserData is first-boot guest configuration supplied at instance creation
+type UserData struct {
+	CloudConfig string            // Raw cloud-init user-data, written to the guest's NoCloud seed
+	Env         map[string]string // Environment variables (override image env, overridden by instance env)
+	Files       []UserDataFile    // Files written into the guest filesystem on first boot
+}
+
+// UserDataFile is a file written into the guest filesystem on first boot
+type UserDataFile struct {
+	Path    string      // Absolute path in the guest
+	Content []byte      // File contents
+	Mode    os.FileMode // Permission bits (default 0644)
+}
+
+// ResolverConfig controls the guest's /etc/resolv.conf and /etc/hosts.
+// The zero value keeps the default: both files are generated at boot.
+type ResolverConfig struct {
+	SkipResolvConf bool        // Leave the image's /etc/resolv.conf untouched
+	Nameservers    []string    // Replace the network's DNS server in resolv.conf
+	SkipHosts      bool        // Leave the image's /etc/hosts and hostname untouched
+	ExtraHosts     []HostEntry // Additional /etc/hosts entries
+}
+
+// HostEntry is an additional /etc/hosts entry
+type HostEntry struct {
+	Hostname string
+	IP       string
+}
+
+// Ulimit is a resource limit set in the guest before the application starts,
+// like docker run --ulimit
+type Ulimit struct {
+	Name string // Resource, e.g. "nofile" for RLIMIT_NOFILE
+	Soft int64  // -1 = unlimited
+	Hard int64  // -1 = unlimited
+}
+
 // CreateInstanceRequest is the domain request for creating an instance
 type CreateInstanceRequest struct {
 	Name                     string             // Required
-	Image                    string             // Required: OCI reference
+	Image                    string             // OCI reference (required unless RootVolume is set)
+	RootVolume               string             // Optional: volume to boot as the writable root filesystem instead of an image
 	Size                     int64              // Base memory in bytes (default: 1GB)
 	HotplugSize              int64              // Hotplug memory in bytes (default: 3GB)
+	FixedMemory              bool               // Optional: no hotplug memory, so the VMM cgroup caps memory at Size
 	OverlaySize              int64              // Overlay disk size in bytes (default: 10GB)
+	SwapSize                 int64              // Optional: swap disk size in bytes (0 = no swap)
 	Vcpus                    int                // Default 2
 	NetworkBandwidthDownload int64              // Download rate limit bytes/sec (0 = auto, proportional to CPU)
 	NetworkBandwidthUpload   int64              // Upload rate limit bytes/sec (0 = auto, proportional to CPU)
+	NetworkDownloadBurst     int64              // Download burst bytes (0 = derived from rate)
+	NetworkUploadBurst       int64              // Upload burst bytes (0 = tc default)
 	DiskIOBps                int64              // Disk I/O rate limit bytes/sec (0 = auto, proportional to CPU)
 	Env                      map[string]string  // Optional environment variables
+	Labels                   map[string]string  // Optional: free-form key/value metadata
+	Entrypoint               []string           // Optional: entrypoint override (non-nil overrides, clearing image cmd unless Cmd is set)
+	Cmd                      []string           // Optional: cmd override (non-nil overrides)
+	Workdir                  string             // Optional: working directory override
 	NetworkEnabled           bool               // Whether to enable networking (uses default network)
 	Devices                  []string           // Device IDs or names to attach (GPU passthrough)
+	USBDevices               []string           // Host USB devices to pass through, as vendor:product or bus-port (QEMU only)
 	Volumes                  []VolumeAttachment // Volumes to attach at creation time
+	ScratchDisks             *ScratchDisks      // Optional: throwaway sparse disks deleted with the instance
+	SharedDirectories        []SharedDirectory  // Host directories to share at creation time
+	Secrets                  []SecretAttachment // Secrets to expose to the guest
+	KernelArgs               []string           // Optional: extra guest kernel parameters
+	Firmware                 string             // Optional: images.FirmwareDirect or images.FirmwareUEFI (defaults to the image's)
+	Sysctls                  map[string]string  // Optional: guest kernel parameters set at boot
+	Ulimits                  []Ulimit           // Optional: guest resource limits set at boot
+	EnableNestedVirt         bool               // Optional: let the guest use KVM (requires host support)
+	ShutdownGracePeriod      *time.Duration     // Optional: time the application gets to exit on stop or delete
 	Hypervisor               hypervisor.Type    // Optional: hypervisor type (defaults to config)
 	GPU                      *GPUConfig         // Optional: vGPU configuration
+	Placement                *PlacementHints    // Optional: GPU and NUMA placement hints
+	Tenant                   string             // Optional: tenant label for access scoping
+	CreatedBy                string             // Optional: principal creating the instance, for quotas
+	UserData                 *UserData          // Optional: first-boot guest configuration
+	Resolver                 *ResolverConfig    // Optional: resolv.conf and hosts control
+	Links                    []string           // Optional: names of peers listed in /etc/hosts
+	IdlePolicy               *IdlePolicy        // Optional: idle standby overrides
+	Schedules                []Schedule         // Optional: scheduled start, standby and stop
+}
+
+// DeleteInstanceRequest is the domain request for deleting an instance
+type DeleteInstanceRequest struct {
+	GracePeriod *time.Duration // Time the application gets to exit (nil = the instance's grace period)
+}
+
+// UpdateNetworkBandwidthRequest is the domain request for changing an instance's
+// bandwidth limits. Nil fields are left unchanged.
+type UpdateNetworkBandwidthRequest struct {
+	DownloadBps   *int64 // Download rate limit bytes/sec
+	UploadBps     *int64 // Upload rate limit bytes/sec
+	DownloadBurst *int64 // Download burst bytes (0 = derived from rate)
+	UploadBurst   *int64 // Upload burst bytes (0 = tc default)
 }
 
 // AttachVolumeRequest is the domain request for attaching a volume (used for API compatibility)
@@ -0,0 +1,135 @@
+package instances
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kernel/hypeman/lib/hypervisor"
+	"github.com/kernel/hypeman/lib/logger"
+)
+
+// clockTicks is USER_HZ, the unit of the CPU times in /proc/<pid>/stat
+const clockTicks = 100
+
+// CPUTime returns the CPU time a running instance's hypervisor process,
+// vCPU threads included, has used since it started. It resets when the
+// instance is restarted or restored.
+func (m *manager) CPUTime(ctx context.Context, id string) (time.Duration, error) {
+	inst, err := m.GetInstance(ctx, id)
+	if err != nil {
+		return 0, err
+	}
+	if inst.State != StateRunning || inst.HypervisorPID == nil {
+		return 0, fmt.Errorf("%w: instance is %s", ErrInvalidState, inst.State)
+	}
+	data, err := os.ReadFile(fmt.Sprintf("/proc/%d/stat", *inst.HypervisorPID))
+	if err != nil {
+		return 0, fmt.Errorf("read hypervisor process stat: %w", err)
+	}
+	return parseProcCPUTime(string(data))
+}
+
+// ResourceUsage is what a running instance's hypervisor process has used
+// since it started
+type ResourceUsage struct {
+	CPUTime         time.Duration // CPU time of the hypervisor process, vCPU threads included
+	PeakMemoryBytes int64         // Peak resident memory of the hypervisor process, guest memory included
+	NetworkRxBytes  int64         // Bytes the guest received, from hypervisor counters (0 if unsupported)
+	NetworkTxBytes  int64         // Bytes the guest sent, from hypervisor counters (0 if unsupported)
+}
+
+// ResourceUsage returns what a running instance's hypervisor process has
+// used since it started. Like CPUTime, it resets when the instance is
+// restarted or restored.
+func (m *manager) ResourceUsage(ctx context.Context, id string) (*ResourceUsage, error) {
+	inst, err := m.GetInstance(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if inst.State != StateRunning || inst.HypervisorPID == nil {
+		return nil, fmt.Errorf("%w: instance is %s", ErrInvalidState, inst.State)
+	}
+	stat, err := os.ReadFile(fmt.Sprintf("/proc/%d/stat", *inst.HypervisorPID))
+	if err != nil {
+		return nil, fmt.Errorf("read hypervisor process stat: %w", err)
+	}
+	status, err := os.ReadFile(fmt.Sprintf("/proc/%d/status", *inst.HypervisorPID))
+	if err != nil {
+		return nil, fmt.Errorf("read hypervisor process status: %w", err)
+	}
+
+	usage := &ResourceUsage{}
+	if usage.CPUTime, err = parseProcCPUTime(string(stat)); err != nil {
+		return nil, err
+	}
+	if usage.PeakMemoryBytes, err = parseProcPeakMemory(string(status)); err != nil {
+		return nil, err
+	}
+
+	// Network counters are best effort: not every hypervisor has them
+	hv, err := m.getHypervisor(inst.SocketPath, inst.HypervisorType)
+	if err == nil && hv.Capabilities().SupportsCounters {
+		counters, err := hv.Counters(ctx)
+		if err != nil {
+			logger.FromContext(ctx).WarnContext(ctx, "failed to read hypervisor counters", "instance_id", id, "error", err)
+		} else {
+			usage.NetworkRxBytes, usage.NetworkTxBytes = networkBytes(counters)
+		}
+	}
+	return usage, nil
+}
+
+// parseProcPeakMemory returns VmHWM, the peak resident set size, from the
+// contents of /proc/<pid>/status
+func parseProcPeakMemory(status string) (int64, error) {
+	for _, line := range strings.Split(status, "\n") {
+		value, ok := strings.CutPrefix(line, "VmHWM:")
+		if !ok {
+			continue
+		}
+		kb, err := strconv.ParseInt(strings.TrimSuffix(strings.TrimSpace(value), " kB"), 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("malformed VmHWM: %q", line)
+		}
+		return kb * 1024, nil
+	}
+	return 0, fmt.Errorf("no VmHWM in status")
+}
+
+// networkBytes sums the rx_bytes and tx_bytes counters of a VM's network
+// devices
+func networkBytes(counters hypervisor.Counters) (rx, tx int64) {
+	for _, device := range counters {
+		rx += device["rx_bytes"]
+		tx += device["tx_bytes"]
+	}
+	return rx, tx
+}
+
+// parseProcCPUTime returns utime+stime from the contents of /proc/<pid>/stat.
+// The command name may contain spaces and parentheses, so fields are counted
+// from the last ')'.
+func parseProcCPUTime(stat string) (time.Duration, error) {
+	end := strings.LastIndexByte(stat, ')')
+	if end < 0 {
+		return 0, fmt.Errorf("malformed stat: %q", stat)
+	}
+	// Fields after the command start at field 3 (state); utime and stime are 14 and 15
+	fields := strings.Fields(stat[end+1:])
+	if len(fields) < 13 {
+		return 0, fmt.Errorf("malformed stat: %q", stat)
+	}
+	var ticks uint64
+	for _, f := range fields[11:13] {
+		n, err := strconv.ParseUint(f, 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("malformed stat: %w", err)
+		}
+		ticks += n
+	}
+	return time.Duration(ticks) * time.Second / clockTicks, nil
+}
@@ -0,0 +1,42 @@
+package instances
+
+import (
+	"testing"
+	"time"
+
+	"github.com/kernel/hypeman/lib/hypervisor"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseProcCPUTime(t *testing.T) {
+	stat := "4242 (cloud (hyper) visor) S 1 4242 4242 0 -1 4194560 12345 0 0 0 250 75 0 0 20 0 9 0 1000 1234567 890 18446744073709551615"
+	d, err := parseProcCPUTime(stat)
+	require.NoError(t, err)
+	assert.Equal(t, 3250*time.Millisecond, d)
+
+	_, err = parseProcCPUTime("4242 (qemu")
+	assert.Error(t, err)
+	_, err = parseProcCPUTime("4242 (qemu) S 1 2")
+	assert.Error(t, err)
+}
+
+func TestParseProcPeakMemory(t *testing.T) {
+	status := "Name:\tcloud-hyperviso\nVmPeak:\t 2270012 kB\nVmHWM:\t  524288 kB\nVmRSS:\t  262144 kB\n"
+	peak, err := parseProcPeakMemory(status)
+	require.NoError(t, err)
+	assert.Equal(t, int64(512*1024*1024), peak)
+
+	_, err = parseProcPeakMemory("Name:\tqemu\n")
+	assert.Error(t, err)
+}
+
+func TestNetworkBytes(t *testing.T) {
+	rx, tx := networkBytes(hypervisor.Counters{
+		"_net2":  {"rx_bytes": 1000, "tx_bytes": 200, "rx_frames": 10},
+		"_net3":  {"rx_bytes": 24, "tx_bytes": 6},
+		"_disk0": {"read_bytes": 4096, "write_bytes": 512},
+	})
+	assert.Equal(t, int64(1024), rx)
+	assert.Equal(t, int64(206), tx)
+}
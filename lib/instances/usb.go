@@ -0,0 +1,261 @@
+package instances
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/kernel/hypeman/lib/devices"
+	"github.com/kernel/hypeman/lib/hypervisor"
+	"github.com/kernel/hypeman/lib/logger"
+)
+
+// MaxUSBDevicesPerInstance is the maximum number of USB devices passed
+// through to an instance
+const MaxUSBDevicesPerInstance = 8
+
+// supportsUSBPassthrough reports whether a hypervisor can pass USB devices
+// through. Cloud Hypervisor emulates no USB controller.
+func supportsUSBPassthrough(hvType hypervisor.Type) bool {
+	return hvType == hypervisor.TypeQEMU
+}
+
+// normalizeUSBDevices validates USB device selectors and returns them in
+// canonical form (lowercase vendor:product or bus-port)
+func normalizeUSBDevices(selectors []string, hvType hypervisor.Type) ([]string, error) {
+	if len(selectors) == 0 {
+		return nil, nil
+	}
+	if !supportsUSBPassthrough(hvType) {
+		return nil, fmt.Errorf("%w: hypervisor %s", ErrUSBNotSupported, hvType)
+	}
+	if len(selectors) > MaxUSBDevicesPerInstance {
+		return nil, fmt.Errorf("%w: at most %d USB devices per instance", devices.ErrInvalidUSBSelector, MaxUSBDevicesPerInstance)
+	}
+	normalized := make([]string, 0, len(selectors))
+	for _, s := range selectors {
+		sel, err := devices.ParseUSBSelector(s)
+		if err != nil {
+			return nil, err
+		}
+		if slices.Contains(normalized, sel.String()) {
+			return nil, fmt.Errorf("%w: %s listed twice", devices.ErrInvalidUSBSelector, sel)
+		}
+		normalized = append(normalized, sel.String())
+	}
+	return normalized, nil
+}
+
+// usbDeviceID returns the hypervisor device ID for a USB selector, e.g.
+// "usb-1050-0407" or "usb-1-2.3"
+func usbDeviceID(sel devices.USBSelector) string {
+	return "usb-" + strings.ReplaceAll(sel.String(), ":", "-")
+}
+
+// usbDeviceConfig returns the hypervisor configuration passing through the
+// USB device a selector identifies
+func usbDeviceConfig(selector string) (hypervisor.USBDeviceConfig, error) {
+	sel, err := devices.ParseUSBSelector(selector)
+	if err != nil {
+		return hypervisor.USBDeviceConfig{}, err
+	}
+	return hypervisor.USBDeviceConfig{
+		ID:        usbDeviceID(sel),
+		VendorID:  sel.VendorID,
+		ProductID: sel.ProductID,
+		HostBus:   sel.Bus,
+		HostPort:  sel.Port,
+	}, nil
+}
+
+// usbDeviceConfigs returns the hypervisor configuration for an instance's USB devices
+func usbDeviceConfigs(selectors []string) ([]hypervisor.USBDeviceConfig, error) {
+	var configs []hypervisor.USBDeviceConfig
+	for _, s := range selectors {
+		cfg, err := usbDeviceConfig(s)
+		if err != nil {
+			return nil, err
+		}
+		configs = append(configs, cfg)
+	}
+	return configs, nil
+}
+
+// warnMissingUSBDevice logs when no host device matches a selector. The
+// device is still passed through: the hypervisor connects it once plugged in.
+func warnMissingUSBDevice(ctx context.Context, instanceID, selector string) {
+	sel, err := devices.ParseUSBSelector(selector)
+	if err != nil {
+		return
+	}
+	if _, err := devices.FindUSBDevice(sel); errors.Is(err, devices.ErrUSBDeviceNotFound) {
+		logger.FromContext(ctx).WarnContext(ctx, "USB device not plugged in, it will be connected when it is",
+			"instance_id", instanceID, "usb_device", selector)
+	}
+}
+
+// attachUSBDevice passes a host USB device through to an instance: hot-plugged
+// if it's running, at its next boot if it's stopped.
+func (m *manager) attachUSBDevice(ctx context.Context, id string, selector string) (*Instance, error) {
+	log := logger.FromContext(ctx)
+
+	meta, err := m.loadMetadata(id)
+	if err != nil {
+		return nil, err
+	}
+	stored := &meta.StoredMetadata
+
+	normalized, err := normalizeUSBDevices([]string{selector}, stored.HypervisorType)
+	if err != nil {
+		return nil, err
+	}
+	selector = normalized[0]
+	if slices.Contains(stored.USBDevices, selector) {
+		return nil, fmt.Errorf("%w: %s", ErrUSBDeviceAttached, selector)
+	}
+	if len(stored.USBDevices) >= MaxUSBDevicesPerInstance {
+		return nil, fmt.Errorf("%w: at most %d USB devices per instance", devices.ErrInvalidUSBSelector, MaxUSBDevicesPerInstance)
+	}
+
+	inst := m.toInstance(ctx, meta)
+	switch inst.State {
+	case StateRunning:
+		if !stored.USBController {
+			return nil, fmt.Errorf("%w: instance was started without a USB controller, restart it to attach USB devices", ErrInvalidState)
+		}
+		cfg, err := usbDeviceConfig(selector)
+		if err != nil {
+			return nil, err
+		}
+		hv, err := m.getHypervisor(inst.SocketPath, stored.HypervisorType)
+		if err != nil {
+			return nil, fmt.Errorf("create hypervisor client: %w", err)
+		}
+		if err := hv.AttachUSBDevice(ctx, cfg); err != nil {
+			log.ErrorContext(ctx, "failed to hot-plug USB device", "instance_id", id, "usb_device", selector, "error", err)
+			return nil, fmt.Errorf("attach USB device: %w", err)
+		}
+	case StateStopped:
+		// Passed through at the next boot
+	default:
+		return nil, fmt.Errorf("%w: cannot attach USB devices in state %s", ErrInvalidState, inst.State)
+	}
+	warnMissingUSBDevice(ctx, id, selector)
+
+	stored.USBDevices = append(stored.USBDevices, selector)
+	if err := m.saveMetadata(meta); err != nil {
+		return nil, fmt.Errorf("save metadata: %w", err)
+	}
+
+	log.InfoContext(ctx, "USB device attached", "instance_id", id, "usb_device", selector, "hotplug", inst.State == StateRunning)
+	result := m.toInstance(ctx, meta)
+	return &result, nil
+}
+
+// detachUSBDevice removes a USB device from an instance, hot-unplugging it
+// if the instance is running.
+func (m *manager) detachUSBDevice(ctx context.Context, id string, selector string) (*Instance, error) {
+	log := logger.FromContext(ctx)
+
+	meta, err := m.loadMetadata(id)
+	if err != nil {
+		return nil, err
+	}
+	stored := &meta.StoredMetadata
+
+	sel, err := devices.ParseUSBSelector(selector)
+	if err != nil {
+		return nil, err
+	}
+	selector = sel.String()
+	if !slices.Contains(stored.USBDevices, selector) {
+		return nil, fmt.Errorf("%w: %s", ErrUSBDeviceNotAttached, selector)
+	}
+
+	inst := m.toInstance(ctx, meta)
+	switch inst.State {
+	case StateRunning:
+		hv, err := m.getHypervisor(inst.SocketPath, stored.HypervisorType)
+		if err != nil {
+			return nil, fmt.Errorf("create hypervisor client: %w", err)
+		}
+		if err := hv.DetachUSBDevice(ctx, usbDeviceID(sel)); err != nil {
+			log.ErrorContext(ctx, "failed to hot-unplug USB device", "instance_id", id, "usb_device", selector, "error", err)
+			return nil, fmt.Errorf("detach USB device: %w", err)
+		}
+	case StateStopped:
+	default:
+		return nil, fmt.Errorf("%w: cannot detach USB devices in state %s", ErrInvalidState, inst.State)
+	}
+
+	stored.USBDevices = slices.DeleteFunc(stored.USBDevices, func(s string) bool { return s == selector })
+	if err := m.saveMetadata(meta); err != nil {
+		return nil, fmt.Errorf("save metadata: %w", err)
+	}
+
+	log.InfoContext(ctx, "USB device detached", "instance_id", id, "usb_device", selector, "hotplug", inst.State == StateRunning)
+	result := m.toInstance(ctx, meta)
+	return &result, nil
+}
+
+// reconcileUSBDevices brings a running instance's USB devices in line with
+// its metadata, e.g. after the server stopped between a hot-plug and saving
+// it. Returns the number of devices attached or detached.
+func (m *manager) reconcileUSBDevices(ctx context.Context, inst *Instance) (int, error) {
+	if !supportsUSBPassthrough(inst.HypervisorType) || !inst.USBController {
+		return 0, nil
+	}
+	hv, err := m.getHypervisor(inst.SocketPath, inst.HypervisorType)
+	if err != nil {
+		return 0, fmt.Errorf("create hypervisor client: %w", err)
+	}
+	attached, err := hv.ListUSBDevices(ctx)
+	if err != nil {
+		return 0, err
+	}
+	wanted, err := usbDeviceConfigs(inst.USBDevices)
+	if err != nil {
+		return 0, err
+	}
+	toAttach, toDetach := diffUSBDevices(wanted, attached)
+
+	log := logger.FromContext(ctx)
+	changed := 0
+	for _, cfg := range toAttach {
+		if err := hv.AttachUSBDevice(ctx, cfg); err != nil {
+			log.WarnContext(ctx, "failed to reattach USB device", "instance_id", inst.Id, "usb_device", cfg.ID, "error", err)
+			continue
+		}
+		changed++
+	}
+	for _, id := range toDetach {
+		if err := hv.DetachUSBDevice(ctx, id); err != nil {
+			log.WarnContext(ctx, "failed to detach unknown USB device", "instance_id", inst.Id, "usb_device", id, "error", err)
+			continue
+		}
+		changed++
+	}
+	return changed, nil
+}
+
+// diffUSBDevices returns the wanted devices missing from the attached IDs,
+// and the attached IDs that aren't wanted
+func diffUSBDevices(wanted []hypervisor.USBDeviceConfig, attached []string) ([]hypervisor.USBDeviceConfig, []string) {
+	var toAttach []hypervisor.USBDeviceConfig
+	wantedIDs := make(map[string]bool, len(wanted))
+	for _, cfg := range wanted {
+		wantedIDs[cfg.ID] = true
+		if !slices.Contains(attached, cfg.ID) {
+			toAttach = append(toAttach, cfg)
+		}
+	}
+	var toDetach []string
+	for _, id := range attached {
+		if !wantedIDs[id] {
+			toDetach = append(toDetach, id)
+		}
+	}
+	return toAttach, toDetach
+}
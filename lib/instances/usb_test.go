@@ -0,0 +1,53 @@
+package instances
+
+import (
+	"testing"
+
+	"github.com/kernel/hypeman/lib/devices"
+	"github.com/kernel/hypeman/lib/hypervisor"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNormalizeUSBDevices(t *testing.T) {
+	normalized, err := normalizeUSBDevices([]string{"1050:0407", "0BDA:8153", "1-2.3"}, hypervisor.TypeQEMU)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"1050:0407", "0bda:8153", "1-2.3"}, normalized)
+
+	normalized, err = normalizeUSBDevices(nil, hypervisor.TypeCloudHypervisor)
+	require.NoError(t, err)
+	assert.Nil(t, normalized)
+
+	_, err = normalizeUSBDevices([]string{"1050:0407"}, hypervisor.TypeCloudHypervisor)
+	assert.ErrorIs(t, err, ErrUSBNotSupported)
+
+	_, err = normalizeUSBDevices([]string{"1050:0407", "1050:0407"}, hypervisor.TypeQEMU)
+	assert.ErrorIs(t, err, devices.ErrInvalidUSBSelector, "duplicates are rejected")
+
+	_, err = normalizeUSBDevices([]string{"yubikey"}, hypervisor.TypeQEMU)
+	assert.ErrorIs(t, err, devices.ErrInvalidUSBSelector)
+}
+
+func TestUSBDeviceConfig(t *testing.T) {
+	cfg, err := usbDeviceConfig("1050:0407")
+	require.NoError(t, err)
+	assert.Equal(t, hypervisor.USBDeviceConfig{ID: "usb-1050-0407", VendorID: "1050", ProductID: "0407"}, cfg)
+
+	cfg, err = usbDeviceConfig("1-2.3")
+	require.NoError(t, err)
+	assert.Equal(t, hypervisor.USBDeviceConfig{ID: "usb-1-2.3", HostBus: 1, HostPort: "2.3"}, cfg)
+}
+
+func TestDiffUSBDevices(t *testing.T) {
+	wanted, err := usbDeviceConfigs([]string{"1050:0407", "1-2.3"})
+	require.NoError(t, err)
+
+	toAttach, toDetach := diffUSBDevices(wanted, []string{"usb-1-2.3", "usb-0403-6001"})
+	require.Len(t, toAttach, 1)
+	assert.Equal(t, "usb-1050-0407", toAttach[0].ID)
+	assert.Equal(t, []string{"usb-0403-6001"}, toDetach)
+
+	toAttach, toDetach = diffUSBDevices(wanted, []string{"usb-1050-0407", "usb-1-2.3"})
+	assert.Empty(t, toAttach)
+	assert.Empty(t, toDetach)
+}
@@ -0,0 +1,78 @@
+package instances
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/kernel/hypeman/lib/vmconfig"
+)
+
+// MaxUserDataSize is the maximum combined size of cloud_config and file contents.
+// User data travels on the config disk, which is sized to its contents.
+const MaxUserDataSize = 64 * 1024
+
+// defaultUserDataFileMode is applied to user data files without an explicit mode
+const defaultUserDataFileMode = 0644
+
+// validateUserData checks user data paths, modes and size limits.
+func validateUserData(ud *UserData) error {
+	if ud == nil {
+		return nil
+	}
+
+	total := len(ud.CloudConfig)
+	seen := make(map[string]bool)
+	for _, f := range ud.Files {
+		if !filepath.IsAbs(f.Path) {
+			return fmt.Errorf("%w: file path %q must be absolute", ErrInvalidUserData, f.Path)
+		}
+		if filepath.Clean(f.Path) != f.Path || f.Path == "/" {
+			return fmt.Errorf("%w: file path %q must be a clean path to a file", ErrInvalidUserData, f.Path)
+		}
+		if seen[f.Path] {
+			return fmt.Errorf("%w: duplicate file path %q", ErrInvalidUserData, f.Path)
+		}
+		seen[f.Path] = true
+		if f.Mode&^0777 != 0 {
+			return fmt.Errorf("%w: file %q: mode %#o may only contain permission bits", ErrInvalidUserData, f.Path, f.Mode)
+		}
+		total += len(f.Content)
+	}
+	for k := range ud.Env {
+		if k == "" || strings.ContainsAny(k, "=\x00") {
+			return fmt.Errorf("%w: invalid environment variable name %q", ErrInvalidUserData, k)
+		}
+	}
+	if total > MaxUserDataSize {
+		return fmt.Errorf("%w: cloud_config and file contents total %d bytes, limit is %d", ErrInvalidUserData, total, MaxUserDataSize)
+	}
+	return nil
+}
+
+// guestUserData converts an instance's user data to the guest config representation.
+// Returns nil when there is nothing for the guest init to apply.
+func guestUserData(inst *Instance) *vmconfig.UserData {
+	ud := inst.UserData
+	if ud == nil || (ud.CloudConfig == "" && len(ud.Files) == 0) {
+		return nil
+	}
+
+	out := &vmconfig.UserData{
+		InstanceID:  inst.Id,
+		Hostname:    inst.Name,
+		CloudConfig: ud.CloudConfig,
+	}
+	for _, f := range ud.Files {
+		mode := uint32(f.Mode)
+		if mode == 0 {
+			mode = defaultUserDataFileMode
+		}
+		out.Files = append(out.Files, vmconfig.File{
+			Path:    f.Path,
+			Content: f.Content,
+			Mode:    mode,
+		})
+	}
+	return out
+}
@@ -0,0 +1,71 @@
+package instances
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/kernel/hypeman/lib/images"
+	"github.com/kernel/hypeman/lib/vmconfig"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateUserData(t *testing.T) {
+	require.NoError(t, validateUserData(nil))
+	require.NoError(t, validateUserData(&UserData{
+		CloudConfig: "#cloud-config\npackages: [nginx]\n",
+		Env:         map[string]string{"LOG_LEVEL": "debug"},
+		Files:       []UserDataFile{{Path: "/etc/app/config.toml", Content: []byte("x"), Mode: 0600}},
+	}))
+
+	tests := []struct {
+		name string
+		ud   *UserData
+	}{
+		{"relative path", &UserData{Files: []UserDataFile{{Path: "etc/app.conf"}}}},
+		{"unclean path", &UserData{Files: []UserDataFile{{Path: "/etc/../root/.ssh/authorized_keys"}}}},
+		{"root path", &UserData{Files: []UserDataFile{{Path: "/"}}}},
+		{"duplicate path", &UserData{Files: []UserDataFile{{Path: "/etc/a"}, {Path: "/etc/a"}}}},
+		{"setuid mode", &UserData{Files: []UserDataFile{{Path: "/usr/bin/tool", Mode: 04755}}}},
+		{"invalid env name", &UserData{Env: map[string]string{"A=B": "c"}}},
+		{"too large", &UserData{CloudConfig: strings.Repeat("x", MaxUserDataSize+1)}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.ErrorIs(t, validateUserData(tt.ud), ErrInvalidUserData)
+		})
+	}
+}
+
+func TestBuildGuestConfig_UserData(t *testing.T) {
+	m := &manager{}
+	imageInfo := &images.Image{Env: map[string]string{"A": "image", "B": "image", "C": "image"}}
+
+	inst := &Instance{StoredMetadata: StoredMetadata{
+		Id:   "inst-1",
+		Name: "web",
+		Env:  map[string]string{"C": "instance"},
+		UserData: &UserData{
+			CloudConfig: "#cloud-config\n",
+			Env:         map[string]string{"B": "userdata", "C": "userdata"},
+			Files:       []UserDataFile{{Path: "/etc/app.conf", Content: []byte("x")}},
+		},
+	}}
+
+	cfg := m.buildGuestConfig(context.Background(), inst, imageInfo, nil)
+	assert.Equal(t, map[string]string{"A": "image", "B": "userdata", "C": "instance"}, cfg.Env)
+	require.NotNil(t, cfg.UserData)
+	assert.Equal(t, &vmconfig.UserData{
+		InstanceID:  "inst-1",
+		Hostname:    "web",
+		CloudConfig: "#cloud-config\n",
+		Files:       []vmconfig.File{{Path: "/etc/app.conf", Content: []byte("x"), Mode: 0644}},
+	}, cfg.UserData)
+
+	// Env-only user data has nothing for the guest init to apply
+	inst.UserData = &UserData{Env: map[string]string{"B": "userdata"}}
+	cfg = m.buildGuestConfig(context.Background(), inst, imageInfo, nil)
+	assert.Nil(t, cfg.UserData)
+	assert.Equal(t, "userdata", cfg.Env["B"])
+}
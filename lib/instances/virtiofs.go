@@ -0,0 +1,231 @@
+package instances
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/kernel/hypeman/lib/logger"
+)
+
+// virtiofsdSocketTimeout bounds the wait for a virtiofsd process to listen
+// on its vhost-user socket
+const virtiofsdSocketTimeout = 5 * time.Second
+
+// virtiofsTag is the guest mount tag of an instance's index-th shared directory
+func virtiofsTag(index int) string {
+	return fmt.Sprintf("fs%d", index)
+}
+
+// resolveSharedDirectories validates the shared directories of a create
+// request and returns them with host paths resolved, so that a symlink
+// swapped in later can't move a share outside the configured roots.
+// volumes are checked for conflicting mount paths.
+func (m *manager) resolveSharedDirectories(dirs []SharedDirectory, volumes []VolumeAttachment) ([]SharedDirectory, error) {
+	if len(dirs) == 0 {
+		return nil, nil
+	}
+	if len(m.limits.SharedDirectoryRoots) == 0 {
+		return nil, fmt.Errorf("%w: shared directories are not enabled on this host", ErrInvalidSharedDirectory)
+	}
+	if len(dirs) > MaxSharedDirectoriesPerInstance {
+		return nil, fmt.Errorf("%w: cannot share more than %d directories per instance", ErrInvalidSharedDirectory, MaxSharedDirectoriesPerInstance)
+	}
+
+	seenPaths := make(map[string]bool)
+	for _, vol := range volumes {
+		seenPaths[filepath.Clean(vol.MountPath)] = true
+	}
+
+	resolved := make([]SharedDirectory, 0, len(dirs))
+	for _, dir := range dirs {
+		if !filepath.IsAbs(dir.HostPath) {
+			return nil, fmt.Errorf("%w: host path %q must be absolute", ErrInvalidSharedDirectory, dir.HostPath)
+		}
+		hostPath, err := filepath.EvalSymlinks(dir.HostPath)
+		if err != nil {
+			return nil, fmt.Errorf("%w: host path %q: %v", ErrInvalidSharedDirectory, dir.HostPath, err)
+		}
+		if !m.underSharedDirectoryRoot(hostPath) {
+			return nil, fmt.Errorf("%w: host path %q is not under a shared directory root", ErrInvalidSharedDirectory, dir.HostPath)
+		}
+		info, err := os.Stat(hostPath)
+		if err != nil {
+			return nil, fmt.Errorf("%w: host path %q: %v", ErrInvalidSharedDirectory, dir.HostPath, err)
+		}
+		if !info.IsDir() {
+			return nil, fmt.Errorf("%w: host path %q is not a directory", ErrInvalidSharedDirectory, dir.HostPath)
+		}
+
+		if !filepath.IsAbs(dir.MountPath) {
+			return nil, fmt.Errorf("%w: mount path %q must be absolute", ErrInvalidSharedDirectory, dir.MountPath)
+		}
+		mountPath := filepath.Clean(dir.MountPath)
+		if isSystemDirectory(mountPath) {
+			return nil, fmt.Errorf("%w: cannot mount to system directory %q", ErrInvalidSharedDirectory, mountPath)
+		}
+		if seenPaths[mountPath] {
+			return nil, fmt.Errorf("%w: duplicate mount path %q", ErrInvalidSharedDirectory, mountPath)
+		}
+		seenPaths[mountPath] = true
+
+		resolved = append(resolved, SharedDirectory{
+			HostPath:  hostPath,
+			MountPath: mountPath,
+			Readonly:  dir.Readonly,
+		})
+	}
+	return resolved, nil
+}
+
+// underSharedDirectoryRoot reports whether a resolved host path is, or is
+// under, one of the configured shared directory roots
+func (m *manager) underSharedDirectoryRoot(path string) bool {
+	for _, root := range m.limits.SharedDirectoryRoots {
+		if resolvedRoot, err := filepath.EvalSymlinks(root); err == nil {
+			root = resolvedRoot
+		}
+		if path == root || strings.HasPrefix(path, strings.TrimSuffix(root, "/")+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// startVirtiofsd starts a virtiofsd process per shared directory of an
+// instance and waits for each to listen on its socket. Processes are cloned
+// into cgroupDir, if set, and their PIDs are recorded in stored. On failure
+// the processes already started are stopped.
+func (m *manager) startVirtiofsd(ctx context.Context, stored *StoredMetadata, cgroupDir string) error {
+	if len(stored.SharedDirectories) == 0 {
+		return nil
+	}
+	log := logger.FromContext(ctx)
+
+	if err := os.MkdirAll(m.paths.InstanceLogs(stored.Id), 0755); err != nil {
+		return fmt.Errorf("create logs directory: %w", err)
+	}
+	logFile, err := os.OpenFile(m.paths.InstanceVirtiofsdLog(stored.Id), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("create virtiofsd log: %w", err)
+	}
+	defer logFile.Close()
+
+	stored.VirtiofsdPIDs = nil
+	for i, dir := range stored.SharedDirectories {
+		socketPath := m.paths.InstanceVirtiofsSocket(stored.Id, i)
+		os.Remove(socketPath)
+
+		args := []string{
+			"--socket-path", socketPath,
+			"--shared-dir", dir.HostPath,
+			"--cache", "auto",
+			"--sandbox", "namespace",
+		}
+		if dir.Readonly {
+			args = append(args, "--readonly")
+		}
+
+		// Use Command (not CommandContext) so the process survives the request
+		cmd := exec.Command(m.limits.VirtiofsdBinary, args...)
+		cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+		cmd.Stdout = logFile
+		cmd.Stderr = logFile
+		if cgroupDir != "" {
+			cgroup, err := os.Open(cgroupDir)
+			if err != nil {
+				m.stopVirtiofsd(ctx, stored)
+				return fmt.Errorf("open cgroup: %w", err)
+			}
+			cmd.SysProcAttr.UseCgroupFD = true
+			cmd.SysProcAttr.CgroupFD = int(cgroup.Fd())
+			err = cmd.Start()
+			cgroup.Close()
+			if err != nil {
+				m.stopVirtiofsd(ctx, stored)
+				return fmt.Errorf("start virtiofsd for %s: %w", dir.HostPath, err)
+			}
+		} else if err := cmd.Start(); err != nil {
+			m.stopVirtiofsd(ctx, stored)
+			return fmt.Errorf("start virtiofsd for %s: %w", dir.HostPath, err)
+		}
+
+		// virtiofsd exits on its own once the hypervisor disconnects; reap it
+		go cmd.Wait()
+
+		stored.VirtiofsdPIDs = append(stored.VirtiofsdPIDs, cmd.Process.Pid)
+		log.DebugContext(ctx, "started virtiofsd", "instance_id", stored.Id, "pid", cmd.Process.Pid, "host_path", dir.HostPath, "tag", virtiofsTag(i))
+
+		if err := waitForVirtiofsdSocket(socketPath, cmd.Process.Pid); err != nil {
+			m.stopVirtiofsd(ctx, stored)
+			if logData, readErr := os.ReadFile(m.paths.InstanceVirtiofsdLog(stored.Id)); readErr == nil && len(logData) > 0 {
+				return fmt.Errorf("%w; virtiofsd.log: %s", err, string(logData))
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// waitForVirtiofsdSocket waits for a virtiofsd process to create its socket,
+// failing early if the process exits
+func waitForVirtiofsdSocket(socketPath string, pid int) error {
+	deadline := time.Now().Add(virtiofsdSocketTimeout)
+	for time.Now().Before(deadline) {
+		if _, err := os.Stat(socketPath); err == nil {
+			return nil
+		}
+		if !isVirtiofsd(pid) {
+			return fmt.Errorf("virtiofsd exited before creating %s", socketPath)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	return fmt.Errorf("timed out waiting for virtiofsd socket %s", socketPath)
+}
+
+// stopVirtiofsd kills an instance's virtiofsd processes, if still running,
+// and clears their PIDs from stored. Each process is killed with its process
+// group, which holds the child virtiofsd forks into its sandbox.
+func (m *manager) stopVirtiofsd(ctx context.Context, stored *StoredMetadata) {
+	log := logger.FromContext(ctx)
+	for _, pid := range stored.VirtiofsdPIDs {
+		// PIDs may be stale after a host restart; don't kill a reused PID
+		if !isVirtiofsd(pid) {
+			continue
+		}
+		if err := syscall.Kill(-pid, syscall.SIGKILL); err != nil && !errors.Is(err, syscall.ESRCH) {
+			log.WarnContext(ctx, "failed to kill virtiofsd", "instance_id", stored.Id, "pid", pid, "error", err)
+			continue
+		}
+		if !WaitForProcessExit(pid, 2*time.Second) {
+			log.WarnContext(ctx, "virtiofsd did not exit in time", "instance_id", stored.Id, "pid", pid)
+		}
+	}
+	for i := range stored.SharedDirectories {
+		os.Remove(m.paths.InstanceVirtiofsSocket(stored.Id, i))
+	}
+	stored.VirtiofsdPIDs = nil
+}
+
+// isVirtiofsd reports whether pid is a live virtiofsd process
+func isVirtiofsd(pid int) bool {
+	comm, err := os.ReadFile(filepath.Join("/proc", strconv.Itoa(pid), "comm"))
+	if err != nil {
+		return false
+	}
+	// Zombies keep their name until reaped
+	stat, err := os.ReadFile(filepath.Join("/proc", strconv.Itoa(pid), "stat"))
+	if err == nil {
+		if i := strings.LastIndexByte(string(stat), ')'); i >= 0 && strings.HasPrefix(string(stat[i+1:]), " Z") {
+			return false
+		}
+	}
+	return strings.HasPrefix(strings.TrimSpace(string(comm)), "virtiofsd")
+}
@@ -0,0 +1,74 @@
+package instances
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolveSharedDirectories(t *testing.T) {
+	root := t.TempDir()
+	outside := t.TempDir()
+	require.NoError(t, os.Mkdir(filepath.Join(root, "data"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "file"), nil, 0644))
+	require.NoError(t, os.Symlink(outside, filepath.Join(root, "escape")))
+	require.NoError(t, os.Symlink(filepath.Join(root, "data"), filepath.Join(root, "link")))
+
+	m := &manager{limits: ResourceLimits{SharedDirectoryRoots: []string{root}}}
+
+	t.Run("resolves host paths", func(t *testing.T) {
+		dirs, err := m.resolveSharedDirectories([]SharedDirectory{
+			{HostPath: filepath.Join(root, "link"), MountPath: "/mnt/data/", Readonly: true},
+		}, nil)
+		require.NoError(t, err)
+		resolvedRoot, err := filepath.EvalSymlinks(root)
+		require.NoError(t, err)
+		assert.Equal(t, []SharedDirectory{
+			{HostPath: filepath.Join(resolvedRoot, "data"), MountPath: "/mnt/data", Readonly: true},
+		}, dirs)
+	})
+
+	tests := []struct {
+		name    string
+		dir     SharedDirectory
+		volumes []VolumeAttachment
+		wantErr string
+	}{
+		{name: "relative host path", dir: SharedDirectory{HostPath: "data", MountPath: "/mnt"}, wantErr: "must be absolute"},
+		{name: "missing host path", dir: SharedDirectory{HostPath: filepath.Join(root, "missing"), MountPath: "/mnt"}, wantErr: "no such file"},
+		{name: "outside roots", dir: SharedDirectory{HostPath: outside, MountPath: "/mnt"}, wantErr: "not under a shared directory root"},
+		{name: "symlink escaping root", dir: SharedDirectory{HostPath: filepath.Join(root, "escape"), MountPath: "/mnt"}, wantErr: "not under a shared directory root"},
+		{name: "not a directory", dir: SharedDirectory{HostPath: filepath.Join(root, "file"), MountPath: "/mnt"}, wantErr: "not a directory"},
+		{name: "relative mount path", dir: SharedDirectory{HostPath: root, MountPath: "mnt"}, wantErr: "must be absolute"},
+		{name: "system directory", dir: SharedDirectory{HostPath: root, MountPath: "/etc/app"}, wantErr: "system directory"},
+		{
+			name:    "volume mount path",
+			dir:     SharedDirectory{HostPath: root, MountPath: "/mnt/data"},
+			volumes: []VolumeAttachment{{VolumeID: "vol-1", MountPath: "/mnt/data"}},
+			wantErr: "duplicate mount path",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := m.resolveSharedDirectories([]SharedDirectory{tt.dir}, tt.volumes)
+			assert.ErrorIs(t, err, ErrInvalidSharedDirectory)
+			assert.ErrorContains(t, err, tt.wantErr)
+		})
+	}
+
+	t.Run("disabled without roots", func(t *testing.T) {
+		m := &manager{}
+		_, err := m.resolveSharedDirectories([]SharedDirectory{{HostPath: root, MountPath: "/mnt"}}, nil)
+		assert.ErrorIs(t, err, ErrInvalidSharedDirectory)
+		assert.ErrorContains(t, err, "not enabled")
+	})
+
+	t.Run("limits count", func(t *testing.T) {
+		dirs := make([]SharedDirectory, MaxSharedDirectoriesPerInstance+1)
+		_, err := m.resolveSharedDirectories(dirs, nil)
+		assert.ErrorContains(t, err, "cannot share more than")
+	})
+}
@@ -0,0 +1,42 @@
+package instances
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/kernel/hypeman/lib/logger"
+)
+
+// unlockVolumes opens the instance's decrypted mappings of its encrypted
+// volumes, for a restore: a fresh boot opens them in buildHypervisorConfig.
+// On failure the mappings already opened are closed.
+func (m *manager) unlockVolumes(ctx context.Context, stored *StoredMetadata) error {
+	if stored.RootVolume != "" {
+		if _, err := m.volumeManager.UnlockVolume(ctx, stored.RootVolume, stored.Id, false); err != nil {
+			return fmt.Errorf("unlock root volume %s: %w", stored.RootVolume, err)
+		}
+	}
+	for _, volAttach := range stored.Volumes {
+		if _, err := m.volumeManager.UnlockVolume(ctx, volAttach.VolumeID, stored.Id, volAttach.Readonly); err != nil {
+			m.lockVolumes(ctx, stored)
+			return fmt.Errorf("unlock volume %s: %w", volAttach.VolumeID, err)
+		}
+	}
+	return nil
+}
+
+// lockVolumes closes the instance's decrypted mappings of its encrypted
+// volumes once the hypervisor no longer has them open
+func (m *manager) lockVolumes(ctx context.Context, stored *StoredMetadata) {
+	log := logger.FromContext(ctx)
+	if stored.RootVolume != "" {
+		if err := m.volumeManager.LockVolume(ctx, stored.RootVolume, stored.Id); err != nil {
+			log.WarnContext(ctx, "failed to lock root volume", "instance_id", stored.Id, "volume_id", stored.RootVolume, "error", err)
+		}
+	}
+	for _, volAttach := range stored.Volumes {
+		if err := m.volumeManager.LockVolume(ctx, volAttach.VolumeID, stored.Id); err != nil {
+			log.WarnContext(ctx, "failed to lock volume", "instance_id", stored.Id, "volume_id", volAttach.VolumeID, "error", err)
+		}
+	}
+}
@@ -54,7 +54,7 @@ func TestVolumeMultiAttachReadOnly(t *testing.T) {
 	p := paths.New(tmpDir)
 
 	// Setup: prepare image and system files
-	imageManager, err := images.NewManager(p, 1, nil)
+	imageManager, err := images.NewManager(p, 1, nil, nil)
 	require.NoError(t, err)
 
 	t.Log("Pulling alpine image...")
@@ -79,7 +79,7 @@ func TestVolumeMultiAttachReadOnly(t *testing.T) {
 	t.Log("System files ready")
 
 	// Create volume
-	volumeManager := volumes.NewManager(p, 0, nil)
+	volumeManager := volumes.NewManager(p, 0, nil, nil)
 	t.Log("Creating volume...")
 	vol, err := volumeManager.CreateVolume(ctx, volumes.CreateVolumeRequest{
 		Name:   "shared-data",
@@ -121,7 +121,7 @@ func TestVolumeMultiAttachReadOnly(t *testing.T) {
 
 	// Delete writer instance (detaches volume)
 	t.Log("Deleting writer instance...")
-	err = manager.DeleteInstance(ctx, writerInst.Id)
+	err = manager.DeleteInstance(ctx, writerInst.Id, DeleteInstanceRequest{})
 	require.NoError(t, err)
 
 	// Verify volume is detached
@@ -214,8 +214,8 @@ func TestVolumeMultiAttachReadOnly(t *testing.T) {
 
 	// Cleanup
 	t.Log("Cleaning up...")
-	manager.DeleteInstance(ctx, reader1.Id)
-	manager.DeleteInstance(ctx, reader2.Id)
+	manager.DeleteInstance(ctx, reader1.Id, DeleteInstanceRequest{})
+	manager.DeleteInstance(ctx, reader2.Id, DeleteInstanceRequest{})
 	volumeManager.DeleteVolume(ctx, vol.Id)
 }
 
@@ -237,7 +237,7 @@ func TestOverlayDiskCleanupOnDelete(t *testing.T) {
 	p := paths.New(tmpDir)
 
 	// Setup: prepare image and system files
-	imageManager, err := images.NewManager(p, 1, nil)
+	imageManager, err := images.NewManager(p, 1, nil, nil)
 	require.NoError(t, err)
 
 	t.Log("Pulling alpine image...")
@@ -259,7 +259,7 @@ func TestOverlayDiskCleanupOnDelete(t *testing.T) {
 	require.NoError(t, err)
 
 	// Create volume
-	volumeManager := volumes.NewManager(p, 0, nil)
+	volumeManager := volumes.NewManager(p, 0, nil, nil)
 	vol, err := volumeManager.CreateVolume(ctx, volumes.CreateVolumeRequest{
 		Name:   "cleanup-test-vol",
 		SizeGb: 1,
@@ -292,7 +292,7 @@ func TestOverlayDiskCleanupOnDelete(t *testing.T) {
 	require.NoError(t, err, "overlay disk file should exist after instance creation")
 
 	// Delete the instance
-	err = manager.DeleteInstance(ctx, inst.Id)
+	err = manager.DeleteInstance(ctx, inst.Id, DeleteInstanceRequest{})
 	require.NoError(t, err)
 
 	// Verify instance directory is removed (which includes vol-overlays/)
@@ -346,7 +346,7 @@ func TestVolumeFromArchive(t *testing.T) {
 	p := paths.New(tmpDir)
 
 	// Setup: prepare image and system files
-	imageManager, err := images.NewManager(p, 1, nil)
+	imageManager, err := images.NewManager(p, 1, nil, nil)
 	require.NoError(t, err)
 
 	t.Log("Pulling alpine image...")
@@ -380,7 +380,7 @@ func TestVolumeFromArchive(t *testing.T) {
 	archive := createTestTarGz(t, testFiles)
 
 	// Create volume from archive
-	volumeManager := volumes.NewManager(p, 0, nil)
+	volumeManager := volumes.NewManager(p, 0, nil, nil)
 	t.Log("Creating volume from archive...")
 	vol, err := volumeManager.CreateVolumeFromArchive(ctx, volumes.CreateVolumeFromArchiveRequest{
 		Name:   "archive-data",
@@ -448,6 +448,6 @@ func TestVolumeFromArchive(t *testing.T) {
 
 	// Cleanup
 	t.Log("Cleaning up...")
-	manager.DeleteInstance(ctx, inst.Id)
+	manager.DeleteInstance(ctx, inst.Id, DeleteInstanceRequest{})
 	volumeManager.DeleteVolume(ctx, vol.Id)
 }
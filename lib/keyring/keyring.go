@@ -0,0 +1,149 @@
+// Package keyring stores secret keys on the host, sealed under a master key.
+package keyring
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// MasterKeySize is the size in bytes of the AES-256 master key
+const MasterKeySize = 32
+
+var (
+	ErrNotFound  = errors.New("key not found")
+	ErrInvalidID = errors.New("invalid key id")
+)
+
+// validID matches key IDs that are safe to use as file names
+var validID = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_.-]*$`)
+
+// Keyring stores secret keys by ID
+type Keyring interface {
+	// Put stores a key, replacing any existing key with the same ID.
+	Put(id string, key []byte) error
+	// Get returns a key, or ErrNotFound.
+	Get(id string) ([]byte, error)
+	// Delete removes a key. Deleting a missing key is not an error.
+	Delete(id string) error
+}
+
+// fileKeyring stores each key in its own file, sealed with AES-256-GCM
+// under the master key, so key files alone don't reveal the keys.
+type fileKeyring struct {
+	dir  string
+	aead cipher.AEAD
+}
+
+// NewFileKeyring creates a keyring storing keys under dir, sealed with
+// masterKey, which must be MasterKeySize bytes.
+func NewFileKeyring(dir string, masterKey []byte) (Keyring, error) {
+	if len(masterKey) != MasterKeySize {
+		return nil, fmt.Errorf("master key must be %d bytes, got %d", MasterKeySize, len(masterKey))
+	}
+	block, err := aes.NewCipher(masterKey)
+	if err != nil {
+		return nil, fmt.Errorf("create cipher: %w", err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("create gcm: %w", err)
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("create keyring directory: %w", err)
+	}
+	return &fileKeyring{dir: dir, aead: aead}, nil
+}
+
+// LoadMasterKey reads a hex-encoded master key from a file, e.g. one
+// created with `openssl rand -hex 32`
+func LoadMasterKey(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read master key: %w", err)
+	}
+	key, err := hex.DecodeString(strings.TrimSpace(string(data)))
+	if err != nil {
+		return nil, fmt.Errorf("decode master key: %w", err)
+	}
+	if len(key) != MasterKeySize {
+		return nil, fmt.Errorf("master key must be %d bytes, got %d", MasterKeySize, len(key))
+	}
+	return key, nil
+}
+
+func (k *fileKeyring) Put(id string, key []byte) error {
+	path, err := k.keyPath(id)
+	if err != nil {
+		return err
+	}
+
+	nonce := make([]byte, k.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return fmt.Errorf("generate nonce: %w", err)
+	}
+	// The ID is authenticated so a sealed key can't be swapped to another ID
+	sealed := k.aead.Seal(nonce, nonce, key, []byte(id))
+
+	// Write to a temp file and rename so a crash can't leave a partial key
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, sealed, 0600); err != nil {
+		return fmt.Errorf("write key: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("write key: %w", err)
+	}
+	return nil
+}
+
+func (k *fileKeyring) Get(id string) ([]byte, error) {
+	path, err := k.keyPath(id)
+	if err != nil {
+		return nil, err
+	}
+
+	sealed, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("read key: %w", err)
+	}
+	nonceSize := k.aead.NonceSize()
+	if len(sealed) < nonceSize {
+		return nil, fmt.Errorf("key %s is corrupt", id)
+	}
+	key, err := k.aead.Open(nil, sealed[:nonceSize], sealed[nonceSize:], []byte(id))
+	if err != nil {
+		return nil, fmt.Errorf("unseal key %s: %w", id, err)
+	}
+	return key, nil
+}
+
+func (k *fileKeyring) Delete(id string) error {
+	path, err := k.keyPath(id)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("delete key: %w", err)
+	}
+	return nil
+}
+
+// keyPath returns the file holding a key, rejecting IDs that could escape
+// the keyring directory
+func (k *fileKeyring) keyPath(id string) (string, error) {
+	if !validID.MatchString(id) {
+		return "", fmt.Errorf("%w: %q", ErrInvalidID, id)
+	}
+	return filepath.Join(k.dir, id+".key"), nil
+}
@@ -0,0 +1,64 @@
+package keyring
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileKeyring(t *testing.T) {
+	dir := t.TempDir()
+	masterKey := bytes.Repeat([]byte{1}, MasterKeySize)
+	k, err := NewFileKeyring(dir, masterKey)
+	require.NoError(t, err)
+
+	_, err = k.Get("vol1")
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	require.NoError(t, k.Put("vol1", []byte("secret")))
+	key, err := k.Get("vol1")
+	require.NoError(t, err)
+	assert.Equal(t, []byte("secret"), key)
+
+	// The key is not stored in the clear
+	data, err := os.ReadFile(filepath.Join(dir, "vol1.key"))
+	require.NoError(t, err)
+	assert.NotContains(t, string(data), "secret")
+
+	// A different master key can't unseal it
+	other, err := NewFileKeyring(dir, bytes.Repeat([]byte{2}, MasterKeySize))
+	require.NoError(t, err)
+	_, err = other.Get("vol1")
+	assert.Error(t, err)
+
+	// A sealed key moved to another ID doesn't unseal
+	require.NoError(t, os.Rename(filepath.Join(dir, "vol1.key"), filepath.Join(dir, "vol2.key")))
+	_, err = k.Get("vol2")
+	assert.Error(t, err)
+
+	require.NoError(t, k.Delete("vol2"))
+	_, err = k.Get("vol2")
+	assert.ErrorIs(t, err, ErrNotFound)
+	assert.NoError(t, k.Delete("vol2"))
+
+	assert.ErrorIs(t, k.Put("../escape", []byte("x")), ErrInvalidID)
+}
+
+func TestLoadMasterKey(t *testing.T) {
+	dir := t.TempDir()
+
+	path := filepath.Join(dir, "master.key")
+	require.NoError(t, os.WriteFile(path, []byte(" "+string(bytes.Repeat([]byte("ab"), MasterKeySize))+"\n"), 0600))
+	key, err := LoadMasterKey(path)
+	require.NoError(t, err)
+	assert.Equal(t, bytes.Repeat([]byte{0xab}, MasterKeySize), key)
+
+	short := filepath.Join(dir, "short.key")
+	require.NoError(t, os.WriteFile(short, []byte("abcd"), 0600))
+	_, err = LoadMasterKey(short)
+	assert.Error(t, err)
+}
@@ -7,6 +7,7 @@ import (
 	"log/slog"
 	"os"
 	"strings"
+	"sync"
 
 	"go.opentelemetry.io/otel/trace"
 )
@@ -29,6 +30,24 @@ const (
 	SubsystemExec      = "EXEC"
 )
 
+// levels holds the level of each subsystem's loggers, so SetLevels can
+// change it after they are created.
+var levels sync.Map // subsystem -> *slog.LevelVar
+
+// levelVar returns the shared level of a subsystem's loggers.
+func levelVar(subsystem string) *slog.LevelVar {
+	v, _ := levels.LoadOrStore(subsystem, new(slog.LevelVar))
+	return v.(*slog.LevelVar)
+}
+
+// SetLevels applies the levels in cfg to the subsystem loggers already created.
+func SetLevels(cfg Config) {
+	levels.Range(func(key, value any) bool {
+		value.(*slog.LevelVar).Set(cfg.LevelFor(key.(string)))
+		return true
+	})
+}
+
 // Config holds logging configuration.
 type Config struct {
 	// DefaultLevel is the default log level for all subsystems.
@@ -104,8 +123,10 @@ func NewLogger(cfg Config) *slog.Logger {
 
 // NewSubsystemLogger creates a logger for a specific subsystem with its configured level.
 // If otelHandler is provided, logs will be sent both to stdout and to OTel.
+// Loggers of a subsystem share their level, which SetLevels can change.
 func NewSubsystemLogger(subsystem string, cfg Config, otelHandler slog.Handler) *slog.Logger {
-	level := cfg.LevelFor(subsystem)
+	level := levelVar(subsystem)
+	level.Set(cfg.LevelFor(subsystem))
 	jsonHandler := slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{
 		Level:     level,
 		AddSource: cfg.AddSource,
@@ -134,12 +155,12 @@ func NewSubsystemLogger(subsystem string, cfg Config, otelHandler slog.Handler)
 type traceContextHandler struct {
 	slog.Handler
 	subsystem string
-	level     slog.Level
+	level     slog.Leveler
 }
 
 // Enabled reports whether the handler handles records at the given level.
 func (h *traceContextHandler) Enabled(ctx context.Context, level slog.Level) bool {
-	return level >= h.level
+	return level >= h.level.Level()
 }
 
 // Handle adds trace_id and span_id from the context if available.
@@ -0,0 +1,95 @@
+package logship
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kernel/hypeman/lib/logger"
+)
+
+// lokiPushPath is the Loki push API endpoint, relative to the Loki URL.
+const lokiPushPath = "/loki/api/v1/push"
+
+// LokiSink pushes records to Loki's HTTP push API, one stream per instance.
+type LokiSink struct {
+	url    string
+	labels map[string]string
+	client *http.Client
+}
+
+// NewLokiSink returns a sink that pushes to the Loki at baseURL. labels are
+// added to every stream, next to instance_id, instance_name and log_source.
+func NewLokiSink(baseURL string, labels map[string]string) *LokiSink {
+	return &LokiSink{
+		url:    strings.TrimSuffix(baseURL, "/") + lokiPushPath,
+		labels: labels,
+		client: &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+type lokiPush struct {
+	Streams []lokiStream `json:"streams"`
+}
+
+type lokiStream struct {
+	Stream map[string]string `json:"stream"`
+	Values [][2]string       `json:"values"`
+}
+
+// Send pushes the records. Batches Loki rejects as invalid (4xx other than
+// 429) are dropped, since sending them again would fail the same way.
+func (s *LokiSink) Send(ctx context.Context, records []Record) error {
+	streams := make(map[string]int) // instance ID -> index in push.Streams
+	var push lokiPush
+	for _, r := range records {
+		i, ok := streams[r.InstanceID]
+		if !ok {
+			labels := maps.Clone(s.labels)
+			if labels == nil {
+				labels = make(map[string]string)
+			}
+			labels["instance_id"] = r.InstanceID
+			labels["instance_name"] = r.InstanceName
+			labels["log_source"] = "app"
+			i = len(push.Streams)
+			push.Streams = append(push.Streams, lokiStream{Stream: labels})
+			streams[r.InstanceID] = i
+		}
+		push.Streams[i].Values = append(push.Streams[i].Values, [2]string{strconv.FormatInt(r.Time.UnixNano(), 10), r.Line})
+	}
+
+	body, err := json.Marshal(push)
+	if err != nil {
+		return fmt.Errorf("encode push: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("push to loki: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 == 2 {
+		return nil
+	}
+
+	msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	if resp.StatusCode/100 == 4 && resp.StatusCode != http.StatusTooManyRequests {
+		logger.FromContext(ctx).WarnContext(ctx, "loki rejected console log batch, dropping it",
+			"status", resp.StatusCode, "lines", len(records), "error", strings.TrimSpace(string(msg)))
+		return nil
+	}
+	return fmt.Errorf("push to loki: %s: %s", resp.Status, strings.TrimSpace(string(msg)))
+}
@@ -0,0 +1,68 @@
+package logship
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/metric"
+)
+
+// Metrics holds the metrics instruments for console log shipping.
+type Metrics struct {
+	linesShipped metric.Int64Counter
+	sendErrors   metric.Int64Counter
+	gaps         metric.Int64Counter
+}
+
+// newMetrics creates and registers all log shipping metrics.
+func newMetrics(meter metric.Meter) (*Metrics, error) {
+	linesShipped, err := meter.Int64Counter(
+		"hypeman_logship_lines_total",
+		metric.WithDescription("Console log lines shipped"),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	sendErrors, err := meter.Int64Counter(
+		"hypeman_logship_send_errors_total",
+		metric.WithDescription("Failed attempts to send console log lines"),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	gaps, err := meter.Int64Counter(
+		"hypeman_logship_gaps_total",
+		metric.WithDescription("Times unshipped console log lines were lost to log rotation"),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Metrics{
+		linesShipped: linesShipped,
+		sendErrors:   sendErrors,
+		gaps:         gaps,
+	}, nil
+}
+
+func (m *Metrics) recordShipped(ctx context.Context, n int) {
+	if m == nil {
+		return
+	}
+	m.linesShipped.Add(ctx, int64(n))
+}
+
+func (m *Metrics) recordSendError(ctx context.Context) {
+	if m == nil {
+		return
+	}
+	m.sendErrors.Add(ctx, 1)
+}
+
+func (m *Metrics) recordGap(ctx context.Context) {
+	if m == nil {
+		return
+	}
+	m.gaps.Add(ctx, 1)
+}
@@ -0,0 +1,48 @@
+package logship
+
+import (
+	"context"
+	"fmt"
+
+	otellog "go.opentelemetry.io/otel/log"
+	sdklog "go.opentelemetry.io/otel/sdk/log"
+)
+
+// consoleScope is the instrumentation scope of shipped console log records.
+const consoleScope = "hypeman/console"
+
+// OTelSink emits records through an OTel logger provider, so they are
+// exported over OTLP alongside hypeman's own logs.
+type OTelSink struct {
+	provider *sdklog.LoggerProvider
+	logger   otellog.Logger
+}
+
+// NewOTelSink returns a sink that emits through provider.
+func NewOTelSink(provider *sdklog.LoggerProvider) *OTelSink {
+	return &OTelSink{
+		provider: provider,
+		logger:   provider.Logger(consoleScope),
+	}
+}
+
+// Send emits the records and flushes the provider, so export failures are
+// reported and the shipper backs off instead of overrunning the export queue.
+func (s *OTelSink) Send(ctx context.Context, records []Record) error {
+	for _, r := range records {
+		var rec otellog.Record
+		rec.SetTimestamp(r.Time)
+		rec.SetObservedTimestamp(r.Time)
+		rec.SetBody(otellog.StringValue(r.Line))
+		rec.AddAttributes(
+			otellog.String("instance_id", r.InstanceID),
+			otellog.String("instance_name", r.InstanceName),
+			otellog.String("log_source", "app"),
+		)
+		s.logger.Emit(ctx, rec)
+	}
+	if err := s.provider.ForceFlush(ctx); err != nil {
+		return fmt.Errorf("flush: %w", err)
+	}
+	return nil
+}
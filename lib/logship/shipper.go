@@ -0,0 +1,313 @@
+// Package logship forwards instance console logs to a central logging stack.
+package logship
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/kernel/hypeman/lib/instances"
+	"github.com/kernel/hypeman/lib/logger"
+	"github.com/kernel/hypeman/lib/paths"
+	"go.opentelemetry.io/otel/metric"
+)
+
+const (
+	// DefaultInterval is how often console logs are checked for new lines.
+	DefaultInterval = time.Second
+
+	// DefaultBatchSize is the maximum number of lines sent to a sink at once.
+	DefaultBatchSize = 1000
+
+	// maxBatchesPerPoll bounds how much of one instance's backlog is shipped
+	// per poll, so a noisy instance doesn't delay the others.
+	maxBatchesPerPoll = 10
+
+	// maxLineBytes is where long lines are cut; the rest of the line is dropped.
+	maxLineBytes = 64 * 1024
+
+	// maxBackoff caps the wait between attempts while the sink is failing.
+	maxBackoff = time.Minute
+)
+
+// Record is one console log line. Lines are timestamped when they are read,
+// a nanosecond apart within a batch to keep them ordered and distinct.
+type Record struct {
+	Time         time.Time
+	InstanceID   string
+	InstanceName string
+	Line         string
+}
+
+// Sink delivers batches of records. A batch that returns an error is sent
+// again, so delivery is at-least-once.
+type Sink interface {
+	Send(ctx context.Context, records []Record) error
+}
+
+// InstanceLister lists the instances whose console logs are shipped.
+type InstanceLister interface {
+	ListInstances(ctx context.Context) ([]instances.Instance, error)
+}
+
+// Config configures a Shipper.
+type Config struct {
+	// Interval is how often console logs are checked for new lines.
+	Interval time.Duration
+
+	// BatchSize is the maximum number of lines per Send.
+	BatchSize int
+}
+
+// Shipper tails each instance's console log and forwards new lines to a
+// Sink. Positions are kept in memory: logs that exist when the shipper
+// starts are shipped from their end, logs of instances created later from
+// their start.
+//
+// While the sink is failing, the shipper stops reading and retries with
+// exponential backoff, leaving unshipped lines in the log files. Lines are
+// only lost if log rotation truncates a file further than the shipper has
+// read and its rotated copy is gone as well.
+type Shipper struct {
+	paths   *paths.Paths
+	lister  InstanceLister
+	sink    Sink
+	cfg     Config
+	metrics *Metrics
+
+	files   map[string]*fileState // instance ID -> tail position
+	started bool
+	backoff time.Duration
+	now     func() time.Time
+}
+
+// fileState is the tail position in one instance's console log.
+type fileState struct {
+	name   string
+	offset int64
+}
+
+// NewShipper creates a shipper. If meter is nil, metrics are disabled.
+func NewShipper(p *paths.Paths, lister InstanceLister, sink Sink, cfg Config, meter metric.Meter) (*Shipper, error) {
+	if cfg.Interval <= 0 {
+		cfg.Interval = DefaultInterval
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = DefaultBatchSize
+	}
+	s := &Shipper{
+		paths:  p,
+		lister: lister,
+		sink:   sink,
+		cfg:    cfg,
+		files:  make(map[string]*fileState),
+		now:    time.Now,
+	}
+	if meter != nil {
+		metrics, err := newMetrics(meter)
+		if err != nil {
+			return nil, fmt.Errorf("create metrics: %w", err)
+		}
+		s.metrics = metrics
+	}
+	return s, nil
+}
+
+// Run ships console logs until ctx is done.
+func (s *Shipper) Run(ctx context.Context) {
+	log := logger.FromContext(ctx)
+	for {
+		wait := s.cfg.Interval
+		if err := s.Poll(ctx); err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			s.backoff = min(max(2*s.backoff, s.cfg.Interval), maxBackoff)
+			wait = s.backoff
+			log.WarnContext(ctx, "console log shipping failed, backing off", "error", err, "retry_in", wait)
+		} else {
+			s.backoff = 0
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(wait):
+		}
+	}
+}
+
+// Poll ships the lines written since the last poll. It stops at the first
+// failed send; the failed lines are sent again by the next poll.
+func (s *Shipper) Poll(ctx context.Context) error {
+	insts, err := s.lister.ListInstances(ctx)
+	if err != nil {
+		return fmt.Errorf("list instances: %w", err)
+	}
+
+	seen := make(map[string]bool, len(insts))
+	for _, inst := range insts {
+		seen[inst.Id] = true
+		st, ok := s.files[inst.Id]
+		if !ok {
+			st = &fileState{}
+			// Ship only new output of instances that predate the shipper
+			if !s.started {
+				if info, err := os.Stat(s.paths.InstanceAppLog(inst.Id)); err == nil {
+					st.offset = info.Size()
+				}
+			}
+			s.files[inst.Id] = st
+		}
+		st.name = inst.Name
+	}
+	s.started = true
+
+	// Forget deleted instances
+	for id := range s.files {
+		if !seen[id] {
+			delete(s.files, id)
+		}
+	}
+
+	for _, inst := range insts {
+		if err := s.shipInstance(ctx, inst.Id, s.files[inst.Id]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// shipInstance ships up to maxBatchesPerPoll batches of one instance's log.
+func (s *Shipper) shipInstance(ctx context.Context, id string, st *fileState) error {
+	path := s.paths.InstanceAppLog(id)
+	for range maxBatchesPerPoll {
+		records, next, err := s.readBatch(ctx, path, id, st)
+		if err != nil {
+			return fmt.Errorf("read console log of %s: %w", id, err)
+		}
+		if len(records) > 0 {
+			if err := s.sink.Send(ctx, records); err != nil {
+				s.metrics.recordSendError(ctx)
+				return fmt.Errorf("send console log of %s: %w", id, err)
+			}
+			s.metrics.recordShipped(ctx, len(records))
+		}
+		if next == st.offset {
+			return nil
+		}
+		st.offset = next
+	}
+	return nil
+}
+
+// readBatch reads up to BatchSize complete lines after st.offset and returns
+// them with the offset following the last one.
+func (s *Shipper) readBatch(ctx context.Context, path, id string, st *fileState) ([]Record, int64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, st.offset, nil
+		}
+		return nil, st.offset, err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return nil, st.offset, err
+	}
+	if info.Size() < st.offset {
+		// Rotated by copytruncate: finish from the copy, then start over
+		records, err := s.readRotated(ctx, path+".1", id, st)
+		return records, 0, err
+	}
+
+	if _, err := f.Seek(st.offset, io.SeekStart); err != nil {
+		return nil, st.offset, err
+	}
+	records, n := s.readLines(bufio.NewReaderSize(f, maxLineBytes), id, st.name)
+	return records, st.offset + n, nil
+}
+
+// readRotated reads what's left after st.offset in the rotated copy of a
+// log. The copy must hold at least st.offset bytes to be the one the
+// shipper was reading; otherwise the remaining lines are lost.
+func (s *Shipper) readRotated(ctx context.Context, path, id string, st *fileState) ([]Record, error) {
+	lost := func() ([]Record, error) {
+		logger.FromContext(ctx).WarnContext(ctx, "console log rotated before it was shipped; unshipped lines are lost", "instance_id", id)
+		s.metrics.recordGap(ctx)
+		return nil, nil
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return lost()
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil || info.Size() < st.offset {
+		return lost()
+	}
+	if _, err := f.Seek(st.offset, io.SeekStart); err != nil {
+		return nil, err
+	}
+
+	// Ship the whole remainder so none of it is lost when the offset resets
+	br := bufio.NewReaderSize(f, maxLineBytes)
+	var all []Record
+	for {
+		records, n := s.readLines(br, id, st.name)
+		all = append(all, records...)
+		if n == 0 {
+			return all, nil
+		}
+	}
+}
+
+// readLines reads up to BatchSize complete lines from br and returns them with
+// the number of bytes consumed. A trailing partial line is left for later.
+func (s *Shipper) readLines(br *bufio.Reader, id, name string) ([]Record, int64) {
+	now := s.now()
+	var records []Record
+	var consumed int64
+	for len(records) < s.cfg.BatchSize {
+		line, err := br.ReadSlice('\n')
+		if errors.Is(err, bufio.ErrBufferFull) {
+			// Cut long lines and skip the rest of them
+			cut := string(trimLine(line))
+			n := int64(len(line))
+			for errors.Is(err, bufio.ErrBufferFull) {
+				var more []byte
+				more, err = br.ReadSlice('\n')
+				n += int64(len(more))
+			}
+			if err != nil {
+				return records, consumed
+			}
+			consumed += n
+			records = append(records, Record{Time: now.Add(time.Duration(len(records))), InstanceID: id, InstanceName: name, Line: cut})
+			continue
+		}
+		if err != nil {
+			return records, consumed
+		}
+		consumed += int64(len(line))
+		records = append(records, Record{Time: now.Add(time.Duration(len(records))), InstanceID: id, InstanceName: name, Line: string(trimLine(line))})
+	}
+	return records, consumed
+}
+
+// trimLine strips the line ending, including the carriage return serial
+// consoles emit.
+func trimLine(line []byte) []byte {
+	for len(line) > 0 && (line[len(line)-1] == '\n' || line[len(line)-1] == '\r') {
+		line = line[:len(line)-1]
+	}
+	return line
+}
@@ -0,0 +1,149 @@
+package logship
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/kernel/hypeman/lib/instances"
+	"github.com/kernel/hypeman/lib/paths"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type staticLister []instances.Instance
+
+func (l staticLister) ListInstances(ctx context.Context) ([]instances.Instance, error) {
+	return l, nil
+}
+
+// recordingSink records the lines it receives, failing while err is set.
+type recordingSink struct {
+	lines []string
+	err   error
+}
+
+func (s *recordingSink) Send(ctx context.Context, records []Record) error {
+	if s.err != nil {
+		return s.err
+	}
+	for _, r := range records {
+		s.lines = append(s.lines, r.InstanceName+": "+r.Line)
+	}
+	return nil
+}
+
+func setupShipper(t *testing.T, sink Sink) (*Shipper, func(string)) {
+	t.Helper()
+	p := paths.New(t.TempDir())
+	require.NoError(t, os.MkdirAll(p.InstanceLogs("inst-1"), 0755))
+	path := p.InstanceAppLog("inst-1")
+
+	appendLog := func(s string) {
+		f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+		require.NoError(t, err)
+		_, err = f.WriteString(s)
+		require.NoError(t, err)
+		require.NoError(t, f.Close())
+	}
+
+	lister := staticLister{{StoredMetadata: instances.StoredMetadata{Id: "inst-1", Name: "web"}}}
+	s, err := NewShipper(p, lister, sink, Config{BatchSize: 2}, nil)
+	require.NoError(t, err)
+	return s, appendLog
+}
+
+func TestShipper_ShipsNewLines(t *testing.T) {
+	sink := &recordingSink{}
+	s, appendLog := setupShipper(t, sink)
+	ctx := context.Background()
+
+	// Output from before the shipper started isn't shipped
+	appendLog("booting\r\n")
+	require.NoError(t, s.Poll(ctx))
+	assert.Empty(t, sink.lines)
+
+	// Partial lines wait for their newline; batches are split by BatchSize
+	appendLog("one\ntwo\nthree\nfou")
+	require.NoError(t, s.Poll(ctx))
+	assert.Equal(t, []string{"web: one", "web: two", "web: three"}, sink.lines)
+
+	appendLog("r\n")
+	require.NoError(t, s.Poll(ctx))
+	assert.Equal(t, []string{"web: one", "web: two", "web: three", "web: four"}, sink.lines)
+}
+
+func TestShipper_RetriesWhileSinkFails(t *testing.T) {
+	sink := &recordingSink{}
+	s, appendLog := setupShipper(t, sink)
+	ctx := context.Background()
+	require.NoError(t, s.Poll(ctx))
+
+	appendLog("one\ntwo\n")
+	sink.err = errors.New("collector unavailable")
+	assert.Error(t, s.Poll(ctx))
+	assert.Error(t, s.Poll(ctx))
+
+	// Nothing is lost or duplicated once the sink recovers
+	sink.err = nil
+	require.NoError(t, s.Poll(ctx))
+	assert.Equal(t, []string{"web: one", "web: two"}, sink.lines)
+}
+
+func TestShipper_FollowsRotation(t *testing.T) {
+	sink := &recordingSink{}
+	s, appendLog := setupShipper(t, sink)
+	ctx := context.Background()
+	require.NoError(t, s.Poll(ctx))
+
+	appendLog("shipped\n")
+	require.NoError(t, s.Poll(ctx))
+
+	// Rotate by copytruncate while a line is still unshipped
+	appendLog("pending\n")
+	path := s.paths.InstanceAppLog("inst-1")
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(path+".1", data, 0644))
+	require.NoError(t, os.Truncate(path, 0))
+	appendLog("after\n")
+
+	require.NoError(t, s.Poll(ctx))
+	assert.Equal(t, []string{"web: shipped", "web: pending", "web: after"}, sink.lines)
+}
+
+func TestLokiSink(t *testing.T) {
+	var got lokiPush
+	status := http.StatusNoContent
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, lokiPushPath, r.URL.Path)
+		body, _ := io.ReadAll(r.Body)
+		require.NoError(t, json.Unmarshal(body, &got))
+		w.WriteHeader(status)
+	}))
+	defer srv.Close()
+
+	sink := NewLokiSink(srv.URL+"/", map[string]string{"host": "node-1"})
+	records := []Record{
+		{InstanceID: "inst-1", InstanceName: "web", Line: "one"},
+		{InstanceID: "inst-2", InstanceName: "db", Line: "two"},
+		{InstanceID: "inst-1", InstanceName: "web", Line: "three"},
+	}
+	require.NoError(t, sink.Send(context.Background(), records))
+
+	require.Len(t, got.Streams, 2)
+	assert.Equal(t, map[string]string{"host": "node-1", "instance_id": "inst-1", "instance_name": "web", "log_source": "app"}, got.Streams[0].Stream)
+	require.Len(t, got.Streams[0].Values, 2)
+	assert.Equal(t, "three", got.Streams[0].Values[1][1])
+
+	// Invalid batches are dropped; server errors are retried
+	status = http.StatusBadRequest
+	assert.NoError(t, sink.Send(context.Background(), records))
+	status = http.StatusServiceUnavailable
+	assert.Error(t, sink.Send(context.Background(), records))
+}
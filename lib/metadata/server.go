@@ -0,0 +1,139 @@
+// Package metadata serves instance metadata to guests, like the metadata
+// services of cloud providers. Guests reach it at network.MetadataAddress,
+// and each request is answered for the instance with the client's address.
+package metadata
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net"
+	"net/http"
+
+	"github.com/kernel/hypeman/lib/instances"
+)
+
+// RequiredHeader must be set to "true" on requests, so guest applications
+// can't be tricked into fetching metadata by redirects or request forgery.
+const RequiredHeader = "Metadata"
+
+// InstanceLister lists instances. This interface is implemented by the
+// instances package's Manager.
+type InstanceLister interface {
+	ListInstances(ctx context.Context) ([]instances.Instance, error)
+}
+
+// Document is what an instance learns about itself
+type Document struct {
+	ID       string            `json:"id"`
+	Name     string            `json:"name"`
+	Hostname string            `json:"hostname"`
+	Image    string            `json:"image,omitempty"`
+	IP       string            `json:"ip"`
+	MAC      string            `json:"mac"`
+	Labels   map[string]string `json:"labels"`
+	Volumes  []Volume          `json:"volumes"`
+}
+
+// Volume is a volume attached to the instance
+type Volume struct {
+	ID        string `json:"id"`
+	MountPath string `json:"mount_path"`
+	Readonly  bool   `json:"readonly"`
+}
+
+// Server answers guests' metadata requests
+type Server struct {
+	instances InstanceLister
+	log       *slog.Logger
+}
+
+// NewServer creates a metadata server looking instances up in lister.
+func NewServer(lister InstanceLister, log *slog.Logger) *Server {
+	if log == nil {
+		log = slog.Default()
+	}
+	return &Server{instances: lister, log: log}
+}
+
+// Handler returns the service's HTTP routes:
+//
+//	GET /v1/instance   the instance's Document, as JSON
+//	GET /v1/user-data  the instance's cloud-config user data
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /v1/instance", s.withInstance(s.serveInstance))
+	mux.HandleFunc("GET /v1/user-data", s.withInstance(s.serveUserData))
+	return mux
+}
+
+// withInstance resolves the instance a request comes from before calling next
+func (s *Server) withInstance(next func(http.ResponseWriter, *instances.Instance)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get(RequiredHeader) != "true" {
+			http.Error(w, "the "+RequiredHeader+": true header is required", http.StatusBadRequest)
+			return
+		}
+		ip, _, err := net.SplitHostPort(r.RemoteAddr)
+		if err != nil {
+			http.Error(w, "unknown client address", http.StatusBadRequest)
+			return
+		}
+		inst, err := s.lookup(r.Context(), ip)
+		if err != nil {
+			s.log.ErrorContext(r.Context(), "failed to look up metadata client", "ip", ip, "error", err)
+			http.Error(w, "failed to look up instance", http.StatusInternalServerError)
+			return
+		}
+		if inst == nil {
+			http.Error(w, "no instance has address "+ip, http.StatusNotFound)
+			return
+		}
+		next(w, inst)
+	}
+}
+
+// lookup returns the running instance with address ip, or nil. Stopped
+// instances may still record an address that has been given to another.
+func (s *Server) lookup(ctx context.Context, ip string) (*instances.Instance, error) {
+	list, err := s.instances.ListInstances(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for i := range list {
+		if list[i].NetworkEnabled && list[i].IP == ip && list[i].State == instances.StateRunning {
+			return &list[i], nil
+		}
+	}
+	return nil, nil
+}
+
+func (s *Server) serveInstance(w http.ResponseWriter, inst *instances.Instance) {
+	doc := Document{
+		ID:       inst.Id,
+		Name:     inst.Name,
+		Hostname: inst.Name,
+		Image:    inst.Image,
+		IP:       inst.IP,
+		MAC:      inst.MAC,
+		Labels:   inst.Labels,
+		Volumes:  make([]Volume, 0, len(inst.Volumes)),
+	}
+	if doc.Labels == nil {
+		doc.Labels = map[string]string{}
+	}
+	for _, v := range inst.Volumes {
+		doc.Volumes = append(doc.Volumes, Volume{ID: v.VolumeID, MountPath: v.MountPath, Readonly: v.Readonly})
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(doc)
+}
+
+func (s *Server) serveUserData(w http.ResponseWriter, inst *instances.Instance) {
+	if inst.UserData == nil || inst.UserData.CloudConfig == "" {
+		http.Error(w, "instance has no user data", http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Write([]byte(inst.UserData.CloudConfig))
+}
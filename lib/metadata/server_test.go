@@ -0,0 +1,85 @@
+package metadata
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/kernel/hypeman/lib/instances"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// mockLister implements InstanceLister for testing
+type mockLister []instances.Instance
+
+func (m mockLister) ListInstances(ctx context.Context) ([]instances.Instance, error) {
+	return m, nil
+}
+
+func instance(id, ip string, state instances.State) instances.Instance {
+	inst := instances.Instance{State: state}
+	inst.Id = id
+	inst.Name = "web-" + id
+	inst.NetworkEnabled = true
+	inst.IP = ip
+	return inst
+}
+
+func get(t *testing.T, h http.Handler, path, remoteAddr string, withHeader bool) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	req.RemoteAddr = remoteAddr
+	if withHeader {
+		req.Header.Set(RequiredHeader, "true")
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestServeInstance(t *testing.T) {
+	running := instance("a1", "10.100.0.7", instances.StateRunning)
+	running.Image = "docker.io/library/nginx:1.27"
+	running.MAC = "02:00:00:00:00:07"
+	running.Labels = map[string]string{"app": "web"}
+	running.Volumes = []instances.VolumeAttachment{{VolumeID: "v1", MountPath: "/data", Readonly: true}}
+	// A stopped instance still recording an address now given to another
+	stopped := instance("b2", "10.100.0.7", instances.StateStopped)
+
+	h := NewServer(mockLister{stopped, running}, nil).Handler()
+
+	rec := get(t, h, "/v1/instance", "10.100.0.7:40000", true)
+	require.Equal(t, http.StatusOK, rec.Code)
+	var doc Document
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &doc))
+	assert.Equal(t, Document{
+		ID:       "a1",
+		Name:     "web-a1",
+		Hostname: "web-a1",
+		Image:    "docker.io/library/nginx:1.27",
+		IP:       "10.100.0.7",
+		MAC:      "02:00:00:00:00:07",
+		Labels:   map[string]string{"app": "web"},
+		Volumes:  []Volume{{ID: "v1", MountPath: "/data", Readonly: true}},
+	}, doc)
+
+	assert.Equal(t, http.StatusBadRequest, get(t, h, "/v1/instance", "10.100.0.7:40000", false).Code)
+	assert.Equal(t, http.StatusNotFound, get(t, h, "/v1/instance", "10.100.0.8:40000", true).Code)
+}
+
+func TestServeUserData(t *testing.T) {
+	withUserData := instance("a1", "10.100.0.7", instances.StateRunning)
+	withUserData.UserData = &instances.UserData{CloudConfig: "#cloud-config\npackages: [curl]\n"}
+	without := instance("b2", "10.100.0.8", instances.StateRunning)
+
+	h := NewServer(mockLister{withUserData, without}, nil).Handler()
+
+	rec := get(t, h, "/v1/user-data", "10.100.0.7:40000", true)
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "#cloud-config\npackages: [curl]\n", rec.Body.String())
+
+	assert.Equal(t, http.StatusNotFound, get(t, h, "/v1/user-data", "10.100.0.8:40000", true).Code)
+}
@@ -0,0 +1,297 @@
+// Package metering records what each instance uses, for chargeback: vCPU
+// time, memory, disk and vGPU time, rolled up per hour and kept on disk.
+package metering
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kernel/hypeman/lib/instances"
+	"github.com/kernel/hypeman/lib/logger"
+	"github.com/kernel/hypeman/lib/paths"
+)
+
+// Record is an instance's usage during one hour
+type Record struct {
+	Hour              time.Time `json:"hour"` // Start of the hour, UTC
+	InstanceID        string    `json:"instance_id"`
+	InstanceName      string    `json:"instance_name"`
+	Tenant            string    `json:"tenant,omitempty"`
+	VcpuSeconds       float64   `json:"vcpu_seconds"`        // Allocated vCPUs × seconds running
+	MemoryByteSeconds float64   `json:"memory_byte_seconds"` // Guest memory × seconds held
+	DiskGBHours       float64   `json:"disk_gb_hours"`       // Instance disks in GB × hours existing
+	GPUProfile        string    `json:"gpu_profile,omitempty"`
+	GPUProfileHours   float64   `json:"gpu_profile_hours,omitempty"` // Hours holding a vGPU of GPUProfile
+}
+
+// Instances is the part of the instance manager metering samples
+type Instances interface {
+	ListInstances(ctx context.Context) ([]instances.Instance, error)
+}
+
+// Config configures a Meter
+type Config struct {
+	// Interval is how often instances are sampled. Usage between samples
+	// is attributed to the state seen at the later one.
+	Interval time.Duration
+
+	// Retention is how long rollups are kept (0 = forever)
+	Retention time.Duration
+}
+
+// Meter samples instances and adds their usage to hourly rollups stored in
+// {dataDir}/usage/<hour>.json
+type Meter struct {
+	paths     *paths.Paths
+	instances Instances
+	cfg       Config
+	now       func() time.Time
+
+	mu         sync.Mutex // Serializes samples and rollup writes
+	last       time.Time  // Time of the previous sample
+	prunedHour time.Time  // Hour rollups were last pruned in
+}
+
+// NewMeter creates a meter. Nothing is recorded until Run or Sample is called.
+func NewMeter(p *paths.Paths, instanceManager Instances, cfg Config) *Meter {
+	return &Meter{paths: p, instances: instanceManager, cfg: cfg, now: time.Now}
+}
+
+// Run samples instances every interval until ctx is done
+func (m *Meter) Run(ctx context.Context) {
+	log := logger.FromContext(ctx)
+	ticker := time.NewTicker(m.cfg.Interval)
+	defer ticker.Stop()
+	for {
+		if err := m.Sample(ctx); err != nil && ctx.Err() == nil {
+			log.WarnContext(ctx, "usage metering sample failed", "error", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Sample adds the usage since the previous sample to the rollups. The first
+// sample only starts the clock. Gaps longer than two intervals, like the
+// server being down, aren't counted.
+func (m *Meter) Sample(ctx context.Context) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := m.now().UTC()
+	from := m.last
+	m.last = now
+	if from.IsZero() || !now.After(from) {
+		return nil
+	}
+	if maxGap := 2 * m.cfg.Interval; maxGap > 0 && now.Sub(from) > maxGap {
+		from = now.Add(-maxGap)
+	}
+
+	insts, err := m.instances.ListInstances(ctx)
+	if err != nil {
+		return fmt.Errorf("list instances: %w", err)
+	}
+
+	var errs []error
+	for hour, d := range splitHours(from, now) {
+		if err := m.add(hour, insts, d); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if hour := now.Truncate(time.Hour); hour != m.prunedHour {
+		m.prunedHour = hour
+		errs = append(errs, m.prune(now))
+	}
+	return errors.Join(errs...)
+}
+
+// splitHours splits the interval [from, to) at hour boundaries
+func splitHours(from, to time.Time) map[time.Time]time.Duration {
+	out := make(map[time.Time]time.Duration)
+	for from.Before(to) {
+		hour := from.Truncate(time.Hour)
+		end := hour.Add(time.Hour)
+		if to.Before(end) {
+			end = to
+		}
+		out[hour] += end.Sub(from)
+		from = end
+	}
+	return out
+}
+
+// add adds d of each instance's usage to the rollup of hour
+func (m *Meter) add(hour time.Time, insts []instances.Instance, d time.Duration) error {
+	records, err := m.load(hour)
+	if err != nil {
+		return err
+	}
+	byID := make(map[string]Record, len(records)+len(insts))
+	for _, r := range records {
+		byID[r.InstanceID] = r
+	}
+
+	seconds, hours := d.Seconds(), d.Hours()
+	for _, inst := range insts {
+		r, ok := byID[inst.Id]
+		if !ok {
+			r = Record{Hour: hour, InstanceID: inst.Id}
+		}
+		r.InstanceName = inst.Name
+		r.Tenant = inst.Tenant
+		u := instanceUsage(inst)
+		r.VcpuSeconds += float64(u.vcpus) * seconds
+		r.MemoryByteSeconds += float64(u.memory) * seconds
+		r.DiskGBHours += float64(u.disk) / (1 << 30) * hours
+		if u.gpuProfile != "" {
+			r.GPUProfile = u.gpuProfile
+			r.GPUProfileHours += hours
+		}
+		byID[inst.Id] = r
+	}
+
+	records = records[:0]
+	for _, r := range byID {
+		records = append(records, r)
+	}
+	sort.Slice(records, func(i, j int) bool { return records[i].InstanceID < records[j].InstanceID })
+	return m.store(hour, records)
+}
+
+// usage is what an instance holds while in its current state
+type usage struct {
+	vcpus      int
+	memory     int64
+	disk       int64
+	gpuProfile string
+}
+
+// instanceUsage returns what an instance holds. vCPUs count while it runs;
+// memory and its vGPU while its guest is in memory (running, paused or
+// created); its disks for as long as it exists.
+func instanceUsage(inst instances.Instance) usage {
+	var u usage
+	switch inst.State {
+	case instances.StateRunning:
+		u.vcpus = inst.Vcpus
+		fallthrough
+	case instances.StatePaused, instances.StateCreated:
+		u.memory = inst.Size + inst.HotplugSize
+		u.gpuProfile = inst.GPUProfile
+	}
+
+	u.disk = inst.OverlaySize + inst.SwapSize
+	if inst.ScratchDisks != nil {
+		u.disk += int64(inst.ScratchDisks.Count) * inst.ScratchDisks.Size
+	}
+	for _, vol := range inst.Volumes {
+		if vol.Overlay {
+			u.disk += vol.OverlaySize
+		}
+	}
+	return u
+}
+
+// load reads the rollup of hour, if any
+func (m *Meter) load(hour time.Time) ([]Record, error) {
+	data, err := os.ReadFile(m.paths.UsageRollup(hour))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read usage rollup: %w", err)
+	}
+	var records []Record
+	if err := json.Unmarshal(data, &records); err != nil {
+		return nil, fmt.Errorf("parse usage rollup %s: %w", m.paths.UsageRollup(hour), err)
+	}
+	return records, nil
+}
+
+// store replaces the rollup of hour, atomically so Query never reads a
+// partial file
+func (m *Meter) store(hour time.Time, records []Record) error {
+	if err := os.MkdirAll(m.paths.UsageDir(), 0755); err != nil {
+		return fmt.Errorf("create usage directory: %w", err)
+	}
+	data, err := json.MarshalIndent(records, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshal usage rollup: %w", err)
+	}
+	path := m.paths.UsageRollup(hour)
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("write usage rollup: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("write usage rollup: %w", err)
+	}
+	return nil
+}
+
+// rollupHour returns the hour a rollup file is for
+func rollupHour(name string) (time.Time, bool) {
+	hour, err := time.Parse("2006-01-02T15", strings.TrimSuffix(name, ".json"))
+	return hour, err == nil && strings.HasSuffix(name, ".json")
+}
+
+// prune deletes rollups older than the retention
+func (m *Meter) prune(now time.Time) error {
+	if m.cfg.Retention <= 0 {
+		return nil
+	}
+	entries, err := os.ReadDir(m.paths.UsageDir())
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("read usage directory: %w", err)
+	}
+	cutoff := now.Add(-m.cfg.Retention)
+	for _, e := range entries {
+		if hour, ok := rollupHour(e.Name()); ok && hour.Add(time.Hour).Before(cutoff) {
+			if err := os.Remove(filepath.Join(m.paths.UsageDir(), e.Name())); err != nil {
+				return fmt.Errorf("remove expired usage rollup: %w", err)
+			}
+		}
+	}
+	return nil
+}
+
+// Query returns the records of the hours starting in [from, to), oldest
+// first
+func (m *Meter) Query(from, to time.Time) ([]Record, error) {
+	entries, err := os.ReadDir(m.paths.UsageDir())
+	if errors.Is(err, fs.ErrNotExist) {
+		return []Record{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read usage directory: %w", err)
+	}
+
+	from = from.UTC().Truncate(time.Hour)
+	records := []Record{}
+	// Entries are sorted by name, which sorts rollups by hour
+	for _, e := range entries {
+		hour, ok := rollupHour(e.Name())
+		if !ok || hour.Before(from) || !hour.Before(to) {
+			continue
+		}
+		rs, err := m.load(hour)
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, rs...)
+	}
+	return records, nil
+}
@@ -0,0 +1,129 @@
+package metering
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/kernel/hypeman/lib/instances"
+	"github.com/kernel/hypeman/lib/paths"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const gb = 1 << 30
+
+type fakeInstances []instances.Instance
+
+func (f *fakeInstances) ListInstances(ctx context.Context) ([]instances.Instance, error) {
+	return *f, nil
+}
+
+func newInstance(id string, state instances.State) instances.Instance {
+	inst := instances.Instance{State: state}
+	inst.Id = id
+	inst.Name = "name-" + id
+	inst.Tenant = "team-a"
+	inst.Vcpus = 2
+	inst.Size = gb
+	inst.HotplugSize = gb
+	inst.OverlaySize = 10 * gb
+	return inst
+}
+
+func setupTestMeter(t *testing.T, start time.Time, insts ...instances.Instance) (*Meter, *time.Time) {
+	t.Helper()
+	f := fakeInstances(insts)
+	m := NewMeter(paths.New(t.TempDir()), &f, Config{Interval: time.Minute, Retention: 24 * time.Hour})
+	now := start
+	m.now = func() time.Time { return now }
+	return m, &now
+}
+
+func TestSplitHours(t *testing.T) {
+	from := time.Date(2026, 1, 1, 9, 50, 0, 0, time.UTC)
+	assert.Equal(t, map[time.Time]time.Duration{
+		time.Date(2026, 1, 1, 9, 0, 0, 0, time.UTC):  10 * time.Minute,
+		time.Date(2026, 1, 1, 10, 0, 0, 0, time.UTC): 5 * time.Minute,
+	}, splitHours(from, from.Add(15*time.Minute)))
+	assert.Empty(t, splitHours(from, from))
+}
+
+func TestSample(t *testing.T) {
+	start := time.Date(2026, 1, 1, 9, 59, 30, 0, time.UTC)
+	running := newInstance("a", instances.StateRunning)
+	running.GPUProfile = "L40S-1Q"
+	stopped := newInstance("b", instances.StateStopped)
+	m, now := setupTestMeter(t, start, running, stopped)
+	ctx := context.Background()
+
+	// The first sample starts the clock; the next spans the hour boundary
+	require.NoError(t, m.Sample(ctx))
+	*now = start.Add(time.Minute)
+	require.NoError(t, m.Sample(ctx))
+
+	records, err := m.Query(start.Add(-time.Hour), start.Add(time.Hour))
+	require.NoError(t, err)
+	require.Len(t, records, 4)
+
+	a9 := records[0]
+	assert.Equal(t, time.Date(2026, 1, 1, 9, 0, 0, 0, time.UTC), a9.Hour)
+	assert.Equal(t, "a", a9.InstanceID)
+	assert.Equal(t, "name-a", a9.InstanceName)
+	assert.Equal(t, "team-a", a9.Tenant)
+	assert.InDelta(t, 60, a9.VcpuSeconds, 1e-9)
+	assert.InDelta(t, 2*gb*30, a9.MemoryByteSeconds, 1e-3)
+	assert.InDelta(t, 10.0*30/3600, a9.DiskGBHours, 1e-9)
+	assert.Equal(t, "L40S-1Q", a9.GPUProfile)
+	assert.InDelta(t, 30.0/3600, a9.GPUProfileHours, 1e-9)
+
+	// A stopped instance only uses its disk
+	b9 := records[1]
+	assert.Equal(t, "b", b9.InstanceID)
+	assert.Zero(t, b9.VcpuSeconds)
+	assert.Zero(t, b9.MemoryByteSeconds)
+	assert.InDelta(t, 10.0*30/3600, b9.DiskGBHours, 1e-9)
+
+	assert.Equal(t, time.Date(2026, 1, 1, 10, 0, 0, 0, time.UTC), records[2].Hour)
+
+	// Later samples add to the same rollup
+	*now = start.Add(2 * time.Minute)
+	require.NoError(t, m.Sample(ctx))
+	records, err = m.Query(start.Add(time.Minute), start.Add(time.Hour))
+	require.NoError(t, err)
+	require.Len(t, records, 2)
+	assert.InDelta(t, 2*90, records[0].VcpuSeconds, 1e-9)
+}
+
+func TestSampleSkipsGaps(t *testing.T) {
+	start := time.Date(2026, 1, 1, 9, 0, 0, 0, time.UTC)
+	m, now := setupTestMeter(t, start, newInstance("a", instances.StateRunning))
+	ctx := context.Background()
+
+	require.NoError(t, m.Sample(ctx))
+	*now = start.Add(30 * time.Minute)
+	require.NoError(t, m.Sample(ctx))
+
+	records, err := m.Query(start, start.Add(time.Hour))
+	require.NoError(t, err)
+	require.Len(t, records, 1)
+	assert.InDelta(t, 2*120, records[0].VcpuSeconds, 1e-9, "only two intervals count")
+}
+
+func TestPrune(t *testing.T) {
+	start := time.Date(2026, 1, 1, 9, 0, 0, 0, time.UTC)
+	m, now := setupTestMeter(t, start, newInstance("a", instances.StateRunning))
+	ctx := context.Background()
+
+	require.NoError(t, m.Sample(ctx))
+	*now = start.Add(time.Minute)
+	require.NoError(t, m.Sample(ctx))
+	old := m.paths.UsageRollup(start)
+	require.FileExists(t, old)
+
+	*now = start.Add(26 * time.Hour)
+	require.NoError(t, m.Sample(ctx))
+	_, err := os.Stat(old)
+	assert.True(t, os.IsNotExist(err), "rollup past retention should be deleted")
+}
@@ -0,0 +1,1612 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// source: lib/mgmt/mgmt.proto
+
+package mgmt
+
+import (
+	fmt "fmt"
+	proto "github.com/golang/protobuf/proto"
+	math "math"
+)
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ = proto.Marshal
+var _ = fmt.Errorf
+var _ = math.Inf
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the proto package it is being compiled against.
+// A compilation error at this line likely means your copy of the
+// proto package needs to be updated.
+const _ = proto.ProtoPackageIsVersion3 // please upgrade the proto package
+
+// Instance describes a VM instance
+type Instance struct {
+	Id                   string            `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Name                 string            `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`
+	Image                string            `protobuf:"bytes,3,opt,name=image,proto3" json:"image,omitempty"`
+	State                string            `protobuf:"bytes,4,opt,name=state,proto3" json:"state,omitempty"`
+	StateError           string            `protobuf:"bytes,5,opt,name=state_error,json=stateError,proto3" json:"state_error,omitempty"`
+	Vcpus                int32             `protobuf:"varint,6,opt,name=vcpus,proto3" json:"vcpus,omitempty"`
+	Size                 string            `protobuf:"bytes,7,opt,name=size,proto3" json:"size,omitempty"`
+	CreatedAt            int64             `protobuf:"varint,8,opt,name=created_at,json=createdAt,proto3" json:"created_at,omitempty"`
+	StartedAt            int64             `protobuf:"varint,9,opt,name=started_at,json=startedAt,proto3" json:"started_at,omitempty"`
+	StoppedAt            int64             `protobuf:"varint,10,opt,name=stopped_at,json=stoppedAt,proto3" json:"stopped_at,omitempty"`
+	Ip                   string            `protobuf:"bytes,11,opt,name=ip,proto3" json:"ip,omitempty"`
+	Tenant               string            `protobuf:"bytes,12,opt,name=tenant,proto3" json:"tenant,omitempty"`
+	Env                  map[string]string `protobuf:"bytes,13,rep,name=env,proto3" json:"env,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+	XXX_NoUnkeyedLiteral struct{}          `json:"-"`
+	XXX_unrecognized     []byte            `json:"-"`
+	XXX_sizecache        int32             `json:"-"`
+}
+
+func (m *Instance) Reset()         { *m = Instance{} }
+func (m *Instance) String() string { return proto.CompactTextString(m) }
+func (*Instance) ProtoMessage()    {}
+func (*Instance) Descriptor() ([]byte, []int) {
+	return fileDescriptor_1fe173d347cc7416, []int{0}
+}
+
+func (m *Instance) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Instance.Unmarshal(m, b)
+}
+func (m *Instance) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Instance.Marshal(b, m, deterministic)
+}
+func (m *Instance) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Instance.Merge(m, src)
+}
+func (m *Instance) XXX_Size() int {
+	return xxx_messageInfo_Instance.Size(m)
+}
+func (m *Instance) XXX_DiscardUnknown() {
+	xxx_messageInfo_Instance.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Instance proto.InternalMessageInfo
+
+func (m *Instance) GetId() string {
+	if m != nil {
+		return m.Id
+	}
+	return ""
+}
+
+func (m *Instance) GetName() string {
+	if m != nil {
+		return m.Name
+	}
+	return ""
+}
+
+func (m *Instance) GetImage() string {
+	if m != nil {
+		return m.Image
+	}
+	return ""
+}
+
+func (m *Instance) GetState() string {
+	if m != nil {
+		return m.State
+	}
+	return ""
+}
+
+func (m *Instance) GetStateError() string {
+	if m != nil {
+		return m.StateError
+	}
+	return ""
+}
+
+func (m *Instance) GetVcpus() int32 {
+	if m != nil {
+		return m.Vcpus
+	}
+	return 0
+}
+
+func (m *Instance) GetSize() string {
+	if m != nil {
+		return m.Size
+	}
+	return ""
+}
+
+func (m *Instance) GetCreatedAt() int64 {
+	if m != nil {
+		return m.CreatedAt
+	}
+	return 0
+}
+
+func (m *Instance) GetStartedAt() int64 {
+	if m != nil {
+		return m.StartedAt
+	}
+	return 0
+}
+
+func (m *Instance) GetStoppedAt() int64 {
+	if m != nil {
+		return m.StoppedAt
+	}
+	return 0
+}
+
+func (m *Instance) GetIp() string {
+	if m != nil {
+		return m.Ip
+	}
+	return ""
+}
+
+func (m *Instance) GetTenant() string {
+	if m != nil {
+		return m.Tenant
+	}
+	return ""
+}
+
+func (m *Instance) GetEnv() map[string]string {
+	if m != nil {
+		return m.Env
+	}
+	return nil
+}
+
+// InstanceRequest identifies an instance by ID, name or ID prefix
+type InstanceRequest struct {
+	Id                   string   `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *InstanceRequest) Reset()         { *m = InstanceRequest{} }
+func (m *InstanceRequest) String() string { return proto.CompactTextString(m) }
+func (*InstanceRequest) ProtoMessage()    {}
+func (*InstanceRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_1fe173d347cc7416, []int{1}
+}
+
+func (m *InstanceRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_InstanceRequest.Unmarshal(m, b)
+}
+func (m *InstanceRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_InstanceRequest.Marshal(b, m, deterministic)
+}
+func (m *InstanceRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_InstanceRequest.Merge(m, src)
+}
+func (m *InstanceRequest) XXX_Size() int {
+	return xxx_messageInfo_InstanceRequest.Size(m)
+}
+func (m *InstanceRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_InstanceRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_InstanceRequest proto.InternalMessageInfo
+
+func (m *InstanceRequest) GetId() string {
+	if m != nil {
+		return m.Id
+	}
+	return ""
+}
+
+// ListInstancesRequest lists instances
+type ListInstancesRequest struct {
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ListInstancesRequest) Reset()         { *m = ListInstancesRequest{} }
+func (m *ListInstancesRequest) String() string { return proto.CompactTextString(m) }
+func (*ListInstancesRequest) ProtoMessage()    {}
+func (*ListInstancesRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_1fe173d347cc7416, []int{2}
+}
+
+func (m *ListInstancesRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ListInstancesRequest.Unmarshal(m, b)
+}
+func (m *ListInstancesRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ListInstancesRequest.Marshal(b, m, deterministic)
+}
+func (m *ListInstancesRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ListInstancesRequest.Merge(m, src)
+}
+func (m *ListInstancesRequest) XXX_Size() int {
+	return xxx_messageInfo_ListInstancesRequest.Size(m)
+}
+func (m *ListInstancesRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_ListInstancesRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ListInstancesRequest proto.InternalMessageInfo
+
+// ListInstancesResponse contains the instances
+type ListInstancesResponse struct {
+	Instances            []*Instance `protobuf:"bytes,1,rep,name=instances,proto3" json:"instances,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}    `json:"-"`
+	XXX_unrecognized     []byte      `json:"-"`
+	XXX_sizecache        int32       `json:"-"`
+}
+
+func (m *ListInstancesResponse) Reset()         { *m = ListInstancesResponse{} }
+func (m *ListInstancesResponse) String() string { return proto.CompactTextString(m) }
+func (*ListInstancesResponse) ProtoMessage()    {}
+func (*ListInstancesResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_1fe173d347cc7416, []int{3}
+}
+
+func (m *ListInstancesResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ListInstancesResponse.Unmarshal(m, b)
+}
+func (m *ListInstancesResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ListInstancesResponse.Marshal(b, m, deterministic)
+}
+func (m *ListInstancesResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ListInstancesResponse.Merge(m, src)
+}
+func (m *ListInstancesResponse) XXX_Size() int {
+	return xxx_messageInfo_ListInstancesResponse.Size(m)
+}
+func (m *ListInstancesResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_ListInstancesResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ListInstancesResponse proto.InternalMessageInfo
+
+func (m *ListInstancesResponse) GetInstances() []*Instance {
+	if m != nil {
+		return m.Instances
+	}
+	return nil
+}
+
+// CreateInstanceRequest creates an instance. Settings not covered here are
+// available through the REST API.
+type CreateInstanceRequest struct {
+	Name                 string            `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Image                string            `protobuf:"bytes,2,opt,name=image,proto3" json:"image,omitempty"`
+	Vcpus                int32             `protobuf:"varint,3,opt,name=vcpus,proto3" json:"vcpus,omitempty"`
+	Size                 string            `protobuf:"bytes,4,opt,name=size,proto3" json:"size,omitempty"`
+	HotplugSize          string            `protobuf:"bytes,5,opt,name=hotplug_size,json=hotplugSize,proto3" json:"hotplug_size,omitempty"`
+	OverlaySize          string            `protobuf:"bytes,6,opt,name=overlay_size,json=overlaySize,proto3" json:"overlay_size,omitempty"`
+	Env                  map[string]string `protobuf:"bytes,7,rep,name=env,proto3" json:"env,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+	Cmd                  []string          `protobuf:"bytes,8,rep,name=cmd,proto3" json:"cmd,omitempty"`
+	Entrypoint           []string          `protobuf:"bytes,9,rep,name=entrypoint,proto3" json:"entrypoint,omitempty"`
+	Workdir              string            `protobuf:"bytes,10,opt,name=workdir,proto3" json:"workdir,omitempty"`
+	DisableNetwork       bool              `protobuf:"varint,11,opt,name=disable_network,json=disableNetwork,proto3" json:"disable_network,omitempty"`
+	Volumes              []*VolumeMount    `protobuf:"bytes,12,rep,name=volumes,proto3" json:"volumes,omitempty"`
+	ShutdownGracePeriod  string            `protobuf:"bytes,13,opt,name=shutdown_grace_period,json=shutdownGracePeriod,proto3" json:"shutdown_grace_period,omitempty"`
+	Tenant               string            `protobuf:"bytes,14,opt,name=tenant,proto3" json:"tenant,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}          `json:"-"`
+	XXX_unrecognized     []byte            `json:"-"`
+	XXX_sizecache        int32             `json:"-"`
+}
+
+func (m *CreateInstanceRequest) Reset()         { *m = CreateInstanceRequest{} }
+func (m *CreateInstanceRequest) String() string { return proto.CompactTextString(m) }
+func (*CreateInstanceRequest) ProtoMessage()    {}
+func (*CreateInstanceRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_1fe173d347cc7416, []int{4}
+}
+
+func (m *CreateInstanceRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_CreateInstanceRequest.Unmarshal(m, b)
+}
+func (m *CreateInstanceRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_CreateInstanceRequest.Marshal(b, m, deterministic)
+}
+func (m *CreateInstanceRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_CreateInstanceRequest.Merge(m, src)
+}
+func (m *CreateInstanceRequest) XXX_Size() int {
+	return xxx_messageInfo_CreateInstanceRequest.Size(m)
+}
+func (m *CreateInstanceRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_CreateInstanceRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_CreateInstanceRequest proto.InternalMessageInfo
+
+func (m *CreateInstanceRequest) GetName() string {
+	if m != nil {
+		return m.Name
+	}
+	return ""
+}
+
+func (m *CreateInstanceRequest) GetImage() string {
+	if m != nil {
+		return m.Image
+	}
+	return ""
+}
+
+func (m *CreateInstanceRequest) GetVcpus() int32 {
+	if m != nil {
+		return m.Vcpus
+	}
+	return 0
+}
+
+func (m *CreateInstanceRequest) GetSize() string {
+	if m != nil {
+		return m.Size
+	}
+	return ""
+}
+
+func (m *CreateInstanceRequest) GetHotplugSize() string {
+	if m != nil {
+		return m.HotplugSize
+	}
+	return ""
+}
+
+func (m *CreateInstanceRequest) GetOverlaySize() string {
+	if m != nil {
+		return m.OverlaySize
+	}
+	return ""
+}
+
+func (m *CreateInstanceRequest) GetEnv() map[string]string {
+	if m != nil {
+		return m.Env
+	}
+	return nil
+}
+
+func (m *CreateInstanceRequest) GetCmd() []string {
+	if m != nil {
+		return m.Cmd
+	}
+	return nil
+}
+
+func (m *CreateInstanceRequest) GetEntrypoint() []string {
+	if m != nil {
+		return m.Entrypoint
+	}
+	return nil
+}
+
+func (m *CreateInstanceRequest) GetWorkdir() string {
+	if m != nil {
+		return m.Workdir
+	}
+	return ""
+}
+
+func (m *CreateInstanceRequest) GetDisableNetwork() bool {
+	if m != nil {
+		return m.DisableNetwork
+	}
+	return false
+}
+
+func (m *CreateInstanceRequest) GetVolumes() []*VolumeMount {
+	if m != nil {
+		return m.Volumes
+	}
+	return nil
+}
+
+func (m *CreateInstanceRequest) GetShutdownGracePeriod() string {
+	if m != nil {
+		return m.ShutdownGracePeriod
+	}
+	return ""
+}
+
+func (m *CreateInstanceRequest) GetTenant() string {
+	if m != nil {
+		return m.Tenant
+	}
+	return ""
+}
+
+// VolumeMount attaches a volume to a new instance
+type VolumeMount struct {
+	VolumeId             string   `protobuf:"bytes,1,opt,name=volume_id,json=volumeId,proto3" json:"volume_id,omitempty"`
+	MountPath            string   `protobuf:"bytes,2,opt,name=mount_path,json=mountPath,proto3" json:"mount_path,omitempty"`
+	Readonly             bool     `protobuf:"varint,3,opt,name=readonly,proto3" json:"readonly,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *VolumeMount) Reset()         { *m = VolumeMount{} }
+func (m *VolumeMount) String() string { return proto.CompactTextString(m) }
+func (*VolumeMount) ProtoMessage()    {}
+func (*VolumeMount) Descriptor() ([]byte, []int) {
+	return fileDescriptor_1fe173d347cc7416, []int{5}
+}
+
+func (m *VolumeMount) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_VolumeMount.Unmarshal(m, b)
+}
+func (m *VolumeMount) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_VolumeMount.Marshal(b, m, deterministic)
+}
+func (m *VolumeMount) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_VolumeMount.Merge(m, src)
+}
+func (m *VolumeMount) XXX_Size() int {
+	return xxx_messageInfo_VolumeMount.Size(m)
+}
+func (m *VolumeMount) XXX_DiscardUnknown() {
+	xxx_messageInfo_VolumeMount.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_VolumeMount proto.InternalMessageInfo
+
+func (m *VolumeMount) GetVolumeId() string {
+	if m != nil {
+		return m.VolumeId
+	}
+	return ""
+}
+
+func (m *VolumeMount) GetMountPath() string {
+	if m != nil {
+		return m.MountPath
+	}
+	return ""
+}
+
+func (m *VolumeMount) GetReadonly() bool {
+	if m != nil {
+		return m.Readonly
+	}
+	return false
+}
+
+// DeleteInstanceRequest deletes an instance
+type DeleteInstanceRequest struct {
+	Id                   string   `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	GracePeriod          string   `protobuf:"bytes,2,opt,name=grace_period,json=gracePeriod,proto3" json:"grace_period,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *DeleteInstanceRequest) Reset()         { *m = DeleteInstanceRequest{} }
+func (m *DeleteInstanceRequest) String() string { return proto.CompactTextString(m) }
+func (*DeleteInstanceRequest) ProtoMessage()    {}
+func (*DeleteInstanceRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_1fe173d347cc7416, []int{6}
+}
+
+func (m *DeleteInstanceRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_DeleteInstanceRequest.Unmarshal(m, b)
+}
+func (m *DeleteInstanceRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_DeleteInstanceRequest.Marshal(b, m, deterministic)
+}
+func (m *DeleteInstanceRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_DeleteInstanceRequest.Merge(m, src)
+}
+func (m *DeleteInstanceRequest) XXX_Size() int {
+	return xxx_messageInfo_DeleteInstanceRequest.Size(m)
+}
+func (m *DeleteInstanceRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_DeleteInstanceRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_DeleteInstanceRequest proto.InternalMessageInfo
+
+func (m *DeleteInstanceRequest) GetId() string {
+	if m != nil {
+		return m.Id
+	}
+	return ""
+}
+
+func (m *DeleteInstanceRequest) GetGracePeriod() string {
+	if m != nil {
+		return m.GracePeriod
+	}
+	return ""
+}
+
+// DeleteResponse is returned by deletions and cancellations
+type DeleteResponse struct {
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *DeleteResponse) Reset()         { *m = DeleteResponse{} }
+func (m *DeleteResponse) String() string { return proto.CompactTextString(m) }
+func (*DeleteResponse) ProtoMessage()    {}
+func (*DeleteResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_1fe173d347cc7416, []int{7}
+}
+
+func (m *DeleteResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_DeleteResponse.Unmarshal(m, b)
+}
+func (m *DeleteResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_DeleteResponse.Marshal(b, m, deterministic)
+}
+func (m *DeleteResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_DeleteResponse.Merge(m, src)
+}
+func (m *DeleteResponse) XXX_Size() int {
+	return xxx_messageInfo_DeleteResponse.Size(m)
+}
+func (m *DeleteResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_DeleteResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_DeleteResponse proto.InternalMessageInfo
+
+// StreamInstanceLogsRequest streams an instance's logs
+type StreamInstanceLogsRequest struct {
+	Id                   string   `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Tail                 int32    `protobuf:"varint,2,opt,name=tail,proto3" json:"tail,omitempty"`
+	Follow               bool     `protobuf:"varint,3,opt,name=follow,proto3" json:"follow,omitempty"`
+	Source               string   `protobuf:"bytes,4,opt,name=source,proto3" json:"source,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *StreamInstanceLogsRequest) Reset()         { *m = StreamInstanceLogsRequest{} }
+func (m *StreamInstanceLogsRequest) String() string { return proto.CompactTextString(m) }
+func (*StreamInstanceLogsRequest) ProtoMessage()    {}
+func (*StreamInstanceLogsRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_1fe173d347cc7416, []int{8}
+}
+
+func (m *StreamInstanceLogsRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_StreamInstanceLogsRequest.Unmarshal(m, b)
+}
+func (m *StreamInstanceLogsRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_StreamInstanceLogsRequest.Marshal(b, m, deterministic)
+}
+func (m *StreamInstanceLogsRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_StreamInstanceLogsRequest.Merge(m, src)
+}
+func (m *StreamInstanceLogsRequest) XXX_Size() int {
+	return xxx_messageInfo_StreamInstanceLogsRequest.Size(m)
+}
+func (m *StreamInstanceLogsRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_StreamInstanceLogsRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_StreamInstanceLogsRequest proto.InternalMessageInfo
+
+func (m *StreamInstanceLogsRequest) GetId() string {
+	if m != nil {
+		return m.Id
+	}
+	return ""
+}
+
+func (m *StreamInstanceLogsRequest) GetTail() int32 {
+	if m != nil {
+		return m.Tail
+	}
+	return 0
+}
+
+func (m *StreamInstanceLogsRequest) GetFollow() bool {
+	if m != nil {
+		return m.Follow
+	}
+	return false
+}
+
+func (m *StreamInstanceLogsRequest) GetSource() string {
+	if m != nil {
+		return m.Source
+	}
+	return ""
+}
+
+// LogLine is a line of an instance's log
+type LogLine struct {
+	Line                 string   `protobuf:"bytes,1,opt,name=line,proto3" json:"line,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *LogLine) Reset()         { *m = LogLine{} }
+func (m *LogLine) String() string { return proto.CompactTextString(m) }
+func (*LogLine) ProtoMessage()    {}
+func (*LogLine) Descriptor() ([]byte, []int) {
+	return fileDescriptor_1fe173d347cc7416, []int{9}
+}
+
+func (m *LogLine) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_LogLine.Unmarshal(m, b)
+}
+func (m *LogLine) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_LogLine.Marshal(b, m, deterministic)
+}
+func (m *LogLine) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_LogLine.Merge(m, src)
+}
+func (m *LogLine) XXX_Size() int {
+	return xxx_messageInfo_LogLine.Size(m)
+}
+func (m *LogLine) XXX_DiscardUnknown() {
+	xxx_messageInfo_LogLine.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_LogLine proto.InternalMessageInfo
+
+func (m *LogLine) GetLine() string {
+	if m != nil {
+		return m.Line
+	}
+	return ""
+}
+
+// Image describes an OCI image converted for use by instances
+type Image struct {
+	Name                 string   `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Digest               string   `protobuf:"bytes,2,opt,name=digest,proto3" json:"digest,omitempty"`
+	Status               string   `protobuf:"bytes,3,opt,name=status,proto3" json:"status,omitempty"`
+	Error                string   `protobuf:"bytes,4,opt,name=error,proto3" json:"error,omitempty"`
+	SizeBytes            int64    `protobuf:"varint,5,opt,name=size_bytes,json=sizeBytes,proto3" json:"size_bytes,omitempty"`
+	CreatedAt            int64    `protobuf:"varint,6,opt,name=created_at,json=createdAt,proto3" json:"created_at,omitempty"`
+	Tenant               string   `protobuf:"bytes,7,opt,name=tenant,proto3" json:"tenant,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *Image) Reset()         { *m = Image{} }
+func (m *Image) String() string { return proto.CompactTextString(m) }
+func (*Image) ProtoMessage()    {}
+func (*Image) Descriptor() ([]byte, []int) {
+	return fileDescriptor_1fe173d347cc7416, []int{10}
+}
+
+func (m *Image) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Image.Unmarshal(m, b)
+}
+func (m *Image) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Image.Marshal(b, m, deterministic)
+}
+func (m *Image) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Image.Merge(m, src)
+}
+func (m *Image) XXX_Size() int {
+	return xxx_messageInfo_Image.Size(m)
+}
+func (m *Image) XXX_DiscardUnknown() {
+	xxx_messageInfo_Image.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Image proto.InternalMessageInfo
+
+func (m *Image) GetName() string {
+	if m != nil {
+		return m.Name
+	}
+	return ""
+}
+
+func (m *Image) GetDigest() string {
+	if m != nil {
+		return m.Digest
+	}
+	return ""
+}
+
+func (m *Image) GetStatus() string {
+	if m != nil {
+		return m.Status
+	}
+	return ""
+}
+
+func (m *Image) GetError() string {
+	if m != nil {
+		return m.Error
+	}
+	return ""
+}
+
+func (m *Image) GetSizeBytes() int64 {
+	if m != nil {
+		return m.SizeBytes
+	}
+	return 0
+}
+
+func (m *Image) GetCreatedAt() int64 {
+	if m != nil {
+		return m.CreatedAt
+	}
+	return 0
+}
+
+func (m *Image) GetTenant() string {
+	if m != nil {
+		return m.Tenant
+	}
+	return ""
+}
+
+// ImageRequest identifies an image by name
+type ImageRequest struct {
+	Name                 string   `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ImageRequest) Reset()         { *m = ImageRequest{} }
+func (m *ImageRequest) String() string { return proto.CompactTextString(m) }
+func (*ImageRequest) ProtoMessage()    {}
+func (*ImageRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_1fe173d347cc7416, []int{11}
+}
+
+func (m *ImageRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ImageRequest.Unmarshal(m, b)
+}
+func (m *ImageRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ImageRequest.Marshal(b, m, deterministic)
+}
+func (m *ImageRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ImageRequest.Merge(m, src)
+}
+func (m *ImageRequest) XXX_Size() int {
+	return xxx_messageInfo_ImageRequest.Size(m)
+}
+func (m *ImageRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_ImageRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ImageRequest proto.InternalMessageInfo
+
+func (m *ImageRequest) GetName() string {
+	if m != nil {
+		return m.Name
+	}
+	return ""
+}
+
+// ListImagesRequest lists images
+type ListImagesRequest struct {
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ListImagesRequest) Reset()         { *m = ListImagesRequest{} }
+func (m *ListImagesRequest) String() string { return proto.CompactTextString(m) }
+func (*ListImagesRequest) ProtoMessage()    {}
+func (*ListImagesRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_1fe173d347cc7416, []int{12}
+}
+
+func (m *ListImagesRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ListImagesRequest.Unmarshal(m, b)
+}
+func (m *ListImagesRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ListImagesRequest.Marshal(b, m, deterministic)
+}
+func (m *ListImagesRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ListImagesRequest.Merge(m, src)
+}
+func (m *ListImagesRequest) XXX_Size() int {
+	return xxx_messageInfo_ListImagesRequest.Size(m)
+}
+func (m *ListImagesRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_ListImagesRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ListImagesRequest proto.InternalMessageInfo
+
+// ListImagesResponse contains the images
+type ListImagesResponse struct {
+	Images               []*Image `protobuf:"bytes,1,rep,name=images,proto3" json:"images,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ListImagesResponse) Reset()         { *m = ListImagesResponse{} }
+func (m *ListImagesResponse) String() string { return proto.CompactTextString(m) }
+func (*ListImagesResponse) ProtoMessage()    {}
+func (*ListImagesResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_1fe173d347cc7416, []int{13}
+}
+
+func (m *ListImagesResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ListImagesResponse.Unmarshal(m, b)
+}
+func (m *ListImagesResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ListImagesResponse.Marshal(b, m, deterministic)
+}
+func (m *ListImagesResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ListImagesResponse.Merge(m, src)
+}
+func (m *ListImagesResponse) XXX_Size() int {
+	return xxx_messageInfo_ListImagesResponse.Size(m)
+}
+func (m *ListImagesResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_ListImagesResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ListImagesResponse proto.InternalMessageInfo
+
+func (m *ListImagesResponse) GetImages() []*Image {
+	if m != nil {
+		return m.Images
+	}
+	return nil
+}
+
+// CreateImageRequest pulls an image
+type CreateImageRequest struct {
+	Name                 string   `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Tenant               string   `protobuf:"bytes,2,opt,name=tenant,proto3" json:"tenant,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *CreateImageRequest) Reset()         { *m = CreateImageRequest{} }
+func (m *CreateImageRequest) String() string { return proto.CompactTextString(m) }
+func (*CreateImageRequest) ProtoMessage()    {}
+func (*CreateImageRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_1fe173d347cc7416, []int{14}
+}
+
+func (m *CreateImageRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_CreateImageRequest.Unmarshal(m, b)
+}
+func (m *CreateImageRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_CreateImageRequest.Marshal(b, m, deterministic)
+}
+func (m *CreateImageRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_CreateImageRequest.Merge(m, src)
+}
+func (m *CreateImageRequest) XXX_Size() int {
+	return xxx_messageInfo_CreateImageRequest.Size(m)
+}
+func (m *CreateImageRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_CreateImageRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_CreateImageRequest proto.InternalMessageInfo
+
+func (m *CreateImageRequest) GetName() string {
+	if m != nil {
+		return m.Name
+	}
+	return ""
+}
+
+func (m *CreateImageRequest) GetTenant() string {
+	if m != nil {
+		return m.Tenant
+	}
+	return ""
+}
+
+// Volume describes a persistent volume
+type Volume struct {
+	Id                   string   `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Name                 string   `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`
+	SizeGb               int32    `protobuf:"varint,3,opt,name=size_gb,json=sizeGb,proto3" json:"size_gb,omitempty"`
+	CreatedAt            int64    `protobuf:"varint,4,opt,name=created_at,json=createdAt,proto3" json:"created_at,omitempty"`
+	Tenant               string   `protobuf:"bytes,5,opt,name=tenant,proto3" json:"tenant,omitempty"`
+	InstanceIds          []string `protobuf:"bytes,6,rep,name=instance_ids,json=instanceIds,proto3" json:"instance_ids,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *Volume) Reset()         { *m = Volume{} }
+func (m *Volume) String() string { return proto.CompactTextString(m) }
+func (*Volume) ProtoMessage()    {}
+func (*Volume) Descriptor() ([]byte, []int) {
+	return fileDescriptor_1fe173d347cc7416, []int{15}
+}
+
+func (m *Volume) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Volume.Unmarshal(m, b)
+}
+func (m *Volume) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Volume.Marshal(b, m, deterministic)
+}
+func (m *Volume) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Volume.Merge(m, src)
+}
+func (m *Volume) XXX_Size() int {
+	return xxx_messageInfo_Volume.Size(m)
+}
+func (m *Volume) XXX_DiscardUnknown() {
+	xxx_messageInfo_Volume.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Volume proto.InternalMessageInfo
+
+func (m *Volume) GetId() string {
+	if m != nil {
+		return m.Id
+	}
+	return ""
+}
+
+func (m *Volume) GetName() string {
+	if m != nil {
+		return m.Name
+	}
+	return ""
+}
+
+func (m *Volume) GetSizeGb() int32 {
+	if m != nil {
+		return m.SizeGb
+	}
+	return 0
+}
+
+func (m *Volume) GetCreatedAt() int64 {
+	if m != nil {
+		return m.CreatedAt
+	}
+	return 0
+}
+
+func (m *Volume) GetTenant() string {
+	if m != nil {
+		return m.Tenant
+	}
+	return ""
+}
+
+func (m *Volume) GetInstanceIds() []string {
+	if m != nil {
+		return m.InstanceIds
+	}
+	return nil
+}
+
+// VolumeRequest identifies a volume by ID or name
+type VolumeRequest struct {
+	Id                   string   `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *VolumeRequest) Reset()         { *m = VolumeRequest{} }
+func (m *VolumeRequest) String() string { return proto.CompactTextString(m) }
+func (*VolumeRequest) ProtoMessage()    {}
+func (*VolumeRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_1fe173d347cc7416, []int{16}
+}
+
+func (m *VolumeRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_VolumeRequest.Unmarshal(m, b)
+}
+func (m *VolumeRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_VolumeRequest.Marshal(b, m, deterministic)
+}
+func (m *VolumeRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_VolumeRequest.Merge(m, src)
+}
+func (m *VolumeRequest) XXX_Size() int {
+	return xxx_messageInfo_VolumeRequest.Size(m)
+}
+func (m *VolumeRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_VolumeRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_VolumeRequest proto.InternalMessageInfo
+
+func (m *VolumeRequest) GetId() string {
+	if m != nil {
+		return m.Id
+	}
+	return ""
+}
+
+// ListVolumesRequest lists volumes
+type ListVolumesRequest struct {
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ListVolumesRequest) Reset()         { *m = ListVolumesRequest{} }
+func (m *ListVolumesRequest) String() string { return proto.CompactTextString(m) }
+func (*ListVolumesRequest) ProtoMessage()    {}
+func (*ListVolumesRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_1fe173d347cc7416, []int{17}
+}
+
+func (m *ListVolumesRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ListVolumesRequest.Unmarshal(m, b)
+}
+func (m *ListVolumesRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ListVolumesRequest.Marshal(b, m, deterministic)
+}
+func (m *ListVolumesRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ListVolumesRequest.Merge(m, src)
+}
+func (m *ListVolumesRequest) XXX_Size() int {
+	return xxx_messageInfo_ListVolumesRequest.Size(m)
+}
+func (m *ListVolumesRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_ListVolumesRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ListVolumesRequest proto.InternalMessageInfo
+
+// ListVolumesResponse contains the volumes
+type ListVolumesResponse struct {
+	Volumes              []*Volume `protobuf:"bytes,1,rep,name=volumes,proto3" json:"volumes,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}  `json:"-"`
+	XXX_unrecognized     []byte    `json:"-"`
+	XXX_sizecache        int32     `json:"-"`
+}
+
+func (m *ListVolumesResponse) Reset()         { *m = ListVolumesResponse{} }
+func (m *ListVolumesResponse) String() string { return proto.CompactTextString(m) }
+func (*ListVolumesResponse) ProtoMessage()    {}
+func (*ListVolumesResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_1fe173d347cc7416, []int{18}
+}
+
+func (m *ListVolumesResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ListVolumesResponse.Unmarshal(m, b)
+}
+func (m *ListVolumesResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ListVolumesResponse.Marshal(b, m, deterministic)
+}
+func (m *ListVolumesResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ListVolumesResponse.Merge(m, src)
+}
+func (m *ListVolumesResponse) XXX_Size() int {
+	return xxx_messageInfo_ListVolumesResponse.Size(m)
+}
+func (m *ListVolumesResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_ListVolumesResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ListVolumesResponse proto.InternalMessageInfo
+
+func (m *ListVolumesResponse) GetVolumes() []*Volume {
+	if m != nil {
+		return m.Volumes
+	}
+	return nil
+}
+
+// CreateVolumeRequest creates an empty volume
+type CreateVolumeRequest struct {
+	Name                 string   `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	SizeGb               int32    `protobuf:"varint,2,opt,name=size_gb,json=sizeGb,proto3" json:"size_gb,omitempty"`
+	Id                   string   `protobuf:"bytes,3,opt,name=id,proto3" json:"id,omitempty"`
+	Tenant               string   `protobuf:"bytes,4,opt,name=tenant,proto3" json:"tenant,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *CreateVolumeRequest) Reset()         { *m = CreateVolumeRequest{} }
+func (m *CreateVolumeRequest) String() string { return proto.CompactTextString(m) }
+func (*CreateVolumeRequest) ProtoMessage()    {}
+func (*CreateVolumeRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_1fe173d347cc7416, []int{19}
+}
+
+func (m *CreateVolumeRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_CreateVolumeRequest.Unmarshal(m, b)
+}
+func (m *CreateVolumeRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_CreateVolumeRequest.Marshal(b, m, deterministic)
+}
+func (m *CreateVolumeRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_CreateVolumeRequest.Merge(m, src)
+}
+func (m *CreateVolumeRequest) XXX_Size() int {
+	return xxx_messageInfo_CreateVolumeRequest.Size(m)
+}
+func (m *CreateVolumeRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_CreateVolumeRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_CreateVolumeRequest proto.InternalMessageInfo
+
+func (m *CreateVolumeRequest) GetName() string {
+	if m != nil {
+		return m.Name
+	}
+	return ""
+}
+
+func (m *CreateVolumeRequest) GetSizeGb() int32 {
+	if m != nil {
+		return m.SizeGb
+	}
+	return 0
+}
+
+func (m *CreateVolumeRequest) GetId() string {
+	if m != nil {
+		return m.Id
+	}
+	return ""
+}
+
+func (m *CreateVolumeRequest) GetTenant() string {
+	if m != nil {
+		return m.Tenant
+	}
+	return ""
+}
+
+// Build describes an image build
+type Build struct {
+	Id                   string   `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Status               string   `protobuf:"bytes,2,opt,name=status,proto3" json:"status,omitempty"`
+	ImageRef             string   `protobuf:"bytes,3,opt,name=image_ref,json=imageRef,proto3" json:"image_ref,omitempty"`
+	ImageDigest          string   `protobuf:"bytes,4,opt,name=image_digest,json=imageDigest,proto3" json:"image_digest,omitempty"`
+	Error                string   `protobuf:"bytes,5,opt,name=error,proto3" json:"error,omitempty"`
+	CreatedAt            int64    `protobuf:"varint,6,opt,name=created_at,json=createdAt,proto3" json:"created_at,omitempty"`
+	StartedAt            int64    `protobuf:"varint,7,opt,name=started_at,json=startedAt,proto3" json:"started_at,omitempty"`
+	CompletedAt          int64    `protobuf:"varint,8,opt,name=completed_at,json=completedAt,proto3" json:"completed_at,omitempty"`
+	Tenant               string   `protobuf:"bytes,9,opt,name=tenant,proto3" json:"tenant,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *Build) Reset()         { *m = Build{} }
+func (m *Build) String() string { return proto.CompactTextString(m) }
+func (*Build) ProtoMessage()    {}
+func (*Build) Descriptor() ([]byte, []int) {
+	return fileDescriptor_1fe173d347cc7416, []int{20}
+}
+
+func (m *Build) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Build.Unmarshal(m, b)
+}
+func (m *Build) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Build.Marshal(b, m, deterministic)
+}
+func (m *Build) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Build.Merge(m, src)
+}
+func (m *Build) XXX_Size() int {
+	return xxx_messageInfo_Build.Size(m)
+}
+func (m *Build) XXX_DiscardUnknown() {
+	xxx_messageInfo_Build.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Build proto.InternalMessageInfo
+
+func (m *Build) GetId() string {
+	if m != nil {
+		return m.Id
+	}
+	return ""
+}
+
+func (m *Build) GetStatus() string {
+	if m != nil {
+		return m.Status
+	}
+	return ""
+}
+
+func (m *Build) GetImageRef() string {
+	if m != nil {
+		return m.ImageRef
+	}
+	return ""
+}
+
+func (m *Build) GetImageDigest() string {
+	if m != nil {
+		return m.ImageDigest
+	}
+	return ""
+}
+
+func (m *Build) GetError() string {
+	if m != nil {
+		return m.Error
+	}
+	return ""
+}
+
+func (m *Build) GetCreatedAt() int64 {
+	if m != nil {
+		return m.CreatedAt
+	}
+	return 0
+}
+
+func (m *Build) GetStartedAt() int64 {
+	if m != nil {
+		return m.StartedAt
+	}
+	return 0
+}
+
+func (m *Build) GetCompletedAt() int64 {
+	if m != nil {
+		return m.CompletedAt
+	}
+	return 0
+}
+
+func (m *Build) GetTenant() string {
+	if m != nil {
+		return m.Tenant
+	}
+	return ""
+}
+
+// BuildRequest identifies a build by ID
+type BuildRequest struct {
+	Id                   string   `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *BuildRequest) Reset()         { *m = BuildRequest{} }
+func (m *BuildRequest) String() string { return proto.CompactTextString(m) }
+func (*BuildRequest) ProtoMessage()    {}
+func (*BuildRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_1fe173d347cc7416, []int{21}
+}
+
+func (m *BuildRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_BuildRequest.Unmarshal(m, b)
+}
+func (m *BuildRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_BuildRequest.Marshal(b, m, deterministic)
+}
+func (m *BuildRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_BuildRequest.Merge(m, src)
+}
+func (m *BuildRequest) XXX_Size() int {
+	return xxx_messageInfo_BuildRequest.Size(m)
+}
+func (m *BuildRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_BuildRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_BuildRequest proto.InternalMessageInfo
+
+func (m *BuildRequest) GetId() string {
+	if m != nil {
+		return m.Id
+	}
+	return ""
+}
+
+// ListBuildsRequest lists builds
+type ListBuildsRequest struct {
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ListBuildsRequest) Reset()         { *m = ListBuildsRequest{} }
+func (m *ListBuildsRequest) String() string { return proto.CompactTextString(m) }
+func (*ListBuildsRequest) ProtoMessage()    {}
+func (*ListBuildsRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_1fe173d347cc7416, []int{22}
+}
+
+func (m *ListBuildsRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ListBuildsRequest.Unmarshal(m, b)
+}
+func (m *ListBuildsRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ListBuildsRequest.Marshal(b, m, deterministic)
+}
+func (m *ListBuildsRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ListBuildsRequest.Merge(m, src)
+}
+func (m *ListBuildsRequest) XXX_Size() int {
+	return xxx_messageInfo_ListBuildsRequest.Size(m)
+}
+func (m *ListBuildsRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_ListBuildsRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ListBuildsRequest proto.InternalMessageInfo
+
+// ListBuildsResponse contains the builds
+type ListBuildsResponse struct {
+	Builds               []*Build `protobuf:"bytes,1,rep,name=builds,proto3" json:"builds,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ListBuildsResponse) Reset()         { *m = ListBuildsResponse{} }
+func (m *ListBuildsResponse) String() string { return proto.CompactTextString(m) }
+func (*ListBuildsResponse) ProtoMessage()    {}
+func (*ListBuildsResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_1fe173d347cc7416, []int{23}
+}
+
+func (m *ListBuildsResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ListBuildsResponse.Unmarshal(m, b)
+}
+func (m *ListBuildsResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ListBuildsResponse.Marshal(b, m, deterministic)
+}
+func (m *ListBuildsResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ListBuildsResponse.Merge(m, src)
+}
+func (m *ListBuildsResponse) XXX_Size() int {
+	return xxx_messageInfo_ListBuildsResponse.Size(m)
+}
+func (m *ListBuildsResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_ListBuildsResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ListBuildsResponse proto.InternalMessageInfo
+
+func (m *ListBuildsResponse) GetBuilds() []*Build {
+	if m != nil {
+		return m.Builds
+	}
+	return nil
+}
+
+// StreamBuildEventsRequest streams a build's events
+type StreamBuildEventsRequest struct {
+	Id                   string   `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Follow               bool     `protobuf:"varint,2,opt,name=follow,proto3" json:"follow,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *StreamBuildEventsRequest) Reset()         { *m = StreamBuildEventsRequest{} }
+func (m *StreamBuildEventsRequest) String() string { return proto.CompactTextString(m) }
+func (*StreamBuildEventsRequest) ProtoMessage()    {}
+func (*StreamBuildEventsRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_1fe173d347cc7416, []int{24}
+}
+
+func (m *StreamBuildEventsRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_StreamBuildEventsRequest.Unmarshal(m, b)
+}
+func (m *StreamBuildEventsRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_StreamBuildEventsRequest.Marshal(b, m, deterministic)
+}
+func (m *StreamBuildEventsRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_StreamBuildEventsRequest.Merge(m, src)
+}
+func (m *StreamBuildEventsRequest) XXX_Size() int {
+	return xxx_messageInfo_StreamBuildEventsRequest.Size(m)
+}
+func (m *StreamBuildEventsRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_StreamBuildEventsRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_StreamBuildEventsRequest proto.InternalMessageInfo
+
+func (m *StreamBuildEventsRequest) GetId() string {
+	if m != nil {
+		return m.Id
+	}
+	return ""
+}
+
+func (m *StreamBuildEventsRequest) GetFollow() bool {
+	if m != nil {
+		return m.Follow
+	}
+	return false
+}
+
+// BuildEvent is a build log line or status change
+type BuildEvent struct {
+	Type                 string   `protobuf:"bytes,1,opt,name=type,proto3" json:"type,omitempty"`
+	Timestamp            int64    `protobuf:"varint,2,opt,name=timestamp,proto3" json:"timestamp,omitempty"`
+	Content              string   `protobuf:"bytes,3,opt,name=content,proto3" json:"content,omitempty"`
+	Status               string   `protobuf:"bytes,4,opt,name=status,proto3" json:"status,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *BuildEvent) Reset()         { *m = BuildEvent{} }
+func (m *BuildEvent) String() string { return proto.CompactTextString(m) }
+func (*BuildEvent) ProtoMessage()    {}
+func (*BuildEvent) Descriptor() ([]byte, []int) {
+	return fileDescriptor_1fe173d347cc7416, []int{25}
+}
+
+func (m *BuildEvent) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_BuildEvent.Unmarshal(m, b)
+}
+func (m *BuildEvent) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_BuildEvent.Marshal(b, m, deterministic)
+}
+func (m *BuildEvent) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_BuildEvent.Merge(m, src)
+}
+func (m *BuildEvent) XXX_Size() int {
+	return xxx_messageInfo_BuildEvent.Size(m)
+}
+func (m *BuildEvent) XXX_DiscardUnknown() {
+	xxx_messageInfo_BuildEvent.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_BuildEvent proto.InternalMessageInfo
+
+func (m *BuildEvent) GetType() string {
+	if m != nil {
+		return m.Type
+	}
+	return ""
+}
+
+func (m *BuildEvent) GetTimestamp() int64 {
+	if m != nil {
+		return m.Timestamp
+	}
+	return 0
+}
+
+func (m *BuildEvent) GetContent() string {
+	if m != nil {
+		return m.Content
+	}
+	return ""
+}
+
+func (m *BuildEvent) GetStatus() string {
+	if m != nil {
+		return m.Status
+	}
+	return ""
+}
+
+func init() {
+	proto.RegisterType((*Instance)(nil), "mgmt.Instance")
+	proto.RegisterMapType((map[string]string)(nil), "mgmt.Instance.EnvEntry")
+	proto.RegisterType((*InstanceRequest)(nil), "mgmt.InstanceRequest")
+	proto.RegisterType((*ListInstancesRequest)(nil), "mgmt.ListInstancesRequest")
+	proto.RegisterType((*ListInstancesResponse)(nil), "mgmt.ListInstancesResponse")
+	proto.RegisterType((*CreateInstanceRequest)(nil), "mgmt.CreateInstanceRequest")
+	proto.RegisterMapType((map[string]string)(nil), "mgmt.CreateInstanceRequest.EnvEntry")
+	proto.RegisterType((*VolumeMount)(nil), "mgmt.VolumeMount")
+	proto.RegisterType((*DeleteInstanceRequest)(nil), "mgmt.DeleteInstanceRequest")
+	proto.RegisterType((*DeleteResponse)(nil), "mgmt.DeleteResponse")
+	proto.RegisterType((*StreamInstanceLogsRequest)(nil), "mgmt.StreamInstanceLogsRequest")
+	proto.RegisterType((*LogLine)(nil), "mgmt.LogLine")
+	proto.RegisterType((*Image)(nil), "mgmt.Image")
+	proto.RegisterType((*ImageRequest)(nil), "mgmt.ImageRequest")
+	proto.RegisterType((*ListImagesRequest)(nil), "mgmt.ListImagesRequest")
+	proto.RegisterType((*ListImagesResponse)(nil), "mgmt.ListImagesResponse")
+	proto.RegisterType((*CreateImageRequest)(nil), "mgmt.CreateImageRequest")
+	proto.RegisterType((*Volume)(nil), "mgmt.Volume")
+	proto.RegisterType((*VolumeRequest)(nil), "mgmt.VolumeRequest")
+	proto.RegisterType((*ListVolumesRequest)(nil), "mgmt.ListVolumesRequest")
+	proto.RegisterType((*ListVolumesResponse)(nil), "mgmt.ListVolumesResponse")
+	proto.RegisterType((*CreateVolumeRequest)(nil), "mgmt.CreateVolumeRequest")
+	proto.RegisterType((*Build)(nil), "mgmt.Build")
+	proto.RegisterType((*BuildRequest)(nil), "mgmt.BuildRequest")
+	proto.RegisterType((*ListBuildsRequest)(nil), "mgmt.ListBuildsRequest")
+	proto.RegisterType((*ListBuildsResponse)(nil), "mgmt.ListBuildsResponse")
+	proto.RegisterType((*StreamBuildEventsRequest)(nil), "mgmt.StreamBuildEventsRequest")
+	proto.RegisterType((*BuildEvent)(nil), "mgmt.BuildEvent")
+}
+
+func init() {
+	proto.RegisterFile("lib/mgmt/mgmt.proto", fileDescriptor_1fe173d347cc7416)
+}
+
+var fileDescriptor_1fe173d347cc7416 = []byte{
+	// 1390 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x9c, 0x57, 0xdb, 0x6e, 0xdb, 0x46,
+	0x10, 0x85, 0xee, 0xd2, 0x50, 0x76, 0xe2, 0xb5, 0x9d, 0x30, 0x72, 0x93, 0xd8, 0x6c, 0xd0, 0xba,
+	0x48, 0x61, 0x07, 0x69, 0x9b, 0x4b, 0x81, 0xa0, 0x8d, 0x13, 0xd7, 0x75, 0xe1, 0x14, 0x01, 0x0d,
+	0xf4, 0xa1, 0x2f, 0x02, 0x25, 0x4e, 0x64, 0x36, 0x24, 0x97, 0x25, 0x57, 0x0a, 0x94, 0x7f, 0x28,
+	0xfa, 0x09, 0xfd, 0x8c, 0x7e, 0x5a, 0xdf, 0x8a, 0x62, 0x67, 0x97, 0xd2, 0x92, 0x92, 0x9c, 0x3a,
+	0x2f, 0x02, 0xf7, 0xcc, 0x5e, 0x66, 0x67, 0xce, 0x9c, 0x59, 0xc1, 0x66, 0x18, 0x0c, 0x0e, 0xa3,
+	0x51, 0x24, 0xe8, 0xe7, 0x20, 0x49, 0xb9, 0xe0, 0xac, 0x2e, 0xbf, 0x9d, 0x3f, 0x6a, 0xd0, 0x3e,
+	0x8d, 0x33, 0xe1, 0xc5, 0x43, 0x64, 0xeb, 0x50, 0x0d, 0x7c, 0xbb, 0xb2, 0x5b, 0xd9, 0xef, 0xb8,
+	0xd5, 0xc0, 0x67, 0x0c, 0xea, 0xb1, 0x17, 0xa1, 0x5d, 0x25, 0x84, 0xbe, 0xd9, 0x16, 0x34, 0x82,
+	0xc8, 0x1b, 0xa1, 0x5d, 0x23, 0x50, 0x0d, 0x24, 0x9a, 0x09, 0x4f, 0xa0, 0x5d, 0x57, 0x28, 0x0d,
+	0xd8, 0x5d, 0xb0, 0xe8, 0xa3, 0x8f, 0x69, 0xca, 0x53, 0xbb, 0x41, 0x36, 0x20, 0xe8, 0x58, 0x22,
+	0x72, 0xd9, 0x64, 0x98, 0x8c, 0x33, 0xbb, 0xb9, 0x5b, 0xd9, 0x6f, 0xb8, 0x6a, 0x20, 0x8f, 0xcd,
+	0x82, 0xf7, 0x68, 0xb7, 0xd4, 0xb1, 0xf2, 0x9b, 0xdd, 0x06, 0x18, 0xa6, 0xe8, 0x09, 0xf4, 0xfb,
+	0x9e, 0xb0, 0xdb, 0xbb, 0x95, 0xfd, 0x9a, 0xdb, 0xd1, 0xc8, 0x73, 0x21, 0xcd, 0x99, 0xf0, 0x52,
+	0x6d, 0xee, 0x28, 0xb3, 0x46, 0x72, 0x33, 0x4f, 0x12, 0x65, 0x86, 0xdc, 0x4c, 0xc8, 0x73, 0x41,
+	0xf7, 0x4e, 0x6c, 0x4b, 0xdf, 0x3b, 0x61, 0x37, 0xa0, 0x29, 0x30, 0xf6, 0x62, 0x61, 0x77, 0x09,
+	0xd3, 0x23, 0xf6, 0x05, 0xd4, 0x30, 0x9e, 0xd8, 0x6b, 0xbb, 0xb5, 0x7d, 0xeb, 0xe1, 0xcd, 0x03,
+	0x0a, 0x66, 0x1e, 0xbc, 0x83, 0xe3, 0x78, 0x72, 0x1c, 0x8b, 0x74, 0xea, 0xca, 0x39, 0xbd, 0x47,
+	0xd0, 0xce, 0x01, 0x76, 0x1d, 0x6a, 0x6f, 0x71, 0xaa, 0xe3, 0x2a, 0x3f, 0xe9, 0xde, 0x5e, 0x38,
+	0xce, 0x23, 0xab, 0x06, 0xdf, 0x56, 0x9f, 0x54, 0x9c, 0x3d, 0xb8, 0x96, 0xef, 0xe8, 0xe2, 0xef,
+	0x63, 0xcc, 0x44, 0x39, 0x2b, 0xce, 0x0d, 0xd8, 0x3a, 0x0b, 0x32, 0x91, 0x4f, 0xcb, 0xf4, 0x3c,
+	0xe7, 0x18, 0xb6, 0x4b, 0x78, 0x96, 0xf0, 0x38, 0x43, 0xf6, 0x25, 0x74, 0x82, 0x1c, 0xb4, 0x2b,
+	0xe4, 0xfc, 0x7a, 0xd1, 0x79, 0x77, 0x3e, 0xc1, 0xf9, 0xb3, 0x0e, 0xdb, 0x2f, 0x28, 0xb0, 0x65,
+	0x47, 0x72, 0x3a, 0x54, 0x96, 0xd1, 0xa1, 0x5a, 0xa2, 0x83, 0xca, 0x6b, 0x6d, 0x59, 0x5e, 0xeb,
+	0x46, 0x5e, 0xf7, 0xa0, 0x7b, 0xc1, 0x45, 0x12, 0x8e, 0x47, 0x7d, 0xb2, 0x29, 0x8e, 0x58, 0x1a,
+	0x3b, 0xd7, 0x53, 0xf8, 0x04, 0xd3, 0xd0, 0x9b, 0xaa, 0x29, 0x4d, 0x35, 0x45, 0x63, 0x34, 0xe5,
+	0x91, 0x4a, 0x4c, 0x8b, 0xee, 0x76, 0x4f, 0xdd, 0x6d, 0xe9, 0x1d, 0x8a, 0x59, 0x92, 0x99, 0x19,
+	0x46, 0xbe, 0xdd, 0xde, 0xad, 0xc9, 0xcc, 0x0c, 0x23, 0x9f, 0xdd, 0x01, 0x40, 0x69, 0x4f, 0x78,
+	0x10, 0x4b, 0x22, 0x49, 0x83, 0x81, 0x30, 0x1b, 0x5a, 0xef, 0x78, 0xfa, 0xd6, 0x0f, 0x52, 0xa2,
+	0x51, 0xc7, 0xcd, 0x87, 0xec, 0x73, 0xb8, 0xe6, 0x07, 0x99, 0x37, 0x08, 0xb1, 0x1f, 0xa3, 0x90,
+	0x28, 0x31, 0xaa, 0xed, 0xae, 0x6b, 0xf8, 0x67, 0x85, 0xb2, 0xfb, 0xd0, 0x9a, 0xf0, 0x70, 0x1c,
+	0x61, 0x66, 0x77, 0xc9, 0xe1, 0x0d, 0xe5, 0xf0, 0x2f, 0x04, 0xbe, 0xe2, 0xe3, 0x58, 0xb8, 0xf9,
+	0x0c, 0xf6, 0x10, 0xb6, 0xb3, 0x8b, 0xb1, 0xf0, 0xf9, 0xbb, 0xb8, 0x3f, 0x4a, 0xbd, 0x21, 0xf6,
+	0x13, 0x4c, 0x03, 0xee, 0xdb, 0x6b, 0x74, 0xfa, 0x66, 0x6e, 0x3c, 0x91, 0xb6, 0xd7, 0x64, 0x32,
+	0xe8, 0xbb, 0x6e, 0xd2, 0xf7, 0xa3, 0x39, 0x89, 0x60, 0x19, 0xbe, 0xb1, 0x1d, 0xe8, 0x28, 0xef,
+	0xfa, 0x33, 0x5a, 0xb6, 0x15, 0x70, 0xea, 0xcb, 0x4a, 0x8b, 0xe4, 0xac, 0x7e, 0xe2, 0x89, 0x0b,
+	0xbd, 0x55, 0x87, 0x90, 0xd7, 0x9e, 0xb8, 0x60, 0x3d, 0x68, 0xa7, 0xe8, 0xf9, 0x3c, 0x0e, 0xa7,
+	0xc4, 0x8d, 0xb6, 0x3b, 0x1b, 0x3b, 0x3f, 0xc1, 0xf6, 0x4b, 0x0c, 0x51, 0xe0, 0x07, 0x0a, 0x40,
+	0x12, 0xa2, 0x10, 0x0a, 0x75, 0x8a, 0x35, 0x9a, 0x87, 0xc0, 0xb9, 0x0e, 0xeb, 0x6a, 0xaf, 0xbc,
+	0x08, 0x1c, 0x0e, 0xb7, 0xce, 0x45, 0x8a, 0x5e, 0x94, 0xef, 0x7e, 0xc6, 0x47, 0xd9, 0xaa, 0x13,
+	0x18, 0xd4, 0x85, 0x17, 0x84, 0xb4, 0x73, 0xc3, 0xa5, 0x6f, 0x19, 0xd5, 0x37, 0x3c, 0x0c, 0xf9,
+	0x3b, 0xed, 0xb8, 0x1e, 0x49, 0x3c, 0xe3, 0xe3, 0x74, 0x98, 0xf3, 0x5a, 0x8f, 0x9c, 0xdb, 0xd0,
+	0x3a, 0xe3, 0xa3, 0xb3, 0x20, 0x46, 0xb9, 0x5d, 0x18, 0xc4, 0xb3, 0xc2, 0x91, 0xdf, 0xce, 0xdf,
+	0x15, 0x68, 0x9c, 0x52, 0xb1, 0x2c, 0x2b, 0xab, 0x1b, 0xd0, 0xf4, 0x83, 0x11, 0x66, 0x42, 0x5f,
+	0x4e, 0x8f, 0xe8, 0x30, 0xe1, 0x09, 0x5d, 0x59, 0x1d, 0x57, 0x8f, 0x64, 0xf2, 0x94, 0xc6, 0x6a,
+	0xfd, 0xa5, 0x01, 0xc9, 0x5e, 0xf0, 0x1e, 0xfb, 0x83, 0xa9, 0xc0, 0xcc, 0x6e, 0x68, 0xd9, 0x0b,
+	0xde, 0xe3, 0x91, 0x04, 0x4a, 0x9a, 0xda, 0x2c, 0x6b, 0xea, 0x9c, 0x46, 0x2d, 0x93, 0x46, 0x8e,
+	0x03, 0x5d, 0x72, 0xfc, 0x12, 0x59, 0x70, 0x36, 0x61, 0x83, 0xb4, 0x48, 0xce, 0x9b, 0x09, 0xd4,
+	0x53, 0x60, 0x26, 0xa8, 0xd5, 0xe9, 0x53, 0x68, 0x92, 0x68, 0xe4, 0xd2, 0x64, 0x69, 0x69, 0xa2,
+	0x23, 0xb4, 0xc9, 0xf9, 0x1e, 0x98, 0xae, 0xe7, 0x0f, 0x9c, 0x6c, 0x78, 0x5d, 0x2d, 0x78, 0xfd,
+	0x57, 0x05, 0x9a, 0x8a, 0xc5, 0xff, 0xab, 0xcd, 0xdd, 0x84, 0x16, 0x85, 0x6e, 0x34, 0xd0, 0x1a,
+	0xd6, 0x94, 0xc3, 0x93, 0x41, 0x29, 0x68, 0xf5, 0xd5, 0x41, 0x6b, 0x14, 0x5a, 0xc7, 0x1e, 0x74,
+	0x73, 0x89, 0xed, 0x07, 0xbe, 0x6c, 0x78, 0x52, 0x59, 0xac, 0x1c, 0x3b, 0xf5, 0x33, 0xe7, 0x2e,
+	0xac, 0x29, 0x07, 0x57, 0x09, 0xff, 0x96, 0x8a, 0x9f, 0x9a, 0x34, 0x8b, 0xea, 0x33, 0xd8, 0x2c,
+	0xa0, 0x3a, 0xac, 0x9f, 0xcd, 0x55, 0x46, 0xc5, 0xb5, 0x6b, 0xaa, 0xcc, 0x4c, 0x60, 0x9c, 0xdf,
+	0x60, 0x53, 0x45, 0xb6, 0x78, 0xf6, 0xb2, 0xd0, 0x1a, 0x31, 0xa9, 0x16, 0x62, 0xa2, 0x1c, 0xad,
+	0xcd, 0x02, 0x3a, 0x0f, 0x42, 0xbd, 0x90, 0x83, 0x7f, 0x2b, 0xd0, 0x38, 0x1a, 0x07, 0xa1, 0xbf,
+	0x90, 0x82, 0x39, 0xaf, 0xab, 0x05, 0x5e, 0xef, 0x40, 0x87, 0x18, 0xd0, 0x4f, 0xf1, 0x8d, 0x3e,
+	0xa0, 0x1d, 0x28, 0x0a, 0xbc, 0xa1, 0x98, 0x92, 0x51, 0x97, 0x8a, 0x3a, 0xcc, 0x22, 0xec, 0x25,
+	0x41, 0xf3, 0xba, 0x68, 0x94, 0xea, 0xe2, 0x32, 0xe2, 0x17, 0x1f, 0x13, 0xad, 0xf2, 0x63, 0x62,
+	0x0f, 0xba, 0x43, 0x1e, 0x25, 0x21, 0x16, 0x1e, 0x23, 0xd6, 0x0c, 0x2b, 0xb0, 0xa0, 0x53, 0x08,
+	0xc0, 0x1d, 0xe8, 0xd2, 0xfd, 0x57, 0x65, 0x58, 0x97, 0x0d, 0xcd, 0x29, 0x97, 0x4d, 0x0e, 0xce,
+	0xcb, 0x66, 0x40, 0x48, 0xb1, 0x6c, 0xd4, 0xf6, 0xda, 0xe4, 0x1c, 0x81, 0xad, 0x44, 0x8f, 0xe0,
+	0xe3, 0x09, 0xc6, 0x62, 0xa5, 0xe6, 0xcd, 0xf5, 0xad, 0x6a, 0xea, 0x9b, 0x93, 0x00, 0xcc, 0x57,
+	0x93, 0x32, 0x4e, 0x93, 0x19, 0x2f, 0xe4, 0x37, 0xfb, 0x04, 0x3a, 0x22, 0x88, 0x30, 0x13, 0x5e,
+	0x94, 0xd0, 0xe2, 0x9a, 0x3b, 0x07, 0x64, 0xc7, 0x1c, 0xf2, 0x58, 0x60, 0x2c, 0x74, 0x02, 0xf3,
+	0xa1, 0x91, 0xf4, 0xba, 0x99, 0xf4, 0x87, 0xff, 0x74, 0x60, 0xe3, 0x95, 0x17, 0x7b, 0x23, 0x8c,
+	0x30, 0x16, 0xe7, 0x98, 0x4e, 0x82, 0x21, 0xb2, 0x1f, 0x61, 0xad, 0xf0, 0xbc, 0x61, 0x3d, 0x75,
+	0xe3, 0x65, 0x6f, 0xa1, 0xde, 0xce, 0x52, 0x9b, 0x0e, 0xdd, 0xd7, 0x60, 0x9d, 0xe0, 0x0c, 0x67,
+	0xdb, 0xa5, 0xb7, 0x90, 0xde, 0xa2, 0xf4, 0x44, 0x62, 0xcf, 0x60, 0xbd, 0xf8, 0xa4, 0x60, 0x3b,
+	0x97, 0x3c, 0x34, 0x16, 0x96, 0xbf, 0xc8, 0x3b, 0x52, 0x79, 0xf9, 0xd2, 0x9e, 0xd7, 0xdb, 0x32,
+	0x8d, 0x33, 0xcf, 0x1f, 0xc1, 0xda, 0xb9, 0xe4, 0xe1, 0x55, 0x7d, 0xff, 0x06, 0xba, 0xe7, 0x82,
+	0x27, 0x57, 0x5d, 0xf6, 0x04, 0xae, 0x9d, 0x0b, 0x2f, 0xf6, 0x07, 0xd3, 0x8f, 0x58, 0xe9, 0x62,
+	0x26, 0x78, 0x8a, 0x57, 0x5d, 0xf9, 0x03, 0xb0, 0xc5, 0x3e, 0xcd, 0xee, 0xaa, 0x59, 0x2b, 0x3b,
+	0x78, 0x6f, 0x4d, 0x27, 0x5c, 0x75, 0xdc, 0x07, 0x15, 0xf6, 0x1d, 0xc0, 0xbc, 0xd9, 0xb0, 0x9b,
+	0x06, 0x1f, 0xcc, 0x9e, 0xd4, 0xb3, 0x17, 0x0d, 0x3a, 0xd6, 0xf7, 0xa1, 0x7d, 0x82, 0x0a, 0x64,
+	0xcc, 0xec, 0x49, 0x7a, 0xa5, 0xd9, 0xa7, 0x24, 0xa5, 0x8c, 0xfe, 0xc4, 0xec, 0x02, 0x33, 0x56,
+	0xae, 0x7a, 0x0c, 0x96, 0xce, 0xfe, 0xca, 0x53, 0x96, 0xf3, 0xe0, 0x08, 0x2c, 0x43, 0xf3, 0x99,
+	0x71, 0x89, 0x62, 0x73, 0xe8, 0xdd, 0x5a, 0x62, 0xd1, 0x7b, 0x1c, 0x40, 0xe7, 0x04, 0x35, 0xca,
+	0x36, 0x0b, 0xcd, 0x41, 0x2f, 0x2e, 0x74, 0x0c, 0xf6, 0x18, 0xba, 0x66, 0xa3, 0x60, 0xb7, 0xcc,
+	0x3b, 0x5e, 0xb6, 0xf0, 0x29, 0x74, 0x95, 0xfb, 0x97, 0x9d, 0xb5, 0xfc, 0x9e, 0x3a, 0x89, 0x4a,
+	0xfa, 0xcc, 0x24, 0x16, 0x14, 0xb2, 0x67, 0x2f, 0x1a, 0x0a, 0x49, 0x24, 0x30, 0x0f, 0xaf, 0x29,
+	0xc0, 0x3d, 0x53, 0x35, 0x65, 0x3a, 0x5e, 0x48, 0x5e, 0x85, 0xab, 0xe7, 0x2f, 0x77, 0xf3, 0x04,
+	0x36, 0x16, 0x64, 0x96, 0xdd, 0x31, 0x29, 0xbb, 0xa8, 0xbf, 0xbd, 0xeb, 0xc6, 0xf6, 0x64, 0x79,
+	0x50, 0x39, 0xba, 0xf7, 0xab, 0x33, 0x0a, 0xc4, 0xc5, 0x78, 0x70, 0x30, 0xe4, 0xd1, 0xe1, 0x5b,
+	0x4c, 0x63, 0x0c, 0x0f, 0x2f, 0xa6, 0x09, 0x46, 0x5e, 0x7c, 0x98, 0xff, 0x89, 0x1f, 0x34, 0xe9,
+	0x0f, 0xfc, 0x57, 0xff, 0x0d, 0x00, 0xeb, 0x59, 0x1f, 0xd0, 0xd7, 0x0f, 0x00, 0x00,
+}
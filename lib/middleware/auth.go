@@ -0,0 +1,362 @@
+package middleware
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"crypto/tls"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/kernel/hypeman/lib/logger"
+)
+
+const claimsKey contextKey = "claims"
+
+// APIKeyHeader is the header clients can use to present a static API key.
+// API keys are also accepted as a bearer token in the Authorization header.
+const APIKeyHeader = "X-API-Key"
+
+// Authentication methods recorded on Claims
+const (
+	AuthMethodJWT        = "jwt"
+	AuthMethodAPIKey     = "api_key"
+	AuthMethodClientCert = "client_cert"
+)
+
+// AuthConfig configures how API requests are authenticated.
+type AuthConfig struct {
+	// JwtSecret verifies HMAC-signed (HS256/384/512) tokens. Empty disables HMAC tokens.
+	JwtSecret string
+
+	// JwksURL is fetched to verify asymmetric (RS*, PS*, ES*, EdDSA) tokens. Empty disables them.
+	JwksURL string
+
+	// JwksRefreshInterval controls how often the JWKS is refetched (default: 1h).
+	JwksRefreshInterval time.Duration
+
+	// Issuer and Audience, if set, must match the token's iss/aud claims.
+	Issuer   string
+	Audience string
+
+	// APIKeys maps a static API key to the principal it authenticates as.
+	APIKeys map[string]APIKey
+
+	// DefaultRole is assigned to credentials that don't specify a role (default: admin).
+	DefaultRole Role
+
+	// ClientCertAuth accepts a verified TLS client certificate as credentials
+	// when the request carries no API key or Authorization header.
+	ClientCertAuth bool
+}
+
+// APIKey describes the principal a static API key authenticates as.
+type APIKey struct {
+	Subject string
+	Role    Role   // Empty = AuthConfig.DefaultRole
+	Tenant  string // Empty = not tenant-scoped
+}
+
+// Claims describes the authenticated principal of a request.
+type Claims struct {
+	// Subject identifies the principal (JWT "sub", API key name or client certificate CN)
+	Subject string
+	// AuthMethod is AuthMethodJWT or AuthMethodAPIKey
+	AuthMethod string
+	// Role determines which operations the principal may perform
+	Role Role
+	// Tenant scopes the principal to resources labelled with this tenant (empty = all tenants)
+	Tenant string
+	// Raw holds the full JWT claim set (nil for API keys)
+	Raw map[string]any
+}
+
+// AuthError is returned when a request fails authentication (401) or
+// authenticates but is not permitted to proceed (403).
+type AuthError struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *AuthError) Error() string { return e.Message }
+
+func unauthorized(msg string) error {
+	return &AuthError{StatusCode: http.StatusUnauthorized, Message: msg}
+}
+
+// apiKey is a configured static key. Only the hash is kept in memory so keys
+// can be compared in constant time regardless of their length.
+type apiKey struct {
+	principal APIKey
+	hash      [sha256.Size]byte
+}
+
+// Authenticator validates API credentials: static API keys, HMAC-signed JWTs
+// and JWTs signed by keys published at a JWKS endpoint.
+type Authenticator struct {
+	cfg     AuthConfig
+	apiKeys []apiKey
+	jwks    *jwksCache
+}
+
+// NewAuthenticator creates an Authenticator from the given configuration.
+func NewAuthenticator(cfg AuthConfig) *Authenticator {
+	if cfg.DefaultRole == "" {
+		cfg.DefaultRole = RoleAdmin
+	}
+	a := &Authenticator{cfg: cfg}
+	for key, principal := range cfg.APIKeys {
+		a.apiKeys = append(a.apiKeys, apiKey{principal: principal, hash: sha256.Sum256([]byte(key))})
+	}
+	if cfg.JwksURL != "" {
+		a.jwks = newJWKSCache(cfg.JwksURL, cfg.JwksRefreshInterval)
+	}
+	return a
+}
+
+// ParseAPIKeys parses a comma-separated list of "subject:key[:role[:tenant]]" entries.
+func ParseAPIKeys(s string) (map[string]APIKey, error) {
+	keys := make(map[string]APIKey)
+	for _, entry := range strings.Split(s, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		parts := strings.Split(entry, ":")
+		if len(parts) < 2 || len(parts) > 4 || parts[0] == "" || parts[1] == "" {
+			return nil, fmt.Errorf("invalid API key entry for %q: expected subject:key[:role[:tenant]]", parts[0])
+		}
+		principal := APIKey{Subject: parts[0]}
+		if len(parts) > 2 && parts[2] != "" {
+			role, err := ParseRole(parts[2])
+			if err != nil {
+				return nil, fmt.Errorf("API key for subject %q: %w", parts[0], err)
+			}
+			principal.Role = role
+		}
+		if len(parts) > 3 {
+			principal.Tenant = parts[3]
+		}
+		key := parts[1]
+		if _, exists := keys[key]; exists {
+			return nil, fmt.Errorf("duplicate API key for subject %q", principal.Subject)
+		}
+		keys[key] = principal
+	}
+	return keys, nil
+}
+
+// Authenticate validates the credentials on the request and returns the
+// authenticated claims. Errors are always of type *AuthError.
+func (a *Authenticator) Authenticate(r *http.Request) (*Claims, error) {
+	return a.authenticate(r.Context(), credentials{
+		apiKey:        r.Header.Get(APIKeyHeader),
+		authorization: r.Header.Get("Authorization"),
+		tls:           r.TLS,
+	})
+}
+
+// credentials are what a caller presents to authenticate, taken from HTTP
+// headers or gRPC metadata.
+type credentials struct {
+	apiKey        string               // APIKeyHeader value
+	authorization string               // Authorization header value
+	tls           *tls.ConnectionState // Nil without TLS
+}
+
+func (a *Authenticator) authenticate(ctx context.Context, creds credentials) (*Claims, error) {
+	log := logger.FromContext(ctx)
+
+	if key := creds.apiKey; key != "" {
+		if claims, ok := a.matchAPIKey(key); ok {
+			return claims, nil
+		}
+		log.DebugContext(ctx, "invalid API key")
+		return nil, unauthorized("invalid API key")
+	}
+
+	authHeader := creds.authorization
+	if authHeader == "" {
+		if claims, ok := a.matchClientCert(creds.tls); ok {
+			return claims, nil
+		}
+		log.DebugContext(ctx, "missing authorization header")
+		return nil, unauthorized("authorization header required")
+	}
+
+	token, err := extractBearerToken(authHeader)
+	if err != nil {
+		log.DebugContext(ctx, "invalid authorization header", "error", err)
+		return nil, unauthorized("invalid authorization header format")
+	}
+
+	if claims, ok := a.matchAPIKey(token); ok {
+		return claims, nil
+	}
+
+	return a.authenticateJWT(ctx, token)
+}
+
+// matchAPIKey compares the key against every configured key in constant time.
+func (a *Authenticator) matchAPIKey(key string) (*Claims, bool) {
+	hash := sha256.Sum256([]byte(key))
+	var matched *apiKey
+	for i := range a.apiKeys {
+		if subtle.ConstantTimeCompare(hash[:], a.apiKeys[i].hash[:]) == 1 {
+			matched = &a.apiKeys[i]
+		}
+	}
+	if matched == nil {
+		return nil, false
+	}
+	role := matched.principal.Role
+	if role == "" {
+		role = a.cfg.DefaultRole
+	}
+	return &Claims{
+		Subject:    matched.principal.Subject,
+		AuthMethod: AuthMethodAPIKey,
+		Role:       role,
+		Tenant:     matched.principal.Tenant,
+	}, true
+}
+
+// matchClientCert authenticates the caller by its TLS client certificate.
+// The certificate chain has already been verified against the client CA during
+// the TLS handshake; the leaf's common name becomes the subject.
+func (a *Authenticator) matchClientCert(state *tls.ConnectionState) (*Claims, bool) {
+	if !a.cfg.ClientCertAuth || state == nil || len(state.VerifiedChains) == 0 || len(state.VerifiedChains[0]) == 0 {
+		return nil, false
+	}
+	leaf := state.VerifiedChains[0][0]
+	if leaf.Subject.CommonName == "" {
+		return nil, false
+	}
+	return &Claims{
+		Subject:    leaf.Subject.CommonName,
+		AuthMethod: AuthMethodClientCert,
+		Role:       a.cfg.DefaultRole,
+	}, true
+}
+
+func (a *Authenticator) authenticateJWT(ctx context.Context, token string) (*Claims, error) {
+	log := logger.FromContext(ctx)
+
+	var opts []jwt.ParserOption
+	if a.cfg.Issuer != "" {
+		opts = append(opts, jwt.WithIssuer(a.cfg.Issuer))
+	}
+	if a.cfg.Audience != "" {
+		opts = append(opts, jwt.WithAudience(a.cfg.Audience))
+	}
+
+	claims := jwt.MapClaims{}
+	parsedToken, err := jwt.ParseWithClaims(token, claims, a.keyFunc(ctx), opts...)
+	if err != nil {
+		log.DebugContext(ctx, "failed to parse JWT", "error", err)
+		return nil, unauthorized("invalid token")
+	}
+	if !parsedToken.Valid {
+		log.DebugContext(ctx, "invalid JWT token")
+		return nil, unauthorized("invalid token")
+	}
+
+	// Reject registry tokens - they should not be used for API authentication.
+	// Registry tokens have specific claims (repos, scope, build_id) that user tokens don't have.
+	// This provides defense-in-depth even though BuildKit isolates build containers.
+	for _, claim := range []string{"repos", "scope", "build_id"} {
+		if _, ok := claims[claim]; ok {
+			log.DebugContext(ctx, "rejected registry token used for API auth", "claim", claim)
+			return nil, unauthorized("invalid token type")
+		}
+	}
+
+	sub, _ := claims["sub"].(string)
+	// Also reject tokens with "builder-" prefix in subject as an extra safeguard
+	if strings.HasPrefix(sub, "builder-") {
+		log.DebugContext(ctx, "rejected builder token used for API auth", "sub", sub)
+		return nil, unauthorized("invalid token type")
+	}
+
+	role := a.cfg.DefaultRole
+	if roleClaim, ok := claims["role"].(string); ok && roleClaim != "" {
+		role, err = ParseRole(roleClaim)
+		if err != nil {
+			log.DebugContext(ctx, "invalid role claim", "error", err)
+			return nil, unauthorized("invalid token")
+		}
+	}
+	tenant, _ := claims["tenant"].(string)
+
+	return &Claims{Subject: sub, AuthMethod: AuthMethodJWT, Role: role, Tenant: tenant, Raw: claims}, nil
+}
+
+// keyFunc selects the verification key based on the token's signing method.
+func (a *Authenticator) keyFunc(ctx context.Context) jwt.Keyfunc {
+	return func(token *jwt.Token) (interface{}, error) {
+		switch token.Method.(type) {
+		case *jwt.SigningMethodHMAC:
+			if a.cfg.JwtSecret == "" {
+				return nil, fmt.Errorf("HMAC-signed tokens are not accepted")
+			}
+			return []byte(a.cfg.JwtSecret), nil
+		case *jwt.SigningMethodRSA, *jwt.SigningMethodRSAPSS, *jwt.SigningMethodECDSA, *jwt.SigningMethodEd25519:
+			if a.jwks == nil {
+				return nil, fmt.Errorf("asymmetric tokens require JWKS_URL")
+			}
+			kid, _ := token.Header["kid"].(string)
+			return a.jwks.Key(ctx, kid)
+		default:
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		}
+	}
+}
+
+// Middleware returns a chi middleware that requires valid credentials and
+// adds the authenticated claims to the request context.
+func (a *Authenticator) Middleware() func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			// Registry paths accept scoped registry tokens via Bearer or Basic auth
+			if isRegistryPath(r.URL.Path) {
+				a.registryAuth(w, r, next)
+				return
+			}
+
+			claims, err := a.Authenticate(r)
+			if err != nil {
+				writeAuthError(w, err)
+				return
+			}
+			next.ServeHTTP(w, r.WithContext(WithClaims(r.Context(), claims)))
+		})
+	}
+}
+
+// writeAuthError writes a 401 or 403 error response for an authentication failure.
+func writeAuthError(w http.ResponseWriter, err error) {
+	status := http.StatusUnauthorized
+	if authErr, ok := err.(*AuthError); ok {
+		status = authErr.StatusCode
+	}
+	if status == http.StatusUnauthorized {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="hypeman"`)
+	}
+	OapiErrorHandler(w, err.Error(), status)
+}
+
+// WithClaims returns a context carrying the authenticated claims and user ID.
+func WithClaims(ctx context.Context, claims *Claims) context.Context {
+	ctx = context.WithValue(ctx, claimsKey, claims)
+	return context.WithValue(ctx, userIDKey, claims.Subject)
+}
+
+// GetClaimsFromContext returns the authenticated claims, or nil if the request
+// was not authenticated.
+func GetClaimsFromContext(ctx context.Context) *Claims {
+	claims, _ := ctx.Value(claimsKey).(*Claims)
+	return claims
+}
@@ -0,0 +1,216 @@
+package middleware
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// claimsEchoHandler returns 200 with the authenticated subject in the body
+var claimsEchoHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	claims := GetClaimsFromContext(r.Context())
+	if claims == nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(claims.AuthMethod + ":" + claims.Subject))
+})
+
+func TestParseAPIKeys(t *testing.T) {
+	keys, err := ParseAPIKeys("ci:key-one, ops:key-two:viewer:tenant-a,")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]APIKey{
+		"key-one": {Subject: "ci"},
+		"key-two": {Subject: "ops", Role: RoleViewer, Tenant: "tenant-a"},
+	}, keys)
+
+	_, err = ParseAPIKeys("ci:key:superuser")
+	assert.Error(t, err)
+
+	_, err = ParseAPIKeys("missing-separator")
+	assert.Error(t, err)
+
+	_, err = ParseAPIKeys("a:same,b:same")
+	assert.Error(t, err)
+}
+
+func TestAuthenticator_APIKeys(t *testing.T) {
+	auth := NewAuthenticator(AuthConfig{
+		JwtSecret: testJWTSecret,
+		APIKeys:   map[string]APIKey{"secret-key": {Subject: "ci"}},
+	})
+	handler := auth.Middleware()(claimsEchoHandler)
+
+	t.Run("X-API-Key header is accepted", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/instances", nil)
+		req.Header.Set(APIKeyHeader, "secret-key")
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, "api_key:ci", rr.Body.String())
+	})
+
+	t.Run("API key as bearer token is accepted", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/instances", nil)
+		req.Header.Set("Authorization", "Bearer secret-key")
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, "api_key:ci", rr.Body.String())
+	})
+
+	t.Run("unknown API key is rejected", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/instances", nil)
+		req.Header.Set(APIKeyHeader, "wrong-key")
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusUnauthorized, rr.Code)
+		assert.NotEmpty(t, rr.Header().Get("WWW-Authenticate"))
+		assert.Contains(t, rr.Body.String(), "invalid API key")
+	})
+
+	t.Run("JWT still accepted alongside API keys", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/instances", nil)
+		req.Header.Set("Authorization", "Bearer "+generateUserToken(t, "user-123"))
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, "jwt:user-123", rr.Body.String())
+	})
+}
+
+func TestAuthenticator_JWKS(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	jwksServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]any{
+			"keys": []map[string]string{{
+				"kty": "RSA",
+				"kid": "key-1",
+				"use": "sig",
+				"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+			}},
+		})
+	}))
+	defer jwksServer.Close()
+
+	auth := NewAuthenticator(AuthConfig{
+		JwksURL:  jwksServer.URL,
+		Issuer:   "https://issuer.example.com",
+		Audience: "hypeman",
+	})
+	handler := auth.Middleware()(claimsEchoHandler)
+
+	signRS256 := func(t *testing.T, kid string, claims jwt.MapClaims) string {
+		token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+		token.Header["kid"] = kid
+		s, err := token.SignedString(key)
+		require.NoError(t, err)
+		return s
+	}
+
+	validClaims := func() jwt.MapClaims {
+		return jwt.MapClaims{
+			"sub": "user-456",
+			"iss": "https://issuer.example.com",
+			"aud": "hypeman",
+			"exp": time.Now().Add(time.Hour).Unix(),
+		}
+	}
+
+	t.Run("RS256 token verified via JWKS", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/instances", nil)
+		req.Header.Set("Authorization", "Bearer "+signRS256(t, "key-1", validClaims()))
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, "jwt:user-456", rr.Body.String())
+	})
+
+	t.Run("unknown kid is rejected", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/instances", nil)
+		req.Header.Set("Authorization", "Bearer "+signRS256(t, "key-2", validClaims()))
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusUnauthorized, rr.Code)
+	})
+
+	t.Run("wrong audience is rejected", func(t *testing.T) {
+		claims := validClaims()
+		claims["aud"] = "someone-else"
+		req := httptest.NewRequest(http.MethodGet, "/instances", nil)
+		req.Header.Set("Authorization", "Bearer "+signRS256(t, "key-1", claims))
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusUnauthorized, rr.Code)
+	})
+
+	t.Run("HMAC token rejected when no secret configured", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/instances", nil)
+		req.Header.Set("Authorization", "Bearer "+generateUserToken(t, "user-123"))
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusUnauthorized, rr.Code)
+	})
+}
+
+func TestAuthenticator_ClientCert(t *testing.T) {
+	verified := &tls.ConnectionState{
+		VerifiedChains: [][]*x509.Certificate{{{Subject: pkix.Name{CommonName: "ci-runner"}}}},
+	}
+
+	t.Run("verified client certificate authenticates", func(t *testing.T) {
+		handler := NewAuthenticator(AuthConfig{ClientCertAuth: true}).Middleware()(claimsEchoHandler)
+		req := httptest.NewRequest(http.MethodGet, "/instances", nil)
+		req.TLS = verified
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, "client_cert:ci-runner", rr.Body.String())
+	})
+
+	t.Run("client certificate ignored when disabled", func(t *testing.T) {
+		handler := NewAuthenticator(AuthConfig{JwtSecret: testJWTSecret}).Middleware()(claimsEchoHandler)
+		req := httptest.NewRequest(http.MethodGet, "/instances", nil)
+		req.TLS = verified
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusUnauthorized, rr.Code)
+	})
+
+	t.Run("unverified connection is rejected", func(t *testing.T) {
+		handler := NewAuthenticator(AuthConfig{ClientCertAuth: true}).Middleware()(claimsEchoHandler)
+		req := httptest.NewRequest(http.MethodGet, "/instances", nil)
+		req.TLS = &tls.ConnectionState{}
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusUnauthorized, rr.Code)
+	})
+}
@@ -0,0 +1,189 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/kernel/hypeman/lib/logger"
+)
+
+// Role is a coarse-grained permission level. Each role includes the permissions
+// of the roles below it: viewer < operator < admin.
+type Role string
+
+const (
+	// RoleViewer can read resources but not change them
+	RoleViewer Role = "viewer"
+	// RoleOperator can create, modify and delete instances, images, volumes, builds and ingresses
+	RoleOperator Role = "operator"
+	// RoleAdmin can additionally manage host-level resources such as passthrough devices and network DNS
+	RoleAdmin Role = "admin"
+)
+
+var roleRank = map[Role]int{
+	RoleViewer:   1,
+	RoleOperator: 2,
+	RoleAdmin:    3,
+}
+
+// ErrTenantForbidden is returned when a tenant-scoped principal tries to act on another tenant.
+var ErrTenantForbidden = errors.New("operation not permitted for this tenant")
+
+// ParseRole parses a role name.
+func ParseRole(s string) (Role, error) {
+	role := Role(strings.ToLower(strings.TrimSpace(s)))
+	if _, ok := roleRank[role]; !ok {
+		return "", fmt.Errorf("invalid role %q: must be viewer, operator or admin", s)
+	}
+	return role, nil
+}
+
+// Allows reports whether the role grants at least the permissions of required.
+func (r Role) Allows(required Role) bool {
+	return roleRank[r] >= roleRank[required]
+}
+
+// RequiredRole returns the minimum role needed to perform the request.
+func RequiredRole(r *http.Request) Role {
+	path := r.URL.Path
+
+	// exec and cp are WebSocket upgrades (GET) but can modify the guest
+	if strings.HasPrefix(path, "/instances/") && (strings.HasSuffix(path, "/exec") || strings.HasSuffix(path, "/cp")) {
+		return RoleOperator
+	}
+
+	// Debug endpoints expose profiles and host-wide internals
+	if strings.HasPrefix(path, "/debug/") {
+		return RoleAdmin
+	}
+
+	// Config reload changes host-wide settings
+	if strings.HasPrefix(path, "/admin/") {
+		return RoleAdmin
+	}
+
+	// Exec recordings hold whatever was typed or printed in a session
+	if path == "/recordings" || strings.HasPrefix(path, "/recordings/") {
+		return RoleAdmin
+	}
+
+	switch r.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return RoleViewer
+	}
+
+	// Device registration binds host PCI devices to VFIO
+	if path == "/devices" || strings.HasPrefix(path, "/devices/") {
+		return RoleAdmin
+	}
+
+	// Host USB devices aren't registered first like PCI devices, so passing
+	// one through takes the role registration does
+	if strings.HasPrefix(path, "/instances/") && strings.Contains(path, "/usb-devices") {
+		return RoleAdmin
+	}
+
+	// Network DNS configuration is shared by every instance on the host
+	if strings.HasPrefix(path, "/networks/") {
+		return RoleAdmin
+	}
+
+	// Overlay peers and keys decide which hosts reach every instance network
+	if path == "/overlay" || strings.HasPrefix(path, "/overlay/") {
+		return RoleAdmin
+	}
+
+	// Log rotation deletes rotated logs of every instance on the host
+	if strings.HasPrefix(path, "/logs/") {
+		return RoleAdmin
+	}
+
+	// Pruning and maintenance tasks act on every tenant's resources
+	if strings.HasPrefix(path, "/system/") {
+		return RoleAdmin
+	}
+
+	// Node slots reserve host capacity on behalf of the control plane
+	if path == "/node" || strings.HasPrefix(path, "/node/") {
+		return RoleAdmin
+	}
+	return RoleOperator
+}
+
+// Authorize creates middleware that rejects authenticated requests whose role
+// does not permit the operation with 403. Requests without claims (endpoints
+// with no security requirements) pass through; authentication is enforced
+// by the authenticator, which must run first.
+func Authorize() func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			claims := GetClaimsFromContext(r.Context())
+			if claims == nil {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			required := RequiredRole(r)
+			if !claims.Role.Allows(required) {
+				logger.FromContext(r.Context()).DebugContext(r.Context(), "insufficient role",
+					"subject", claims.Subject, "role", claims.Role, "required", required)
+				writeAuthError(w, &AuthError{
+					StatusCode: http.StatusForbidden,
+					Message:    fmt.Sprintf("role %q is not permitted to perform this operation (requires %q)", claims.Role, required),
+				})
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// RoleAllowed reports whether the request's principal has at least the
+// required role, for checks that depend on the request body rather than its
+// path. Requests without claims (auth disabled) are allowed, as in Authorize.
+func RoleAllowed(ctx context.Context, required Role) bool {
+	claims := GetClaimsFromContext(ctx)
+	return claims == nil || claims.Role.Allows(required)
+}
+
+// TenantFromContext returns the tenant the request is scoped to, or "" if the
+// principal may access all tenants.
+func TenantFromContext(ctx context.Context) string {
+	if claims := GetClaimsFromContext(ctx); claims != nil {
+		return claims.Tenant
+	}
+	return ""
+}
+
+// CreatorFromContext identifies the principal creating a resource as
+// "<auth method>:<subject>" (e.g. "api_key:ci"), or "" if the request isn't
+// authenticated. Resources record it so quotas can be kept per API key.
+func CreatorFromContext(ctx context.Context) string {
+	if claims := GetClaimsFromContext(ctx); claims != nil && claims.Subject != "" {
+		return claims.AuthMethod + ":" + claims.Subject
+	}
+	return ""
+}
+
+// TenantVisible reports whether a resource labelled with tenant is visible to the request.
+func TenantVisible(ctx context.Context, tenant string) bool {
+	scope := TenantFromContext(ctx)
+	return scope == "" || scope == tenant
+}
+
+// TenantForCreate returns the tenant label to apply to a new resource.
+// Tenant-scoped principals always create resources in their own tenant and may
+// not request a different one; unscoped principals may label resources freely.
+func TenantForCreate(ctx context.Context, requested *string) (string, error) {
+	scope := TenantFromContext(ctx)
+	if requested == nil || *requested == "" {
+		return scope, nil
+	}
+	if scope != "" && *requested != scope {
+		return "", ErrTenantForbidden
+	}
+	return *requested, nil
+}
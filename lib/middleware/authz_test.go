@@ -0,0 +1,126 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRequiredRole(t *testing.T) {
+	tests := []struct {
+		method string
+		path   string
+		want   Role
+	}{
+		{http.MethodGet, "/instances", RoleViewer},
+		{http.MethodGet, "/instances/abc/logs", RoleViewer},
+		{http.MethodGet, "/instances/abc/exec", RoleOperator},
+		{http.MethodGet, "/instances/abc/cp", RoleOperator},
+		{http.MethodPost, "/instances", RoleOperator},
+		{http.MethodDelete, "/volumes/vol-1", RoleOperator},
+		{http.MethodGet, "/devices", RoleViewer},
+		{http.MethodPost, "/devices", RoleAdmin},
+		{http.MethodDelete, "/devices/gpu-1", RoleAdmin},
+		{http.MethodPost, "/instances/abc/usb-devices", RoleAdmin},
+		{http.MethodDelete, "/instances/abc/usb-devices/1050:0407", RoleAdmin},
+		{http.MethodGet, "/networks/default/dns", RoleViewer},
+		{http.MethodPost, "/networks/default/dns/records", RoleAdmin},
+		{http.MethodGet, "/overlay", RoleViewer},
+		{http.MethodPost, "/overlay/peers", RoleAdmin},
+		{http.MethodDelete, "/overlay/peers/host-b", RoleAdmin},
+		{http.MethodPost, "/logs/rotate", RoleAdmin},
+		{http.MethodGet, "/system/disk-usage", RoleViewer},
+		{http.MethodPost, "/system/prune", RoleAdmin},
+		{http.MethodGet, "/system/maintenance", RoleViewer},
+		{http.MethodGet, "/system/retention", RoleViewer},
+		{http.MethodPost, "/system/maintenance/gc/run", RoleAdmin},
+		{http.MethodPost, "/system/images/reconvert", RoleAdmin},
+		{http.MethodGet, "/node", RoleViewer},
+		{http.MethodPost, "/node/slots", RoleAdmin},
+		{http.MethodDelete, "/node/slots/placement-1", RoleAdmin},
+		{http.MethodGet, "/debug/state", RoleAdmin},
+		{http.MethodGet, "/debug/pprof/heap", RoleAdmin},
+		{http.MethodPost, "/admin/reload", RoleAdmin},
+		{http.MethodGet, "/recordings", RoleAdmin},
+		{http.MethodGet, "/recordings/x1cmn8ruk3l3ynq7pzkp4nbl", RoleAdmin},
+	}
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.path, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			assert.Equal(t, tt.want, RequiredRole(req))
+		})
+	}
+}
+
+func TestAuthorize(t *testing.T) {
+	handler := Authorize()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	serve := func(method, path string, claims *Claims) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, nil)
+		if claims != nil {
+			req = req.WithContext(WithClaims(req.Context(), claims))
+		}
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr
+	}
+
+	t.Run("viewer can read", func(t *testing.T) {
+		rr := serve(http.MethodGet, "/instances", &Claims{Subject: "u", Role: RoleViewer})
+		assert.Equal(t, http.StatusOK, rr.Code)
+	})
+
+	t.Run("viewer cannot create", func(t *testing.T) {
+		rr := serve(http.MethodPost, "/instances", &Claims{Subject: "u", Role: RoleViewer})
+		assert.Equal(t, http.StatusForbidden, rr.Code)
+		assert.Empty(t, rr.Header().Get("WWW-Authenticate"))
+	})
+
+	t.Run("operator cannot register devices", func(t *testing.T) {
+		rr := serve(http.MethodPost, "/devices", &Claims{Subject: "u", Role: RoleOperator})
+		assert.Equal(t, http.StatusForbidden, rr.Code)
+	})
+
+	t.Run("admin can register devices", func(t *testing.T) {
+		rr := serve(http.MethodPost, "/devices", &Claims{Subject: "u", Role: RoleAdmin})
+		assert.Equal(t, http.StatusOK, rr.Code)
+	})
+
+	t.Run("unauthenticated requests pass through", func(t *testing.T) {
+		rr := serve(http.MethodGet, "/health", nil)
+		assert.Equal(t, http.StatusOK, rr.Code)
+	})
+}
+
+func TestTenantScoping(t *testing.T) {
+	scoped := WithClaims(context.Background(), &Claims{Subject: "u", Role: RoleOperator, Tenant: "team-a"})
+	unscoped := WithClaims(context.Background(), &Claims{Subject: "admin", Role: RoleAdmin})
+	other := "team-b"
+	same := "team-a"
+
+	assert.True(t, TenantVisible(scoped, "team-a"))
+	assert.False(t, TenantVisible(scoped, "team-b"))
+	assert.False(t, TenantVisible(scoped, ""))
+	assert.True(t, TenantVisible(unscoped, "team-b"))
+
+	tenant, err := TenantForCreate(scoped, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "team-a", tenant)
+
+	tenant, err = TenantForCreate(scoped, &same)
+	require.NoError(t, err)
+	assert.Equal(t, "team-a", tenant)
+
+	_, err = TenantForCreate(scoped, &other)
+	assert.ErrorIs(t, err, ErrTenantForbidden)
+
+	tenant, err = TenantForCreate(unscoped, &other)
+	require.NoError(t, err)
+	assert.Equal(t, "team-b", tenant)
+}
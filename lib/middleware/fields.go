@@ -0,0 +1,97 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+)
+
+// FieldsParam is the query parameter selecting the fields of a response
+const FieldsParam = "fields"
+
+// fieldTree is a parsed field selection: each selected field maps to the
+// selection of its nested fields, or nil to keep the whole value.
+type fieldTree map[string]fieldTree
+
+// parseFields parses a comma-separated selection like "id,name,network.ip".
+// Selecting a field keeps all of it, even if some of its nested fields are
+// selected too.
+func parseFields(s string) fieldTree {
+	tree := fieldTree{}
+	for _, field := range strings.Split(s, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		node := tree
+		parts := strings.Split(field, ".")
+		for i, part := range parts {
+			child, seen := node[part]
+			if seen && child == nil {
+				break // the whole field is already selected
+			}
+			if i == len(parts)-1 {
+				node[part] = nil
+				break
+			}
+			if child == nil {
+				child = fieldTree{}
+				node[part] = child
+			}
+			node = child
+		}
+	}
+	return tree
+}
+
+// apply keeps only the selected fields of every object in v. Selected
+// fields the object doesn't have are skipped.
+func (t fieldTree) apply(v any) any {
+	switch v := v.(type) {
+	case map[string]any:
+		out := make(map[string]any, len(t))
+		for k, sub := range t {
+			child, ok := v[k]
+			if !ok {
+				continue
+			}
+			if sub != nil {
+				child = sub.apply(child)
+			}
+			out[k] = child
+		}
+		return out
+	case []any:
+		for i, child := range v {
+			v[i] = t.apply(child)
+		}
+	}
+	return v
+}
+
+// SelectFields returns sparse responses: when a GET request has a fields
+// query parameter, its JSON response keeps only the listed fields. Lists are
+// filtered per item, and dotted paths select nested fields. Errors and
+// non-JSON responses are passed through.
+func SelectFields() func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != http.MethodGet {
+				next.ServeHTTP(w, r)
+				return
+			}
+			tree := parseFields(r.URL.Query().Get(FieldsParam))
+			if len(tree) == 0 {
+				next.ServeHTTP(w, r)
+				return
+			}
+			jw := &jsonWriter{ResponseWriter: w, status: http.StatusOK}
+			next.ServeHTTP(jw, r)
+			jw.finish(func(body any) any {
+				if jw.status >= http.StatusBadRequest {
+					return body
+				}
+				return tree.apply(body)
+			})
+		})
+	}
+}
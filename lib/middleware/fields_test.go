@@ -0,0 +1,68 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseFields(t *testing.T) {
+	assert.Equal(t, fieldTree{"id": nil, "name": nil}, parseFields("id, name,,"))
+	assert.Equal(t, fieldTree{"network": fieldTree{"ip": nil, "mac": nil}}, parseFields("network.ip,network.mac"))
+	// Selecting a whole field wins over its nested fields, in either order
+	assert.Equal(t, fieldTree{"network": nil}, parseFields("network.ip,network"))
+	assert.Equal(t, fieldTree{"network": nil}, parseFields("network,network.ip"))
+	assert.Empty(t, parseFields(""))
+}
+
+func TestSelectFields(t *testing.T) {
+	instance := map[string]any{
+		"id":      "abc",
+		"name":    "web",
+		"state":   "Running",
+		"network": map[string]any{"ip": "10.0.0.2", "mac": "02:00:00:00:00:01"},
+		"volumes": []any{map[string]any{"volume_id": "v1", "mount_path": "/data"}},
+	}
+	h := SelectFields()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Path == "/missing" {
+			w.WriteHeader(http.StatusNotFound)
+		}
+		switch r.URL.Path {
+		case "/instances":
+			json.NewEncoder(w).Encode([]any{instance, instance})
+		default:
+			json.NewEncoder(w).Encode(instance)
+		}
+	}))
+	get := func(target string) (int, any) {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+		var body any
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+		return rec.Code, body
+	}
+
+	_, body := get("/instances/abc?fields=id,state,unknown")
+	assert.Equal(t, map[string]any{"id": "abc", "state": "Running"}, body)
+
+	_, body = get("/instances?fields=name")
+	assert.Equal(t, []any{map[string]any{"name": "web"}, map[string]any{"name": "web"}}, body)
+
+	_, body = get("/instances/abc?fields=network.ip,volumes.volume_id")
+	assert.Equal(t, map[string]any{
+		"network": map[string]any{"ip": "10.0.0.2"},
+		"volumes": []any{map[string]any{"volume_id": "v1"}},
+	}, body)
+
+	// Without a selection, and for errors, responses are unchanged
+	_, body = get("/instances/abc")
+	assert.Len(t, body, 5)
+	code, body := get("/missing?fields=id")
+	assert.Equal(t, http.StatusNotFound, code)
+	assert.Len(t, body, 5)
+}
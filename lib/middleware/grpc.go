@@ -0,0 +1,147 @@
+package middleware
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/kernel/hypeman/lib/logger"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	grpccredentials "google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// GRPCServerOptions returns server options that prepare each gRPC call the way
+// the REST middleware prepares a request: the logger is added to the context,
+// the caller is authenticated with the same credentials the REST API accepts
+// (the x-api-key or authorization metadata, or a TLS client certificate), and
+// calls whose role doesn't permit the method are rejected.
+func (a *Authenticator) GRPCServerOptions(log *slog.Logger, requiredRole func(fullMethod string) Role) []grpc.ServerOption {
+	prepare := func(ctx context.Context, fullMethod string) (context.Context, error) {
+		ctx = logger.AddToContext(ctx, log)
+		return a.authorizeGRPC(ctx, fullMethod, requiredRole(fullMethod))
+	}
+	return []grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+			ctx, err := prepare(ctx, info.FullMethod)
+			if err != nil {
+				return nil, err
+			}
+			return handler(ctx, req)
+		}),
+		grpc.ChainStreamInterceptor(func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+			ctx, err := prepare(ss.Context(), info.FullMethod)
+			if err != nil {
+				return err
+			}
+			return handler(srv, &contextStream{ServerStream: ss, ctx: ctx})
+		}),
+	}
+}
+
+// GRPCLimitOptions returns server options applying the REST API's per-client
+// limits to gRPC calls: rate to every call and create to the calls isCreate
+// matches. Calls over either limit fail with ResourceExhausted. They must come
+// after GRPCServerOptions so calls are accounted to the principal.
+func GRPCLimitOptions(rate *RateLimiter, create *ConcurrencyLimiter, isCreate func(fullMethod string) bool) []grpc.ServerOption {
+	// admit takes a token and, for creates, a concurrency slot, returning
+	// the function that gives the slot back
+	admit := func(ctx context.Context, fullMethod string) (func(), error) {
+		key := grpcClientKey(ctx)
+		if ok, _ := rate.Allow(key); !ok {
+			logger.FromContext(ctx).WarnContext(ctx, "rate limit exceeded", "client", key)
+			return nil, status.Error(codes.ResourceExhausted, "rate limit exceeded")
+		}
+		if !isCreate(fullMethod) {
+			return func() {}, nil
+		}
+		if !create.Acquire(key) {
+			logger.FromContext(ctx).WarnContext(ctx, "concurrency limit exceeded",
+				"client", key, "limit", create.name, "max", create.max)
+			return nil, status.Error(codes.ResourceExhausted, "too many concurrent "+create.name)
+		}
+		return func() { create.Release(key) }, nil
+	}
+	return []grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+			release, err := admit(ctx, info.FullMethod)
+			if err != nil {
+				return nil, err
+			}
+			defer release()
+			return handler(ctx, req)
+		}),
+		grpc.ChainStreamInterceptor(func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+			release, err := admit(ss.Context(), info.FullMethod)
+			if err != nil {
+				return err
+			}
+			defer release()
+			return handler(srv, ss)
+		}),
+	}
+}
+
+// grpcClientKey identifies the client of a gRPC call like ClientKey does for
+// an HTTP request
+func grpcClientKey(ctx context.Context) string {
+	var addr string
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		addr = p.Addr.String()
+	}
+	return clientKey(ctx, addr)
+}
+
+// authorizeGRPC authenticates a gRPC call and checks its role, returning a
+// context carrying the claims
+func (a *Authenticator) authorizeGRPC(ctx context.Context, fullMethod string, required Role) (context.Context, error) {
+	var creds credentials
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		creds.apiKey = firstValue(md, strings.ToLower(APIKeyHeader))
+		creds.authorization = firstValue(md, "authorization")
+	}
+	if p, ok := peer.FromContext(ctx); ok {
+		if info, ok := p.AuthInfo.(grpccredentials.TLSInfo); ok {
+			creds.tls = &info.State
+		}
+	}
+
+	claims, err := a.authenticate(ctx, creds)
+	if err != nil {
+		return nil, grpcAuthError(err)
+	}
+	if !claims.Role.Allows(required) {
+		logger.FromContext(ctx).DebugContext(ctx, "insufficient role",
+			"subject", claims.Subject, "role", claims.Role, "required", required, "method", fullMethod)
+		return nil, status.Errorf(codes.PermissionDenied, "role %q is not permitted to perform this operation (requires %q)", claims.Role, required)
+	}
+	return WithClaims(ctx, claims), nil
+}
+
+// grpcAuthError converts an *AuthError to the matching gRPC status
+func grpcAuthError(err error) error {
+	code := codes.Unauthenticated
+	if authErr, ok := err.(*AuthError); ok && authErr.StatusCode == http.StatusForbidden {
+		code = codes.PermissionDenied
+	}
+	return status.Error(code, err.Error())
+}
+
+func firstValue(md metadata.MD, key string) string {
+	if values := md.Get(key); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+// contextStream is a grpc.ServerStream whose context has been replaced
+type contextStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *contextStream) Context() context.Context { return s.ctx }
@@ -0,0 +1,60 @@
+package middleware
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	grpccredentials "google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+func TestAuthorizeGRPC(t *testing.T) {
+	auth := NewAuthenticator(AuthConfig{
+		APIKeys:        map[string]APIKey{"secret-key": {Subject: "ci", Role: RoleViewer, Tenant: "team-a"}},
+		ClientCertAuth: true,
+	})
+	withMD := func(kv ...string) context.Context {
+		return metadata.NewIncomingContext(context.Background(), metadata.Pairs(kv...))
+	}
+
+	t.Run("x-api-key metadata is accepted", func(t *testing.T) {
+		ctx, err := auth.authorizeGRPC(withMD("x-api-key", "secret-key"), "/mgmt.ManagementService/ListInstances", RoleViewer)
+		require.NoError(t, err)
+		claims := GetClaimsFromContext(ctx)
+		require.NotNil(t, claims)
+		assert.Equal(t, "ci", claims.Subject)
+		assert.Equal(t, "team-a", claims.Tenant)
+	})
+
+	t.Run("bearer token is accepted", func(t *testing.T) {
+		_, err := auth.authorizeGRPC(withMD("authorization", "Bearer secret-key"), "/mgmt.ManagementService/ListInstances", RoleViewer)
+		assert.NoError(t, err)
+	})
+
+	t.Run("missing credentials are unauthenticated", func(t *testing.T) {
+		_, err := auth.authorizeGRPC(context.Background(), "/mgmt.ManagementService/ListInstances", RoleViewer)
+		assert.Equal(t, codes.Unauthenticated, status.Code(err))
+	})
+
+	t.Run("insufficient role is denied", func(t *testing.T) {
+		_, err := auth.authorizeGRPC(withMD("x-api-key", "secret-key"), "/mgmt.ManagementService/DeleteInstance", RoleOperator)
+		assert.Equal(t, codes.PermissionDenied, status.Code(err))
+	})
+
+	t.Run("verified client certificate authenticates", func(t *testing.T) {
+		ctx := peer.NewContext(context.Background(), &peer.Peer{AuthInfo: grpccredentials.TLSInfo{State: tls.ConnectionState{
+			VerifiedChains: [][]*x509.Certificate{{{Subject: pkix.Name{CommonName: "ci-runner"}}}},
+		}}})
+		ctx, err := auth.authorizeGRPC(ctx, "/mgmt.ManagementService/DeleteInstance", RoleOperator)
+		require.NoError(t, err)
+		assert.Equal(t, AuthMethodClientCert, GetClaimsFromContext(ctx).AuthMethod)
+	})
+}
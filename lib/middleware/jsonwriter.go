@@ -0,0 +1,81 @@
+package middleware
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// jsonWriter holds back JSON responses so they can be rewritten before
+// they're sent. Other responses, like streams and problems, are passed
+// through.
+type jsonWriter struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+	buffering   bool
+	buf         bytes.Buffer
+}
+
+func (w *jsonWriter) WriteHeader(status int) {
+	if w.wroteHeader {
+		return
+	}
+	w.wroteHeader = true
+	w.status = status
+	w.buffering = strings.HasPrefix(w.Header().Get("Content-Type"), "application/json")
+	if !w.buffering {
+		w.ResponseWriter.WriteHeader(status)
+	}
+}
+
+func (w *jsonWriter) Write(p []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	if w.buffering {
+		return w.buf.Write(p)
+	}
+	return w.ResponseWriter.Write(p)
+}
+
+func (w *jsonWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok && !w.buffering {
+		f.Flush()
+	}
+}
+
+// Hijack hands the connection over, e.g. for exec and log streams upgraded
+// to WebSocket; nothing is held back from a hijacked connection.
+func (w *jsonWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	if h, ok := w.ResponseWriter.(http.Hijacker); ok {
+		return h.Hijack()
+	}
+	return nil, nil, fmt.Errorf("underlying ResponseWriter does not implement http.Hijacker")
+}
+
+func (w *jsonWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// finish rewrites a held back response with transform and writes it. A
+// body that isn't valid JSON is written unchanged.
+func (w *jsonWriter) finish(transform func(body any) any) {
+	if !w.buffering {
+		return
+	}
+	data := w.buf.Bytes()
+	var body any
+	if err := json.Unmarshal(data, &body); err == nil {
+		if out, err := json.Marshal(transform(body)); err == nil {
+			data = append(out, '\n')
+		}
+	}
+	w.Header().Del("Content-Length")
+	w.ResponseWriter.WriteHeader(w.status)
+	w.ResponseWriter.Write(data)
+}
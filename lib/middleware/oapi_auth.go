@@ -3,6 +3,7 @@ package middleware
 import (
 	"context"
 	"encoding/base64"
+	"errors"
 	"fmt"
 	"net/http"
 	"regexp"
@@ -11,6 +12,8 @@ import (
 	"github.com/getkin/kin-openapi/openapi3filter"
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/kernel/hypeman/lib/logger"
+	"github.com/kernel/hypeman/lib/problem"
+	nethttpmiddleware "github.com/oapi-codegen/nethttp-middleware"
 )
 
 type contextKey string
@@ -32,94 +35,65 @@ type RegistryTokenClaims struct {
 // OapiAuthenticationFunc creates an AuthenticationFunc compatible with nethttp-middleware
 // that validates JWT bearer tokens for endpoints with security requirements.
 func OapiAuthenticationFunc(jwtSecret string) openapi3filter.AuthenticationFunc {
-	return func(ctx context.Context, input *openapi3filter.AuthenticationInput) error {
-		log := logger.FromContext(ctx)
+	return NewAuthenticator(AuthConfig{JwtSecret: jwtSecret}).OapiAuthenticationFunc()
+}
 
+// OapiAuthenticationFunc creates an AuthenticationFunc compatible with nethttp-middleware
+// that validates API keys and JWT bearer tokens for endpoints with security requirements.
+func (a *Authenticator) OapiAuthenticationFunc() openapi3filter.AuthenticationFunc {
+	return func(ctx context.Context, input *openapi3filter.AuthenticationInput) error {
 		// If no security requirements, allow the request
 		if input.SecurityScheme == nil {
 			return nil
 		}
 
-		// Only handle bearer auth
-		if input.SecurityScheme.Type != "http" || input.SecurityScheme.Scheme != "bearer" {
-			return fmt.Errorf("unsupported security scheme: %s", input.SecurityScheme.Type)
-		}
-
-		// Extract token from Authorization header
-		authHeader := input.RequestValidationInput.Request.Header.Get("Authorization")
-		if authHeader == "" {
-			log.DebugContext(ctx, "missing authorization header")
-			return fmt.Errorf("authorization header required")
-		}
-
-		// Extract bearer token
-		token, err := extractBearerToken(authHeader)
-		if err != nil {
-			log.DebugContext(ctx, "invalid authorization header", "error", err)
-			return fmt.Errorf("invalid authorization header format")
+		scheme := input.SecurityScheme
+		isBearer := scheme.Type == "http" && scheme.Scheme == "bearer"
+		isAPIKey := scheme.Type == "apiKey" && scheme.In == "header"
+		if !isBearer && !isAPIKey {
+			return fmt.Errorf("unsupported security scheme: %s", scheme.Type)
 		}
 
-		// Parse and validate JWT
-		claims := jwt.MapClaims{}
-		parsedToken, err := jwt.ParseWithClaims(token, claims, func(token *jwt.Token) (interface{}, error) {
-			// Validate signing method
-			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-				return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-			}
-			return []byte(jwtSecret), nil
-		})
-
+		req := input.RequestValidationInput.Request
+		claims, err := a.Authenticate(req)
 		if err != nil {
-			log.DebugContext(ctx, "failed to parse JWT", "error", err)
-			return fmt.Errorf("invalid token")
+			return err
 		}
 
-		if !parsedToken.Valid {
-			log.DebugContext(ctx, "invalid JWT token")
-			return fmt.Errorf("invalid token")
-		}
-
-		// Reject registry tokens - they should not be used for API authentication.
-		// Registry tokens have specific claims (repos, scope, build_id) that user tokens don't have.
-		if _, hasRepos := claims["repos"]; hasRepos {
-			log.DebugContext(ctx, "rejected registry token used for API auth")
-			return fmt.Errorf("invalid token type")
-		}
-		if _, hasScope := claims["scope"]; hasScope {
-			log.DebugContext(ctx, "rejected registry token used for API auth")
-			return fmt.Errorf("invalid token type")
-		}
-		if _, hasBuildID := claims["build_id"]; hasBuildID {
-			log.DebugContext(ctx, "rejected registry token used for API auth")
-			return fmt.Errorf("invalid token type")
-		}
-
-		// Extract user ID from claims and add to context
-		var userID string
-		if sub, ok := claims["sub"].(string); ok {
-			userID = sub
-		}
-
-		// Update the context with user ID
-		newCtx := context.WithValue(ctx, userIDKey, userID)
-
-		// Update the request with the new context
-		*input.RequestValidationInput.Request = *input.RequestValidationInput.Request.WithContext(newCtx)
-
+		// Update the request with the authenticated claims
+		*req = *req.WithContext(WithClaims(req.Context(), claims))
 		return nil
 	}
 }
 
 // OapiErrorHandler creates a custom error handler for nethttp-middleware
-// that returns consistent error responses.
+// that returns consistent problem+json error responses.
 func OapiErrorHandler(w http.ResponseWriter, message string, statusCode int) {
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(statusCode)
+	problem.Write(w, statusCode, problem.CodeForStatus(statusCode), message)
+}
 
-	// Return a simple JSON error response matching our Error schema
-	fmt.Fprintf(w, `{"code":"%s","message":"%s"}`,
-		http.StatusText(statusCode),
-		message)
+// OapiErrorHandlerWithOpts is an nethttp-middleware error handler that maps
+// authentication failures to 401 or 403, request validation failures to 400
+// with a detail per violated field, and other errors to the status suggested
+// by the validator. Authentication failures take precedence when the
+// validator reports several errors.
+func OapiErrorHandlerWithOpts(ctx context.Context, err error, w http.ResponseWriter, r *http.Request, opts nethttpmiddleware.ErrorHandlerOpts) {
+	var authErr *AuthError
+	if errors.As(err, &authErr) {
+		writeAuthError(w, authErr)
+		return
+	}
+	var secErr *openapi3filter.SecurityRequirementsError
+	if errors.As(err, &secErr) {
+		writeAuthError(w, secErr)
+		return
+	}
+	var reqErr *openapi3filter.RequestError
+	if errors.As(err, &reqErr) {
+		problem.WriteProblem(w, http.StatusBadRequest, validationProblem(err))
+		return
+	}
+	OapiErrorHandler(w, err.Error(), opts.StatusCode)
 }
 
 // extractBearerToken extracts the token from "Bearer <token>" format
@@ -248,6 +222,12 @@ func validateRegistryToken(tokenString, jwtSecret, requestPath, method string) (
 		return nil, fmt.Errorf("could not extract repository from path")
 	}
 
+	// Any registry token may pull through the pull-through cache
+	// (/v2/proxy/{upstream}/...), which only serves public upstream content
+	if strings.HasPrefix(repo, "proxy/") && !isWriteOperation(method) {
+		return claims, nil
+	}
+
 	// Check if the repository is allowed by the token
 	allowed := false
 	for _, allowedRepo := range claims.Repositories {
@@ -270,129 +250,52 @@ func validateRegistryToken(tokenString, jwtSecret, requestPath, method string) (
 
 // JwtAuth creates a chi middleware that validates JWT bearer tokens
 func JwtAuth(jwtSecret string) func(http.Handler) http.Handler {
-	return func(next http.Handler) http.Handler {
-		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			log := logger.FromContext(r.Context())
-
-			// Extract token from Authorization header
-			authHeader := r.Header.Get("Authorization")
-
-			// For registry paths, handle specially to support both Bearer and Basic auth
-			if isRegistryPath(r.URL.Path) {
-				if authHeader != "" {
-					// Try to extract token (supports both Bearer and Basic auth)
-					token, authType, err := extractTokenFromAuth(authHeader)
-					if err == nil {
-						log.DebugContext(r.Context(), "extracted token for registry request", "auth_type", authType)
-
-						// Try to validate as a registry-scoped token
-						registryClaims, err := validateRegistryToken(token, jwtSecret, r.URL.Path, r.Method)
-						if err == nil {
-							// Valid registry token - set build ID as user for audit trail
-							log.DebugContext(r.Context(), "registry token validated",
-								"build_id", registryClaims.BuildID,
-								"repos", registryClaims.Repositories,
-								"scope", registryClaims.Scope)
-							ctx := context.WithValue(r.Context(), userIDKey, "builder-"+registryClaims.BuildID)
-							next.ServeHTTP(w, r.WithContext(ctx))
-							return
-						}
-						log.DebugContext(r.Context(), "registry token validation failed", "error", err)
-					} else {
-						log.DebugContext(r.Context(), "failed to extract token", "error", err)
-					}
-				}
-
-				// Fallback: Allow internal VM network (10.102.x.x) for registry pushes
-				// This is a transitional fallback for older builder images without token auth
-				if isInternalVMRequest(r) {
-					log.DebugContext(r.Context(), "allowing internal VM request via IP fallback (deprecated)",
-						"remote_addr", r.RemoteAddr,
-						"path", r.URL.Path)
-					ctx := context.WithValue(r.Context(), userIDKey, "internal-builder-legacy")
-					next.ServeHTTP(w, r.WithContext(ctx))
-					return
-				}
-
-				// Registry auth failed
-				log.DebugContext(r.Context(), "registry request unauthorized", "remote_addr", r.RemoteAddr)
-				OapiErrorHandler(w, "registry authentication required", http.StatusUnauthorized)
-				return
-			}
-
-			// For non-registry paths, require Bearer token
-			if authHeader == "" {
-				log.DebugContext(r.Context(), "missing authorization header")
-				OapiErrorHandler(w, "authorization header required", http.StatusUnauthorized)
-				return
-			}
-
-			// Extract bearer token
-			token, err := extractBearerToken(authHeader)
-			if err != nil {
-				log.DebugContext(r.Context(), "invalid authorization header", "error", err)
-				OapiErrorHandler(w, "invalid authorization header format", http.StatusUnauthorized)
-				return
-			}
-
-			// Parse and validate as regular user JWT
-			claims := jwt.MapClaims{}
-			parsedToken, err := jwt.ParseWithClaims(token, claims, func(token *jwt.Token) (interface{}, error) {
-				// Validate signing method
-				if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-					return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-				}
-				return []byte(jwtSecret), nil
-			})
-
-			if err != nil {
-				log.DebugContext(r.Context(), "failed to parse JWT", "error", err)
-				OapiErrorHandler(w, "invalid token", http.StatusUnauthorized)
-				return
-			}
-
-			if !parsedToken.Valid {
-				log.DebugContext(r.Context(), "invalid JWT token")
-				OapiErrorHandler(w, "invalid token", http.StatusUnauthorized)
-				return
-			}
+	return NewAuthenticator(AuthConfig{JwtSecret: jwtSecret}).Middleware()
+}
 
-			// Reject registry tokens - they should not be used for API authentication.
-			// Registry tokens have specific claims that user tokens don't have.
-			// This provides defense-in-depth even though BuildKit isolates build containers.
-			if _, hasRepos := claims["repos"]; hasRepos {
-				log.DebugContext(r.Context(), "rejected registry token used for API auth")
-				OapiErrorHandler(w, "invalid token type", http.StatusUnauthorized)
-				return
-			}
-			if _, hasScope := claims["scope"]; hasScope {
-				log.DebugContext(r.Context(), "rejected registry token used for API auth")
-				OapiErrorHandler(w, "invalid token type", http.StatusUnauthorized)
-				return
-			}
-			if _, hasBuildID := claims["build_id"]; hasBuildID {
-				log.DebugContext(r.Context(), "rejected registry token used for API auth")
-				OapiErrorHandler(w, "invalid token type", http.StatusUnauthorized)
-				return
-			}
-			// Also reject tokens with "builder-" prefix in subject as an extra safeguard
-			if sub, ok := claims["sub"].(string); ok && strings.HasPrefix(sub, "builder-") {
-				log.DebugContext(r.Context(), "rejected builder token used for API auth", "sub", sub)
-				OapiErrorHandler(w, "invalid token type", http.StatusUnauthorized)
+// registryAuth authenticates OCI registry requests. Only scoped registry tokens
+// (via Bearer or Basic auth) are accepted, plus the deprecated fallback for
+// pushes from the internal VM network; regular API credentials are not.
+func (a *Authenticator) registryAuth(w http.ResponseWriter, r *http.Request, next http.Handler) {
+	log := logger.FromContext(r.Context())
+	authHeader := r.Header.Get("Authorization")
+
+	if authHeader != "" {
+		// Try to extract token (supports both Bearer and Basic auth)
+		token, authType, err := extractTokenFromAuth(authHeader)
+		if err == nil {
+			log.DebugContext(r.Context(), "extracted token for registry request", "auth_type", authType)
+
+			// Try to validate as a registry-scoped token
+			registryClaims, err := validateRegistryToken(token, a.cfg.JwtSecret, r.URL.Path, r.Method)
+			if err == nil {
+				// Valid registry token - set build ID as user for audit trail
+				log.DebugContext(r.Context(), "registry token validated",
+					"build_id", registryClaims.BuildID,
+					"repos", registryClaims.Repositories,
+					"scope", registryClaims.Scope)
+				ctx := WithClaims(r.Context(), &Claims{Subject: "builder-" + registryClaims.BuildID, AuthMethod: AuthMethodJWT, Role: RoleOperator})
+				next.ServeHTTP(w, r.WithContext(ctx))
 				return
 			}
+			log.DebugContext(r.Context(), "registry token validation failed", "error", err)
+		} else {
+			log.DebugContext(r.Context(), "failed to extract token", "error", err)
+		}
+	}
 
-			// Extract user ID from claims and add to context
-			var userID string
-			if sub, ok := claims["sub"].(string); ok {
-				userID = sub
-			}
-
-			// Update the context with user ID
-			newCtx := context.WithValue(r.Context(), userIDKey, userID)
-
-			// Call next handler with updated context
-			next.ServeHTTP(w, r.WithContext(newCtx))
-		})
+	// Fallback: Allow internal VM network (10.102.x.x) for registry pushes
+	// This is a transitional fallback for older builder images without token auth
+	if isInternalVMRequest(r) {
+		log.DebugContext(r.Context(), "allowing internal VM request via IP fallback (deprecated)",
+			"remote_addr", r.RemoteAddr,
+			"path", r.URL.Path)
+		ctx := WithClaims(r.Context(), &Claims{Subject: "internal-builder-legacy", Role: RoleOperator})
+		next.ServeHTTP(w, r.WithContext(ctx))
+		return
 	}
+
+	// Registry auth failed
+	log.DebugContext(r.Context(), "registry request unauthorized", "remote_addr", r.RemoteAddr)
+	OapiErrorHandler(w, "registry authentication required", http.StatusUnauthorized)
 }
@@ -0,0 +1,162 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/getkin/kin-openapi/routers"
+	"github.com/getkin/kin-openapi/routers/gorillamux"
+	"github.com/kernel/hypeman/lib/oapi"
+)
+
+// BodyValidator checks request bodies built outside the REST API, such as
+// those of gRPC calls, against the OpenAPI operation they stand in for, the
+// way the REST request validator checks them
+type BodyValidator struct {
+	router routers.Router
+}
+
+// NewBodyValidator creates a validator for the operations in spec
+func NewBodyValidator(spec *openapi3.T) (*BodyValidator, error) {
+	router, err := gorillamux.NewRouter(spec)
+	if err != nil {
+		return nil, fmt.Errorf("build OpenAPI router: %w", err)
+	}
+	return &BodyValidator{router: router}, nil
+}
+
+// Validate checks body as the JSON body of a request to method and path. It
+// returns the problem the REST API would answer an invalid body with, or nil.
+// Authentication isn't checked.
+func (v *BodyValidator) Validate(ctx context.Context, method, path string, body any) (*oapi.Error, error) {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return nil, fmt.Errorf("encode body: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, path, bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	route, pathParams, err := v.router.FindRoute(req)
+	if err != nil {
+		return nil, fmt.Errorf("find operation for %s %s: %w", method, path, err)
+	}
+
+	err = openapi3filter.ValidateRequest(ctx, &openapi3filter.RequestValidationInput{
+		Request:    req,
+		PathParams: pathParams,
+		Route:      route,
+		Options: &openapi3filter.Options{
+			AuthenticationFunc: openapi3filter.NoopAuthenticationFunc,
+			MultiError:         true,
+		},
+	})
+	if err != nil {
+		problem := validationProblem(err)
+		return &problem, nil
+	}
+	return nil, nil
+}
+
+// validationProblem converts an OpenAPI request validation error into a
+// problem with one detail per violated parameter or body field.
+func validationProblem(err error) oapi.Error {
+	details := validationDetails(err)
+	message := "request validation failed"
+	switch {
+	case len(details) == 1 && details[0].Message != nil:
+		message = *details[0].Message
+	case len(details) > 1:
+		message = fmt.Sprintf("request validation failed with %d errors", len(details))
+	}
+	e := oapi.Error{Code: oapi.InvalidRequest, Message: message}
+	if len(details) > 0 {
+		e.Details = &details
+	}
+	return e
+}
+
+// validationDetails flattens request and schema errors into error details.
+func validationDetails(err error) []oapi.ErrorDetail {
+	if m, ok := err.(openapi3.MultiError); ok {
+		var details []oapi.ErrorDetail
+		for _, e := range m {
+			details = append(details, validationDetails(e)...)
+		}
+		return details
+	}
+
+	var reqErr *openapi3filter.RequestError
+	if !errors.As(err, &reqErr) {
+		return []oapi.ErrorDetail{newDetail("", "", err.Error())}
+	}
+
+	field := ""
+	if reqErr.Parameter != nil {
+		field = reqErr.Parameter.Name
+	}
+
+	// Schema violations carry the failing keyword and the path within the value
+	var schemaErrs []*openapi3.SchemaError
+	collectSchemaErrors(reqErr.Err, &schemaErrs)
+	if len(schemaErrs) == 0 {
+		reason := reqErr.Reason
+		if reqErr.Err != nil && (reason == "" || reqErr.Parameter != nil) {
+			reason = reqErr.Err.Error()
+		}
+		return []oapi.ErrorDetail{newDetail(field, "", reason)}
+	}
+
+	details := make([]oapi.ErrorDetail, 0, len(schemaErrs))
+	for _, se := range schemaErrs {
+		f := field
+		if pointer := se.JSONPointer(); len(pointer) > 0 {
+			if reqErr.Parameter != nil {
+				f += "/" + strings.Join(pointer, "/")
+			} else {
+				f = "/" + strings.Join(pointer, "/")
+			}
+		} else if reqErr.RequestBody != nil && f == "" {
+			f = "/"
+		}
+		details = append(details, newDetail(f, se.SchemaField, se.Reason))
+	}
+	return details
+}
+
+// collectSchemaErrors appends the schema errors in err, which may be a
+// single SchemaError or a MultiError of them.
+func collectSchemaErrors(err error, out *[]*openapi3.SchemaError) {
+	if err == nil {
+		return
+	}
+	if m, ok := err.(openapi3.MultiError); ok {
+		for _, e := range m {
+			collectSchemaErrors(e, out)
+		}
+		return
+	}
+	var se *openapi3.SchemaError
+	if errors.As(err, &se) {
+		*out = append(*out, se)
+	}
+}
+
+func newDetail(field, code, message string) oapi.ErrorDetail {
+	d := oapi.ErrorDetail{Message: &message}
+	if field != "" {
+		d.Field = &field
+	}
+	if code != "" {
+		d.Code = &code
+	}
+	return d
+}
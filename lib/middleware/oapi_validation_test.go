@@ -0,0 +1,149 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/kernel/hypeman/lib/oapi"
+	nethttpmiddleware "github.com/oapi-codegen/nethttp-middleware"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const validationTestSpec = `
+openapi: 3.0.3
+info: {title: test, version: "1"}
+components:
+  securitySchemes:
+    bearerAuth: {type: http, scheme: bearer}
+security:
+  - bearerAuth: []
+paths:
+  /instances:
+    post:
+      parameters:
+        - {name: wait, in: query, schema: {type: boolean}}
+      requestBody:
+        required: true
+        content:
+          application/json:
+            schema:
+              type: object
+              required: [name, image]
+              properties:
+                name: {type: string, pattern: "^[a-z]+$"}
+                image: {type: string}
+                vcpus: {type: integer, minimum: 1}
+      responses:
+        "201": {description: created}
+`
+
+func newValidationTestHandler(t *testing.T) http.Handler {
+	t.Helper()
+	spec, err := openapi3.NewLoader().LoadFromData([]byte(validationTestSpec))
+	require.NoError(t, err)
+
+	auth := NewAuthenticator(AuthConfig{JwtSecret: testJWTSecret})
+	return nethttpmiddleware.OapiRequestValidatorWithOptions(spec, &nethttpmiddleware.Options{
+		Options: openapi3filter.Options{
+			AuthenticationFunc: auth.OapiAuthenticationFunc(),
+			MultiError:         true,
+		},
+		ErrorHandlerWithOpts: OapiErrorHandlerWithOpts,
+	})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+	}))
+}
+
+func postInstances(t *testing.T, h http.Handler, query, body string, authenticated bool) (*httptest.ResponseRecorder, oapi.Error) {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodPost, "/instances"+query, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	if authenticated {
+		req.Header.Set("Authorization", "Bearer "+generateUserToken(t, "user-123"))
+	}
+	rr := httptest.NewRecorder()
+	h.ServeHTTP(rr, req)
+
+	var e oapi.Error
+	if rr.Code >= 400 {
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &e))
+	}
+	return rr, e
+}
+
+func TestOapiValidation_FieldDetails(t *testing.T) {
+	h := newValidationTestHandler(t)
+
+	rr, e := postInstances(t, h, "?wait=maybe", `{"name": "Bad Name", "vcpus": 0}`, true)
+	require.Equal(t, http.StatusBadRequest, rr.Code)
+	assert.Equal(t, "application/problem+json", rr.Header().Get("Content-Type"))
+	assert.Equal(t, oapi.InvalidRequest, e.Code)
+	require.NotNil(t, e.Details)
+
+	got := map[string]string{}
+	for _, d := range *e.Details {
+		require.NotNil(t, d.Field, "detail %+v has no field", d)
+		code := ""
+		if d.Code != nil {
+			code = *d.Code
+		}
+		got[*d.Field] = code
+	}
+	assert.Contains(t, got, "wait")
+	assert.Equal(t, "pattern", got["/name"])
+	assert.Equal(t, "minimum", got["/vcpus"])
+	assert.Equal(t, "required", got["/image"])
+	assert.Contains(t, e.Message, "errors")
+}
+
+func TestOapiValidation_Valid(t *testing.T) {
+	h := newValidationTestHandler(t)
+	rr, _ := postInstances(t, h, "", `{"name": "web", "image": "nginx"}`, true)
+	assert.Equal(t, http.StatusCreated, rr.Code)
+}
+
+func TestOapiValidation_AuthTakesPrecedence(t *testing.T) {
+	h := newValidationTestHandler(t)
+
+	// An unauthenticated request with an invalid body is a 401, not a 400 or 500
+	rr, e := postInstances(t, h, "", `{"vcpus": 0}`, false)
+	assert.Equal(t, http.StatusUnauthorized, rr.Code)
+	assert.Equal(t, oapi.Unauthorized, e.Code)
+	assert.Nil(t, e.Details)
+}
+
+func TestValidationProblem_SingleError(t *testing.T) {
+	e := validationProblem(&openapi3filter.RequestError{Reason: "body is required"})
+	assert.Equal(t, oapi.InvalidRequest, e.Code)
+	assert.Equal(t, "body is required", e.Message)
+	require.NotNil(t, e.Details)
+	assert.Len(t, *e.Details, 1)
+}
+
+func TestBodyValidator(t *testing.T) {
+	spec, err := openapi3.NewLoader().LoadFromData([]byte(validationTestSpec))
+	require.NoError(t, err)
+	v, err := NewBodyValidator(spec)
+	require.NoError(t, err)
+
+	problem, err := v.Validate(context.Background(), http.MethodPost, "/instances", map[string]any{"name": "web", "image": "nginx"})
+	require.NoError(t, err)
+	assert.Nil(t, problem)
+
+	// Authentication is left to the caller
+	problem, err = v.Validate(context.Background(), http.MethodPost, "/instances", map[string]any{"name": "Bad Name", "image": "nginx"})
+	require.NoError(t, err)
+	require.NotNil(t, problem)
+	assert.Equal(t, oapi.InvalidRequest, problem.Code)
+	assert.Contains(t, problem.Message, "regular expression")
+
+	_, err = v.Validate(context.Background(), http.MethodPost, "/volumes", map[string]any{})
+	assert.Error(t, err)
+}
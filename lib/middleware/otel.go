@@ -10,8 +10,10 @@ import (
 
 	"github.com/go-chi/chi/v5"
 	"github.com/kernel/hypeman/lib/logger"
+	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/propagation"
 )
 
 // HTTPMetrics holds the OTel metrics for HTTP requests.
@@ -84,6 +86,17 @@ func NoopHTTPMetrics() func(http.Handler) http.Handler {
 	}
 }
 
+// PropagateTraceContext returns a middleware that extracts an incoming trace
+// context (W3C traceparent) into the request context without starting a span
+// or wrapping the response writer. Used on WebSocket routes, which can't use
+// otelchi, so spans started by their handlers join the caller's trace.
+func PropagateTraceContext(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := otel.GetTextMapPropagator().Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
 // AccessLogger returns a middleware that logs HTTP requests using slog with trace context.
 // This replaces chi's middleware.Logger to get logs into OTel/Loki with trace correlation.
 func AccessLogger(log *slog.Logger) func(http.Handler) http.Handler {
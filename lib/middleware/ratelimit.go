@@ -0,0 +1,255 @@
+package middleware
+
+import (
+	"context"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kernel/hypeman/lib/logger"
+)
+
+const (
+	// idleClientTTL is how long an idle client's limiter state is kept
+	idleClientTTL = 10 * time.Minute
+	// sweepInterval bounds how often idle client state is garbage collected
+	sweepInterval = time.Minute
+)
+
+// ClientKey identifies the client a request is accounted to: the authenticated
+// principal when known, otherwise the remote IP.
+func ClientKey(r *http.Request) string {
+	return clientKey(r.Context(), r.RemoteAddr)
+}
+
+// clientKey identifies a client by the principal in ctx, falling back to the
+// IP of remoteAddr
+func clientKey(ctx context.Context, remoteAddr string) string {
+	if creator := CreatorFromContext(ctx); creator != "" {
+		return creator
+	}
+	host, _, err := net.SplitHostPort(remoteAddr)
+	if err != nil {
+		host = remoteAddr
+	}
+	return "ip:" + host
+}
+
+// tokenBucket is a classic token bucket refilled continuously at rate tokens per second.
+type tokenBucket struct {
+	tokens   float64
+	last     time.Time
+	lastUsed time.Time
+}
+
+// RateLimiter limits each client to a steady request rate with bursts.
+type RateLimiter struct {
+	now func() time.Time
+
+	mu        sync.Mutex
+	rate      float64 // <= 0 = unlimited
+	burst     float64
+	buckets   map[string]*tokenBucket
+	lastSweep time.Time
+}
+
+// NewRateLimiter creates a per-client token bucket limiter allowing rps
+// requests per second with bursts of up to burst requests. burst < 1
+// defaults to 1, and rps <= 0 allows every request.
+func NewRateLimiter(rps float64, burst int) *RateLimiter {
+	l := &RateLimiter{
+		now:     time.Now,
+		buckets: make(map[string]*tokenBucket),
+	}
+	l.SetLimit(rps, burst)
+	return l
+}
+
+// SetLimit changes the rate and burst, as in NewRateLimiter. Clients keep
+// the tokens they have, up to the new burst.
+func (l *RateLimiter) SetLimit(rps float64, burst int) {
+	if burst < 1 {
+		burst = 1
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.rate = rps
+	l.burst = float64(burst)
+	if rps <= 0 {
+		// Start from full buckets if limiting is enabled again
+		clear(l.buckets)
+	}
+}
+
+// Allow takes a token for the client. If none is available it returns false
+// and how long until one will be.
+func (l *RateLimiter) Allow(key string) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.rate <= 0 {
+		return true, 0
+	}
+	now := l.now()
+	l.sweep(now)
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &tokenBucket{tokens: l.burst, last: now}
+		l.buckets[key] = b
+	}
+	b.tokens = math.Min(l.burst, b.tokens+now.Sub(b.last).Seconds()*l.rate)
+	b.last = now
+	b.lastUsed = now
+
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0
+	}
+	wait := time.Duration((1 - b.tokens) / l.rate * float64(time.Second))
+	return false, wait
+}
+
+// sweep drops buckets of clients that have been idle for a while. Must be called with mu held.
+func (l *RateLimiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < sweepInterval {
+		return
+	}
+	l.lastSweep = now
+	for key, b := range l.buckets {
+		if now.Sub(b.lastUsed) > idleClientTTL {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+// Middleware returns a chi middleware that rejects requests over the client's
+// rate with 429 and a Retry-After header. Must run after authentication so
+// requests are accounted to the principal rather than the IP.
+func (l *RateLimiter) Middleware() func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := ClientKey(r)
+			ok, wait := l.Allow(key)
+			if !ok {
+				logger.FromContext(r.Context()).WarnContext(r.Context(), "rate limit exceeded", "client", key)
+				writeTooManyRequests(w, wait, "rate limit exceeded")
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// ConcurrencyLimiter caps the number of in-flight requests per client.
+type ConcurrencyLimiter struct {
+	name string
+	max  int
+
+	mu       sync.Mutex
+	inFlight map[string]int
+}
+
+// NewConcurrencyLimiter creates a limiter allowing at most max concurrent
+// requests per client, or any number if max <= 0. name is used in errors and
+// logs (e.g. "exec sessions").
+func NewConcurrencyLimiter(name string, max int) *ConcurrencyLimiter {
+	return &ConcurrencyLimiter{
+		name:     name,
+		max:      max,
+		inFlight: make(map[string]int),
+	}
+}
+
+// Acquire reserves a slot for the client. It returns false if the client is at the limit.
+func (l *ConcurrencyLimiter) Acquire(key string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.max > 0 && l.inFlight[key] >= l.max {
+		return false
+	}
+	l.inFlight[key]++
+	return true
+}
+
+// Release frees a slot previously reserved with Acquire.
+func (l *ConcurrencyLimiter) Release(key string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.inFlight[key]--
+	if l.inFlight[key] <= 0 {
+		delete(l.inFlight, key)
+	}
+}
+
+// Middleware returns a chi middleware that limits concurrent requests matching
+// match (all requests if nil), rejecting excess requests with 429.
+func (l *ConcurrencyLimiter) Middleware(match func(*http.Request) bool) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if match != nil && !match(r) {
+				next.ServeHTTP(w, r)
+				return
+			}
+			key := ClientKey(r)
+			if !l.Acquire(key) {
+				logger.FromContext(r.Context()).WarnContext(r.Context(), "concurrency limit exceeded",
+					"client", key, "limit", l.name, "max", l.max)
+				writeTooManyRequests(w, time.Second, "too many concurrent "+l.name)
+				return
+			}
+			defer l.Release(key)
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// writeTooManyRequests writes a 429 response with a Retry-After header (whole seconds, at least 1).
+func writeTooManyRequests(w http.ResponseWriter, retryAfter time.Duration, message string) {
+	seconds := int(math.Ceil(retryAfter.Seconds()))
+	if seconds < 1 {
+		seconds = 1
+	}
+	w.Header().Set("Retry-After", strconv.Itoa(seconds))
+	OapiErrorHandler(w, message, http.StatusTooManyRequests)
+}
+
+// RateLimit returns per-client rate limiting middleware, or a pass-through
+// middleware if rps <= 0.
+func RateLimit(rps float64, burst int) func(http.Handler) http.Handler {
+	if rps <= 0 {
+		return passthrough
+	}
+	return NewRateLimiter(rps, burst).Middleware()
+}
+
+// LimitConcurrency returns per-client concurrency limiting middleware for
+// requests matching match, or a pass-through middleware if max <= 0.
+// The returned middleware shares one limiter wherever it is mounted.
+func LimitConcurrency(name string, max int, match func(*http.Request) bool) func(http.Handler) http.Handler {
+	if max <= 0 {
+		return passthrough
+	}
+	return NewConcurrencyLimiter(name, max).Middleware(match)
+}
+
+// IsCreateRequest matches requests that create instances, images, volumes or
+// builds, build retries included.
+func IsCreateRequest(r *http.Request) bool {
+	if r.Method != http.MethodPost {
+		return false
+	}
+	switch r.URL.Path {
+	case "/instances", "/images", "/images/import", "/volumes", "/builds":
+		return true
+	}
+	return strings.HasPrefix(r.URL.Path, "/builds/") && strings.HasSuffix(r.URL.Path, "/retry")
+}
+
+func passthrough(next http.Handler) http.Handler {
+	return next
+}
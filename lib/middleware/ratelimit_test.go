@@ -0,0 +1,125 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRateLimiter_Allow(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	l := NewRateLimiter(2, 3)
+	l.now = func() time.Time { return now }
+
+	// Burst is available immediately
+	for i := 0; i < 3; i++ {
+		ok, _ := l.Allow("client-a")
+		require.True(t, ok, "request %d should be allowed", i)
+	}
+	ok, wait := l.Allow("client-a")
+	assert.False(t, ok)
+	assert.Equal(t, 500*time.Millisecond, wait)
+
+	// Other clients have their own bucket
+	ok, _ = l.Allow("client-b")
+	assert.True(t, ok)
+
+	// Tokens refill at the configured rate
+	now = now.Add(500 * time.Millisecond)
+	ok, _ = l.Allow("client-a")
+	assert.True(t, ok)
+	ok, _ = l.Allow("client-a")
+	assert.False(t, ok)
+}
+
+func TestRateLimiter_SetLimit(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	l := NewRateLimiter(0, 1)
+	l.now = func() time.Time { return now }
+
+	// Unlimited until a rate is set
+	for i := 0; i < 10; i++ {
+		ok, _ := l.Allow("client-a")
+		require.True(t, ok)
+	}
+
+	l.SetLimit(1, 2)
+	for i := 0; i < 2; i++ {
+		ok, _ := l.Allow("client-a")
+		require.True(t, ok, "request %d should be allowed", i)
+	}
+	ok, wait := l.Allow("client-a")
+	assert.False(t, ok)
+	assert.Equal(t, time.Second, wait)
+
+	l.SetLimit(0, 0)
+	ok, _ = l.Allow("client-a")
+	assert.True(t, ok)
+}
+
+func TestRateLimit_Middleware(t *testing.T) {
+	handler := RateLimit(1, 1)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	serve := func(subject string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/instances", nil)
+		req = req.WithContext(WithClaims(req.Context(), &Claims{Subject: subject, AuthMethod: AuthMethodAPIKey}))
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr
+	}
+
+	assert.Equal(t, http.StatusOK, serve("ci").Code)
+
+	rr := serve("ci")
+	assert.Equal(t, http.StatusTooManyRequests, rr.Code)
+	assert.Equal(t, "1", rr.Header().Get("Retry-After"))
+
+	assert.Equal(t, http.StatusOK, serve("ops").Code)
+}
+
+func TestLimitConcurrency(t *testing.T) {
+	release := make(chan struct{})
+	started := make(chan struct{})
+	handler := LimitConcurrency("exec sessions", 1, nil)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		started <- struct{}{}
+		<-release
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	serve := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/instances/abc/exec", nil)
+		req.RemoteAddr = "10.0.0.1:1234"
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	var first *httptest.ResponseRecorder
+	go func() {
+		defer wg.Done()
+		first = serve()
+	}()
+	<-started
+
+	// Second session from the same client is rejected while the first is open
+	rr := serve()
+	assert.Equal(t, http.StatusTooManyRequests, rr.Code)
+	assert.Equal(t, "1", rr.Header().Get("Retry-After"))
+
+	close(release)
+	wg.Wait()
+	assert.Equal(t, http.StatusOK, first.Code)
+
+	// Slot is released once the session ends
+	go func() { <-started }()
+	assert.Equal(t, http.StatusOK, serve().Code)
+}
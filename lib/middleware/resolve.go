@@ -41,6 +41,8 @@ type Resolvers struct {
 	Volume   ResourceResolver
 	Ingress  ResourceResolver
 	Image    ResourceResolver
+	Secret   ResourceResolver
+	Group    ResourceResolver
 }
 
 // ErrorResponder handles resolver errors by writing HTTP responses.
@@ -55,6 +57,8 @@ type ErrorResponder func(w http.ResponseWriter, err error, lookup string)
 //   - /volumes/{id}/* -> uses Volume resolver
 //   - /ingresses/{id}/* -> uses Ingress resolver
 //   - /images/{name}/* -> uses Image resolver (by name, not ID)
+//   - /secrets/{id}/* -> uses Secret resolver
+//   - /instance-groups/{id}/* -> uses Group resolver
 func ResolveResource(resolvers Resolvers, errResponder ErrorResponder) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -83,6 +87,14 @@ func ResolveResource(resolvers Resolvers, errResponder ErrorResponder) func(http
 				resolver = resolvers.Image
 				resourceType = "image"
 				paramName = "name"
+			case strings.HasPrefix(path, "/secrets/"):
+				resolver = resolvers.Secret
+				resourceType = "secret"
+				paramName = "id"
+			case strings.HasPrefix(path, "/instance-groups/"):
+				resolver = resolvers.Group
+				resourceType = "group"
+				paramName = "id"
 			default:
 				// No resource to resolve (e.g., list endpoints, health)
 				next.ServeHTTP(w, r)
@@ -171,6 +183,18 @@ func GetResolvedImage[T any](ctx context.Context) *T {
 	return getResolved[T](ctx, "image")
 }
 
+// GetResolvedSecret retrieves the resolved secret from context.
+// Returns nil if not found or wrong type.
+func GetResolvedSecret[T any](ctx context.Context) *T {
+	return getResolved[T](ctx, "secret")
+}
+
+// GetResolvedGroup retrieves the resolved instance group from context.
+// Returns nil if not found or wrong type.
+func GetResolvedGroup[T any](ctx context.Context) *T {
+	return getResolved[T](ctx, "group")
+}
+
 // GetResolvedID retrieves just the resolved ID for a resource type.
 func GetResolvedID(ctx context.Context, resourceType string) string {
 	if resolved, ok := ctx.Value(resolvedResourceKey{resourceType}).(ResolvedResource); ok {
@@ -199,7 +223,8 @@ func getResolved[T any](ctx context.Context, resourceType string) *T {
 	return nil
 }
 
-// Test helpers for setting resolved resources in context (used by tests)
+// Helpers for setting resolved resources in context, used by tests and by
+// handlers that act on resources they looked up themselves
 
 // WithResolvedInstance returns a context with the given instance set as resolved.
 func WithResolvedInstance(ctx context.Context, id string, inst any) context.Context {
@@ -220,3 +245,13 @@ func WithResolvedIngress(ctx context.Context, id string, ing any) context.Contex
 func WithResolvedImage(ctx context.Context, id string, img any) context.Context {
 	return context.WithValue(ctx, resolvedResourceKey{"image"}, ResolvedResource{ID: id, Resource: img})
 }
+
+// WithResolvedSecret returns a context with the given secret set as resolved.
+func WithResolvedSecret(ctx context.Context, id string, secret any) context.Context {
+	return context.WithValue(ctx, resolvedResourceKey{"secret"}, ResolvedResource{ID: id, Resource: secret})
+}
+
+// WithResolvedGroup returns a context with the given instance group set as resolved.
+func WithResolvedGroup(ctx context.Context, id string, group any) context.Context {
+	return context.WithValue(ctx, resolvedResourceKey{"group"}, ResolvedResource{ID: id, Resource: group})
+}
@@ -0,0 +1,171 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/kernel/hypeman/lib/oapi"
+	"github.com/kernel/hypeman/lib/problem"
+)
+
+const (
+	// APIPrefix is the path prefix of the current major API version
+	APIPrefix = "/v1"
+	// VersionHeader pins the schema revision of responses, and reports the
+	// revision a response follows
+	VersionHeader = "Hypeman-Version"
+)
+
+// Revision is a dated change to response schemas within a major version
+// that can break existing clients, like an enum gaining a value. Clients
+// pin the revision they were built against with the Hypeman-Version header,
+// and responses are downgraded to it.
+type Revision struct {
+	Version     string // Date of the revision (YYYY-MM-DD), as sent in Hypeman-Version
+	Description string
+
+	// Downgrade rewrites a decoded JSON response of route (a pattern like
+	// "GET /instances/{id}") to how it looked before this revision. Nil
+	// for the baseline.
+	Downgrade func(route string, body any) any
+}
+
+// Revisions lists the schema revisions of /v1, oldest first. Append a
+// revision, with a Downgrade undoing it, when a response changes in a way
+// existing clients can't handle; additive fields don't need one.
+var Revisions = []Revision{
+	{Version: "2026-10-17", Description: "Baseline of /v1"},
+}
+
+// ReplaceEnumValue returns a Downgrade for an enum that gained a value: it
+// replaces value with replacement in every field named field, in any object
+// of any response. For example, ReplaceEnumValue("state", "Hibernated",
+// "Standby") hides a new instance state from older clients.
+func ReplaceEnumValue(field, value, replacement string) func(route string, body any) any {
+	var walk func(v any) any
+	walk = func(v any) any {
+		switch v := v.(type) {
+		case map[string]any:
+			for k, child := range v {
+				if s, ok := child.(string); ok && k == field && s == value {
+					v[k] = replacement
+				} else {
+					v[k] = walk(child)
+				}
+			}
+		case []any:
+			for i, child := range v {
+				v[i] = walk(child)
+			}
+		}
+		return v
+	}
+	return func(_ string, body any) any { return walk(body) }
+}
+
+// unprefixedDeprecation is when unprefixed API paths were deprecated in
+// favor of /v1
+var unprefixedDeprecation = time.Date(2026, 10, 17, 0, 0, 0, 0, time.UTC)
+
+// unversionedPaths are served without a version prefix and aren't deprecated:
+// the OCI registry (which owns /v2), the spec, probes and metrics
+var unversionedPaths = []string{"/v2", "/spec.yaml", "/spec.json", "/swagger", "/healthz", "/readyz", "/metrics", "/debug"}
+
+// VersionConfig configures API versioning
+type VersionConfig struct {
+	// Revisions are the known schema revisions, oldest first
+	Revisions []Revision
+	// Sunset is when unprefixed API paths stop working (zero = not scheduled)
+	Sunset time.Time
+}
+
+// APIVersion serves the API under /v1 and negotiates the schema revision.
+// It must run before routing: /v1 is stripped from the path, so routes and
+// other middleware see unprefixed paths. Requests to unprefixed API paths
+// still work but get Deprecation, Link and Sunset headers pointing to /v1.
+func APIVersion(cfg VersionConfig) func(http.Handler) http.Handler {
+	latest := cfg.Revisions[len(cfg.Revisions)-1].Version
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if path, ok := stripAPIPrefix(r.URL.Path); ok {
+				r.URL.Path = path
+				r.URL.RawPath = ""
+			} else if !isUnversioned(r.URL.Path) {
+				w.Header().Set("Deprecation", "@"+strconv.FormatInt(unprefixedDeprecation.Unix(), 10))
+				w.Header().Add("Link", fmt.Sprintf("<%s%s>; rel=\"successor-version\"", APIPrefix, r.URL.Path))
+				if !cfg.Sunset.IsZero() {
+					w.Header().Set("Sunset", cfg.Sunset.UTC().Format(http.TimeFormat))
+				}
+			}
+
+			version := r.Header.Get(VersionHeader)
+			if version == "" {
+				version = latest
+			}
+			pinned := slices.IndexFunc(cfg.Revisions, func(rev Revision) bool { return rev.Version == version })
+			if pinned < 0 {
+				problem.Write(w, http.StatusBadRequest, oapi.InvalidRequest,
+					fmt.Sprintf("unknown %s %q; known versions are %s", VersionHeader, version, revisionList(cfg.Revisions)))
+				return
+			}
+			w.Header().Set(VersionHeader, version)
+
+			// Revisions after the pinned one are undone, newest first
+			var downgrades []Revision
+			for i := len(cfg.Revisions) - 1; i > pinned; i-- {
+				if cfg.Revisions[i].Downgrade != nil {
+					downgrades = append(downgrades, cfg.Revisions[i])
+				}
+			}
+			if len(downgrades) == 0 {
+				next.ServeHTTP(w, r)
+				return
+			}
+			jw := &jsonWriter{ResponseWriter: w, status: http.StatusOK}
+			next.ServeHTTP(jw, r)
+			route := r.Method + " " + r.URL.Path
+			if rctx := chi.RouteContext(r.Context()); rctx != nil && rctx.RoutePattern() != "" {
+				route = r.Method + " " + rctx.RoutePattern()
+			}
+			jw.finish(func(body any) any {
+				for _, rev := range downgrades {
+					body = rev.Downgrade(route, body)
+				}
+				return body
+			})
+		})
+	}
+}
+
+// stripAPIPrefix returns path without the /v1 prefix
+func stripAPIPrefix(path string) (string, bool) {
+	if path == APIPrefix {
+		return "/", true
+	}
+	if rest, ok := strings.CutPrefix(path, APIPrefix+"/"); ok {
+		return "/" + rest, true
+	}
+	return "", false
+}
+
+func isUnversioned(path string) bool {
+	for _, p := range unversionedPaths {
+		if path == p || strings.HasPrefix(path, p+"/") {
+			return true
+		}
+	}
+	return path == "/"
+}
+
+func revisionList(revisions []Revision) string {
+	versions := make([]string, len(revisions))
+	for i, rev := range revisions {
+		versions[i] = rev.Version
+	}
+	return strings.Join(versions, ", ")
+}
@@ -0,0 +1,148 @@
+package middleware
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newVersionedRouter(cfg VersionConfig) http.Handler {
+	r := chi.NewRouter()
+	r.Use(APIVersion(cfg))
+	r.Get("/instances/{id}", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{"id": chi.URLParam(r, "id"), "state": "Hibernated"})
+	})
+	r.Get("/healthz", func(w http.ResponseWriter, r *http.Request) {})
+	return r
+}
+
+func TestAPIVersion_Prefix(t *testing.T) {
+	sunset := time.Date(2027, 6, 30, 0, 0, 0, 0, time.UTC)
+	h := newVersionedRouter(VersionConfig{Revisions: Revisions, Sunset: sunset})
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v1/instances/abc", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"id":"abc"`)
+	assert.Empty(t, rec.Header().Get("Deprecation"))
+	assert.Equal(t, Revisions[len(Revisions)-1].Version, rec.Header().Get(VersionHeader))
+
+	// Unprefixed paths still work, flagged as deprecated
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/instances/abc", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "@1792195200", rec.Header().Get("Deprecation"))
+	assert.Equal(t, `</v1/instances/abc>; rel="successor-version"`, rec.Header().Get("Link"))
+	assert.Equal(t, "Wed, 30 Jun 2027 00:00:00 GMT", rec.Header().Get("Sunset"))
+
+	// Probes aren't versioned
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Empty(t, rec.Header().Get("Deprecation"))
+}
+
+func TestAPIVersion_Revisions(t *testing.T) {
+	revisions := []Revision{
+		{Version: "2026-01-01"},
+		{Version: "2026-03-01", Downgrade: ReplaceEnumValue("state", "Hibernated", "Standby")},
+	}
+	h := newVersionedRouter(VersionConfig{Revisions: revisions})
+
+	get := func(version string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/v1/instances/abc", nil)
+		if version != "" {
+			req.Header.Set(VersionHeader, version)
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec
+	}
+
+	// Without a pinned revision, responses follow the latest
+	rec := get("")
+	assert.JSONEq(t, `{"id":"abc","state":"Hibernated"}`, rec.Body.String())
+	assert.Equal(t, "2026-03-01", rec.Header().Get(VersionHeader))
+
+	// Older clients get the value they know
+	rec = get("2026-01-01")
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"id":"abc","state":"Standby"}`, rec.Body.String())
+	assert.Equal(t, "2026-01-01", rec.Header().Get(VersionHeader))
+
+	rec = get("2025-01-01")
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Contains(t, rec.Body.String(), "2026-01-01, 2026-03-01")
+}
+
+func TestAPIVersion_DowngradeStreams(t *testing.T) {
+	revisions := []Revision{
+		{Version: "2026-01-01"},
+		{Version: "2026-03-01", Downgrade: ReplaceEnumValue("state", "Hibernated", "Standby")},
+	}
+	r := chi.NewRouter()
+	r.Use(APIVersion(VersionConfig{Revisions: revisions}))
+	r.Get("/instances/{id}/exec", func(w http.ResponseWriter, r *http.Request) {
+		// WebSocket upgraders assert the interface rather than unwrapping
+		h, ok := w.(http.Hijacker)
+		if !assert.True(t, ok) {
+			return
+		}
+		conn, buf, err := h.Hijack()
+		if !assert.NoError(t, err) {
+			return
+		}
+		defer conn.Close()
+		buf.WriteString("HTTP/1.1 101 Switching Protocols\r\nConnection: Upgrade\r\nUpgrade: websocket\r\n\r\n")
+		buf.Flush()
+	})
+	r.Get("/instances/{id}/logs", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Write([]byte("data: line\n\n"))
+		f, ok := w.(http.Flusher)
+		if assert.True(t, ok) {
+			f.Flush()
+		}
+	})
+	srv := httptest.NewServer(r)
+	defer srv.Close()
+
+	// Pinned to an older revision, so responses go through the downgrade
+	// writer; upgrades and streams must still reach the connection
+	conn, err := net.Dial("tcp", srv.Listener.Addr().String())
+	require.NoError(t, err)
+	defer conn.Close()
+	_, err = io.WriteString(conn, "GET /v1/instances/abc/exec HTTP/1.1\r\nHost: hypeman\r\nHypeman-Version: 2026-01-01\r\n\r\n")
+	require.NoError(t, err)
+	resp, err := http.ReadResponse(bufio.NewReader(conn), nil)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusSwitchingProtocols, resp.StatusCode)
+
+	req, err := http.NewRequest(http.MethodGet, srv.URL+"/v1/instances/abc/logs", nil)
+	require.NoError(t, err)
+	req.Header.Set(VersionHeader, "2026-01-01")
+	resp, err = http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	assert.Equal(t, "data: line\n\n", string(body))
+}
+
+func TestReplaceEnumValue(t *testing.T) {
+	var body any
+	require.NoError(t, json.Unmarshal([]byte(`[{"state":"Hibernated","name":"Hibernated","members":[{"state":"Hibernated"}]}]`), &body))
+	out, err := json.Marshal(ReplaceEnumValue("state", "Hibernated", "Standby")("GET /instances", body))
+	require.NoError(t, err)
+	assert.JSONEq(t, `[{"state":"Standby","name":"Hibernated","members":[{"state":"Standby"}]}]`, string(out))
+}
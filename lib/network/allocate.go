@@ -21,45 +21,34 @@ func (m *manager) CreateAllocation(ctx context.Context, req AllocateRequest) (*N
 
 	log := logger.FromContext(ctx)
 
-	// 1. Get default network
-	network, err := m.getDefaultNetwork(ctx)
+	// 1-2. Get default network and check name uniqueness
+	network, err := m.admitAllocation(ctx, req.InstanceName)
 	if err != nil {
-		return nil, fmt.Errorf("get default network: %w", err)
+		return nil, err
 	}
 
-	// 2. Check name uniqueness
-	exists, err := m.NameExists(ctx, req.InstanceName)
+	// 3-5. Reuse the instance's reserved IP/MAC, or reserve new ones.
+	// TAP name is deterministic (tap-{first8chars-of-id}).
+	r, err := m.reserve(ctx, network, req.InstanceID)
 	if err != nil {
-		return nil, fmt.Errorf("check name exists: %w", err)
-	}
-	if exists {
-		return nil, fmt.Errorf("%w: instance name '%s' already exists, can't assign into same network: %s",
-			ErrNameExists, req.InstanceName, network.Name)
+		return nil, err
 	}
+	ip, mac, tap := r.IP, r.MAC, r.TAPDevice
 
-	// 3. Allocate random available IP
-	// Random selection reduces predictability and helps distribute IPs across the subnet.
-	// This is especially useful for large /16 networks and reduces conflicts when
-	// moving standby VMs across hosts.
-	ip, err := m.allocateNextIP(ctx, network.Subnet)
+	// 6. Create TAP device with bidirectional rate limiting and the network's MTU
+	dhcpOpts, err := m.loadDHCPOptions(network.Name)
 	if err != nil {
-		return nil, fmt.Errorf("allocate IP: %w", err)
+		return nil, err
 	}
-
-	// 4. Generate MAC (02:00:00:... format - locally administered)
-	mac, err := generateMAC()
-	if err != nil {
-		return nil, fmt.Errorf("generate MAC: %w", err)
-	}
-
-	// 5. Generate TAP name (tap-{first8chars-of-id})
-	tap := generateTAPName(req.InstanceID)
-
-	// 6. Create TAP device with bidirectional rate limiting
-	if err := m.createTAPDevice(tap, network.Bridge, network.Isolated, req.DownloadBps, req.UploadBps, req.UploadCeilBps); err != nil {
-		return nil, fmt.Errorf("create TAP device: %w", err)
+	uplink := m.uplink()
+	if err := m.createGuestDevice(tap, mac, network, uplink, req.BandwidthLimits, dhcpOpts.MTU); err != nil {
+		return nil, err
 	}
 	m.recordTAPOperation(ctx, "create")
+	if err := m.filterMetadata(ctx, tap, ip, network.Gateway); err != nil {
+		m.deleteTAPDevice(tap)
+		return nil, fmt.Errorf("guard metadata requests: %w", err)
+	}
 
 	log.InfoContext(ctx, "allocated network",
 		"instance_id", req.InstanceID,
@@ -75,26 +64,108 @@ func (m *manager) CreateAllocation(ctx context.Context, req AllocateRequest) (*N
 	_, ipNet, _ := net.ParseCIDR(network.Subnet)
 	netmask := fmt.Sprintf("%d.%d.%d.%d", ipNet.Mask[0], ipNet.Mask[1], ipNet.Mask[2], ipNet.Mask[3])
 
-	// 8. Return config (will be used in CH VmConfig)
+	// 8. Custom search domains and the service domain for the guest's resolv.conf
+	var searchDomains []string
+	serviceDomain := network.Name + "." + DefaultDNSDomain
+	if dnsConfig, err := m.loadDNSConfig(network.Name); err != nil {
+		log.WarnContext(ctx, "failed to load custom DNS config, booting without search domains", "error", err)
+	} else {
+		searchDomains = dnsConfig.SearchDomains
+		serviceDomain = dnsConfig.ServiceDomain(network.Name)
+	}
+
+	// 9. Return config (will be used in CH VmConfig)
 	return &NetworkConfig{
-		IP:        ip,
-		MAC:       mac,
-		Gateway:   network.Gateway,
-		Netmask:   netmask,
-		DNS:       m.config.DNSServer,
-		TAPDevice: tap,
+		IP:            ip,
+		MAC:           mac,
+		Gateway:       network.Gateway,
+		Netmask:       netmask,
+		DNS:           m.config.DNSServer,
+		SearchDomains: searchDomains,
+		ServiceDomain: serviceDomain,
+		MTU:           dhcpOpts.MTU,
+		NTPServers:    dhcpOpts.NTPServers,
+		Routes:        dhcpOpts.Routes,
+		TAPDevice:     tap,
+		Macvtap:       uplink.Mode == UplinkModeMacvtap,
 	}, nil
 }
 
+// CheckAllocation reports whether CreateAllocation would succeed for an
+// instance name: the default network exists, the name is unused and an IP is
+// free. Nothing is allocated.
+func (m *manager) CheckAllocation(ctx context.Context, instanceName string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	network, err := m.admitAllocation(ctx, instanceName)
+	if err != nil {
+		return err
+	}
+	res, err := m.loadReservations(network.Name)
+	if err != nil {
+		return err
+	}
+	if _, err := m.allocateNextIP(ctx, network.Subnet, res, ""); err != nil {
+		return fmt.Errorf("allocate IP: %w", err)
+	}
+	return nil
+}
+
+// RenameAllocation moves an instance's allocation to a new name. commit
+// persists the new name; it runs under the allocation lock, after the name is
+// checked to be free, so a concurrent create can't claim it in between. The
+// instance name records served by dnsmasq are refreshed afterwards.
+func (m *manager) RenameAllocation(ctx context.Context, instanceID, name string, commit func() error) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	exists, err := m.NameExists(ctx, name)
+	if err != nil {
+		return fmt.Errorf("check name exists: %w", err)
+	}
+	if exists {
+		return fmt.Errorf("%w: instance name '%s' already exists", ErrNameExists, name)
+	}
+	if err := commit(); err != nil {
+		return err
+	}
+
+	if err := m.RefreshInstanceDNS(ctx); err != nil {
+		logger.FromContext(ctx).WarnContext(ctx, "failed to refresh instance DNS records after rename",
+			"instance_id", instanceID, "name", name, "error", err)
+	}
+	return nil
+}
+
+// admitAllocation returns the default network if an instance name can join
+// it. Callers must hold m.mu.
+func (m *manager) admitAllocation(ctx context.Context, instanceName string) (*Network, error) {
+	network, err := m.getDefaultNetwork(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("get default network: %w", err)
+	}
+
+	exists, err := m.NameExists(ctx, instanceName)
+	if err != nil {
+		return nil, fmt.Errorf("check name exists: %w", err)
+	}
+	if exists {
+		return nil, fmt.Errorf("%w: instance name '%s' already exists, can't assign into same network: %s",
+			ErrNameExists, instanceName, network.Name)
+	}
+	return network, nil
+}
+
 // RecreateAllocation recreates TAP for restore from standby
 // Note: No lock needed - this operation:
-// 1. Doesn't allocate new IPs (reuses existing from snapshot)
+// 1. Doesn't allocate new IPs (reuses the instance's reservation)
 // 2. Is already protected by instance-level locking
 // 3. Uses deterministic TAP names that can't conflict
-func (m *manager) RecreateAllocation(ctx context.Context, instanceID string, downloadBps, uploadBps int64) error {
+func (m *manager) RecreateAllocation(ctx context.Context, instanceID string, limits BandwidthLimits) error {
 	log := logger.FromContext(ctx)
 
-	// 1. Derive allocation from snapshot
+	// 1. Derive allocation from the instance's reservation
 	alloc, err := m.deriveAllocation(ctx, instanceID)
 	if err != nil {
 		return fmt.Errorf("derive allocation: %w", err)
@@ -110,19 +181,88 @@ func (m *manager) RecreateAllocation(ctx context.Context, instanceID string, dow
 		return fmt.Errorf("get default network: %w", err)
 	}
 
-	// 3. Recreate TAP device with same name and rate limits from instance metadata
-	uploadCeilBps := uploadBps * int64(m.GetUploadBurstMultiplier())
-	if err := m.createTAPDevice(alloc.TAPDevice, network.Bridge, network.Isolated, downloadBps, uploadBps, uploadCeilBps); err != nil {
-		return fmt.Errorf("create TAP device: %w", err)
+	// 3. Recreate TAP device with same name, rate limits from instance metadata
+	// and the network's MTU
+	dhcpOpts, err := m.loadDHCPOptions(network.Name)
+	if err != nil {
+		return err
+	}
+	if err := m.createGuestDevice(alloc.TAPDevice, alloc.MAC, network, m.uplink(), limits, dhcpOpts.MTU); err != nil {
+		return err
 	}
 	m.recordTAPOperation(ctx, "create")
+	if err := m.filterMetadata(ctx, alloc.TAPDevice, alloc.IP, network.Gateway); err != nil {
+		m.deleteTAPDevice(alloc.TAPDevice)
+		return fmt.Errorf("guard metadata requests: %w", err)
+	}
 
 	log.InfoContext(ctx, "recreated network for restore",
 		"instance_id", instanceID,
 		"network", "default",
 		"tap", alloc.TAPDevice,
-		"download_bps", downloadBps,
-		"upload_bps", uploadBps)
+		"download_bps", limits.DownloadBps,
+		"upload_bps", limits.UploadBps)
+
+	return nil
+}
+
+// UpdateBandwidth changes the traffic shaping of a running instance's TAP device in place.
+// If the instance has no active TAP (stopped or in standby), this is a no-op; the
+// caller persists the limits and they're applied when the TAP is next created.
+func (m *manager) UpdateBandwidth(ctx context.Context, instanceID string, limits BandwidthLimits) error {
+	log := logger.FromContext(ctx)
+
+	alloc, err := m.deriveAllocation(ctx, instanceID)
+	if err != nil {
+		return fmt.Errorf("derive allocation: %w", err)
+	}
+	if alloc == nil || alloc.State != "running" {
+		return nil
+	}
+
+	if m.uplink().Mode == UplinkModeMacvtap {
+		if err := m.applyMacvtapLimits(alloc.TAPDevice, limits); err != nil {
+			return err
+		}
+		log.InfoContext(ctx, "updated network bandwidth",
+			"instance_id", instanceID,
+			"macvtap", alloc.TAPDevice,
+			"download_bps", limits.DownloadBps,
+			"upload_bps", limits.UploadBps)
+		return nil
+	}
+
+	// Download: TBF on TAP egress (replace is idempotent)
+	if limits.DownloadBps > 0 {
+		if err := m.applyDownloadRateLimit(alloc.TAPDevice, limits.DownloadBps, limits.DownloadBurstBytes); err != nil {
+			return fmt.Errorf("apply download rate limit: %w", err)
+		}
+	} else {
+		m.removeRateLimit(alloc.TAPDevice)
+	}
+
+	// Upload: HTB class on bridge
+	bridge := m.config.BridgeName
+	switch {
+	case limits.UploadBps <= 0:
+		m.removeVMClass(bridge, alloc.TAPDevice)
+	case m.hasVMClass(bridge, alloc.TAPDevice):
+		if err := m.changeVMClass(bridge, alloc.TAPDevice, limits.UploadBps, limits.UploadCeilBps, limits.UploadBurstBytes); err != nil {
+			return fmt.Errorf("apply upload rate limit: %w", err)
+		}
+	default:
+		if err := m.addVMClass(bridge, alloc.TAPDevice, limits.UploadBps, limits.UploadCeilBps, limits.UploadBurstBytes); err != nil {
+			return fmt.Errorf("apply upload rate limit: %w", err)
+		}
+	}
+
+	log.InfoContext(ctx, "updated network bandwidth",
+		"instance_id", instanceID,
+		"tap", alloc.TAPDevice,
+		"download_bps", limits.DownloadBps,
+		"download_burst_bytes", limits.DownloadBurstBytes,
+		"upload_bps", limits.UploadBps,
+		"upload_burst_bytes", limits.UploadBurstBytes)
 
 	return nil
 }
@@ -158,8 +298,9 @@ func (m *manager) ReleaseAllocation(ctx context.Context, alloc *Allocation) erro
 }
 
 // allocateNextIP picks a random available IP in the subnet
-// Retries up to 5 times if conflicts occur
-func (m *manager) allocateNextIP(ctx context.Context, subnet string) (string, error) {
+// Retries up to 5 times if conflicts occur. Addresses held by reservations or
+// recorded in instance metadata are taken, except those of instanceID itself.
+func (m *manager) allocateNextIP(ctx context.Context, subnet string, res map[string]reservation, instanceID string) (string, error) {
 	// Parse subnet
 	_, ipNet, err := net.ParseCIDR(subnet)
 	if err != nil {
@@ -175,12 +316,22 @@ func (m *manager) allocateNextIP(ctx context.Context, subnet string) (string, er
 	// Build set of used IPs
 	usedIPs := make(map[string]bool)
 	for _, alloc := range allocations {
-		usedIPs[alloc.IP] = true
+		if alloc.InstanceID != instanceID {
+			usedIPs[alloc.IP] = true
+		}
+	}
+	for id, r := range res {
+		if id != instanceID {
+			usedIPs[r.IP] = true
+		}
 	}
 
 	// Reserve network address and gateway
 	usedIPs[ipNet.IP.String()] = true                 // Network address
 	usedIPs[incrementIP(ipNet.IP, 1).String()] = true // Gateway (network + 1)
+	if gateway := m.gateway(); gateway != "" {
+		usedIPs[gateway] = true // Configured gateway, e.g. a VLAN's router
+	}
 
 	// Calculate broadcast address
 	broadcast := make(net.IP, 4)
@@ -221,7 +372,7 @@ func (m *manager) allocateNextIP(ctx context.Context, subnet string) (string, er
 		}
 	}
 
-	return "", fmt.Errorf("no available IPs in subnet %s after %d random attempts and full scan", subnet, maxRetries)
+	return "", fmt.Errorf("%w in subnet %s after %d random attempts and full scan", ErrNoAvailableIP, subnet, maxRetries)
 }
 
 // incrementIP increments IP address by n
@@ -9,6 +9,7 @@ import (
 	"os/exec"
 	"strings"
 	"syscall"
+	"time"
 
 	"github.com/kernel/hypeman/lib/logger"
 	"github.com/vishvananda/netlink"
@@ -88,7 +89,9 @@ func (m *manager) checkSubnetConflicts(ctx context.Context, subnet string) error
 	return nil
 }
 
-// createBridge creates or verifies a bridge interface using netlink
+// createBridge creates or verifies a bridge interface using netlink. With a
+// vlan or macvtap uplink the bridge gets no gateway address or NAT rules, and
+// what an uplink set up before is undone when it changes.
 func (m *manager) createBridge(ctx context.Context, name, gateway, subnet string) error {
 	log := logger.FromContext(ctx)
 
@@ -98,8 +101,19 @@ func (m *manager) createBridge(ctx context.Context, name, gateway, subnet string
 		return fmt.Errorf("parse subnet: %w", err)
 	}
 
+	// With a routed uplink the bridge carries no address; guests use the
+	// datacenter router
+	uplink := m.uplink()
+
 	// 2. Check if bridge already exists
 	existing, err := netlink.LinkByName(name)
+	if err == nil && uplink.routed() {
+		if err := netlink.LinkSetUp(existing); err != nil {
+			return fmt.Errorf("set bridge up: %w", err)
+		}
+		log.InfoContext(ctx, "bridge ready", "bridge", name, "gateway", gateway, "status", "existing")
+		return m.ensureRoutedUplink(ctx, existing, uplink)
+	}
 	if err == nil {
 		// Bridge exists - verify it has the expected gateway IP
 		addrs, err := netlink.AddrList(existing, netlink.FAMILY_V4)
@@ -117,6 +131,14 @@ func (m *manager) createBridge(ctx context.Context, name, gateway, subnet string
 			}
 		}
 
+		// A bridge without addresses was left by a vlan or macvtap uplink
+		if len(addrs) == 0 {
+			if err := addGatewayAddress(existing, gateway, ipNet); err != nil {
+				return err
+			}
+			hasExpectedIP = true
+		}
+
 		if !hasExpectedIP {
 			ones, _ := ipNet.Mask.Size()
 			return fmt.Errorf("bridge %s exists with IPs %v but expected gateway %s/%d. "+
@@ -130,6 +152,9 @@ func (m *manager) createBridge(ctx context.Context, name, gateway, subnet string
 		if err := netlink.LinkSetUp(existing); err != nil {
 			return fmt.Errorf("set bridge up: %w", err)
 		}
+		if err := m.detachVLANs(ctx, existing, ""); err != nil {
+			return err
+		}
 		log.InfoContext(ctx, "bridge ready", "bridge", name, "gateway", gateway, "status", "existing")
 
 		// Still need to ensure iptables rules are configured
@@ -155,7 +180,28 @@ func (m *manager) createBridge(ctx context.Context, name, gateway, subnet string
 		return fmt.Errorf("set bridge up: %w", err)
 	}
 
+	if uplink.routed() {
+		log.InfoContext(ctx, "bridge ready", "bridge", name, "gateway", gateway, "status", "created")
+		return m.ensureRoutedUplink(ctx, bridge, uplink)
+	}
+
 	// 5. Add gateway IP to bridge
+	if err := addGatewayAddress(bridge, gateway, ipNet); err != nil {
+		return err
+	}
+
+	log.InfoContext(ctx, "bridge ready", "bridge", name, "gateway", gateway, "status", "created")
+
+	// 6. Setup iptables rules
+	if err := m.setupIPTablesRules(ctx, subnet, name); err != nil {
+		return fmt.Errorf("setup iptables: %w", err)
+	}
+
+	return nil
+}
+
+// addGatewayAddress adds the gateway address of the subnet to the bridge.
+func addGatewayAddress(bridge netlink.Link, gateway string, ipNet *net.IPNet) error {
 	gatewayIP := net.ParseIP(gateway)
 	if gatewayIP == nil {
 		return fmt.Errorf("invalid gateway IP: %s", gateway)
@@ -171,14 +217,6 @@ func (m *manager) createBridge(ctx context.Context, name, gateway, subnet string
 	if err := netlink.AddrAdd(bridge, addr); err != nil {
 		return fmt.Errorf("add gateway IP to bridge: %w", err)
 	}
-
-	log.InfoContext(ctx, "bridge ready", "bridge", name, "gateway", gateway, "status", "created")
-
-	// 6. Setup iptables rules
-	if err := m.setupIPTablesRules(ctx, subnet, name); err != nil {
-		return fmt.Errorf("setup iptables: %w", err)
-	}
-
 	return nil
 }
 
@@ -187,6 +225,7 @@ const (
 	commentNAT    = "hypeman-nat"
 	commentFwdOut = "hypeman-fwd-out"
 	commentFwdIn  = "hypeman-fwd-in"
+	commentMeta   = "hypeman-metadata"
 )
 
 // HTB handles for traffic control
@@ -283,7 +322,7 @@ func (m *manager) ensureNATRule(subnet, uplink string) (string, error) {
 	}
 
 	// Delete any existing rule with our comment (handles uplink changes)
-	m.deleteNATRuleByComment(commentNAT)
+	m.deleteNATRuleByComment("POSTROUTING", commentNAT)
 
 	// Add rule with comment
 	addCmd := exec.Command("iptables", "-t", "nat", "-A", "POSTROUTING",
@@ -299,10 +338,10 @@ func (m *manager) ensureNATRule(subnet, uplink string) (string, error) {
 	return "added", nil
 }
 
-// deleteNATRuleByComment deletes any NAT POSTROUTING rule containing our comment
-func (m *manager) deleteNATRuleByComment(comment string) {
-	// List NAT POSTROUTING rules
-	cmd := exec.Command("iptables", "-t", "nat", "-L", "POSTROUTING", "--line-numbers", "-n")
+// deleteNATRuleByComment deletes any rule of a NAT chain containing our comment
+func (m *manager) deleteNATRuleByComment(chain, comment string) {
+	// List the chain's rules
+	cmd := exec.Command("iptables", "-t", "nat", "-L", chain, "--line-numbers", "-n")
 	cmd.SysProcAttr = &syscall.SysProcAttr{
 		AmbientCaps: []uintptr{unix.CAP_NET_ADMIN},
 	}
@@ -325,7 +364,7 @@ func (m *manager) deleteNATRuleByComment(comment string) {
 
 	// Delete in reverse order
 	for i := len(ruleNums) - 1; i >= 0; i-- {
-		delCmd := exec.Command("iptables", "-t", "nat", "-D", "POSTROUTING", ruleNums[i])
+		delCmd := exec.Command("iptables", "-t", "nat", "-D", chain, ruleNums[i])
 		delCmd.SysProcAttr = &syscall.SysProcAttr{
 			AmbientCaps: []uintptr{unix.CAP_NET_ADMIN},
 		}
@@ -424,9 +463,10 @@ func (m *manager) deleteForwardRuleByComment(comment string) {
 }
 
 // createTAPDevice creates TAP device and attaches to bridge.
-// downloadBps: rate limit for download (external→VM), applied as TBF on TAP egress
-// uploadBps/uploadCeilBps: rate limit for upload (VM→external), applied as HTB class on bridge
-func (m *manager) createTAPDevice(tapName, bridgeName string, isolated bool, downloadBps, uploadBps, uploadCeilBps int64) error {
+// Download limits (external→VM) are applied as TBF on TAP egress,
+// upload limits (VM→external) as an HTB class on the bridge.
+// A non-zero mtu is set on the TAP to match the guest's interface.
+func (m *manager) createTAPDevice(tapName, bridgeName string, isolated bool, limits BandwidthLimits, mtu int) error {
 	// 1. Check if TAP already exists
 	if _, err := netlink.LinkByName(tapName); err == nil {
 		// TAP already exists, delete it first
@@ -459,6 +499,12 @@ func (m *manager) createTAPDevice(tapName, bridgeName string, isolated bool, dow
 		return fmt.Errorf("get TAP link: %w", err)
 	}
 
+	if mtu > 0 {
+		if err := netlink.LinkSetMTU(tapLink, mtu); err != nil {
+			return fmt.Errorf("set TAP MTU: %w", err)
+		}
+	}
+
 	if err := netlink.LinkSetUp(tapLink); err != nil {
 		return fmt.Errorf("set TAP up: %w", err)
 	}
@@ -489,15 +535,15 @@ func (m *manager) createTAPDevice(tapName, bridgeName string, isolated bool, dow
 	}
 
 	// 6. Apply download rate limiting (TBF on TAP egress)
-	if downloadBps > 0 {
-		if err := m.applyDownloadRateLimit(tapName, downloadBps); err != nil {
+	if limits.DownloadBps > 0 {
+		if err := m.applyDownloadRateLimit(tapName, limits.DownloadBps, limits.DownloadBurstBytes); err != nil {
 			return fmt.Errorf("apply download rate limit: %w", err)
 		}
 	}
 
 	// 7. Apply upload rate limiting (HTB class on bridge)
-	if uploadBps > 0 {
-		if err := m.addVMClass(bridgeName, tapName, uploadBps, uploadCeilBps); err != nil {
+	if limits.UploadBps > 0 {
+		if err := m.addVMClass(bridgeName, tapName, limits.UploadBps, limits.UploadCeilBps, limits.UploadBurstBytes); err != nil {
 			return fmt.Errorf("apply upload rate limit: %w", err)
 		}
 	}
@@ -506,20 +552,24 @@ func (m *manager) createTAPDevice(tapName, bridgeName string, isolated bool, dow
 }
 
 // applyDownloadRateLimit applies download (external→VM) rate limiting using TBF on TAP egress.
-func (m *manager) applyDownloadRateLimit(tapName string, rateLimitBps int64) error {
+// Replaces any existing TBF, so it's also used to adjust limits at runtime.
+// burstBytes is the bucket size; 0 derives it from the rate and download burst multiplier.
+func (m *manager) applyDownloadRateLimit(tapName string, rateLimitBps, burstBytes int64) error {
 	rateStr := formatTcRate(rateLimitBps)
 
 	// Use Token Bucket Filter (tbf) for download shaping
 	// burst: bucket size = (rate * multiplier) / 250 for HZ=250 kernels
 	// The multiplier allows initial burst before settling to sustained rate.
 	// latency: max time a packet can wait in queue
-	multiplier := m.GetDownloadBurstMultiplier()
-	burstBytes := (rateLimitBps * int64(multiplier)) / 250
+	if burstBytes <= 0 {
+		multiplier := m.GetDownloadBurstMultiplier()
+		burstBytes = (rateLimitBps * int64(multiplier)) / 250
+	}
 	if burstBytes < 1540 {
 		burstBytes = 1540 // Minimum burst for standard MTU
 	}
 
-	cmd := exec.Command("tc", "qdisc", "add", "dev", tapName, "root", "tbf",
+	cmd := exec.Command("tc", "qdisc", "replace", "dev", tapName, "root", "tbf",
 		"rate", rateStr,
 		"burst", fmt.Sprintf("%d", burstBytes),
 		"latency", "50ms")
@@ -528,7 +578,7 @@ func (m *manager) applyDownloadRateLimit(tapName string, rateLimitBps int64) err
 	}
 	output, err := cmd.CombinedOutput()
 	if err != nil {
-		return fmt.Errorf("tc qdisc add tbf: %w (output: %s)", err, string(output))
+		return fmt.Errorf("tc qdisc replace tbf: %w (output: %s)", err, string(output))
 	}
 
 	return nil
@@ -545,6 +595,52 @@ func (m *manager) removeRateLimit(tapName string) error {
 	return nil
 }
 
+// applyIngressPolicer polices the traffic a macvtap device passes to its
+// guest, dropping what exceeds the rate. Unlike a TBF it can't queue, so
+// bursts above the bucket are dropped rather than delayed.
+func (m *manager) applyIngressPolicer(devName string, rateLimitBps, burstBytes int64) error {
+	if burstBytes <= 0 {
+		multiplier := m.GetDownloadBurstMultiplier()
+		burstBytes = (rateLimitBps * int64(multiplier)) / 250
+	}
+	if burstBytes < 1540 {
+		burstBytes = 1540 // Minimum burst for standard MTU
+	}
+
+	cmd := exec.Command("tc", "qdisc", "replace", "dev", devName, "ingress")
+	cmd.SysProcAttr = &syscall.SysProcAttr{
+		AmbientCaps: []uintptr{unix.CAP_NET_ADMIN},
+	}
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("tc qdisc replace ingress: %w (output: %s)", err, string(output))
+	}
+
+	cmd = exec.Command("tc", "filter", "replace", "dev", devName, "parent", "ffff:",
+		"prio", "1", "matchall",
+		"action", "police",
+		"rate", formatTcRate(rateLimitBps),
+		"burst", fmt.Sprintf("%d", burstBytes),
+		"conform-exceed", "drop")
+	cmd.SysProcAttr = &syscall.SysProcAttr{
+		AmbientCaps: []uintptr{unix.CAP_NET_ADMIN},
+	}
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("tc filter replace police: %w (output: %s)", err, string(output))
+	}
+
+	return nil
+}
+
+// removeIngressPolicer removes the policer of applyIngressPolicer.
+func (m *manager) removeIngressPolicer(devName string) {
+	cmd := exec.Command("tc", "qdisc", "del", "dev", devName, "ingress")
+	cmd.SysProcAttr = &syscall.SysProcAttr{
+		AmbientCaps: []uintptr{unix.CAP_NET_ADMIN},
+	}
+	// Ignore errors - qdisc may not exist
+	cmd.Run()
+}
+
 // setupBridgeHTB sets up HTB qdisc on bridge for upload (VM→external) fair sharing.
 // This is one-time setup - per-VM classes are added dynamically via addVMClass.
 func (m *manager) setupBridgeHTB(ctx context.Context, bridgeName string, capacityBps int64) error {
@@ -593,8 +689,9 @@ func (m *manager) setupBridgeHTB(ctx context.Context, bridgeName string, capacit
 }
 
 // addVMClass adds an HTB class for a VM on the bridge for upload rate limiting.
-// Called during TAP device creation. rateBps is guaranteed, ceilBps is burst ceiling.
-func (m *manager) addVMClass(bridgeName, tapName string, rateBps, ceilBps int64) error {
+// Called during TAP device creation. rateBps is guaranteed, ceilBps is burst ceiling,
+// burstBytes is how much may be sent at ceil speed before shaping (0 = tc default).
+func (m *manager) addVMClass(bridgeName, tapName string, rateBps, ceilBps, burstBytes int64) error {
 	if rateBps <= 0 {
 		return nil // No rate limiting configured
 	}
@@ -604,15 +701,9 @@ func (m *manager) addVMClass(bridgeName, tapName string, rateBps, ceilBps int64)
 	classID := deriveClassID(tapName)
 	fullClassID := fmt.Sprintf("1:%s", classID)
 
-	rateStr := formatTcRate(rateBps)
-	if ceilBps <= 0 {
-		ceilBps = rateBps
-	}
-	ceilStr := formatTcRate(ceilBps)
-
 	// 1. Add HTB class for this VM
-	cmd := exec.Command("tc", "class", "add", "dev", bridgeName, "parent", htbRootClassID,
-		"classid", fullClassID, "htb", "rate", rateStr, "ceil", ceilStr, "prio", "1")
+	cmd := exec.Command("tc", append([]string{"class", "add", "dev", bridgeName, "parent", htbRootClassID,
+		"classid", fullClassID}, htbClassParams(rateBps, ceilBps, burstBytes)...)...)
 	cmd.SysProcAttr = &syscall.SysProcAttr{
 		AmbientCaps: []uintptr{unix.CAP_NET_ADMIN},
 	}
@@ -650,6 +741,45 @@ func (m *manager) addVMClass(bridgeName, tapName string, rateBps, ceilBps int64)
 	return nil
 }
 
+// changeVMClass changes the rates of an existing HTB class for a VM.
+func (m *manager) changeVMClass(bridgeName, tapName string, rateBps, ceilBps, burstBytes int64) error {
+	fullClassID := fmt.Sprintf("1:%s", deriveClassID(tapName))
+
+	cmd := exec.Command("tc", append([]string{"class", "change", "dev", bridgeName, "parent", htbRootClassID,
+		"classid", fullClassID}, htbClassParams(rateBps, ceilBps, burstBytes)...)...)
+	cmd.SysProcAttr = &syscall.SysProcAttr{
+		AmbientCaps: []uintptr{unix.CAP_NET_ADMIN},
+	}
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("tc class change vm: %w (output: %s)", err, string(output))
+	}
+	return nil
+}
+
+// hasVMClass reports whether an HTB class exists for a VM on the bridge.
+func (m *manager) hasVMClass(bridgeName, tapName string) bool {
+	fullClassID := fmt.Sprintf("1:%s", deriveClassID(tapName))
+
+	cmd := exec.Command("tc", "class", "show", "dev", bridgeName, "classid", fullClassID)
+	cmd.SysProcAttr = &syscall.SysProcAttr{
+		AmbientCaps: []uintptr{unix.CAP_NET_ADMIN},
+	}
+	output, err := cmd.Output()
+	return err == nil && strings.Contains(string(output), fullClassID)
+}
+
+// htbClassParams returns the "htb rate ... ceil ..." arguments for a VM class.
+func htbClassParams(rateBps, ceilBps, burstBytes int64) []string {
+	if ceilBps <= 0 {
+		ceilBps = rateBps
+	}
+	params := []string{"htb", "rate", formatTcRate(rateBps), "ceil", formatTcRate(ceilBps)}
+	if burstBytes > 0 {
+		params = append(params, "burst", fmt.Sprintf("%d", burstBytes), "cburst", fmt.Sprintf("%d", burstBytes))
+	}
+	return append(params, "prio", "1")
+}
+
 // removeVMClass removes the HTB class for a VM from the bridge.
 func (m *manager) removeVMClass(bridgeName, tapName string) error {
 	classID := deriveClassID(tapName)
@@ -737,6 +867,7 @@ func formatTcRate(bytesPerSec int64) string {
 func (m *manager) deleteTAPDevice(tapName string) error {
 	// Remove HTB class from bridge before deleting TAP
 	m.removeVMClass(m.config.BridgeName, tapName)
+	m.unfilterMetadata(tapName)
 
 	link, err := netlink.LinkByName(tapName)
 	if err != nil {
@@ -762,6 +893,9 @@ func (m *manager) queryNetworkState(bridgeName string) (*Network, error) {
 	if link.Type() != "bridge" {
 		return nil, fmt.Errorf("link %s is not a bridge", bridgeName)
 	}
+	if m.uplink().routed() {
+		return m.routedNetworkState(bridgeName)
+	}
 
 	// Get IP addresses
 	addrs, err := netlink.AddrList(link, netlink.FAMILY_V4)
@@ -787,57 +921,72 @@ func (m *manager) queryNetworkState(bridgeName string) (*Network, error) {
 	}, nil
 }
 
-// CleanupOrphanedTAPs removes TAP devices that aren't used by any running instance.
-// runningInstanceIDs is a list of instance IDs that currently have a running VMM.
-// Pass nil to skip cleanup entirely (used when we couldn't determine running instances).
-// Returns the number of TAPs deleted.
-func (m *manager) CleanupOrphanedTAPs(ctx context.Context, runningInstanceIDs []string) int {
+// CleanupOrphans removes TAP devices that no running instance uses once
+// they've been orphaned for at least grace, then HTB classes without a TAP.
+// A TAP is created for an instance before its metadata is saved, so while
+// instances are being created the grace keeps their TAPs from being taken
+// for orphans. Pass nil runningInstanceIDs to skip TAP cleanup.
+func (m *manager) CleanupOrphans(ctx context.Context, runningInstanceIDs []string, grace time.Duration) (int, int) {
 	log := logger.FromContext(ctx)
 
-	// If nil, skip cleanup entirely to avoid accidentally deleting TAPs for running VMs
+	deleted := 0
 	if runningInstanceIDs == nil {
 		log.DebugContext(ctx, "skipping TAP cleanup (nil instance list)")
-		return 0
+	} else if orphans, err := orphanedTAPs(runningInstanceIDs); err != nil {
+		log.WarnContext(ctx, "failed to list network links for TAP cleanup", "error", err)
+	} else {
+		m.orphanMu.Lock()
+		now := time.Now()
+		since := make(map[string]time.Time, len(orphans))
+		for _, name := range orphans {
+			first, seen := m.orphanSince[name]
+			if !seen {
+				first = now
+			}
+			if now.Sub(first) < grace {
+				since[name] = first
+				continue
+			}
+			if err := m.deleteTAPDevice(name); err != nil {
+				log.WarnContext(ctx, "failed to delete orphaned TAP", "tap", name, "error", err)
+				since[name] = first
+				continue
+			}
+			log.InfoContext(ctx, "deleted orphaned TAP device", "tap", name, "orphaned_for", now.Sub(first).String())
+			deleted++
+		}
+		// TAPs that are gone or back in use are forgotten
+		m.orphanSince = since
+		m.orphanMu.Unlock()
 	}
 
+	return deleted, m.CleanupOrphanedClasses(ctx)
+}
+
+// orphanedTAPs returns the names of hypeman's TAP devices that belong to
+// none of the given instances
+func orphanedTAPs(instanceIDs []string) ([]string, error) {
 	// Build set of expected TAP names for running instances
 	expectedTAPs := make(map[string]bool)
-	for _, id := range runningInstanceIDs {
-		tapName := generateTAPName(id)
-		expectedTAPs[tapName] = true
+	for _, id := range instanceIDs {
+		expectedTAPs[generateTAPName(id)] = true
 	}
 
 	// List all network interfaces
 	links, err := netlink.LinkList()
 	if err != nil {
-		log.WarnContext(ctx, "failed to list network links for TAP cleanup", "error", err)
-		return 0
+		return nil, err
 	}
 
-	deleted := 0
+	var orphans []string
 	for _, link := range links {
 		name := link.Attrs().Name
-
-		// Only consider TAP devices with our naming prefix
-		if !strings.HasPrefix(name, TAPPrefix) {
-			continue
-		}
-
-		// Check if this TAP is expected (belongs to a running instance)
-		if expectedTAPs[name] {
-			continue
-		}
-
-		// Orphaned TAP - delete it
-		if err := m.deleteTAPDevice(name); err != nil {
-			log.WarnContext(ctx, "failed to delete orphaned TAP", "tap", name, "error", err)
-			continue
+		// Only consider TAP devices with our naming prefix that no instance expects
+		if strings.HasPrefix(name, TAPPrefix) && !expectedTAPs[name] {
+			orphans = append(orphans, name)
 		}
-		log.InfoContext(ctx, "deleted orphaned TAP device", "tap", name)
-		deleted++
 	}
-
-	return deleted
+	return orphans, nil
 }
 
 // CleanupOrphanedClasses removes HTB classes on the bridge that don't have matching TAP devices.
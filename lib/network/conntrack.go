@@ -0,0 +1,79 @@
+package network
+
+import (
+	"fmt"
+	"net"
+	"syscall"
+
+	"github.com/vishvananda/netlink"
+)
+
+// flushConntrack removes the connection tracking entries of an instance IP.
+// NAT and forwarding state outlives the instance; if the IP is handed to a new
+// instance, replies to the old connections would be translated or dropped as
+// if they were its own.
+func flushConntrack(ip string) (uint, error) {
+	addr := net.ParseIP(ip)
+	if addr == nil {
+		return 0, fmt.Errorf("invalid IP %q", ip)
+	}
+
+	// Outbound connections have the instance as original source; inbound ones
+	// (forwarded, or DNAT'd by ingress) reply from it.
+	var filters []netlink.CustomConntrackFilter
+	for _, tp := range []netlink.ConntrackFilterType{netlink.ConntrackOrigSrcIP, netlink.ConntrackOrigDstIP, netlink.ConntrackReplyAnyIP} {
+		filter := &netlink.ConntrackFilter{}
+		if err := filter.AddIP(tp, addr); err != nil {
+			return 0, err
+		}
+		filters = append(filters, filter)
+	}
+
+	n, err := netlink.ConntrackDeleteFilters(netlink.ConntrackTable, netlink.FAMILY_V4, filters...)
+	if err != nil {
+		return n, fmt.Errorf("flush conntrack entries of %s: %w", ip, err)
+	}
+	return n, nil
+}
+
+// connectionCounts counts tracked connections per instance IP and protocol.
+func connectionCounts(ips map[string]bool) (map[string]map[string]int64, error) {
+	flows, err := netlink.ConntrackTableList(netlink.ConntrackTable, netlink.FAMILY_V4)
+	if err != nil {
+		return nil, fmt.Errorf("list conntrack entries: %w", err)
+	}
+	return countConnections(flows, ips), nil
+}
+
+// countConnections attributes each flow to the instance IP it originates from
+// or is answered by, so a connection is counted once whichever side opened it.
+func countConnections(flows []*netlink.ConntrackFlow, ips map[string]bool) map[string]map[string]int64 {
+	counts := make(map[string]map[string]int64)
+	for _, flow := range flows {
+		ip := flow.Forward.SrcIP.String()
+		if !ips[ip] {
+			ip = flow.Reverse.SrcIP.String()
+			if !ips[ip] {
+				continue
+			}
+		}
+		if counts[ip] == nil {
+			counts[ip] = make(map[string]int64)
+		}
+		counts[ip][protocolName(flow.Forward.Protocol)]++
+	}
+	return counts
+}
+
+func protocolName(proto uint8) string {
+	switch proto {
+	case syscall.IPPROTO_TCP:
+		return "tcp"
+	case syscall.IPPROTO_UDP:
+		return "udp"
+	case syscall.IPPROTO_ICMP:
+		return "icmp"
+	default:
+		return "other"
+	}
+}
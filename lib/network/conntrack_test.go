@@ -0,0 +1,37 @@
+package network
+
+import (
+	"net"
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vishvananda/netlink"
+)
+
+func flow(proto uint8, origSrc, origDst, replySrc, replyDst string) *netlink.ConntrackFlow {
+	return &netlink.ConntrackFlow{
+		Forward: netlink.IPTuple{Protocol: proto, SrcIP: net.ParseIP(origSrc), DstIP: net.ParseIP(origDst)},
+		Reverse: netlink.IPTuple{Protocol: proto, SrcIP: net.ParseIP(replySrc), DstIP: net.ParseIP(replyDst)},
+	}
+}
+
+func TestCountConnections(t *testing.T) {
+	flows := []*netlink.ConntrackFlow{
+		// Outbound, masqueraded
+		flow(syscall.IPPROTO_TCP, "10.100.0.5", "1.1.1.1", "1.1.1.1", "192.0.2.10"),
+		flow(syscall.IPPROTO_UDP, "10.100.0.5", "1.1.1.1", "1.1.1.1", "192.0.2.10"),
+		// Inbound through ingress (DNAT to the instance)
+		flow(syscall.IPPROTO_TCP, "198.51.100.7", "192.0.2.10", "10.100.0.5", "198.51.100.7"),
+		// Another instance
+		flow(syscall.IPPROTO_ICMP, "10.100.0.6", "8.8.8.8", "8.8.8.8", "192.0.2.10"),
+		// Host traffic
+		flow(syscall.IPPROTO_TCP, "192.0.2.10", "1.1.1.1", "1.1.1.1", "192.0.2.10"),
+	}
+
+	counts := countConnections(flows, map[string]bool{"10.100.0.5": true, "10.100.0.6": true})
+	assert.Equal(t, map[string]map[string]int64{
+		"10.100.0.5": {"tcp": 2, "udp": 1},
+		"10.100.0.6": {"icmp": 1},
+	}, counts)
+}
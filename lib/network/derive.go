@@ -23,6 +23,12 @@ type instanceMetadata struct {
 
 // deriveAllocation derives network allocation from CH or snapshot
 func (m *manager) deriveAllocation(ctx context.Context, instanceID string) (*Allocation, error) {
+	return m.deriveAllocationFrom(ctx, instanceID, m.reservationsOrNil(ctx))
+}
+
+// deriveAllocationFrom derives an instance's allocation, taking its IP, MAC and
+// TAP from res when it holds a reservation for the instance.
+func (m *manager) deriveAllocationFrom(ctx context.Context, instanceID string, res map[string]reservation) (*Allocation, error) {
 	log := logger.FromContext(ctx)
 
 	// 1. Load instance metadata to get instance name and network status
@@ -50,9 +56,13 @@ func (m *manager) deriveAllocation(ctx context.Context, instanceID string) (*All
 	}
 	netmask := fmt.Sprintf("%d.%d.%d.%d", ipNet.Mask[0], ipNet.Mask[1], ipNet.Mask[2], ipNet.Mask[3])
 
-	// 4. Use stored metadata to derive allocation (works for all hypervisors)
-	if meta.IP != "" && meta.MAC != "" {
-		tap := generateTAPName(instanceID)
+	// 4. Prefer the persisted reservation; fall back to stored metadata for
+	// instances without one (works for all hypervisors)
+	ip, mac, tap := meta.IP, meta.MAC, generateTAPName(instanceID)
+	if r, ok := res[instanceID]; ok {
+		ip, mac, tap = r.IP, r.MAC, r.TAPDevice
+	}
+	if ip != "" && mac != "" {
 
 		// Determine state based on socket existence and snapshot
 		socketPath := m.paths.InstanceSocket(instanceID, hypervisor.SocketNameForType(hypervisor.Type(meta.HypervisorType)))
@@ -72,8 +82,8 @@ func (m *manager) deriveAllocation(ctx context.Context, instanceID string) (*All
 			InstanceID:   instanceID,
 			InstanceName: meta.Name,
 			Network:      "default",
-			IP:           meta.IP,
-			MAC:          meta.MAC,
+			IP:           ip,
+			MAC:          mac,
 			TAPDevice:    tap,
 			Gateway:      defaultNet.Gateway,
 			Netmask:      netmask,
@@ -100,12 +110,13 @@ func (m *manager) ListAllocations(ctx context.Context) ([]Allocation, error) {
 		return nil, fmt.Errorf("read guests dir: %w", err)
 	}
 
+	res := m.reservationsOrNil(ctx)
 	var allocations []Allocation
 	for _, guest := range guests {
 		if !guest.IsDir() {
 			continue
 		}
-		alloc, err := m.deriveAllocation(ctx, guest.Name())
+		alloc, err := m.deriveAllocationFrom(ctx, guest.Name(), res)
 		if err == nil && alloc != nil {
 			allocations = append(allocations, *alloc)
 		}
@@ -128,6 +139,18 @@ func (m *manager) NameExists(ctx context.Context, name string) (bool, error) {
 	return false, nil
 }
 
+// reservationsOrNil loads the default network's reservations for deriving
+// allocations. On error it logs and returns nil, so allocations fall back to
+// instance metadata.
+func (m *manager) reservationsOrNil(ctx context.Context) map[string]reservation {
+	res, err := m.loadReservations("default")
+	if err != nil {
+		logger.FromContext(ctx).WarnContext(ctx, "failed to load network reservations, using instance metadata", "error", err)
+		return nil
+	}
+	return res
+}
+
 // loadInstanceMetadata loads minimal instance metadata
 func (m *manager) loadInstanceMetadata(instanceID string) (*instanceMetadata, error) {
 	metaPath := m.paths.InstanceMetadata(instanceID)
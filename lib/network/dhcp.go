@@ -0,0 +1,193 @@
+package network
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+
+	"github.com/kernel/hypeman/lib/logger"
+)
+
+// MTU bounds for DHCPOptions.MTU. 9000 covers jumbo-frame storage networks.
+const (
+	MinMTU = 576
+	MaxMTU = 9000
+)
+
+// MaxNTPServers is the maximum number of NTP servers per network.
+const MaxNTPServers = 4
+
+// MaxRoutes is the maximum number of static routes per network.
+const MaxRoutes = 16
+
+// Route is a static route pushed to guests on a network.
+type Route struct {
+	Destination string `json:"destination"` // IPv4 CIDR, e.g. 10.20.0.0/16
+	Gateway     string `json:"gateway"`     // Next hop, reachable from the network's subnet
+}
+
+// DHCPOptions are the settings handed to guests on a network besides their
+// address and DNS: they're rendered as dnsmasq dhcp-option lines and written
+// into each guest's config disk, so they apply whether or not the image runs
+// a DHCP client. Search domains are part of the network's DNS configuration.
+type DHCPOptions struct {
+	MTU        int      `json:"mtu,omitempty"` // 0 keeps the interface default (1500)
+	NTPServers []string `json:"ntp_servers"`
+	Routes     []Route  `json:"routes"`
+}
+
+// GetDHCPOptions returns the DHCP options of a network.
+func (m *manager) GetDHCPOptions(ctx context.Context, networkName string) (*DHCPOptions, error) {
+	if err := checkNetworkName(networkName); err != nil {
+		return nil, err
+	}
+	return m.loadDHCPOptions(networkName)
+}
+
+// SetDHCPOptions replaces the DHCP options of a network and reloads dnsmasq.
+// Guests pick them up on their next boot; the MTU of an instance's TAP device
+// is set when the device is created.
+func (m *manager) SetDHCPOptions(ctx context.Context, networkName string, opts DHCPOptions) (*DHCPOptions, error) {
+	if err := checkNetworkName(networkName); err != nil {
+		return nil, err
+	}
+	normalized, err := NormalizeDHCPOptions(opts)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidDHCPOptions, err)
+	}
+
+	m.dnsMu.Lock()
+	defer m.dnsMu.Unlock()
+
+	if err := os.MkdirAll(m.paths.NetworkDir(networkName), 0755); err != nil {
+		return nil, fmt.Errorf("create network directory: %w", err)
+	}
+	data, err := json.MarshalIndent(normalized, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("marshal DHCP options: %w", err)
+	}
+	if err := writeFileAtomic(m.paths.NetworkDHCPConfig(networkName), data); err != nil {
+		return nil, fmt.Errorf("write DHCP options: %w", err)
+	}
+
+	dnsConfig, err := m.loadDNSConfig(networkName)
+	if err != nil {
+		return nil, err
+	}
+	if err := m.renderDnsmasqConfig(networkName, dnsConfig); err != nil {
+		return nil, err
+	}
+	m.reloadDnsmasq(ctx)
+
+	logger.FromContext(ctx).InfoContext(ctx, "set DHCP options", "network", networkName,
+		"mtu", normalized.MTU, "ntp_servers", normalized.NTPServers, "routes", len(normalized.Routes))
+	return normalized, nil
+}
+
+// NormalizeDHCPOptions validates opts and returns them with addresses in
+// canonical form, as SetDHCPOptions stores them.
+func NormalizeDHCPOptions(opts DHCPOptions) (*DHCPOptions, error) {
+	out := &DHCPOptions{MTU: opts.MTU, NTPServers: []string{}, Routes: []Route{}}
+	if opts.MTU != 0 && (opts.MTU < MinMTU || opts.MTU > MaxMTU) {
+		return nil, fmt.Errorf("mtu must be between %d and %d, got %d", MinMTU, MaxMTU, opts.MTU)
+	}
+
+	if len(opts.NTPServers) > MaxNTPServers {
+		return nil, fmt.Errorf("at most %d NTP servers are supported, got %d", MaxNTPServers, len(opts.NTPServers))
+	}
+	for _, server := range opts.NTPServers {
+		// DHCP option 42 carries addresses, not hostnames
+		ip := net.ParseIP(strings.TrimSpace(server)).To4()
+		if ip == nil {
+			return nil, fmt.Errorf("NTP server %q is not an IPv4 address", server)
+		}
+		out.NTPServers = append(out.NTPServers, ip.String())
+	}
+
+	if len(opts.Routes) > MaxRoutes {
+		return nil, fmt.Errorf("at most %d routes are supported, got %d", MaxRoutes, len(opts.Routes))
+	}
+	seen := make(map[string]bool, len(opts.Routes))
+	for _, r := range opts.Routes {
+		_, dst, err := net.ParseCIDR(strings.TrimSpace(r.Destination))
+		if err != nil || dst.IP.To4() == nil {
+			return nil, fmt.Errorf("route destination %q is not an IPv4 CIDR", r.Destination)
+		}
+		if ones, _ := dst.Mask.Size(); ones == 0 {
+			return nil, fmt.Errorf("route destination %q replaces the default route", r.Destination)
+		}
+		gw := net.ParseIP(strings.TrimSpace(r.Gateway)).To4()
+		if gw == nil {
+			return nil, fmt.Errorf("route gateway %q is not an IPv4 address", r.Gateway)
+		}
+		if seen[dst.String()] {
+			return nil, fmt.Errorf("duplicate route to %s", dst)
+		}
+		seen[dst.String()] = true
+		out.Routes = append(out.Routes, Route{Destination: dst.String(), Gateway: gw.String()})
+	}
+	return out, nil
+}
+
+// loadDHCPOptions reads a network's DHCP options, returning empty ones if none are stored.
+func (m *manager) loadDHCPOptions(networkName string) (*DHCPOptions, error) {
+	opts := &DHCPOptions{NTPServers: []string{}, Routes: []Route{}}
+
+	data, err := os.ReadFile(m.paths.NetworkDHCPConfig(networkName))
+	if os.IsNotExist(err) {
+		return opts, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read DHCP options: %w", err)
+	}
+	if err := json.Unmarshal(data, opts); err != nil {
+		return nil, fmt.Errorf("unmarshal DHCP options: %w", err)
+	}
+	if opts.NTPServers == nil {
+		opts.NTPServers = []string{}
+	}
+	if opts.Routes == nil {
+		opts.Routes = []Route{}
+	}
+	return opts, nil
+}
+
+// dhcpOptionLines renders DHCP options as dnsmasq config lines. Clients that
+// accept classless static routes ignore the router option (RFC 3442), so the
+// default route via gateway is sent along with them.
+func dhcpOptionLines(opts *DHCPOptions, gateway string) string {
+	var conf strings.Builder
+	if opts.MTU > 0 {
+		fmt.Fprintf(&conf, "dhcp-option=option:mtu,%d\n", opts.MTU)
+	}
+	if len(opts.NTPServers) > 0 {
+		fmt.Fprintf(&conf, "dhcp-option=option:ntp-server,%s\n", strings.Join(opts.NTPServers, ","))
+	}
+	if len(opts.Routes) > 0 {
+		pairs := make([]string, len(opts.Routes))
+		for i, r := range opts.Routes {
+			pairs[i] = r.Destination + "," + r.Gateway
+		}
+		if gateway != "" {
+			pairs = append(pairs, "0.0.0.0/0,"+gateway)
+		}
+		fmt.Fprintf(&conf, "dhcp-option=option:classless-static-route,%s\n", strings.Join(pairs, ","))
+	}
+	return conf.String()
+}
+
+// gateway returns the default network's gateway address from configuration,
+// or "" if it can't be determined.
+func (m *manager) gateway() string {
+	if m.config.SubnetGateway != "" {
+		return m.config.SubnetGateway
+	}
+	gateway, err := DeriveGateway(m.config.SubnetCIDR)
+	if err != nil {
+		return ""
+	}
+	return gateway
+}
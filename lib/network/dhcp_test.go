@@ -0,0 +1,87 @@
+package network
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSetDHCPOptions(t *testing.T) {
+	m := newDNSTestManager(t)
+	m.config.SubnetCIDR = "10.100.0.0/16"
+	ctx := context.Background()
+
+	opts, err := m.GetDHCPOptions(ctx, "default")
+	require.NoError(t, err)
+	assert.Zero(t, opts.MTU)
+	assert.Empty(t, opts.NTPServers)
+
+	opts, err = m.SetDHCPOptions(ctx, "default", DHCPOptions{
+		MTU:        9000,
+		NTPServers: []string{" 10.100.0.1"},
+		Routes:     []Route{{Destination: "10.20.1.0/16", Gateway: "10.100.0.254"}},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 9000, opts.MTU)
+	assert.Equal(t, []string{"10.100.0.1"}, opts.NTPServers)
+	assert.Equal(t, []Route{{Destination: "10.20.0.0/16", Gateway: "10.100.0.254"}}, opts.Routes)
+
+	conf, err := os.ReadFile(m.paths.NetworkDnsmasqConfig("default"))
+	require.NoError(t, err)
+	assert.Contains(t, string(conf), "dhcp-option=option:mtu,9000\n")
+	assert.Contains(t, string(conf), "dhcp-option=option:ntp-server,10.100.0.1\n")
+	assert.Contains(t, string(conf), "dhcp-option=option:classless-static-route,10.20.0.0/16,10.100.0.254,0.0.0.0/0,10.100.0.1\n")
+
+	// Options persist and survive unrelated DNS changes
+	_, err = m.SetSearchDomains(ctx, "default", []string{"svc.internal"})
+	require.NoError(t, err)
+	conf, err = os.ReadFile(m.paths.NetworkDnsmasqConfig("default"))
+	require.NoError(t, err)
+	assert.Contains(t, string(conf), "dhcp-option=option:mtu,9000\n")
+	loaded, err := m.GetDHCPOptions(ctx, "default")
+	require.NoError(t, err)
+	assert.Equal(t, opts, loaded)
+
+	// Clearing the options drops the lines
+	_, err = m.SetDHCPOptions(ctx, "default", DHCPOptions{})
+	require.NoError(t, err)
+	conf, err = os.ReadFile(m.paths.NetworkDnsmasqConfig("default"))
+	require.NoError(t, err)
+	assert.NotContains(t, string(conf), "option:mtu")
+	assert.NotContains(t, string(conf), "classless-static-route")
+
+	_, err = m.SetDHCPOptions(ctx, "internal", DHCPOptions{})
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestSetDHCPOptions_Validation(t *testing.T) {
+	m := newDNSTestManager(t)
+	ctx := context.Background()
+
+	tests := []struct {
+		name string
+		opts DHCPOptions
+	}{
+		{"MTU too small", DHCPOptions{MTU: 100}},
+		{"MTU too large", DHCPOptions{MTU: 9216}},
+		{"NTP hostname", DHCPOptions{NTPServers: []string{"pool.ntp.org"}}},
+		{"NTP IPv6", DHCPOptions{NTPServers: []string{"fd00::1"}}},
+		{"too many NTP servers", DHCPOptions{NTPServers: []string{"10.0.0.1", "10.0.0.2", "10.0.0.3", "10.0.0.4", "10.0.0.5"}}},
+		{"bad destination", DHCPOptions{Routes: []Route{{Destination: "10.20.0.0", Gateway: "10.100.0.254"}}}},
+		{"default route", DHCPOptions{Routes: []Route{{Destination: "0.0.0.0/0", Gateway: "10.100.0.254"}}}},
+		{"bad gateway", DHCPOptions{Routes: []Route{{Destination: "10.20.0.0/16", Gateway: "gw"}}}},
+		{"duplicate route", DHCPOptions{Routes: []Route{
+			{Destination: "10.20.0.0/16", Gateway: "10.100.0.254"},
+			{Destination: "10.20.0.1/16", Gateway: "10.100.0.253"},
+		}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := m.SetDHCPOptions(ctx, "default", tt.opts)
+			assert.ErrorIs(t, err, ErrInvalidDHCPOptions)
+		})
+	}
+}
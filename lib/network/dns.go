@@ -0,0 +1,411 @@
+package network
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/kernel/hypeman/lib/logger"
+)
+
+// MaxSearchDomains is the maximum number of search domains per network.
+// glibc's resolver ignores search domains beyond the sixth.
+const MaxSearchDomains = 6
+
+// DefaultDNSDomain is the domain instance names are served under until a
+// network sets its own: <instance>.<network>.hypeman
+const DefaultDNSDomain = "hypeman"
+
+// hostnameLabelPattern matches a single RFC 1123 hostname label.
+var hostnameLabelPattern = regexp.MustCompile(`^[a-z0-9]([a-z0-9-]{0,61}[a-z0-9])?$`)
+
+// DNSRecord is a static name→IP record served to guests on a network.
+type DNSRecord struct {
+	Name string `json:"name"`
+	IP   string `json:"ip"`
+}
+
+// DNSConfig is the custom DNS configuration for a network.
+type DNSConfig struct {
+	Records       []DNSRecord `json:"records"`
+	SearchDomains []string    `json:"search_domains"`
+	Domain        string      `json:"domain,omitempty"` // Instance names are served as <instance>.<network>.<domain>
+}
+
+// ServiceDomain returns the zone a network's instances are served in, e.g.
+// "default.hypeman"
+func (c *DNSConfig) ServiceDomain(networkName string) string {
+	return networkName + "." + c.Domain
+}
+
+// GetDNSConfig returns the custom DNS records and search domains for a network.
+func (m *manager) GetDNSConfig(ctx context.Context, networkName string) (*DNSConfig, error) {
+	if err := checkNetworkName(networkName); err != nil {
+		return nil, err
+	}
+	return m.loadDNSConfig(networkName)
+}
+
+// AddDNSRecord adds a static DNS record to a network and reloads dnsmasq.
+func (m *manager) AddDNSRecord(ctx context.Context, networkName string, record DNSRecord) (*DNSRecord, error) {
+	if err := checkNetworkName(networkName); err != nil {
+		return nil, err
+	}
+
+	name, err := normalizeHostname(record.Name)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidDNSRecord, err)
+	}
+	ip := net.ParseIP(record.IP)
+	if ip == nil {
+		return nil, fmt.Errorf("%w: invalid IP address %q", ErrInvalidDNSRecord, record.IP)
+	}
+	record = DNSRecord{Name: name, IP: ip.String()}
+
+	m.dnsMu.Lock()
+	defer m.dnsMu.Unlock()
+
+	cfg, err := m.loadDNSConfig(networkName)
+	if err != nil {
+		return nil, err
+	}
+	for _, existing := range cfg.Records {
+		if existing.Name == record.Name {
+			return nil, fmt.Errorf("%w: %s", ErrDNSRecordExists, record.Name)
+		}
+	}
+	cfg.Records = append(cfg.Records, record)
+
+	if err := m.saveDNSConfig(ctx, networkName, cfg); err != nil {
+		return nil, err
+	}
+	logger.FromContext(ctx).InfoContext(ctx, "added DNS record", "network", networkName, "name", record.Name, "ip", record.IP)
+	return &record, nil
+}
+
+// DeleteDNSRecord removes a static DNS record from a network and reloads dnsmasq.
+func (m *manager) DeleteDNSRecord(ctx context.Context, networkName, name string) error {
+	if err := checkNetworkName(networkName); err != nil {
+		return err
+	}
+	name = strings.TrimSuffix(strings.ToLower(name), ".")
+
+	m.dnsMu.Lock()
+	defer m.dnsMu.Unlock()
+
+	cfg, err := m.loadDNSConfig(networkName)
+	if err != nil {
+		return err
+	}
+	idx := slices.IndexFunc(cfg.Records, func(r DNSRecord) bool { return r.Name == name })
+	if idx < 0 {
+		return fmt.Errorf("%w: %s", ErrDNSRecordNotFound, name)
+	}
+	cfg.Records = slices.Delete(cfg.Records, idx, idx+1)
+
+	if err := m.saveDNSConfig(ctx, networkName, cfg); err != nil {
+		return err
+	}
+	logger.FromContext(ctx).InfoContext(ctx, "deleted DNS record", "network", networkName, "name", name)
+	return nil
+}
+
+// SetSearchDomains replaces the search domains for a network.
+// Guests pick up the new list on their next boot.
+func (m *manager) SetSearchDomains(ctx context.Context, networkName string, domains []string) (*DNSConfig, error) {
+	if err := checkNetworkName(networkName); err != nil {
+		return nil, err
+	}
+
+	normalized := make([]string, 0, len(domains))
+	for _, domain := range domains {
+		d, err := normalizeHostname(domain)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidSearchDomain, err)
+		}
+		if !slices.Contains(normalized, d) {
+			normalized = append(normalized, d)
+		}
+	}
+	if len(normalized) > MaxSearchDomains {
+		return nil, fmt.Errorf("%w: at most %d search domains are supported, got %d",
+			ErrInvalidSearchDomain, MaxSearchDomains, len(normalized))
+	}
+
+	m.dnsMu.Lock()
+	defer m.dnsMu.Unlock()
+
+	cfg, err := m.loadDNSConfig(networkName)
+	if err != nil {
+		return nil, err
+	}
+	cfg.SearchDomains = normalized
+
+	if err := m.saveDNSConfig(ctx, networkName, cfg); err != nil {
+		return nil, err
+	}
+	logger.FromContext(ctx).InfoContext(ctx, "set search domains", "network", networkName, "search_domains", normalized)
+	return cfg, nil
+}
+
+// SetDNSDomain replaces the domain a network's instances are served under.
+// Records are served under the new domain right away; guests search it from
+// their next boot.
+func (m *manager) SetDNSDomain(ctx context.Context, networkName, domain string) (*DNSConfig, error) {
+	if err := checkNetworkName(networkName); err != nil {
+		return nil, err
+	}
+	domain, err := normalizeHostname(domain)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidDNSDomain, err)
+	}
+
+	m.dnsMu.Lock()
+	defer m.dnsMu.Unlock()
+
+	cfg, err := m.loadDNSConfig(networkName)
+	if err != nil {
+		return nil, err
+	}
+	cfg.Domain = domain
+
+	if err := m.saveDNSConfig(ctx, networkName, cfg); err != nil {
+		return nil, err
+	}
+	logger.FromContext(ctx).InfoContext(ctx, "set DNS domain", "network", networkName, "domain", domain)
+	return cfg, nil
+}
+
+// checkNetworkName returns ErrNotFound for anything but the default network.
+func checkNetworkName(name string) error {
+	if name != "default" {
+		return fmt.Errorf("%w: %s (only the default network exists)", ErrNotFound, name)
+	}
+	return nil
+}
+
+// normalizeHostname lowercases name, strips a trailing dot and validates it
+// as an RFC 1123 hostname.
+func normalizeHostname(name string) (string, error) {
+	name = strings.TrimSuffix(strings.ToLower(strings.TrimSpace(name)), ".")
+	if name == "" {
+		return "", fmt.Errorf("name is empty")
+	}
+	if len(name) > 253 {
+		return "", fmt.Errorf("name %q is longer than 253 characters", name)
+	}
+	for _, label := range strings.Split(name, ".") {
+		if !hostnameLabelPattern.MatchString(label) {
+			return "", fmt.Errorf("invalid hostname %q", name)
+		}
+	}
+	return name, nil
+}
+
+// loadDNSConfig reads a network's DNS configuration, returning an empty one if none is stored.
+// Records are sorted by name.
+func (m *manager) loadDNSConfig(networkName string) (*DNSConfig, error) {
+	cfg := &DNSConfig{Records: []DNSRecord{}, SearchDomains: []string{}, Domain: DefaultDNSDomain}
+
+	data, err := os.ReadFile(m.paths.NetworkDNSConfig(networkName))
+	if os.IsNotExist(err) {
+		return cfg, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read DNS config: %w", err)
+	}
+	if err := json.Unmarshal(data, cfg); err != nil {
+		return nil, fmt.Errorf("unmarshal DNS config: %w", err)
+	}
+	if cfg.Records == nil {
+		cfg.Records = []DNSRecord{}
+	}
+	if cfg.SearchDomains == nil {
+		cfg.SearchDomains = []string{}
+	}
+	if cfg.Domain == "" {
+		cfg.Domain = DefaultDNSDomain
+	}
+	slices.SortFunc(cfg.Records, func(a, b DNSRecord) int { return strings.Compare(a.Name, b.Name) })
+	return cfg, nil
+}
+
+// saveDNSConfig persists a network's DNS configuration, re-renders the dnsmasq
+// files and signals dnsmasq to reload them.
+func (m *manager) saveDNSConfig(ctx context.Context, networkName string, cfg *DNSConfig) error {
+	slices.SortFunc(cfg.Records, func(a, b DNSRecord) int { return strings.Compare(a.Name, b.Name) })
+
+	if err := os.MkdirAll(m.paths.NetworkDir(networkName), 0755); err != nil {
+		return fmt.Errorf("create network directory: %w", err)
+	}
+	data, err := json.MarshalIndent(cfg, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshal DNS config: %w", err)
+	}
+	if err := writeFileAtomic(m.paths.NetworkDNSConfig(networkName), data); err != nil {
+		return fmt.Errorf("write DNS config: %w", err)
+	}
+
+	if err := m.renderDnsmasqConfig(networkName, cfg); err != nil {
+		return err
+	}
+	m.reloadDnsmasq(ctx)
+	return nil
+}
+
+// RefreshInstanceDNS re-renders the dnsmasq hosts file so it serves the
+// current instance names, and signals dnsmasq to reload it.
+func (m *manager) RefreshInstanceDNS(ctx context.Context) error {
+	m.dnsMu.Lock()
+	defer m.dnsMu.Unlock()
+
+	cfg, err := m.loadDNSConfig("default")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(m.paths.NetworkDir("default"), 0755); err != nil {
+		return fmt.Errorf("create network directory: %w", err)
+	}
+	if err := m.renderDnsmasqConfig("default", cfg); err != nil {
+		return err
+	}
+	m.reloadDnsmasq(ctx)
+	return nil
+}
+
+// instanceRecords returns a record for each networked instance, named after
+// the instance. Instances are read from metadata rather than derived
+// allocations, so records don't depend on the bridge being up.
+func (m *manager) instanceRecords() []DNSRecord {
+	guests, err := os.ReadDir(m.paths.GuestsDir())
+	if err != nil {
+		return nil
+	}
+	var records []DNSRecord
+	for _, guest := range guests {
+		if !guest.IsDir() {
+			continue
+		}
+		meta, err := m.loadInstanceMetadata(guest.Name())
+		if err != nil || !meta.NetworkEnabled || meta.IP == "" || meta.Name == "" {
+			continue
+		}
+		records = append(records, DNSRecord{Name: meta.Name, IP: meta.IP})
+	}
+	slices.SortFunc(records, func(a, b DNSRecord) int { return strings.Compare(a.Name, b.Name) })
+	return records
+}
+
+// renderDnsmasqConfig writes the dnsmasq config snippet and addn-hosts file
+// for a network. Operators include the snippet from their dnsmasq config
+// (conf-file=...); records live in the hosts file so a SIGHUP reloads them.
+// Instance names are served alongside the custom records, which take
+// precedence over an instance of the same name, both bare and qualified
+// with the network's service domain.
+func (m *manager) renderDnsmasqConfig(networkName string, cfg *DNSConfig) error {
+	header := fmt.Sprintf("# Generated by hypeman for network %q. Do not edit.\n", networkName)
+	hostsPath := m.paths.NetworkDnsmasqHosts(networkName)
+	zone := cfg.ServiceDomain(networkName)
+
+	var hosts strings.Builder
+	hosts.WriteString(header)
+	for _, r := range cfg.Records {
+		fmt.Fprintf(&hosts, "%s %s\n", r.IP, r.Name)
+	}
+	for _, r := range m.instanceRecords() {
+		fqdn := r.Name + "." + zone
+		if slices.ContainsFunc(cfg.Records, func(c DNSRecord) bool { return c.Name == r.Name || c.Name == fqdn }) {
+			continue
+		}
+		fmt.Fprintf(&hosts, "%s %s %s\n", r.IP, fqdn, r.Name)
+	}
+	if err := writeFileAtomic(hostsPath, []byte(hosts.String())); err != nil {
+		return fmt.Errorf("write dnsmasq hosts: %w", err)
+	}
+
+	var conf strings.Builder
+	conf.WriteString(header)
+	fmt.Fprintf(&conf, "addn-hosts=%s\n", hostsPath)
+	// Answer for the service domain alone, so unknown instance names fail
+	// fast instead of being forwarded upstream
+	fmt.Fprintf(&conf, "local=/%s/\n", zone)
+	if ttl, err := time.ParseDuration(m.config.DnsRecordTTL); err == nil && ttl >= time.Second {
+		// Short, so a renamed or deleted instance's name doesn't linger in caches
+		fmt.Fprintf(&conf, "local-ttl=%d\n", int(ttl.Seconds()))
+	}
+	if len(cfg.SearchDomains) > 0 {
+		fmt.Fprintf(&conf, "dhcp-option=option:domain-search,%s\n", strings.Join(cfg.SearchDomains, ","))
+	}
+	dhcpOpts, err := m.loadDHCPOptions(networkName)
+	if err != nil {
+		return err
+	}
+	conf.WriteString(dhcpOptionLines(dhcpOpts, m.gateway()))
+	if err := writeFileAtomic(m.paths.NetworkDnsmasqConfig(networkName), []byte(conf.String())); err != nil {
+		return fmt.Errorf("write dnsmasq config: %w", err)
+	}
+	return nil
+}
+
+// reloadDnsmasq sends SIGHUP to dnsmasq if DNSMASQ_PID_FILE is configured.
+// Failures are logged: the configuration is already persisted and will be
+// picked up on the next reload.
+func (m *manager) reloadDnsmasq(ctx context.Context) {
+	if m.config.DnsmasqPIDFile == "" {
+		return
+	}
+	log := logger.FromContext(ctx)
+
+	pid, err := m.dnsmasqPID()
+	if err != nil {
+		log.WarnContext(ctx, "failed to find dnsmasq", "error", err)
+		return
+	}
+	if err := syscall.Kill(pid, syscall.SIGHUP); err != nil {
+		log.WarnContext(ctx, "failed to reload dnsmasq", "pid", pid, "error", err)
+	}
+}
+
+// dnsmasqPID reads dnsmasq's PID from DNSMASQ_PID_FILE
+func (m *manager) dnsmasqPID() (int, error) {
+	data, err := os.ReadFile(m.config.DnsmasqPIDFile)
+	if err != nil {
+		return 0, fmt.Errorf("read dnsmasq PID file: %w", err)
+	}
+	pid, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil || pid <= 0 {
+		return 0, fmt.Errorf("invalid dnsmasq PID file %s", m.config.DnsmasqPIDFile)
+	}
+	return pid, nil
+}
+
+// writeFileAtomic writes data to a temporary file and renames it into place,
+// so readers (dnsmasq) never see a partially written file.
+func writeFileAtomic(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
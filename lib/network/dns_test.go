@@ -0,0 +1,212 @@
+package network
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/kernel/hypeman/cmd/api/config"
+	"github.com/kernel/hypeman/lib/paths"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newDNSTestManager(t *testing.T) *manager {
+	return &manager{
+		paths:  paths.New(t.TempDir()),
+		config: &config.Config{},
+	}
+}
+
+func TestDNSRecords(t *testing.T) {
+	m := newDNSTestManager(t)
+	ctx := context.Background()
+
+	cfg, err := m.GetDNSConfig(ctx, "default")
+	require.NoError(t, err)
+	assert.Empty(t, cfg.Records)
+	assert.Empty(t, cfg.SearchDomains)
+
+	rec, err := m.AddDNSRecord(ctx, "default", DNSRecord{Name: "DB.svc.internal.", IP: "10.100.5.42"})
+	require.NoError(t, err)
+	assert.Equal(t, DNSRecord{Name: "db.svc.internal", IP: "10.100.5.42"}, *rec)
+
+	_, err = m.AddDNSRecord(ctx, "default", DNSRecord{Name: "cache.svc.internal", IP: "fd00::10"})
+	require.NoError(t, err)
+
+	_, err = m.AddDNSRecord(ctx, "default", DNSRecord{Name: "db.svc.internal", IP: "10.100.5.43"})
+	assert.ErrorIs(t, err, ErrDNSRecordExists)
+
+	cfg, err = m.GetDNSConfig(ctx, "default")
+	require.NoError(t, err)
+	assert.Equal(t, []DNSRecord{
+		{Name: "cache.svc.internal", IP: "fd00::10"},
+		{Name: "db.svc.internal", IP: "10.100.5.42"},
+	}, cfg.Records)
+
+	hosts, err := os.ReadFile(m.paths.NetworkDnsmasqHosts("default"))
+	require.NoError(t, err)
+	assert.Contains(t, string(hosts), "fd00::10 cache.svc.internal\n10.100.5.42 db.svc.internal\n")
+
+	require.NoError(t, m.DeleteDNSRecord(ctx, "default", "cache.svc.internal"))
+	assert.ErrorIs(t, m.DeleteDNSRecord(ctx, "default", "cache.svc.internal"), ErrDNSRecordNotFound)
+
+	cfg, err = m.GetDNSConfig(ctx, "default")
+	require.NoError(t, err)
+	assert.Len(t, cfg.Records, 1)
+}
+
+func TestDNSRecords_Validation(t *testing.T) {
+	m := newDNSTestManager(t)
+	ctx := context.Background()
+
+	tests := []struct {
+		name   string
+		record DNSRecord
+	}{
+		{"empty name", DNSRecord{Name: "", IP: "10.0.0.1"}},
+		{"underscore", DNSRecord{Name: "my_svc.internal", IP: "10.0.0.1"}},
+		{"leading hyphen", DNSRecord{Name: "-svc.internal", IP: "10.0.0.1"}},
+		{"empty label", DNSRecord{Name: "svc..internal", IP: "10.0.0.1"}},
+		{"invalid IP", DNSRecord{Name: "svc.internal", IP: "10.0.0.256"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := m.AddDNSRecord(ctx, "default", tt.record)
+			assert.ErrorIs(t, err, ErrInvalidDNSRecord)
+		})
+	}
+
+	_, err := m.AddDNSRecord(ctx, "internal", DNSRecord{Name: "svc.internal", IP: "10.0.0.1"})
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestRefreshInstanceDNS(t *testing.T) {
+	m := newDNSTestManager(t)
+	m.config.DnsRecordTTL = "5s"
+	ctx := context.Background()
+
+	writeMeta := func(id, body string) {
+		require.NoError(t, os.MkdirAll(m.paths.InstanceDir(id), 0755))
+		require.NoError(t, os.WriteFile(m.paths.InstanceMetadata(id), []byte(body), 0644))
+	}
+	writeMeta("inst-a", `{"Name":"web","NetworkEnabled":true,"IP":"10.100.0.2"}`)
+	writeMeta("inst-b", `{"Name":"db","NetworkEnabled":true,"IP":"10.100.0.3"}`)
+	writeMeta("inst-c", `{"Name":"offline","NetworkEnabled":false}`)
+
+	// A custom record shadows the instance of the same name
+	_, err := m.AddDNSRecord(ctx, "default", DNSRecord{Name: "db", IP: "10.200.0.1"})
+	require.NoError(t, err)
+
+	hosts, err := os.ReadFile(m.paths.NetworkDnsmasqHosts("default"))
+	require.NoError(t, err)
+	assert.Contains(t, string(hosts), "10.200.0.1 db\n10.100.0.2 web.default.hypeman web\n")
+	assert.NotContains(t, string(hosts), "10.100.0.3")
+	assert.NotContains(t, string(hosts), "offline")
+
+	conf, err := os.ReadFile(m.paths.NetworkDnsmasqConfig("default"))
+	require.NoError(t, err)
+	assert.Contains(t, string(conf), "local-ttl=5\n")
+	assert.Contains(t, string(conf), "local=/default.hypeman/\n")
+
+	// Deleted instances drop out on refresh
+	require.NoError(t, os.RemoveAll(m.paths.InstanceDir("inst-a")))
+	require.NoError(t, m.RefreshInstanceDNS(ctx))
+	hosts, err = os.ReadFile(m.paths.NetworkDnsmasqHosts("default"))
+	require.NoError(t, err)
+	assert.NotContains(t, string(hosts), "web")
+}
+
+func TestSetSearchDomains(t *testing.T) {
+	m := newDNSTestManager(t)
+	ctx := context.Background()
+
+	cfg, err := m.SetSearchDomains(ctx, "default", []string{"svc.internal", "Corp.Example.com", "svc.internal"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"svc.internal", "corp.example.com"}, cfg.SearchDomains)
+
+	conf, err := os.ReadFile(m.paths.NetworkDnsmasqConfig("default"))
+	require.NoError(t, err)
+	assert.Contains(t, string(conf), "addn-hosts="+m.paths.NetworkDnsmasqHosts("default")+"\n")
+	assert.Contains(t, string(conf), "dhcp-option=option:domain-search,svc.internal,corp.example.com\n")
+
+	_, err = m.SetSearchDomains(ctx, "default", []string{"a", "b", "c", "d", "e", "f", "g"})
+	assert.ErrorIs(t, err, ErrInvalidSearchDomain)
+	_, err = m.SetSearchDomains(ctx, "default", []string{"bad domain"})
+	assert.ErrorIs(t, err, ErrInvalidSearchDomain)
+
+	// Clearing the list
+	cfg, err = m.SetSearchDomains(ctx, "default", nil)
+	require.NoError(t, err)
+	assert.Empty(t, cfg.SearchDomains)
+}
+
+func TestSetDNSDomain(t *testing.T) {
+	m := newDNSTestManager(t)
+	ctx := context.Background()
+
+	cfg, err := m.GetDNSConfig(ctx, "default")
+	require.NoError(t, err)
+	assert.Equal(t, "default.hypeman", cfg.ServiceDomain("default"))
+
+	require.NoError(t, os.MkdirAll(m.paths.InstanceDir("inst-a"), 0755))
+	require.NoError(t, os.WriteFile(m.paths.InstanceMetadata("inst-a"),
+		[]byte(`{"Name":"web","NetworkEnabled":true,"IP":"10.100.0.2"}`), 0644))
+
+	cfg, err = m.SetDNSDomain(ctx, "default", "Svc.Example.")
+	require.NoError(t, err)
+	assert.Equal(t, "svc.example", cfg.Domain)
+
+	hosts, err := os.ReadFile(m.paths.NetworkDnsmasqHosts("default"))
+	require.NoError(t, err)
+	assert.Contains(t, string(hosts), "10.100.0.2 web.default.svc.example web\n")
+	conf, err := os.ReadFile(m.paths.NetworkDnsmasqConfig("default"))
+	require.NoError(t, err)
+	assert.Contains(t, string(conf), "local=/default.svc.example/\n")
+
+	// Kept with the rest of the network's DNS configuration
+	cfg, err = m.GetDNSConfig(ctx, "default")
+	require.NoError(t, err)
+	assert.Equal(t, "svc.example", cfg.Domain)
+
+	_, err = m.SetDNSDomain(ctx, "default", "bad domain")
+	assert.ErrorIs(t, err, ErrInvalidDNSDomain)
+	_, err = m.SetDNSDomain(ctx, "default", "")
+	assert.ErrorIs(t, err, ErrInvalidDNSDomain)
+	_, err = m.SetDNSDomain(ctx, "internal", "svc.example")
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestReloadDnsmasq(t *testing.T) {
+	m := newDNSTestManager(t)
+	ctx := context.Background()
+
+	// Stand-in for dnsmasq: SIGHUP terminates it, which proves the signal was sent
+	proc := exec.Command("sleep", "60")
+	require.NoError(t, proc.Start())
+	exited := make(chan error, 1)
+	go func() { exited <- proc.Wait() }()
+	t.Cleanup(func() { proc.Process.Kill() })
+
+	pidFile := filepath.Join(t.TempDir(), "dnsmasq.pid")
+	require.NoError(t, os.WriteFile(pidFile, []byte(strconv.Itoa(proc.Process.Pid)+"\n"), 0644))
+	m.config.DnsmasqPIDFile = pidFile
+
+	_, err := m.AddDNSRecord(ctx, "default", DNSRecord{Name: "svc.internal", IP: "10.0.0.1"})
+	require.NoError(t, err)
+
+	select {
+	case err := <-exited:
+		var exitErr *exec.ExitError
+		require.ErrorAs(t, err, &exitErr)
+		status := exitErr.Sys().(syscall.WaitStatus)
+		assert.Equal(t, syscall.SIGHUP, status.Signal())
+	case <-time.After(5 * time.Second):
+		t.Fatal("dnsmasq was not signalled")
+	}
+}
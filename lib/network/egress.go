@@ -0,0 +1,102 @@
+package network
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/netip"
+	"os/exec"
+	"strings"
+
+	"github.com/kernel/hypeman/lib/logger"
+)
+
+// EgressRule allows an instance to send traffic to a destination
+type EgressRule struct {
+	Prefix netip.Prefix // Destination addresses
+	Port   uint16       // TCP and UDP destination port, 0 = any port and protocol
+}
+
+// EgressPolicy is what an instance with restricted egress may reach
+type EgressPolicy struct {
+	Rules []EgressRule
+	// AllowDNS allows queries to the network's DNS server
+	AllowDNS bool
+}
+
+// egressTable returns the nftables table restricting a TAP device's egress
+func egressTable(tap string) string {
+	return "hypeman_egress_" + strings.ReplaceAll(tap, "-", "_")
+}
+
+// egressRuleset renders an nftables ruleset dropping whatever tap receives
+// from its VM that policy doesn't allow. Filtering on the TAP's ingress hook
+// sees the VM's packets before they're bridged or routed, whatever their
+// destination. A macvtap device transmits the VM's packets instead, so hook
+// is "egress" for those (Linux 5.16+). ARP stays allowed so the VM can reach
+// its gateway. The table is replaced atomically.
+func egressRuleset(tap, hook string, policy EgressPolicy, dnsServer string) (string, error) {
+	rules := policy.Rules
+	if policy.AllowDNS && dnsServer != "" {
+		addr, err := netip.ParseAddr(dnsServer)
+		if err != nil {
+			return "", fmt.Errorf("parse DNS server %q: %w", dnsServer, err)
+		}
+		rules = append(rules, EgressRule{Prefix: netip.PrefixFrom(addr, addr.BitLen()), Port: 53})
+	}
+
+	table := egressTable(tap)
+	var b strings.Builder
+	fmt.Fprintf(&b, "table netdev %s {}\n", table)
+	fmt.Fprintf(&b, "delete table netdev %s\n", table)
+	fmt.Fprintf(&b, "table netdev %s {\n", table)
+	fmt.Fprintf(&b, "\tchain %s {\n", hook)
+	fmt.Fprintf(&b, "\t\ttype filter hook %s device %q priority 0; policy drop;\n", hook, tap)
+	fmt.Fprintf(&b, "\t\tmeta protocol arp accept\n")
+	for _, rule := range rules {
+		family := "ip"
+		if rule.Prefix.Addr().Is6() {
+			family = "ip6"
+		}
+		if rule.Port == 0 {
+			fmt.Fprintf(&b, "\t\t%s daddr %s accept\n", family, rule.Prefix.Masked())
+			continue
+		}
+		fmt.Fprintf(&b, "\t\t%s daddr %s meta l4proto { tcp, udp } th dport %d accept\n", family, rule.Prefix.Masked(), rule.Port)
+	}
+	fmt.Fprintf(&b, "\t}\n}\n")
+	return b.String(), nil
+}
+
+// RestrictEgress limits what an instance can send to the destinations its
+// policy allows, replacing any earlier restriction.
+func (m *manager) RestrictEgress(ctx context.Context, instanceID string, policy EgressPolicy) error {
+	tap := generateTAPName(instanceID)
+	hook := "ingress"
+	if m.uplink().Mode == UplinkModeMacvtap {
+		hook = "egress"
+	}
+	ruleset, err := egressRuleset(tap, hook, policy, m.config.DNSServer)
+	if err != nil {
+		return err
+	}
+	cmd := exec.CommandContext(ctx, "nft", "-f", "-")
+	cmd.Stdin = strings.NewReader(ruleset)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("apply nftables egress rules for %s: %w: %s", tap, err, bytes.TrimSpace(out))
+	}
+	logger.FromContext(ctx).DebugContext(ctx, "restricted instance egress", "instance_id", instanceID, "tap", tap, "rules", len(policy.Rules))
+	return nil
+}
+
+// ClearEgress removes the restriction RestrictEgress put on an instance.
+func (m *manager) ClearEgress(ctx context.Context, instanceID string) error {
+	table := egressTable(generateTAPName(instanceID))
+	// Adding the table first makes deleting it succeed whether or not it exists
+	cmd := exec.CommandContext(ctx, "nft", "-f", "-")
+	cmd.Stdin = strings.NewReader(fmt.Sprintf("table netdev %s {}\ndelete table netdev %s\n", table, table))
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("remove nftables egress rules %s: %w: %s", table, err, bytes.TrimSpace(out))
+	}
+	return nil
+}
@@ -0,0 +1,48 @@
+package network
+
+import (
+	"net/netip"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEgressRuleset(t *testing.T) {
+	policy := EgressPolicy{
+		Rules: []EgressRule{
+			{Prefix: netip.MustParsePrefix("10.102.0.1/32"), Port: 8083},
+			{Prefix: netip.MustParsePrefix("151.101.0.0/16")},
+			{Prefix: netip.MustParsePrefix("2a04:4e42::1/128"), Port: 443},
+		},
+		AllowDNS: true,
+	}
+	ruleset, err := egressRuleset("hype-abc123", "ingress", policy, "10.102.0.1")
+	require.NoError(t, err)
+	assert.Equal(t, `table netdev hypeman_egress_hype_abc123 {}
+delete table netdev hypeman_egress_hype_abc123
+table netdev hypeman_egress_hype_abc123 {
+	chain ingress {
+		type filter hook ingress device "hype-abc123" priority 0; policy drop;
+		meta protocol arp accept
+		ip daddr 10.102.0.1/32 meta l4proto { tcp, udp } th dport 8083 accept
+		ip daddr 151.101.0.0/16 accept
+		ip6 daddr 2a04:4e42::1/128 meta l4proto { tcp, udp } th dport 443 accept
+		ip daddr 10.102.0.1/32 meta l4proto { tcp, udp } th dport 53 accept
+	}
+}
+`, ruleset)
+
+	// Nothing but ARP is allowed without rules
+	ruleset, err = egressRuleset("hype-abc123", "ingress", EgressPolicy{}, "10.102.0.1")
+	require.NoError(t, err)
+	assert.NotContains(t, ruleset, "daddr")
+
+	// Macvtap devices transmit the VM's packets
+	ruleset, err = egressRuleset("hype-abc123", "egress", EgressPolicy{}, "10.102.0.1")
+	require.NoError(t, err)
+	assert.Contains(t, ruleset, `type filter hook egress device "hype-abc123"`)
+
+	_, err = egressRuleset("hype-abc123", "ingress", EgressPolicy{AllowDNS: true}, "dns.example.com")
+	assert.Error(t, err)
+}
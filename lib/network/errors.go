@@ -8,5 +8,32 @@ var (
 
 	// ErrNameExists is returned when an instance name already exists
 	ErrNameExists = errors.New("instance name already exists")
+
+	// ErrInvalidDNSRecord is returned when a DNS record has an invalid name or IP
+	ErrInvalidDNSRecord = errors.New("invalid DNS record")
+
+	// ErrInvalidSearchDomain is returned when a search domain is invalid or too many are given
+	ErrInvalidSearchDomain = errors.New("invalid search domain")
+
+	// ErrInvalidDNSDomain is returned when a network's DNS domain is invalid
+	ErrInvalidDNSDomain = errors.New("invalid DNS domain")
+
+	// ErrInvalidDHCPOptions is returned when a network's DHCP options are invalid
+	ErrInvalidDHCPOptions = errors.New("invalid DHCP options")
+
+	// ErrInvalidUplink is returned when a network's uplink mode, interface or VLAN is invalid
+	ErrInvalidUplink = errors.New("invalid uplink")
+
+	// ErrUplinkInUse is returned when changing the uplink of a network instances are on
+	ErrUplinkInUse = errors.New("uplink in use")
+
+	// ErrDNSRecordExists is returned when a DNS record with the same name already exists
+	ErrDNSRecordExists = errors.New("DNS record already exists")
+
+	// ErrDNSRecordNotFound is returned when a DNS record doesn't exist
+	ErrDNSRecordNotFound = errors.New("DNS record not found")
+
+	// ErrNoAvailableIP is returned when every address in the subnet is allocated
+	ErrNoAvailableIP = errors.New("no available IP addresses")
 )
 
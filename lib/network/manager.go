@@ -2,8 +2,11 @@ package network
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"os"
 	"sync"
+	"syscall"
 	"time"
 
 	"github.com/kernel/hypeman/cmd/api/config"
@@ -19,18 +22,65 @@ type Manager interface {
 
 	// Instance allocation operations (called by instance manager)
 	CreateAllocation(ctx context.Context, req AllocateRequest) (*NetworkConfig, error)
-	RecreateAllocation(ctx context.Context, instanceID string, downloadBps, uploadBps int64) error
+	// CheckAllocation reports whether CreateAllocation would succeed, without allocating.
+	CheckAllocation(ctx context.Context, instanceName string) error
+	RecreateAllocation(ctx context.Context, instanceID string, limits BandwidthLimits) error
 	ReleaseAllocation(ctx context.Context, alloc *Allocation) error
+	// DeleteAllocation frees the IP and MAC reserved for a deleted instance.
+	DeleteAllocation(ctx context.Context, instanceID string) error
+	// RenameAllocation checks that name is free on the network and calls
+	// commit to persist it, atomically with respect to other allocations.
+	RenameAllocation(ctx context.Context, instanceID, name string, commit func() error) error
+
+	// UpdateBandwidth adjusts traffic shaping on a running instance's TAP device.
+	UpdateBandwidth(ctx context.Context, instanceID string, limits BandwidthLimits) error
 
 	// SetupHTB initializes HTB qdisc on the bridge for upload fair sharing.
 	// Should be called during network initialization with the total network capacity.
 	SetupHTB(ctx context.Context, capacityBps int64) error
 
+	// CleanupOrphans removes TAP devices that no running instance uses once
+	// they've been orphaned for at least grace, then HTB classes without a
+	// TAP. Startup reconciliation runs it with no grace, since no instance
+	// can be mid-create then.
+	CleanupOrphans(ctx context.Context, runningInstanceIDs []string, grace time.Duration) (taps, classes int)
+
+	// RestrictEgress drops the traffic an instance sends anywhere its policy
+	// doesn't allow, with an nftables filter on its TAP device. It replaces
+	// earlier restrictions of the instance.
+	RestrictEgress(ctx context.Context, instanceID string, policy EgressPolicy) error
+	// ClearEgress removes the restriction RestrictEgress put on an instance.
+	ClearEgress(ctx context.Context, instanceID string) error
+
 	// Queries (derive from CH/snapshots)
 	GetAllocation(ctx context.Context, instanceID string) (*Allocation, error)
 	ListAllocations(ctx context.Context) ([]Allocation, error)
 	NameExists(ctx context.Context, name string) (bool, error)
 
+	// Custom DNS (static records and search domains, rendered for dnsmasq)
+	GetDNSConfig(ctx context.Context, networkName string) (*DNSConfig, error)
+	AddDNSRecord(ctx context.Context, networkName string, record DNSRecord) (*DNSRecord, error)
+	DeleteDNSRecord(ctx context.Context, networkName, name string) error
+	SetSearchDomains(ctx context.Context, networkName string, domains []string) (*DNSConfig, error)
+	// SetDNSDomain sets the domain instances are served under, as
+	// <instance>.<network>.<domain>.
+	SetDNSDomain(ctx context.Context, networkName, domain string) (*DNSConfig, error)
+	// GetDHCPOptions returns the MTU, NTP servers and static routes handed to guests on a network.
+	GetDHCPOptions(ctx context.Context, networkName string) (*DHCPOptions, error)
+	// SetDHCPOptions replaces a network's DHCP options; guests apply them on their next boot.
+	SetDHCPOptions(ctx context.Context, networkName string, opts DHCPOptions) (*DHCPOptions, error)
+	// GetUplink returns how a network reaches the host's network.
+	GetUplink(ctx context.Context, networkName string) (*Uplink, error)
+	// SetUplink changes a network's uplink while no instance is on it.
+	SetUplink(ctx context.Context, networkName string, uplink Uplink) (*Uplink, error)
+	// RefreshInstanceDNS updates the instance name records served by dnsmasq.
+	// Called after instances are created, renamed or deleted.
+	RefreshInstanceDNS(ctx context.Context) error
+
+	// CheckHealth reports whether the default bridge is up with an address
+	// and, if DNSMASQ_PID_FILE is configured, dnsmasq is running.
+	CheckHealth(ctx context.Context) (string, error)
+
 	// GetUploadBurstMultiplier returns the configured multiplier for upload burst ceiling.
 	GetUploadBurstMultiplier() int
 
@@ -43,7 +93,14 @@ type manager struct {
 	paths   *paths.Paths
 	config  *config.Config
 	mu      sync.Mutex // Protects network allocation operations (IP allocation)
+	dnsMu   sync.Mutex // Protects custom DNS configuration updates
 	metrics *Metrics
+
+	orphanMu    sync.Mutex
+	orphanSince map[string]time.Time // TAP name -> when CleanupOrphans first found it orphaned
+
+	uplinkMu     sync.Mutex
+	activeUplink *Uplink // Set with SetUplink; nil uses UPLINK_MODE
 }
 
 // NewManager creates a new network manager.
@@ -67,6 +124,8 @@ func NewManager(p *paths.Paths, cfg *config.Config, meter metric.Meter) Manager
 
 // Initialize initializes the network manager and creates default network.
 // runningInstanceIDs should contain IDs of instances currently running (have active VMM).
+// TAP devices left by earlier runs aren't touched: startup reconciliation
+// removes them with CleanupOrphans once the bridge's HTB qdisc is set up.
 func (m *manager) Initialize(ctx context.Context, runningInstanceIDs []string) error {
 	log := logger.FromContext(ctx)
 
@@ -90,20 +149,53 @@ func (m *manager) Initialize(ctx context.Context, runningInstanceIDs []string) e
 		return err
 	}
 
+	// An uplink set through the API takes precedence over UPLINK_MODE
+	stored, err := m.loadUplink("default")
+	if err != nil {
+		return err
+	}
+	if stored != nil {
+		if stored.routed() {
+			if err := m.checkRoutedUplink(stored.Mode); err != nil {
+				return fmt.Errorf("network default uplink: %w", err)
+			}
+		}
+		m.setUplink(*stored)
+	}
+	uplink := m.uplink()
+	log.InfoContext(ctx, "network uplink", "mode", uplink.Mode, "interface", uplink.Interface, "vlan", uplink.VLAN)
+
 	// Ensure default network bridge exists and iptables rules are configured
 	// createBridge is idempotent - handles both new and existing bridges
 	if err := m.createBridge(ctx, m.config.BridgeName, gateway, m.config.SubnetCIDR); err != nil {
 		return fmt.Errorf("setup default network: %w", err)
 	}
 
-	// Cleanup orphaned TAP devices from previous runs (crashes, power loss, etc.)
-	if deleted := m.CleanupOrphanedTAPs(ctx, runningInstanceIDs); deleted > 0 {
-		log.InfoContext(ctx, "cleaned up orphaned TAP devices", "count", deleted)
+	// Redirect the metadata service's address to the gateway
+	metaStatus, err := m.ensureMetadataRule(m.config.BridgeName, gateway)
+	if err != nil {
+		return fmt.Errorf("setup metadata service: %w", err)
+	}
+	if m.metadataEnabled() {
+		m.filterRunningMetadata(ctx, runningInstanceIDs)
+	}
+	log.InfoContext(ctx, "metadata service redirect ready", "address", MetadataAddress, "port", m.config.MetadataPort, "status", metaStatus)
+
+	// Persist address reservations (seeded from instance metadata on first run)
+	// and drop those left behind by interrupted deletes
+	if pruned, err := m.pruneReservations(ctx); err != nil {
+		log.WarnContext(ctx, "failed to load network reservations", "error", err)
+	} else if pruned > 0 {
+		log.InfoContext(ctx, "dropped orphaned network reservations", "count", pruned)
 	}
 
-	// Cleanup orphaned HTB classes (TAPs deleted externally but classes remain)
-	if deleted := m.CleanupOrphanedClasses(ctx); deleted > 0 {
-		log.InfoContext(ctx, "cleaned up orphaned HTB classes", "count", deleted)
+	// Re-render dnsmasq files so they exist (and match dns.json) before dnsmasq is pointed at them
+	if dnsConfig, err := m.loadDNSConfig("default"); err != nil {
+		log.WarnContext(ctx, "failed to load custom DNS config", "error", err)
+	} else if err := os.MkdirAll(m.paths.NetworkDir("default"), 0755); err != nil {
+		log.WarnContext(ctx, "failed to create network directory", "error", err)
+	} else if err := m.renderDnsmasqConfig("default", dnsConfig); err != nil {
+		log.WarnContext(ctx, "failed to render dnsmasq config", "error", err)
 	}
 
 	log.InfoContext(ctx, "network manager initialized")
@@ -152,3 +244,25 @@ func (m *manager) GetDownloadBurstMultiplier() int {
 	}
 	return m.config.DownloadBurstMultiplier
 }
+
+// CheckHealth reports whether the default bridge and dnsmasq are up
+func (m *manager) CheckHealth(ctx context.Context) (string, error) {
+	state, err := m.queryNetworkState(m.config.BridgeName)
+	if err != nil {
+		return "", fmt.Errorf("bridge %s: %w", m.config.BridgeName, err)
+	}
+	detail := fmt.Sprintf("bridge %s up (%s)", state.Bridge, state.Subnet)
+
+	if m.config.DnsmasqPIDFile == "" {
+		return detail, nil
+	}
+	pid, err := m.dnsmasqPID()
+	if err != nil {
+		return "", err
+	}
+	// Signal 0 only checks that the process exists
+	if err := syscall.Kill(pid, 0); err != nil && !errors.Is(err, syscall.EPERM) {
+		return "", fmt.Errorf("dnsmasq (pid %d) not running: %w", pid, err)
+	}
+	return fmt.Sprintf("%s, dnsmasq running (pid %d)", detail, pid), nil
+}
@@ -0,0 +1,132 @@
+package network
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net"
+	"os/exec"
+	"strings"
+	"syscall"
+
+	"github.com/kernel/hypeman/lib/logger"
+	"golang.org/x/sys/unix"
+)
+
+// MetadataAddress is the link-local address guests reach the metadata
+// service at. Its HTTP port is redirected to METADATA_PORT on the gateway.
+const MetadataAddress = "169.254.169.254"
+
+// metadataEnabled reports whether the metadata service is configured
+func (m *manager) metadataEnabled() bool {
+	return m.config.MetadataPort != ""
+}
+
+// metadataTable returns the nftables table guarding a TAP device's metadata
+// requests
+func metadataTable(tap string) string {
+	return "hypeman_metadata_" + strings.ReplaceAll(tap, "-", "_")
+}
+
+// metadataRuleset renders an nftables ruleset dropping packets tap receives
+// for the metadata service, or anything else on the gateway, that don't come
+// from the instance's own address. The service identifies instances by
+// source address, so this keeps one instance from reading another's
+// metadata. The table is replaced atomically.
+func metadataRuleset(tap, ip, gateway string) (string, error) {
+	for _, addr := range []string{ip, gateway} {
+		if parsed := net.ParseIP(addr); parsed == nil || parsed.To4() == nil {
+			return "", fmt.Errorf("invalid IPv4 address %q", addr)
+		}
+	}
+
+	table := metadataTable(tap)
+	var b strings.Builder
+	fmt.Fprintf(&b, "table netdev %s {}\n", table)
+	fmt.Fprintf(&b, "delete table netdev %s\n", table)
+	fmt.Fprintf(&b, "table netdev %s {\n", table)
+	fmt.Fprintf(&b, "\tchain ingress {\n")
+	fmt.Fprintf(&b, "\t\ttype filter hook ingress device %q priority -1; policy accept;\n", tap)
+	fmt.Fprintf(&b, "\t\tip daddr { %s, %s } ip saddr != %s drop\n", MetadataAddress, gateway, ip)
+	fmt.Fprintf(&b, "\t}\n}\n")
+	return b.String(), nil
+}
+
+// filterMetadata restricts the metadata requests tap accepts to those from
+// ip. It's a no-op when the metadata service is disabled.
+func (m *manager) filterMetadata(ctx context.Context, tap, ip, gateway string) error {
+	if !m.metadataEnabled() {
+		return nil
+	}
+	ruleset, err := metadataRuleset(tap, ip, gateway)
+	if err != nil {
+		return err
+	}
+	cmd := exec.CommandContext(ctx, "nft", "-f", "-")
+	cmd.Stdin = strings.NewReader(ruleset)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("apply nftables metadata rules for %s: %w: %s", tap, err, bytes.TrimSpace(out))
+	}
+	return nil
+}
+
+// unfilterMetadata removes the table filterMetadata created (best effort)
+func (m *manager) unfilterMetadata(tap string) {
+	if !m.metadataEnabled() {
+		return
+	}
+	table := metadataTable(tap)
+	cmd := exec.Command("nft", "-f", "-")
+	cmd.Stdin = strings.NewReader(fmt.Sprintf("table netdev %s {}\ndelete table netdev %s\n", table, table))
+	cmd.Run() // ignore error
+}
+
+// ensureMetadataRule redirects guests' HTTP requests to MetadataAddress to
+// the metadata service on the gateway, or removes the redirect when the
+// service is disabled.
+func (m *manager) ensureMetadataRule(bridgeName, gateway string) (string, error) {
+	if !m.metadataEnabled() {
+		m.deleteNATRuleByComment("PREROUTING", commentMeta)
+		return "disabled", nil
+	}
+
+	args := []string{"PREROUTING",
+		"-i", bridgeName, "-d", MetadataAddress + "/32", "-p", "tcp", "--dport", "80",
+		"-m", "comment", "--comment", commentMeta,
+		"-j", "DNAT", "--to-destination", net.JoinHostPort(gateway, m.config.MetadataPort)}
+
+	checkCmd := exec.Command("iptables", append([]string{"-t", "nat", "-C"}, args...)...)
+	checkCmd.SysProcAttr = &syscall.SysProcAttr{
+		AmbientCaps: []uintptr{unix.CAP_NET_ADMIN},
+	}
+	if checkCmd.Run() == nil {
+		return "existing", nil
+	}
+
+	// Delete any existing rule with our comment (handles gateway and port changes)
+	m.deleteNATRuleByComment("PREROUTING", commentMeta)
+
+	addCmd := exec.Command("iptables", append([]string{"-t", "nat", "-A"}, args...)...)
+	addCmd.SysProcAttr = &syscall.SysProcAttr{
+		AmbientCaps: []uintptr{unix.CAP_NET_ADMIN},
+	}
+	if err := addCmd.Run(); err != nil {
+		return "", fmt.Errorf("add metadata redirect rule: %w", err)
+	}
+	return "added", nil
+}
+
+// filterRunningMetadata guards the TAP devices of instances that were
+// running before the service was set up
+func (m *manager) filterRunningMetadata(ctx context.Context, runningInstanceIDs []string) {
+	log := logger.FromContext(ctx)
+	for _, id := range runningInstanceIDs {
+		alloc, err := m.deriveAllocation(ctx, id)
+		if err != nil || alloc == nil {
+			continue
+		}
+		if err := m.filterMetadata(ctx, alloc.TAPDevice, alloc.IP, alloc.Gateway); err != nil {
+			log.WarnContext(ctx, "failed to guard metadata requests", "instance_id", id, "tap", alloc.TAPDevice, "error", err)
+		}
+	}
+}
@@ -0,0 +1,27 @@
+package network
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMetadataRuleset(t *testing.T) {
+	ruleset, err := metadataRuleset("hype-abc123", "10.102.0.7", "10.102.0.1")
+	require.NoError(t, err)
+	assert.Equal(t, `table netdev hypeman_metadata_hype_abc123 {}
+delete table netdev hypeman_metadata_hype_abc123
+table netdev hypeman_metadata_hype_abc123 {
+	chain ingress {
+		type filter hook ingress device "hype-abc123" priority -1; policy accept;
+		ip daddr { 169.254.169.254, 10.102.0.1 } ip saddr != 10.102.0.7 drop
+	}
+}
+`, ruleset)
+
+	_, err = metadataRuleset("hype-abc123", "", "10.102.0.1")
+	assert.Error(t, err)
+	_, err = metadataRuleset("hype-abc123", "10.102.0.7", "fd00::1")
+	assert.Error(t, err)
+}
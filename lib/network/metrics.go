@@ -46,6 +46,112 @@ func newNetworkMetrics(meter metric.Meter, m *manager) (*Metrics, error) {
 		return nil, err
 	}
 
+	// Traffic shaper counters per instance and direction (read from tc statistics)
+	shapedBytes, err := meter.Int64ObservableCounter(
+		"hypeman_network_shaped_bytes_total",
+		metric.WithDescription("Bytes passed through an instance's bandwidth shaper"),
+		metric.WithUnit("By"),
+	)
+	if err != nil {
+		return nil, err
+	}
+	shapedDropped, err := meter.Int64ObservableCounter(
+		"hypeman_network_shaped_dropped_packets_total",
+		metric.WithDescription("Packets dropped by an instance's bandwidth shaper"),
+	)
+	if err != nil {
+		return nil, err
+	}
+	shapedOverlimits, err := meter.Int64ObservableCounter(
+		"hypeman_network_shaped_overlimits_total",
+		metric.WithDescription("Times an instance's bandwidth shaper delayed traffic because the rate limit was exceeded"),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = meter.RegisterCallback(
+		func(ctx context.Context, o metric.Observer) error {
+			allocs, err := m.ListAllocations(ctx)
+			if err != nil {
+				return nil
+			}
+			for _, alloc := range allocs {
+				if alloc.State != "running" {
+					continue
+				}
+				download, upload, downloadOK, uploadOK := m.shapingStats(alloc.TAPDevice)
+				observe := func(direction string, stats ShapingStats) {
+					attrs := metric.WithAttributes(
+						attribute.String("instance_id", alloc.InstanceID),
+						attribute.String("direction", direction),
+					)
+					o.ObserveInt64(shapedBytes, stats.Bytes, attrs)
+					o.ObserveInt64(shapedDropped, stats.Dropped, attrs)
+					o.ObserveInt64(shapedOverlimits, stats.Overlimits, attrs)
+				}
+				if downloadOK {
+					observe("download", download)
+				}
+				if uploadOK {
+					observe("upload", upload)
+				}
+			}
+			return nil
+		},
+		shapedBytes, shapedDropped, shapedOverlimits,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	// Tracked connections per instance (read from the conntrack table)
+	connections, err := meter.Int64ObservableGauge(
+		"hypeman_network_connections",
+		metric.WithDescription("Connections of an instance tracked by conntrack"),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = meter.RegisterCallback(
+		func(ctx context.Context, o metric.Observer) error {
+			allocs, err := m.ListAllocations(ctx)
+			if err != nil {
+				return nil
+			}
+			instanceByIP := make(map[string]string, len(allocs))
+			ips := make(map[string]bool, len(allocs))
+			for _, alloc := range allocs {
+				if alloc.State != "running" {
+					continue
+				}
+				instanceByIP[alloc.IP] = alloc.InstanceID
+				ips[alloc.IP] = true
+			}
+			if len(ips) == 0 {
+				return nil
+			}
+			counts, err := connectionCounts(ips)
+			if err != nil {
+				return nil
+			}
+			for ip, instanceID := range instanceByIP {
+				for _, proto := range []string{"tcp", "udp", "icmp", "other"} {
+					o.ObserveInt64(connections, counts[ip][proto], metric.WithAttributes(
+						attribute.String("instance_id", instanceID),
+						attribute.String("protocol", proto),
+					))
+				}
+			}
+			return nil
+		},
+		connections,
+	)
+	if err != nil {
+		return nil, err
+	}
+
 	return &Metrics{
 		tapOperations: tapOperations,
 	}, nil
@@ -0,0 +1,207 @@
+package overlay
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/vishvananda/netlink"
+	"golang.org/x/sys/unix"
+)
+
+// Rule comments for identifying the overlay's iptables rules
+const (
+	commentOverlayOut = "hypeman-overlay-out"
+	commentOverlayIn  = "hypeman-overlay-in"
+)
+
+// persistentKeepalive keeps NAT mappings to peers with an endpoint open.
+const persistentKeepalive = 25
+
+// peerState is a peer's WireGuard session state.
+type peerState struct {
+	LatestHandshake *time.Time
+	RxBytes         int64
+	TxBytes         int64
+}
+
+// kernel applies the overlay to the host. It is an interface so the manager
+// can be tested without WireGuard or CAP_NET_ADMIN.
+type kernel interface {
+	ensureInterface(name string) error
+	ensureForwarding(bridge, iface string) error
+	configure(iface string, key privateKey, listenPort int, peers []Peer, allowedIPs func(Peer) []string) error
+	syncRoutes(iface string, subnets []string) error
+	peerStats(iface string) (map[string]peerState, error)
+}
+
+// wgKernel uses netlink for the interface and routes, and wireguard-tools
+// (wg) for the WireGuard configuration.
+type wgKernel struct{}
+
+func (wgKernel) ensureInterface(name string) error {
+	link, err := netlink.LinkByName(name)
+	if err != nil {
+		link = &netlink.Wireguard{LinkAttrs: netlink.LinkAttrs{Name: name}}
+		if err := netlink.LinkAdd(link); err != nil {
+			return fmt.Errorf("create %s: %w", name, err)
+		}
+	} else if link.Type() != "wireguard" {
+		return fmt.Errorf("interface %s exists but is a %s device", name, link.Type())
+	}
+	if err := netlink.LinkSetUp(link); err != nil {
+		return fmt.Errorf("set %s up: %w", name, err)
+	}
+	return nil
+}
+
+// ensureForwarding accepts traffic between the bridge and the mesh in both
+// directions, ahead of the NAT network's stateful rules: instances on other
+// hosts open connections too.
+func (wgKernel) ensureForwarding(bridge, iface string) error {
+	if err := ensureRule(bridge, iface, commentOverlayOut); err != nil {
+		return err
+	}
+	return ensureRule(iface, bridge, commentOverlayIn)
+}
+
+func ensureRule(in, out, comment string) error {
+	args := []string{"FORWARD", "-i", in, "-o", out, "-m", "comment", "--comment", comment, "-j", "ACCEPT"}
+	if iptables(append([]string{"-C"}, args...)...) == nil {
+		return nil
+	}
+	insert := append([]string{"-I", args[0], "1"}, args[1:]...)
+	if err := iptables(insert...); err != nil {
+		return fmt.Errorf("add forward rule %s: %w", comment, err)
+	}
+	return nil
+}
+
+func iptables(args ...string) error {
+	cmd := exec.Command("iptables", args...)
+	cmd.SysProcAttr = &syscall.SysProcAttr{
+		AmbientCaps: []uintptr{unix.CAP_NET_ADMIN},
+	}
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("%w: %s", err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+// configure replaces the interface's key, port and peers with wg syncconf,
+// which leaves sessions of unchanged peers up.
+func (wgKernel) configure(iface string, key privateKey, listenPort int, peers []Peer, allowedIPs func(Peer) []string) error {
+	tmp, err := os.CreateTemp("", "hypeman-wg-*.conf")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.WriteString(renderConfig(key, listenPort, peers, allowedIPs)); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	_, err = wg("syncconf", iface, tmp.Name())
+	return err
+}
+
+// renderConfig renders the interface configuration in wg(8) format.
+func renderConfig(key privateKey, listenPort int, peers []Peer, allowedIPs func(Peer) []string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "[Interface]\nPrivateKey = %s\nListenPort = %d\n", key, listenPort)
+	for _, p := range peers {
+		fmt.Fprintf(&b, "\n# %s\n[Peer]\nPublicKey = %s\n", p.Name, p.PublicKey)
+		if p.Endpoint != "" {
+			fmt.Fprintf(&b, "Endpoint = %s\nPersistentKeepalive = %d\n", p.Endpoint, persistentKeepalive)
+		}
+		if ips := allowedIPs(p); len(ips) > 0 {
+			fmt.Fprintf(&b, "AllowedIPs = %s\n", strings.Join(ips, ", "))
+		}
+	}
+	return b.String()
+}
+
+// syncRoutes routes exactly the given subnets through the interface.
+func (wgKernel) syncRoutes(iface string, subnets []string) error {
+	link, err := netlink.LinkByName(iface)
+	if err != nil {
+		return fmt.Errorf("get %s: %w", iface, err)
+	}
+
+	wanted := make(map[string]bool, len(subnets))
+	for _, subnet := range subnets {
+		_, dst, err := net.ParseCIDR(subnet)
+		if err != nil {
+			return fmt.Errorf("parse subnet %s: %w", subnet, err)
+		}
+		wanted[dst.String()] = true
+		route := &netlink.Route{LinkIndex: link.Attrs().Index, Dst: dst, Scope: netlink.SCOPE_LINK}
+		if err := netlink.RouteReplace(route); err != nil {
+			return fmt.Errorf("route %s: %w", dst, err)
+		}
+	}
+
+	existing, err := netlink.RouteList(link, netlink.FAMILY_V4)
+	if err != nil {
+		return fmt.Errorf("list routes: %w", err)
+	}
+	for _, route := range existing {
+		if route.Dst != nil && !wanted[route.Dst.String()] {
+			if err := netlink.RouteDel(&route); err != nil {
+				return fmt.Errorf("remove route %s: %w", route.Dst, err)
+			}
+		}
+	}
+	return nil
+}
+
+func (wgKernel) peerStats(iface string) (map[string]peerState, error) {
+	out, err := wg("show", iface, "dump")
+	if err != nil {
+		return nil, err
+	}
+	return parseDump(out), nil
+}
+
+// parseDump parses `wg show <iface> dump`: an interface line, then one line
+// per peer with tab-separated public key, preshared key, endpoint, allowed
+// IPs, latest handshake (unix seconds, 0 = never), rx bytes, tx bytes and
+// keepalive.
+func parseDump(out string) map[string]peerState {
+	stats := make(map[string]peerState)
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	for _, line := range lines[min(1, len(lines)):] {
+		fields := strings.Split(line, "\t")
+		if len(fields) < 8 {
+			continue
+		}
+		var s peerState
+		if sec, err := strconv.ParseInt(fields[4], 10, 64); err == nil && sec > 0 {
+			t := time.Unix(sec, 0).UTC()
+			s.LatestHandshake = &t
+		}
+		s.RxBytes, _ = strconv.ParseInt(fields[5], 10, 64)
+		s.TxBytes, _ = strconv.ParseInt(fields[6], 10, 64)
+		stats[fields[0]] = s
+	}
+	return stats
+}
+
+func wg(args ...string) (string, error) {
+	cmd := exec.Command("wg", args...)
+	cmd.SysProcAttr = &syscall.SysProcAttr{
+		AmbientCaps: []uintptr{unix.CAP_NET_ADMIN},
+	}
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("wg %s: %w: %s", args[0], err, strings.TrimSpace(string(output)))
+	}
+	return string(output), nil
+}
@@ -0,0 +1,88 @@
+package overlay
+
+import (
+	"crypto/ecdh"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// privateKey is a WireGuard (Curve25519) private key.
+type privateKey struct {
+	key *ecdh.PrivateKey
+}
+
+// String returns the key in WireGuard's base64 form.
+func (k privateKey) String() string {
+	return base64.StdEncoding.EncodeToString(k.key.Bytes())
+}
+
+// publicKey returns the matching public key in WireGuard's base64 form.
+func (k privateKey) publicKey() string {
+	return base64.StdEncoding.EncodeToString(k.key.PublicKey().Bytes())
+}
+
+// generateKey creates a new private key.
+func generateKey() (privateKey, error) {
+	key, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		return privateKey{}, fmt.Errorf("generate overlay key: %w", err)
+	}
+	return privateKey{key: key}, nil
+}
+
+// parsePrivateKey decodes a base64 private key.
+func parsePrivateKey(s string) (privateKey, error) {
+	raw, err := base64.StdEncoding.DecodeString(strings.TrimSpace(s))
+	if err != nil {
+		return privateKey{}, fmt.Errorf("decode private key: %w", err)
+	}
+	key, err := ecdh.X25519().NewPrivateKey(raw)
+	if err != nil {
+		return privateKey{}, fmt.Errorf("parse private key: %w", err)
+	}
+	return privateKey{key: key}, nil
+}
+
+// parsePublicKey validates a base64 public key and returns it in canonical form.
+func parsePublicKey(s string) (string, error) {
+	raw, err := base64.StdEncoding.DecodeString(strings.TrimSpace(s))
+	if err != nil {
+		return "", fmt.Errorf("public key %q is not base64", s)
+	}
+	if _, err := ecdh.X25519().NewPublicKey(raw); err != nil {
+		return "", fmt.Errorf("public key %q is not a 32-byte Curve25519 key", s)
+	}
+	return base64.StdEncoding.EncodeToString(raw), nil
+}
+
+// loadOrCreateKey reads this host's private key, generating and saving one
+// on first use. Callers must hold m.mu.
+func (m *manager) loadOrCreateKey() (privateKey, error) {
+	data, err := os.ReadFile(m.paths.OverlayPrivateKey())
+	if errors.Is(err, os.ErrNotExist) {
+		key, err := generateKey()
+		if err != nil {
+			return privateKey{}, err
+		}
+		if err := m.saveKey(key); err != nil {
+			return privateKey{}, err
+		}
+		return key, nil
+	}
+	if err != nil {
+		return privateKey{}, fmt.Errorf("read overlay key: %w", err)
+	}
+	return parsePrivateKey(string(data))
+}
+
+// saveKey persists the private key, readable by the owner only.
+func (m *manager) saveKey(key privateKey) error {
+	if err := writeFileAtomic(m.paths.OverlayPrivateKey(), []byte(key.String()+"\n"), 0600); err != nil {
+		return fmt.Errorf("write overlay key: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,468 @@
+// Package overlay connects the networks of several hypeman hosts over a
+// WireGuard mesh, so instances on different hosts in the same named network
+// can reach each other.
+package overlay
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kernel/hypeman/lib/logger"
+	"github.com/kernel/hypeman/lib/paths"
+)
+
+// MaxPeers is the maximum number of peers in the mesh.
+const MaxPeers = 64
+
+var (
+	// ErrDisabled is returned by write operations when the overlay isn't configured
+	ErrDisabled = errors.New("overlay networking is disabled")
+
+	// ErrInvalidPeer is returned when a peer has an invalid name, key, endpoint or route
+	ErrInvalidPeer = errors.New("invalid overlay peer")
+
+	// ErrPeerExists is returned when a peer with the same name or public key is registered
+	ErrPeerExists = errors.New("overlay peer already exists")
+
+	// ErrPeerNotFound is returned when a peer isn't registered
+	ErrPeerNotFound = errors.New("overlay peer not found")
+
+	// ErrRouteConflict is returned when a peer's route overlaps a local network or another peer's route
+	ErrRouteConflict = errors.New("overlay route conflicts with an existing route")
+)
+
+// peerNamePattern matches a peer name: a lowercase RFC 1123 label.
+var peerNamePattern = regexp.MustCompile(`^[a-z0-9]([a-z0-9-]{0,61}[a-z0-9])?$`)
+
+// Route is a network's subnet on a host. Routes are only installed for
+// networks that also exist locally.
+type Route struct {
+	Network string `json:"network"`
+	Subnet  string `json:"subnet"` // IPv4 CIDR
+}
+
+// LocalNetwork is a network on this host whose subnet is advertised to peers.
+type LocalNetwork struct {
+	Name   string
+	Subnet string // IPv4 CIDR
+	Bridge string // Bridge forwarded to and from the mesh
+}
+
+// Config configures the overlay. An empty ListenPort disables it.
+type Config struct {
+	Interface  string // WireGuard interface name
+	ListenPort int
+	Endpoint   string // host:port other hosts reach this one at
+	Networks   []LocalNetwork
+}
+
+// Peer is another hypeman host in the mesh.
+type Peer struct {
+	Name      string    `json:"name"`
+	PublicKey string    `json:"public_key"`
+	Endpoint  string    `json:"endpoint,omitempty"` // host:port; empty if the peer connects to this host
+	Routes    []Route   `json:"routes"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// PeerStatus is a peer with its WireGuard session state.
+type PeerStatus struct {
+	Peer
+	LatestHandshake *time.Time // nil if no handshake has completed
+	RxBytes         int64
+	TxBytes         int64
+}
+
+// Status is this host's side of the mesh. PublicKey, Endpoint and Routes are
+// what the other hosts register this one with.
+type Status struct {
+	Enabled    bool
+	Interface  string
+	PublicKey  string
+	ListenPort int
+	Endpoint   string
+	Routes     []Route
+	Peers      []PeerStatus
+}
+
+// Manager manages the WireGuard overlay.
+type Manager interface {
+	// Initialize creates the host key (first run), the WireGuard interface,
+	// peer routes and forwarding rules. It is a no-op when the overlay is disabled.
+	Initialize(ctx context.Context) error
+	// Status returns this host's key, endpoint and routes, and the registered peers.
+	Status(ctx context.Context) (*Status, error)
+	// AddPeer registers a host and applies it to the interface.
+	AddPeer(ctx context.Context, peer Peer) (*Peer, error)
+	// DeletePeer removes a host from the mesh.
+	DeletePeer(ctx context.Context, name string) error
+	// RotateKey replaces this host's key pair. Peers must re-register the
+	// new public key before traffic flows again.
+	RotateKey(ctx context.Context) (*Status, error)
+}
+
+type manager struct {
+	paths  *paths.Paths
+	config Config
+	kernel kernel
+	mu     sync.Mutex
+}
+
+// NewManager creates an overlay manager.
+func NewManager(p *paths.Paths, cfg Config) Manager {
+	return &manager{paths: p, config: cfg, kernel: &wgKernel{}}
+}
+
+func (m *manager) enabled() bool {
+	return m.config.ListenPort > 0
+}
+
+func (m *manager) Initialize(ctx context.Context) error {
+	if !m.enabled() {
+		return nil
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, err := m.loadOrCreateKey(); err != nil {
+		return err
+	}
+	if err := m.kernel.ensureInterface(m.config.Interface); err != nil {
+		return fmt.Errorf("create WireGuard interface: %w", err)
+	}
+	for _, n := range m.config.Networks {
+		if err := m.kernel.ensureForwarding(n.Bridge, m.config.Interface); err != nil {
+			return fmt.Errorf("forward %s to the mesh: %w", n.Bridge, err)
+		}
+	}
+	if err := m.apply(ctx); err != nil {
+		return err
+	}
+
+	logger.FromContext(ctx).InfoContext(ctx, "overlay ready",
+		"interface", m.config.Interface, "listen_port", m.config.ListenPort, "endpoint", m.config.Endpoint)
+	return nil
+}
+
+func (m *manager) Status(ctx context.Context) (*Status, error) {
+	if !m.enabled() {
+		return &Status{Routes: []Route{}, Peers: []PeerStatus{}}, nil
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	key, err := m.loadOrCreateKey()
+	if err != nil {
+		return nil, err
+	}
+	return m.status(ctx, key)
+}
+
+// status assembles the status for key. Callers must hold m.mu.
+func (m *manager) status(ctx context.Context, key privateKey) (*Status, error) {
+	peers, err := m.loadPeers()
+	if err != nil {
+		return nil, err
+	}
+	stats, err := m.kernel.peerStats(m.config.Interface)
+	if err != nil {
+		// Peers are still listed, without session state
+		logger.FromContext(ctx).WarnContext(ctx, "failed to read WireGuard peer state", "error", err)
+	}
+
+	status := &Status{
+		Enabled:    true,
+		Interface:  m.config.Interface,
+		PublicKey:  key.publicKey(),
+		ListenPort: m.config.ListenPort,
+		Endpoint:   m.config.Endpoint,
+		Routes:     m.localRoutes(),
+		Peers:      make([]PeerStatus, len(peers)),
+	}
+	for i, p := range peers {
+		status.Peers[i] = PeerStatus{Peer: p}
+		if s, ok := stats[p.PublicKey]; ok {
+			status.Peers[i].LatestHandshake = s.LatestHandshake
+			status.Peers[i].RxBytes = s.RxBytes
+			status.Peers[i].TxBytes = s.TxBytes
+		}
+	}
+	return status, nil
+}
+
+func (m *manager) AddPeer(ctx context.Context, peer Peer) (*Peer, error) {
+	if !m.enabled() {
+		return nil, ErrDisabled
+	}
+	normalized, err := normalizePeer(peer)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPeer, err)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	key, err := m.loadOrCreateKey()
+	if err != nil {
+		return nil, err
+	}
+	if normalized.PublicKey == key.publicKey() {
+		return nil, fmt.Errorf("%w: public key is this host's own", ErrInvalidPeer)
+	}
+	peers, err := m.loadPeers()
+	if err != nil {
+		return nil, err
+	}
+	if len(peers) >= MaxPeers {
+		return nil, fmt.Errorf("%w: at most %d peers are supported", ErrInvalidPeer, MaxPeers)
+	}
+	for _, p := range peers {
+		if p.Name == normalized.Name || p.PublicKey == normalized.PublicKey {
+			return nil, fmt.Errorf("%w: %s", ErrPeerExists, p.Name)
+		}
+	}
+	if err := m.checkRoutes(normalized, peers); err != nil {
+		return nil, err
+	}
+
+	normalized.CreatedAt = time.Now().UTC()
+	peers = append(peers, *normalized)
+	if err := m.savePeers(peers); err != nil {
+		return nil, err
+	}
+	if err := m.apply(ctx); err != nil {
+		return nil, err
+	}
+
+	logger.FromContext(ctx).InfoContext(ctx, "added overlay peer",
+		"name", normalized.Name, "endpoint", normalized.Endpoint, "routes", len(normalized.Routes))
+	return normalized, nil
+}
+
+func (m *manager) DeletePeer(ctx context.Context, name string) error {
+	if !m.enabled() {
+		return ErrDisabled
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	peers, err := m.loadPeers()
+	if err != nil {
+		return err
+	}
+	idx := slices.IndexFunc(peers, func(p Peer) bool { return p.Name == name })
+	if idx < 0 {
+		return fmt.Errorf("%w: %s", ErrPeerNotFound, name)
+	}
+	peers = slices.Delete(peers, idx, idx+1)
+	if err := m.savePeers(peers); err != nil {
+		return err
+	}
+	if err := m.apply(ctx); err != nil {
+		return err
+	}
+
+	logger.FromContext(ctx).InfoContext(ctx, "deleted overlay peer", "name", name)
+	return nil
+}
+
+func (m *manager) RotateKey(ctx context.Context) (*Status, error) {
+	if !m.enabled() {
+		return nil, ErrDisabled
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	key, err := generateKey()
+	if err != nil {
+		return nil, err
+	}
+	if err := m.saveKey(key); err != nil {
+		return nil, err
+	}
+	if err := m.apply(ctx); err != nil {
+		return nil, err
+	}
+
+	logger.FromContext(ctx).InfoContext(ctx, "rotated overlay key", "public_key", key.publicKey())
+	return m.status(ctx, key)
+}
+
+// apply pushes the key, peers and routes to the kernel. Callers must hold m.mu.
+func (m *manager) apply(ctx context.Context) error {
+	key, err := m.loadOrCreateKey()
+	if err != nil {
+		return err
+	}
+	peers, err := m.loadPeers()
+	if err != nil {
+		return err
+	}
+	if err := m.kernel.configure(m.config.Interface, key, m.config.ListenPort, peers, m.routedSubnets); err != nil {
+		return fmt.Errorf("configure WireGuard: %w", err)
+	}
+
+	var subnets []string
+	for _, p := range peers {
+		subnets = append(subnets, m.routedSubnets(p)...)
+	}
+	if err := m.kernel.syncRoutes(m.config.Interface, subnets); err != nil {
+		return fmt.Errorf("sync overlay routes: %w", err)
+	}
+	logger.FromContext(ctx).DebugContext(ctx, "applied overlay configuration", "peers", len(peers), "routes", len(subnets))
+	return nil
+}
+
+// routedSubnets returns the subnets of a peer's routes for networks that
+// exist on this host. They are the peer's WireGuard allowed IPs and are
+// routed through the interface.
+func (m *manager) routedSubnets(p Peer) []string {
+	var subnets []string
+	for _, r := range p.Routes {
+		if slices.ContainsFunc(m.config.Networks, func(n LocalNetwork) bool { return n.Name == r.Network }) {
+			subnets = append(subnets, r.Subnet)
+		}
+	}
+	return subnets
+}
+
+// localRoutes returns the routes this host advertises.
+func (m *manager) localRoutes() []Route {
+	routes := make([]Route, 0, len(m.config.Networks))
+	for _, n := range m.config.Networks {
+		_, ipNet, err := net.ParseCIDR(n.Subnet)
+		if err != nil {
+			continue
+		}
+		routes = append(routes, Route{Network: n.Name, Subnet: ipNet.String()})
+	}
+	return routes
+}
+
+// checkRoutes rejects routes that overlap a local subnet or another peer's route.
+func (m *manager) checkRoutes(peer *Peer, peers []Peer) error {
+	taken := make(map[string]string) // subnet -> owner
+	for _, r := range m.localRoutes() {
+		taken[r.Subnet] = "local network " + r.Network
+	}
+	for _, p := range peers {
+		for _, r := range p.Routes {
+			taken[r.Subnet] = "peer " + p.Name
+		}
+	}
+	for _, r := range peer.Routes {
+		_, want, _ := net.ParseCIDR(r.Subnet)
+		for subnet, owner := range taken {
+			_, have, _ := net.ParseCIDR(subnet)
+			if want.Contains(have.IP) || have.Contains(want.IP) {
+				return fmt.Errorf("%w: %s overlaps %s of %s", ErrRouteConflict, r.Subnet, subnet, owner)
+			}
+		}
+	}
+	return nil
+}
+
+// normalizePeer validates a peer and returns it with its key, endpoint and
+// subnets in canonical form.
+func normalizePeer(peer Peer) (*Peer, error) {
+	name := strings.ToLower(strings.TrimSpace(peer.Name))
+	if !peerNamePattern.MatchString(name) {
+		return nil, fmt.Errorf("name %q must be a lowercase hostname label", peer.Name)
+	}
+	key, err := parsePublicKey(peer.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+	out := &Peer{Name: name, PublicKey: key, Routes: []Route{}}
+
+	if endpoint := strings.TrimSpace(peer.Endpoint); endpoint != "" {
+		host, port, err := net.SplitHostPort(endpoint)
+		if err != nil || host == "" || port == "" {
+			return nil, fmt.Errorf("endpoint %q must be host:port", peer.Endpoint)
+		}
+		out.Endpoint = endpoint
+	}
+
+	if len(peer.Routes) == 0 {
+		return nil, fmt.Errorf("at least one route is required")
+	}
+	for _, r := range peer.Routes {
+		_, ipNet, err := net.ParseCIDR(strings.TrimSpace(r.Subnet))
+		if err != nil || ipNet.IP.To4() == nil {
+			return nil, fmt.Errorf("route subnet %q is not an IPv4 CIDR", r.Subnet)
+		}
+		if ones, _ := ipNet.Mask.Size(); ones == 0 {
+			return nil, fmt.Errorf("route subnet %q covers every address", r.Subnet)
+		}
+		network := strings.TrimSpace(r.Network)
+		if network == "" {
+			return nil, fmt.Errorf("route %s has no network", r.Subnet)
+		}
+		out.Routes = append(out.Routes, Route{Network: network, Subnet: ipNet.String()})
+	}
+	return out, nil
+}
+
+// loadPeers reads the registered peers, sorted by name.
+func (m *manager) loadPeers() ([]Peer, error) {
+	data, err := os.ReadFile(m.paths.OverlayPeers())
+	if errors.Is(err, os.ErrNotExist) {
+		return []Peer{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read overlay peers: %w", err)
+	}
+	var peers []Peer
+	if err := json.Unmarshal(data, &peers); err != nil {
+		return nil, fmt.Errorf("unmarshal overlay peers: %w", err)
+	}
+	slices.SortFunc(peers, func(a, b Peer) int { return strings.Compare(a.Name, b.Name) })
+	return peers, nil
+}
+
+// savePeers persists the registered peers.
+func (m *manager) savePeers(peers []Peer) error {
+	data, err := json.MarshalIndent(peers, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshal overlay peers: %w", err)
+	}
+	if err := writeFileAtomic(m.paths.OverlayPeers(), data, 0644); err != nil {
+		return fmt.Errorf("write overlay peers: %w", err)
+	}
+	return nil
+}
+
+// writeFileAtomic writes data to a temporary file and renames it into place.
+func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if err := tmp.Chmod(perm); err != nil {
+		tmp.Close()
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,179 @@
+package overlay
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/kernel/hypeman/lib/paths"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeKernel records what the manager applies.
+type fakeKernel struct {
+	config  string
+	subnets []string
+	stats   map[string]peerState
+}
+
+func (k *fakeKernel) ensureInterface(string) error          { return nil }
+func (k *fakeKernel) ensureForwarding(string, string) error { return nil }
+
+func (k *fakeKernel) configure(_ string, key privateKey, port int, peers []Peer, allowedIPs func(Peer) []string) error {
+	k.config = renderConfig(key, port, peers, allowedIPs)
+	return nil
+}
+
+func (k *fakeKernel) syncRoutes(_ string, subnets []string) error {
+	k.subnets = subnets
+	return nil
+}
+
+func (k *fakeKernel) peerStats(string) (map[string]peerState, error) { return k.stats, nil }
+
+func newTestManager(t *testing.T) (*manager, *fakeKernel) {
+	k := &fakeKernel{}
+	return &manager{
+		paths: paths.New(t.TempDir()),
+		config: Config{
+			Interface:  "hypeman-wg",
+			ListenPort: 51820,
+			Endpoint:   "203.0.113.10:51820",
+			Networks:   []LocalNetwork{{Name: "default", Subnet: "10.100.0.0/16", Bridge: "vmbr0"}},
+		},
+		kernel: k,
+	}, k
+}
+
+func testPublicKey(t *testing.T) string {
+	key, err := generateKey()
+	require.NoError(t, err)
+	return key.publicKey()
+}
+
+func TestOverlayPeers(t *testing.T) {
+	m, k := newTestManager(t)
+	ctx := context.Background()
+	require.NoError(t, m.Initialize(ctx))
+
+	status, err := m.Status(ctx)
+	require.NoError(t, err)
+	assert.True(t, status.Enabled)
+	assert.Equal(t, []Route{{Network: "default", Subnet: "10.100.0.0/16"}}, status.Routes)
+	assert.NotEmpty(t, status.PublicKey)
+
+	info, err := os.Stat(m.paths.OverlayPrivateKey())
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+	pub := testPublicKey(t)
+	peer, err := m.AddPeer(ctx, Peer{
+		Name:      "Host-B",
+		PublicKey: pub,
+		Endpoint:  "203.0.113.11:51820",
+		Routes: []Route{
+			{Network: "default", Subnet: "10.101.0.1/16"},
+			{Network: "storage", Subnet: "10.200.0.0/24"},
+		},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "host-b", peer.Name)
+	assert.Equal(t, "10.101.0.0/16", peer.Routes[0].Subnet)
+
+	// Only routes of networks that exist here are installed
+	assert.Equal(t, []string{"10.101.0.0/16"}, k.subnets)
+	assert.Contains(t, k.config, "PublicKey = "+pub+"\nEndpoint = 203.0.113.11:51820\nPersistentKeepalive = 25\nAllowedIPs = 10.101.0.0/16\n")
+
+	_, err = m.AddPeer(ctx, Peer{Name: "host-b", PublicKey: testPublicKey(t), Routes: []Route{{Network: "default", Subnet: "10.102.0.0/16"}}})
+	assert.ErrorIs(t, err, ErrPeerExists)
+	_, err = m.AddPeer(ctx, Peer{Name: "host-c", PublicKey: testPublicKey(t), Routes: []Route{{Network: "default", Subnet: "10.101.128.0/17"}}})
+	assert.ErrorIs(t, err, ErrRouteConflict)
+	_, err = m.AddPeer(ctx, Peer{Name: "host-c", PublicKey: testPublicKey(t), Routes: []Route{{Network: "default", Subnet: "10.0.0.0/8"}}})
+	assert.ErrorIs(t, err, ErrRouteConflict, "overlaps the local subnet")
+	_, err = m.AddPeer(ctx, Peer{Name: "self", PublicKey: status.PublicKey, Routes: []Route{{Network: "default", Subnet: "10.103.0.0/16"}}})
+	assert.ErrorIs(t, err, ErrInvalidPeer)
+
+	// Peers persist across restarts
+	restarted := &manager{paths: m.paths, config: m.config, kernel: k}
+	status, err = restarted.Status(ctx)
+	require.NoError(t, err)
+	require.Len(t, status.Peers, 1)
+	assert.Equal(t, pub, status.Peers[0].PublicKey)
+
+	require.NoError(t, m.DeletePeer(ctx, "host-b"))
+	assert.Empty(t, k.subnets)
+	assert.ErrorIs(t, m.DeletePeer(ctx, "host-b"), ErrPeerNotFound)
+}
+
+func TestOverlayRotateKey(t *testing.T) {
+	m, k := newTestManager(t)
+	ctx := context.Background()
+	require.NoError(t, m.Initialize(ctx))
+
+	before, err := m.Status(ctx)
+	require.NoError(t, err)
+	after, err := m.RotateKey(ctx)
+	require.NoError(t, err)
+	assert.NotEqual(t, before.PublicKey, after.PublicKey)
+
+	key, err := m.loadOrCreateKey()
+	require.NoError(t, err)
+	assert.Equal(t, after.PublicKey, key.publicKey())
+	assert.Contains(t, k.config, "PrivateKey = "+key.String()+"\n")
+}
+
+func TestOverlayDisabled(t *testing.T) {
+	m, _ := newTestManager(t)
+	m.config.ListenPort = 0
+	ctx := context.Background()
+
+	require.NoError(t, m.Initialize(ctx))
+	status, err := m.Status(ctx)
+	require.NoError(t, err)
+	assert.False(t, status.Enabled)
+	_, err = m.RotateKey(ctx)
+	assert.ErrorIs(t, err, ErrDisabled)
+	_, err = m.AddPeer(ctx, Peer{Name: "host-b"})
+	assert.ErrorIs(t, err, ErrDisabled)
+	assert.NoFileExists(t, m.paths.OverlayPrivateKey())
+}
+
+func TestNormalizePeer_Validation(t *testing.T) {
+	pub := testPublicKey(t)
+	routes := []Route{{Network: "default", Subnet: "10.101.0.0/16"}}
+
+	tests := []struct {
+		name string
+		peer Peer
+	}{
+		{"bad name", Peer{Name: "host b", PublicKey: pub, Routes: routes}},
+		{"bad key", Peer{Name: "host-b", PublicKey: "not-a-key", Routes: routes}},
+		{"short key", Peer{Name: "host-b", PublicKey: "AAAA", Routes: routes}},
+		{"bad endpoint", Peer{Name: "host-b", PublicKey: pub, Endpoint: "203.0.113.11", Routes: routes}},
+		{"no routes", Peer{Name: "host-b", PublicKey: pub}},
+		{"bad subnet", Peer{Name: "host-b", PublicKey: pub, Routes: []Route{{Network: "default", Subnet: "10.101.0.0"}}}},
+		{"default route", Peer{Name: "host-b", PublicKey: pub, Routes: []Route{{Network: "default", Subnet: "0.0.0.0/0"}}}},
+		{"no network", Peer{Name: "host-b", PublicKey: pub, Routes: []Route{{Subnet: "10.101.0.0/16"}}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := normalizePeer(tt.peer)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestParseDump(t *testing.T) {
+	out := "priv\tpub\t51820\toff\n" +
+		"peerA=\t(none)\t203.0.113.11:51820\t10.101.0.0/16\t1700000000\t1024\t2048\t25\n" +
+		"peerB=\t(none)\t(none)\t10.102.0.0/16\t0\t0\t0\toff\n"
+
+	stats := parseDump(out)
+	require.Len(t, stats, 2)
+	require.NotNil(t, stats["peerA="].LatestHandshake)
+	assert.Equal(t, int64(1700000000), stats["peerA="].LatestHandshake.Unix())
+	assert.Equal(t, int64(1024), stats["peerA="].RxBytes)
+	assert.Equal(t, int64(2048), stats["peerA="].TxBytes)
+	assert.Nil(t, stats["peerB="].LatestHandshake)
+}
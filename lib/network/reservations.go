@@ -0,0 +1,242 @@
+package network
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/kernel/hypeman/lib/logger"
+)
+
+// reservation is an instance's claim on an address in a network. It outlives
+// the TAP device: stopping or putting an instance in standby keeps it, so the
+// instance comes back with the same IP and MAC. Only deleting the instance
+// frees the address.
+type reservation struct {
+	InstanceID string    `json:"instance_id"`
+	Network    string    `json:"network"`
+	IP         string    `json:"ip"`
+	MAC        string    `json:"mac"`
+	TAPDevice  string    `json:"tap_device"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// reservationFile is the on-disk form of network/{name}/allocations.json.
+type reservationFile struct {
+	Reservations map[string]reservation `json:"reservations"`
+}
+
+// loadReservations reads a network's reservations keyed by instance ID. If
+// the file doesn't exist yet (hosts upgraded from metadata-derived
+// allocations), reservations are seeded from the IP and MAC recorded in
+// instance metadata; the seed is persisted by the next save.
+func (m *manager) loadReservations(network string) (map[string]reservation, error) {
+	data, err := os.ReadFile(m.paths.NetworkAllocations(network))
+	if errors.Is(err, os.ErrNotExist) {
+		return m.seedReservations(network)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read allocations: %w", err)
+	}
+
+	var file reservationFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("parse allocations: %w", err)
+	}
+	if file.Reservations == nil {
+		file.Reservations = make(map[string]reservation)
+	}
+	return file.Reservations, nil
+}
+
+// seedReservations builds reservations from the metadata of networked instances.
+func (m *manager) seedReservations(network string) (map[string]reservation, error) {
+	res := make(map[string]reservation)
+	guests, err := os.ReadDir(m.paths.GuestsDir())
+	if errors.Is(err, os.ErrNotExist) {
+		return res, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read guests dir: %w", err)
+	}
+
+	for _, guest := range guests {
+		if !guest.IsDir() {
+			continue
+		}
+		meta, err := m.loadInstanceMetadata(guest.Name())
+		if err != nil || !meta.NetworkEnabled || meta.IP == "" || meta.MAC == "" {
+			continue
+		}
+		res[guest.Name()] = reservation{
+			InstanceID: guest.Name(),
+			Network:    network,
+			IP:         meta.IP,
+			MAC:        meta.MAC,
+			TAPDevice:  generateTAPName(guest.Name()),
+		}
+	}
+	return res, nil
+}
+
+// saveReservations persists a network's reservations. Callers must hold m.mu.
+func (m *manager) saveReservations(network string, res map[string]reservation) error {
+	if err := os.MkdirAll(m.paths.NetworkDir(network), 0755); err != nil {
+		return fmt.Errorf("create network directory: %w", err)
+	}
+	data, err := json.MarshalIndent(reservationFile{Reservations: res}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshal allocations: %w", err)
+	}
+	if err := writeFileDurable(m.paths.NetworkAllocations(network), data); err != nil {
+		return fmt.Errorf("write allocations: %w", err)
+	}
+	return nil
+}
+
+// reserve returns the instance's reservation on the network, creating one if
+// it has none or its address no longer fits the subnet. The reservation is
+// on disk before it's returned, so a crash while the TAP device is being
+// created can't hand the same address to another instance. Callers must hold
+// m.mu.
+func (m *manager) reserve(ctx context.Context, network *Network, instanceID string) (reservation, error) {
+	res, err := m.loadReservations(network.Name)
+	if err != nil {
+		return reservation{}, err
+	}
+
+	_, ipNet, err := net.ParseCIDR(network.Subnet)
+	if err != nil {
+		return reservation{}, fmt.Errorf("parse subnet: %w", err)
+	}
+	if r, ok := res[instanceID]; ok && r.MAC != "" && ipNet.Contains(net.ParseIP(r.IP)) {
+		return r, nil
+	}
+
+	// Random selection reduces predictability and helps distribute IPs across the subnet.
+	// This is especially useful for large /16 networks and reduces conflicts when
+	// moving standby VMs across hosts.
+	ip, err := m.allocateNextIP(ctx, network.Subnet, res, instanceID)
+	if err != nil {
+		return reservation{}, fmt.Errorf("allocate IP: %w", err)
+	}
+	// MAC uses the 02:00:00:... format (locally administered)
+	mac, err := generateMAC()
+	if err != nil {
+		return reservation{}, fmt.Errorf("generate MAC: %w", err)
+	}
+
+	r := reservation{
+		InstanceID: instanceID,
+		Network:    network.Name,
+		IP:         ip,
+		MAC:        mac,
+		TAPDevice:  generateTAPName(instanceID),
+		CreatedAt:  time.Now().UTC(),
+	}
+	res[instanceID] = r
+	if err := m.saveReservations(network.Name, res); err != nil {
+		return reservation{}, err
+	}
+	return r, nil
+}
+
+// DeleteAllocation frees the address reserved for an instance. It's called
+// when the instance is deleted; stopping an instance only releases its TAP
+// device (ReleaseAllocation) and keeps the reservation.
+func (m *manager) DeleteAllocation(ctx context.Context, instanceID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	res, err := m.loadReservations("default")
+	if err != nil {
+		return err
+	}
+	r, ok := res[instanceID]
+	if !ok {
+		return nil
+	}
+	delete(res, instanceID)
+	if err := m.saveReservations("default", res); err != nil {
+		return err
+	}
+
+	log := logger.FromContext(ctx)
+	log.InfoContext(ctx, "freed network reservation",
+		"instance_id", instanceID, "network", r.Network, "ip", r.IP)
+
+	// Best effort: stale entries only matter once the IP is reused
+	if n, err := flushConntrack(r.IP); err != nil {
+		log.WarnContext(ctx, "failed to flush conntrack entries", "ip", r.IP, "error", err)
+	} else if n > 0 {
+		log.DebugContext(ctx, "flushed conntrack entries", "ip", r.IP, "entries", n)
+	}
+	return nil
+}
+
+// pruneReservations persists the default network's reservations, seeding them
+// on first run, and drops those whose instance no longer exists (a delete that
+// failed after removing the instance's data). It returns how many were dropped.
+func (m *manager) pruneReservations(ctx context.Context) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	res, err := m.loadReservations("default")
+	if err != nil {
+		return 0, err
+	}
+	pruned := 0
+	for id, r := range res {
+		if _, err := os.Stat(m.paths.InstanceMetadata(id)); errors.Is(err, os.ErrNotExist) {
+			logger.FromContext(ctx).InfoContext(ctx, "dropping reservation of missing instance",
+				"instance_id", id, "ip", r.IP)
+			delete(res, id)
+			pruned++
+		}
+	}
+	if err := m.saveReservations("default", res); err != nil {
+		return 0, err
+	}
+	return pruned, nil
+}
+
+// writeFileDurable is writeFileAtomic with the data and the rename flushed to
+// disk, so the file survives a host crash in either its old or new form.
+func writeFileDurable(path string, data []byte) error {
+	dir := filepath.Dir(path)
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return err
+	}
+
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	return d.Sync()
+}
@@ -0,0 +1,97 @@
+package network
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/kernel/hypeman/cmd/api/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReserve(t *testing.T) {
+	m := newDNSTestManager(t)
+	ctx := context.Background()
+	network := &Network{Name: "default", Subnet: "10.100.0.0/24"}
+
+	a, err := m.reserve(ctx, network, "inst-a")
+	require.NoError(t, err)
+	assert.Equal(t, "hype-inst-a", a.TAPDevice)
+	assert.NotEqual(t, "10.100.0.0", a.IP)
+	assert.NotEqual(t, "10.100.0.1", a.IP)
+
+	// The same instance gets the same address back
+	again, err := m.reserve(ctx, network, "inst-a")
+	require.NoError(t, err)
+	assert.Equal(t, a.IP, again.IP)
+	assert.Equal(t, a.MAC, again.MAC)
+
+	b, err := m.reserve(ctx, network, "inst-b")
+	require.NoError(t, err)
+	assert.NotEqual(t, a.IP, b.IP)
+
+	// Reservations survive a restart of the manager
+	restarted := &manager{paths: m.paths, config: &config.Config{}}
+	res, err := restarted.loadReservations("default")
+	require.NoError(t, err)
+	assert.Equal(t, a.IP, res["inst-a"].IP)
+	assert.Equal(t, b.MAC, res["inst-b"].MAC)
+
+	// An address outside a changed subnet is replaced
+	moved, err := m.reserve(ctx, &Network{Name: "default", Subnet: "10.200.0.0/24"}, "inst-a")
+	require.NoError(t, err)
+	assert.Contains(t, moved.IP, "10.200.0.")
+	assert.NotEqual(t, a.MAC, moved.MAC)
+}
+
+func TestReserve_Full(t *testing.T) {
+	m := newDNSTestManager(t)
+	ctx := context.Background()
+	network := &Network{Name: "default", Subnet: "10.100.0.0/30"}
+
+	// A /30 has a single address after network, gateway and broadcast
+	_, err := m.reserve(ctx, network, "inst-a")
+	require.NoError(t, err)
+	_, err = m.reserve(ctx, network, "inst-b")
+	assert.ErrorIs(t, err, ErrNoAvailableIP)
+
+	// Freeing the reservation makes the address available again
+	require.NoError(t, m.DeleteAllocation(ctx, "inst-a"))
+	_, err = m.reserve(ctx, network, "inst-b")
+	require.NoError(t, err)
+}
+
+func TestReservations_SeedAndPrune(t *testing.T) {
+	m := newDNSTestManager(t)
+	ctx := context.Background()
+
+	writeMeta := func(id, body string) {
+		require.NoError(t, os.MkdirAll(m.paths.InstanceDir(id), 0755))
+		require.NoError(t, os.WriteFile(m.paths.InstanceMetadata(id), []byte(body), 0644))
+	}
+	writeMeta("inst-a", `{"Name":"web","NetworkEnabled":true,"IP":"10.100.0.2","MAC":"02:00:00:00:00:02"}`)
+	writeMeta("inst-b", `{"Name":"db","NetworkEnabled":false}`)
+
+	// Without allocations.json, reservations come from instance metadata
+	res, err := m.loadReservations("default")
+	require.NoError(t, err)
+	require.Len(t, res, 1)
+	assert.Equal(t, "10.100.0.2", res["inst-a"].IP)
+	assert.Equal(t, "hype-inst-a", res["inst-a"].TAPDevice)
+
+	// Initialization persists the seed
+	pruned, err := m.pruneReservations(ctx)
+	require.NoError(t, err)
+	assert.Zero(t, pruned)
+	assert.FileExists(t, m.paths.NetworkAllocations("default"))
+
+	// A reservation whose instance is gone is dropped on the next initialization
+	require.NoError(t, os.RemoveAll(m.paths.InstanceDir("inst-a")))
+	pruned, err = m.pruneReservations(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, pruned)
+	res, err = m.loadReservations("default")
+	require.NoError(t, err)
+	assert.Empty(t, res)
+}
@@ -0,0 +1,77 @@
+package network
+
+import (
+	"fmt"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// tcSentPattern matches the statistics line of `tc -s qdisc/class show`, e.g.
+// " Sent 12345 bytes 67 pkt (dropped 1, overlimits 3 requeues 0)"
+var tcSentPattern = regexp.MustCompile(`Sent (\d+) bytes (\d+) pkt \(dropped (\d+), overlimits (\d+)`)
+
+// ShapingStats are the traffic shaper counters for one direction of an instance's traffic.
+// Counters reset when the TAP device is recreated (start, restore).
+type ShapingStats struct {
+	Bytes      int64 // Bytes sent through the shaper
+	Packets    int64 // Packets sent through the shaper
+	Dropped    int64 // Packets dropped by the shaper (queue full)
+	Overlimits int64 // Times the shaper delayed packets because the rate was exceeded
+}
+
+// parseTcStats parses the first statistics line from tc -s output.
+func parseTcStats(output string) (ShapingStats, bool) {
+	match := tcSentPattern.FindStringSubmatch(output)
+	if match == nil {
+		return ShapingStats{}, false
+	}
+	var values [4]int64
+	for i := range values {
+		values[i], _ = strconv.ParseInt(match[i+1], 10, 64)
+	}
+	return ShapingStats{
+		Bytes:      values[0],
+		Packets:    values[1],
+		Dropped:    values[2],
+		Overlimits: values[3],
+	}, true
+}
+
+// shapingStats returns the download (TAP TBF) and upload (bridge HTB class)
+// shaper counters for a TAP device, or the download policer and upload TBF of
+// a macvtap device. ok is false for directions without a shaper.
+func (m *manager) shapingStats(tapName string) (download, upload ShapingStats, downloadOK, uploadOK bool) {
+	if m.uplink().Mode == UplinkModeMacvtap {
+		if output, err := runTcShow("filter", "show", "dev", tapName, "ingress"); err == nil && strings.Contains(output, "police") {
+			download, downloadOK = parseTcStats(output)
+		}
+		if output, err := runTcShow("qdisc", "show", "dev", tapName, "root"); err == nil && strings.Contains(output, "qdisc tbf") {
+			upload, uploadOK = parseTcStats(output)
+		}
+		return download, upload, downloadOK, uploadOK
+	}
+	// Without a download limit the TAP has the kernel's default root qdisc, which isn't a shaper
+	if output, err := runTcShow("qdisc", "show", "dev", tapName, "root"); err == nil && strings.Contains(output, "qdisc tbf") {
+		download, downloadOK = parseTcStats(output)
+	}
+	classID := fmt.Sprintf("1:%s", deriveClassID(tapName))
+	if output, err := runTcShow("class", "show", "dev", m.config.BridgeName, "classid", classID); err == nil {
+		upload, uploadOK = parseTcStats(output)
+	}
+	return download, upload, downloadOK, uploadOK
+}
+
+// runTcShow runs `tc -s <args>` and returns its output.
+func runTcShow(args ...string) (string, error) {
+	cmd := exec.Command("tc", append([]string{"-s"}, args...)...)
+	cmd.SysProcAttr = &syscall.SysProcAttr{
+		AmbientCaps: []uintptr{unix.CAP_NET_ADMIN},
+	}
+	output, err := cmd.Output()
+	return string(output), err
+}
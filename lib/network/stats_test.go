@@ -0,0 +1,40 @@
+package network
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseTcStats(t *testing.T) {
+	tbf := `qdisc tbf 8001: root refcnt 2 rate 1Gbit burst 500000b lat 50ms 
+ Sent 123456789 bytes 84210 pkt (dropped 12, overlimits 3456 requeues 0) 
+ backlog 0b 0p requeues 0
+`
+	stats, ok := parseTcStats(tbf)
+	assert.True(t, ok)
+	assert.Equal(t, ShapingStats{Bytes: 123456789, Packets: 84210, Dropped: 12, Overlimits: 3456}, stats)
+
+	htb := `class htb 1:a1b2 parent 1:1 leaf 8002: prio 1 rate 100Mbit ceil 400Mbit burst 1600b cburst 1600b 
+ Sent 4096 bytes 4 pkt (dropped 0, overlimits 0 requeues 0) 
+ backlog 0b 0p requeues 0
+ lended: 4 borrowed: 0 giants: 0
+`
+	stats, ok = parseTcStats(htb)
+	assert.True(t, ok)
+	assert.Equal(t, ShapingStats{Bytes: 4096, Packets: 4}, stats)
+
+	_, ok = parseTcStats("")
+	assert.False(t, ok)
+}
+
+func TestHTBClassParams(t *testing.T) {
+	// 12.5 MB/s = 100 Mbit; ceil defaults to rate
+	assert.Equal(t,
+		[]string{"htb", "rate", "100mbit", "ceil", "100mbit", "prio", "1"},
+		htbClassParams(12500000, 0, 0))
+
+	assert.Equal(t,
+		[]string{"htb", "rate", "100mbit", "ceil", "400mbit", "burst", "2000000", "cburst", "2000000", "prio", "1"},
+		htbClassParams(12500000, 50000000, 2000000))
+}
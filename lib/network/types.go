@@ -39,20 +39,33 @@ type Allocation struct {
 
 // NetworkConfig is the configuration returned after allocation
 type NetworkConfig struct {
-	IP        string
-	MAC       string
-	Gateway   string
-	Netmask   string
-	DNS       string
-	TAPDevice string
+	IP            string
+	MAC           string
+	Gateway       string
+	Netmask       string
+	DNS           string
+	SearchDomains []string // Custom search domains for the network
+	ServiceDomain string   // Zone sibling instances are served in, e.g. "default.hypeman"
+	MTU           int      // Interface MTU from the network's DHCP options (0 = default)
+	NTPServers    []string // NTP servers from the network's DHCP options
+	Routes        []Route  // Static routes from the network's DHCP options
+	TAPDevice     string
+	Macvtap       bool // TAPDevice is a macvtap device (macvtap uplink), attached by file descriptor
+}
+
+// BandwidthLimits configures traffic shaping on an instance's TAP device
+type BandwidthLimits struct {
+	DownloadBps        int64 // Download rate limit in bytes/sec (external→VM, TAP egress TBF), 0 = unlimited
+	DownloadBurstBytes int64 // TBF bucket size in bytes (0 = derived from rate and download burst multiplier)
+	UploadBps          int64 // Upload rate limit in bytes/sec (VM→external, HTB class rate), 0 = unlimited
+	UploadCeilBps      int64 // Upload ceiling in bytes/sec (HTB burst when bandwidth available, 0 = same as UploadBps)
+	UploadBurstBytes   int64 // Bytes the HTB class may send at full speed before being shaped (0 = tc default)
 }
 
 // AllocateRequest is the request to allocate network for an instance
 // Always allocates from the default network
 type AllocateRequest struct {
-	InstanceID    string
-	InstanceName  string
-	DownloadBps   int64 // Download rate limit in bytes/sec (external→VM, TAP egress TBF)
-	UploadBps     int64 // Upload rate limit in bytes/sec (VM→external, HTB class rate)
-	UploadCeilBps int64 // Upload ceiling in bytes/sec (HTB burst when bandwidth available, 0 = same as UploadBps)
+	InstanceID   string
+	InstanceName string
+	BandwidthLimits
 }
@@ -0,0 +1,458 @@
+package network
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+
+	"github.com/kernel/hypeman/lib/logger"
+	"github.com/vishvananda/netlink"
+)
+
+// Uplink modes: how a network reaches the host's network.
+const (
+	// UplinkModeNAT keeps guests on a private subnet behind the host: the
+	// bridge holds the gateway address and traffic is masqueraded out of
+	// UPLINK_INTERFACE.
+	UplinkModeNAT = "nat"
+	// UplinkModeVLAN binds the bridge to a tagged sub-interface of the
+	// uplink interface, so guests sit directly on that VLAN with routable
+	// addresses and use the datacenter router (SUBNET_GATEWAY) as gateway.
+	UplinkModeVLAN = "vlan"
+	// UplinkModeMacvtap attaches each guest to a macvtap device on the
+	// uplink interface (or its VLAN sub-interface) instead of a TAP on the
+	// bridge, so its traffic bypasses the host's bridge and IP stack.
+	UplinkModeMacvtap = "macvtap"
+)
+
+// Uplink is how a network reaches the host's network
+type Uplink struct {
+	Mode      string `json:"mode"`
+	Interface string `json:"interface,omitempty"` // Host NIC of the vlan and macvtap modes
+	VLAN      int    `json:"vlan,omitempty"`      // VLAN ID: required in vlan mode, optional in macvtap mode
+}
+
+// routed reports whether guests get addresses of the datacenter network
+// rather than being NAT'd by the host
+func (u Uplink) routed() bool {
+	return u.Mode != UplinkModeNAT
+}
+
+// macvtapParent returns the interface macvtap devices are created on: the
+// uplink interface, or its VLAN sub-interface if a VLAN is set.
+func (u Uplink) macvtapParent() string {
+	if u.VLAN > 0 {
+		return vlanInterfaceName(u.Interface, u.VLAN)
+	}
+	return u.Interface
+}
+
+// defaultUplink is the uplink of networks without one of their own, from
+// UPLINK_MODE, UPLINK_INTERFACE and UPLINK_VLAN.
+func (m *manager) defaultUplink() Uplink {
+	if m.config.UplinkMode == "" || m.config.UplinkMode == UplinkModeNAT {
+		return Uplink{Mode: UplinkModeNAT}
+	}
+	return Uplink{Mode: m.config.UplinkMode, Interface: m.config.UplinkInterface, VLAN: m.config.UplinkVLAN}
+}
+
+// uplink returns the uplink in effect for the default network.
+func (m *manager) uplink() Uplink {
+	m.uplinkMu.Lock()
+	defer m.uplinkMu.Unlock()
+	if m.activeUplink != nil {
+		return *m.activeUplink
+	}
+	return m.defaultUplink()
+}
+
+func (m *manager) setUplink(u Uplink) {
+	m.uplinkMu.Lock()
+	defer m.uplinkMu.Unlock()
+	m.activeUplink = &u
+}
+
+// GetUplink returns how a network reaches the host's network. Only the
+// default network exists, so other names return ErrNotFound.
+func (m *manager) GetUplink(ctx context.Context, networkName string) (*Uplink, error) {
+	if err := checkNetworkName(networkName); err != nil {
+		return nil, err
+	}
+	u := m.uplink()
+	return &u, nil
+}
+
+// SetUplink changes how a network reaches the host's network and applies it
+// to the bridge right away. No instance on the network may be running or in
+// standby: their devices and snapshots belong to the uplink they were
+// created with. The uplink is kept across restarts, over UPLINK_MODE. Only
+// the default network's uplink can be selected.
+func (m *manager) SetUplink(ctx context.Context, networkName string, uplink Uplink) (*Uplink, error) {
+	log := logger.FromContext(ctx)
+
+	if err := checkNetworkName(networkName); err != nil {
+		return nil, err
+	}
+	normalized, err := m.normalizeUplink(uplink)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidUplink, err)
+	}
+
+	// Held throughout so no instance joins the network meanwhile
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	allocs, err := m.ListAllocations(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("list allocations: %w", err)
+	}
+	if len(allocs) > 0 {
+		return nil, fmt.Errorf("%w: %d instances on network %s are running or in standby", ErrUplinkInUse, len(allocs), networkName)
+	}
+
+	previous := m.uplink()
+	m.setUplink(*normalized)
+	err = m.applyUplink(ctx)
+	if err == nil {
+		err = m.saveUplink(networkName, normalized)
+	}
+	if err != nil {
+		m.setUplink(previous)
+		if restoreErr := m.applyUplink(ctx); restoreErr != nil {
+			log.ErrorContext(ctx, "failed to restore previous uplink", "network", networkName, "mode", previous.Mode, "error", restoreErr)
+		}
+		return nil, err
+	}
+
+	log.InfoContext(ctx, "set network uplink", "network", networkName,
+		"mode", normalized.Mode, "interface", normalized.Interface, "vlan", normalized.VLAN)
+	return normalized, nil
+}
+
+// normalizeUplink validates an uplink, filling in UPLINK_INTERFACE as the
+// interface of the vlan and macvtap modes if none is given.
+func (m *manager) normalizeUplink(u Uplink) (*Uplink, error) {
+	out := &Uplink{Mode: strings.ToLower(strings.TrimSpace(u.Mode))}
+	switch out.Mode {
+	case UplinkModeNAT:
+		if u.Interface != "" || u.VLAN != 0 {
+			return nil, fmt.Errorf("interface and vlan only apply to the vlan and macvtap modes")
+		}
+		return out, nil
+	case UplinkModeVLAN, UplinkModeMacvtap:
+	default:
+		return nil, fmt.Errorf("mode must be nat, vlan or macvtap, got %q", u.Mode)
+	}
+
+	if err := m.checkRoutedUplink(out.Mode); err != nil {
+		return nil, err
+	}
+	out.Interface = strings.TrimSpace(u.Interface)
+	if out.Interface == "" {
+		out.Interface = m.config.UplinkInterface
+	}
+	if out.Interface == "" {
+		return nil, fmt.Errorf("the %s mode needs an interface", out.Mode)
+	}
+	if _, err := net.InterfaceByName(out.Interface); err != nil {
+		return nil, fmt.Errorf("interface %s not found", out.Interface)
+	}
+
+	out.VLAN = u.VLAN
+	if out.VLAN == 0 && out.Mode == UplinkModeVLAN {
+		return nil, fmt.Errorf("the vlan mode needs a VLAN ID")
+	}
+	if out.VLAN < 0 || out.VLAN > 4094 {
+		return nil, fmt.Errorf("vlan must be between 1 and 4094, got %d", out.VLAN)
+	}
+	return out, nil
+}
+
+// checkRoutedUplink reports why a vlan or macvtap uplink can't be used, if
+// the host serves guests from the bridge gateway.
+func (m *manager) checkRoutedUplink(mode string) error {
+	if m.metadataEnabled() {
+		return fmt.Errorf("the %s mode can't be used with the metadata service (METADATA_PORT): it's served from the bridge gateway", mode)
+	}
+	if m.config.OverlayListenPort > 0 {
+		return fmt.Errorf("the %s mode can't be used with the overlay (OVERLAY_LISTEN_PORT): guests don't route through the host", mode)
+	}
+	return nil
+}
+
+// loadUplink reads the uplink set for a network, or nil if none is stored.
+func (m *manager) loadUplink(networkName string) (*Uplink, error) {
+	data, err := os.ReadFile(m.paths.NetworkUplinkConfig(networkName))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read uplink: %w", err)
+	}
+	var u Uplink
+	if err := json.Unmarshal(data, &u); err != nil {
+		return nil, fmt.Errorf("unmarshal uplink: %w", err)
+	}
+	return &u, nil
+}
+
+func (m *manager) saveUplink(networkName string, u *Uplink) error {
+	if err := os.MkdirAll(m.paths.NetworkDir(networkName), 0755); err != nil {
+		return fmt.Errorf("create network directory: %w", err)
+	}
+	data, err := json.MarshalIndent(u, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshal uplink: %w", err)
+	}
+	if err := writeFileAtomic(m.paths.NetworkUplinkConfig(networkName), data); err != nil {
+		return fmt.Errorf("write uplink: %w", err)
+	}
+	return nil
+}
+
+// applyUplink sets up the default network's bridge for the uplink in effect,
+// undoing what an earlier uplink set up.
+func (m *manager) applyUplink(ctx context.Context) error {
+	gateway := m.gateway()
+	if gateway == "" {
+		return fmt.Errorf("no gateway for subnet %s", m.config.SubnetCIDR)
+	}
+	if err := m.createBridge(ctx, m.config.BridgeName, gateway, m.config.SubnetCIDR); err != nil {
+		return fmt.Errorf("setup default network: %w", err)
+	}
+	return nil
+}
+
+// vlanInterfaceName returns the name of the tagged sub-interface, e.g.
+// eth1.100, or vlan100 if that doesn't fit the 15-char interface name limit.
+func vlanInterfaceName(parent string, vlanID int) string {
+	name := fmt.Sprintf("%s.%d", parent, vlanID)
+	if len(name) > 15 {
+		return fmt.Sprintf("vlan%d", vlanID)
+	}
+	return name
+}
+
+// ensureRoutedUplink sets up a vlan or macvtap uplink for the bridge. The
+// bridge carries no address in these modes, and guests are routed by the
+// datacenter network rather than masqueraded by the host: the gateway
+// address, NAT and forwarding rules of an earlier nat uplink are removed,
+// and so are VLAN sub-interfaces an earlier vlan uplink bound to the bridge.
+func (m *manager) ensureRoutedUplink(ctx context.Context, bridge netlink.Link, u Uplink) error {
+	addrs, err := netlink.AddrList(bridge, netlink.FAMILY_V4)
+	if err != nil {
+		return fmt.Errorf("list bridge addresses: %w", err)
+	}
+	for _, addr := range addrs {
+		if err := netlink.AddrDel(bridge, &addr); err != nil {
+			return fmt.Errorf("remove bridge address %s: %w", addr.IPNet, err)
+		}
+	}
+	m.deleteNATRuleByComment("POSTROUTING", commentNAT)
+	m.deleteForwardRuleByComment(commentFwdOut)
+	m.deleteForwardRuleByComment(commentFwdIn)
+
+	keep := ""
+	if u.Mode == UplinkModeVLAN {
+		keep = vlanInterfaceName(u.Interface, u.VLAN)
+	}
+	if err := m.detachVLANs(ctx, bridge, keep); err != nil {
+		return err
+	}
+
+	if u.Mode == UplinkModeVLAN {
+		return m.ensureVLANUplink(ctx, bridge, u)
+	}
+	return m.ensureMacvtapUplink(ctx, u)
+}
+
+// ensureVLANInterface creates the tagged sub-interface of the uplink
+// interface (if needed) and sets both up.
+func (m *manager) ensureVLANInterface(ctx context.Context, u Uplink) (netlink.Link, error) {
+	log := logger.FromContext(ctx)
+
+	parent, err := netlink.LinkByName(u.Interface)
+	if err != nil {
+		return nil, fmt.Errorf("get uplink interface %s: %w", u.Interface, err)
+	}
+	name := vlanInterfaceName(u.Interface, u.VLAN)
+
+	status := "existing"
+	link, err := netlink.LinkByName(name)
+	if err == nil {
+		vlan, ok := link.(*netlink.Vlan)
+		if !ok || vlan.VlanId != u.VLAN || vlan.ParentIndex != parent.Attrs().Index {
+			return nil, fmt.Errorf("interface %s exists but is not VLAN %d on %s. "+
+				"Delete it with: sudo ip link delete %s", name, u.VLAN, u.Interface, name)
+		}
+	} else {
+		link = &netlink.Vlan{
+			LinkAttrs: netlink.LinkAttrs{Name: name, ParentIndex: parent.Attrs().Index},
+			VlanId:    u.VLAN,
+		}
+		if err := netlink.LinkAdd(link); err != nil {
+			return nil, fmt.Errorf("create VLAN interface %s: %w", name, err)
+		}
+		status = "created"
+	}
+
+	if err := netlink.LinkSetUp(parent); err != nil {
+		return nil, fmt.Errorf("set %s up: %w", u.Interface, err)
+	}
+	if err := netlink.LinkSetUp(link); err != nil {
+		return nil, fmt.Errorf("set %s up: %w", name, err)
+	}
+	log.InfoContext(ctx, "VLAN interface ready", "interface", name, "vlan", u.VLAN, "status", status)
+	return link, nil
+}
+
+// ensureVLANUplink enslaves the VLAN sub-interface to the bridge. The bridge
+// port is not isolated, so isolated guests still reach the VLAN's router and
+// hosts.
+func (m *manager) ensureVLANUplink(ctx context.Context, bridge netlink.Link, u Uplink) error {
+	link, err := m.ensureVLANInterface(ctx, u)
+	if err != nil {
+		return err
+	}
+	if err := netlink.LinkSetMaster(link, bridge); err != nil {
+		return fmt.Errorf("attach %s to bridge: %w", link.Attrs().Name, err)
+	}
+	logger.FromContext(ctx).InfoContext(ctx, "VLAN uplink ready", "bridge", bridge.Attrs().Name, "interface", link.Attrs().Name, "vlan", u.VLAN)
+	return nil
+}
+
+// ensureMacvtapUplink sets up the interface guests' macvtap devices are
+// created on. The bridge stays, unused by guests.
+func (m *manager) ensureMacvtapUplink(ctx context.Context, u Uplink) error {
+	if u.VLAN > 0 {
+		if _, err := m.ensureVLANInterface(ctx, u); err != nil {
+			return err
+		}
+	} else {
+		parent, err := netlink.LinkByName(u.Interface)
+		if err != nil {
+			return fmt.Errorf("get uplink interface %s: %w", u.Interface, err)
+		}
+		if err := netlink.LinkSetUp(parent); err != nil {
+			return fmt.Errorf("set %s up: %w", u.Interface, err)
+		}
+	}
+	logger.FromContext(ctx).InfoContext(ctx, "macvtap uplink ready", "interface", u.macvtapParent())
+	return nil
+}
+
+// detachVLANs releases the VLAN sub-interfaces bound to the bridge other
+// than keep, left by an earlier vlan uplink. They aren't deleted: the host
+// may use them otherwise.
+func (m *manager) detachVLANs(ctx context.Context, bridge netlink.Link, keep string) error {
+	links, err := netlink.LinkList()
+	if err != nil {
+		return fmt.Errorf("list links: %w", err)
+	}
+	for _, link := range links {
+		attrs := link.Attrs()
+		if attrs.MasterIndex != bridge.Attrs().Index || link.Type() != "vlan" || attrs.Name == keep {
+			continue
+		}
+		if err := netlink.LinkSetNoMaster(link); err != nil {
+			return fmt.Errorf("detach %s from bridge: %w", attrs.Name, err)
+		}
+		logger.FromContext(ctx).InfoContext(ctx, "detached VLAN interface from bridge", "bridge", bridge.Attrs().Name, "interface", attrs.Name)
+	}
+	return nil
+}
+
+// routedNetworkState describes the bridge of a vlan or macvtap uplink, which
+// carries no address: the subnet and gateway belong to the datacenter network
+// and come from configuration.
+func (m *manager) routedNetworkState(bridgeName string) (*Network, error) {
+	_, ipNet, err := net.ParseCIDR(m.config.SubnetCIDR)
+	if err != nil {
+		return nil, fmt.Errorf("parse subnet: %w", err)
+	}
+	ones, _ := ipNet.Mask.Size()
+	gateway := m.gateway()
+	if gateway == "" {
+		return nil, fmt.Errorf("no gateway for subnet %s", m.config.SubnetCIDR)
+	}
+	return &Network{
+		Bridge:  bridgeName,
+		Gateway: gateway,
+		Subnet:  fmt.Sprintf("%s/%d", gateway, ones),
+	}, nil
+}
+
+// createGuestDevice creates the device an instance is attached by: a macvtap
+// device with a macvtap uplink, a TAP on the bridge otherwise.
+func (m *manager) createGuestDevice(name, mac string, network *Network, uplink Uplink, limits BandwidthLimits, mtu int) error {
+	if uplink.Mode == UplinkModeMacvtap {
+		if err := m.createMacvtapDevice(name, uplink.macvtapParent(), mac, limits, mtu); err != nil {
+			return fmt.Errorf("create macvtap device: %w", err)
+		}
+		return nil
+	}
+	if err := m.createTAPDevice(name, network.Bridge, network.Isolated, limits, mtu); err != nil {
+		return fmt.Errorf("create TAP device: %w", err)
+	}
+	return nil
+}
+
+// createMacvtapDevice creates the macvtap device of an instance on parent. It
+// has the instance's MAC address, since macvtap only passes the guest the
+// frames addressed to it. In VEPA mode frames between guests go out to the
+// switch, so like isolated TAPs they don't reach each other through the host.
+func (m *manager) createMacvtapDevice(name, parent, mac string, limits BandwidthLimits, mtu int) error {
+	if _, err := netlink.LinkByName(name); err == nil {
+		if err := m.deleteTAPDevice(name); err != nil {
+			return fmt.Errorf("delete existing device: %w", err)
+		}
+	}
+
+	parentLink, err := netlink.LinkByName(parent)
+	if err != nil {
+		return fmt.Errorf("get uplink interface %s: %w", parent, err)
+	}
+	hw, err := net.ParseMAC(mac)
+	if err != nil {
+		return fmt.Errorf("parse MAC %q: %w", mac, err)
+	}
+	attrs := netlink.LinkAttrs{Name: name, ParentIndex: parentLink.Attrs().Index, HardwareAddr: hw}
+	if mtu > 0 {
+		attrs.MTU = mtu
+	}
+	link := &netlink.Macvtap{Macvlan: netlink.Macvlan{LinkAttrs: attrs, Mode: netlink.MACVLAN_MODE_VEPA}}
+	if err := netlink.LinkAdd(link); err != nil {
+		return fmt.Errorf("create macvtap device: %w", err)
+	}
+	if err := netlink.LinkSetUp(link); err != nil {
+		return fmt.Errorf("set macvtap up: %w", err)
+	}
+
+	return m.applyMacvtapLimits(name, limits)
+}
+
+// applyMacvtapLimits shapes an instance's macvtap device, replacing earlier
+// limits. Frames the guest sends leave through the device's root qdisc, so
+// uploads are shaped by a TBF there, at their guaranteed rate: there's no
+// bridge HTB class to borrow up to the ceiling from. Frames for the guest
+// only pass the device's ingress hook, so downloads are policed there.
+func (m *manager) applyMacvtapLimits(name string, limits BandwidthLimits) error {
+	if limits.UploadBps > 0 {
+		if err := m.applyDownloadRateLimit(name, limits.UploadBps, limits.UploadBurstBytes); err != nil {
+			return fmt.Errorf("apply upload rate limit: %w", err)
+		}
+	} else {
+		m.removeRateLimit(name)
+	}
+
+	m.removeIngressPolicer(name)
+	if limits.DownloadBps > 0 {
+		if err := m.applyIngressPolicer(name, limits.DownloadBps, limits.DownloadBurstBytes); err != nil {
+			return fmt.Errorf("apply download rate limit: %w", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,105 @@
+package network
+
+import (
+	"context"
+	"testing"
+
+	"github.com/kernel/hypeman/cmd/api/config"
+	"github.com/kernel/hypeman/lib/paths"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVLANInterfaceName(t *testing.T) {
+	assert.Equal(t, "eth1.100", vlanInterfaceName("eth1", 100))
+	assert.Equal(t, "enp94s0f1.4094", vlanInterfaceName("enp94s0f1", 4094))
+	// Would exceed the 15-char interface name limit
+	assert.Equal(t, "vlan4094", vlanInterfaceName("enp94s0f1np1", 4094))
+}
+
+func TestVLANNetworkState(t *testing.T) {
+	m := &manager{config: &config.Config{
+		UplinkMode:    UplinkModeVLAN,
+		SubnetCIDR:    "192.168.40.0/24",
+		SubnetGateway: "192.168.40.254",
+	}}
+
+	state, err := m.routedNetworkState("vmbr0")
+	require.NoError(t, err)
+	assert.Equal(t, "192.168.40.254", state.Gateway)
+	assert.Equal(t, "192.168.40.254/24", state.Subnet)
+
+	// Without SUBNET_GATEWAY the router is assumed at the first address
+	m.config.SubnetGateway = ""
+	state, err = m.routedNetworkState("vmbr0")
+	require.NoError(t, err)
+	assert.Equal(t, "192.168.40.1", state.Gateway)
+}
+
+func TestNormalizeUplink(t *testing.T) {
+	m := &manager{config: &config.Config{UplinkInterface: "lo"}}
+
+	u, err := m.normalizeUplink(Uplink{Mode: "NAT"})
+	require.NoError(t, err)
+	assert.Equal(t, Uplink{Mode: UplinkModeNAT}, *u)
+
+	// The interface defaults to UPLINK_INTERFACE
+	u, err = m.normalizeUplink(Uplink{Mode: UplinkModeVLAN, VLAN: 100})
+	require.NoError(t, err)
+	assert.Equal(t, Uplink{Mode: UplinkModeVLAN, Interface: "lo", VLAN: 100}, *u)
+
+	// A VLAN is optional for macvtap
+	u, err = m.normalizeUplink(Uplink{Mode: UplinkModeMacvtap})
+	require.NoError(t, err)
+	assert.Equal(t, "lo", u.macvtapParent())
+
+	for _, bad := range []Uplink{
+		{Mode: "bridge"},
+		{Mode: UplinkModeNAT, VLAN: 100},
+		{Mode: UplinkModeVLAN},
+		{Mode: UplinkModeVLAN, VLAN: 4095},
+		{Mode: UplinkModeMacvtap, Interface: "does-not-exist0"},
+	} {
+		_, err := m.normalizeUplink(bad)
+		assert.Error(t, err, "%+v", bad)
+	}
+
+	// The metadata service is served from the bridge gateway
+	m.config.MetadataPort = "8083"
+	_, err = m.normalizeUplink(Uplink{Mode: UplinkModeMacvtap})
+	assert.Error(t, err)
+}
+
+func TestUplinkPersistence(t *testing.T) {
+	m := &manager{paths: paths.New(t.TempDir()), config: &config.Config{
+		UplinkMode:      UplinkModeVLAN,
+		UplinkInterface: "eth1",
+		UplinkVLAN:      100,
+	}}
+
+	// Without a stored uplink UPLINK_MODE applies
+	stored, err := m.loadUplink("default")
+	require.NoError(t, err)
+	assert.Nil(t, stored)
+	assert.Equal(t, Uplink{Mode: UplinkModeVLAN, Interface: "eth1", VLAN: 100}, m.uplink())
+
+	want := &Uplink{Mode: UplinkModeMacvtap, Interface: "eth2"}
+	require.NoError(t, m.saveUplink("default", want))
+	stored, err = m.loadUplink("default")
+	require.NoError(t, err)
+	assert.Equal(t, want, stored)
+
+	m.setUplink(*stored)
+	assert.Equal(t, *want, m.uplink())
+}
+
+func TestUplinkOnlyDefaultNetwork(t *testing.T) {
+	m := &manager{paths: paths.New(t.TempDir()), config: &config.Config{}}
+
+	_, err := m.GetUplink(context.Background(), "other")
+	require.ErrorIs(t, err, ErrNotFound)
+	assert.Contains(t, err.Error(), "only the default network exists")
+
+	_, err = m.SetUplink(context.Background(), "other", Uplink{Mode: UplinkModeNAT})
+	require.ErrorIs(t, err, ErrNotFound)
+}
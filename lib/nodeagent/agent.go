@@ -0,0 +1,226 @@
+package nodeagent
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kernel/hypeman/lib/logger"
+	"github.com/kernel/hypeman/lib/resources"
+)
+
+// Control plane endpoints, relative to the control plane URL
+const (
+	registerPath = "/nodes/register"
+	nodesPath    = "/nodes/"
+)
+
+// deregisterTimeout bounds the deregistration sent on shutdown
+const deregisterTimeout = 5 * time.Second
+
+// errUnknownNode is returned when the control plane doesn't know the node,
+// e.g. because it restarted and lost its registrations
+var errUnknownNode = errors.New("node not registered with control plane")
+
+// Config configures the node agent
+type Config struct {
+	NodeID            string        // Identifies the node to the control plane
+	Address           string        // Address the control plane reaches this node's API at
+	ControlPlaneURL   string        // Empty = don't register with a control plane
+	Token             string        // Bearer token for the control plane (optional)
+	HeartbeatInterval time.Duration // How often capacity is reported
+	SlotTTL           time.Duration // Default lifetime of a claimed slot
+	Version           string        // Reported to the control plane
+}
+
+// CapacitySource reports host capacity and allocations. Implemented by
+// *resources.Manager.
+type CapacitySource interface {
+	GetFullStatus(ctx context.Context) (*resources.FullResourceStatus, error)
+}
+
+// Agent registers the node with a central control plane, reports its capacity
+// in heartbeats, and holds the slots the control plane claims when it places
+// instances on the node.
+type Agent struct {
+	cfg      Config
+	capacity CapacitySource
+	client   *http.Client
+	now      func() time.Time
+
+	mu            sync.Mutex
+	slots         map[string]Slot
+	registered    bool
+	lastHeartbeat time.Time
+	lastError     string
+}
+
+// NewAgent creates a node agent. Slots can be claimed whether or not a
+// control plane is configured; Run does nothing without one.
+func NewAgent(cfg Config, capacity CapacitySource) *Agent {
+	return &Agent{
+		cfg:      cfg,
+		capacity: capacity,
+		client:   &http.Client{Timeout: 30 * time.Second},
+		now:      time.Now,
+		slots:    make(map[string]Slot),
+	}
+}
+
+// Run registers the node with the control plane and sends a heartbeat every
+// HeartbeatInterval until ctx is done, then deregisters. Failed registrations
+// are retried on the next tick, and the node registers again when the control
+// plane answers a heartbeat with 404.
+func (a *Agent) Run(ctx context.Context) {
+	if a.cfg.ControlPlaneURL == "" {
+		return
+	}
+	log := logger.FromContext(ctx)
+
+	ticker := time.NewTicker(a.cfg.HeartbeatInterval)
+	defer ticker.Stop()
+	for {
+		err := a.report(ctx)
+		a.mu.Lock()
+		if err != nil {
+			a.lastError = err.Error()
+		} else {
+			a.lastError = ""
+			a.lastHeartbeat = a.now()
+		}
+		a.mu.Unlock()
+		if err != nil && ctx.Err() == nil {
+			log.WarnContext(ctx, "failed to report to control plane", "control_plane", a.cfg.ControlPlaneURL, "error", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			a.deregister(ctx)
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// nodeReport is the body of registration and heartbeat requests
+type nodeReport struct {
+	NodeID    string   `json:"node_id"`
+	Address   string   `json:"address,omitempty"`
+	Version   string   `json:"version,omitempty"`
+	Capacity  Capacity `json:"capacity"`
+	Allocated Capacity `json:"allocated"`
+	Reserved  Capacity `json:"reserved"`
+	Available Capacity `json:"available"`
+	Instances int      `json:"instances"`
+	Slots     []Slot   `json:"slots"`
+}
+
+// report registers the node, or sends a heartbeat if it is registered
+func (a *Agent) report(ctx context.Context) error {
+	status, err := a.Status(ctx)
+	if err != nil {
+		return err
+	}
+	body := nodeReport{
+		NodeID:    status.NodeID,
+		Address:   status.Address,
+		Version:   a.cfg.Version,
+		Capacity:  status.Capacity,
+		Allocated: status.Allocated,
+		Reserved:  status.Reserved,
+		Available: status.Available,
+		Instances: status.Instances,
+		Slots:     status.Slots,
+	}
+
+	if !status.Registered {
+		if err := a.post(ctx, registerPath, body); err != nil {
+			return fmt.Errorf("register: %w", err)
+		}
+		a.setRegistered(true)
+		logger.FromContext(ctx).InfoContext(ctx, "registered with control plane", "control_plane", a.cfg.ControlPlaneURL, "node_id", a.cfg.NodeID)
+		return nil
+	}
+
+	err = a.post(ctx, a.nodePath("heartbeat"), body)
+	if errors.Is(err, errUnknownNode) {
+		a.setRegistered(false)
+		return a.report(ctx)
+	}
+	if err != nil {
+		return fmt.Errorf("heartbeat: %w", err)
+	}
+	return nil
+}
+
+// deregister tells the control plane the node is going away, so it stops
+// placing instances here without waiting for heartbeats to time out
+func (a *Agent) deregister(ctx context.Context) {
+	a.mu.Lock()
+	registered := a.registered
+	a.mu.Unlock()
+	if !registered {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), deregisterTimeout)
+	defer cancel()
+	log := logger.FromContext(ctx)
+	if err := a.post(ctx, a.nodePath("deregister"), nodeReport{NodeID: a.cfg.NodeID}); err != nil && !errors.Is(err, errUnknownNode) {
+		log.WarnContext(ctx, "failed to deregister from control plane", "error", err)
+		return
+	}
+	a.setRegistered(false)
+	log.InfoContext(ctx, "deregistered from control plane", "node_id", a.cfg.NodeID)
+}
+
+func (a *Agent) setRegistered(registered bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.registered = registered
+}
+
+// nodePath returns the path of one of the node's control plane endpoints
+func (a *Agent) nodePath(endpoint string) string {
+	return nodesPath + url.PathEscape(a.cfg.NodeID) + "/" + endpoint
+}
+
+// post sends body as JSON to path on the control plane
+func (a *Agent) post(ctx context.Context, path string, body any) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("encode request: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimSuffix(a.cfg.ControlPlaneURL, "/")+path, bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if a.cfg.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+a.cfg.Token)
+	}
+
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return errUnknownNode
+	case resp.StatusCode >= 300:
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("control plane returned %s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+	io.Copy(io.Discard, resp.Body)
+	return nil
+}
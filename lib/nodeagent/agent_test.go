@@ -0,0 +1,221 @@
+package nodeagent
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/kernel/hypeman/lib/resources"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// staticCapacity reports a host with 8 vCPUs and 16GB of memory, 2 and 4GB of
+// which are allocated to one instance
+type staticCapacity struct{}
+
+func (staticCapacity) GetFullStatus(ctx context.Context) (*resources.FullResourceStatus, error) {
+	return &resources.FullResourceStatus{
+		CPU:         resources.ResourceStatus{EffectiveLimit: 8, Allocated: 2},
+		Memory:      resources.ResourceStatus{EffectiveLimit: 16 << 30, Allocated: 4 << 30},
+		Disk:        resources.ResourceStatus{EffectiveLimit: 100 << 30, Allocated: 10 << 30},
+		Allocations: []resources.AllocationBreakdown{{InstanceID: "a"}},
+	}, nil
+}
+
+func newTestAgent(cfg Config) *Agent {
+	if cfg.NodeID == "" {
+		cfg.NodeID = "node-1"
+	}
+	if cfg.SlotTTL == 0 {
+		cfg.SlotTTL = time.Minute
+	}
+	if cfg.HeartbeatInterval == 0 {
+		cfg.HeartbeatInterval = time.Hour
+	}
+	return NewAgent(cfg, staticCapacity{})
+}
+
+func TestClaimSlot(t *testing.T) {
+	ctx := context.Background()
+	agent := newTestAgent(Config{})
+
+	slot, err := agent.ClaimSlot(ctx, ClaimSlotRequest{ID: "placement-1", Vcpus: 4, MemoryBytes: 8 << 30})
+	require.NoError(t, err)
+	assert.Equal(t, "placement-1", slot.ID)
+
+	status, err := agent.Status(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, Capacity{Vcpus: 4, MemoryBytes: 8 << 30}, status.Reserved)
+	assert.Equal(t, int64(2), status.Available.Vcpus)
+	assert.Equal(t, int64(4<<30), status.Available.MemoryBytes)
+
+	_, err = agent.ClaimSlot(ctx, ClaimSlotRequest{ID: "placement-1", Vcpus: 1, MemoryBytes: 1 << 30})
+	assert.ErrorIs(t, err, ErrSlotExists)
+
+	// Claimed capacity isn't available to other slots
+	_, err = agent.ClaimSlot(ctx, ClaimSlotRequest{Vcpus: 4, MemoryBytes: 1 << 30})
+	assert.ErrorIs(t, err, ErrInsufficientCapacity)
+
+	_, err = agent.ClaimSlot(ctx, ClaimSlotRequest{Vcpus: 0, MemoryBytes: 1 << 30})
+	assert.ErrorIs(t, err, ErrInvalidSlot)
+
+	require.NoError(t, agent.ReleaseSlot("placement-1"))
+	assert.ErrorIs(t, agent.ReleaseSlot("placement-1"), ErrSlotNotFound)
+
+	other, err := agent.ClaimSlot(ctx, ClaimSlotRequest{Vcpus: 4, MemoryBytes: 1 << 30})
+	require.NoError(t, err)
+	assert.NotEmpty(t, other.ID, "an ID is generated when none is given")
+}
+
+func TestTakeSlot(t *testing.T) {
+	ctx := context.Background()
+	agent := newTestAgent(Config{})
+
+	_, err := agent.ClaimSlot(ctx, ClaimSlotRequest{ID: "placement-1", Vcpus: 2, MemoryBytes: 4 << 30, DiskBytes: 10 << 30})
+	require.NoError(t, err)
+
+	// A request larger than the slot leaves it claimed
+	_, err = agent.TakeSlot("placement-1", Capacity{Vcpus: 4, MemoryBytes: 1 << 30})
+	assert.ErrorIs(t, err, ErrSlotTooSmall)
+	_, err = agent.TakeSlot("placement-1", Capacity{Vcpus: 2, MemoryBytes: 4 << 30, DiskBytes: 20 << 30})
+	assert.ErrorIs(t, err, ErrSlotTooSmall)
+
+	slot, err := agent.TakeSlot("placement-1", Capacity{Vcpus: 2, MemoryBytes: 4 << 30, DiskBytes: 10 << 30})
+	require.NoError(t, err)
+	assert.Equal(t, "placement-1", slot.ID)
+
+	// A slot can only be taken once
+	_, err = agent.TakeSlot("placement-1", Capacity{Vcpus: 1, MemoryBytes: 1 << 30})
+	assert.ErrorIs(t, err, ErrSlotNotFound)
+
+	agent.RestoreSlot(*slot)
+	_, err = agent.GetSlot("placement-1")
+	assert.NoError(t, err)
+}
+
+func TestClaimSlot_Expires(t *testing.T) {
+	ctx := context.Background()
+	agent := newTestAgent(Config{})
+	now := time.Now()
+	agent.now = func() time.Time { return now }
+
+	_, err := agent.ClaimSlot(ctx, ClaimSlotRequest{ID: "short", Vcpus: 1, MemoryBytes: 1 << 30, TTL: time.Second})
+	require.NoError(t, err)
+	_, err = agent.ClaimSlot(ctx, ClaimSlotRequest{ID: "default", Vcpus: 1, MemoryBytes: 1 << 30})
+	require.NoError(t, err)
+
+	now = now.Add(2 * time.Second)
+	_, err = agent.GetSlot("short")
+	assert.ErrorIs(t, err, ErrSlotNotFound)
+	_, err = agent.GetSlot("default")
+	assert.NoError(t, err)
+}
+
+// controlPlane records the requests a test control plane receives
+type controlPlane struct {
+	mu       sync.Mutex
+	paths    []string
+	reports  []nodeReport
+	auth     string
+	forgetID bool // answer the next heartbeat with 404
+}
+
+func (c *controlPlane) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.paths = append(c.paths, r.URL.Path)
+	c.auth = r.Header.Get("Authorization")
+	var report nodeReport
+	json.NewDecoder(r.Body).Decode(&report)
+	c.reports = append(c.reports, report)
+	if c.forgetID && r.URL.Path == "/nodes/node-1/heartbeat" {
+		c.forgetID = false
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (c *controlPlane) requests() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]string(nil), c.paths...)
+}
+
+func TestReport(t *testing.T) {
+	ctx := context.Background()
+	cp := &controlPlane{}
+	srv := httptest.NewServer(cp)
+	defer srv.Close()
+	agent := newTestAgent(Config{ControlPlaneURL: srv.URL, Token: "secret", Address: "https://node-1:8080"})
+
+	_, err := agent.ClaimSlot(ctx, ClaimSlotRequest{ID: "placement-1", Vcpus: 1, MemoryBytes: 1 << 30})
+	require.NoError(t, err)
+
+	require.NoError(t, agent.report(ctx))
+	require.NoError(t, agent.report(ctx))
+	assert.Equal(t, []string{"/nodes/register", "/nodes/node-1/heartbeat"}, cp.requests())
+	assert.Equal(t, "Bearer secret", cp.auth)
+
+	report := cp.reports[0]
+	assert.Equal(t, "node-1", report.NodeID)
+	assert.Equal(t, "https://node-1:8080", report.Address)
+	assert.Equal(t, int64(8), report.Capacity.Vcpus)
+	assert.Equal(t, int64(5), report.Available.Vcpus)
+	assert.Equal(t, 1, report.Instances)
+	require.Len(t, report.Slots, 1)
+	assert.Equal(t, "placement-1", report.Slots[0].ID)
+
+	// A control plane that lost the node's registration gets a new one
+	cp.forgetID = true
+	require.NoError(t, agent.report(ctx))
+	assert.Equal(t, []string{"/nodes/register", "/nodes/node-1/heartbeat", "/nodes/node-1/heartbeat", "/nodes/register"}, cp.requests())
+}
+
+func TestRun_Deregisters(t *testing.T) {
+	cp := &controlPlane{}
+	srv := httptest.NewServer(cp)
+	defer srv.Close()
+	agent := newTestAgent(Config{ControlPlaneURL: srv.URL})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		agent.Run(ctx)
+		close(done)
+	}()
+	require.Eventually(t, func() bool {
+		status, err := agent.Status(context.Background())
+		return err == nil && status.LastHeartbeat != nil
+	}, 5*time.Second, 10*time.Millisecond)
+
+	status, err := agent.Status(context.Background())
+	require.NoError(t, err)
+	assert.True(t, status.Registered)
+	assert.Empty(t, status.LastError)
+
+	cancel()
+	<-done
+	assert.Equal(t, []string{"/nodes/register", "/nodes/node-1/deregister"}, cp.requests())
+}
+
+func TestReport_ControlPlaneError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "control plane unavailable", http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+	agent := newTestAgent(Config{ControlPlaneURL: srv.URL})
+
+	err := agent.report(context.Background())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "control plane unavailable")
+
+	status, err := agent.Status(context.Background())
+	require.NoError(t, err)
+	assert.False(t, status.Registered)
+}
@@ -0,0 +1,11 @@
+package nodeagent
+
+import "errors"
+
+var (
+	ErrSlotNotFound         = errors.New("slot not found")
+	ErrSlotExists           = errors.New("slot already exists")
+	ErrInvalidSlot          = errors.New("invalid slot request")
+	ErrInsufficientCapacity = errors.New("insufficient capacity")
+	ErrSlotTooSmall         = errors.New("request exceeds slot")
+)
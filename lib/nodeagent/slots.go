@@ -0,0 +1,214 @@
+package nodeagent
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/kernel/hypeman/lib/resources"
+	"github.com/nrednav/cuid2"
+)
+
+// maxSlotIDLength bounds slot IDs chosen by the control plane
+const maxSlotIDLength = 128
+
+// Status returns the node's registration state and capacity, with claimed
+// slots counted against the available capacity
+func (a *Agent) Status(ctx context.Context) (*Status, error) {
+	full, err := a.capacity.GetFullStatus(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("get resource status: %w", err)
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.expireSlotsLocked()
+	return a.statusLocked(full), nil
+}
+
+// ClaimSlot reserves capacity for an instance the control plane is about to
+// place on the node. The slot is held until it is released, consumed by an
+// instance create, or its TTL passes.
+func (a *Agent) ClaimSlot(ctx context.Context, req ClaimSlotRequest) (*Slot, error) {
+	if req.Vcpus <= 0 || req.MemoryBytes <= 0 {
+		return nil, fmt.Errorf("%w: vcpus and memory_bytes must be positive", ErrInvalidSlot)
+	}
+	if req.DiskBytes < 0 || req.TTL < 0 {
+		return nil, fmt.Errorf("%w: disk_bytes and ttl must not be negative", ErrInvalidSlot)
+	}
+	if len(req.ID) > maxSlotIDLength {
+		return nil, fmt.Errorf("%w: id is longer than %d characters", ErrInvalidSlot, maxSlotIDLength)
+	}
+
+	full, err := a.capacity.GetFullStatus(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("get resource status: %w", err)
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.expireSlotsLocked()
+
+	id := req.ID
+	if id == "" {
+		id = cuid2.Generate()
+	} else if _, ok := a.slots[id]; ok {
+		return nil, fmt.Errorf("%w: %s", ErrSlotExists, id)
+	}
+
+	available := a.statusLocked(full).Available
+	if int64(req.Vcpus) > available.Vcpus {
+		return nil, fmt.Errorf("%w: %d vcpus requested, %d available", ErrInsufficientCapacity, req.Vcpus, available.Vcpus)
+	}
+	if req.MemoryBytes > available.MemoryBytes {
+		return nil, fmt.Errorf("%w: %d bytes of memory requested, %d available", ErrInsufficientCapacity, req.MemoryBytes, available.MemoryBytes)
+	}
+	if req.DiskBytes > available.DiskBytes {
+		return nil, fmt.Errorf("%w: %d bytes of disk requested, %d available", ErrInsufficientCapacity, req.DiskBytes, available.DiskBytes)
+	}
+
+	ttl := req.TTL
+	if ttl == 0 {
+		ttl = a.cfg.SlotTTL
+	}
+	now := a.now()
+	slot := Slot{
+		ID:          id,
+		Vcpus:       req.Vcpus,
+		MemoryBytes: req.MemoryBytes,
+		DiskBytes:   req.DiskBytes,
+		ClaimedAt:   now,
+		ExpiresAt:   now.Add(ttl),
+	}
+	a.slots[id] = slot
+	return &slot, nil
+}
+
+// GetSlot returns a claimed slot that hasn't expired
+func (a *Agent) GetSlot(id string) (*Slot, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.expireSlotsLocked()
+
+	slot, ok := a.slots[id]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrSlotNotFound, id)
+	}
+	return &slot, nil
+}
+
+// TakeSlot removes a claimed slot for an instance needing need, so that only
+// one instance create can consume it. The slot stays claimed if need doesn't
+// fit in it. A create that fails hands the slot back with RestoreSlot.
+func (a *Agent) TakeSlot(id string, need Capacity) (*Slot, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.expireSlotsLocked()
+
+	slot, ok := a.slots[id]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrSlotNotFound, id)
+	}
+	if err := slot.Fits(need); err != nil {
+		return nil, err
+	}
+	delete(a.slots, id)
+	return &slot, nil
+}
+
+// RestoreSlot puts back a slot taken by TakeSlot whose instance wasn't
+// created. The slot keeps its expiry, so one whose TTL passed meanwhile is
+// dropped, as is one whose ID was claimed again.
+func (a *Agent) RestoreSlot(slot Slot) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if _, ok := a.slots[slot.ID]; ok {
+		return
+	}
+	a.slots[slot.ID] = slot
+	a.expireSlotsLocked()
+}
+
+// Fits reports whether an instance needing need fits in the slot. Disk is
+// only checked for slots that claimed disk.
+func (s Slot) Fits(need Capacity) error {
+	if need.Vcpus > int64(s.Vcpus) {
+		return fmt.Errorf("%w: %d vcpus requested, slot %s holds %d", ErrSlotTooSmall, need.Vcpus, s.ID, s.Vcpus)
+	}
+	if need.MemoryBytes > s.MemoryBytes {
+		return fmt.Errorf("%w: %d bytes of memory requested, slot %s holds %d", ErrSlotTooSmall, need.MemoryBytes, s.ID, s.MemoryBytes)
+	}
+	if s.DiskBytes > 0 && need.DiskBytes > s.DiskBytes {
+		return fmt.Errorf("%w: %d bytes of disk requested, slot %s holds %d", ErrSlotTooSmall, need.DiskBytes, s.ID, s.DiskBytes)
+	}
+	return nil
+}
+
+// ReleaseSlot releases a claimed slot, returning its capacity to the node
+func (a *Agent) ReleaseSlot(id string) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.expireSlotsLocked()
+
+	if _, ok := a.slots[id]; !ok {
+		return fmt.Errorf("%w: %s", ErrSlotNotFound, id)
+	}
+	delete(a.slots, id)
+	return nil
+}
+
+// expireSlotsLocked drops slots whose TTL has passed. Requires a.mu.
+func (a *Agent) expireSlotsLocked() {
+	now := a.now()
+	for id, slot := range a.slots {
+		if !now.Before(slot.ExpiresAt) {
+			delete(a.slots, id)
+		}
+	}
+}
+
+// statusLocked builds the node status from the host's resource status and
+// the claimed slots. Requires a.mu.
+func (a *Agent) statusLocked(full *resources.FullResourceStatus) *Status {
+	status := &Status{
+		NodeID:          a.cfg.NodeID,
+		Address:         a.cfg.Address,
+		ControlPlaneURL: a.cfg.ControlPlaneURL,
+		Registered:      a.registered,
+		LastError:       a.lastError,
+		Capacity: Capacity{
+			Vcpus:       full.CPU.EffectiveLimit,
+			MemoryBytes: full.Memory.EffectiveLimit,
+			DiskBytes:   full.Disk.EffectiveLimit,
+		},
+		Allocated: Capacity{
+			Vcpus:       full.CPU.Allocated,
+			MemoryBytes: full.Memory.Allocated,
+			DiskBytes:   full.Disk.Allocated,
+		},
+		Instances: len(full.Allocations),
+		Slots:     make([]Slot, 0, len(a.slots)),
+	}
+	if !a.lastHeartbeat.IsZero() {
+		t := a.lastHeartbeat
+		status.LastHeartbeat = &t
+	}
+
+	for _, slot := range a.slots {
+		status.Slots = append(status.Slots, slot)
+		status.Reserved.Vcpus += int64(slot.Vcpus)
+		status.Reserved.MemoryBytes += slot.MemoryBytes
+		status.Reserved.DiskBytes += slot.DiskBytes
+	}
+	sort.Slice(status.Slots, func(i, j int) bool {
+		return status.Slots[i].ClaimedAt.Before(status.Slots[j].ClaimedAt)
+	})
+
+	status.Available = Capacity{
+		Vcpus:       max(0, status.Capacity.Vcpus-status.Allocated.Vcpus-status.Reserved.Vcpus),
+		MemoryBytes: max(0, status.Capacity.MemoryBytes-status.Allocated.MemoryBytes-status.Reserved.MemoryBytes),
+		DiskBytes:   max(0, status.Capacity.DiskBytes-status.Allocated.DiskBytes-status.Reserved.DiskBytes),
+	}
+	return status
+}
@@ -0,0 +1,47 @@
+package nodeagent
+
+import "time"
+
+// Capacity is an amount of the resources instances are placed by
+type Capacity struct {
+	Vcpus       int64 `json:"vcpus"`
+	MemoryBytes int64 `json:"memory_bytes"`
+	DiskBytes   int64 `json:"disk_bytes"`
+}
+
+// Slot is capacity claimed by the control plane for an instance it is about
+// to place on this node. Slots count against the node's available capacity
+// until they are released, consumed by an instance create, or expire.
+type Slot struct {
+	ID          string    `json:"id"`
+	Vcpus       int       `json:"vcpus"`
+	MemoryBytes int64     `json:"memory_bytes"`
+	DiskBytes   int64     `json:"disk_bytes"`
+	ClaimedAt   time.Time `json:"claimed_at"`
+	ExpiresAt   time.Time `json:"expires_at"`
+}
+
+// ClaimSlotRequest is a request to reserve capacity for one instance
+type ClaimSlotRequest struct {
+	ID          string        // Chosen by the control plane; generated if empty
+	Vcpus       int           // Required
+	MemoryBytes int64         // Required
+	DiskBytes   int64         // Optional
+	TTL         time.Duration // 0 = the agent's default
+}
+
+// Status describes the node's registration and capacity
+type Status struct {
+	NodeID          string
+	Address         string
+	ControlPlaneURL string // Empty when the node isn't registered with a control plane
+	Registered      bool
+	LastHeartbeat   *time.Time
+	LastError       string   // Last registration or heartbeat failure, cleared on success
+	Capacity        Capacity // Effective limits (oversubscription applied)
+	Allocated       Capacity // Used by instances
+	Reserved        Capacity // Held by claimed slots
+	Available       Capacity // Capacity - Allocated - Reserved
+	Instances       int
+	Slots           []Slot
+}
@@ -5,15 +5,19 @@ import (
 	"context"
 	"fmt"
 	"log/slog"
+	"net/http"
 	goruntime "runtime"
 	"time"
 
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"go.opentelemetry.io/contrib/bridges/otelslog"
 	otelruntime "go.opentelemetry.io/contrib/instrumentation/runtime"
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/exporters/otlp/otlplog/otlploggrpc"
 	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetricgrpc"
 	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	otelprom "go.opentelemetry.io/otel/exporters/prometheus"
 	"go.opentelemetry.io/otel/metric"
 	"go.opentelemetry.io/otel/propagation"
 	sdklog "go.opentelemetry.io/otel/sdk/log"
@@ -31,6 +35,7 @@ type Config struct {
 	ServiceName       string
 	ServiceInstanceID string
 	Insecure          bool
+	Prometheus        bool // Serve metrics for Prometheus scraping, independent of OTLP export
 	Version           string
 	Env               string
 }
@@ -43,14 +48,16 @@ type Provider struct {
 	Tracer         trace.Tracer
 	Meter          metric.Meter
 	LogHandler     slog.Handler
+	MetricsHandler http.Handler // Prometheus scrape handler, nil unless Config.Prometheus
 	startTime      time.Time
 }
 
 // Init initializes OpenTelemetry with the given configuration.
 // Returns a shutdown function that should be called on application exit.
-// If OTel is disabled, returns a no-op shutdown function.
+// If OTel is disabled, returns a no-op shutdown function. If only Prometheus
+// is enabled, metrics are collected for scraping but nothing is exported.
 func Init(ctx context.Context, cfg Config) (*Provider, func(context.Context) error, error) {
-	if !cfg.Enabled {
+	if !cfg.Enabled && !cfg.Prometheus {
 		// Return no-op provider when disabled
 		return &Provider{
 			Tracer:    otel.Tracer(cfg.ServiceName),
@@ -74,6 +81,23 @@ func Init(ctx context.Context, cfg Config) (*Provider, func(context.Context) err
 		return nil, nil, fmt.Errorf("create resource: %w", err)
 	}
 
+	// Create Prometheus reader, served by the API's /metrics endpoint
+	var metricReaders []sdkmetric.Option
+	var metricsHandler http.Handler
+	if cfg.Prometheus {
+		registry := prometheus.NewRegistry()
+		promExporter, err := otelprom.New(otelprom.WithRegisterer(registry))
+		if err != nil {
+			return nil, nil, fmt.Errorf("create prometheus exporter: %w", err)
+		}
+		metricReaders = append(metricReaders, sdkmetric.WithReader(promExporter))
+		metricsHandler = promhttp.HandlerFor(registry, promhttp.HandlerOpts{})
+	}
+
+	if !cfg.Enabled {
+		return initMetricsOnly(cfg, res, metricReaders, metricsHandler)
+	}
+
 	// Create trace exporter
 	traceOpts := []otlptracegrpc.Option{
 		otlptracegrpc.WithEndpoint(cfg.Endpoint),
@@ -106,10 +130,10 @@ func Init(ctx context.Context, cfg Config) (*Provider, func(context.Context) err
 	}
 
 	// Create meter provider
-	meterProvider := sdkmetric.NewMeterProvider(
+	meterProvider := sdkmetric.NewMeterProvider(append(metricReaders,
 		sdkmetric.WithReader(sdkmetric.NewPeriodicReader(metricExporter)),
 		sdkmetric.WithResource(res),
-	)
+	)...)
 
 	// Create log exporter
 	logOpts := []otlploggrpc.Option{
@@ -157,6 +181,7 @@ func Init(ctx context.Context, cfg Config) (*Provider, func(context.Context) err
 		Tracer:         tracerProvider.Tracer(cfg.ServiceName),
 		Meter:          meterProvider.Meter(cfg.ServiceName),
 		LogHandler:     logHandler,
+		MetricsHandler: metricsHandler,
 		startTime:      time.Now(),
 	}
 
@@ -188,6 +213,42 @@ func Init(ctx context.Context, cfg Config) (*Provider, func(context.Context) err
 	return provider, shutdown, nil
 }
 
+// initMetricsOnly sets up a meter provider with only the given readers, for
+// when metrics are scraped but OTLP export is disabled. Traces and logs stay no-op.
+func initMetricsOnly(cfg Config, res *resource.Resource, readers []sdkmetric.Option, metricsHandler http.Handler) (*Provider, func(context.Context) error, error) {
+	meterProvider := sdkmetric.NewMeterProvider(append(readers, sdkmetric.WithResource(res))...)
+	otel.SetMeterProvider(meterProvider)
+
+	// Start runtime metrics collection
+	if err := otelruntime.Start(otelruntime.WithMeterProvider(meterProvider)); err != nil {
+		meterProvider.Shutdown(context.Background())
+		return nil, nil, fmt.Errorf("start runtime metrics: %w", err)
+	}
+
+	provider := &Provider{
+		MeterProvider:  meterProvider,
+		Tracer:         otel.Tracer(cfg.ServiceName),
+		Meter:          meterProvider.Meter(cfg.ServiceName),
+		MetricsHandler: metricsHandler,
+		startTime:      time.Now(),
+	}
+
+	// Register system metrics (uptime, info)
+	if err := provider.registerSystemMetrics(cfg); err != nil {
+		meterProvider.Shutdown(context.Background())
+		return nil, nil, fmt.Errorf("register system metrics: %w", err)
+	}
+
+	shutdown := func(ctx context.Context) error {
+		if err := meterProvider.Shutdown(ctx); err != nil {
+			return fmt.Errorf("shutdown meter: %w", err)
+		}
+		return nil
+	}
+
+	return provider, shutdown, nil
+}
+
 // registerSystemMetrics registers uptime and info metrics.
 func (p *Provider) registerSystemMetrics(cfg Config) error {
 	// Uptime gauge
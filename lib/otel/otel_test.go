@@ -0,0 +1,38 @@
+package otel
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInit_Disabled(t *testing.T) {
+	provider, shutdown, err := Init(context.Background(), Config{ServiceName: "hypeman"})
+	require.NoError(t, err)
+	defer shutdown(context.Background())
+
+	assert.Nil(t, provider.MeterProvider)
+	assert.Nil(t, provider.MetricsHandler)
+}
+
+func TestInit_PrometheusOnly(t *testing.T) {
+	provider, shutdown, err := Init(context.Background(), Config{
+		ServiceName: "hypeman",
+		Prometheus:  true,
+		Version:     "test",
+	})
+	require.NoError(t, err)
+	defer shutdown(context.Background())
+	require.NotNil(t, provider.MetricsHandler)
+
+	rec := httptest.NewRecorder()
+	provider.MetricsHandler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), "hypeman_uptime_seconds")
+	assert.Contains(t, rec.Body.String(), "hypeman_info")
+}
@@ -1,7 +1,11 @@
 // Package paths provides centralized path construction for hypeman data directory.
 package paths
 
-import "path/filepath"
+import (
+	"fmt"
+	"path/filepath"
+	"time"
+)
 
 // Paths provides typed path construction for the hypeman data directory.
 type Paths struct {
@@ -60,6 +64,25 @@ func (p *Paths) OCICacheBlob(digestHex string) string {
 	return filepath.Join(p.OCICacheBlobDir(), digestHex)
 }
 
+// RegistryProxyTag returns the path to the digest a tag of an upstream
+// repository resolved to, cached by the registry's pull-through proxy.
+func (p *Paths) RegistryProxyTag(registry, repo, tag string) string {
+	return filepath.Join(p.dataDir, "system", "registry-proxy", "tags", registry, filepath.FromSlash(repo), tag)
+}
+
+// RegistryUpload returns the path to the data received so far by a blob
+// upload session of the registry.
+func (p *Paths) RegistryUpload(id string) string {
+	return filepath.Join(p.RegistryUploadsDir(), id)
+}
+
+// RegistryUploadsDir returns the directory of the registry's blob upload
+// sessions. It shares a filesystem with OCICacheBlobDir so completed uploads
+// are moved into place.
+func (p *Paths) RegistryUploadsDir() string {
+	return filepath.Join(p.dataDir, "system", "registry-uploads")
+}
+
 // OCICacheIndex returns the path to the OCI cache index.json.
 func (p *Paths) OCICacheIndex() string {
 	return filepath.Join(p.SystemOCICache(), "index.json")
@@ -139,6 +162,16 @@ func (p *Paths) InstanceVolumeOverlay(instanceID, volumeID string) string {
 	return filepath.Join(p.InstanceDir(instanceID), "vol-overlays", volumeID+".raw")
 }
 
+// InstanceSwapDisk returns the path to instance swap disk.
+func (p *Paths) InstanceSwapDisk(id string) string {
+	return filepath.Join(p.InstanceDir(id), "swap.raw")
+}
+
+// InstanceScratchDisk returns the path to an instance's scratch disk.
+func (p *Paths) InstanceScratchDisk(id string, index int) string {
+	return filepath.Join(p.InstanceDir(id), "scratch", fmt.Sprintf("%d.raw", index))
+}
+
 // InstanceVolumeOverlaysDir returns the directory for volume overlays.
 func (p *Paths) InstanceVolumeOverlaysDir(instanceID string) string {
 	return filepath.Join(p.InstanceDir(instanceID), "vol-overlays")
@@ -156,6 +189,12 @@ func (p *Paths) InstanceVsockSocket(id string) string {
 	return filepath.Join(p.InstanceDir(id), "vsock.sock")
 }
 
+// InstanceVirtiofsSocket returns the path to the vhost-user socket of an
+// instance's shared directory.
+func (p *Paths) InstanceVirtiofsSocket(id string, index int) string {
+	return filepath.Join(p.InstanceDir(id), fmt.Sprintf("fs%d.sock", index))
+}
+
 // InstanceLogs returns the path to instance logs directory.
 func (p *Paths) InstanceLogs(id string) string {
 	return filepath.Join(p.InstanceDir(id), "logs")
@@ -171,6 +210,11 @@ func (p *Paths) InstanceVMMLog(id string) string {
 	return filepath.Join(p.InstanceLogs(id), "vmm.log")
 }
 
+// InstanceVirtiofsdLog returns the path to instance virtiofsd log (all shared directories).
+func (p *Paths) InstanceVirtiofsdLog(id string) string {
+	return filepath.Join(p.InstanceLogs(id), "virtiofsd.log")
+}
+
 // InstanceHypemanLog returns the path to instance hypeman operations log.
 func (p *Paths) InstanceHypemanLog(id string) string {
 	return filepath.Join(p.InstanceLogs(id), "hypeman.log")
@@ -214,6 +258,16 @@ func (p *Paths) DeviceMetadata(id string) string {
 	return filepath.Join(p.DeviceDir(id), "metadata.json")
 }
 
+// GPUReservations returns the path to the vGPU reservations file.
+func (p *Paths) GPUReservations() string {
+	return filepath.Join(p.DevicesDir(), "gpu-reservations.json")
+}
+
+// GPUHealth returns the path to the GPU health state file.
+func (p *Paths) GPUHealth() string {
+	return filepath.Join(p.DevicesDir(), "gpu-health.json")
+}
+
 // Volume path methods
 
 // VolumesDir returns the root volumes directory.
@@ -236,6 +290,80 @@ func (p *Paths) VolumeMetadata(id string) string {
 	return filepath.Join(p.VolumeDir(id), "metadata.json")
 }
 
+// KeyringDir returns the directory of sealed keys (e.g. encrypted volume keys).
+func (p *Paths) KeyringDir() string {
+	return filepath.Join(p.dataDir, "keyring")
+}
+
+// Secret path methods
+
+// SecretsDir returns the root secrets directory.
+func (p *Paths) SecretsDir() string {
+	return filepath.Join(p.dataDir, "secrets")
+}
+
+// SecretDir returns the directory for a secret.
+func (p *Paths) SecretDir(id string) string {
+	return filepath.Join(p.dataDir, "secrets", id)
+}
+
+// SecretMetadata returns the path to secret metadata.json.
+func (p *Paths) SecretMetadata(id string) string {
+	return filepath.Join(p.SecretDir(id), "metadata.json")
+}
+
+// SecretKeyringDir returns the directory of sealed secret values.
+func (p *Paths) SecretKeyringDir() string {
+	return filepath.Join(p.KeyringDir(), "secrets")
+}
+
+// Instance group path methods
+
+// GroupsDir returns the root instance groups directory.
+func (p *Paths) GroupsDir() string {
+	return filepath.Join(p.dataDir, "groups")
+}
+
+// GroupDir returns the directory for an instance group.
+func (p *Paths) GroupDir(id string) string {
+	return filepath.Join(p.dataDir, "groups", id)
+}
+
+// GroupMetadata returns the path to instance group metadata.json.
+func (p *Paths) GroupMetadata(id string) string {
+	return filepath.Join(p.GroupDir(id), "metadata.json")
+}
+
+// Usage path methods
+
+// UsageDir returns the directory of hourly usage rollups.
+func (p *Paths) UsageDir() string {
+	return filepath.Join(p.dataDir, "usage")
+}
+
+// UsageRollup returns the path to the usage rollup of the hour starting at
+// hour (UTC).
+func (p *Paths) UsageRollup(hour time.Time) string {
+	return filepath.Join(p.UsageDir(), hour.UTC().Format("2006-01-02T15")+".json")
+}
+
+// Exec recording path methods
+
+// ExecRecordingsRoot returns the root directory of exec session recordings.
+func (p *Paths) ExecRecordingsRoot() string {
+	return filepath.Join(p.dataDir, "recordings")
+}
+
+// ExecRecordingsDir returns the directory of an instance's exec session recordings.
+func (p *Paths) ExecRecordingsDir(instanceID string) string {
+	return filepath.Join(p.ExecRecordingsRoot(), instanceID)
+}
+
+// ExecRecording returns the path to an exec session's asciicast recording.
+func (p *Paths) ExecRecording(instanceID, id string) string {
+	return filepath.Join(p.ExecRecordingsDir(instanceID), id+".cast")
+}
+
 // Caddy path methods
 
 // CaddyDir returns the caddy data directory.
@@ -273,6 +401,58 @@ func (p *Paths) CaddyConfigDir() string {
 	return filepath.Join(p.CaddyDir(), "config")
 }
 
+// Network path methods
+
+// NetworkDir returns the state directory for a network.
+func (p *Paths) NetworkDir(name string) string {
+	return filepath.Join(p.dataDir, "network", name)
+}
+
+// NetworkDNSConfig returns the path to a network's custom DNS configuration.
+func (p *Paths) NetworkDNSConfig(name string) string {
+	return filepath.Join(p.NetworkDir(name), "dns.json")
+}
+
+// NetworkDHCPConfig returns the path to a network's DHCP options.
+func (p *Paths) NetworkDHCPConfig(name string) string {
+	return filepath.Join(p.NetworkDir(name), "dhcp.json")
+}
+
+// NetworkUplinkConfig returns the path to a network's uplink.
+func (p *Paths) NetworkUplinkConfig(name string) string {
+	return filepath.Join(p.NetworkDir(name), "uplink.json")
+}
+
+// NetworkAllocations returns the path to a network's persisted address reservations.
+func (p *Paths) NetworkAllocations(name string) string {
+	return filepath.Join(p.NetworkDir(name), "allocations.json")
+}
+
+// NetworkDnsmasqConfig returns the path to the rendered dnsmasq config for a network.
+func (p *Paths) NetworkDnsmasqConfig(name string) string {
+	return filepath.Join(p.NetworkDir(name), "dnsmasq.conf")
+}
+
+// NetworkDnsmasqHosts returns the path to the rendered dnsmasq addn-hosts file for a network.
+func (p *Paths) NetworkDnsmasqHosts(name string) string {
+	return filepath.Join(p.NetworkDir(name), "hosts")
+}
+
+// OverlayDir returns the state directory of the WireGuard overlay.
+func (p *Paths) OverlayDir() string {
+	return filepath.Join(p.dataDir, "overlay")
+}
+
+// OverlayPrivateKey returns the path to this host's WireGuard private key.
+func (p *Paths) OverlayPrivateKey() string {
+	return filepath.Join(p.OverlayDir(), "private.key")
+}
+
+// OverlayPeers returns the path to the overlay's registered peers.
+func (p *Paths) OverlayPeers() string {
+	return filepath.Join(p.OverlayDir(), "peers.json")
+}
+
 // Ingress path methods
 
 // IngressesDir returns the root ingresses directory.
@@ -321,3 +501,18 @@ func (p *Paths) BuildSourceDir(id string) string {
 func (p *Paths) BuildConfig(id string) string {
 	return filepath.Join(p.BuildDir(id), "config.json")
 }
+
+// BuildArtifact returns the path to the artifact tarball of an artifact build.
+func (p *Paths) BuildArtifact(id string) string {
+	return filepath.Join(p.BuildDir(id), "artifact.tar.gz")
+}
+
+// BuildSBOM returns the path to the SPDX SBOM of a build.
+func (p *Paths) BuildSBOM(id string) string {
+	return filepath.Join(p.BuildDir(id), "sbom.spdx.json")
+}
+
+// BuildSLSAProvenance returns the path to the SLSA provenance statement of a build.
+func (p *Paths) BuildSLSAProvenance(id string) string {
+	return filepath.Join(p.BuildDir(id), "provenance.intoto.json")
+}
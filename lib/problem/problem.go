@@ -0,0 +1,199 @@
+// Package problem renders API errors as RFC 7807 problem details
+// (application/problem+json) with the machine-readable codes from the
+// OpenAPI ErrorCode enum.
+package problem
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"regexp"
+	"strconv"
+
+	"github.com/kernel/hypeman/lib/oapi"
+)
+
+// ContentType is the media type of problem responses
+const ContentType = "application/problem+json"
+
+// titles are the short, occurrence-independent summaries of each code
+var titles = map[oapi.ErrorCode]string{
+	oapi.InvalidRequest: "Invalid request",
+	oapi.Unauthorized:   "Unauthorized",
+	oapi.Forbidden:      "Forbidden",
+	oapi.NotFound:       "Not found",
+	oapi.Ambiguous:      "Ambiguous reference",
+	oapi.AlreadyExists:  "Already exists",
+	oapi.Conflict:       "Conflict",
+	oapi.InUse:          "Resource in use",
+	oapi.InvalidState:   "Invalid state",
+	oapi.ImageNotReady:  "Image not ready",
+	oapi.QuotaExceeded:  "Quota exceeded",
+	oapi.RateLimited:    "Rate limited",
+	oapi.InternalError:  "Internal error",
+	oapi.NotImplemented: "Not implemented",
+}
+
+// TypeURI returns the problem type URI for a code
+func TypeURI(code oapi.ErrorCode) string {
+	return "urn:hypeman:error:" + string(code)
+}
+
+// CodeForStatus returns the code for errors that only carry an HTTP status,
+// such as request validation failures.
+func CodeForStatus(status int) oapi.ErrorCode {
+	switch status {
+	case http.StatusUnauthorized:
+		return oapi.Unauthorized
+	case http.StatusForbidden:
+		return oapi.Forbidden
+	case http.StatusNotFound:
+		return oapi.NotFound
+	case http.StatusConflict:
+		return oapi.Conflict
+	case http.StatusTooManyRequests:
+		return oapi.RateLimited
+	case http.StatusNotImplemented:
+		return oapi.NotImplemented
+	}
+	if status >= 500 {
+		return oapi.InternalError
+	}
+	return oapi.InvalidRequest
+}
+
+// Retryable reports whether repeating a request that failed with status and
+// code may succeed without changes.
+func Retryable(status int, code oapi.ErrorCode) bool {
+	switch code {
+	case oapi.RateLimited, oapi.ImageNotReady:
+		return true
+	}
+	switch status {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// Complete fills in the RFC 7807 members of e that aren't already set.
+// instance is the request path, or "" to leave it unset.
+func Complete(e *oapi.Error, status int, instance string) {
+	if e.Code == "" {
+		e.Code = CodeForStatus(status)
+	}
+	if e.Type == nil {
+		t := TypeURI(e.Code)
+		e.Type = &t
+	}
+	if e.Title == nil {
+		title, ok := titles[e.Code]
+		if !ok {
+			title = http.StatusText(status)
+		}
+		e.Title = &title
+	}
+	if e.Status == nil {
+		e.Status = &status
+	}
+	if e.Detail == nil && e.Message != "" {
+		detail := e.Message
+		e.Detail = &detail
+	}
+	if e.Instance == nil && instance != "" {
+		e.Instance = &instance
+	}
+	if e.Retryable == nil {
+		retryable := Retryable(status, e.Code)
+		e.Retryable = &retryable
+	}
+}
+
+// New returns a complete problem for status and code
+func New(status int, code oapi.ErrorCode, message string) oapi.Error {
+	e := oapi.Error{Code: code, Message: message}
+	Complete(&e, status, "")
+	return e
+}
+
+// Write writes a problem response. Use it for errors written outside the
+// generated strict handlers, such as by middleware and WebSocket endpoints.
+func Write(w http.ResponseWriter, status int, code oapi.ErrorCode, message string) {
+	WriteProblem(w, status, oapi.Error{Code: code, Message: message})
+}
+
+// WriteProblem completes e and writes it as a problem response
+func WriteProblem(w http.ResponseWriter, status int, e oapi.Error) {
+	Complete(&e, status, "")
+	w.Header().Set("Content-Type", ContentType)
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(e)
+}
+
+// WriteError writes err as a problem response with the code for status.
+// It matches the strict handlers' request and response error hooks.
+func WriteError(status int) func(w http.ResponseWriter, r *http.Request, err error) {
+	return func(w http.ResponseWriter, r *http.Request, err error) {
+		Write(w, status, CodeForStatus(status), err.Error())
+	}
+}
+
+var (
+	errorType = reflect.TypeOf(oapi.Error{})
+
+	// problemResponseRegex extracts the status from generated error response
+	// types, like GetInstance404ApplicationProblemPlusJSONResponse
+	problemResponseRegex = regexp.MustCompile(`(\d{3})ApplicationProblemPlusJSONResponse$`)
+)
+
+// problemStatus returns the status of a generated error response type
+func problemStatus(t reflect.Type) (int, bool) {
+	if t.Kind() != reflect.Struct || !t.ConvertibleTo(errorType) {
+		return 0, false
+	}
+	m := problemResponseRegex.FindStringSubmatch(t.Name())
+	if m == nil {
+		return 0, false
+	}
+	status, _ := strconv.Atoi(m[1])
+	return status, true
+}
+
+// FromResponse returns the complete problem of a strict handler response,
+// or false if the response isn't an error. It lets handlers that call other
+// handlers inspect their outcome.
+func FromResponse(response any) (oapi.Error, bool) {
+	if response == nil {
+		return oapi.Error{}, false
+	}
+	v := reflect.ValueOf(response)
+	status, ok := problemStatus(v.Type())
+	if !ok {
+		return oapi.Error{}, false
+	}
+	e := v.Convert(errorType).Interface().(oapi.Error)
+	Complete(&e, status, "")
+	return e, true
+}
+
+// StrictMiddleware completes the problem details of error responses returned
+// by strict handlers, so handlers only need to set code and message.
+func StrictMiddleware(f oapi.StrictHandlerFunc, operationID string) oapi.StrictHandlerFunc {
+	return func(ctx context.Context, w http.ResponseWriter, r *http.Request, request interface{}) (interface{}, error) {
+		response, err := f(ctx, w, r, request)
+		if err != nil || response == nil {
+			return response, err
+		}
+
+		v := reflect.ValueOf(response)
+		status, ok := problemStatus(v.Type())
+		if !ok {
+			return response, nil
+		}
+
+		e := v.Convert(errorType).Interface().(oapi.Error)
+		Complete(&e, status, r.URL.Path)
+		return reflect.ValueOf(e).Convert(v.Type()).Interface(), nil
+	}
+}
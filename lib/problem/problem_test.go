@@ -0,0 +1,107 @@
+package problem
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/kernel/hypeman/lib/oapi"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWrite(t *testing.T) {
+	rec := httptest.NewRecorder()
+	Write(rec, http.StatusTooManyRequests, oapi.RateLimited, "too many concurrent exec sessions")
+
+	assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+	assert.Equal(t, ContentType, rec.Header().Get("Content-Type"))
+
+	var body map[string]any
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+	assert.Equal(t, "urn:hypeman:error:rate_limited", body["type"])
+	assert.Equal(t, "Rate limited", body["title"])
+	assert.EqualValues(t, 429, body["status"])
+	assert.Equal(t, "too many concurrent exec sessions", body["detail"])
+	assert.Equal(t, "rate_limited", body["code"])
+	assert.Equal(t, "too many concurrent exec sessions", body["message"])
+	assert.Equal(t, true, body["retryable"])
+}
+
+func TestComplete_KeepsExplicitFields(t *testing.T) {
+	retryable := true
+	e := oapi.Error{Code: oapi.InvalidState, Message: "instance is stopping", Retryable: &retryable}
+	Complete(&e, http.StatusConflict, "/instances/abc/standby")
+
+	assert.Equal(t, "urn:hypeman:error:invalid_state", *e.Type)
+	assert.Equal(t, http.StatusConflict, *e.Status)
+	assert.Equal(t, "/instances/abc/standby", *e.Instance)
+	assert.True(t, *e.Retryable)
+}
+
+func TestCodeForStatus(t *testing.T) {
+	assert.Equal(t, oapi.InvalidRequest, CodeForStatus(http.StatusBadRequest))
+	assert.Equal(t, oapi.Unauthorized, CodeForStatus(http.StatusUnauthorized))
+	assert.Equal(t, oapi.NotFound, CodeForStatus(http.StatusNotFound))
+	assert.Equal(t, oapi.RateLimited, CodeForStatus(http.StatusTooManyRequests))
+	assert.Equal(t, oapi.InternalError, CodeForStatus(http.StatusBadGateway))
+}
+
+func TestRetryable(t *testing.T) {
+	assert.True(t, Retryable(http.StatusBadRequest, oapi.ImageNotReady))
+	assert.True(t, Retryable(http.StatusServiceUnavailable, oapi.InternalError))
+	assert.False(t, Retryable(http.StatusInternalServerError, oapi.InternalError))
+	assert.False(t, Retryable(http.StatusNotFound, oapi.NotFound))
+}
+
+func TestStrictMiddleware(t *testing.T) {
+	serve := func(response any) any {
+		handler := StrictMiddleware(func(ctx context.Context, w http.ResponseWriter, r *http.Request, request interface{}) (interface{}, error) {
+			return response, nil
+		}, "GetInstance")
+		r := httptest.NewRequest(http.MethodGet, "/instances/missing", nil)
+		resp, err := handler(r.Context(), httptest.NewRecorder(), r, nil)
+		require.NoError(t, err)
+		return resp
+	}
+
+	resp := serve(oapi.GetInstance404ApplicationProblemPlusJSONResponse{Code: oapi.NotFound, Message: "instance not found"})
+	notFound, ok := resp.(oapi.GetInstance404ApplicationProblemPlusJSONResponse)
+	require.True(t, ok, "response keeps its generated type")
+	assert.Equal(t, http.StatusNotFound, *notFound.Status)
+	assert.Equal(t, "Not found", *notFound.Title)
+	assert.Equal(t, "/instances/missing", *notFound.Instance)
+	assert.Equal(t, "instance not found", *notFound.Detail)
+	assert.False(t, *notFound.Retryable)
+
+	// Success responses pass through untouched
+	ok200 := oapi.GetInstance200JSONResponse{Id: "abc"}
+	assert.Equal(t, ok200, serve(ok200))
+}
+
+func TestFromResponse(t *testing.T) {
+	e, ok := FromResponse(oapi.DeleteVolume409ApplicationProblemPlusJSONResponse{Code: oapi.InUse, Message: "volume is attached"})
+	require.True(t, ok)
+	assert.Equal(t, oapi.InUse, e.Code)
+	assert.Equal(t, http.StatusConflict, *e.Status)
+	assert.Equal(t, "volume is attached", *e.Detail)
+
+	_, ok = FromResponse(oapi.DeleteVolume204Response{})
+	assert.False(t, ok)
+	_, ok = FromResponse(nil)
+	assert.False(t, ok)
+}
+
+func TestWriteError(t *testing.T) {
+	rec := httptest.NewRecorder()
+	WriteError(http.StatusBadRequest)(rec, httptest.NewRequest(http.MethodPost, "/instances", nil), errors.New("can't decode JSON body"))
+
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Equal(t, ContentType, rec.Header().Get("Content-Type"))
+	var e oapi.Error
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &e))
+	assert.Equal(t, oapi.InvalidRequest, e.Code)
+}
@@ -4,22 +4,36 @@ import (
 	"context"
 	"fmt"
 	"log/slog"
+	"net/http"
+	"path/filepath"
+	"strings"
 	"time"
 
 	"github.com/c2h5oh/datasize"
 	"github.com/kernel/hypeman/cmd/api/config"
 	"github.com/kernel/hypeman/lib/builds"
 	"github.com/kernel/hypeman/lib/devices"
+	"github.com/kernel/hypeman/lib/groups"
+	"github.com/kernel/hypeman/lib/health"
 	"github.com/kernel/hypeman/lib/hypervisor"
 	"github.com/kernel/hypeman/lib/images"
 	"github.com/kernel/hypeman/lib/ingress"
 	"github.com/kernel/hypeman/lib/instances"
+	"github.com/kernel/hypeman/lib/keyring"
 	"github.com/kernel/hypeman/lib/logger"
+	"github.com/kernel/hypeman/lib/metering"
+	mw "github.com/kernel/hypeman/lib/middleware"
 	"github.com/kernel/hypeman/lib/network"
+	"github.com/kernel/hypeman/lib/network/overlay"
+	"github.com/kernel/hypeman/lib/nodeagent"
 	hypemanotel "github.com/kernel/hypeman/lib/otel"
 	"github.com/kernel/hypeman/lib/paths"
+	"github.com/kernel/hypeman/lib/quotas"
+	"github.com/kernel/hypeman/lib/recordings"
 	"github.com/kernel/hypeman/lib/registry"
 	"github.com/kernel/hypeman/lib/resources"
+	"github.com/kernel/hypeman/lib/scheduler"
+	"github.com/kernel/hypeman/lib/secrets"
 	"github.com/kernel/hypeman/lib/system"
 	"github.com/kernel/hypeman/lib/volumes"
 	"go.opentelemetry.io/otel"
@@ -63,9 +77,18 @@ func ProvidePaths(cfg *config.Config) *paths.Paths {
 }
 
 // ProvideImageManager provides the image manager
-func ProvideImageManager(p *paths.Paths, cfg *config.Config) (images.Manager, error) {
+func ProvideImageManager(p *paths.Paths, cfg *config.Config, proxy *registry.Proxy) (images.Manager, error) {
 	meter := otel.GetMeterProvider().Meter("hypeman")
-	return images.NewManager(p, cfg.MaxConcurrentBuilds, meter)
+	tracer := otel.GetTracerProvider().Tracer("hypeman")
+	mgr, err := images.NewManager(p, cfg.MaxConcurrentBuilds, meter, tracer)
+	if err != nil {
+		return nil, err
+	}
+	// Pulls from proxied registries share the pull-through cache
+	if proxy != nil {
+		mgr.SetPullTransport(proxy.Transport(http.DefaultTransport))
+	}
+	return mgr, nil
 }
 
 // ProvideSystemManager provides the system manager
@@ -79,17 +102,87 @@ func ProvideNetworkManager(p *paths.Paths, cfg *config.Config) network.Manager {
 	return network.NewManager(p, cfg, meter)
 }
 
+// ProvideOverlayManager provides the cross-host WireGuard overlay manager.
+// The default network's subnet is advertised to peers.
+func ProvideOverlayManager(p *paths.Paths, cfg *config.Config) overlay.Manager {
+	return overlay.NewManager(p, overlay.Config{
+		Interface:  cfg.OverlayInterface,
+		ListenPort: cfg.OverlayListenPort,
+		Endpoint:   cfg.OverlayEndpoint,
+		Networks:   []overlay.LocalNetwork{{Name: "default", Subnet: cfg.SubnetCIDR, Bridge: cfg.BridgeName}},
+	})
+}
+
 // ProvideDeviceManager provides the device manager
 func ProvideDeviceManager(p *paths.Paths) devices.Manager {
-	return devices.NewManager(p)
+	mgr := devices.NewManager(p)
+	meter := otel.GetMeterProvider().Meter("hypeman")
+	// Metrics are best-effort; the manager works without them
+	_ = devices.RegisterGPUHealthMetrics(meter, mgr)
+	return mgr
 }
 
 // ProvideInstanceManager provides the instance manager
-func ProvideInstanceManager(p *paths.Paths, cfg *config.Config, imageManager images.Manager, systemManager system.Manager, networkManager network.Manager, deviceManager devices.Manager, volumeManager volumes.Manager) (instances.Manager, error) {
+func ProvideInstanceManager(p *paths.Paths, cfg *config.Config, imageManager images.Manager, systemManager system.Manager, networkManager network.Manager, deviceManager devices.Manager, volumeManager volumes.Manager, secretManager secrets.Manager) (instances.Manager, error) {
+	limits, err := ParseAdmissionLimits(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	// Parse hypervisor memory overhead
+	var vmmMemoryOverhead datasize.ByteSize
+	if err := vmmMemoryOverhead.UnmarshalText([]byte(cfg.VMMMemoryOverhead)); err != nil {
+		return nil, fmt.Errorf("failed to parse VMM_MEMORY_OVERHEAD '%s': %w (expected format like '256MB', '1GB')", cfg.VMMMemoryOverhead, err)
+	}
+
+	bootTimeout, err := time.ParseDuration(cfg.BootTimeout)
+	if err != nil {
+		return nil, fmt.Errorf("invalid BOOT_TIMEOUT %q: %w", cfg.BootTimeout, err)
+	}
+	shutdownGracePeriod, err := time.ParseDuration(cfg.ShutdownGracePeriod)
+	if err != nil {
+		return nil, fmt.Errorf("invalid SHUTDOWN_GRACE_PERIOD %q: %w", cfg.ShutdownGracePeriod, err)
+	}
+
+	hooks, err := provideHookConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	limits.VMMCgroup = cfg.VMMCgroup
+	limits.VMMMemoryOverhead = int64(vmmMemoryOverhead)
+	limits.VMMSandbox = cfg.VMMSandbox
+	limits.VirtiofsdBinary = cfg.VirtiofsdBinary
+	limits.UEFIFirmware = map[hypervisor.Type]string{}
+	if cfg.UEFIFirmwareQEMU != "" {
+		limits.UEFIFirmware[hypervisor.TypeQEMU] = cfg.UEFIFirmwareQEMU
+	}
+	if cfg.UEFIFirmwareCloudHypervisor != "" {
+		limits.UEFIFirmware[hypervisor.TypeCloudHypervisor] = cfg.UEFIFirmwareCloudHypervisor
+	}
+	limits.VirtioDriversISO = cfg.VirtioDriversISO
+	limits.BootTimeout = bootTimeout
+	limits.ShutdownGracePeriod = shutdownGracePeriod
+	limits.Hooks = hooks
+	for _, root := range strings.Split(cfg.SharedDirectoryRoots, ",") {
+		if root = strings.TrimSpace(root); root != "" {
+			limits.SharedDirectoryRoots = append(limits.SharedDirectoryRoots, filepath.Clean(root))
+		}
+	}
+
+	meter := otel.GetMeterProvider().Meter("hypeman")
+	tracer := otel.GetTracerProvider().Tracer("hypeman")
+	defaultHypervisor := hypervisor.Type(cfg.DefaultHypervisor)
+	return instances.NewManager(p, imageManager, systemManager, networkManager, deviceManager, volumeManager, secretManager, limits, defaultHypervisor, meter, tracer), nil
+}
+
+// ParseAdmissionLimits parses the limits new instances are admitted
+// against. They can be reloaded without a restart.
+func ParseAdmissionLimits(cfg *config.Config) (instances.ResourceLimits, error) {
 	// Parse max overlay size from config
 	var maxOverlaySize datasize.ByteSize
 	if err := maxOverlaySize.UnmarshalText([]byte(cfg.MaxOverlaySize)); err != nil {
-		return nil, fmt.Errorf("failed to parse MAX_OVERLAY_SIZE '%s': %w (expected format like '100GB', '50G', '10GiB')", cfg.MaxOverlaySize, err)
+		return instances.ResourceLimits{}, fmt.Errorf("failed to parse MAX_OVERLAY_SIZE '%s': %w (expected format like '100GB', '50G', '10GiB')", cfg.MaxOverlaySize, err)
 	}
 
 	// Parse max memory per instance (empty or "0" means unlimited)
@@ -97,7 +190,7 @@ func ProvideInstanceManager(p *paths.Paths, cfg *config.Config, imageManager ima
 	if cfg.MaxMemoryPerInstance != "" && cfg.MaxMemoryPerInstance != "0" {
 		var memSize datasize.ByteSize
 		if err := memSize.UnmarshalText([]byte(cfg.MaxMemoryPerInstance)); err != nil {
-			return nil, fmt.Errorf("failed to parse MAX_MEMORY_PER_INSTANCE '%s': %w", cfg.MaxMemoryPerInstance, err)
+			return instances.ResourceLimits{}, fmt.Errorf("failed to parse MAX_MEMORY_PER_INSTANCE '%s': %w", cfg.MaxMemoryPerInstance, err)
 		}
 		maxMemoryPerInstance = int64(memSize)
 	}
@@ -107,23 +200,55 @@ func ProvideInstanceManager(p *paths.Paths, cfg *config.Config, imageManager ima
 	if cfg.MaxTotalMemory != "" && cfg.MaxTotalMemory != "0" {
 		var memSize datasize.ByteSize
 		if err := memSize.UnmarshalText([]byte(cfg.MaxTotalMemory)); err != nil {
-			return nil, fmt.Errorf("failed to parse MAX_TOTAL_MEMORY '%s': %w", cfg.MaxTotalMemory, err)
+			return instances.ResourceLimits{}, fmt.Errorf("failed to parse MAX_TOTAL_MEMORY '%s': %w", cfg.MaxTotalMemory, err)
 		}
 		maxTotalMemory = int64(memSize)
 	}
 
-	limits := instances.ResourceLimits{
+	return instances.ResourceLimits{
 		MaxOverlaySize:       int64(maxOverlaySize),
 		MaxVcpusPerInstance:  cfg.MaxVcpusPerInstance,
 		MaxMemoryPerInstance: maxMemoryPerInstance,
 		MaxTotalVcpus:        cfg.MaxTotalVcpus,
 		MaxTotalMemory:       maxTotalMemory,
-	}
+	}, nil
+}
 
-	meter := otel.GetMeterProvider().Meter("hypeman")
-	tracer := otel.GetTracerProvider().Tracer("hypeman")
-	defaultHypervisor := hypervisor.Type(cfg.DefaultHypervisor)
-	return instances.NewManager(p, imageManager, systemManager, networkManager, deviceManager, volumeManager, limits, defaultHypervisor, meter, tracer), nil
+// provideHookConfig parses the lifecycle hooks configuration
+func provideHookConfig(cfg *config.Config) (instances.HookConfig, error) {
+	timeout, err := time.ParseDuration(cfg.HookTimeout)
+	if err != nil {
+		return instances.HookConfig{}, fmt.Errorf("invalid HOOK_TIMEOUT %q: %w", cfg.HookTimeout, err)
+	}
+	hooks := instances.HookConfig{
+		Hooks:   make(map[instances.HookEvent][]instances.Hook),
+		Abort:   make(map[instances.HookEvent]bool),
+		Timeout: timeout,
+	}
+	for event, value := range map[instances.HookEvent]string{
+		instances.HookPreCreate:   cfg.HookPreCreate,
+		instances.HookPostBoot:    cfg.HookPostBoot,
+		instances.HookPreStandby:  cfg.HookPreStandby,
+		instances.HookPostRestore: cfg.HookPostRestore,
+		instances.HookPreDelete:   cfg.HookPreDelete,
+	} {
+		for _, s := range strings.Split(value, ",") {
+			if s = strings.TrimSpace(s); s == "" {
+				continue
+			}
+			hook, err := instances.ParseHook(s)
+			if err != nil {
+				return instances.HookConfig{}, fmt.Errorf("invalid %s hook: %w", event, err)
+			}
+			hooks.Hooks[event] = append(hooks.Hooks[event], hook)
+		}
+	}
+	for _, event := range strings.Split(cfg.HookAbortOnFailure, ",") {
+		if event = strings.TrimSpace(event); event != "" {
+			hooks.Abort[instances.HookEvent(event)] = true
+		}
+	}
+	return hooks, nil
 }
 
 // ProvideVolumeManager provides the volume manager
@@ -138,23 +263,133 @@ func ProvideVolumeManager(p *paths.Paths, cfg *config.Config) (volumes.Manager,
 		maxTotalVolumeStorage = int64(storageSize)
 	}
 
+	// Encrypted volumes need a keyring, sealed under the configured master key
+	keys, err := provideKeyring(cfg, p.KeyringDir())
+	if err != nil {
+		return nil, err
+	}
+
 	meter := otel.GetMeterProvider().Meter("hypeman")
-	return volumes.NewManager(p, maxTotalVolumeStorage, meter), nil
+	return volumes.NewManager(p, maxTotalVolumeStorage, keys, meter), nil
+}
+
+// ProvideSecretManager provides the secrets manager. Secrets are disabled
+// unless a master key is configured.
+func ProvideSecretManager(p *paths.Paths, cfg *config.Config) (secrets.Manager, error) {
+	keys, err := provideKeyring(cfg, p.SecretKeyringDir())
+	if err != nil {
+		return nil, err
+	}
+	return secrets.NewManager(p, keys), nil
+}
+
+// provideKeyring creates a keyring in dir sealed under the master key from
+// MASTER_KEY_FILE, or returns nil if none is configured
+func provideKeyring(cfg *config.Config, dir string) (keyring.Keyring, error) {
+	if cfg.MasterKeyFile == "" {
+		return nil, nil
+	}
+	masterKey, err := keyring.LoadMasterKey(cfg.MasterKeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load MASTER_KEY_FILE: %w", err)
+	}
+	keys, err := keyring.NewFileKeyring(dir, masterKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create keyring: %w", err)
+	}
+	return keys, nil
+}
+
+// ProvideGroupManager provides the instance group manager
+func ProvideGroupManager(p *paths.Paths, instanceManager instances.Manager, imageManager images.Manager, ingressManager ingress.Manager) groups.Manager {
+	return groups.NewManager(p, instanceManager, imageManager, ingressManager)
+}
+
+// ProvideMeter provides the usage meter. It records nothing until started.
+func ProvideMeter(p *paths.Paths, cfg *config.Config, instanceManager instances.Manager) (*metering.Meter, error) {
+	interval, err := time.ParseDuration(cfg.MeteringInterval)
+	if err != nil {
+		return nil, fmt.Errorf("invalid METERING_INTERVAL %q: %w", cfg.MeteringInterval, err)
+	}
+	retention, err := time.ParseDuration(cfg.UsageRetention)
+	if err != nil {
+		return nil, fmt.Errorf("invalid USAGE_RETENTION %q: %w", cfg.UsageRetention, err)
+	}
+	return metering.NewMeter(p, instanceManager, metering.Config{Interval: interval, Retention: retention}), nil
+}
+
+// ProvideQuotaManager provides the quota manager enforcing QUOTAS
+func ProvideQuotaManager(cfg *config.Config, instanceManager instances.Manager, volumeManager volumes.Manager, buildManager builds.Manager) (quotas.Manager, error) {
+	q, err := quotas.Parse(cfg.Quotas)
+	if err != nil {
+		return nil, fmt.Errorf("invalid QUOTAS: %w", err)
+	}
+	return quotas.NewManager(q, instanceManager, volumeManager, buildManager), nil
+}
+
+// ProvideRegistryProxy provides the registry's pull-through cache of
+// REGISTRY_PROXY_UPSTREAMS, or nil when none are configured
+func ProvideRegistryProxy(p *paths.Paths, cfg *config.Config) (*registry.Proxy, error) {
+	upstreams := registryProxyUpstreams(cfg)
+	if len(upstreams) == 0 {
+		return nil, nil
+	}
+	ttl, err := time.ParseDuration(cfg.RegistryProxyTagTTL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid REGISTRY_PROXY_TAG_TTL %q: %w", cfg.RegistryProxyTagTTL, err)
+	}
+	return registry.NewProxy(p, registry.ProxyConfig{Upstreams: upstreams, TagTTL: ttl})
+}
+
+func registryProxyUpstreams(cfg *config.Config) []string {
+	var upstreams []string
+	for _, upstream := range strings.Split(cfg.RegistryProxyUpstreams, ",") {
+		if upstream = strings.TrimSpace(upstream); upstream != "" {
+			upstreams = append(upstreams, upstream)
+		}
+	}
+	return upstreams
 }
 
 // ProvideRegistry provides the OCI registry for image push
-func ProvideRegistry(p *paths.Paths, imageManager images.Manager) (*registry.Registry, error) {
-	return registry.New(p, imageManager)
+func ProvideRegistry(p *paths.Paths, imageManager images.Manager, proxy *registry.Proxy) (*registry.Registry, error) {
+	reg, err := registry.New(p, imageManager)
+	if err != nil {
+		return nil, err
+	}
+	if proxy != nil {
+		reg.SetProxy(proxy)
+	}
+	return reg, nil
+}
+
+// ProvideHealthChecker provides the subsystem checks behind /healthz and
+// /readyz. KVM, the network and the registry blob store are required to
+// serve traffic; the rest only degrade the server when they fail.
+func ProvideHealthChecker(imageManager images.Manager, networkManager network.Manager, ingressManager ingress.Manager, reg *registry.Registry) *health.Checker {
+	checker := health.NewChecker(health.DefaultTimeout)
+	checker.Register("kvm", true, health.DeviceCheck("/dev/kvm"))
+	checker.Register("network", true, networkManager.CheckHealth)
+	checker.Register("registry", true, health.WritableCheck(reg.BlobDir()))
+	checker.Register("ingress", false, ingressManager.CheckHealth)
+	// vhost-vsock backs the guest agent connection of QEMU instances
+	checker.Register("vsock", false, health.DeviceCheck("/dev/vhost-vsock"))
+	checker.Register("image_queue", false, func(ctx context.Context) (string, error) {
+		q := imageManager.BuildQueueState()
+		return fmt.Sprintf("%d active, %d pending (max %d concurrent)", len(q.Active), len(q.Pending), q.MaxConcurrent), nil
+	})
+	return checker
 }
 
 // ProvideResourceManager provides the resource manager for capacity tracking
-func ProvideResourceManager(ctx context.Context, cfg *config.Config, p *paths.Paths, imageManager images.Manager, instanceManager instances.Manager, volumeManager volumes.Manager) (*resources.Manager, error) {
+func ProvideResourceManager(ctx context.Context, cfg *config.Config, p *paths.Paths, imageManager images.Manager, instanceManager instances.Manager, volumeManager volumes.Manager, deviceManager devices.Manager) (*resources.Manager, error) {
 	mgr := resources.NewManager(cfg, p)
 
 	// Managers implement the lister interfaces directly
 	mgr.SetImageLister(imageManager)
 	mgr.SetInstanceLister(instanceManager)
 	mgr.SetVolumeLister(volumeManager)
+	mgr.SetGPUReservationLister(deviceManager)
 
 	// Initialize resource discovery
 	if err := mgr.Initialize(ctx); err != nil {
@@ -164,21 +399,94 @@ func ProvideResourceManager(ctx context.Context, cfg *config.Config, p *paths.Pa
 	return mgr, nil
 }
 
-// ProvideIngressManager provides the ingress manager
-func ProvideIngressManager(p *paths.Paths, cfg *config.Config, instanceManager instances.Manager) (ingress.Manager, error) {
+// ProvideNodeAgent provides the node agent that reports capacity to a
+// central control plane and holds the slots it claims
+func ProvideNodeAgent(cfg *config.Config, resourceManager *resources.Manager) (*nodeagent.Agent, error) {
+	slotTTL, err := time.ParseDuration(cfg.NodeSlotTTL)
+	if err != nil {
+		return nil, fmt.Errorf("parse NODE_SLOT_TTL: %w", err)
+	}
+	var heartbeatInterval time.Duration
+	if cfg.ControlPlaneURL != "" {
+		if heartbeatInterval, err = time.ParseDuration(cfg.NodeHeartbeatInterval); err != nil {
+			return nil, fmt.Errorf("parse NODE_HEARTBEAT_INTERVAL: %w", err)
+		}
+	}
+	return nodeagent.NewAgent(nodeagent.Config{
+		NodeID:            cfg.NodeID,
+		Address:           cfg.NodeAddress,
+		ControlPlaneURL:   cfg.ControlPlaneURL,
+		Token:             cfg.ControlPlaneToken,
+		HeartbeatInterval: heartbeatInterval,
+		SlotTTL:           slotTTL,
+		Version:           cfg.Version,
+	}, resourceManager), nil
+}
+
+// ParseACMEConfig parses the ACME settings for TLS ingresses. They can be
+// reloaded without a restart.
+func ParseACMEConfig(cfg *config.Config) (ingress.ACMEConfig, error) {
 	// Parse DNS provider - fail if invalid
 	dnsProvider, err := ingress.ParseDNSProvider(cfg.AcmeDnsProvider)
 	if err != nil {
-		return nil, fmt.Errorf("invalid ACME_DNS_PROVIDER: %w", err)
+		return ingress.ACMEConfig{}, fmt.Errorf("invalid ACME_DNS_PROVIDER: %w", err)
 	}
 
 	// Validate DNS propagation timeout if set (must be a valid Go duration string)
 	if cfg.DnsPropagationTimeout != "" {
 		if _, err := time.ParseDuration(cfg.DnsPropagationTimeout); err != nil {
-			return nil, fmt.Errorf("invalid DNS_PROPAGATION_TIMEOUT %q: %w (expected format like '2m', '120s', '1h')", cfg.DnsPropagationTimeout, err)
+			return ingress.ACMEConfig{}, fmt.Errorf("invalid DNS_PROPAGATION_TIMEOUT %q: %w (expected format like '2m', '120s', '1h')", cfg.DnsPropagationTimeout, err)
 		}
 	}
 
+	return ingress.ACMEConfig{
+		Email:                 cfg.AcmeEmail,
+		DNSProvider:           dnsProvider,
+		CA:                    cfg.AcmeCA,
+		DNSPropagationTimeout: cfg.DnsPropagationTimeout,
+		DNSResolvers:          cfg.DnsResolvers,
+		AllowedDomains:        cfg.TlsAllowedDomains,
+		CloudflareAPIToken:    cfg.CloudflareApiToken,
+	}, nil
+}
+
+// ProvideRateLimiter provides the per-client API rate limiter. It allows
+// every request while RATE_LIMIT_RPS is 0, and can be reconfigured on reload.
+func ProvideRateLimiter(cfg *config.Config) *mw.RateLimiter {
+	return mw.NewRateLimiter(cfg.RateLimitRPS, cfg.RateLimitBurst)
+}
+
+// ProvideScheduler provides the maintenance task scheduler. Tasks are
+// registered at startup, once the managers they use are initialized.
+func ProvideScheduler() *scheduler.Scheduler {
+	return scheduler.New()
+}
+
+// ProvideRecordingStore provides the exec session recording store. It
+// exists even while EXEC_RECORDING is off, so earlier recordings stay
+// downloadable and keep being pruned.
+func ProvideRecordingStore(p *paths.Paths, cfg *config.Config) (*recordings.Store, error) {
+	var maxTotalSize datasize.ByteSize
+	if err := maxTotalSize.UnmarshalText([]byte(cfg.ExecRecordingMaxTotalSize)); err != nil {
+		return nil, fmt.Errorf("invalid EXEC_RECORDING_MAX_TOTAL_SIZE %q: %w", cfg.ExecRecordingMaxTotalSize, err)
+	}
+	maxAge, err := time.ParseDuration(cfg.ExecRecordingMaxAge)
+	if err != nil || maxAge < 0 {
+		return nil, fmt.Errorf("invalid EXEC_RECORDING_MAX_AGE %q: must be a non-negative duration", cfg.ExecRecordingMaxAge)
+	}
+	return recordings.NewStore(p, recordings.Config{
+		MaxAge:        maxAge,
+		MaxTotalBytes: int64(maxTotalSize),
+	}), nil
+}
+
+// ProvideIngressManager provides the ingress manager
+func ProvideIngressManager(p *paths.Paths, cfg *config.Config, instanceManager instances.Manager) (ingress.Manager, error) {
+	acme, err := ParseACMEConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+
 	// Use config value for internal DNS port, fall back to default (0 = random) if not set
 	internalDNSPort := cfg.InternalDNSPort
 	if internalDNSPort == 0 {
@@ -191,15 +499,25 @@ func ProvideIngressManager(p *paths.Paths, cfg *config.Config, instanceManager i
 		AdminPort:      cfg.CaddyAdminPort,
 		DNSPort:        internalDNSPort,
 		StopOnShutdown: cfg.CaddyStopOnShutdown,
-		ACME: ingress.ACMEConfig{
-			Email:                 cfg.AcmeEmail,
-			DNSProvider:           dnsProvider,
-			CA:                    cfg.AcmeCA,
-			DNSPropagationTimeout: cfg.DnsPropagationTimeout,
-			DNSResolvers:          cfg.DnsResolvers,
-			AllowedDomains:        cfg.TlsAllowedDomains,
-			CloudflareAPIToken:    cfg.CloudflareApiToken,
-		},
+		ACME:           acme,
+	}
+
+	// External DNS reuses the ACME provider credentials
+	externalDNSProvider, err := ingress.ParseDNSProvider(cfg.ExternalDnsProvider)
+	if err != nil {
+		return nil, fmt.Errorf("invalid EXTERNAL_DNS_PROVIDER: %w", err)
+	}
+	ingressConfig.ExternalDNS = ingress.ExternalDNSConfig{
+		Provider: externalDNSProvider,
+		TTL:      cfg.ExternalDnsTTL,
+	}
+	for _, t := range strings.Split(cfg.ExternalDnsTargets, ",") {
+		if t = strings.TrimSpace(t); t != "" {
+			ingressConfig.ExternalDNS.Targets = append(ingressConfig.ExternalDNS.Targets, t)
+		}
+	}
+	if err := ingressConfig.ExternalDNS.Validate(ingressConfig.ACME); err != nil {
+		return nil, fmt.Errorf("invalid external DNS config: %w", err)
 	}
 
 	// Create OTEL logger for Caddy log forwarding (if OTEL is enabled)
@@ -210,18 +528,43 @@ func ProvideIngressManager(p *paths.Paths, cfg *config.Config, instanceManager i
 	}
 
 	// IngressResolver from instances package implements ingress.InstanceResolver
-	resolver := instances.NewIngressResolver(instanceManager)
+	idleDefaults, err := ParseIdleDefaults(cfg)
+	if err != nil {
+		return nil, err
+	}
+	resolver := instances.NewIngressResolver(instanceManager, idleDefaults)
 	return ingress.NewManager(p, ingressConfig, resolver, otelLogger), nil
 }
 
 // ProvideBuildManager provides the build manager
-func ProvideBuildManager(p *paths.Paths, cfg *config.Config, instanceManager instances.Manager, volumeManager volumes.Manager, log *slog.Logger) (builds.Manager, error) {
+func ProvideBuildManager(p *paths.Paths, cfg *config.Config, instanceManager instances.Manager, volumeManager volumes.Manager, networkManager network.Manager, log *slog.Logger) (builds.Manager, error) {
+	var maxArtifactSize datasize.ByteSize
+	if err := maxArtifactSize.UnmarshalText([]byte(cfg.BuildMaxArtifactSize)); err != nil {
+		return nil, fmt.Errorf("failed to parse BUILD_MAX_ARTIFACT_SIZE '%s': %w (expected format like '1GB', '500MB')", cfg.BuildMaxArtifactSize, err)
+	}
+	var egressAllowlist []string
+	for _, host := range strings.Split(cfg.BuildEgressAllowlist, ",") {
+		if host = strings.TrimSpace(host); host != "" {
+			egressAllowlist = append(egressAllowlist, host)
+		}
+	}
+	if err := builds.ParseEgressAllowlist(egressAllowlist); err != nil {
+		return nil, fmt.Errorf("invalid BUILD_EGRESS_ALLOWLIST: %w", err)
+	}
+
 	buildConfig := builds.Config{
 		MaxConcurrentBuilds: cfg.MaxConcurrentSourceBuilds,
 		BuilderImage:        cfg.BuilderImage,
 		RegistryURL:         cfg.RegistryURL,
 		DefaultTimeout:      cfg.BuildTimeout,
 		RegistrySecret:      cfg.JwtSecret, // Use same secret for registry tokens
+		WarmPoolSize:        cfg.BuildWarmPoolSize,
+		WarmPoolMaxUses:     cfg.BuildWarmPoolMaxUses,
+		MaxArtifactBytes:    int64(maxArtifactSize),
+		PushAttestations:    cfg.BuildPushAttestations,
+		RegistryMirrors:     registryProxyUpstreams(cfg),
+		EgressAllowlist:     egressAllowlist,
+		PackageCacheVolume:  cfg.BuildPackageCacheVolume,
 	}
 
 	// Apply defaults if not set
@@ -237,6 +580,9 @@ func ProvideBuildManager(p *paths.Paths, cfg *config.Config, instanceManager ins
 	if buildConfig.DefaultTimeout == 0 {
 		buildConfig.DefaultTimeout = 600
 	}
+	if buildConfig.MaxArtifactBytes == 0 {
+		buildConfig.MaxArtifactBytes = builds.DefaultMaxArtifactBytes
+	}
 
 	// Configure secret provider (use NoOpSecretProvider as fallback to avoid nil panics)
 	var secretProvider builds.SecretProvider
@@ -248,5 +594,38 @@ func ProvideBuildManager(p *paths.Paths, cfg *config.Config, instanceManager ins
 	}
 
 	meter := otel.GetMeterProvider().Meter("hypeman")
-	return builds.NewManager(p, buildConfig, instanceManager, volumeManager, secretProvider, log, meter)
+	return builds.NewManager(p, buildConfig, instanceManager, volumeManager, networkManager, secretProvider, log, meter)
+}
+
+// ParseIdleDefaults returns the server-wide idle standby settings
+func ParseIdleDefaults(cfg *config.Config) (instances.IdleDefaults, error) {
+	standbyAfter, err := time.ParseDuration(cfg.IdleStandbyAfter)
+	if err != nil {
+		return instances.IdleDefaults{}, fmt.Errorf("invalid IDLE_STANDBY_AFTER %q: %w", cfg.IdleStandbyAfter, err)
+	}
+	return instances.IdleDefaults{
+		StandbyAfter:  standbyAfter,
+		WakeOnIngress: cfg.IdleWakeOnIngress,
+	}, nil
+}
+
+// ParseLogRetention returns the instance log rotation and retention policy
+func ParseLogRetention(cfg *config.Config) (instances.LogRetention, error) {
+	var maxSize, maxTotalSize datasize.ByteSize
+	if err := maxSize.UnmarshalText([]byte(cfg.LogMaxSize)); err != nil {
+		return instances.LogRetention{}, fmt.Errorf("invalid LOG_MAX_SIZE %q: %w", cfg.LogMaxSize, err)
+	}
+	if err := maxTotalSize.UnmarshalText([]byte(cfg.LogMaxTotalSize)); err != nil {
+		return instances.LogRetention{}, fmt.Errorf("invalid LOG_MAX_TOTAL_SIZE %q: %w", cfg.LogMaxTotalSize, err)
+	}
+	maxAge, err := time.ParseDuration(cfg.LogMaxAge)
+	if err != nil || maxAge < 0 {
+		return instances.LogRetention{}, fmt.Errorf("invalid LOG_MAX_AGE %q: must be a non-negative duration", cfg.LogMaxAge)
+	}
+	return instances.LogRetention{
+		MaxBytes:      int64(maxSize),
+		MaxFiles:      cfg.LogMaxFiles,
+		MaxAge:        maxAge,
+		MaxTotalBytes: int64(maxTotalSize),
+	}, nil
 }
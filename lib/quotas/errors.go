@@ -0,0 +1,41 @@
+package quotas
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrQuotaExceeded is returned when admitting a resource would exceed a quota
+var ErrQuotaExceeded = errors.New("quota exceeded")
+
+// Exceeded describes one limit a request would exceed
+type Exceeded struct {
+	Scope     Scope
+	Resource  string // One of the Resource* names
+	Limit     int64
+	Used      int64
+	Requested int64
+}
+
+// ExceededError lists the limits a request would exceed. It matches
+// ErrQuotaExceeded with errors.Is.
+type ExceededError struct {
+	Exceeded []Exceeded
+}
+
+func (e *ExceededError) Error() string {
+	parts := make([]string, 0, len(e.Exceeded))
+	for _, x := range e.Exceeded {
+		parts = append(parts, x.String())
+	}
+	return "quota exceeded: " + strings.Join(parts, "; ")
+}
+
+func (e *ExceededError) Unwrap() error {
+	return ErrQuotaExceeded
+}
+
+func (x Exceeded) String() string {
+	return fmt.Sprintf("%s %s: %d used + %d requested > limit %d", x.Scope, x.Resource, x.Used, x.Requested, x.Limit)
+}
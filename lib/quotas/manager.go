@@ -0,0 +1,212 @@
+package quotas
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/kernel/hypeman/lib/builds"
+	"github.com/kernel/hypeman/lib/instances"
+	"github.com/kernel/hypeman/lib/volumes"
+)
+
+// keyCreatorPrefix is how resources created with an API key record their
+// creator (see middleware.CreatorFromContext)
+const keyCreatorPrefix = "api_key:"
+
+// Principal is who a resource is admitted for
+type Principal struct {
+	Tenant  string // Tenant the resource is labelled with (empty = none)
+	Creator string // Principal creating it, as recorded on resources (empty = unknown)
+}
+
+// applies reports whether a quota scope covers the principal's resources
+func (s Scope) applies(p Principal) bool {
+	switch s.Kind {
+	case ScopeTenant:
+		return p.Tenant == s.Name
+	case ScopeKey:
+		return p.Creator == keyCreatorPrefix+s.Name
+	}
+	return false
+}
+
+// Instances is the part of the instance manager quotas count
+type Instances interface {
+	ListInstances(ctx context.Context) ([]instances.Instance, error)
+}
+
+// Volumes is the part of the volume manager quotas count
+type Volumes interface {
+	ListVolumes(ctx context.Context) ([]volumes.Volume, error)
+}
+
+// Builds is the part of the build manager quotas count
+type Builds interface {
+	ListBuilds(ctx context.Context) ([]*builds.Build, error)
+}
+
+// Manager admits resources against the configured quotas
+type Manager interface {
+	// Admit checks that a principal may create resources, returning an
+	// *ExceededError if that would exceed a quota. The resources are
+	// reserved until release is called, which must happen once they exist
+	// or their creation failed, so concurrent requests can't both fit.
+	Admit(ctx context.Context, p Principal, req Resources) (release func(), err error)
+	// List returns every quota with its scope's usage
+	List(ctx context.Context) ([]Status, error)
+	// SetQuotas replaces the quotas, e.g. on a config reload
+	SetQuotas(quotas []Quota)
+}
+
+type manager struct {
+	instances Instances
+	volumes   Volumes
+	builds    Builds
+	now       func() time.Time
+
+	mu       sync.Mutex // Serializes admission
+	quotas   []Quota
+	reserved map[Scope]Resources // Admitted but not yet created
+}
+
+// NewManager creates a quota manager enforcing quotas
+func NewManager(quotas []Quota, instanceManager Instances, volumeManager Volumes, buildManager Builds) Manager {
+	return &manager{
+		instances: instanceManager,
+		volumes:   volumeManager,
+		builds:    buildManager,
+		now:       time.Now,
+		quotas:    quotas,
+		reserved:  make(map[Scope]Resources),
+	}
+}
+
+func (m *manager) SetQuotas(quotas []Quota) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.quotas = quotas
+}
+
+func (m *manager) Admit(ctx context.Context, p Principal, req Resources) (func(), error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var applied []Quota
+	for _, q := range m.quotas {
+		if q.Scope.applies(p) {
+			applied = append(applied, q)
+		}
+	}
+	if len(applied) == 0 {
+		return func() {}, nil
+	}
+
+	usage, err := m.usage(ctx, applied)
+	if err != nil {
+		return nil, err
+	}
+
+	var exceeded []Exceeded
+	for _, q := range applied {
+		used := usage[q.Scope].add(m.reserved[q.Scope]).amounts()
+		requested := req.amounts()
+		for i, limit := range q.Limits.amounts() {
+			if limit.value > 0 && requested[i].value > 0 && used[i].value+requested[i].value > limit.value {
+				exceeded = append(exceeded, Exceeded{
+					Scope:     q.Scope,
+					Resource:  limit.name,
+					Limit:     limit.value,
+					Used:      used[i].value,
+					Requested: requested[i].value,
+				})
+			}
+		}
+	}
+	if len(exceeded) > 0 {
+		return nil, &ExceededError{Exceeded: exceeded}
+	}
+
+	for _, q := range applied {
+		m.reserved[q.Scope] = m.reserved[q.Scope].add(req)
+	}
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			m.mu.Lock()
+			defer m.mu.Unlock()
+			for _, q := range applied {
+				m.reserved[q.Scope] = m.reserved[q.Scope].sub(req)
+				if m.reserved[q.Scope] == (Resources{}) {
+					delete(m.reserved, q.Scope)
+				}
+			}
+		})
+	}, nil
+}
+
+func (m *manager) List(ctx context.Context) ([]Status, error) {
+	m.mu.Lock()
+	quotas := m.quotas
+	m.mu.Unlock()
+
+	usage, err := m.usage(ctx, quotas)
+	if err != nil {
+		return nil, err
+	}
+	statuses := make([]Status, 0, len(quotas))
+	for _, q := range quotas {
+		statuses = append(statuses, Status{Quota: q, Usage: usage[q.Scope]})
+	}
+	return statuses, nil
+}
+
+// usage counts the resources of each quota's scope
+func (m *manager) usage(ctx context.Context, quotas []Quota) (map[Scope]Resources, error) {
+	usage := make(map[Scope]Resources, len(quotas))
+	if len(quotas) == 0 {
+		return usage, nil
+	}
+	count := func(p Principal, r Resources) {
+		for _, q := range quotas {
+			if q.Scope.applies(p) {
+				usage[q.Scope] = usage[q.Scope].add(r)
+			}
+		}
+	}
+
+	insts, err := m.instances.ListInstances(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("list instances: %w", err)
+	}
+	for _, inst := range insts {
+		count(Principal{Tenant: inst.Tenant, Creator: inst.CreatedBy}, Resources{
+			Instances: 1,
+			Vcpus:     inst.Vcpus,
+			Memory:    inst.Size + inst.HotplugSize,
+		})
+	}
+
+	vols, err := m.volumes.ListVolumes(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("list volumes: %w", err)
+	}
+	for _, vol := range vols {
+		count(Principal{Tenant: vol.Tenant, Creator: vol.CreatedBy}, Resources{
+			VolumeSize: int64(vol.SizeGb) * 1024 * 1024 * 1024,
+		})
+	}
+
+	blds, err := m.builds.ListBuilds(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("list builds: %w", err)
+	}
+	hourAgo := m.now().Add(-time.Hour)
+	for _, b := range blds {
+		if b.CreatedAt.After(hourAgo) {
+			count(Principal{Tenant: b.Tenant, Creator: b.CreatedBy}, Resources{BuildsPerHour: 1})
+		}
+	}
+	return usage, nil
+}
@@ -0,0 +1,171 @@
+// Package quotas limits what each tenant and API key may create: instances,
+// vCPUs, memory, volume space and builds per hour. Quotas are checked when
+// a resource is admitted; usage is counted from the resources that exist.
+package quotas
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/c2h5oh/datasize"
+)
+
+// Scope kinds
+const (
+	ScopeTenant = "tenant" // Resources labelled with the tenant
+	ScopeKey    = "key"    // Resources created with the API key of a subject
+)
+
+// Resource names, as used in QUOTAS and error details
+const (
+	ResourceInstances     = "instances"
+	ResourceVcpus         = "vcpus"
+	ResourceMemory        = "memory"
+	ResourceVolumeSize    = "volume_size"
+	ResourceBuildsPerHour = "builds_per_hour"
+)
+
+// Scope is who a quota applies to
+type Scope struct {
+	Kind string // ScopeTenant or ScopeKey
+	Name string // Tenant name, or API key subject
+}
+
+func (s Scope) String() string {
+	return s.Kind + ":" + s.Name
+}
+
+// Resources is an amount of each limited resource. As limits, 0 means
+// unlimited.
+type Resources struct {
+	Instances     int
+	Vcpus         int
+	Memory        int64 // Bytes of guest memory (size + hotplug size)
+	VolumeSize    int64 // Bytes of volume capacity
+	BuildsPerHour int   // Builds created in the last hour
+}
+
+// Quota limits a scope's resources
+type Quota struct {
+	Scope  Scope
+	Limits Resources
+}
+
+// Status is a quota with the scope's current usage
+type Status struct {
+	Quota
+	Usage Resources
+}
+
+// Parse parses quotas from a semicolon-separated list of
+// "<tenant|key>:<name>:<resource>=<limit>,..." entries, e.g.
+// "tenant:team-a:instances=10,vcpus=32,memory=64GB;key:ci:builds_per_hour=20".
+// Memory and volume_size take sizes like 64GB; the rest are counts.
+func Parse(s string) ([]Quota, error) {
+	var quotas []Quota
+	seen := make(map[Scope]bool)
+	for _, entry := range strings.Split(s, ";") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		parts := strings.SplitN(entry, ":", 3)
+		if len(parts) != 3 || parts[1] == "" {
+			return nil, fmt.Errorf("invalid quota %q: expected <tenant|key>:<name>:<resource>=<limit>,...", entry)
+		}
+		scope := Scope{Kind: parts[0], Name: parts[1]}
+		if scope.Kind != ScopeTenant && scope.Kind != ScopeKey {
+			return nil, fmt.Errorf("invalid quota %q: scope must be tenant or key", entry)
+		}
+		if seen[scope] {
+			return nil, fmt.Errorf("duplicate quota for %s", scope)
+		}
+		seen[scope] = true
+
+		q := Quota{Scope: scope}
+		for _, limit := range strings.Split(parts[2], ",") {
+			name, value, ok := strings.Cut(strings.TrimSpace(limit), "=")
+			if !ok {
+				return nil, fmt.Errorf("quota %s: invalid limit %q: expected <resource>=<limit>", scope, limit)
+			}
+			if err := q.Limits.set(name, value); err != nil {
+				return nil, fmt.Errorf("quota %s: %w", scope, err)
+			}
+		}
+		quotas = append(quotas, q)
+	}
+	return quotas, nil
+}
+
+// set parses one limit
+func (r *Resources) set(name, value string) error {
+	switch name {
+	case ResourceMemory, ResourceVolumeSize:
+		var size datasize.ByteSize
+		if err := size.UnmarshalText([]byte(value)); err != nil {
+			return fmt.Errorf("invalid %s %q: %v", name, value, err)
+		}
+		if name == ResourceMemory {
+			r.Memory = int64(size)
+		} else {
+			r.VolumeSize = int64(size)
+		}
+		return nil
+	}
+
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 0 {
+		return fmt.Errorf("invalid %s %q: expected a non-negative count", name, value)
+	}
+	switch name {
+	case ResourceInstances:
+		r.Instances = n
+	case ResourceVcpus:
+		r.Vcpus = n
+	case ResourceBuildsPerHour:
+		r.BuildsPerHour = n
+	default:
+		return fmt.Errorf("unknown resource %q", name)
+	}
+	return nil
+}
+
+// add returns the sum of two amounts
+func (r Resources) add(o Resources) Resources {
+	return Resources{
+		Instances:     r.Instances + o.Instances,
+		Vcpus:         r.Vcpus + o.Vcpus,
+		Memory:        r.Memory + o.Memory,
+		VolumeSize:    r.VolumeSize + o.VolumeSize,
+		BuildsPerHour: r.BuildsPerHour + o.BuildsPerHour,
+	}
+}
+
+// sub returns r minus o
+func (r Resources) sub(o Resources) Resources {
+	return r.add(Resources{
+		Instances:     -o.Instances,
+		Vcpus:         -o.Vcpus,
+		Memory:        -o.Memory,
+		VolumeSize:    -o.VolumeSize,
+		BuildsPerHour: -o.BuildsPerHour,
+	})
+}
+
+// amounts lists each resource's amount, in a fixed order
+func (r Resources) amounts() []struct {
+	name  string
+	value int64
+} {
+	return []struct {
+		name  string
+		value int64
+	}{
+		{ResourceInstances, int64(r.Instances)},
+		{ResourceVcpus, int64(r.Vcpus)},
+		{ResourceMemory, r.Memory},
+		{ResourceVolumeSize, r.VolumeSize},
+		{ResourceBuildsPerHour, int64(r.BuildsPerHour)},
+	}
+}
@@ -0,0 +1,164 @@
+package quotas
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/kernel/hypeman/lib/builds"
+	"github.com/kernel/hypeman/lib/instances"
+	"github.com/kernel/hypeman/lib/volumes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const gb = 1024 * 1024 * 1024
+
+type fakeResources struct {
+	mu        sync.Mutex
+	instances []instances.Instance
+	volumes   []volumes.Volume
+	builds    []*builds.Build
+}
+
+func (f *fakeResources) ListInstances(ctx context.Context) ([]instances.Instance, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]instances.Instance(nil), f.instances...), nil
+}
+
+func (f *fakeResources) ListVolumes(ctx context.Context) ([]volumes.Volume, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]volumes.Volume(nil), f.volumes...), nil
+}
+
+func (f *fakeResources) ListBuilds(ctx context.Context) ([]*builds.Build, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]*builds.Build(nil), f.builds...), nil
+}
+
+func (f *fakeResources) addInstance(tenant, createdBy string, vcpus int, size int64) {
+	inst := instances.Instance{}
+	inst.Tenant = tenant
+	inst.CreatedBy = createdBy
+	inst.Vcpus = vcpus
+	inst.Size = size
+	f.instances = append(f.instances, inst)
+}
+
+func setupTestManager(t *testing.T, spec string) (*manager, *fakeResources) {
+	t.Helper()
+	quotas, err := Parse(spec)
+	require.NoError(t, err)
+	f := &fakeResources{}
+	return NewManager(quotas, f, f, f).(*manager), f
+}
+
+func TestParse(t *testing.T) {
+	quotas, err := Parse("tenant:team-a:instances=10,vcpus=32,memory=64GB,volume_size=500GB; key:ci:builds_per_hour=5")
+	require.NoError(t, err)
+	assert.Equal(t, []Quota{
+		{Scope: Scope{Kind: ScopeTenant, Name: "team-a"}, Limits: Resources{Instances: 10, Vcpus: 32, Memory: 64 * gb, VolumeSize: 500 * gb}},
+		{Scope: Scope{Kind: ScopeKey, Name: "ci"}, Limits: Resources{BuildsPerHour: 5}},
+	}, quotas)
+
+	quotas, err = Parse("")
+	require.NoError(t, err)
+	assert.Empty(t, quotas)
+
+	for _, spec := range []string{
+		"team-a:instances=10",
+		"user:team-a:instances=10",
+		"tenant::instances=10",
+		"tenant:team-a:instances",
+		"tenant:team-a:instances=-1",
+		"tenant:team-a:memory=lots",
+		"tenant:team-a:disks=2",
+		"tenant:team-a:instances=1;tenant:team-a:vcpus=2",
+	} {
+		_, err := Parse(spec)
+		assert.Error(t, err, spec)
+	}
+}
+
+func TestAdmit(t *testing.T) {
+	m, f := setupTestManager(t, "tenant:team-a:instances=2,vcpus=4,memory=8GB")
+	ctx := context.Background()
+	teamA := Principal{Tenant: "team-a"}
+	f.addInstance("team-a", "", 2, 4*gb)
+	f.addInstance("team-b", "", 8, 32*gb) // Other tenants don't count
+
+	release, err := m.Admit(ctx, teamA, Resources{Instances: 1, Vcpus: 2, Memory: 4 * gb})
+	require.NoError(t, err)
+
+	// The reservation counts until released
+	_, err = m.Admit(ctx, teamA, Resources{Instances: 1, Vcpus: 1, Memory: gb})
+	var exceeded *ExceededError
+	require.ErrorAs(t, err, &exceeded)
+	assert.True(t, errors.Is(err, ErrQuotaExceeded))
+	assert.Equal(t, []Exceeded{
+		{Scope: Scope{Kind: ScopeTenant, Name: "team-a"}, Resource: ResourceInstances, Limit: 2, Used: 2, Requested: 1},
+		{Scope: Scope{Kind: ScopeTenant, Name: "team-a"}, Resource: ResourceVcpus, Limit: 4, Used: 4, Requested: 1},
+		{Scope: Scope{Kind: ScopeTenant, Name: "team-a"}, Resource: ResourceMemory, Limit: 8 * gb, Used: 8 * gb, Requested: gb},
+	}, exceeded.Exceeded)
+
+	release()
+	release() // Releasing twice is harmless
+	assert.Empty(t, m.reserved)
+	_, err = m.Admit(ctx, teamA, Resources{Instances: 1, Vcpus: 1, Memory: gb})
+	assert.NoError(t, err)
+
+	// Unquota'd principals are always admitted
+	_, err = m.Admit(ctx, Principal{Tenant: "team-b"}, Resources{Instances: 100})
+	assert.NoError(t, err)
+}
+
+func TestAdmitKeyAndBuilds(t *testing.T) {
+	m, f := setupTestManager(t, "key:ci:builds_per_hour=2,volume_size=10GB")
+	ctx := context.Background()
+	now := time.Now()
+	m.now = func() time.Time { return now }
+	ci := Principal{Creator: "api_key:ci"}
+
+	f.builds = []*builds.Build{
+		{CreatedBy: "api_key:ci", CreatedAt: now.Add(-10 * time.Minute)},
+		{CreatedBy: "api_key:ci", CreatedAt: now.Add(-2 * time.Hour)}, // Outside the hour
+		{CreatedBy: "api_key:other", CreatedAt: now},
+	}
+	f.volumes = []volumes.Volume{{CreatedBy: "api_key:ci", SizeGb: 8}}
+
+	_, err := m.Admit(ctx, ci, Resources{BuildsPerHour: 1})
+	require.NoError(t, err)
+	_, err = m.Admit(ctx, ci, Resources{BuildsPerHour: 1})
+	assert.ErrorIs(t, err, ErrQuotaExceeded)
+
+	_, err = m.Admit(ctx, ci, Resources{VolumeSize: 4 * gb})
+	assert.ErrorIs(t, err, ErrQuotaExceeded)
+	_, err = m.Admit(ctx, ci, Resources{VolumeSize: 2 * gb})
+	assert.NoError(t, err)
+
+	// A key quota doesn't apply to subjects with other auth methods
+	_, err = m.Admit(ctx, Principal{Creator: "jwt:ci"}, Resources{BuildsPerHour: 5})
+	assert.NoError(t, err)
+}
+
+func TestList(t *testing.T) {
+	m, f := setupTestManager(t, "tenant:team-a:instances=3;key:ci:vcpus=8")
+	f.addInstance("team-a", "api_key:ci", 2, gb)
+	f.addInstance("team-a", "jwt:alice", 1, gb)
+
+	statuses, err := m.List(context.Background())
+	require.NoError(t, err)
+	require.Len(t, statuses, 2)
+	assert.Equal(t, Resources{Instances: 2, Vcpus: 3, Memory: 2 * gb}, statuses[0].Usage)
+	assert.Equal(t, Resources{Instances: 1, Vcpus: 2, Memory: gb}, statuses[1].Usage)
+
+	m.SetQuotas(nil)
+	statuses, err = m.List(context.Background())
+	require.NoError(t, err)
+	assert.Empty(t, statuses)
+}
@@ -0,0 +1,146 @@
+package recordings
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// castExt is the extension of asciicast files
+const castExt = ".cast"
+
+// Recordings declare the common default terminal size; sessions that
+// report theirs record it as a resize event
+const (
+	defaultWidth  = 80
+	defaultHeight = 24
+)
+
+// asciicast v2 event codes
+const (
+	eventOutput = "o"
+	eventInput  = "i"
+	eventResize = "r"
+)
+
+// header is the first line of an asciicast v2 file. Players ignore the
+// keys they don't know; the hypeman_ ones describe the session.
+type header struct {
+	Version    int      `json:"version"`
+	Width      int      `json:"width"`
+	Height     int      `json:"height"`
+	Timestamp  int64    `json:"timestamp"`
+	Command    string   `json:"command,omitempty"`
+	Title      string   `json:"title,omitempty"`
+	InstanceID string   `json:"hypeman_instance_id"`
+	Tenant     string   `json:"hypeman_tenant,omitempty"`
+	Subject    string   `json:"hypeman_subject"`
+	Argv       []string `json:"hypeman_command"`
+	TTY        bool     `json:"hypeman_tty"`
+}
+
+// Session records one exec session's streams as asciicast events
+type Session struct {
+	ID string
+
+	mu      sync.Mutex
+	f       *os.File
+	start   time.Time
+	pending map[string][]byte // Incomplete UTF-8 sequences held back per stream
+	err     error             // First write error; later events are dropped
+}
+
+func newSession(id string, f *os.File, start time.Time) *Session {
+	return &Session{ID: id, f: f, start: start, pending: make(map[string][]byte)}
+}
+
+// Input returns a writer recording what's sent to the session
+func (s *Session) Input() io.Writer {
+	return stream{s: s, code: eventInput}
+}
+
+// Output returns a writer recording what the session prints. Stdout and
+// stderr share it, as they share the terminal.
+func (s *Session) Output() io.Writer {
+	return stream{s: s, code: eventOutput}
+}
+
+// Resize records the session's terminal changing size
+func (s *Session) Resize(cols, rows int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.err != nil {
+		return
+	}
+	s.writeEvent(eventResize, []byte(fmt.Sprintf("%dx%d", cols, rows)))
+}
+
+// Close records what's left of the streams and closes the file. It returns
+// the first error met while recording.
+func (s *Session) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, code := range []string{eventInput, eventOutput} {
+		if rest := s.pending[code]; len(rest) > 0 {
+			s.writeEvent(code, rest)
+		}
+	}
+	if err := s.f.Close(); err != nil && s.err == nil {
+		s.err = err
+	}
+	return s.err
+}
+
+// record appends an event for data, holding back a trailing partial UTF-8
+// sequence until the rest of it arrives
+func (s *Session) record(code string, data []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.err != nil {
+		return
+	}
+	data = append(s.pending[code], data...)
+	cut := len(data)
+	for i := len(data) - 1; i >= 0 && i >= len(data)-utf8.UTFMax; i-- {
+		if utf8.RuneStart(data[i]) {
+			if !utf8.FullRune(data[i:]) {
+				cut = i
+			}
+			break
+		}
+	}
+	s.pending[code] = append([]byte(nil), data[cut:]...)
+	if cut > 0 {
+		s.writeEvent(code, data[:cut])
+	}
+}
+
+// writeEvent appends one [time, code, data] line. Invalid UTF-8 is
+// replaced, as JSON strings can't carry it.
+func (s *Session) writeEvent(code string, data []byte) {
+	elapsed := math.Round(time.Since(s.start).Seconds()*1e6) / 1e6
+	line, err := json.Marshal([]any{elapsed, code, string(data)})
+	if err == nil {
+		_, err = s.f.Write(append(line, '\n'))
+	}
+	if err != nil {
+		s.err = err
+	}
+}
+
+// stream is one direction of a session. Writes never fail, so a recording
+// problem can't break the session it records.
+type stream struct {
+	s    *Session
+	code string
+}
+
+func (w stream) Write(p []byte) (int, error) {
+	w.s.record(w.code, p)
+	return len(p), nil
+}
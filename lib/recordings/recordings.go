@@ -0,0 +1,274 @@
+// Package recordings keeps recordings of exec sessions for compliance
+// audits: what was typed into a session and what it printed, with timing,
+// in the asciicast v2 format asciinema plays back.
+package recordings
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/kernel/hypeman/lib/paths"
+	"github.com/nrednav/cuid2"
+)
+
+var ErrNotFound = errors.New("recording not found")
+
+// Recording describes a recorded exec session
+type Recording struct {
+	ID         string
+	InstanceID string
+	Tenant     string // The instance's tenant
+	Subject    string // Who opened the session
+	Command    []string
+	TTY        bool
+	StartedAt  time.Time
+	Size       int64 // Bytes on disk
+}
+
+// Config configures a Store's retention
+type Config struct {
+	MaxAge        time.Duration // Remove recordings older than this (0 = no limit)
+	MaxTotalBytes int64         // Cap on all recordings together, oldest removed first (0 = no limit)
+}
+
+// PruneResult summarizes a Prune run
+type PruneResult struct {
+	Removed        int
+	ReclaimedBytes int64
+}
+
+// Store writes recordings to {dataDir}/recordings/<instance-id>/<id>.cast
+type Store struct {
+	paths *paths.Paths
+	cfg   Config
+	now   func() time.Time
+}
+
+// NewStore creates a recording store
+func NewStore(p *paths.Paths, cfg Config) *Store {
+	return &Store{paths: p, cfg: cfg, now: time.Now}
+}
+
+// Start begins recording a session. The caller tees the session's streams
+// into the returned Session and closes it when the session ends.
+func (s *Store) Start(instanceID, tenant, subject string, command []string, tty bool) (*Session, error) {
+	id := cuid2.Generate()
+	path := s.paths.ExecRecording(instanceID, id)
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, fmt.Errorf("create recordings directory: %w", err)
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("create recording: %w", err)
+	}
+
+	start := s.now()
+	h := header{
+		Version:    2,
+		Width:      defaultWidth,
+		Height:     defaultHeight,
+		Timestamp:  start.Unix(),
+		Command:    strings.Join(command, " "),
+		Title:      fmt.Sprintf("exec into %s by %s", instanceID, subject),
+		InstanceID: instanceID,
+		Tenant:     tenant,
+		Subject:    subject,
+		Argv:       command,
+		TTY:        tty,
+	}
+	line, err := json.Marshal(h)
+	if err == nil {
+		_, err = f.Write(append(line, '\n'))
+	}
+	if err != nil {
+		f.Close()
+		os.Remove(path)
+		return nil, fmt.Errorf("write recording header: %w", err)
+	}
+	return newSession(id, f, start), nil
+}
+
+// List returns the recordings of an instance, or of all instances
+// (including deleted ones) if instanceID is empty, oldest first
+func (s *Store) List(instanceID string) ([]Recording, error) {
+	instanceIDs := []string{instanceID}
+	if instanceID == "" {
+		entries, err := os.ReadDir(s.paths.ExecRecordingsRoot())
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return nil, fmt.Errorf("read recordings directory: %w", err)
+		}
+		instanceIDs = instanceIDs[:0]
+		for _, e := range entries {
+			if e.IsDir() {
+				instanceIDs = append(instanceIDs, e.Name())
+			}
+		}
+	}
+
+	recs := []Recording{}
+	for _, instanceID := range instanceIDs {
+		entries, err := os.ReadDir(s.paths.ExecRecordingsDir(instanceID))
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("read recordings directory: %w", err)
+		}
+		for _, e := range entries {
+			id, ok := strings.CutSuffix(e.Name(), castExt)
+			if !ok || e.IsDir() {
+				continue
+			}
+			rec, err := s.load(instanceID, id)
+			if err != nil {
+				// Unreadable leftovers, e.g. a header cut short by a crash
+				continue
+			}
+			recs = append(recs, *rec)
+		}
+	}
+	sort.SliceStable(recs, func(i, j int) bool { return recs[i].StartedAt.Before(recs[j].StartedAt) })
+	return recs, nil
+}
+
+// Open returns a recording and its asciicast file, which the caller closes
+func (s *Store) Open(id string) (*Recording, *os.File, error) {
+	if !validID(id) {
+		return nil, nil, ErrNotFound
+	}
+	matches, err := filepath.Glob(s.paths.ExecRecording("*", id))
+	if err != nil || len(matches) == 0 {
+		return nil, nil, ErrNotFound
+	}
+	instanceID := filepath.Base(filepath.Dir(matches[0]))
+	rec, err := s.load(instanceID, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	f, err := os.Open(matches[0])
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("open recording: %w", err)
+	}
+	return rec, f, nil
+}
+
+// Prune removes recordings beyond the store's retention: those older than
+// MaxAge, then the oldest until the rest fit in MaxTotalBytes. Recordings of
+// deleted instances are kept like any other.
+func (s *Store) Prune() (*PruneResult, error) {
+	res := &PruneResult{}
+	if s.cfg.MaxAge <= 0 && s.cfg.MaxTotalBytes <= 0 {
+		return res, nil
+	}
+
+	type file struct {
+		path    string
+		modTime time.Time
+		size    int64
+	}
+	var files []file
+	var total int64
+	root := s.paths.ExecRecordingsRoot()
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		if d.IsDir() || !strings.HasSuffix(path, castExt) {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return nil
+		}
+		files = append(files, file{path: path, modTime: info.ModTime(), size: info.Size()})
+		total += info.Size()
+		return nil
+	})
+	if err != nil {
+		return res, fmt.Errorf("walk recordings: %w", err)
+	}
+
+	// A recording's modification time is when it last saw output, so a
+	// session still in progress is never the oldest
+	sort.Slice(files, func(i, j int) bool { return files[i].modTime.Before(files[j].modTime) })
+	cutoff := s.now().Add(-s.cfg.MaxAge)
+	var errs []error
+	for _, f := range files {
+		expired := s.cfg.MaxAge > 0 && f.modTime.Before(cutoff)
+		overCap := s.cfg.MaxTotalBytes > 0 && total > s.cfg.MaxTotalBytes
+		if !expired && !overCap {
+			break
+		}
+		if err := os.Remove(f.path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			errs = append(errs, err)
+			continue
+		}
+		total -= f.size
+		res.Removed++
+		res.ReclaimedBytes += f.size
+	}
+	return res, errors.Join(errs...)
+}
+
+// load reads a recording's header
+func (s *Store) load(instanceID, id string) (*Recording, error) {
+	f, err := os.Open(s.paths.ExecRecording(instanceID, id))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("open recording: %w", err)
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return nil, fmt.Errorf("stat recording: %w", err)
+	}
+	line, err := bufio.NewReader(f).ReadBytes('\n')
+	if err != nil {
+		return nil, fmt.Errorf("read recording header: %w", err)
+	}
+	var h header
+	if err := json.Unmarshal(line, &h); err != nil {
+		return nil, fmt.Errorf("parse recording header: %w", err)
+	}
+	return &Recording{
+		ID:         id,
+		InstanceID: instanceID,
+		Tenant:     h.Tenant,
+		Subject:    h.Subject,
+		Command:    h.Argv,
+		TTY:        h.TTY,
+		StartedAt:  time.Unix(h.Timestamp, 0),
+		Size:       info.Size(),
+	}, nil
+}
+
+// validID reports whether id can name a recording file, so IDs from
+// requests can't reach outside the recordings directory or act as globs
+func validID(id string) bool {
+	if id == "" {
+		return false
+	}
+	for _, c := range id {
+		if !(c >= 'a' && c <= 'z' || c >= '0' && c <= '9') {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,128 @@
+package recordings
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/kernel/hypeman/lib/paths"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSessionRecording(t *testing.T) {
+	store := NewStore(paths.New(t.TempDir()), Config{})
+
+	sess, err := store.Start("inst-1", "acme", "alice", []string{"/bin/sh", "-l"}, true)
+	require.NoError(t, err)
+	sess.Resize(120, 40)
+	io.WriteString(sess.Input(), "ls\r")
+	// "é" split across writes is recorded whole
+	sess.Output().Write([]byte{'a', 0xc3})
+	sess.Output().Write([]byte{0xa9, '\r', '\n'})
+	require.NoError(t, sess.Close())
+
+	recs, err := store.List("inst-1")
+	require.NoError(t, err)
+	require.Len(t, recs, 1)
+	rec := recs[0]
+	assert.Equal(t, sess.ID, rec.ID)
+	assert.Equal(t, "inst-1", rec.InstanceID)
+	assert.Equal(t, "acme", rec.Tenant)
+	assert.Equal(t, "alice", rec.Subject)
+	assert.Equal(t, []string{"/bin/sh", "-l"}, rec.Command)
+	assert.True(t, rec.TTY)
+	assert.Positive(t, rec.Size)
+
+	_, f, err := store.Open(rec.ID)
+	require.NoError(t, err)
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+
+	require.True(t, scanner.Scan())
+	var h header
+	require.NoError(t, json.Unmarshal(scanner.Bytes(), &h))
+	assert.Equal(t, 2, h.Version)
+	assert.Equal(t, "/bin/sh -l", h.Command)
+
+	var events [][]any
+	for scanner.Scan() {
+		var ev []any
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &ev))
+		events = append(events, ev)
+	}
+	require.Len(t, events, 4)
+	assert.Equal(t, []any{"r", "120x40"}, events[0][1:])
+	assert.Equal(t, []any{"i", "ls\r"}, events[1][1:])
+	assert.Equal(t, []any{"o", "a"}, events[2][1:])
+	assert.Equal(t, []any{"o", "é\r\n"}, events[3][1:])
+}
+
+func TestListAll(t *testing.T) {
+	store := NewStore(paths.New(t.TempDir()), Config{})
+	recs, err := store.List("")
+	require.NoError(t, err)
+	assert.Empty(t, recs)
+
+	for _, instanceID := range []string{"inst-1", "inst-2"} {
+		sess, err := store.Start(instanceID, "", "alice", []string{"sh"}, false)
+		require.NoError(t, err)
+		require.NoError(t, sess.Close())
+	}
+	recs, err = store.List("")
+	require.NoError(t, err)
+	assert.Len(t, recs, 2)
+	recs, err = store.List("inst-2")
+	require.NoError(t, err)
+	require.Len(t, recs, 1)
+	assert.Equal(t, "inst-2", recs[0].InstanceID)
+}
+
+func TestOpenNotFound(t *testing.T) {
+	store := NewStore(paths.New(t.TempDir()), Config{})
+	for _, id := range []string{"missing", "../inst-2/x", "*", ""} {
+		_, _, err := store.Open(id)
+		assert.ErrorIs(t, err, ErrNotFound, id)
+	}
+}
+
+func TestPrune(t *testing.T) {
+	p := paths.New(t.TempDir())
+	now := time.Now()
+	store := NewStore(p, Config{MaxAge: 24 * time.Hour, MaxTotalBytes: 1 << 20})
+
+	record := func(instanceID string, age time.Duration, size int) string {
+		sess, err := store.Start(instanceID, "", "alice", []string{"sh"}, false)
+		require.NoError(t, err)
+		sess.Output().Write(bytes.Repeat([]byte("x"), size))
+		require.NoError(t, sess.Close())
+		id := sess.ID
+		mtime := now.Add(-age)
+		require.NoError(t, os.Chtimes(p.ExecRecording(instanceID, id), mtime, mtime))
+		return id
+	}
+	expired := record("inst-1", 48*time.Hour, 10)
+	oldest := record("inst-2", 3*time.Hour, 600<<10)
+	newest := record("inst-2", time.Hour, 600<<10)
+	kept := record("inst-1", 2*time.Hour, 10)
+
+	res, err := store.Prune()
+	require.NoError(t, err)
+	assert.Equal(t, 2, res.Removed)
+
+	assert.NoFileExists(t, p.ExecRecording("inst-1", expired))
+	assert.NoFileExists(t, p.ExecRecording("inst-2", oldest))
+	assert.FileExists(t, p.ExecRecording("inst-2", newest))
+	assert.FileExists(t, p.ExecRecording("inst-1", kept))
+}
+
+func TestPruneUnlimited(t *testing.T) {
+	store := NewStore(paths.New(t.TempDir()), Config{})
+	res, err := store.Prune()
+	require.NoError(t, err)
+	assert.Zero(t, res.Removed)
+}
@@ -2,12 +2,19 @@ package registry
 
 import (
 	"context"
+	"crypto/rand"
 	"crypto/sha256"
 	"encoding/hex"
+	"errors"
 	"fmt"
+	"hash"
 	"io"
 	"os"
+	"path/filepath"
+	"regexp"
 	"strings"
+	"sync"
+	"time"
 
 	v1 "github.com/google/go-containerregistry/pkg/v1"
 	"github.com/kernel/hypeman/lib/paths"
@@ -27,9 +34,47 @@ func (e notFoundError) Is(target error) bool {
 // ErrNotFound is returned when a blob is not found.
 var ErrNotFound = notFoundError{}
 
+// ErrUploadUnknown is returned for upload sessions that don't exist, or
+// expired.
+var ErrUploadUnknown = errors.New("blob upload unknown")
+
+// ErrDigestMismatch is returned when an uploaded blob doesn't match the
+// digest it was committed as.
+var ErrDigestMismatch = errors.New("digest mismatch")
+
+// UploadSessionTTL is how long an upload session may go without receiving
+// data before it's removed.
+const UploadSessionTTL = 24 * time.Hour
+
+// uploadIDPattern matches the IDs StartUpload generates
+var uploadIDPattern = regexp.MustCompile(`^[0-9a-f]{32}$`)
+
+// UploadRangeError is returned when a chunk doesn't start where the data an
+// upload session received so far ends.
+type UploadRangeError struct {
+	Size int64 // Bytes received so far
+}
+
+func (e *UploadRangeError) Error() string {
+	return fmt.Sprintf("chunk must start at offset %d", e.Size)
+}
+
 // BlobStore implements blob storage on the filesystem.
 type BlobStore struct {
 	paths *paths.Paths
+
+	mu      sync.Mutex
+	uploads map[string]*uploadSession
+}
+
+// uploadSession is a blob upload in progress. Its data is on disk, and its
+// digest is computed as chunks arrive.
+type uploadSession struct {
+	mu        sync.Mutex
+	size      int64
+	hash      hash.Hash
+	recovered bool // size and hash match the data on disk
+	ended     bool
 }
 
 // NewBlobStore creates a new filesystem-backed blob store.
@@ -38,7 +83,10 @@ func NewBlobStore(p *paths.Paths) (*BlobStore, error) {
 	if err := os.MkdirAll(blobDir, 0755); err != nil {
 		return nil, fmt.Errorf("create blob directory: %w", err)
 	}
-	return &BlobStore{paths: p}, nil
+	if err := os.MkdirAll(p.RegistryUploadsDir(), 0755); err != nil {
+		return nil, fmt.Errorf("create uploads directory: %w", err)
+	}
+	return &BlobStore{paths: p, uploads: make(map[string]*uploadSession)}, nil
 }
 
 func (s *BlobStore) blobPath(digest string) string {
@@ -112,3 +160,208 @@ func (s *BlobStore) Delete(_ context.Context, repo string, h v1.Hash) error {
 	}
 	return nil
 }
+
+// StartUpload starts a blob upload session and returns its ID. Sessions idle
+// for longer than UploadSessionTTL are removed.
+func (s *BlobStore) StartUpload() (string, error) {
+	s.pruneUploads()
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("generate upload id: %w", err)
+	}
+	id := hex.EncodeToString(b)
+	f, err := os.OpenFile(s.paths.RegistryUpload(id), os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+	if err != nil {
+		return "", fmt.Errorf("create upload file: %w", err)
+	}
+	f.Close()
+
+	s.mu.Lock()
+	s.uploads[id] = &uploadSession{hash: sha256.New(), recovered: true}
+	s.mu.Unlock()
+	return id, nil
+}
+
+// session returns an upload session, locked. Sessions started before a
+// restart are recovered from their data on disk.
+func (s *BlobStore) session(id string) (*uploadSession, error) {
+	if !uploadIDPattern.MatchString(id) {
+		return nil, ErrUploadUnknown
+	}
+	s.mu.Lock()
+	sess, ok := s.uploads[id]
+	if !ok {
+		sess = &uploadSession{hash: sha256.New()}
+		s.uploads[id] = sess
+	}
+	s.mu.Unlock()
+
+	sess.mu.Lock()
+	if sess.ended {
+		sess.mu.Unlock()
+		return nil, ErrUploadUnknown
+	}
+	if sess.recovered {
+		return sess, nil
+	}
+	f, err := os.Open(s.paths.RegistryUpload(id))
+	if err == nil {
+		sess.size, err = io.Copy(sess.hash, f)
+		f.Close()
+	}
+	if err != nil {
+		s.endUpload(id, sess)
+		sess.mu.Unlock()
+		if os.IsNotExist(err) {
+			return nil, ErrUploadUnknown
+		}
+		return nil, fmt.Errorf("recover upload: %w", err)
+	}
+	sess.recovered = true
+	return sess, nil
+}
+
+// UploadSize returns how many bytes an upload session received so far.
+func (s *BlobStore) UploadSize(id string) (int64, error) {
+	sess, err := s.session(id)
+	if err != nil {
+		return 0, err
+	}
+	defer sess.mu.Unlock()
+	return sess.size, nil
+}
+
+// AppendUpload appends a chunk to an upload session and returns the bytes
+// received so far. With offset >= 0 the chunk must start there, otherwise
+// it's an UploadRangeError. A chunk cut short still keeps what was received,
+// so the client can resume from the returned size.
+func (s *BlobStore) AppendUpload(id string, offset int64, r io.Reader) (int64, error) {
+	sess, err := s.session(id)
+	if err != nil {
+		return 0, err
+	}
+	defer sess.mu.Unlock()
+	if err := sess.append(s.paths.RegistryUpload(id), offset, r); err != nil {
+		return sess.size, err
+	}
+	return sess.size, nil
+}
+
+func (sess *uploadSession) append(path string, offset int64, r io.Reader) error {
+	if offset >= 0 && offset != sess.size {
+		return &UploadRangeError{Size: sess.size}
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY, 0)
+	if os.IsNotExist(err) {
+		return ErrUploadUnknown
+	}
+	if err != nil {
+		return fmt.Errorf("open upload file: %w", err)
+	}
+	defer f.Close()
+	// A failed write from an earlier chunk may have left bytes past the
+	// ones hashed
+	if err := f.Truncate(sess.size); err != nil {
+		return fmt.Errorf("truncate upload file: %w", err)
+	}
+	if _, err := f.Seek(sess.size, io.SeekStart); err != nil {
+		return fmt.Errorf("seek upload file: %w", err)
+	}
+	n, err := io.Copy(&hashingWriter{w: f, hash: sess.hash}, r)
+	sess.size += n
+	if err != nil {
+		return fmt.Errorf("write chunk: %w", err)
+	}
+	return nil
+}
+
+// hashingWriter hashes the bytes written to w, and only those
+type hashingWriter struct {
+	w    io.Writer
+	hash hash.Hash
+}
+
+func (w *hashingWriter) Write(p []byte) (int, error) {
+	n, err := w.w.Write(p)
+	w.hash.Write(p[:n])
+	return n, err
+}
+
+// CommitUpload appends a last chunk to an upload session, then stores its
+// data as blob h and ends the session. On ErrDigestMismatch the session is
+// ended and its data discarded.
+func (s *BlobStore) CommitUpload(id string, h v1.Hash, r io.Reader) (int64, error) {
+	sess, err := s.session(id)
+	if err != nil {
+		return 0, err
+	}
+	defer sess.mu.Unlock()
+	uploadPath := s.paths.RegistryUpload(id)
+	if err := sess.append(uploadPath, -1, r); err != nil {
+		return sess.size, err
+	}
+
+	if actual := "sha256:" + hex.EncodeToString(sess.hash.Sum(nil)); actual != h.String() {
+		s.endUpload(id, sess)
+		return sess.size, fmt.Errorf("%w: expected %s, got %s", ErrDigestMismatch, h.String(), actual)
+	}
+	path := s.blobPath(h.String())
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		if err := os.Rename(uploadPath, path); err != nil {
+			return sess.size, fmt.Errorf("rename blob: %w", err)
+		}
+	}
+	s.endUpload(id, sess)
+	return sess.size, nil
+}
+
+// CancelUpload ends an upload session and discards its data.
+func (s *BlobStore) CancelUpload(id string) error {
+	sess, err := s.session(id)
+	if err != nil {
+		return err
+	}
+	defer sess.mu.Unlock()
+	s.endUpload(id, sess)
+	return nil
+}
+
+// endUpload forgets an upload session and removes its data. The caller holds
+// sess.mu.
+func (s *BlobStore) endUpload(id string, sess *uploadSession) {
+	sess.ended = true
+	s.mu.Lock()
+	if s.uploads[id] == sess {
+		delete(s.uploads, id)
+	}
+	s.mu.Unlock()
+	os.Remove(s.paths.RegistryUpload(id))
+}
+
+// pruneUploads removes the upload sessions idle for longer than
+// UploadSessionTTL
+func (s *BlobStore) pruneUploads() {
+	entries, err := os.ReadDir(s.paths.RegistryUploadsDir())
+	if err != nil {
+		return
+	}
+	for _, e := range entries {
+		info, err := e.Info()
+		if err != nil || time.Since(info.ModTime()) < UploadSessionTTL {
+			continue
+		}
+		id := e.Name()
+		s.mu.Lock()
+		sess, ok := s.uploads[id]
+		s.mu.Unlock()
+		if !ok {
+			os.Remove(filepath.Join(s.paths.RegistryUploadsDir(), id))
+			continue
+		}
+		// Skip sessions receiving a chunk right now
+		if sess.mu.TryLock() {
+			s.endUpload(id, sess)
+			sess.mu.Unlock()
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package registry
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"runtime"
+	"strings"
+
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+)
+
+// hostPlatform is the platform pushed images are converted for, the same
+// one image pulls select
+func hostPlatform() v1.Platform {
+	return v1.Platform{
+		Architecture: runtime.GOARCH,
+		OS:           runtime.GOOS,
+	}
+}
+
+// platformManifest picks the host platform's image manifest from an index
+func platformManifest(index []byte) (*v1.Descriptor, error) {
+	manifest, err := v1.ParseIndexManifest(bytes.NewReader(index))
+	if err != nil {
+		return nil, fmt.Errorf("parse index: %w", err)
+	}
+	platform := hostPlatform()
+	for i, desc := range manifest.Manifests {
+		if desc.MediaType.IsImage() && desc.Platform != nil && desc.Platform.Satisfies(platform) {
+			return &manifest.Manifests[i], nil
+		}
+	}
+	return nil, fmt.Errorf("no manifest for platform %s", platform.String())
+}
+
+// matchesHost reports whether an image manifest's config is for the host
+// platform. Images whose config doesn't say are assumed to be.
+func (r *Registry) matchesHost(manifest []byte) bool {
+	var m internalManifest
+	if err := json.Unmarshal(manifest, &m); err != nil || m.Config.Digest == "" {
+		return true
+	}
+	data, err := os.ReadFile(r.paths.OCICacheBlob(strings.TrimPrefix(m.Config.Digest, "sha256:")))
+	if err != nil {
+		return true
+	}
+	var config struct {
+		Architecture string `json:"architecture"`
+		OS           string `json:"os"`
+		Variant      string `json:"variant"`
+	}
+	if err := json.Unmarshal(data, &config); err != nil || config.Architecture == "" {
+		return true
+	}
+	platform := v1.Platform{Architecture: config.Architecture, OS: config.OS, Variant: config.Variant}
+	return platform.Satisfies(hostPlatform())
+}
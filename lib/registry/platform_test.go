@@ -0,0 +1,115 @@
+package registry
+
+import (
+	"context"
+	"net/http/httptest"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+	"github.com/kernel/hypeman/lib/images"
+	"github.com/kernel/hypeman/lib/paths"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// importRecorder implements images.Manager, recording the images queued
+// for conversion
+type importRecorder struct {
+	images.Manager
+	mu      sync.Mutex
+	imports []string
+}
+
+func (m *importRecorder) ImportLocalImage(ctx context.Context, repo, reference, digest string) (*images.Image, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.imports = append(m.imports, reference+"="+digest)
+	return &images.Image{}, nil
+}
+
+func (m *importRecorder) recorded() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]string(nil), m.imports...)
+}
+
+// platformImage returns a random OCI image whose config is for platform
+func platformImage(t *testing.T, platform v1.Platform) v1.Image {
+	img, err := random.Image(512, 1)
+	require.NoError(t, err)
+	cfg, err := img.ConfigFile()
+	require.NoError(t, err)
+	cfg.Architecture, cfg.OS = platform.Architecture, platform.OS
+	img, err = mutate.ConfigFile(img, cfg)
+	require.NoError(t, err)
+	img = mutate.MediaType(img, types.OCIManifestSchema1)
+	return mutate.ConfigMediaType(img, types.OCIConfigJSON)
+}
+
+// otherPlatform returns a platform other than the host's
+func otherPlatform() v1.Platform {
+	if runtime.GOARCH == "riscv64" {
+		return v1.Platform{OS: "linux", Architecture: "s390x"}
+	}
+	return v1.Platform{OS: "linux", Architecture: "riscv64"}
+}
+
+func TestIndexPush(t *testing.T) {
+	p := paths.New(t.TempDir())
+	rec := &importRecorder{}
+	reg, err := New(p, rec)
+	require.NoError(t, err)
+	srv := httptest.NewServer(reg.Handler())
+	defer srv.Close()
+	host := strings.TrimPrefix(srv.URL, "http://")
+
+	platform, other := hostPlatform(), otherPlatform()
+	hostImg := platformImage(t, platform)
+	idx := mutate.AppendManifests(empty.Index,
+		mutate.IndexAddendum{Add: platformImage(t, other), Descriptor: v1.Descriptor{Platform: &other}},
+		mutate.IndexAddendum{Add: hostImg, Descriptor: v1.Descriptor{Platform: &platform}},
+	)
+	ref, err := name.ParseReference(host+"/multi/app:v1", name.Insecure)
+	require.NoError(t, err)
+	require.NoError(t, remote.WriteIndex(ref, idx))
+
+	// The host's image is converted by digest as it's pushed, then tagged
+	// once the index is
+	hostDigest, err := hostImg.Digest()
+	require.NoError(t, err)
+	want := []string{hostDigest.String() + "=" + hostDigest.String(), "v1=" + hostDigest.String()}
+	assert.Eventually(t, func() bool { return len(rec.recorded()) == len(want) }, 5*time.Second, 10*time.Millisecond)
+	assert.ElementsMatch(t, want, rec.recorded())
+
+	// The index stays pullable for other platforms
+	got, err := remote.Index(ref)
+	require.NoError(t, err)
+	gotDigest, err := got.Digest()
+	require.NoError(t, err)
+	idxDigest, err := idx.Digest()
+	require.NoError(t, err)
+	assert.Equal(t, idxDigest, gotDigest)
+	_, err = remote.Image(ref, remote.WithPlatform(other))
+	assert.NoError(t, err)
+}
+
+func TestPlatformManifest(t *testing.T) {
+	other := otherPlatform()
+	idx := mutate.AppendManifests(empty.Index,
+		mutate.IndexAddendum{Add: platformImage(t, other), Descriptor: v1.Descriptor{Platform: &other}},
+	)
+	raw, err := idx.RawManifest()
+	require.NoError(t, err)
+	_, err = platformManifest(raw)
+	assert.ErrorContains(t, err, "no manifest for platform")
+}
@@ -0,0 +1,427 @@
+package registry
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/google/go-containerregistry/pkg/v1/remote/transport"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+	"github.com/kernel/hypeman/lib/paths"
+)
+
+// ProxyRepoPrefix is the repository prefix upstream registries are served
+// under, like "proxy/docker.io/library/alpine"
+const ProxyRepoPrefix = "proxy/"
+
+// DefaultProxyTagTTL is how long a tag's cached digest is served before it
+// is resolved upstream again
+const DefaultProxyTagTTL = 10 * time.Minute
+
+// ErrUnknownUpstream is returned for registries the proxy isn't configured for
+var ErrUnknownUpstream = errors.New("upstream registry not configured")
+
+// errInvalidReference is returned for malformed repositories, tags and digests
+var errInvalidReference = errors.New("invalid reference")
+
+// proxyPattern matches GET and HEAD requests to
+// /v2/proxy/{upstream}/{name}/(manifests|blobs)/{reference}
+var proxyPattern = regexp.MustCompile(`^/v2/` + ProxyRepoPrefix + `([^/]+)/(.+)/(manifests|blobs)/([^/]+)$`)
+
+// upstreamPattern matches the manifest and blob requests of registry clients
+// to an upstream: /v2/{name}/(manifests|blobs)/{reference}
+var upstreamPattern = regexp.MustCompile(`^/v2/(.+)/(manifests|blobs)/([^/]+)$`)
+
+// ProxyConfig configures the pull-through cache
+type ProxyConfig struct {
+	Upstreams []string      // Registries to proxy, like "docker.io" or "ghcr.io"
+	TagTTL    time.Duration // How long resolved tags are cached (0 = DefaultProxyTagTTL)
+}
+
+// Proxy is a pull-through cache of upstream registries. Manifests and blobs
+// are fetched once and kept in the OCI cache's blob store, where they're
+// shared with pushed and pulled images; tags are re-resolved once their TTL
+// passes, falling back to the cached digest while the upstream is down.
+type Proxy struct {
+	paths     *paths.Paths
+	blobStore *BlobStore
+	tagTTL    time.Duration
+	upstreams map[string]name.Registry // By configured name
+	hosts     map[string]string        // Configured name by API host, like index.docker.io
+
+	mu       sync.Mutex
+	inflight map[string]chan struct{} // Blob fetches by digest
+}
+
+// NewProxy creates a pull-through cache of cfg's upstreams. Upstreams are
+// reached with the host's registry credentials (~/.docker/config.json).
+func NewProxy(p *paths.Paths, cfg ProxyConfig) (*Proxy, error) {
+	blobStore, err := NewBlobStore(p)
+	if err != nil {
+		return nil, err
+	}
+	px := &Proxy{
+		paths:     p,
+		blobStore: blobStore,
+		tagTTL:    cfg.TagTTL,
+		upstreams: make(map[string]name.Registry),
+		hosts:     make(map[string]string),
+		inflight:  make(map[string]chan struct{}),
+	}
+	if px.tagTTL <= 0 {
+		px.tagTTL = DefaultProxyTagTTL
+	}
+	for _, upstream := range cfg.Upstreams {
+		reg, err := name.NewRegistry(upstream, name.StrictValidation)
+		if err != nil || strings.Contains(upstream, "/") {
+			return nil, fmt.Errorf("invalid upstream registry %q", upstream)
+		}
+		px.upstreams[upstream] = reg
+		px.hosts[reg.RegistryStr()] = upstream
+	}
+	return px, nil
+}
+
+// cachedContent is a manifest or blob in the blob store
+type cachedContent struct {
+	path      string
+	digest    string
+	mediaType string
+	size      int64
+}
+
+func (p *Proxy) remoteOptions(ctx context.Context) []remote.Option {
+	return []remote.Option{
+		remote.WithContext(ctx),
+		remote.WithAuthFromKeychain(authn.DefaultKeychain),
+	}
+}
+
+// repository parses an upstream repository, normalizing names like
+// docker.io's "alpine" to "library/alpine"
+func (p *Proxy) repository(upstream, repo string) (name.Repository, error) {
+	if _, ok := p.upstreams[upstream]; !ok {
+		return name.Repository{}, fmt.Errorf("%w: %s", ErrUnknownUpstream, upstream)
+	}
+	for _, part := range strings.Split(repo, "/") {
+		if part == "." || part == ".." {
+			return name.Repository{}, fmt.Errorf("%w: repository %q", errInvalidReference, repo)
+		}
+	}
+	return name.NewRepository(upstream+"/"+repo, name.StrictValidation)
+}
+
+// manifest returns an upstream manifest by tag or digest, fetching it on a
+// cache miss
+func (p *Proxy) manifest(ctx context.Context, upstream, repo, reference string) (*cachedContent, error) {
+	repository, err := p.repository(upstream, repo)
+	if err != nil {
+		return nil, err
+	}
+	if strings.HasPrefix(reference, "sha256:") {
+		if _, err := v1.NewHash(reference); err != nil {
+			return nil, fmt.Errorf("%w: digest %q", errInvalidReference, reference)
+		}
+		if c, err := p.cachedManifest(reference); err == nil {
+			return c, nil
+		}
+		return p.fetchManifest(ctx, repository.Digest(reference))
+	}
+
+	tag := repository.Tag(reference)
+	if _, err := name.NewTag(tag.String(), name.StrictValidation); err != nil {
+		return nil, fmt.Errorf("%w: tag %q", errInvalidReference, reference)
+	}
+	tagPath := p.paths.RegistryProxyTag(upstream, repository.RepositoryStr(), reference)
+	cachedDigest, fresh := p.cachedTag(tagPath)
+	if fresh {
+		if c, err := p.cachedManifest(cachedDigest); err == nil {
+			return c, nil
+		}
+	}
+
+	c, err := p.fetchManifest(ctx, tag)
+	if err != nil {
+		// Keep serving the last digest while the upstream is unreachable
+		if cachedDigest != "" && upstreamStatus(err) != http.StatusNotFound {
+			if stale, cerr := p.cachedManifest(cachedDigest); cerr == nil {
+				fmt.Fprintf(os.Stderr, "Warning: serving cached %s after upstream error: %v\n", tag, err)
+				return stale, nil
+			}
+		}
+		return nil, err
+	}
+	if err := writeFileAtomic(tagPath, []byte(c.digest)); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to cache tag %s: %v\n", tag, err)
+	}
+	return c, nil
+}
+
+// cachedTag returns the digest a tag was last resolved to, and whether that
+// was within the tag TTL
+func (p *Proxy) cachedTag(tagPath string) (string, bool) {
+	info, err := os.Stat(tagPath)
+	if err != nil {
+		return "", false
+	}
+	data, err := os.ReadFile(tagPath)
+	if err != nil {
+		return "", false
+	}
+	return strings.TrimSpace(string(data)), time.Since(info.ModTime()) < p.tagTTL
+}
+
+func (p *Proxy) cachedManifest(digest string) (*cachedContent, error) {
+	path := p.blobStore.blobPath(digest)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return &cachedContent{path: path, digest: digest, mediaType: manifestMediaType(data), size: int64(len(data))}, nil
+}
+
+func (p *Proxy) fetchManifest(ctx context.Context, ref name.Reference) (*cachedContent, error) {
+	desc, err := remote.Get(ref, p.remoteOptions(ctx)...)
+	if err != nil {
+		return nil, err
+	}
+	if err := p.blobStore.Put(ctx, ref.Context().String(), desc.Digest, io.NopCloser(bytes.NewReader(desc.Manifest))); err != nil {
+		return nil, fmt.Errorf("store manifest: %w", err)
+	}
+	return &cachedContent{
+		path:      p.blobStore.blobPath(desc.Digest.String()),
+		digest:    desc.Digest.String(),
+		mediaType: string(desc.MediaType),
+		size:      int64(len(desc.Manifest)),
+	}, nil
+}
+
+// blob returns an upstream blob, fetching it on a cache miss. Concurrent
+// requests for a blob being fetched wait for that fetch.
+func (p *Proxy) blob(ctx context.Context, upstream, repo, digest string) (*cachedContent, error) {
+	repository, err := p.repository(upstream, repo)
+	if err != nil {
+		return nil, err
+	}
+	hash, err := v1.NewHash(digest)
+	if err != nil {
+		return nil, fmt.Errorf("%w: digest %q", errInvalidReference, digest)
+	}
+
+	for {
+		if size, err := p.blobStore.Stat(ctx, repo, hash); err == nil {
+			return &cachedContent{path: p.blobStore.blobPath(digest), digest: digest, mediaType: "application/octet-stream", size: size}, nil
+		}
+
+		p.mu.Lock()
+		wait, busy := p.inflight[digest]
+		if !busy {
+			p.inflight[digest] = make(chan struct{})
+		}
+		p.mu.Unlock()
+		if !busy {
+			break
+		}
+		select {
+		case <-wait:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	defer func() {
+		p.mu.Lock()
+		close(p.inflight[digest])
+		delete(p.inflight, digest)
+		p.mu.Unlock()
+	}()
+
+	layer, err := remote.Layer(repository.Digest(digest), p.remoteOptions(ctx)...)
+	if err != nil {
+		return nil, err
+	}
+	rc, err := layer.Compressed()
+	if err != nil {
+		return nil, err
+	}
+	if err := p.blobStore.Put(ctx, repo, hash, rc); err != nil {
+		return nil, fmt.Errorf("store blob: %w", err)
+	}
+	size, err := p.blobStore.Stat(ctx, repo, hash)
+	if err != nil {
+		return nil, err
+	}
+	return &cachedContent{path: p.blobStore.blobPath(digest), digest: digest, mediaType: "application/octet-stream", size: size}, nil
+}
+
+func (p *Proxy) content(ctx context.Context, upstream, repo, kind, reference string) (*cachedContent, error) {
+	if kind == "manifests" {
+		return p.manifest(ctx, upstream, repo, reference)
+	}
+	return p.blob(ctx, upstream, repo, reference)
+}
+
+// serveHTTP serves a manifest or blob of /v2/proxy/{upstream}/...
+func (p *Proxy) serveHTTP(w http.ResponseWriter, req *http.Request, upstream, repo, kind, reference string) {
+	c, err := p.content(req.Context(), upstream, repo, kind, reference)
+	if err != nil {
+		status, code := proxyError(err, kind)
+		if status == http.StatusBadGateway {
+			fmt.Fprintf(os.Stderr, "Warning: pull-through of %s/%s %s failed: %v\n", upstream, repo, reference, err)
+		}
+		writeRegistryError(w, status, code, err.Error())
+		return
+	}
+	f, err := os.Open(c.path)
+	if err != nil {
+		writeRegistryError(w, http.StatusInternalServerError, "UNKNOWN", err.Error())
+		return
+	}
+	defer f.Close()
+	w.Header().Set("Content-Type", c.mediaType)
+	w.Header().Set("Docker-Content-Digest", c.digest)
+	http.ServeContent(w, req, "", time.Time{}, f)
+}
+
+// Transport returns a RoundTripper that serves the manifest and blob
+// requests of registry clients to proxied upstreams from the cache, and
+// sends everything else, like authentication, to inner. Image pulls through
+// it share the cache with builds.
+func (p *Proxy) Transport(inner http.RoundTripper) http.RoundTripper {
+	return &proxyTransport{proxy: p, inner: inner}
+}
+
+type proxyTransport struct {
+	proxy *Proxy
+	inner http.RoundTripper
+}
+
+func (t *proxyTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	upstream, ok := t.proxy.hosts[req.URL.Host]
+	if !ok || (req.Method != http.MethodGet && req.Method != http.MethodHead) {
+		return t.inner.RoundTrip(req)
+	}
+	m := upstreamPattern.FindStringSubmatch(req.URL.Path)
+	if m == nil {
+		return t.inner.RoundTrip(req)
+	}
+
+	resp := &http.Response{
+		Proto:      "HTTP/1.1",
+		ProtoMajor: 1,
+		ProtoMinor: 1,
+		Header:     http.Header{},
+		Request:    req,
+	}
+	c, err := t.proxy.content(req.Context(), upstream, m[1], m[2], m[3])
+	if err != nil {
+		status, code := proxyError(err, m[2])
+		if status != http.StatusNotFound {
+			return nil, err
+		}
+		body, _ := json.Marshal(registryErrors(code, err.Error()))
+		resp.StatusCode = status
+		resp.Header.Set("Content-Type", "application/json")
+		resp.Body = io.NopCloser(strings.NewReader(string(body)))
+		resp.ContentLength = int64(len(body))
+		return resp, nil
+	}
+
+	resp.StatusCode = http.StatusOK
+	resp.Header.Set("Content-Type", c.mediaType)
+	resp.Header.Set("Docker-Content-Digest", c.digest)
+	resp.Header.Set("Content-Length", strconv.FormatInt(c.size, 10))
+	resp.ContentLength = c.size
+	resp.Body = http.NoBody
+	if req.Method == http.MethodGet {
+		f, err := os.Open(c.path)
+		if err != nil {
+			return nil, err
+		}
+		resp.Body = f
+	}
+	return resp, nil
+}
+
+// upstreamStatus returns the HTTP status an upstream error is answered with
+func upstreamStatus(err error) int {
+	var terr *transport.Error
+	if errors.As(err, &terr) && terr.StatusCode == http.StatusNotFound {
+		return http.StatusNotFound
+	}
+	return http.StatusBadGateway
+}
+
+// proxyError returns the HTTP status and OCI error code of a proxy error
+func proxyError(err error, kind string) (int, string) {
+	switch {
+	case errors.Is(err, ErrUnknownUpstream):
+		return http.StatusNotFound, "NAME_UNKNOWN"
+	case errors.Is(err, errInvalidReference), name.IsErrBadName(err):
+		return http.StatusBadRequest, "NAME_INVALID"
+	case upstreamStatus(err) == http.StatusNotFound:
+		if kind == "manifests" {
+			return http.StatusNotFound, "MANIFEST_UNKNOWN"
+		}
+		return http.StatusNotFound, "BLOB_UNKNOWN"
+	}
+	return http.StatusBadGateway, "UNKNOWN"
+}
+
+type registryError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+func registryErrors(code, message string) map[string][]registryError {
+	return map[string][]registryError{"errors": {{Code: code, Message: message}}}
+}
+
+// writeRegistryError writes an OCI Distribution Spec error response
+func writeRegistryError(w http.ResponseWriter, status int, code, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(registryErrors(code, message))
+}
+
+// manifestMediaType returns a cached manifest's media type, which manifests
+// may leave out of their JSON
+func manifestMediaType(data []byte) string {
+	var m struct {
+		MediaType string            `json:"mediaType"`
+		Manifests []json.RawMessage `json:"manifests"`
+	}
+	json.Unmarshal(data, &m)
+	switch {
+	case m.MediaType != "":
+		return m.MediaType
+	case m.Manifests != nil:
+		return string(types.OCIImageIndex)
+	}
+	return string(types.OCIManifestSchema1)
+}
+
+func writeFileAtomic(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
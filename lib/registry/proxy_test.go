@@ -0,0 +1,167 @@
+package registry
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/registry"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/google/go-containerregistry/pkg/v1/remote/transport"
+	"github.com/kernel/hypeman/lib/paths"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// countingUpstream is a registry counting the blob requests it serves
+type countingUpstream struct {
+	*httptest.Server
+	host  string
+	blobs atomic.Int32
+}
+
+func newCountingUpstream(t *testing.T) *countingUpstream {
+	u := &countingUpstream{}
+	reg := registry.New()
+	u.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet && strings.Contains(r.URL.Path, "/blobs/") {
+			u.blobs.Add(1)
+		}
+		reg.ServeHTTP(w, r)
+	}))
+	t.Cleanup(u.Close)
+	u.host = strings.TrimPrefix(u.URL, "http://")
+	return u
+}
+
+func (u *countingUpstream) push(t *testing.T, ref string) v1.Image {
+	img, err := random.Image(1024, 2)
+	require.NoError(t, err)
+	tag, err := name.NewTag(u.host + "/" + ref)
+	require.NoError(t, err)
+	require.NoError(t, remote.Write(tag, img))
+	return img
+}
+
+// pull reads an image's manifest and layers
+func pull(t *testing.T, ref string, opts ...remote.Option) (v1.Hash, error) {
+	t.Helper()
+	r, err := name.ParseReference(ref)
+	require.NoError(t, err)
+	img, err := remote.Image(r, opts...)
+	if err != nil {
+		return v1.Hash{}, err
+	}
+	layers, err := img.Layers()
+	require.NoError(t, err)
+	for _, l := range layers {
+		rc, err := l.Compressed()
+		require.NoError(t, err)
+		_, err = io.Copy(io.Discard, rc)
+		rc.Close()
+		require.NoError(t, err)
+	}
+	return img.Digest()
+}
+
+func TestProxy_Handler(t *testing.T) {
+	upstream := newCountingUpstream(t)
+	img := upstream.push(t, "team/app:v1")
+	digest, err := img.Digest()
+	require.NoError(t, err)
+	manifest, err := img.Manifest()
+	require.NoError(t, err)
+
+	p := paths.New(t.TempDir())
+	proxy, err := NewProxy(p, ProxyConfig{Upstreams: []string{upstream.host}, TagTTL: time.Hour})
+	require.NoError(t, err)
+	reg, err := New(p, nil)
+	require.NoError(t, err)
+	reg.SetProxy(proxy)
+	local := httptest.NewServer(reg.Handler())
+	defer local.Close()
+	repoURL := local.URL + "/v2/proxy/" + upstream.host + "/team/app"
+
+	get := func(path string) (*http.Response, string) {
+		t.Helper()
+		resp, err := http.Get(repoURL + path)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		return resp, string(body)
+	}
+	pullAll := func() {
+		t.Helper()
+		resp, _ := get("/manifests/v1")
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, digest.String(), resp.Header.Get("Docker-Content-Digest"))
+		assert.Equal(t, string(manifest.MediaType), resp.Header.Get("Content-Type"))
+		for _, l := range append(manifest.Layers, manifest.Config) {
+			resp, body := get("/blobs/" + l.Digest.String())
+			require.Equal(t, http.StatusOK, resp.StatusCode)
+			assert.Equal(t, l.Size, int64(len(body)))
+		}
+	}
+
+	pullAll()
+	assert.Equal(t, int32(3), upstream.blobs.Load()) // Config and two layers
+
+	// Cached tags, manifests and blobs are served without the upstream
+	upstream.Close()
+	pullAll()
+	resp, _ := get("/manifests/" + digest.String())
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	// An expired tag falls back to its cached digest while the upstream is down
+	old := time.Now().Add(-2 * time.Hour)
+	tagPath := p.RegistryProxyTag(upstream.host, "team/app", "v1")
+	require.NoError(t, os.Chtimes(tagPath, old, old))
+	pullAll()
+
+	// Tags never resolved can't be served
+	resp, _ = get("/manifests/v2")
+	assert.Equal(t, http.StatusBadGateway, resp.StatusCode)
+	resp, _ = get("/manifests/v%21")
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	// Only configured upstreams are proxied
+	resp, err = http.Get(local.URL + "/v2/proxy/example.com/app/manifests/v1")
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+}
+
+func TestProxy_Transport(t *testing.T) {
+	upstream := newCountingUpstream(t)
+	img := upstream.push(t, "app:v1")
+	want, err := img.Digest()
+	require.NoError(t, err)
+
+	proxy, err := NewProxy(paths.New(t.TempDir()), ProxyConfig{Upstreams: []string{upstream.host}})
+	require.NoError(t, err)
+	rt := remote.WithTransport(proxy.Transport(http.DefaultTransport))
+	ref := upstream.host + "/app:v1"
+
+	for range 2 {
+		got, err := pull(t, ref, rt)
+		require.NoError(t, err)
+		assert.Equal(t, want, got)
+	}
+	// The second pull's layers were served from the cache
+	assert.Equal(t, int32(2), upstream.blobs.Load())
+
+	// Missing images are reported as not found
+	_, err = pull(t, upstream.host+"/missing:v1", rt)
+	var terr *transport.Error
+	require.ErrorAs(t, err, &terr)
+	assert.Equal(t, http.StatusNotFound, terr.StatusCode)
+}
@@ -31,6 +31,7 @@ type Registry struct {
 	imageManager images.Manager
 	blobStore    *BlobStore
 	handler      http.Handler
+	proxy        *Proxy
 }
 
 // manifestPutPattern matches PUT requests to /v2/{name}/manifests/{reference}
@@ -44,9 +45,11 @@ func New(p *paths.Paths, imgManager images.Manager) (*Registry, error) {
 		return nil, err
 	}
 
-	// Create registry with custom blob handler
+	// Create registry with custom blob handler. Referrers support serves the
+	// SBOMs and provenance attached to built images.
 	regHandler := registry.New(
 		registry.WithBlobHandler(blobStore),
+		registry.WithReferrersSupport(true),
 	)
 
 	r := &Registry{
@@ -59,10 +62,35 @@ func New(p *paths.Paths, imgManager images.Manager) (*Registry, error) {
 	return r, nil
 }
 
+// BlobDir returns the directory pushed blobs are stored in
+func (r *Registry) BlobDir() string {
+	return r.paths.OCICacheBlobDir()
+}
+
+// SetProxy serves the upstream registries of p under ProxyRepoPrefix. It
+// must be called before the registry serves requests.
+func (r *Registry) SetProxy(p *Proxy) {
+	r.proxy = p
+}
+
 // Handler returns the http.Handler for the registry endpoints.
 // This wraps the underlying registry to intercept manifest PUTs and trigger conversion.
 func (r *Registry) Handler() http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		// Pulls of proxied repositories are served from the pull-through cache
+		if r.proxy != nil && (req.Method == http.MethodGet || req.Method == http.MethodHead) {
+			if m := proxyPattern.FindStringSubmatch(req.URL.Path); m != nil {
+				r.proxy.serveHTTP(w, req, m[1], m[2], m[3], m[4])
+				return
+			}
+		}
+
+		// Blob uploads are written to the blob store as chunks arrive
+		if m := uploadPattern.FindStringSubmatch(req.URL.Path); m != nil {
+			r.serveUpload(w, req, m[1], m[2])
+			return
+		}
+
 		// Intercept manifest PUT requests to store in blob store and trigger conversion
 		if req.Method == http.MethodPut {
 			matches := manifestPutPattern.FindStringSubmatch(req.URL.Path)
@@ -101,8 +129,10 @@ func (r *Registry) Handler() http.Handler {
 				wrapper := &responseWrapper{ResponseWriter: w}
 				r.handler.ServeHTTP(wrapper, req)
 
-				if wrapper.statusCode == http.StatusCreated {
-					go r.triggerConversion(fullRepo, reference, digest)
+				// Referrers (SBOMs, signatures, ...) are attached to an
+				// image rather than being one, so there's nothing to convert
+				if wrapper.statusCode == http.StatusCreated && !isReferrer(body) {
+					go r.convertPushed(fullRepo, reference, digest, body)
 				}
 				return
 			}
@@ -112,6 +142,38 @@ func (r *Registry) Handler() http.Handler {
 	})
 }
 
+// ForgetTags stops serving tags removed from the image store, like
+// "10.102.0.1:8083/builds/abc:latest". Their blobs stay in the shared blob
+// store.
+func (r *Registry) ForgetTags(refs []string) {
+	for _, ref := range refs {
+		i := strings.LastIndex(ref, ":")
+		if i <= 0 {
+			continue
+		}
+		// Repositories are stored with the host they were pushed to
+		repo, tag := ref[:i], ref[i+1:]
+		if _, path, ok := strings.Cut(repo, "/"); ok {
+			repo = path
+		}
+		req, err := http.NewRequest(http.MethodDelete, "/v2/"+repo+"/manifests/"+tag, nil)
+		if err != nil {
+			continue
+		}
+		r.handler.ServeHTTP(&discardResponse{header: http.Header{}}, req)
+	}
+}
+
+// discardResponse is a ResponseWriter for requests made to the registry
+// itself, whose responses don't matter
+type discardResponse struct {
+	header http.Header
+}
+
+func (w *discardResponse) Header() http.Header         { return w.header }
+func (w *discardResponse) Write(p []byte) (int, error) { return len(p), nil }
+func (w *discardResponse) WriteHeader(int)             {}
+
 // storeManifestBlob stores a manifest in the blob store by its digest.
 func (r *Registry) storeManifestBlob(digest string, data []byte) error {
 	digestHex := strings.TrimPrefix(digest, "sha256:")
@@ -126,6 +188,15 @@ func (r *Registry) storeManifestBlob(digest string, data []byte) error {
 	return os.WriteFile(blobPath, data, 0644)
 }
 
+// isReferrer reports whether a manifest is an artifact attached to another
+// manifest through its subject field
+func isReferrer(manifest []byte) bool {
+	var m struct {
+		Subject *v1.Descriptor `json:"subject"`
+	}
+	return json.Unmarshal(manifest, &m) == nil && m.Subject != nil
+}
+
 // responseWrapper captures the status code from the response
 type responseWrapper struct {
 	http.ResponseWriter
@@ -137,6 +208,37 @@ func (w *responseWrapper) WriteHeader(code int) {
 	w.ResponseWriter.WriteHeader(code)
 }
 
+// convertPushed queues the conversion of a pushed manifest. Indexes are
+// converted through their host platform's manifest. Manifests of other
+// platforms pushed by digest, like an index's children, aren't converted
+// since they can't run here.
+func (r *Registry) convertPushed(repo, reference, digest string, manifest []byte) {
+	if types.MediaType(manifestMediaType(manifest)).IsIndex() {
+		r.triggerIndexConversion(repo, reference, digest, manifest)
+		return
+	}
+	if strings.HasPrefix(reference, "sha256:") && !r.matchesHost(manifest) {
+		return
+	}
+	r.triggerConversion(repo, reference, digest)
+}
+
+// triggerIndexConversion queues the conversion of an index's host platform
+// manifest, tagged with the index's tag. The index itself stays in the blob
+// store for clients pulling other platforms.
+func (r *Registry) triggerIndexConversion(repo, reference, indexDigest string, manifest []byte) {
+	child, err := platformManifest(manifest)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: not converting index %s of %s: %v\n", indexDigest, repo, err)
+		return
+	}
+	// An index pushed by digest has no tag to give its child
+	if strings.HasPrefix(reference, "sha256:") {
+		reference = child.Digest.String()
+	}
+	r.triggerConversion(repo, reference, child.Digest.String())
+}
+
 // triggerConversion queues the image for conversion to ext4 disk format.
 func (r *Registry) triggerConversion(repo, reference, dockerDigest string) {
 	imageRef := repo + ":" + reference
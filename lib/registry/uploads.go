@@ -0,0 +1,165 @@
+package registry
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+)
+
+// uploadPattern matches /v2/{name}/blobs/uploads/ and
+// /v2/{name}/blobs/uploads/{id}
+var uploadPattern = regexp.MustCompile(`^/v2/(.+)/blobs/uploads/([^/]*)$`)
+
+// serveUpload implements the OCI blob upload protocol on the blob store:
+// monolithic and chunked uploads, upload status for resuming an interrupted
+// upload, and cross-repository mounts. Chunks are written to disk as they
+// arrive instead of being buffered in memory.
+func (r *Registry) serveUpload(w http.ResponseWriter, req *http.Request, repo, id string) {
+	if id == "" {
+		if req.Method != http.MethodPost {
+			writeRegistryError(w, http.StatusMethodNotAllowed, "UNSUPPORTED", "unsupported method")
+			return
+		}
+		r.startUpload(w, req, repo)
+		return
+	}
+
+	switch req.Method {
+	case http.MethodGet:
+		size, err := r.blobStore.UploadSize(id)
+		if err != nil {
+			writeUploadError(w, err)
+			return
+		}
+		writeUploadStatus(w, http.StatusNoContent, repo, id, size)
+	case http.MethodPatch:
+		offset, err := chunkOffset(req)
+		if err != nil {
+			writeRegistryError(w, http.StatusRequestedRangeNotSatisfiable, "BLOB_UPLOAD_INVALID", err.Error())
+			return
+		}
+		size, err := r.blobStore.AppendUpload(id, offset, req.Body)
+		var rangeErr *UploadRangeError
+		if errors.As(err, &rangeErr) {
+			setUploadHeaders(w, repo, id, rangeErr.Size)
+			writeRegistryError(w, http.StatusRequestedRangeNotSatisfiable, "BLOB_UPLOAD_INVALID", err.Error())
+			return
+		}
+		if err != nil {
+			writeUploadError(w, err)
+			return
+		}
+		writeUploadStatus(w, http.StatusAccepted, repo, id, size)
+	case http.MethodPut:
+		r.commitUpload(w, req, repo, id)
+	case http.MethodDelete:
+		if err := r.blobStore.CancelUpload(id); err != nil {
+			writeUploadError(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		writeRegistryError(w, http.StatusMethodNotAllowed, "UNSUPPORTED", "unsupported method")
+	}
+}
+
+// startUpload starts an upload session. Uploads with a digest are completed
+// in the same request, and mounts of blobs already stored don't need one.
+func (r *Registry) startUpload(w http.ResponseWriter, req *http.Request, repo string) {
+	query := req.URL.Query()
+	// Every repository shares the blob store, so any stored blob can be mounted
+	if mount := query.Get("mount"); mount != "" {
+		if h, err := v1.NewHash(mount); err == nil {
+			if _, err := r.blobStore.Stat(req.Context(), repo, h); err == nil {
+				writeBlobCreated(w, repo, h)
+				return
+			}
+		}
+	}
+
+	id, err := r.blobStore.StartUpload()
+	if err != nil {
+		writeUploadError(w, err)
+		return
+	}
+	if query.Get("digest") != "" {
+		r.commitUpload(w, req, repo, id)
+		return
+	}
+	writeUploadStatus(w, http.StatusAccepted, repo, id, 0)
+}
+
+// commitUpload completes an upload session with the request's body as its
+// last chunk
+func (r *Registry) commitUpload(w http.ResponseWriter, req *http.Request, repo, id string) {
+	h, err := v1.NewHash(req.URL.Query().Get("digest"))
+	if err != nil {
+		writeRegistryError(w, http.StatusBadRequest, "DIGEST_INVALID", "digest parameter missing or invalid")
+		return
+	}
+	if _, err := r.blobStore.CommitUpload(id, h, req.Body); err != nil {
+		writeUploadError(w, err)
+		return
+	}
+	writeBlobCreated(w, repo, h)
+}
+
+// chunkOffset returns where a PATCH's Content-Range says its chunk starts, or
+// -1 for a chunk appended wherever the upload ends
+func chunkOffset(req *http.Request) (int64, error) {
+	contentRange := strings.TrimPrefix(req.Header.Get("Content-Range"), "bytes ")
+	if contentRange == "" {
+		return -1, nil
+	}
+	startStr, endStr, ok := strings.Cut(contentRange, "-")
+	start, err := strconv.ParseInt(startStr, 10, 64)
+	if !ok || err != nil || start < 0 {
+		return 0, fmt.Errorf("invalid Content-Range %q", contentRange)
+	}
+	end, err := strconv.ParseInt(endStr, 10, 64)
+	if err != nil || end < start {
+		return 0, fmt.Errorf("invalid Content-Range %q", contentRange)
+	}
+	if req.ContentLength >= 0 && req.ContentLength != end-start+1 {
+		return 0, fmt.Errorf("content range %q doesn't match content length %d", contentRange, req.ContentLength)
+	}
+	return start, nil
+}
+
+// setUploadHeaders sets where an upload session continues and the range of
+// bytes it received so far
+func setUploadHeaders(w http.ResponseWriter, repo, id string, size int64) {
+	w.Header().Set("Location", "/v2/"+repo+"/blobs/uploads/"+id)
+	w.Header().Set("Docker-Upload-UUID", id)
+	// An empty upload is reported as 0-0, like other registries do
+	w.Header().Set("Range", fmt.Sprintf("0-%d", max(size-1, 0)))
+}
+
+func writeUploadStatus(w http.ResponseWriter, status int, repo, id string, size int64) {
+	setUploadHeaders(w, repo, id, size)
+	w.Header().Set("Content-Length", "0")
+	w.WriteHeader(status)
+}
+
+func writeBlobCreated(w http.ResponseWriter, repo string, h v1.Hash) {
+	w.Header().Set("Location", "/v2/"+repo+"/blobs/"+h.String())
+	w.Header().Set("Docker-Content-Digest", h.String())
+	w.Header().Set("Content-Length", "0")
+	w.WriteHeader(http.StatusCreated)
+}
+
+func writeUploadError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, ErrUploadUnknown):
+		writeRegistryError(w, http.StatusNotFound, "BLOB_UPLOAD_UNKNOWN", err.Error())
+	case errors.Is(err, ErrDigestMismatch):
+		writeRegistryError(w, http.StatusBadRequest, "DIGEST_INVALID", err.Error())
+	default:
+		writeRegistryError(w, http.StatusInternalServerError, "BLOB_UPLOAD_INVALID", err.Error())
+	}
+}
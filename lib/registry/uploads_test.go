@@ -0,0 +1,134 @@
+package registry
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/kernel/hypeman/lib/paths"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUploads_Push(t *testing.T) {
+	p := paths.New(t.TempDir())
+	reg, err := New(p, nil)
+	require.NoError(t, err)
+	srv := httptest.NewServer(reg.Handler())
+	defer srv.Close()
+
+	layer, err := random.Layer(64*1024, "")
+	require.NoError(t, err)
+	repo, err := name.NewRepository(strings.TrimPrefix(srv.URL, "http://") + "/app")
+	require.NoError(t, err)
+	require.NoError(t, remote.WriteLayer(repo, layer))
+
+	digest, err := layer.Digest()
+	require.NoError(t, err)
+	got, err := remote.Layer(repo.Digest(digest.String()))
+	require.NoError(t, err)
+	rc, err := got.Compressed()
+	require.NoError(t, err)
+	defer rc.Close()
+	data, err := io.ReadAll(rc)
+	require.NoError(t, err)
+	size, err := layer.Size()
+	require.NoError(t, err)
+	assert.Equal(t, size, int64(len(data)))
+}
+
+func TestUploads_Chunked(t *testing.T) {
+	p := paths.New(t.TempDir())
+	reg, err := New(p, nil)
+	require.NoError(t, err)
+	srv := httptest.NewServer(reg.Handler())
+	defer srv.Close()
+
+	blob := make([]byte, 3000)
+	_, err = rand.Read(blob)
+	require.NoError(t, err)
+	sum := sha256.Sum256(blob)
+	digest := "sha256:" + hex.EncodeToString(sum[:])
+
+	do := func(method, url string, body []byte, header ...string) *http.Response {
+		t.Helper()
+		if !strings.HasPrefix(url, "http") {
+			url = srv.URL + url
+		}
+		req, err := http.NewRequest(method, url, bytes.NewReader(body))
+		require.NoError(t, err)
+		for i := 0; i < len(header); i += 2 {
+			req.Header.Set(header[i], header[i+1])
+		}
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		resp.Body.Close()
+		return resp
+	}
+	patch := func(location string, start, end int) *http.Response {
+		t.Helper()
+		return do(http.MethodPatch, location, blob[start:end], "Content-Range", fmt.Sprintf("%d-%d", start, end-1))
+	}
+
+	resp := do(http.MethodPost, "/v2/builds/abc/blobs/uploads/", nil)
+	require.Equal(t, http.StatusAccepted, resp.StatusCode)
+	location := resp.Header.Get("Location")
+	assert.Equal(t, "0-0", resp.Header.Get("Range"))
+
+	resp = patch(location, 0, 1000)
+	require.Equal(t, http.StatusAccepted, resp.StatusCode)
+	assert.Equal(t, "0-999", resp.Header.Get("Range"))
+
+	// A chunk that doesn't start where the upload ends is rejected
+	resp = patch(location, 2000, 3000)
+	assert.Equal(t, http.StatusRequestedRangeNotSatisfiable, resp.StatusCode)
+	assert.Equal(t, "0-999", resp.Header.Get("Range"))
+
+	// The session survives a restart, and resumes from what was received
+	srv.Close()
+	reg, err = New(p, nil)
+	require.NoError(t, err)
+	srv = httptest.NewServer(reg.Handler())
+	resp = do(http.MethodGet, location, nil)
+	require.Equal(t, http.StatusNoContent, resp.StatusCode)
+	assert.Equal(t, "0-999", resp.Header.Get("Range"))
+
+	resp = patch(location, 1000, 2000)
+	require.Equal(t, http.StatusAccepted, resp.StatusCode)
+	resp = do(http.MethodPut, location+"?digest="+digest, blob[2000:])
+	require.Equal(t, http.StatusCreated, resp.StatusCode)
+	assert.Equal(t, digest, resp.Header.Get("Docker-Content-Digest"))
+	assert.FileExists(t, p.OCICacheBlob(hex.EncodeToString(sum[:])))
+
+	resp = do(http.MethodGet, "/v2/builds/abc/blobs/"+digest, nil)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	resp = do(http.MethodGet, location, nil)
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+	// Stored blobs can be mounted into other repositories
+	resp = do(http.MethodPost, "/v2/builds/def/blobs/uploads/?mount="+digest+"&from=builds/abc", nil)
+	assert.Equal(t, http.StatusCreated, resp.StatusCode)
+
+	// Uploads not matching their digest are discarded
+	resp = do(http.MethodPost, "/v2/builds/def/blobs/uploads/", nil)
+	location = resp.Header.Get("Location")
+	resp = do(http.MethodPut, location+"?digest="+digest, blob[:10])
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	resp = do(http.MethodGet, location, nil)
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+	// Monolithic uploads complete in a single request
+	resp = do(http.MethodPost, "/v2/builds/def/blobs/uploads/?digest="+digest, blob)
+	assert.Equal(t, http.StatusCreated, resp.StatusCode)
+	srv.Close()
+}
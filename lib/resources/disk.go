@@ -94,13 +94,13 @@ func (d *DiskResource) GetBreakdown(ctx context.Context) (*DiskBreakdown, error)
 		}
 	}
 
-	// Get overlay sizes from instances (rootfs overlays + volume overlays)
+	// Get overlay sizes from instances (rootfs overlays + volume overlays + scratch and swap disks)
 	if d.instanceLister != nil {
 		instances, err := d.instanceLister.ListInstanceAllocations(ctx)
 		if err == nil {
 			for _, inst := range instances {
 				if isActiveState(inst.State) {
-					breakdown.Overlays += inst.OverlayBytes + inst.VolumeOverlayBytes
+					breakdown.Overlays += inst.OverlayBytes + inst.VolumeOverlayBytes + inst.ScratchBytes
 				}
 			}
 		}
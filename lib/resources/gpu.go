@@ -1,6 +1,8 @@
 package resources
 
 import (
+	"context"
+
 	"github.com/kernel/hypeman/lib/devices"
 )
 
@@ -12,11 +14,13 @@ type GPUResourceStatus struct {
 	UsedSlots  int                         `json:"used_slots"`         // Slots currently in use
 	Profiles   []devices.GPUProfile        `json:"profiles,omitempty"` // vGPU mode only
 	Devices    []devices.PassthroughDevice `json:"devices,omitempty"`  // passthrough mode only
+	MIGGPUs    []devices.MIGGPU            `json:"mig_gpus,omitempty"` // vGPU mode only
 }
 
-// GetGPUStatus returns the current GPU resource status.
+// GetGPUStatus returns the current GPU resource status, with reserved per
+// vGPU profile the reservations their tenants have yet to use.
 // Returns nil if no GPU is available or the mode is "none".
-func GetGPUStatus() *GPUResourceStatus {
+func GetGPUStatus(reserved map[string]int) *GPUResourceStatus {
 	mode := devices.DetectHostGPUMode()
 	if mode == devices.GPUModeNone {
 		return nil
@@ -24,7 +28,7 @@ func GetGPUStatus() *GPUResourceStatus {
 
 	switch mode {
 	case devices.GPUModeVGPU:
-		return getVGPUStatus()
+		return getVGPUStatus(reserved)
 	case devices.GPUModePassthrough:
 		return getPassthroughStatus()
 	default:
@@ -33,7 +37,7 @@ func GetGPUStatus() *GPUResourceStatus {
 }
 
 // getVGPUStatus returns GPU status for vGPU mode (SR-IOV + mdev).
-func getVGPUStatus() *GPUResourceStatus {
+func getVGPUStatus(reserved map[string]int) *GPUResourceStatus {
 	vfs, err := devices.DiscoverVFs()
 	if err != nil || len(vfs) == 0 {
 		return nil
@@ -52,12 +56,21 @@ func getVGPUStatus() *GPUResourceStatus {
 	if err != nil {
 		profiles = nil
 	}
+	migGPUs, err := devices.ListMIGGPUs(context.Background())
+	if err != nil {
+		migGPUs = nil
+	}
+	for i := range profiles {
+		profiles[i].Reserved = reserved[profiles[i].Name]
+		profiles[i].Free = max(0, profiles[i].Available-profiles[i].Reserved)
+	}
 
 	return &GPUResourceStatus{
 		Mode:       string(devices.GPUModeVGPU),
 		TotalSlots: len(vfs),
 		UsedSlots:  usedSlots,
 		Profiles:   profiles,
+		MIGGPUs:    migGPUs,
 	}
 }
 
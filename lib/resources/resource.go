@@ -8,6 +8,7 @@ import (
 	"sync"
 
 	"github.com/kernel/hypeman/cmd/api/config"
+	"github.com/kernel/hypeman/lib/diskusage"
 	"github.com/kernel/hypeman/lib/logger"
 	"github.com/kernel/hypeman/lib/paths"
 )
@@ -97,6 +98,7 @@ type InstanceAllocation struct {
 	MemoryBytes        int64  // Size + HotplugSize
 	OverlayBytes       int64  // Rootfs overlay size
 	VolumeOverlayBytes int64  // Sum of volume overlay sizes
+	ScratchBytes       int64  // Sum of scratch and swap disk sizes
 	NetworkDownloadBps int64  // Download rate limit (external→VM)
 	NetworkUploadBps   int64  // Upload rate limit (VM→external)
 	State              string // Only count running/paused/created instances
@@ -117,6 +119,13 @@ type VolumeLister interface {
 	TotalVolumeBytes(ctx context.Context) (int64, error)
 }
 
+// GPUReservationLister provides the vGPUs held by reservations.
+type GPUReservationLister interface {
+	// ReservedGPUs returns, per profile, the reserved vGPUs their tenants
+	// have yet to use.
+	ReservedGPUs(ctx context.Context) map[string]int
+}
+
 // Manager coordinates resource discovery and allocation tracking.
 type Manager struct {
 	cfg   *config.Config
@@ -126,9 +135,10 @@ type Manager struct {
 	resources map[ResourceType]Resource
 
 	// Dependencies for allocation calculations
-	instanceLister InstanceLister
-	imageLister    ImageLister
-	volumeLister   VolumeLister
+	instanceLister       InstanceLister
+	imageLister          ImageLister
+	volumeLister         VolumeLister
+	gpuReservationLister GPUReservationLister
 }
 
 // NewManager creates a new resource manager.
@@ -161,6 +171,14 @@ func (m *Manager) SetVolumeLister(lister VolumeLister) {
 	m.volumeLister = lister
 }
 
+// SetGPUReservationLister sets the lister of vGPU reservations reported in
+// the GPU status.
+func (m *Manager) SetGPUReservationLister(lister GPUReservationLister) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.gpuReservationLister = lister
+}
+
 // Initialize discovers host resources and registers them.
 // Must be called after setting listers and before using the manager.
 func (m *Manager) Initialize(ctx context.Context) error {
@@ -315,7 +333,7 @@ func (m *Manager) GetFullStatus(ctx context.Context) (*FullResourceStatus, error
 						InstanceName:       inst.Name,
 						CPU:                inst.Vcpus,
 						MemoryBytes:        inst.MemoryBytes,
-						DiskBytes:          inst.OverlayBytes + inst.VolumeBytes,
+						DiskBytes:          inst.OverlayBytes + inst.VolumeBytes + inst.ScratchBytes,
 						NetworkDownloadBps: inst.NetworkDownloadBps,
 						NetworkUploadBps:   inst.NetworkUploadBps,
 					})
@@ -325,7 +343,14 @@ func (m *Manager) GetFullStatus(ctx context.Context) (*FullResourceStatus, error
 	}
 
 	// Get GPU status
-	gpuStatus := GetGPUStatus()
+	var reserved map[string]int
+	m.mu.RLock()
+	reservationLister := m.gpuReservationLister
+	m.mu.RUnlock()
+	if reservationLister != nil {
+		reserved = reservationLister.ReservedGPUs(ctx)
+	}
+	gpuStatus := GetGPUStatus(reserved)
 
 	return &FullResourceStatus{
 		CPU:         *cpuStatus,
@@ -338,6 +363,12 @@ func (m *Manager) GetFullStatus(ctx context.Context) (*FullResourceStatus, error
 	}, nil
 }
 
+// DiskUsage measures the disk space used in the data directory by category.
+// Unlike the disk breakdown, which sums provisioned sizes, it walks the files.
+func (m *Manager) DiskUsage(ctx context.Context) (*diskusage.Usage, error) {
+	return diskusage.Measure(m.paths)
+}
+
 // CanAllocate checks if the requested amount can be allocated for a resource type.
 func (m *Manager) CanAllocate(ctx context.Context, rt ResourceType, amount int64) (bool, error) {
 	status, err := m.GetStatus(ctx, rt)
@@ -0,0 +1,273 @@
+// Package scheduler runs maintenance tasks on cron schedules, so that
+// disruptive work can be kept to maintenance windows, and lets them be
+// triggered on demand.
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/kernel/hypeman/lib/logger"
+)
+
+var (
+	ErrTaskNotFound = errors.New("task not found")
+	ErrTaskExists   = errors.New("task already registered")
+	ErrTaskRunning  = errors.New("task is already running")
+)
+
+// TaskFunc runs a task and returns a short summary of what it did
+type TaskFunc func(ctx context.Context) (string, error)
+
+// TaskStatus describes a task's schedule and its most recent run
+type TaskStatus struct {
+	Name        string
+	Description string
+	// Schedule is empty for tasks that only run when triggered
+	Schedule     string
+	Running      bool
+	NextRunAt    *time.Time
+	LastRunAt    *time.Time
+	LastDuration time.Duration
+	LastResult   string
+	LastError    string
+}
+
+type task struct {
+	name        string
+	description string
+	schedule    *Schedule
+	run         TaskFunc
+
+	running      bool
+	next         time.Time
+	lastRunAt    time.Time
+	lastDuration time.Duration
+	lastResult   string
+	lastError    string
+}
+
+// Scheduler runs registered tasks when their schedules fire. A task never
+// runs twice at once: a scheduled run that finds it still running is skipped.
+type Scheduler struct {
+	mu    sync.Mutex
+	tasks []*task // in registration order
+	now   func() time.Time
+	// wake interrupts Run's wait when a task is added
+	wake chan struct{}
+}
+
+// New creates a scheduler with no tasks
+func New() *Scheduler {
+	return &Scheduler{now: time.Now, wake: make(chan struct{}, 1)}
+}
+
+// Add registers a task. schedule is a cron expression in server local time;
+// an empty schedule registers a task that only runs when triggered.
+func (s *Scheduler) Add(name, description, schedule string, run TaskFunc) error {
+	var sched *Schedule
+	if schedule != "" {
+		var err error
+		if sched, err = Parse(schedule); err != nil {
+			return fmt.Errorf("task %s: %w", name, err)
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, t := range s.tasks {
+		if t.name == name {
+			return fmt.Errorf("%w: %s", ErrTaskExists, name)
+		}
+	}
+	t := &task{name: name, description: description, schedule: sched, run: run}
+	if sched != nil {
+		t.next = sched.Next(s.now())
+	}
+	s.tasks = append(s.tasks, t)
+
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// Run runs scheduled tasks until ctx is cancelled, then waits for the
+// runs it started to return
+func (s *Scheduler) Run(ctx context.Context) {
+	var wg sync.WaitGroup
+	defer wg.Wait()
+
+	for {
+		var timer *time.Timer
+		var wait <-chan time.Time
+		if next := s.nextRun(); !next.IsZero() {
+			timer = time.NewTimer(next.Sub(s.now()))
+			wait = timer.C
+		}
+
+		select {
+		case <-ctx.Done():
+		case <-s.wake:
+		case <-wait:
+		}
+		if timer != nil {
+			timer.Stop()
+		}
+		if ctx.Err() != nil {
+			return
+		}
+
+		for _, t := range s.due() {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				s.execute(ctx, t)
+			}()
+		}
+	}
+}
+
+// nextRun returns the earliest time a task is due, or zero if none is scheduled
+func (s *Scheduler) nextRun() time.Time {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var next time.Time
+	for _, t := range s.tasks {
+		if !t.next.IsZero() && (next.IsZero() || t.next.Before(next)) {
+			next = t.next
+		}
+	}
+	return next
+}
+
+// due marks the tasks whose time has come as running and advances their
+// schedules. Tasks still running from an earlier run are skipped.
+func (s *Scheduler) due() []*task {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now()
+	var due []*task
+	for _, t := range s.tasks {
+		if t.next.IsZero() || t.next.After(now) {
+			continue
+		}
+		t.next = t.schedule.Next(now)
+		if t.running {
+			continue
+		}
+		t.running = true
+		due = append(due, t)
+	}
+	return due
+}
+
+// Trigger runs a task now and waits for it to finish. It returns
+// ErrTaskRunning if the task is already running. A failed run is reported in
+// the returned status rather than as an error.
+func (s *Scheduler) Trigger(ctx context.Context, name string) (TaskStatus, error) {
+	s.mu.Lock()
+	t := s.find(name)
+	if t == nil {
+		s.mu.Unlock()
+		return TaskStatus{}, fmt.Errorf("%w: %s", ErrTaskNotFound, name)
+	}
+	if t.running {
+		s.mu.Unlock()
+		return TaskStatus{}, fmt.Errorf("%w: %s", ErrTaskRunning, name)
+	}
+	t.running = true
+	s.mu.Unlock()
+
+	s.execute(ctx, t)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return t.status(), nil
+}
+
+// execute runs a task that has been marked as running and records the result
+func (s *Scheduler) execute(ctx context.Context, t *task) {
+	log := logger.FromContext(ctx)
+	log.InfoContext(ctx, "maintenance task started", "task", t.name)
+
+	start := s.now()
+	result, err := t.run(ctx)
+	duration := s.now().Sub(start)
+
+	if err != nil {
+		log.ErrorContext(ctx, "maintenance task failed", "task", t.name, "duration", duration, "error", err)
+	} else {
+		log.InfoContext(ctx, "maintenance task completed", "task", t.name, "duration", duration, "result", result)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	t.running = false
+	t.lastRunAt = start
+	t.lastDuration = duration
+	t.lastResult = result
+	t.lastError = ""
+	if err != nil {
+		t.lastError = err.Error()
+	}
+}
+
+// Get returns the status of one task
+func (s *Scheduler) Get(name string) (TaskStatus, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	t := s.find(name)
+	if t == nil {
+		return TaskStatus{}, fmt.Errorf("%w: %s", ErrTaskNotFound, name)
+	}
+	return t.status(), nil
+}
+
+// List returns the status of every task, in registration order
+func (s *Scheduler) List() []TaskStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	statuses := make([]TaskStatus, 0, len(s.tasks))
+	for _, t := range s.tasks {
+		statuses = append(statuses, t.status())
+	}
+	return statuses
+}
+
+func (s *Scheduler) find(name string) *task {
+	for _, t := range s.tasks {
+		if t.name == name {
+			return t
+		}
+	}
+	return nil
+}
+
+// status must be called with the scheduler's lock held
+func (t *task) status() TaskStatus {
+	st := TaskStatus{
+		Name:         t.name,
+		Description:  t.description,
+		Running:      t.running,
+		LastDuration: t.lastDuration,
+		LastResult:   t.lastResult,
+		LastError:    t.lastError,
+	}
+	if t.schedule != nil {
+		st.Schedule = t.schedule.String()
+	}
+	if !t.next.IsZero() {
+		next := t.next
+		st.NextRunAt = &next
+	}
+	if !t.lastRunAt.IsZero() {
+		last := t.lastRunAt
+		st.LastRunAt = &last
+	}
+	return st
+}
@@ -0,0 +1,105 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTrigger(t *testing.T) {
+	s := New()
+	require.NoError(t, s.Add("ok", "succeeds", "", func(ctx context.Context) (string, error) {
+		return "did 3 things", nil
+	}))
+	require.NoError(t, s.Add("fail", "fails", "0 2 * * *", func(ctx context.Context) (string, error) {
+		return "", errors.New("boom")
+	}))
+
+	st, err := s.Trigger(context.Background(), "ok")
+	require.NoError(t, err)
+	assert.Equal(t, "did 3 things", st.LastResult)
+	assert.Empty(t, st.LastError)
+	assert.NotNil(t, st.LastRunAt)
+	assert.Nil(t, st.NextRunAt, "manual-only task has no next run")
+	assert.False(t, st.Running)
+
+	st, err = s.Trigger(context.Background(), "fail")
+	require.NoError(t, err)
+	assert.Equal(t, "boom", st.LastError)
+	assert.NotNil(t, st.NextRunAt)
+	assert.Equal(t, "0 2 * * *", st.Schedule)
+
+	_, err = s.Trigger(context.Background(), "missing")
+	assert.ErrorIs(t, err, ErrTaskNotFound)
+
+	list := s.List()
+	require.Len(t, list, 2)
+	assert.Equal(t, "ok", list[0].Name)
+	assert.Equal(t, "fail", list[1].Name)
+}
+
+func TestTriggerWhileRunning(t *testing.T) {
+	s := New()
+	started := make(chan struct{})
+	release := make(chan struct{})
+	require.NoError(t, s.Add("slow", "", "", func(ctx context.Context) (string, error) {
+		close(started)
+		<-release
+		return "", nil
+	}))
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_, _ = s.Trigger(context.Background(), "slow")
+	}()
+	<-started
+
+	st, err := s.Get("slow")
+	require.NoError(t, err)
+	assert.True(t, st.Running)
+
+	_, err = s.Trigger(context.Background(), "slow")
+	assert.ErrorIs(t, err, ErrTaskRunning)
+
+	close(release)
+	<-done
+}
+
+func TestAddInvalid(t *testing.T) {
+	s := New()
+	noop := func(ctx context.Context) (string, error) { return "", nil }
+	assert.Error(t, s.Add("bad", "", "not a schedule", noop))
+	require.NoError(t, s.Add("gc", "", "", noop))
+	assert.ErrorIs(t, s.Add("gc", "", "", noop), ErrTaskExists)
+}
+
+func TestRunFiresScheduledTasks(t *testing.T) {
+	s := New()
+	var runs atomic.Int32
+	require.NoError(t, s.Add("tick", "", "@every 1s", func(ctx context.Context) (string, error) {
+		runs.Add(1)
+		return "", nil
+	}))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		s.Run(ctx)
+		close(done)
+	}()
+
+	require.Eventually(t, func() bool { return runs.Load() >= 2 }, 5*time.Second, 50*time.Millisecond)
+	cancel()
+	<-done
+
+	st, err := s.Get("tick")
+	require.NoError(t, err)
+	assert.NotNil(t, st.LastRunAt)
+	assert.True(t, st.NextRunAt.After(*st.LastRunAt))
+}
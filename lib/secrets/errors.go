@@ -0,0 +1,14 @@
+package secrets
+
+import "errors"
+
+var (
+	ErrNotFound      = errors.New("secret not found")
+	ErrAlreadyExists = errors.New("secret already exists")
+	ErrAmbiguousName = errors.New("multiple secrets with the same name")
+	ErrInvalidName   = errors.New("invalid secret name")
+	ErrTooLarge      = errors.New("secret value is too large")
+
+	// ErrDisabled is returned when no keyring is configured
+	ErrDisabled = errors.New("secrets are not enabled")
+)
@@ -0,0 +1,210 @@
+// Package secrets stores named secret values, encrypted at rest, for
+// delivery to instances.
+package secrets
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/kernel/hypeman/lib/keyring"
+	"github.com/kernel/hypeman/lib/paths"
+	"github.com/nrednav/cuid2"
+)
+
+// validName matches secret names and IDs. Names become file names under
+// /run/secrets in the guest, so they can't contain path separators.
+var validName = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_.-]*$`)
+
+// Manager provides secret lifecycle operations
+type Manager interface {
+	ListSecrets(ctx context.Context) ([]Secret, error)
+	CreateSecret(ctx context.Context, req CreateSecretRequest) (*Secret, error)
+	GetSecret(ctx context.Context, id string) (*Secret, error)
+	GetSecretByName(ctx context.Context, name string) (*Secret, error)
+	DeleteSecret(ctx context.Context, id string) error
+
+	// GetSecretValue returns a secret's decrypted value. It's only used to
+	// deliver secrets to guests and is never exposed by the API.
+	GetSecretValue(ctx context.Context, id string) ([]byte, error)
+}
+
+type manager struct {
+	paths   *paths.Paths
+	keyring keyring.Keyring // Sealed secret values (nil = secrets disabled)
+	mu      sync.Mutex      // Serializes creates and deletes
+}
+
+// NewManager creates a new secrets manager. Secret values are sealed in
+// keys; if keys is nil, secrets are disabled: no secret can be created or
+// looked up (ErrDisabled) and none are listed.
+func NewManager(p *paths.Paths, keys keyring.Keyring) Manager {
+	return &manager{
+		paths:   p,
+		keyring: keys,
+	}
+}
+
+// ListSecrets returns all secrets. It's empty when secrets are disabled.
+func (m *manager) ListSecrets(ctx context.Context) ([]Secret, error) {
+	if m.keyring == nil {
+		return []Secret{}, nil
+	}
+
+	ids, err := listSecretIDs(m.paths)
+	if err != nil {
+		return nil, err
+	}
+
+	secrets := make([]Secret, 0, len(ids))
+	for _, id := range ids {
+		meta, err := loadMetadata(m.paths, id)
+		if err != nil {
+			// Skip secrets that can't be loaded
+			continue
+		}
+		secrets = append(secrets, *metadataToSecret(meta))
+	}
+	return secrets, nil
+}
+
+// CreateSecret stores a new secret. The value is sealed in the keyring
+// before the metadata is written, so a listed secret always has a value.
+func (m *manager) CreateSecret(ctx context.Context, req CreateSecretRequest) (*Secret, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err := m.checkCreate(req); err != nil {
+		return nil, err
+	}
+
+	id := cuid2.Generate()
+	if req.Id != nil && *req.Id != "" {
+		id = *req.Id
+	}
+
+	if err := m.keyring.Put(id, req.Value); err != nil {
+		return nil, fmt.Errorf("store secret value: %w", err)
+	}
+
+	meta := &storedMetadata{
+		Id:        id,
+		Name:      req.Name,
+		Tenant:    req.Tenant,
+		CreatedAt: time.Now().Format(time.RFC3339),
+	}
+	if err := saveMetadata(m.paths, meta); err != nil {
+		os.RemoveAll(m.paths.SecretDir(id))
+		m.keyring.Delete(id)
+		return nil, err
+	}
+
+	return metadataToSecret(meta), nil
+}
+
+// checkCreate validates a create request. Callers hold m.mu.
+func (m *manager) checkCreate(req CreateSecretRequest) error {
+	if m.keyring == nil {
+		return ErrDisabled
+	}
+	if !validName.MatchString(req.Name) {
+		return fmt.Errorf("%w: %q", ErrInvalidName, req.Name)
+	}
+	if len(req.Value) > MaxValueSize {
+		return fmt.Errorf("%w: %d bytes, limit is %d", ErrTooLarge, len(req.Value), MaxValueSize)
+	}
+	if req.Id != nil && *req.Id != "" {
+		if !validName.MatchString(*req.Id) {
+			return fmt.Errorf("%w: invalid id %q", ErrInvalidName, *req.Id)
+		}
+		if _, err := loadMetadata(m.paths, *req.Id); err == nil {
+			return ErrAlreadyExists
+		}
+	}
+	return nil
+}
+
+// GetSecret returns a secret by ID
+func (m *manager) GetSecret(ctx context.Context, id string) (*Secret, error) {
+	if m.keyring == nil {
+		return nil, ErrDisabled
+	}
+	if !validName.MatchString(id) {
+		return nil, ErrNotFound
+	}
+
+	meta, err := loadMetadata(m.paths, id)
+	if err != nil {
+		return nil, err
+	}
+	return metadataToSecret(meta), nil
+}
+
+// GetSecretByName returns a secret by name
+// Returns ErrNotFound if no secret matches, ErrAmbiguousName if multiple match
+func (m *manager) GetSecretByName(ctx context.Context, name string) (*Secret, error) {
+	secrets, err := m.ListSecrets(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var matches []Secret
+	for _, s := range secrets {
+		if s.Name == name {
+			matches = append(matches, s)
+		}
+	}
+
+	if len(matches) == 0 {
+		return nil, ErrNotFound
+	}
+	if len(matches) > 1 {
+		return nil, ErrAmbiguousName
+	}
+	return &matches[0], nil
+}
+
+// DeleteSecret deletes a secret and its sealed value. Instances it's
+// attached to keep it until they stop, but can't be started again.
+func (m *manager) DeleteSecret(ctx context.Context, id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, err := m.GetSecret(ctx, id); err != nil {
+		return err
+	}
+	if err := m.keyring.Delete(id); err != nil {
+		return err
+	}
+	if err := os.RemoveAll(m.paths.SecretDir(id)); err != nil {
+		return fmt.Errorf("remove secret directory: %w", err)
+	}
+	return nil
+}
+
+// GetSecretValue returns a secret's decrypted value
+func (m *manager) GetSecretValue(ctx context.Context, id string) ([]byte, error) {
+	if _, err := m.GetSecret(ctx, id); err != nil {
+		return nil, err
+	}
+	value, err := m.keyring.Get(id)
+	if errors.Is(err, keyring.ErrNotFound) {
+		return nil, fmt.Errorf("%w: value of secret %s is missing", ErrNotFound, id)
+	}
+	return value, err
+}
+
+// metadataToSecret converts stored metadata to a Secret
+func metadataToSecret(meta *storedMetadata) *Secret {
+	createdAt, _ := time.Parse(time.RFC3339, meta.CreatedAt)
+	return &Secret{
+		Id:        meta.Id,
+		Name:      meta.Name,
+		Tenant:    meta.Tenant,
+		CreatedAt: createdAt,
+	}
+}
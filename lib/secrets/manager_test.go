@@ -0,0 +1,104 @@
+package secrets
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/kernel/hypeman/lib/keyring"
+	"github.com/kernel/hypeman/lib/paths"
+	"github.com/samber/lo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setupTestManager(t *testing.T) (Manager, *paths.Paths) {
+	t.Helper()
+
+	p := paths.New(t.TempDir())
+	keys, err := keyring.NewFileKeyring(p.SecretKeyringDir(), bytes.Repeat([]byte{1}, keyring.MasterKeySize))
+	require.NoError(t, err)
+	return NewManager(p, keys), p
+}
+
+func TestSecretLifecycle(t *testing.T) {
+	manager, p := setupTestManager(t)
+	ctx := context.Background()
+
+	secret, err := manager.CreateSecret(ctx, CreateSecretRequest{
+		Name:   "db-password",
+		Value:  []byte("hunter2"),
+		Tenant: "team-a",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "db-password", secret.Name)
+	assert.Equal(t, "team-a", secret.Tenant)
+
+	// The value is not stored in the clear anywhere in the data directory
+	err = filepath.WalkDir(p.DataDir(), func(path string, d os.DirEntry, err error) error {
+		require.NoError(t, err)
+		if !d.IsDir() {
+			data, err := os.ReadFile(path)
+			require.NoError(t, err)
+			assert.NotContains(t, string(data), "hunter2", path)
+		}
+		return nil
+	})
+	require.NoError(t, err)
+
+	value, err := manager.GetSecretValue(ctx, secret.Id)
+	require.NoError(t, err)
+	assert.Equal(t, []byte("hunter2"), value)
+
+	byName, err := manager.GetSecretByName(ctx, "db-password")
+	require.NoError(t, err)
+	assert.Equal(t, secret.Id, byName.Id)
+
+	list, err := manager.ListSecrets(ctx)
+	require.NoError(t, err)
+	assert.Len(t, list, 1)
+
+	require.NoError(t, manager.DeleteSecret(ctx, secret.Id))
+	_, err = manager.GetSecret(ctx, secret.Id)
+	assert.ErrorIs(t, err, ErrNotFound)
+	_, err = manager.GetSecretValue(ctx, secret.Id)
+	assert.ErrorIs(t, err, ErrNotFound)
+	assert.ErrorIs(t, manager.DeleteSecret(ctx, secret.Id), ErrNotFound)
+}
+
+func TestCreateSecret_Validation(t *testing.T) {
+	manager, _ := setupTestManager(t)
+	ctx := context.Background()
+
+	_, err := manager.CreateSecret(ctx, CreateSecretRequest{Name: "../escape", Value: []byte("x")})
+	assert.ErrorIs(t, err, ErrInvalidName)
+
+	_, err = manager.CreateSecret(ctx, CreateSecretRequest{Name: "big", Value: make([]byte, MaxValueSize+1)})
+	assert.ErrorIs(t, err, ErrTooLarge)
+
+	_, err = manager.CreateSecret(ctx, CreateSecretRequest{Name: "token", Id: lo.ToPtr("sec-1"), Value: []byte("x")})
+	require.NoError(t, err)
+	_, err = manager.CreateSecret(ctx, CreateSecretRequest{Name: "token", Id: lo.ToPtr("sec-1"), Value: []byte("y")})
+	assert.ErrorIs(t, err, ErrAlreadyExists)
+
+	// Names aren't unique, but then can't be used to look secrets up
+	_, err = manager.CreateSecret(ctx, CreateSecretRequest{Name: "token", Value: []byte("z")})
+	require.NoError(t, err)
+	_, err = manager.GetSecretByName(ctx, "token")
+	assert.ErrorIs(t, err, ErrAmbiguousName)
+}
+
+func TestSecrets_Disabled(t *testing.T) {
+	manager := NewManager(paths.New(t.TempDir()), nil)
+	ctx := context.Background()
+
+	_, err := manager.CreateSecret(ctx, CreateSecretRequest{Name: "token", Value: []byte("x")})
+	assert.ErrorIs(t, err, ErrDisabled)
+	_, err = manager.GetSecret(ctx, "token")
+	assert.ErrorIs(t, err, ErrDisabled)
+	list, err := manager.ListSecrets(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, list)
+}
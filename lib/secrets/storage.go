@@ -0,0 +1,79 @@
+package secrets
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/kernel/hypeman/lib/paths"
+)
+
+// Filesystem structure:
+// {dataDir}/secrets/{secret-id}/
+//   metadata.json   # Secret metadata
+// {dataDir}/keyring/secrets/
+//   {secret-id}.key # Secret value, sealed under the master key
+
+// storedMetadata represents secret metadata that is persisted to disk
+type storedMetadata struct {
+	Id        string `json:"id"`
+	Name      string `json:"name"`
+	Tenant    string `json:"tenant,omitempty"`
+	CreatedAt string `json:"created_at"` // RFC3339 format
+}
+
+// loadMetadata loads secret metadata from disk
+func loadMetadata(p *paths.Paths, id string) (*storedMetadata, error) {
+	data, err := os.ReadFile(p.SecretMetadata(id))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("read metadata: %w", err)
+	}
+
+	var meta storedMetadata
+	if err := json.Unmarshal(data, &meta); err != nil {
+		return nil, fmt.Errorf("unmarshal metadata: %w", err)
+	}
+	return &meta, nil
+}
+
+// saveMetadata saves secret metadata to disk
+func saveMetadata(p *paths.Paths, meta *storedMetadata) error {
+	if err := os.MkdirAll(p.SecretDir(meta.Id), 0755); err != nil {
+		return fmt.Errorf("create secret directory: %w", err)
+	}
+
+	data, err := json.MarshalIndent(meta, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshal metadata: %w", err)
+	}
+	if err := os.WriteFile(p.SecretMetadata(meta.Id), data, 0644); err != nil {
+		return fmt.Errorf("write metadata: %w", err)
+	}
+	return nil
+}
+
+// listSecretIDs returns all secret IDs by scanning the secrets directory
+func listSecretIDs(p *paths.Paths) ([]string, error) {
+	entries, err := os.ReadDir(p.SecretsDir())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("read secrets directory: %w", err)
+	}
+
+	var ids []string
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		if _, err := os.Stat(filepath.Join(p.SecretsDir(), entry.Name(), "metadata.json")); err == nil {
+			ids = append(ids, entry.Name())
+		}
+	}
+	return ids, nil
+}
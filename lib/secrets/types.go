@@ -0,0 +1,23 @@
+package secrets
+
+import "time"
+
+// MaxValueSize is the largest secret value accepted, in bytes
+const MaxValueSize = 64 * 1024
+
+// Secret is a named value stored encrypted at rest. The value itself is
+// only returned by GetSecretValue, for delivery to a guest.
+type Secret struct {
+	Id        string
+	Name      string
+	Tenant    string // Optional tenant label for access scoping
+	CreatedAt time.Time
+}
+
+// CreateSecretRequest is the domain request for creating a secret
+type CreateSecretRequest struct {
+	Name   string
+	Value  []byte
+	Id     *string // Optional custom ID
+	Tenant string  // Optional tenant label
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	pb "github.com/kernel/hypeman/lib/guest"
+	"golang.org/x/sys/unix"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// GetAppStatus reports the application's exit, as recorded by init
+func (s *guestServer) GetAppStatus(ctx context.Context, req *pb.GetAppStatusRequest) (*pb.GetAppStatusResponse, error) {
+	data, err := os.ReadFile(pb.AppExitFile)
+	if os.IsNotExist(err) {
+		return &pb.GetAppStatusResponse{}, nil
+	}
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "read %s: %v", pb.AppExitFile, err)
+	}
+
+	var exit pb.AppExit
+	if err := json.Unmarshal(data, &exit); err != nil {
+		return nil, status.Errorf(codes.Internal, "parse %s: %v", pb.AppExitFile, err)
+	}
+	return &pb.GetAppStatusResponse{
+		Exited:   true,
+		ExitCode: int32(exit.ExitCode),
+		Signal:   exit.Signal,
+		ExitedAt: exit.ExitedAt.Unix(),
+	}, nil
+}
+
+// appExitPollInterval is how often StopApp checks whether the application exited
+const appExitPollInterval = 100 * time.Millisecond
+
+// StopApp sends SIGTERM to the application started by init and waits for
+// init to record its exit. Filesystems are synced before answering, so the
+// host can power the VM off without losing writes.
+func (s *guestServer) StopApp(ctx context.Context, req *pb.StopAppRequest) (*pb.StopAppResponse, error) {
+	defer unix.Sync()
+
+	if _, err := os.Stat(pb.AppExitFile); err == nil {
+		return &pb.StopAppResponse{Exited: true}, nil
+	}
+	data, err := os.ReadFile(pb.AppPidFile)
+	if os.IsNotExist(err) {
+		return nil, status.Error(codes.FailedPrecondition, "application not supervised by init")
+	}
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "read %s: %v", pb.AppPidFile, err)
+	}
+	pid, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "parse %s: %v", pb.AppPidFile, err)
+	}
+
+	log.Printf("[guest-agent] stop-app: pid=%d timeout=%ds", pid, req.TimeoutSeconds)
+	if err := unix.Kill(pid, unix.SIGTERM); err != nil && err != unix.ESRCH {
+		return nil, status.Errorf(codes.Internal, "signal application: %v", err)
+	}
+
+	deadline := time.Now().Add(time.Duration(req.TimeoutSeconds) * time.Second)
+	for {
+		if _, err := os.Stat(pb.AppExitFile); err == nil {
+			return &pb.StopAppResponse{Exited: true}, nil
+		}
+		if time.Now().After(deadline) {
+			return &pb.StopAppResponse{}, nil
+		}
+		select {
+		case <-ctx.Done():
+			return nil, status.FromContextError(ctx.Err()).Err()
+		case <-time.After(appExitPollInterval):
+		}
+	}
+}
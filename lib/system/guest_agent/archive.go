@@ -0,0 +1,490 @@
+package main
+
+import (
+	"archive/tar"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	pb "github.com/kernel/hypeman/lib/guest"
+	"golang.org/x/sys/unix"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// archiveChunkSize is the size of tar data chunks streamed to the host
+	archiveChunkSize = 32 * 1024
+
+	// archiveProgressInterval throttles progress reports
+	archiveProgressInterval = 250 * time.Millisecond
+
+	// xattrPAXPrefix is the PAX record prefix GNU tar and bsdtar use for xattrs
+	xattrPAXPrefix = "SCHILY.xattr."
+)
+
+// archiveProgress tracks and throttles progress reports
+type archiveProgress struct {
+	files, bytes int64
+	lastSent     time.Time
+}
+
+func (p *archiveProgress) due() bool {
+	return time.Since(p.lastSent) >= archiveProgressInterval
+}
+
+func (p *archiveProgress) report(done bool) *pb.CopyArchiveProgress {
+	p.lastSent = time.Now()
+	return &pb.CopyArchiveProgress{Files: p.files, Bytes: p.bytes, Done: done}
+}
+
+// CopyArchiveToGuest extracts a tar stream into a directory, preserving mode,
+// ownership, timestamps and xattrs. Entries are filtered by the include and
+// exclude globs of the first message.
+func (s *guestServer) CopyArchiveToGuest(stream pb.GuestService_CopyArchiveToGuestServer) error {
+	first, err := stream.Recv()
+	if err != nil {
+		return status.Errorf(codes.InvalidArgument, "receive start: %v", err)
+	}
+	log.Printf("[guest-agent] copy-archive-to-guest: path=%s include=%v exclude=%v", first.Path, first.Include, first.Exclude)
+
+	if !filepath.IsAbs(first.Path) {
+		return status.Errorf(codes.InvalidArgument, "path must be absolute: %q", first.Path)
+	}
+	filter, err := pb.NewPathFilter(first.Include, first.Exclude)
+	if err != nil {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+	if info, err := os.Stat(first.Path); err == nil && !info.IsDir() {
+		return status.Errorf(codes.FailedPrecondition, "destination %s is not a directory", first.Path)
+	}
+	if err := os.MkdirAll(first.Path, 0755); err != nil {
+		return status.Errorf(codes.Internal, "create destination: %v", err)
+	}
+
+	// Feed received chunks to the tar reader
+	pr, pw := io.Pipe()
+	go func() {
+		if len(first.Data) > 0 {
+			if _, err := pw.Write(first.Data); err != nil {
+				return
+			}
+		}
+		for {
+			req, err := stream.Recv()
+			if err == io.EOF {
+				pw.Close()
+				return
+			}
+			if err != nil {
+				pw.CloseWithError(err)
+				return
+			}
+			if _, err := pw.Write(req.Data); err != nil {
+				return
+			}
+		}
+	}()
+	defer pr.Close()
+
+	progress := &archiveProgress{lastSent: time.Now()}
+	if err := extractArchive(tar.NewReader(pr), first.Path, filter, progress, func() error {
+		return stream.Send(progress.report(false))
+	}); err != nil {
+		return err
+	}
+
+	// Consume the end-of-archive padding so the host's sends complete
+	if _, err := io.Copy(io.Discard, pr); err != nil {
+		return status.Errorf(codes.Aborted, "receive: %v", err)
+	}
+
+	log.Printf("[guest-agent] copy-archive-to-guest complete: %d entries, %d bytes to %s", progress.files, progress.bytes, first.Path)
+	return stream.Send(progress.report(true))
+}
+
+// extractArchive writes the entries of tr below dest. Directory timestamps
+// are applied last so that extracting their contents does not reset them.
+func extractArchive(tr *tar.Reader, dest string, filter *pb.PathFilter, progress *archiveProgress, report func() error) error {
+	type dirTimes struct {
+		path  string
+		mtime time.Time
+	}
+	var dirs []dirTimes
+	// Symlinks extracted so far; later entries must not be written through them
+	symlinks := make(map[string]bool)
+
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return status.Errorf(codes.InvalidArgument, "read archive: %v", err)
+		}
+
+		// Clean against a virtual root so entries can't escape dest
+		rel := strings.TrimPrefix(path.Clean("/"+hdr.Name), "/")
+		if rel == "" || !filter.Match(rel) {
+			continue
+		}
+		target := filepath.Join(dest, filepath.FromSlash(rel))
+		if parent := symlinkParent(symlinks, rel); parent != "" {
+			return status.Errorf(codes.InvalidArgument, "extract %s: parent %s is a symlink from the archive", rel, parent)
+		}
+
+		if err := extractEntry(tr, hdr, dest, target); err != nil {
+			return status.Errorf(codes.Internal, "extract %s: %v", rel, err)
+		}
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			dirs = append(dirs, dirTimes{target, hdr.ModTime})
+		case tar.TypeSymlink:
+			symlinks[rel] = true
+		}
+
+		progress.files++
+		if hdr.Typeflag == tar.TypeReg {
+			progress.bytes += hdr.Size
+		}
+		if progress.due() {
+			if err := report(); err != nil {
+				return err
+			}
+		}
+	}
+
+	for i := len(dirs) - 1; i >= 0; i-- {
+		os.Chtimes(dirs[i].path, dirs[i].mtime, dirs[i].mtime)
+	}
+	return nil
+}
+
+func extractEntry(tr *tar.Reader, hdr *tar.Header, dest, target string) error {
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return err
+	}
+	mode := fs.FileMode(hdr.Mode).Perm()
+
+	switch hdr.Typeflag {
+	case tar.TypeDir:
+		if info, err := os.Lstat(target); err == nil && !info.IsDir() {
+			if err := os.Remove(target); err != nil {
+				return err
+			}
+		}
+		if err := os.MkdirAll(target, mode); err != nil {
+			return err
+		}
+	case tar.TypeReg:
+		if err := removeNonDir(target); err != nil {
+			return err
+		}
+		f, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, mode)
+		if err != nil {
+			return err
+		}
+		if _, err := io.Copy(f, tr); err != nil {
+			f.Close()
+			return err
+		}
+		if err := f.Close(); err != nil {
+			return err
+		}
+	case tar.TypeSymlink:
+		if err := removeNonDir(target); err != nil {
+			return err
+		}
+		if err := os.Symlink(hdr.Linkname, target); err != nil {
+			return err
+		}
+	case tar.TypeLink:
+		if err := removeNonDir(target); err != nil {
+			return err
+		}
+		source := filepath.Join(dest, filepath.FromSlash(path.Clean("/"+hdr.Linkname)))
+		if err := os.Link(source, target); err != nil {
+			return err
+		}
+		return nil
+	default:
+		log.Printf("[guest-agent] copy-archive-to-guest: skipping %s (unsupported type %q)", hdr.Name, hdr.Typeflag)
+		return nil
+	}
+
+	// Ownership first: chown clears setuid/setgid bits
+	if err := os.Lchown(target, hdr.Uid, hdr.Gid); err != nil {
+		log.Printf("[guest-agent] warning: failed to set ownership on %s: %v", target, err)
+	}
+	if hdr.Typeflag != tar.TypeSymlink {
+		if err := os.Chmod(target, mode|tarSpecialBits(hdr.Mode)); err != nil {
+			return err
+		}
+	}
+	for key, value := range hdr.PAXRecords {
+		if name, ok := strings.CutPrefix(key, xattrPAXPrefix); ok {
+			if err := unix.Lsetxattr(target, name, []byte(value), 0); err != nil {
+				log.Printf("[guest-agent] warning: failed to set xattr %s on %s: %v", name, target, err)
+			}
+		}
+	}
+	if hdr.Typeflag == tar.TypeReg {
+		os.Chtimes(target, hdr.ModTime, hdr.ModTime)
+	}
+	return nil
+}
+
+// symlinkParent returns the first parent of rel that is in symlinks
+func symlinkParent(symlinks map[string]bool, rel string) string {
+	if len(symlinks) == 0 {
+		return ""
+	}
+	for p := path.Dir(rel); p != "."; p = path.Dir(p) {
+		if symlinks[p] {
+			return p
+		}
+	}
+	return ""
+}
+
+// removeNonDir clears an existing non-directory entry so it can be replaced
+func removeNonDir(target string) error {
+	info, err := os.Lstat(target)
+	if err != nil {
+		return nil
+	}
+	if info.IsDir() {
+		return fmt.Errorf("%s is a directory", target)
+	}
+	return os.Remove(target)
+}
+
+// tarSpecialBits converts the setuid, setgid and sticky bits of a tar mode
+func tarSpecialBits(mode int64) fs.FileMode {
+	var m fs.FileMode
+	if mode&04000 != 0 {
+		m |= fs.ModeSetuid
+	}
+	if mode&02000 != 0 {
+		m |= fs.ModeSetgid
+	}
+	if mode&01000 != 0 {
+		m |= fs.ModeSticky
+	}
+	return m
+}
+
+// CopyArchiveFromGuest streams a file or directory as a tar archive. Entry
+// names are relative to the parent of the requested path.
+func (s *guestServer) CopyArchiveFromGuest(req *pb.CopyArchiveFromGuestRequest, stream pb.GuestService_CopyArchiveFromGuestServer) error {
+	log.Printf("[guest-agent] copy-archive-from-guest: path=%s include=%v exclude=%v follow_links=%v",
+		req.Path, req.Include, req.Exclude, req.FollowLinks)
+
+	if !filepath.IsAbs(req.Path) {
+		return status.Errorf(codes.InvalidArgument, "path must be absolute: %q", req.Path)
+	}
+	filter, err := pb.NewPathFilter(req.Include, req.Exclude)
+	if err != nil {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+	root := filepath.Clean(req.Path)
+	base := filepath.Base(root)
+	if _, err := os.Lstat(root); err != nil {
+		if os.IsNotExist(err) {
+			return status.Errorf(codes.NotFound, "path not found: %s", root)
+		}
+		return status.Errorf(codes.FailedPrecondition, "stat %s: %v", root, err)
+	}
+	if req.FollowLinks {
+		// Walk the target of a symlinked root, keeping the requested name
+		if root, err = filepath.EvalSymlinks(root); err != nil {
+			return status.Errorf(codes.FailedPrecondition, "resolve %s: %v", req.Path, err)
+		}
+	}
+
+	progress := &archiveProgress{lastSent: time.Now()}
+	out := &archiveStreamWriter{stream: stream, progress: progress}
+	tw := tar.NewWriter(out)
+	hardlinks := make(map[[2]uint64]string)
+
+	err = filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.Type()&fs.ModeSocket != 0 {
+			// Sockets can't be archived
+			return nil
+		}
+		rel, _ := filepath.Rel(root, p)
+		name := path.Join(base, filepath.ToSlash(rel))
+		if filter.Excluded(name) {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !filter.Match(name) {
+			// Keep walking: entries below may still be included
+			return nil
+		}
+		return writeArchiveEntry(tw, p, name, req.FollowLinks, hardlinks, progress)
+	})
+	if err == nil {
+		err = tw.Close()
+	}
+	if err == nil {
+		err = out.flush()
+	}
+	if err != nil {
+		if _, ok := status.FromError(err); ok {
+			return err
+		}
+		return status.Errorf(codes.Internal, "archive %s: %v", root, err)
+	}
+
+	log.Printf("[guest-agent] copy-archive-from-guest complete: %d entries, %d bytes from %s", progress.files, progress.bytes, root)
+	return stream.Send(&pb.CopyArchiveFromGuestResponse{Progress: progress.report(true)})
+}
+
+// writeArchiveEntry writes the header, xattrs and content of one file
+func writeArchiveEntry(tw *tar.Writer, p, name string, followLinks bool, hardlinks map[[2]uint64]string, progress *archiveProgress) error {
+	info, err := os.Lstat(p)
+	if err != nil {
+		return err
+	}
+	if followLinks && info.Mode()&os.ModeSymlink != 0 {
+		if info, err = os.Stat(p); err != nil {
+			return err
+		}
+	}
+
+	var link string
+	if info.Mode()&os.ModeSymlink != 0 {
+		if link, err = os.Readlink(p); err != nil {
+			return err
+		}
+	}
+	hdr, err := tar.FileInfoHeader(info, link)
+	if err != nil {
+		return err
+	}
+	hdr.Name = name
+	if info.IsDir() {
+		hdr.Name += "/"
+	}
+
+	// Store repeated inodes as hard links to the first name seen
+	if st, ok := info.Sys().(*syscall.Stat_t); ok && info.Mode().IsRegular() && st.Nlink > 1 {
+		key := [2]uint64{uint64(st.Dev), st.Ino}
+		if first, ok := hardlinks[key]; ok {
+			hdr.Typeflag = tar.TypeLink
+			hdr.Linkname = first
+			hdr.Size = 0
+		} else {
+			hardlinks[key] = name
+		}
+	}
+
+	if xattrs := readXattrs(p, !followLinks); len(xattrs) > 0 {
+		hdr.PAXRecords = make(map[string]string, len(xattrs))
+		for k, v := range xattrs {
+			hdr.PAXRecords[xattrPAXPrefix+k] = v
+		}
+		hdr.Format = tar.FormatPAX
+	}
+
+	if err := tw.WriteHeader(hdr); err != nil {
+		return err
+	}
+	progress.files++
+	if hdr.Typeflag == tar.TypeReg {
+		f, err := os.Open(p)
+		if err != nil {
+			return err
+		}
+		n, err := io.Copy(tw, f)
+		f.Close()
+		progress.bytes += n
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// readXattrs returns the extended attributes of p; failures yield none
+func readXattrs(p string, noFollow bool) map[string]string {
+	list, get := unix.Listxattr, unix.Getxattr
+	if noFollow {
+		list, get = unix.Llistxattr, unix.Lgetxattr
+	}
+	size, err := list(p, nil)
+	if err != nil || size == 0 {
+		return nil
+	}
+	buf := make([]byte, size)
+	if size, err = list(p, buf); err != nil {
+		return nil
+	}
+	xattrs := make(map[string]string)
+	for _, name := range strings.Split(strings.TrimRight(string(buf[:size]), "\x00"), "\x00") {
+		n, err := get(p, name, nil)
+		if err != nil {
+			continue
+		}
+		value := make([]byte, n)
+		if n, err = get(p, name, value); err != nil {
+			if !errors.Is(err, unix.ENODATA) {
+				log.Printf("[guest-agent] warning: failed to read xattr %s on %s: %v", name, p, err)
+			}
+			continue
+		}
+		xattrs[name] = string(value[:n])
+	}
+	return xattrs
+}
+
+// archiveStreamWriter sends tar output in chunks, attaching throttled progress
+type archiveStreamWriter struct {
+	stream   pb.GuestService_CopyArchiveFromGuestServer
+	progress *archiveProgress
+	buf      []byte
+}
+
+func (w *archiveStreamWriter) Write(p []byte) (int, error) {
+	n := len(p)
+	for len(p) > 0 {
+		take := min(archiveChunkSize-len(w.buf), len(p))
+		w.buf = append(w.buf, p[:take]...)
+		p = p[take:]
+		if len(w.buf) == archiveChunkSize {
+			if err := w.flush(); err != nil {
+				return 0, err
+			}
+		}
+	}
+	return n, nil
+}
+
+func (w *archiveStreamWriter) flush() error {
+	if len(w.buf) == 0 {
+		return nil
+	}
+	resp := &pb.CopyArchiveFromGuestResponse{Data: w.buf}
+	if w.progress.due() {
+		resp.Progress = w.progress.report(false)
+	}
+	if err := w.stream.Send(resp); err != nil {
+		return err
+	}
+	w.buf = w.buf[:0]
+	return nil
+}
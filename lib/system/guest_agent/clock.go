@@ -0,0 +1,23 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	pb "github.com/kernel/hypeman/lib/guest"
+	"golang.org/x/sys/unix"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// SyncClock steps the guest's wall clock to the host's time. The clock
+// stands still while the VM is in standby, so after a restore it lags by
+// however long the snapshot sat on disk.
+func (s *guestServer) SyncClock(ctx context.Context, req *pb.SyncClockRequest) (*pb.SyncClockResponse, error) {
+	skew := time.Now().UnixNano() - req.HostTimeUnixNano
+	ts := unix.NsecToTimespec(req.HostTimeUnixNano)
+	if err := unix.ClockSettime(unix.CLOCK_REALTIME, &ts); err != nil {
+		return nil, status.Errorf(codes.Internal, "set clock: %v", err)
+	}
+	return &pb.SyncClockResponse{SkewNanos: skew}, nil
+}
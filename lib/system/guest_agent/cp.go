@@ -1,12 +1,16 @@
 package main
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
+	"hash"
 	"io"
 	"io/fs"
 	"log"
 	"os"
 	"path/filepath"
+	"strings"
 	"syscall"
 	"time"
 
@@ -89,17 +93,18 @@ func (s *guestServer) CopyToGuest(stream pb.GuestService_CopyToGuestServer) erro
 		})
 	}
 
-	// Create file
-	file, err := os.OpenFile(start.Path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, fs.FileMode(start.Mode))
+	// Create file, or reopen it to resume after the bytes already written
+	file, hasher, err := openCopyTarget(start)
 	if err != nil {
 		return stream.SendAndClose(&pb.CopyToGuestResponse{
 			Success: false,
-			Error:   fmt.Sprintf("create file: %v", err),
+			Error:   err.Error(),
 		})
 	}
 	defer file.Close()
 
 	var bytesWritten int64
+	var end *pb.CopyToGuestEnd
 
 	// Receive data chunks
 	for {
@@ -122,14 +127,27 @@ func (s *guestServer) CopyToGuest(stream pb.GuestService_CopyToGuestServer) erro
 					Error:   fmt.Sprintf("write: %v", err),
 				})
 			}
+			hasher.Write(data[:n])
 			bytesWritten += int64(n)
 		}
 
-		if req.GetEnd() != nil {
+		if end = req.GetEnd(); end != nil {
 			break
 		}
 	}
 
+	// Verify the complete file against the sender's checksum
+	sum := hex.EncodeToString(hasher.Sum(nil))
+	if expected := end.GetSha256(); expected != "" && !strings.EqualFold(expected, sum) {
+		log.Printf("[guest-agent] copy-to-guest checksum mismatch for %s: expected %s, got %s", start.Path, expected, sum)
+		return stream.SendAndClose(&pb.CopyToGuestResponse{
+			Success:      false,
+			Error:        fmt.Sprintf("checksum mismatch: expected %s, got %s", expected, sum),
+			BytesWritten: bytesWritten,
+			Sha256:       sum,
+		})
+	}
+
 	// Set modification time if provided
 	if start.Mtime > 0 {
 		mtime := time.Unix(start.Mtime, 0)
@@ -145,17 +163,58 @@ func (s *guestServer) CopyToGuest(stream pb.GuestService_CopyToGuestServer) erro
 		}
 	}
 
-	log.Printf("[guest-agent] copy-to-guest complete: %d bytes written to %s", bytesWritten, start.Path)
+	log.Printf("[guest-agent] copy-to-guest complete: %d bytes written to %s (offset %d)", bytesWritten, start.Path, start.Offset)
 
 	return stream.SendAndClose(&pb.CopyToGuestResponse{
 		Success:      true,
 		BytesWritten: bytesWritten,
+		Sha256:       sum,
 	})
 }
 
+// openCopyTarget opens the destination of a file copy. With an offset, the
+// existing file is cut back to offset bytes and its prefix is hashed so the
+// returned hash covers the complete file once the rest has been written.
+func openCopyTarget(start *pb.CopyToGuestStart) (*os.File, hash.Hash, error) {
+	h := sha256.New()
+	if start.Offset <= 0 {
+		file, err := os.OpenFile(start.Path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, fs.FileMode(start.Mode))
+		if err != nil {
+			return nil, nil, fmt.Errorf("create file: %v", err)
+		}
+		return file, h, nil
+	}
+
+	file, err := os.OpenFile(start.Path, os.O_RDWR, 0)
+	if err != nil {
+		return nil, nil, fmt.Errorf("open file to resume: %v", err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, nil, fmt.Errorf("stat file to resume: %v", err)
+	}
+	if info.Size() < start.Offset {
+		file.Close()
+		return nil, nil, fmt.Errorf("cannot resume at offset %d: file has %d bytes", start.Offset, info.Size())
+	}
+	if _, err := io.CopyN(h, file, start.Offset); err != nil {
+		file.Close()
+		return nil, nil, fmt.Errorf("read file to resume: %v", err)
+	}
+	if err := file.Truncate(start.Offset); err != nil {
+		file.Close()
+		return nil, nil, fmt.Errorf("truncate file to resume: %v", err)
+	}
+	if err := file.Chmod(fs.FileMode(start.Mode)); err != nil {
+		log.Printf("[guest-agent] warning: failed to set mode on %s: %v", start.Path, err)
+	}
+	return file, h, nil
+}
+
 // CopyFromGuest handles copying files from the guest filesystem
 func (s *guestServer) CopyFromGuest(req *pb.CopyFromGuestRequest, stream pb.GuestService_CopyFromGuestServer) error {
-	log.Printf("[guest-agent] copy-from-guest: path=%s follow_links=%v", req.Path, req.FollowLinks)
+	log.Printf("[guest-agent] copy-from-guest: path=%s follow_links=%v offset=%d", req.Path, req.FollowLinks, req.Offset)
 
 	// Stat the source path
 	var info os.FileInfo
@@ -176,17 +235,29 @@ func (s *guestServer) CopyFromGuest(req *pb.CopyFromGuestRequest, stream pb.Gues
 		})
 	}
 
+	if req.Offset > 0 && !info.Mode().IsRegular() {
+		return stream.Send(&pb.CopyFromGuestResponse{
+			Response: &pb.CopyFromGuestResponse_Error{
+				Error: &pb.CopyFromGuestError{
+					Message: "offset is only supported for regular files",
+					Path:    req.Path,
+				},
+			},
+		})
+	}
+
 	if info.IsDir() {
 		// Walk directory and stream all files
 		return s.copyFromGuestDir(req.Path, req.FollowLinks, stream)
 	}
 
 	// Single file
-	return s.copyFromGuestFile(req.Path, "", info, req.FollowLinks, stream, true)
+	return s.copyFromGuestFile(req.Path, "", info, req.FollowLinks, req.Offset, stream, true)
 }
 
 // copyFromGuestFile streams a single file
-func (s *guestServer) copyFromGuestFile(fullPath, relativePath string, info os.FileInfo, followLinks bool, stream pb.GuestService_CopyFromGuestServer, isFinal bool) error {
+// Data starts at offset, but the checksum in the end marker covers the whole file.
+func (s *guestServer) copyFromGuestFile(fullPath, relativePath string, info os.FileInfo, followLinks bool, offset int64, stream pb.GuestService_CopyFromGuestServer, isFinal bool) error {
 	if relativePath == "" {
 		relativePath = filepath.Base(fullPath)
 	}
@@ -256,10 +327,26 @@ func (s *guestServer) copyFromGuestFile(fullPath, relativePath string, info os.F
 	}
 	defer file.Close()
 
+	// Hash the bytes the client already has without sending them
+	hasher := sha256.New()
+	if offset > 0 {
+		if _, err := io.CopyN(hasher, file, offset); err != nil {
+			return stream.Send(&pb.CopyFromGuestResponse{
+				Response: &pb.CopyFromGuestResponse_Error{
+					Error: &pb.CopyFromGuestError{
+						Message: fmt.Sprintf("cannot resume at offset %d: %v", offset, err),
+						Path:    fullPath,
+					},
+				},
+			})
+		}
+	}
+
 	buf := make([]byte, 32*1024)
 	for {
 		n, err := file.Read(buf)
 		if n > 0 {
+			hasher.Write(buf[:n])
 			if sendErr := stream.Send(&pb.CopyFromGuestResponse{
 				Response: &pb.CopyFromGuestResponse_Data{Data: buf[:n]},
 			}); sendErr != nil {
@@ -283,7 +370,10 @@ func (s *guestServer) copyFromGuestFile(fullPath, relativePath string, info os.F
 
 	// Send end marker
 	return stream.Send(&pb.CopyFromGuestResponse{
-		Response: &pb.CopyFromGuestResponse_End{End: &pb.CopyFromGuestEnd{Final: isFinal}},
+		Response: &pb.CopyFromGuestResponse_End{End: &pb.CopyFromGuestEnd{
+			Final:  isFinal,
+			Sha256: hex.EncodeToString(hasher.Sum(nil)),
+		}},
 	})
 }
 
@@ -390,7 +480,7 @@ func (s *guestServer) copyFromGuestDir(rootPath string, followLinks bool, stream
 				return err
 			}
 		} else {
-			if err := s.copyFromGuestFile(e.fullPath, e.relativePath, e.info, followLinks, stream, isFinal); err != nil {
+			if err := s.copyFromGuestFile(e.fullPath, e.relativePath, e.info, followLinks, 0, stream, isFinal); err != nil {
 				return err
 			}
 		}
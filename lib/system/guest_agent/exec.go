@@ -7,11 +7,14 @@ import (
 	"log"
 	"os"
 	"os/exec"
+	"strings"
 	"sync"
+	"syscall"
 	"time"
 
 	"github.com/creack/pty"
 	pb "github.com/kernel/hypeman/lib/guest"
+	"golang.org/x/sys/unix"
 )
 
 // Exec handles command execution with bidirectional streaming
@@ -68,6 +71,9 @@ func (s *guestServer) executeNoTTY(ctx context.Context, stream pb.GuestService_E
 		cmd.Dir = start.Cwd
 	}
 
+	// Own process group, so signals reach the command's children too
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+
 	stdin, _ := cmd.StdinPipe()
 	stdout, _ := cmd.StdoutPipe()
 	stderr, _ := cmd.StderrPipe()
@@ -83,7 +89,7 @@ func (s *guestServer) executeNoTTY(ctx context.Context, stream pb.GuestService_E
 	var wg sync.WaitGroup
 	var stdoutData, stderrData []byte
 
-	// Handle stdin in background
+	// Handle stdin and signals in background
 	go func() {
 		defer stdin.Close()
 		for {
@@ -94,6 +100,9 @@ func (s *guestServer) executeNoTTY(ctx context.Context, stream pb.GuestService_E
 			if data := req.GetStdin(); data != nil {
 				stdin.Write(data)
 			}
+			if name := req.GetSignal(); name != "" {
+				signalProcessGroup(cmd.Process.Pid, name)
+			}
 		}
 	}()
 
@@ -178,7 +187,11 @@ func (s *guestServer) executeTTY(ctx context.Context, stream pb.GuestService_Exe
 	}
 
 	// Start with PTY
-	ptmx, err := pty.Start(cmd)
+	var size *pty.Winsize
+	if ws := start.GetSize(); ws.GetRows() > 0 && ws.GetCols() > 0 {
+		size = &pty.Winsize{Rows: uint16(ws.Rows), Cols: uint16(ws.Cols)}
+	}
+	ptmx, err := pty.StartWithSize(cmd, size)
 	if err != nil {
 		return fmt.Errorf("start pty: %w", err)
 	}
@@ -190,7 +203,7 @@ func (s *guestServer) executeTTY(ctx context.Context, stream pb.GuestService_Exe
 	// Use WaitGroup to ensure all output is sent before exit code
 	var wg sync.WaitGroup
 
-	// Handle stdin in background
+	// Handle stdin, resizes and signals in background
 	go func() {
 		for {
 			req, err := stream.Recv()
@@ -201,6 +214,19 @@ func (s *guestServer) executeTTY(ctx context.Context, stream pb.GuestService_Exe
 			if data := req.GetStdin(); data != nil {
 				ptmx.Write(data)
 			}
+			if ws := req.GetResize(); ws != nil {
+				// The kernel sends SIGWINCH to the terminal's foreground process group
+				pty.Setsize(ptmx, &pty.Winsize{Rows: uint16(ws.Rows), Cols: uint16(ws.Cols)})
+			}
+			if name := req.GetSignal(); name != "" {
+				// Signal the foreground job, like the terminal would for its
+				// control characters, falling back to the command's own group
+				pgid, err := unix.IoctlGetInt(int(ptmx.Fd()), unix.TIOCGPGRP)
+				if err != nil || pgid <= 0 {
+					pgid = cmd.Process.Pid
+				}
+				signalProcessGroup(pgid, name)
+			}
 		}
 	}()
 
@@ -246,6 +272,22 @@ func (s *guestServer) executeTTY(ctx context.Context, stream pb.GuestService_Exe
 	})
 }
 
+// signalProcessGroup sends the named signal, e.g. "SIGINT" or "INT", to a
+// process group. Unknown signal names are logged and ignored.
+func signalProcessGroup(pgid int, name string) {
+	if !strings.HasPrefix(name, "SIG") {
+		name = "SIG" + name
+	}
+	sig := unix.SignalNum(name)
+	if sig == 0 {
+		log.Printf("[guest-agent] ignoring unknown signal %q", name)
+		return
+	}
+	if err := unix.Kill(-pgid, sig); err != nil {
+		log.Printf("[guest-agent] failed to send %s to process group %d: %v", name, pgid, err)
+	}
+}
+
 // buildEnv constructs environment variables by merging provided env with defaults
 func (s *guestServer) buildEnv(envMap map[string]string) []string {
 	// Start with current environment as base
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"log"
+	"os"
+	"strings"
+	"time"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+const (
+	// fstrimInterval is how often writable filesystems are trimmed, handing
+	// blocks freed in the guest back to the host
+	fstrimInterval = time.Hour
+
+	// fstrimInitialDelay lets the application settle before the first trim
+	fstrimInitialDelay = 5 * time.Minute
+
+	// fitrim is the FITRIM ioctl, _IOWR('X', 121, struct fstrim_range),
+	// which x/sys/unix doesn't define
+	fitrim = 0xc0185879
+)
+
+// fstrimRange mirrors the kernel's struct fstrim_range
+type fstrimRange struct {
+	Start  uint64
+	Len    uint64
+	MinLen uint64
+}
+
+// trimmableFS lists filesystems supporting FITRIM that hypeman disks carry
+var trimmableFS = map[string]bool{"ext4": true, "xfs": true, "btrfs": true}
+
+// runFstrim trims writable virtio-blk filesystems every fstrimInterval. The
+// disks pass discards through, so freed blocks become holes in the host's
+// sparse disk files instead of only ever growing them.
+func runFstrim() {
+	time.Sleep(fstrimInitialDelay)
+	for {
+		trimFilesystems()
+		time.Sleep(fstrimInterval)
+	}
+}
+
+// trimFilesystems trims each mounted writable virtio-blk filesystem once
+func trimFilesystems() {
+	for _, mountPoint := range trimmableMounts() {
+		trimmed, err := fstrim(mountPoint)
+		if errors.Is(err, unix.EOPNOTSUPP) {
+			continue
+		}
+		if err != nil {
+			log.Printf("[guest-agent] fstrim %s: %v", mountPoint, err)
+			continue
+		}
+		if trimmed > 0 {
+			log.Printf("[guest-agent] fstrim %s: %d bytes trimmed", mountPoint, trimmed)
+		}
+	}
+}
+
+// trimmableMounts returns one mount point per writable virtio-blk device
+// with a filesystem that supports trimming
+func trimmableMounts() []string {
+	f, err := os.Open("/proc/self/mounts")
+	if err != nil {
+		log.Printf("[guest-agent] fstrim: read mounts: %v", err)
+		return nil
+	}
+	defer f.Close()
+
+	seen := make(map[string]bool)
+	var mounts []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 4 {
+			continue
+		}
+		device, mountPoint, fsType, opts := fields[0], fields[1], fields[2], fields[3]
+		if !strings.HasPrefix(device, "/dev/vd") || !trimmableFS[fsType] || seen[device] {
+			continue
+		}
+		if !strings.HasPrefix(opts+",", "rw,") {
+			continue
+		}
+		seen[device] = true
+		mounts = append(mounts, mountPoint)
+	}
+	return mounts
+}
+
+// fstrim discards the free blocks of the filesystem mounted at mountPoint
+// and returns how many bytes were trimmed
+func fstrim(mountPoint string) (uint64, error) {
+	fd, err := unix.Open(mountPoint, unix.O_RDONLY|unix.O_DIRECTORY|unix.O_CLOEXEC, 0)
+	if err != nil {
+		return 0, err
+	}
+	defer unix.Close(fd)
+
+	r := fstrimRange{Len: ^uint64(0)}
+	if _, _, errno := unix.Syscall(unix.SYS_IOCTL, uintptr(fd), fitrim, uintptr(unsafe.Pointer(&r))); errno != 0 {
+		return 0, errno
+	}
+	// The kernel replaces Len with the number of bytes trimmed
+	return r.Len, nil
+}
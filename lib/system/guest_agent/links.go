@@ -0,0 +1,52 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	pb "github.com/kernel/hypeman/lib/guest"
+	"github.com/kernel/hypeman/lib/vmconfig"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const hostsPath = "/etc/hosts"
+
+// UpdateLinks rewrites the linked peers' block of /etc/hosts, keeping the
+// rest of the file. The new file is renamed into place, so resolvers never
+// read it half written.
+func (s *guestServer) UpdateLinks(ctx context.Context, req *pb.UpdateLinksRequest) (*pb.UpdateLinksResponse, error) {
+	hosts, err := os.ReadFile(hostsPath)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, status.Errorf(codes.Internal, "read hosts: %v", err)
+	}
+
+	links := make([]vmconfig.HostEntry, 0, len(req.Links))
+	for _, l := range req.Links {
+		links = append(links, vmconfig.HostEntry{Hostname: l.Hostname, IP: l.Ip})
+	}
+	data := vmconfig.ReplaceLinks(string(hosts), links)
+
+	tmp, err := os.CreateTemp(filepath.Dir(hostsPath), ".hosts-*")
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "create hosts: %v", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.WriteString(data); err != nil {
+		tmp.Close()
+		return nil, status.Errorf(codes.Internal, "write hosts: %v", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return nil, status.Errorf(codes.Internal, "write hosts: %v", err)
+	}
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		return nil, status.Errorf(codes.Internal, "write hosts: %v", err)
+	}
+	if err := os.Rename(tmp.Name(), hostsPath); err != nil {
+		return nil, status.Errorf(codes.Internal, "replace hosts: %v", err)
+	}
+	return &pb.UpdateLinksResponse{}, nil
+}
@@ -7,6 +7,7 @@ import (
 	"github.com/mdlayher/vsock"
 	pb "github.com/kernel/hypeman/lib/guest"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/keepalive"
 )
 
 // guestServer implements the gRPC GuestService
@@ -35,8 +36,14 @@ func main() {
 
 	log.Println("[guest-agent] listening on vsock port 2222")
 
-	// Create gRPC server
-	grpcServer := grpc.NewServer()
+	go runFstrim()
+
+	// Create gRPC server. The host pings pooled connections every 30s to
+	// notice broken ones; the default policy would reject pings that often.
+	grpcServer := grpc.NewServer(grpc.KeepaliveEnforcementPolicy(keepalive.EnforcementPolicy{
+		MinTime:             10 * time.Second,
+		PermitWithoutStream: true,
+	}))
 	pb.RegisterGuestServiceServer(grpcServer, &guestServer{})
 
 	// Serve gRPC over vsock
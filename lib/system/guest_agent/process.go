@@ -0,0 +1,333 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"sync"
+	"syscall"
+	"time"
+
+	pb "github.com/kernel/hypeman/lib/guest"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// processLogDir holds the stdout/stderr capture of supervised processes
+	processLogDir = "/var/log/hypeman"
+
+	// defaultStopTimeout is the grace period between SIGTERM and SIGKILL
+	defaultStopTimeout = 10 * time.Second
+
+	// processRestartDelay is the pause before the supervisor restarts an exited process
+	processRestartDelay = time.Second
+)
+
+// Restart policies for supervised processes
+const (
+	restartNever     = "never"
+	restartOnFailure = "on-failure"
+	restartAlways    = "always"
+)
+
+// Process states reported in ProcessInfo
+const (
+	processRunning = "running"
+	processExited  = "exited"
+	processStopped = "stopped"
+)
+
+var processNamePattern = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_.-]{0,62}$`)
+
+// processes is the supervisor for processes started over StartProcess
+var processes = &supervisor{procs: make(map[string]*managedProcess)}
+
+// supervisor runs named long-running processes, capturing their output to
+// log files and restarting them according to their restart policy.
+type supervisor struct {
+	mu    sync.Mutex
+	procs map[string]*managedProcess
+}
+
+// managedProcess is one supervised process. Fields are guarded by supervisor.mu.
+type managedProcess struct {
+	spec      *pb.StartProcessRequest
+	env       []string
+	state     string
+	pid       int
+	exitCode  int32
+	restarts  int32
+	startedAt time.Time
+	stopping  bool
+	stop      chan struct{} // closed by stop to cancel a pending restart
+	done      chan struct{} // closed once the process has exited for good
+}
+
+// active reports whether the process is running or about to be restarted
+func (p *managedProcess) active() bool {
+	select {
+	case <-p.done:
+		return false
+	default:
+		return true
+	}
+}
+
+func (p *managedProcess) info() *pb.ProcessInfo {
+	return &pb.ProcessInfo{
+		Name:          p.spec.Name,
+		Command:       p.spec.Command,
+		RestartPolicy: p.spec.RestartPolicy,
+		State:         p.state,
+		Pid:           int32(p.pid),
+		ExitCode:      p.exitCode,
+		Restarts:      p.restarts,
+		StartedAt:     p.startedAt.Unix(),
+		LogPath:       processLogPath(p.spec.Name),
+	}
+}
+
+func processLogPath(name string) string {
+	return filepath.Join(processLogDir, name+".log")
+}
+
+// StartProcess starts a supervised process. A process that has exited can be
+// started again under the same name; a running one must be stopped first.
+func (s *guestServer) StartProcess(ctx context.Context, req *pb.StartProcessRequest) (*pb.StartProcessResponse, error) {
+	log.Printf("[guest-agent] start-process: name=%s command=%v restart=%s", req.Name, req.Command, req.RestartPolicy)
+
+	if !processNamePattern.MatchString(req.Name) {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid process name %q", req.Name)
+	}
+	if len(req.Command) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "command is required")
+	}
+	switch req.RestartPolicy {
+	case "":
+		req.RestartPolicy = restartNever
+	case restartNever, restartOnFailure, restartAlways:
+	default:
+		return nil, status.Errorf(codes.InvalidArgument, "invalid restart policy %q", req.RestartPolicy)
+	}
+
+	info, err := processes.start(req, s.buildEnv(req.Env), 0)
+	if err != nil {
+		return nil, err
+	}
+	return &pb.StartProcessResponse{Process: info}, nil
+}
+
+// StopProcess stops a supervised process with SIGTERM, then SIGKILL after the timeout
+func (s *guestServer) StopProcess(ctx context.Context, req *pb.StopProcessRequest) (*pb.StopProcessResponse, error) {
+	log.Printf("[guest-agent] stop-process: name=%s", req.Name)
+
+	p, err := processes.stop(req.Name, stopTimeout(req.TimeoutSeconds))
+	if err != nil {
+		return nil, err
+	}
+	return &pb.StopProcessResponse{Process: processes.info(p)}, nil
+}
+
+// RestartProcess stops a supervised process and starts it again with the same spec
+func (s *guestServer) RestartProcess(ctx context.Context, req *pb.RestartProcessRequest) (*pb.RestartProcessResponse, error) {
+	log.Printf("[guest-agent] restart-process: name=%s", req.Name)
+
+	p, err := processes.stop(req.Name, stopTimeout(req.TimeoutSeconds))
+	if err != nil {
+		return nil, err
+	}
+	info, err := processes.start(p.spec, p.env, p.restarts+1)
+	if err != nil {
+		return nil, err
+	}
+	return &pb.RestartProcessResponse{Process: info}, nil
+}
+
+// ListProcesses lists the supervised processes, sorted by name
+func (s *guestServer) ListProcesses(ctx context.Context, req *pb.ListProcessesRequest) (*pb.ListProcessesResponse, error) {
+	processes.mu.Lock()
+	defer processes.mu.Unlock()
+
+	resp := &pb.ListProcessesResponse{}
+	for _, p := range processes.procs {
+		resp.Processes = append(resp.Processes, p.info())
+	}
+	sort.Slice(resp.Processes, func(i, j int) bool {
+		return resp.Processes[i].Name < resp.Processes[j].Name
+	})
+	return resp, nil
+}
+
+func stopTimeout(seconds int32) time.Duration {
+	if seconds <= 0 {
+		return defaultStopTimeout
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+func (s *supervisor) info(p *managedProcess) *pb.ProcessInfo {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return p.info()
+}
+
+// start launches a process and hands it to a supervising goroutine
+func (s *supervisor) start(spec *pb.StartProcessRequest, env []string, restarts int32) (*pb.ProcessInfo, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if existing, ok := s.procs[spec.Name]; ok && existing.active() {
+		return nil, status.Errorf(codes.AlreadyExists, "process %q is already running", spec.Name)
+	}
+
+	p := &managedProcess{
+		spec:     spec,
+		env:      env,
+		restarts: restarts,
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+	cmd, err := launch(p)
+	if err != nil {
+		return nil, status.Errorf(codes.FailedPrecondition, "start %q: %v", spec.Name, err)
+	}
+	s.procs[spec.Name] = p
+	go s.supervise(p, cmd)
+	return p.info(), nil
+}
+
+// launch starts the process in its own process group with output appended to
+// its log file. Called with supervisor.mu held.
+func launch(p *managedProcess) (*exec.Cmd, error) {
+	if err := os.MkdirAll(processLogDir, 0755); err != nil {
+		return nil, fmt.Errorf("create log directory: %w", err)
+	}
+	logFile, err := os.OpenFile(processLogPath(p.spec.Name), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("open log file: %w", err)
+	}
+	// The child keeps its own copy of the descriptor
+	defer logFile.Close()
+
+	cmd := exec.Command(p.spec.Command[0], p.spec.Command[1:]...)
+	cmd.Env = p.env
+	cmd.Dir = p.spec.Cwd
+	cmd.Stdout = logFile
+	cmd.Stderr = logFile
+	// Own process group so stop reaches the whole tree
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+
+	p.state = processRunning
+	p.pid = cmd.Process.Pid
+	p.startedAt = time.Now()
+	log.Printf("[guest-agent] process %s started (PID %d)", p.spec.Name, p.pid)
+	return cmd, nil
+}
+
+// supervise waits for the process and restarts it according to its policy
+func (s *supervisor) supervise(p *managedProcess, cmd *exec.Cmd) {
+	defer close(p.done)
+
+	for {
+		err := cmd.Wait()
+		exitCode := int32(0)
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			exitCode = int32(exitErr.ExitCode())
+		}
+
+		s.mu.Lock()
+		p.pid = 0
+		p.exitCode = exitCode
+		p.state = processExited
+		if p.stopping {
+			p.state = processStopped
+		}
+		restart := !p.stopping && shouldRestart(p.spec.RestartPolicy, err)
+		s.mu.Unlock()
+
+		log.Printf("[guest-agent] process %s exited with code %d", p.spec.Name, exitCode)
+		if !restart {
+			return
+		}
+
+		select {
+		case <-p.stop:
+			s.mu.Lock()
+			p.state = processStopped
+			s.mu.Unlock()
+			return
+		case <-time.After(processRestartDelay):
+		}
+
+		s.mu.Lock()
+		if p.stopping {
+			p.state = processStopped
+			s.mu.Unlock()
+			return
+		}
+		cmd, err = launch(p)
+		if err != nil {
+			s.mu.Unlock()
+			log.Printf("[guest-agent] process %s restart failed: %v", p.spec.Name, err)
+			return
+		}
+		p.restarts++
+		s.mu.Unlock()
+	}
+}
+
+func shouldRestart(policy string, waitErr error) bool {
+	switch policy {
+	case restartAlways:
+		return true
+	case restartOnFailure:
+		return waitErr != nil
+	default:
+		return false
+	}
+}
+
+// stop terminates a process and waits for the supervisor to release it
+func (s *supervisor) stop(name string, timeout time.Duration) (*managedProcess, error) {
+	s.mu.Lock()
+	p, ok := s.procs[name]
+	if !ok {
+		s.mu.Unlock()
+		return nil, status.Errorf(codes.NotFound, "process %q not found", name)
+	}
+	if !p.active() {
+		s.mu.Unlock()
+		return p, nil
+	}
+	if !p.stopping {
+		p.stopping = true
+		close(p.stop)
+	}
+	pid := p.pid
+	s.mu.Unlock()
+
+	if pid > 0 {
+		syscall.Kill(-pid, syscall.SIGTERM)
+	}
+	select {
+	case <-p.done:
+	case <-time.After(timeout):
+		log.Printf("[guest-agent] process %s did not stop within %s, killing", name, timeout)
+		if pid > 0 {
+			syscall.Kill(-pid, syscall.SIGKILL)
+		}
+		<-p.done
+	}
+	return p, nil
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	pb "github.com/kernel/hypeman/lib/guest"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// clockTicks is USER_HZ, the unit of CPU times in /proc/<pid>/stat. It's
+// 100 on every architecture the guest kernel is built for.
+const clockTicks = 100
+
+// ListGuestProcesses returns a snapshot of the guest's process table from /proc
+func (s *guestServer) ListGuestProcesses(ctx context.Context, req *pb.ListGuestProcessesRequest) (*pb.ListGuestProcessesResponse, error) {
+	uptime, err := readUptime()
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "read uptime: %v", err)
+	}
+
+	entries, err := os.ReadDir("/proc")
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "read /proc: %v", err)
+	}
+
+	resp := &pb.ListGuestProcessesResponse{}
+	for _, entry := range entries {
+		pid, err := strconv.Atoi(entry.Name())
+		if err != nil {
+			continue
+		}
+		// Processes can exit while /proc is walked
+		if proc, err := readProcess(pid, uptime); err == nil {
+			resp.Processes = append(resp.Processes, proc)
+		}
+	}
+	sort.Slice(resp.Processes, func(i, j int) bool {
+		return resp.Processes[i].Pid < resp.Processes[j].Pid
+	})
+	return resp, nil
+}
+
+// readProcess reads one process from /proc. CPU% is computed like ps: CPU
+// time used divided by the time since the process started.
+func readProcess(pid int, uptime float64) (*pb.GuestProcess, error) {
+	stat, err := os.ReadFile(fmt.Sprintf("/proc/%d/stat", pid))
+	if err != nil {
+		return nil, err
+	}
+
+	// The command name is in parentheses and may itself contain spaces or
+	// parentheses, so fields are split after the last ')'
+	open := bytes.IndexByte(stat, '(')
+	end := bytes.LastIndexByte(stat, ')')
+	if open < 0 || end < open {
+		return nil, fmt.Errorf("malformed stat for pid %d", pid)
+	}
+	comm := string(stat[open+1 : end])
+	fields := strings.Fields(string(stat[end+1:]))
+	// fields[0] is field 3 (state) in proc(5)
+	if len(fields) < 22 {
+		return nil, fmt.Errorf("short stat for pid %d", pid)
+	}
+	ppid, _ := strconv.Atoi(fields[1])
+	utime, _ := strconv.ParseUint(fields[11], 10, 64)
+	stime, _ := strconv.ParseUint(fields[12], 10, 64)
+	starttime, _ := strconv.ParseUint(fields[19], 10, 64)
+	rssPages, _ := strconv.ParseInt(fields[21], 10, 64)
+
+	var cpuPercent float64
+	if elapsed := uptime - float64(starttime)/clockTicks; elapsed > 0 {
+		cpuPercent = float64(utime+stime) / clockTicks / elapsed * 100
+	}
+
+	// Kernel threads have an empty cmdline and are shown as [comm], like ps
+	command := "[" + comm + "]"
+	if cmdline, err := os.ReadFile(fmt.Sprintf("/proc/%d/cmdline", pid)); err == nil && len(cmdline) > 0 {
+		command = strings.TrimSpace(strings.ReplaceAll(string(cmdline), "\x00", " "))
+	}
+
+	return &pb.GuestProcess{
+		Pid:        int32(pid),
+		Ppid:       int32(ppid),
+		Command:    command,
+		CpuPercent: cpuPercent,
+		RssBytes:   rssPages * int64(os.Getpagesize()),
+	}, nil
+}
+
+// readUptime returns the seconds since the guest booted
+func readUptime() (float64, error) {
+	data, err := os.ReadFile("/proc/uptime")
+	if err != nil {
+		return 0, err
+	}
+	fields := strings.Fields(string(data))
+	if len(fields) == 0 {
+		return 0, fmt.Errorf("malformed /proc/uptime")
+	}
+	return strconv.ParseFloat(fields[0], 64)
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+
+	pb "github.com/kernel/hypeman/lib/guest"
+	"golang.org/x/sys/unix"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// validSecretName matches secret file names; they can't leave the secrets directory
+var validSecretName = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_.-]*$`)
+
+// PutSecrets writes secrets to a tmpfs, so they never reach a disk, and
+// leaves the environment variables for init to hand to the application
+func (s *guestServer) PutSecrets(ctx context.Context, req *pb.PutSecretsRequest) (*pb.PutSecretsResponse, error) {
+	log.Printf("[guest-agent] put-secrets: files=%d env=%d", len(req.Files), len(req.Env))
+
+	for _, f := range req.Files {
+		if !validSecretName.MatchString(f.Name) {
+			return nil, status.Errorf(codes.InvalidArgument, "invalid secret name: %q", f.Name)
+		}
+	}
+
+	if err := mountSecretsDir(); err != nil {
+		return nil, status.Errorf(codes.Internal, "mount %s: %v", pb.SecretsDir, err)
+	}
+	for _, f := range req.Files {
+		if err := writeSecretFile(filepath.Join(pb.SecretsDir, f.Name), f.Value); err != nil {
+			return nil, status.Errorf(codes.Internal, "write secret %s: %v", f.Name, err)
+		}
+	}
+
+	// Written last: init waits for this file before starting the application
+	env := req.Env
+	if env == nil {
+		env = map[string]string{}
+	}
+	data, err := json.Marshal(env)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "marshal env: %v", err)
+	}
+	if err := writeSecretFile(pb.SecretsEnvFile, data); err != nil {
+		return nil, status.Errorf(codes.Internal, "write env: %v", err)
+	}
+
+	return &pb.PutSecretsResponse{}, nil
+}
+
+// mountSecretsDir mounts a tmpfs on the secrets directory, unless one is
+// already mounted there (secrets are delivered again on each boot)
+func mountSecretsDir() error {
+	if err := os.MkdirAll(pb.SecretsDir, 0700); err != nil {
+		return err
+	}
+	var st unix.Statfs_t
+	if err := unix.Statfs(pb.SecretsDir, &st); err == nil && st.Type == unix.TMPFS_MAGIC {
+		return nil
+	}
+	return unix.Mount("tmpfs", pb.SecretsDir, "tmpfs", unix.MS_NOSUID|unix.MS_NODEV|unix.MS_NOEXEC, "mode=0700,size=16m")
+}
+
+// writeSecretFile writes a read-only file via a temp file and rename, so a
+// reader never sees a partial secret
+func writeSecretFile(path string, value []byte) error {
+	tmp := path + ".tmp"
+	os.Remove(tmp)
+	if err := os.WriteFile(tmp, value, 0400); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("rename: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,211 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	pb "github.com/kernel/hypeman/lib/guest"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// maxTailLines caps the initial tail of a file
+	maxTailLines = 10000
+
+	// tailPollInterval is how often a followed file is checked for new data
+	tailPollInterval = 250 * time.Millisecond
+
+	// tailBlockSize is the read size when scanning backwards for line breaks
+	tailBlockSize = 64 * 1024
+
+	// maxTailBytes bounds how far back the initial tail scans
+	maxTailBytes = 16 * 1024 * 1024
+)
+
+// TailFile streams the last lines of a file and, with follow, lines appended
+// to it afterwards. A followed file that is truncated is read again from the
+// start; one that is replaced (log rotation) is reopened by path.
+func (s *guestServer) TailFile(req *pb.TailFileRequest, stream pb.GuestService_TailFileServer) error {
+	log.Printf("[guest-agent] tail-file: path=%s lines=%d follow=%v", req.Path, req.Lines, req.Follow)
+
+	if !filepath.IsAbs(req.Path) {
+		return status.Errorf(codes.InvalidArgument, "path must be absolute: %q", req.Path)
+	}
+	lines := int(req.Lines)
+	if lines < 0 {
+		lines = 0
+	}
+	if lines > maxTailLines {
+		lines = maxTailLines
+	}
+
+	f, err := openTailFile(req.Path)
+	if err != nil {
+		return err
+	}
+	defer func() { f.Close() }()
+
+	initial, partial, offset, err := lastLines(f, lines)
+	if err != nil {
+		return status.Errorf(codes.Internal, "read %s: %v", req.Path, err)
+	}
+	if !req.Follow {
+		if len(partial) > 0 {
+			initial = append(initial, string(partial))
+			if len(initial) > lines {
+				initial = initial[len(initial)-lines:]
+			}
+		}
+		return stream.Send(&pb.TailFileResponse{Lines: initial})
+	}
+	// Always send the first response so the client knows the file is open.
+	// An unterminated last line is held back until it is complete.
+	if err := stream.Send(&pb.TailFileResponse{Lines: initial}); err != nil {
+		return err
+	}
+
+	ctx := stream.Context()
+	ticker := time.NewTicker(tailPollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+
+		info, err := f.Stat()
+		if err != nil {
+			return status.Errorf(codes.Internal, "stat %s: %v", req.Path, err)
+		}
+		if info.Size() < offset {
+			// Truncated in place
+			offset, partial = 0, nil
+		} else if info.Size() == offset && replaced(req.Path, info) {
+			// Rotated: finish with the old file, then switch to the new one
+			if nf, err := openTailFile(req.Path); err == nil {
+				f.Close()
+				f, offset, partial = nf, 0, nil
+			}
+		}
+
+		data, err := readFrom(f, offset)
+		if err != nil {
+			return status.Errorf(codes.Internal, "read %s: %v", req.Path, err)
+		}
+		if len(data) == 0 {
+			continue
+		}
+		offset += int64(len(data))
+
+		// Only send complete lines; keep the remainder for the next poll
+		data = append(partial, data...)
+		end := bytes.LastIndexByte(data, '\n')
+		if end < 0 {
+			partial = data
+			continue
+		}
+		partial = append([]byte(nil), data[end+1:]...)
+		if err := stream.Send(&pb.TailFileResponse{Lines: splitLines(data[:end])}); err != nil {
+			return err
+		}
+	}
+}
+
+func openTailFile(path string) (*os.File, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, status.Errorf(codes.NotFound, "file not found: %s", path)
+		}
+		return nil, status.Errorf(codes.FailedPrecondition, "open %s: %v", path, err)
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, status.Errorf(codes.Internal, "stat %s: %v", path, err)
+	}
+	if !info.Mode().IsRegular() {
+		f.Close()
+		return nil, status.Errorf(codes.InvalidArgument, "not a regular file: %s", path)
+	}
+	return f, nil
+}
+
+// replaced reports whether path now refers to a different file than info
+func replaced(path string, info os.FileInfo) bool {
+	current, err := os.Stat(path)
+	if err != nil {
+		return false
+	}
+	a, ok1 := info.Sys().(*syscall.Stat_t)
+	b, ok2 := current.Sys().(*syscall.Stat_t)
+	return ok1 && ok2 && (a.Ino != b.Ino || a.Dev != b.Dev)
+}
+
+// lastLines returns up to n complete lines from the end of f, any unterminated
+// data after the last newline, and the offset just past the data read.
+func lastLines(f *os.File, n int) ([]string, []byte, int64, error) {
+	info, err := f.Stat()
+	if err != nil {
+		return nil, nil, 0, err
+	}
+	size := info.Size()
+	if size == 0 || n == 0 {
+		return nil, nil, size, nil
+	}
+
+	// Scan backwards until the block holds more than n line breaks
+	start := size
+	var buf []byte
+	for start > 0 && size-start < maxTailBytes && bytes.Count(buf, []byte{'\n'}) <= n {
+		readSize := int64(tailBlockSize)
+		if start < readSize {
+			readSize = start
+		}
+		start -= readSize
+		block := make([]byte, readSize)
+		if _, err := f.ReadAt(block, start); err != nil && err != io.EOF {
+			return nil, nil, 0, err
+		}
+		buf = append(block, buf...)
+	}
+
+	end := bytes.LastIndexByte(buf, '\n')
+	partial := append([]byte(nil), buf[end+1:]...)
+	if end < 0 {
+		return nil, partial, size, nil
+	}
+	lines := splitLines(buf[:end])
+	if start > 0 {
+		// The first line is cut off by the block boundary
+		lines = lines[1:]
+	}
+	if len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	return lines, partial, size, nil
+}
+
+func readFrom(f *os.File, offset int64) ([]byte, error) {
+	data, err := io.ReadAll(io.NewSectionReader(f, offset, 1<<62))
+	if err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+func splitLines(data []byte) []string {
+	parts := bytes.Split(data, []byte{'\n'})
+	lines := make([]string, len(parts))
+	for i, p := range parts {
+		lines[i] = string(bytes.TrimSuffix(p, []byte{'\r'}))
+	}
+	return lines
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	pb "github.com/kernel/hypeman/lib/guest"
+)
+
+// agentPath is the installed guest-agent binary, resolved at startup before an
+// update can replace it.
+var agentPath, _ = os.Executable()
+
+// updateMu serializes agent updates
+var updateMu sync.Mutex
+
+// runningChecksum caches the SHA-256 of the running binary
+var runningChecksum = sync.OnceValues(func() (string, error) {
+	// /proc/self/exe is the running binary even after an update replaces agentPath
+	f, err := os.Open("/proc/self/exe")
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+})
+
+// GetAgentInfo returns the checksum of the running guest-agent binary
+func (s *guestServer) GetAgentInfo(ctx context.Context, req *pb.GetAgentInfoRequest) (*pb.GetAgentInfoResponse, error) {
+	sum, err := runningChecksum()
+	if err != nil {
+		return nil, fmt.Errorf("checksum running binary: %w", err)
+	}
+	return &pb.GetAgentInfoResponse{Sha256: sum}, nil
+}
+
+// UpdateAgent receives a new guest-agent binary, verifies its checksum, installs
+// it over the current binary and re-executes the agent in place. The PID is kept,
+// so init (exec mode) and systemd (systemd mode) keep supervising the same process.
+// In-flight exec and copy sessions are dropped by the restart.
+func (s *guestServer) UpdateAgent(stream pb.GuestService_UpdateAgentServer) error {
+	fail := func(format string, args ...any) error {
+		msg := fmt.Sprintf(format, args...)
+		log.Printf("[guest-agent] update failed: %s", msg)
+		return stream.SendAndClose(&pb.UpdateAgentResponse{Success: false, Error: msg})
+	}
+
+	if !updateMu.TryLock() {
+		return fail("another update is in progress")
+	}
+	defer updateMu.Unlock()
+
+	req, err := stream.Recv()
+	if err != nil {
+		return fmt.Errorf("receive start request: %w", err)
+	}
+	start := req.GetStart()
+	if start == nil {
+		return fail("first message must be UpdateAgentStart")
+	}
+	log.Printf("[guest-agent] update: size=%d sha256=%s", start.Size, start.Sha256)
+
+	// Stage next to the binary so the rename is atomic
+	tmp, err := os.CreateTemp(filepath.Dir(agentPath), ".guest-agent-update-*")
+	if err != nil {
+		return fail("create temp file: %v", err)
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	h := sha256.New()
+	w := io.MultiWriter(tmp, h)
+	var received int64
+	for {
+		req, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fail("receive: %v", err)
+		}
+		data := req.GetData()
+		received += int64(len(data))
+		if received > start.Size {
+			return fail("received more than the declared %d bytes", start.Size)
+		}
+		if _, err := w.Write(data); err != nil {
+			return fail("write: %v", err)
+		}
+	}
+
+	if received != start.Size {
+		return fail("received %d bytes, expected %d", received, start.Size)
+	}
+	if sum := hex.EncodeToString(h.Sum(nil)); sum != strings.ToLower(start.Sha256) {
+		return fail("checksum mismatch: got %s, expected %s", sum, start.Sha256)
+	}
+	if err := tmp.Chmod(0755); err != nil {
+		return fail("chmod: %v", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		return fail("sync: %v", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fail("close: %v", err)
+	}
+	if err := os.Rename(tmp.Name(), agentPath); err != nil {
+		return fail("install: %v", err)
+	}
+
+	log.Printf("[guest-agent] installed new binary at %s, restarting", agentPath)
+	if err := stream.SendAndClose(&pb.UpdateAgentResponse{Success: true}); err != nil {
+		return err
+	}
+
+	// Give gRPC a moment to flush the response before the process image is replaced
+	time.AfterFunc(200*time.Millisecond, func() {
+		if err := syscall.Exec(agentPath, os.Args, os.Environ()); err != nil {
+			log.Printf("[guest-agent] re-exec failed, continuing with old binary: %v", err)
+		}
+	})
+	return nil
+}
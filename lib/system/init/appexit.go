@@ -0,0 +1,93 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"syscall"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// appExitFile is where init records how the application exited, for the
+// guest agent to report (guest.AppExitFile), relative to the new root
+const appExitFile = "/run/hypeman/app-exit.json"
+
+// appPidFile is where init records the application's PID, for the guest
+// agent to signal it on graceful shutdown (guest.AppPidFile)
+const appPidFile = "/run/hypeman/app.pid"
+
+// appExit mirrors guest.AppExit
+type appExit struct {
+	ExitCode int       `json:"exit_code"`
+	Signal   string    `json:"signal,omitempty"`
+	ExitedAt time.Time `json:"exited_at"`
+}
+
+// clearAppExit removes the exit and PID recorded by a previous boot, in case
+// /run isn't a tmpfs in the image
+func clearAppExit() {
+	os.Remove(appExitFile)
+	os.Remove(appPidFile)
+}
+
+// recordAppStart records the application's PID for the guest agent
+func recordAppStart(log *Logger, pid int) {
+	err := os.MkdirAll(filepath.Dir(appPidFile), 0755)
+	if err == nil {
+		err = os.WriteFile(appPidFile, []byte(strconv.Itoa(pid)), 0644)
+	}
+	if err != nil {
+		log.Error("exec", "failed to record app pid", err)
+	}
+}
+
+// recordAppExit logs how the application exited and records it for the
+// guest agent. waitErr is the error returned by waiting for the application.
+// It returns the exit code init should exit with.
+func recordAppExit(log *Logger, waitErr error) int {
+	exit := appExit{ExitedAt: time.Now().UTC()}
+
+	var exitErr *exec.ExitError
+	if errors.As(waitErr, &exitErr) {
+		exit.ExitCode = exitErr.ExitCode()
+		if ws, ok := exitErr.Sys().(syscall.WaitStatus); ok && ws.Signaled() {
+			exit.Signal = unix.SignalName(ws.Signal())
+		}
+	} else if waitErr != nil {
+		exit.ExitCode = -1
+	}
+
+	if exit.Signal != "" {
+		log.Info("exec", fmt.Sprintf("app killed by signal %s", exit.Signal))
+	} else {
+		log.Info("exec", fmt.Sprintf("app exited with code %d", exit.ExitCode))
+	}
+
+	writeAppExit(log, exit)
+	return exit.ExitCode
+}
+
+// recordSecretsFailure records the application as exited with
+// secretsFailedExitCode without it having run, so the host reports the
+// instance's secrets weren't delivered instead of a running application.
+func recordSecretsFailure(log *Logger) {
+	writeAppExit(log, appExit{ExitCode: secretsFailedExitCode, ExitedAt: time.Now().UTC()})
+}
+
+func writeAppExit(log *Logger, exit appExit) {
+	data, err := json.Marshal(exit)
+	if err == nil {
+		if err = os.MkdirAll(filepath.Dir(appExitFile), 0755); err == nil {
+			err = os.WriteFile(appExitFile, data, 0644)
+		}
+	}
+	if err != nil {
+		log.Error("exec", "failed to record app exit", err)
+	}
+}
@@ -10,7 +10,7 @@ import (
 )
 
 // readConfig mounts and reads the config disk, parsing the JSON configuration.
-func readConfig(log *Logger) (*vmconfig.Config, error) {
+func readConfig(log *Logger, device string) (*vmconfig.Config, error) {
 	const configMount = "/mnt/config"
 	const configFile = "/mnt/config/config.json"
 
@@ -19,8 +19,8 @@ func readConfig(log *Logger) (*vmconfig.Config, error) {
 		return nil, fmt.Errorf("mkdir config mount: %w", err)
 	}
 
-	// Mount config disk (/dev/vdc) read-only
-	cmd := exec.Command("/bin/mount", "-o", "ro", "/dev/vdc", configMount)
+	// Mount config disk (/dev/vdc, or /dev/vdb with a root volume) read-only
+	cmd := exec.Command("/bin/mount", "-o", "ro", device, configMount)
 	if output, err := cmd.CombinedOutput(); err != nil {
 		return nil, fmt.Errorf("mount config disk: %s: %s", err, output)
 	}
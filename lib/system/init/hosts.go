@@ -0,0 +1,68 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"syscall"
+
+	"github.com/kernel/hypeman/lib/vmconfig"
+)
+
+// configureHosts sets the guest hostname and writes /etc/hosts and /etc/hostname
+// in the new root.
+func configureHosts(log *Logger, cfg *vmconfig.Config) error {
+	if cfg.Hostname != "" {
+		if err := syscall.Sethostname([]byte(cfg.Hostname)); err != nil {
+			return fmt.Errorf("set hostname: %w", err)
+		}
+	}
+
+	if err := os.MkdirAll("/overlay/newroot/etc", 0755); err != nil {
+		return fmt.Errorf("mkdir /etc: %w", err)
+	}
+	if cfg.Hostname != "" {
+		if err := replaceFile("/overlay/newroot/etc/hostname", []byte(cfg.Hostname+"\n")); err != nil {
+			return fmt.Errorf("write hostname: %w", err)
+		}
+	}
+	if err := replaceFile("/overlay/newroot/etc/hosts", []byte(renderHosts(cfg))); err != nil {
+		return fmt.Errorf("write hosts: %w", err)
+	}
+
+	log.Info("hosts", fmt.Sprintf("configured hostname %s and /etc/hosts", cfg.Hostname))
+	return nil
+}
+
+// renderHosts generates /etc/hosts: loopback entries, the instance's own
+// name, any extra entries from the instance config, then linked peers.
+func renderHosts(cfg *vmconfig.Config) string {
+	var b strings.Builder
+	b.WriteString("# Generated by hypeman at boot\n")
+	b.WriteString("127.0.0.1\tlocalhost\n")
+	b.WriteString("::1\tlocalhost ip6-localhost ip6-loopback\n")
+	if cfg.Hostname != "" {
+		selfIP := "127.0.1.1"
+		if cfg.NetworkEnabled && cfg.GuestIP != "" {
+			selfIP = cfg.GuestIP
+		}
+		if cfg.NetworkEnabled && cfg.GuestDomain != "" {
+			fmt.Fprintf(&b, "%s\t%s.%s %s\n", selfIP, cfg.Hostname, cfg.GuestDomain, cfg.Hostname)
+		} else {
+			fmt.Fprintf(&b, "%s\t%s\n", selfIP, cfg.Hostname)
+		}
+	}
+	for _, h := range cfg.ExtraHosts {
+		fmt.Fprintf(&b, "%s\t%s\n", h.IP, h.Hostname)
+	}
+	if len(cfg.Links) > 0 {
+		b.WriteString(vmconfig.RenderLinks(cfg.Links))
+	}
+	return b.String()
+}
+
+// replaceFile writes data to path, replacing (not following) any existing symlink.
+func replaceFile(path string, data []byte) error {
+	os.Remove(path)
+	return os.WriteFile(path, data, 0644)
+}
@@ -25,14 +25,21 @@ func main() {
 		dropToShell()
 	}
 
-	// Phase 2: Setup overlay rootfs
-	if err := setupOverlay(log); err != nil {
+	// Phase 2: Setup overlay rootfs, or the root volume that replaces it
+	configDevice := "/dev/vdc"
+	if bootsFromRootVolume() {
+		if err := setupRootVolume(log); err != nil {
+			log.Error("overlay", "failed to mount root volume", err)
+			dropToShell()
+		}
+		configDevice = "/dev/vdb"
+	} else if err := setupOverlay(log); err != nil {
 		log.Error("overlay", "failed to setup overlay", err)
 		dropToShell()
 	}
 
 	// Phase 3: Read and parse config
-	cfg, err := readConfig(log)
+	cfg, err := readConfig(log, configDevice)
 	if err != nil {
 		log.Error("config", "failed to read config", err)
 		dropToShell()
@@ -46,33 +53,69 @@ func main() {
 		}
 	}
 
-	// Phase 5: Mount volumes
+	// Phase 5: Hostname and /etc/hosts
+	if !cfg.SkipHosts {
+		if err := configureHosts(log, cfg); err != nil {
+			log.Error("hosts", "failed to configure hosts", err)
+			// Continue anyway - name resolution falls back to DNS
+		}
+	}
+
+	// Phase 6: Mount volumes
 	if len(cfg.VolumeMounts) > 0 {
 		if err := mountVolumes(log, cfg); err != nil {
 			log.Error("volumes", "failed to mount volumes", err)
 			// Continue anyway
 		}
 	}
+	if len(cfg.SharedMounts) > 0 {
+		if err := mountSharedDirectories(log, cfg); err != nil {
+			log.Error("volumes", "failed to mount shared directories", err)
+			// Continue anyway
+		}
+	}
+	if cfg.SwapDevice != "" {
+		if err := enableSwap(log, cfg.SwapDevice); err != nil {
+			log.Error("swap", "failed to enable swap", err)
+			// Continue anyway - the workload runs without swap
+		}
+	}
 
-	// Phase 6: Bind mount filesystems to new root
+	// Phase 7: Bind mount filesystems to new root
 	if err := bindMountsToNewRoot(log); err != nil {
 		log.Error("bind", "failed to bind mounts", err)
 		dropToShell()
 	}
 
-	// Phase 7: Copy guest-agent to target location
+	// Phase 8: Copy guest-agent to target location
 	if err := copyGuestAgent(log); err != nil {
 		log.Error("agent", "failed to copy guest-agent", err)
 		// Continue anyway - exec will still work, just no remote access
 	}
 
-	// Phase 8: Setup kernel headers for DKMS
+	// Phase 9: Setup kernel headers for DKMS
 	if err := setupKernelHeaders(log); err != nil {
 		log.Error("headers", "failed to setup kernel headers", err)
 		// Continue anyway - only needed for DKMS module building
 	}
 
-	// Phase 9: Mode-specific execution
+	// Phase 10: Sysctls and resource limits
+	if len(cfg.Sysctls) > 0 || len(cfg.Rlimits) > 0 {
+		if err := applyTuning(log, cfg); err != nil {
+			log.Error("tuning", "failed to apply some sysctls or ulimits", err)
+			// Continue anyway - the workload runs with the kernel defaults
+		}
+	}
+
+	// Phase 11: Apply user data (cloud-init seed, first-boot files)
+	if cfg.UserData != nil {
+		if err := applyUserData(log, cfg.UserData); err != nil {
+			log.Error("userdata", "failed to apply user data", err)
+			// Continue anyway - the workload may not depend on it
+		}
+	}
+
+	// Phase 12: Mode-specific execution
 	if cfg.InitMode == "systemd" {
 		log.Info("mode", "entering systemd mode")
 		runSystemdMode(log, cfg)
@@ -70,36 +70,56 @@ func runExecMode(log *Logger, cfg *vmconfig.Config) {
 	appCmd.Stdout = os.Stdout
 	appCmd.Stderr = os.Stderr
 
-	// Set up environment for the app
-	appCmd.Env = buildEnv(cfg.Env)
+	// Set up environment for the app. Secrets override the configured env
+	env := cfg.Env
+	if cfg.WaitForSecrets {
+		secretEnv, err := waitForSecrets(log)
+		if err != nil {
+			// Never run the app without the credentials it was given: record
+			// the failure for the host and keep the VM up for debugging
+			log.Error("secrets", "not starting app", err)
+			clearAppExit()
+			recordSecretsFailure(log)
+			waitForAgent(agentCmd)
+			syscall.Exit(secretsFailedExitCode)
+		}
+		if len(secretEnv) > 0 {
+			env = make(map[string]string, len(cfg.Env)+len(secretEnv))
+			for k, v := range cfg.Env {
+				env[k] = v
+			}
+			for k, v := range secretEnv {
+				env[k] = v
+			}
+		}
+	}
+	appCmd.Env = buildEnv(env)
 
+	clearAppExit()
 	if err := appCmd.Start(); err != nil {
 		log.Error("exec", "failed to start entrypoint", err)
 		dropToShell()
 	}
 
 	log.Info("exec", fmt.Sprintf("container app started (PID %d)", appCmd.Process.Pid))
+	recordAppStart(log, appCmd.Process.Pid)
 
-	// Wait for app to exit
-	err := appCmd.Wait()
-	exitCode := 0
-	if err != nil {
-		if exitErr, ok := err.(*exec.ExitError); ok {
-			exitCode = exitErr.ExitCode()
-		}
-	}
+	// Wait for app to exit, and record how for the host
+	exitCode := recordAppExit(log, appCmd.Wait())
+
+	waitForAgent(agentCmd)
 
-	log.Info("exec", fmt.Sprintf("app exited with code %d", exitCode))
+	// Exit with the app's exit code
+	syscall.Exit(exitCode)
+}
 
-	// Wait for guest-agent (keeps init alive, prevents kernel panic)
-	// The guest-agent runs forever, so this effectively keeps the VM alive
-	// until it's explicitly terminated
+// waitForAgent waits for guest-agent (keeps init alive, prevents kernel panic)
+// The guest-agent runs forever, so this effectively keeps the VM alive
+// until it's explicitly terminated
+func waitForAgent(agentCmd *exec.Cmd) {
 	if agentCmd.Process != nil {
 		agentCmd.Wait()
 	}
-
-	// Exit with the app's exit code
-	syscall.Exit(exitCode)
 }
 
 // buildEnv constructs environment variables from the config.
@@ -24,6 +24,13 @@ func runSystemdMode(log *Logger, cfg *vmconfig.Config) {
 		// Continue anyway - VM will work, just without agent
 	}
 
+	if len(cfg.Rlimits) > 0 {
+		if err := writeSystemdLimits(newroot, cfg.Rlimits); err != nil {
+			log.Error("systemd", "failed to write resource limits", err)
+			// Continue anyway - services get systemd's default limits
+		}
+	}
+
 	// Change root to the new filesystem using chroot
 	log.Info("systemd", "executing chroot")
 	if err := syscall.Chroot(newroot); err != nil {
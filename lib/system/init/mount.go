@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"os"
 	"os/exec"
+	"slices"
+	"strings"
 	"syscall"
 	"time"
 )
@@ -106,6 +108,34 @@ func setupOverlay(log *Logger) error {
 	return nil
 }
 
+// bootsFromRootVolume reports whether the host asked for the root volume on
+// /dev/vda to be booted instead of the image overlay (hypeman.root=volume)
+func bootsFromRootVolume() bool {
+	cmdline, err := os.ReadFile("/proc/cmdline")
+	if err != nil {
+		return false
+	}
+	return slices.Contains(strings.Fields(string(cmdline)), "hypeman.root=volume")
+}
+
+// setupRootVolume mounts the writable root volume where the overlay would go:
+// - /dev/vda: root volume (ext4)
+// - /overlay/newroot: the root volume, mounted read-write
+func setupRootVolume(log *Logger) error {
+	// Wait for block devices to be ready
+	time.Sleep(500 * time.Millisecond)
+
+	if err := os.MkdirAll("/overlay/newroot", 0755); err != nil {
+		return fmt.Errorf("mkdir /overlay/newroot: %w", err)
+	}
+	if err := mount("/dev/vda", "/overlay/newroot", "ext4", ""); err != nil {
+		return fmt.Errorf("mount root volume: %w", err)
+	}
+	log.Info("overlay", "mounted root volume from /dev/vda")
+
+	return nil
+}
+
 // bindMountsToNewRoot bind-mounts essential filesystems to the new root.
 // Uses bind mounts instead of move so that the original /dev remains populated
 // for processes running in the initrd namespace.
@@ -4,6 +4,9 @@ import (
 	"fmt"
 	"os"
 	"os/exec"
+	"slices"
+	"strconv"
+	"strings"
 
 	"github.com/kernel/hypeman/lib/vmconfig"
 )
@@ -22,6 +25,13 @@ func configureNetwork(log *Logger, cfg *vmconfig.Config) error {
 		return fmt.Errorf("add IP address: %w", err)
 	}
 
+	// Match the network's MTU (e.g. jumbo frames) before bringing eth0 up
+	if cfg.GuestMTU > 0 {
+		if err := runIP("link", "set", "eth0", "mtu", strconv.Itoa(cfg.GuestMTU)); err != nil {
+			return fmt.Errorf("set MTU: %w", err)
+		}
+	}
+
 	// Bring up eth0
 	if err := runIP("link", "set", "eth0", "up"); err != nil {
 		return fmt.Errorf("bring up eth0: %w", err)
@@ -32,8 +42,49 @@ func configureNetwork(log *Logger, cfg *vmconfig.Config) error {
 		return fmt.Errorf("add default route: %w", err)
 	}
 
-	// Configure DNS in the new root
-	resolvConf := fmt.Sprintf("nameserver %s\n", cfg.GuestDNS)
+	// Static routes from the network's DHCP options; one that can't be added
+	// (e.g. an unreachable gateway) doesn't keep the others out
+	for _, r := range cfg.GuestRoutes {
+		if err := runIP("route", "add", r.Destination, "via", r.Gateway, "dev", "eth0"); err != nil {
+			log.Error("network", fmt.Sprintf("failed to add route to %s via %s", r.Destination, r.Gateway), err)
+		}
+	}
+
+	log.Info("network", fmt.Sprintf("configured eth0 with %s", addr))
+
+	if len(cfg.GuestNTPServers) > 0 {
+		if err := writeNTPConfig(cfg.GuestNTPServers); err != nil {
+			log.Error("network", "failed to configure NTP servers", err)
+		}
+	}
+
+	if cfg.SkipResolvConf {
+		log.Info("network", "leaving image resolv.conf in place")
+		return nil
+	}
+	return writeResolvConf(cfg)
+}
+
+// writeResolvConf configures DNS in the new root.
+func writeResolvConf(cfg *vmconfig.Config) error {
+	nameservers := cfg.GuestNameservers
+	if len(nameservers) == 0 {
+		nameservers = []string{cfg.GuestDNS}
+	}
+
+	var resolvConf strings.Builder
+	for _, ns := range nameservers {
+		fmt.Fprintf(&resolvConf, "nameserver %s\n", ns)
+	}
+	// Sibling instances resolve by bare name through the service domain,
+	// searched after the network's custom domains
+	search := cfg.GuestSearchDomains
+	if cfg.GuestDomain != "" && !slices.Contains(search, cfg.GuestDomain) {
+		search = append(slices.Clone(search), cfg.GuestDomain)
+	}
+	if len(search) > 0 {
+		fmt.Fprintf(&resolvConf, "search %s\n", strings.Join(search, " "))
+	}
 	resolvPath := "/overlay/newroot/etc/resolv.conf"
 
 	// Ensure /etc exists
@@ -41,14 +92,26 @@ func configureNetwork(log *Logger, cfg *vmconfig.Config) error {
 		return fmt.Errorf("mkdir /etc: %w", err)
 	}
 
-	if err := os.WriteFile(resolvPath, []byte(resolvConf), 0644); err != nil {
+	// Images often ship resolv.conf as a symlink (e.g. to systemd-resolved's stub);
+	// replace it rather than writing through it
+	if err := replaceFile(resolvPath, []byte(resolvConf.String())); err != nil {
 		return fmt.Errorf("write resolv.conf: %w", err)
 	}
-
-	log.Info("network", fmt.Sprintf("configured eth0 with %s", addr))
 	return nil
 }
 
+// writeNTPConfig points systemd-timesyncd at the network's NTP servers with a
+// drop-in, leaving the image's own timesyncd.conf untouched. Images running
+// another NTP client keep their configuration.
+func writeNTPConfig(servers []string) error {
+	dir := "/overlay/newroot/etc/systemd/timesyncd.conf.d"
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("mkdir %s: %w", dir, err)
+	}
+	conf := fmt.Sprintf("# Generated by hypeman from the network's DHCP options\n[Time]\nNTP=%s\n", strings.Join(servers, " "))
+	return replaceFile(dir+"/hypeman.conf", []byte(conf))
+}
+
 // runIP executes an 'ip' command with the given arguments.
 func runIP(args ...string) error {
 	cmd := exec.Command("/sbin/ip", args...)
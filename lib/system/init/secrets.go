@@ -0,0 +1,49 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+)
+
+const (
+	// secretsEnvFile is where the guest agent leaves secret environment
+	// variables (guest.SecretsEnvFile), relative to the new root
+	secretsEnvFile = "/run/secrets/.env.json"
+
+	// secretsTimeout bounds how long the application waits for its secrets
+	secretsTimeout = 2 * time.Minute
+
+	// secretsFailedExitCode is the exit code init exits with when the
+	// application isn't started because its secrets never arrived (EX_CONFIG)
+	secretsFailedExitCode = 78
+)
+
+// waitForSecrets waits for the host to deliver the instance's secrets
+// through the guest agent and returns those exposed as environment
+// variables. The env file is removed once read, so only the application's
+// environment holds them.
+func waitForSecrets(log *Logger) (map[string]string, error) {
+	log.Info("secrets", "waiting for secrets")
+	deadline := time.Now().Add(secretsTimeout)
+	for {
+		data, err := os.ReadFile(secretsEnvFile)
+		if err == nil {
+			os.Remove(secretsEnvFile)
+			var env map[string]string
+			if err := json.Unmarshal(data, &env); err != nil {
+				return nil, fmt.Errorf("parse %s: %w", secretsEnvFile, err)
+			}
+			log.Info("secrets", fmt.Sprintf("received secrets (%d env vars)", len(env)))
+			return env, nil
+		}
+		if !os.IsNotExist(err) {
+			return nil, fmt.Errorf("read %s: %w", secretsEnvFile, err)
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("no secrets received after %s", secretsTimeout)
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+}
@@ -0,0 +1,20 @@
+package main
+
+import (
+	"fmt"
+	"os/exec"
+)
+
+// enableSwap formats the swap disk and turns it on. Its contents don't
+// outlive a boot, so it's reformatted every time.
+func enableSwap(log *Logger, device string) error {
+	if output, err := exec.Command("/sbin/mkswap", device).CombinedOutput(); err != nil {
+		return fmt.Errorf("mkswap: %s: %s", err, output)
+	}
+	if output, err := exec.Command("/sbin/swapon", device).CombinedOutput(); err != nil {
+		return fmt.Errorf("swapon: %s: %s", err, output)
+	}
+
+	log.Info("swap", fmt.Sprintf("enabled swap on %s", device))
+	return nil
+}
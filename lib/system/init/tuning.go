@@ -0,0 +1,103 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/kernel/hypeman/lib/vmconfig"
+	"golang.org/x/sys/unix"
+)
+
+// rlimitResources maps ulimit names to their resource and systemd's
+// Default<Limit>= setting
+var rlimitResources = map[string]struct {
+	resource int
+	systemd  string
+}{
+	"as":         {unix.RLIMIT_AS, "AS"},
+	"core":       {unix.RLIMIT_CORE, "CORE"},
+	"cpu":        {unix.RLIMIT_CPU, "CPU"},
+	"data":       {unix.RLIMIT_DATA, "DATA"},
+	"fsize":      {unix.RLIMIT_FSIZE, "FSIZE"},
+	"locks":      {unix.RLIMIT_LOCKS, "LOCKS"},
+	"memlock":    {unix.RLIMIT_MEMLOCK, "MEMLOCK"},
+	"msgqueue":   {unix.RLIMIT_MSGQUEUE, "MSGQUEUE"},
+	"nice":       {unix.RLIMIT_NICE, "NICE"},
+	"nofile":     {unix.RLIMIT_NOFILE, "NOFILE"},
+	"nproc":      {unix.RLIMIT_NPROC, "NPROC"},
+	"rss":        {unix.RLIMIT_RSS, "RSS"},
+	"rtprio":     {unix.RLIMIT_RTPRIO, "RTPRIO"},
+	"rttime":     {unix.RLIMIT_RTTIME, "RTTIME"},
+	"sigpending": {unix.RLIMIT_SIGPENDING, "SIGPENDING"},
+	"stack":      {unix.RLIMIT_STACK, "STACK"},
+}
+
+// applyTuning sets the configured sysctls, then the resource limits on init
+// itself so every process started afterwards inherits them. Sysctls go first
+// since one may raise a ceiling a limit needs (fs.nr_open for nofile). Each
+// setting is applied independently and failures are reported together.
+func applyTuning(log *Logger, cfg *vmconfig.Config) error {
+	var errs []error
+	for name, value := range cfg.Sysctls {
+		path := filepath.Join("/proc/sys", strings.ReplaceAll(name, ".", "/"))
+		if err := os.WriteFile(path, []byte(value), 0644); err != nil {
+			errs = append(errs, fmt.Errorf("sysctl %s: %w", name, err))
+			continue
+		}
+		log.Info("tuning", fmt.Sprintf("set %s=%s", name, value))
+	}
+
+	for _, rl := range cfg.Rlimits {
+		res, ok := rlimitResources[rl.Name]
+		if !ok {
+			errs = append(errs, fmt.Errorf("ulimit %s: unknown resource", rl.Name))
+			continue
+		}
+		limit := unix.Rlimit{Cur: rlimitValue(rl.Soft), Max: rlimitValue(rl.Hard)}
+		if err := unix.Setrlimit(res.resource, &limit); err != nil {
+			errs = append(errs, fmt.Errorf("ulimit %s: %w", rl.Name, err))
+			continue
+		}
+		log.Info("tuning", fmt.Sprintf("set ulimit %s=%d:%d", rl.Name, rl.Soft, rl.Hard))
+	}
+	return errors.Join(errs...)
+}
+
+func rlimitValue(v int64) uint64 {
+	if v < 0 {
+		return unix.RLIM_INFINITY
+	}
+	return uint64(v)
+}
+
+// writeSystemdLimits writes a system.conf drop-in with the configured
+// resource limits, since systemd sets its own defaults for services rather
+// than passing on the limits it inherited from init.
+func writeSystemdLimits(newroot string, rlimits []vmconfig.Rlimit) error {
+	var b strings.Builder
+	b.WriteString("# Generated by hypeman at boot\n[Manager]\n")
+	for _, rl := range rlimits {
+		res, ok := rlimitResources[rl.Name]
+		if !ok {
+			continue
+		}
+		fmt.Fprintf(&b, "DefaultLimit%s=%s:%s\n", res.systemd, systemdLimitValue(rl.Soft), systemdLimitValue(rl.Hard))
+	}
+
+	dir := newroot + "/etc/systemd/system.conf.d"
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("mkdir system.conf.d: %w", err)
+	}
+	return replaceFile(dir+"/hypeman-limits.conf", []byte(b.String()))
+}
+
+func systemdLimitValue(v int64) string {
+	if v < 0 {
+		return "infinity"
+	}
+	return strconv.FormatInt(v, 10)
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/kernel/hypeman/lib/vmconfig"
+)
+
+const (
+	// cloud-init's NoCloud datasource reads its seed from this directory
+	nocloudSeedDir = "/overlay/newroot/var/lib/cloud/seed/nocloud"
+
+	// userDataMarker records that user data files were written. It lives on the
+	// overlay disk, so files are written once per instance rather than per boot.
+	userDataMarker = "/overlay/newroot/var/lib/hypeman/user-data-applied"
+)
+
+// applyUserData writes the cloud-init NoCloud seed and, on first boot, the
+// user data files into the new root.
+func applyUserData(log *Logger, ud *vmconfig.UserData) error {
+	if ud.CloudConfig != "" {
+		if err := writeNoCloudSeed(ud); err != nil {
+			return err
+		}
+		log.Info("userdata", "wrote cloud-init NoCloud seed")
+	}
+
+	if len(ud.Files) == 0 {
+		return nil
+	}
+	if _, err := os.Stat(userDataMarker); err == nil {
+		log.Info("userdata", "user data files already applied")
+		return nil
+	}
+	for _, f := range ud.Files {
+		if err := writeGuestFile(filepath.Join("/overlay/newroot", f.Path), f.Content, os.FileMode(f.Mode)); err != nil {
+			return fmt.Errorf("write %s: %w", f.Path, err)
+		}
+	}
+	if err := writeGuestFile(userDataMarker, nil, 0644); err != nil {
+		return fmt.Errorf("write marker: %w", err)
+	}
+	log.Info("userdata", fmt.Sprintf("wrote %d user data files", len(ud.Files)))
+	return nil
+}
+
+// writeNoCloudSeed writes user-data and meta-data for cloud-init. The seed is
+// rewritten every boot; cloud-init itself decides what runs once per instance.
+func writeNoCloudSeed(ud *vmconfig.UserData) error {
+	metaData := fmt.Sprintf("instance-id: %s\nlocal-hostname: %s\n", ud.InstanceID, ud.Hostname)
+	if err := writeGuestFile(filepath.Join(nocloudSeedDir, "meta-data"), []byte(metaData), 0644); err != nil {
+		return fmt.Errorf("write meta-data: %w", err)
+	}
+	// user-data may contain secrets
+	if err := writeGuestFile(filepath.Join(nocloudSeedDir, "user-data"), []byte(ud.CloudConfig), 0600); err != nil {
+		return fmt.Errorf("write user-data: %w", err)
+	}
+	return nil
+}
+
+// writeGuestFile writes a file, creating parent directories as needed.
+func writeGuestFile(path string, data []byte, mode os.FileMode) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, data, mode); err != nil {
+		return err
+	}
+	// WriteFile doesn't change the mode of existing files and is subject to umask
+	return os.Chmod(path, mode)
+}
@@ -111,3 +111,33 @@ func mountVolumeReadWrite(log *Logger, vol vmconfig.VolumeMount, mountPath strin
 	return nil
 }
 
+
+// mountSharedDirectories mounts the virtiofs shares of host directories.
+func mountSharedDirectories(log *Logger, cfg *vmconfig.Config) error {
+	log.Info("volumes", "mounting shared directories")
+
+	for _, share := range cfg.SharedMounts {
+		mountPath := filepath.Join("/overlay/newroot", share.Path)
+		if err := os.MkdirAll(mountPath, 0755); err != nil {
+			log.Error("volumes", fmt.Sprintf("mkdir %s failed", share.Path), err)
+			continue
+		}
+
+		args := []string{"-t", "virtiofs"}
+		mode := "rw"
+		if share.Readonly {
+			args = append(args, "-o", "ro")
+			mode = "ro"
+		}
+		args = append(args, share.Tag, mountPath)
+		cmd := exec.Command("/bin/mount", args...)
+		if output, err := cmd.CombinedOutput(); err != nil {
+			log.Error("volumes", fmt.Sprintf("mount virtiofs %s failed", share.Path), fmt.Errorf("%s: %s", err, output))
+			continue
+		}
+
+		log.Info("volumes", fmt.Sprintf("mounted %s at %s (virtiofs, %s)", share.Tag, share.Path, mode))
+	}
+
+	return nil
+}
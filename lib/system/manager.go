@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"slices"
 
 	"github.com/kernel/hypeman/lib/paths"
 )
@@ -16,6 +17,10 @@ type Manager interface {
 	// GetKernelPath returns path to kernel file
 	GetKernelPath(version KernelVersion) (string, error)
 
+	// EnsureKernel downloads a supported kernel version if missing and
+	// returns its path
+	EnsureKernel(version KernelVersion) (string, error)
+
 	// GetInitrdPath returns path to current initrd file
 	GetInitrdPath() (string, error)
 
@@ -58,6 +63,14 @@ func (m *manager) GetKernelPath(version KernelVersion) (string, error) {
 	return path, nil
 }
 
+// EnsureKernel downloads a supported kernel version if missing
+func (m *manager) EnsureKernel(version KernelVersion) (string, error) {
+	if !slices.Contains(SupportedKernelVersions, version) {
+		return "", fmt.Errorf("unsupported kernel version %s", version)
+	}
+	return m.ensureKernel(version)
+}
+
 // GetInitrdPath returns the path to the current initrd file
 func (m *manager) GetInitrdPath() (string, error) {
 	arch := GetArch()
@@ -14,17 +14,89 @@ type Config struct {
 	Env map[string]string `json:"env"`
 
 	// Network configuration
-	NetworkEnabled bool   `json:"network_enabled"`
-	GuestIP        string `json:"guest_ip,omitempty"`
-	GuestCIDR      int    `json:"guest_cidr,omitempty"`
-	GuestGW        string `json:"guest_gw,omitempty"`
-	GuestDNS       string `json:"guest_dns,omitempty"`
+	NetworkEnabled     bool     `json:"network_enabled"`
+	GuestIP            string   `json:"guest_ip,omitempty"`
+	GuestCIDR          int      `json:"guest_cidr,omitempty"`
+	GuestGW            string   `json:"guest_gw,omitempty"`
+	GuestDNS           string   `json:"guest_dns,omitempty"`
+	GuestSearchDomains []string `json:"guest_search_domains,omitempty"`
+	GuestNameservers   []string `json:"guest_nameservers,omitempty"` // Overrides GuestDNS when set
+	GuestDomain        string   `json:"guest_domain,omitempty"`      // Zone sibling instances resolve in, e.g. default.hypeman
+	GuestMTU           int      `json:"guest_mtu,omitempty"`         // eth0 MTU (0 = kernel default)
+	GuestNTPServers    []string `json:"guest_ntp_servers,omitempty"`
+	GuestRoutes        []Route  `json:"guest_routes,omitempty"` // Static routes besides the default route
+
+	// Name resolution files
+	Hostname       string      `json:"hostname,omitempty"`
+	SkipResolvConf bool        `json:"skip_resolv_conf,omitempty"` // Keep the image's /etc/resolv.conf
+	SkipHosts      bool        `json:"skip_hosts,omitempty"`       // Keep the image's /etc/hosts and hostname
+	ExtraHosts     []HostEntry `json:"extra_hosts,omitempty"`
+	Links          []HostEntry `json:"links,omitempty"` // Linked peers, kept current by the guest agent
+
+	// Kernel parameters and resource limits set before the application starts
+	Sysctls map[string]string `json:"sysctls,omitempty"` // By dotted name, e.g. net.core.somaxconn
+	Rlimits []Rlimit          `json:"rlimits,omitempty"`
 
 	// Volume mounts
 	VolumeMounts []VolumeMount `json:"volume_mounts,omitempty"`
 
+	// Shared host directories (virtiofs)
+	SharedMounts []SharedMount `json:"shared_mounts,omitempty"`
+
+	// Swap disk, formatted and enabled by init on every boot
+	SwapDevice string `json:"swap_device,omitempty"`
+
+	// Secrets are delivered by the host through the guest agent after boot,
+	// never through this config. In exec mode, init waits for them before
+	// starting the application, and doesn't start it if they never arrive.
+	WaitForSecrets bool `json:"wait_for_secrets,omitempty"`
+
 	// Init mode: "exec" (default) or "systemd"
 	InitMode string `json:"init_mode"`
+
+	// First-boot configuration (nil if the instance has no user data)
+	UserData *UserData `json:"user_data,omitempty"`
+}
+
+// Route is a static route added to eth0.
+type Route struct {
+	Destination string `json:"destination"` // IPv4 CIDR
+	Gateway     string `json:"gateway"`
+}
+
+// UserData is first-boot configuration applied by the guest init binary.
+// Env from the user data is merged into Config.Env by the host.
+type UserData struct {
+	// Identity written to the cloud-init NoCloud meta-data
+	InstanceID string `json:"instance_id"`
+	Hostname   string `json:"hostname"`
+
+	// Raw cloud-init user-data, written to /var/lib/cloud/seed/nocloud/user-data
+	CloudConfig string `json:"cloud_config,omitempty"`
+
+	// Files written into the new root on first boot
+	Files []File `json:"files,omitempty"`
+}
+
+// File is a file written into the guest filesystem.
+type File struct {
+	Path    string `json:"path"`
+	Content []byte `json:"content"` // base64 in JSON, so binary files survive
+	Mode    uint32 `json:"mode"`
+}
+
+// HostEntry is an additional /etc/hosts entry.
+type HostEntry struct {
+	Hostname string `json:"hostname"`
+	IP       string `json:"ip"`
+}
+
+// Rlimit is a resource limit init sets on itself, so the application, the
+// guest agent and (in systemd mode) services inherit it.
+type Rlimit struct {
+	Name string `json:"name"` // Resource, e.g. "nofile" for RLIMIT_NOFILE
+	Soft int64  `json:"soft"` // -1 = unlimited
+	Hard int64  `json:"hard"` // -1 = unlimited
 }
 
 // VolumeMount represents a volume mount configuration.
@@ -34,3 +106,10 @@ type VolumeMount struct {
 	Mode          string `json:"mode"` // "ro", "rw", or "overlay"
 	OverlayDevice string `json:"overlay_device,omitempty"`
 }
+
+// SharedMount represents a virtiofs mount of a shared host directory.
+type SharedMount struct {
+	Tag      string `json:"tag"`
+	Path     string `json:"path"`
+	Readonly bool   `json:"readonly,omitempty"`
+}
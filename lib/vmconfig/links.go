@@ -0,0 +1,43 @@
+package vmconfig
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Linked peers' entries sit between these lines of /etc/hosts, so the guest
+// agent can replace them when a peer's address changes
+const (
+	linksBegin = "# BEGIN hypeman links"
+	linksEnd   = "# END hypeman links"
+)
+
+// RenderLinks returns the /etc/hosts block listing linked peers
+func RenderLinks(links []HostEntry) string {
+	var b strings.Builder
+	b.WriteString(linksBegin + "\n")
+	for _, l := range links {
+		fmt.Fprintf(&b, "%s\t%s\n", l.IP, l.Hostname)
+	}
+	b.WriteString(linksEnd + "\n")
+	return b.String()
+}
+
+// ReplaceLinks returns hosts with its links block replaced by one listing
+// links. The block is appended if hosts has none, e.g. because the peers
+// weren't up when the guest booted.
+func ReplaceLinks(hosts string, links []HostEntry) string {
+	if start := strings.Index(hosts, linksBegin+"\n"); start >= 0 {
+		if n := strings.Index(hosts[start:], linksEnd); n >= 0 {
+			end := start + n + len(linksEnd)
+			if end < len(hosts) && hosts[end] == '\n' {
+				end++
+			}
+			return hosts[:start] + RenderLinks(links) + hosts[end:]
+		}
+	}
+	if hosts != "" && !strings.HasSuffix(hosts, "\n") {
+		hosts += "\n"
+	}
+	return hosts + RenderLinks(links)
+}
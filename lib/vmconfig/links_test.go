@@ -0,0 +1,23 @@
+package vmconfig
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReplaceLinks(t *testing.T) {
+	links := []HostEntry{{Hostname: "db", IP: "10.100.0.3"}}
+	block := "# BEGIN hypeman links\n10.100.0.3\tdb\n# END hypeman links\n"
+
+	// Appended when missing
+	assert.Equal(t, "127.0.0.1\tlocalhost\n"+block, ReplaceLinks("127.0.0.1\tlocalhost", links))
+	assert.Equal(t, block, ReplaceLinks("", links))
+
+	// Replaced in place, keeping what's around it
+	hosts := "127.0.0.1\tlocalhost\n# BEGIN hypeman links\n10.100.0.9\tdb\n10.100.0.8\tcache\n# END hypeman links\n10.0.0.1\textra\n"
+	assert.Equal(t, "127.0.0.1\tlocalhost\n"+block+"10.0.0.1\textra\n", ReplaceLinks(hosts, links))
+
+	// Emptied, and a block cut short at the end of the file
+	assert.Equal(t, "# BEGIN hypeman links\n# END hypeman links\n", ReplaceLinks("# BEGIN hypeman links\n10.100.0.9\tdb\n# END hypeman links", nil))
+}
@@ -1,8 +1,12 @@
 package vmm
 
 import (
+	"bufio"
+	"bytes"
 	"context"
+	"encoding/json"
 	"fmt"
+	"io"
 	"net"
 	"net/http"
 	"os"
@@ -14,6 +18,7 @@ import (
 	"github.com/kernel/hypeman/lib/paths"
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/metric"
+	"golang.org/x/sys/unix"
 )
 
 // VMM wraps the generated Cloud Hypervisor client (API v0.3.0)
@@ -80,6 +85,54 @@ func NewVMM(socketPath string) (*VMM, error) {
 	}, nil
 }
 
+// PutWithFiles sends a PUT request for endpoint (e.g. "/vm.add-net") with
+// files attached to it as SCM_RIGHTS, which is how Cloud Hypervisor takes file
+// descriptors, like those of macvtap devices. The generated client can't
+// attach them. It returns an error unless the VMM answers with 200 or 204.
+func (v *VMM) PutWithFiles(ctx context.Context, endpoint string, body any, files []*os.File) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("marshal %s body: %w", endpoint, err)
+	}
+
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "unix", v.socketPath)
+	if err != nil {
+		return fmt.Errorf("dial vmm: %w", err)
+	}
+	defer conn.Close()
+	deadline := time.Now().Add(30 * time.Second)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	conn.SetDeadline(deadline)
+
+	var req bytes.Buffer
+	fmt.Fprintf(&req, "PUT /api/v1%s HTTP/1.1\r\nHost: localhost\r\nAccept: application/json\r\n", endpoint)
+	fmt.Fprintf(&req, "Content-Type: application/json\r\nContent-Length: %d\r\n\r\n", len(data))
+	req.Write(data)
+
+	// The descriptors must arrive with the request's first bytes
+	fds := make([]int, len(files))
+	for i, f := range files {
+		fds[i] = int(f.Fd())
+	}
+	if _, _, err := conn.(*net.UnixConn).WriteMsgUnix(req.Bytes(), unix.UnixRights(fds...), nil); err != nil {
+		return fmt.Errorf("send %s: %w", endpoint, err)
+	}
+
+	resp, err := http.ReadResponse(bufio.NewReader(conn), nil)
+	if err != nil {
+		return fmt.Errorf("read %s response: %w", endpoint, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNoContent {
+		msg, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("%s failed with status %d: %s", endpoint, resp.StatusCode, string(msg))
+	}
+	return nil
+}
+
 // StartProcess starts a Cloud Hypervisor VMM process with the given version
 // It extracts the embedded binary if needed and starts the VMM as a daemon.
 // Returns the process ID of the started Cloud Hypervisor process.
@@ -90,6 +143,13 @@ func StartProcess(ctx context.Context, p *paths.Paths, version CHVersion, socket
 // StartProcessWithArgs starts a Cloud Hypervisor VMM process with additional command-line arguments.
 // This is useful for testing or when you need to pass specific flags like verbosity.
 func StartProcessWithArgs(ctx context.Context, p *paths.Paths, version CHVersion, socketPath string, extraArgs []string) (int, error) {
+	return StartProcessInCgroup(ctx, p, version, socketPath, "", extraArgs)
+}
+
+// StartProcessInCgroup starts a Cloud Hypervisor VMM process directly inside the
+// cgroup v2 directory cgroupDir, so none of its resource usage is accounted outside
+// of it. An empty cgroupDir starts it in the caller's cgroup.
+func StartProcessInCgroup(ctx context.Context, p *paths.Paths, version CHVersion, socketPath string, cgroupDir string, extraArgs []string) (int, error) {
 	// Get binary path (extracts if needed)
 	binaryPath, err := GetBinaryPath(p, version)
 	if err != nil {
@@ -117,6 +177,18 @@ func StartProcessWithArgs(ctx context.Context, p *paths.Paths, version CHVersion
 		Setpgid: true, // Create new process group
 	}
 
+	// Clone into the cgroup (clone3 CLONE_INTO_CGROUP) rather than moving the
+	// process after it started
+	if cgroupDir != "" {
+		cgroup, err := os.Open(cgroupDir)
+		if err != nil {
+			return 0, fmt.Errorf("open cgroup: %w", err)
+		}
+		defer cgroup.Close()
+		cmd.SysProcAttr.UseCgroupFD = true
+		cmd.SysProcAttr.CgroupFD = int(cgroup.Fd())
+	}
+
 	// Redirect stdout/stderr to combined VMM log file (process won't block on I/O)
 	instanceDir := filepath.Dir(socketPath)
 	logsDir := filepath.Join(instanceDir, "logs")
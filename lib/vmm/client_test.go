@@ -2,6 +2,8 @@ package vmm
 
 import (
 	"context"
+	"io"
+	"net"
 	"os"
 	"path/filepath"
 	"testing"
@@ -9,6 +11,7 @@ import (
 	"github.com/kernel/hypeman/lib/paths"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"golang.org/x/sys/unix"
 )
 
 func TestExtractBinary(t *testing.T) {
@@ -190,3 +193,61 @@ func TestStartProcessCreatesLogFiles(t *testing.T) {
 	// Cleanup
 	client.ShutdownVMMWithResponse(ctx)
 }
+
+func TestPutWithFiles(t *testing.T) {
+	socketPath := filepath.Join(t.TempDir(), "ch.sock")
+	ln, err := net.ListenUnix("unix", &net.UnixAddr{Name: socketPath, Net: "unix"})
+	require.NoError(t, err)
+	defer ln.Close()
+
+	type received struct {
+		request string
+		file    string
+	}
+	got := make(chan received, 1)
+	go func() {
+		conn, err := ln.AcceptUnix()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		buf := make([]byte, 4096)
+		oob := make([]byte, unix.CmsgSpace(4))
+		n, oobn, _, _, err := conn.ReadMsgUnix(buf, oob)
+		if err != nil {
+			return
+		}
+		var r received
+		r.request = string(buf[:n])
+		if msgs, err := unix.ParseSocketControlMessage(oob[:oobn]); err == nil && len(msgs) == 1 {
+			if fds, err := unix.ParseUnixRights(&msgs[0]); err == nil && len(fds) == 1 {
+				f := os.NewFile(uintptr(fds[0]), "received")
+				data, _ := io.ReadAll(f)
+				f.Close()
+				r.file = string(data)
+			}
+		}
+		got <- r
+		conn.Write([]byte("HTTP/1.1 204 No Content\r\n\r\n"))
+	}()
+
+	path := filepath.Join(t.TempDir(), "tap")
+	require.NoError(t, os.WriteFile(path, []byte("macvtap"), 0644))
+	f, err := os.Open(path)
+	require.NoError(t, err)
+	defer f.Close()
+
+	v, err := NewVMM(socketPath)
+	require.NoError(t, err)
+	err = v.PutWithFiles(context.Background(), "/vm.add-net", NetConfig{Id: ptr("hype-abc")}, []*os.File{f})
+	require.NoError(t, err)
+
+	r := <-got
+	assert.Contains(t, r.request, "PUT /api/v1/vm.add-net HTTP/1.1\r\n")
+	assert.Contains(t, r.request, `{"id":"hype-abc"}`)
+	assert.Equal(t, "macvtap", r.file, "descriptor sent along with the request")
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
@@ -89,6 +89,7 @@ type CpusConfig struct {
 	KvmHyperv   *bool          `json:"kvm_hyperv,omitempty"`
 	MaxPhysBits *int           `json:"max_phys_bits,omitempty"`
 	MaxVcpus    int            `json:"max_vcpus"`
+	Nested      *bool          `json:"nested,omitempty"`
 	Topology    *CpuTopology   `json:"topology,omitempty"`
 }
 
@@ -294,8 +295,15 @@ type ReceiveMigrationData struct {
 
 // RestoreConfig defines model for RestoreConfig.
 type RestoreConfig struct {
-	Prefault  *bool  `json:"prefault,omitempty"`
-	SourceUrl string `json:"source_url"`
+	NetFds    *[]RestoredNetConfig `json:"net_fds,omitempty"`
+	Prefault  *bool                `json:"prefault,omitempty"`
+	SourceUrl string               `json:"source_url"`
+}
+
+// RestoredNetConfig defines model for RestoredNetConfig.
+type RestoredNetConfig struct {
+	Id     string `json:"id"`
+	NumFds int    `json:"num_fds"`
 }
 
 // RngConfig defines model for RngConfig.
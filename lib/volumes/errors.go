@@ -7,5 +7,9 @@ var (
 	ErrInUse         = errors.New("volume is in use")
 	ErrAlreadyExists = errors.New("volume already exists")
 	ErrAmbiguousName = errors.New("multiple volumes with the same name")
+	ErrReadOnly      = errors.New("volume is shared read-only")
+
+	// ErrEncryptionDisabled is returned for encrypted volumes when no keyring is configured
+	ErrEncryptionDisabled = errors.New("volume encryption is not enabled")
 )
 
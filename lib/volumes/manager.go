@@ -10,6 +10,7 @@ import (
 
 	"github.com/nrednav/cuid2"
 	"github.com/kernel/hypeman/lib/images"
+	"github.com/kernel/hypeman/lib/keyring"
 	"github.com/kernel/hypeman/lib/paths"
 	"go.opentelemetry.io/otel/metric"
 )
@@ -19,21 +20,36 @@ type Manager interface {
 	ListVolumes(ctx context.Context) ([]Volume, error)
 	CreateVolume(ctx context.Context, req CreateVolumeRequest) (*Volume, error)
 	CreateVolumeFromArchive(ctx context.Context, req CreateVolumeFromArchiveRequest, archive io.Reader) (*Volume, error)
+	// CheckCreateVolume reports whether CreateVolume would accept the request, without creating anything.
+	CheckCreateVolume(ctx context.Context, req CreateVolumeRequest) error
 	GetVolume(ctx context.Context, id string) (*Volume, error)
 	GetVolumeByName(ctx context.Context, name string) (*Volume, error)
 	DeleteVolume(ctx context.Context, id string) error
+	// UpdateVolume marks a volume as shared or unshares it.
+	UpdateVolume(ctx context.Context, id string, req UpdateVolumeRequest) (*Volume, error)
 
 	// Attachment operations (called by instance manager)
 	// Multi-attach rules:
+	// - Shared volumes: only ro attachments, any number of them
 	// - If no attachments: allow any mode (rw or ro)
 	// - If existing attachment is rw: reject all new attachments
 	// - If existing attachments are ro: only allow new ro attachments
 	AttachVolume(ctx context.Context, id string, req AttachVolumeRequest) error
+	// CheckAttachVolume reports whether AttachVolume would accept the attachment, without attaching.
+	CheckAttachVolume(ctx context.Context, id string, req AttachVolumeRequest) error
 	DetachVolume(ctx context.Context, volumeID string, instanceID string) error
 
 	// GetVolumePath returns the path to the volume data file
 	GetVolumePath(id string) string
 
+	// UnlockVolume returns the path of the block device an instance should
+	// attach for a volume: for an encrypted volume, the instance's decrypted
+	// mapping, which is opened if needed; otherwise the volume data file.
+	UnlockVolume(ctx context.Context, id string, instanceID string, readonly bool) (string, error)
+	// LockVolume closes an instance's decrypted mapping of an encrypted
+	// volume, if open. It's a no-op for unencrypted volumes.
+	LockVolume(ctx context.Context, id string, instanceID string) error
+
 	// TotalVolumeBytes returns the total size of all volumes.
 	// Used by the resource manager for disk capacity tracking.
 	TotalVolumeBytes(ctx context.Context) (int64, error)
@@ -41,18 +57,21 @@ type Manager interface {
 
 type manager struct {
 	paths                 *paths.Paths
-	maxTotalVolumeStorage int64    // Maximum total volume storage in bytes (0 = unlimited)
-	volumeLocks           sync.Map // map[string]*sync.RWMutex - per-volume locks
+	maxTotalVolumeStorage int64           // Maximum total volume storage in bytes (0 = unlimited)
+	keyring               keyring.Keyring // Encrypted volume keys (nil = encryption disabled)
+	volumeLocks           sync.Map        // map[string]*sync.RWMutex - per-volume locks
 	metrics               *Metrics
 }
 
 // NewManager creates a new volumes manager.
 // maxTotalVolumeStorage is the maximum total volume storage in bytes (0 = unlimited).
+// If keys is nil, encrypted volumes are rejected.
 // If meter is nil, metrics are disabled.
-func NewManager(p *paths.Paths, maxTotalVolumeStorage int64, meter metric.Meter) Manager {
+func NewManager(p *paths.Paths, maxTotalVolumeStorage int64, keys keyring.Keyring, meter metric.Meter) Manager {
 	m := &manager{
 		paths:                 p,
 		maxTotalVolumeStorage: maxTotalVolumeStorage,
+		keyring:               keys,
 		volumeLocks:           sync.Map{},
 	}
 
@@ -117,23 +136,11 @@ func (m *manager) CreateVolume(ctx context.Context, req CreateVolumeRequest) (*V
 		id = *req.Id
 	}
 
-	// Check volume doesn't already exist
-	if _, err := loadMetadata(m.paths, id); err == nil {
-		return nil, ErrAlreadyExists
+	if req.Encrypted && m.keyring == nil {
+		return nil, ErrEncryptionDisabled
 	}
-
-	// Check total volume storage limit
-	if m.maxTotalVolumeStorage > 0 {
-		currentStorage, err := m.calculateTotalVolumeStorage(ctx)
-		if err != nil {
-			// Log but don't fail - continue with creation
-			// (better to allow creation than block due to listing error)
-		} else {
-			newVolumeSize := int64(req.SizeGb) * 1024 * 1024 * 1024
-			if currentStorage+newVolumeSize > m.maxTotalVolumeStorage {
-				return nil, fmt.Errorf("total volume storage would be %d bytes, exceeds limit of %d bytes", currentStorage+newVolumeSize, m.maxTotalVolumeStorage)
-			}
-		}
+	if err := m.admitCreate(ctx, id, req.SizeGb); err != nil {
+		return nil, err
 	}
 
 	// Create volume directory
@@ -142,7 +149,12 @@ func (m *manager) CreateVolume(ctx context.Context, req CreateVolumeRequest) (*V
 	}
 
 	// Create and format the disk
-	if err := createVolumeDisk(m.paths, id, req.SizeGb); err != nil {
+	if req.Encrypted {
+		if err := m.createEncryptedVolume(ctx, id, req.SizeGb); err != nil {
+			deleteVolumeData(m.paths, id)
+			return nil, err
+		}
+	} else if err := createVolumeDisk(m.paths, id, req.SizeGb); err != nil {
 		// Cleanup on error
 		deleteVolumeData(m.paths, id)
 		return nil, err
@@ -154,6 +166,10 @@ func (m *manager) CreateVolume(ctx context.Context, req CreateVolumeRequest) (*V
 		Id:        id,
 		Name:      req.Name,
 		SizeGb:    req.SizeGb,
+		Tenant:    req.Tenant,
+		CreatedBy: req.CreatedBy,
+		Shared:    req.Shared,
+		Encrypted: req.Encrypted,
 		CreatedAt: now.Format(time.RFC3339),
 	}
 
@@ -161,6 +177,9 @@ func (m *manager) CreateVolume(ctx context.Context, req CreateVolumeRequest) (*V
 	if err := saveMetadata(m.paths, meta); err != nil {
 		// Cleanup on error
 		deleteVolumeData(m.paths, id)
+		if req.Encrypted {
+			m.keyring.Delete(id)
+		}
 		return nil, err
 	}
 
@@ -168,6 +187,23 @@ func (m *manager) CreateVolume(ctx context.Context, req CreateVolumeRequest) (*V
 	return m.metadataToVolume(meta), nil
 }
 
+// createEncryptedVolume generates a key for a volume, stores it in the
+// keyring and creates the LUKS2 disk. The key is removed on failure.
+func (m *manager) createEncryptedVolume(ctx context.Context, id string, sizeGb int) error {
+	key, err := generateLUKSKey()
+	if err != nil {
+		return err
+	}
+	if err := m.keyring.Put(id, key); err != nil {
+		return fmt.Errorf("store volume key: %w", err)
+	}
+	if err := createEncryptedVolumeDisk(ctx, m.paths.VolumeData(id), id, sizeGb, key); err != nil {
+		m.keyring.Delete(id)
+		return err
+	}
+	return nil
+}
+
 // CreateVolumeFromArchive creates a new volume pre-populated with content from a tar.gz archive.
 // The archive is safely extracted with size limits to prevent tar bombs.
 func (m *manager) CreateVolumeFromArchive(ctx context.Context, req CreateVolumeFromArchiveRequest, archive io.Reader) (*Volume, error) {
@@ -179,25 +215,12 @@ func (m *manager) CreateVolumeFromArchive(ctx context.Context, req CreateVolumeF
 		id = *req.Id
 	}
 
-	// Check volume doesn't already exist
-	if _, err := loadMetadata(m.paths, id); err == nil {
-		return nil, ErrAlreadyExists
+	if err := m.admitCreate(ctx, id, req.SizeGb); err != nil {
+		return nil, err
 	}
 
 	maxBytes := int64(req.SizeGb) * 1024 * 1024 * 1024
 
-	// Check total volume storage limit
-	if m.maxTotalVolumeStorage > 0 {
-		currentStorage, err := m.calculateTotalVolumeStorage(ctx)
-		if err != nil {
-			// Log but don't fail - continue with creation
-		} else {
-			if currentStorage+maxBytes > m.maxTotalVolumeStorage {
-				return nil, fmt.Errorf("total volume storage would be %d bytes, exceeds limit of %d bytes", currentStorage+maxBytes, m.maxTotalVolumeStorage)
-			}
-		}
-	}
-
 	// Create temp directory for extraction
 	tempDir, err := os.MkdirTemp("", "volume-archive-*")
 	if err != nil {
@@ -236,6 +259,9 @@ func (m *manager) CreateVolumeFromArchive(ctx context.Context, req CreateVolumeF
 		Id:        id,
 		Name:      req.Name,
 		SizeGb:    actualSizeGb,
+		Tenant:    req.Tenant,
+		CreatedBy: req.CreatedBy,
+		Shared:    req.Shared,
 		CreatedAt: now.Format(time.RFC3339),
 	}
 
@@ -310,14 +336,94 @@ func (m *manager) DeleteVolume(ctx context.Context, id string) error {
 		return err
 	}
 
+	// The key is deleted last: without it, the data can't be recovered
+	if meta.Encrypted {
+		if err := m.keyring.Delete(id); err != nil {
+			return err
+		}
+	}
+
 	// Clean up lock
 	m.volumeLocks.Delete(id)
 
 	return nil
 }
 
+// UpdateVolume marks a volume as shared or unshares it. Attachments are
+// counted under the volume lock: a volume can only be shared while it has
+// no read-write attachment, and only unshared once all of its attachments
+// are gone, so no instance can write to it while it's mounted as shared.
+func (m *manager) UpdateVolume(ctx context.Context, id string, req UpdateVolumeRequest) (*Volume, error) {
+	lock := m.getVolumeLock(id)
+	lock.Lock()
+	defer lock.Unlock()
+
+	meta, err := loadMetadata(m.paths, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Shared != nil && *req.Shared != meta.Shared {
+		if *req.Shared {
+			for _, att := range meta.Attachments {
+				if !att.Readonly {
+					return nil, fmt.Errorf("%w: volume has read-write attachment to instance %s", ErrInUse, att.InstanceID)
+				}
+			}
+		} else if len(meta.Attachments) > 0 {
+			return nil, fmt.Errorf("%w: volume has %d shared attachments", ErrInUse, len(meta.Attachments))
+		}
+		meta.Shared = *req.Shared
+		if err := saveMetadata(m.paths, meta); err != nil {
+			return nil, err
+		}
+	}
+
+	return m.metadataToVolume(meta), nil
+}
+
+// CheckCreateVolume reports whether CreateVolume would accept the request,
+// without creating anything.
+func (m *manager) CheckCreateVolume(ctx context.Context, req CreateVolumeRequest) error {
+	id := ""
+	if req.Id != nil {
+		id = *req.Id
+	}
+	if req.Encrypted && m.keyring == nil {
+		return ErrEncryptionDisabled
+	}
+	return m.admitCreate(ctx, id, req.SizeGb)
+}
+
+// admitCreate checks that a volume ID is unused and that the storage limit
+// leaves room for the volume. An empty ID is always unused.
+func (m *manager) admitCreate(ctx context.Context, id string, sizeGb int) error {
+	// Check volume doesn't already exist
+	if id != "" {
+		if _, err := loadMetadata(m.paths, id); err == nil {
+			return ErrAlreadyExists
+		}
+	}
+
+	// Check total volume storage limit
+	if m.maxTotalVolumeStorage > 0 {
+		currentStorage, err := m.calculateTotalVolumeStorage(ctx)
+		if err != nil {
+			// Log but don't fail - continue with creation
+			// (better to allow creation than block due to listing error)
+		} else {
+			newVolumeSize := int64(sizeGb) * 1024 * 1024 * 1024
+			if currentStorage+newVolumeSize > m.maxTotalVolumeStorage {
+				return fmt.Errorf("total volume storage would be %d bytes, exceeds limit of %d bytes", currentStorage+newVolumeSize, m.maxTotalVolumeStorage)
+			}
+		}
+	}
+	return nil
+}
+
 // AttachVolume marks a volume as attached to an instance
 // Multi-attach rules (dynamic based on current state):
+// - Shared volumes: only ro attachments, any number of them
 // - If no attachments: allow any mode (rw or ro)
 // - If existing attachment is rw: reject all new attachments
 // - If existing attachments are ro: only allow new ro attachments
@@ -330,7 +436,36 @@ func (m *manager) AttachVolume(ctx context.Context, id string, req AttachVolumeR
 	if err != nil {
 		return err
 	}
+	if err := checkAttach(meta, req); err != nil {
+		return err
+	}
 
+	// Add new attachment
+	meta.Attachments = append(meta.Attachments, storedAttachment{
+		InstanceID: req.InstanceID,
+		MountPath:  req.MountPath,
+		Readonly:   req.Readonly,
+	})
+
+	return saveMetadata(m.paths, meta)
+}
+
+// CheckAttachVolume reports whether AttachVolume would accept the
+// attachment under the multi-attach rules, without attaching.
+func (m *manager) CheckAttachVolume(ctx context.Context, id string, req AttachVolumeRequest) error {
+	lock := m.getVolumeLock(id)
+	lock.Lock()
+	defer lock.Unlock()
+
+	meta, err := loadMetadata(m.paths, id)
+	if err != nil {
+		return err
+	}
+	return checkAttach(meta, req)
+}
+
+// checkAttach applies the multi-attach rules to a new attachment
+func checkAttach(meta *storedMetadata, req AttachVolumeRequest) error {
 	// Check if this instance is already attached
 	for _, att := range meta.Attachments {
 		if att.InstanceID == req.InstanceID {
@@ -338,28 +473,25 @@ func (m *manager) AttachVolume(ctx context.Context, id string, req AttachVolumeR
 		}
 	}
 
+	// Shared volumes are never written, so ro attachments can't be locked out
+	if meta.Shared && !req.Readonly {
+		return fmt.Errorf("%w: volume %s can only be attached read-only", ErrReadOnly, meta.Id)
+	}
+
 	// Apply multi-attach rules
 	if len(meta.Attachments) > 0 {
 		// Check if any existing attachment is read-write
 		for _, att := range meta.Attachments {
 			if !att.Readonly {
-				return fmt.Errorf("volume has exclusive read-write attachment to instance %s", att.InstanceID)
+				return fmt.Errorf("%w: volume has exclusive read-write attachment to instance %s", ErrInUse, att.InstanceID)
 			}
 		}
 		// Existing attachments are all read-only, new attachment must also be read-only
 		if !req.Readonly {
-			return fmt.Errorf("cannot attach read-write: volume has existing read-only attachments")
+			return fmt.Errorf("%w: cannot attach read-write: volume has existing read-only attachments", ErrInUse)
 		}
 	}
-
-	// Add new attachment
-	meta.Attachments = append(meta.Attachments, storedAttachment{
-		InstanceID: req.InstanceID,
-		MountPath:  req.MountPath,
-		Readonly:   req.Readonly,
-	})
-
-	return saveMetadata(m.paths, meta)
+	return nil
 }
 
 // DetachVolume removes the attachment for a specific instance
@@ -397,6 +529,54 @@ func (m *manager) GetVolumePath(id string) string {
 	return m.paths.VolumeData(id)
 }
 
+// UnlockVolume returns the path of the block device an instance should
+// attach for a volume, opening the instance's decrypted mapping of an
+// encrypted volume if needed. Each attachment gets its own mapping, opened
+// read-only for read-only attachments.
+func (m *manager) UnlockVolume(ctx context.Context, id string, instanceID string, readonly bool) (string, error) {
+	lock := m.getVolumeLock(id)
+	lock.RLock()
+	defer lock.RUnlock()
+
+	meta, err := loadMetadata(m.paths, id)
+	if err != nil {
+		return "", err
+	}
+	if !meta.Encrypted {
+		return m.paths.VolumeData(id), nil
+	}
+	if m.keyring == nil {
+		return "", fmt.Errorf("%w: can't unlock volume %s", ErrEncryptionDisabled, id)
+	}
+
+	key, err := m.keyring.Get(id)
+	if err != nil {
+		return "", fmt.Errorf("get volume key: %w", err)
+	}
+	name := luksMapperName(id, instanceID)
+	if err := luksOpen(ctx, m.paths.VolumeData(id), name, key, readonly); err != nil {
+		return "", err
+	}
+	return luksDevicePath(name), nil
+}
+
+// LockVolume closes an instance's decrypted mapping of an encrypted volume,
+// if open
+func (m *manager) LockVolume(ctx context.Context, id string, instanceID string) error {
+	lock := m.getVolumeLock(id)
+	lock.RLock()
+	defer lock.RUnlock()
+
+	meta, err := loadMetadata(m.paths, id)
+	if err != nil {
+		return err
+	}
+	if !meta.Encrypted {
+		return nil
+	}
+	return luksClose(ctx, luksMapperName(id, instanceID))
+}
+
 // TotalVolumeBytes returns the total size of all volumes.
 func (m *manager) TotalVolumeBytes(ctx context.Context) (int64, error) {
 	return m.calculateTotalVolumeStorage(ctx)
@@ -420,6 +600,10 @@ func (m *manager) metadataToVolume(meta *storedMetadata) *Volume {
 		Id:          meta.Id,
 		Name:        meta.Name,
 		SizeGb:      meta.SizeGb,
+		Tenant:      meta.Tenant,
+		CreatedBy:   meta.CreatedBy,
+		Shared:      meta.Shared,
+		Encrypted:   meta.Encrypted,
 		CreatedAt:   createdAt,
 		Attachments: attachments,
 	}
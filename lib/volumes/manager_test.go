@@ -1,12 +1,16 @@
 package volumes
 
 import (
+	"bytes"
 	"context"
 	"fmt"
 	"os"
+	"os/exec"
 	"testing"
 
+	"github.com/kernel/hypeman/lib/keyring"
 	"github.com/kernel/hypeman/lib/paths"
+	"github.com/samber/lo"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -23,7 +27,7 @@ func setupTestManager(t *testing.T) (Manager, *paths.Paths, func()) {
 	// Create required directories
 	require.NoError(t, os.MkdirAll(p.VolumesDir(), 0755))
 
-	manager := NewManager(p, 0, nil) // 0 = unlimited storage
+	manager := NewManager(p, 0, nil, nil) // 0 = unlimited storage
 
 	cleanup := func() {
 		os.RemoveAll(tmpDir)
@@ -389,3 +393,170 @@ func TestMultiAttach_ConcurrentRWConflict(t *testing.T) {
 	assert.Len(t, vol.Attachments, 1, "Should have exactly one attachment")
 	assert.False(t, vol.Attachments[0].Readonly, "Attachment should be read-write")
 }
+
+func TestCheckAttachVolume_DoesNotAttach(t *testing.T) {
+	manager, _, cleanup := setupTestManager(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	vol, err := manager.CreateVolume(ctx, CreateVolumeRequest{Name: "test-vol", SizeGb: 1})
+	require.NoError(t, err)
+
+	// A check passes without recording an attachment
+	req := AttachVolumeRequest{InstanceID: "instance-1", MountPath: "/data"}
+	require.NoError(t, manager.CheckAttachVolume(ctx, vol.Id, req))
+	vol, err = manager.GetVolume(ctx, vol.Id)
+	require.NoError(t, err)
+	assert.Empty(t, vol.Attachments)
+
+	// Once attached read-write, the same rules as AttachVolume apply
+	require.NoError(t, manager.AttachVolume(ctx, vol.Id, req))
+	err = manager.CheckAttachVolume(ctx, vol.Id, AttachVolumeRequest{InstanceID: "instance-2", MountPath: "/data", Readonly: true})
+	assert.ErrorIs(t, err, ErrInUse)
+}
+
+func TestCheckCreateVolume_DuplicateID(t *testing.T) {
+	manager, _, cleanup := setupTestManager(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	id := "vol-fixed"
+	req := CreateVolumeRequest{Name: "test-vol", SizeGb: 1, Id: &id}
+	require.NoError(t, manager.CheckCreateVolume(ctx, req))
+
+	// Checking doesn't create the volume
+	_, err := manager.GetVolume(ctx, id)
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	_, err = manager.CreateVolume(ctx, req)
+	require.NoError(t, err)
+	assert.ErrorIs(t, manager.CheckCreateVolume(ctx, req), ErrAlreadyExists)
+}
+
+func TestSharedVolume_OnlyReadOnlyAttachments(t *testing.T) {
+	manager, _, cleanup := setupTestManager(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	vol, err := manager.CreateVolume(ctx, CreateVolumeRequest{Name: "weights", SizeGb: 1, Shared: true})
+	require.NoError(t, err)
+	assert.True(t, vol.Shared)
+
+	// Even the first attachment can't be read-write
+	err = manager.CheckAttachVolume(ctx, vol.Id, AttachVolumeRequest{InstanceID: "instance-1", MountPath: "/models"})
+	assert.ErrorIs(t, err, ErrReadOnly)
+
+	for i := range 3 {
+		require.NoError(t, manager.AttachVolume(ctx, vol.Id, AttachVolumeRequest{
+			InstanceID: fmt.Sprintf("instance-%d", i),
+			MountPath:  "/models",
+			Readonly:   true,
+		}))
+	}
+	vol, err = manager.GetVolume(ctx, vol.Id)
+	require.NoError(t, err)
+	assert.Len(t, vol.Attachments, 3)
+	assert.ErrorIs(t, manager.DeleteVolume(ctx, vol.Id), ErrInUse)
+}
+
+func TestUpdateVolume_Shared(t *testing.T) {
+	manager, _, cleanup := setupTestManager(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	vol, err := manager.CreateVolume(ctx, CreateVolumeRequest{Name: "weights", SizeGb: 1})
+	require.NoError(t, err)
+
+	// Can't share while an instance writes to the volume
+	require.NoError(t, manager.AttachVolume(ctx, vol.Id, AttachVolumeRequest{InstanceID: "writer", MountPath: "/models"}))
+	_, err = manager.UpdateVolume(ctx, vol.Id, UpdateVolumeRequest{Shared: lo.ToPtr(true)})
+	assert.ErrorIs(t, err, ErrInUse)
+	require.NoError(t, manager.DetachVolume(ctx, vol.Id, "writer"))
+
+	vol, err = manager.UpdateVolume(ctx, vol.Id, UpdateVolumeRequest{Shared: lo.ToPtr(true)})
+	require.NoError(t, err)
+	assert.True(t, vol.Shared)
+
+	// Can't unshare while shared attachments exist
+	require.NoError(t, manager.AttachVolume(ctx, vol.Id, AttachVolumeRequest{InstanceID: "reader", MountPath: "/models", Readonly: true}))
+	_, err = manager.UpdateVolume(ctx, vol.Id, UpdateVolumeRequest{Shared: lo.ToPtr(false)})
+	assert.ErrorIs(t, err, ErrInUse)
+	require.NoError(t, manager.DetachVolume(ctx, vol.Id, "reader"))
+
+	vol, err = manager.UpdateVolume(ctx, vol.Id, UpdateVolumeRequest{Shared: lo.ToPtr(false)})
+	require.NoError(t, err)
+	assert.False(t, vol.Shared)
+
+	_, err = manager.UpdateVolume(ctx, "missing", UpdateVolumeRequest{Shared: lo.ToPtr(true)})
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestCreateVolume_EncryptionDisabled(t *testing.T) {
+	manager, _, cleanup := setupTestManager(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	req := CreateVolumeRequest{Name: "secret", SizeGb: 1, Encrypted: true}
+	assert.ErrorIs(t, manager.CheckCreateVolume(ctx, req), ErrEncryptionDisabled)
+	_, err := manager.CreateVolume(ctx, req)
+	assert.ErrorIs(t, err, ErrEncryptionDisabled)
+
+	vols, err := manager.ListVolumes(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, vols)
+}
+
+func TestEncryptedVolume(t *testing.T) {
+	if _, err := exec.LookPath("cryptsetup"); err != nil {
+		t.Skip("cryptsetup not found in PATH - skipping encrypted volume test")
+	}
+	if os.Geteuid() != 0 {
+		t.Skip("encrypted volumes require root - skipping encrypted volume test")
+	}
+
+	p := paths.New(t.TempDir())
+	keys, err := keyring.NewFileKeyring(p.KeyringDir(), bytes.Repeat([]byte{7}, keyring.MasterKeySize))
+	require.NoError(t, err)
+	manager := NewManager(p, 0, keys, nil)
+	ctx := context.Background()
+
+	vol, err := manager.CreateVolume(ctx, CreateVolumeRequest{Name: "secret", SizeGb: 1, Encrypted: true})
+	require.NoError(t, err)
+	assert.True(t, vol.Encrypted)
+	_, err = keys.Get(vol.Id)
+	require.NoError(t, err)
+
+	devicePath, err := manager.UnlockVolume(ctx, vol.Id, "instance-1", false)
+	require.NoError(t, err)
+	assert.Equal(t, "/dev/mapper/"+luksMapperName(vol.Id, "instance-1"), devicePath)
+	_, err = os.Stat(devicePath)
+	assert.NoError(t, err)
+
+	// Unlocking again reuses the open mapping
+	again, err := manager.UnlockVolume(ctx, vol.Id, "instance-1", false)
+	require.NoError(t, err)
+	assert.Equal(t, devicePath, again)
+
+	require.NoError(t, manager.LockVolume(ctx, vol.Id, "instance-1"))
+	_, err = os.Stat(devicePath)
+	assert.True(t, os.IsNotExist(err))
+
+	// Deleting the volume deletes its key
+	require.NoError(t, manager.DeleteVolume(ctx, vol.Id))
+	_, err = keys.Get(vol.Id)
+	assert.ErrorIs(t, err, keyring.ErrNotFound)
+}
+
+func TestUnlockVolume_Unencrypted(t *testing.T) {
+	manager, p, cleanup := setupTestManager(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	vol, err := manager.CreateVolume(ctx, CreateVolumeRequest{Name: "plain", SizeGb: 1})
+	require.NoError(t, err)
+
+	path, err := manager.UnlockVolume(ctx, vol.Id, "instance-1", false)
+	require.NoError(t, err)
+	assert.Equal(t, p.VolumeData(vol.Id), path)
+	assert.NoError(t, manager.LockVolume(ctx, vol.Id, "instance-1"))
+}
@@ -12,7 +12,7 @@ import (
 
 // Filesystem structure:
 // {dataDir}/volumes/{volume-id}/
-//   data.raw        # ext4-formatted sparse disk
+//   data.raw        # ext4-formatted sparse disk (LUKS2 if encrypted)
 //   metadata.json   # Volume metadata
 
 // storedAttachment represents an attachment in stored metadata
@@ -27,6 +27,10 @@ type storedMetadata struct {
 	Id          string             `json:"id"`
 	Name        string             `json:"name"`
 	SizeGb      int                `json:"size_gb"`
+	Tenant      string             `json:"tenant,omitempty"`
+	CreatedBy   string             `json:"created_by,omitempty"`
+	Shared      bool               `json:"shared,omitempty"`
+	Encrypted   bool               `json:"encrypted,omitempty"`
 	CreatedAt   string             `json:"created_at"` // RFC3339 format
 	Attachments []storedAttachment `json:"attachments,omitempty"`
 }
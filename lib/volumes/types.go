@@ -14,15 +14,29 @@ type Volume struct {
 	Id          string
 	Name        string
 	SizeGb      int
+	Tenant      string // Optional tenant label for access scoping
+	CreatedBy   string // Principal that created the volume, for quotas (empty = unknown)
+	Shared      bool   // Shared volumes can only be attached read-only, by any number of instances
+	Encrypted   bool   // LUKS2-encrypted at rest, unlocked on the host while attached
 	CreatedAt   time.Time
 	Attachments []Attachment // List of current attachments (empty if not attached)
 }
 
 // CreateVolumeRequest is the domain request for creating a volume
 type CreateVolumeRequest struct {
-	Name   string
-	SizeGb int
-	Id     *string // Optional custom ID
+	Name      string
+	SizeGb    int
+	Id        *string // Optional custom ID
+	Tenant    string  // Optional tenant label
+	CreatedBy string  // Optional principal creating the volume, for quotas
+	Shared    bool    // Only allow read-only attachments
+	Encrypted bool    // Format as LUKS2 with a key from the keyring
+}
+
+// UpdateVolumeRequest is the domain request for updating a volume. Nil
+// fields are left unchanged.
+type UpdateVolumeRequest struct {
+	Shared *bool // Mark as shared (requires no read-write attachments) or unshare (requires no attachments)
 }
 
 // AttachVolumeRequest is the domain request for attaching a volume to an instance
@@ -35,8 +49,11 @@ type AttachVolumeRequest struct {
 // CreateVolumeFromArchiveRequest is the domain request for creating a volume
 // pre-populated with content from a tar.gz archive
 type CreateVolumeFromArchiveRequest struct {
-	Name   string
-	SizeGb int     // Maximum size in GB (extraction fails if content exceeds this)
-	Id     *string // Optional custom ID
+	Name      string
+	SizeGb    int     // Maximum size in GB (extraction fails if content exceeds this)
+	Id        *string // Optional custom ID
+	Tenant    string  // Optional tenant label
+	CreatedBy string  // Optional principal creating the volume, for quotas
+	Shared    bool    // Only allow read-only attachments
 }
 